@@ -42,6 +42,7 @@ import (
 var _ bind.ContractBackend = (*SimulatedBackend)(nil)
 
 var errBlockNumberUnsupported = errors.New("SimulatedBackend cannot access blocks other than the latest block")
+var errDecoyQueryUnsupported = errors.New("SimulatedBackend can only select decoys uniformly")
 
 var (
 	key, _      = crypto.HexToECDSA("f1572f76b75b40a7da72d6f2ee7fda3d1189c2d28f0a2f096347055abe344d7f")
@@ -292,6 +293,28 @@ func (b *SimulatedBackend) callContract(ctx context.Context, call ethereum.CallM
 	return ret, gasUsed, failed, err
 }
 
+// GetOTAMixSet returns size OTAs of the blockchain holding the same value as
+// ota, to hide it among in a ring signature. The simulated chain has no OTA
+// index, so the decoys can only be selected uniformly.
+func (b *SimulatedBackend) GetOTAMixSet(ctx context.Context, ota []byte, size int, q *ethereum.DecoyQuery) ([][]byte, error) {
+	if q != nil && (q.Strategy != "" && q.Strategy != vm.DecoyUniform || q.ExcludeSpent) {
+		return nil, errDecoyQueryUnsupported
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	otaAX, err := vm.GetAXFromWanAddr(ota)
+	if err != nil {
+		return nil, err
+	}
+	statedb, err := b.env.Blockchain().State()
+	if err != nil {
+		return nil, err
+	}
+	set, _, err := vm.GetOTASet(statedb, otaAX, size)
+	return set, err
+}
+
 // SendTransaction updates the pending block to include the given transaction.
 // It panics if the transaction is invalid.
 func (b *SimulatedBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
@@ -49,6 +49,9 @@ var (
 	ErrLocked  = accounts.NewAuthNeededError("password or unlock")
 	ErrNoMatch = errors.New("no key for given address or file")
 	ErrDecrypt = errors.New("could not decrypt key with given passphrase")
+
+	ErrNotOTAOwner = errors.New("OTA not owned by account")
+	ErrNoOTAOwner  = errors.New("OTA not owned by any unlocked account")
 )
 
 // KeyStoreType is the reflect type of a keystore backend.
@@ -308,6 +311,63 @@ func (ks *KeyStore) ComputeOTAPPKeys(a accounts.Account, AX, AY, BX, BY string)
 	return []string{pub1X, pub1Y, priv1D, priv2D}, err
 }
 
+// RingSignOTAWithPassphrase ring signs msg with the private key of an OTA, in
+// WAddress format, owned by the account whose key can be decrypted with the
+// given passphrase. The OTA public key is hidden among mixPubs. The returned
+// values are those of crypto.RingSign.
+func (ks *KeyStore) RingSignOTAWithPassphrase(a accounts.Account, passphrase string, ota []byte, msg []byte, mixPubs []*ecdsa.PublicKey) ([]*ecdsa.PublicKey, *ecdsa.PublicKey, []*big.Int, []*big.Int, error) {
+	_, key, err := ks.getDecryptedKey(a, passphrase)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	defer zeroKey(key.PrivateKey)
+	defer zeroKey(key.PrivateKey2)
+
+	A1, R, err := GeneratePKPairFromWAddress(ota)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if key.PrivateKey2 == nil || !crypto.CompareA1(key.PrivateKey2.D.Bytes(), &key.PrivateKey.PublicKey, R, A1) {
+		return nil, nil, nil, nil, ErrNotOTAOwner
+	}
+	otaKey, _, err := crypto.GenerateOneTimePrivateKey2528(key.PrivateKey, key.PrivateKey2, A1, R)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	defer zeroKey(otaKey)
+
+	publicKeys := make([]*ecdsa.PublicKey, 0, len(mixPubs)+1)
+	publicKeys = append(publicKeys, A1)
+	publicKeys = append(publicKeys, mixPubs...)
+	return crypto.RingSign(msg, otaKey.D, publicKeys)
+}
+
+// OTAKeyImage returns the key image spending an OTA, in WAddress format, owned
+// by an unlocked account.
+func (ks *KeyStore) OTAKeyImage(ota []byte) ([]byte, error) {
+	A1, R, err := GeneratePKPairFromWAddress(ota)
+	if err != nil {
+		return nil, err
+	}
+
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	for _, u := range ks.unlocked {
+		if u.PrivateKey2 == nil || !crypto.CompareA1(u.PrivateKey2.D.Bytes(), &u.PrivateKey.PublicKey, R, A1) {
+			continue
+		}
+		otaKey, _, err := crypto.GenerateOneTimePrivateKey2528(u.PrivateKey, u.PrivateKey2, A1, R)
+		if err != nil {
+			return nil, err
+		}
+		defer zeroKey(otaKey)
+
+		return crypto.FromECDSAPub(crypto.KeyImage(otaKey.D, A1)), nil
+	}
+	return nil, ErrNoOTAOwner
+}
+
 // SignHashWithPassphrase signs hash if the private key matching the given address
 // can be decrypted with the given passphrase. The produced signature is in the
 // [R || S || V] format where V is 0 or 1.
@@ -570,6 +630,21 @@ func (ks *KeyStore) GetWanAddress(account accounts.Account) (common.WAddress, er
 	return ret, nil
 }
 
+// ViewKeys returns the view keys of the unlocked accounts.
+func (ks *KeyStore) ViewKeys() []*ViewKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	keys := make([]*ViewKey, 0, len(ks.unlocked))
+	for _, u := range ks.unlocked {
+		if u.PrivateKey2 == nil {
+			continue
+		}
+		keys = append(keys, newViewKey(u.Key))
+	}
+	return keys
+}
+
 // zeroKey zeroes a private key in memory.
 func zeroKey(k *ecdsa.PrivateKey) {
 	if k == nil {
@@ -18,6 +18,8 @@
 package keystore
 
 import (
+	"bytes"
+	"crypto/ecdsa"
 	"io/ioutil"
 	"math/rand"
 	"os"
@@ -534,3 +536,141 @@ func TestComputeOTAPPKeys(t *testing.T) {
 		t.Errorf("invalid ota pk. pk lenght:%d", len(pk))
 	}
 }
+
+func TestViewKeys(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	auth := "wanchain_test"
+	a, err := ks.NewAccount(auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ks.NewAccount(auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := ks.ViewKeys(); len(keys) != 0 {
+		t.Fatalf("view keys of locked accounts: have %d, want 0", len(keys))
+	}
+	if err := ks.Unlock(a, auth); err != nil {
+		t.Fatal(err)
+	}
+	keys := ks.ViewKeys()
+	if len(keys) != 1 || keys[0].Address != a.Address {
+		t.Fatalf("view keys mismatch: have %v, want %x", keys, a.Address)
+	}
+
+	wAddrA, _ := ks.GetWanAddress(a)
+	wAddrB, _ := ks.GetWanAddress(b)
+	otaA, err := genOTA(hexutil.Encode(wAddrA[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otaB, err := genOTA(hexutil.Encode(wAddrB[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !keys[0].IsOTAOwner(common.FromHex(otaA)) {
+		t.Error("OTA of the account not recognised")
+	}
+	if keys[0].IsOTAOwner(common.FromHex(otaB)) {
+		t.Error("OTA of another account recognised")
+	}
+
+	// The view key survives locking the account
+	if err := ks.Lock(a.Address); err != nil {
+		t.Fatal(err)
+	}
+	if !keys[0].IsOTAOwner(common.FromHex(otaA)) {
+		t.Error("OTA not recognised after locking the account")
+	}
+}
+
+func TestRingSignOTAWithPassphrase(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	auth := "wanchain_test"
+	a, err := ks.NewAccount(auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ks.NewAccount(auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wAddrA, _ := ks.GetWanAddress(a)
+	wAddrB, _ := ks.GetWanAddress(b)
+	otaA, err := genOTA(hexutil.Encode(wAddrA[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otaB, err := genOTA(hexutil.Encode(wAddrB[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mixPubs []*ecdsa.PublicKey
+	for i := 0; i < 3; i++ {
+		key, _ := crypto.GenerateKey()
+		mixPubs = append(mixPubs, &key.PublicKey)
+	}
+	msg := a.Address.Bytes()
+
+	if _, _, _, _, err := ks.RingSignOTAWithPassphrase(a, "bad", common.FromHex(otaA), msg, mixPubs); err != ErrDecrypt {
+		t.Fatalf("ring sign with a wrong passphrase: have %v, want %v", err, ErrDecrypt)
+	}
+	if _, _, _, _, err := ks.RingSignOTAWithPassphrase(a, auth, common.FromHex(otaB), msg, mixPubs); err != ErrNotOTAOwner {
+		t.Fatalf("ring sign with a foreign OTA: have %v, want %v", err, ErrNotOTAOwner)
+	}
+	publicKeys, keyImage, w, q, err := ks.RingSignOTAWithPassphrase(a, auth, common.FromHex(otaA), msg, mixPubs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(publicKeys) != len(mixPubs)+1 {
+		t.Fatalf("ring size mismatch: have %d, want %d", len(publicKeys), len(mixPubs)+1)
+	}
+	if !crypto.VerifyRingSign(msg, publicKeys, keyImage, w, q) {
+		t.Error("ring signature invalid")
+	}
+	if ks.unlocked[a.Address] != nil {
+		t.Error("account unlocked by ring signing")
+	}
+}
+
+func TestOTAKeyImage(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	auth := "wanchain_test"
+	a, err := ks.NewAccount(auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wAddr, _ := ks.GetWanAddress(a)
+	ota, err := genOTA(hexutil.Encode(wAddr[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ks.OTAKeyImage(common.FromHex(ota)); err != ErrNoOTAOwner {
+		t.Fatalf("key image with a locked owner: have %v, want %v", err, ErrNoOTAOwner)
+	}
+	if err := ks.Unlock(a, auth); err != nil {
+		t.Fatal(err)
+	}
+	image, err := ks.OTAKeyImage(common.FromHex(ota))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The image must be the one the ring signatures spending the OTA carry
+	_, keyImage, _, _, err := ks.RingSignOTAWithPassphrase(a, auth, common.FromHex(ota), a.Address.Bytes(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := crypto.FromECDSAPub(keyImage); !bytes.Equal(image, want) {
+		t.Errorf("key image mismatch: have %x, want %x", image, want)
+	}
+}
@@ -0,0 +1,386 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package keystore
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+
+	ethereum "github.com/wanchain/go-wanchain"
+	"github.com/wanchain/go-wanchain/accounts"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/event"
+	"github.com/wanchain/go-wanchain/log"
+)
+
+var (
+	ErrViewOnly       = errors.New("view-only account can't sign or spend")
+	ErrInvalidViewKey = errors.New("invalid view key")
+	ErrViewKeyExists  = errors.New("view key already imported")
+)
+
+// ViewKeyStoreType is the reflect type of a view key store backend.
+var ViewKeyStoreType = reflect.TypeOf(&ViewKeyStore{})
+
+// ViewKeyScheme is the protocol scheme prefixing view-only account and wallet
+// URLs.
+var ViewKeyScheme = "viewkey"
+
+// viewKeyVersion is the version of the view key export format.
+const viewKeyVersion = 1
+
+// ViewKey is the view material of a Wanchain privacy account, the public key
+// A and the private key b of its WAddress. It recognises the OTAs generated for
+// the WAddress but can't spend them, as that takes the private key a.
+type ViewKey struct {
+	Address common.Address
+	A       *ecdsa.PublicKey
+	B       *ecdsa.PrivateKey
+}
+
+// viewKeyJSON is the export format of a view key.
+type viewKeyJSON struct {
+	Address     string `json:"address"`
+	WAddress    string `json:"waddress"`
+	PublicKeyA  string `json:"publicKeyA"`
+	PrivateKeyB string `json:"privateKeyB"`
+	Version     int    `json:"version"`
+}
+
+// newViewKey copies the view material out of a key, so it survives the key
+// being locked and zeroed.
+func newViewKey(k *Key) *ViewKey {
+	pub := k.PrivateKey.PublicKey
+	return &ViewKey{
+		Address: k.Address,
+		A:       &pub,
+		B: &ecdsa.PrivateKey{
+			PublicKey: k.PrivateKey2.PublicKey,
+			D:         new(big.Int).Set(k.PrivateKey2.D),
+		},
+	}
+}
+
+// WAddress returns the WAddress the view key belongs to.
+func (k *ViewKey) WAddress() common.WAddress {
+	return *GenerateWaddressFromPK(k.A, &k.B.PublicKey)
+}
+
+// IsOTAOwner reports whether an OTA, in WAddress format, was generated for the
+// WAddress of the view key.
+func (k *ViewKey) IsOTAOwner(otaWanAddr []byte) bool {
+	A1, R, err := GeneratePKPairFromWAddress(otaWanAddr)
+	if err != nil {
+		return false
+	}
+	return crypto.CompareA1(k.B.D.Bytes(), k.A, R, A1)
+}
+
+func (k *ViewKey) MarshalJSON() ([]byte, error) {
+	wAddr := k.WAddress()
+	return json.Marshal(&viewKeyJSON{
+		Address:     hexutil.Encode(k.Address[:]),
+		WAddress:    hexutil.Encode(wAddr[:]),
+		PublicKeyA:  hexutil.Encode(crypto.FromECDSAPub(k.A)),
+		PrivateKeyB: hexutil.Encode(crypto.FromECDSA(k.B)),
+		Version:     viewKeyVersion,
+	})
+}
+
+func (k *ViewKey) UnmarshalJSON(j []byte) error {
+	var keyJSON viewKeyJSON
+	if err := json.Unmarshal(j, &keyJSON); err != nil {
+		return err
+	}
+	if keyJSON.Version != viewKeyVersion {
+		return fmt.Errorf("view key version not supported: %d", keyJSON.Version)
+	}
+	pub, err := hexutil.Decode(keyJSON.PublicKeyA)
+	if err != nil {
+		return ErrInvalidViewKey
+	}
+	A := crypto.ToECDSAPub(pub)
+	if A == nil {
+		return ErrInvalidViewKey
+	}
+	priv, err := hexutil.Decode(keyJSON.PrivateKeyB)
+	if err != nil {
+		return ErrInvalidViewKey
+	}
+	B, err := crypto.ToECDSA(priv)
+	if err != nil {
+		return ErrInvalidViewKey
+	}
+	k.Address, k.A, k.B = crypto.PubkeyToAddress(*A), A, B
+
+	// The address and the WAddress are informative, but must match the keys
+	if keyJSON.Address != "" && common.HexToAddress(keyJSON.Address) != k.Address {
+		return ErrInvalidViewKey
+	}
+	if keyJSON.WAddress != "" {
+		wAddr := k.WAddress()
+		if keyJSON.WAddress != hexutil.Encode(wAddr[:]) {
+			return ErrInvalidViewKey
+		}
+	}
+	return nil
+}
+
+// ImportViewKey loads a view key exported by ExportViewKey.
+func ImportViewKey(keyJSON []byte) (*ViewKey, error) {
+	key := new(ViewKey)
+	if err := json.Unmarshal(keyJSON, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// ExportViewKey exports the view key of an account as JSON. The view key holds
+// the private key b unencrypted, but can't spend the funds of the account.
+func (ks *KeyStore) ExportViewKey(a accounts.Account, passphrase string) (keyJSON []byte, err error) {
+	_, key, err := ks.getDecryptedKey(a, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key.PrivateKey)
+	defer zeroKey(key.PrivateKey2)
+
+	return json.Marshal(newViewKey(key))
+}
+
+// ViewKeyStore manages the view keys of watch-only accounts, stored
+// unencrypted in a directory. Its wallets recognise the OTAs paid to the
+// accounts but refuse to sign.
+type ViewKeyStore struct {
+	dir     string
+	keys    map[common.Address]*ViewKey
+	wallets []accounts.Wallet // Wallets of the view keys, sorted by URL
+
+	updateFeed  event.Feed              // Event feed to notify wallet additions/removals
+	updateScope event.SubscriptionScope // Subscription scope tracking current live listeners
+
+	mu sync.RWMutex
+}
+
+// NewViewKeyStore creates a view key store for the given directory, loading
+// the view keys stored in it.
+func NewViewKeyStore(dir string) *ViewKeyStore {
+	vs := &ViewKeyStore{
+		dir:  dir,
+		keys: make(map[common.Address]*ViewKey),
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		log.Warn("Failed to read view key directory", "dir", dir, "err", err)
+	}
+	for _, fi := range files {
+		if skipKeyFile(fi) {
+			continue
+		}
+		path := filepath.Join(dir, fi.Name())
+		keyJSON, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Warn("Failed to read view key", "path", path, "err", err)
+			continue
+		}
+		key, err := ImportViewKey(keyJSON)
+		if err != nil {
+			log.Warn("Failed to load view key", "path", path, "err", err)
+			continue
+		}
+		vs.add(key, path)
+	}
+	return vs
+}
+
+// add wraps a view key stored at path in a wallet, the caller holds the lock.
+func (vs *ViewKeyStore) add(key *ViewKey, path string) accounts.Wallet {
+	wallet := &viewKeyWallet{
+		account: accounts.Account{Address: key.Address, URL: accounts.URL{Scheme: ViewKeyScheme, Path: path}},
+		key:     key,
+	}
+	vs.keys[key.Address] = key
+	vs.wallets = append(vs.wallets, wallet)
+	sort.Slice(vs.wallets, func(i, j int) bool {
+		return vs.wallets[i].URL().Cmp(vs.wallets[j].URL()) < 0
+	})
+	return wallet
+}
+
+// Wallets implements accounts.Backend, returning a wallet for every view key.
+func (vs *ViewKeyStore) Wallets() []accounts.Wallet {
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+
+	cpy := make([]accounts.Wallet, len(vs.wallets))
+	copy(cpy, vs.wallets)
+	return cpy
+}
+
+// Subscribe implements accounts.Backend, creating an async subscription to
+// receive notifications on the addition or removal of view key wallets.
+func (vs *ViewKeyStore) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return vs.updateScope.Track(vs.updateFeed.Subscribe(sink))
+}
+
+// Import stores a view key exported by ExportViewKey and adds a view-only
+// account for it.
+func (vs *ViewKeyStore) Import(keyJSON []byte) (accounts.Account, error) {
+	key, err := ImportViewKey(keyJSON)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	vs.mu.Lock()
+	if _, ok := vs.keys[key.Address]; ok {
+		vs.mu.Unlock()
+		return accounts.Account{}, ErrViewKeyExists
+	}
+	content, err := json.Marshal(key)
+	if err != nil {
+		vs.mu.Unlock()
+		return accounts.Account{}, err
+	}
+	path := filepath.Join(vs.dir, "viewkey--"+common.Bytes2Hex(key.Address[:]))
+	if err := writeKeyFile(path, content); err != nil {
+		vs.mu.Unlock()
+		return accounts.Account{}, err
+	}
+	wallet := vs.add(key, path)
+	vs.mu.Unlock()
+
+	vs.updateFeed.Send(accounts.WalletEvent{Wallet: wallet, Kind: accounts.WalletArrived})
+	return wallet.Accounts()[0], nil
+}
+
+// Delete removes the view key of an account.
+func (vs *ViewKeyStore) Delete(addr common.Address) error {
+	vs.mu.Lock()
+	for i, wallet := range vs.wallets {
+		account := wallet.Accounts()[0]
+		if account.Address != addr {
+			continue
+		}
+		if err := os.Remove(account.URL.Path); err != nil {
+			vs.mu.Unlock()
+			return err
+		}
+		delete(vs.keys, addr)
+		vs.wallets = append(vs.wallets[:i], vs.wallets[i+1:]...)
+		vs.mu.Unlock()
+
+		vs.updateFeed.Send(accounts.WalletEvent{Wallet: wallet, Kind: accounts.WalletDropped})
+		return nil
+	}
+	vs.mu.Unlock()
+	return ErrNoMatch
+}
+
+// ViewKeys returns the view keys of the watch-only accounts.
+func (vs *ViewKeyStore) ViewKeys() []*ViewKey {
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+
+	keys := make([]*ViewKey, 0, len(vs.keys))
+	for _, key := range vs.keys {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// viewKeyWallet implements the accounts.Wallet interface for a watch-only
+// account. It recognises the OTAs paid to the account but refuses to sign.
+type viewKeyWallet struct {
+	account accounts.Account // Single account contained in this wallet
+	key     *ViewKey         // View key of the account
+}
+
+// URL implements accounts.Wallet, returning the URL of the account within.
+func (w *viewKeyWallet) URL() accounts.URL {
+	return w.account.URL
+}
+
+// Status implements accounts.Wallet, view-only wallets never unlock.
+func (w *viewKeyWallet) Status() (string, error) {
+	return "View-only", nil
+}
+
+// Open implements accounts.Wallet, but is a noop for view-only wallets.
+func (w *viewKeyWallet) Open(passphrase string) error { return nil }
+
+// Close implements accounts.Wallet, but is a noop for view-only wallets.
+func (w *viewKeyWallet) Close() error { return nil }
+
+// Accounts implements accounts.Wallet, returning the watch-only account.
+func (w *viewKeyWallet) Accounts() []accounts.Account {
+	return []accounts.Account{w.account}
+}
+
+// Contains implements accounts.Wallet, returning whether a particular account is
+// or is not wrapped by this wallet instance.
+func (w *viewKeyWallet) Contains(account accounts.Account) bool {
+	return account.Address == w.account.Address && (account.URL == (accounts.URL{}) || account.URL == w.account.URL)
+}
+
+// Derive implements accounts.Wallet, but view-only wallets don't derive accounts.
+func (w *viewKeyWallet) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	return accounts.Account{}, accounts.ErrNotSupported
+}
+
+// SelfDerive implements accounts.Wallet, but is a noop for view-only wallets.
+func (w *viewKeyWallet) SelfDerive(base accounts.DerivationPath, chain ethereum.ChainStateReader) {}
+
+// SignHash implements accounts.Wallet, view-only wallets can't sign.
+func (w *viewKeyWallet) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	return nil, ErrViewOnly
+}
+
+// SignTx implements accounts.Wallet, view-only wallets can't sign.
+func (w *viewKeyWallet) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return nil, ErrViewOnly
+}
+
+// SignHashWithPassphrase implements accounts.Wallet, view-only wallets can't sign.
+func (w *viewKeyWallet) SignHashWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	return nil, ErrViewOnly
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, view-only wallets can't sign.
+func (w *viewKeyWallet) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return nil, ErrViewOnly
+}
+
+// GetWanAddress implements accounts.Wallet, returning the WAddress of the
+// watch-only account.
+func (w *viewKeyWallet) GetWanAddress(account accounts.Account) (common.WAddress, error) {
+	if !w.Contains(account) {
+		return common.WAddress{}, accounts.ErrUnknownAccount
+	}
+	return w.key.WAddress(), nil
+}
+
+// ComputeOTAPPKeys implements accounts.Wallet, the private key of an OTA can't
+// be derived without the private key a, which view-only wallets don't hold.
+func (w *viewKeyWallet) ComputeOTAPPKeys(account accounts.Account, AX, AY, BX, BY string) ([]string, error) {
+	return nil, ErrViewOnly
+}
+
+// IsOTAOwner reports whether an OTA, in WAddress format, was paid to the
+// watch-only account.
+func (w *viewKeyWallet) IsOTAOwner(account accounts.Account, otaWanAddr []byte) (bool, error) {
+	if !w.Contains(account) {
+		return false, accounts.ErrUnknownAccount
+	}
+	return w.key.IsOTAOwner(otaWanAddr), nil
+}
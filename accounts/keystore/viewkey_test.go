@@ -0,0 +1,138 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package keystore
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/accounts"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+)
+
+func TestViewKeyExportImport(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	auth := "wanchain_test"
+	a, err := ks.NewAccount(auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.ExportViewKey(a, "wrong"); err != ErrDecrypt {
+		t.Fatalf("export with wrong passphrase: have %v, want %v", err, ErrDecrypt)
+	}
+	keyJSON, err := ks.ExportViewKey(a, auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ImportViewKey(keyJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wAddr, _ := ks.GetWanAddress(a)
+	if key.Address != a.Address || key.WAddress() != wAddr {
+		t.Fatalf("view key mismatch: have %x %x, want %x %x", key.Address, key.WAddress(), a.Address, wAddr)
+	}
+	ota, err := genOTA(hexutil.Encode(wAddr[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.IsOTAOwner(common.FromHex(ota)) {
+		t.Error("OTA of the account not recognised by the imported view key")
+	}
+
+	// A view key whose address doesn't match its keys is rejected
+	b, _ := ks.NewAccount(auth)
+	otherJSON, _ := ks.ExportViewKey(b, auth)
+	other, _ := ImportViewKey(otherJSON)
+	var fields map[string]interface{}
+	if err := json.Unmarshal(keyJSON, &fields); err != nil {
+		t.Fatal(err)
+	}
+	fields["address"] = hexutil.Encode(other.Address[:])
+	tampered, _ := json.Marshal(fields)
+	if _, err := ImportViewKey(tampered); err != ErrInvalidViewKey {
+		t.Fatalf("import of mismatching view key: have %v, want %v", err, ErrInvalidViewKey)
+	}
+}
+
+func TestViewKeyStore(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	auth := "wanchain_test"
+	a, err := ks.NewAccount(auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyJSON, err := ks.ExportViewKey(a, auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	viewDir, err := ioutil.TempDir("", "wanchain-viewkeys-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(viewDir)
+
+	vs := NewViewKeyStore(viewDir)
+	updates := make(chan accounts.WalletEvent, 1)
+	sub := vs.Subscribe(updates)
+	defer sub.Unsubscribe()
+
+	acc, err := vs.Import(keyJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acc.Address != a.Address || acc.URL.Scheme != ViewKeyScheme || filepath.Dir(acc.URL.Path) != viewDir {
+		t.Fatalf("view-only account mismatch: have %v", acc)
+	}
+	if ev := <-updates; ev.Kind != accounts.WalletArrived {
+		t.Errorf("wallet event mismatch: have %v, want %v", ev.Kind, accounts.WalletArrived)
+	}
+	if _, err := vs.Import(keyJSON); err != ErrViewKeyExists {
+		t.Fatalf("second import: have %v, want %v", err, ErrViewKeyExists)
+	}
+
+	// View-only wallets recognise OTAs but can't sign or spend
+	wallet := vs.Wallets()[0]
+	if _, err := wallet.SignHash(acc, make([]byte, 32)); err != ErrViewOnly {
+		t.Errorf("sign with view-only wallet: have %v, want %v", err, ErrViewOnly)
+	}
+	wAddr, err := wallet.GetWanAddress(acc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ota, err := genOTA(hexutil.Encode(wAddr[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	PKs, err := WaddrToUncompressedRawBytes(common.FromHex(ota))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := hexutil.Encode(PKs)[2:]
+	if _, err := wallet.ComputeOTAPPKeys(acc, "0x"+raw[0:64], "0x"+raw[64:128], "0x"+raw[128:192], "0x"+raw[192:256]); err != ErrViewOnly {
+		t.Errorf("OTA keys from view-only wallet: have %v, want %v", err, ErrViewOnly)
+	}
+	if owned, err := wallet.(*viewKeyWallet).IsOTAOwner(acc, common.FromHex(ota)); !owned || err != nil {
+		t.Errorf("OTA of the account not recognised: %v %v", owned, err)
+	}
+
+	// The view keys are loaded again from the directory
+	if keys := NewViewKeyStore(viewDir).ViewKeys(); len(keys) != 1 || keys[0].Address != a.Address {
+		t.Fatalf("reloaded view keys mismatch: have %v", keys)
+	}
+	if err := vs.Delete(a.Address); err != nil {
+		t.Fatal(err)
+	}
+	if keys := NewViewKeyStore(viewDir).ViewKeys(); len(keys) != 0 {
+		t.Fatalf("view keys left after delete: have %d", len(keys))
+	}
+}
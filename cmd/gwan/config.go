@@ -38,7 +38,6 @@ import (
 
 	//"github.com/wanchain/go-wanchain/pos/posconfig"
 
-	"github.com/wanchain/go-wanchain/pos/posdb"
 	whisper "github.com/wanchain/go-wanchain/whisper/whisperv5"
 )
 
@@ -142,9 +141,9 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 
 	utils.SetShhConfig(ctx, stack, &cfg.Shh)
 
-	//Init wanpos private db
-	posdb.DbInitAll(cfg.Node.DataDir)
 	posconfig.Cfg().NodeCfg = &cfg.Node
+	posconfig.Cfg().HeaderWarnOnly = ctx.GlobalBool(utils.PosHeaderWarnOnlyFlag.Name)
+	posconfig.Cfg().RetainEpochs = ctx.GlobalUint64(utils.PosRetainEpochsFlag.Name)
 
 	return stack, cfg
 }
@@ -0,0 +1,184 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/wanchain/go-wanchain/cmd/utils"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/types"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	epochGenesisCommand = cli.Command{
+		Name:     "epochgenesis",
+		Usage:    "Manage the epoch geneses of the pos chain",
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+Export, import and inspect the epoch geneses of the pos chain. An epoch genesis
+records the random beacon and the leaders an epoch was started with.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "export",
+				Usage:     "Export the geneses of a range of epochs into a file",
+				Action:    utils.MigrateFlags(exportEpochGenesis),
+				ArgsUsage: "<from> <to> <filename>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+				},
+				Description: `
+Writes the geneses of the epochs <from> to <to> as an RLP stream. Geneses not
+stored locally are generated from the chain. If the file ends with .gz, the
+output is gzipped.`,
+			},
+			{
+				Name:      "import",
+				Usage:     "Import epoch geneses from a file",
+				Action:    utils.MigrateFlags(importEpochGenesis),
+				ArgsUsage: "<filename>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+				},
+				Description: `
+Reads an RLP stream of epoch geneses, as written by the export command, and
+stores them. Every genesis is verified, and has to build on the last block the
+local chain holds before its epoch, before it is stored. The import stops at the
+first invalid one.`,
+			},
+			{
+				Name:      "show",
+				Usage:     "Print the genesis of an epoch",
+				Action:    utils.MigrateFlags(showEpochGenesis),
+				ArgsUsage: "<epoch>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+				},
+				Description: `
+Prints the genesis of an epoch as JSON. A genesis not stored locally is
+generated from the chain.`,
+			},
+		},
+	}
+)
+
+// makeEpochGenesisChain opens the chain with the pos parts required to
+// generate epoch geneses.
+func makeEpochGenesisChain(ctx *cli.Context) *core.BlockChain {
+	stack := makeFullNode(ctx)
+	chain, _ := utils.MakeChain(ctx, stack)
+	if chain.Config().Pluto == nil {
+		utils.Fatalf("The chain does not run pos")
+	}
+	return chain
+}
+
+func parseEpochID(arg string) uint64 {
+	epochID, err := strconv.ParseUint(arg, 10, 64)
+	if err != nil {
+		utils.Fatalf("Invalid epoch %q: %v", arg, err)
+	}
+	return epochID
+}
+
+func exportEpochGenesis(ctx *cli.Context) error {
+	if len(ctx.Args()) != 3 {
+		utils.Fatalf("This command requires three arguments.")
+	}
+	first, last := parseEpochID(ctx.Args().Get(0)), parseEpochID(ctx.Args().Get(1))
+	if first > last {
+		utils.Fatalf("Export error: first epoch %d is after last epoch %d", first, last)
+	}
+	chain := makeEpochGenesisChain(ctx)
+	defer chain.Stop()
+
+	start := time.Now()
+	n, err := utils.ExportEpochGenesis(chain, ctx.Args().Get(2), first, last)
+	if err != nil {
+		utils.Fatalf("Export error: %v\n", err)
+	}
+	fmt.Printf("Exported %d epoch geneses in %v\n", n, time.Since(start))
+	return nil
+}
+
+func importEpochGenesis(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	chain := makeEpochGenesisChain(ctx)
+	defer chain.Stop()
+
+	start := time.Now()
+	n, err := utils.ImportEpochGenesis(chain, ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Import error after %d epoch geneses: %v\n", n, err)
+	}
+	fmt.Printf("Imported %d epoch geneses in %v\n", n, time.Since(start))
+	return nil
+}
+
+// epochGenesisJSON is the printed form of an epoch genesis.
+type epochGenesisJSON struct {
+	ProtocolMagic       string          `json:"protocolMagic"`
+	EpochId             uint64          `json:"epochId"`
+	PreEpochLastBlkHash common.Hash     `json:"preEpochLastBlkHash"`
+	Random              hexutil.Bytes   `json:"random"`
+	EpochLeaders        []hexutil.Bytes `json:"epochLeaders"`
+	SlotLeaders         []hexutil.Bytes `json:"slotLeaders"`
+	RBLeaders           []hexutil.Bytes `json:"rbLeaders"`
+	GenesisBlkHash      common.Hash     `json:"genesisBlkHash"`
+	Extra               hexutil.Bytes   `json:"extra"`
+}
+
+func toHexBytes(list [][]byte) []hexutil.Bytes {
+	out := make([]hexutil.Bytes, len(list))
+	for i, b := range list {
+		out[i] = b
+	}
+	return out
+}
+
+func showEpochGenesis(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	epochID := parseEpochID(ctx.Args().First())
+
+	chain := makeEpochGenesisChain(ctx)
+	defer chain.Stop()
+
+	var (
+		genesis *types.EpochGenesis
+		err     error
+	)
+	if genesis = chain.GetEpochGenesis(epochID); genesis == nil {
+		if genesis, err = chain.GenerateEpochGenesis(epochID); err != nil {
+			utils.Fatalf("Failed to generate genesis of epoch %d: %v", epochID, err)
+		}
+	}
+	out, err := json.MarshalIndent(&epochGenesisJSON{
+		ProtocolMagic:       string(genesis.ProtocolMagic),
+		EpochId:             genesis.EpochId,
+		PreEpochLastBlkHash: genesis.PreEpochLastBlkHash,
+		Random:              genesis.Random,
+		EpochLeaders:        toHexBytes(genesis.EpochLeaders),
+		SlotLeaders:         toHexBytes(genesis.SlotLeaders),
+		RBLeaders:           toHexBytes(genesis.RBLeaders),
+		GenesisBlkHash:      genesis.GenesisBlkHash,
+		Extra:               genesis.Extra,
+	}, "", "  ")
+	if err != nil {
+		utils.Fatalf("Failed to encode genesis of epoch %d: %v", epochID, err)
+	}
+	fmt.Println(string(out))
+	return nil
+}
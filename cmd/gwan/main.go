@@ -84,6 +84,8 @@ var (
 		utils.LightServFlag,
 		utils.LightPeersFlag,
 		utils.LightKDFFlag,
+		utils.OTAIndexFlag,
+		utils.StampRefillFlag,
 		utils.CacheFlag,
 		utils.TrieCacheGenFlag,
 		utils.ListenPortFlag,
@@ -101,6 +103,9 @@ var (
 		utils.NodeKeyFileFlag,
 		utils.NodeKeyHexFlag,
 		utils.DevModeFlag,
+		utils.DevPosFlag,
+		utils.PosHeaderWarnOnlyFlag,
+		utils.PosRetainEpochsFlag,
 		utils.TestnetFlag,
 		utils.DevInternalFlag,
 		utils.PlutoFlag,
@@ -152,6 +157,8 @@ func init() {
 		copydbCommand,
 		removedbCommand,
 		dumpCommand,
+		// See epochgenesiscmd.go:
+		epochGenesisCommand,
 		// See monitorcmd.go:
 		monitorCommand,
 		// See accountcmd.go:
@@ -274,7 +281,7 @@ func startNode(ctx *cli.Context, stack *node.Node) {
 		}
 	}()
 	// Start auxiliary services if enabled
-	if ctx.GlobalBool(utils.MiningEnabledFlag.Name) {
+	if ctx.GlobalBool(utils.MiningEnabledFlag.Name) || ctx.GlobalBool(utils.DevPosFlag.Name) {
 		// Mining only makes sense if a full Ethereum node is running
 		var ethereum *eth.Ethereum
 		if err := stack.Service(&ethereum); err != nil {
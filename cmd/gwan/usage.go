@@ -75,12 +75,17 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.DevInternalFlag,
 			utils.PlutoFlag,
 			utils.DevModeFlag,
+			utils.DevPosFlag,
+			utils.PosHeaderWarnOnlyFlag,
+			utils.PosRetainEpochsFlag,
 			utils.SyncModeFlag,
 			utils.EthStatsURLFlag,
 			utils.IdentityFlag,
 			utils.LightServFlag,
 			utils.LightPeersFlag,
 			utils.LightKDFFlag,
+			utils.OTAIndexFlag,
+			utils.StampRefillFlag,
 		},
 	},
 	{
@@ -214,3 +214,70 @@ func ExportAppendChain(blockchain *core.BlockChain, fn string, first uint64, las
 	log.Info("Exported blockchain to", "file", fn)
 	return nil
 }
+
+// ExportEpochGenesis writes the geneses of the epochs first to last into a file
+// as an RLP stream. Geneses not stored locally are generated from the chain.
+func ExportEpochGenesis(blockchain *core.BlockChain, fn string, first uint64, last uint64) (int, error) {
+	log.Info("Exporting epoch geneses", "file", fn, "first", first, "last", last)
+	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return 0, err
+	}
+	defer fh.Close()
+
+	var writer io.Writer = fh
+	if strings.HasSuffix(fn, ".gz") {
+		writer = gzip.NewWriter(writer)
+		defer writer.(*gzip.Writer).Close()
+	}
+
+	n := 0
+	for epochID := first; epochID <= last; epochID++ {
+		genesis := blockchain.GetEpochGenesis(epochID)
+		if genesis == nil {
+			if genesis, err = blockchain.GenerateEpochGenesis(epochID); err != nil {
+				return n, fmt.Errorf("epoch %d: %v", epochID, err)
+			}
+		}
+		if err := rlp.Encode(writer, genesis); err != nil {
+			return n, err
+		}
+		n++
+	}
+	log.Info("Exported epoch geneses", "file", fn, "count", n)
+	return n, nil
+}
+
+// ImportEpochGenesis reads an RLP stream of epoch geneses from a file, verifies
+// and stores them.
+func ImportEpochGenesis(blockchain *core.BlockChain, fn string) (int, error) {
+	log.Info("Importing epoch geneses", "file", fn)
+	fh, err := os.Open(fn)
+	if err != nil {
+		return 0, err
+	}
+	defer fh.Close()
+
+	var reader io.Reader = fh
+	if strings.HasSuffix(fn, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return 0, err
+		}
+	}
+
+	stream := rlp.NewStream(reader, 0)
+	n := 0
+	for ; ; n++ {
+		var genesis types.EpochGenesis
+		if err := stream.Decode(&genesis); err == io.EOF {
+			break
+		} else if err != nil {
+			return n, fmt.Errorf("at epoch genesis %d: %v", n, err)
+		}
+		if err := blockchain.ImportEpochGenesis(&genesis); err != nil {
+			return n, fmt.Errorf("invalid genesis of epoch %d: %v", genesis.EpochId, err)
+		}
+	}
+	log.Info("Imported epoch geneses", "file", fn, "count", n)
+	return n, nil
+}
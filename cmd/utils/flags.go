@@ -33,6 +33,7 @@ import (
 	"github.com/wanchain/go-wanchain/consensus"
 	"github.com/wanchain/go-wanchain/consensus/clique"
 	"github.com/wanchain/go-wanchain/consensus/ethash"
+	"github.com/wanchain/go-wanchain/consensus/pluto"
 	"github.com/wanchain/go-wanchain/core"
 	"github.com/wanchain/go-wanchain/core/state"
 	"github.com/wanchain/go-wanchain/core/vm"
@@ -45,6 +46,7 @@ import (
 	"github.com/wanchain/go-wanchain/les"
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/metrics"
+	"github.com/wanchain/go-wanchain/miner"
 	"github.com/wanchain/go-wanchain/node"
 	"github.com/wanchain/go-wanchain/p2p"
 	"github.com/wanchain/go-wanchain/p2p/discover"
@@ -52,6 +54,8 @@ import (
 	"github.com/wanchain/go-wanchain/p2p/nat"
 	"github.com/wanchain/go-wanchain/p2p/netutil"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posdb"
 	whisper "github.com/wanchain/go-wanchain/whisper/whisperv5"
 	cli "gopkg.in/urfave/cli.v1"
 )
@@ -146,6 +150,18 @@ var (
 		Name:  "dev",
 		Usage: "Developer mode: pre-configured private network with several debugging flags",
 	}
+	DevPosFlag = cli.BoolFlag{
+		Name:  "dev.pos",
+		Usage: "Developer mode: single node proof-of-stake chain sealed by a generated validator",
+	}
+	PosHeaderWarnOnlyFlag = cli.BoolFlag{
+		Name:  "pos.headerwarnonly",
+		Usage: "Log pos headers failing the strict header checks instead of rejecting them",
+	}
+	PosRetainEpochsFlag = cli.Uint64Flag{
+		Name:  "pos.retainepochs",
+		Usage: "Number of past epochs (at least 2) whose local pos data is kept, older ones are pruned, incentive and fork history always stay (0 = keep all)",
+	}
 	IdentityFlag = cli.StringFlag{
 		Name:  "identity",
 		Usage: "Custom node name",
@@ -320,6 +336,14 @@ var (
 		Name:  "vmdebug",
 		Usage: "Record information useful for VM and contract debugging",
 	}
+	OTAIndexFlag = cli.BoolFlag{
+		Name:  "otaindex",
+		Usage: "Enable the OTA index for decoy selection, indexing the whole chain on first start",
+	}
+	StampRefillFlag = cli.BoolFlag{
+		Name:  "stamprefill",
+		Usage: "Buy stamps in the background for the unlocked accounts running low on stamps",
+	}
 	// Logging and debug settings
 	EthStatsURLFlag = cli.StringFlag{
 		Name:  "ethstats",
@@ -755,6 +779,30 @@ func setEtherbase(ctx *cli.Context, ks *keystore.KeyStore, cfg *eth.Config) {
 	}
 }
 
+// devPosValidator returns the --dev.pos validator key, creating it on the
+// first start, and unlocks it so the node can seal blocks and send the stage
+// transactions. The key has an empty passphrase.
+func devPosValidator(ks *keystore.KeyStore) *keystore.Key {
+	var (
+		account accounts.Account
+		err     error
+	)
+	if existing := ks.Accounts(); len(existing) > 0 {
+		account = existing[0]
+	} else if account, err = ks.NewAccount(""); err != nil {
+		Fatalf("Failed to create developer validator: %v", err)
+	}
+	key, err := ks.GetKey(account, "")
+	if err != nil {
+		Fatalf("Failed to load developer validator %x: %v", account.Address, err)
+	}
+	if err := ks.Unlock(account, ""); err != nil {
+		Fatalf("Failed to unlock developer validator %x: %v", account.Address, err)
+	}
+	log.Info("Using developer validator", "address", account.Address)
+	return key
+}
+
 // MakePasswordList reads password lines from the file specified by the global --password flag.
 func MakePasswordList(ctx *cli.Context) []string {
 	path := ctx.GlobalString(PasswordFileFlag.Name)
@@ -809,7 +857,7 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 		cfg.NetRestrict = list
 	}
 
-	if ctx.GlobalBool(DevModeFlag.Name) {
+	if ctx.GlobalBool(DevModeFlag.Name) || ctx.GlobalBool(DevPosFlag.Name) {
 		// --dev mode can't use p2p networking.
 		cfg.MaxPeers = 0
 		cfg.ListenAddr = ":0"
@@ -832,6 +880,8 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 		cfg.DataDir = ctx.GlobalString(DataDirFlag.Name)
 	case ctx.GlobalBool(DevModeFlag.Name):
 		cfg.DataDir = filepath.Join(os.TempDir(), "ethereum_dev_mode")
+	case ctx.GlobalBool(DevPosFlag.Name):
+		cfg.DataDir = filepath.Join(os.TempDir(), "wanchain_dev_pos")
 	case ctx.GlobalBool(TestnetFlag.Name):
 		cfg.DataDir = filepath.Join(node.DefaultDataDir(), "testnet")
 	case ctx.GlobalBool(DevInternalFlag.Name):
@@ -939,7 +989,7 @@ func SetShhConfig(ctx *cli.Context, stack *node.Node, cfg *whisper.Config) {
 // SetEthConfig applies eth-related command line flags to the config.
 func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	// Avoid conflicting network flags
-	checkExclusive(ctx, DevModeFlag, TestnetFlag, DevInternalFlag, PlutoFlag)
+	checkExclusive(ctx, DevModeFlag, DevPosFlag, TestnetFlag, DevInternalFlag, PlutoFlag)
 	checkExclusive(ctx, FastSyncFlag, LightModeFlag, SyncModeFlag)
 
 	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
@@ -987,6 +1037,12 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
 	}
+	if ctx.GlobalIsSet(OTAIndexFlag.Name) {
+		cfg.OTAIndex = ctx.GlobalBool(OTAIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(StampRefillFlag.Name) {
+		cfg.StampRefill = ctx.GlobalBool(StampRefillFlag.Name)
+	}
 
 	// Override any default configs for hard coded networks.
 	switch {
@@ -1011,6 +1067,17 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 			cfg.GasPrice = new(big.Int)
 		}
 		cfg.PowTest = true
+	case ctx.GlobalBool(DevPosFlag.Name):
+		if !ctx.GlobalIsSet(NetworkIdFlag.Name) {
+			cfg.NetworkId = params.DevPosChainConfig.ChainId.Uint64()
+		}
+		key := devPosValidator(ks)
+		cfg.Etherbase = key.Address
+		cfg.Genesis = core.DevPosGenesisBlock(key.Address, crypto.FromECDSAPub(&key.PrivateKey.PublicKey),
+			key.PrivateKey3.PublicKeyBn256.G1.Marshal())
+		if !ctx.GlobalIsSet(GasPriceFlag.Name) {
+			cfg.GasPrice = new(big.Int)
+		}
 	}
 
 	// TODO(fjl): move trie cache generations into config
@@ -1125,12 +1192,33 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 				stack.ResolvePath(eth.DefaultConfig.EthashDatasetDir), eth.DefaultConfig.EthashDatasetsInMem, eth.DefaultConfig.EthashDatasetsOnDisk, chainDb,
 			)
 		}
+		if config.Pluto != nil {
+			if err := posconfig.Init(config.Pos); err != nil {
+				Fatalf("Invalid pos config: %v", err)
+			}
+			// PPoW seals the chain until the pos fork block
+			if pos := pluto.New(config.Pluto, chainDb); config.PosForkBlock == nil || config.PosForkBlock.Sign() == 0 {
+				engine = pos
+			} else {
+				engine = pluto.NewHybrid(config, engine, pos)
+			}
+		}
 	}
 	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name)}
 	chain, err = core.NewBlockChain(chainDb, config, engine, vmcfg)
 	if err != nil {
 		Fatalf("Can't create BlockChain: %v", err)
 	}
+	if config.Pluto != nil {
+		dbs, err := posdb.OpenDbs(func(name string) (ethdb.Database, error) {
+			return stack.OpenDatabase(name, 0, 0)
+		})
+		if err != nil {
+			Fatalf("Could not open pos databases: %v", err)
+		}
+		chain.SetPosDbs(dbs)
+		miner.PosInit(chain)
+	}
 	return chain, chainDb
 }
 
@@ -97,6 +97,19 @@ type Engine interface {
 	APIs(chain ChainReader) []rpc.API
 }
 
+// ForkChoice is implemented by consensus engines choosing the canonical chain
+// by their own rule instead of the chain height.
+type ForkChoice interface {
+	// ReorgNeeded reports whether the chain ending in extern should replace the
+	// canonical chain ending in current. It returns ErrReorgTooDeep when extern
+	// forks off below the engine's finality bound.
+	ReorgNeeded(chain ChainReader, current, extern *types.Header) (bool, error)
+
+	// Finalized returns the most recent ancestor of head that can no longer be
+	// reorganised away, or nil if there is none yet.
+	Finalized(chain ChainReader, head *types.Header) *types.Header
+}
+
 // PoW is a consensus engine based on proof-of-work.
 type PoW interface {
 	Engine
@@ -32,4 +32,8 @@ var (
 	ErrInvalidNumber = errors.New("invalid block number")
 
 	ErrOldblockNumber = errors.New("invalid block number which lagged K block")
+
+	// ErrReorgTooDeep is returned by a fork choice refusing to revert blocks
+	// beyond its finality bound.
+	ErrReorgTooDeep = errors.New("reorg deeper than the finality bound")
 )
@@ -0,0 +1,150 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package pluto
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/consensus"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/incentive"
+	"github.com/wanchain/go-wanchain/pos/slotleader"
+	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+// Hybrid is the consensus engine of a chain switching from PPoW to pos at the
+// PosForkBlock. Blocks below the fork are handled by the PPoW engine, the
+// fork block and all blocks after it by Pluto.
+type Hybrid struct {
+	config *params.ChainConfig
+	ppow   consensus.Engine
+	pos    *Pluto
+}
+
+// NewHybrid creates an engine handing over from ppow to pos at the
+// PosForkBlock of config.
+func NewHybrid(config *params.ChainConfig, ppow consensus.Engine, pos *Pluto) *Hybrid {
+	return &Hybrid{
+		config: config,
+		ppow:   ppow,
+		pos:    pos,
+	}
+}
+
+// PPoW returns the engine sealing the blocks before the fork.
+func (h *Hybrid) PPoW() consensus.Engine {
+	return h.ppow
+}
+
+// Pos returns the engine sealing the blocks from the fork on.
+func (h *Hybrid) Pos() *Pluto {
+	return h.pos
+}
+
+// SetPos injects the node's pos parts into the pos engine.
+func (h *Hybrid) SetPos(sls *slotleader.SLS, inc *incentive.Incentive, clock *util.SlotClock) {
+	h.pos.SetPos(sls, inc, clock)
+}
+
+// engine returns the engine responsible for the block number.
+func (h *Hybrid) engine(number *big.Int) consensus.Engine {
+	if h.config.IsPosActive(number) {
+		return h.pos
+	}
+	return h.ppow
+}
+
+// Author implements consensus.Engine.
+func (h *Hybrid) Author(header *types.Header) (common.Address, error) {
+	return h.engine(header.Number).Author(header)
+}
+
+// VerifyHeader implements consensus.Engine.
+func (h *Hybrid) VerifyHeader(chain consensus.ChainReader, header *types.Header, seal bool) error {
+	return h.engine(header.Number).VerifyHeader(chain, header, seal)
+}
+
+// VerifyHeaders implements consensus.Engine. A batch crossing the fork is
+// verified by the PPoW engine up to the fork and by Pluto from there on, the
+// results are delivered in the order of the input slice.
+func (h *Hybrid) VerifyHeaders(chain consensus.ChainReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	split := sort.Search(len(headers), func(i int) bool {
+		return h.config.IsPosActive(headers[i].Number)
+	})
+	switch split {
+	case 0:
+		return h.pos.VerifyHeaders(chain, headers, seals)
+	case len(headers):
+		return h.ppow.VerifyHeaders(chain, headers, seals)
+	}
+
+	abort := make(chan struct{})
+	results := make(chan error, len(headers))
+	ppowAbort, ppowResults := h.ppow.VerifyHeaders(chain, headers[:split], seals[:split])
+
+	go func() {
+		defer close(ppowAbort)
+
+		for i, header := range headers {
+			var err error
+			if i < split {
+				select {
+				case <-abort:
+					return
+				case err = <-ppowResults:
+				}
+			} else {
+				// the pos headers may descend from ppow headers not yet in the chain
+				err = h.pos.verifyHeader(chain, header, headers[:i])
+			}
+			select {
+			case <-abort:
+				return
+			case results <- err:
+			}
+		}
+	}()
+	return abort, results
+}
+
+// VerifyUncles implements consensus.Engine.
+func (h *Hybrid) VerifyUncles(chain consensus.ChainReader, block *types.Block) error {
+	return h.engine(block.Number()).VerifyUncles(chain, block)
+}
+
+// VerifySeal implements consensus.Engine.
+func (h *Hybrid) VerifySeal(chain consensus.ChainReader, header *types.Header) error {
+	return h.engine(header.Number).VerifySeal(chain, header)
+}
+
+// Prepare implements consensus.Engine.
+func (h *Hybrid) Prepare(chain consensus.ChainReader, header *types.Header, mining bool) error {
+	return h.engine(header.Number).Prepare(chain, header, mining)
+}
+
+// Finalize implements consensus.Engine.
+func (h *Hybrid) Finalize(chain consensus.ChainReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
+	return h.engine(header.Number).Finalize(chain, header, state, txs, uncles, receipts)
+}
+
+// Seal implements consensus.Engine.
+func (h *Hybrid) Seal(chain consensus.ChainReader, block *types.Block, stop <-chan struct{}) (*types.Block, error) {
+	return h.engine(block.Number()).Seal(chain, block, stop)
+}
+
+// CalcDifficulty implements consensus.Engine, using the engine of the block
+// following parent.
+func (h *Hybrid) CalcDifficulty(chain consensus.ChainReader, time uint64, parent *types.Header) *big.Int {
+	next := new(big.Int).Add(parent.Number, big.NewInt(1))
+	return h.engine(next).CalcDifficulty(chain, time, parent)
+}
+
+// APIs implements consensus.Engine, returning the APIs of both engines.
+func (h *Hybrid) APIs(chain consensus.ChainReader) []rpc.API {
+	return append(h.ppow.APIs(chain), h.pos.APIs(chain)...)
+}
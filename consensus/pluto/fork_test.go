@@ -0,0 +1,60 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package pluto
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/consensus/ethash"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/params"
+)
+
+func newTestHybrid(fork int64) *Hybrid {
+	db, _ := ethdb.NewMemDatabase()
+	config := &params.ChainConfig{
+		Ethash:       new(params.EthashConfig),
+		Pluto:        &params.PlutoConfig{Epoch: 100},
+		PosForkBlock: big.NewInt(fork),
+	}
+	return NewHybrid(config, ethash.NewFullFaker(db), New(config.Pluto, db))
+}
+
+func TestHybridEngineSelection(t *testing.T) {
+	h := newTestHybrid(10)
+
+	if _, ok := h.engine(big.NewInt(9)).(*ethash.Ethash); !ok {
+		t.Error("block before the fork not handled by ppow")
+	}
+	if h.engine(big.NewInt(10)) != h.pos {
+		t.Error("fork block not handled by pos")
+	}
+	if h.engine(big.NewInt(11)) != h.pos {
+		t.Error("block after the fork not handled by pos")
+	}
+}
+
+// Tests that a header batch crossing the fork is verified by both engines and
+// the results keep the order of the batch.
+func TestHybridVerifyHeadersAcrossFork(t *testing.T) {
+	h := newTestHybrid(10)
+
+	var headers []*types.Header
+	for i := int64(7); i < 13; i++ {
+		// an empty uncle hash is rejected by pluto before any chain access
+		headers = append(headers, &types.Header{Number: big.NewInt(i), Time: big.NewInt(0)})
+	}
+	abort, results := h.VerifyHeaders(nil, headers, make([]bool, len(headers)))
+	defer close(abort)
+
+	for i, header := range headers {
+		err := <-results
+		if pos := header.Number.Int64() >= 10; pos && err != errInvalidUncleHash {
+			t.Errorf("header %d: error mismatch: have %v, want %v", i, err, errInvalidUncleHash)
+		} else if !pos && err != nil {
+			t.Errorf("header %d: ppow verification failed: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package pluto
+
+import (
+	"github.com/wanchain/go-wanchain/consensus"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+)
+
+// headerSlot returns the absolute slot a header was sealed in. Headers sealed
+// before pos took over count as slot 0.
+func headerSlot(chain consensus.ChainReader, header *types.Header) uint64 {
+	if !chain.Config().IsPosActive(header.Number) {
+		return 0
+	}
+	epochID := header.Difficulty.Uint64() >> 32
+	slotID := (header.Difficulty.Uint64() >> 8) & 0x00FFFFFF
+	return epochID*posconfig.SlotCount + slotID
+}
+
+// ReorgNeeded implements consensus.ForkChoice with a chain density rule. Of two
+// chains forking at most MaxReorgSlots slots back, the one with more blocks in
+// the MaxReorgSlots slots following the fork point wins, the higher one on a
+// tie. Reorgs reverting older blocks are refused with ErrReorgTooDeep, as soon
+// as either branch is walked back past that bound.
+func (c *Pluto) ReorgNeeded(chain consensus.ChainReader, current, extern *types.Header) (bool, error) {
+	if current.Number.Sign() == 0 || extern.ParentHash == current.Hash() {
+		return extern.Number.Cmp(current.Number) > 0, nil
+	}
+	// Slots only grow along a chain, so a branch header sealed before the bound
+	// means the fork point is older still and current would be reverted past it
+	headSlot := headerSlot(chain, current)
+	tooDeep := func(header *types.Header) bool {
+		return headSlot > posconfig.MaxReorgSlots && headerSlot(chain, header) < headSlot-posconfig.MaxReorgSlots
+	}
+	refuse := func(header *types.Header) (bool, error) {
+		log.Debug("Refused deep reorg", "number", header.Number, "head", current.Number, "bound", posconfig.MaxReorgSlots)
+		return false, consensus.ErrReorgTooDeep
+	}
+	parent := func(header *types.Header) *types.Header {
+		return chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	}
+
+	// Walk both chains back to the fork point
+	var currentBranch, externBranch []*types.Header
+	cur, ext := current, extern
+	for cur != nil && ext != nil && cur.Number.Cmp(ext.Number) > 0 {
+		if tooDeep(cur) {
+			return refuse(cur)
+		}
+		currentBranch = append(currentBranch, cur)
+		cur = parent(cur)
+	}
+	for cur != nil && ext != nil && ext.Number.Cmp(cur.Number) > 0 {
+		if tooDeep(ext) {
+			return refuse(ext)
+		}
+		externBranch = append(externBranch, ext)
+		ext = parent(ext)
+	}
+	for cur != nil && ext != nil && cur.Hash() != ext.Hash() {
+		if tooDeep(cur) {
+			return refuse(cur)
+		}
+		if tooDeep(ext) {
+			return refuse(ext)
+		}
+		currentBranch = append(currentBranch, cur)
+		externBranch = append(externBranch, ext)
+		cur, ext = parent(cur), parent(ext)
+	}
+	if cur == nil || ext == nil {
+		return false, consensus.ErrUnknownAncestor
+	}
+	forkSlot := headerSlot(chain, cur)
+
+	if len(currentBranch) > 0 && tooDeep(cur) {
+		return refuse(cur)
+	}
+	// Compare the chain density in the window following the fork point
+	density := func(branch []*types.Header) (n int) {
+		for _, header := range branch {
+			if headerSlot(chain, header) <= forkSlot+posconfig.MaxReorgSlots {
+				n++
+			}
+		}
+		return n
+	}
+	currentDensity, externDensity := density(currentBranch), density(externBranch)
+	if externDensity != currentDensity {
+		return externDensity > currentDensity, nil
+	}
+	return extern.Number.Cmp(current.Number) > 0, nil
+}
+
+// ReorgNeeded implements consensus.ForkChoice, applying the pos rule once the
+// canonical head is sealed by pos and the height rule of PPoW before.
+func (h *Hybrid) ReorgNeeded(chain consensus.ChainReader, current, extern *types.Header) (bool, error) {
+	if h.config.IsPosActive(current.Number) {
+		return h.pos.ReorgNeeded(chain, current, extern)
+	}
+	return extern.Number.Cmp(current.Number) > 0, nil
+}
+
+// Finalized implements consensus.ForkChoice. A block is final once the head is
+// sealed more than MaxReorgSlots slots after it, since ReorgNeeded refuses to
+// revert it from then on. The genesis block is always final, the other blocks
+// sealed before pos took over never are. The result is cached per head.
+func (c *Pluto) Finalized(chain consensus.ChainReader, head *types.Header) *types.Header {
+	if cached, ok := c.finality.Get(head.Hash()); ok {
+		final, _ := cached.(*types.Header)
+		return final
+	}
+	final := c.finalized(chain, head)
+	c.finality.Add(head.Hash(), final)
+	return final
+}
+
+func (c *Pluto) finalized(chain consensus.ChainReader, head *types.Header) *types.Header {
+	headSlot := headerSlot(chain, head)
+	for header := head; header != nil; header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1) {
+		if header.Number.Sign() == 0 {
+			return header
+		}
+		if !chain.Config().IsPosActive(header.Number) {
+			return nil
+		}
+		if headSlot-headerSlot(chain, header) > posconfig.MaxReorgSlots {
+			return header
+		}
+	}
+	return nil
+}
+
+// Finalized implements consensus.ForkChoice. PPoW blocks are never final, so
+// nothing is reported until the head is sealed by pos.
+func (h *Hybrid) Finalized(chain consensus.ChainReader, head *types.Header) *types.Header {
+	if !h.config.IsPosActive(head.Number) {
+		return nil
+	}
+	return h.pos.Finalized(chain, head)
+}
@@ -0,0 +1,195 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package pluto
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/consensus"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+)
+
+// testChainReader is a consensus.ChainReader over a set of loose headers.
+type testChainReader struct {
+	config  *params.ChainConfig
+	headers map[common.Hash]*types.Header
+	lookups int // Number of headers retrieved by GetHeader
+}
+
+func (r *testChainReader) Config() *params.ChainConfig            { return r.config }
+func (r *testChainReader) CurrentHeader() *types.Header           { return nil }
+func (r *testChainReader) GetHeaderByNumber(uint64) *types.Header { return nil }
+func (r *testChainReader) GetBlock(common.Hash, uint64) *types.Block {
+	return nil
+}
+func (r *testChainReader) GetHeaderByHash(hash common.Hash) *types.Header {
+	return r.headers[hash]
+}
+func (r *testChainReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	r.lookups++
+	if header := r.headers[hash]; header != nil && header.Number.Uint64() == number {
+		return header
+	}
+	return nil
+}
+
+// extend adds headers sealed in the given slots of epoch 0 on top of parent
+// and returns the new head. Branches with distinct salts never share a block.
+func (r *testChainReader) extend(parent *types.Header, salt uint64, slots ...uint64) *types.Header {
+	for _, slot := range slots {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number, big.NewInt(1)),
+			Difficulty: new(big.Int).SetUint64(slot<<8 | 1),
+			Time:       new(big.Int).SetUint64(slot + salt),
+		}
+		r.headers[header.Hash()] = header
+		parent = header
+	}
+	return parent
+}
+
+func TestReorgNeeded(t *testing.T) {
+	defer func(bound uint64) { posconfig.MaxReorgSlots = bound }(posconfig.MaxReorgSlots)
+	posconfig.MaxReorgSlots = 10
+
+	c := &Pluto{}
+	newChain := func() (*testChainReader, *types.Header) {
+		r := &testChainReader{
+			config:  &params.ChainConfig{Pluto: &params.PlutoConfig{}},
+			headers: make(map[common.Hash]*types.Header),
+		}
+		genesis := &types.Header{Number: new(big.Int), Difficulty: big.NewInt(1), Time: new(big.Int)}
+		r.headers[genesis.Hash()] = genesis
+		return r, r.extend(genesis, 0, 1)
+	}
+	tests := []struct {
+		current, extern []uint64 // slots of the blocks after the fork point at slot 1
+		want            bool
+		err             error
+	}{
+		// plain extension of the canonical head
+		{current: nil, extern: []uint64{2}, want: true},
+		// the denser chain wins
+		{current: []uint64{2, 3, 4}, extern: []uint64{2, 3, 4, 5}, want: true},
+		{current: []uint64{2, 3, 4, 5}, extern: []uint64{2, 3, 4}, want: false},
+		// blocks beyond the density window don't count, even if the chain is higher
+		{current: []uint64{2, 3, 4}, extern: []uint64{5, 6, 20, 21}, want: false},
+		// equal density falls back to the height
+		{current: []uint64{2, 3}, extern: []uint64{4, 5, 15}, want: true},
+		{current: []uint64{2, 3}, extern: []uint64{4, 5}, want: false},
+		// reorgs reverting blocks beyond the bound are refused
+		{current: []uint64{2, 3, 30}, extern: []uint64{4, 5, 6, 7, 31}, err: consensus.ErrReorgTooDeep},
+	}
+	for i, test := range tests {
+		r, fork := newChain()
+		current := r.extend(fork, 0, test.current...)
+		extern := r.extend(fork, 1000, test.extern...)
+		have, err := c.ReorgNeeded(r, current, extern)
+		if err != test.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, test.err)
+			continue
+		}
+		if have != test.want {
+			t.Errorf("test %d: reorg mismatch: have %v, want %v", i, have, test.want)
+		}
+	}
+}
+
+func TestReorgNeededBoundedWalk(t *testing.T) {
+	defer func(bound uint64) { posconfig.MaxReorgSlots = bound }(posconfig.MaxReorgSlots)
+	posconfig.MaxReorgSlots = 10
+
+	r := &testChainReader{
+		config:  &params.ChainConfig{Pluto: &params.PlutoConfig{}},
+		headers: make(map[common.Hash]*types.Header),
+	}
+	genesis := &types.Header{Number: new(big.Int), Difficulty: big.NewInt(1), Time: new(big.Int)}
+	r.headers[genesis.Hash()] = genesis
+
+	// A long side chain forking at genesis is refused within the bound
+	slots := make([]uint64, 1000)
+	for i := range slots {
+		slots[i] = uint64(i + 1)
+	}
+	current := r.extend(genesis, 0, slots...)
+	extern := r.extend(genesis, 100000, slots[:999]...)
+	extern = r.extend(extern, 100000, 1001)
+
+	r.lookups = 0
+	if _, err := (&Pluto{}).ReorgNeeded(r, current, extern); err != consensus.ErrReorgTooDeep {
+		t.Fatalf("error mismatch: have %v, want %v", err, consensus.ErrReorgTooDeep)
+	}
+	if limit := 2 * int(posconfig.MaxReorgSlots+2); r.lookups > limit {
+		t.Errorf("walked %d headers, want at most %d", r.lookups, limit)
+	}
+}
+
+func TestFinalized(t *testing.T) {
+	defer func(bound uint64) { posconfig.MaxReorgSlots = bound }(posconfig.MaxReorgSlots)
+	posconfig.MaxReorgSlots = 10
+
+	r := &testChainReader{
+		config:  &params.ChainConfig{Pluto: &params.PlutoConfig{}},
+		headers: make(map[common.Hash]*types.Header),
+	}
+	genesis := &types.Header{Number: new(big.Int), Difficulty: big.NewInt(1), Time: new(big.Int)}
+	r.headers[genesis.Hash()] = genesis
+
+	c := New(&params.PlutoConfig{}, nil)
+	tests := []struct {
+		slots []uint64 // slots of the blocks after genesis
+		want  uint64   // number of the finalized block
+	}{
+		{slots: []uint64{1, 2, 3}, want: 0},
+		{slots: []uint64{1, 2, 11}, want: 0},
+		{slots: []uint64{1, 2, 12}, want: 1},
+		{slots: []uint64{1, 2, 5, 13, 14}, want: 2},
+		{slots: []uint64{1, 30}, want: 1},
+	}
+	for i, tt := range tests {
+		head := r.extend(genesis, uint64(i)*1000, tt.slots...)
+		final := c.Finalized(r, head)
+		if final == nil {
+			t.Errorf("test %d: no finalized block", i)
+			continue
+		}
+		if final.Number.Uint64() != tt.want {
+			t.Errorf("test %d: finalized block mismatch: have %d, want %d", i, final.Number, tt.want)
+		}
+	}
+}
+
+func TestFinalizedPPoW(t *testing.T) {
+	defer func(bound uint64) { posconfig.MaxReorgSlots = bound }(posconfig.MaxReorgSlots)
+	posconfig.MaxReorgSlots = 10
+
+	// Blocks 1 and 2 are sealed by ppow, pos takes over at block 3
+	r := &testChainReader{
+		config:  &params.ChainConfig{Pluto: &params.PlutoConfig{}, PosForkBlock: big.NewInt(3)},
+		headers: make(map[common.Hash]*types.Header),
+	}
+	genesis := &types.Header{Number: new(big.Int), Difficulty: big.NewInt(1), Time: new(big.Int)}
+	r.headers[genesis.Hash()] = genesis
+	ppow := r.extend(genesis, 0, 0, 0)
+
+	c := New(&params.PlutoConfig{}, nil)
+	if final := c.Finalized(r, r.extend(ppow, 0, 15, 20)); final != nil {
+		t.Errorf("ppow block %d reported final", final.Number)
+	}
+	head := r.extend(ppow, 1000, 1, 20)
+	final := c.Finalized(r, head)
+	if final == nil || final.Number.Uint64() != 3 {
+		t.Fatalf("finalized block mismatch: have %v, want 3", final)
+	}
+
+	// The finalized block is cached per head
+	r.lookups = 0
+	if cached := c.Finalized(r, head); cached != final || r.lookups != 0 {
+		t.Errorf("finalized block not cached: have %v after %d lookups", cached, r.lookups)
+	}
+}
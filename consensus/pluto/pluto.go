@@ -59,6 +59,7 @@ const (
 	checkpointInterval = 1024 // Number of blocks after which to save the vote snapshot to the database
 	inmemorySnapshots  = 128  // Number of recent vote snapshots to keep in memory
 	inmemorySignatures = 4096 // Number of recent block signatures to keep in memory
+	inmemoryFinality   = 128  // Number of recent heads to keep the finalized block of in memory
 
 	wiggleTime = 500 * time.Millisecond // Random delay (per signer) to allow concurrent signers
 )
@@ -89,6 +90,14 @@ var (
 	// that is not part of the local blockchain.
 	errUnknownBlock = errors.New("unknown block")
 
+	// errNoSlotLeaderSelection is returned if slot proofs are packed or verified
+	// before the node's slot leader selection is set.
+	errNoSlotLeaderSelection = errors.New("no slot leader selection")
+
+	// errNoIncentive is returned if a block due an incentive run is finalized
+	// by an engine that has not been given the node's incentive.
+	errNoIncentive = errors.New("no incentive")
+
 	// errInvalidCheckpointBeneficiary is returned if a checkpoint/epoch transition
 	// block has a beneficiary set to non-zeroes.
 	errInvalidCheckpointBeneficiary = errors.New("beneficiary in checkpoint block non-zero")
@@ -222,6 +231,7 @@ type Pluto struct {
 
 	recents    *lru.ARCCache // Snapshots for recent block to speed up reorgs
 	signatures *lru.ARCCache // Signatures of recent blocks to speed up mining
+	finality   *lru.ARCCache // Finalized blocks of recent heads to speed up finality queries
 
 	proposals map[common.Address]bool // Current list of proposals we are pushing
 
@@ -230,6 +240,10 @@ type Pluto struct {
 	lock   sync.RWMutex   // Protects the signer fields
 
 	key *keystore.Key // Unlocked key
+
+	sls       *slotleader.SLS      // Slot leader selection of the node
+	incentive *incentive.Incentive // Incentive of the node, run when finalizing blocks
+	clock     *util.SlotClock      // Slot timing of the node, nil runs on the system clock
 }
 
 // New creates a Pluto proof-of-authority consensus engine with the initial
@@ -243,12 +257,14 @@ func New(config *params.PlutoConfig, db ethdb.Database) *Pluto {
 	// Allocate the snapshot caches and create the engine
 	recents, _ := lru.NewARC(inmemorySnapshots)
 	signatures, _ := lru.NewARC(inmemorySignatures)
+	finality, _ := lru.NewARC(inmemoryFinality)
 
 	return &Pluto{
 		config:     &conf,
 		db:         db,
 		recents:    recents,
 		signatures: signatures,
+		finality:   finality,
 		proposals:  make(map[common.Address]bool),
 	}
 }
@@ -297,7 +313,7 @@ func (c *Pluto) verifyHeader(chain consensus.ChainReader, header *types.Header,
 	//number := header.Number.Uint64()
 
 	// Don't waste time checking blocks from the future
-	if header.Time.Cmp(big.NewInt(time.Now().Unix())) > 0 {
+	if header.Time.Cmp(new(big.Int).SetUint64(c.slotClock().NowUnix())) > 0 {
 		return consensus.ErrFutureBlock
 	}
 	// Checkpoint blocks need to enforce zero beneficiary
@@ -335,6 +351,16 @@ func (c *Pluto) verifyHeader(chain consensus.ChainReader, header *types.Header,
 	if header.UncleHash != uncleHash {
 		return errInvalidUncleHash
 	}
+	// Ensure the slot encoding and the extra-data layout are well-formed
+	if header.Number.Sign() > 0 {
+		epochID, _, err := decodeSlot(header.Difficulty)
+		if err == nil {
+			_, _, err = decodeExtra(header, epochID)
+		}
+		if err := checked(header, err); err != nil {
+			return err
+		}
+	}
 	// Ensure that the block's difficulty is meaningful (may not be correct at this point)
 	// if number > 0 {
 	// 	if header.Difficulty == nil || (header.Difficulty.Cmp(diffInTurn) != 0 && header.Difficulty.Cmp(diffNoTurn) != 0) {
@@ -377,6 +403,10 @@ func (c *Pluto) verifyCascadingFields(chain consensus.ChainReader, header *types
 	if parent == nil || parent.Number.Uint64() != number-1 || parent.Hash() != header.ParentHash {
 		return consensus.ErrUnknownAncestor
 	}
+	// Ensure the block is sealed in a later slot than its parent, at slot time
+	if err := checked(header, verifySlot(chain, header, parent, parents)); err != nil {
+		return err
+	}
 	// if parent.Time.Uint64()+c.config.Period > header.Time.Uint64() {
 	// 	return ErrInvalidTimestamp
 	// }
@@ -438,6 +468,11 @@ func (c *Pluto) snapshot(chain consensus.ChainReader, number uint64, hash common
 			log.Trace("Stored genesis voting snapshot to disk")
 			break
 		}
+		// The last ppow block before the pos fork starts the pos voting
+		if fork := chain.Config().PosForkBlock; fork != nil && fork.Sign() > 0 && number == fork.Uint64()-1 {
+			snap = newSnapshot(c.config, c.signatures, number, hash, nil)
+			break
+		}
 		// No snapshot for this header, gather the header and move backward
 		var header *types.Header
 		if len(parents) > 0 {
@@ -502,7 +537,12 @@ func (c *Pluto) verifyProof(block *types.Block, header *types.Header, parents []
 	epochID := header.Difficulty.Uint64() >> 32
 	slotID := (header.Difficulty.Uint64() >> 8) & 0x00FFFFFF
 
-	s := slotleader.GetSlotLeaderSelection()
+	c.lock.RLock()
+	s := c.sls
+	c.lock.RUnlock()
+	if s == nil {
+		return errNoSlotLeaderSelection
+	}
 
 	proof, proofMeg, err := s.GetInfoFromHeadExtra(epochID, header.Extra[:len(header.Extra)-extraSeal])
 
@@ -514,7 +554,7 @@ func (c *Pluto) verifyProof(block *types.Block, header *types.Header, parents []
 
 		if !s.VerifySlotProof(block, epochID, slotID, proof, proofMeg) {
 			log.Error("verifyProof failed", "number", number, "epochID", epochID, "slotID", slotID)
-			return errUnauthorized
+			return checked(header, ErrInvalidSlotProof)
 		} else {
 			//log.Info("VerifyPackedSlotProof success", "number", number, "epochID", epochID, "slotID", slotID)
 		}
@@ -538,17 +578,16 @@ func (c *Pluto) verifySeal(chain consensus.ChainReader, header *types.Header, pa
 	epochID := header.Difficulty.Uint64() >> 32
 	slotID := (header.Difficulty.Uint64() >> 8) & 0x00FFFFFF
 
-	s := slotleader.GetSlotLeaderSelection()
-
 	if len(header.Extra) == extraSeal {
 		log.Warn("Header extra info length is too short")
 		return errUnauthorized
 
 	} else {
-		_, proofMeg, err := s.GetInfoFromHeadExtra(epochID, header.Extra[:len(header.Extra)-extraSeal])
+		_, proofMeg, err := decodeExtra(header, epochID)
 
 		if err != nil {
 			log.Error("Can not GetInfoFromHeadExtra, verify failed", "error", err.Error())
+			return checked(header, err)
 		} else {
 			log.Debug("verifySeal GetInfoFromHeadExtra", "pk", hex.EncodeToString(crypto.FromECDSAPub(proofMeg[0])))
 
@@ -557,6 +596,7 @@ func (c *Pluto) verifySeal(chain consensus.ChainReader, header *types.Header, pa
 			signer, err := ecrecover(header, c.signatures)
 			if err != nil {
 				log.Error(err.Error())
+				return checked(header, err)
 			}
 
 			if signer.Hex() != crypto.PubkeyToAddress(*pk).Hex() {
@@ -566,9 +606,16 @@ func (c *Pluto) verifySeal(chain consensus.ChainReader, header *types.Header, pa
 			}
 
 			if isSlotVerify {
+				c.lock.RLock()
+				s := c.sls
+				c.lock.RUnlock()
+				if s == nil {
+					return errNoSlotLeaderSelection
+				}
 				err := s.ValidateBody(types.NewBlockWithHeader(header))
 				if err != nil {
-					return err
+					log.Warn("Slot proof verification failed", "number", number, "error", err)
+					return checked(header, ErrInvalidSlotProof)
 				}
 			}
 		}
@@ -672,14 +719,15 @@ func (c *Pluto) Prepare(chain consensus.ChainReader, header *types.Header, minin
 	//if header.Time.Int64() < time.Now().Unix() {
 	//	header.Time = big.NewInt(time.Now().Unix())
 	//}
-	curEpochId, curSlotId := util.GetEpochSlotID()
+	clock := c.slotClock()
+	curEpochId, curSlotId := clock.GetEpochSlotID()
 
-	if posconfig.EpochBaseTime == 0 {
-		cur := time.Now().Unix()
+	if baseTime := clock.BaseTime(); baseTime == 0 {
+		cur := clock.NowUnix()
 		hcur := cur - (cur % posconfig.SlotTime) + posconfig.SlotTime
-		header.Time = big.NewInt(hcur)
+		header.Time = new(big.Int).SetUint64(hcur)
 	} else {
-		header.Time = big.NewInt(int64(posconfig.EpochBaseTime + (curEpochId*posconfig.SlotCount+curSlotId)*posconfig.SlotTime))
+		header.Time = big.NewInt(int64(baseTime + (curEpochId*posconfig.SlotCount+curSlotId)*posconfig.SlotTime))
 	}
 
 	epochSlotId := uint64(1)
@@ -699,8 +747,14 @@ func (c *Pluto) Finalize(chain consensus.ChainReader, header *types.Header, stat
 	slotID := (header.Difficulty.Uint64() >> 8) & 0x00FFFFFF
 	if epochID >= posconfig.IncentiveDelayEpochs && slotID > posconfig.IncentiveStartStage {
 		//log.Info("--------Incentive Runs--------", "number", header.Number.String(), "epochID", epochID)
+		c.lock.RLock()
+		inc := c.incentive
+		c.lock.RUnlock()
+		if inc == nil {
+			return nil, errNoIncentive
+		}
 		snap := state.Snapshot()
-		if !incentive.Run(chain, state, epochID-posconfig.IncentiveDelayEpochs, header.Number.Uint64()) {
+		if !inc.Run(chain, state, epochID-posconfig.IncentiveDelayEpochs, header.Number.Uint64()) {
 			log.Error("incentive.Run failed")
 			state.RevertToSnapshot(snap)
 		}
@@ -732,6 +786,25 @@ func (c *Pluto) Authorize(signer common.Address, signFn SignerFn, key *keystore.
 	c.key = key
 }
 
+// SetPos injects the node's slot leader selection, incentive and slot timing
+// into the consensus engine.
+func (c *Pluto) SetPos(sls *slotleader.SLS, inc *incentive.Incentive, clock *util.SlotClock) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.sls = sls
+	c.incentive = inc
+	c.clock = clock
+}
+
+// slotClock returns the slot timing of the node.
+func (c *Pluto) slotClock() *util.SlotClock {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.clock
+}
+
 // Seal implements consensus.Engine, attempting to create a sealed block using
 // the local signing credentials.
 func (c *Pluto) Seal(chain consensus.ChainReader, block *types.Block, stop <-chan struct{}) (*types.Block, error) {
@@ -748,8 +821,11 @@ func (c *Pluto) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 	}
 	// Don't hold the signer fields for the entire sealing procedure
 	c.lock.RLock()
-	signer, signFn, key := c.signer, c.signFn, c.key
+	signer, signFn, key, s, clock := c.signer, c.signFn, c.key, c.sls, c.clock
 	c.lock.RUnlock()
+	if s == nil {
+		return nil, errNoSlotLeaderSelection
+	}
 	localPublicKey := hex.EncodeToString(crypto.FromECDSAPub(&c.key.PrivateKey.PublicKey))
 
 	// Bail out if we're unauthorized to sign a block
@@ -764,9 +840,16 @@ func (c *Pluto) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 	epochSlotId := uint64(1)
 	var epochIDPack uint64
 	var slotIDPack uint64
-	epochId, slotId := util.GetEpochSlotID()
+	epochId, slotId := clock.GetEpochSlotID()
 	log.Info(fmt.Sprintln("Pluto Seal: epochId:", epochId, "slotId:", slotId))
 
+	// The slot may have passed while the work was committed, the header time
+	// would then lie outside of the slot we sign and peers reject the block
+	if headerEpochId, headerSlotId := clock.CalEpochSlotID(header.Time.Uint64()); headerEpochId != epochId || headerSlotId != slotId {
+		log.Debug("Skipping stale work, slot has passed", "number", number, "epochID", headerEpochId, "slotID", headerSlotId)
+		return nil, nil
+	}
+
 	var leader string
 	//if epochId != 0 {
 	//	leaderPub, err := slotleader.GetSlotLeaderSelection().GetSlotLeader(epochId, slotId)
@@ -779,19 +862,19 @@ func (c *Pluto) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 	//	//genesis block miner publicKey
 	//	leader = posconfig.GenesisPK
 	//}
-	leaderPub, err := slotleader.GetSlotLeaderSelection().GetSlotLeader(epochId, slotId)
+	leaderPub, err := s.GetSlotLeader(epochId, slotId)
 	if err != nil {
 		return nil, err
 	}
 	leader = hex.EncodeToString(crypto.FromECDSAPub(leaderPub))
 	if leader == localPublicKey {
-		cur := uint64(time.Now().Unix())
+		cur := clock.NowUnix()
 		sleepTime := uint64(0)
 		sealTime := uint64(0)
-		if posconfig.EpochBaseTime == 0 {
+		if baseTime := clock.BaseTime(); baseTime == 0 {
 			sealTime = header.Time.Uint64() + posconfig.SlotTime/2
 		} else {
-			sealTime = posconfig.EpochBaseTime + posconfig.SlotTime/2 + (epochId*posconfig.SlotCount+slotId)*posconfig.SlotTime
+			sealTime = baseTime + posconfig.SlotTime/2 + (epochId*posconfig.SlotCount+slotId)*posconfig.SlotTime
 		}
 		if cur < sealTime {
 			sleepTime = sealTime - cur
@@ -801,7 +884,7 @@ func (c *Pluto) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 		select {
 		case <-stop:
 			return nil, nil
-		case <-time.After(time.Duration(sleepTime) * time.Second): // TODO when generate new block
+		case <-clock.After(time.Duration(sleepTime) * time.Second): // TODO when generate new block
 			epochSlotId += slotId << 8
 			epochSlotId += epochId << 32
 
@@ -855,7 +938,6 @@ func (c *Pluto) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 	header.Difficulty.SetUint64(epochSlotId)
 	header.Coinbase = signer
 
-	s := slotleader.GetSlotLeaderSelection()
 	buf, err := s.PackSlotProof(epochIDPack, slotIDPack, key.PrivateKey)
 	if err != nil {
 		log.Warn("PackSlotProof failed in Seal", "epochID", epochIDPack, "slotID", slotIDPack, "error", err.Error())
@@ -892,7 +974,6 @@ func (c *Pluto) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 		return nil, err
 	}
 
-	util.UpdateEpochBlock(epochIDPack, slotIDPack, number)
 	return block.WithSeal(header), nil
 }
 
@@ -0,0 +1,141 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package pluto
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/consensus"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/slotleader"
+	"github.com/wanchain/go-wanchain/pos/util"
+)
+
+// Errors returned by the strict pos header checks.
+var (
+	// ErrInvalidSlot is returned if the epoch and slot encoded in the difficulty
+	// of a header are malformed.
+	ErrInvalidSlot = errors.New("invalid epoch/slot encoding")
+
+	// ErrSlotNotIncreasing is returned if a header isn't sealed in a later slot
+	// than its parent.
+	ErrSlotNotIncreasing = errors.New("slot not after parent slot")
+
+	// ErrTimestampOutsideSlot is returned if the timestamp of a header lies
+	// outside the time window of its slot.
+	ErrTimestampOutsideSlot = errors.New("timestamp outside of slot window")
+
+	// ErrInvalidExtra is returned if the extra-data of a header isn't a slot
+	// proof pack followed by the 65 byte seal.
+	ErrInvalidExtra = errors.New("invalid extra-data layout")
+
+	// ErrInvalidSlotProof is returned if the slot leader proof of a header
+	// doesn't verify.
+	ErrInvalidSlotProof = errors.New("invalid slot leader proof")
+)
+
+// checked reports the failure of a strict header check. In warn-only mode the
+// failure is logged and the header accepted.
+func checked(header *types.Header, err error) error {
+	if err == nil || !posconfig.Cfg().HeaderWarnOnly {
+		return err
+	}
+	log.Warn("Accepting invalid pos header", "number", header.Number, "hash", header.Hash(), "err", err)
+	return nil
+}
+
+// decodeSlot splits the difficulty of a pos header into the epoch and the slot
+// it was sealed in. The difficulty is laid out as epochID<<32 | slotID<<8 | 1.
+func decodeSlot(difficulty *big.Int) (epochID, slotID uint64, err error) {
+	if difficulty == nil || difficulty.Sign() <= 0 || difficulty.BitLen() > 64 {
+		return 0, 0, ErrInvalidSlot
+	}
+	d := difficulty.Uint64()
+	if d&0xFF != 1 {
+		return 0, 0, ErrInvalidSlot
+	}
+	epochID, slotID = d>>32, (d>>8)&0x00FFFFFF
+	if slotID >= posconfig.SlotCount {
+		return 0, 0, ErrInvalidSlot
+	}
+	return epochID, slotID, nil
+}
+
+// decodeExtra unpacks the slot leader proof from the extra-data of a header.
+func decodeExtra(header *types.Header, epochID uint64) ([]*big.Int, []*ecdsa.PublicKey, error) {
+	if len(header.Extra) <= extraSeal {
+		return nil, nil, ErrInvalidExtra
+	}
+	proof, proofMeg, err := slotleader.UnpackSlotProof(header.Extra[:len(header.Extra)-extraSeal])
+	if err != nil || len(proof) == 0 || len(proofMeg) == 0 {
+		return nil, nil, ErrInvalidExtra
+	}
+	for _, pk := range proofMeg {
+		if pk == nil || pk.X == nil {
+			return nil, nil, ErrInvalidExtra
+		}
+	}
+	return proof, proofMeg, nil
+}
+
+// posBaseTime returns the start of slot 0, the timestamp of the first pos block.
+// The caller may pass in a batch of parents not yet part of the chain. Nodes
+// missing the first pos block fall back to the base time of their slot clock,
+// if any.
+func posBaseTime(chain consensus.ChainReader, header *types.Header, parents []*types.Header) (uint64, bool) {
+	first := chain.Config().PosFirstBlock()
+	if header.Number.Uint64() == first {
+		return header.Time.Uint64(), true
+	}
+	for _, parent := range parents {
+		if parent.Number.Uint64() == first {
+			return parent.Time.Uint64(), true
+		}
+	}
+	if base := chain.GetHeaderByNumber(first); base != nil {
+		return base.Time.Uint64(), true
+	}
+	base := util.ChainSlotClock(chain).BaseTime()
+	return base, base != 0
+}
+
+// verifySlot checks that header is sealed in a slot after the one of its parent
+// and that its timestamp lies within that slot.
+func verifySlot(chain consensus.ChainReader, header, parent *types.Header, parents []*types.Header) error {
+	epochID, slotID, err := decodeSlot(header.Difficulty)
+	if err != nil {
+		return err
+	}
+	slot := epochID*posconfig.SlotCount + slotID
+
+	// The first pos block opens slot 0 and has no pos parent to compare with
+	if header.Number.Uint64() == chain.Config().PosFirstBlock() {
+		if slot != 0 {
+			return ErrInvalidSlot
+		}
+		return nil
+	}
+	if chain.Config().IsPosActive(parent.Number) {
+		parentEpoch, parentSlot, err := decodeSlot(parent.Difficulty)
+		if err != nil {
+			return err
+		}
+		if slot <= parentEpoch*posconfig.SlotCount+parentSlot {
+			return ErrSlotNotIncreasing
+		}
+	}
+	base, ok := posBaseTime(chain, header, parents)
+	if !ok {
+		log.Debug("Skipping slot window check, pos base time unknown", "number", header.Number)
+		return nil
+	}
+	start := base + slot*posconfig.SlotTime
+	if time := header.Time.Uint64(); header.Time.BitLen() > 64 || time < start || time >= start+posconfig.SlotTime {
+		return ErrTimestampOutsideSlot
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package pluto
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+func slotDifficulty(epochID, slotID uint64) *big.Int {
+	return new(big.Int).SetUint64(epochID<<32 | slotID<<8 | 1)
+}
+
+func TestDecodeSlot(t *testing.T) {
+	tests := []struct {
+		difficulty  *big.Int
+		epoch, slot uint64
+		err         error
+	}{
+		{slotDifficulty(0, 0), 0, 0, nil},
+		{slotDifficulty(3, 7), 3, 7, nil},
+		{nil, 0, 0, ErrInvalidSlot},
+		{big.NewInt(0), 0, 0, ErrInvalidSlot},
+		{big.NewInt(2), 0, 0, ErrInvalidSlot},
+		{slotDifficulty(0, posconfig.SlotCount), 0, 0, ErrInvalidSlot},
+		{new(big.Int).Lsh(big.NewInt(1), 64), 0, 0, ErrInvalidSlot},
+	}
+	for i, tt := range tests {
+		epoch, slot, err := decodeSlot(tt.difficulty)
+		if err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+			continue
+		}
+		if epoch != tt.epoch || slot != tt.slot {
+			t.Errorf("test %d: slot mismatch: have %d/%d, want %d/%d", i, epoch, slot, tt.epoch, tt.slot)
+		}
+	}
+}
+
+func TestDecodeExtra(t *testing.T) {
+	pk := common.FromHex(posconfig.GenesisPK)
+	pack := func(proof, proofMeg [][]byte) []byte {
+		buf, err := rlp.EncodeToBytes(struct{ Proof, ProofMeg [][]byte }{proof, proofMeg})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return append(buf, make([]byte, extraSeal)...)
+	}
+	tests := []struct {
+		extra []byte
+		err   error
+	}{
+		{pack([][]byte{{1}}, [][]byte{pk}), nil},
+		{make([]byte, extraSeal), ErrInvalidExtra},
+		{append([]byte{0xff, 0x01}, make([]byte, extraSeal)...), ErrInvalidExtra},
+		{pack(nil, [][]byte{pk}), ErrInvalidExtra},
+		{pack([][]byte{{1}}, nil), ErrInvalidExtra},
+		{pack([][]byte{{1}}, [][]byte{{4, 1, 2}}), ErrInvalidExtra},
+	}
+	for i, tt := range tests {
+		if _, _, err := decodeExtra(&types.Header{Extra: tt.extra}, 0); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+func TestVerifySlot(t *testing.T) {
+	defer func(slotTime uint64) { posconfig.SlotTime = slotTime }(posconfig.SlotTime)
+	posconfig.SlotTime = 10
+
+	r := &testChainReader{
+		config:  &params.ChainConfig{Pluto: &params.PlutoConfig{}},
+		headers: make(map[common.Hash]*types.Header),
+	}
+	genesis := &types.Header{Number: new(big.Int), Difficulty: big.NewInt(1), Time: new(big.Int)}
+	first := &types.Header{ParentHash: genesis.Hash(), Number: big.NewInt(1), Difficulty: slotDifficulty(0, 0), Time: big.NewInt(1000)}
+	parent := &types.Header{ParentHash: first.Hash(), Number: big.NewInt(2), Difficulty: slotDifficulty(0, 5), Time: big.NewInt(1050)}
+
+	child := func(epoch, slot, time uint64) *types.Header {
+		return &types.Header{ParentHash: parent.Hash(), Number: big.NewInt(3), Difficulty: slotDifficulty(epoch, slot), Time: new(big.Int).SetUint64(time)}
+	}
+	nextEpoch := 1000 + posconfig.SlotCount*10
+	tests := []struct {
+		header *types.Header
+		err    error
+	}{
+		{child(0, 6, 1060), nil},
+		{child(0, 6, 1069), nil},
+		{child(1, 0, nextEpoch), nil},
+		{child(0, 5, 1050), ErrSlotNotIncreasing},
+		{child(0, 4, 1040), ErrSlotNotIncreasing},
+		{child(0, 6, 1059), ErrTimestampOutsideSlot},
+		{child(0, 6, 1070), ErrTimestampOutsideSlot},
+		{&types.Header{ParentHash: parent.Hash(), Number: big.NewInt(3), Difficulty: big.NewInt(2), Time: big.NewInt(1060)}, ErrInvalidSlot},
+	}
+	for i, tt := range tests {
+		if err := verifySlot(r, tt.header, parent, []*types.Header{first, parent}); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	// The first pos block has to open slot 0
+	if err := verifySlot(r, first, genesis, nil); err != nil {
+		t.Errorf("first pos block: unexpected error %v", err)
+	}
+	bad := &types.Header{ParentHash: genesis.Hash(), Number: big.NewInt(1), Difficulty: slotDifficulty(0, 1), Time: big.NewInt(1000)}
+	if err := verifySlot(r, bad, genesis, nil); err != ErrInvalidSlot {
+		t.Errorf("first pos block in slot 1: error mismatch: have %v, want %v", err, ErrInvalidSlot)
+	}
+}
+
+func TestHeaderWarnOnly(t *testing.T) {
+	defer func(warnOnly bool) { posconfig.Cfg().HeaderWarnOnly = warnOnly }(posconfig.Cfg().HeaderWarnOnly)
+
+	header := &types.Header{Number: big.NewInt(1)}
+	posconfig.Cfg().HeaderWarnOnly = false
+	if err := checked(header, ErrInvalidSlot); err != ErrInvalidSlot {
+		t.Errorf("strict mode: error mismatch: have %v, want %v", err, ErrInvalidSlot)
+	}
+	posconfig.Cfg().HeaderWarnOnly = true
+	if err := checked(header, ErrInvalidSlot); err != nil {
+		t.Errorf("warn-only mode: unexpected error %v", err)
+	}
+}
@@ -40,16 +40,21 @@ import (
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/metrics"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posdb"
 	posUtil "github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/rlp"
 	"github.com/wanchain/go-wanchain/trie"
-	"github.com/wanchain/go-wanchain/pos/posconfig"
 )
 
 var (
 	blockInsertTimer = metrics.NewTimer("chain/inserts")
+	reorgRejectMeter = metrics.NewMeter("chain/reorg/rejected")
 
 	ErrNoGenesis = errors.New("Genesis not found in chain")
+
+	// ErrNoFinalizedBlock is returned when the finalized block is requested
+	// from a chain whose engine has not finalized any block yet.
+	ErrNoFinalizedBlock = errors.New("no finalized block")
 )
 
 const (
@@ -86,6 +91,8 @@ type BlockChain struct {
 	chainFeed     event.Feed
 	chainSideFeed event.Feed
 	chainHeadFeed event.Feed
+	finalFeed     event.Feed
+	reorgFeed     event.Feed
 	logsFeed      event.Feed
 	scope         event.SubscriptionScope
 	genesisBlock  *types.Block
@@ -98,6 +105,9 @@ type BlockChain struct {
 	currentBlock     *types.Block // Current head of the block chain
 	currentFastBlock *types.Block // Current head of the fast-sync chain (may be above the block chain!)
 
+	finalMu     sync.Mutex // finalized head announcement lock
+	finalNumber uint64     // Number of the last announced finalized block
+
 	stateCache   state.Database // State database to reuse between imports (contains state cache)
 	bodyCache    *lru.Cache     // Cache for the most recent block bodies
 	bodyRLPCache *lru.Cache     // Cache for the most recent block bodies in RLP encoded format
@@ -118,6 +128,7 @@ type BlockChain struct {
 	badBlocks *lru.Cache // Bad block cache
 
 	epochGene 	  *EpochGenesisBlock
+	posDbs        *posdb.Dbs // Pos databases of the node, nil before pos
 
 	slotValidator   Validator
 }
@@ -161,6 +172,14 @@ func NewBlockChain(chainDb ethdb.Database, config *params.ChainConfig, engine co
 	if err := bc.loadLastState(); err != nil {
 		return nil, err
 	}
+	if bc.vmConfig.SlotClock == nil {
+		bc.vmConfig.SlotClock = posUtil.NewSlotClock(nil)
+	}
+	if config.Pluto != nil {
+		if first := bc.GetHeaderByNumber(config.PosFirstBlock()); first != nil {
+			bc.vmConfig.SlotClock.SetBaseTime(first.Time.Uint64())
+		}
+	}
 	// Check the current state of the block hashes and make sure that we do not have any of the bad blocks in our chain
 	for hash := range BadHashes {
 		if header := bc.GetHeaderByHash(hash); header != nil {
@@ -336,6 +355,21 @@ func (bc *BlockChain) CurrentBlock() *types.Block {
 	return bc.currentBlock
 }
 
+// CurrentFinalizedBlock retrieves the most recent canonical block that can no
+// longer be reorganised away. It returns nil if the consensus engine has no
+// notion of finality or has not finalized any block yet.
+func (bc *BlockChain) CurrentFinalizedBlock() *types.Block {
+	fc, ok := bc.engine.(consensus.ForkChoice)
+	if !ok {
+		return nil
+	}
+	header := fc.Finalized(bc, bc.CurrentBlock().Header())
+	if header == nil {
+		return nil
+	}
+	return bc.GetBlock(header.Hash(), header.Number.Uint64())
+}
+
 // CurrentFastBlock retrieves the current fast-sync head block of the canonical
 // chain. The block is retrieved from the blockchain's internal cache.
 func (bc *BlockChain) CurrentFastBlock() *types.Block {
@@ -822,6 +856,11 @@ func (bc *BlockChain) WriteBlockAndState(block *types.Block, receipts []*types.R
 	//localTd := bc.currentBlock.Difficulty()
 	//externTd := block.Difficulty()
 
+	// The first pos block, sealed locally or imported, opens slot 0
+	if block.NumberU64() == bc.config.PosFirstBlock() && bc.config.IsPosActive(block.Number()) {
+		bc.vmConfig.SlotClock.SetBaseTime(block.Time().Uint64())
+	}
+
 	// Irrelevant of the canonical status, write the block itself to the database
 	if err := bc.hc.WriteTd(block.Hash(), block.NumberU64(), externTd); err != nil {
 		return NonStatTy, err
@@ -848,7 +887,18 @@ func (bc *BlockChain) WriteBlockAndState(block *types.Block, receipts []*types.R
 	//get maxvalid chain as our suppose
 
 	//if externTd.Cmp(localTd) > 0 {
-	 if bc.currentBlock.NumberU64() == 0||block.NumberU64() > bc.currentBlock.NumberU64() {
+	canon := bc.currentBlock.NumberU64() == 0 || block.NumberU64() > bc.currentBlock.NumberU64()
+	if forkChoice, ok := bc.engine.(consensus.ForkChoice); ok {
+		if canon, err = forkChoice.ReorgNeeded(bc, bc.currentBlock.Header(), block.Header()); err != nil {
+			if err == consensus.ErrReorgTooDeep {
+				reorgRejectMeter.Mark(1)
+			}
+			log.Warn("Rejected chain reorganisation", "number", block.Number(), "hash", block.Hash(),
+				"head", bc.currentBlock.Number(), "headhash", bc.currentBlock.Hash(), "err", err)
+			canon = false
+		}
+	}
+	 if canon {
 
 		 // Reorganise the chain if the parent is not the head block
 		 if block.ParentHash() != bc.currentBlock.Hash() {
@@ -1045,7 +1095,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 			return i, events, coalescedLogs, err
 		}
 
-		if bc.config.Pluto != nil {
+		if bc.config.IsPosActive(block.Number()) {
 			err = bc.SlotValidator().ValidateBody(block)
 			if err != nil {
 				bc.reportBlock(block, receipts, err)
@@ -1085,15 +1135,9 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 		stats.usedGas += usedGas.Uint64()
 		stats.report(chain, i)
 		// TODO: update epoch ->blockNumber
-		if bc.config.Pluto != nil {
+		if bc.config.IsPosActive(block.Number()) {
 			epochID := block.Header().Difficulty.Uint64() >> 32
-			slotID := (block.Header().Difficulty.Uint64() >> 8) & 0x00FFFFFF
-			if block.NumberU64() == 1 {
-				posconfig.EpochBaseTime = block.Time().Uint64()
-			}
-
 			bc.epochGene.UpdateEpochGenesis(epochID)
-			posUtil.UpdateEpochBlock(epochID, slotID, block.Number().Uint64())
 		}
 	}
 	// Append a single chain head event if we've progressed the chain
@@ -1227,7 +1271,12 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 	}
 
 	//ppow extend
-	if ethash, ok := bc.engine.(*ethash.Ethash); ok {
+	engine := bc.engine
+	if hybrid, ok := engine.(interface{ PPoW() consensus.Engine }); ok && !bc.config.IsPosActive(oldBlock.Number()) {
+		// the chains fork before pos took over
+		engine = hybrid.PPoW()
+	}
+	if ethash, ok := engine.(*ethash.Ethash); ok {
 		log.Trace("wanchain willing revert")
 		err := ethash.VerifyPPOWReorg(bc, oldBlock, oldChain, newChain)
 		if err != nil {
@@ -1296,10 +1345,41 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 			}
 		}()
 	}
+	go bc.reorgFeed.Send(bc.newReorgEvent(commonBlock, oldChain, newChain))
 
 	return nil
 }
 
+// newReorgEvent assembles the event announcing a reorg from the common ancestor
+// and the dropped and added blocks, given from the heads down.
+func (bc *BlockChain) newReorgEvent(commonBlock *types.Block, oldChain, newChain types.Blocks) ChainReorgEvent {
+	ev := ChainReorgEvent{
+		OldHead:        oldChain[0].Header(),
+		NewHead:        newChain[0].Header(),
+		CommonAncestor: commonBlock.Header(),
+		Depth:          uint64(len(oldChain)),
+		Dropped:        make([]common.Hash, len(oldChain)),
+		Added:          make([]common.Hash, len(newChain)),
+	}
+	for i, block := range oldChain {
+		ev.Dropped[len(oldChain)-1-i] = block.Hash()
+	}
+	for i, block := range newChain {
+		ev.Added[len(newChain)-1-i] = block.Hash()
+	}
+	// The range spans from the lowest to the highest slot on either branch
+	oldFirst, newFirst := oldChain[len(oldChain)-1], newChain[len(newChain)-1]
+	ev.FromEpoch, ev.FromSlot = bc.GetBlockEpochIdAndSlotId(oldFirst)
+	if epochID, slotID := bc.GetBlockEpochIdAndSlotId(newFirst); epochID < ev.FromEpoch || (epochID == ev.FromEpoch && slotID < ev.FromSlot) {
+		ev.FromEpoch, ev.FromSlot = epochID, slotID
+	}
+	ev.ToEpoch, ev.ToSlot = bc.GetBlockEpochIdAndSlotId(oldChain[0])
+	if epochID, slotID := bc.GetBlockEpochIdAndSlotId(newChain[0]); epochID > ev.ToEpoch || (epochID == ev.ToEpoch && slotID > ev.ToSlot) {
+		ev.ToEpoch, ev.ToSlot = epochID, slotID
+	}
+	return ev
+}
+
 
 // PostChainEvents iterates over the events generated by a chain insertion and
 // posts them into the event feed.
@@ -1316,6 +1396,7 @@ func (bc *BlockChain) PostChainEvents(events []interface{}, logs []*types.Log) {
 
 		case ChainHeadEvent:
 			bc.chainHeadFeed.Send(ev)
+			bc.postFinalizedEvent()
 
 		case ChainSideEvent:
 			bc.chainSideFeed.Send(ev)
@@ -1323,6 +1404,23 @@ func (bc *BlockChain) PostChainEvents(events []interface{}, logs []*types.Log) {
 	}
 }
 
+// postFinalizedEvent announces the finalized head of the chain if it advanced
+// since the last announcement.
+func (bc *BlockChain) postFinalizedEvent() {
+	block := bc.CurrentFinalizedBlock()
+	if block == nil {
+		return
+	}
+	bc.finalMu.Lock()
+	defer bc.finalMu.Unlock()
+
+	if block.NumberU64() <= bc.finalNumber {
+		return
+	}
+	bc.finalNumber = block.NumberU64()
+	bc.finalFeed.Send(ChainFinalizedEvent{Block: block})
+}
+
 func (bc *BlockChain) update() {
 	futureTimer := time.Tick(5 * time.Second)
 	for {
@@ -1486,6 +1584,12 @@ func (bc *BlockChain) GetHeaderByNumber(number uint64) *types.Header {
 // Config retrieves the blockchain's chain configuration.
 func (bc *BlockChain) Config() *params.ChainConfig { return bc.config }
 
+// GetVMConfig returns the block chain VM config.
+func (bc *BlockChain) GetVMConfig() *vm.Config { return &bc.vmConfig }
+
+// SlotClock returns the pos slot timing of the node.
+func (bc *BlockChain) SlotClock() *posUtil.SlotClock { return bc.vmConfig.SlotClock }
+
 // Engine retrieves the blockchain's consensus engine.
 func (bc *BlockChain) Engine() consensus.Engine { return bc.engine }
 
@@ -1504,6 +1608,16 @@ func (bc *BlockChain) SubscribeChainHeadEvent(ch chan<- ChainHeadEvent) event.Su
 	return bc.scope.Track(bc.chainHeadFeed.Subscribe(ch))
 }
 
+// SubscribeChainFinalizedEvent registers a subscription of ChainFinalizedEvent.
+func (bc *BlockChain) SubscribeChainFinalizedEvent(ch chan<- ChainFinalizedEvent) event.Subscription {
+	return bc.scope.Track(bc.finalFeed.Subscribe(ch))
+}
+
+// SubscribeChainReorgEvent registers a subscription of ChainReorgEvent.
+func (bc *BlockChain) SubscribeChainReorgEvent(ch chan<- ChainReorgEvent) event.Subscription {
+	return bc.scope.Track(bc.reorgFeed.Subscribe(ch))
+}
+
 // SubscribeChainSideEvent registers a subscription of ChainSideEvent.
 func (bc *BlockChain) SubscribeChainSideEvent(ch chan<- ChainSideEvent) event.Subscription {
 	return bc.scope.Track(bc.chainSideFeed.Subscribe(ch))
@@ -1521,6 +1635,25 @@ func (bc *BlockChain)SetRbSelector(rbs RbLeadersSelInt){
 	bc.epochGene.rbLeaderSelector = rbs
 }
 
+// SetPosDbs sets the pos databases of the node, keeping the fork counters, the
+// epoch geneses and the data the pos contracts record locally. It must be called
+// before the chain processes blocks.
+func (bc *BlockChain) SetPosDbs(dbs *posdb.Dbs) {
+	bc.posDbs = dbs
+	bc.vmConfig.PosDb = dbs.Pos
+	bc.epochGene.db = dbs.EpochGen
+}
+
+// PosDbs returns the pos databases of the node, nil before they are set.
+func (bc *BlockChain) PosDbs() *posdb.Dbs { return bc.posDbs }
+
+// SetEpocher sets the epoch leader selection of the node the pos contracts
+// check their callers against. It must be called before the chain processes
+// pos blocks.
+func (bc *BlockChain) SetEpocher(epocher posUtil.SelectLead) {
+	bc.vmConfig.Epocher = epocher
+}
+
 func (bc *BlockChain)SetSlSelector(sls SlLeadersSelInt){
 	bc.epochGene.slotLeaderSelector = sls
 }
@@ -1536,10 +1669,17 @@ func (bc *BlockChain) GenerateEpochGenesis(epochid uint64) (*types.EpochGenesis,
 		return nil , errors.New("error epochid")
 	}
 
+	if bc.epochGene.rbLeaderSelector == nil || bc.epochGene.slotLeaderSelector == nil {
+		return nil, errors.New("epoch leader selectors not set")
+	}
+
 	//it is the first block of this epoch
 	blkNum := bc.epochGene.rbLeaderSelector.GetEpochLastBlkNumber(epochid)
 
 	preblk := bc.GetBlockByNumber(blkNum - 1 )
+	if preblk == nil {
+		return nil, errors.New("last block of previous epoch not found")
+	}
 
 	stateDb, err := bc.StateAt(preblk.Root())
 	if err != nil {
@@ -1568,6 +1708,45 @@ func (bc *BlockChain) SetEpochGenesis(epochgen *types.EpochGenesis) error{
 	return bc.epochGene.SetEpochGenesis(epochgen)
 }
 
+// GetEpochGenesis returns the stored genesis of an epoch, nil if none.
+func (bc *BlockChain) GetEpochGenesis(epochid uint64) *types.EpochGenesis {
+	return bc.epochGene.GetEpochGenesis(epochid)
+}
+
+// ImportEpochGenesis verifies an epoch genesis from an untrusted source and
+// stores it. The genesis has to build on the last canonical block before its
+// epoch.
+func (bc *BlockChain) ImportEpochGenesis(epochgen *types.EpochGenesis) error {
+	if epochgen == nil {
+		return errors.New("inputing epoch genesis is nil")
+	}
+	if err := bc.verifyEpochGenesisAnchor(epochgen); err != nil {
+		return err
+	}
+	return bc.epochGene.ImportEpochGenesis(epochgen)
+}
+
+// verifyEpochGenesisAnchor checks that the PreEpochLastBlkHash of an epoch
+// genesis names a canonical block sealed before the genesis epoch, followed by
+// none or one sealed in or after it.
+func (bc *BlockChain) verifyEpochGenesisAnchor(epochgen *types.EpochGenesis) error {
+	header := bc.GetHeaderByHash(epochgen.PreEpochLastBlkHash)
+	if header == nil {
+		return ErrEpochGenesisAnchor
+	}
+	number := header.Number.Uint64()
+	if GetCanonicalHash(bc.chainDb, number) != epochgen.PreEpochLastBlkHash {
+		return ErrEpochGenesisAnchor
+	}
+	if bc.config.IsPosActive(header.Number) && header.Difficulty.Uint64()>>32 >= epochgen.EpochId {
+		return ErrEpochGenesisAnchor
+	}
+	if next := bc.GetHeaderByNumber(number + 1); next != nil && bc.config.IsPosActive(next.Number) && next.Difficulty.Uint64()>>32 < epochgen.EpochId {
+		return ErrEpochGenesisAnchor
+	}
+	return nil
+}
+
 func (bc *BlockChain) GetEpochStartCh() (chan uint64) {
 	return bc.epochGene.epochGenesisCh
 }
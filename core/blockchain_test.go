@@ -1030,6 +1030,51 @@ done:
 
 }
 
+// Tests that a reorg announces both heads, the common ancestor and the dropped
+// and added blocks.
+func TestReorgEvent(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	gspec := DefaultPPOWTestingGenesisBlock()
+	genesis := gspec.MustCommit(db)
+	engine := ethash.NewFaker(db)
+	blockchain, _ := NewBlockChain(db, gspec.Config, engine, vm.Config{})
+	defer blockchain.Stop()
+	chainEnv := NewChainEnv(params.TestChainConfig, gspec, engine, blockchain, db)
+
+	chain, _ := chainEnv.GenerateChain(genesis, 2, func(i int, gen *BlockGen) {})
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	reorgCh := make(chan ChainReorgEvent, 1)
+	blockchain.SubscribeChainReorgEvent(reorgCh)
+
+	forked, _ := chainEnv.GenerateChain(genesis, 3, func(i int, gen *BlockGen) { gen.OffsetTime(1) })
+	if _, err := blockchain.InsertChain(forked); err != nil {
+		t.Fatalf("failed to insert forked chain: %v", err)
+	}
+
+	select {
+	case ev := <-reorgCh:
+		if ev.OldHead.Hash() != chain[1].Hash() || ev.NewHead.Hash() != forked[2].Hash() {
+			t.Errorf("heads mismatch: have %x -> %x, want %x -> %x", ev.OldHead.Hash(), ev.NewHead.Hash(), chain[1].Hash(), forked[2].Hash())
+		}
+		if ev.CommonAncestor.Hash() != genesis.Hash() {
+			t.Errorf("common ancestor mismatch: have %x, want %x", ev.CommonAncestor.Hash(), genesis.Hash())
+		}
+		if ev.Depth != 2 {
+			t.Errorf("depth mismatch: have %d, want 2", ev.Depth)
+		}
+		if len(ev.Dropped) != 2 || ev.Dropped[0] != chain[0].Hash() || ev.Dropped[1] != chain[1].Hash() {
+			t.Errorf("dropped blocks mismatch: have %x", ev.Dropped)
+		}
+		if len(ev.Added) != 3 || ev.Added[0] != forked[0].Hash() || ev.Added[2] != forked[2].Hash() {
+			t.Errorf("added blocks mismatch: have %x", ev.Added)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout. There is no ChainReorgEvent has been sent.")
+	}
+}
+
 // Tests if the canonical block can be fetched from the database during chain insertion.
 func TestCanonicalBlockRetrieval(t *testing.T) {
 	bc, chainEnv := newTestBlockChain(true)
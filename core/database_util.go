@@ -57,6 +57,7 @@ var (
 	blockReceiptsPrefix = []byte("r") // blockReceiptsPrefix + num (uint64 big endian) + hash -> block receipts
 	lookupPrefix        = []byte("l") // lookupPrefix + hash -> transaction/receipt lookup metadata
 	bloomBitsPrefix     = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
+	epochGenesisPrefix  = []byte("G") // epochGenesisPrefix + epoch (uint64 big endian) -> epoch genesis
 
 	preimagePrefix = "secure-key-"              // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-") // config prefix for the db
@@ -251,6 +252,21 @@ func GetBlockReceipts(db DatabaseReader, hash common.Hash, number uint64) types.
 	return receipts
 }
 
+// GetEpochGenesis retrieves the genesis of a pos epoch stored by a light client,
+// nil if none found.
+func GetEpochGenesis(db DatabaseReader, epochID uint64) *types.EpochGenesis {
+	data, _ := db.Get(append(epochGenesisPrefix, encodeBlockNumber(epochID)...))
+	if len(data) == 0 {
+		return nil
+	}
+	genesis := new(types.EpochGenesis)
+	if err := rlp.DecodeBytes(data, genesis); err != nil {
+		log.Error("Invalid epoch genesis RLP", "epoch", epochID, "err", err)
+		return nil
+	}
+	return genesis
+}
+
 // GetTxLookupEntry retrieves the positional metadata associated with a transaction
 // hash to allow retrieving the transaction or receipt by hash.
 func GetTxLookupEntry(db DatabaseReader, hash common.Hash) (common.Hash, uint64, uint64) {
@@ -460,6 +476,19 @@ func WriteBlockReceipts(db ethdb.Putter, hash common.Hash, number uint64, receip
 	return nil
 }
 
+// WriteEpochGenesis stores the genesis of a pos epoch into the database.
+func WriteEpochGenesis(db ethdb.Putter, genesis *types.EpochGenesis) error {
+	data, err := rlp.EncodeToBytes(genesis)
+	if err != nil {
+		return err
+	}
+	key := append(epochGenesisPrefix, encodeBlockNumber(genesis.EpochId)...)
+	if err := db.Put(key, data); err != nil {
+		log.Crit("Failed to store epoch genesis", "err", err)
+	}
+	return nil
+}
+
 // WriteTxLookupEntries stores a positional metadata for every transaction from
 // a block, enabling hash based transaction and receipt lookups.
 func WriteTxLookupEntries(db ethdb.Putter, block *types.Block) error {
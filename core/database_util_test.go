@@ -387,3 +387,31 @@ func TestBlockReceiptStorage(t *testing.T) {
 		t.Fatalf("deleted receipts returned: %v", rs)
 	}
 }
+
+// Tests epoch genesis and epoch leader storage and retrieval operations.
+func TestEpochDataStorage(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+
+	genesis := &types.EpochGenesis{
+		ProtocolMagic:       []byte("wanchainpos"),
+		EpochId:             7,
+		PreEpochLastBlkHash: common.Hash{0x07},
+		Random:              []byte{0x01, 0x02},
+		EpochLeaders:        [][]byte{{0x04, 0x01}, {0x04, 0x02}},
+	}
+	hash, err := EpochGenesisHash(genesis)
+	if err != nil {
+		t.Fatalf("failed to hash epoch genesis: %v", err)
+	}
+	genesis.GenesisBlkHash = hash
+
+	if entry := GetEpochGenesis(db, 7); entry != nil {
+		t.Fatalf("non existent epoch genesis returned: %v", entry)
+	}
+	WriteEpochGenesis(db, genesis)
+	if entry := GetEpochGenesis(db, 7); entry == nil {
+		t.Fatalf("stored epoch genesis not found")
+	} else if have, err := EpochGenesisHash(entry); err != nil || have != hash || entry.GenesisBlkHash != hash {
+		t.Fatalf("retrieved epoch genesis mismatch: have %x/%x, want %x", have, entry.GenesisBlkHash, hash)
+	}
+}
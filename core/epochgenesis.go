@@ -9,7 +9,6 @@ import (
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/pos/posdb"
-	 posUtil "github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/rlp"
 	"encoding/binary"
 	"bytes"
@@ -19,10 +18,10 @@ import (
 
 
 func (bc *BlockChain) updateReOrg(epochId uint64, length uint64) {
-	reOrgDb := posdb.GetDbByName("forkdb")
-	if reOrgDb == nil {
-		reOrgDb = posdb.NewDb("forkdb")
+	if bc.posDbs == nil {
+		return
 	}
+	reOrgDb := bc.posDbs.Fork
 
 	numberBytes, _ := reOrgDb.Get(epochId, "reorgNumber")
 
@@ -42,10 +41,10 @@ func (bc *BlockChain) updateReOrg(epochId uint64, length uint64) {
 }
 
 func (bc *BlockChain) updateFork(epochId uint64) {
-	reOrgDb := posdb.GetDbByName("forkdb")
-	if reOrgDb == nil {
-		reOrgDb = posdb.NewDb("forkdb")
+	if bc.posDbs == nil {
+		return
 	}
+	reOrgDb := bc.posDbs.Fork
 
 	numberBytes, _ := reOrgDb.Get(0, "forkNumber")
 
@@ -61,6 +60,15 @@ func (bc *BlockChain) updateFork(epochId uint64) {
 }
 
 
+// Errors returned by VerifyEpochGenesis.
+var (
+	ErrEpochGenesisMagic   = errors.New("invalid epoch genesis protocol magic")
+	ErrEpochGenesisLeaders = errors.New("epoch genesis lacks leaders")
+	ErrEpochGenesisHash    = errors.New("epoch genesis hash mismatch")
+	ErrEpochGenesisAnchor  = errors.New("epoch genesis not anchored in the local chain")
+	ErrNoEpochGenesisDb    = errors.New("no epoch genesis db")
+)
+
 type RbLeadersSelInt interface {
 	GetEpochLastBlkNumber(epochId uint64) uint64
 	GetRBProposerGroup(epochID uint64) []vm.Leader
@@ -72,6 +80,7 @@ type SlLeadersSelInt interface {
 }
 
 type EpochGenesisBlock struct {
+	db                 		*posdb.Db // Epoch geneses, nil until the pos dbs are set
 	useEpochGenesis    		bool
 	rbLeaderSelector   		RbLeadersSelInt
 	slotLeaderSelector 		SlLeadersSelInt
@@ -134,48 +143,82 @@ func (f *EpochGenesisBlock) GenerateEpochGenesis(epochid uint64,lastblk *types.B
 		}
 	}
 
-	epGen.GenesisBlkHash = common.Hash{}
-
-	byteVal, err := json.Marshal(epGen)
+	hash, err := EpochGenesisHash(epGen)
 	if err != nil {
 		log.Debug("Failed to marshal epoch genesis data", "err", err)
 		return nil, err
 	}
 
-	epGen.GenesisBlkHash = crypto.Keccak256Hash(byteVal)
+	epGen.GenesisBlkHash = hash
 
 	return epGen, nil
 }
 
+// EpochGenesisHash returns the hash sealing an epoch genesis, the keccak256 of
+// its JSON encoding with GenesisBlkHash left empty. RLP doesn't tell nil and
+// empty fields apart, so they're hashed the way GenerateEpochGenesis leaves
+// them: byte fields as nil and leader lists as empty, which keeps the preimage
+// byte-identical to the one of geneses generated before it was introduced.
+func EpochGenesisHash(epGen *types.EpochGenesis) (common.Hash, error) {
+	cpy := *epGen
+	cpy.GenesisBlkHash = common.Hash{}
+	if len(cpy.ProtocolMagic) == 0 {
+		cpy.ProtocolMagic = nil
+	}
+	if len(cpy.Random) == 0 {
+		cpy.Random = nil
+	}
+	if cpy.EpochLeaders == nil {
+		cpy.EpochLeaders = make([][]byte, 0)
+	}
+	if cpy.SlotLeaders == nil {
+		cpy.SlotLeaders = make([][]byte, 0)
+	}
+	if cpy.RBLeaders == nil {
+		cpy.RBLeaders = make([][]byte, 0)
+	}
+	if len(cpy.Extra) == 0 {
+		cpy.Extra = nil
+	}
+
+	byteVal, err := json.Marshal(&cpy)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(byteVal), nil
+}
+
 func (f *EpochGenesisBlock) preVerifyEpochGenesis(epGen *types.EpochGenesis) bool {
 
 	if !f.useEpochGenesis {
 		return true
 	}
 
-	res := bytes.Equal(epGen.ProtocolMagic,[]byte("wanchainpos"))
-	if !res {
-		return false
-	}
+	return VerifyEpochGenesis(epGen) == nil
+}
 
-	if len(epGen.RBLeaders)==0 || len(epGen.SlotLeaders)==0 || len(epGen.EpochLeaders)==0 {
-		return false
+// VerifyEpochGenesis checks the protocol magic of an epoch genesis, that it
+// names the leaders of its epoch and that it's sealed by its hash.
+func VerifyEpochGenesis(epGen *types.EpochGenesis) error {
+	if !bytes.Equal(epGen.ProtocolMagic, []byte("wanchainpos")) {
+		return ErrEpochGenesisMagic
 	}
 
-	genesisHash := epGen.GenesisBlkHash
+	if len(epGen.RBLeaders) == 0 || len(epGen.SlotLeaders) == 0 || len(epGen.EpochLeaders) == 0 {
+		return ErrEpochGenesisLeaders
+	}
 
-	epGen.GenesisBlkHash = common.Hash{}
-	byteVal, err := json.Marshal(epGen)
+	calHash, err := EpochGenesisHash(epGen)
 	if err != nil {
 		log.Debug("Failed to marshal epoch genesis data", "err", err)
-		return false
+		return err
 	}
 
-	calHash := crypto.Keccak256Hash(byteVal)
-
-	res = (genesisHash == calHash)
+	if epGen.GenesisBlkHash != calHash {
+		return ErrEpochGenesisHash
+	}
 
-	return res
+	return nil
 }
 
 func (f *EpochGenesisBlock) IsFirstBlockInEpoch(firstBlk *types.Block) bool {
@@ -200,13 +243,16 @@ func (f *EpochGenesisBlock) UpdateEpochGenesis(epochID uint64) {
 }
 
 func (f *EpochGenesisBlock) GetLastBlkInPreEpoch(bc *BlockChain, blk *types.Block) *types.Block {
+	if f.rbLeaderSelector == nil {
+		return nil
+	}
 	epochID := blk.Header().Difficulty.Uint64() >> 32
-	blkNUm := posUtil.GetEpochBlock(epochID - 1)
+	blkNUm := f.rbLeaderSelector.GetEpochLastBlkNumber(epochID - 1)
 	return bc.GetBlockByNumber(blkNUm)
 }
 
 func (f *EpochGenesisBlock) IsExistEpochGenesis(epochid uint64) bool {
-	epochGenDb := posdb.GetDbByName("epochGendb")
+	epochGenDb := f.db
 	if epochGenDb == nil {
 		return false
 	}
@@ -220,10 +266,9 @@ func (f *EpochGenesisBlock) IsExistEpochGenesis(epochid uint64) bool {
 
 }
 
+// SetEpochGenesis verifies an epoch genesis and stores it. Verification runs
+// outside the store lock, so geneses may be set concurrently.
 func (f *EpochGenesisBlock) SetEpochGenesis(epochgen *types.EpochGenesis) error {
-	f.epsetmu.Lock()
-	defer f.epsetmu.Unlock()
-
 	if epochgen == nil {
 		return errors.New("inputing epoch genesis is nil")
 	}
@@ -233,9 +278,30 @@ func (f *EpochGenesisBlock) SetEpochGenesis(epochgen *types.EpochGenesis) error
 		return errors.New("epoch genesis preverify is failed")
 	}
 
-	epochGenDb := posdb.GetDbByName("epochGendb")
+	return f.storeEpochGenesis(epochgen)
+}
+
+// ImportEpochGenesis stores an epoch genesis from an untrusted source, e.g. a
+// file. Unlike SetEpochGenesis it always verifies the genesis.
+func (f *EpochGenesisBlock) ImportEpochGenesis(epochgen *types.EpochGenesis) error {
+	if epochgen == nil {
+		return errors.New("inputing epoch genesis is nil")
+	}
+
+	if err := VerifyEpochGenesis(epochgen); err != nil {
+		return err
+	}
+
+	return f.storeEpochGenesis(epochgen)
+}
+
+func (f *EpochGenesisBlock) storeEpochGenesis(epochgen *types.EpochGenesis) error {
+	f.epsetmu.Lock()
+	defer f.epsetmu.Unlock()
+
+	epochGenDb := f.db
 	if epochGenDb == nil {
-		epochGenDb = posdb.NewDb("epochGendb")
+		return ErrNoEpochGenesisDb
 	}
 
 	val,err := rlp.EncodeToBytes(epochgen)
@@ -254,7 +320,7 @@ func (f *EpochGenesisBlock) SetEpochGenesis(epochgen *types.EpochGenesis) error
 }
 
 func (f *EpochGenesisBlock) GetEpochGenesis(epochid uint64) *types.EpochGenesis{
-	epochGenDb := posdb.GetDbByName("epochGendb")
+	epochGenDb := f.db
 	if epochGenDb == nil {
 		return nil
 	}
@@ -0,0 +1,103 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+func TestVerifyEpochGenesis(t *testing.T) {
+	newGenesis := func() *types.EpochGenesis {
+		genesis := &types.EpochGenesis{
+			ProtocolMagic: []byte("wanchainpos"),
+			EpochId:       7,
+			Random:        []byte{0x01},
+			EpochLeaders:  [][]byte{{0x02}},
+			SlotLeaders:   [][]byte{{0x03}},
+			RBLeaders:     [][]byte{{0x04}},
+		}
+		hash, err := EpochGenesisHash(genesis)
+		if err != nil {
+			t.Fatalf("failed to hash genesis: %v", err)
+		}
+		genesis.GenesisBlkHash = hash
+		return genesis
+	}
+	if err := VerifyEpochGenesis(newGenesis()); err != nil {
+		t.Errorf("valid genesis rejected: %v", err)
+	}
+
+	genesis := newGenesis()
+	genesis.ProtocolMagic = []byte("wanchain")
+	if err := VerifyEpochGenesis(genesis); err != ErrEpochGenesisMagic {
+		t.Errorf("bad magic: have %v, want %v", err, ErrEpochGenesisMagic)
+	}
+
+	genesis = newGenesis()
+	genesis.SlotLeaders = nil
+	if err := VerifyEpochGenesis(genesis); err != ErrEpochGenesisLeaders {
+		t.Errorf("missing leaders: have %v, want %v", err, ErrEpochGenesisLeaders)
+	}
+
+	genesis = newGenesis()
+	genesis.Random = []byte{0x05}
+	if err := VerifyEpochGenesis(genesis); err != ErrEpochGenesisHash {
+		t.Errorf("tampered genesis: have %v, want %v", err, ErrEpochGenesisHash)
+	}
+}
+
+// Tests that the hash of a generated genesis is the keccak256 of its plain JSON
+// encoding, and stays so once the genesis went through RLP.
+func TestEpochGenesisHashPreimage(t *testing.T) {
+	genesis := &types.EpochGenesis{
+		ProtocolMagic: []byte("wanchainpos"),
+		EpochId:       7,
+		EpochLeaders:  [][]byte{{0x02}},
+		SlotLeaders:   [][]byte{{0x03}},
+		RBLeaders:     make([][]byte, 0),
+	}
+	preimage, err := json.Marshal(genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := crypto.Keccak256Hash(preimage)
+	if hash, err := EpochGenesisHash(genesis); err != nil || hash != want {
+		t.Fatalf("hash mismatch: have %x, want %x (err %v)", hash, want, err)
+	}
+
+	blob, err := rlp.EncodeToBytes(genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := new(types.EpochGenesis)
+	if err := rlp.DecodeBytes(blob, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if hash, err := EpochGenesisHash(decoded); err != nil || hash != want {
+		t.Fatalf("decoded hash mismatch: have %x, want %x (err %v)", hash, want, err)
+	}
+}
+
+// Tests that imported epoch geneses have to build on the local canonical chain.
+func TestImportEpochGenesisAnchor(t *testing.T) {
+	_, blockchain, err, _ := newCanonical(4, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer blockchain.Stop()
+
+	genesis := &types.EpochGenesis{EpochId: 1, PreEpochLastBlkHash: blockchain.GetBlockByNumber(3).Hash()}
+	if err := blockchain.verifyEpochGenesisAnchor(genesis); err != nil {
+		t.Errorf("canonical anchor rejected: %v", err)
+	}
+	genesis.PreEpochLastBlkHash = common.HexToHash("0x01")
+	if err := blockchain.ImportEpochGenesis(genesis); err != ErrEpochGenesisAnchor {
+		t.Errorf("unknown anchor: have %v, want %v", err, ErrEpochGenesisAnchor)
+	}
+}
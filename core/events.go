@@ -52,3 +52,21 @@ type ChainSideEvent struct {
 }
 
 type ChainHeadEvent struct{ Block *types.Block }
+
+// ChainReorgEvent is posted when the canonical chain is reorganised. The block
+// hash lists run in ascending order from the block after the common ancestor.
+type ChainReorgEvent struct {
+	OldHead        *types.Header
+	NewHead        *types.Header
+	CommonAncestor *types.Header
+	Depth          uint64 // number of canonical blocks dropped
+
+	FromEpoch, FromSlot uint64 // first slot of the reorganised range
+	ToEpoch, ToSlot     uint64 // last slot of the reorganised range
+
+	Dropped []common.Hash // blocks leaving the canonical chain
+	Added   []common.Hash // blocks joining the canonical chain
+}
+
+// ChainFinalizedEvent is posted when the finalized head of the chain advances.
+type ChainFinalizedEvent struct{ Block *types.Block }
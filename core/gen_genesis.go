@@ -15,18 +15,19 @@ import (
 
 func (g Genesis) MarshalJSON() ([]byte, error) {
 	type Genesis struct {
-		Config     *params.ChainConfig                         `json:"config"`
-		Nonce      math.HexOrDecimal64                         `json:"nonce"`
-		Timestamp  math.HexOrDecimal64                         `json:"timestamp"`
-		ExtraData  hexutil.Bytes                               `json:"extraData"`
-		GasLimit   math.HexOrDecimal64                         `json:"gasLimit"   gencodec:"required"`
-		Difficulty *math.HexOrDecimal256                       `json:"difficulty" gencodec:"required"`
-		Mixhash    common.Hash                                 `json:"mixHash"`
-		Coinbase   common.Address                              `json:"coinbase"`
-		Alloc      map[common.UnprefixedAddress]GenesisAccount `json:"alloc"      gencodec:"required"`
-		Number     math.HexOrDecimal64                         `json:"number"`
-		GasUsed    math.HexOrDecimal64                         `json:"gasUsed"`
-		ParentHash common.Hash                                 `json:"parentHash"`
+		Config         *params.ChainConfig                         `json:"config"`
+		Nonce          math.HexOrDecimal64                         `json:"nonce"`
+		Timestamp      math.HexOrDecimal64                         `json:"timestamp"`
+		ExtraData      hexutil.Bytes                               `json:"extraData"`
+		GasLimit       math.HexOrDecimal64                         `json:"gasLimit"   gencodec:"required"`
+		Difficulty     *math.HexOrDecimal256                       `json:"difficulty" gencodec:"required"`
+		Mixhash        common.Hash                                 `json:"mixHash"`
+		Coinbase       common.Address                              `json:"coinbase"`
+		Alloc          map[common.UnprefixedAddress]GenesisAccount `json:"alloc"      gencodec:"required"`
+		InitialStakers []GenesisStaker                             `json:"initialStakers,omitempty"`
+		Number         math.HexOrDecimal64                         `json:"number"`
+		GasUsed        math.HexOrDecimal64                         `json:"gasUsed"`
+		ParentHash     common.Hash                                 `json:"parentHash"`
 	}
 	var enc Genesis
 	enc.Config = g.Config
@@ -43,6 +44,7 @@ func (g Genesis) MarshalJSON() ([]byte, error) {
 			enc.Alloc[common.UnprefixedAddress(k)] = v
 		}
 	}
+	enc.InitialStakers = g.InitialStakers
 	enc.Number = math.HexOrDecimal64(g.Number)
 	enc.GasUsed = math.HexOrDecimal64(g.GasUsed)
 	enc.ParentHash = g.ParentHash
@@ -51,18 +53,19 @@ func (g Genesis) MarshalJSON() ([]byte, error) {
 
 func (g *Genesis) UnmarshalJSON(input []byte) error {
 	type Genesis struct {
-		Config     *params.ChainConfig                         `json:"config"`
-		Nonce      *math.HexOrDecimal64                        `json:"nonce"`
-		Timestamp  *math.HexOrDecimal64                        `json:"timestamp"`
-		ExtraData  hexutil.Bytes                               `json:"extraData"`
-		GasLimit   *math.HexOrDecimal64                        `json:"gasLimit"   gencodec:"required"`
-		Difficulty *math.HexOrDecimal256                       `json:"difficulty" gencodec:"required"`
-		Mixhash    *common.Hash                                `json:"mixHash"`
-		Coinbase   *common.Address                             `json:"coinbase"`
-		Alloc      map[common.UnprefixedAddress]GenesisAccount `json:"alloc"      gencodec:"required"`
-		Number     *math.HexOrDecimal64                        `json:"number"`
-		GasUsed    *math.HexOrDecimal64                        `json:"gasUsed"`
-		ParentHash *common.Hash                                `json:"parentHash"`
+		Config         *params.ChainConfig                         `json:"config"`
+		Nonce          *math.HexOrDecimal64                        `json:"nonce"`
+		Timestamp      *math.HexOrDecimal64                        `json:"timestamp"`
+		ExtraData      hexutil.Bytes                               `json:"extraData"`
+		GasLimit       *math.HexOrDecimal64                        `json:"gasLimit"   gencodec:"required"`
+		Difficulty     *math.HexOrDecimal256                       `json:"difficulty" gencodec:"required"`
+		Mixhash        *common.Hash                                `json:"mixHash"`
+		Coinbase       *common.Address                             `json:"coinbase"`
+		Alloc          map[common.UnprefixedAddress]GenesisAccount `json:"alloc"      gencodec:"required"`
+		InitialStakers []GenesisStaker                             `json:"initialStakers,omitempty"`
+		Number         *math.HexOrDecimal64                        `json:"number"`
+		GasUsed        *math.HexOrDecimal64                        `json:"gasUsed"`
+		ParentHash     *common.Hash                                `json:"parentHash"`
 	}
 	var dec Genesis
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -101,6 +104,9 @@ func (g *Genesis) UnmarshalJSON(input []byte) error {
 	for k, v := range dec.Alloc {
 		g.Alloc[common.Address(k)] = v
 	}
+	if dec.InitialStakers != nil {
+		g.InitialStakers = dec.InitialStakers
+	}
 	if dec.Number != nil {
 		g.Number = uint64(*dec.Number)
 	}
@@ -30,16 +30,19 @@ import (
 	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/crypto/bn256"
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/params"
 	"github.com/wanchain/go-wanchain/rlp"
 	"math/big"
+	"sort"
 	"strings"
 )
 
 //go:generate gencodec -type Genesis -field-override genesisSpecMarshaling -out gen_genesis.go
 //go:generate gencodec -type GenesisAccount -field-override genesisAccountMarshaling -out gen_genesis_account.go
+//go:generate gencodec -type GenesisStaker -field-override genesisStakerMarshaling -out gen_genesis_staker.go
 
 var errGenesisNoConfig = errors.New("genesis has no chain configuration")
 
@@ -56,6 +59,10 @@ type Genesis struct {
 	Coinbase   common.Address      `json:"coinbase"`
 	Alloc      GenesisAlloc        `json:"alloc"      gencodec:"required"`
 
+	// InitialStakers are registered in the pos staking contract at genesis,
+	// the epoch leaders following epoch 0 are selected among them.
+	InitialStakers []GenesisStaker `json:"initialStakers,omitempty"`
+
 	// These fields are used for consensus tests. Please don't use them
 	// in actual genesis blocks.
 	Number     uint64      `json:"number"`
@@ -84,6 +91,17 @@ type GenesisAccountStaking struct {
 	Bn256pk []byte   `json:"bn256pk"`
 }
 
+// GenesisStaker is a validator staking in the pos staking contract from the
+// genesis block on.
+type GenesisStaker struct {
+	S256pk     []byte         `json:"s256pk"     gencodec:"required"`
+	Bn256pk    []byte         `json:"bn256pk"    gencodec:"required"`
+	Amount     *big.Int       `json:"amount"     gencodec:"required"`
+	LockEpochs uint64         `json:"lockEpochs"` // 0 = never expires
+	FeeRate    uint64         `json:"feeRate"`
+	From       common.Address `json:"from,omitempty"` // Owner of the stake, defaults to the staker address
+}
+
 // GenesisAccount is an account in the state of the genesis block.
 type GenesisAccount struct {
 	Code       []byte                      `json:"code,omitempty"`
@@ -114,6 +132,14 @@ type genesisAccountMarshaling struct {
 	PrivateKey hexutil.Bytes
 }
 
+type genesisStakerMarshaling struct {
+	S256pk     hexutil.Bytes
+	Bn256pk    hexutil.Bytes
+	Amount     *math.HexOrDecimal256
+	LockEpochs math.HexOrDecimal64
+	FeeRate    math.HexOrDecimal64
+}
+
 // storageJSON represents a 256 bit byte array, but allows less than 256 bits when
 // unmarshaling from hex.
 type storageJSON common.Hash
@@ -163,6 +189,11 @@ func SetupGenesisBlock(db ethdb.Database, genesis *Genesis) (*params.ChainConfig
 	if genesis != nil && genesis.Config == nil {
 		return params.AllProtocolChanges, common.Hash{}, errGenesisNoConfig
 	}
+	if genesis != nil && genesis.Config.OTA != nil {
+		if err := genesis.Config.OTA.Validate(); err != nil {
+			return genesis.Config, common.Hash{}, err
+		}
+	}
 
 	// Just commit the new block if there is no stored genesis block.
 	stored := GetCanonicalHash(db, 0)
@@ -177,11 +208,17 @@ func SetupGenesisBlock(db ethdb.Database, genesis *Genesis) (*params.ChainConfig
 			log.Info("Writing custom genesis block")
 		}
 		block, err := genesis.Commit(db)
-		return genesis.Config, block.Hash(), err
+		if err != nil {
+			return genesis.Config, common.Hash{}, err
+		}
+		return genesis.Config, block.Hash(), nil
 	}
 
 	// Check whether the genesis block is already written.
 	if genesis != nil {
+		if err := genesis.validateStakers(); err != nil {
+			return genesis.Config, common.Hash{}, err
+		}
 		block, _ := genesis.ToBlock()
 		hash := block.Hash()
 		if hash != stored {
@@ -246,38 +283,16 @@ func (g *Genesis) ToBlock() (*types.Block, *state.StateDB) {
 		statedb.AddBalance(addr, account.Balance)
 		statedb.SetCode(addr, account.Code)
 		statedb.SetNonce(addr, account.Nonce)
-
-		if account.Staking.S256pk != nil {
-			pub := crypto.ToECDSAPub(account.Staking.S256pk)
-			if nil == pub {
-				panic("Invalid genesis.")
-			}
-			secAddr := crypto.PubkeyToAddress(*pub)
-			staker := &vm.StakerInfo{
-				PubSec256:   account.Staking.S256pk,
-				PubBn256:    account.Staking.Bn256pk,
-				Amount:      account.Staking.Amount,
-				From:		 secAddr,
-				Address:	 secAddr,
-				LockEpochs:    0, // never expired
-				StakingEpoch: uint64(0),
-				FeeRate:	 uint64(100),
-			}
-
-			infoArray, err := rlp.EncodeToBytes(staker)
-			if err != nil {
-				panic(err)
-			}
-			addr := crypto.PubkeyToAddress(*crypto.ToECDSAPub(account.Staking.S256pk))
-			addrHash := common.BytesToHash(addr[:])
-			statedb.AddBalance(vm.WanCscPrecompileAddr,staker.Amount)
-
-			statedb.SetStateByteArray(vm.StakersInfoAddr, addrHash, infoArray)
-		}
 		for key, value := range account.Storage {
 			statedb.SetState(addr, key, value)
 		}
 	}
+	// The stakers are checked by validateStakers before committing
+	for _, staker := range g.stakers() {
+		if err := staker.commit(statedb); err != nil {
+			panic(fmt.Sprintf("invalid %s: %v", staker.name, err))
+		}
+	}
 	root := statedb.IntermediateRoot(false)
 	head := &types.Header{
 		Number:     new(big.Int).SetUint64(g.Number),
@@ -301,9 +316,120 @@ func (g *Genesis) ToBlock() (*types.Block, *state.StateDB) {
 	return types.NewBlock(head, nil, nil, nil), statedb
 }
 
+// validate checks that the staking records written at genesis are sound.
+func (s *GenesisStaker) validate() error {
+	if len(s.S256pk) == 0 || crypto.ToECDSAPub(s.S256pk) == nil {
+		return errors.New("invalid s256pk")
+	}
+	var g1 bn256.G1
+	if _, err := g1.Unmarshal(s.Bn256pk); err != nil {
+		return errors.New("invalid bn256pk")
+	}
+	if s.Amount == nil || s.Amount.Sign() <= 0 {
+		return errors.New("staking amount must be positive")
+	}
+	if s.LockEpochs != 0 && (s.LockEpochs < vm.PSMinEpochNum || s.LockEpochs > vm.PSMaxEpochNum) {
+		return fmt.Errorf("lock epochs %d out of range [%d, %d]", s.LockEpochs, vm.PSMinEpochNum, vm.PSMaxEpochNum)
+	}
+	if s.FeeRate > vm.PSMaxFeeRate {
+		return fmt.Errorf("fee rate %d above %d", s.FeeRate, vm.PSMaxFeeRate)
+	}
+	return nil
+}
+
+// namedStaker is a genesis staker with the name it is reported by.
+type namedStaker struct {
+	GenesisStaker
+	name string
+}
+
+// stakers returns the stakers of the alloc accounts, sorted by address, followed
+// by the initial stakers.
+func (g *Genesis) stakers() []namedStaker {
+	var addrs []common.Address
+	for addr, account := range g.Alloc {
+		if account.Staking.S256pk != nil {
+			addrs = append(addrs, addr)
+		}
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+
+	stakers := make([]namedStaker, 0, len(addrs)+len(g.InitialStakers))
+	for _, addr := range addrs {
+		staking := g.Alloc[addr].Staking
+		stakers = append(stakers, namedStaker{
+			GenesisStaker: GenesisStaker{
+				S256pk:  staking.S256pk,
+				Bn256pk: staking.Bn256pk,
+				Amount:  staking.Amount,
+				FeeRate: vm.PSMaxFeeRate,
+			},
+			name: fmt.Sprintf("genesis staker of alloc %x", addr),
+		})
+	}
+	for i, staker := range g.InitialStakers {
+		stakers = append(stakers, namedStaker{GenesisStaker: staker, name: fmt.Sprintf("genesis staker %d", i)})
+	}
+	return stakers
+}
+
+// validateStakers checks every staker of the alloc accounts and of the initial
+// stakers, and that none is registered twice across both.
+func (g *Genesis) validateStakers() error {
+	seen := make(map[common.Address]string)
+	for _, staker := range g.stakers() {
+		if err := staker.validate(); err != nil {
+			return fmt.Errorf("invalid %s: %v", staker.name, err)
+		}
+		addr := crypto.PubkeyToAddress(*crypto.ToECDSAPub(staker.S256pk))
+		if first, ok := seen[addr]; ok {
+			return fmt.Errorf("invalid %s: %x already registered by %s", staker.name, addr, first)
+		}
+		seen[addr] = staker.name
+	}
+	return nil
+}
+
+// commit writes the staker into the pos staking contract storage and locks
+// its amount in the contract, as a stakeIn transaction in epoch 0 would.
+func (s *GenesisStaker) commit(statedb *state.StateDB) error {
+	if err := s.validate(); err != nil {
+		return err
+	}
+	secAddr := crypto.PubkeyToAddress(*crypto.ToECDSAPub(s.S256pk))
+	from := s.From
+	if from == (common.Address{}) {
+		from = secAddr
+	}
+	info := &vm.StakerInfo{
+		Address:      secAddr,
+		PubSec256:    s.S256pk,
+		PubBn256:     s.Bn256pk,
+		Amount:       s.Amount,
+		LockEpochs:   s.LockEpochs,
+		From:         from,
+		StakingEpoch: 0,
+		FeeRate:      s.FeeRate,
+	}
+	infoBytes, err := rlp.EncodeToBytes(info)
+	if err != nil {
+		return err
+	}
+	key := vm.GetStakeInKeyHash(secAddr)
+	if len(statedb.GetStateByteArray(vm.StakersInfoAddr, key)) != 0 {
+		return fmt.Errorf("staker %x registered twice", secAddr)
+	}
+	statedb.AddBalance(vm.WanCscPrecompileAddr, s.Amount)
+	statedb.SetStateByteArray(vm.StakersInfoAddr, key, infoBytes)
+	return nil
+}
+
 // Commit writes the block and state of a genesis specification to the database.
 // The block is committed as the canonical head block.
 func (g *Genesis) Commit(db ethdb.Database) (*types.Block, error) {
+	if err := g.validateStakers(); err != nil {
+		return nil, err
+	}
 	block, statedb := g.ToBlock()
 	if block.Number().Sign() != 0 {
 		return nil, fmt.Errorf("can't commit genesis block with number > 0")
@@ -430,6 +556,28 @@ func DevGenesisBlock() *Genesis {
 	}
 }
 
+// DevPosGenesisBlock returns the 'gwan --dev.pos' genesis block. The given
+// validator is funded, staked and signs epoch 0.
+func DevPosGenesisBlock(validator common.Address, s256pk, bn256pk []byte) *Genesis {
+	stake := new(big.Int).Mul(big.NewInt(100000), big.NewInt(params.Wan))
+	return &Genesis{
+		Config:     params.DevPosChainConfig,
+		ExtraData:  s256pk,
+		GasLimit:   0x47b760,
+		Difficulty: big.NewInt(1),
+		Alloc: GenesisAlloc{
+			validator: {
+				Balance: new(big.Int).Mul(big.NewInt(10000000), big.NewInt(params.Wan)),
+				Staking: GenesisAccountStaking{
+					Amount:  stake,
+					S256pk:  s256pk,
+					Bn256pk: bn256pk,
+				},
+			},
+		},
+	}
+}
+
 func decodePrealloc(data string) GenesisAlloc {
 	var p []struct{ Addr, Balance *big.Int }
 	if err := rlp.NewStream(strings.NewReader(data), 0).Decode(&p); err != nil {
@@ -17,6 +17,8 @@
 package core
 
 import (
+	"bytes"
+	"encoding/json"
 	"math/big"
 	"reflect"
 	"testing"
@@ -25,8 +27,13 @@ import (
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/crypto/bn256"
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/rlp"
 )
 
 func TestDefaultGenesisBlock(t *testing.T) {
@@ -184,3 +191,141 @@ func TestSetupGenesis(t *testing.T) {
 		}
 	}
 }
+
+func TestDevPosGenesisBlock(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	s256pk := crypto.FromECDSAPub(&key.PublicKey)
+	bn256pk := new(bn256.G1).ScalarBaseMult(big.NewInt(7)).Marshal()
+
+	db, _ := ethdb.NewMemDatabase()
+	block := DevPosGenesisBlock(addr, s256pk, bn256pk).MustCommit(db)
+	if !bytes.Equal(block.Extra(), s256pk) {
+		t.Fatalf("genesis extra is not the validator key: %x", block.Extra())
+	}
+	if err := params.DevPosChainConfig.Pos.Validate(); err != nil {
+		t.Fatalf("invalid dev pos config: %v", err)
+	}
+	statedb, err := state.New(block.Root(), state.NewDatabase(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var staker vm.StakerInfo
+	data := statedb.GetStateByteArray(vm.StakersInfoAddr, common.BytesToHash(addr[:]))
+	if err := rlp.DecodeBytes(data, &staker); err != nil {
+		t.Fatalf("validator not staked: %v", err)
+	}
+	if staker.Address != addr || !bytes.Equal(staker.PubBn256, bn256pk) {
+		t.Fatalf("staker mismatch: %+v", staker)
+	}
+	if statedb.GetBalance(vm.WanCscPrecompileAddr).Cmp(staker.Amount) != 0 {
+		t.Fatalf("stake not locked in the staking contract")
+	}
+}
+
+func TestGenesisInitialStakers(t *testing.T) {
+	var stakers []GenesisStaker
+	for i := 0; i < 3; i++ {
+		key, _ := crypto.GenerateKey()
+		stakers = append(stakers, GenesisStaker{
+			S256pk:     crypto.FromECDSAPub(&key.PublicKey),
+			Bn256pk:    new(bn256.G1).ScalarBaseMult(big.NewInt(int64(i + 1))).Marshal(),
+			Amount:     new(big.Int).Mul(big.NewInt(int64(100000*(i+1))), big.NewInt(params.Wan)),
+			LockEpochs: uint64(10 * i),
+			FeeRate:    uint64(10 * i),
+		})
+	}
+	genesis := DevPosGenesisBlock(common.Address{1}, stakers[0].S256pk, stakers[0].Bn256pk)
+	genesis.Alloc = GenesisAlloc{common.Address{1}: {Balance: big.NewInt(1)}}
+	genesis.InitialStakers = stakers
+
+	// the section survives a json round trip
+	blob, err := json.Marshal(genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := new(Genesis)
+	if err := json.Unmarshal(blob, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.InitialStakers, stakers) {
+		t.Fatalf("initial stakers mismatch after json round trip:\nhave %v\nwant %v", spew.Sdump(decoded.InitialStakers), spew.Sdump(stakers))
+	}
+
+	db, _ := ethdb.NewMemDatabase()
+	block, err := decoded.Commit(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statedb, _ := state.New(block.Root(), state.NewDatabase(db))
+
+	snap := vm.GetStakersSnap(statedb)
+	if len(snap) != len(stakers) {
+		t.Fatalf("staker count mismatch: have %d, want %d", len(snap), len(stakers))
+	}
+	total := new(big.Int)
+	for _, want := range stakers {
+		addr := crypto.PubkeyToAddress(*crypto.ToECDSAPub(want.S256pk))
+		var have vm.StakerInfo
+		if err := rlp.DecodeBytes(statedb.GetStateByteArray(vm.StakersInfoAddr, vm.GetStakeInKeyHash(addr)), &have); err != nil {
+			t.Fatalf("staker %x not registered: %v", addr, err)
+		}
+		if have.Address != addr || have.From != addr || have.Amount.Cmp(want.Amount) != 0 ||
+			have.LockEpochs != want.LockEpochs || have.FeeRate != want.FeeRate || !bytes.Equal(have.PubBn256, want.Bn256pk) {
+			t.Errorf("staker %x mismatch: %+v", addr, have)
+		}
+		total.Add(total, want.Amount)
+	}
+	if statedb.GetBalance(vm.WanCscPrecompileAddr).Cmp(total) != 0 {
+		t.Errorf("locked stake mismatch: have %v, want %v", statedb.GetBalance(vm.WanCscPrecompileAddr), total)
+	}
+}
+
+func TestGenesisInitialStakersValidation(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	valid := GenesisStaker{
+		S256pk:  crypto.FromECDSAPub(&key.PublicKey),
+		Bn256pk: new(bn256.G1).ScalarBaseMult(big.NewInt(1)).Marshal(),
+		Amount:  big.NewInt(1),
+	}
+	tests := []func(s *GenesisStaker){
+		func(s *GenesisStaker) { s.S256pk = []byte{1, 2, 3} },
+		func(s *GenesisStaker) { s.Bn256pk = []byte{1, 2, 3} },
+		func(s *GenesisStaker) { s.Amount = new(big.Int) },
+		func(s *GenesisStaker) { s.LockEpochs = vm.PSMinEpochNum - 1 },
+		func(s *GenesisStaker) { s.FeeRate = vm.PSMaxFeeRate + 1 },
+	}
+	for i, mutate := range tests {
+		staker := valid
+		mutate(&staker)
+		genesis := &Genesis{Config: params.DevPosChainConfig, InitialStakers: []GenesisStaker{staker}}
+		db, _ := ethdb.NewMemDatabase()
+		if _, err := genesis.Commit(db); err == nil {
+			t.Errorf("test %d: invalid staker accepted", i)
+		}
+	}
+	// registering the same staker twice is rejected as well
+	genesis := &Genesis{Config: params.DevPosChainConfig, InitialStakers: []GenesisStaker{valid, valid}}
+	db, _ := ethdb.NewMemDatabase()
+	if _, err := genesis.Commit(db); err == nil {
+		t.Error("duplicate staker accepted")
+	}
+	// so is a staker registered both by an alloc account and as initial staker
+	genesis = &Genesis{
+		Config: params.DevPosChainConfig,
+		Alloc: GenesisAlloc{
+			common.Address{1}: {
+				Balance: new(big.Int),
+				Staking: GenesisAccountStaking{S256pk: valid.S256pk, Bn256pk: valid.Bn256pk, Amount: valid.Amount},
+			},
+		},
+		InitialStakers: []GenesisStaker{valid},
+	}
+	db, _ = ethdb.NewMemDatabase()
+	if _, err := genesis.Commit(db); err == nil {
+		t.Error("staker duplicated across alloc and initial stakers accepted")
+	}
+	if _, _, err := SetupGenesisBlock(db, genesis); err == nil {
+		t.Error("staker duplicated across alloc and initial stakers set up")
+	}
+}
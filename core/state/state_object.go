@@ -159,6 +159,7 @@ func (self *stateObject) setError(err error) {
 	if self.dbErr == nil {
 		self.dbErr = err
 	}
+	self.db.setError(err)
 }
 
 func (self *stateObject) markSuicided() {
@@ -226,7 +227,11 @@ func (self *stateObject) GetStateByteArray(db Database, key common.Hash) []byte
 	}
 	// Load from DB in case it is missing.
 	value, err := self.getTrie(db).TryGet(key[:])
-	if err == nil && len(value) != 0 {
+	if err != nil {
+		self.setError(err)
+		return nil
+	}
+	if len(value) != 0 {
 		self.cachedStorageByteArray[key] = value
 	}
 	return value
@@ -488,6 +488,9 @@ func (db *StateDB) ForEachStorageByteArray(addr common.Address, cb func(key comm
 			}
 		}
 	}
+	if it.Err != nil {
+		db.setError(it.Err)
+	}
 }
 
 // Copy creates a deep, independent copy of the state.
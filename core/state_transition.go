@@ -372,9 +372,11 @@ type PrivacyTxInfo struct {
 	GasLeftSubRingSign uint64
 }
 
-func FetchPrivacyTxInfo(stateDB vm.StateDB, hashInput []byte, in []byte, gasPrice *big.Int) (info *PrivacyTxInfo, err error) {
+// UnpackPrivacyTxData returns the ring signature spending the stamp of a
+// privacy transaction and the call data it carries.
+func UnpackPrivacyTxData(in []byte) (ringSignedData string, callData []byte, err error) {
 	if len(in) < 4 {
-		return nil, vm.ErrInvalidRingSigned
+		return "", nil, vm.ErrInvalidRingSigned
 	}
 
 	var TxDataWithRing struct {
@@ -383,26 +385,37 @@ func FetchPrivacyTxInfo(stateDB vm.StateDB, hashInput []byte, in []byte, gasPric
 	}
 
 	err = utilAbi.Unpack(&TxDataWithRing, "combine", in[4:])
+	if err != nil {
+		return "", nil, err
+	}
+
+	return TxDataWithRing.RingSignedData, TxDataWithRing.CxtCallParams, nil
+}
+
+func FetchPrivacyTxInfo(stateDB vm.StateDB, hashInput []byte, in []byte, gasPrice *big.Int) (info *PrivacyTxInfo, err error) {
+	ringSignedData, callData, err := UnpackPrivacyTxData(in)
 	if err != nil {
 		return
 	}
 
-	ringSignInfo, err := vm.FetchRingSignInfo(stateDB, hashInput, TxDataWithRing.RingSignedData)
+	ringSignInfo, err := vm.FetchRingSignInfo(stateDB, hashInput, ringSignedData)
 	if err != nil {
 		return
 	}
 
+	return newPrivacyTxInfo(ringSignInfo, callData, gasPrice)
+}
+
+// newPrivacyTxInfo returns the info of a privacy transaction spending the
+// stamp of a verified ring signature, whose OTABalance is set.
+func newPrivacyTxInfo(ringSignInfo *vm.RingSignInfo, callData []byte, gasPrice *big.Int) (info *PrivacyTxInfo, err error) {
 	stampGasBigInt := new(big.Int).Div(ringSignInfo.OTABalance, gasPrice)
 	if stampGasBigInt.BitLen() > 64 {
 		return nil, vm.ErrOutOfGas
 	}
 
 	StampTotalGas := stampGasBigInt.Uint64()
-	mixLen := len(ringSignInfo.PublicKeys)
-	ringSigDiffRequiredGas := params.RequiredGasPerMixPub * (uint64(mixLen))
-
-	// ringsign compute gas + ota image key store setting gas
-	preSubGas := ringSigDiffRequiredGas + params.SstoreSetGas
+	preSubGas := privacyTxRingGas(len(ringSignInfo.PublicKeys))
 	if StampTotalGas < preSubGas {
 		return nil, vm.ErrOutOfGas
 	}
@@ -413,7 +426,7 @@ func FetchPrivacyTxInfo(stateDB vm.StateDB, hashInput []byte, in []byte, gasPric
 		ringSignInfo.KeyImage,
 		ringSignInfo.W_Random,
 		ringSignInfo.Q_Random,
-		TxDataWithRing.CxtCallParams[:],
+		callData[:],
 		ringSignInfo.OTABalance,
 		StampTotalGas,
 		GasLeftSubRingSign,
@@ -422,8 +435,38 @@ func FetchPrivacyTxInfo(stateDB vm.StateDB, hashInput []byte, in []byte, gasPric
 	return
 }
 
+// privacyTxRingGas returns the gas a privacy transaction pays from its stamp
+// before the intrinsic gas, for a stamp ring of ringSize OTAs.
+func privacyTxRingGas(ringSize int) uint64 {
+	// ringsign compute gas + ota image key store setting gas
+	return params.RequiredGasPerMixPub*uint64(ringSize) + params.SstoreSetGas
+}
+
+// PrivacyTxStampGas returns the gas a stamp must buy at least to pay for a
+// privacy transaction whose stamp ring has ringSize OTAs, whose data costs
+// intrGas and whose call needs evmGas. It is the gas PreProcessPrivacyTx leaves
+// to the call plus the gas it charges.
+func PrivacyTxStampGas(ringSize int, intrGas uint64, evmGas uint64) uint64 {
+	return privacyTxRingGas(ringSize) + intrGas + evmGas
+}
+
 func ValidPrivacyTx(stateDB vm.StateDB, hashInput []byte, in []byte, gasPrice *big.Int,
 	intrGas *big.Int, txValue *big.Int, gasLimit *big.Int) error {
+	if err := validPrivacyTxFields(gasPrice, intrGas, txValue); err != nil {
+		return err
+	}
+
+	info, err := FetchPrivacyTxInfo(stateDB, hashInput, in, gasPrice)
+	if err != nil {
+		return err
+	}
+
+	return validPrivacyTxInfo(stateDB, info, intrGas, gasLimit)
+}
+
+// validPrivacyTxFields checks the fields of a privacy transaction that do not
+// depend on its ring signature.
+func validPrivacyTxFields(gasPrice *big.Int, intrGas *big.Int, txValue *big.Int) error {
 	if intrGas == nil || intrGas.BitLen() > 64 {
 		return vm.ErrOutOfGas
 	}
@@ -436,11 +479,12 @@ func ValidPrivacyTx(stateDB vm.StateDB, hashInput []byte, in []byte, gasPrice *b
 		return vm.ErrInvalidGasPrice
 	}
 
-	info, err := FetchPrivacyTxInfo(stateDB, hashInput, in, gasPrice)
-	if err != nil {
-		return err
-	}
+	return nil
+}
 
+// validPrivacyTxInfo checks that the stamp of a privacy transaction is unspent
+// and pays for its gas.
+func validPrivacyTxInfo(stateDB vm.StateDB, info *PrivacyTxInfo, intrGas *big.Int, gasLimit *big.Int) error {
 	if info.StampTotalGas > gasLimit.Uint64() {
 		return ErrGasLimit
 	}
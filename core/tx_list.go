@@ -27,6 +27,7 @@ import (
 	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/pos/util"
 )
 
 // nonceHeap is a heap.Interface implementation over 64bit unsigned integers for
@@ -316,20 +317,16 @@ func (l *txList) Filter(costLimit, gasLimit *big.Int) (types.Transactions, types
 	return removed, invalids
 }
 
-// InvalidPrivacyTx remove invalidate privacy transactions
-func (l *txList) InvalidPrivacyTx(stateDB vm.StateDB, signer types.Signer, gasLimit *big.Int) types.Transactions {
+// InvalidPrivacyTx remove invalidate privacy transactions, reusing the ring
+// signature verifications cached in ringSigs
+func (l *txList) InvalidPrivacyTx(ringSigs *ringSigCache, stateDB vm.StateDB, signer types.Signer, gasLimit *big.Int) types.Transactions {
 	removed := l.txs.Filter(func(tx *types.Transaction) bool {
 		if !types.IsPrivacyTransaction(tx.Txtype()){
 			return false
 		}
 
-		from, err := types.Sender(signer, tx)
-		if err != nil {
-			return true
-		}
-
 		intrGas := IntrinsicGas(tx.Data(), tx.To(), true)
-		err = ValidPrivacyTx(stateDB, from.Bytes(), tx.Data(), tx.GasPrice(), intrGas, tx.Value(), gasLimit)
+		err := ringSigs.validPrivacyTx(stateDB, signer, tx, intrGas, gasLimit)
 
 		return err != nil
 	})
@@ -352,7 +349,7 @@ func (l *txList) InvalidPrivacyTx(stateDB vm.StateDB, signer types.Signer, gasLi
 }
 
 // InvalidPosTx remove invalidate pos transactions
-func (l *txList) InvalidPosRBTx(stateDB vm.StateDB, signer types.Signer) types.Transactions {
+func (l *txList) InvalidPosRBTx(stateDB vm.StateDB, signer types.Signer, epocher util.SelectLead, clock *util.SlotClock) types.Transactions {
 	removed := l.txs.Filter(func(tx *types.Transaction) bool {
 		if !types.IsPosTransaction(tx.Txtype()) || (*tx.To()) != vm.GetRBAddress() {
 			return false
@@ -363,7 +360,7 @@ func (l *txList) InvalidPosRBTx(stateDB vm.StateDB, signer types.Signer) types.T
 			return true
 		}
 
-		err = vm.ValidPosRBTx(stateDB, from, tx.Data())
+		err = vm.ValidPosRBTx(stateDB, epocher, clock, from, tx.Data())
 		return err != nil
 	})
 
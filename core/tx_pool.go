@@ -34,6 +34,7 @@ import (
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/metrics"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/util"
 	"gopkg.in/karalabe/cookiejar.v2/collections/prque"
 )
 
@@ -83,6 +84,10 @@ var (
 
 	// ErrInvalidTxType is returned if input transaction's type is unknown.
 	ErrInvalidTxType = errors.New("invalid transaction type")
+
+	// ErrPosInactive is returned if a transaction calls a pos contract before
+	// the pos fork block.
+	ErrPosInactive = errors.New("pos is not active yet")
 )
 
 var (
@@ -203,6 +208,13 @@ type TxPool struct {
 	all     map[common.Hash]*types.Transaction // All transactions to allow lookups
 	priced  *txPricedList                      // All transactions sorted by price
 
+	ringSigs *ringSigCache // Ring signature verifications of the privacy transactions
+
+	next        *big.Int                                  // Number of the block the pool prepares transactions for
+	posActive   bool                                      // Whether pos is active in the next block
+	precompiles map[common.Address]vm.PrecompiledContract // Pre-compiled contracts of the next block
+	epocher     util.SelectLead                           // Epoch leader selection the pos transactions are checked against
+
 	wg sync.WaitGroup // for shutdown sync
 
 	homestead bool
@@ -229,6 +241,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 	}
 	pool.locals = newAccountSet(pool.signer)
 	pool.priced = newTxPricedList(&pool.all)
+	pool.ringSigs = newRingSigCache()
 	pool.reset(nil, chain.CurrentBlock().Header())
 
 	// If local transactions and journaling is enabled, load from disk
@@ -404,10 +417,19 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 		log.Error("Failed to reset txpool state", "err", err)
 		return
 	}
+	// The OTAs stored in the new state may differ unless it extends the old one
+	if oldHead != nil && oldHead.Hash() != newHead.ParentHash {
+		pool.ringSigs.resetOTAs()
+	}
 	pool.currentState = statedb
 	pool.pendingState = state.ManageState(statedb)
 	pool.currentMaxGas = newHead.GasLimit
 
+	// The pre-compiled contracts only change at the pos fork
+	pool.next = new(big.Int).Add(newHead.Number, common.Big1)
+	pool.posActive = pool.chainconfig.IsPosActive(pool.next)
+	pool.precompiles = vm.ActivePrecompiledContracts(pool.chainconfig, pool.next)
+
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
 	pool.addTxsLocked(reinject, false)
@@ -470,6 +492,15 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 	log.Info("Transaction pool price threshold updated", "price", price)
 }
 
+// SetEpocher sets the epoch leader selection of the node, the senders of the
+// pos transactions are checked against its leaders.
+func (pool *TxPool) SetEpocher(epocher util.SelectLead) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.epocher = epocher
+}
+
 // State returns the virtual managed state of the transaction pool.
 func (pool *TxPool) State() *state.ManagedState {
 	pool.mu.RLock()
@@ -604,7 +635,7 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 		}
 
 	} else {
-		err := ValidPrivacyTx(pool.currentState, from.Bytes(), tx.Data(), tx.GasPrice(), intrGas, tx.Value(), pool.currentMaxGas)
+		err := pool.ringSigs.validPrivacyTx(pool.currentState, pool.signer, tx, intrGas, pool.currentMaxGas)
 		if err != nil {
 			return err
 		}
@@ -612,8 +643,14 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 
 	// Check precompile contracts transactions validation
 	if tx.To() != nil {
-		if p := vm.PrecompiledContractsByzantium[*tx.To()]; p != nil {
-			if err = p.ValidTx(pool.currentState, pool.signer, tx); err != nil {
+		if !pool.posActive && (isPosType || vm.IsPosOnlyPrecompiledAddr(tx.To())) {
+			return ErrPosInactive
+		}
+		if p := pool.precompiles[*tx.To()]; p != nil {
+			if err = p.ValidTx(pool.currentState, pool.signer, pool.epocher, tx); err != nil {
+				return err
+			}
+			if err = vm.ValidOTADenomination(pool.chainconfig, pool.next, *tx.To(), tx.Data()); err != nil {
 				return err
 			}
 		}
@@ -825,6 +862,9 @@ func (pool *TxPool) addTx(tx *types.Transaction, local bool) error {
 
 // addTxs attempts to queue a batch of transactions if they are valid.
 func (pool *TxPool) addTxs(txs []*types.Transaction, local bool) error {
+	// Verify the ring signatures concurrently before taking the lock
+	pool.ringSigs.verifyBatch(pool.signer, txs)
+
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
@@ -944,7 +984,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		}
 
 		// Remove all invalid privacy transactions
-		invalidPrivacy := list.InvalidPrivacyTx(pool.currentState, pool.signer, pool.currentMaxGas)
+		invalidPrivacy := list.InvalidPrivacyTx(pool.ringSigs, pool.currentState, pool.signer, pool.currentMaxGas)
 		for _, tx := range invalidPrivacy {
 			hash := tx.Hash()
 			log.Trace("Removed invalid privacy transaction", "hash", hash)
@@ -954,7 +994,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		}
 
 		// Remove all invalid pos transactions
-		invalidPos := list.InvalidPosRBTx(pool.currentState, pool.signer)
+		invalidPos := list.InvalidPosRBTx(pool.currentState, pool.signer, pool.epocher, util.ChainSlotClock(pool.chain))
 		for _, tx := range invalidPos {
 			hash := tx.Hash()
 			log.Trace("Removed invalid pos transaction", "hash", hash)
@@ -1130,7 +1170,7 @@ func (pool *TxPool) demoteUnexecutables() {
 		}
 
 		// Remove all invalid privacy transactions
-		invalidPrivacy := list.InvalidPrivacyTx(pool.currentState, pool.signer, pool.currentMaxGas)
+		invalidPrivacy := list.InvalidPrivacyTx(pool.ringSigs, pool.currentState, pool.signer, pool.currentMaxGas)
 		for _, tx := range invalidPrivacy {
 			hash := tx.Hash()
 			log.Trace("Removed invalid privacy transaction", "hash", hash)
@@ -1140,7 +1180,7 @@ func (pool *TxPool) demoteUnexecutables() {
 		}
 
 		// Remove all invalid pos transactions
-		invalidPos := list.InvalidPosRBTx(pool.currentState, pool.signer)
+		invalidPos := list.InvalidPosRBTx(pool.currentState, pool.signer, pool.epocher, util.ChainSlotClock(pool.chain))
 		for _, tx := range invalidPos {
 			hash := tx.Hash()
 			log.Trace("Removed invalid pos transaction", "hash", hash)
@@ -0,0 +1,181 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"runtime"
+	"sync"
+
+	"github.com/hashicorp/golang-lru"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+)
+
+// ringSigCacheLimit is the number of privacy transactions whose ring signature
+// verification is cached, a bit more than the default pool capacity.
+const ringSigCacheLimit = 8192
+
+// ringSigResult is the cached verification of the ring signature of a privacy
+// transaction. The decoding and the signature verification only depend on the
+// transaction, whose hash commits to the ring and to the sender it signs, so
+// they are kept as long as the transaction is cached. The OTAs of the ring are
+// looked up again only once the OTAs stored in the state may have changed.
+// Cached results are shared with the concurrent verifications and never
+// modified, a new lookup of the OTAs caches a new result.
+type ringSigResult struct {
+	ring     *vm.RingSignInfo // Decoded ring signature, without OTA balance
+	callData []byte           // Call data carried by the transaction
+	err      error            // Decoding or verification error, if any
+
+	otaEpoch   uint64   // OTA epoch the ring OTAs were last found in
+	otaBalance *big.Int // Balance of the ring OTAs in that epoch
+}
+
+// ringSigCache caches the ring signature verifications of the privacy
+// transactions of a pool, so that revalidating them after every new head
+// only checks the key image spent.
+//
+// OTAs are only ever added to the state of a chain, with a fixed balance, so
+// the OTAs of a ring found in the state stay valid as long as the chain is
+// extended. Missing OTAs are looked up again on every validation, as they may
+// be bought in a later block. The OTA epoch is bumped when the pool is reset to
+// a head that does not extend the previous one, and the rings are then looked
+// up again. Key images are also spent by refunds made from contracts, which the
+// pool cannot follow, so the key image of a transaction is looked up on every
+// validation.
+type ringSigCache struct {
+	results  *lru.Cache // Verification results by transaction hash
+	otaEpoch uint64     // Epoch of the OTAs stored in the pool state
+	workers  int        // Number of transactions verified concurrently
+
+	otaBalance func(stateDB vm.StateDB, publicKeys []*ecdsa.PublicKey) (*big.Int, error) // Looks up the ring OTAs
+}
+
+func newRingSigCache() *ringSigCache {
+	results, _ := lru.New(ringSigCacheLimit)
+	return &ringSigCache{
+		results:    results,
+		otaEpoch:   1,
+		workers:    runtime.NumCPU(),
+		otaBalance: vm.RingOTABalance,
+	}
+}
+
+// resetOTAs marks the ring OTAs looked up so far as stale. It must be called
+// with the pool lock held, whenever the pool state is not a descendant of the
+// previous one.
+func (c *ringSigCache) resetOTAs() {
+	c.otaEpoch++
+}
+
+// verify decodes and verifies the ring signature of a privacy transaction,
+// caching the result. It does not access the state and is safe for concurrent
+// use.
+func (c *ringSigCache) verify(signer types.Signer, tx *types.Transaction) *ringSigResult {
+	if cached, ok := c.results.Get(tx.Hash()); ok {
+		return cached.(*ringSigResult)
+	}
+	result := new(ringSigResult)
+
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		result.err = ErrInvalidSender
+		return result
+	}
+	ringSignedData, callData, err := UnpackPrivacyTxData(tx.Data())
+	if err != nil {
+		result.err = err
+	} else {
+		result.callData = callData
+		result.ring, result.err = decodeRingSign(from.Bytes(), ringSignedData)
+	}
+
+	c.results.Add(tx.Hash(), result)
+	return result
+}
+
+func decodeRingSign(hashInput []byte, ringSignedData string) (*vm.RingSignInfo, error) {
+	ring := new(vm.RingSignInfo)
+
+	var err error
+	err, ring.PublicKeys, ring.KeyImage, ring.W_Random, ring.Q_Random = vm.DecodeRingSignOut(ringSignedData)
+	if err != nil {
+		return nil, err
+	}
+	if !ring.Verify(hashInput) {
+		return nil, vm.ErrInvalidRingSigned
+	}
+	return ring, nil
+}
+
+// verifyBatch verifies the ring signatures of the privacy transactions of a
+// batch concurrently, so that their validation under the pool lock hits the
+// cache.
+func (c *ringSigCache) verifyBatch(signer types.Signer, txs []*types.Transaction) {
+	var privacyTxs []*types.Transaction
+	for _, tx := range txs {
+		if types.IsPrivacyTransaction(tx.Txtype()) && !c.results.Contains(tx.Hash()) {
+			privacyTxs = append(privacyTxs, tx)
+		}
+	}
+	if len(privacyTxs) == 0 {
+		return
+	}
+
+	workers := c.workers
+	if workers > len(privacyTxs) {
+		workers = len(privacyTxs)
+	}
+	tasks := make(chan *types.Transaction, len(privacyTxs))
+	for _, tx := range privacyTxs {
+		tasks <- tx
+	}
+	close(tasks)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for tx := range tasks {
+				c.verify(signer, tx)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// validPrivacyTx is the cached equivalent of ValidPrivacyTx. It must be called
+// with the pool lock held, stateDB being the pool state.
+func (c *ringSigCache) validPrivacyTx(stateDB vm.StateDB, signer types.Signer, tx *types.Transaction,
+	intrGas *big.Int, gasLimit *big.Int) error {
+	if err := validPrivacyTxFields(tx.GasPrice(), intrGas, tx.Value()); err != nil {
+		return err
+	}
+
+	result := c.verify(signer, tx)
+	if result.err != nil {
+		return result.err
+	}
+	if result.otaEpoch != c.otaEpoch {
+		balance, err := c.otaBalance(stateDB, result.ring.PublicKeys)
+		if err != nil {
+			return err
+		}
+		updated := *result
+		updated.otaEpoch, updated.otaBalance = c.otaEpoch, balance
+		c.results.Add(tx.Hash(), &updated)
+		result = &updated
+	}
+
+	ring := *result.ring
+	ring.OTABalance = result.otaBalance
+	info, err := newPrivacyTxInfo(&ring, result.callData, tx.GasPrice())
+	if err != nil {
+		return err
+	}
+
+	return validPrivacyTxInfo(stateDB, info, intrGas, gasLimit)
+}
@@ -0,0 +1,184 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/params"
+)
+
+var (
+	testStampValue    = big.NewInt(90000000000000000) // 0.09 wan
+	testStampGasPrice = big.NewInt(450000000000)      // 200000 gas from the stamp
+)
+
+func newTestRingSigState(t *testing.T) *state.StateDB {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return statedb
+}
+
+// newTestStamp stores a stamp OTA of the public key of stampKey in statedb.
+func newTestStamp(t *testing.T, statedb *state.StateDB, stampKey *ecdsa.PrivateKey) {
+	viewKey, _ := crypto.GenerateKey()
+	ota := append(keystore.ECDSAPKCompression(&stampKey.PublicKey), keystore.ECDSAPKCompression(&viewKey.PublicKey)...)
+	if _, err := vm.AddOTAIfNotExist(statedb, testStampValue, ota); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTestPrivacyTx returns a privacy transaction sent by key, spending the
+// stamp of stampKey. The ring signs signed instead of the sender if not nil.
+func newTestPrivacyTx(t *testing.T, signer types.Signer, key, stampKey *ecdsa.PrivateKey, signed []byte) *types.Transaction {
+	if signed == nil {
+		signed = crypto.PubkeyToAddress(key.PublicKey).Bytes()
+	}
+	publicKeys, keyImage, w, q, err := crypto.RingSign(signed, stampKey.D, []*ecdsa.PublicKey{&stampKey.PublicKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := TokenAbi.Pack("combine", vm.EncodeRingSignOut(publicKeys, keyImage, w, q), []byte{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := types.NewOTATransaction(0, common.Address{}, new(big.Int), big.NewInt(200000), testStampGasPrice, data)
+	tx, err = types.SignTx(tx, signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func TestRingSigCache(t *testing.T) {
+	var (
+		signer      = types.NewEIP155Signer(params.TestChainConfig.ChainId)
+		statedb     = newTestRingSigState(t)
+		gasLimit    = big.NewInt(1000000)
+		cache       = newRingSigCache()
+		key, _      = crypto.GenerateKey()
+		stampKey, _ = crypto.GenerateKey()
+	)
+	newTestStamp(t, statedb, stampKey)
+
+	tx := newTestPrivacyTx(t, signer, key, stampKey, nil)
+	intrGas := IntrinsicGas(tx.Data(), tx.To(), true)
+	if err := cache.validPrivacyTx(statedb, signer, tx, intrGas, gasLimit); err != nil {
+		t.Fatalf("valid privacy tx rejected: %v", err)
+	}
+	if !cache.results.Contains(tx.Hash()) {
+		t.Fatal("verification not cached")
+	}
+
+	// The ring OTAs are not looked up again until the OTA epoch changes
+	empty := newTestRingSigState(t)
+	if err := cache.validPrivacyTx(empty, signer, tx, intrGas, gasLimit); err != nil {
+		t.Fatalf("cached privacy tx rejected: %v", err)
+	}
+	cache.resetOTAs()
+	if err := cache.validPrivacyTx(empty, signer, tx, intrGas, gasLimit); err == nil {
+		t.Fatal("privacy tx spending a missing stamp accepted after OTA reset")
+	}
+
+	// The key image is looked up on every validation
+	keyImage := crypto.FromECDSAPub(crypto.KeyImage(stampKey.D, &stampKey.PublicKey))
+	if err := vm.AddOTAImage(statedb, keyImage, testStampValue.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.validPrivacyTx(statedb, signer, tx, intrGas, gasLimit); err == nil {
+		t.Fatal("privacy tx spending a spent stamp accepted")
+	}
+
+	// Invalid signatures are cached as well
+	forged := newTestPrivacyTx(t, signer, key, stampKey, []byte("forged"))
+	for i := 0; i < 2; i++ {
+		if err := cache.validPrivacyTx(statedb, signer, forged, intrGas, gasLimit); err != vm.ErrInvalidRingSigned {
+			t.Fatalf("attempt %d: error mismatch: have %v, want %v", i, err, vm.ErrInvalidRingSigned)
+		}
+	}
+}
+
+func TestRingSigCacheOTAReset(t *testing.T) {
+	var (
+		signer      = types.NewEIP155Signer(params.TestChainConfig.ChainId)
+		statedb     = newTestRingSigState(t)
+		gasLimit    = big.NewInt(1000000)
+		cache       = newRingSigCache()
+		key, _      = crypto.GenerateKey()
+		stampKey, _ = crypto.GenerateKey()
+		lookups     int
+	)
+	newTestStamp(t, statedb, stampKey)
+	cache.otaBalance = func(stateDB vm.StateDB, publicKeys []*ecdsa.PublicKey) (*big.Int, error) {
+		lookups++
+		return vm.RingOTABalance(stateDB, publicKeys)
+	}
+
+	tx := newTestPrivacyTx(t, signer, key, stampKey, nil)
+	intrGas := IntrinsicGas(tx.Data(), tx.To(), true)
+	for i := 0; i < 2; i++ {
+		if err := cache.validPrivacyTx(statedb, signer, tx, intrGas, gasLimit); err != nil {
+			t.Fatalf("validation %d: valid privacy tx rejected: %v", i, err)
+		}
+	}
+	if lookups != 1 {
+		t.Fatalf("OTA lookups mismatch: have %d, want 1", lookups)
+	}
+	cached, _ := cache.results.Get(tx.Hash())
+	before := cached.(*ringSigResult)
+
+	// A reorg looks the OTAs up again, caching a new result
+	cache.resetOTAs()
+	if err := cache.validPrivacyTx(statedb, signer, tx, intrGas, gasLimit); err != nil {
+		t.Fatalf("privacy tx rejected after OTA reset: %v", err)
+	}
+	if lookups != 2 {
+		t.Fatalf("OTA lookups after reset mismatch: have %d, want 2", lookups)
+	}
+	if before.otaEpoch != 1 {
+		t.Errorf("shared cached result modified: have OTA epoch %d, want 1", before.otaEpoch)
+	}
+	if cached, _ := cache.results.Get(tx.Hash()); cached.(*ringSigResult).otaEpoch != cache.otaEpoch {
+		t.Errorf("cached OTA epoch mismatch: have %d, want %d", cached.(*ringSigResult).otaEpoch, cache.otaEpoch)
+	}
+}
+
+func TestRingSigCacheVerifyBatch(t *testing.T) {
+	var (
+		signer = types.NewEIP155Signer(params.TestChainConfig.ChainId)
+		cache  = newRingSigCache()
+		key, _ = crypto.GenerateKey()
+	)
+	txs := make([]*types.Transaction, 0, 9)
+	for i := 0; i < 8; i++ {
+		stampKey, _ := crypto.GenerateKey()
+		txs = append(txs, newTestPrivacyTx(t, signer, key, stampKey, nil))
+	}
+	txs = append(txs, transaction(0, big.NewInt(100000), key))
+
+	cache.verifyBatch(signer, txs)
+	for i, tx := range txs[:8] {
+		cached, ok := cache.results.Get(tx.Hash())
+		if !ok {
+			t.Fatalf("privacy tx %d not verified", i)
+		}
+		if err := cached.(*ringSigResult).err; err != nil {
+			t.Errorf("privacy tx %d: verification failed: %v", i, err)
+		}
+	}
+	if cache.results.Contains(txs[8].Hash()) {
+		t.Error("normal tx verified")
+	}
+}
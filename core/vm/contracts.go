@@ -35,6 +35,7 @@ import (
 	"github.com/wanchain/go-wanchain/crypto/bn256"
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/util"
 	"golang.org/x/crypto/ripemd160"
 	"fmt"
 )
@@ -81,7 +82,7 @@ func (c *ecrecover) Run(input []byte, contract *Contract, evm *EVM) ([]byte, err
 	return common.LeftPadBytes(crypto.Keccak256(pubKey[1:])[12:], 32), nil
 }
 
-func (c *ecrecover) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *ecrecover) ValidTx(stateDB StateDB, signer types.Signer, epocher util.SelectLead, tx *types.Transaction) error {
 	return nil
 }
 
@@ -100,7 +101,7 @@ func (c *sha256hash) Run(input []byte, contract *Contract, evm *EVM) ([]byte, er
 	return h[:], nil
 }
 
-func (c *sha256hash) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *sha256hash) ValidTx(stateDB StateDB, signer types.Signer, epocher util.SelectLead, tx *types.Transaction) error {
 	return nil
 }
 
@@ -120,7 +121,7 @@ func (c *ripemd160hash) Run(input []byte, contract *Contract, evm *EVM) ([]byte,
 	return common.LeftPadBytes(ripemd.Sum(nil), 32), nil
 }
 
-func (c *ripemd160hash) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *ripemd160hash) ValidTx(stateDB StateDB, signer types.Signer, epocher util.SelectLead, tx *types.Transaction) error {
 	return nil
 }
 
@@ -138,7 +139,7 @@ func (c *dataCopy) Run(in []byte, contract *Contract, evm *EVM) ([]byte, error)
 	return in, nil
 }
 
-func (c *dataCopy) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *dataCopy) ValidTx(stateDB StateDB, signer types.Signer, epocher util.SelectLead, tx *types.Transaction) error {
 	return nil
 }
 
@@ -247,7 +248,7 @@ func (c *bigModExp) Run(input []byte, contract *Contract, evm *EVM) ([]byte, err
 	return common.LeftPadBytes(base.Exp(base, exp, mod).Bytes(), int(modLen)), nil
 }
 
-func (c *bigModExp) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *bigModExp) ValidTx(stateDB StateDB, signer types.Signer, epocher util.SelectLead, tx *types.Transaction) error {
 	return nil
 }
 
@@ -306,7 +307,7 @@ func (c *bn256Add) Run(input []byte, contract *Contract, evm *EVM) ([]byte, erro
 	return res.Marshal(), nil
 }
 
-func (c *bn256Add) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *bn256Add) ValidTx(stateDB StateDB, signer types.Signer, epocher util.SelectLead, tx *types.Transaction) error {
 	return nil
 }
 
@@ -328,7 +329,7 @@ func (c *bn256ScalarMul) Run(input []byte, contract *Contract, evm *EVM) ([]byte
 	return res.Marshal(), nil
 }
 
-func (c *bn256ScalarMul) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *bn256ScalarMul) ValidTx(stateDB StateDB, signer types.Signer, epocher util.SelectLead, tx *types.Transaction) error {
 	return nil
 }
 
@@ -380,7 +381,7 @@ func (c *bn256Pairing) Run(input []byte, contract *Contract, evm *EVM) ([]byte,
 	return false32Byte, nil
 }
 
-func (c *bn256Pairing) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *bn256Pairing) ValidTx(stateDB StateDB, signer types.Signer, epocher util.SelectLead, tx *types.Transaction) error {
 	return nil
 }
 
@@ -417,38 +418,6 @@ var (
 	ErrInvalidOTASet = errors.New("invalid OTA mix set")
 
 	ErrOTAReused = errors.New("OTA is reused")
-
-	StampValueSet   = make(map[string]string, 5)
-	WanCoinValueSet = make(map[string]string, 10)
-)
-
-const (
-	Wancoin10  = "10000000000000000000"  //10
-	Wancoin20  = "20000000000000000000"  //20
-	Wancoin50  = "50000000000000000000"  //50
-	Wancoin100 = "100000000000000000000" //100
-
-	Wancoin200   = "200000000000000000000"   //200
-	Wancoin500   = "500000000000000000000"   //500
-	Wancoin1000  = "1000000000000000000000"  //1000
-	Wancoin5000  = "5000000000000000000000"  //5000
-	Wancoin50000 = "50000000000000000000000" //50000
-
-	WanStampdot001 = "1000000000000000" //0.001
-	WanStampdot002 = "2000000000000000" //0.002
-	WanStampdot005 = "5000000000000000" //0.005
-
-	WanStampdot003 = "3000000000000000" //0.003
-	WanStampdot006 = "6000000000000000" //0.006
-	WanStampdot009 = "9000000000000000" //0.009
-
-	WanStampdot03 = "30000000000000000"  //0.03
-	WanStampdot06 = "60000000000000000"  //0.06
-	WanStampdot09 = "90000000000000000"  //0.09
-	WanStampdot2  = "200000000000000000" //0.2
-	WanStampdot3  = "300000000000000000" //0.3
-	WanStampdot5  = "500000000000000000" //0.5
-
 )
 
 func init() {
@@ -461,70 +430,6 @@ func init() {
 	copy(getCoinsIdArr[:], coinAbi.Methods["getCoins"].Id())
 
 	copy(stBuyId[:], stampAbi.Methods["buyStamp"].Id())
-
-	svaldot001, _ := new(big.Int).SetString(WanStampdot001, 10)
-	StampValueSet[svaldot001.Text(16)] = WanStampdot001
-
-	svaldot002, _ := new(big.Int).SetString(WanStampdot002, 10)
-	StampValueSet[svaldot002.Text(16)] = WanStampdot002
-
-	svaldot005, _ := new(big.Int).SetString(WanStampdot005, 10)
-	StampValueSet[svaldot005.Text(16)] = WanStampdot005
-
-	svaldot003, _ := new(big.Int).SetString(WanStampdot003, 10)
-	StampValueSet[svaldot003.Text(16)] = WanStampdot003
-
-	svaldot006, _ := new(big.Int).SetString(WanStampdot006, 10)
-	StampValueSet[svaldot006.Text(16)] = WanStampdot006
-
-	svaldot009, _ := new(big.Int).SetString(WanStampdot009, 10)
-	StampValueSet[svaldot009.Text(16)] = WanStampdot009
-
-	svaldot03, _ := new(big.Int).SetString(WanStampdot03, 10)
-	StampValueSet[svaldot03.Text(16)] = WanStampdot03
-
-	svaldot06, _ := new(big.Int).SetString(WanStampdot06, 10)
-	StampValueSet[svaldot06.Text(16)] = WanStampdot06
-
-	svaldot09, _ := new(big.Int).SetString(WanStampdot09, 10)
-	StampValueSet[svaldot09.Text(16)] = WanStampdot09
-
-	svaldot2, _ := new(big.Int).SetString(WanStampdot2, 10)
-	StampValueSet[svaldot2.Text(16)] = WanStampdot2
-
-	svaldot3, _ := new(big.Int).SetString(WanStampdot3, 10)
-	StampValueSet[svaldot3.Text(16)] = WanStampdot3
-
-	svaldot5, _ := new(big.Int).SetString(WanStampdot5, 10)
-	StampValueSet[svaldot5.Text(16)] = WanStampdot5
-
-	cval10, _ := new(big.Int).SetString(Wancoin10, 10)
-	WanCoinValueSet[cval10.Text(16)] = Wancoin10
-
-	cval20, _ := new(big.Int).SetString(Wancoin20, 10)
-	WanCoinValueSet[cval20.Text(16)] = Wancoin20
-
-	cval50, _ := new(big.Int).SetString(Wancoin50, 10)
-	WanCoinValueSet[cval50.Text(16)] = Wancoin50
-
-	cval100, _ := new(big.Int).SetString(Wancoin100, 10)
-	WanCoinValueSet[cval100.Text(16)] = Wancoin100
-
-	cval200, _ := new(big.Int).SetString(Wancoin200, 10)
-	WanCoinValueSet[cval200.Text(16)] = Wancoin200
-
-	cval500, _ := new(big.Int).SetString(Wancoin500, 10)
-	WanCoinValueSet[cval500.Text(16)] = Wancoin500
-
-	cval1000, _ := new(big.Int).SetString(Wancoin1000, 10)
-	WanCoinValueSet[cval1000.Text(16)] = Wancoin1000
-
-	cval5000, _ := new(big.Int).SetString(Wancoin5000, 10)
-	WanCoinValueSet[cval5000.Text(16)] = Wancoin5000
-
-	cval50000, _ := new(big.Int).SetString(Wancoin50000, 10)
-	WanCoinValueSet[cval50000.Text(16)] = Wancoin50000
-
 }
 
 type wanchainStampSC struct{}
@@ -549,7 +454,7 @@ func (c *wanchainStampSC) Run(in []byte, contract *Contract, env *EVM) ([]byte,
 	return nil, errMethodId
 }
 
-func (c *wanchainStampSC) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *wanchainStampSC) ValidTx(stateDB StateDB, signer types.Signer, epocher util.SelectLead, tx *types.Transaction) error {
 	if stateDB == nil || signer == nil || tx == nil {
 		return errParameters
 	}
@@ -588,11 +493,6 @@ func (c *wanchainStampSC) ValidBuyStampReq(stateDB StateDB, payload []byte, valu
 		return nil, ErrMismatchedValue
 	}
 
-	_, ok := StampValueSet[StampInput.Value.Text(16)]
-	if !ok {
-		return nil, errStampValue
-	}
-
 	wanAddr, err := hexutil.Decode(StampInput.OtaAddr)
 	if err != nil {
 		return nil, err
@@ -616,6 +516,9 @@ func (c *wanchainStampSC) buyStamp(in []byte, contract *Contract, evm *EVM) ([]b
 	if err != nil {
 		return nil, err
 	}
+	if !evm.ChainConfig().IsStampDenomination(contract.value, evm.BlockNumber) {
+		return nil, errStampValue
+	}
 
 	add, err := AddOTAIfNotExist(evm.StateDB, contract.value, wanAddr)
 	if err != nil || !add {
@@ -692,7 +595,7 @@ func (c *wanCoinSC) Run(in []byte, contract *Contract, evm *EVM) ([]byte, error)
 	return nil, errMethodId
 }
 
-func (c *wanCoinSC) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *wanCoinSC) ValidTx(stateDB StateDB, signer types.Signer, epocher util.SelectLead, tx *types.Transaction) error {
 	if stateDB == nil || signer == nil || tx == nil {
 		return errParameters
 	}
@@ -745,11 +648,6 @@ func (c *wanCoinSC) ValidBuyCoinReq(stateDB StateDB, payload []byte, txValue *bi
 		return nil, ErrMismatchedValue
 	}
 
-	_, ok := WanCoinValueSet[outStruct.Value.Text(16)]
-	if !ok {
-		return nil, errCoinValue
-	}
-
 	wanAddr, err := hexutil.Decode(outStruct.OtaAddr)
 	if err != nil {
 		return nil, err
@@ -777,6 +675,9 @@ func (c *wanCoinSC) buyCoin(in []byte, contract *Contract, evm *EVM) ([]byte, er
 	if err != nil {
 		return nil, err
 	}
+	if !evm.ChainConfig().IsWanCoinDenomination(contract.value, evm.BlockNumber) {
+		return nil, errCoinValue
+	}
 
 	add, err := AddOTAIfNotExist(evm.StateDB, contract.value, otaAddr)
 	if err != nil || !add {
@@ -795,6 +696,117 @@ func (c *wanCoinSC) buyCoin(in []byte, contract *Contract, evm *EVM) ([]byte, er
 	}
 }
 
+// UnpackBuyOTA returns the OTA, in WanAddr format, and the value of a wancoin
+// or stamp purchase sent to the precompiled contract at to. The OTA is stored
+// through AddOTAIfNotExist if the purchase succeeds.
+func UnpackBuyOTA(to common.Address, payload []byte) (otaWanAddr []byte, value *big.Int, err error) {
+	if len(payload) < 4 {
+		return nil, nil, errParameters
+	}
+
+	var methodId [4]byte
+	copy(methodId[:], payload[:4])
+
+	var input struct {
+		OtaAddr string
+		Value   *big.Int
+	}
+	switch {
+	case to == wanCoinPrecompileAddr && methodId == buyIdArr:
+		err = coinAbi.Unpack(&input, "buyCoinNote", payload[4:])
+	case to == wanStampPrecompileAddr && methodId == stBuyId:
+		err = stampAbi.Unpack(&input, "buyStamp", payload[4:])
+	default:
+		return nil, nil, errMethodId
+	}
+	if err != nil || input.Value == nil {
+		return nil, nil, errParameters
+	}
+
+	otaWanAddr, err = hexutil.Decode(input.OtaAddr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(otaWanAddr) != common.WAddressLength {
+		return nil, nil, ErrInvalidOTAAddr
+	}
+
+	return otaWanAddr, input.Value, nil
+}
+
+// ValidOTADenomination checks that a wancoin or stamp purchase sent to the
+// precompiled contract at to buys a denomination of the chain active in the
+// block num. Other calls are not checked.
+func ValidOTADenomination(config *params.ChainConfig, num *big.Int, to common.Address, payload []byte) error {
+	if to != wanCoinPrecompileAddr && to != wanStampPrecompileAddr {
+		return nil
+	}
+	_, value, err := UnpackBuyOTA(to, payload)
+	if err == errMethodId {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if to == wanCoinPrecompileAddr && !config.IsWanCoinDenomination(value, num) {
+		return errCoinValue
+	}
+	if to == wanStampPrecompileAddr && !config.IsStampDenomination(value, num) {
+		return errStampValue
+	}
+	return nil
+}
+
+// PackRefundCoin returns the address of the wancoin precompiled contract and
+// the payload refunding the OTA of a ring signature, encoded as by
+// EncodeRingSignOut, to the sender of the transaction.
+func PackRefundCoin(ringSignedData string, value *big.Int) (to common.Address, payload []byte, err error) {
+	payload, err = coinAbi.Pack("refundCoin", ringSignedData, value)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	return wanCoinPrecompileAddr, payload, nil
+}
+
+// PackBuyStamp returns the address of the stamp precompiled contract and the
+// payload buying a stamp of the given value paid to an OTA, in WanAddr format.
+func PackBuyStamp(otaWanAddr []byte, value *big.Int) (to common.Address, payload []byte, err error) {
+	if len(otaWanAddr) != common.WAddressLength {
+		return common.Address{}, nil, ErrInvalidOTAAddr
+	}
+	payload, err = stampAbi.Pack("buyStamp", hexutil.Encode(otaWanAddr), value)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	return wanStampPrecompileAddr, payload, nil
+}
+
+// UnpackRefundCoin returns the ring signature and the value of a wancoin
+// refund sent to the precompiled contract at to.
+func UnpackRefundCoin(to common.Address, payload []byte) (ringSignedData string, value *big.Int, err error) {
+	if len(payload) < 4 {
+		return "", nil, errParameters
+	}
+
+	var methodId [4]byte
+	copy(methodId[:], payload[:4])
+	if to != wanCoinPrecompileAddr || methodId != refundIdArr {
+		return "", nil, errMethodId
+	}
+
+	var RefundStruct struct {
+		RingSignedData string
+		Value          *big.Int
+	}
+	err = coinAbi.Unpack(&RefundStruct, "refundCoin", payload[4:])
+	if err != nil || RefundStruct.Value == nil {
+		return "", nil, errRefundCoin
+	}
+
+	return RefundStruct.RingSignedData, RefundStruct.Value, nil
+}
+
 func (c *wanCoinSC) ValidRefundReq(stateDB StateDB, payload []byte, from []byte) (image []byte, value *big.Int, err error) {
 	if stateDB == nil || len(payload) == 0 || len(from) == 0 {
 		return nil, nil, errors.New("unknown error")
@@ -853,6 +865,30 @@ func (c *wanCoinSC) refund(all []byte, contract *Contract, evm *EVM) ([]byte, er
 	return []byte{1}, nil
 
 }
+
+// EncodeRingSignOut encodes a ring signature in the format decoded by
+// DecodeRingSignOut.
+func EncodeRingSignOut(publicKeys []*ecdsa.PublicKey, keyImage *ecdsa.PublicKey, w []*big.Int, q []*big.Int) string {
+	pks := make([]string, 0, len(publicKeys))
+	for _, pk := range publicKeys {
+		pks = append(pks, common.ToHex(crypto.FromECDSAPub(pk)))
+	}
+	ws := make([]string, 0, len(w))
+	for _, wi := range w {
+		ws = append(ws, hexutil.EncodeBig(wi))
+	}
+	qs := make([]string, 0, len(q))
+	for _, qi := range q {
+		qs = append(qs, hexutil.EncodeBig(qi))
+	}
+	return strings.Join([]string{
+		strings.Join(pks, "&"),
+		common.ToHex(crypto.FromECDSAPub(keyImage)),
+		strings.Join(ws, "&"),
+		strings.Join(qs, "&"),
+	}, "+")
+}
+
 func DecodeRingSignOut(s string) (error, []*ecdsa.PublicKey, *ecdsa.PublicKey, []*big.Int, []*big.Int) {
 	ss := strings.Split(s, "+")
 	if len(ss) < 4 {
@@ -930,73 +966,54 @@ func FetchRingSignInfo(stateDB StateDB, hashInput []byte, ringSignedStr string)
 		return nil, err
 	}
 
-	otaLongs := make([][]byte, 0, len(infoTmp.PublicKeys))
-	for i := 0; i < len(infoTmp.PublicKeys); i++ {
-		otaLongs = append(otaLongs, keystore.ECDSAPKCompression(infoTmp.PublicKeys[i]))
-	}
-
-	exist, balanceGet, _, err := BatCheckOTAExist(stateDB, otaLongs)
+	infoTmp.OTABalance, err = RingOTABalance(stateDB, infoTmp.PublicKeys)
 	if err != nil {
-
-		log.Error("verify mix ota fail", "err", err.Error())
 		return nil, err
 	}
 
-	if !exist {
-		return nil, ErrInvalidOTASet
-	}
-
-	infoTmp.OTABalance = balanceGet
-
-	valid := crypto.VerifyRingSign(hashInput, infoTmp.PublicKeys, infoTmp.KeyImage, infoTmp.W_Random, infoTmp.Q_Random)
-	if !valid {
+	if !infoTmp.Verify(hashInput) {
 		return nil, ErrInvalidRingSigned
 	}
 
 	return infoTmp, nil
 }
 
-func GetSupportWanCoinOTABalances() []*big.Int {
-	cval10, _ := new(big.Int).SetString(Wancoin10, 10)
-	cval20, _ := new(big.Int).SetString(Wancoin20, 10)
-	cval50, _ := new(big.Int).SetString(Wancoin50, 10)
-	cval100, _ := new(big.Int).SetString(Wancoin100, 10)
+// RingOTABalance returns the balance of the OTAs of a ring, which must all be
+// stored with the same balance.
+func RingOTABalance(stateDB StateDB, publicKeys []*ecdsa.PublicKey) (*big.Int, error) {
+	otaLongs := make([][]byte, 0, len(publicKeys))
+	for i := 0; i < len(publicKeys); i++ {
+		otaLongs = append(otaLongs, keystore.ECDSAPKCompression(publicKeys[i]))
+	}
 
-	cval200, _ := new(big.Int).SetString(Wancoin200, 10)
-	cval500, _ := new(big.Int).SetString(Wancoin500, 10)
-	cval1000, _ := new(big.Int).SetString(Wancoin1000, 10)
-	cval5000, _ := new(big.Int).SetString(Wancoin5000, 10)
-	cval50000, _ := new(big.Int).SetString(Wancoin50000, 10)
+	exist, balanceGet, _, err := BatCheckOTAExist(stateDB, otaLongs)
+	if err != nil {
 
-	wancoinBalances := []*big.Int{
-		cval10,
-		cval20,
-		cval50,
-		cval100,
+		log.Error("verify mix ota fail", "err", err.Error())
+		return nil, err
+	}
 
-		cval200,
-		cval500,
-		cval1000,
-		cval5000,
-		cval50000,
+	if !exist {
+		return nil, ErrInvalidOTASet
 	}
 
-	return wancoinBalances
+	return balanceGet, nil
 }
 
-func GetSupportStampOTABalances() []*big.Int {
-
-	svaldot09, _ := new(big.Int).SetString(WanStampdot09, 10)
-	svaldot2, _ := new(big.Int).SetString(WanStampdot2, 10)
-	svaldot5, _ := new(big.Int).SetString(WanStampdot5, 10)
+// Verify returns whether the ring signature signs hashInput. It does not
+// depend on the state, so its result can be reused for the same ring.
+func (info *RingSignInfo) Verify(hashInput []byte) bool {
+	return crypto.VerifyRingSign(hashInput, info.PublicKeys, info.KeyImage, info.W_Random, info.Q_Random)
+}
 
-	stampBalances := []*big.Int{
-		//svaldot03,
-		//svaldot06,
-		svaldot09,
-		svaldot2,
-		svaldot5,
-	}
+// GetSupportWanCoinOTABalances returns the wancoin denominations of the
+// chain that can be bought in the block num.
+func GetSupportWanCoinOTABalances(config *params.ChainConfig, num *big.Int) []*big.Int {
+	return config.WanCoinDenominations(num)
+}
 
-	return stampBalances
+// GetSupportStampOTABalances returns the stamp denominations of the chain
+// offered to wallets that can be bought in the block num.
+func GetSupportStampOTABalances(config *params.ChainConfig, num *big.Int) []*big.Int {
+	return config.OfferedStampDenominations(num)
 }
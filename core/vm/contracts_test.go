@@ -0,0 +1,56 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/params"
+)
+
+func TestValidOTADenomination(t *testing.T) {
+	config := &params.ChainConfig{OTA: &params.OTAConfig{
+		WanCoins: []params.OTADenomination{{Value: big.NewInt(10), Block: big.NewInt(5)}},
+		Stamps:   []params.OTADenomination{{Value: big.NewInt(1)}},
+	}}
+	ota := hexutil.Encode(make([]byte, common.WAddressLength))
+
+	pack := func(method string, value int64) []byte {
+		var (
+			payload []byte
+			err     error
+		)
+		if method == "buyStamp" {
+			payload, err = stampAbi.Pack(method, ota, big.NewInt(value))
+		} else {
+			payload, err = coinAbi.Pack(method, ota, big.NewInt(value))
+		}
+		if err != nil {
+			t.Fatalf("failed to pack %s: %v", method, err)
+		}
+		return payload
+	}
+
+	tests := []struct {
+		to      common.Address
+		payload []byte
+		num     int64
+		err     error
+	}{
+		{wanCoinPrecompileAddr, pack("buyCoinNote", 10), 5, nil},
+		{wanCoinPrecompileAddr, pack("buyCoinNote", 10), 4, errCoinValue},
+		{wanCoinPrecompileAddr, pack("buyCoinNote", 1), 5, errCoinValue},
+		{wanStampPrecompileAddr, pack("buyStamp", 1), 0, nil},
+		{wanStampPrecompileAddr, pack("buyStamp", 10), 5, errStampValue},
+		{wanCoinPrecompileAddr, pack("refundCoin", 3), 5, nil},
+		{common.Address{}, pack("buyCoinNote", 3), 5, nil},
+	}
+	for i, test := range tests {
+		if err := ValidOTADenomination(config, big.NewInt(test.num), test.to, test.payload); err != test.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, test.err)
+		}
+	}
+}
@@ -45,7 +45,7 @@ func run(evm *EVM, snapshot int, contract *Contract, input []byte) ([]byte, erro
 		//precompiles := PrecompiledContractsHomestead
 
 		//if evm.ChainConfig().IsByzantium(evm.BlockNumber) {
-		precompiles := PrecompiledContractsByzantium
+		precompiles := evm.activePrecompiles()
 		//}
 
 		if p := precompiles[*contract.CodeAddr]; p != nil {
@@ -160,7 +160,7 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 		//precompiles = PrecompiledContractsHomestead
 		//if evm.ChainConfig().IsByzantium(evm.BlockNumber) {
 
-		precompiles = PrecompiledContractsByzantium
+		precompiles = evm.activePrecompiles()
 
 		//}
 
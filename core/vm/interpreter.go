@@ -24,6 +24,8 @@ import (
 	"github.com/wanchain/go-wanchain/common/math"
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/util"
 )
 
 // Config are the configuration options for the Interpreter
@@ -43,6 +45,15 @@ type Config struct {
 	DisableGasMetering bool
 	// Enable recording of SHA3/keccak preimages
 	EnablePreimageRecording bool
+	// PosDb keeps the local pos data of the node, the slot leader contract
+	// calls are counted in it if set
+	PosDb *posdb.Db
+	// Epocher selects the epoch leaders and random proposers of the node, the
+	// pos contracts check their callers against it
+	Epocher util.SelectLead
+	// SlotClock is the pos slot timing of the node, the pos contracts derive
+	// epochs and slots from it
+	SlotClock *util.SlotClock
 	// JumpTable contains the EVM instruction table. This
 	// may be left uninitialised and will be set to the default
 	// table.
@@ -0,0 +1,158 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package vm
+
+import (
+	crand "crypto/rand"
+	"errors"
+	"math"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/common"
+)
+
+// Decoy selection strategies of the OTA sets sampled from an OTAIndex.
+const (
+	DecoyUniform = "uniform" // Every indexed OTA is drawn with the same probability
+	DecoyRecent  = "recent"  // Recently indexed OTAs are drawn more often
+)
+
+// DefaultDecoyHalfLife is the number of OTAs indexed after an OTA that halve
+// its weight under the recent strategy, if none is requested.
+const DefaultDecoyHalfLife = 1024
+
+var ErrUnknownDecoyStrategy = errors.New("unknown decoy selection strategy")
+
+// DecoyPolicy selects the decoys of the OTA sets sampled from an OTAIndex.
+type DecoyPolicy struct {
+	Distribution DecoyDistribution // Distribution of the ordinals drawn, uniform if nil
+	ExcludeSpent bool              // Whether to skip the OTAs the index knows to be spent, see OTAIndex.OTASpent
+}
+
+// NewDecoyPolicy returns the policy of a decoy selection strategy. The half
+// life only applies to the recent strategy, and defaults to
+// DefaultDecoyHalfLife if zero.
+func NewDecoyPolicy(strategy string, halfLife uint64, excludeSpent bool) (*DecoyPolicy, error) {
+	policy := &DecoyPolicy{ExcludeSpent: excludeSpent}
+	switch strategy {
+	case "", DecoyUniform:
+		policy.Distribution = UniformDecoys{}
+	case DecoyRecent:
+		if halfLife == 0 {
+			halfLife = DefaultDecoyHalfLife
+		}
+		policy.Distribution = RecentDecoys{HalfLife: halfLife}
+	default:
+		return nil, ErrUnknownDecoyStrategy
+	}
+	return policy, nil
+}
+
+// DecoyDistribution is the distribution of the ordinals of the decoys drawn
+// from an OTAIndex. The ordinals follow the order the OTAs were indexed in.
+type DecoyDistribution interface {
+	// NewSampler returns a sampler drawing ordinals below count.
+	NewSampler(count uint64) DecoySampler
+}
+
+// DecoySampler draws the ordinals of the decoys of one OTA set, without
+// replacement.
+type DecoySampler interface {
+	// Draw returns the next ordinal drawn.
+	Draw() (uint64, error)
+}
+
+// UniformDecoys draws every ordinal with the same probability.
+type UniformDecoys struct{}
+
+// NewSampler implements DecoyDistribution, returning a sampler that shuffles
+// the ordinals lazily.
+func (UniformDecoys) NewSampler(count uint64) DecoySampler {
+	return &uniformSampler{count: count, swapped: make(map[uint64]uint64)}
+}
+
+type uniformSampler struct {
+	count   uint64
+	drawn   uint64
+	swapped map[uint64]uint64 // Ordinals moved by the shuffle, by position
+}
+
+func (s *uniformSampler) ordinalAt(i uint64) uint64 {
+	if ordinal, ok := s.swapped[i]; ok {
+		return ordinal
+	}
+	return i
+}
+
+func (s *uniformSampler) Draw() (uint64, error) {
+	if s.drawn >= s.count {
+		return 0, ErrOTASetSample
+	}
+	rnd, err := crand.Int(crand.Reader, new(big.Int).SetUint64(s.count-s.drawn))
+	if err != nil {
+		return 0, err
+	}
+	i, j := s.drawn, s.drawn+rnd.Uint64()
+	ordinal := s.ordinalAt(j)
+	s.swapped[j] = s.ordinalAt(i)
+	s.drawn++
+
+	return ordinal, nil
+}
+
+// RecentDecoys draws the ordinals with a weight halving every HalfLife OTAs
+// indexed after them, so that the decoys have ages similar to the ones of the
+// OTAs usually spent.
+type RecentDecoys struct {
+	HalfLife uint64
+}
+
+// NewSampler implements DecoyDistribution.
+func (d RecentDecoys) NewSampler(count uint64) DecoySampler {
+	halfLife := d.HalfLife
+	if halfLife == 0 {
+		halfLife = DefaultDecoyHalfLife
+	}
+	return &recentSampler{count: count, halfLife: float64(halfLife), drawn: make(map[uint64]bool)}
+}
+
+type recentSampler struct {
+	count    uint64
+	halfLife float64
+	drawn    map[uint64]bool
+}
+
+// Draw draws the age of an OTA, the number of OTAs indexed after it, from an
+// exponential distribution truncated to the ages indexed, by inverting its
+// cumulative distribution. An ordinal already drawn is replaced by the closest
+// one not drawn yet.
+func (s *recentSampler) Draw() (uint64, error) {
+	if uint64(len(s.drawn)) >= s.count {
+		return 0, ErrOTASetSample
+	}
+	rnd, err := crand.Int(crand.Reader, new(big.Int).Lsh(common.Big1, 53))
+	if err != nil {
+		return 0, err
+	}
+	u := float64(rnd.Uint64()) / (1 << 53)
+
+	mass := 1 - math.Exp2(-float64(s.count)/s.halfLife)
+	age := uint64(-s.halfLife * math.Log2(1-u*mass))
+	if age >= s.count {
+		age = s.count - 1
+	}
+	ordinal := s.count - 1 - age
+	for delta := uint64(0); ; delta++ {
+		if ordinal+delta < s.count && !s.drawn[ordinal+delta] {
+			ordinal += delta
+			break
+		}
+		if delta <= ordinal && !s.drawn[ordinal-delta] {
+			ordinal -= delta
+			break
+		}
+	}
+	s.drawn[ordinal] = true
+
+	return ordinal, nil
+}
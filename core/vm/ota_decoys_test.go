@@ -0,0 +1,69 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package vm
+
+import (
+	"testing"
+)
+
+func TestDecoySamplers(t *testing.T) {
+	distributions := []DecoyDistribution{UniformDecoys{}, RecentDecoys{HalfLife: 1}, RecentDecoys{HalfLife: 1000}}
+	for _, distribution := range distributions {
+		sampler := distribution.NewSampler(10)
+		drawn := make(map[uint64]bool)
+		for i := 0; i < 10; i++ {
+			ordinal, err := sampler.Draw()
+			if err != nil {
+				t.Fatalf("%T: draw %d failed: %v", distribution, i, err)
+			}
+			if ordinal >= 10 || drawn[ordinal] {
+				t.Fatalf("%T: draw %d returned ordinal %d, drawn %v", distribution, i, ordinal, drawn)
+			}
+			drawn[ordinal] = true
+		}
+		if _, err := sampler.Draw(); err != ErrOTASetSample {
+			t.Errorf("%T: draw past the ordinals: have %v, want %v", distribution, err, ErrOTASetSample)
+		}
+	}
+}
+
+func TestRecentDecoysHalfLife(t *testing.T) {
+	const (
+		count    = 100000
+		halfLife = 100
+		samples  = 4000
+	)
+	// Half of the draws should fall within the most recent half life, and
+	// three quarters within two
+	var recent, older int
+	for i := 0; i < samples; i++ {
+		ordinal, err := RecentDecoys{HalfLife: halfLife}.NewSampler(count).Draw()
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch age := count - 1 - ordinal; {
+		case age < halfLife:
+			recent++
+		case age < 2*halfLife:
+			older++
+		}
+	}
+	if recent < samples*45/100 || recent > samples*55/100 {
+		t.Errorf("draws within one half life: have %d, want about %d", recent, samples/2)
+	}
+	if older < samples*20/100 || older > samples*30/100 {
+		t.Errorf("draws within the second half life: have %d, want about %d", older, samples/4)
+	}
+}
+
+func TestNewDecoyPolicy(t *testing.T) {
+	if policy, err := NewDecoyPolicy("", 0, false); err != nil || policy.Distribution != (UniformDecoys{}) {
+		t.Errorf("default policy: have %v, %v, want uniform", policy, err)
+	}
+	if policy, err := NewDecoyPolicy(DecoyRecent, 0, true); err != nil || policy.Distribution != (RecentDecoys{HalfLife: DefaultDecoyHalfLife}) || !policy.ExcludeSpent {
+		t.Errorf("recent policy: have %v, %v", policy, err)
+	}
+	if _, err := NewDecoyPolicy("oldest", 0, false); err != ErrUnknownDecoyStrategy {
+		t.Errorf("unknown strategy: have %v, want %v", err, ErrUnknownDecoyStrategy)
+	}
+}
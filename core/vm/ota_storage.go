@@ -23,6 +23,7 @@ var (
 	ErrInvalidOTAAX     = errors.New("invalid OTA AX")
 	ErrOTAExistAlready  = errors.New("OTA exist already")
 	ErrOTABalanceIsZero = errors.New("OTA balance is 0")
+	ErrOTASetSample     = errors.New("failed to sample enough indexed OTAs")
 )
 
 // OTABalance2ContractAddr convert ota balance to ota storage address
@@ -140,8 +141,7 @@ func CheckOTALongAddrExist(statedb StateDB, otaLongAddr []byte) (exist bool, bal
 	mptAddr := OTABalance2ContractAddr(balance)
 	otaStored := statedb.GetStateByteArray(mptAddr, common.BytesToHash(otaAX))
 
-	fmt.Println(common.ToHex(mptAddr.Bytes()))
-	fmt.Println(common.ToHex(otaLongAddr))
+	log.Trace("CheckOTALongAddrExist", "mptAddr", common.ToHex(mptAddr.Bytes()), "ota", common.ToHex(otaLongAddr))
 
 	if otaStored == nil {
 		return false, nil, nil
@@ -434,6 +434,94 @@ func GetOTASet(statedb StateDB, otaAX []byte, setNum int) (otaWanAddrs [][]byte,
 	}
 }
 
+// OTAIndex numbers the OTAs stored for each balance, so that OTA sets can be
+// sampled without travelling the ota mpt.
+type OTAIndex interface {
+	// OTACount returns the number of OTAs indexed with a balance.
+	OTACount(balance *big.Int) uint64
+
+	// OTAByOrdinal returns the WanAddr of the OTA indexed with a balance under
+	// an ordinal below OTACount, or nil if it can't be retrieved.
+	OTAByOrdinal(balance *big.Int, ordinal uint64) []byte
+
+	// OTASpent reports whether the OTA of an AX is known to be spent. Only
+	// rings of size one reveal their spender, so larger rings never mark an
+	// OTA spent.
+	OTASpent(otaAX []byte) bool
+}
+
+// otaSampleDraws is the number of indexed OTAs GetOTASetFromIndex may look up
+// per OTA of the requested set, bounding the cost of an index listing OTAs
+// missing from the state.
+const otaSampleDraws = 4
+
+// GetOTASetFromIndex retrieves the setNum of same balance OTA address of the
+// input OTA setting by otaAX, and ota balance, like GetOTASet. The OTAs are
+// drawn without replacement from the ordinals of index, following the
+// distribution of policy, or uniformly if policy is nil. Those not stored in
+// statedb, such as the ones of reorged blocks, are skipped, as are the ones
+// known to be spent if the policy excludes them. A set is sampled in
+// O(setNum log n) for n stored OTAs.
+func GetOTASetFromIndex(statedb StateDB, index OTAIndex, otaAX []byte, setNum int, policy *DecoyPolicy) (otaWanAddrs [][]byte, balance *big.Int, err error) {
+	if statedb == nil || index == nil {
+		return nil, nil, ErrUnknown
+	}
+	if len(otaAX) != common.HashLength {
+		return nil, nil, ErrInvalidOTAAX
+	}
+	if policy == nil {
+		policy = new(DecoyPolicy)
+	}
+	distribution := policy.Distribution
+	if distribution == nil {
+		distribution = UniformDecoys{}
+	}
+
+	balance, err = GetOtaBalanceFromAX(statedb, otaAX)
+	if err != nil {
+		return nil, nil, err
+	} else if balance == nil || balance.Cmp(common.Big0) == 0 {
+		return nil, nil, errors.New("can't find ota address balance!")
+	}
+
+	count := index.OTACount(balance)
+	if count == 0 {
+		return nil, balance, errors.New("no ota exist! balance:" + balance.String())
+	} else if uint64(setNum) >= count {
+		return nil, balance, errors.New("too more required ota number! balance:" + balance.String() +
+			", exist count:" + strconv.FormatUint(count, 10))
+	}
+
+	mptAddr := OTABalance2ContractAddr(balance)
+	otaWanAddrs = make([][]byte, 0, setNum)
+
+	sampler := distribution.NewSampler(count)
+	draws := uint64(setNum)*otaSampleDraws + 1
+	for i := uint64(0); i < count && i < draws && len(otaWanAddrs) < setNum; i++ {
+		ordinal, err := sampler.Draw()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		ota := index.OTAByOrdinal(balance, ordinal)
+		if len(ota) != common.WAddressLength || IsAXPointToWanAddr(otaAX, ota) {
+			continue
+		}
+		AX, _ := GetAXFromWanAddr(ota)
+		if !bytes.Equal(statedb.GetStateByteArray(mptAddr, common.BytesToHash(AX)), ota) {
+			continue
+		}
+		if policy.ExcludeSpent && index.OTASpent(AX) {
+			continue
+		}
+		otaWanAddrs = append(otaWanAddrs, ota)
+	}
+	if len(otaWanAddrs) < setNum {
+		return nil, balance, ErrOTASetSample
+	}
+	return otaWanAddrs, balance, nil
+}
+
 // CheckOTAImageExist checks ota image key exist already or not
 func CheckOTAImageExist(statedb StateDB, otaImage []byte) (bool, []byte, error) {
 	if statedb == nil || len(otaImage) == 0 {
@@ -1604,3 +1604,164 @@ func TestAddOTAImage(t *testing.T) {
 		t.Errorf("err:%s", err.Error())
 	}
 }
+
+// testOTAIndex is an OTAIndex kept in memory.
+type testOTAIndex struct {
+	otas  map[string][][]byte
+	spent map[common.Hash]bool
+}
+
+func newTestOTAIndex() *testOTAIndex {
+	return &testOTAIndex{otas: make(map[string][][]byte), spent: make(map[common.Hash]bool)}
+}
+
+func (idx *testOTAIndex) add(balance *big.Int, ota []byte) {
+	idx.otas[balance.String()] = append(idx.otas[balance.String()], ota)
+}
+
+func (idx *testOTAIndex) spend(ota []byte) {
+	AX, _ := GetAXFromWanAddr(ota)
+	idx.spent[common.BytesToHash(AX)] = true
+}
+
+func (idx *testOTAIndex) OTACount(balance *big.Int) uint64 {
+	return uint64(len(idx.otas[balance.String()]))
+}
+
+func (idx *testOTAIndex) OTAByOrdinal(balance *big.Int, ordinal uint64) []byte {
+	otas := idx.otas[balance.String()]
+	if ordinal >= uint64(len(otas)) {
+		return nil
+	}
+	return otas[ordinal]
+}
+
+func (idx *testOTAIndex) OTASpent(otaAX []byte) bool {
+	return idx.spent[common.BytesToHash(otaAX)]
+}
+
+// newTestOTAState stores n synthetic OTAs with a balance in a committed state
+// and indexes them, along with stale ones missing from the state.
+func newTestOTAState(n, stale int, balance *big.Int) (*state.StateDB, *testOTAIndex, [][]byte) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	index := newTestOTAIndex()
+
+	otas := make([][]byte, 0, n)
+	for i := 0; i < n+stale; i++ {
+		ota := make([]byte, common.WAddressLength)
+		ota[0], ota[common.WAddressLength/2] = 2, 3
+		copy(ota[1:], crypto.Keccak256(big.NewInt(int64(i)).Bytes()))
+		copy(ota[common.WAddressLength/2+1:], crypto.Keccak256(ota[1:common.WAddressLength/2]))
+		if i < n {
+			if _, err := AddOTAIfNotExist(statedb, balance, ota); err != nil {
+				panic(err)
+			}
+			otas = append(otas, ota)
+		}
+		index.add(balance, ota)
+	}
+	root, _ := statedb.CommitTo(db, false)
+	statedb, _ = state.New(root, state.NewDatabase(db))
+	return statedb, index, otas
+}
+
+func TestGetOTASetFromIndex(t *testing.T) {
+	balance := big.NewInt(10)
+	statedb, index, otas := newTestOTAState(20, 10, balance)
+	otaAX, _ := GetAXFromWanAddr(otas[0])
+
+	set, setBalance, err := GetOTASetFromIndex(statedb, index, otaAX, 8, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if setBalance.Cmp(balance) != 0 {
+		t.Errorf("balance mismatch: have %v, want %v", setBalance, balance)
+	}
+	if len(set) != 8 {
+		t.Fatalf("set size mismatch: have %d, want 8", len(set))
+	}
+	for i, ota := range set {
+		if IsAXPointToWanAddr(otaAX, ota) {
+			t.Errorf("set contains the OTA itself")
+		}
+		stored := false
+		for _, other := range otas {
+			stored = stored || bytes.Equal(ota, other)
+		}
+		if !stored {
+			t.Errorf("set contains stale OTA %x", ota)
+		}
+		for _, other := range set[:i] {
+			if bytes.Equal(ota, other) {
+				t.Errorf("set contains OTA %x twice", ota)
+			}
+		}
+	}
+
+	if _, _, err := GetOTASetFromIndex(statedb, index, otaAX, 30, nil); err == nil {
+		t.Error("set larger than the index sampled")
+	}
+
+	// An index listing only OTAs missing from the state fails the sampling
+	staleIndex := newTestOTAIndex()
+	for _, ota := range index.otas[balance.String()][20:] {
+		staleIndex.add(balance, ota)
+	}
+	staleIndex.add(balance, otas[0])
+	if _, _, err := GetOTASetFromIndex(statedb, staleIndex, otaAX, 2, nil); err != ErrOTASetSample {
+		t.Errorf("sampling stale index: have %v, want %v", err, ErrOTASetSample)
+	}
+}
+
+func TestGetOTASetFromIndexExcludeSpent(t *testing.T) {
+	balance := big.NewInt(10)
+	statedb, index, otas := newTestOTAState(8, 0, balance)
+	otaAX, _ := GetAXFromWanAddr(otas[0])
+	for _, ota := range otas[4:] {
+		index.spend(ota)
+	}
+	policy := &DecoyPolicy{Distribution: RecentDecoys{HalfLife: 2}, ExcludeSpent: true}
+
+	set, _, err := GetOTASetFromIndex(statedb, index, otaAX, 3, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ota := range set {
+		if index.OTASpent(ota[1 : 1+common.HashLength]) {
+			t.Errorf("set contains spent OTA %x", ota)
+		}
+	}
+	if _, _, err := GetOTASetFromIndex(statedb, index, otaAX, 4, policy); err != ErrOTASetSample {
+		t.Errorf("sampling more unspent OTAs than indexed: have %v, want %v", err, ErrOTASetSample)
+	}
+	policy.ExcludeSpent = false
+	if _, _, err := GetOTASetFromIndex(statedb, index, otaAX, 4, policy); err != nil {
+		t.Errorf("sampling spent OTAs failed: %v", err)
+	}
+}
+
+func benchmarkGetOTASet(b *testing.B, n int, indexed bool) {
+	statedb, index, otas := newTestOTAState(n, 0, big.NewInt(10))
+	otaAX, _ := GetAXFromWanAddr(otas[0])
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var err error
+		if indexed {
+			_, _, err = GetOTASetFromIndex(statedb, index, otaAX, 8, nil)
+		} else {
+			_, _, err = GetOTASet(statedb, otaAX, 8)
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetOTASet1K(b *testing.B)            { benchmarkGetOTASet(b, 1000, false) }
+func BenchmarkGetOTASet10K(b *testing.B)           { benchmarkGetOTASet(b, 10000, false) }
+func BenchmarkGetOTASet100K(b *testing.B)          { benchmarkGetOTASet(b, 100000, false) }
+func BenchmarkGetOTASetFromIndex1K(b *testing.B)   { benchmarkGetOTASet(b, 1000, true) }
+func BenchmarkGetOTASetFromIndex10K(b *testing.B)  { benchmarkGetOTASet(b, 10000, true) }
+func BenchmarkGetOTASetFromIndex100K(b *testing.B) { benchmarkGetOTASet(b, 100000, true) }
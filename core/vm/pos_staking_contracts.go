@@ -203,7 +203,7 @@ func (p *PosStaking) Run(input []byte, contract *Contract, evm *EVM) ([]byte, er
 	return nil, nil
 }
 
-func (p *PosStaking) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (p *PosStaking) ValidTx(stateDB StateDB, signer types.Signer, epocher util.SelectLead, tx *types.Transaction) error {
 	input := tx.Data()
 	if len(input) < 4 {
 		return errors.New("parameter is too short")
@@ -289,7 +289,7 @@ func (p *PosStaking) StakeIn(payload []byte, contract *Contract, evm *EVM) ([]by
 	}
 
 	// create stakeholder's information
-	eidNow, _ := util.CalEpochSlotID(evm.Time.Uint64())
+	eidNow, _ := evm.vmConfig.SlotClock.CalEpochSlotID(evm.Time.Uint64())
 	stakeholder := &StakerInfo{
 		Address:      secAddr,
 		PubSec256:    info.SecPk,
@@ -342,7 +342,7 @@ func (p *PosStaking) DelegateIn(payload []byte, contract *Contract, evm *EVM) ([
 	}
 
 	// 3. epoch is valid
-	eidNow, _ := util.CalEpochSlotID(evm.Time.Uint64())
+	eidNow, _ := evm.vmConfig.SlotClock.CalEpochSlotID(evm.Time.Uint64())
 	//lockEpochs := delegateInParam.LockEpochs.Uint64()
 	//eidEnd := EidNow + lockEpochs + posEpochGap
 	//
@@ -7,7 +7,6 @@ import (
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/params"
-	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/rlp"
 	"io/ioutil"
 	"math/big"
@@ -202,7 +201,7 @@ func doStakeIn() error {
 	contract.CallerAddress = common.HexToAddress("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e")
 	a := new(big.Int).Mul(big.NewInt(200000), ether)
 	contract.Value().Set(a)
-	eidNow, _ := util.CalEpochSlotID(stakerevm.Time.Uint64())
+	eidNow, _ := stakerevm.vmConfig.SlotClock.CalEpochSlotID(stakerevm.Time.Uint64())
 
 	var input StakeInParam
 	//input.SecPk = common.FromHex("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e")
@@ -252,7 +251,7 @@ func doDelegateOne(from common.Address) error {
 	contract.CallerAddress = from
 	a := new(big.Int).Mul(big.NewInt(20000), ether)
 	contract.Value().Set(a)
-	eidNow, _ := util.CalEpochSlotID(stakerevm.Time.Uint64())
+	eidNow, _ := stakerevm.vmConfig.SlotClock.CalEpochSlotID(stakerevm.Time.Uint64())
 
 	var input DelegateInParam
 	input.DelegateAddress = common.HexToAddress("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e")
@@ -7,6 +7,8 @@ import (
 
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/util"
 )
 
 // Precompiled contracts address or
@@ -31,32 +33,6 @@ var (
 	otaBalanceStorageAddr = common.BytesToAddress(big.NewInt(300).Bytes())
 	otaImageStorageAddr   = common.BytesToAddress(big.NewInt(301).Bytes())
 
-	// 0.01wan --> "0x0000000000000000000000010000000000000000"
-	otaBalancePercentdot001WStorageAddr = common.HexToAddress(WanStampdot001)
-	otaBalancePercentdot002WStorageAddr = common.HexToAddress(WanStampdot002)
-	otaBalancePercentdot005WStorageAddr = common.HexToAddress(WanStampdot005)
-
-	otaBalancePercentdot003WStorageAddr = common.HexToAddress(WanStampdot003)
-	otaBalancePercentdot006WStorageAddr = common.HexToAddress(WanStampdot006)
-	otaBalancePercentdot009WStorageAddr = common.HexToAddress(WanStampdot009)
-
-	otaBalancePercentdot03WStorageAddr = common.HexToAddress(WanStampdot03)
-	otaBalancePercentdot06WStorageAddr = common.HexToAddress(WanStampdot06)
-	otaBalancePercentdot09WStorageAddr = common.HexToAddress(WanStampdot09)
-	otaBalancePercentdot2WStorageAddr  = common.HexToAddress(WanStampdot2)
-	otaBalancePercentdot5WStorageAddr  = common.HexToAddress(WanStampdot5)
-
-	otaBalance10WStorageAddr  = common.HexToAddress(Wancoin10)
-	otaBalance20WStorageAddr  = common.HexToAddress(Wancoin20)
-	otaBalance50WStorageAddr  = common.HexToAddress(Wancoin50)
-	otaBalance100WStorageAddr = common.HexToAddress(Wancoin100)
-
-	otaBalance200WStorageAddr   = common.HexToAddress(Wancoin200)
-	otaBalance500WStorageAddr   = common.HexToAddress(Wancoin500)
-	otaBalance1000WStorageAddr  = common.HexToAddress(Wancoin1000)
-	otaBalance5000WStorageAddr  = common.HexToAddress(Wancoin5000)
-	otaBalance50000WStorageAddr = common.HexToAddress(Wancoin50000)
-
 	//pos
 	slotLeaderPrecompileAddr = common.BytesToAddress(big.NewInt(600).Bytes())
 
@@ -75,7 +51,7 @@ var (
 type PrecompiledContract interface {
 	RequiredGas(input []byte) uint64                                // RequiredPrice calculates the contract gas use
 	Run(input []byte, contract *Contract, evm *EVM) ([]byte, error) // Run runs the precompiled contract
-	ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error
+	ValidTx(stateDB StateDB, signer types.Signer, epocher util.SelectLead, tx *types.Transaction) error
 }
 
 // PrecompiledContractsHomestead contains the default set of pre-compiled Ethereum
@@ -104,13 +80,56 @@ var PrecompiledContractsByzantium = map[common.Address]PrecompiledContract{
 
 	wanCoinPrecompileAddr:  &wanCoinSC{},
 	wanStampPrecompileAddr: &wanchainStampSC{},
+}
 
-	//pos
+// precompiledContractsPosOnly contains the pos staking, slot leader and random
+// beacon contracts, which only exist from the pos fork on.
+var precompiledContractsPosOnly = map[common.Address]PrecompiledContract{
 	WanCscPrecompileAddr:       &PosStaking{},
 	slotLeaderPrecompileAddr:   &slotLeaderSC{},
 	randomBeaconPrecompileAddr: &RandomBeaconContract{},
 }
 
+// PrecompiledContractsPos contains the pre-compiled contracts active from the
+// pos fork on, the Byzantium set plus the pos only contracts.
+var PrecompiledContractsPos = mergePrecompiles(PrecompiledContractsByzantium, precompiledContractsPosOnly)
+
+// mergePrecompiles returns a set of pre-compiled contracts holding the
+// contracts of all sets, the later sets overriding the earlier ones.
+func mergePrecompiles(sets ...map[common.Address]PrecompiledContract) map[common.Address]PrecompiledContract {
+	merged := make(map[common.Address]PrecompiledContract)
+	for _, set := range sets {
+		for addr, p := range set {
+			merged[addr] = p
+		}
+	}
+	return merged
+}
+
+// IsPosOnlyPrecompiledAddr returns whether addr is a pre-compiled contract
+// that only exists from the pos fork on.
+func IsPosOnlyPrecompiledAddr(addr *common.Address) bool {
+	return addr != nil && precompiledContractsPosOnly[*addr] != nil
+}
+
+// ActivePrecompiledContracts returns the pre-compiled contracts of the block
+// num, the pos contracts only exist once pos is active.
+func ActivePrecompiledContracts(config *params.ChainConfig, num *big.Int) map[common.Address]PrecompiledContract {
+	if config.IsPosActive(num) {
+		return PrecompiledContractsPos
+	}
+	return PrecompiledContractsByzantium
+}
+
+// activePrecompiles returns the pre-compiled contracts of the block the evm
+// runs in.
+func (evm *EVM) activePrecompiles() map[common.Address]PrecompiledContract {
+	if evm.chainRules.IsPos {
+		return PrecompiledContractsPos
+	}
+	return PrecompiledContractsByzantium
+}
+
 func IsPosPrecompiledAddr(addr *common.Address) bool {
 	if addr == nil {
 		return false
@@ -124,3 +143,9 @@ func IsPosPrecompiledAddr(addr *common.Address) bool {
 
 	return false
 }
+
+// IsStampPrecompiledAddr returns whether addr is the stamp precompiled
+// contract.
+func IsStampPrecompiledAddr(addr *common.Address) bool {
+	return addr != nil && *addr == wanStampPrecompileAddr
+}
@@ -0,0 +1,44 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/params"
+)
+
+func TestActivePrecompiledContracts(t *testing.T) {
+	config := &params.ChainConfig{Pluto: &params.PlutoConfig{}, PosForkBlock: big.NewInt(10)}
+
+	before := ActivePrecompiledContracts(config, big.NewInt(9))
+	if before[WanCscPrecompileAddr] != nil || before[slotLeaderPrecompileAddr] != nil || before[randomBeaconPrecompileAddr] != nil {
+		t.Fatal("pos contracts active before the pos fork")
+	}
+	if before[wanCoinPrecompileAddr] == nil {
+		t.Fatal("privacy contract missing before the pos fork")
+	}
+
+	after := ActivePrecompiledContracts(config, big.NewInt(10))
+	if after[WanCscPrecompileAddr] == nil || after[slotLeaderPrecompileAddr] == nil || after[randomBeaconPrecompileAddr] == nil {
+		t.Fatal("pos contracts inactive at the pos fork")
+	}
+	if after[wanCoinPrecompileAddr] == nil {
+		t.Fatal("privacy contract missing after the pos fork")
+	}
+}
+
+func TestPrecompiledContractsPos(t *testing.T) {
+	for addr, p := range PrecompiledContractsByzantium {
+		if PrecompiledContractsPos[addr] != p {
+			t.Errorf("Byzantium contract %x differs after the pos fork", addr)
+		}
+	}
+	for addr := range PrecompiledContractsPos {
+		pos := addr
+		if want := PrecompiledContractsByzantium[addr] == nil; IsPosOnlyPrecompiledAddr(&pos) != want {
+			t.Errorf("contract %x pos only mismatch: want %v", addr, want)
+		}
+	}
+}
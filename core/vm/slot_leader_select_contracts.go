@@ -131,14 +131,14 @@ func (c *slotLeaderSC) Run(in []byte, contract *Contract, evm *EVM) ([]byte, err
 	from = contract.CallerAddress
 
 	if methodId == stgOneIdArr {
-		err := c.validTxStg1ByData(evm.StateDB, from, in[:])
+		err := c.validTxStg1ByData(evm.StateDB, evm.vmConfig.Epocher, from, in[:])
 		if err != nil {
 			log.Error("slotLeaderSC:Run:validTxStg1ByData", "from", from)
 			return nil, err
 		}
 		return c.handleStgOne(in[:], contract, evm) //Do not use [4:] because it has do it in function
 	} else if methodId == stgTwoIdArr {
-		err := c.validTxStg2ByData(evm.StateDB, from, in[:])
+		err := c.validTxStg2ByData(evm.StateDB, evm.vmConfig.Epocher, from, in[:])
 		if err != nil {
 			log.Error("slotLeaderSC:Run:validTxStg2ByData", "from", from)
 			return nil, err
@@ -150,14 +150,14 @@ func (c *slotLeaderSC) Run(in []byte, contract *Contract, evm *EVM) ([]byte, err
 	return nil, errMethodId
 }
 
-func (c *slotLeaderSC) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *slotLeaderSC) ValidTx(stateDB StateDB, signer types.Signer, epocher util.SelectLead, tx *types.Transaction) error {
 	var methodId [4]byte
 	copy(methodId[:], tx.Data()[:4])
 
 	if methodId == stgOneIdArr {
-		return c.validTxStg1(stateDB, signer, tx)
+		return c.validTxStg1(stateDB, signer, epocher, tx)
 	} else if methodId == stgTwoIdArr {
-		return c.validTxStg2(stateDB, signer, tx)
+		return c.validTxStg2(stateDB, signer, epocher, tx)
 	} else {
 		return errMethodId
 	}
@@ -187,7 +187,7 @@ func (c *slotLeaderSC) handleStgOne(in []byte, contract *Contract, evm *EVM) ([]
 		return nil, err
 	}
 
-	addSlotScCallTimes(convert.BytesToUint64(epochIDBuf))
+	addSlotScCallTimes(evm.vmConfig.PosDb, convert.BytesToUint64(epochIDBuf))
 
 	log.Debug(fmt.Sprintf("handleStgOne save data addr:%s, key:%s, data len:%d", slotLeaderPrecompileAddr.Hex(),
 		keyHash.Hex(), len(in)))
@@ -218,7 +218,7 @@ func (c *slotLeaderSC) handleStgTwo(in []byte, contract *Contract, evm *EVM) ([]
 	if err != nil {
 		return nil, err
 	}
-	addSlotScCallTimes(convert.BytesToUint64(epochIDBuf))
+	addSlotScCallTimes(evm.vmConfig.PosDb, convert.BytesToUint64(epochIDBuf))
 
 	log.Debug(fmt.Sprintf("handleStgTwo save data addr:%s, key:%s, data len:%d", slotLeaderPrecompileAddr.Hex(),
 		keyHash.Hex(), len(in)))
@@ -229,16 +229,16 @@ func (c *slotLeaderSC) handleStgTwo(in []byte, contract *Contract, evm *EVM) ([]
 	return nil, nil
 }
 
-func (c *slotLeaderSC) validTxStg1(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *slotLeaderSC) validTxStg1(stateDB StateDB, signer types.Signer, epocher util.SelectLead, tx *types.Transaction) error {
 	sender, err := signer.Sender(tx)
 	if err != nil {
 		return err
 	}
 
-	return c.validTxStg1ByData(stateDB, sender, tx.Data())
+	return c.validTxStg1ByData(stateDB, epocher, sender, tx.Data())
 }
 
-func (c *slotLeaderSC) validTxStg1ByData(stateDB StateDB, from common.Address, payload []byte) error {
+func (c *slotLeaderSC) validTxStg1ByData(stateDB StateDB, epocher util.SelectLead, from common.Address, payload []byte) error {
 
 	epochIDBuf, _, err := RlpGetStage1IDFromTx(payload[:])
 	if err != nil {
@@ -246,7 +246,7 @@ func (c *slotLeaderSC) validTxStg1ByData(stateDB StateDB, from common.Address, p
 		return err
 	}
 
-	if !InEpochLeadersOrNotByAddress(convert.BytesToUint64(epochIDBuf), from) {
+	if !InEpochLeadersOrNotByAddress(epocher, convert.BytesToUint64(epochIDBuf), from) {
 		log.Error("validTxStg1 failed")
 		return ErrIllegalSender
 	}
@@ -255,14 +255,14 @@ func (c *slotLeaderSC) validTxStg1ByData(stateDB StateDB, from common.Address, p
 	return nil
 }
 
-func (c *slotLeaderSC) validTxStg2ByData(stateDB StateDB, from common.Address, payload []byte) error {
+func (c *slotLeaderSC) validTxStg2ByData(stateDB StateDB, epocher util.SelectLead, from common.Address, payload []byte) error {
 	epochID, selfIndex, _, alphaPkis, proofs, err := RlpUnpackStage2DataForTx(payload[:])
 	if err != nil {
 		log.Error("validTxStg2:RlpUnpackStage2DataForTx failed")
 		return err
 	}
 
-	if !InEpochLeadersOrNotByAddress(epochID, from) {
+	if !InEpochLeadersOrNotByAddress(epocher, epochID, from) {
 		log.Error("validTxStg2:InEpochLeadersOrNotByAddress failed")
 		return ErrIllegalSender
 	}
@@ -286,7 +286,7 @@ func (c *slotLeaderSC) validTxStg2ByData(stateDB StateDB, from common.Address, p
 	}
 	//Dleq
 
-	buff := util.GetEpocherInst().GetEpochLeaders(epochID)
+	buff := epocher.GetEpochLeaders(epochID)
 	epochLeaders := make([]*ecdsa.PublicKey, len(buff))
 	for i := 0; i < len(buff); i++ {
 		epochLeaders[i] = crypto.ToECDSAPub(buff[i])
@@ -299,12 +299,12 @@ func (c *slotLeaderSC) validTxStg2ByData(stateDB StateDB, from common.Address, p
 	return nil
 }
 
-func (c *slotLeaderSC) validTxStg2(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *slotLeaderSC) validTxStg2(stateDB StateDB, signer types.Signer, epocher util.SelectLead, tx *types.Transaction) error {
 	sender, err := signer.Sender(tx)
 	if err != nil {
 		return err
 	}
-	return c.validTxStg2ByData(stateDB, sender, tx.Data())
+	return c.validTxStg2ByData(stateDB, epocher, sender, tx.Data())
 }
 
 // GetSlotLeaderStage2KeyHash use to get SlotLeader Stage 1 KeyHash by epochid and selfindex
@@ -326,9 +326,9 @@ func GetSlotLeaderScAbiString() string {
 	return slotLeaderSCDef
 }
 
-// GetSlotScCallTimes can get this precompile contract called times
-func GetSlotScCallTimes(epochID uint64) uint64 {
-	buf, err := posdb.GetDb().Get(epochID, scCallTimes)
+// GetSlotScCallTimes can get this precompile contract called times, counted in db
+func GetSlotScCallTimes(db *posdb.Db, epochID uint64) uint64 {
+	buf, err := db.Get(epochID, scCallTimes)
 	if err != nil {
 		return 0
 	} else {
@@ -377,11 +377,15 @@ func PackStage1Data(input []byte, abiString string) ([]byte, error) {
 	return outBuf, err
 }
 
-func InEpochLeadersOrNotByAddress(epochID uint64, senderAddress common.Address) bool {
-	epochLeaders := util.GetEpocherInst().GetEpochLeaders(epochID)
+func InEpochLeadersOrNotByAddress(epocher util.SelectLead, epochID uint64, senderAddress common.Address) bool {
+	if epocher == nil {
+		log.Warn("no epoch leader selection at InEpochLeadersOrNotByAddress", "epochID", epochID)
+		return false
+	}
+	epochLeaders := epocher.GetEpochLeaders(epochID)
 	if len(epochLeaders) != posconfig.EpochLeaderCount {
 		log.Warn("epoch leader is not ready use epoch 0 at InEpochLeadersOrNotByAddress", "epochID", epochID)
-		epochLeaders = util.GetEpocherInst().GetEpochLeaders(0)
+		epochLeaders = epocher.GetEpochLeaders(0)
 	}
 
 	for i := 0; i < len(epochLeaders); i++ {
@@ -608,11 +612,16 @@ func getSlotLeaderStageKeyHash(epochID, selfIndex []byte, slotLeaderStage string
 	return crypto.Keccak256Hash(keyBuf.Bytes())
 }
 
-func addSlotScCallTimes(epochID uint64) error {
-	buf, err := posdb.GetDb().Get(epochID, scCallTimes)
+// addSlotScCallTimes counts a call of this precompile contract in db, nil if
+// the calls aren't counted.
+func addSlotScCallTimes(db *posdb.Db, epochID uint64) error {
+	if db == nil {
+		return nil
+	}
+	buf, err := db.Get(epochID, scCallTimes)
 	times := uint64(0)
 	if err != nil {
-		if err.Error() != "leveldb: not found" {
+		if err != posdb.ErrNotFound {
 			return err
 		}
 	} else {
@@ -621,12 +630,12 @@ func addSlotScCallTimes(epochID uint64) error {
 
 	times++
 
-	posdb.GetDb().Put(epochID, scCallTimes, convert.Uint64ToBytes(times))
+	db.Put(epochID, scCallTimes, convert.Uint64ToBytes(times))
 	return nil
 }
 
 func isInValidStage(epochID uint64, evm *EVM, kStart uint64, kEnd uint64) bool {
-	eid, sid := util.CalEpochSlotID(evm.Time.Uint64())
+	eid, sid := evm.vmConfig.SlotClock.CalEpochSlotID(evm.Time.Uint64())
 	if epochID != eid {
 		log.Warn("Tx epochID is not current epoch", "epochID", eid, "slotID", sid, "currentEpochID", epochID)
 
@@ -644,34 +653,30 @@ func isInValidStage(epochID uint64, evm *EVM, kStart uint64, kEnd uint64) bool {
 }
 
 func updateSlotLeaderStageIndex(evm *EVM, epochID []byte, slotLeaderStageIndexes string, index uint64) error {
-	var sendtrans [posconfig.EpochLeaderCount]bool
-	var sendtransGet [posconfig.EpochLeaderCount]bool
+	if index >= uint64(posconfig.EpochLeaderCount) {
+		return ErrInvalidTxLen
+	}
+	sendtrans := make([]bool, posconfig.EpochLeaderCount)
 
 	key := getSlotLeaderStageIndexesKeyHash(epochID, slotLeaderStageIndexes)
 	bytes := evm.StateDB.GetStateByteArray(slotLeaderPrecompileAddr, key)
 
-	if len(bytes) == 0 {
-		sendtrans[index] = true
-		value, err := rlp.EncodeToBytes(sendtrans)
-		if err != nil {
-			return err
-		}
-		evm.StateDB.SetStateByteArray(slotLeaderPrecompileAddr, key, value)
-
-		log.Debug("updateSlotLeaderStageIndex", "key", key, "value", sendtrans)
-	} else {
+	if len(bytes) != 0 {
+		var sendtransGet []bool
 		err := rlp.DecodeBytes(bytes, &sendtransGet)
 		if err != nil {
 			return err
 		}
+		copy(sendtrans, sendtransGet)
+	}
 
-		sendtransGet[index] = true
-		value, err := rlp.EncodeToBytes(sendtransGet)
-		if err != nil {
-			return err
-		}
-		evm.StateDB.SetStateByteArray(slotLeaderPrecompileAddr, key, value)
-		log.Debug("updateSlotLeaderStageIndex", "key", key, "value", sendtransGet)
+	sendtrans[index] = true
+	value, err := rlp.EncodeToBytes(sendtrans)
+	if err != nil {
+		return err
 	}
+	evm.StateDB.SetStateByteArray(slotLeaderPrecompileAddr, key, value)
+
+	log.Debug("updateSlotLeaderStageIndex", "key", key, "value", sendtrans)
 	return nil
 }
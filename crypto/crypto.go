@@ -398,6 +398,12 @@ func xScalarHashP(x []byte, pub *ecdsa.PublicKey) (I *ecdsa.PublicKey) {
 	return
 }
 
+// KeyImage returns the key image of the private key x of pub, which the ring
+// signatures made with x carry to be linked.
+func KeyImage(x *big.Int, pub *ecdsa.PublicKey) *ecdsa.PublicKey {
+	return xScalarHashP(x.Bytes(), pub)
+}
+
 var (
 	ErrInvalidRingSignParams = errors.New("invalid ring sign params")
 	ErrRingSignFail          = errors.New("ring sign fail")
@@ -321,9 +321,12 @@ func (api *PublicDebugAPI) DumpBlock(blockNr rpc.BlockNumber) (state.Dump, error
 		return stateDb.RawDump(), nil
 	}
 	var block *types.Block
-	if blockNr == rpc.LatestBlockNumber {
+	switch blockNr {
+	case rpc.LatestBlockNumber:
 		block = api.eth.blockchain.CurrentBlock()
-	} else {
+	case rpc.FinalizedBlockNumber:
+		block = api.eth.blockchain.CurrentFinalizedBlock()
+	default:
 		block = api.eth.blockchain.GetBlockByNumber(uint64(blockNr))
 	}
 	if block == nil {
@@ -401,6 +404,8 @@ func (api *PrivateDebugAPI) TraceBlockByNumber(blockNr rpc.BlockNumber, config *
 		block = api.eth.miner.PendingBlock()
 	case rpc.LatestBlockNumber:
 		block = api.eth.blockchain.CurrentBlock()
+	case rpc.FinalizedBlockNumber:
+		block = api.eth.blockchain.CurrentFinalizedBlock()
 	default:
 		block = api.eth.blockchain.GetBlockByNumber(uint64(blockNr))
 	}
@@ -637,3 +642,58 @@ func storageRangeAt(st state.Trie, start []byte, maxResult int) StorageRangeResu
 	}
 	return result
 }
+
+// PrivateOTAScannerAPI exposes the OTAs the scanner discovered for the local
+// accounts.
+type PrivateOTAScannerAPI struct {
+	scanner *OTAScanner
+}
+
+// NewPrivateOTAScannerAPI creates a new API definition for the OTA scanner.
+func NewPrivateOTAScannerAPI(scanner *OTAScanner) *PrivateOTAScannerAPI {
+	return &PrivateOTAScannerAPI{scanner: scanner}
+}
+
+// RPCReceivedOTA is the RPC representation of an OTA paid to a local account.
+type RPCReceivedOTA struct {
+	OTA         hexutil.Bytes  `json:"ota"`
+	Value       *hexutil.Big   `json:"value"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	TxHash      common.Hash    `json:"transactionHash"`
+}
+
+// ListReceivedOTAs returns the OTAs paid to an account. Only the watch-only
+// accounts and the accounts whose keys were unlocked while the blocks were
+// scanned are indexed.
+func (api *PrivateOTAScannerAPI) ListReceivedOTAs(account common.Address) []*RPCReceivedOTA {
+	received := api.scanner.Received(account)
+	list := make([]*RPCReceivedOTA, 0, len(received))
+	for _, r := range received {
+		list = append(list, &RPCReceivedOTA{
+			OTA:         r.OTA,
+			Value:       (*hexutil.Big)(r.Value),
+			BlockNumber: hexutil.Uint64(r.BlockNumber),
+			BlockHash:   r.BlockHash,
+			TxHash:      r.TxHash,
+		})
+	}
+	return list
+}
+
+// GetOTAWalletBalance returns the total value of the unspent OTAs paid to an
+// account. The OTAs of a locked or watch-only account can't be told spent and
+// are all counted.
+func (api *PrivateOTAScannerAPI) GetOTAWalletBalance(account common.Address) (*hexutil.Big, error) {
+	balance, err := api.scanner.Balance(account)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(balance), nil
+}
+
+// RescanOTAs scans the blocks from the given one on again for the OTAs paid to
+// the unlocked accounts.
+func (api *PrivateOTAScannerAPI) RescanOTAs(from hexutil.Uint64) error {
+	return api.scanner.Rescan(uint64(from))
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/wanchain/go-wanchain/eth/gasprice"
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/event"
+	"github.com/wanchain/go-wanchain/internal/ethapi"
 	"github.com/wanchain/go-wanchain/params"
 	"github.com/wanchain/go-wanchain/rpc"
 )
@@ -50,6 +51,27 @@ func (b *EthApiBackend) CurrentBlock() *types.Block {
 	return b.eth.blockchain.CurrentBlock()
 }
 
+func (b *EthApiBackend) OTAIndex() (vm.OTAIndex, error) {
+	if b.eth.otaIndexer == nil {
+		return nil, nil
+	}
+	if !b.eth.otaIndexer.Synced() {
+		return nil, ethapi.ErrOTAIndexSyncing
+	}
+	return b.eth.otaIndexer, nil
+}
+
+func (b *EthApiBackend) OTAKeyImageTx(image []byte) (common.Hash, bool) {
+	if b.eth.otaIndexer == nil {
+		return common.Hash{}, false
+	}
+	return b.eth.otaIndexer.KeyImageTx(image)
+}
+
+func (b *EthApiBackend) StampPool() ethapi.StampPool {
+	return b.eth.stampPool
+}
+
 func (b *EthApiBackend) SetHead(number uint64) {
 	b.eth.protocolManager.downloader.Cancel()
 	b.eth.blockchain.SetHead(number)
@@ -65,6 +87,13 @@ func (b *EthApiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNum
 	if blockNr == rpc.LatestBlockNumber {
 		return b.eth.blockchain.CurrentBlock().Header(), nil
 	}
+	if blockNr == rpc.FinalizedBlockNumber {
+		block := b.eth.blockchain.CurrentFinalizedBlock()
+		if block == nil {
+			return nil, core.ErrNoFinalizedBlock
+		}
+		return block.Header(), nil
+	}
 	return b.eth.blockchain.GetHeaderByNumber(uint64(blockNr)), nil
 }
 
@@ -78,6 +107,13 @@ func (b *EthApiBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumb
 	if blockNr == rpc.LatestBlockNumber {
 		return b.eth.blockchain.CurrentBlock(), nil
 	}
+	if blockNr == rpc.FinalizedBlockNumber {
+		block := b.eth.blockchain.CurrentFinalizedBlock()
+		if block == nil {
+			return nil, core.ErrNoFinalizedBlock
+		}
+		return block, nil
+	}
 	return b.eth.blockchain.GetBlockByNumber(uint64(blockNr)), nil
 }
 
@@ -112,6 +148,9 @@ func (b *EthApiBackend) GetEVM(ctx context.Context, msg core.Message, state *sta
 	state.SetBalance(msg.From(), math.MaxBig256)
 	vmError := func() error { return nil }
 
+	// the pos contracts check their callers against the leaders of the node
+	vmCfg.Epocher = b.eth.blockchain.GetVMConfig().Epocher
+	vmCfg.SlotClock = b.eth.blockchain.SlotClock()
 	context := core.NewEVMContext(msg, header, b.eth.BlockChain(), nil)
 	return vm.NewEVM(context, state, b.eth.chainConfig, vmCfg), vmError, nil
 }
@@ -128,6 +167,14 @@ func (b *EthApiBackend) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) e
 	return b.eth.BlockChain().SubscribeChainHeadEvent(ch)
 }
 
+func (b *EthApiBackend) SubscribeChainFinalizedEvent(ch chan<- core.ChainFinalizedEvent) event.Subscription {
+	return b.eth.BlockChain().SubscribeChainFinalizedEvent(ch)
+}
+
+func (b *EthApiBackend) SubscribeChainReorgEvent(ch chan<- core.ChainReorgEvent) event.Subscription {
+	return b.eth.BlockChain().SubscribeChainReorgEvent(ch)
+}
+
 func (b *EthApiBackend) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) event.Subscription {
 	return b.eth.BlockChain().SubscribeChainSideEvent(ch)
 }
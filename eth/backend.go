@@ -49,6 +49,9 @@ import (
 	"github.com/wanchain/go-wanchain/node"
 	"github.com/wanchain/go-wanchain/p2p"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/rlp"
 	"github.com/wanchain/go-wanchain/rpc"
 )
@@ -76,6 +79,8 @@ type Ethereum struct {
 
 	// DB interfaces
 	chainDb ethdb.Database // Block chain database
+	posDbs  *posdb.Dbs     // Local pos data
+	pos     *miner.Pos     // Pos parts of the node, nil without pluto
 
 	eventMux       *event.TypeMux
 	engine         consensus.Engine
@@ -86,9 +91,13 @@ type Ethereum struct {
 
 	ApiBackend *EthApiBackend
 
-	miner     *miner.Miner
-	gasPrice  *big.Int
-	etherbase common.Address
+	miner      *miner.Miner
+	posPruner  *posdb.Pruner // Prunes the local pos data of old epochs, nil if disabled
+	otaScanner *OTAScanner   // Discovers the OTAs paid to the local accounts
+	otaIndexer *OTAIndexer   // Numbers the OTAs of the chain to sample decoys from, nil if disabled
+	stampPool  *StampPool    // Stamps owned by the local accounts, found by the OTA scanner
+	gasPrice   *big.Int
+	etherbase  common.Address
 
 	networkId     uint64
 	netRPCService *ethapi.PublicNetAPI
@@ -119,10 +128,23 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		return nil, genesisErr
 	}
 	log.Info("Initialised chain configuration", "config", chainConfig)
+	if chainConfig.Pluto != nil {
+		if err := posconfig.Init(chainConfig.Pos); err != nil {
+			return nil, err
+		}
+	}
+
+	posDbs, err := posdb.OpenDbs(func(name string) (ethdb.Database, error) {
+		return ctx.OpenDatabase(name, 0, 0)
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	eth := &Ethereum{
 		config:         config,
 		chainDb:        chainDb,
+		posDbs:         posDbs,
 		chainConfig:    chainConfig,
 		eventMux:       ctx.EventMux,
 		accountManager: ctx.AccountManager,
@@ -147,11 +169,15 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	}
 
 
-	vmConfig := vm.Config{EnablePreimageRecording: config.EnablePreimageRecording}
+	vmConfig := vm.Config{
+		EnablePreimageRecording: config.EnablePreimageRecording,
+		SlotClock:               util.NewSlotClock(config.PosClock),
+	}
 	eth.blockchain, err = core.NewBlockChain(chainDb, eth.chainConfig, eth.engine, vmConfig)
 	if err != nil {
 		return nil, err
 	}
+	eth.blockchain.SetPosDbs(posDbs)
 	// Rewind the chain in case of an incompatible config upgrade.
 	if compat, ok := genesisErr.(*params.ConfigCompatError); ok {
 		log.Warn("Rewinding chain to upgrade configuration", "err", compat)
@@ -159,21 +185,43 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		core.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
 	eth.bloomIndexer.Start(eth.blockchain.CurrentHeader(), eth.blockchain.SubscribeChainEvent)
+	blockOTAs := NewBlockOTAs(eth.blockchain, chainDb)
+	eth.otaScanner = NewOTAScanner(eth.blockchain, chainDb, blockOTAs, keystoreViewKeys(eth.accountManager), keystoreKeyImages(eth.accountManager))
+	if config.OTAIndex {
+		eth.otaIndexer = NewOTAIndexer(eth.blockchain, chainDb, blockOTAs)
+	}
 
 	if chainConfig.Pluto != nil {
-		miner.PosInit(eth)
+		eth.pos = miner.PosInit(eth.blockchain)
+
+		// The incentive history and the fork counters are kept for good
+		if retain := posconfig.Cfg().RetainEpochs; retain > 0 {
+			dbs := map[string]*posdb.Db{
+				posconfig.PosLocalDB: posDbs.Pos,
+				posconfig.RbLocalDB:  posDbs.Rb,
+				posconfig.EpLocalDB:  posDbs.Ep,
+			}
+			if eth.posPruner, err = posdb.NewPruner(retain, eth.currentEpoch, dbs); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
 	}
 	eth.txPool = core.NewTxPool(config.TxPool, eth.chainConfig, eth.blockchain)
+	if eth.pos != nil {
+		eth.txPool.SetEpocher(eth.pos.Epocher)
+	}
+	eth.stampPool = NewStampPool(eth.otaScanner, eth.blockchain, eth.txPool, chainDb, config.StampRefill)
 
 	if eth.protocolManager, err = NewProtocolManager(eth.chainConfig, config.SyncMode, config.NetworkId, eth.eventMux, eth.txPool, eth.engine, eth.blockchain, chainDb); err != nil {
 		return nil, err
 	}
 	eth.miner = miner.New(eth, eth.chainConfig, eth.EventMux(), eth.engine)
 	eth.miner.SetExtra(makeExtraData(config.ExtraData))
+	eth.miner.SetPos(eth.pos)
 
 	eth.ApiBackend = &EthApiBackend{eth, nil}
 	gpoParams := config.GPO
@@ -185,6 +233,16 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	return eth, nil
 }
 
+// currentEpoch returns the epoch of the current head block, 0 before pos.
+func (s *Ethereum) currentEpoch() uint64 {
+	head := s.blockchain.CurrentBlock()
+	if !s.chainConfig.IsPosActive(head.Number()) {
+		return 0
+	}
+	epochID, _ := s.blockchain.GetBlockEpochIdAndSlotId(head)
+	return epochID
+}
+
 func makeExtraData(extra []byte) []byte {
 	if len(extra) == 0 {
 		// create default extradata
@@ -221,9 +279,19 @@ func CreateConsensusEngine(ctx *node.ServiceContext, config *Config, chainConfig
 		return clique.New(chainConfig.Clique, db)
 	}
 	if chainConfig.Pluto != nil {
-		return pluto.New(chainConfig.Pluto, db)
+		engine := pluto.New(chainConfig.Pluto, db)
+		if chainConfig.PosForkBlock == nil || chainConfig.PosForkBlock.Sign() == 0 {
+			return engine
+		}
+		// PPoW seals the chain until the pos fork block
+		return pluto.NewHybrid(chainConfig, createEthashEngine(ctx, config, db), engine)
 	}
 	// Otherwise assume proof-of-work
+	return createEthashEngine(ctx, config, db)
+}
+
+// createEthashEngine creates the proof-of-work engine selected by config.
+func createEthashEngine(ctx *node.ServiceContext, config *Config, db ethdb.Database) consensus.Engine {
 	switch {
 	case config.PowFake:
 		log.Warn("Ethash used in fake mode")
@@ -249,7 +317,11 @@ func (s *Ethereum) APIs() []rpc.API {
 
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
-	apis = append(apis, posapi.APIs(s.BlockChain(), s.ApiBackend)...)
+	var pos miner.Pos
+	if s.pos != nil {
+		pos = *s.pos
+	}
+	apis = append(apis, posapi.APIs(s.BlockChain(), s.ApiBackend, s.posDbs, pos.Epocher, pos.Sls, pos.Incentive)...)
 
 	// Append all the local APIs and return
 	return append(apis, []rpc.API{
@@ -291,6 +363,10 @@ func (s *Ethereum) APIs() []rpc.API {
 			Namespace: "debug",
 			Version:   "1.0",
 			Service:   NewPrivateDebugAPI(s.chainConfig, s),
+		}, {
+			Namespace: "wan",
+			Version:   "1.0",
+			Service:   NewPrivateOTAScannerAPI(s.otaScanner),
 		}, {
 			Namespace: "net",
 			Version:   "1.0",
@@ -335,39 +411,45 @@ func (s *Ethereum) StartMining(local bool) error {
 		log.Error("Cannot start mining without etherbase", "err", err)
 		return fmt.Errorf("etherbase missing: %v", err)
 	}
-	if clique, ok := s.engine.(*clique.Clique); ok {
-		wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
-		if wallet == nil || err != nil {
-			log.Error("Etherbase account unavailable locally", "err", err)
-			return fmt.Errorf("signer missing: %v", err)
-		}
-		clique.Authorize(eb, wallet.SignHash)
+	engines := []consensus.Engine{s.engine}
+	if hybrid, ok := s.engine.(*pluto.Hybrid); ok {
+		engines = []consensus.Engine{hybrid.PPoW(), hybrid.Pos()}
 	}
-	if pluto, ok := s.engine.(*pluto.Pluto); ok {
-		wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
-		if wallet == nil || err != nil {
-			log.Error("Etherbase account unavailable locally", "err", err)
-			return fmt.Errorf("signer missing: %v", err)
-		}
-		//------------Get local unlock publicKey
-		type getKey interface {
-			GetUnlockedKey(address common.Address) (*keystore.Key, error)
+	for _, engine := range engines {
+		if clique, ok := engine.(*clique.Clique); ok {
+			wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
+			if wallet == nil || err != nil {
+				log.Error("Etherbase account unavailable locally", "err", err)
+				return fmt.Errorf("signer missing: %v", err)
+			}
+			clique.Authorize(eb, wallet.SignHash)
 		}
-		key, err := wallet.(getKey).GetUnlockedKey(eb)
-		if key == nil || err != nil {
-			panic(err)
+		if pluto, ok := engine.(*pluto.Pluto); ok {
+			wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
+			if wallet == nil || err != nil {
+				log.Error("Etherbase account unavailable locally", "err", err)
+				return fmt.Errorf("signer missing: %v", err)
+			}
+			//------------Get local unlock publicKey
+			type getKey interface {
+				GetUnlockedKey(address common.Address) (*keystore.Key, error)
+			}
+			key, err := wallet.(getKey).GetUnlockedKey(eb)
+			if key == nil || err != nil {
+				panic(err)
+			}
+			//------------
+			pluto.Authorize(eb, wallet.SignHash, key)
 		}
-		//------------
-		pluto.Authorize(eb, wallet.SignHash, key)
-	}
 
-	if ethash, ok := s.engine.(*ethash.Ethash); ok {
-		wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
-		if wallet == nil || err != nil {
-			log.Error("Etherbase account unavailable locally", "err", err)
-			return fmt.Errorf("singer missing: %v", err)
+		if ethash, ok := engine.(*ethash.Ethash); ok {
+			wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
+			if wallet == nil || err != nil {
+				log.Error("Etherbase account unavailable locally", "err", err)
+				return fmt.Errorf("singer missing: %v", err)
+			}
+			ethash.Authorize(eb, wallet.SignHash)
 		}
-		ethash.Authorize(eb, wallet.SignHash)
 	}
 	if local {
 		// If local (CPU) mining is started, we can disable the transaction rejection
@@ -384,6 +466,9 @@ func (s *Ethereum) StopMining()         { s.miner.Stop() }
 func (s *Ethereum) IsMining() bool      { return s.miner.Mining() }
 func (s *Ethereum) Miner() *miner.Miner { return s.miner }
 
+// Pos returns the pos parts of the node, nil without pluto.
+func (s *Ethereum) Pos() *miner.Pos { return s.pos }
+
 func (s *Ethereum) AccountManager() *accounts.Manager  { return s.accountManager }
 func (s *Ethereum) BlockChain() *core.BlockChain       { return s.blockchain }
 func (s *Ethereum) TxPool() *core.TxPool               { return s.txPool }
@@ -426,6 +511,14 @@ func (s *Ethereum) Start(srvr *p2p.Server) error {
 	if s.lesServer != nil {
 		s.lesServer.Start(srvr)
 	}
+	if s.posPruner != nil {
+		s.posPruner.Start()
+	}
+	s.otaScanner.Start()
+	if s.otaIndexer != nil {
+		s.otaIndexer.Start()
+	}
+	s.stampPool.Start(s.ApiBackend, s.accountManager)
 	return nil
 }
 
@@ -436,6 +529,17 @@ func (s *Ethereum) Stop() error {
 		s.stopDbUpgrade()
 	}
 	s.bloomIndexer.Close()
+	if s.posPruner != nil {
+		s.posPruner.Stop()
+	}
+	if s.pos != nil {
+		s.pos.Epocher.Stop()
+	}
+	s.stampPool.Stop()
+	s.otaScanner.Stop()
+	if s.otaIndexer != nil {
+		s.otaIndexer.Stop()
+	}
 	s.blockchain.Stop()
 	s.protocolManager.Stop()
 	if s.lesServer != nil {
@@ -446,6 +550,7 @@ func (s *Ethereum) Stop() error {
 	s.eventMux.Stop()
 
 	s.chainDb.Close()
+	s.posDbs.Close()
 	close(s.shutdownChan)
 
 	return nil
@@ -0,0 +1,172 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package eth
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"sync"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/consensus"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/trie"
+)
+
+// blockOTACacheLimit is the number of blocks whose OTAs are kept in memory.
+const blockOTACacheLimit = 256
+
+// BlockOTAs finds the OTAs stored by the blocks of a chain for the OTA scanner
+// and indexer. The OTAs of the recent blocks are cached, so that the scanner
+// and the indexer following the same head diff the OTA storage of a block
+// once.
+type BlockOTAs struct {
+	chain otaChain
+	db    ethdb.Database
+
+	lock  sync.Mutex // Serialises the lookups, for a block to be diffed once
+	cache *lru.Cache // Block hash -> OTAs stored by the block
+}
+
+// NewBlockOTAs creates a finder of the OTAs stored by the blocks of chain.
+func NewBlockOTAs(chain otaChain, db ethdb.Database) *BlockOTAs {
+	cache, _ := lru.New(blockOTACacheLimit)
+	return &BlockOTAs{
+		chain: chain,
+		db:    db,
+		cache: cache,
+	}
+}
+
+// Get returns the OTAs stored by a block. The returned OTAs are shared and
+// must not be modified.
+func (b *BlockOTAs) Get(block *types.Block) []*ReceivedOTA {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if otas, ok := b.cache.Get(block.Hash()); ok {
+		return otas.([]*ReceivedOTA)
+	}
+	otas := readBlockOTAs(b.chain, b.db, block)
+	b.cache.Add(block.Hash(), otas)
+	return otas
+}
+
+// readBlockOTAs returns the OTAs stored by a block. The OTAs bought by the
+// successful transactions of the block, privacy ones included, are returned
+// first, with the transactions. The OTAs bought by contract calls are found
+// by diffing the OTA storage of the block state with the one of its parent
+// state, and are missing if either state was pruned.
+func readBlockOTAs(chain otaChain, db ethdb.Database, block *types.Block) []*ReceivedOTA {
+	var (
+		otas     []*ReceivedOTA
+		found    = make(map[string]bool)
+		receipts types.Receipts
+	)
+	for i, tx := range block.Transactions() {
+		if tx.To() == nil {
+			continue
+		}
+		data := tx.Data()
+		if types.IsPrivacyTransaction(tx.Txtype()) {
+			_, callData, err := core.UnpackPrivacyTxData(data)
+			if err != nil {
+				continue
+			}
+			data = callData
+		}
+		ota, value, err := vm.UnpackBuyOTA(*tx.To(), data)
+		if err != nil {
+			continue
+		}
+		if receipts == nil {
+			receipts = core.GetBlockReceipts(db, block.Hash(), block.NumberU64())
+		}
+		if i >= len(receipts) || receipts[i].Status != types.ReceiptStatusSuccessful {
+			continue
+		}
+		otas = append(otas, &ReceivedOTA{
+			OTA:         ota,
+			Value:       value,
+			BlockNumber: block.NumberU64(),
+			BlockHash:   block.Hash(),
+			TxHash:      tx.Hash(),
+			Stamp:       vm.IsStampPrecompiledAddr(tx.To()),
+		})
+		found[string(ota)] = true
+	}
+	stored, err := storedOTAs(chain, block)
+	if err != nil {
+		log.Debug("Failed to diff OTA storage", "number", block.Number(), "err", err)
+		return otas
+	}
+	config := chain.Config()
+	for _, r := range stored {
+		if found[string(r.OTA)] {
+			continue
+		}
+		r.BlockNumber, r.BlockHash = block.NumberU64(), block.Hash()
+		r.Stamp = config.IsStampDenomination(r.Value, block.Number())
+		otas = append(otas, r)
+	}
+	return otas
+}
+
+// storedOTAs returns the OTAs in the OTA storage of the state of a block that
+// are not in the one of its parent state, sorted by OTA for each value.
+func storedOTAs(chain otaChain, block *types.Block) ([]*ReceivedOTA, error) {
+	if block.NumberU64() == 0 {
+		return nil, nil
+	}
+	parent := chain.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, consensus.ErrUnknownAncestor
+	}
+	if parent.Root == block.Root() {
+		return nil, nil
+	}
+	parentState, err := chain.StateAt(parent.Root)
+	if err != nil {
+		return nil, err
+	}
+	blockState, err := chain.StateAt(block.Root())
+	if err != nil {
+		return nil, err
+	}
+	config := chain.Config()
+	values := append(config.WanCoinDenominations(block.Number()), config.StampDenominations(block.Number())...)
+
+	var stored []*ReceivedOTA
+	for _, value := range values {
+		mptAddr := vm.OTABalance2ContractAddr(value)
+		blockTrie := blockState.StorageTrie(mptAddr)
+		if blockTrie == nil {
+			continue
+		}
+		it := blockTrie.NodeIterator(nil)
+		if parentTrie := parentState.StorageTrie(mptAddr); parentTrie != nil {
+			if parentTrie.Hash() == blockTrie.Hash() {
+				continue
+			}
+			it, _ = trie.NewDifferenceIterator(parentTrie.NodeIterator(nil), it)
+		}
+		var otas []*ReceivedOTA
+		for it.Next(true) {
+			if it.Leaf() && len(it.LeafBlob()) == common.WAddressLength {
+				otas = append(otas, &ReceivedOTA{OTA: common.CopyBytes(it.LeafBlob()), Value: new(big.Int).Set(value)})
+			}
+		}
+		if it.Error() != nil {
+			return nil, it.Error()
+		}
+		sort.Slice(otas, func(i, j int) bool { return bytes.Compare(otas[i].OTA, otas[j].OTA) < 0 })
+		stored = append(stored, otas...)
+	}
+	return stored, nil
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/wanchain/go-wanchain/eth/downloader"
 	"github.com/wanchain/go-wanchain/eth/gasprice"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/util"
 )
 
 // DefaultConfig contains default settings for use on the Ethereum main net.
@@ -108,11 +109,22 @@ type Config struct {
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// Enables the OTA index, which indexes the whole chain on first start.
+	// Without it OTA mix sets are sampled from the state
+	OTAIndex bool `toml:",omitempty"`
+
+	// Enables the background refill of the stamps of the unlocked accounts
+	// running low, which spends their funds unasked
+	StampRefill bool `toml:",omitempty"`
+
 	// Miscellaneous options
 	DocRoot   string `toml:"-"`
 	PowFake   bool   `toml:"-"`
 	PowTest   bool   `toml:"-"`
 	PowShared bool   `toml:"-"`
+
+	// Time source of the pos slot timing, nil selects the system clock
+	PosClock util.Clock `toml:"-"`
 }
 
 type configMarshaling struct {
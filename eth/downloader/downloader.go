@@ -112,10 +112,11 @@ type Downloader struct {
 	rttConfidence uint64 // Confidence in the estimated RTT (unit: millionths to allow atomic ops)
 
 	// Statistics
-	syncStatsChainOrigin uint64 // Origin block number where syncing started at
-	syncStatsChainHeight uint64 // Highest block number known when syncing started
-	syncStatsState       stateSyncStats
-	syncStatsLock        sync.RWMutex // Lock protecting the sync stats fields
+	syncStatsChainOrigin  uint64 // Origin block number where syncing started at
+	syncStatsChainHeight  uint64 // Highest block number known when syncing started
+	syncStatsState        stateSyncStats
+	syncStatsEpochGenesis epochGenesisSyncStats
+	syncStatsLock         sync.RWMutex // Lock protecting the sync stats fields
 
 	lightchain LightChain
 	blockchain BlockChain
@@ -136,11 +137,10 @@ type Downloader struct {
 	receiptWakeCh chan bool            // [eth/63] Channel to signal the receipt fetcher of new tasks
 	headerProcCh  chan []*types.Header // [eth/62] Channel to feed the header processor new tasks
 
-	//for epoch genesis
-	epochGenesisSyncStart chan	uint64
-	epochGenesisCh        chan  dataPack
-	epochGenesisFbCh 	  chan uint64
-	//trackEpochGenesisReq  chan  *epochGenesisReq
+	// for epochGenesisFetcher
+	epochGenesisSyncStart chan uint64            // Channel of epochs the chain entered
+	epochGenesisBatchCh   chan *epochGenesisSync // Channel of epoch ranges a sync cycle waits for
+	epochGenesisCh        chan dataPack          // Channel receiving inbound epoch geneses
 
 	// for stateFetcher
 	stateSyncStart chan *stateSync
@@ -251,15 +251,22 @@ func New(mode SyncMode, stateDb ethdb.Database, mux *event.TypeMux, chain BlockC
 		stateSyncStart: make(chan *stateSync),
 		trackStateReq:  make(chan *stateReq),
 
-		epochGenesisSyncStart : chain.GetEpochStartCh(),
-		epochGenesisCh: make(chan  dataPack,1),
+		epochGenesisBatchCh: make(chan *epochGenesisSync),
+		epochGenesisCh:      make(chan dataPack, 1),
 	}
 
 	go dl.qosTuner()
 
 	go dl.stateFetcher()
 
-	if dl.mode == FastSync || dl.mode == LightSync {
+	// The fetcher only runs for full chains. In LightSync mode the downloader is
+	// driven by les, whose peers don't speak the eth GetEpochGenesisMsg (see
+	// lightPeerWrapper) and there's no BlockChain to store the geneses in. Light
+	// clients retrieve every epoch genesis over LES instead, on demand while
+	// verifying the slot proofs of inserted headers (light.LightChain), which
+	// also lets them anchor a genesis to an already inserted header.
+	if chain != nil {
+		dl.epochGenesisSyncStart = chain.GetEpochStartCh()
 		go dl.epochGenesisFetcher()
 	}
 
@@ -293,6 +300,9 @@ func (d *Downloader) Progress() ethereum.SyncProgress {
 		HighestBlock:  d.syncStatsChainHeight,
 		PulledStates:  d.syncStatsState.processed,
 		KnownStates:   d.syncStatsState.processed + d.syncStatsState.pending,
+
+		PulledEpochGeneses: d.syncStatsEpochGenesis.processed,
+		KnownEpochGeneses:  d.syncStatsEpochGenesis.processed + d.syncStatsEpochGenesis.pending,
 	}
 }
 
@@ -458,21 +468,16 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 		return err
 	}
 
-	/////////////get max genesis epochid////////////
-	localBlk := d.blockchain.CurrentBlock()
-	beginEpid,_:= d.blockchain.GetBlockEpochIdAndSlotId(localBlk)
-
-
-	endblk := types.NewBlockWithHeader(latest)
-	endEpid,_:= d.blockchain.GetBlockEpochIdAndSlotId(endblk)
+	// Retrieve the geneses of the epochs to sync over. Light clients retrieve
+	// them over LES on header insertion instead, see New.
+	if d.mode == FastSync {
+		beginEpid, _ := d.blockchain.GetBlockEpochIdAndSlotId(d.blockchain.CurrentBlock())
+		endEpid, _ := d.blockchain.GetBlockEpochIdAndSlotId(types.NewBlockWithHeader(latest))
 
-	if d.mode == FastSync || d.mode == LightSync {
-		err = d.fetchEpochGenesises(beginEpid, endEpid)
-		if err != nil {
+		if err := d.fetchEpochGenesises(beginEpid, endEpid); err != nil {
 			return err
 		}
 	}
-	////////////////////////////////////////////////
 
 	height := latest.Number.Uint64()
 
@@ -1549,7 +1554,7 @@ func (d *Downloader) DeliverNodeData(id string, data [][]byte) (err error) {
 
 func (d *Downloader) DeliverEpochGenesisData(id string,data *types.EpochGenesis ) (err error) {
 
-	return d.deliver(id, d.epochGenesisCh, &epochGenesisPack{id, data}, stateInMeter, stateDropMeter)
+	return d.deliver(id, d.epochGenesisCh, &epochGenesisPack{id, data}, epochGenesisInMeter, epochGenesisDropMeter)
 }
 
 // deliver injects a new batch of data received from a remote node.
@@ -1,167 +1,255 @@
-
 package downloader
 
 import (
+	"errors"
 	"time"
+
 	"github.com/wanchain/go-wanchain/log"
-	"math/big"
-	"math/rand"
 )
 
+// maxEpochGenesisRetries is the number of times the retrieval of an epoch
+// genesis may fail before it is given up on.
+const maxEpochGenesisRetries = 5
+
+var errEpochGenesisRetries = errors.New("epoch genesis retrieval exceeded retry limit")
 
+// epochGenesisReq tracks an epoch genesis retrieval in flight.
 type epochGenesisReq struct {
-	epochid  *big.Int              			// epochid items to download
-	timeout  time.Duration              	// Maximum round trip time for this to complete
-	timer    *time.Timer                	// Timer to fire when the RTT timeout expires
-	peer     *peerConnection            	// Peer that we're requesting from
+	epochid uint64          // Epoch whose genesis is requested
+	timeout time.Duration   // Maximum round trip time for this to complete
+	timer   *time.Timer     // Timer to fire when the RTT timeout expires
+	peer    *peerConnection // Peer that we're requesting from
+	started time.Time       // Time the request was sent
 }
 
+// epochGenesisSync is a range of epochs whose geneses a sync cycle waits for.
+type epochGenesisSync struct {
+	start, end uint64        // Epoch range to retrieve, inclusive
+	done       chan error    // Channel to signal the retrieval of the whole range
+	abort      chan struct{} // Channel to signal the sync cycle gave up waiting
+}
 
-func (d *Downloader) fetchEpochGenesises(startEpochid uint64,endEpochid uint64) (error) {
-
-	if d.epochGenesisFbCh != nil {
-		return nil
-	}
-
-	fbchan  := make(chan uint64,1)
-	d.epochGenesisFbCh = fbchan
-
-	for i := startEpochid;i <= endEpochid;i++ {
-
-		if i==0 || d.blockchain.IsExistEpochGenesis(i) {
-			continue
-		}
+// epochGenesisResult is the outcome of verifying and storing a delivered
+// epoch genesis.
+type epochGenesisResult struct {
+	epochid uint64
+	peer    string
+	err     error
+}
 
-		d.epochGenesisSyncStart <- i
+// epochGenesisSyncStats tracks the epoch genesis retrieval of a sync cycle.
+type epochGenesisSyncStats struct {
+	processed uint64 // Number of epoch geneses retrieved and stored
+	pending   uint64 // Number of epoch geneses still to retrieve
+}
 
-		select {
-		case epid := <- fbchan:
-			log.Info("got epoch data","",epid)
-		}
+// fetchEpochGenesises retrieves the missing geneses of the epochs between
+// startEpochid and endEpochid, blocking until all of them are stored.
+func (d *Downloader) fetchEpochGenesises(startEpochid uint64, endEpochid uint64) error {
+	batch := &epochGenesisSync{
+		start: startEpochid,
+		end:   endEpochid,
+		done:  make(chan error, 1),
+		abort: make(chan struct{}),
+	}
+	select {
+	case d.epochGenesisBatchCh <- batch:
+	case <-d.cancelCh:
+		return errCancelEpochGenesisFetch
+	case <-d.quitCh:
+		return errCancelEpochGenesisFetch
+	}
+	select {
+	case err := <-batch.done:
+		return err
+	case <-d.cancelCh:
+		close(batch.abort)
+		return errCancelEpochGenesisFetch
+	case <-d.quitCh:
+		return errCancelEpochGenesisFetch
 	}
-
-	d.epochGenesisFbCh = nil
-
-	return nil
 }
 
+// epochGenesisFetcher retrieves epoch geneses requested either by a sync cycle
+// or by the chain entering a new epoch. Every idle peer is assigned its own
+// epoch, so retrievals are pipelined across peers, and the delivered geneses
+// are verified concurrently. Timed out or failed retrievals are rescheduled up
+// to maxEpochGenesisRetries times.
 func (d *Downloader) epochGenesisFetcher() {
-
 	var (
-		active   = make(map[string]*epochGenesisReq) // Currently in-flight requests
-		timeout  = make(chan *epochGenesisReq)       // Timed out active requests
+		queue   []uint64                            // Epochs waiting for an idle peer
+		queued  = make(map[uint64]bool)             // Epochs queued, in flight or being verified
+		retries = make(map[uint64]int)              // Failed retrievals per epoch
+		active  = make(map[string]*epochGenesisReq) // Currently in-flight requests
+		timeout = make(chan *epochGenesisReq)       // Timed out active requests
+		results = make(chan *epochGenesisResult)    // Verification outcomes of delivered geneses
+		batch   *epochGenesisSync                   // Range a sync cycle is waiting for
+		waiting = make(map[uint64]bool)             // Epochs of the batch not yet stored
 	)
-
 	peerDrop := make(chan *peerConnection, 1024)
 	peerSub := d.peers.SubscribePeerDrops(peerDrop)
 	defer peerSub.Unsubscribe()
 
-	for {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
 
-		select {
+	enqueue := func(epochid uint64) bool {
+		if epochid == 0 || d.blockchain.IsExistEpochGenesis(epochid) {
+			return false
+		}
+		if !queued[epochid] {
+			queued[epochid] = true
+			queue = append(queue, epochid)
+		}
+		return true
+	}
+	finish := func(err error) {
+		batch.done <- err
+		batch, waiting = nil, make(map[uint64]bool)
+	}
+	// retry reschedules a failed retrieval, giving up on the epoch once it
+	// failed too often.
+	retry := func(epochid uint64) {
+		if retries[epochid]++; retries[epochid] <= maxEpochGenesisRetries {
+			queue = append(queue, epochid)
+			return
+		}
+		log.Warn("Epoch genesis retrieval failed", "epochid", epochid, "retries", maxEpochGenesisRetries)
+		delete(queued, epochid)
+		delete(retries, epochid)
+		if waiting[epochid] {
+			finish(errEpochGenesisRetries)
+		}
+	}
+	for {
+		// Assign the queued epochs to the idle peers
+		if len(queue) > 0 {
+			peers, _ := d.peers.EpochGenesisIdlePeers()
+			for _, p := range peers {
+				if len(queue) == 0 {
+					break
+				}
+				if _, ok := active[p.id]; ok {
+					continue
+				}
+				req := &epochGenesisReq{epochid: queue[0], timeout: d.requestTTL(), peer: p, started: time.Now()}
+				if err := p.FetchEpochGenesisData(req.epochid); err != nil {
+					continue
+				}
+				queue = queue[1:]
+				active[p.id] = req
 
-			case epochid := <-d.epochGenesisSyncStart:
-				req := d.sendEpochGenesisReq(epochid,active)
-				// Start a timer to notify the sync loop if the peer stalled.
 				req.timer = time.AfterFunc(req.timeout, func() {
 					select {
-						case timeout <- req:
+					case timeout <- req:
+					case <-d.quitCh:
 					}
 				})
+			}
+		}
+		var abort chan struct{}
+		if batch != nil {
+			abort = batch.abort
+		}
 
-			case pack := <-d.epochGenesisCh:
-
-				req := active[pack.PeerId()]
-				if req == nil {
-					log.Debug("Unrequested epoch genesis data", "peer", pack.PeerId(), "len", pack.Items())
-					continue
+		select {
+		case epochid := <-d.epochGenesisSyncStart:
+			enqueue(epochid)
+
+		case b := <-d.epochGenesisBatchCh:
+			batch, waiting = b, make(map[uint64]bool)
+			for i := b.start; i <= b.end; i++ {
+				if enqueue(i) {
+					waiting[i] = true
 				}
-
-				response := pack.(*epochGenesisPack).epochGenesis
-				log.Info("got epoch genesis data", "peer", pack.PeerId(), "epochid", response.EpochId)
-
+			}
+			d.syncStatsLock.Lock()
+			d.syncStatsEpochGenesis = epochGenesisSyncStats{pending: uint64(len(waiting))}
+			d.syncStatsLock.Unlock()
+
+			if len(waiting) == 0 {
+				finish(nil)
+			}
+
+		case <-abort:
+			batch, waiting = nil, make(map[uint64]bool)
+
+		case pack := <-d.epochGenesisCh:
+			req := active[pack.PeerId()]
+			if req == nil {
+				log.Debug("Unrequested epoch genesis data", "peer", pack.PeerId(), "len", pack.Items())
+				continue
+			}
+			req.timer.Stop()
+			req.peer.SetEpochGenesisDataIdle(1)
+			delete(active, pack.PeerId())
+			epochGenesisReqTimer.UpdateSince(req.started)
+
+			response := pack.(*epochGenesisPack).epochGenesis
+			if response == nil || response.EpochId != req.epochid {
+				log.Debug("Mismatching epoch genesis data", "peer", pack.PeerId(), "epochid", req.epochid)
+				retry(req.epochid)
+				continue
+			}
+			// Verify and store the genesis without holding up the retrievals
+			go func(epochid uint64, peer string) {
 				err := d.blockchain.SetEpochGenesis(response)
-
-				if err != nil {
-					log.Debug("epoch genesis data error,try again", "peer", pack.PeerId(), "len", pack.Items())
-					d.epochGenesisSyncStart <- req.epochid.Uint64()
-				} else {
-					if d.epochGenesisFbCh != nil {
-						d.epochGenesisFbCh <- response.EpochId
-					}
+				select {
+				case results <- &epochGenesisResult{epochid: epochid, peer: peer, err: err}:
+				case <-d.quitCh:
 				}
-
-				// Finalize the request and queue up for processing
-				req.timer.Stop()
-				req.peer.SetEpochGenesisDataIdle(1)
-
-				delete(active, pack.PeerId())
-
-				// Handle dropped peer connections:
-			case p := <-peerDrop:
-				// Skip if no request is currently pending
-				req := active[p.id]
-				if req == nil {
-					continue
+			}(req.epochid, pack.PeerId())
+
+		case res := <-results:
+			if res.err != nil {
+				log.Debug("Invalid epoch genesis data", "peer", res.peer, "epochid", res.epochid, "err", res.err)
+				retry(res.epochid)
+				continue
+			}
+			log.Debug("Stored epoch genesis", "peer", res.peer, "epochid", res.epochid)
+			delete(queued, res.epochid)
+			delete(retries, res.epochid)
+			if waiting[res.epochid] {
+				delete(waiting, res.epochid)
+
+				d.syncStatsLock.Lock()
+				d.syncStatsEpochGenesis.processed++
+				d.syncStatsEpochGenesis.pending--
+				d.syncStatsLock.Unlock()
+
+				if len(waiting) == 0 {
+					finish(nil)
 				}
-				// Finalize the request and queue up for processing
-				req.timer.Stop()
-
-				delete(active, req.peer.id)
-				req.peer.SetEpochGenesisDataIdle(1)
-
-				d.epochGenesisSyncStart <- req.epochid.Uint64()
-				// Handle timed-out requests:
-			case req := <-timeout:
-				// If the peer is already requesting something else, ignore the stale timeout.
-				// This can happen when the timeout and the delivery happens simultaneously,
-				// causing both pathways to trigger.
-				if active[req.peer.id] != req || req==nil {
-					continue
-				}
-
-				delete(active, req.peer.id)
-				req.peer.SetEpochGenesisDataIdle(1)
-				d.epochGenesisSyncStart <- req.epochid.Uint64()
-
-			case <-d.quitCh:
-				return
-			case <-d.cancelCh:
-				return
-
+			}
+
+		case p := <-peerDrop:
+			// Skip if no request is currently pending
+			req := active[p.id]
+			if req == nil {
+				continue
+			}
+			// Reschedule the retrieval, the peer isn't to blame
+			req.timer.Stop()
+			delete(active, p.id)
+			queue = append(queue, req.epochid)
+
+		case req := <-timeout:
+			// If the peer is already requesting something else, ignore the stale timeout.
+			// This can happen when the timeout and the delivery happens simultaneously,
+			// causing both pathways to trigger.
+			if active[req.peer.id] != req {
+				continue
+			}
+			epochGenesisTimeoutMeter.Mark(1)
+			delete(active, req.peer.id)
+			req.peer.SetEpochGenesisDataIdle(0)
+			retry(req.epochid)
+
+		case <-ticker.C:
+			// Sanity check to reassign retrievals to peers that turned idle
+
+		case <-d.quitCh:
+			return
 		}
 	}
 }
-
-
-func (d *Downloader) sendEpochGenesisReq(epochid uint64,active map[string]*epochGenesisReq) *epochGenesisReq {
-
-	newPeer := make(chan *peerConnection, 1024)
-	peerSub := d.peers.SubscribeNewPeers(newPeer)
-	defer peerSub.Unsubscribe()
-
-	req := &epochGenesisReq{epochid: big.NewInt(int64(epochid)), timeout: d.requestTTL()}
-	for {
-
-		peers, _ := d.peers.EpochGenesisIdlePeers()
-		if len(peers) == 0 {
-			continue
-		}
-
-		idx := rand.Intn(len(peers))
-		req.peer = peers[idx]
-
-		err := req.peer.FetchEpochGenesisData(epochid)
-		if err != nil {
-			continue
-		} else {
-			break
-		}
-	}
-
-	if active != nil {
-		active[req.peer.id] = req
-	}
-
-	return req
-}
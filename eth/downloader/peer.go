@@ -120,7 +120,7 @@ func (w *lightPeerWrapper) RequestNodeData([]common.Hash) error {
 }
 
 func (w *lightPeerWrapper)RequestEpochGenesisData(uint64) error {
-	panic("RequestNodeData not supported in light client mode sync")
+	panic("RequestEpochGenesisData not supported in light client mode sync")
 }
 
 // newPeerConnection creates a new downloader peer.
@@ -102,6 +102,19 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	}
 	head := header.Number.Uint64()
 
+	// Resolve the finalized block, which there may not be yet
+	if f.begin == rpc.FinalizedBlockNumber.Int64() || f.end == rpc.FinalizedBlockNumber.Int64() {
+		final, err := f.backend.HeaderByNumber(ctx, rpc.FinalizedBlockNumber)
+		if err != nil {
+			return nil, err
+		}
+		if f.begin == rpc.FinalizedBlockNumber.Int64() {
+			f.begin = final.Number.Int64()
+		}
+		if f.end == rpc.FinalizedBlockNumber.Int64() {
+			f.end = final.Number.Int64()
+		}
+	}
 	if f.begin == -1 {
 		f.begin = int64(head)
 	}
@@ -37,6 +37,10 @@ import (
 	"github.com/wanchain/go-wanchain/rpc"
 )
 
+// testFinalityDepth is the number of blocks after which the test backend
+// reports a block final.
+const testFinalityDepth = 10
+
 type testBackend struct {
 	mux        *event.TypeMux
 	db         ethdb.Database
@@ -58,10 +62,18 @@ func (b *testBackend) EventMux() *event.TypeMux {
 func (b *testBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
 	var hash common.Hash
 	var num uint64
-	if blockNr == rpc.LatestBlockNumber {
+	switch blockNr {
+	case rpc.LatestBlockNumber:
 		hash = core.GetHeadBlockHash(b.db)
 		num = core.GetBlockNumber(b.db, hash)
-	} else {
+	case rpc.FinalizedBlockNumber:
+		head := core.GetBlockNumber(b.db, core.GetHeadBlockHash(b.db))
+		if head < testFinalityDepth {
+			return nil, core.ErrNoFinalizedBlock
+		}
+		num = head - testFinalityDepth
+		hash = core.GetCanonicalHash(b.db, num)
+	default:
 		num = uint64(blockNr)
 		hash = core.GetCanonicalHash(b.db, num)
 	}
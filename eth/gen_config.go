@@ -10,6 +10,7 @@ import (
 	"github.com/wanchain/go-wanchain/core"
 	"github.com/wanchain/go-wanchain/eth/downloader"
 	"github.com/wanchain/go-wanchain/eth/gasprice"
+	"github.com/wanchain/go-wanchain/pos/util"
 )
 
 func (c Config) MarshalTOML() (interface{}, error) {
@@ -36,10 +37,13 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		TxPool                  core.TxPoolConfig
 		GPO                     gasprice.Config
 		EnablePreimageRecording bool
-		DocRoot                 string `toml:"-"`
-		PowFake                 bool   `toml:"-"`
-		PowTest                 bool   `toml:"-"`
-		PowShared               bool   `toml:"-"`
+		OTAIndex                bool       `toml:",omitempty"`
+		StampRefill             bool       `toml:",omitempty"`
+		DocRoot                 string     `toml:"-"`
+		PowFake                 bool       `toml:"-"`
+		PowTest                 bool       `toml:"-"`
+		PowShared               bool       `toml:"-"`
+		PosClock                util.Clock `toml:"-"`
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
@@ -63,10 +67,13 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
+	enc.OTAIndex = c.OTAIndex
+	enc.StampRefill = c.StampRefill
 	enc.DocRoot = c.DocRoot
 	enc.PowFake = c.PowFake
 	enc.PowTest = c.PowTest
 	enc.PowShared = c.PowShared
+	enc.PosClock = c.PosClock
 	return &enc, nil
 }
 
@@ -94,10 +101,13 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		TxPool                  *core.TxPoolConfig
 		GPO                     *gasprice.Config
 		EnablePreimageRecording *bool
-		DocRoot                 *string `toml:"-"`
-		PowFake                 *bool   `toml:"-"`
-		PowTest                 *bool   `toml:"-"`
-		PowShared               *bool   `toml:"-"`
+		OTAIndex                *bool       `toml:",omitempty"`
+		StampRefill             *bool       `toml:",omitempty"`
+		DocRoot                 *string     `toml:"-"`
+		PowFake                 *bool       `toml:"-"`
+		PowTest                 *bool       `toml:"-"`
+		PowShared               *bool       `toml:"-"`
+		PosClock                *util.Clock `toml:"-"`
 	}
 	var dec Config
 	if err := unmarshal(&dec); err != nil {
@@ -166,6 +176,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.EnablePreimageRecording != nil {
 		c.EnablePreimageRecording = *dec.EnablePreimageRecording
 	}
+	if dec.OTAIndex != nil {
+		c.OTAIndex = *dec.OTAIndex
+	}
+	if dec.StampRefill != nil {
+		c.StampRefill = *dec.StampRefill
+	}
 	if dec.DocRoot != nil {
 		c.DocRoot = *dec.DocRoot
 	}
@@ -178,5 +194,8 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.PowShared != nil {
 		c.PowShared = *dec.PowShared
 	}
+	if dec.PosClock != nil {
+		c.PosClock = *dec.PosClock
+	}
 	return nil
 }
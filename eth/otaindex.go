@@ -0,0 +1,285 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package eth
+
+import (
+	"encoding/binary"
+	"math/big"
+	"sync"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/log"
+)
+
+var (
+	otaIndexHeadKey     = []byte("ota-index-head") // otaIndexHeadKey -> last block indexed
+	otaIndexCountPrefix = []byte("ota-index-n-")   // otaIndexCountPrefix + storage address -> number of OTAs
+	otaIndexPrefix      = []byte("ota-index-o-")   // otaIndexPrefix + storage address + ordinal (uint64 big endian) -> OTA
+	otaIndexAXPrefix    = []byte("ota-index-a-")   // otaIndexAXPrefix + OTA AX -> indexed marker
+	otaIndexSpentPrefix = []byte("ota-index-s-")   // otaIndexSpentPrefix + OTA AX -> spent marker
+	otaIndexImagePrefix = []byte("ota-index-i-")   // otaIndexImagePrefix + key image -> spending transaction hash
+)
+
+func otaIndexCountKey(mptAddr common.Address) []byte {
+	return append(append([]byte{}, otaIndexCountPrefix...), mptAddr.Bytes()...)
+}
+
+func otaIndexKey(mptAddr common.Address, ordinal uint64) []byte {
+	key := append(append([]byte{}, otaIndexPrefix...), mptAddr.Bytes()...)
+	return append(key, encodeOrdinal(ordinal)...)
+}
+
+func otaIndexAXKey(otaAX []byte) []byte {
+	return append(append([]byte{}, otaIndexAXPrefix...), otaAX...)
+}
+
+func otaIndexSpentKey(otaAX []byte) []byte {
+	return append(append([]byte{}, otaIndexSpentPrefix...), otaAX...)
+}
+
+func otaIndexImageKey(image []byte) []byte {
+	return append(append([]byte{}, otaIndexImagePrefix...), image...)
+}
+
+func encodeOrdinal(ordinal uint64) []byte {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, ordinal)
+	return enc
+}
+
+// otaIndexSyncLag is the number of blocks the OTA index may lag behind the head
+// and still be used to sample OTA sets.
+const otaIndexSyncLag = 16
+
+// OTAIndexer numbers the OTAs stored by the blocks of the chain for each
+// value, so that decoys can be sampled without travelling the OTA storage
+// tries. The whole chain is indexed on first start, unless the index is
+// disabled. The index only grows: the OTAs of reorged blocks stay indexed and
+// are skipped by vm.GetOTASetFromIndex when missing from the state sampled for.
+// The OTAs bought through contract calls are found in the block states, see
+// BlockOTAs, and are missed in the blocks whose states were pruned.
+//
+// The indexer also records the transactions spending the key images, and the
+// OTAs known to be spent, which are the ones spent by a ring signature without
+// decoys. A key image spent again after a reorg is recorded with its new
+// transaction. The spent OTAs of reorged blocks stay recorded, at worst
+// excluding unspent OTAs from the decoys.
+type OTAIndexer struct {
+	chain otaChain
+	db    ethdb.Database
+	otas  *BlockOTAs
+
+	lock sync.Mutex // Serialises the index updates
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewOTAIndexer creates an indexer following chain, finding the OTAs of the
+// blocks with otas and storing its index in db.
+func NewOTAIndexer(chain otaChain, db ethdb.Database, otas *BlockOTAs) *OTAIndexer {
+	return &OTAIndexer{
+		chain: chain,
+		db:    db,
+		otas:  otas,
+		quit:  make(chan struct{}),
+	}
+}
+
+// Start starts indexing the new blocks in the background.
+func (i *OTAIndexer) Start() {
+	i.wg.Add(1)
+	go i.loop()
+}
+
+// Stop terminates the background indexing.
+func (i *OTAIndexer) Stop() {
+	close(i.quit)
+	i.wg.Wait()
+}
+
+func (i *OTAIndexer) loop() {
+	defer i.wg.Done()
+
+	headCh := make(chan core.ChainHeadEvent, 16)
+	sub := i.chain.SubscribeChainHeadEvent(headCh)
+	defer sub.Unsubscribe()
+
+	for {
+		i.index()
+
+		select {
+		case <-headCh:
+		case <-sub.Err():
+			return
+		case <-i.quit:
+			return
+		}
+	}
+}
+
+// index brings the index up to the current head.
+func (i *OTAIndexer) index() {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	head := i.chain.CurrentBlock().NumberU64()
+	from := resumeBlock(i.chain, readScanProgress(i.db, otaIndexHeadKey))
+
+	var (
+		batch   = i.db.NewBatch()
+		counts  = make(map[common.Address]uint64)
+		indexed = make(map[common.Hash]bool)
+		last    *types.Block
+	)
+	commit := func(block *types.Block) {
+		writeScanProgress(batch, otaIndexHeadKey, block)
+		if err := batch.Write(); err != nil {
+			log.Crit("Failed to store OTA index", "err", err)
+		}
+		batch = i.db.NewBatch()
+	}
+	for number := from; number <= head; number++ {
+		select {
+		case <-i.quit:
+			if last != nil {
+				commit(last)
+			}
+			return
+		default:
+		}
+		block := i.chain.GetBlockByNumber(number)
+		if block == nil {
+			break
+		}
+		i.indexBlock(batch, block, counts, indexed)
+
+		if last = block; (number-from+1)%otaScanBatch == 0 {
+			commit(block)
+		}
+	}
+	if last != nil {
+		commit(last)
+	}
+}
+
+// indexBlock adds the OTAs stored by a block, and the key images and OTAs its
+// successful transactions spend, to batch. The counts and OTAs already added
+// to the uncommitted batches are tracked in counts and indexed.
+func (i *OTAIndexer) indexBlock(batch ethdb.Batch, block *types.Block, counts map[common.Address]uint64, indexed map[common.Hash]bool) {
+	for _, r := range i.otas.Get(block) {
+		i.indexOTA(batch, r.OTA, r.Value, counts, indexed)
+	}
+	var receipts types.Receipts
+	succeeded := func(n int) bool {
+		if receipts == nil {
+			receipts = core.GetBlockReceipts(i.db, block.Hash(), block.NumberU64())
+		}
+		return n < len(receipts) && receipts[n].Status == types.ReceiptStatusSuccessful
+	}
+	for n, tx := range block.Transactions() {
+		if tx.To() == nil {
+			continue
+		}
+		data := tx.Data()
+		if types.IsPrivacyTransaction(tx.Txtype()) {
+			// The stamp is spent once the transaction is included, whatever
+			// the outcome of the call it carries
+			ringSigned, callData, err := core.UnpackPrivacyTxData(data)
+			if err != nil {
+				continue
+			}
+			indexSpent(batch, ringSigned, tx.Hash())
+			data = callData
+		}
+		if ringSigned, _, err := vm.UnpackRefundCoin(*tx.To(), data); err == nil && succeeded(n) {
+			indexSpent(batch, ringSigned, tx.Hash())
+		}
+	}
+}
+
+// indexOTA numbers an OTA after the ones of its value, unless already indexed.
+func (i *OTAIndexer) indexOTA(batch ethdb.Batch, ota []byte, value *big.Int, counts map[common.Address]uint64, indexed map[common.Hash]bool) {
+	otaAX, _ := vm.GetAXFromWanAddr(ota)
+	if indexed[common.BytesToHash(otaAX)] {
+		return
+	}
+	if has, _ := i.db.Has(otaIndexAXKey(otaAX)); has {
+		return
+	}
+	mptAddr := vm.OTABalance2ContractAddr(value)
+	count, ok := counts[mptAddr]
+	if !ok {
+		count = i.count(mptAddr)
+	}
+	batch.Put(otaIndexKey(mptAddr, count), ota)
+	batch.Put(otaIndexCountKey(mptAddr), encodeOrdinal(count+1))
+	batch.Put(otaIndexAXKey(otaAX), []byte{1})
+
+	counts[mptAddr] = count + 1
+	indexed[common.BytesToHash(otaAX)] = true
+}
+
+// indexSpent records the transaction spending the key image of a ring
+// signature, and the OTA it spends if the ring has no decoys, its only public
+// key being the one of the OTA.
+func indexSpent(batch ethdb.Batch, ringSignedData string, txHash common.Hash) {
+	err, publicKeys, keyImage, _, _ := vm.DecodeRingSignOut(ringSignedData)
+	if err != nil {
+		return
+	}
+	batch.Put(otaIndexImageKey(crypto.FromECDSAPub(keyImage)), txHash.Bytes())
+	if len(publicKeys) != 1 {
+		return
+	}
+	batch.Put(otaIndexSpentKey(common.LeftPadBytes(publicKeys[0].X.Bytes(), common.HashLength)), []byte{1})
+}
+
+func (i *OTAIndexer) count(mptAddr common.Address) uint64 {
+	data, _ := i.db.Get(otaIndexCountKey(mptAddr))
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// OTACount implements vm.OTAIndex, returning the number of OTAs indexed with a
+// value.
+func (i *OTAIndexer) OTACount(value *big.Int) uint64 {
+	return i.count(vm.OTABalance2ContractAddr(value))
+}
+
+// OTAByOrdinal implements vm.OTAIndex, returning the OTA indexed with a value
+// under an ordinal.
+func (i *OTAIndexer) OTAByOrdinal(value *big.Int, ordinal uint64) []byte {
+	ota, _ := i.db.Get(otaIndexKey(vm.OTABalance2ContractAddr(value), ordinal))
+	return ota
+}
+
+// OTASpent implements vm.OTAIndex, reporting whether the OTA of an AX was
+// revealed spent by a ring signature without decoys.
+func (i *OTAIndexer) OTASpent(otaAX []byte) bool {
+	has, _ := i.db.Has(otaIndexSpentKey(otaAX))
+	return has
+}
+
+// KeyImageTx returns the hash of the transaction spending a key image, or
+// false if none was indexed.
+func (i *OTAIndexer) KeyImageTx(image []byte) (common.Hash, bool) {
+	data, _ := i.db.Get(otaIndexImageKey(image))
+	if len(data) != common.HashLength {
+		return common.Hash{}, false
+	}
+	return common.BytesToHash(data), true
+}
+
+// Synced reports whether the index is close enough to the head to sample OTA
+// sets from.
+func (i *OTAIndexer) Synced() bool {
+	progress := readScanProgress(i.db, otaIndexHeadKey)
+	return progress != nil && progress.Number+otaIndexSyncLag >= i.chain.CurrentBlock().NumberU64()
+}
@@ -0,0 +1,155 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package eth
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+)
+
+func TestOTAIndexer(t *testing.T) {
+	chain := newTestOTAChain(t)
+	indexer := NewOTAIndexer(chain, chain.db, NewBlockOTAs(chain, chain.db))
+	_, wAddr := newTestViewKey(t)
+
+	if indexer.Synced() {
+		t.Fatal("index synced before indexing")
+	}
+
+	// Block 1 buys two OTAs, block 2 a failing purchase
+	ota1, ota2, otaFailed := newTestOTA(t, wAddr), newTestOTA(t, wAddr), newTestOTA(t, wAddr)
+	genesis := chain.head
+	block1, receipts1 := newTestOTABlock(genesis, 0, []*types.Transaction{newBuyCoinTx(t, 0, ota1), newBuyCoinTx(t, 1, ota2)}, nil)
+	chain.insert(t, block1, receipts1)
+	block2, receipts2 := newTestOTABlock(block1, 0, []*types.Transaction{newBuyCoinTx(t, 2, otaFailed)}, map[int]bool{0: true})
+	chain.insert(t, block2, receipts2)
+
+	indexer.index()
+	if !indexer.Synced() {
+		t.Fatal("index not synced after indexing")
+	}
+	if count := indexer.OTACount(testCoinValue); count != 2 {
+		t.Fatalf("OTA count mismatch: have %d, want 2", count)
+	}
+	if ota := indexer.OTAByOrdinal(testCoinValue, 0); !bytes.Equal(ota, ota1) {
+		t.Errorf("OTA 0 mismatch: have %x, want %x", ota, ota1)
+	}
+	if ota := indexer.OTAByOrdinal(testCoinValue, 1); !bytes.Equal(ota, ota2) {
+		t.Errorf("OTA 1 mismatch: have %x, want %x", ota, ota2)
+	}
+	if ota := indexer.OTAByOrdinal(testCoinValue, 2); ota != nil {
+		t.Errorf("OTA beyond the count indexed: %x", ota)
+	}
+
+	// A reorg indexes the OTAs of the new blocks once
+	ota3 := newTestOTA(t, wAddr)
+	fork1, forkReceipts1 := newTestOTABlock(genesis, 1, []*types.Transaction{newBuyCoinTx(t, 0, ota2), newBuyCoinTx(t, 1, ota3)}, nil)
+	chain.insert(t, fork1, forkReceipts1)
+	fork2, forkReceipts2 := newTestOTABlock(fork1, 1, nil, nil)
+	chain.insert(t, fork2, forkReceipts2)
+
+	indexer.index()
+	if count := indexer.OTACount(testCoinValue); count != 3 {
+		t.Fatalf("OTA count after reorg mismatch: have %d, want 3", count)
+	}
+	if ota := indexer.OTAByOrdinal(testCoinValue, 2); !bytes.Equal(ota, ota3) {
+		t.Errorf("OTA 2 mismatch: have %x, want %x", ota, ota3)
+	}
+
+	// The OTAs bought by contract calls are indexed from the state
+	ota4 := newTestOTA(t, wAddr)
+	chain.insert(t, newTestOTAStateBlock(t, chain, chain.head, func(statedb *state.StateDB) {
+		if _, err := vm.AddOTAIfNotExist(statedb, testCoinValue, ota4); err != nil {
+			t.Fatal(err)
+		}
+	}), nil)
+
+	indexer.index()
+	if count := indexer.OTACount(testCoinValue); count != 4 {
+		t.Fatalf("OTA count after a contract purchase mismatch: have %d, want 4", count)
+	}
+	if ota := indexer.OTAByOrdinal(testCoinValue, 3); !bytes.Equal(ota, ota4) {
+		t.Errorf("OTA 3 mismatch: have %x, want %x", ota, ota4)
+	}
+}
+
+// newTestRing ring signs msg with key, hidden among decoys.
+func newTestRing(t *testing.T, msg []byte, key *ecdsa.PrivateKey, decoys ...*ecdsa.PublicKey) string {
+	publicKeys, keyImage, w, q, err := crypto.RingSign(msg, key.D, append([]*ecdsa.PublicKey{&key.PublicKey}, decoys...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vm.EncodeRingSignOut(publicKeys, keyImage, w, q)
+}
+
+// newRefundCoinData packs the call refunding the OTA of a ring signature.
+func newRefundCoinData(t *testing.T, ring string) []byte {
+	_, payload, err := vm.PackRefundCoin(ring, testCoinValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+func TestOTAIndexerSpent(t *testing.T) {
+	chain := newTestOTAChain(t)
+	indexer := NewOTAIndexer(chain, chain.db, NewBlockOTAs(chain, chain.db))
+
+	keys := make([]*ecdsa.PrivateKey, 6)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	otaAX := func(key *ecdsa.PrivateKey) []byte {
+		return common.LeftPadBytes(key.PublicKey.X.Bytes(), common.HashLength)
+	}
+	msg := []byte("sender")
+
+	// Refunds spending keys 0 to 2, of which only the one of key 0 has no
+	// decoys and succeeds
+	refund0 := types.NewTransaction(0, testCoinAddr, new(big.Int), big.NewInt(100000), big.NewInt(1), newRefundCoinData(t, newTestRing(t, msg, keys[0])))
+	refund1 := types.NewTransaction(1, testCoinAddr, new(big.Int), big.NewInt(100000), big.NewInt(1), newRefundCoinData(t, newTestRing(t, msg, keys[1], &keys[5].PublicKey)))
+	refund2 := types.NewTransaction(2, testCoinAddr, new(big.Int), big.NewInt(100000), big.NewInt(1), newRefundCoinData(t, newTestRing(t, msg, keys[2])))
+
+	// A failing privacy transaction spends the stamp of key 3, not the coin of
+	// key 4 it refunds
+	data, err := core.TokenAbi.Pack("combine", newTestRing(t, msg, keys[3]), newRefundCoinData(t, newTestRing(t, msg, keys[4])))
+	if err != nil {
+		t.Fatal(err)
+	}
+	privacy := types.NewOTATransaction(3, testCoinAddr, new(big.Int), big.NewInt(100000), big.NewInt(1), data)
+
+	block, receipts := newTestOTABlock(chain.head, 0, []*types.Transaction{refund0, refund1, refund2, privacy}, map[int]bool{2: true, 3: true})
+	chain.insert(t, block, receipts)
+	indexer.index()
+
+	for i, want := range []bool{true, false, false, true, false, false} {
+		if spent := indexer.OTASpent(otaAX(keys[i])); spent != want {
+			t.Errorf("OTA %d spent mismatch: have %v, want %v", i, spent, want)
+		}
+	}
+
+	// The key images of the successful spends are recorded with their
+	// transactions, whatever the size of the ring
+	keyImage := func(key *ecdsa.PrivateKey) []byte {
+		return crypto.FromECDSAPub(crypto.KeyImage(key.D, &key.PublicKey))
+	}
+	spends := []*types.Transaction{refund0, refund1, nil, privacy, nil, nil}
+	for i, tx := range spends {
+		txHash, ok := indexer.KeyImageTx(keyImage(keys[i]))
+		switch {
+		case tx == nil && ok:
+			t.Errorf("key image %d recorded as spent by %x", i, txHash)
+		case tx != nil && (!ok || txHash != tx.Hash()):
+			t.Errorf("key image %d spending tx mismatch: have %x, %v, want %x", i, txHash, ok, tx.Hash())
+		}
+	}
+}
@@ -0,0 +1,436 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package eth
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/wanchain/go-wanchain/accounts"
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/event"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+var (
+	otaReceivedCountPrefix = []byte("ota-received-n-") // otaReceivedCountPrefix + address -> number of received OTAs
+	otaReceivedPrefix      = []byte("ota-received-o-") // otaReceivedPrefix + address + ordinal (uint64 big endian) -> received OTA
+	otaReceivedOTAPrefix   = []byte("ota-received-a-") // otaReceivedOTAPrefix + address + OTA -> ordinal of the received OTA
+	otaScannedPrefix       = []byte("ota-scanned-")    // otaScannedPrefix + address -> last block scanned
+
+	errRescanFuture = errors.New("rescan block is in the future")
+)
+
+func otaReceivedCountKey(account common.Address) []byte {
+	return append(append([]byte{}, otaReceivedCountPrefix...), account.Bytes()...)
+}
+
+func otaReceivedKey(account common.Address, ordinal uint64) []byte {
+	key := append(append([]byte{}, otaReceivedPrefix...), account.Bytes()...)
+	return append(key, encodeOrdinal(ordinal)...)
+}
+
+func otaReceivedOTAKey(account common.Address, ota []byte) []byte {
+	key := append(append([]byte{}, otaReceivedOTAPrefix...), account.Bytes()...)
+	return append(key, ota...)
+}
+
+func otaScannedKey(account common.Address) []byte {
+	return append(append([]byte{}, otaScannedPrefix...), account.Bytes()...)
+}
+
+// otaScanBatch is the number of blocks scanned between two commits of the scan
+// progress.
+const otaScanBatch = 1024
+
+// otaChain is the chain the OTA scanner follows.
+type otaChain interface {
+	Config() *params.ChainConfig
+	CurrentBlock() *types.Block
+	GetBlockByNumber(number uint64) *types.Block
+	GetHeader(hash common.Hash, number uint64) *types.Header
+	GetHeaderByNumber(number uint64) *types.Header
+	StateAt(root common.Hash) (*state.StateDB, error)
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// ReceivedOTA is an OTA paid to a local account, stored by a wancoin or stamp
+// purchase.
+type ReceivedOTA struct {
+	OTA         []byte      // OTA in WAddress format
+	Value       *big.Int    // Value of the OTA
+	BlockNumber uint64      // Block the purchase was included in
+	BlockHash   common.Hash // Hash of the block the purchase was included in
+	TxHash      common.Hash // Hash of the purchase transaction, zero if bought by a contract
+	Stamp       bool        // Whether the OTA was bought from the stamp contract
+}
+
+// otaScanProgress is the last block scanned for the OTAs of an account.
+type otaScanProgress struct {
+	Number uint64
+	Hash   common.Hash
+}
+
+// OTAScanner discovers the OTAs paid to the local accounts. Every OTA stored
+// by a new block, whether bought by a transaction or by a contract call, is
+// tested against the view keys of the accounts and the OTAs they own are
+// recorded in a local index. The blocks are scanned per account, so an account
+// whose keys become available later is brought up to date from where its scan
+// stopped. The OTAs of an account are numbered in the order they are found.
+type OTAScanner struct {
+	chain    otaChain
+	db       ethdb.Database
+	otas     *BlockOTAs
+	keys     func() []*keystore.ViewKey       // Returns the view keys of the accounts to scan for
+	keyImage func(ota []byte) ([]byte, error) // Returns the key image of an OTA of an unlocked account
+
+	lock   sync.Mutex    // Serialises the index updates of scan batches and rescans
+	wakeCh chan struct{} // Channel to request a scan after a rescan was scheduled
+	quit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewOTAScanner creates a scanner following chain, finding the OTAs of the
+// blocks with otas and storing its index in db.
+func NewOTAScanner(chain otaChain, db ethdb.Database, otas *BlockOTAs, keys func() []*keystore.ViewKey, keyImage func(ota []byte) ([]byte, error)) *OTAScanner {
+	return &OTAScanner{
+		chain:    chain,
+		db:       db,
+		otas:     otas,
+		keys:     keys,
+		keyImage: keyImage,
+		wakeCh:   make(chan struct{}, 1),
+		quit:     make(chan struct{}),
+	}
+}
+
+// keystoreViewKeys returns the view keys of the unlocked accounts of the
+// keystores and of the watch-only accounts managed by am.
+func keystoreViewKeys(am *accounts.Manager) func() []*keystore.ViewKey {
+	return func() []*keystore.ViewKey {
+		var keys []*keystore.ViewKey
+		for _, backend := range am.Backends(keystore.KeyStoreType) {
+			keys = append(keys, backend.(*keystore.KeyStore).ViewKeys()...)
+		}
+		for _, backend := range am.Backends(keystore.ViewKeyStoreType) {
+			keys = append(keys, backend.(*keystore.ViewKeyStore).ViewKeys()...)
+		}
+		return keys
+	}
+}
+
+// keystoreKeyImages returns the key images of the OTAs owned by the unlocked
+// accounts of the keystores managed by am.
+func keystoreKeyImages(am *accounts.Manager) func(ota []byte) ([]byte, error) {
+	return func(ota []byte) ([]byte, error) {
+		for _, backend := range am.Backends(keystore.KeyStoreType) {
+			if image, err := backend.(*keystore.KeyStore).OTAKeyImage(ota); err == nil {
+				return image, nil
+			}
+		}
+		return nil, keystore.ErrNoOTAOwner
+	}
+}
+
+// Start starts scanning the new blocks in the background.
+func (s *OTAScanner) Start() {
+	s.wg.Add(1)
+	go s.loop()
+}
+
+// Stop terminates the background scanning.
+func (s *OTAScanner) Stop() {
+	close(s.quit)
+	s.wg.Wait()
+}
+
+func (s *OTAScanner) loop() {
+	defer s.wg.Done()
+
+	headCh := make(chan core.ChainHeadEvent, 16)
+	sub := s.chain.SubscribeChainHeadEvent(headCh)
+	defer sub.Unsubscribe()
+
+	for {
+		s.scan()
+
+		select {
+		case <-headCh:
+		case <-s.wakeCh:
+		case <-sub.Err():
+			return
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// scan brings the index of every account with an available view key up to
+// the current head. The blocks are scanned in batches of otaScanBatch blocks,
+// the lock being released between the batches for the rescans to proceed.
+func (s *OTAScanner) scan() {
+	for s.scanBatch() {
+	}
+}
+
+// scanBatch scans the next batch of blocks for the accounts with an available
+// view key and commits the progress, returning whether the scan goes on.
+func (s *OTAScanner) scanBatch() bool {
+	keys := s.keys()
+	if len(keys) == 0 {
+		return false
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	head := s.chain.CurrentBlock().NumberU64()
+	next := make(map[common.Address]uint64, len(keys))
+	from := head + 1
+	for _, key := range keys {
+		next[key.Address] = s.nextBlock(key.Address)
+		if next[key.Address] < from {
+			from = next[key.Address]
+		}
+	}
+	var last *types.Block
+	for number := from; number <= head && number < from+otaScanBatch && !s.stopped(); number++ {
+		block := s.chain.GetBlockByNumber(number)
+		if block == nil {
+			break
+		}
+		var active []*keystore.ViewKey
+		for _, key := range keys {
+			if next[key.Address] <= number {
+				active = append(active, key)
+			}
+		}
+		s.scanBlock(block, active)
+		last = block
+	}
+	if last == nil {
+		return false
+	}
+	for _, key := range keys {
+		if next[key.Address] <= last.NumberU64() {
+			s.writeProgress(key.Address, last)
+		}
+	}
+	return last.NumberU64() < head && !s.stopped()
+}
+
+// stopped returns whether the scanner was stopped.
+func (s *OTAScanner) stopped() bool {
+	select {
+	case <-s.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+// scanBlock records the OTAs stored by a block that the view keys own.
+func (s *OTAScanner) scanBlock(block *types.Block, keys []*keystore.ViewKey) {
+	for _, received := range s.otas.Get(block) {
+		for _, key := range keys {
+			if !key.IsOTAOwner(received.OTA) {
+				continue
+			}
+			if err := s.addReceived(key.Address, received); err != nil {
+				log.Error("Failed to store received OTA", "account", key.Address, "tx", received.TxHash, "err", err)
+				break
+			}
+			log.Info("Discovered incoming OTA", "account", key.Address, "number", block.Number(), "tx", received.TxHash, "value", received.Value)
+			break
+		}
+	}
+}
+
+// nextBlock returns the first block to scan for an account.
+func (s *OTAScanner) nextBlock(account common.Address) uint64 {
+	return resumeBlock(s.chain, readScanProgress(s.db, otaScannedKey(account)))
+}
+
+func (s *OTAScanner) writeProgress(account common.Address, block *types.Block) {
+	writeScanProgress(s.db, otaScannedKey(account), block)
+}
+
+// readScanProgress returns the last block scanned stored under key, or nil if
+// there is none.
+func readScanProgress(db ethdb.Database, key []byte) *otaScanProgress {
+	data, _ := db.Get(key)
+	if len(data) == 0 {
+		return nil
+	}
+	progress := new(otaScanProgress)
+	if err := rlp.DecodeBytes(data, progress); err != nil {
+		log.Error("Invalid OTA scan progress", "key", common.ToHex(key), "err", err)
+		return nil
+	}
+	return progress
+}
+
+func writeScanProgress(db ethdb.Putter, key []byte, block *types.Block) {
+	data, err := rlp.EncodeToBytes(&otaScanProgress{Number: block.NumberU64(), Hash: block.Hash()})
+	if err != nil {
+		log.Crit("Failed to RLP encode OTA scan progress", "err", err)
+	}
+	if err := db.Put(key, data); err != nil {
+		log.Crit("Failed to store OTA scan progress", "err", err)
+	}
+}
+
+// resumeBlock returns the block a scan resumes at after the last block scanned.
+// If that block was reorged out of the chain, the scan resumes after the block
+// the chains forked at.
+func resumeBlock(chain otaChain, progress *otaScanProgress) uint64 {
+	if progress == nil {
+		return 0
+	}
+	header := chain.GetHeader(progress.Hash, progress.Number)
+	for header != nil {
+		if canonical := chain.GetHeaderByNumber(header.Number.Uint64()); canonical != nil && canonical.Hash() == header.Hash() {
+			return header.Number.Uint64() + 1
+		}
+		if header.Number.Sign() == 0 {
+			break
+		}
+		header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	}
+	return 0
+}
+
+// Rescan schedules the blocks from the given one on to be scanned again for
+// the accounts with an available view key.
+func (s *OTAScanner) Rescan(from uint64) error {
+	head := s.chain.CurrentBlock()
+	if from > head.NumberU64() {
+		return errRescanFuture
+	}
+	s.lock.Lock()
+	for _, key := range s.keys() {
+		if from == 0 {
+			s.db.Delete(otaScannedKey(key.Address))
+		} else if s.nextBlock(key.Address) > from {
+			s.writeProgress(key.Address, s.chain.GetBlockByNumber(from-1))
+		}
+	}
+	s.lock.Unlock()
+
+	select {
+	case s.wakeCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// receivedCount returns the number of OTAs in the index of an account.
+func (s *OTAScanner) receivedCount(account common.Address) uint64 {
+	data, _ := s.db.Get(otaReceivedCountKey(account))
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+func (s *OTAScanner) readReceived(account common.Address) []*ReceivedOTA {
+	count := s.receivedCount(account)
+	received := make([]*ReceivedOTA, 0, count)
+	for ordinal := uint64(0); ordinal < count; ordinal++ {
+		data, _ := s.db.Get(otaReceivedKey(account, ordinal))
+		if len(data) == 0 {
+			continue
+		}
+		r := new(ReceivedOTA)
+		if err := rlp.DecodeBytes(data, r); err != nil {
+			log.Error("Invalid received OTA", "account", account, "ordinal", ordinal, "err", err)
+			continue
+		}
+		received = append(received, r)
+	}
+	return received
+}
+
+// addReceived adds an OTA to the index of an account, replacing the entry of
+// an earlier inclusion of the purchase.
+func (s *OTAScanner) addReceived(account common.Address, ota *ReceivedOTA) error {
+	data, err := rlp.EncodeToBytes(ota)
+	if err != nil {
+		return err
+	}
+	count := s.receivedCount(account)
+	if enc, _ := s.db.Get(otaReceivedOTAKey(account, ota.OTA)); len(enc) == 8 {
+		if ordinal := binary.BigEndian.Uint64(enc); ordinal < count {
+			return s.db.Put(otaReceivedKey(account, ordinal), data)
+		}
+	}
+	batch := s.db.NewBatch()
+	batch.Put(otaReceivedKey(account, count), data)
+	batch.Put(otaReceivedOTAKey(account, ota.OTA), encodeOrdinal(count))
+	batch.Put(otaReceivedCountKey(account), encodeOrdinal(count+1))
+	return batch.Write()
+}
+
+// removeReceived drops an OTA from the index of an account.
+func (s *OTAScanner) removeReceived(account common.Address, ota []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	enc, _ := s.db.Get(otaReceivedOTAKey(account, ota))
+	if len(enc) != 8 {
+		return nil
+	}
+	if err := s.db.Delete(otaReceivedKey(account, binary.BigEndian.Uint64(enc))); err != nil {
+		return err
+	}
+	return s.db.Delete(otaReceivedOTAKey(account, ota))
+}
+
+// Received returns the OTAs paid to an account by the canonical chain.
+func (s *OTAScanner) Received(account common.Address) []*ReceivedOTA {
+	var received []*ReceivedOTA
+	for _, r := range s.readReceived(account) {
+		if header := s.chain.GetHeaderByNumber(r.BlockNumber); header != nil && header.Hash() == r.BlockHash {
+			received = append(received, r)
+		}
+	}
+	return received
+}
+
+// Balance returns the total value of the unspent OTAs paid to an account. The
+// spent OTAs are known by their key images, which can only be computed for the
+// OTAs of the unlocked accounts: the balance of a locked or watch-only account
+// counts its spent OTAs.
+func (s *OTAScanner) Balance(account common.Address) (*big.Int, error) {
+	statedb, err := s.chain.StateAt(s.chain.CurrentBlock().Root())
+	if err != nil {
+		return nil, err
+	}
+	balance := new(big.Int)
+	for _, r := range s.Received(account) {
+		if !s.keyImageSpent(statedb, r.OTA) {
+			balance.Add(balance, r.Value)
+		}
+	}
+	return balance, nil
+}
+
+// keyImageSpent returns whether the key image of an OTA of an unlocked account
+// is in statedb.
+func (s *OTAScanner) keyImageSpent(statedb *state.StateDB, ota []byte) bool {
+	image, err := s.keyImage(ota)
+	if err != nil {
+		return false
+	}
+	exist, _, err := vm.CheckOTAImageExist(statedb, image)
+	return err == nil && exist
+}
@@ -0,0 +1,279 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package eth
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/accounts/abi"
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/event"
+	"github.com/wanchain/go-wanchain/params"
+)
+
+// testOTAChain is a chain kept in a database without any verification.
+type testOTAChain struct {
+	db   ethdb.Database
+	head *types.Block
+	feed event.Feed
+}
+
+func newTestOTAChain(t *testing.T) *testOTAChain {
+	db, _ := ethdb.NewMemDatabase()
+	chain := &testOTAChain{db: db}
+	chain.insert(t, types.NewBlock(&types.Header{Number: new(big.Int)}, nil, nil, nil), nil)
+	return chain
+}
+
+// insert makes block the canonical block of its number and the head.
+func (c *testOTAChain) insert(t *testing.T, block *types.Block, receipts types.Receipts) {
+	if err := core.WriteBlock(c.db, block); err != nil {
+		t.Fatal(err)
+	}
+	if err := core.WriteCanonicalHash(c.db, block.Hash(), block.NumberU64()); err != nil {
+		t.Fatal(err)
+	}
+	if err := core.WriteBlockReceipts(c.db, block.Hash(), block.NumberU64(), receipts); err != nil {
+		t.Fatal(err)
+	}
+	if err := core.WriteTxLookupEntries(c.db, block); err != nil {
+		t.Fatal(err)
+	}
+	c.head = block
+}
+
+func (c *testOTAChain) Config() *params.ChainConfig { return params.TestChainConfig }
+
+func (c *testOTAChain) CurrentBlock() *types.Block { return c.head }
+
+func (c *testOTAChain) GetBlockByNumber(number uint64) *types.Block {
+	if number > c.head.NumberU64() {
+		return nil
+	}
+	return core.GetBlock(c.db, core.GetCanonicalHash(c.db, number), number)
+}
+
+func (c *testOTAChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return core.GetHeader(c.db, hash, number)
+}
+
+func (c *testOTAChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number > c.head.NumberU64() {
+		return nil
+	}
+	return core.GetHeader(c.db, core.GetCanonicalHash(c.db, number), number)
+}
+
+func (c *testOTAChain) StateAt(root common.Hash) (*state.StateDB, error) {
+	return state.New(root, state.NewDatabase(c.db))
+}
+
+func (c *testOTAChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return c.feed.Subscribe(ch)
+}
+
+// newTestViewKey creates the view key of a random WAddress and returns it with
+// the WAddress.
+func newTestViewKey(t *testing.T) (*keystore.ViewKey, *common.WAddress) {
+	a, _ := crypto.GenerateKey()
+	b, _ := crypto.GenerateKey()
+	key := &keystore.ViewKey{
+		Address: crypto.PubkeyToAddress(a.PublicKey),
+		A:       &a.PublicKey,
+		B:       b,
+	}
+	return key, keystore.GenerateWaddressFromPK(&a.PublicKey, &b.PublicKey)
+}
+
+// newTestOTA generates an OTA for a WAddress.
+func newTestOTA(t *testing.T, wAddr *common.WAddress) []byte {
+	A, B, err := keystore.GeneratePKPairFromWAddress(wAddr[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	pks := hexutil.PKPair2HexSlice(A, B)
+	ota, err := crypto.GenerateOneTimeKey(pks[0], pks[1], pks[2], pks[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := common.FromHex(strings.Replace(strings.Join(ota, ""), "0x", "", -1))
+	otaAddr, err := keystore.WaddrFromUncompressedRawBytes(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return otaAddr[:]
+}
+
+var (
+	testCoinAddr  = common.BytesToAddress([]byte{100})
+	testCoinValue = new(big.Int).Mul(big.NewInt(10), big.NewInt(1e18))
+	testCoinABI   = `[{"constant": false,"type": "function","stateMutability": "nonpayable","inputs": [{"name": "OtaAddr","type":"string"},{"name": "Value","type": "uint256"}],"name": "buyCoinNote","outputs": [{"name": "OtaAddr","type":"string"},{"name": "Value","type": "uint256"}]}]`
+)
+
+// newBuyCoinTx creates a transaction buying a wancoin for an OTA.
+func newBuyCoinTx(t *testing.T, nonce uint64, ota []byte) *types.Transaction {
+	coinAbi, err := abi.JSON(strings.NewReader(testCoinABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := coinAbi.Pack("buyCoinNote", hexutil.Encode(ota), testCoinValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return types.NewTransaction(nonce, testCoinAddr, testCoinValue, big.NewInt(100000), big.NewInt(1), payload)
+}
+
+// newTestOTABlock creates a block on top of parent including txs, whose
+// execution fails for the ones marked in failed.
+func newTestOTABlock(parent *types.Block, extra byte, txs []*types.Transaction, failed map[int]bool) (*types.Block, types.Receipts) {
+	receipts := make(types.Receipts, len(txs))
+	for i, tx := range txs {
+		receipts[i] = types.NewReceipt(nil, failed[i], new(big.Int))
+		receipts[i].TxHash = tx.Hash()
+	}
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		Extra:      []byte{extra},
+	}
+	return types.NewBlock(header, txs, nil, receipts), receipts
+}
+
+// newTestOTAStateBlock creates a block on top of parent without transactions,
+// whose state is the one of parent updated by update, as contract calls
+// buying or spending OTAs update it.
+func newTestOTAStateBlock(t *testing.T, chain *testOTAChain, parent *types.Block, update func(statedb *state.StateDB)) *types.Block {
+	statedb, err := chain.StateAt(parent.Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	update(statedb)
+	root, err := statedb.CommitTo(chain.db, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		Root:       root,
+	}
+	return types.NewBlock(header, nil, nil, nil)
+}
+
+func TestOTAScanner(t *testing.T) {
+	chain := newTestOTAChain(t)
+	key1, wAddr1 := newTestViewKey(t)
+	key2, wAddr2 := newTestViewKey(t)
+
+	keys := []*keystore.ViewKey{key1}
+	images := make(map[string][]byte)
+	keyImage := func(ota []byte) ([]byte, error) {
+		if image, ok := images[string(ota)]; ok {
+			return image, nil
+		}
+		return nil, keystore.ErrNoOTAOwner
+	}
+	scanner := NewOTAScanner(chain, chain.db, NewBlockOTAs(chain, chain.db), func() []*keystore.ViewKey { return keys }, keyImage)
+
+	// Block 1 pays both accounts, block 2 pays account 1 with a failing purchase
+	ota1, ota2, otaFailed := newTestOTA(t, wAddr1), newTestOTA(t, wAddr2), newTestOTA(t, wAddr1)
+	genesis := chain.head
+	block1, receipts1 := newTestOTABlock(genesis, 0, []*types.Transaction{newBuyCoinTx(t, 0, ota1), newBuyCoinTx(t, 1, ota2)}, nil)
+	chain.insert(t, block1, receipts1)
+	block2, receipts2 := newTestOTABlock(block1, 0, []*types.Transaction{newBuyCoinTx(t, 2, otaFailed)}, map[int]bool{0: true})
+	chain.insert(t, block2, receipts2)
+
+	scanner.scan()
+	if received := scanner.Received(key1.Address); len(received) != 1 || !bytes.Equal(received[0].OTA, ota1) || received[0].BlockHash != block1.Hash() {
+		t.Fatalf("received OTAs of account 1 mismatch: have %v", received)
+	}
+	if balance, err := scanner.Balance(key1.Address); err != nil || balance.Cmp(testCoinValue) != 0 {
+		t.Errorf("balance of account 1 mismatch: have %v (%v), want %v", balance, err, testCoinValue)
+	}
+	if received := scanner.Received(key2.Address); len(received) != 0 {
+		t.Fatalf("account 2 scanned without its key: have %d OTAs", len(received))
+	}
+
+	// An account whose key becomes available is scanned from the start
+	keys = append(keys, key2)
+	scanner.scan()
+	if received := scanner.Received(key2.Address); len(received) != 1 || !bytes.Equal(received[0].OTA, ota2) {
+		t.Fatalf("received OTAs of account 2 mismatch: have %v", received)
+	}
+
+	// A reorg drops the OTAs of the replaced blocks and scans the new ones
+	ota3 := newTestOTA(t, wAddr1)
+	fork1, forkReceipts1 := newTestOTABlock(genesis, 1, []*types.Transaction{newBuyCoinTx(t, 0, ota3)}, nil)
+	chain.insert(t, fork1, forkReceipts1)
+	fork2, forkReceipts2 := newTestOTABlock(fork1, 1, nil, nil)
+	chain.insert(t, fork2, forkReceipts2)
+
+	if received := scanner.Received(key2.Address); len(received) != 0 {
+		t.Fatalf("reorged OTAs of account 2 reported: have %d OTAs", len(received))
+	}
+	if next := scanner.nextBlock(key1.Address); next != 1 {
+		t.Fatalf("next block after reorg mismatch: have %d, want 1", next)
+	}
+	scanner.scan()
+	if received := scanner.Received(key1.Address); len(received) != 1 || !bytes.Equal(received[0].OTA, ota3) {
+		t.Fatalf("received OTAs of account 1 after reorg mismatch: have %v", received)
+	}
+
+	// A rescan finds the OTAs again
+	chain.db.Delete(otaReceivedCountKey(key1.Address))
+	if err := scanner.Rescan(chain.head.NumberU64() + 1); err != errRescanFuture {
+		t.Fatalf("rescan from the future: have %v, want %v", err, errRescanFuture)
+	}
+	if err := scanner.Rescan(1); err != nil {
+		t.Fatal(err)
+	}
+	if next := scanner.nextBlock(key1.Address); next != 1 {
+		t.Fatalf("next block after rescan mismatch: have %d, want 1", next)
+	}
+	scanner.scan()
+	if received := scanner.Received(key1.Address); len(received) != 1 || !bytes.Equal(received[0].OTA, ota3) {
+		t.Fatalf("received OTAs of account 1 after rescan mismatch: have %v", received)
+	}
+
+	// The OTAs bought by contract calls are found in the state, and the spent
+	// OTAs of unlocked accounts are left out of the balance
+	ota4 := newTestOTA(t, wAddr1)
+	images[string(ota3)] = []byte("ota3 key image")
+	block3 := newTestOTAStateBlock(t, chain, chain.head, func(statedb *state.StateDB) {
+		if _, err := vm.AddOTAIfNotExist(statedb, testCoinValue, ota4); err != nil {
+			t.Fatal(err)
+		}
+		if err := vm.AddOTAImage(statedb, images[string(ota3)], testCoinValue.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+	})
+	chain.insert(t, block3, nil)
+	scanner.scan()
+	received := scanner.Received(key1.Address)
+	if len(received) != 2 || !bytes.Equal(received[1].OTA, ota4) || received[1].TxHash != (common.Hash{}) || received[1].Stamp {
+		t.Fatalf("received OTAs of account 1 after a contract purchase mismatch: have %v", received)
+	}
+	if balance, err := scanner.Balance(key1.Address); err != nil || balance.Cmp(testCoinValue) != 0 {
+		t.Errorf("balance of account 1 after a spend mismatch: have %v (%v), want %v", balance, err, testCoinValue)
+	}
+
+	// Scanning the blocks again replaces the recorded OTAs
+	if err := scanner.Rescan(1); err != nil {
+		t.Fatal(err)
+	}
+	scanner.scan()
+	if count := scanner.receivedCount(key1.Address); count != 2 {
+		t.Fatalf("received OTA count of account 1 after a second rescan mismatch: have %d, want 2", count)
+	}
+}
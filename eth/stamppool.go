@@ -0,0 +1,302 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package eth
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/wanchain/go-wanchain/accounts"
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/internal/ethapi"
+	"github.com/wanchain/go-wanchain/log"
+)
+
+var otaStampSpentPrefix = []byte("ota-stamp-spent-") // otaStampSpentPrefix + OTA -> hash of the transaction spending it
+
+func otaStampSpentKey(ota []byte) []byte {
+	return append(append([]byte{}, otaStampSpentPrefix...), ota...)
+}
+
+// stampSpendConfirmations is the number of blocks on top of the one including
+// the transaction spending a stamp after which the stamp is pruned from the
+// pool.
+const stampSpendConfirmations = 12
+
+// stampChain is the chain the stamp pool checks the key images of the stamps
+// against.
+type stampChain interface {
+	CurrentBlock() *types.Block
+	State() (*state.StateDB, error)
+}
+
+// stampTxPool is the transaction pool the stamp pool follows the transactions
+// it recorded in.
+type stampTxPool interface {
+	Get(hash common.Hash) *types.Transaction
+}
+
+// stampPurchase is a stamp purchase sent by a local account.
+type stampPurchase struct {
+	ota    []byte      // OTA the stamp is paid to
+	txHash common.Hash // Hash of the purchase transaction
+}
+
+// stampsLow is a request to refill the stamps of an account that ran low.
+type stampsLow struct {
+	account common.Address
+	value   *big.Int // Value of the stamp spent last
+}
+
+// StampPool keeps the stamps owned by the local accounts, the OTAs bought from
+// the stamp contract that the OTA scanner found for them. A stamp is spent once
+// a transaction recorded as spending it is pending or included in the chain,
+// or once its key image is in the state, which can only be computed for the
+// stamps of the unlocked accounts. A stamp whose spending transaction is
+// confirmed is pruned from the pool and from the index of the scanner. If
+// enabled, an account whose stamps run low after a spend is refilled in the
+// background if it is unlocked.
+type StampPool struct {
+	scanner    *OTAScanner
+	chain      stampChain
+	txPool     stampTxPool
+	db         ethdb.Database
+	autoRefill bool // Whether to refill the unlocked accounts running low
+
+	lock      sync.Mutex
+	purchases map[common.Address][]stampPurchase // Stamp purchases not found by the scanner yet
+	refilling map[common.Address]bool            // Accounts whose stamps are being refilled
+
+	lowCh chan stampsLow
+	quit  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// NewStampPool creates a stamp pool of the stamps found by scanner, refilling
+// the unlocked accounts running low if refill is set.
+func NewStampPool(scanner *OTAScanner, chain stampChain, txPool stampTxPool, db ethdb.Database, refill bool) *StampPool {
+	return &StampPool{
+		scanner:    scanner,
+		chain:      chain,
+		txPool:     txPool,
+		db:         db,
+		autoRefill: refill,
+		purchases:  make(map[common.Address][]stampPurchase),
+		refilling:  make(map[common.Address]bool),
+		lowCh:      make(chan stampsLow, 16),
+		quit:       make(chan struct{}),
+	}
+}
+
+// Start starts refilling, in the background, the stamps of the unlocked
+// accounts of am that run low, sending the purchases through b. Nothing is
+// refilled unless the pool was created with refill set.
+func (p *StampPool) Start(b ethapi.Backend, am *accounts.Manager) {
+	p.wg.Add(1)
+	go p.loop(b, am)
+}
+
+// Stop terminates the background refills.
+func (p *StampPool) Stop() {
+	close(p.quit)
+	p.wg.Wait()
+}
+
+func (p *StampPool) loop(b ethapi.Backend, am *accounts.Manager) {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case low := <-p.lowCh:
+			p.refill(b, am, low)
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// refill buys the stamps missing to an account with the keystore holding it
+// unlocked. The stamps of locked accounts are refilled by their next refund.
+func (p *StampPool) refill(b ethapi.Backend, am *accounts.Manager, low stampsLow) {
+	if !p.BeginRefill(low.account) {
+		return
+	}
+	defer p.EndRefill(low.account)
+
+	var ks *keystore.KeyStore
+	for _, backend := range am.Backends(keystore.KeyStoreType) {
+		if backend.(*keystore.KeyStore).HasAddress(low.account) {
+			ks = backend.(*keystore.KeyStore)
+			break
+		}
+	}
+	if ks == nil {
+		return
+	}
+	var chainID *big.Int
+	if config := b.ChainConfig(); config != nil {
+		chainID = config.ChainId
+	}
+	account := accounts.Account{Address: low.account}
+	sign := func(tx *types.Transaction) (*types.Transaction, error) {
+		return ks.SignTx(account, tx, chainID)
+	}
+	if err := ethapi.RefillStamps(context.Background(), b, ks, account, sign, p, low.value); err != nil {
+		log.Debug("Failed to refill stamp pool", "account", low.account, "err", err)
+	}
+}
+
+// stampsByValue sorts stamps cheapest first.
+type stampsByValue []*ethapi.Stamp
+
+func (s stampsByValue) Len() int           { return len(s) }
+func (s stampsByValue) Less(i, j int) bool { return s[i].Value.ToInt().Cmp(s[j].Value.ToInt()) < 0 }
+func (s stampsByValue) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// Stamps implements ethapi.StampPool.
+func (p *StampPool) Stamps(account common.Address) []*ethapi.Stamp {
+	statedb, err := p.chain.State()
+	if err != nil {
+		log.Warn("Failed to retrieve state to check stamps", "err", err)
+		return nil
+	}
+	head := p.chain.CurrentBlock().NumberU64()
+
+	var stamps []*ethapi.Stamp
+	for _, r := range p.scanner.Received(account) {
+		if !r.Stamp || p.spent(statedb, head, account, r.OTA) {
+			continue
+		}
+		stamps = append(stamps, &ethapi.Stamp{
+			OTA:   common.CopyBytes(r.OTA),
+			Value: (*hexutil.Big)(new(big.Int).Set(r.Value)),
+		})
+	}
+	sort.Stable(stampsByValue(stamps))
+	return stamps
+}
+
+// spent returns whether a stamp of an account is spent by a recorded
+// transaction, or has its key image in statedb. A stamp whose transaction is
+// confirmed on top of the head block is pruned.
+func (p *StampPool) spent(statedb *state.StateDB, head uint64, account common.Address, ota []byte) bool {
+	if data, _ := p.db.Get(otaStampSpentKey(ota)); len(data) == common.HashLength {
+		txHash := common.BytesToHash(data)
+		if p.txPool.Get(txHash) != nil {
+			return true
+		}
+		if tx, _, number, _ := core.GetTransaction(p.db, txHash); tx != nil {
+			if number+stampSpendConfirmations <= head {
+				p.prune(account, ota)
+			}
+			return true
+		}
+	}
+	return p.scanner.keyImageSpent(statedb, ota)
+}
+
+// prune drops a stamp of an account whose spend is confirmed, with its spend
+// record. A rescan finds the stamp again, which is then known spent by its key
+// image only if the account is unlocked.
+func (p *StampPool) prune(account common.Address, ota []byte) {
+	if err := p.scanner.removeReceived(account, ota); err != nil {
+		log.Error("Failed to prune spent stamp", "account", account, "err", err)
+		return
+	}
+	if err := p.db.Delete(otaStampSpentKey(ota)); err != nil {
+		log.Error("Failed to prune stamp spend record", "account", account, "err", err)
+	}
+}
+
+// PendingPurchases implements ethapi.StampPool. A purchase is pending until
+// its stamp is found by the scanner, or until its transaction is dropped or
+// fails.
+func (p *StampPool) PendingPurchases(account common.Address) int {
+	received := make(map[string]bool)
+	for _, r := range p.scanner.Received(account) {
+		received[string(r.OTA)] = true
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var pending []stampPurchase
+	for _, purchase := range p.purchases[account] {
+		if !received[string(purchase.ota)] && p.purchasePending(purchase.txHash) {
+			pending = append(pending, purchase)
+		}
+	}
+	if len(pending) == 0 {
+		delete(p.purchases, account)
+	} else {
+		p.purchases[account] = pending
+	}
+	return len(pending)
+}
+
+// purchasePending returns whether a purchase transaction is pending or was
+// included successfully.
+func (p *StampPool) purchasePending(txHash common.Hash) bool {
+	if p.txPool.Get(txHash) != nil {
+		return true
+	}
+	receipt, _, _, _ := core.GetReceipt(p.db, txHash)
+	return receipt != nil && receipt.Status == types.ReceiptStatusSuccessful
+}
+
+// Spend implements ethapi.StampPool.
+func (p *StampPool) Spend(account common.Address, ota []byte, txHash common.Hash) {
+	if err := p.db.Put(otaStampSpentKey(ota), txHash.Bytes()); err != nil {
+		log.Error("Failed to record spent stamp", "tx", txHash, "err", err)
+	}
+	if !p.autoRefill || len(p.Stamps(account))+p.PendingPurchases(account) >= ethapi.StampPoolLowWater {
+		return
+	}
+	for _, r := range p.scanner.Received(account) {
+		if bytes.Equal(r.OTA, ota) {
+			select {
+			case p.lowCh <- stampsLow{account: account, value: new(big.Int).Set(r.Value)}:
+			default:
+				log.Debug("Stamp refill queue full", "account", account)
+			}
+			return
+		}
+	}
+}
+
+// Purchase implements ethapi.StampPool.
+func (p *StampPool) Purchase(account common.Address, ota []byte, txHash common.Hash) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.purchases[account] = append(p.purchases[account], stampPurchase{ota: common.CopyBytes(ota), txHash: txHash})
+}
+
+// BeginRefill implements ethapi.StampPool.
+func (p *StampPool) BeginRefill(account common.Address) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.refilling[account] {
+		return false
+	}
+	p.refilling[account] = true
+	return true
+}
+
+// EndRefill implements ethapi.StampPool.
+func (p *StampPool) EndRefill(account common.Address) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	delete(p.refilling, account)
+}
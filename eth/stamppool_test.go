@@ -0,0 +1,170 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package eth
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+)
+
+// testStampChain is a stamp chain with a fixed state.
+type testStampChain struct {
+	*testOTAChain
+	statedb *state.StateDB
+}
+
+func (c *testStampChain) State() (*state.StateDB, error) { return c.statedb, nil }
+
+// testStampTxPool is a transaction pool of the transactions added to it.
+type testStampTxPool map[common.Hash]*types.Transaction
+
+func (p testStampTxPool) Get(hash common.Hash) *types.Transaction { return p[hash] }
+
+// newBuyStampTx creates a transaction buying a stamp of value for an OTA.
+func newBuyStampTx(t *testing.T, nonce uint64, ota []byte, value *big.Int) *types.Transaction {
+	to, payload, err := vm.PackBuyStamp(ota, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return types.NewTransaction(nonce, to, value, big.NewInt(100000), big.NewInt(1), payload)
+}
+
+func TestStampPool(t *testing.T) {
+	chain := newTestOTAChain(t)
+	key, wAddr := newTestViewKey(t)
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(chain.db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		txPool   = make(testStampTxPool)
+		images   = make(map[string][]byte)
+		keyImage = func(ota []byte) ([]byte, error) {
+			if image, ok := images[string(ota)]; ok {
+				return image, nil
+			}
+			return nil, errors.New("locked")
+		}
+		scanner = NewOTAScanner(chain, chain.db, NewBlockOTAs(chain, chain.db), func() []*keystore.ViewKey { return []*keystore.ViewKey{key} }, keyImage)
+		pool    = NewStampPool(scanner, &testStampChain{chain, statedb}, txPool, chain.db, true)
+	)
+
+	// Only the OTAs bought from the stamp contract are stamps, sorted cheapest first
+	dear, cheap, coin := newTestOTA(t, wAddr), newTestOTA(t, wAddr), newTestOTA(t, wAddr)
+	dearTx := newBuyStampTx(t, 0, dear, big.NewInt(2e17))
+	block, receipts := newTestOTABlock(chain.head, 0, []*types.Transaction{
+		dearTx,
+		newBuyStampTx(t, 1, cheap, big.NewInt(9e16)),
+		newBuyCoinTx(t, 2, coin),
+	}, nil)
+	chain.insert(t, block, receipts)
+	scanner.scan()
+
+	stamps := pool.Stamps(key.Address)
+	if len(stamps) != 2 || !bytes.Equal(stamps[0].OTA, cheap) || !bytes.Equal(stamps[1].OTA, dear) {
+		t.Fatalf("stamps mismatch: have %v", stamps)
+	}
+
+	// A stamp is spent while its recorded transaction is pending or included
+	spender := newBuyCoinTx(t, 3, newTestOTA(t, wAddr))
+	pool.Spend(key.Address, cheap, spender.Hash())
+	txPool[spender.Hash()] = spender
+	if stamps := pool.Stamps(key.Address); len(stamps) != 1 || !bytes.Equal(stamps[0].OTA, dear) {
+		t.Fatalf("stamps with a pending spend mismatch: have %v", stamps)
+	}
+	delete(txPool, spender.Hash())
+	if stamps := pool.Stamps(key.Address); len(stamps) != 2 {
+		t.Fatalf("stamp of a dropped spend not returned: have %v", stamps)
+	}
+	pool.Spend(key.Address, cheap, dearTx.Hash())
+	if stamps := pool.Stamps(key.Address); len(stamps) != 1 || !bytes.Equal(stamps[0].OTA, dear) {
+		t.Fatalf("stamps with an included spend mismatch: have %v", stamps)
+	}
+
+	// Spending a stamp leaving the account low on stamps requests a refill
+	// with stamps of the spent value
+	select {
+	case low := <-pool.lowCh:
+		if low.account != key.Address || low.value.Cmp(big.NewInt(9e16)) != 0 {
+			t.Fatalf("refill request mismatch: have %x %v", low.account, low.value)
+		}
+	default:
+		t.Fatalf("spend leaving one stamp didn't request a refill")
+	}
+	if len(pool.lowCh) != 0 {
+		t.Fatalf("spend leaving two stamps requested a refill")
+	}
+	manual := NewStampPool(scanner, &testStampChain{chain, statedb}, txPool, chain.db, false)
+	manual.Spend(key.Address, cheap, dearTx.Hash())
+	if len(manual.lowCh) != 0 {
+		t.Fatalf("pool without refill requested a refill")
+	}
+	if !pool.BeginRefill(key.Address) || pool.BeginRefill(key.Address) {
+		t.Fatalf("refill of an account not reserved once")
+	}
+	pool.EndRefill(key.Address)
+
+	// A stamp is spent once its key image is in the state
+	images[string(dear)] = []byte("dear key image")
+	if err := vm.AddOTAImage(statedb, images[string(dear)], big.NewInt(2e17).Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if stamps := pool.Stamps(key.Address); len(stamps) != 0 {
+		t.Fatalf("stamps with a spent key image mismatch: have %v", stamps)
+	}
+
+	// A purchase is pending until it is found by the scanner, dropped or failed
+	bought, dropped := newTestOTA(t, wAddr), newTestOTA(t, wAddr)
+	boughtTx, droppedTx := newBuyStampTx(t, 4, bought, big.NewInt(9e16)), newBuyStampTx(t, 5, dropped, big.NewInt(9e16))
+	txPool[boughtTx.Hash()], txPool[droppedTx.Hash()] = boughtTx, droppedTx
+	pool.Purchase(key.Address, bought, boughtTx.Hash())
+	pool.Purchase(key.Address, dropped, droppedTx.Hash())
+	if pending := pool.PendingPurchases(key.Address); pending != 2 {
+		t.Fatalf("pending purchases mismatch: have %d, want 2", pending)
+	}
+	delete(txPool, droppedTx.Hash())
+	if pending := pool.PendingPurchases(key.Address); pending != 1 {
+		t.Fatalf("pending purchases after drop mismatch: have %d, want 1", pending)
+	}
+	delete(txPool, boughtTx.Hash())
+	block, receipts = newTestOTABlock(chain.head, 0, []*types.Transaction{boughtTx}, nil)
+	chain.insert(t, block, receipts)
+	if pending := pool.PendingPurchases(key.Address); pending != 1 {
+		t.Fatalf("pending purchases after inclusion mismatch: have %d, want 1", pending)
+	}
+	scanner.scan()
+	if pending := pool.PendingPurchases(key.Address); pending != 0 {
+		t.Fatalf("pending purchases after scan mismatch: have %d, want 0", pending)
+	}
+	if stamps := pool.Stamps(key.Address); len(stamps) != 1 || !bytes.Equal(stamps[0].OTA, bought) {
+		t.Fatalf("stamps after purchase mismatch: have %v", stamps)
+	}
+
+	// A stamp whose spend is confirmed is pruned with its spend record
+	if has, _ := chain.db.Has(otaStampSpentKey(cheap)); !has {
+		t.Fatalf("spend record of an unconfirmed spend pruned")
+	}
+	for chain.head.NumberU64() < 1+stampSpendConfirmations {
+		block, receipts = newTestOTABlock(chain.head, 0, nil, nil)
+		chain.insert(t, block, receipts)
+	}
+	if stamps := pool.Stamps(key.Address); len(stamps) != 1 || !bytes.Equal(stamps[0].OTA, bought) {
+		t.Fatalf("stamps after a confirmed spend mismatch: have %v", stamps)
+	}
+	if has, _ := chain.db.Has(otaStampSpentKey(cheap)); has {
+		t.Fatalf("spend record of a confirmed spend not pruned")
+	}
+	for _, r := range scanner.Received(key.Address) {
+		if bytes.Equal(r.OTA, cheap) {
+			t.Fatalf("stamp of a confirmed spend not pruned from the scanner")
+		}
+	}
+}
@@ -267,6 +267,9 @@ type rpcProgress struct {
 	HighestBlock  hexutil.Uint64
 	PulledStates  hexutil.Uint64
 	KnownStates   hexutil.Uint64
+
+	PulledEpochGeneses hexutil.Uint64
+	KnownEpochGeneses  hexutil.Uint64
 }
 
 // SyncProgress retrieves the current progress of the sync algorithm. If there's
@@ -291,6 +294,9 @@ func (ec *Client) SyncProgress(ctx context.Context) (*ethereum.SyncProgress, err
 		HighestBlock:  uint64(progress.HighestBlock),
 		PulledStates:  uint64(progress.PulledStates),
 		KnownStates:   uint64(progress.KnownStates),
+
+		PulledEpochGeneses: uint64(progress.PulledEpochGeneses),
+		KnownEpochGeneses:  uint64(progress.KnownEpochGeneses),
 	}, nil
 }
 
@@ -477,6 +483,33 @@ func (ec *Client) SendTransaction(ctx context.Context, tx *types.Transaction) er
 	return ec.c.CallContext(ctx, nil, "eth_sendRawTransaction", common.ToHex(data))
 }
 
+// GetOTAMixSet returns size OTAs, in WAddress format, holding the same value as
+// ota, to hide it among in a ring signature. The decoys are selected as by q,
+// or uniformly if q is nil.
+func (ec *Client) GetOTAMixSet(ctx context.Context, ota []byte, size int, q *ethereum.DecoyQuery) ([][]byte, error) {
+	args := []interface{}{hexutil.Encode(ota), size}
+	if q != nil {
+		args = append(args, toDecoyArg(*q))
+	}
+	var result []hexutil.Bytes
+	if err := ec.c.CallContext(ctx, &result, "wan_getOTAMixSet", args...); err != nil {
+		return nil, err
+	}
+	set := make([][]byte, len(result))
+	for i, mix := range result {
+		set[i] = mix
+	}
+	return set, nil
+}
+
+func toDecoyArg(q ethereum.DecoyQuery) interface{} {
+	return map[string]interface{}{
+		"strategy":     q.Strategy,
+		"halfLife":     hexutil.Uint64(q.HalfLife),
+		"excludeSpent": q.ExcludeSpent,
+	}
+}
+
 func toCallArg(msg ethereum.CallMsg) interface{} {
 	arg := map[string]interface{}{
 		"from": msg.From,
@@ -103,6 +103,9 @@ type SyncProgress struct {
 	HighestBlock  uint64 // Highest alleged block number in the chain
 	PulledStates  uint64 // Number of state trie entries already downloaded
 	KnownStates   uint64 // Total number os state trie entries known about
+
+	PulledEpochGeneses uint64 // Number of epoch geneses already downloaded
+	KnownEpochGeneses  uint64 // Total number of epoch geneses known about
 }
 
 // ChainSyncReader wraps access to the node's current sync status. If there's no
@@ -160,6 +163,16 @@ type LogFilterer interface {
 	SubscribeFilterLogs(ctx context.Context, q FilterQuery, ch chan<- types.Log) (Subscription, error)
 }
 
+// DecoyQuery contains options for the selection of the decoys hiding an OTA in a
+// ring signature. The zero value selects the decoys uniformly. ExcludeSpent only
+// skips the OTAs spent by rings of size one, the spender of a larger ring stays
+// hidden among its decoys.
+type DecoyQuery struct {
+	Strategy     string // "uniform", or "recent" to favour recently bought OTAs
+	HalfLife     uint64 // number of newer OTAs halving the weight of an OTA with the "recent" strategy, 0 for the default
+	ExcludeSpent bool   // skip the OTAs known to be spent
+}
+
 // TransactionSender wraps transaction sending. The SendTransaction method injects a
 // signed transaction into the pending transaction pool for execution. If the transaction
 // was a contract creation, the TransactionReceipt method can be used to retrieve the
@@ -66,6 +66,11 @@ var (
 	ErrReqTooManyOTAMix                 = errors.New("Require too many OTA mix address")
 	ErrInvalidOTAMixNum                 = errors.New("Invalid required OTA mix address number")
 	ErrInvalidInput                     = errors.New("Invalid input")
+	ErrNoViewKeyStore                   = errors.New("View keys not supported")
+	ErrNoStampOTA                       = errors.New("No stamp OTA to pay the privacy transaction with")
+	ErrDecoyQueryNoIndex                = errors.New("Decoy selection requires the OTA index, enable it with --otaindex")
+	ErrOTAIndexSyncing                  = errors.New("OTA index is still syncing, try again later")
+	ErrReqTooManyKeyImages              = errors.New("Require too many OTA key images")
 )
 
 // PublicEthereumAPI provides an API to access Ethereum related information.
@@ -104,11 +109,13 @@ func (s *PublicEthereumAPI) ProtocolVersion() hexutil.Uint {
 // - highestBlock:  block number of the highest block header this node has received from peers
 // - pulledStates:  number of state entries processed until now
 // - knownStates:   number of known state entries that still need to be pulled
+// - pulledEpochGeneses: number of epoch geneses downloaded until now
+// - knownEpochGeneses:  number of epoch geneses the sync needs
 func (s *PublicEthereumAPI) Syncing() (interface{}, error) {
 	progress := s.b.Downloader().Progress()
 
 	// Return not syncing if the synchronisation already completed
-	if progress.CurrentBlock >= progress.HighestBlock {
+	if progress.CurrentBlock >= progress.HighestBlock && progress.PulledEpochGeneses >= progress.KnownEpochGeneses {
 		return false, nil
 	}
 	// Otherwise gather the block sync stats
@@ -118,6 +125,9 @@ func (s *PublicEthereumAPI) Syncing() (interface{}, error) {
 		"highestBlock":  hexutil.Uint64(progress.HighestBlock),
 		"pulledStates":  hexutil.Uint64(progress.PulledStates),
 		"knownStates":   hexutil.Uint64(progress.KnownStates),
+
+		"pulledEpochGeneses": hexutil.Uint64(progress.PulledEpochGeneses),
+		"knownEpochGeneses":  hexutil.Uint64(progress.KnownEpochGeneses),
 	}, nil
 }
 
@@ -375,6 +385,27 @@ func (s *PrivateAccountAPI) ImportRawKey(privkey0, privkey1, privkey2 string, pa
 	return acc.Address, err
 }
 
+// ExportViewKey returns the view key of an account as JSON. The view key lets
+// a watch-only node recognise the OTAs paid to the account, but can't spend them.
+func (s *PrivateAccountAPI) ExportViewKey(addr common.Address, password string) (string, error) {
+	keyJSON, err := fetchKeystore(s.am).ExportViewKey(accounts.Account{Address: addr}, password)
+	if err != nil {
+		return "", err
+	}
+	return string(keyJSON), nil
+}
+
+// ImportViewKey adds a watch-only account for a view key exported by
+// ExportViewKey.
+func (s *PrivateAccountAPI) ImportViewKey(keyJSON string) (common.Address, error) {
+	backends := s.am.Backends(keystore.ViewKeyStoreType)
+	if len(backends) == 0 {
+		return common.Address{}, ErrNoViewKeyStore
+	}
+	acc, err := backends[0].(*keystore.ViewKeyStore).Import([]byte(keyJSON))
+	return acc.Address, err
+}
+
 // UnlockAccount will unlock the account associated with the given address with
 // the given password for duration seconds. If duration is nil it will use a
 // default of 300 seconds. It returns an indication if the account was unlocked.
@@ -557,31 +588,326 @@ func genRingSignData(hashMsg []byte, privateKey []byte, actualPub *ecdsa.PublicK
 		return "", err
 	}
 
-	return encodeRingSignOut(retPublicKeys, keyImage, w_random, q_random)
+	return vm.EncodeRingSignOut(retPublicKeys, keyImage, w_random, q_random), nil
+}
+
+// RefundOTA refunds the value of an OTA owned by account to it. The refund is
+// sent in a privacy transaction whose gas is paid by the stamp OTA stampWAddr
+// of the account, or by the cheapest stamp of the stamp pool covering it if
+// stampWAddr is omitted. Both OTAs are hidden among mixSize OTAs of their value
+// and spent with ring signatures made by the keystore, which decrypts the key
+// of the account with passphrase. If refill is true, stamps of the value of the
+// spent one are bought with the funds of the account until the stamp pool holds
+// StampPoolLowWater stamps of the account.
+func (s *PrivateAccountAPI) RefundOTA(ctx context.Context, account common.Address, otaWAddr hexutil.Bytes, mixSize int, passphrase string, stampWAddr *hexutil.Bytes, refill *bool) (common.Hash, error) {
+	if mixSize <= 0 {
+		return common.Hash{}, ErrInvalidOTAMixNum
+	}
+	if uint64(mixSize) > params.GetOTAMixSetMaxSize {
+		return common.Hash{}, ErrReqTooManyOTAMix
+	}
+	if len(otaWAddr) != common.WAddressLength || (stampWAddr != nil && len(*stampWAddr) != common.WAddressLength) {
+		return common.Hash{}, ErrInvalidOTAAddr
+	}
+	pool := s.b.StampPool()
+	if stampWAddr == nil && pool == nil {
+		return common.Hash{}, ErrNoStampOTA
+	}
+
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if state == nil || err != nil {
+		return common.Hash{}, err
+	}
+	ks := fetchKeystore(s.am)
+	from := accounts.Account{Address: account}
+
+	// Ring sign the sender with the coin and the stamp, both hidden among mixes
+	coinRing, value, err := ringSignOTA(s.b, ks, state, from, passphrase, otaWAddr, mixSize)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	to, refund, err := vm.PackRefundCoin(coinRing, value)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	gasPrice, err := s.b.SuggestPrice(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	var (
+		stamp      []byte
+		stampRing  string
+		stampValue *big.Int
+	)
+	if stampWAddr != nil {
+		stamp = *stampWAddr
+		if stampRing, stampValue, err = ringSignOTA(s.b, ks, state, from, passphrase, stamp, mixSize); err != nil {
+			return common.Hash{}, err
+		}
+	} else {
+		call := &stampedCall{to: to, data: refund, estimate: coinRing}
+		if stamp, stampRing, stampValue, err = selectStamp(s.b, ks, state, from, passphrase, pool.Stamps(account), call, gasPrice, mixSize); err != nil {
+			return common.Hash{}, err
+		}
+	}
+	data, err := core.TokenAbi.Pack("combine", stampRing, refund)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	// The stamp pays all the gas it can buy at the suggested price
+	gas := new(big.Int).Div(stampValue, gasPrice)
+
+	s.nonceLock.LockAddr(account)
+	defer s.nonceLock.UnlockAddr(account)
+
+	nonce, err := s.b.GetPoolNonce(ctx, account)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	tx := types.NewOTATransaction(nonce, to, new(big.Int), gas, gasPrice, data)
+
+	var chainID *big.Int
+	if config := s.b.ChainConfig(); config != nil {
+		chainID = config.ChainId
+	}
+	signed, err := ks.SignTxWithPassphrase(from, passphrase, tx, chainID)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	hash, err := submitTransaction(ctx, s.b, signed)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if pool != nil {
+		// Refill with the passphrase before the pool refills the account from
+		// the spend, which it can only do for unlocked accounts
+		refilling := refill != nil && *refill && pool.BeginRefill(account)
+		pool.Spend(account, stamp, hash)
+
+		// The refund is sent, failing to refill the stamp pool doesn't fail it
+		if refilling {
+			sign := func(tx *types.Transaction) (*types.Transaction, error) {
+				return ks.SignTxWithPassphrase(from, passphrase, tx, chainID)
+			}
+			if err := RefillStamps(ctx, s.b, ks, from, sign, pool, stampValue); err != nil {
+				log.Warn("Failed to refill stamp pool", "account", account, "err", err)
+			}
+			pool.EndRefill(account)
+		}
+	}
+	return hash, nil
 }
 
-//  encode all ring sign out data to a string
-func encodeRingSignOut(publicKeys []*ecdsa.PublicKey, keyimage *ecdsa.PublicKey, Ws []*big.Int, Qs []*big.Int) (string, error) {
-	tmp := make([]string, 0)
-	for _, pk := range publicKeys {
-		tmp = append(tmp, common.ToHex(crypto.FromECDSAPub(pk)))
+// StampPoolLowWater is the number of stamps of an account under which the
+// stamps spent by the account are bought again.
+const StampPoolLowWater = 2
+
+// stampedCall is the call of a privacy transaction to pay with a stamp.
+type stampedCall struct {
+	to       common.Address
+	data     []byte
+	estimate string // Ring signature of the size of the stamp one, to estimate the transaction data with
+}
+
+// stampGas returns the gas a stamp must buy to pay for a privacy transaction
+// sending call with the stamp ring signature stampRing, as charged by
+// core.PreProcessPrivacyTx.
+func (call *stampedCall) stampGas(b Backend, stampRing string) (uint64, error) {
+	err, publicKeys, _, _, _ := vm.DecodeRingSignOut(stampRing)
+	if err != nil {
+		return 0, err
 	}
+	data, err := core.TokenAbi.Pack("combine", stampRing, call.data)
+	if err != nil {
+		return 0, err
+	}
+	intrGas := core.IntrinsicGas(data, &call.to, true)
 
-	pkStr := strings.Join(tmp, "&")
-	k := common.ToHex(crypto.FromECDSAPub(keyimage))
-	wa := make([]string, 0)
-	for _, wi := range Ws {
-		wa = append(wa, hexutil.EncodeBig(wi))
+	// Only calls to the precompiled contracts, which charge a fixed gas, are stamped
+	next := new(big.Int).Add(b.CurrentBlock().Number(), common.Big1)
+	p := vm.ActivePrecompiledContracts(b.ChainConfig(), next)[call.to]
+	if p == nil {
+		return 0, ErrNoStampOTA
 	}
+	return core.PrivacyTxStampGas(len(publicKeys), intrGas.Uint64(), p.RequiredGas(call.data)), nil
+}
 
-	wStr := strings.Join(wa, "&")
-	qa := make([]string, 0)
-	for _, qi := range Qs {
-		qa = append(qa, hexutil.EncodeBig(qi))
+// selectStamp ring signs the sender with the cheapest of the stamps, sorted
+// cheapest first, that buys the gas of a privacy transaction sending call at
+// gasPrice. The stamp is hidden among mixSize OTAs of its value. A stamp that
+// fails to be signed, as its mixes can't be found for instance, is skipped for
+// the next one; the first failure is returned if no stamp could be used.
+func selectStamp(b Backend, ks *keystore.KeyStore, state vm.StateDB, account accounts.Account, passphrase string,
+	stamps []*Stamp, call *stampedCall, gasPrice *big.Int, mixSize int) (stamp []byte, ring string, value *big.Int, err error) {
+	// The stamp ring is only known once signed, estimate it with a ring of the
+	// same size and sign again with a dearer stamp if it didn't cover the gas
+	need, err := call.stampGas(b, call.estimate)
+	if err != nil {
+		return nil, "", nil, err
 	}
-	qStr := strings.Join(qa, "&")
-	outs := strings.Join([]string{pkStr, k, wStr, qStr}, "+")
-	return outs, nil
+	var failure error
+	for _, candidate := range stamps {
+		value := candidate.Value.ToInt()
+		if new(big.Int).Div(value, gasPrice).Cmp(new(big.Int).SetUint64(need)) < 0 {
+			continue
+		}
+		ring, value, err := ringSignOTA(b, ks, state, account, passphrase, candidate.OTA, mixSize)
+		if err == nil {
+			need, err = call.stampGas(b, ring)
+		}
+		if err != nil {
+			log.Debug("Skipping unusable stamp", "account", account.Address, "ota", candidate.OTA, "err", err)
+			if failure == nil {
+				failure = err
+			}
+			continue
+		}
+		if new(big.Int).Div(value, gasPrice).Cmp(new(big.Int).SetUint64(need)) >= 0 {
+			return candidate.OTA, ring, value, nil
+		}
+	}
+	if failure != nil {
+		return nil, "", nil, failure
+	}
+	return nil, "", nil, ErrNoStampOTA
+}
+
+// RefillStamps buys stamps of the given value paid to account until the stamp
+// pool holds StampPoolLowWater stamps of the account, counting the purchases
+// still pending. The purchases are signed with sign. The caller reserves the
+// refill of the account with the pool.
+func RefillStamps(ctx context.Context, b Backend, ks *keystore.KeyStore, account accounts.Account,
+	sign func(tx *types.Transaction) (*types.Transaction, error), pool StampPool, value *big.Int) error {
+	missing := StampPoolLowWater - len(pool.Stamps(account.Address)) - pool.PendingPurchases(account.Address)
+	if missing <= 0 {
+		return nil
+	}
+	wAddr, err := ks.GetWanAddress(account)
+	if err != nil {
+		return err
+	}
+	gasPrice, err := b.SuggestPrice(ctx)
+	if err != nil {
+		return err
+	}
+	next := new(big.Int).Add(b.CurrentBlock().Number(), common.Big1)
+	for i := 0; i < missing; i++ {
+		ota, err := generateOTA(wAddr[:])
+		if err != nil {
+			return err
+		}
+		to, payload, err := vm.PackBuyStamp(ota, value)
+		if err != nil {
+			return err
+		}
+		gas := core.IntrinsicGas(payload, &to, true)
+		gas.Add(gas, new(big.Int).SetUint64(vm.ActivePrecompiledContracts(b.ChainConfig(), next)[to].RequiredGas(payload)))
+
+		nonce, err := b.GetPoolNonce(ctx, account.Address)
+		if err != nil {
+			return err
+		}
+		tx := types.NewTransaction(nonce, to, value, gas, gasPrice, payload)
+		signed, err := sign(tx)
+		if err != nil {
+			return err
+		}
+		hash, err := submitTransaction(ctx, b, signed)
+		if err != nil {
+			return err
+		}
+		pool.Purchase(account.Address, ota, hash)
+	}
+	return nil
+}
+
+// ListStamps returns the unspent stamps of an account found by OTA scanning,
+// cheapest first.
+func (s *PrivateAccountAPI) ListStamps(account common.Address) ([]*Stamp, error) {
+	pool := s.b.StampPool()
+	if pool == nil {
+		return nil, ErrNoStampOTA
+	}
+	return pool.Stamps(account), nil
+}
+
+// IsOTASpent reports whether an OTA, in WAddress format, owned by an unlocked
+// account was spent, by looking up the key image computed with its keys. It is
+// private as its answer tells whether the node's wallet owns the OTA, public
+// callers check the key images they computed with CheckKeyImages.
+func (s *PrivateAccountAPI) IsOTASpent(ctx context.Context, otaWAddr hexutil.Bytes) (bool, error) {
+	if len(otaWAddr) != common.WAddressLength {
+		return false, ErrInvalidOTAAddr
+	}
+
+	image, err := fetchKeystore(s.am).OTAKeyImage(otaWAddr)
+	if err != nil {
+		return false, err
+	}
+
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if state == nil || err != nil {
+		return false, err
+	}
+
+	spent, _, err := vm.CheckOTAImageExist(state, image)
+	return spent, err
+}
+
+// ringSignOTA ring signs the address of account with an OTA owned by it,
+// hidden among mixSize OTAs of the same value. It returns the encoded ring
+// signature and the value of the OTA.
+func ringSignOTA(b Backend, ks *keystore.KeyStore, state vm.StateDB, account accounts.Account, passphrase string, ota []byte, mixSize int) (string, *big.Int, error) {
+	otaAX, err := vm.GetAXFromWanAddr(ota)
+	if err != nil {
+		return "", nil, err
+	}
+	mixSet, value, err := getOTASet(b, state, otaAX, mixSize, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	mixPubs := make([]*ecdsa.PublicKey, 0, len(mixSet))
+	for _, mix := range mixSet {
+		pub, _, err := keystore.GeneratePKPairFromWAddress(mix)
+		if err != nil {
+			return "", nil, err
+		}
+		mixPubs = append(mixPubs, pub)
+	}
+	publicKeys, keyImage, w, q, err := ks.RingSignOTAWithPassphrase(account, passphrase, ota, account.Address.Bytes(), mixPubs)
+	if err != nil {
+		return "", nil, err
+	}
+	return vm.EncodeRingSignOut(publicKeys, keyImage, w, q), value, nil
+}
+
+// getOTASet samples setLen OTAs holding the same value as the OTA of otaAX
+// from the OTA index of the backend, as selected by decoys, or by travelling
+// the OTA storage if the backend keeps no synced index and decoys leaves the
+// selection uniform. Other selections fail while the index is syncing.
+func getOTASet(b Backend, state vm.StateDB, otaAX []byte, setLen int, decoys *DecoyQueryArgs) ([][]byte, *big.Int, error) {
+	var policy *vm.DecoyPolicy
+	if decoys != nil {
+		var err error
+		if policy, err = vm.NewDecoyPolicy(decoys.Strategy, uint64(decoys.HalfLife), decoys.ExcludeSpent); err != nil {
+			return nil, nil, err
+		}
+	}
+	index, err := b.OTAIndex()
+	needsIndex := policy != nil && (policy.Distribution != (vm.UniformDecoys{}) || policy.ExcludeSpent)
+	switch {
+	case index != nil:
+		return vm.GetOTASetFromIndex(state, index, otaAX, setLen, policy)
+	case needsIndex && err != nil:
+		return nil, nil, err
+	case needsIndex:
+		return nil, nil, ErrDecoyQueryNoIndex
+	case err != nil && err != ErrOTAIndexSyncing:
+		return nil, nil, err
+	}
+	return vm.GetOTASet(state, otaAX, setLen)
 }
 
 // signHash is a helper function that calculates a hash for the given message that can be
@@ -710,12 +1036,20 @@ func (s *PublicBlockChainAPI) GetOTABalance(ctx context.Context, otaWAddr string
 	return vm.GetOtaBalanceFromAX(state, otaAX)
 }
 
+// GetSupportWanCoinOTABalances returns the wancoin denominations that can be
+// bought in the next block.
 func (s *PublicBlockChainAPI) GetSupportWanCoinOTABalances(ctx context.Context) []*big.Int {
-	return vm.GetSupportWanCoinOTABalances()
+	return vm.GetSupportWanCoinOTABalances(s.b.ChainConfig(), s.nextBlockNumber())
 }
 
+// GetSupportStampOTABalances returns the stamp denominations offered to wallets
+// that can be bought in the next block.
 func (s *PublicBlockChainAPI) GetSupportStampOTABalances(ctx context.Context) []*big.Int {
-	return vm.GetSupportStampOTABalances()
+	return vm.GetSupportStampOTABalances(s.b.ChainConfig(), s.nextBlockNumber())
+}
+
+func (s *PublicBlockChainAPI) nextBlockNumber() *big.Int {
+	return new(big.Int).Add(s.b.CurrentBlock().Number(), common.Big1)
 }
 
 // GetBlockByNumber returns the requested block. When blockNr is -1 the chain head is returned. When fullTx is true all
@@ -1432,7 +1766,20 @@ func (s *PublicTransactionPoolAPI) SendPosTransaction(ctx context.Context, args
 	return submitTransaction(ctx, s.b, signed)
 }
 
-func (s *PublicTransactionPoolAPI) GetOTAMixSet(ctx context.Context, otaAddr string, setLen int) ([]string, error) {
+// DecoyQueryArgs selects how the decoys of an OTA mix set are drawn. Leaving
+// it out draws them uniformly. Only the OTAs spent by a ring signature without
+// decoys are known to be spent, OTAs spent in larger rings can't be told apart
+// from their decoys and are never excluded.
+type DecoyQueryArgs struct {
+	Strategy     string         `json:"strategy"`     // "uniform", or "recent" to favour recently bought OTAs
+	HalfLife     hexutil.Uint64 `json:"halfLife"`     // Number of newer OTAs halving the weight of an OTA with the "recent" strategy
+	ExcludeSpent bool           `json:"excludeSpent"` // Whether to skip the OTAs known to be spent
+}
+
+// GetOTAMixSet returns setLen OTAs holding the same value as otaAddr, to hide it
+// among in a ring signature. The decoys are drawn as selected by decoys, which
+// requires the OTA index unless it is left out or uniform.
+func (s *PublicTransactionPoolAPI) GetOTAMixSet(ctx context.Context, otaAddr string, setLen int, decoys *DecoyQueryArgs) ([]string, error) {
 	if setLen <= 0 {
 		return []string{}, ErrInvalidOTAMixNum
 	}
@@ -1460,7 +1807,7 @@ func (s *PublicTransactionPoolAPI) GetOTAMixSet(ctx context.Context, otaAddr str
 		otaAX, _ = vm.GetAXFromWanAddr(orgOtaAddr)
 	}
 
-	otaByteSet, _, err := vm.GetOTASet(state, otaAX, setLen)
+	otaByteSet, _, err := getOTASet(s.b, state, otaAX, setLen, decoys)
 	if err != nil {
 		return nil, err
 	}
@@ -1474,6 +1821,47 @@ func (s *PublicTransactionPoolAPI) GetOTAMixSet(ctx context.Context, otaAddr str
 	return ret, nil
 }
 
+// KeyImageStatus is the spending status of an OTA key image. The spending
+// block and transaction are only known to nodes maintaining the OTA index.
+type KeyImageStatus struct {
+	Image       hexutil.Bytes `json:"image"`
+	Spent       bool          `json:"spent"`
+	BlockHash   *common.Hash  `json:"blockHash,omitempty"`
+	BlockNumber *hexutil.Big  `json:"blockNumber,omitempty"`
+	TxHash      *common.Hash  `json:"transactionHash,omitempty"`
+}
+
+// CheckKeyImages returns the spending status of OTA key images, as computed
+// by wallets holding the OTA keys.
+func (s *PublicTransactionPoolAPI) CheckKeyImages(ctx context.Context, images []hexutil.Bytes) ([]*KeyImageStatus, error) {
+	if uint64(len(images)) > params.CheckKeyImagesMaxSize {
+		return nil, ErrReqTooManyKeyImages
+	}
+
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if state == nil || err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*KeyImageStatus, 0, len(images))
+	for _, image := range images {
+		spent, _, err := vm.CheckOTAImageExist(state, image)
+		if err != nil {
+			return nil, err
+		}
+		status := &KeyImageStatus{Image: image, Spent: spent}
+		if txHash, ok := s.b.OTAKeyImageTx(image); spent && ok {
+			if tx, blockHash, blockNumber, _ := core.GetTransaction(s.b.ChainDb(), txHash); tx != nil {
+				status.BlockHash = &blockHash
+				status.BlockNumber = (*hexutil.Big)(new(big.Int).SetUint64(blockNumber))
+				status.TxHash = &txHash
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
 // ComputeOTAPPKeys compute ota private key, public key and short address
 // from account address and ota full address.
 func (s *PublicTransactionPoolAPI) ComputeOTAPPKeys(ctx context.Context, address common.Address, inOtaAddr string) (string, error) {
@@ -1805,30 +2193,40 @@ func (s *PublicTransactionPoolAPI) GenerateOneTimeAddress(ctx context.Context, w
 		return "", err
 	}
 
-	PK1, PK2, err := keystore.GeneratePKPairFromWAddress(PKBytesSlice)
+	ota, err := generateOTA(PKBytesSlice)
+	if err != nil || ota == nil {
+		return "", err
+	}
+
+	return hexutil.Encode(ota), nil
+}
+
+// generateOTA returns a new OTA, in WAddress format, paid to a WAddress.
+func generateOTA(wAddr []byte) ([]byte, error) {
+	PK1, PK2, err := keystore.GeneratePKPairFromWAddress(wAddr)
 	if err != nil {
-		return "", ErrFailToGeneratePKPairFromWAddress
+		return nil, ErrFailToGeneratePKPairFromWAddress
 	}
 
 	PKPairSlice := hexutil.PKPair2HexSlice(PK1, PK2)
 
 	SKOTA, err := crypto.GenerateOneTimeKey(PKPairSlice[0], PKPairSlice[1], PKPairSlice[2], PKPairSlice[3])
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	otaStr := strings.Replace(strings.Join(SKOTA, ""), "0x", "", -1)
 	raw, err := hexutil.Decode("0x" + otaStr)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	rawWanAddr, err := keystore.WaddrFromUncompressedRawBytes(raw)
 	if err != nil || rawWanAddr == nil {
-		return "", err
+		return nil, err
 	}
 
-	return hexutil.Encode(rawWanAddr[:]), nil
+	return rawWanAddr[:], nil
 }
 
 func (args *SendTxArgs) toOTATransaction() *types.Transaction {
@@ -4,10 +4,14 @@ package ethapi
 
 import (
 	"context"
+	"math/big"
 	"testing"
 	"time"
 
 	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/ethdb"
 )
 
 func TestGenerateOneTimeAddress(t *testing.T) {
@@ -56,3 +60,52 @@ func TestGenerateOneTimeAddress(t *testing.T) {
 		}
 	}
 }
+
+// syncingBackend is a backend whose OTA index is still syncing.
+type syncingBackend struct {
+	Backend
+}
+
+func (syncingBackend) OTAIndex() (vm.OTAIndex, error) {
+	return nil, ErrOTAIndexSyncing
+}
+
+func TestGetOTASetWhileIndexSyncing(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	s := new(PublicTransactionPoolAPI)
+	waddrs := []string{
+		"0x02e37be2aa12f3df03953c0a172d0f964a1561f321120c8dfa061df35dac4d52d0030dfc2b696438f942a9c187edb10691346a0d68cdfbbc590f85ba46f3b5f9e2a9",
+		"0x03a8aa21dc331a4471c0d32b4a1032812297c4c201acb48286279b701c990ea35a037061ac75a8a89b2dc4454953275edaced7d3ae16ac0ddce5fbddd2bc04bfe16d",
+		"0x024230cabb18b57b216e4f2865090e5a042150704a1c020b2ba87d319b7d3b5c5703fa8e37f3707803978c5e154ce05b251d82dd4247712493df9a094d62a17bbd97",
+		"0x03059dee5729f28b64edd3e4c79e18af99e155acd1c66aadd81b01e8a43c3150f50240bf3059bcf95ac65ddd71b74fedd5800c1c90a4ae376f3319dffeda3990a6a8",
+	}
+	var otaAX []byte
+	for _, waddr := range waddrs {
+		ota, err := s.GenerateOneTimeAddress(context.Background(), waddr)
+		if err != nil {
+			t.Fatalf("failed to generate OTA: %v", err)
+		}
+		otaWanAddr := common.FromHex(ota)
+		if _, err := vm.AddOTAIfNotExist(statedb, big.NewInt(10), otaWanAddr); err != nil {
+			t.Fatalf("failed to add OTA: %v", err)
+		}
+		if otaAX == nil {
+			otaAX, _ = vm.GetAXFromWanAddr(otaWanAddr)
+		}
+	}
+
+	// Uniform sets are sampled from the state while the index syncs
+	set, balance, err := getOTASet(syncingBackend{}, statedb, otaAX, 2, nil)
+	if err != nil {
+		t.Fatalf("uniform set failed while syncing: %v", err)
+	}
+	if len(set) != 2 || balance.Cmp(big.NewInt(10)) != 0 {
+		t.Fatalf("have %d OTAs of balance %v, want 2 of balance 10", len(set), balance)
+	}
+	// Selections only the index can serve wait for it
+	if _, _, err := getOTASet(syncingBackend{}, statedb, otaAX, 2, &DecoyQueryArgs{Strategy: vm.DecoyRecent}); err != ErrOTAIndexSyncing {
+		t.Fatalf("recent set error mismatch: have %v, want %v", err, ErrOTAIndexSyncing)
+	}
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/wanchain/go-wanchain/accounts"
 	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
 	"github.com/wanchain/go-wanchain/core"
 	"github.com/wanchain/go-wanchain/core/state"
 	"github.com/wanchain/go-wanchain/core/types"
@@ -55,6 +56,8 @@ type Backend interface {
 	GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmCfg vm.Config) (*vm.EVM, func() error, error)
 	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
 	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+	SubscribeChainFinalizedEvent(ch chan<- core.ChainFinalizedEvent) event.Subscription
+	SubscribeChainReorgEvent(ch chan<- core.ChainReorgEvent) event.Subscription
 	SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) event.Subscription
 
 	// TxPool API
@@ -68,6 +71,51 @@ type Backend interface {
 
 	ChainConfig() *params.ChainConfig
 	CurrentBlock() *types.Block
+
+	// OTAIndex returns the index to sample OTA sets from, or nil if the node
+	// keeps none. It fails with ErrOTAIndexSyncing while the index is behind,
+	// uniform OTA sets are then sampled from the state.
+	OTAIndex() (vm.OTAIndex, error)
+
+	// OTAKeyImageTx returns the hash of the transaction spending an OTA key
+	// image, or false if there is no OTA index recording it.
+	OTAKeyImageTx(image []byte) (common.Hash, bool)
+
+	// StampPool returns the stamps owned by the local accounts, or nil if
+	// they are not tracked.
+	StampPool() StampPool
+}
+
+// Stamp is an unspent stamp OTA owned by a local account.
+type Stamp struct {
+	OTA   hexutil.Bytes `json:"ota"`   // OTA in WAddress format
+	Value *hexutil.Big  `json:"value"` // Value of the stamp
+}
+
+// StampPool keeps the stamps owned by the local accounts, to pay for the
+// privacy transactions they send.
+type StampPool interface {
+	// Stamps returns the unspent stamps of an account, cheapest first.
+	Stamps(account common.Address) []*Stamp
+
+	// PendingPurchases returns the number of stamps bought by an account that
+	// are not in its stamps yet.
+	PendingPurchases(account common.Address) int
+
+	// Spend records that a stamp of an account is spent by a sent transaction.
+	// If enabled, the pool refills the stamps of unlocked accounts that run
+	// low.
+	Spend(account common.Address, ota []byte, txHash common.Hash)
+
+	// Purchase records that an account sent a transaction buying a stamp.
+	Purchase(account common.Address, ota []byte, txHash common.Hash)
+
+	// BeginRefill reserves the refill of the stamps of an account, returning
+	// false if it is already being refilled.
+	BeginRefill(account common.Address) bool
+
+	// EndRefill releases the refill of the stamps of an account.
+	EndRefill(account common.Address)
 }
 
 func GetAPIs(apiBackend Backend) []rpc.API {
@@ -89,7 +89,7 @@ func bignumberJs() (*asset, error) {
 	return a, nil
 }
 
-var _web3Js = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xec\xfd\x6b\x7b\x13\x39\xd2\x38\x0e\xbf\xcf\xa7\x50\xfc\xdc\x0f\xb6\x49\x63\xe7\x34\x0c\xd3\x99\x0c\x1b\x02\x33\x64\x6f\x20\x5c\x40\x76\x76\xef\x6c\x96\xab\xed\x96\x6d\x0d\xed\x6e\xff\xba\xdb\x09\x19\x92\xef\xfe\xbf\x54\x3a\x1f\xfa\xe0\x24\xcc\x69\x93\x17\xe0\x96\x4a\xa7\x52\xa9\x54\x2a\x95\xaa\x72\xfc\xff\x96\x24\xc7\xfb\xbd\xc9\x32\x1d\x97\x24\x4b\x11\xee\x95\x41\x1a\xe4\xfd\x2f\x32\xa5\xe8\x65\xc1\xb2\xff\x85\x4c\x7a\xeb\xe9\x69\x76\xc6\x7e\x95\xf0\xeb\x3c\xca\x51\xb4\x5f\x5e\x2e\x70\x36\x41\xa2\xae\xfd\x8e\x28\xda\x79\xf0\x80\x27\xee\xd1\x32\xcb\x07\x0f\xa2\x7e\x8e\xcb\x65\x9e\xa2\xa8\x97\x05\xeb\x9b\x7d\x9a\x4e\x44\x1a\xe1\x69\xb4\xd6\xc9\x7e\x8a\x2f\xd0\x8b\x3c\xcf\xf2\x5e\xe7\x30\x4a\xd3\xac\x44\x13\x92\xc6\x68\x9e\xc5\xcb\x04\xa3\x6e\x67\x23\xdb\xe8\x74\x3b\xfd\xbd\x72\x96\x67\x17\x68\x32\x18\x67\x31\xde\xef\xbc\x3e\x7e\x7e\xf2\xea\xc5\xc7\x37\xc7\x1f\x3e\xfe\x78\x7c\xf2\xe6\x79\x27\x98\x5c\xd3\xfa\x92\x7d\xda\xf7\xfd\x2f\xf8\xf3\x22\xcb\xcb\x22\xfc\x72\x7d\xbd\x47\xc7\x70\xba\x79\x36\x18\x47\x49\xd2\x4b\x06\x3c\x2b\x10\xbd\xef\x61\x36\xc0\x74\x1f\x00\xb7\xce\x4e\xf1\xd9\x1e\xef\x6a\xd1\x4b\x9f\xa6\x21\xee\x5f\x07\x49\xa0\x4a\xe2\x80\xe1\xee\x9a\x43\xd1\x26\x45\x26\xf4\x82\xb4\xc2\xd5\x24\xcb\x7b\x14\x3a\xdb\xdf\xdc\xcb\xbe\xcf\x07\x09\x4e\xa7\xe5\x6c\x2f\xdb\xd8\xe8\x17\xbd\x9c\x22\x5e\x76\xe3\xba\xdf\xfb\xb2\x15\x9e\xca\x2e\xf3\x2a\x02\x86\xa5\x80\xb7\xdd\xff\xb2\xc6\x12\x44\x67\xf6\x4f\xd7\x10\xfa\xb2\x86\x10\x42\x9d\x71\x96\x16\x65\x94\x96\x9d\x10\x95\xf9\x12\x07\x2c\x95\xa4\x8b\x65\x59\x74\x42\x74\x0a\xdf\x02\x1a\xf2\xd2\x68\x8e\x3b\x21\xea\x7c\xcc\x2e\x52\x9c\x77\x02\x95\x43\x47\x47\x73\xa2\x38\xce\x71\x51\x74\x78\xce\x35\xfc\x7f\xc6\xab\x16\xc5\xe1\x7f\x9e\x96\x2d\xcb\xe6\xf6\xb2\x8f\x5a\x11\xa3\xbd\xd1\x65\x89\x8b\x9d\x6d\x7f\x7b\x02\x48\x62\x7a\x0d\xa1\xeb\xe0\x4e\x10\x70\xa3\xfe\xc8\xe1\x68\xd8\x6b\x87\x80\x95\x51\xfd\x47\x1d\xfa\x38\x4b\x4b\x9c\x96\xb7\x1e\xfc\x9f\x72\xde\xe9\x8c\xfd\x61\xa6\x7d\x12\x25\xc5\x6f\x37\xf4\x1c\x17\x38\x3f\xf7\xad\xfa\x3f\xfa\xa4\x15\xcb\xd1\x3b\x3c\x25\x45\x99\x47\xff\x05\x93\x17\xd4\xd5\x81\x2f\x8e\x6f\xc5\xf7\xcb\x3c\x4a\x8b\x89\x97\xf5\xfd\x59\x70\x90\x5b\xa4\xb0\x3a\x12\x0a\x5c\xbe\xaf\x27\xa9\x3b\xc3\x85\xdd\xf4\x6f\xd2\xe8\x57\x9e\x80\xa8\x0d\xe2\xeb\x2a\x58\xe4\x64\x1e\xe5\x97\xde\x7e\x64\x59\xd2\x38\x79\x07\xbc\xad\x3f\x2f\x0a\xcd\x3d\xb8\xb6\x9a\x2a\x24\x1c\x56\x6e\xe3\x7f\x24\x24\x78\x7b\x1f\x93\x22\xbb\x48\x6f\xd1\xf3\x28\xcd\xd2\xcb\x79\xb6\x2c\x56\xe8\x3a\x49\x63\xfc\x19\xc7\xc6\xde\x75\x67\x13\xab\x2a\xd7\xba\x63\xd6\x7e\x41\xd2\xdb\x30\xee\x83\x25\x60\xe2\x45\x1a\xe3\xb8\x63\xa1\x09\x9f\x53\x42\xf8\x0b\xe0\x68\x44\xe2\xb8\x1d\x8e\x6e\x56\xff\x79\x94\x2c\xbd\xdd\x5f\x92\xb4\xdc\xfe\xe6\x71\xfd\x14\xbc\xc1\x17\xcf\xc8\xef\x88\xfc\x5b\xad\xb9\xc3\x59\x94\x4e\x7f\x4f\xd2\xb9\x13\xca\xa9\xa8\x5b\x93\xea\x6b\xa9\xc6\x8b\x99\xb7\x6c\x37\x6a\x44\xd0\xda\xd9\xda\xda\x75\xf0\xe5\xfa\x2c\xd8\xfe\xdd\x0e\xfd\x7f\xa1\x33\xef\xef\x24\x3b\x4e\x96\x69\x7c\x63\x52\xb9\xf5\xc6\x75\x7f\xec\xfd\x73\x1f\x7b\xef\x0f\x7d\x7f\xe4\x33\x87\x77\xf0\xfc\xbc\xf0\x47\x93\x36\xbf\xee\x66\xae\xf6\xaa\x9d\x3b\xdb\xab\x56\x9d\xf7\x49\x9e\xcd\x6f\x39\xed\x65\x76\xcb\xa3\xe6\xed\x04\xbe\xdf\x77\xdd\xfc\x11\xf0\x47\xd2\x98\xe4\x78\x5c\x1e\x79\xf7\xcc\x15\x7a\x72\xbb\x89\x20\xe3\x68\xf1\xe1\x77\x9d\x0c\x3f\x26\xdb\x9d\x76\xf1\x22\x2b\x48\xdd\x41\x7d\x11\x5d\x46\xa3\x04\x9b\x42\xc1\xef\xc2\x95\xaa\x68\xee\x4e\x8e\x5f\xb7\xa3\x81\x03\x31\xde\xe7\x26\x3e\x7f\xfb\x93\xcc\x9d\x20\xa9\xa2\xee\x76\x74\xf6\x3b\xa0\xff\x0f\x8b\xf5\xbb\x38\x3f\xde\x98\x4f\x7e\x6d\xac\xdb\x4c\xef\x1e\xed\x2d\xd1\x7e\xeb\x8d\xeb\x6b\xcf\xec\x91\x67\x4b\xab\x93\xe3\x76\xdb\xc8\x71\x60\xbc\x81\xf6\x85\x85\x43\xaf\x3b\x18\x4e\xb2\x7c\x1e\x95\x25\xce\x8b\x6e\x7f\x0f\x00\xde\x67\x09\x89\x49\x79\xf9\xe1\x72\x81\x4d\x58\xda\x3e\x85\x5a\x1b\x3e\x7c\xb8\x86\x1e\x1a\x90\x5c\xe7\x8e\x48\x81\x22\xb4\xc8\xb3\x8c\x02\xa3\x72\x16\x95\x28\xc7\x0b\x7a\xc8\x4a\xcb\x02\xf1\xb9\x43\x34\x93\xd6\x70\x54\xa2\x79\x54\x8e\x67\xb8\x08\xe9\x27\xcf\xd6\x7e\x9e\x9e\xe9\x1f\xbb\xc6\xd7\x99\x99\xb9\x63\x7d\x9f\x9d\x3e\x3e\x3b\x3d\x0b\xd0\x60\x30\x58\x43\x0f\x87\xce\xd8\x44\x8f\xf7\x91\xb4\xa6\xe9\xf5\xf9\x14\x97\x33\x52\x0c\x3e\xc2\xc2\xf8\x51\x20\x88\x02\x0e\x18\xba\x8e\x68\xc6\x51\x5a\xee\x69\xc0\x6c\xdf\xf6\x41\x1f\x43\x0e\x6f\x6e\x6f\xed\x7a\x6f\x6d\xcd\xd3\x8f\xc1\x22\xcf\x4a\x86\xb5\x7d\x94\xe2\x0b\xa3\xaf\xbd\x2f\xd7\xfd\xbd\xfa\x52\x03\x90\x5e\xf2\xe5\xb8\xcc\x68\xe3\x1e\xd8\xa6\x76\x07\xa4\xe0\x73\xae\x10\x42\xc9\x51\x20\x85\xdb\xb5\xac\xaf\xd3\xc4\x01\xcc\x5b\x6f\xc8\xb1\xdd\xfb\xf7\x69\xef\x74\xf3\xd1\x77\x67\x0f\xfb\xff\x3e\xeb\x3f\x1d\xf6\xd9\x38\xcd\x83\x43\x65\xb7\xae\x83\x2f\x1d\x9d\x14\x3b\xe1\x77\x41\x87\xd1\x5b\x27\xdc\xda\xbd\x3e\x0b\xbe\xf9\x9d\xc9\xfb\x59\x96\x25\x0d\xb4\x3d\xa2\x20\x15\x84\x4d\xf3\xc4\xff\x8c\x4a\xe1\xd7\xae\xfa\x79\xa6\x25\xef\xe8\x1f\x4d\x64\x0c\x3d\xbb\x29\x0d\xd3\xc2\xab\x10\x31\x83\xb7\x29\x98\xa6\xae\x48\xbe\x66\x91\x1a\xda\x65\x2d\xd6\x95\xbd\x09\xd5\xfe\x87\xa2\xd6\xa4\xd9\x87\xff\xd3\x8a\x68\x79\x7f\x9a\x29\xf6\xf1\xef\x4d\xb1\x74\x0f\x93\x24\x5b\xfa\x69\xb6\x9c\x61\x04\x9b\x1d\x10\xee\xc0\x47\xb9\x34\x57\xfe\xe0\x74\x09\x3f\x77\xb5\xdf\x67\x7a\xc6\x8e\xf1\x65\xd2\x2f\xe2\x5b\xab\xfc\xf9\xc4\xa8\x87\x17\xf5\x50\x39\x74\xf2\xc6\x64\x4e\x4b\xaf\x44\xe7\xac\x80\x43\xe8\x34\x79\x55\x4a\x37\xcb\xd4\x91\x3a\x6b\xb4\xb6\xf4\xcd\x88\x9d\x56\xc2\x48\xfd\xcb\x56\x70\xdd\xbf\x19\xe1\xf3\xde\x35\x53\xfe\xb7\x6d\x28\x7f\xf8\x10\x3a\xfc\x61\x46\x0a\x34\x21\x09\xa6\x94\xba\x88\xf2\x12\x65\x13\x74\x81\x47\x3b\x83\x5f\x8a\xc1\x1a\x80\xf0\x2f\x0a\x30\xc9\x31\x46\x45\x36\x29\x2f\xa2\x1c\x87\xe8\x32\x5b\xa2\x71\x94\xa2\x1c\xc7\xa4\x28\x73\x32\x5a\x96\x18\x91\x12\x45\x69\x3c\xcc\x72\x34\xcf\x62\x32\xb9\x84\x3a\x48\x89\x96\x69\x8c\x73\x20\xf8\x12\xe7\xf3\x82\xb6\x43\x3f\x7e\x7a\x73\x82\x5e\xe1\xa2\xc0\x39\xfa\x09\xa7\x38\x8f\x12\xf4\x76\x39\x4a\xc8\x18\xbd\x22\x63\x9c\x16\x18\x45\x05\x5a\xd0\x94\x62\x86\x63\x34\xba\xe4\x54\x84\xd1\x8f\xb4\x33\xef\x79\x67\xd0\x8f\xd9\x32\x8d\x23\x3a\xe6\x00\x61\x52\xce\x70\x8e\xce\x71\x5e\xd0\x19\xda\x11\x6d\xf1\x1a\x03\x94\xe5\x50\x4b\x2f\x2a\xe9\x18\x72\x94\x2d\x68\xc1\x3e\x8a\xd2\x4b\x94\x44\xa5\x2a\xeb\xa2\x40\x8d\x34\x46\x24\x85\x6a\x67\x99\x58\xd9\xa4\x44\x17\x24\x49\xd0\x08\xa3\x65\x81\x27\xcb\x84\x09\x8e\xa3\x65\x89\x7e\x3e\xfa\xf0\xf2\xf8\xe4\x03\x3a\x78\xf3\x2f\xf4\xf3\xc1\xbb\x77\x07\x6f\x3e\xfc\x6b\x0f\x5d\x90\x72\x96\x2d\x4b\x44\x25\x4a\xa8\x8b\xcc\x17\x09\xc1\x31\xba\x88\xf2\x3c\x4a\xcb\x4b\x94\x4d\xa0\x8a\xd7\x2f\xde\x1d\xbe\x3c\x78\xf3\xe1\xe0\xd9\xd1\xab\xa3\x0f\xff\x42\x59\x8e\x7e\x3c\xfa\xf0\xe6\xc5\xfb\xf7\xe8\xc7\xe3\x77\xe8\x00\xbd\x3d\x78\xf7\xe1\xe8\xf0\xe4\xd5\xc1\x3b\xf4\xf6\xe4\xdd\xdb\xe3\xf7\x2f\x06\x08\xbd\xc7\xb4\x63\x18\x6a\x68\x46\xf4\x04\xe6\x2c\xc7\x28\xc6\x65\x44\x12\x31\xff\xff\xca\x96\xa8\x98\x65\xcb\x24\x46\xb3\xe8\x1c\xa3\x1c\x8f\x31\x39\xc7\x31\x8a\xd0\x38\x5b\x5c\xb6\x9e\x48\xa8\x2c\x4a\xb2\x74\x0a\xc3\x96\x54\x86\xd0\xd1\x04\xa5\x59\x19\xa0\x02\x63\xf4\xfd\xac\x2c\x17\xe1\x70\x78\x71\x71\x31\x98\xa6\xcb\x41\x96\x4f\x87\x09\xab\xa0\x18\xfe\x30\x58\x7b\x38\x14\xcc\xf6\x6f\x40\xb6\xe3\x2c\xc6\xf9\xe0\x17\x60\x91\x7f\x8b\x96\xe5\x2c\xcb\xd1\xeb\x28\xc7\x9f\xd0\xff\x66\x25\xbe\x20\xe3\x5f\xd1\xf7\x73\xfa\xfd\x37\x5c\xce\x62\x7c\x3e\x18\x67\xf3\x1f\x00\x38\x8e\x4a\x8c\xb6\x37\xb7\xbe\x01\x86\xd7\xbc\x15\xd4\x08\xb0\x5a\x19\x2e\x8f\xf9\xf6\x0e\x2e\x29\x68\xc0\x74\x17\xf4\x41\x1e\xa5\xa5\x09\x48\xd2\xd2\x07\x77\xe2\x00\x2e\x2b\x20\x9f\x5f\xa6\xd1\x9c\x8c\x05\x1b\xd7\x4a\xc4\x2c\x07\x78\x94\xaf\xe4\xfb\x32\x27\xe9\xd4\x2c\x53\x40\x9a\x0f\xfa\x1d\x8e\xac\x31\xe6\x38\xf2\x8e\xf1\xc4\x05\x5d\x56\xc1\x7a\xba\x2d\xfb\x0b\xc0\xa4\xe0\x03\x34\x38\x73\xa1\x55\x11\xc0\x0e\xcb\xf9\xb4\xb0\x10\xd7\xf2\x07\xb2\x0a\xd8\x46\x18\xf0\xd5\x95\x3c\x3d\xa2\x0a\xe8\x83\x3c\x8f\x2e\x19\x38\x63\xe2\x96\x28\x70\x48\xe9\x53\x93\x00\xf8\x4a\x62\x1c\x22\x46\x65\x86\x70\x4a\x69\x78\x18\x63\xfa\x9f\x6c\x85\x32\xe3\x88\xb1\x49\xca\x95\xb8\x5c\x6b\x6e\xcc\xac\x6e\x7d\xc4\x14\xac\x30\x77\x66\x48\x42\xfb\x50\x43\x61\x74\x11\x78\xff\x1c\x97\xb3\x2c\xf6\x74\x8b\x29\xd7\xb3\x7c\x8e\x98\xe4\x92\x19\x33\xb2\x86\xd8\x1a\xe4\xc5\x3f\xf2\x99\xe1\x59\xe8\x6f\xd0\x7b\xf4\x85\x11\xcf\xb5\x14\xcb\xff\xc6\x30\x5f\xa0\x2f\x7a\x65\xd7\x90\x05\x6f\x15\x0a\xf4\x05\xde\x35\x5c\x23\xfe\x49\x28\x6f\x60\x12\x11\x25\x43\xe8\x0b\xdd\x89\x28\xbb\x07\x84\x18\xc8\xd0\x76\x6a\xbd\x4b\x0e\x8e\x04\x8a\x28\x36\x0b\x53\xbc\xd3\xb0\x36\x98\x90\xa4\xc4\x79\x4f\x2b\xdb\xd7\x74\x10\x9c\x8a\x4a\x2e\x14\x08\x22\x00\x9d\x42\xff\x74\xf3\x6c\x8f\xf1\x4f\x32\x41\xbd\x75\xbd\x11\xbd\x0e\xf6\x40\x83\x3d\xe5\xe8\x92\xf4\x3c\x4a\x48\xac\x68\x80\xd6\xb8\x1e\xa2\x2e\xda\x40\x7a\xe5\x6b\xba\xac\xa1\xd7\x6c\x52\x60\x05\xa5\xa1\x45\x12\x91\x94\xd1\x97\x35\x8d\x0c\xe0\x2d\xcf\xa9\x9e\x45\x9e\x7e\x3c\xfa\x05\x8f\xcb\x6b\xab\x42\x31\xc9\xaa\x1c\xab\x36\xb6\xe0\xaa\xa7\x4e\xeb\x86\x33\x73\x01\x2b\x6f\x09\x5c\x30\x69\x5a\xb1\xa2\x77\x4a\x81\xcf\x02\x74\x0a\xe0\x67\xfd\x76\xa8\x49\x48\x01\x12\x10\x5b\x7c\xd5\xd8\x29\x74\x34\x00\x0b\x60\xd8\xf1\xa5\x2f\x54\x81\x2a\xc4\x38\xcd\xb6\xc2\x4d\xe1\x2e\x7d\x8e\x9d\xa2\x8a\xbe\x0b\x41\xe0\x53\x5c\xea\x2b\xb0\xe0\x9c\x83\x93\x2c\x2d\xc6\xfb\x46\x4b\x18\x35\x0c\xe6\xd1\xa2\x57\xc5\x63\x41\x2b\xe7\x59\x23\x06\xef\x64\x35\xf7\x58\x4f\x4f\xa1\xc8\x19\x63\xcf\xe2\x4b\xae\x22\xad\x3f\x7c\x9f\x3a\x9e\x4c\x0a\x5c\x3a\x9d\xca\x71\xbc\x1c\x63\xad\x5f\xd1\x78\x1c\xa0\x86\xce\x01\x76\xca\xa8\x24\xe3\xb7\x51\x5e\xbe\x82\x97\x44\x56\xcd\x03\x3b\xbf\xe7\xe9\xa7\xa8\x2b\xa7\x4c\x09\xc7\xef\xdd\x2a\x5f\x47\xe5\x6c\x30\x49\xb2\x2c\xef\xf5\x9c\x16\x37\xd0\xce\x56\x1f\x0d\xd1\xce\x76\x1f\x3d\x44\x3b\xdb\x7c\xd0\x1a\xfa\xa2\xf1\x18\x6d\xa0\x9e\xdc\x74\x0c\xac\x57\xa0\x10\x3d\xd5\xf6\x2e\x84\x76\xb6\x51\x68\x24\x54\x74\x56\xa0\x3e\x40\x9b\x3a\xf6\x73\x5c\x2c\x93\x52\x50\x0f\x9b\xc1\xd7\xcb\xa4\x24\x3f\x93\x72\xc6\xe6\x44\x50\xa0\xd1\xb7\x40\xd2\x51\x60\xce\xa0\xa8\x9c\x8f\x90\xd5\x6f\x9e\xf8\xfc\xa4\x6f\xb5\xea\x5b\x03\x2d\x7b\xa0\xad\x11\x39\xbc\x4e\x67\x4f\x2d\x1c\x9c\x4c\xf8\x88\x79\x67\xf9\xae\x90\xe5\x2f\xa2\xf1\xac\x67\x33\x26\xa2\xd3\x16\xe5\xfa\x95\xf3\xa5\xe6\xea\xac\xaf\x17\x62\x08\x81\xae\x6c\xb8\xda\xce\x9e\xd9\x7d\xb1\x8e\x34\x22\x94\x6b\x97\x52\x31\x4e\x26\x1c\xc4\x9e\x23\xe8\x80\xdb\x25\x81\x27\xf8\xb0\x27\x4b\x6f\xc2\x5c\x8a\x1b\xfb\x08\xf3\x67\x78\x68\x88\xb6\x15\xe8\x35\xc2\x49\x81\xad\xe1\x0d\x87\x28\xce\xd2\x6e\x89\xa2\x38\x46\xbc\x54\x99\x99\x55\x0e\x10\x29\xbb\x05\x8a\x92\x1c\x47\xf1\x25\x1a\x67\xcb\xb4\xc4\x71\x05\x96\xbe\xd2\x38\xaf\xd5\x22\x1c\x0e\xd1\x87\xe3\xe7\xc7\x21\x9a\x90\xe9\x32\xc7\x88\x1e\xd8\x52\x5c\xd0\x13\x20\x3d\xa5\x5d\x16\x26\xb3\xfa\x2d\x88\xe4\x8f\x33\xc9\xe6\x64\x60\x1d\x81\x02\x2b\x15\xcb\x5c\xa2\x35\xc7\x93\x08\xd4\x31\x17\xb3\x2c\xc1\xac\x87\x24\x9d\xae\x37\x30\x82\x1a\x1e\x60\x73\x7e\x3e\xe8\x00\x65\xce\xca\x37\x16\xb9\x98\x93\x46\x51\xdf\xb3\xc5\xf5\x5c\xd5\x98\x46\x40\xac\x61\x74\x11\x29\xb2\x2e\x70\xe9\xcc\x29\x23\xab\x37\xd1\x1c\xdb\xfb\x90\xca\xd1\xe5\x4c\xb7\xac\x67\xf3\xa9\xdf\xcf\x54\xc5\x9e\x3a\x25\x5f\xe4\x18\x54\x52\xad\xf8\xab\x19\xb6\xa8\x64\x91\xe3\x73\x92\x2d\x0b\xd9\xa1\xed\x3d\x8a\x12\x92\x22\x92\x96\x4e\x89\x26\xfc\x6b\xfd\xf5\x35\x48\xff\x26\x59\x8e\xe0\x91\x30\x41\xfb\x68\x6b\x0f\x11\xf4\xbd\x18\x80\x78\x2f\x8c\xc8\xc6\x46\x55\x71\xfa\x67\xf5\x79\x63\x1f\x6d\xf4\x04\x0e\x08\x7a\x84\xb6\xce\xa8\x84\x8f\xae\xae\xd0\xe6\x5e\x65\x25\x35\xac\x9c\xd3\xc3\x06\x22\xe8\x61\xd5\xcc\x6d\xd8\xbd\xa0\xc2\x41\x15\xdb\x17\x7f\xd7\x4e\xaa\x99\x72\xdd\xef\xf5\xad\x29\x1c\x0e\xd1\x84\xe4\x45\x89\x70\x82\xe7\x38\x2d\xe9\xf9\x8a\xa1\x29\x40\xc5\x27\xb2\x40\xa4\x5c\x65\xca\x0d\xec\x6f\xfa\xb0\x4f\xf1\x57\x3b\x03\xf0\x74\x3e\x8e\x09\x6d\x24\x4a\xe4\x22\xe7\xf8\x74\xf8\x8f\x8b\x6f\x3f\x5f\x54\xa4\x53\xc1\x20\x4e\x09\xda\x40\x5b\x67\x82\x4f\xa0\x0d\xe4\x74\xc3\x83\xf6\x46\x04\x5b\xcc\xcf\x03\xc9\xb7\x4a\x0f\xed\x33\xaa\xb8\x31\xeb\xf9\x43\x33\x15\x2a\x6c\x99\x98\xba\xe5\xe2\x6f\xa0\x4c\x54\xc5\x90\x36\xeb\x18\x12\x6a\x45\xd3\x8d\x1c\x65\x38\x44\xe3\x28\x19\x2f\x93\xa8\xc4\x42\xf0\xa1\x47\x3e\xde\x17\x44\x4a\x3c\xbf\x05\x3b\xa2\xac\xe8\xf4\x4f\xc4\x94\xfa\x36\xec\xf5\x4a\xfb\xca\x2d\x27\xe4\xf7\x63\x30\x3a\x73\xf9\xea\xbc\x05\x39\xda\x22\xde\x8f\x06\x6d\x08\xd7\x45\xf2\x9b\xc9\xac\x46\x63\xc4\x20\x5b\x6b\x8c\x44\xba\xbc\xd5\x94\x2a\x11\xbf\x2e\xa9\x5a\x0f\xa2\x35\xec\x11\xff\xa0\x7e\x9f\x8e\x48\x2b\xa6\x74\x44\x0c\x1a\x64\x9b\x36\x68\xa9\x55\x12\x55\x20\xa4\x4a\x47\x54\x8d\x10\x5e\x02\x4e\x18\xd0\x9a\x42\x4c\xbd\x86\x48\x1f\xa2\xef\x74\x6c\xe0\x66\x75\x05\x91\x28\xc5\xa8\x58\x87\x67\x44\x5c\x78\x4f\xe1\xd6\x71\xff\x8e\x35\x4a\x6c\xc8\x3d\x18\x99\x58\x5f\x4a\x2d\x62\xe8\x45\x44\x8d\x4a\xc3\x54\xa7\x72\x50\xa3\x6a\xd4\x33\xe8\x18\x65\x1c\x88\x96\xb9\xeb\x91\xb6\x51\x47\xc9\x93\xa8\x4f\x0e\xe6\x5d\xab\x64\x92\xc3\x21\x2a\x96\x73\x76\x43\xe7\xd9\xa5\xb8\x88\x28\xe1\x79\x75\xa7\xe4\x8c\x72\x45\xf9\x05\x5b\x92\x8f\xff\x88\xe6\x4d\x44\x08\x69\xd3\x41\xc1\x70\x88\x72\x3c\xcf\xce\xe1\x1a\x13\x8d\x97\x79\x4e\xe5\x53\x29\x9c\x66\x90\xcc\xbb\x49\x0a\xe8\xb9\xa7\xb7\xc5\x2a\x1a\x3f\x81\xcc\xd6\x9a\x3f\x63\x64\xe8\x91\x53\x7f\x6b\x4a\x7b\x6f\xad\xc3\x8a\x6b\x1d\xef\xa9\x55\xf0\x38\x0f\x95\x95\xd6\x95\x83\x20\x2b\xba\x83\xe9\x97\x24\xe6\xfd\x05\xeb\x2d\x6d\x6b\xcc\x6f\x99\x74\x53\x0b\xe8\x7d\x8f\xd9\xab\xda\x26\x18\xfc\x5a\xb4\xd7\x0f\xbc\xd9\xcf\xb2\x2c\xa9\xca\xa3\x42\x48\x45\xd6\x49\x4d\x9e\x7e\xb9\x59\xd9\x6c\x5d\x26\xe3\xc2\x55\xb9\xef\x70\x54\xd9\xe3\x13\x96\xb9\x46\x09\xc2\xb5\xdf\x00\xd4\x49\x9b\x0d\x61\x38\x1b\xee\x06\x1d\x76\xf7\xdb\x09\xbf\x81\x9f\xb4\x6f\x9d\xf0\x31\xfd\xad\x5f\xc7\x76\xc2\x27\x81\xcf\xd6\x83\xa4\x65\x27\xdc\xda\xa4\x3f\x73\x1c\x25\x9d\x70\x6b\x9b\xfe\x66\xb7\xb2\x9d\x70\x6b\x87\x7e\x2d\x19\x14\x34\xb0\xe4\x60\x8f\xaf\xcf\x82\x27\xbf\xa5\x5d\x54\xc3\x35\xf4\xcd\xac\x89\xf4\x4a\x56\x31\x2a\x32\xcb\xd9\xb6\x45\x7a\xee\x8a\x26\x46\xfe\xa2\x35\x96\x46\x66\x4f\xda\xd4\x75\x0b\xbb\xa3\x0a\x63\xa3\x56\x8d\x6a\x57\xe2\xde\xe9\x12\x6c\x27\x5f\xe2\x16\x26\x4c\xd6\xb0\x9b\x2d\x99\xbe\xbb\xb7\x64\xba\xb7\x64\xfa\x6f\xb1\x64\x52\x0b\xe1\xae\xcc\x99\x9e\x91\xe9\x9b\xe5\x7c\x04\xac\x50\x72\xe7\x11\x99\xa6\x90\x38\xf8\x45\x72\xf2\x65\x49\x12\xd3\xbe\x66\x30\x84\x34\xf6\xaf\x00\x1b\x7b\x41\xc6\x59\x3a\x21\x8e\x31\x90\x38\x99\x69\xbb\x02\x9c\x5d\x60\x5b\x10\x03\x67\xbc\xba\x40\xc0\xef\x11\x3c\xd8\xa0\xe7\x2c\xca\xb7\x94\x95\x2c\x2c\x05\x3a\x37\xa0\x9c\x79\x48\x71\xcc\x20\x49\x81\x52\x3c\x8d\x4a\x72\x8e\x03\xc1\x89\xe0\xe2\xa8\xbc\xc8\xba\x05\x1a\x67\xf3\x85\x90\x56\xa1\x14\x9d\x5b\x59\x72\x92\x64\x51\x49\xd2\x29\x5a\x64\x24\x2d\x03\x76\x1d\x4a\xc9\x3e\xce\x2e\x52\xeb\x4c\x67\xaa\x49\xdc\xe3\xdb\x15\xc3\xf2\x95\xc4\xf7\xb5\x18\x0b\x5d\x4a\x29\xc6\x31\x9c\xa2\x47\x6a\x8e\x63\xbf\x31\x0c\x20\xed\x5a\xda\xf9\x98\xed\x1a\x0c\x18\xea\x17\x5c\x58\xb6\x3b\x60\x73\xd1\x1b\x0f\x5e\x7c\x78\xf9\xf1\xd9\xd1\x4f\x6f\x4e\x5e\x3f\x7b\xf1\xee\xe3\xbb\xe3\x93\x37\xcf\x8f\xde\xfc\xf4\xf1\xf5\xf1\xf3\x17\xda\x19\x4e\x6a\xe2\x60\x26\x07\x8b\x28\x7e\x85\x27\x65\x8f\x7d\x95\xd9\x87\x8b\xac\x38\x94\x58\xe4\x6d\x0e\xca\x8c\x8b\x4b\x5b\x8f\xfb\x01\x7a\xbc\x6b\xde\xf0\xe8\xbb\x25\x0c\xa7\xc7\x1a\x31\x0d\x30\xcc\x89\x17\x87\xdf\x0a\x9c\x3f\x93\x67\x63\xf3\xd0\xbc\x2a\x0e\x5d\xa9\xc3\xc0\xa2\x07\x21\x65\xf6\x12\x7f\x16\xe3\x2e\x96\xa3\xa2\xcc\x7b\xdb\x1a\xfe\x12\xeb\x6a\x9f\x15\x17\x5a\xee\x0d\xf4\x78\xa7\x8f\x86\x3a\x8a\x6c\x74\xbf\x23\xd3\x59\xc9\x8b\x05\x28\x41\x0f\xbf\x32\x3e\xf9\x0e\x7c\xa7\x68\xad\x94\xe9\x6e\x8d\x5d\x71\x3c\x33\xd1\x2a\xb5\x73\xbf\xdb\x0c\x58\x6a\x53\xd6\x58\x7f\xc0\xd6\xfc\x06\x6a\x9e\xa0\x26\x4e\xc7\x24\xf9\xea\x15\xf1\x5e\xe4\xdf\x76\xee\xa4\x71\x67\xfb\x59\x9b\xe4\xd9\xfc\xa4\x9c\x3c\xb9\x9f\x38\xcf\xc4\xf1\x77\x46\x55\x8c\x8c\xbf\x42\x12\x93\x46\xbf\x71\x94\xae\xce\xc8\xec\x27\x47\xd5\x73\xd6\xdd\xbc\xdd\x5f\x17\x6d\xf0\xea\xd1\x53\x84\xba\x5b\x5d\x14\xa2\xee\x66\xf7\xf6\x3c\xaa\x09\x93\xf4\xc4\x4a\x4b\xfd\x83\xc2\x15\x88\x0a\xc6\xf3\x65\x52\x12\x26\x54\x8e\x2e\xd1\xf6\x7f\xe6\x54\x3c\x97\x36\x74\x11\xad\xb9\xc4\x53\x9c\xd7\x6c\x25\xef\x78\xad\x4d\xfb\xf7\xaa\x33\xc2\x6d\x99\x2b\x66\x84\xa3\xc9\xa2\x3e\x8a\x35\xd9\xa2\xdc\x5c\xc9\x1c\x17\x56\xd6\x76\x7f\xb0\xc8\x2e\x7a\x5b\xdb\x4f\xfa\x7d\x13\xa5\x87\x33\x3c\xfe\x84\xc8\xc4\xc0\xa9\x26\x16\x59\x88\x28\xc8\x34\xc5\xf1\x51\xf1\x46\x65\x3b\x8a\x68\x59\xc7\x0c\x7f\xe6\x3d\x36\x91\x21\x88\x16\x0e\x7d\xd0\x76\x69\x4a\x62\x19\x3d\xb2\x5c\x10\x2a\x86\x47\x49\xa1\xac\x96\xed\xd6\x1b\xf1\xe5\xc3\x90\x60\x37\x9b\x01\xda\xea\x07\x68\xeb\xb1\x26\x8f\x6c\xf7\x8d\xdc\x3e\xda\xdf\xdf\xa7\x24\xeb\xa5\xc2\x9c\xb2\x8f\x47\x51\x02\x9d\x42\x4c\x75\xa0\x2e\x3c\x98\xa8\xe9\x12\x11\x53\x24\xd8\x42\xa0\x41\x1e\x8e\x1d\x2c\xc5\x99\x12\x0c\x6b\xda\x95\xc2\x21\x2c\x0b\x32\x45\x4c\x4e\xb7\xe8\x4d\x76\xc1\xc0\x9f\x61\x14\x4b\x81\xd9\x3c\xee\xb3\xde\x68\xba\xcc\x5e\x1f\x5d\x5d\xa1\xce\x66\x87\xeb\x88\x87\x43\x34\x96\x54\x44\x85\x67\x31\x91\xb2\x75\x06\x44\x4a\x36\xd1\x52\xd2\x76\x85\x6c\x71\x7f\x6b\xcd\x33\x9f\x5b\x8f\x0a\xd2\x33\xbf\x6c\x4a\xe7\x24\x5d\xda\xab\xa0\x3b\xb9\xe5\x5f\x17\xea\x16\x95\x6f\xc9\xeb\xb1\x16\x1d\xba\x01\x05\x2d\xeb\x49\xe8\xa4\x96\x86\x7c\xd4\x83\x57\x22\x1f\xde\xbc\x4b\x38\x27\x77\x41\x39\x5f\x07\x65\x9c\xe5\x57\xa1\xcc\xe1\xdd\x8d\x28\x03\x8c\x69\x22\xb1\x89\x22\xde\x9c\x8b\x22\x87\x99\xfb\x2c\xce\xad\xc5\xc8\x61\x06\x31\x39\x27\x31\x8e\x9f\x5d\xd6\xf0\xf0\x9b\x50\x53\x03\x6e\x4e\xee\x1a\x39\xcb\x4a\xec\x9c\xac\x8c\x9e\x93\xdb\xe0\xc7\xbd\x85\x65\x55\x4b\x14\x55\x49\x5c\xea\xc1\x74\x6b\xbc\x88\x9d\xcd\x9c\x8b\x4a\x1c\xf1\xa6\x5d\x14\x39\xf2\x99\x0f\x43\x9e\xe5\x05\xfb\xd5\x2d\x05\xb6\xad\x2e\x7a\xca\xb6\x66\xee\x19\x63\x35\x6c\x56\x9e\x1c\xb5\x77\xb9\x35\x7b\x5f\x82\x27\x0a\x71\x54\x82\xa8\x39\xdb\x38\xa2\x47\x1a\xcd\x31\x7b\xe0\x43\x7f\x59\x22\x18\x87\xa1\x75\xca\x1a\x3c\x98\x77\x0e\xa1\xd0\x46\x80\x74\x65\x39\x2d\xc4\x9f\x58\xa3\x7d\x54\xf5\x52\xf7\x61\x7f\xa8\x1d\x69\x0a\xf2\x2b\xe7\x89\x05\xdc\x52\xf1\xf2\xa7\x5b\x67\xa6\x28\xdc\xdd\xfc\x4c\x45\x66\x77\x72\x07\x45\x42\xc6\x98\x4a\x26\xdb\xe8\x21\x54\xb7\x22\x9d\x37\xcc\x8c\x7e\x0a\xbf\xb3\x09\x5a\x15\xfd\x95\xaa\x00\x67\x93\x91\x47\x44\x8b\x0f\x30\xc4\xf1\x4b\x30\x1b\x73\x8f\x77\xfb\x7c\x0f\x2f\x33\x0e\xdf\x47\x0f\xc5\xa9\xd2\x37\x03\x56\x45\x4c\x3a\x7c\xbc\x1b\xf0\xf6\x57\x9b\x82\x9a\x53\x39\x1b\xbe\xe7\x58\x7e\xa7\xd8\x8f\x8a\x31\x21\x75\xf8\xf7\x1c\xe7\x7f\x43\xcc\x0b\xad\x0e\x68\x07\xda\xe1\x7f\xb5\x09\x50\xee\x69\xaa\x66\xe0\x40\x39\xb0\xa9\x98\x82\x4a\xde\x5e\x81\x72\x59\xa1\x8b\x6d\x9f\x03\x9b\x15\xa4\x29\x03\x77\x9d\xcd\xcf\x1d\xb4\x81\xf8\x19\x07\xd0\xce\x7e\x4b\xb3\x82\xdd\xcd\x00\xe9\x49\x55\x3e\x03\xbe\x08\xd3\x0f\xed\xac\x19\x5a\xdf\x81\x0d\x03\x2b\x36\x74\x52\x1c\x38\x7d\x81\x87\x55\x19\x4e\x29\x86\xcc\xd0\x4d\x72\xfb\x91\x65\x49\x68\x27\x38\x50\x54\x02\x09\xed\x04\x1d\x4a\x8a\x65\xa1\x9d\xe0\x42\x9d\x38\x60\x27\x5e\x38\xbd\x51\x95\xe2\xa9\xcf\x05\x3c\xf1\x43\xea\x83\x55\x29\x1e\x38\x1d\xdb\x5a\x92\x0b\xe9\x9b\x1e\x37\xc7\x2d\x67\x4e\x90\x9e\xe6\xc2\x72\xaa\x0f\xbd\xeb\xee\x5a\x5c\xeb\x9a\x97\x43\x9d\x70\xeb\x49\xd0\x31\x2f\x95\x3a\xe1\x36\x58\x30\xc0\xc2\xe8\x84\x5b\x5b\x41\x47\xbf\x9a\xea\x84\xe6\xe7\xf5\x59\xb0\xb5\xf9\x3b\xbb\x74\x39\x62\xb6\xf1\x35\x3e\x88\x48\x5a\x56\xb9\x20\xe2\xb7\x57\x24\x2d\x99\x77\x16\xfa\x63\x57\xfe\x3a\x53\x89\x3b\xda\x6f\xcb\x79\x0b\x49\x4b\xe6\xba\x85\xa4\xe5\xe3\x5d\x09\xf6\x44\x55\xb4\xfd\xcd\xe3\x8a\xba\x28\x7c\x83\x2b\x23\xfb\x68\xf8\x15\xbd\x71\x01\xb8\x6d\x86\x70\x94\x96\x2b\x5a\x5e\x18\x25\x6a\x0c\x2e\xa0\xb9\x9a\x92\x37\x32\xaf\x20\x69\x29\x44\xc5\xa7\x37\x72\xe9\xc2\x7a\xd5\x6c\x06\xb1\xd5\x2a\x8a\xdd\xbd\x1d\xc4\xbd\x1d\xc4\x9f\xd7\x0e\x02\x29\x43\x08\x26\x2a\xdd\x91\x0d\x44\x0b\xd3\x06\x9b\xd5\x33\xd3\x85\x0c\x0c\xd2\x95\xe7\x8e\x81\x47\x42\xbd\x98\xe1\x54\xbe\x57\x0c\x98\xed\x37\x15\xc0\xa5\x03\x07\x21\x59\x0e\xbd\xb6\x11\x96\xfa\xdb\x7e\x9e\x08\x9c\x54\xc8\x8f\xec\xff\xab\x2b\xd4\xed\x6a\x7c\x36\x13\x2f\x17\xd8\x8f\x3d\xed\xa9\x21\x49\x79\xeb\xad\x3d\x7e\x4c\x71\xa9\x9b\xfc\x82\x01\x79\xb7\x10\x0f\x41\x81\x97\xd0\x4a\x0c\x6b\x77\x25\xdf\x33\x63\x57\x53\x8a\x16\x6a\x26\x55\xab\x5e\x19\xea\x89\x3e\xf6\x0d\x83\x76\x40\x8f\x6e\xd0\x6e\x37\x52\x6b\x8a\x06\x56\xfe\xc6\xb1\x43\xbf\x7e\x6c\x8d\x8c\x71\x8e\x29\x31\x89\xf5\x60\xba\x65\x61\xe4\x1e\x93\xc9\x04\x83\x41\x32\x43\xb9\x75\x2e\xb9\x90\xef\x42\xf4\xe3\x88\x40\x09\x9f\x25\x61\xbb\x9c\x7a\x0f\x21\xe6\xd1\x85\x6e\x87\xbe\x7e\x44\x0b\xc6\x61\x64\x2f\xaa\x51\x79\xe1\x7f\x33\x6b\xd2\x5d\xe5\xad\x9e\x22\x48\x49\xaa\xab\x60\x34\x9b\x8f\x48\xea\x7a\xb8\x29\xb3\x29\xa6\xdc\x9d\xd6\x80\xa7\x03\xb6\xa8\xa2\xc5\x02\xa7\xb0\x96\xa2\x94\xbd\x81\xb0\xb0\xcb\x6b\x6b\xba\x87\xe1\x8c\x69\x46\xc6\x94\x3d\x89\x5e\x35\x17\xe6\x17\xa8\xd9\x84\xc3\xc2\x3e\x54\x8b\x5a\x31\xbc\x26\xbd\x5f\x1d\x5a\xa5\xde\x82\x5d\x99\xec\xa1\x66\xec\x8e\xa3\x24\xe1\xf8\x15\xd7\x38\x6c\x44\xb3\x48\x2d\xdd\x82\xfc\xca\x9d\x0b\xc2\x75\xdd\x2c\x2a\x02\xfa\xbf\x20\x34\x70\xff\xeb\xb9\xb7\xd3\xf1\x2d\x6d\x41\xfd\x3a\xd3\x5a\xd4\xf8\xbd\x33\xf9\x16\x2e\x5f\x15\xeb\xfb\xfb\x20\x5d\x4c\x48\x6a\xbd\x55\x6a\x42\x82\xf2\x5a\xc4\xab\xe2\x37\xcc\xb6\xd2\x80\xe5\x1e\x14\xcf\xaa\x8f\xfe\x4c\xe3\xeb\x6a\x68\x5a\x2c\x33\xa3\xf6\xba\x41\xaf\xc3\xa8\x95\x0b\x80\x3e\x7a\x8a\xba\x5d\x14\xb6\x33\xc8\xd2\x50\xe6\x35\xcb\x5a\x01\x6f\x94\xf7\x33\xe5\x84\x94\x19\x7d\xcf\xbd\x94\xfe\xc2\x8f\x33\xb1\xf7\x88\x5b\xe1\x48\x67\xf8\xd1\x5c\x27\x32\x20\xf1\x5a\x2c\xaa\xc6\xbc\x28\x04\xbf\x4a\x36\xfe\x7c\xfe\x99\xe4\xf2\xda\x43\xec\xca\x0f\x55\xd0\x1d\x9f\xb0\xde\xea\xa8\x33\xb6\xb5\x0a\xdc\x69\x9b\x92\x1f\x79\x22\x21\x12\x97\xf0\x2d\xb0\x88\xe7\x8b\xf2\x52\x57\x09\xb6\xd8\x44\x1b\x57\xa1\x49\x8f\x1a\x7b\x0a\x41\xfa\x58\x01\x37\xc2\xe3\x54\xa5\xaf\x29\x2f\x26\x6a\x07\xc2\xab\x6c\x1a\x83\x71\xb1\xb2\xe1\x11\x0b\x6e\x32\x0e\xf5\x18\xaf\xda\x3f\xd4\x2b\x52\x94\xce\xcb\xbf\x53\x63\x34\x67\x1e\xa7\x50\xb5\xa3\x57\x35\xbb\xdb\x8b\x7c\x17\x24\x6e\xea\x97\x8b\x98\x59\xb6\xf2\x77\x70\x52\x15\x59\x66\xa5\xf6\xd6\x95\x15\x16\xc2\x11\xf3\x3b\x84\x8c\xb7\x7d\xf2\x09\x21\x07\x35\x9f\x15\x19\x7b\x9b\x5c\x8f\x6c\xfb\xaa\x58\x90\xf6\xed\x97\xed\x2c\xc4\x6c\x1e\xed\xeb\x3d\x56\xb0\xfa\x30\x36\xf6\x5d\x45\x3f\x7f\xad\xe5\xbe\xd0\x62\x90\x4a\x04\xea\x65\xfa\xab\x5b\xf9\x6a\x6e\x38\x14\xd3\x8d\xcf\x71\x7e\x59\xce\xc0\x17\x89\x56\x8f\x8e\x1d\xd7\xf1\x94\xb0\x48\x73\xf0\x63\xbc\xd4\xf5\xdf\x50\x48\xdf\x4b\x77\xda\x84\xab\x74\xbe\x0e\x50\xb7\x2b\x94\xef\x35\x4a\x8a\xb7\x6c\x96\x2c\x9d\x9e\x54\xdf\x5d\x9f\x05\x5b\xad\x62\xed\x7d\x45\x9d\x1c\xdc\x46\xd7\x2b\xe5\x72\x0a\x52\xa1\x95\x13\x66\x66\xf4\x7f\xa6\x2a\x83\x5f\xbb\xea\xe7\x99\x96\xbc\xa3\x7f\x58\xba\x39\x9a\xc6\x94\x73\xf4\x97\xd0\xce\xd1\xdf\x4f\xb4\xea\x34\xfd\x9c\x53\x63\x0b\x0d\x9d\x73\xf7\xbe\x8a\x8a\x8e\x16\x5e\x45\x47\xc7\xe0\x6d\x25\x1d\x4d\x5d\x51\x4b\x67\x16\xa9\x51\xd3\xb1\x16\xeb\xca\xde\x44\x51\x47\x71\x5b\xa1\xa8\x6b\xe7\x28\x9f\x77\xab\x85\xa2\xae\x55\x34\xaf\xaf\xf5\xb8\xce\x73\xfb\xb7\x0a\x79\xb0\xe2\xab\x10\x88\x28\x61\x93\x08\x4b\x5f\x91\x48\xec\x42\x35\x64\x22\xda\xad\x2f\x7f\x23\x9d\x2e\x93\xa4\xda\xbc\x99\xf3\xb4\x77\xb7\xaf\xe5\xe4\x28\x5b\xd0\xdd\xdd\x47\x1f\xa9\x7d\xbf\xe3\xe1\xc3\x9a\x8b\x5b\x52\xb4\xf7\x6d\x3b\xc6\x79\x19\x91\xd4\xef\xdf\xd6\x41\x24\xbb\x4d\x6a\x20\x6a\x06\x34\x30\xd3\xeb\xc9\x9a\x17\xb1\x32\x1a\xbd\x41\x94\x38\x9f\xd3\x23\x3f\x99\x40\xcd\x66\xbf\x63\xee\xb5\x16\x4d\xc9\x39\x4e\x85\x49\x8b\x79\xa4\xae\x72\x97\x6b\xd9\xbf\xb0\x63\xb6\xb2\xb8\x05\x2c\xb3\xca\x9d\x76\xfd\xf6\xb7\x3a\x44\xfb\x25\xc2\x9c\xd3\x76\x4a\xaf\x70\x9c\x9d\xe3\x3c\xbf\xc8\x49\x59\x62\x30\xf7\x62\xbd\xea\xa0\x0d\xe8\x7d\x6b\xdc\x5d\x80\x96\xbd\xd0\x1f\xf2\x83\x15\x84\x3a\x8a\x92\x94\xa3\xb0\x74\xfd\x0e\xdb\x6f\xed\x5b\x21\xd3\xd5\x4a\x5a\xcd\x29\xad\x6d\x05\xde\x3c\x2e\x04\xfc\x18\x1c\x0e\x41\x15\x1e\xcd\xe9\xaa\x00\xaf\x87\x5c\x9b\x45\xc7\x4b\x39\x01\x66\x77\x0c\x09\xf9\x84\x51\x84\x0a\x92\x4e\x13\x2c\xfd\x70\x01\xe4\xc0\x30\x89\x06\x0a\x66\x6e\x66\x98\x5b\x0e\xd6\xda\xd5\x15\x3a\xed\x9e\x6e\x9d\x75\xcf\xfa\x52\x18\x6c\x70\x03\xc0\xbb\x67\xe2\x9d\x7e\xe9\xae\x0d\x2b\x44\x77\x66\x03\xc5\x50\x01\xb6\x0a\x5b\x01\x7a\x04\xf6\xd8\x9b\xd0\x97\x2d\xdd\x11\x8d\xea\x90\x23\xc8\x0a\x47\x0d\x81\x70\xed\x50\x75\x5a\x10\x0e\x1d\x1e\x0a\x40\xd5\xc0\x70\x88\xa2\x24\x41\xa3\xa8\x20\x63\xe6\xff\x00\x1e\x0b\xec\x6c\x73\x05\x4e\x92\xd1\x93\xb1\xe8\x4d\x80\x76\xb6\x9b\x8c\x4e\xcc\x85\xcd\x39\x9a\x38\x81\x0b\x5d\x24\xc2\x53\x10\x20\x21\x28\xd4\xe9\x59\x07\xed\xff\x00\xeb\x53\xa5\xed\xb2\xc4\x5a\x65\xda\x81\xa8\x6d\x55\x0e\x30\xc3\x95\x3d\xab\x59\xed\x7a\xab\x95\x34\xab\xdc\x7e\x19\x0e\x61\x1c\xa2\xdb\xb3\xb6\x51\xad\xc8\x83\x07\x48\xff\x3e\xd5\x7e\x6b\x2e\xe0\xce\xc4\xae\x2b\x23\x63\x0c\xa7\x37\x9a\x1b\xbe\x7c\xeb\xa6\x46\xcc\x82\x39\x37\x7c\xc2\xcc\xa9\xd1\x3c\xae\xdd\x72\x66\xac\x7e\xd5\x4c\x8c\xd6\xe6\xd7\x9e\x97\xbb\x9c\x18\xd3\xf5\x89\x62\xa4\xda\x4c\xc0\xd9\xa8\x03\xb6\x08\xdb\x0c\xe9\xec\x90\xd4\xe1\xc6\x0a\x5b\x7c\x2a\xb6\x76\x25\xe0\xf6\xd9\xe9\x0e\x07\x15\x69\x0c\x44\x42\x6c\x9d\x59\x09\xea\xdb\xdd\x1d\x00\xab\x37\xd8\x1e\xf4\xb1\xf0\x21\x36\xef\x09\x5a\x63\x77\x34\x91\x64\x82\x7a\x5a\x96\xc6\x21\x6d\x7e\x7c\xc3\x89\x05\x86\xed\x7b\x0d\xb1\x55\x33\xe5\x7c\x93\x10\xa7\x6a\xdf\x3c\xc3\xbc\xf9\xa6\xba\x23\xe3\xef\x39\x13\xce\x3f\x3b\xc6\xbc\x1b\x15\x9d\x9a\x95\xeb\xd3\xad\xbc\xaf\xb5\x9a\x67\x99\xc1\x86\xc2\xf3\x2b\xe7\xd7\xf0\xa2\x58\xb9\xdb\x73\x6f\x45\x49\x54\x94\xe8\xf4\x8c\x0a\x13\xac\xde\x1b\x4d\xfb\xba\x7f\xde\xe5\x1c\x80\x9c\x85\x1c\x1f\x4b\x70\xa0\x51\x2f\xa1\xe0\x53\xd2\x40\x1b\x22\xa9\x31\x8e\xd5\x8e\x30\x92\x03\xdb\x37\x4d\x68\x74\x89\x62\x3c\x89\x96\x09\x28\x42\x8b\x25\x95\x53\xe5\xc6\xdc\xe1\x6e\x6a\x02\x1e\xe6\xd1\x9e\x45\xe3\x18\x75\x03\x06\xac\x76\xc4\x15\x45\xe1\x96\xa7\xb7\x4a\xa3\x7a\xe1\xab\x5d\xe8\x88\xb5\x25\x52\xd8\x6b\x04\x28\x9e\x93\xf2\x69\x87\x52\x7c\x80\x3a\x74\x11\xd0\xff\xce\x3a\x67\x8a\xda\x39\x84\x96\x06\x85\xd2\x65\x62\x3f\x7b\xd0\x66\xb3\x15\xda\x6c\x07\x73\x56\x7f\x1b\x16\x82\xeb\xa4\xca\x59\x09\x6c\x6f\xe0\xce\xf2\xd8\xac\x17\x70\xc3\x4b\x87\x63\x8c\x97\xfe\x0b\xab\xde\x22\x62\xce\xad\x7a\xff\x3e\x65\xa7\xf1\x7f\x9f\xf5\x9b\x45\x04\xae\xbc\x95\xde\x1e\xaa\xef\x1d\xac\x30\x16\x02\xba\x3d\xeb\x10\x6f\x4f\xdd\xbb\x2c\x0b\x67\x9e\x4b\x0b\x7e\x8f\x6e\x6f\x0c\x5e\x7f\xd4\xe6\xad\x0c\x77\x85\x2a\x9c\xa0\xda\x6c\xa1\xc1\x1b\xac\xb4\xff\xd6\x8d\x89\xf7\x50\xe5\x9f\xdf\x31\xaa\xeb\x57\x16\x27\x13\xdd\x9f\x2c\x67\x65\x4e\x21\xf9\x32\xf9\xf4\xcc\xe7\x44\x7c\xb0\x58\x16\xb3\x9e\xe3\x99\x54\xbc\xd4\x16\x6e\x46\xdd\x9a\xe9\x58\x5c\x9f\xeb\xe7\x3e\x07\xa0\x7a\x4b\x9a\x1f\xcf\xde\x79\x80\x74\xff\xb2\x96\x7b\xd2\x5b\x39\xf5\xe5\x13\xa8\x3b\xf3\xbd\xf5\xfc\x41\xd7\x1d\xa9\x83\x23\xfe\xb7\x9f\x3f\x9f\x47\xd6\x06\x4f\xac\x95\x13\x41\x67\x13\x5c\xa5\xd6\xcc\xc7\xca\xb3\xb1\xe6\xdc\x11\x5a\xba\x23\x63\x49\x6a\x1e\x6d\xdb\xf8\x04\x65\xf7\xa3\x93\x3c\x9b\x7b\xcd\x0d\x18\x94\x8f\xb7\x8c\xec\x07\x3b\x96\x81\x90\x61\x19\xb4\xc2\x83\x29\xc1\xd4\x58\xcb\x2d\x58\x14\x1f\x88\xce\xa2\x0c\x7f\x9a\x0d\xac\xea\xab\xf0\x2a\xd8\x9b\xf4\x1b\x4b\x26\xe8\xf2\x27\x3e\xd0\x3d\x21\xe8\x70\x74\x3d\x44\xdb\x60\xfc\xd0\x17\x1e\x9d\x39\xf2\xaa\x16\x51\x6d\x9d\x7a\xf3\x4e\xc5\xbe\x15\x05\x05\xde\x97\xec\x8e\x5d\x2f\xbd\x81\x76\x98\xd3\x7b\xb6\xdb\x16\x14\xa4\x40\xd1\xa4\xc4\xb9\x5c\x24\x7a\x7f\x6f\xb4\x56\xfd\x65\x7c\xbe\xbb\x15\xe7\xa8\xf0\xd9\x8d\x6a\xb1\xc7\x43\xc7\xbc\xa9\xaa\x5f\xf7\xeb\x51\xe9\x46\xda\x8e\x79\x53\xcb\x68\x5a\x72\x1a\xf4\xb0\xbe\x6f\x14\x76\x63\xbf\x1e\xa6\x15\xa3\x32\x1d\xce\x6a\xd3\xbe\x81\xc8\xdd\x72\xad\x3f\xc4\x1e\xa2\xff\xb5\xa4\x7e\x61\x90\xda\xf2\xef\x0f\x45\xfc\xf7\xb4\xaf\xfd\xfd\x2e\xb4\x8f\xbc\xa4\xaf\x07\x68\xbc\x29\xe9\xdb\x61\xc4\x56\xdc\x54\x1c\x62\xb5\xeb\x6f\xb7\xb3\x98\xbd\x58\xa5\x7e\x3e\x7f\x5e\x7a\x4b\x1c\xfa\xf2\xaf\xbf\xea\x25\xbc\xe0\xb7\x7e\xae\x91\x6a\x53\xf7\x7b\x68\x0b\x6d\x98\xbd\xeb\x33\x9f\x4c\x2c\x92\x98\x67\xea\x99\x07\x62\xeb\xd2\xcd\x78\xb0\x5d\xe3\xcf\xde\xc0\xb5\x65\xf1\x65\x70\xb1\xb5\x15\xc7\xa6\xcf\xb9\x5c\x59\xdb\x7d\x53\xad\xea\xbd\x48\xb4\xba\xde\x78\xc1\x5b\x7d\xb5\x2b\xdf\xc4\x5d\x9f\x05\x5b\xbf\x77\xe8\xfd\x93\xe6\x67\x6f\xcb\x9a\x77\x6f\xdc\x13\x09\xfc\xcf\x6c\x5d\x96\xea\xe9\xdb\x52\x7b\xfb\xb6\xd4\x1f\xac\x2d\x3d\xaf\xdf\x96\xf2\xf9\xdb\x52\x7b\xff\xb6\xd4\x1e\xc0\x2d\xcd\x17\x70\x4e\x8d\x2d\x2c\x6c\x1c\xff\x28\x5f\xf1\x11\xdc\x89\xf7\x15\xdc\xc9\xea\xcf\xe0\x4e\xda\xbe\x83\x3b\x71\x1f\xc2\x9d\xdc\xc1\x4b\xb8\xe5\xad\x9f\xc2\x9d\xb4\x7e\x0b\xf7\x7b\xc7\xf5\x3f\x69\x61\x71\xb6\xac\x33\x39\x13\xae\x55\xd8\x0f\x4e\x9c\x9a\xd5\xd9\x52\x37\x3b\x5b\x1a\x56\x62\x4b\x9f\xe1\xd9\x52\x59\x9e\x2d\x75\xd3\xb3\xa5\x6e\x7b\xb6\xb4\x8c\xcf\x3c\xf5\xb6\x59\x1c\xbf\xa9\xfd\xd9\x89\xdf\x00\xed\xe4\x06\x16\x68\x27\xad\x4d\xd0\x4e\x3c\x36\x68\x76\xe9\x9b\xad\x91\x1a\x33\xb4\xb6\x8b\xa4\xbd\x21\xda\xb7\x6d\x56\x49\x77\x59\x60\x50\xcc\x8e\xcb\x2e\x0b\xc8\x37\xcd\x10\x4e\xcf\x51\x9c\x61\xb0\x56\x80\xd7\x81\x51\x1a\x83\x0f\x5b\xf4\xcf\xd7\xaf\x5e\x96\xe5\xe2\x1d\xfe\x7f\x4b\x5c\x94\x6b\x20\x98\x5d\x2e\x70\x36\xb1\x72\x98\x1f\x1b\xf9\x7e\xa3\x2b\xf0\xc2\x1b\x1e\xd8\xd0\xe8\xcb\xf5\xde\x9a\x11\x2c\xb2\x12\xd2\x4c\x00\x49\xfd\x97\x62\x46\x77\x1f\x32\x4d\xb3\x1c\x87\x09\x49\xf1\xda\x35\xb3\x58\xa5\x78\x68\xe5\xed\xfe\xfe\xe5\xec\xfd\xcb\xd9\x3f\xf1\xcb\x59\xf6\x6a\x96\xdb\xb0\x19\xcf\x66\xd9\x86\x83\x6e\xf6\x7a\x96\xef\x7d\x27\x25\x49\xa0\x4e\xa6\xcf\x84\xb5\xc3\x9e\x27\x39\x60\xa4\xbc\x94\x2c\x51\x15\x19\x27\x51\x51\xa0\x53\x28\x72\xc6\xbb\xc9\x32\x14\x13\x66\x55\xad\x0d\xe1\xde\x08\x56\x29\x57\xae\x52\x0e\x82\x6a\x9c\x59\xb7\xf7\x73\x0e\x90\x3f\x1f\xbc\xf9\x78\xf2\xe6\xe8\xc3\x7b\x7a\xb6\x86\x49\xe8\x5e\x90\xb4\xcb\x48\xb3\xfb\x49\xfb\xfd\x5a\xfb\xfd\x93\xf6\xbb\xf8\x35\x1a\x65\xe2\x63\x42\xd2\x14\x5f\xca\x2f\x3c\x2f\xb3\x8b\x48\x82\x2e\xc8\x58\xff\x4c\xa3\x54\xff\x9c\x93\x71\x6e\x7e\x27\x09\xb1\xc0\x0d\x58\x03\x50\x7c\x68\x05\xa6\x79\x94\xc6\xaa\xfb\x91\xd6\x7d\xf5\xfb\x83\xf6\xfb\xad\xf6\xfb\x85\xf6\xfb\xff\xb4\xdf\xff\xd2\x7e\xbf\xd1\x7e\x3f\xd7\x7e\xff\x43\xfb\x7d\x42\x7f\xaf\x9d\x55\x3b\xa6\xa1\x73\xf9\xf6\xe0\x39\x9d\xc0\x10\xed\x6c\x07\x32\xf1\xfd\xd1\x4f\x6f\x0e\x3e\x9c\xbc\x7b\xf1\xf1\xd5\x8b\x37\x3f\x7d\x78\x19\xa2\x5d\x96\x29\xe7\x2c\x54\x3f\x55\xb1\x0a\xba\x08\xd1\x17\x64\x25\x28\x2f\xe9\x90\xf1\xf1\xf9\xf1\xcf\x6f\xd0\xb5\xaa\xe9\xed\xf1\xab\x57\x14\xfa\xc3\xd1\xeb\x17\xc7\x27\x1f\x42\xb4\xb5\xb9\xb9\x39\xe4\x3d\xe4\xf7\xd9\xcf\x92\x6c\xfc\x29\x44\x5d\xca\x18\x8b\xb2\x6b\xe4\x1d\x8c\x21\x50\x71\xa8\x5e\x2e\xb2\xe7\x15\x74\xb7\x6e\xf2\x6c\x72\x1f\xe8\xe2\x7e\x9b\xfa\xab\x6f\x53\x6b\xd2\xc1\x43\x31\x8b\x76\xee\xca\xbf\xc3\x61\x7e\xb9\x28\xb3\xbf\xbf\xd7\x59\xff\x18\xd2\x1e\xa9\xf8\x16\xb4\x41\x2f\xc0\x90\xe6\x74\xbd\xb1\x9b\x5c\xe7\x0c\x40\x71\x85\xfe\xfc\x94\x27\xa1\x07\x0f\x44\xee\x40\x78\x83\x60\x42\xf0\x0c\x7f\xee\xda\x6f\xe4\x0c\xbf\x5e\x3f\xa0\x6d\x5a\xda\xf6\x6d\xbc\x2d\x9c\x41\x9a\xc5\x91\xb8\xea\x96\xd7\xf7\x96\xf7\x75\x64\xbd\xa5\x63\xa0\x02\x47\xb4\x73\x83\x97\xf8\xf3\x00\x74\x93\xdc\x2f\xaf\xcf\x82\x88\x62\x45\x0c\x5b\xb5\xce\xce\x6b\x4c\xb9\x16\xa2\xed\x6f\x1e\xb3\x92\xda\xd3\x63\xf1\xa2\x8c\xb2\x3c\x89\xe3\x4e\xf8\xcd\x77\x41\xc7\x44\x79\x27\x7c\xb2\x79\x7d\x16\x6c\xb7\xf2\xe8\x74\xcf\xf7\xee\xf9\xde\x9f\x97\xef\x29\xb6\xc7\x5e\xf1\xdf\x01\xdf\xb3\x24\xf3\xd5\x05\x73\x8f\x5c\x2e\x0a\xfa\xc4\xf2\x95\x62\x09\xd9\xbc\x76\x30\xe4\xec\x5e\x05\x1b\x9a\x3c\xd1\x01\xe8\xb7\x14\xd0\x97\x29\x29\x5f\x47\x0b\x29\x2e\x76\x99\xcc\x1c\x32\x0e\xd4\xdd\x94\x72\x2f\x91\x89\xf0\xbd\x65\xc8\xf0\xa1\x96\xb1\xb9\x29\x0b\xfd\x6f\x4d\xde\x28\x1a\x8d\xa2\x29\x96\x2d\xe9\x79\x52\xb0\x0f\xed\x9c\xb9\xa7\x46\x2d\xfb\x75\x7d\x76\x92\x9d\xe3\x24\x1a\x8b\x46\xed\x6c\x71\x7e\x08\x7d\x99\x53\x7f\xd5\x1a\xc4\x4f\x8d\x10\xc5\x2c\x4a\xd3\x2c\x35\xc6\x6c\x42\x88\x33\x4b\x58\x93\xdf\xd0\x06\x9c\x97\x42\x0f\x84\x8e\x46\x71\x16\x0a\xeb\x41\x9a\xea\xe1\xe7\xb1\xd0\x0b\x64\x54\xc6\xcf\x5a\xf6\xb8\x3c\x60\xfe\xe1\x99\x80\x1a\x89\x7a\xa0\x34\xc0\x4f\x3a\xa4\x0f\x50\x9f\x61\x38\xcf\xd5\x56\x6b\x50\x62\x73\xcd\x06\xf9\xb4\x02\xd7\x4a\x94\xad\x4b\xd0\x42\x7e\xa3\x29\xe5\x50\x61\x11\xc5\xdc\x24\x14\x4c\x36\x3f\x2f\xf0\x98\x6e\x53\xd2\xcc\x5e\x37\x9e\xe2\x5e\x50\x7c\x16\x50\xaa\x8a\x11\xa6\x70\x31\x8f\xac\x65\xd9\x52\x8d\x67\x51\x1e\x8d\x4b\x9c\x17\x42\x55\x0f\xf7\xeb\xbc\xb4\xb6\x63\x78\xdb\x20\xd3\x34\xd0\x6c\x9a\xd1\xe6\x9a\xdf\x7d\x07\x99\xce\x4a\x24\x3c\xcb\x5a\x5e\x7a\xf9\x18\x0c\xb9\x92\x81\x04\xd0\xbb\x22\x80\x76\x3c\xbe\x82\x98\xa5\x07\xc0\x40\x70\x59\x78\x75\x2a\x6f\x7a\xb7\xfa\x83\x5f\x32\x92\x42\xd0\x05\xf4\x14\xea\x40\x21\xea\x6c\x76\xfa\x68\x83\x03\x57\x18\xb0\xdd\x78\x2e\x20\xf0\xce\x9f\x7d\x32\x60\x10\x2b\xce\x06\xef\xe1\x06\xf3\x9c\x7c\xd3\x79\xa9\x32\x28\x34\x1d\xca\xc0\x26\x09\xe6\x84\x10\x94\xc3\xf5\x15\x6d\xcd\x0b\xf3\xba\x5c\x33\x2b\x24\xa5\x95\xf8\x91\xa5\xfb\x95\xf6\x38\x3c\xa2\x8d\x2b\xf3\x41\xe6\x49\xe4\x84\x6d\xe2\x52\x78\x9f\xb1\xb8\xcd\xc3\x21\xfa\x91\xa4\x31\x62\x8f\xb4\x78\x47\x65\xdc\x65\x2a\x3b\x74\x3a\xea\x46\x1e\x6c\x58\x02\x08\x05\x35\xc3\x9f\x85\x29\xb2\x3c\x5d\xd1\x34\x76\xbe\xa1\x67\x8b\xea\x53\x11\xad\x66\x5b\x7f\xbf\x02\x46\x32\xdc\x36\x66\x0f\x91\x8d\xfd\x6d\x1d\x5c\xc4\x34\xd6\x6d\x34\x54\x53\x3d\x42\xdb\xe1\x21\x2c\x64\x0b\x13\xd4\x63\x45\xf6\xf7\xd1\x66\xdf\x38\x8f\x8d\x72\x1c\x7d\x52\xa0\x74\x94\x1b\xfb\x88\xbf\x0e\xa7\x33\x78\x38\x8b\xf2\xc3\x2c\xc6\x50\x83\xf7\xb8\x45\x27\x5b\x98\xd5\x14\x65\xde\x8e\x42\xd8\xa4\xad\x44\x22\x07\xb4\xc8\x6f\x47\x23\xd0\xdc\x7f\x0f\x91\xdc\x64\xe6\x8b\xb2\xea\x95\xb9\x39\xd9\x1e\x5f\xf1\xbd\x45\x8e\x27\xe4\x33\x0b\x86\xb5\xf9\xb9\x4f\x67\x01\xb8\x86\xdf\x4d\x3d\x8f\xda\x56\x3d\xfb\x5e\x1b\x64\x38\x6c\x46\x09\x70\xf3\xda\xa0\x00\xbe\x68\x9d\x86\xcf\x7c\xee\x3e\x9d\x77\x43\xa7\x0a\x4a\xf1\x1c\xf3\x6c\xf6\x61\x39\x70\xf3\x6b\xb6\x1c\xc4\x8c\xd0\x96\x14\x75\x4c\xb2\xdc\x36\x85\x2b\xca\xbc\x2a\xb2\xbd\x36\xa3\x14\x6a\xcc\xe7\xe6\xa0\xec\x91\x9b\xad\x74\xb0\x32\xe4\x41\xbe\x0d\xef\x6b\x0a\x84\xf6\x77\x63\x1f\xa5\x62\x5f\xf8\x1e\x6d\xa3\xa7\xf4\x14\x83\x36\x10\xdd\x0f\x52\x1f\x4d\x70\x57\xf0\x33\xfc\xf9\x2e\x49\xc3\x8a\x1d\x60\xd3\x46\x03\x6b\xf8\xcd\x88\xc3\xe1\x19\x1a\x75\xfc\x36\x14\xf0\xbb\x4d\xab\xe5\x79\x74\xb2\x4c\x12\x89\x86\x21\x3e\xc7\x69\xc9\x0c\xfe\x81\xe5\xff\x52\x64\x29\x8a\x46\xc4\xe6\xf1\xc2\xfd\xe1\x87\xec\xc7\x65\x92\xd8\x6f\x21\xc5\xa3\x00\x5a\xfa\x11\x2b\xed\x3e\x6a\x62\x0d\x3b\xed\x2a\xc6\xee\xb6\x61\x08\x52\xb4\x72\x5d\x49\x4a\xbf\x07\x60\x0a\x41\xd2\x18\x7f\x3e\x9e\xf4\xba\xbd\x6e\x1f\x7c\x3c\x3e\xda\xf2\x3c\x6b\x94\xf0\x8e\xbd\x5f\x79\xb9\xc0\xbc\x39\x00\x02\x2a\x32\xfd\x92\xf5\x48\xff\x8b\x08\x05\x3c\xa0\xf0\x7b\xe8\x9a\x8b\x62\xa6\x05\x9f\x6c\x05\x6d\xa0\x6e\x8f\xce\x9c\xac\x7d\x03\x75\xfb\xdd\x56\x6b\x2f\x26\xc5\x22\x89\x2e\xd9\xbc\x80\xaf\xd0\xb4\xa4\xb2\xad\xc4\x86\xfd\xf6\xec\x33\x64\x3f\x67\xc5\xea\x5e\xaa\xd2\xda\xcc\xc9\xf7\x2f\x2f\xa3\x07\x74\x4b\xb3\x28\x06\x4f\x07\x22\x6e\xe2\x65\x8f\x9b\xc7\xf5\xd1\xa3\x1f\x64\xa2\x9c\x56\xb7\x6f\xb5\x0f\x98\xa5\xed\xa5\x33\xb3\x06\x9a\x39\x18\x9b\x6c\xf4\xd4\x7e\x9b\xca\xdf\x75\xd1\x35\xa3\x9c\x86\x0c\x87\x6a\xa0\xd9\x39\xce\x93\x2c\x8a\x71\x2c\x55\xbe\x9e\x35\xa1\x0f\xe0\x83\x22\x92\xaa\xb7\x89\x43\xf4\xe1\xf8\xf9\x71\x88\xe6\xd1\x27\x50\x02\x93\xf4\x7c\x99\xa4\x38\x8f\x46\x09\xbe\xcb\x01\xaa\xd3\x80\xfd\x0a\x77\x0b\x3d\x42\x5a\x76\xbf\x3f\xc8\xf1\x22\x89\xc6\xb8\xd7\x45\x5d\x70\xce\x46\x4f\x0b\x1d\x33\xd8\x63\x96\x9e\xe3\xbc\x2c\x54\xe8\x4c\x90\xfb\x62\x3c\x26\xf3\x28\xb1\x99\x2c\x49\xfd\xcc\xbe\xcc\x9e\xb3\x02\x2e\xe5\xd5\x86\xc1\x34\xdd\x13\x32\x01\x8f\xd7\xd4\x18\xc8\xb1\xcc\xdc\x38\x97\x32\x8c\x4c\x9b\x31\x36\xca\xb6\x94\x27\xde\xd5\xb8\xb4\xba\xea\x83\xac\xa6\x42\x1f\xea\x8e\xcf\x13\x62\x9b\x2b\x4a\xcd\x1d\xc5\x38\xec\x33\x80\x04\x17\xc5\x87\x59\x94\xf6\x36\xc1\x19\xec\x23\x66\x3d\xce\xad\xf0\x39\x61\x6d\xf5\x21\x0c\xab\x96\x63\x60\xf1\x60\x09\xee\x96\x39\x2a\xa3\xf4\x92\x3b\xd0\xe1\x6e\x45\xd3\x6a\xb4\x0e\x38\x5e\x0f\xd2\x98\x29\xfa\x19\x0d\x91\xc9\x65\xc1\x1d\xa2\x17\x68\x84\x27\x59\x8e\x07\x0e\x5d\xbd\xe4\x47\x87\x7a\xdc\x5f\xf1\x3d\xa8\x81\xb4\x5e\xc2\x3e\x6f\x20\x5f\xae\xdf\x87\xdc\xe4\x6b\x1e\x7d\x66\xe1\x27\x3f\x93\xf2\x32\x44\x4f\x40\x59\x2d\x76\x1d\x52\x70\xd7\xc4\x50\xb4\x6f\x6f\x32\xda\x24\xf7\x36\x28\xc4\x9e\x51\x54\x9f\xce\xfa\xc2\x56\x59\x6e\x10\x77\x4e\x69\x87\x9e\x42\x18\xd2\xba\xde\x2a\x20\x4e\xd2\xdf\xdf\x1f\xbf\x19\x48\x2c\xb3\xf6\x94\x23\x4a\x70\x01\x5b\xa0\xc8\x8e\xca\x19\xa0\x45\x54\x14\x94\x77\x95\xb3\x3c\x5b\x4e\x67\xe6\x0a\x90\x03\xe1\xb4\x06\xb5\xba\xd7\x90\x8a\xab\x3d\x82\xd3\x92\x47\xe6\xad\x1c\xb1\x00\xe0\x6f\x34\xcc\xea\x1a\x6a\x3b\x17\x76\xa0\x5a\x05\xa8\xb7\x4e\x8a\x1f\x49\x4a\x4a\x6c\x21\xdd\xea\x06\x48\x88\x5a\x27\x4c\x29\xcb\xed\xa8\xb6\x2e\xde\xf1\x4d\x85\xad\x03\x7a\x5e\x4a\x81\xfb\xa3\x9f\x89\xcd\x50\xa6\xb8\x84\xc8\xc3\xc7\x93\x93\x94\x78\xb5\x5d\x50\xb6\x9c\x61\xfe\x43\x2e\x38\x54\x66\x81\xd4\x4e\x49\xc7\xe6\xde\xe8\x8b\xb2\x1f\xb2\x9a\x1e\xeb\x4c\x1f\x8a\x80\xfb\xad\x02\xe1\x3c\xcf\x72\xe1\x5a\x86\xf5\xb8\x40\x69\x56\xa2\x71\x96\xe7\x78\x5c\x86\x17\x72\xdd\x98\xbd\x36\x16\x10\x2d\x28\x48\x60\xc9\x32\xe1\xbf\xa7\xf0\xdf\xa0\xcc\x5e\x65\x17\x38\x3f\x8c\x0a\xdc\x03\xe6\x72\x11\xa5\x5d\xc5\xc5\x28\xcc\x3f\xf8\x6d\x32\xbf\xa2\x39\xa5\xff\x9f\xa9\x83\xb8\x06\xa2\x7b\xef\xd6\xc9\x8e\xf9\x13\x4b\xf1\x05\x7a\x41\xc7\xd4\xeb\xc2\x95\x2e\x74\x03\x2c\x4e\xff\xdd\x2d\x11\xfe\x4c\x8a\xb2\x08\xd0\x22\xc1\x51\x01\x42\x31\x8c\x3b\x4b\x25\xa2\x26\x59\x92\x64\x17\x24\x9d\x42\xc9\x82\xf2\x40\x6b\x11\xf1\x1e\x06\xe0\x25\x21\x50\x8f\x37\x6a\x62\xbd\xca\xde\x83\x0f\x2b\xd3\x37\x70\xf4\x09\xc3\x12\x64\xec\x3e\x9b\xa0\x0b\x92\x82\x55\xac\x64\xb1\x8c\x00\x28\xa3\x05\x8f\x13\xe8\x22\x4a\x19\x5a\x39\x55\xbd\xcd\x8a\x82\x8c\x12\x36\x7d\xe0\x06\x83\x1b\xe6\xbd\x3f\xa2\xb2\x65\x5e\xb2\x9f\x54\x9c\x16\xb8\x7a\x31\x99\x90\xe9\x25\xff\x38\x16\x64\xf4\x08\x7d\xa2\x8d\xb3\x3f\x71\x2d\x05\x1f\xfc\xfe\x8a\x01\xcd\x15\x10\xbf\x44\x62\x1f\xe2\xc2\x89\x41\x4d\x15\x14\xbf\xe7\x61\x1f\xfc\x5a\x48\xe5\x30\xf0\x47\x8f\xe4\x72\x14\xf7\x35\x0c\xfc\xd7\x68\x94\x19\x39\x1e\x78\x76\xdf\xc2\xba\x0d\x97\x34\x7a\x0e\x83\x97\xed\x6b\x05\xd9\xa7\x18\xb9\x00\x40\x56\xbe\xf6\x01\x97\x26\x02\x09\x51\x2a\x06\x2a\x7e\x95\xec\x97\x2d\x3a\xb0\xf5\x6f\x9d\x70\xaf\xa4\x3c\xce\x26\x7e\x1c\xa5\xf4\x04\x10\x49\x16\xcc\xd3\xb9\x4e\x2c\xcb\x51\x84\x5e\xbe\xf8\x27\x1c\xb6\x85\x54\x76\x63\xc6\xc1\x7b\x6c\xed\x90\x62\xf3\x44\x3f\xcf\xb0\xf0\x8a\x17\x69\x57\xb3\x3c\x66\x89\xe6\xda\x9f\xae\x9b\xa8\x40\x17\x98\x2e\x04\xe5\x0e\x45\x0c\x62\x4d\x93\x79\x7e\x26\xa9\x7e\xf0\x16\xa3\xd4\x19\x07\x13\x68\x68\xcd\x82\x19\xe8\xa2\x0f\x2b\xa1\xc7\x78\x35\xf9\x11\xe3\x41\x96\xd4\xa3\x6f\xb6\xbc\x02\xf4\xd4\x68\x24\xd4\xbf\x34\xf9\xa9\x71\x99\x46\x0c\xc3\x9e\x95\x7a\x41\xd2\xfb\xa5\xf9\xd7\x5b\xca\x65\x56\xb7\x90\x35\xf9\xff\x37\x5b\xd2\x74\x5d\xfd\x6e\x8b\x1a\xd0\x71\x37\x4b\xba\x24\x73\x5c\xfc\x1e\xcb\x39\xe5\xaa\x42\xba\x88\xa5\xe2\xa9\x60\x87\x76\xd8\x72\x91\xb4\x41\x71\x88\x41\xf6\xa4\x15\x49\x28\x22\x10\x37\x81\x74\xe6\x15\x25\x98\xb5\x49\xdf\x53\xaa\x02\x05\x20\xf1\xaf\x1b\xcd\x58\xb3\xd0\x70\x9a\xf9\x86\x8a\x74\xb0\xc8\x45\x79\xfe\xe3\xea\x0a\x6d\xee\x79\x8f\x2a\xbc\x5e\xe7\xa8\xc1\xd2\x8d\xf3\x0d\xc7\xb9\xe8\xc9\x83\x07\x88\xff\xf6\x89\xf0\xb4\x49\x3b\x57\x3f\x2f\x34\x06\xf6\xe7\x85\xa5\x86\x63\xf3\x73\x37\xe8\x76\xf5\xeb\x13\xcb\x7f\x99\xaf\xb4\x4e\x28\x95\x32\x5a\x2a\x22\xba\x7a\x48\x45\xd2\x09\x03\x13\xb1\x35\xe4\xc1\x8a\x9b\x3b\x02\x6c\x79\x91\x75\x0b\x34\x96\x91\x56\x1c\xd2\x32\x03\xb1\xb4\xa1\xaf\x0a\xaa\xd1\x8e\xbc\x66\x9d\xa6\xfa\xca\x20\x19\x0a\x3e\xd2\x28\xcb\xb7\x60\xe1\x21\x76\x4f\xf3\x25\x27\x0b\xe8\x0a\x86\xc6\xa9\xeb\x4c\x6e\xf9\xd7\x81\x59\x1e\x2c\x92\x65\xa1\xba\xc0\xbf\xbd\x4e\x07\x25\x90\xa9\x17\x9a\xe1\xf1\xa7\x42\x1c\x82\x18\x8f\x14\x97\x96\x05\x7f\xc2\x96\x5c\x82\x77\x5d\x6f\x8c\x60\x46\xf2\x63\x6f\x7c\x60\x33\xd2\xaf\xd6\x00\x5d\xff\x91\x82\xd7\xdd\xcd\x41\xc8\x23\x3e\x73\x56\xdd\xc6\xc4\xf1\x4a\x2d\x7d\xd8\xf0\x3f\x9b\x9f\x4f\x37\x1f\x7d\x17\x3d\x9a\x9c\x7d\xd9\xdd\xbc\xfe\x9f\x21\x19\x94\xb8\x28\x25\xf8\x0a\x63\xaf\x19\xf2\xd7\x19\x6c\x8b\x61\xc2\x69\x7e\xf8\x9f\xde\xe6\xe7\xfe\xd3\xda\x71\xea\xf4\x37\x1c\xaa\x08\x56\x2c\x46\x15\xf4\x8e\xf9\xf5\xe5\xc6\x82\x73\x78\x5d\x4b\x77\x63\x6d\xd4\x26\xfd\x72\xf7\x7c\xc8\x74\x20\xe1\xed\x8c\xd9\x17\xca\xe6\x34\xb0\x83\x47\x3f\x7a\xc1\xac\x2e\x43\x40\xad\x6e\x01\x2e\x88\x8b\x39\xfd\x77\x1c\x2d\x0a\x90\x1c\x92\x04\x89\xef\x40\xf7\x9b\x68\xf7\x98\xb9\x83\xd7\x3a\x6c\x34\x70\x2c\xb7\x77\x86\x1d\x1c\x8d\x67\x68\x1c\x15\x4e\x35\xa4\x60\x84\xb2\x9c\xf3\x19\xd2\xa8\x89\xad\xb2\xf6\x34\xc5\xda\x2a\x96\xf3\x39\x8e\x2b\xc9\xcb\x6a\xee\x8e\xc9\xcc\xaa\xbd\x8e\xdc\x86\x43\x36\x1e\x0b\x37\x91\x2c\xc9\x7f\x39\x3b\x90\xd2\x6f\x70\x88\x97\x51\x01\x2e\x62\x66\xd1\x8e\x68\xc4\xd4\x8c\x08\x69\xc7\xe7\x86\x65\x77\x13\x6e\x1c\x91\x45\x21\xe0\x52\xbb\x9c\xa1\x04\xc3\x23\x67\x2d\x2e\xde\x62\x81\x73\xda\x5b\x31\x0d\x29\xc4\x14\x9c\x12\x16\x76\x2e\x2a\xf0\x3c\x5a\xd0\xe9\xd8\x32\xf4\x76\x3d\x69\x8f\xa0\x75\x1a\x9c\xa5\x6d\x3d\xee\xa3\x1f\xd0\xb7\x74\x3b\xe7\x59\xa7\xe4\xcc\xd2\xec\xac\xef\xef\x6b\x99\x40\xf4\xf5\x15\x7e\xbf\xef\xa9\xf1\x84\x76\xdd\x5f\x63\x85\x07\x6f\x6d\x59\x2a\x96\x6f\x30\x7e\x15\x15\x41\x52\xe9\x6b\x7e\xa4\x13\xd4\x58\x41\x8a\x65\x76\x97\xa4\x28\x74\xd1\x72\x5f\x5e\x81\x12\x35\x0d\xb0\xa4\x3e\xfb\x2d\x34\x6f\xa7\xdb\xe5\xa4\xe4\x92\xa9\x31\x63\x37\x22\x5a\x0d\x68\xec\xf4\x9e\x56\x54\x43\xc6\xa2\x17\xda\x25\xba\x4d\xd2\xc0\xf4\x66\xca\x90\x8f\x12\xe9\x77\x10\xae\x9f\xb9\xb5\x17\xf7\x6a\x92\xc2\x0d\x02\x6e\x24\x37\x93\x84\xec\x01\x6c\xec\x57\xd2\xa2\x46\x5f\x36\x27\x6d\xac\xa9\x96\x36\xad\x92\xa6\xe8\xa9\x44\x4d\x83\x8f\x96\x19\x15\x29\x49\x8c\xb6\x37\x99\x2b\x9f\x47\xfc\xbe\x8f\xb5\xc9\x1e\x16\x6c\x7e\x46\xcc\x56\xc3\xb5\xd5\x6a\x24\x65\xff\xe5\x3d\x77\x0a\xa0\xb3\x6d\x69\x8f\xd5\x8e\x80\x2d\x79\xc6\xbb\xf3\x09\x5f\xb7\x02\x1f\x9a\x10\xcf\xb6\xdb\xba\x9d\xd6\x53\x11\xbf\xcb\xf2\xd5\x67\x42\x88\x88\x88\x70\x43\x24\x6b\x54\xcf\xa0\x02\xb4\xbb\xe9\x57\xfa\x0b\xff\xc0\xe2\x38\x5d\x28\x31\xb7\x39\xf6\xa5\xf7\x28\xe9\xbb\xc9\x32\xe2\x61\xb2\x9d\xcc\x77\x08\x44\x3d\xd6\x0d\x4b\x30\x14\x7d\x4b\x8b\x32\x4a\xc7\x94\x8d\xa8\xc2\x57\x57\x12\x69\xbc\x30\x3c\x3b\x83\x5f\x86\x3f\x0b\x6f\x2a\x33\x75\x00\x66\x24\xab\xec\xb6\x45\x94\x38\x10\x37\x61\xe9\xbd\x63\x2b\xd4\x12\x45\x9e\xc8\x45\x5e\xfc\x70\xde\xca\x7b\x06\xa3\x61\x7d\xeb\xde\x1d\x7a\x58\x5f\x5a\xe3\x46\xf4\xb8\x19\x3b\x3f\x2a\x9b\x90\x55\xf1\x23\x8a\xde\x08\x43\xa2\x44\xb7\xe5\x88\x68\x9f\xca\xe6\xe1\xb0\xae\xdf\x60\x30\xc7\xbc\x6f\x2d\x17\x03\x95\x2f\xd2\x65\x92\x40\xf0\x82\x9e\xbb\x42\xc0\x0e\x1b\x74\x18\x9e\xb1\xf3\xeb\xd7\x96\x23\x1f\xb1\xce\xb6\x60\x07\x0c\xf0\x26\xcc\x80\x25\xdd\x68\x22\x79\xf7\xda\x8e\x06\x3c\xf3\x35\x8f\xc5\x09\x74\xd1\x72\x24\x6e\xb0\x8a\x96\x2c\x0d\x0a\xd6\x8e\xc1\x3e\x8f\xb0\x7d\x14\x4c\xde\x32\x41\x75\xe6\x20\xfe\x5e\x98\xeb\x4a\x19\x14\xd4\x8e\x81\x16\xb3\x1f\x01\x48\x8f\x46\xf9\xa5\xbb\x4f\xad\xaf\xc3\x55\x23\x7b\x3f\xab\x6c\x53\xaf\xd1\x18\xa2\x31\xf5\xb0\x67\x6f\xd3\x4f\xa4\xab\x8c\x3a\xe2\xe3\x7e\x41\x27\x10\x2f\xe7\x68\x94\x64\xe3\x4f\x68\x86\xa3\x18\xe7\xf4\x23\x9b\xdb\x46\x18\xa4\x78\x46\x93\x7d\x42\xc3\x0c\x7f\x96\xee\xca\xa1\x2c\x9a\x90\xa4\xb4\xb5\x99\x1e\x82\x05\x58\xc3\x2b\x30\x4d\xa9\x3d\xea\x7f\xb3\xb5\xad\xce\xfa\x0c\xbc\x01\x2f\xd5\x27\x75\x56\x97\x5e\x95\xef\xa0\xee\x42\xf9\x02\x01\xab\x83\xf6\x9a\xdb\x8f\x1b\xcc\x4c\x92\x51\x31\x6f\x41\xc6\xee\x3c\x7c\xa0\xc9\x4d\xf3\x50\x4a\xa0\x9a\x09\x80\x9a\x8c\x09\x80\x62\xb5\x13\xf0\x78\x57\xe1\x9f\x41\xdf\x18\xff\x50\x95\x5e\x93\x0f\xfd\x0e\xd0\x8d\xb0\x5f\xe1\x31\x84\x8b\x7c\xa1\xf8\x11\x88\x54\x78\xc3\x13\xca\x5f\x2c\x1d\x04\xc3\x90\xfd\x27\x52\xb8\x41\x48\xa8\x7e\xb2\x1c\xcd\x58\x24\xd4\x3f\x44\xb9\x93\x72\xf2\x24\xe4\xff\x8b\x34\x30\x3f\x09\xc5\x0f\x55\x0f\x83\x15\xbf\x54\x3a\x87\x97\x3f\x79\x3d\xae\x0d\x6d\xe8\x4b\x64\xd0\xae\x69\x66\xe8\x49\x33\x60\x85\x15\x64\x68\x27\x88\x71\xfc\x4c\xd2\x90\xfd\xa7\xfa\x0a\x69\xfc\x87\x80\x93\xd2\x62\xa8\x7f\x88\x5c\x53\x67\x1d\x3a\x29\x12\x6b\x4c\x50\x0f\xd5\x4f\x96\xa3\x49\xc7\xa1\xfe\x21\x72\x8d\x93\x48\x68\x27\x08\x28\x2d\xdf\xca\xb1\xce\xee\xa1\x9b\x24\x7a\xe8\x40\x3a\x49\xa2\x4e\x21\x8c\x85\xda\x6f\xbd\xbf\xe9\x34\x94\xbf\x44\x3a\xdb\x53\x43\xf9\x4b\x8e\x9e\xad\xf7\x50\xfd\x94\x63\xa2\xbb\x64\x28\x7e\x88\x54\xba\x61\x85\xfc\x7f\x59\x07\xe5\x77\xa1\xf8\x21\x52\x81\x6d\x84\xe2\x47\x00\x0b\x8c\xf9\x8d\xe3\xcf\xb1\x3b\xe1\xd6\x77\x41\xad\x63\x9a\xa0\xb3\x2c\x27\x4f\x3a\xe1\x93\x6f\xae\xcf\x82\xed\xad\x36\xae\x1a\xcc\x25\xbc\xcf\x16\x70\x87\x7b\x28\xe8\x84\xa8\xb3\x39\xd8\xde\x1c\x6c\x75\xd6\xae\x85\x87\xb6\xed\x56\x01\x84\xef\x5d\x40\xdc\xbb\x80\xf8\x2b\xb8\x80\xe0\xb5\xac\xb9\x2e\xda\xfe\x8e\x27\x93\x1c\x5f\xa2\x9f\x49\x32\xfe\x84\xd1\xf7\xbf\xe0\xc9\xc4\xf6\x03\xd1\xd2\x91\x1b\x80\x91\x28\x45\xc7\x54\xe2\x8e\x00\x8a\x44\xa9\x0b\xf6\x63\x34\xa2\x60\xff\xc8\xa6\x38\x29\x4a\x9c\x24\x38\x47\xdf\x4f\x20\xd1\x05\xfe\x29\x3a\x47\x3f\x67\x59\x8c\xbe\x9f\x56\xfa\xa7\xd8\x55\x7e\x79\xb8\x8b\xc6\xd7\x51\x1a\x4d\x4d\xa7\x11\x83\x21\xc5\xc2\x30\x67\x00\x73\x06\x20\x9c\x43\x1c\x8d\xe0\x70\x64\x03\x93\x51\x94\x0a\x90\x17\x60\x95\x6f\x43\x30\xc9\xab\x18\xe2\x72\x26\x00\x7f\xf6\x56\x25\x00\x2f\x54\x8d\xcf\x9f\xd5\xc0\xc5\x23\xe9\x2f\x76\x56\xd7\x70\x31\x93\x0d\xbf\x01\xcf\xe2\x55\x80\x29\x2e\x05\xe0\x5b\x9c\x17\xf0\x84\xaa\x1a\x7a\xc1\x41\x64\x27\x2e\xa2\x7c\x5e\xd7\x0d\x9a\x2f\x81\x71\x59\x42\xd4\x25\x17\xbe\xe0\x59\x02\x54\xb0\x1f\x03\x52\xf0\x15\x7a\xf4\x52\xee\x3b\x48\x62\x55\xa8\x85\x85\xaf\xf6\x02\xc2\x80\x84\xbf\x25\xfe\x60\x03\xa7\xb1\xa7\x6f\x2c\x43\x80\x3d\x83\xa3\x95\x0b\x35\xa2\xe9\x12\x93\x79\xb6\xc0\x79\x79\xe9\x81\x5b\xf0\x2c\x01\xfa\xb2\x2c\x17\x6f\xf3\xec\x9c\xc4\x5e\xba\xa4\x2b\x7a\xc1\xb3\x25\x55\x2e\xc6\x35\x25\xc8\x62\x6c\x17\x68\xe7\x91\x70\x6d\x4d\x0a\xf5\x3f\xe3\xd1\x0e\xea\x89\x6a\x4c\xaf\xba\xb9\xbd\x94\x52\x7c\x61\xad\x2f\x55\x52\x73\xb0\xcb\x43\xa5\x6a\x3d\x17\x50\x1a\x10\x66\x6f\x5c\xf0\x05\x5d\x57\xe0\x68\x5f\xaf\xe2\x02\x96\x10\xcd\xfd\x39\x4a\x9d\xdc\x78\xc4\x33\x9f\x3f\x73\xf2\x8a\x99\xa8\xf7\xfd\xcc\xad\x37\x85\x15\x42\x73\xdf\xe0\xd2\xc9\x5d\xa8\x65\x41\x41\xc4\x2a\x71\xe0\x46\xbf\xfe\x2a\xda\xa0\x54\xef\xf6\x41\x91\x3f\x00\xf1\xcf\x9e\x0e\xa3\xe8\x5e\x9d\x58\xa2\x05\x09\xe5\x9e\xca\xff\x67\x27\x17\xbd\x93\x1c\x97\x85\x51\x54\x27\xae\xd0\xf8\x0a\x24\x8c\x46\x4d\xa1\xfe\xe1\x34\xf1\x51\xae\x10\xf6\xc3\x19\x20\x07\xe8\xa9\xf6\x39\xb1\x13\x5c\x84\xda\xef\x1e\x33\x0a\xba\xee\xef\x51\xc1\x6b\x38\x04\x07\xbb\x05\x46\x6a\x0c\x19\xdb\xd0\xc1\x27\xd0\x1a\x25\x46\xcf\xf8\x9a\xc6\x56\x39\x2e\x2a\x7b\x8a\x3a\x45\xb4\x4c\xac\xd3\xa5\x1e\x11\xb8\x79\x05\xe8\x85\x57\x26\x7c\x4f\x5f\x72\xcc\x42\x41\xa8\x5e\x7c\xc2\x78\x71\x54\xbc\xbf\x4c\xc7\x24\x9d\xd6\x76\x05\xca\x5a\xf0\xed\x28\xd0\xd3\x11\x9d\x6b\x3c\x53\x76\x30\x16\x94\x78\x1c\xc3\xdc\x49\xc1\x97\x07\x46\x3c\xa4\x15\x50\xf0\xed\x81\xe3\xaf\xb1\x05\x18\xfd\x74\xa0\xf4\x87\xb9\x0c\x50\xa6\x78\x61\x8d\x3a\x45\x82\xa7\x6d\xf5\xca\x4a\x34\xcf\x53\xbc\xb5\xda\xd0\x5a\x9a\xa7\x6e\x1d\x97\xa2\xf6\x3a\x9c\x32\x53\x49\x01\xf9\x33\x49\xbd\x7d\xd0\xa1\xf8\xb7\x03\xa7\x9b\xc8\x30\x48\x99\xe2\x81\x75\x6f\x97\x45\x99\x43\xfb\xd2\xd1\xe9\x73\x55\x59\x27\xc7\xd3\xee\xd1\xb3\x83\x37\x5a\x63\xf4\xd3\x81\x52\x4e\x6b\x18\x18\xff\xf6\xc1\x09\x7f\x2a\x12\x10\x12\xe8\x1e\x67\x2f\x7c\x26\x18\xe8\xef\xbb\xd9\xdd\x92\xe9\x5d\xd1\xbc\xf5\x83\xbb\x6d\x1b\xb2\xea\x16\x36\x26\x39\x68\x9c\xc7\xd1\x02\x5e\x65\x68\xf7\xa0\x9e\x19\x3d\x3a\x3c\x78\x6b\xac\x7d\x5a\x4e\xb7\xb4\x23\x23\xb9\x07\x52\xe1\x94\xe5\x1b\x4f\x40\x29\xc4\x80\x37\x23\x56\x36\xb8\x99\x91\xfc\xb7\x2e\x9e\xe8\xa9\xe4\xdd\xb0\xd7\x71\x61\xa5\x67\x5e\x5d\xa7\xa0\xca\xe9\x8a\x3d\x29\xcd\x62\xdc\x0d\x0c\x88\x29\x98\x97\x84\xa8\x4b\x45\x92\x8f\xe3\x84\xe0\xb4\xfc\x07\x03\xef\xaa\xab\xed\x7e\x70\x93\xd6\x70\x79\x91\xe5\x9f\xaa\x1a\x4c\x71\xf9\x91\x83\x5a\x20\x66\x38\x80\xd0\x5e\xe5\xb7\xec\x16\xe6\x7a\xf1\xaa\x7e\xe1\x72\xf6\x11\xe6\x7a\x9c\x25\xff\xf8\x1d\xfa\x77\x31\x23\xc5\x02\xe7\x55\xdd\x2b\x66\xb3\x5b\xa3\x0d\x7e\x9e\x79\xf7\x12\x52\x1c\x66\x69\xca\x3c\x39\x69\xcb\xad\x6f\xd0\x5e\xcf\xbb\x5d\x3e\x78\xe0\xdd\x46\xf5\x2a\x7b\x7d\xff\x0e\xc6\x7c\x17\x08\x89\xbd\x92\xe6\xc1\xc8\x14\xe2\x22\x70\x89\xc6\xab\xfe\xa5\x75\x0b\x6f\xa2\xfa\xf9\x81\x69\xae\x8c\x63\x47\x27\xdc\xde\xa4\x49\xfa\x81\xa5\x13\x6e\x6f\xd1\x34\x75\x58\xe8\x84\xdb\xbb\x32\x85\x89\x4e\x9d\x70\xfb\x89\x4c\xd2\x45\xff\x4e\xb8\xb3\x2d\x33\xe8\x0a\xef\x84\x3b\x3b\x2a\x41\x89\xfc\x9d\x70\x47\x55\xaa\x0e\x8d\x9d\x70\xe7\x5b\x27\x19\x97\xb3\x4e\xb8\xf3\xc4\x49\xbf\xa0\xd5\x3f\x71\xe1\x53\x5c\x76\xc2\x9d\xef\x9c\x74\x21\x20\x77\xc2\xdd\x4d\x27\xb3\x98\xcd\x3a\xe1\xee\x96\x9b\x4e\x65\xe4\x4e\xb8\xab\x86\x25\x4e\x46\x9d\x70\xf7\x1b\x99\x68\x9e\xcb\x3b\xe1\xee\x63\x99\x25\xa4\x99\x4e\xb8\xfb\x6d\xbd\xea\xf0\xfa\x2c\xd8\xde\xb9\x57\xec\xdd\x2b\xf6\xfe\x5b\x14\x7b\x51\x92\x80\x3b\x8a\xdb\xf9\x77\xd5\xf4\x67\x8e\x02\xc5\xa7\x41\x11\xc1\x61\x5e\x9c\xb3\x17\x03\x9a\x66\x02\x7a\x23\xe0\x54\xa8\x98\xa6\x98\x48\xae\x76\xc7\xab\xde\xf9\x11\xee\x8e\xad\xca\x20\x4d\x40\x5c\xb0\x88\x47\x26\x88\xe2\x77\x90\x29\xbd\xbe\x1e\x24\x89\x31\x14\x53\x60\x32\x8f\x5c\x01\x18\x02\x04\xc8\xb2\x5c\xab\x50\x95\x98\x09\xfa\xd1\xf9\x17\x76\xf7\x4f\xff\xd3\x93\x1d\x73\x4c\xb6\x39\x39\x3d\xac\x0f\xee\x6d\x49\xb3\xc2\x4f\xf8\xbe\xfc\x75\x75\x05\x51\x6f\x90\xed\x25\x82\x26\x42\xea\x69\x97\x4a\xa7\x10\x6f\xa0\x1b\xa0\x6e\x99\xb1\x9f\x67\x03\x86\x66\x2d\xca\xe1\xc4\x73\xd9\xca\x9b\x39\x9d\x9c\x81\xfd\x8c\xb4\x3f\xe5\x17\xb0\x7d\x4f\xa8\x6c\xab\x1a\xda\x1f\x5a\x7c\x5f\x23\x1e\xe6\x70\x07\x3a\xc2\xce\x51\x2a\xf4\x9d\x6a\x50\x98\x44\xa8\x87\x61\xe0\xb9\x42\xe1\xd5\xc0\xb3\xf9\x7a\x4c\xf7\x65\x51\x87\x71\x4f\xac\xe2\x38\x2a\x23\x31\x02\xfa\x7b\x40\xff\x41\xfb\xda\xef\xab\x2b\x30\xba\x95\x00\x70\x53\x5d\x08\x10\xfe\x75\x75\xa5\x62\x6e\x82\x8e\x92\x36\x2d\xae\xe0\x35\xc0\xd3\xcd\xb3\x41\x41\x19\x82\x74\xbd\x4e\xa1\xe7\x5c\xf0\x51\x14\xe6\x4e\xd7\x2f\x9e\xe9\xd2\x5b\xd9\xe7\xa6\xbc\x5c\xea\x73\xef\x84\x7b\xbf\xc8\xf7\xec\xfd\xd3\xcd\x33\xed\x61\xd7\x3a\xb4\xdf\x47\x5f\xe0\x31\x45\x94\xa6\x59\x89\x26\x24\x8d\x59\xbf\x48\x3a\x65\x0d\x3d\x95\xcd\x8f\xb3\xb4\xc8\x12\x3c\xb8\x88\xf2\xb4\xd7\xd5\x4b\x30\xdf\x3a\x94\x15\x27\xd9\xb4\xab\x59\xd6\xf2\x1e\x53\x54\x38\xfe\x99\x30\x67\x43\x7a\x3c\x2e\x98\xbb\x9e\x6f\x75\x06\xac\x5b\x81\x49\x10\xe6\xd1\x0a\x6a\x14\xae\x11\x61\x8a\x5b\x2c\xc7\xcf\x78\x4c\x25\x00\xcf\x7a\x0c\xc0\x7f\xd3\x28\x1a\x7f\x92\x91\x43\xc1\x75\x01\x3f\x04\x8b\xdb\xdc\x5e\x94\x4f\x97\xf0\xd6\xe4\x54\xfe\xd2\x7c\xf7\x98\x46\xee\xa2\x46\x88\xf8\x5c\x5b\x4c\xf7\x32\xd7\x73\x20\xe8\xc4\x6f\x99\x5e\x2f\x14\xdb\x48\x97\x49\xe2\xa0\x3b\x13\x94\xc6\x7d\xe5\xa9\x83\xb1\x80\x98\x68\xb1\xd5\x98\xc6\x16\x30\x39\x18\x11\x53\x99\xa8\x89\xe5\x8c\xb3\xf7\xb2\x00\x0e\x50\xdd\xc0\xcb\x58\x03\xc1\xde\xe9\x21\xac\xd7\x0f\x54\x43\x1a\x86\x9b\x67\x2a\x2a\xcb\x68\x3c\xfb\x90\x1d\x0a\xb7\x59\xfa\x94\x09\x5f\x5a\xfa\x59\x5c\x4d\x2d\x1b\x37\xfb\x74\x86\x23\x8a\x0e\xa2\x24\x91\xfb\x09\x07\xae\x38\x6b\x38\xdd\x94\x07\x0f\xcf\xc9\xc3\x7b\xf4\x00\x52\xed\x84\xdb\x20\xc5\xb3\x55\xdf\x09\xb7\x41\x76\xd7\x23\xb5\xed\x00\xb0\xb5\x11\x76\xc2\xdd\x1d\x2a\x32\xef\xde\x8b\xcc\xf7\x22\xf3\x5f\x5b\x64\xd6\xc2\xc0\xc0\x99\xfc\xae\xe2\xc0\xfc\xbd\xc8\xd2\x7c\x31\x36\xe5\xcd\x5f\x58\xa2\xbc\x70\xcc\xf3\xcc\x16\x81\x59\x9a\x94\x44\x5d\xd5\x05\x1d\xac\x21\x64\x3a\x32\x26\xa0\xe3\x63\xa5\xa4\xc9\x33\x0a\x1e\xce\xbb\xc1\xad\x60\x14\xc7\xc2\x03\x24\x65\xc7\xbc\x30\x38\xd5\x86\xae\xf1\x04\xcb\xe0\x30\x8a\x63\x8f\x09\x2f\xe2\xe3\x67\x85\x4a\x65\x3c\x3b\x5c\x83\x71\xea\xac\x38\x8e\x7d\x32\xb7\x6f\xe0\x05\x8b\x05\x2e\x20\x1a\x47\x24\x98\x76\x5d\xff\x39\x8c\xb7\x6b\xbe\xfd\xdc\xf4\x5e\x20\xf1\x6b\x74\xd3\x9d\x02\x75\x7f\x94\xc6\x4c\xfd\x24\x60\x03\xad\x6e\x9c\xe7\x01\x97\x44\x0b\x57\x26\x66\xe4\xc3\x7e\x71\x61\x51\x01\x70\xfc\xe8\x0e\x29\x25\x2a\x03\x04\xaf\xe7\x2b\xde\xc7\xf1\x2a\x4f\x01\xe6\x4c\x3f\x1e\x54\x0a\xec\xac\x48\x45\xb5\x54\xac\x11\xfd\xe1\x95\x0e\x1c\xd9\xc7\x2e\xb0\xce\x17\xd1\x80\x14\xff\x88\x12\x12\xbf\xc3\xc5\x22\x4b\x0b\xcc\x9b\x72\xde\xf6\x39\x63\xf0\xb7\xd7\x63\x6b\x6c\x70\x94\x9e\x7b\x6b\xdd\x73\x2a\xbd\x76\xfb\x57\x59\x39\xf3\xf1\xe4\x0c\x96\xed\xb9\xe0\x7b\xc2\x97\xc1\x1b\x1f\xf0\x3e\x80\x57\x0a\x3d\xc1\x89\x5d\xaf\xa6\x42\x9e\x6f\x90\x5f\x94\x00\xca\x52\x7a\x4b\x36\xf8\x4e\xb8\x0d\x7a\x34\xbe\x22\x3b\xe1\x0e\x18\xd5\xb5\x8a\xed\x7d\xbf\xe1\xdf\x6f\xf8\x7f\xde\x0d\x5f\xed\xf7\x52\x2c\xbf\x23\x15\x59\x4b\x5d\x15\x3d\xf1\xe4\x16\x58\xc1\x65\xfd\x21\x64\xae\xaa\x4e\x13\x70\xda\xb3\x2c\x5d\x0f\x26\xde\x5b\x71\xe8\x03\xed\x10\xa2\x81\x49\x8d\xa1\x11\x68\xdc\xb7\x7f\x32\xf5\x92\xfe\x86\x0d\xb6\x79\xfb\x19\x34\x83\x3b\x54\x60\x6f\x05\x94\x94\x0b\xc0\x96\xf8\x1a\x09\xcf\xcf\x66\xaa\xb7\x01\xee\x1b\xd8\x5f\xb5\xf9\x92\x74\x44\x02\x5e\xce\xba\xed\x89\x46\xc4\xa3\x06\xd1\xbc\x44\x23\xcb\x2d\x33\x0b\xeb\xbd\xbf\x8f\xba\x5a\x9f\xba\xe8\xc1\x03\xc3\xed\xb3\x76\x6e\x66\xcd\x1a\xb1\x01\xae\xfb\xd6\x36\x5c\xd7\xa0\xc7\x91\x34\xea\x41\x62\xc5\x76\x0d\x79\xcc\xbf\xb4\x67\x67\xb0\x2a\xa2\x60\x85\xa7\x69\xa0\x3d\x7e\x78\x67\x08\x65\xa0\x12\x8d\x9a\x96\x47\x68\xaf\x5a\x48\x8f\x32\xd0\x17\x77\x44\xc5\x8e\xd6\xde\x87\x53\x51\x1c\x0b\x1a\x2e\xd4\x31\x5c\xa7\x0d\x91\x76\x2d\x6b\xaa\xa4\x27\x46\x2a\xfe\x2a\x6b\x4f\xf6\xea\xb8\x7e\x73\x42\xd1\x1e\x39\xae\x32\xfb\xba\xa6\x4a\x6a\x7f\x64\x7f\x3e\xe2\x72\x26\xd4\xcd\xaa\x93\xa6\x57\x8d\x46\x55\xea\xc4\x51\x73\x28\x04\x28\x55\x69\x8b\x79\x65\xdc\xa2\xd5\xa4\x32\x7e\x73\x77\x33\x6a\xd7\xd7\xac\xa8\x11\x0c\xef\x2e\xe6\x96\xf1\x5e\x4b\xad\xcc\x39\x2b\xd7\x36\x4a\x1e\x6b\x4e\x9e\xab\xb7\x62\x1d\xab\x9c\xce\x83\x24\xa9\x9d\x2e\x00\xe2\x17\x3d\x2b\x13\x18\x53\x85\x36\x74\x70\x75\x6a\x33\xde\x1c\xba\x4a\xb5\x2a\x6a\xab\x23\x37\xe9\x88\x07\x6c\x02\xc5\xa4\x4f\x71\x59\x70\xa3\x96\xe4\x12\xc5\x78\x91\x64\x97\x38\x16\xa6\x83\xf0\x3a\x71\x3c\x8b\x94\x6b\x45\x41\x90\x50\xdb\x8f\x59\x2e\x7a\xe4\x71\x6d\x20\x0e\xac\x3e\x92\x14\xeb\xf2\x5a\xaa\x16\xd7\x0c\x87\xba\x27\xe2\x72\x43\xbd\x9a\xab\x68\x51\x37\x84\x10\x2d\x69\x0a\x4b\x45\xbe\x10\xc8\x19\xe2\xce\x38\x6a\xde\x1f\x21\x36\xf9\xbe\x78\xb7\x06\xf9\xc3\x21\xba\x88\x08\x53\x97\x83\xc8\xb5\x28\x95\x0a\x56\xdc\x94\x99\xf3\xce\x97\x82\x0c\x24\xad\x3a\x86\xfb\xa6\x9f\xe6\x75\x0c\x2f\x9f\x8d\xf6\xed\x5d\x09\xfa\xbb\xb1\xb1\x67\x1e\x9b\x86\x43\x54\x94\xd9\x82\xe9\x6a\x49\x3a\x45\xd1\x84\x76\xe5\x9b\x4d\x36\x57\x05\xea\x95\x64\x8e\xb3\x65\xd9\x77\x8e\x8e\x0c\x01\x3f\xa0\x6f\x36\xbd\x87\x45\xd6\xfb\x01\xad\xfd\x67\x5e\xb9\x8a\xc0\xd0\x47\x5f\xae\x3d\x67\x3a\x1b\x81\xec\x3d\xa0\xf7\x1c\x2a\x67\xc4\x7b\xda\x54\x27\x3f\xe5\x48\x58\x32\x26\xb8\x2f\x89\xd8\xca\x18\x53\xc2\x06\xa7\xc2\x23\x2a\x31\x2f\xd3\xd8\xc6\x40\xd7\x77\xf8\xc4\x89\xe6\xb0\x44\xff\x73\xdc\x17\xdf\xb8\x55\xba\xfc\xf4\x9a\xa5\xbb\x12\x17\x6b\x06\xd5\x4c\x71\xf9\x41\x35\xf5\x8e\x91\x9a\xe2\x28\x5a\x37\x5e\x46\xc5\x4c\x27\xaa\x40\x10\x66\xdf\x7f\x84\x27\x93\x1e\x07\xf0\x53\x9b\xb7\x90\xb7\x83\x10\xf0\x88\xd7\x35\x18\x9b\x0b\xd0\xec\x11\x44\x45\xf2\x77\x47\xfc\x55\xf9\x7a\x7f\x2c\x7d\xbd\x57\xfd\x91\x49\xcf\xa4\xb8\xab\x2b\xb4\x0e\x2d\xd6\x16\x43\x92\x75\x7b\x68\x53\xff\xbb\xc9\x12\xd0\xff\x5a\x2e\x07\x7b\x48\x59\xac\x85\x8e\xde\xa9\x9d\x19\xf1\x37\x1c\xca\x7b\xbe\x24\x9b\x6a\x54\x0b\xc7\x0a\xc1\xc6\xd7\xbb\xfd\x86\xe6\x91\x21\xaa\x49\x8e\x5a\x31\xd5\x2d\x2a\x1b\x0e\x11\xdb\xac\x84\xb8\x10\xa5\x31\xe2\x37\x23\x28\x9a\x46\x24\xe5\x2b\xe7\x02\xf3\x78\x80\x0d\x7f\x7e\xd9\xd3\xde\x00\x1b\x6a\xb0\x65\x1d\x67\xfb\x6f\x18\xd2\x98\x39\x7d\xe3\x97\x82\x74\x4b\xa0\xbb\x63\x81\xc7\x59\x1a\x23\xca\x70\x1b\x2b\xd1\x48\xb7\x99\x58\x91\xc1\x11\x41\x17\xd6\xb6\xc3\x5e\x27\x49\x77\xdc\x21\xdd\xd7\xbb\x26\x4a\xf0\x13\xad\xc6\x29\x8b\x32\xcb\x71\x2c\xfd\xbe\x33\x09\x04\x34\x3e\xd3\xa8\x40\xd1\x9c\x6e\x48\x03\x2f\xbf\xb6\xff\x2a\xf9\xb7\xfd\xe7\x71\x47\x7f\x17\x5d\xac\xef\xe1\x75\x65\x6e\x15\xc7\x70\x4b\xd8\x90\x9a\x76\xb2\xed\x81\x42\xbb\x62\x10\x84\xfe\x63\x44\x8f\xd9\x97\xd2\x55\x87\x25\xc5\x59\x60\x0d\x87\x06\xbb\x52\xfd\xc0\x00\xa7\xaa\x68\x44\x8c\xcb\x05\xf6\x0e\x09\x8b\xe3\x3b\xa4\x45\x23\x82\xf6\x29\xa4\x90\xb3\x1e\x32\x4d\x68\xf3\x98\xd4\x09\x29\x45\x91\x26\x9a\xf2\xe2\xa2\x16\x31\xb6\x14\x5f\xc8\x24\x31\xa6\xf4\xf2\x5a\x27\x06\x4b\x37\xb2\x25\x6c\x0a\xa2\xa4\xbf\x62\xd1\xed\x9a\xa2\xb6\x1c\x6c\x48\x16\xdc\x95\x8a\x50\x14\xc7\x4e\x69\x9f\xa4\xcc\x21\xa4\xb4\xac\x8e\x7f\x22\x49\xb6\xa5\x26\x1e\x0a\x0d\xd5\x44\x50\x94\xfa\xae\x5f\x50\xcd\x16\xfd\xad\xa8\x81\xfe\xc9\x1f\x4c\xba\x96\xa7\x48\x9d\xfe\x7a\x82\x0e\x02\x39\xfb\x3a\x07\x1b\x0e\x59\x24\x46\x65\x65\x61\x54\xaa\x6c\x25\xbe\x5c\xef\x51\x60\x81\xa5\x75\xb3\x6d\x8e\x18\xad\x62\x38\xe3\xe6\xf0\x46\x07\x08\x59\x7f\xac\x90\x90\x31\x86\xab\x06\x65\xaf\x61\x05\x0b\xf4\xd9\x8e\x80\xfd\x47\xb5\xc5\x08\x72\xac\x46\x8a\xc1\x22\x5b\x18\xfe\xeb\xcc\xee\x25\x51\x51\x72\x48\xa7\x6a\x7f\x77\x18\x21\xf5\x68\x41\xf0\x8d\xb4\x2e\x5f\xa2\x40\xd8\x5a\x48\xb7\xfb\xa4\x50\xd8\xd0\x25\xda\x10\x07\x1e\xb0\x18\x26\x3f\xa0\x4d\xbb\x36\x3e\xd3\x82\xf6\x0f\xc4\x5a\x6e\xd6\x02\x88\xbf\x5b\xa9\x04\x35\x34\x59\xcc\x52\xa8\xd3\xa4\xa9\x9d\x3e\xac\x75\xb3\xcb\x83\x45\x74\x19\x8d\x12\xec\xeb\x9e\x7b\x1c\x60\xf6\x53\x05\x4e\x63\x15\xbf\x2a\xcd\xd2\x47\xbc\x12\x1d\x1d\xf6\x36\x71\x5d\x35\xf5\xe0\x3a\x90\x72\x46\xbf\x0a\xb6\x27\x96\x4a\x00\x23\xd6\x6a\x15\x13\x04\xb6\x6f\x1b\xfb\xac\xa2\x3d\x73\x12\x2b\x6f\x04\xf5\x13\xad\xa1\x03\x10\x72\x5f\x14\xa7\xb6\x96\x20\xc6\xe8\x22\x2a\xa4\x40\xb9\x66\xe2\x8a\x2d\x6d\xb8\x7a\xd5\x8e\x30\xca\x30\xcb\xba\x7f\x9d\x45\xc5\xcc\x87\x74\xda\x6b\x9c\xe7\x55\x37\x91\xfa\x95\xa3\xef\x5e\xb1\x4e\xe2\xa1\xe2\x68\x1c\xb3\x6b\x2f\x8d\xeb\xd2\x9e\xf8\xdb\xaa\x38\x76\xa1\x7d\x28\x53\x21\x7c\x55\x4a\x88\x13\x92\x17\x65\xb5\x80\xb8\xa2\x8c\x57\xa1\x01\xf1\xa9\x3d\x7c\xd7\xaf\xc6\x57\x93\x5f\x4d\x88\xcb\xc9\x06\xde\x34\xcf\x56\x63\x6d\x51\xde\x88\xea\x55\x86\xee\xe7\x69\x52\x27\xcf\x80\xb8\xae\x8c\x63\x57\x6c\x82\xf4\x7c\xfb\x9c\xd9\x95\x42\x12\xff\x34\x0c\xd0\x6e\x2c\x78\xd9\x5a\xb3\x26\xed\xac\x67\x53\xe7\x35\x5d\x9b\x32\xd0\x44\xd6\x3f\x5c\x1b\x0e\xad\x1d\xd8\xb8\xc0\x51\x1e\x95\x35\xf5\xa5\x55\x79\x8f\xed\xcb\xc3\xa1\xe1\xaa\xb7\x32\x4a\xf5\x78\x0c\x5e\x77\x33\x16\xd8\x89\xa4\xd3\x1a\xd9\xcc\x54\x63\x9b\x23\x67\x93\x78\xed\x72\x22\x5d\x1c\xaa\x13\x85\xd0\x17\x4d\xea\x6a\x2b\x11\x4d\x50\x9a\xa9\x1a\x28\x7b\x5b\x44\x45\x81\xe3\x80\x56\xa1\x3c\xeb\x51\x88\x42\x5b\xd2\x26\x2f\x93\x84\x07\x33\x60\xa1\xd3\x30\x87\xf4\xb9\x66\x35\x6d\x56\xd1\xca\x32\x94\xb2\x94\x57\xda\xca\x72\xa6\x79\xae\x84\xa0\x37\x10\x50\x4c\x18\x15\xc8\x2e\x05\xa2\xc0\x08\x8f\xa3\x65\x81\xe9\x49\x3c\xce\x52\x88\x64\x02\x36\x49\xc5\x22\x23\x09\xbb\x0d\x4f\x4b\x9c\x4f\xa2\xb1\xf4\xbd\xdd\xe2\x24\xde\xe6\xb4\x6d\x6f\x53\xcd\xfc\x10\x39\xde\x7b\xe5\x9a\xd6\xd6\xe6\x4f\xb8\x64\xce\xa0\xe9\xfe\x18\xa0\x8b\x19\x19\xcf\xc0\x68\x80\x2e\xef\x32\xe3\xdb\x18\x5a\x24\xcb\xa2\xf9\xea\x95\xf3\x81\x86\xf9\x55\xcc\xc3\x6f\xc8\xd4\x20\xc2\xae\x2e\xa7\xca\x62\xcd\xf2\xe3\x6d\x64\xc7\x6a\xb9\x51\x33\x56\xbe\x91\x1c\x53\x27\xc3\x98\x2f\x1d\x06\xcc\x20\xbd\x3d\xf3\xf5\x9c\x7a\xbc\xc7\xdd\x16\xd7\xe7\x55\xac\xc9\x39\x0c\x7b\x4f\xc1\x15\x2f\x59\x7c\xe7\x61\x77\xf7\x53\x76\xe1\x0c\x7f\xee\xe3\x15\xe4\x39\x4c\x7b\xcd\x96\x2c\xba\xdd\x93\xe6\xcf\xa6\xad\x44\x27\xfc\xb6\xca\x02\x5a\x5a\x34\x74\xc2\xed\x1d\xd7\x24\x9a\x8f\xbc\x13\xee\x6c\x5d\x9f\x05\xdb\x8f\xef\x4d\x9f\xee\x4d\x9f\xfe\xda\xa6\x4f\x9a\xad\x33\x37\x81\xbc\x03\x63\xe7\x0a\x2f\x99\xdc\xb8\x92\xbd\xcb\x3a\x9e\x88\x3b\xe7\x83\x7c\x5a\x84\x15\x9a\x1b\x4d\xc6\xe3\x27\x58\x5e\x89\x1e\x43\x4f\x6c\x27\x94\x7d\x8a\x4a\x91\x6e\xe2\x04\x7c\x3e\xf0\xf5\xe1\xdd\xdb\x43\xc6\xdc\x6f\xd3\x01\x16\xcf\x09\x58\x2d\x86\x07\x8c\x65\x86\xde\xbd\x3d\xe4\xf7\x04\xfe\x0e\xf0\x67\xea\xe0\xa3\x51\xb5\x3c\xcb\x0a\xfd\xf6\xcb\x6d\xfc\xf0\xf8\xcd\x9b\x17\x87\x1f\x8e\x8e\xdf\xa0\x17\xef\xde\x1d\xbf\x0b\xd1\xa1\x54\xff\x8e\x59\x95\xec\x44\x1f\x63\xd4\xdd\x40\xb4\x3e\xb4\xd1\x1d\xf8\xfb\xa0\x3c\xe1\xb4\x1d\xbb\x7c\x7f\xcf\x94\x08\x25\x5d\x3d\x84\x55\xe6\x6f\x42\x18\xd2\x86\xc8\x36\x0a\xd6\x86\x09\xcf\xd2\x70\x51\x44\x53\x8c\xf6\xd1\xfa\x3a\x7f\x69\x48\xb7\x75\xfe\x7b\xc0\x02\xcc\x3a\x29\x03\x51\xec\x29\xf2\x26\x87\x48\x4e\xd7\xdf\xdf\x1f\xbf\x81\x59\xc9\x65\x97\x3c\x61\x59\x79\xdf\x9c\xb7\x64\x0a\x07\xbc\x6a\x73\xb4\x6a\x36\x3f\xb0\xeb\x6a\x7d\xbc\xf3\xa2\xed\x94\x7e\x38\x7a\xfd\xe2\xf8\xe4\x43\x88\xf8\xa5\x37\x25\x2e\xda\xc9\x79\x81\x36\x50\x97\xfe\x17\x8d\x67\x94\x63\x74\x8d\x80\x39\xdc\x4b\xe5\xb7\xf7\xbb\xd5\xfd\x6e\xf5\xd7\xde\xad\xb4\xcd\x0a\x5e\x5d\xfe\x51\xad\x74\xdb\x3f\x66\x6f\xf5\x86\xfe\x0e\x9f\xb2\x0b\x5f\x44\x74\xfd\xcb\xc3\x99\x1e\xf1\xa9\x30\x8e\x21\xfc\x8d\x2d\xb4\xa5\x0e\x0b\xb6\x11\xf2\xd7\x7e\x07\xbf\x10\xa6\xbc\x9a\x22\x5d\xcf\x67\x91\x31\x50\xcd\x73\xe4\x22\x4b\xfb\x0d\x4f\xe8\xb5\xcc\x34\x4b\x2f\xe7\xd9\x52\xb6\x28\x13\x2a\x4e\x6f\x02\x69\x53\x2c\x70\x85\x63\x26\x0f\x40\x8c\x04\x27\x18\x14\x4f\x93\xc7\xb3\x67\x59\x96\x5c\x43\xf0\xd6\x18\x3c\x9c\xb3\x4d\x02\x33\xc8\x58\x9b\x1d\x78\x1f\x82\x63\xc3\x1f\xbb\x38\xf1\x41\xac\x03\xba\x28\x79\xed\xc3\x35\x63\x9a\x74\xaf\x5c\x14\xc2\x74\x28\xc5\xea\xb5\xe3\x3b\x68\xc8\x77\xaf\x44\x88\x47\x54\x20\x03\x5e\x13\xdc\x6f\xf0\xdf\x15\xe6\xae\xfe\xf2\xca\x5e\xb9\xf2\x86\x58\xc7\x36\xa3\xcf\x98\x79\x18\x07\xef\x46\x16\xae\x63\xe5\x7c\xdc\x1b\x8c\xc8\x5b\x41\x35\xea\xb4\xea\xea\x9c\x07\x31\x4a\x74\xfd\x9f\xf7\x14\xbd\xb6\x1f\x1d\xac\x50\xcf\xd0\x4a\xee\x2e\xbd\x66\x5c\x7a\xd1\x7a\x7a\x58\x69\x44\xc2\x51\xfb\x8d\x86\x53\x90\x69\x1a\x95\xcb\xdc\x1e\x8e\x9e\x5e\x35\x1e\x1d\xa6\x7a\x3c\x12\xaa\x6e\x40\xe0\xc0\xa0\x7d\xff\xf9\x0b\x07\x41\xde\x9c\x23\x45\x69\x2c\x55\x4b\x65\x06\x41\x8f\x27\x24\x8d\x12\xbf\xd5\x33\xab\xc3\x67\x53\xaa\xaf\x6b\x2b\x8b\x57\x6f\x20\x45\xe4\xe1\x73\x9c\x5f\x96\x33\xa6\xb1\x9e\x8f\x08\xb0\x8c\x6c\x0a\x5e\xc7\xa0\x6f\x3c\x8a\x43\x2d\xb6\x3c\xae\x41\x78\x77\x1c\x8f\x77\x72\x71\xcb\x5f\xda\x23\xc0\x7b\x07\x22\xca\x5d\x87\xf4\xcf\xd1\xe4\x59\x44\xe8\x35\xd7\xad\x9d\xc7\xed\x27\xaf\x9c\x3d\x6c\xe5\x3e\x07\x99\xff\x4e\x41\xed\xbd\xbe\xab\xd2\xe4\xf3\xf4\x41\x74\xec\xb6\x2c\x5d\x8b\x90\x51\xcb\xcf\xc1\x5d\x33\x0f\xd6\xcd\xcb\x9f\x12\x88\x60\x65\x19\x03\x70\x20\x7d\xfe\x28\xdd\xa8\xe4\x74\x99\x24\x15\x2f\x5c\x94\x66\x11\xb9\x97\xff\xa6\x42\x18\xea\xab\x0a\xfc\x08\x99\xd6\x68\xce\x6b\x6e\xfb\x39\xf6\x9d\xb7\x31\x3d\xb6\x7d\x05\xe8\xdc\xbe\x39\xef\xdb\xa1\xfb\x56\xaa\x0d\xfa\xde\x40\x71\x26\x91\x8c\xb3\x74\x1c\x95\x3d\x63\xf6\xfb\xd5\x7e\x6c\x2a\xb9\x1e\x77\x62\x53\xcd\xf5\xec\xdd\x16\x57\x71\xb8\x98\xdd\x3d\xb8\x3c\x8c\x73\x05\x6e\x38\x04\x27\x04\x56\x4b\x2c\x9b\x7d\xf0\x00\xf4\x0d\x66\x2f\xea\xb7\xe9\x6a\xe7\x3b\x80\x83\x3b\xf4\xbe\x13\xe5\x53\x6b\x75\x29\xf1\xf1\xa9\x51\x32\xd4\xbf\xb8\x67\x9e\x2d\xcd\x13\x0a\x1f\x1f\xbf\x7f\x91\xf5\xda\x2f\xb5\xd8\x64\xa2\x2f\x52\x4a\xd3\xeb\xdb\xee\xef\xd1\x95\xf9\x4b\x46\xd2\x5e\xa7\xe3\x56\x2e\x1f\xc5\x31\x72\x63\x78\xd2\xaf\x37\x40\x36\xec\xd1\x65\xde\x0f\xf4\x1e\xe9\x57\x35\x69\x56\x1e\x19\x7d\x95\x28\xf4\xf8\x1b\x52\xc0\x2d\xdb\x86\x57\x0b\xfd\xc0\x6a\x45\x6f\x5f\x6e\x24\x1a\xd7\xce\x96\xe5\x62\x59\xbe\xca\xa6\x8a\x5d\x73\x5f\x3c\xda\x6a\x11\xce\x7f\x98\x9f\x19\x4d\x2c\x33\xc1\x14\xb7\x86\x31\xd9\x6e\xa0\x18\x0c\xbb\xe5\x32\xf8\x69\x8e\xe3\xe5\x18\x6b\x73\x15\x8d\xc7\x01\xe2\x2e\x2a\x75\x7e\x12\x8d\xc7\xa7\x3c\x99\xf1\x44\x8a\x14\xfe\x2d\xa8\xfc\xa9\x39\x65\x83\x62\x46\x26\x65\xaf\x8f\x42\x07\xa3\x22\xcb\x51\x5a\x45\xe3\xb1\xd0\x52\x31\x63\x6f\x46\xda\x38\xc1\x25\x16\xe3\x50\x4e\x92\xcc\x74\x46\x55\x37\x60\x19\xda\xfd\x15\x7f\x57\xc2\x97\x36\xdd\xea\x99\x18\x57\xe9\x58\xe1\xae\xe4\x22\xa3\xe1\x7a\xe1\xc7\xe3\xb8\xc1\x96\x7e\xfe\xe8\x1e\x99\xb6\x9b\x3d\x32\xd5\x15\xdf\xaa\xb6\xb1\x33\x2b\x40\x86\x04\x68\xf8\x7e\xb0\xc5\x0e\xdb\xed\x93\x23\x50\xfe\xa1\xfc\x3f\x55\xd2\xb2\x6e\xfa\xdf\xe2\x51\xa3\xf5\xaa\xcd\xfb\xa2\xb1\x96\x1a\xbf\x96\xb3\x29\x0a\x6a\x9e\x5c\xab\x38\xa0\xb0\x2f\x84\x96\x4e\x35\x80\x33\x83\x7a\x7d\x00\xba\xff\x2a\x45\x14\x5e\xd0\x53\xc9\xee\x59\xdb\x67\x95\x03\x30\xac\x26\xbc\x77\xc2\x06\x2e\x35\x8f\x58\x75\x57\xc2\x4d\x7e\xb2\x6e\xe8\x1a\xeb\x49\x1b\x05\xfc\x6d\x7d\x5d\x0e\xfd\xba\xc9\xd7\x8c\x06\x3d\xfa\xbf\xfa\xf0\x23\x7a\xe4\x91\xb5\xe1\x10\x7d\x38\x7e\x7e\x1c\xa2\x1c\x33\x83\xac\x00\x15\x19\x37\x9d\x91\x57\x5c\xca\x16\x27\x62\x9a\xae\x01\x2d\x47\xca\x6e\x81\x52\x3c\xc6\x45\x11\xe5\x97\x74\xb1\x40\x84\xed\x82\x92\x5b\x17\xfc\x18\x83\x17\x69\x74\x91\xe5\x9f\x98\x94\x37\x5f\x26\x25\x59\x24\x5a\x84\x07\x33\xe2\x8a\xdf\xbd\xd1\xf0\x21\xf2\xda\x72\x7f\x23\x4c\xb9\x59\x1d\xa6\x19\x83\x68\xde\xb0\x21\x55\x8d\xe1\x98\x6d\x1c\xe6\xc9\x44\x96\x1a\x08\x1c\xf9\x1c\xd8\xac\xd3\xce\x9d\xba\xb0\x67\xbe\xf3\x43\x15\xac\xc5\x4e\x91\x63\xdf\x68\xf6\x93\xfb\x73\xf2\xd5\xd4\x60\x06\xe9\xad\xa7\xf2\x08\xa5\xea\xe7\x04\x6f\x8f\xc9\x01\xf0\x1c\xb9\x59\x8e\x0f\x1b\x2c\x47\x32\x3d\x6e\xd2\x63\x76\xd1\x63\xf9\xc9\x8b\x15\xb8\xb4\xe2\xcb\xf8\x7c\x5c\xd5\x1e\xc4\xea\xa7\x9b\xe0\x9a\xf1\x2a\x18\xcf\x90\xab\xe8\x05\xa9\xe0\x27\x97\x2b\x0f\x5b\x16\xbc\x83\x81\x23\xcd\x6e\x14\x7f\x1e\x18\xec\x48\x7d\xec\x21\x01\x20\xb8\x10\xfc\xbf\x27\x52\x25\xcb\x61\x3f\x64\xba\xc6\x68\xc4\x4f\x53\x8e\xc4\x9f\xf9\xf3\x6e\x97\x9b\x33\x34\x28\xcf\x44\x15\xfc\xb9\x82\x23\x77\xc2\x1d\xf0\x60\xa4\x3b\x0d\xa7\x8c\xf9\xbb\xfb\x9b\xd1\xfb\x9b\xd1\xbf\xf6\xcd\x28\xbf\x16\xe5\x4f\x7e\xff\x2b\xc2\xf7\xdd\xa9\xc7\x70\x38\x04\x3c\x44\x87\x59\x7a\x8e\x29\x2b\x8a\x78\x44\x65\x38\x07\xc3\x59\x00\xc2\x22\x8b\x00\x2f\x94\x80\xa3\xa4\xc8\x50\x94\x24\xd9\x45\xc1\xa2\xbf\x83\xa2\xae\x18\xac\xd1\x8a\x84\xe0\xff\x9a\x7c\xc6\xf1\x35\xcb\x5a\x73\xef\x35\xd6\xf8\x8d\x6a\x99\xd9\x31\x94\xb9\xca\x52\x1e\x38\x7b\xa6\x4a\x14\x5d\x5d\x89\xf8\xeb\x2a\xa3\x2b\x75\xa8\xdd\xbe\xad\x0c\x60\x67\x39\x2e\x22\x31\xc5\x2c\xeb\x43\x4f\xe8\x17\x8d\x86\x98\x1e\xe2\x78\x02\xaa\xe6\x3e\xd4\xbe\xe9\xd4\x09\x90\x9c\xef\xeb\x2f\x5a\x8d\x3b\x23\x11\x5c\x49\xb6\x03\x47\x2e\x2a\x6a\x52\x4e\x2b\x2e\x7f\x6c\x4b\xd8\x4c\xea\xf7\xf9\x61\x2b\xa0\x93\x70\x8e\x73\x32\x01\xbf\x1e\x39\x1e\x47\x94\xe3\x68\x01\x6c\x1e\x3c\x40\x49\xf4\xeb\x25\x4a\xb2\x28\x46\xf1\x65\x1a\xcd\xc9\x18\x65\x29\x2e\xa0\x35\x3e\x21\xaa\x21\x1e\x2b\x3b\x93\x7a\x02\x80\x12\xf6\xf5\xa2\x71\x07\x8a\xce\xd6\x14\x97\xc7\xf2\x90\xec\xf1\xe0\x4c\x27\x46\x09\xac\x4d\xee\x01\x74\x65\x02\x9f\x12\x71\x4c\xae\xbe\xf5\x30\x34\xfd\x95\x57\x2f\x2c\xbb\xb8\x20\x10\xc7\x44\xeb\x15\x02\x1d\x44\x81\xd9\x09\x3a\x74\x5e\x56\x31\xe1\x7d\x99\x63\xae\x5e\x0c\xe0\x02\x6f\xcc\x56\x07\x3b\x1c\xcf\xf0\x67\x9f\xda\x40\x69\x4d\xad\x04\xcb\x13\x65\x8b\x22\x86\xe6\x93\x47\xed\xae\x54\xca\x5b\x0a\x7f\x11\xe3\xfb\x29\x8f\x7e\x4e\xab\xe2\x8b\xac\x8f\x42\xb1\xde\x38\x98\x2b\x2b\xf9\xae\x09\x3c\xcf\xeb\xa0\x9b\xa1\xd5\xed\xc0\x81\xa3\x4b\x40\x41\xd1\x2f\x17\xa6\xcc\xf4\x7a\xdc\xfc\x48\x04\x7d\x16\x40\x91\x1e\xf2\xd9\x6e\x0d\xee\xaf\xc2\x95\xae\xb5\x06\xb2\x5c\xdf\xd7\xbb\x9b\xd4\xc8\x4b\x99\xfa\x29\xed\xa0\xc3\x28\xb0\x98\x51\x0a\xf4\x20\xdc\x22\x75\xa1\xaa\x39\x88\x63\xf6\x2c\x42\x2a\xd1\xa2\x34\x46\x05\x2e\x0b\xb4\x5c\x40\x06\x3f\x8d\x00\xcb\x20\x25\xce\xe9\xde\x91\x9d\x73\x61\x8b\xbb\x31\x1d\xac\xad\x69\x4f\x23\x5e\x65\xd3\xe2\xa0\x7c\x5f\x46\x79\xb9\x66\x6b\x1a\x0b\x9c\x4c\x64\xe2\xc4\x7d\xbf\xcc\x59\xb8\x59\x8b\x11\x3f\x0c\x27\x13\xc7\x87\x8f\x78\x64\x37\xc5\x25\xd3\x67\xd1\xc2\xd6\x4b\x3b\xd0\x2f\xa8\x61\x16\xd0\x3d\x24\x4e\x9e\x16\xcf\xa0\xad\x0c\x7c\x8c\x03\x32\xa6\xb8\xec\x59\x6f\x7e\xb8\x25\xa3\x73\xca\x19\x0e\x51\x9c\xa5\x5d\xfe\x4a\x94\xf6\x91\xa3\x0d\xcc\x26\xe1\xa2\x5b\x24\x0a\xb3\x23\xf0\x84\x31\x18\x0c\xd0\x2f\x4b\xe6\x08\x98\xb6\x49\x79\xaf\x73\x5e\xae\x78\x18\x59\xf3\x28\xf2\xda\x7e\x01\x6b\xad\x74\x39\x0c\xff\x19\x93\x65\x7a\x0f\xa6\xcc\x90\xb3\xe9\x99\x26\x7b\xbc\x63\x9a\x7d\x1a\xfd\x6b\xf6\xc3\xfa\xf5\x48\x77\x91\x25\x09\x23\x1f\x3f\xd9\x72\xda\x54\x60\x36\x5d\x4a\x95\x08\xa8\x6d\xd3\xd7\xd2\x0c\xd7\x20\x96\xac\x82\x5c\xf8\x8c\x66\xce\x9c\x0a\x23\x0b\x4a\x7a\x62\xac\xbe\x49\xf0\x3d\x9b\xf2\xd1\x44\xd6\xfa\x24\xdf\x96\x3a\x6e\x46\x19\xca\x4c\x19\x86\xa6\x94\xd7\x4f\xad\x04\x55\x49\x28\x0a\xb9\xa4\x73\x2b\xf4\xdc\x8e\x48\x2b\xf5\x01\xd0\x27\xdb\x19\x35\x65\x3c\x6f\xb3\x24\xa1\x7c\x46\xf5\x84\xd1\x60\xc8\x8a\xd0\x73\x1a\x9e\xe3\xb4\x84\x23\xe7\x80\x52\x1c\x0c\x4d\xed\x25\x0b\x6e\x68\x7f\xca\x30\x05\xe4\x78\x14\x9f\x05\xe2\x8a\xca\x48\x0e\x14\x31\xf2\x1c\xdd\x8d\x11\x53\x10\x03\xfd\xd2\xcd\x5a\x44\x33\x74\x48\xdc\x92\xc9\x02\xe4\x84\xf7\x10\xcb\xcd\x73\x3b\x10\xf0\xd3\xd4\x61\x8e\x61\x4c\x60\xaf\x7d\xe8\x79\xe8\x08\xcc\x8e\x69\xb0\xb5\x0b\x57\x03\x1f\x9a\x86\x6f\x15\x55\x59\xa5\xae\xab\x52\xd9\xe3\x57\xaa\x99\x9d\xd1\x6c\x09\x50\xa5\xc7\xf8\x4a\x6b\x4c\x25\x6c\x2a\x31\xd8\x12\x7d\x35\x68\x07\x0d\x66\x82\x06\x29\x66\xde\x7d\x32\x26\x57\x08\xb7\xac\x91\x19\x7c\xcb\x3d\xac\xca\x57\x6c\xcf\xc9\xd2\xaf\x9d\xe4\xef\xca\x7e\x0f\x52\x7c\xc1\x6f\x9d\x74\x1c\xe8\xbe\xc2\x18\x93\x24\xb1\xe1\x1a\xcf\xcf\x1c\x1b\x96\x7d\x6f\x3c\xf2\x88\xb9\xe3\x51\x23\x1f\x44\x9c\x23\xf3\x73\x61\x0d\xf5\x5a\x0e\x49\x5d\xf6\x52\x53\xd6\xdf\x8d\x7a\xbd\xb3\xb1\xb4\x29\x11\xc4\xae\x1f\x40\xdd\x55\x43\x8e\xe1\x92\x81\x9e\x39\x16\x38\x8d\xc1\xc0\x4d\x4e\x72\x54\x80\xa2\x25\x2d\x28\x85\x4a\x5f\x30\xaa\xa2\x6c\x02\xc0\xb4\x10\x95\x7a\xfa\x4c\xb9\x22\x5b\x5f\xa6\x51\x51\x90\x69\x8a\xe3\x81\xdb\x47\x9b\xa2\x7c\x3c\xd9\x37\x3b\x52\xc6\x1a\x8f\x1a\x26\xc8\xdb\x8c\x6e\x32\xa6\x8d\x44\xd9\x13\xa3\x44\x97\x0e\xa3\x24\xc7\x51\x7c\xa9\xde\xab\x2b\x41\xb1\xb8\x3d\xa5\x99\x82\xac\x90\x5e\x9b\xc6\x45\x26\x3d\xab\x35\xe9\x03\x6e\xd3\xf5\xc8\xa5\x56\x26\xe3\xe2\x3e\xb7\x90\x54\x8a\x2e\x33\x3e\x36\x32\x9f\xe3\x98\x44\x25\x4e\x2e\xed\x66\x39\xb9\x8f\xdb\xd2\xb6\x29\x9d\x40\xf5\xbd\x0a\x4f\x13\x3e\xaf\x55\xba\x26\x9b\xb1\x7c\xba\xfd\xb0\xc1\x68\x77\xb9\x17\x4e\x94\x0e\x7b\x33\x37\x79\x1b\x33\xec\xd3\xfa\x21\xd4\x31\x06\x73\xd4\x47\x63\xcd\x13\xbf\x2e\x75\x07\xa2\xe1\x5a\xbb\x13\xbe\x6e\x3b\x10\x7d\xdf\x6d\x1e\x8f\x23\x39\x64\x0b\x21\x38\x98\x03\x69\xe0\xef\xf0\xb4\x7c\x9e\x9d\x0b\x95\x26\x8a\x8a\xcb\x74\x2c\x0f\x3f\x3e\xc1\xc8\xc7\xb7\x97\x29\xbc\x9d\x36\x10\xa0\xc9\x18\x16\xb6\x1c\xde\xa5\x1b\xc2\xaf\x52\xb3\x21\xf8\x3b\x18\x9d\x5a\xa1\xdc\x7d\xce\x13\x1c\x99\xc2\x6b\x72\x22\x4b\xda\x42\xb9\xb5\xa3\x56\xd8\x51\x0e\x87\xe8\x68\xa2\x38\x23\x29\xe4\xbb\xbe\x4b\xcc\xdd\xaf\x20\x52\x22\xe5\xa5\x4b\x95\xbb\x98\x61\x30\xc6\xe0\xa3\xef\x23\xc6\x54\x0b\x44\x4a\x93\xad\x7a\x37\x6a\x87\xd8\xe5\x32\xf3\xed\x1e\x3e\xf4\xf3\x1a\xed\x09\xd5\xf7\x63\x88\x90\xe2\xe1\x6f\x5f\xd1\x3f\x8f\x25\x8f\xe7\xd8\xb6\xde\x4b\xb2\x69\x55\xbb\xc8\x62\x4c\x35\x67\x0b\xa8\x25\xa4\x7b\x42\x85\x3b\x3e\x7f\xc0\x12\x13\xc4\x39\x00\xd8\x03\x6b\x4f\x47\x8e\x9b\x29\x2e\x88\x1f\x3d\x67\x09\x21\xa7\xb1\x5e\x7f\xc0\x76\xe4\x71\x24\x1c\x16\x82\x5b\x15\x1c\x23\xba\xba\x67\x79\x96\x66\xcb\x42\x7a\x2f\xe4\x86\x01\x74\xb7\xb7\x3d\x11\xb1\x6a\xb8\xb0\xdb\xf5\x9a\xd7\x82\x53\x89\x4c\x59\xe9\xb5\x21\x20\xd7\x86\x8e\xd6\xd0\x3c\x87\xb7\x98\xb7\xeb\x06\x7e\xec\x5c\x91\x32\xdc\x3a\xb1\xdf\x6a\x2e\x48\xaf\xcf\x82\x9d\xcd\x36\x57\xa0\xdd\x65\xc1\xf4\xe2\xe3\xb2\xbb\x76\x7f\x21\x7a\x7f\x21\xfa\x27\xbe\x10\x55\x4f\x45\x35\x95\xf5\x4d\xde\x8b\x72\xe0\x15\x6e\x32\x7d\xb1\xdf\x5a\x3f\x31\x4d\x27\x64\xea\x85\x63\x59\x02\xf0\x68\x14\x59\x31\x5d\xc8\x28\x4a\x3d\x71\x5a\x40\x9b\xcc\x02\x4d\x31\x1b\x69\x76\x99\x39\x22\x53\xee\xc1\xc0\xb2\x62\x64\x40\xcf\xc8\xd4\x52\xea\xeb\xd6\x8c\x4c\xe3\x7c\xc5\x20\xae\x24\xec\xb5\xe9\xb4\x4a\xa5\xeb\x96\xb8\xa0\x67\xac\x68\xc3\x90\x8a\x58\xef\xbc\xcf\xd0\xca\x4c\x96\x15\x60\x7b\x52\x09\x44\x8a\xb7\x39\xe6\xd7\xa0\xda\xed\x84\x51\xf7\x48\xa5\x5b\x0d\x8c\xf4\x12\xf4\x20\xc1\xbd\xdf\xa3\xab\x2b\x37\x8f\x9f\x4d\xfd\x99\x38\xca\x13\x42\x8b\x6a\x5d\x4b\x17\xcb\xf2\x39\x9e\x44\xcb\xc4\x7b\x71\xd2\xd4\x47\xba\x23\xdb\xed\xc8\x2b\x5f\x6f\xf4\x16\x4a\x32\x83\x58\x6b\xd1\xe3\x7b\xaa\xfa\x1e\x47\xef\x82\x35\x8a\xdf\xa2\xfb\xf6\x8b\x2e\x26\xa0\xd0\x5a\x2a\xe6\xd8\x68\xd4\x53\xa1\x96\xed\xc1\x83\xa0\xad\x97\xf8\xb3\x67\xe4\x7c\x55\xb1\xc1\x16\x9a\x51\x64\x36\x41\x91\xe1\x1a\x30\x4a\x63\x71\x53\x5a\xc0\x4d\x09\xb3\x0b\xa0\xeb\xee\xe5\x8b\x7f\x5a\xcb\x0d\xea\xa0\x72\xb1\x77\xa1\x09\x5d\xbe\xe1\x62\xd7\x31\x7c\x17\x57\xe4\x42\xdb\xef\xd6\xe9\x0d\xfc\xfd\xc5\xb8\x3c\x86\xeb\x3f\xed\x0a\x16\x3e\xaf\xae\x2c\x1a\x3a\x18\x43\xd8\x05\xcd\xf1\x99\x0e\xef\xf1\xb8\x25\xaa\x85\x3e\x71\x37\x54\xfe\xab\x47\x53\x0e\xd2\xab\x2e\x33\x1e\x2f\x9a\x94\x68\x4e\xa6\x33\x26\x38\x4a\xe7\xc5\x5c\x49\xe5\xb4\x5c\x66\x8d\xed\x96\x99\xd9\xea\x69\x77\x1a\x15\x6f\x73\x32\xc6\xdd\x00\xd1\xdf\xf4\x3f\x98\x3e\xfa\x23\xcd\xd2\x31\xf6\xbd\x4e\xfc\x84\x2f\x6b\xde\x27\x7e\xc2\x97\x6d\x5f\x28\x42\x4d\x0e\x0e\x59\x0d\xfb\x9a\x3d\xc5\x73\x3c\x26\xf3\x28\xe9\xe9\x00\xee\x0b\x31\xf3\x12\xf5\x6b\x13\xb1\xe6\x4a\xf3\xae\x69\xd9\x57\xf5\xdd\x93\xf4\x4d\xa9\xf6\x9e\x5e\x7f\x4b\x7a\xe5\x42\x8c\x43\xb0\x70\x5f\x2a\x62\xff\x70\x6a\xf5\x8a\x36\xad\xe9\xf4\xb3\x29\xce\xf0\xf4\x35\x43\x86\x69\xa4\xcc\xf2\x73\xff\x8b\xd4\xe4\x7d\x1e\xe8\xdb\xdf\xba\x38\x4d\x2b\x0d\x96\x09\x20\x3d\x5a\x64\x02\x7f\x26\x80\x7c\xb3\x41\xd3\x35\x5c\xc0\xeb\x30\x7f\xf5\x0e\x94\xb7\x0d\x1b\x4a\x28\xeb\x3e\x0f\x80\xa4\xfc\x85\x20\x4b\x41\x4e\xa3\xc2\x0f\x37\x8d\x0a\x03\x0a\xc8\x57\x03\x55\xa2\x9d\x96\xaf\x4a\x08\xe3\x35\x2f\xb8\xfe\x54\x56\x9c\xa4\x3f\xaf\x4c\x4a\x22\xca\xcc\x4d\x48\x8a\x07\xbc\xa9\xa5\x2c\x19\x7f\x69\x15\xf2\xb2\x2b\xb6\xdc\xae\xe8\x81\x73\x54\xa0\x9c\x06\x7a\xf3\x41\xb9\x73\xe6\x81\x52\x94\x27\x32\x5b\x90\x5f\x25\x68\x75\x93\x15\x84\x28\xe3\xb9\x2c\xe7\xcb\x24\x2a\xc9\x39\xfe\x29\x2a\x4e\x0a\x78\x97\x56\x55\x95\x03\x6b\xd5\x35\x6d\xac\x61\x2a\xcb\x89\xc1\x9b\xa6\x06\x02\x2e\xc9\xa6\xb6\xe5\x9e\xca\xd0\xa2\xd1\x38\xaa\x25\xd0\x0f\x79\x15\x4b\x9e\xd7\xad\x14\xb6\x49\x8b\xc4\x5b\x6a\xb5\x00\x60\x76\xdb\x93\x3c\x48\xdd\xb5\x54\x0e\x15\xb6\xa0\x71\xb3\x26\xdd\x3e\x05\x6a\x90\x06\x2a\xc3\x21\x92\xae\x7d\xc0\xc7\x1d\x3f\xbd\x22\xc4\x9a\xa2\xf3\xf3\x8a\xcc\x49\xe9\x99\x42\x13\x80\xe3\x4a\x26\x56\xcc\xbb\x91\x6f\x94\x29\xc8\xaf\x3e\x26\xa8\x32\x0d\xe8\x92\xcc\x71\x51\x46\xf3\x45\x65\x11\x09\xa1\xd6\x15\xcb\x48\xab\x56\xae\x91\x5d\x55\xad\x3c\x1a\x6b\x9d\x89\xc9\x64\x42\xc6\xcb\x04\x9e\x67\xb8\x3c\xd4\x06\x32\x07\x92\x95\x51\xf2\xbc\x4d\x05\x16\xa4\x2e\x24\x99\x6b\x86\x83\xab\x65\x6e\xae\x1c\x37\xdb\x15\x41\x48\x89\xe7\x7d\xfb\x61\x96\x63\x1d\x07\x50\xee\x3d\xa4\xb1\xbe\x7c\x9b\x39\x2b\xd8\xb4\xd0\x46\xec\x68\xdd\x62\x99\x25\xd9\xd4\xbb\x9e\xf4\xb5\xed\x5b\x4d\x49\x36\x55\x5a\x14\x77\x49\x41\xbd\xc6\xb2\xd2\x2b\xd4\x17\x95\xa6\xbd\x26\x13\xfa\x65\xf0\x75\x39\x4e\x2b\xc3\x43\x63\x76\xd1\x3d\xbd\x4e\x87\x71\x1b\x15\xb7\xd8\x01\xbc\x95\x18\x4d\x24\xd9\xd4\x53\xb5\x48\xad\xa8\x52\x16\x32\x85\x4e\xd0\xc8\x37\x1f\x90\x2e\x66\xa4\xa0\x7c\x71\x91\x15\xe5\x0d\x4e\x48\x6f\xb3\xa2\x5e\x30\x70\x23\xe9\xd4\xf2\x4f\xb7\x52\x7d\xa2\x69\x27\x75\xe6\x49\xbf\x07\x8b\xe8\x12\xcc\xe3\xf7\x0d\x6d\x87\x9e\xc5\x91\x0c\x49\x65\x99\x78\xc5\x78\x91\xa9\xc3\x5e\x64\xf9\xa7\x0f\xd9\xdb\x3c\x3b\xc7\xd5\x65\x34\x20\xbd\xec\x22\x27\x59\x4e\x34\x8e\xe2\x14\x14\x10\x9a\x83\xf8\x89\x1e\x3f\xc8\x30\x88\x65\xac\x85\x75\x92\x79\xcb\xd0\x79\x8a\x96\x8e\xf6\x8d\xaf\xa7\xe8\x54\xfb\x3c\x43\xa1\xbc\x8d\xbe\x56\xad\x32\x55\x2a\xd3\xaa\x26\x49\x76\x01\xaf\x03\xc4\x31\xb6\xae\xfa\x7a\x6b\x76\x16\x01\x8f\x12\x13\xca\xd2\xe4\x92\xb9\xf5\x2f\x0d\x23\x7b\x61\xe8\xce\x0c\xda\x7d\xef\x33\x84\xb5\x3b\x0a\xed\xb7\x17\xba\x9d\xbb\x7d\xe2\xa2\x7d\x6c\xc5\xbb\xa4\x5a\x1f\xe8\x9f\x1b\x1b\x7a\xb9\x59\x13\xa5\xfb\xc9\xda\x54\x0f\xd7\x13\x36\xa7\x6b\xc0\x2f\xfe\xbc\x20\xf9\xa5\x67\xc5\x6b\xb9\x3a\xb9\x15\xcc\x0b\x88\x17\x9a\xe6\x55\x2d\x01\x0b\xd4\xb3\x00\x80\xb2\x7d\xb2\x84\x05\xd1\xdf\xf3\xad\xca\x77\xd1\x85\x20\x19\x9e\xe2\x05\xd3\xaa\x3f\x28\xc6\x84\xd8\xcb\x57\x94\xd1\x77\xdb\xbf\x17\x1c\x71\x12\x4e\xc5\xd1\x50\xab\x42\x35\x00\x6e\x71\x21\x96\x95\x8f\x39\x0c\x87\xab\xac\x08\x58\x9b\xfa\x6a\xac\x5c\x8c\x6a\xb9\xdd\x62\x25\x59\xda\x5c\x86\xa2\x76\xf4\x2f\x99\xaa\xad\x80\xf1\x05\xbd\xd0\xad\x35\x08\xbb\x8d\x49\xf1\x05\x5c\xcc\xf4\xcc\x90\xc8\xa0\xb1\x1e\x45\xe9\x80\x14\xff\x88\x12\x12\xf7\x20\x64\x01\x4f\x79\x4e\x72\x3c\x2e\x7b\x3e\x75\x35\xf7\x0c\x05\x80\xbc\xc6\x5e\xdf\xd1\x85\xeb\x82\x8e\x8a\x24\x23\x7a\xe0\xa9\xd6\x70\x3e\xe6\xa9\xa8\x45\x15\xbc\x67\x66\x4d\xfc\xda\xd5\x32\x05\xe0\xee\xa1\x05\x6c\x57\x84\x76\x56\x0b\xfd\xfd\x65\x3a\x26\xa9\x5f\x5a\xe1\xfe\x98\xb5\x0b\x84\x75\x33\x09\xb9\xee\x69\x0c\xf1\x0c\x9c\xd9\x80\x2d\x19\x49\xa7\x70\xd4\xf0\x9e\xf0\x5c\x30\xd3\x25\x10\xf7\xce\xd3\x50\x81\x0e\x65\x96\x9f\x91\xe9\x0c\x17\x4d\xe5\x75\x28\x8d\x76\x78\xee\xa7\x34\xbb\x48\xdf\x97\x51\x69\x47\xea\xb7\x73\xab\x1b\xd0\xab\xd8\xb3\x6b\x58\x2c\x93\x04\xc7\x4d\x55\xe8\x50\x15\x87\x4e\xe5\x25\xa8\xc2\x11\x7c\xd3\x3d\x58\xd8\x08\x11\xa8\x7a\x6a\x2a\x68\x28\x69\x5c\x91\x84\x9e\x34\x0d\xd6\x77\x36\x08\xab\xb3\xb4\x92\x36\x2f\x09\xfd\xc9\x5a\x09\x63\xe7\x0b\x3d\x69\x0c\xb6\xea\x16\x35\xac\xcc\xd1\xcb\xf9\x07\x54\x9d\x57\x51\xd6\x56\x4b\x79\xaa\xb0\x41\x8c\xde\x1b\x87\xff\xd0\x9b\xaa\xc3\xeb\xa7\x9a\xd0\x93\xa6\xc3\x5a\x68\xf4\x24\xea\xd0\x36\x17\x0a\x2b\xd2\x19\xf7\x32\x4c\x6f\xd8\x7d\x42\x27\xdc\x7a\x52\xe5\x50\x86\xb2\xf0\x4e\xb8\xb3\x73\x7d\x16\xec\x6c\xdd\x3b\x23\xb8\xb7\xbd\xf9\xaf\xb1\xbd\xe1\x94\x7e\x17\x51\x45\x56\x73\xc1\xde\xd2\xe0\x86\x05\x3d\x31\x2d\x69\x58\xda\x57\xf0\xe5\xde\xde\xfb\x7a\x94\x24\x43\x2b\x3e\x21\x3c\xab\xb4\xa3\x9b\xb8\x3e\xd9\x85\x6d\xb0\x1b\xc8\xa9\xc6\x17\xbb\x2f\x92\xd3\x47\xb6\xb9\x71\x5f\xe1\x7a\x08\xd3\xd5\xfd\x78\xab\x4a\xf9\xee\xa0\xd7\xca\x92\x6e\x57\x2d\x84\x3f\x8b\xe0\x58\x07\x75\x8a\x6f\x1d\x46\x84\x38\xe5\x20\xfc\x53\x87\xb8\x13\x3f\xf2\x74\x7f\xb0\x27\xc3\xf0\x38\x08\xd7\xc0\xea\x25\x8e\x7e\xb2\xc8\xa7\x85\x2e\xeb\xde\x20\x70\xaf\x90\xf1\x55\x94\x33\x78\xce\x0f\xbc\x9e\xbd\x1d\xc9\xa7\x05\x73\x16\xbf\xce\x85\xb7\x76\x1d\xd6\x85\xb1\xda\x4e\xeb\xdd\xfb\xc1\x21\x25\x91\xa3\xc7\x6c\xe3\x8f\xd4\xdc\xc1\xf9\xc7\x66\x3f\x41\xaf\x11\x13\x7b\x0a\x0f\x2d\x11\x51\x17\x99\x4d\x8f\x25\xeb\x0b\x3f\x44\x0a\x34\xce\xf2\xdc\xf5\x0c\x08\x27\xa0\xa8\xc4\x07\xf9\xb4\xf0\x05\x6b\x53\xd1\xa2\x1f\xa2\xbf\xc1\x09\xaa\x40\x5f\xe0\xfc\x74\x4d\xdb\x23\x25\x37\xcd\x37\x9c\x07\x7a\xa6\x4a\x6f\xa7\x72\x8e\xd4\x21\x97\x41\x01\x8a\x1c\x13\x81\x48\x21\x9e\x9e\xf1\x99\x3d\x37\x28\x84\x0c\xaf\x1c\xb0\x79\x82\x2f\x38\x15\x84\x89\x6e\xb5\x11\xbc\xe0\xca\xa3\x4b\xf1\x1e\x89\xcf\xdd\x7a\xcf\x89\xe1\x17\xf5\xa5\x67\x69\x7a\x2e\x76\x54\xf4\xd6\x8d\x01\x07\x67\x5e\x62\x6b\xe1\xd5\xa5\x84\x51\xc6\x2a\x60\x3d\x86\x84\xb3\xba\xc0\x8e\x20\xc4\xf5\xfd\xfd\x2a\x42\x36\x1f\xa0\xd0\xb3\x2f\x0f\xa3\x55\x13\xec\xaa\xe7\xbc\x0b\xaf\xf3\xa4\x2a\x94\x32\x60\x92\xa2\x93\x8a\x11\x0b\x20\x7b\xcb\xc0\x3c\xe4\xe5\x6c\x1a\xca\x83\x72\x85\x57\xd5\x28\x91\xad\x2a\x0b\x9d\x5a\xca\x93\xed\xd7\x92\x9d\x11\x3d\x72\x75\x86\xb1\x2a\xbf\x30\x83\x3e\x56\x44\x95\xbc\x56\xdc\x5c\x5f\x3e\x3d\x4f\x88\xc7\x32\xf3\x3b\x70\x37\x42\x40\xee\xa3\x0a\xe7\xec\x3e\x1f\xdf\xfc\xf9\x81\x36\x5c\x23\xb6\x64\x8d\x49\x4c\x45\xf0\x11\x81\xfa\x9b\x05\x59\xf0\x16\xaf\x9d\xf7\x1b\x85\x5a\xe0\x5e\xa2\x37\x03\xf4\x44\x68\x83\x6a\x9a\x58\xa6\x8b\x68\xfc\xe9\x98\xe9\xa1\x0d\xa3\x2f\x48\x32\x74\x43\x66\x92\xea\x82\xe9\x2d\x47\x54\xc5\x7e\x48\xd2\xdb\x47\xdb\xe8\xa9\x48\x14\x8e\xac\x91\x38\x07\xaa\x97\xdd\xd2\xfd\x74\x95\x1f\x6b\x5d\xc8\x09\x78\x71\x73\x46\xb9\x2e\x45\xf7\xc2\x2b\x43\x98\x9d\x6e\x9e\xa1\xd0\xe7\x6b\xf9\x10\x22\xf8\x46\x5a\xd0\x64\x81\x2c\x3b\x2c\x73\x94\x24\xfa\xe2\x1e\x0c\x06\x62\x7d\x1f\xda\x65\xad\xcd\xc7\xf1\x72\x72\xc4\xb6\x3b\x88\xce\x2a\x40\xe9\x6e\x14\xc9\x1a\x02\x33\xfa\xba\x48\x66\x2e\xbd\xe0\x09\x99\x38\x74\x45\xc6\x23\x9f\x28\x8d\x4d\x57\x18\x02\x8c\x85\x2f\x66\x27\x23\x5a\x07\x8b\xfd\x46\xc1\x39\xda\xbc\xb4\xcb\x67\x15\xc2\xaf\x36\x51\x2d\xf4\xaa\x2a\xc4\xed\x2a\xf1\x6b\xfd\xfb\xa6\x90\xc1\x2c\x23\x3a\xb9\xc7\xc0\x41\x46\xc9\x7f\xdc\xf3\xad\x21\x16\xea\xec\x07\xbc\xf7\x9a\xd2\x97\x5e\x44\x17\x7f\xec\x62\x4a\xa1\xcf\x3c\xee\x0a\x2e\x2d\xe0\xd4\xb5\xfc\xba\xef\x45\xa5\xd2\xd1\x4a\xc6\x87\xcb\x19\x43\x02\xaf\x3a\x30\xba\xe6\xbe\x9f\x82\x52\xfa\x12\xee\x19\xeb\x41\xf3\x19\xed\xbc\x78\x6c\xd3\x60\xe0\xfa\x29\x71\x79\x80\xe6\xa5\x44\x18\xc6\x1b\x6f\xd9\x03\x66\x5c\xb1\x67\xfa\x86\x65\x9d\xc6\xb1\xe3\x07\xbb\xcc\x2f\xad\xe7\x5f\x1a\x28\xbc\xf8\xaa\x1e\x2f\x32\x9e\xa8\x8d\xe1\x8d\x70\xcf\xf1\x34\xc2\x28\x7e\x1f\x61\xaf\x8f\x13\xbb\xf3\xa2\x75\x4d\x92\xa9\xdd\x28\xda\x9c\x2b\xed\x6d\xc3\x2c\xd2\xb8\x2b\x58\x2d\xfc\xa9\x96\x5a\xe3\x9a\x11\x24\xc5\x01\xb9\x45\xe2\x0f\x68\x13\x0e\x35\xc6\x59\xd3\x95\x0e\xf5\xc0\x8f\x51\xca\x9e\xdb\xa6\x31\x77\xc9\x07\x91\x3b\xd3\x47\xe2\xa0\xea\x84\x30\x6d\x58\xae\x46\xd8\x2e\xba\x6e\xac\x79\xe8\x99\xb7\x78\xbc\xba\x46\xf0\xf6\xfe\xd2\x71\x51\x92\x79\x54\xe2\x9f\x22\x50\x20\x36\x51\x95\x06\xde\x44\x51\x7a\xcd\x77\x41\x4d\x5f\x9f\x3a\xda\xcd\x90\x36\xae\xa6\xd9\xf1\x80\x56\xcd\xcc\x3b\xd1\x0c\x16\x11\xa0\x58\xcc\x78\xae\x0b\xe4\xf2\x81\x3f\x3a\xbc\xbb\xb3\xda\xd5\x34\xcd\x55\x53\x0c\xf9\x9b\xcd\x53\x2b\xc4\x8b\x1b\x5d\xbe\x32\x6b\x02\xae\x7b\xa5\xe6\x5b\xc4\x91\xd3\x8b\x0a\x3c\x6b\x64\x5f\x8b\xb0\xdf\x36\xa8\x9c\xac\xff\x46\x71\xe5\x64\xa1\x55\x07\xf9\x35\x83\xcc\x29\x1d\x0d\x1d\x60\xbe\x18\x0b\xef\x45\x05\x3b\x35\x37\x31\x22\x0e\x5d\x6d\x95\x52\x33\x2e\x5e\xf6\x8f\xcd\x95\x90\x11\x6c\x43\x80\xe9\x62\x8a\x11\x33\x42\x9f\x13\xd7\xdd\x97\xa5\x71\x7d\x0a\x3e\x44\x3f\xd2\x1e\x77\x51\xc8\x3e\xac\x9d\xa4\x1b\x38\xc2\x4b\xa8\xdc\x6c\xc9\x3c\xe9\x20\x8c\x0f\xe7\x4c\x65\xb1\x8e\x0b\xf7\x93\x8c\x41\x36\x12\x83\x88\x8e\x51\xb5\xfd\xc8\xb8\x33\xf5\x5b\x8f\x27\x44\x8c\x3e\xc1\xa5\x21\xe8\xac\x9b\xd8\x51\xd6\x38\xb0\xcd\x97\xba\x0c\x25\x5c\x2c\xa8\xb4\xaa\xad\xc2\x42\xe7\x20\x5a\x2c\x92\x4b\xee\xf0\xa5\x15\x61\xf5\x6d\x33\x36\xb6\x05\x58\xcd\xd0\xc4\x1b\xd5\xdd\x30\x0f\x3c\x8c\x8a\x62\x3c\x2a\x92\xca\xad\x43\xa8\x78\x26\xec\x6b\x45\x51\x11\xe9\x6a\xc5\xeb\x1e\x6f\x2a\xc1\xf9\x61\x53\x61\xb8\x0a\xd0\x95\x9a\xbd\x93\x5f\x55\xdc\x14\x91\xd8\x48\x54\x52\x65\x31\xb5\x5b\x0b\x4f\x2d\xf4\xf3\x4f\x19\x42\x46\x94\x05\x02\x27\xf9\x78\x99\x44\xf9\xfa\xfa\xfa\x7a\x7d\xe0\x18\x41\x41\x7b\x77\x12\x3a\x86\x69\x7f\x3b\xe1\xf6\x63\xbf\x1f\x8e\xed\xfb\xdb\xff\xfb\xdb\xff\xbf\xf6\xed\x3f\xbf\xfa\xa7\xb0\x22\xb4\x8f\x3f\x20\xc1\xef\x16\x6a\xc0\x67\x59\x50\x6f\x08\xb0\x36\x1c\x42\xe8\xa2\x28\xa7\xa4\x4c\x77\xb0\x65\x61\x0e\x91\x12\x5c\x4c\x26\x13\x9c\xe3\xb4\x44\x38\x3d\x2f\xa0\xd0\x28\xcf\x2e\x0a\x9c\xaf\x69\x7e\x19\x2f\x48\x1a\x67\x17\xa0\xb1\xd0\x1c\xf6\xa3\x07\x0f\x78\xce\xe0\x9f\xaf\x5f\xbd\x2c\xcb\x05\x77\xf9\xc9\xb8\xa6\x99\x86\xf6\xfd\xb0\xc0\xfa\xb8\xbf\x79\x32\x4d\x33\xca\x08\x12\x92\x62\xda\x93\x34\x8b\xf1\x9a\xe6\xe4\xc9\xa9\x51\x0e\xfc\xf3\x3c\xa1\x23\xe3\x1b\x5b\xb7\xdf\xb6\x91\x6b\x86\xc9\x7f\xbe\x7c\xb7\x6d\x54\x37\xcb\xb7\xbb\xfd\xca\x52\x42\x72\xa0\x2d\xbc\x15\xc8\x74\x4d\x22\x40\x7e\xa2\xa2\x3d\x78\x39\x64\x3e\x91\x69\x2f\xa5\x01\x84\x51\x5e\xdf\xf2\x67\x59\x51\x06\xa8\x24\x73\x9c\x2d\xcb\x80\x56\x98\x07\xa0\x64\xbe\xc8\x72\xee\x76\x02\x36\x13\x0a\x87\xf6\x11\xfc\x77\x75\x85\xba\x9c\xd8\x93\x6c\x1c\x25\x34\x31\x7c\xf2\xcd\xee\x37\x10\x1f\x94\xed\x3d\xac\x42\xba\x13\xf2\x5f\x57\x57\x68\x53\x66\xd3\x66\xd0\x3e\xb4\x26\xd3\x44\xa3\x68\x5f\xb6\xdf\x28\x3c\x2d\x72\xbc\x80\x80\x5b\xf8\xc2\x9a\x32\x4b\x76\xe2\x80\xef\xb4\xb3\x8c\x16\x5e\x1e\x47\xe9\x35\xdc\xb1\xb2\xf8\xf2\x6c\xce\x15\x96\xb9\x77\x3d\xed\xc0\x67\xb6\x65\xb8\x70\xd1\x31\xad\xc9\x5d\x66\x07\xcc\x8b\x40\x5a\x3d\x43\x35\xbb\x41\x61\x84\x44\x79\x18\xdc\x00\xaa\x6c\x84\x94\x78\x05\x43\x7e\xf9\x6e\x5b\x85\xe7\x64\x92\x96\x86\x79\x6d\x22\x28\x8c\xe1\xc3\xcc\xaa\xc8\x18\x0f\xab\x12\xe4\x61\x51\x6b\xb6\xc0\x69\xaf\xfb\xf6\xf8\xfd\x07\x11\x51\x90\x11\x0e\xeb\xdc\xde\x9a\xe6\x10\x0d\xe6\xf6\xc1\x03\x73\x52\x8d\x43\xdf\x12\x0c\x6a\xba\xcf\xa2\x82\x8c\x51\x17\x6d\x40\x17\x9e\x2d\x29\x7b\xd0\xaa\xd8\x40\xdd\x50\x5e\x15\xca\x7a\x06\x65\xc6\x1f\xad\x75\x47\x51\x81\x1f\xef\x76\xad\xf1\x2b\x77\xc0\x2f\x71\x14\xe3\xbc\xd7\x3d\x00\xbe\x4a\x7e\x8d\xd8\x69\x0b\xda\x67\x23\xac\x29\x44\xe5\x63\x9c\x96\x8f\xe8\x41\xbb\x1b\xa0\x2e\x95\xfc\xc9\x18\xaa\x18\xfe\x52\x08\xb5\xa3\xbc\xb1\xe2\x53\xd6\x40\xae\x2c\x70\xc4\x65\x3a\xd6\x0e\xd5\xb6\x26\xd9\x77\xf1\xbc\xd0\xae\xaf\xfd\x21\x82\xeb\x48\xaf\xb0\x43\xc6\x09\x5d\x9a\x4d\x72\x82\x66\x4c\x5a\xe5\x31\x67\x81\x1e\x95\x76\x5f\x61\x2e\x8d\x7b\xf0\x20\x82\x05\x03\x21\x93\x4b\x59\x3f\x47\xb2\x50\xe4\xeb\xde\x7e\x7d\xd6\x00\x87\x59\x9a\x62\xfe\x6c\x41\x50\x98\xa2\x44\xe3\x72\x51\xb4\x2e\xfc\xee\x7f\xc0\x9f\x4b\xa7\x83\x1c\x56\x7b\xad\xc1\xad\xe3\xcd\x6e\xd5\x75\xe9\x1d\xaf\xbf\xe7\x6b\x50\x5f\x25\xed\x43\xc0\x02\x0d\x44\x0d\x44\x70\x20\x39\x4e\x0d\x25\xf0\xac\x1f\x9d\xa0\x0b\xa8\xcc\xc9\x74\x8a\x73\x16\x29\x86\xce\x3e\x88\x2d\xd2\xed\x23\xc5\x41\x13\xc1\x40\x0f\x7c\x54\x63\x06\x7c\x6d\x43\x3f\x60\xbc\xb2\x67\x70\x93\x14\x5c\xf4\x16\x65\x54\xe2\xf1\x2c\x4a\xa7\x7e\x05\x02\x33\xef\x17\x88\x8f\xe2\x4b\x30\xac\x87\x1b\xe1\x5d\xca\x61\x6c\x96\xb7\x6e\x06\x6c\x6d\x41\x31\x0a\x50\xdc\x2a\x69\x91\x80\xec\xcb\xac\x06\x8a\x82\x33\x99\xf7\xd6\x4a\xde\x58\xad\x48\x5b\x48\xbf\xda\xb2\x2f\xb6\x8c\x96\xe9\x59\xf0\xda\x42\xb1\xda\x08\x5c\xcc\x9a\x95\x15\x03\xb5\xf4\x3e\xb0\x52\x3d\x7d\xf3\xe0\x0b\xf9\x6e\x39\x80\xdd\x85\x3a\x26\xc0\x57\x9a\xbe\xae\xd4\x65\x79\x72\x49\xe9\x9d\xbd\x2d\x85\xc5\x45\xa9\xba\x62\x6d\x91\x82\xd7\x8f\x4d\x66\x2a\x85\x00\xa1\xe0\xb4\x85\x01\x7a\x7e\x48\xbb\x25\x9a\x44\x24\xc1\xf1\x00\x1d\xd3\x73\xda\x05\xa1\x67\x8f\x08\x82\x3b\x55\xaf\x26\xad\x4d\xcf\x5c\x28\x7c\x4a\x7d\x86\x0c\x22\x40\xe2\x10\x7d\x27\xff\x84\xbe\x8f\xee\x3e\xf9\x62\x1c\xa2\xee\xf6\x60\x53\x2a\x0f\x85\xfe\xb1\x9b\xe2\xf2\x63\x42\x8a\x12\xa7\x24\x9d\xca\x6c\xa9\x3d\x3c\x33\x0c\xba\x84\x82\x2b\x67\x91\xaa\x5c\xf2\x15\x56\x85\x74\x90\x6a\x12\x1c\x75\x81\x3e\x74\xa1\x2a\x30\x4e\xfb\x54\xcc\xed\x84\x4f\xe8\x2f\x43\x7e\xee\x84\x5b\xdf\xd2\x93\xff\xce\xfd\xc9\xff\xfe\xe4\xff\x17\x3f\xf9\x2b\xc3\x7f\x78\xb4\x78\x47\x46\xff\xd2\x90\x53\x3f\x55\x8e\xc8\x94\xd9\xe0\x0e\x7e\x61\x27\x74\x76\x0f\x12\xbf\xc2\x13\x73\x43\x90\x21\xfb\x2e\xb5\x87\x73\xc6\xc6\xc9\x20\x18\xbb\xb8\x98\xd1\xde\xf7\x4c\x03\xad\xef\x59\x61\xf4\x10\x6d\xbb\x2f\xef\xc0\xe2\x8f\x4a\xf1\xe6\xfb\x43\x54\x1b\x37\xff\x2d\x3f\xd5\x45\x29\x3a\x7a\x76\xf0\x86\x4f\x72\x8c\xbe\xfb\x16\x8d\xb3\xf9\x62\xc9\xc3\x65\x8c\x2e\xd1\x3c\x3b\x27\xe9\x54\x0b\x0a\xb5\x8b\xc6\xb3\x28\x87\xbd\x80\xdd\xcc\xc6\xcc\x94\x4a\x98\xab\x0b\xe8\x04\xb3\x47\x0b\x65\x46\x1b\x64\xb8\x2a\x50\xef\x00\xed\xa3\xad\xcd\x00\x3d\xa3\xff\x6f\x05\x68\x30\x18\x04\xe8\xff\xd0\x3e\xda\xf9\xa6\x4f\x0f\x3b\xa8\x58\xe0\x31\x99\x10\xb6\x90\x8e\xde\x1f\x6f\xed\x3c\xde\x7a\x6c\x9b\x98\x91\x22\x83\x74\x3e\x0e\xd7\x39\xe8\x35\x7b\x13\x4b\x3b\x42\x07\x68\x5e\xad\xe9\x37\xcb\x5c\x92\x8a\x05\x18\x77\x6d\x60\xd6\x6f\xc6\xe7\x1f\x45\xa9\x3e\x8f\x74\x44\xdd\x83\xee\x80\xa2\xe5\x30\x8b\xf1\x41\xd9\xdb\xd4\xb4\xd6\x74\x6c\xdd\xff\x73\xb2\x19\x03\x64\xef\x76\x81\x58\xcb\xec\x64\xb1\xc0\xf9\x61\x54\x28\x55\xb6\x96\x5d\x2c\x47\x45\x99\xf7\x76\xfb\xe2\x45\x2e\x4f\xd8\x0c\x76\xad\x1b\x33\x96\xbb\x48\x48\xd9\xeb\x76\xfb\xe6\x63\xe5\xb4\x6f\x5a\x57\x8d\xb3\x98\x0e\x2e\xf5\x75\x5e\xc8\x87\x00\xf3\xc3\x3e\x3a\xa0\x02\x21\x7c\x7c\xbf\x8f\xfe\xaf\xef\xb8\x72\xf7\xcc\x2c\x9f\x58\x03\x52\xfa\x06\x8d\x31\x7a\x84\x0e\xd0\x06\xda\xda\xd4\xec\x8c\x7c\xee\xcd\x45\x08\x49\xdb\x86\xe9\xba\x3f\xf8\x25\x23\x29\x1d\xa6\x6d\xa9\x38\x5e\x82\xe3\x54\x98\xe2\xd7\xc7\xcf\x29\x61\x6f\x6d\x0a\xa6\xc4\x2d\xfc\x80\xf2\x3d\x14\xf7\xed\xe6\xe3\x5d\x9b\xe0\xe6\x59\xfc\xdd\xb7\x5b\x9b\x55\x84\x66\xd2\x97\x72\x47\xcb\xa8\x89\x17\xae\xa5\xa2\x1c\xcf\x23\x92\x32\xdd\x11\xcd\x53\x77\x8f\xdc\xb9\x8b\xc9\x1e\x38\xb0\xb2\x5b\xde\xee\x5b\x6e\x6b\x80\x59\x09\x30\x69\xf1\xfa\x9d\x61\x22\xa7\x9a\x04\x59\xfb\x28\x2d\x99\x47\x9c\x00\x6d\x6d\xf6\xd1\xff\x9f\x62\x6d\xc3\xa9\x85\x39\xc5\xe1\xe6\xe7\xbe\x17\xb8\xb2\x2e\x59\x52\xd5\x67\xcc\x53\xf3\x3b\x24\x66\x82\x0e\xeb\x80\x1b\xfc\xc3\x85\x3a\x24\xf0\xb7\x0e\x9c\x7d\x8a\xf9\xf2\x4f\xce\x50\x77\x5a\xec\x9f\x04\x6e\x09\xad\x96\x9c\xdb\x55\x27\x58\x68\x53\x3f\x31\xc4\x92\x59\xce\xc5\xeb\x1c\x8b\xa8\x28\xcc\x81\xcc\x71\xfa\x1e\x69\x59\x42\x8c\xa6\x43\xb8\x96\x6c\x4d\xd7\x8a\xd1\x9c\x81\x56\x63\x93\xcf\x80\xa8\x78\x26\x7c\x06\x68\x2f\x15\x78\x50\x47\xb4\xf5\x58\x63\x61\xa3\xa8\xc0\x3b\x8f\xd1\x3e\x94\x51\xea\xa1\x9d\xc7\x86\x09\x40\x1c\x63\xa6\x59\x84\x3d\xb0\xc7\x0a\x05\x68\xeb\x1b\x53\x12\x96\xfd\x7c\x36\x8a\xd2\x1e\x2b\x66\x32\x3f\x6b\x31\x73\xb7\x24\xda\xc2\x7d\x46\x87\x5e\x66\xc6\xee\x45\xa7\x0f\x81\x27\xcd\xfc\x52\xac\x68\xa6\x4c\x02\x7b\xdd\xb7\xcc\xa5\x7f\x9a\x95\x5c\x28\xfb\x9e\xfc\xd0\x99\x82\x44\xc2\xdc\xdd\x4c\x14\x52\x8b\x59\xc4\xa4\x35\xd8\xdf\x3e\x8f\x93\x65\x41\xce\x65\x04\x42\x32\x22\x09\x29\xa5\x80\x33\x8a\xd2\x4f\xc3\x51\x1e\xa5\xe3\x19\x2a\x70\x7e\x4e\xc6\x62\x03\x8c\x98\x63\xcf\xce\xf7\x43\xf2\xc3\xc0\xa6\x21\x19\x0d\xa0\x10\xbb\xd0\x04\xe7\x74\x1b\x8a\x92\x69\x96\x93\x72\x36\x47\x31\x2e\xc6\x39\x19\x31\xb6\xc4\xe5\x1f\x9c\x0e\x2e\xc8\x27\xb2\xc0\x31\x89\x40\x08\xa2\x5f\xc3\xa3\xb4\xc4\x79\x1a\xb1\xa7\x13\x1f\x9f\x45\xe9\xa7\x8f\xdc\xab\xe8\x47\x36\xaf\xff\xbf\x9f\xf8\x48\xd3\xe9\x47\x3a\xc4\x8f\xf0\x96\xe8\x63\x4c\xa6\xc4\x79\xca\x21\xa6\xc6\x47\x91\x23\xb1\xa7\x8a\x19\x10\x3e\x63\xca\xcc\xb3\xcd\xb6\xa0\xd5\x67\xf6\x8a\x1c\x59\x6c\x91\xcf\xe8\x21\xdb\xa7\xba\xff\x7c\xd1\xdd\x5b\xf3\xf2\x4c\xce\x63\x7b\xd6\xce\xdd\xd3\x2b\xd8\x40\xdd\x4d\x10\x95\xa0\x15\xdd\xdc\x85\xa2\xe3\x39\xc5\x06\xda\x47\x3d\x26\x4e\xf5\xbe\x7b\x82\x1e\xa9\x26\xfa\xe2\xd9\xc0\xa3\x6d\x6b\xbf\x95\x5e\x37\xcc\xa6\xb4\x3a\x79\x83\x0d\x6a\x2b\xce\x44\x34\x5c\x01\x61\xb3\x38\xd0\x24\x2d\x4a\x52\x2e\x4b\xe1\x1b\x97\xc4\x38\x2d\xe9\xa6\x65\xfb\x4f\x67\xb5\x1c\xa5\x31\xc9\xb1\x69\xc0\x60\xbe\xb1\x29\x02\x21\xcb\xca\x47\x36\xf0\x6a\xaa\xa3\xb5\xd4\x81\xa6\x3a\xaa\xad\xce\x2a\xbc\xc8\xec\x89\xd7\x5f\xae\x79\x04\x36\x39\x43\xf7\xc5\x87\x97\x74\x1e\xc4\xeb\x16\x1d\x03\x5a\xaa\xec\x5b\xdf\xe2\xd7\x59\x1d\xbf\x16\x4f\xe9\x18\x72\x79\x90\x65\x52\xb0\x97\x72\x3a\x1f\x77\xe4\x4e\xf0\xa0\x52\x29\x6f\xca\xbd\xc8\xa3\xf8\x10\x0a\x0f\xf6\x9c\x8e\xb5\x24\x85\xce\x23\xcd\x3f\x4b\xad\x9c\x40\x74\x3f\x2f\x84\x91\x95\x2e\xfc\x29\x27\x2f\x8d\xba\x72\x89\x05\xe8\x7a\xe5\xeb\x41\x7d\xcc\xca\xb9\xbe\x77\x54\x01\xca\x3c\x5a\x1b\x18\x9b\xae\xad\x71\x47\x69\x51\xc2\xf0\x3f\xff\x7c\x71\xba\xf9\xe8\xbb\xb3\x2f\xdb\xd7\xbd\x17\x1f\x5e\xd2\xdf\x07\x8f\xfe\xef\xec\xcb\xd6\xce\xf5\x95\xfc\xd8\xd9\x0c\x76\xb6\xae\xfb\xff\x33\x1c\x94\xa0\x04\x95\x1b\xb8\xf1\x2e\xaf\x8a\x31\x68\xe0\xec\x79\xde\xd6\x8a\x08\xe3\x4f\x30\xe1\xf4\xef\x45\xdb\x73\xb5\x04\xef\x06\x6f\xcf\xdd\x95\x64\x21\x4e\x0d\x4a\x7f\xdc\xb3\xb3\x0b\xe1\xda\xfd\x79\xdf\xdc\x70\xd8\x13\x44\xd2\x8a\x81\x1b\xdc\xe7\x6e\x86\xee\x65\x23\xad\x06\xbf\xbd\xd9\xca\x6a\x93\x89\x94\x74\xa4\xc5\x72\x4e\x01\x4f\x0a\x7e\x7c\x98\x67\xf1\xa3\xef\xbe\x7d\xb4\xb5\x29\xb3\xe1\x8c\x0b\xbd\x1b\x67\x09\xea\x1d\xbd\x3f\x1e\x1e\xbd\x38\x44\xf4\xdc\x10\x6e\x6f\x6e\xee\xf4\x6d\x9e\xac\x55\xeb\x9e\x42\xb5\x5c\x67\xe0\x22\xaf\xe5\xb0\xf9\x99\x70\x3b\x40\xdb\xed\x6c\x55\x75\xa6\x6a\x6c\x29\x08\x4f\x07\xe8\x9f\xef\x5e\xfc\xe4\x38\x12\x94\x05\xfc\xa3\xa9\xac\xd1\x9d\x54\x0d\xb2\x69\x78\x8a\x00\x7a\xe0\x65\xce\x19\xf2\xb7\x01\xda\xed\xa3\x10\x75\xbb\xad\xc6\x3d\x4e\x08\x3c\x24\x93\x1d\x04\xe5\x13\x49\xed\xf1\x51\x2c\xfc\x74\xf0\x8f\xe3\x1f\xff\x75\xfc\xee\x7f\xed\x59\x85\x3a\x2a\xe6\xd4\xae\xdf\x3b\xb9\x0c\xe8\xd6\x63\xdf\xda\x5a\x7d\xe4\x7c\x35\xf9\xcf\x25\xee\xc1\xc3\x1d\x9a\x53\x81\x33\xbc\xc8\x73\x0e\xd1\xef\x9d\xf8\xe0\x7c\x0e\xc8\x8c\x43\x87\x3b\xe0\x5d\xed\x10\x5b\x79\x94\x11\xe7\x0f\x79\x4a\x31\x4e\xa8\xec\x8c\x62\x9e\x67\xb6\x1e\xf7\x03\xb4\xbd\x29\x5d\x9c\x19\x52\x9e\x40\xaf\x35\x48\x51\xb8\xdd\x02\xad\xf0\x4a\x75\x04\x59\x4c\xa9\xaf\xeb\x15\x3b\xa1\xf9\x79\x7d\x16\xec\xec\xde\xab\xf1\xef\xd5\xf8\x7f\x71\x35\x3e\x57\xe1\x2f\xc6\xf5\xf6\x7b\xb7\xb0\xb8\xeb\xa8\x48\x73\x9d\xbd\x95\x22\x64\x35\xd8\xe9\x31\x3d\xd3\x62\xec\xb5\x04\x5b\x44\xe5\x2c\x40\x29\x36\xac\xbf\x3f\x82\xe6\xc2\x79\x78\x2a\xae\xaa\xf5\x18\xbd\xc2\x6b\x01\xb7\xd7\x01\x1b\x1f\xfa\x1f\x4b\x55\x59\x63\x79\xc3\x0b\x5c\xb1\x14\x09\xbd\x2f\x14\x3a\x54\xe5\xa5\x2b\x4a\xab\xd8\x20\x4b\x7b\x5d\x18\x55\x57\x8f\x69\xd9\x37\xec\xa7\x8b\x8c\x32\x31\xf6\x96\xf0\xe8\xed\x21\x52\xf7\xca\xec\x85\x61\x37\x40\x7a\x28\xf8\x8f\x8c\x0d\xf2\x0b\xef\x9e\xed\x0c\xd3\xdb\x83\x34\xd6\xdb\xd7\x9a\xaf\xac\x0c\xad\xc9\x37\x06\xaf\x8e\xde\x7f\x78\xf1\x06\x56\xd0\xe1\xf1\x9b\x37\x2f\x0e\x3f\x1c\x1d\xbf\x41\xef\x5e\xbc\x7f\x7b\xfc\xe6\xfd\x8b\xf7\x95\xad\xc6\x51\x19\xe9\xcd\xd2\x6f\x7d\x73\x1a\x3e\xe4\x66\x84\xf3\xe8\xf3\x38\x9b\x2f\x12\xfc\x99\x94\x97\x21\x7a\x0c\x94\x65\xf5\x10\x74\xa1\xd2\x0e\x81\x56\xa5\xf6\x9b\xbe\x27\xa0\x09\xb7\x39\xf8\x62\xc6\x23\x86\x83\x5f\x6c\xdb\x4e\xf0\xee\xb0\x38\xcd\xc0\x5f\x62\x74\x31\x23\xe3\x19\x9a\x47\xe5\x78\xc6\xc5\x57\xb6\x09\x51\x86\x16\x1b\xe5\x3c\xf1\x03\xa0\x69\x7f\xc0\x62\xb8\x8e\x72\x7a\x0b\x16\x08\xfe\x70\xa2\x64\xd2\xfb\xe8\x27\xe4\x53\x78\x1b\x47\xe2\x33\xd7\x1d\xba\x2c\x4c\xc7\xca\xc1\xf6\x1c\x28\x3b\xf4\x73\x65\x48\x54\xa8\x86\xf7\xdd\xae\xe8\xda\xc1\xe2\x84\xe4\xd8\xf0\x08\x60\xa3\xab\x6a\x3c\x74\x28\x9e\xd6\x6b\xc0\x55\x7c\x56\xdd\xb4\x45\xfd\xc5\x38\xc1\x25\xae\xab\xc1\x1e\x8c\x8d\x1b\xfd\x15\xf6\xcf\x74\xd7\x02\x42\xe4\x04\xc1\xea\x03\xe5\x0e\xb3\x95\x4a\x99\xb3\x1c\x94\x31\x57\xcb\xa4\x1c\xac\xad\x09\x61\xd0\x24\xe1\x35\x5b\xed\x01\x2f\x32\xa9\xf0\xa7\x78\x9e\x26\x1e\x99\x85\x75\xcf\xaa\xfa\xaa\xb2\xd9\x60\x60\xc9\x6b\xff\x60\x9e\xcd\x95\x83\x5e\xb1\xc4\x9f\xbf\x78\x74\xf8\xf2\xe4\xcd\xff\xbe\x78\x27\xeb\x89\xf1\x78\xb6\x4c\x3f\xe1\x98\xbf\x2a\x61\x2f\x46\xf9\xdf\x20\xc7\x8b\x24\x1a\xe3\xde\xf0\xdf\xd7\xa7\xff\x4e\xff\x9d\x9f\x3d\xfd\xf7\x97\xe1\x34\xe8\x5e\x5f\x3d\x7a\x74\xf5\xa5\xdb\x07\xd7\xc2\x5f\xbc\xf0\xff\x3e\x13\x25\x4e\x79\x99\x33\x5a\xe8\x54\x94\x3a\x3b\xf5\x97\xb3\x4b\x19\x85\x2a\xca\xa8\xb6\xb4\x96\x64\x43\x5a\x19\x7e\xcd\x47\xb3\xbb\x82\x93\x1a\x18\x70\xd7\x2c\x20\x5e\xe3\x2f\xc3\x21\xdc\x81\x62\xee\x0e\x03\x3c\x6d\x40\x05\x6b\x0e\xe9\xd3\xbc\x43\x9a\x65\xae\x5c\xee\x77\xc6\x82\x41\x1b\x88\xbd\x7f\x35\x44\x75\x79\x67\x6d\x71\x32\xd7\xa9\x81\xcf\x16\x0c\xfa\xae\x95\xe2\xd6\x34\xcc\x98\x66\xcd\x5d\x7c\xaa\x33\xfb\x76\x67\x90\x11\xe6\x9d\x1b\x72\x81\xeb\xbb\x74\x8c\x13\x70\xa8\x2f\x1e\x71\x1a\x65\xc6\x09\x8e\x72\x61\xc2\x65\xb5\xc2\x93\xad\x05\xed\x07\x02\x5f\x0d\xa5\xa8\xc8\xb7\xc7\x99\xe5\xed\xbd\x4e\xff\xab\xb5\xab\xe4\x38\xd3\xe1\xaf\x03\xb4\xb5\xb9\xb9\x89\x1e\xb2\xcb\x19\xcf\x5d\xab\xd7\xf1\x03\xbc\xdb\x03\xec\x08\x7c\x51\x0e\x52\x60\x4e\x2f\x2c\x28\x0a\x7f\xd7\xb7\x3a\xaa\xdc\x19\xb3\x48\x04\xa2\xfd\x70\xcb\x4a\xa7\xc3\x8c\x45\xb0\xb8\xde\xa6\xdd\x9e\xa5\xad\xd7\xc1\xb9\xf3\x1f\xca\x23\x7f\xe2\x5b\x68\x14\xc7\x85\x1e\xf0\x99\x5b\x39\xb8\xd2\x18\x53\x0f\x07\x6b\x6c\xc3\x15\x07\x03\x7e\xd6\x26\xcc\x5d\x3d\xe7\x7a\x73\x11\xbd\xde\xe0\xbe\x47\x31\x2b\x15\xe5\x39\x39\xc7\x3a\xc3\x8d\x62\x39\x7b\xa2\xbd\x1a\x0e\xeb\x81\x36\xbc\xd5\xfb\x6d\x4a\x35\x99\x42\xbc\x56\x27\x31\xba\xba\x12\x5f\xa7\x9b\x67\x72\xcb\x84\x2b\x6c\xd6\x37\x05\xcd\x13\xcc\x12\x2c\x51\x97\xe8\xbc\x9b\x17\xda\x97\xbd\xa9\x93\x78\x29\xe8\x40\x36\x2c\xea\x16\xbb\x9a\x58\x47\xfa\x4a\x65\x61\xe1\xb9\x59\x0a\x13\xcb\xe1\xf4\x05\x1a\x77\xba\xbf\xc7\x1a\x9a\x39\x11\xd7\xa0\xd6\xb5\xef\x94\x47\xfb\x49\x96\xf7\x28\x5e\x3e\xe1\x4b\x76\x52\xf4\x0d\xc0\x74\x02\xd3\xf3\x03\x0d\x66\x51\x71\x7c\x91\xbe\x85\x90\x47\xe5\x25\x84\xb4\xb3\xb8\x40\x05\x7a\x3e\xe1\xcb\xb3\x6a\xdb\xce\x6e\x96\xa2\xa3\xb7\x87\x5d\x3b\x16\x3b\x97\x2d\x6a\xea\x74\xcc\x2c\xd4\x32\x39\xd4\x7d\x10\x72\x77\xe3\x48\x3b\x6e\x90\x02\x15\x25\x61\xc1\x48\x48\xac\x11\xb5\x6e\x16\x5a\x89\x70\xbf\x1d\x67\xaf\xfa\xb4\x24\xe4\x00\xba\x7b\xe4\x98\xf7\x23\x60\x54\x60\xf6\x6a\x9a\xa5\x98\x6b\x9e\x7a\xeb\x1f\x6d\xb1\xff\x22\x27\x25\xf8\x4b\xb1\xb8\x91\x06\x62\x1d\xa1\x3e\xba\x67\x28\xe1\x62\x70\xbd\xaa\x76\xae\x40\xf2\x0e\xbd\xee\x05\xc1\x9a\x4e\x3f\x56\xbd\xf8\xbd\xf6\x74\x05\x19\x9b\xec\x9e\xc1\xb9\x57\x40\x91\x40\x53\x33\x96\x90\xe7\x08\xd5\x78\xd6\x14\xbd\x8c\xb5\x67\xbf\xbe\x51\xd5\x58\x3c\xdf\x4c\x6c\x90\x54\xad\x4b\x0d\xe6\x50\x1a\xf7\x51\x64\xfd\xf9\xf6\x49\xcb\xec\x8e\x6b\x13\xad\x33\x8a\xe3\x8e\xe7\x5f\xd9\x12\xac\xac\xd5\x6b\xb3\x4e\x77\xc3\x66\xb7\x1b\xdd\x0e\x2a\x74\xc3\xec\x81\x6e\xa7\xad\xf8\x20\xbc\xd8\xca\x4a\x54\x2c\x17\x8b\x2c\x2f\x41\xb7\xc6\x6e\x6a\xdf\x1e\x22\xa9\x55\xe9\x1a\x8e\x20\xab\x09\xb3\xf5\x4b\x85\x9b\x2c\xc6\x66\x2a\x5b\x89\xc2\xbc\xc7\x7a\xa0\xa9\x5a\x0b\x7a\xcd\xa1\xae\xbd\x9b\x56\x7a\xbb\x71\xf5\xb8\x0a\x83\x8e\x93\xf6\x8a\x57\xda\xd7\x67\xc1\xce\x37\xf7\x2a\xdd\x7b\x95\xee\x7f\x85\x4a\x97\x3f\xac\xb8\xd5\x73\xec\x83\x28\xcf\x52\xf4\xbf\xcb\x79\x74\x4e\x0a\xf4\x7d\x44\x3f\xff\xf6\x89\x7d\x0e\xe6\xd8\xab\xee\x1d\x0e\xd1\x51\x4a\x4a\x12\x25\xe4\x57\x8c\xfe\xce\x7a\x41\x09\x35\x42\x05\x58\x62\x09\x83\x1b\x18\x28\x5d\xaa\x86\x93\xf3\x01\x68\x75\x45\x31\x11\x45\x84\x07\xa0\x3a\x8a\x43\xb4\xd9\x74\xf3\xc6\xac\x3d\xe8\xf0\x6d\xb7\xba\x5e\x33\x13\xaf\x3b\x5d\xf5\x0a\x4d\xc4\xba\x9a\x08\x84\x42\x4b\xca\xa0\xc7\xe3\x84\x97\xbd\x4e\x09\x34\x55\xcf\x44\x54\x23\xb2\x84\xef\x5d\xaf\x1b\x22\x6d\x04\xb4\x3d\xa7\xf7\xc3\x35\x8e\x9e\x0a\x57\xbc\xac\xad\x80\x37\x66\x38\x4d\x65\x59\xfd\x2a\xd5\xb2\x68\xd2\x31\xe6\x91\x66\xbb\xeb\x5d\x2d\xea\x54\x14\x9f\xd3\x33\xaa\x98\x1d\x74\xf4\x1c\x72\x44\xef\xe4\xa4\x6d\x6c\x54\xf9\x19\xf2\xbf\xfe\x61\x6f\x85\x9c\x6a\x54\xb6\x78\x1e\xc4\x47\x2a\xd3\xc5\xbb\x20\xf6\x3f\x3d\x30\x89\x17\x42\xed\x0d\x2f\x04\x0e\xe4\xe1\x51\x18\x10\xf9\x4d\x75\xa4\xb2\xae\x29\xf2\x99\xe7\x65\xb6\xd5\x80\xdf\x3c\x43\xa0\xc1\x6a\xcf\x8a\x09\xcc\x12\xad\xcb\x50\xe6\xd3\x47\xd3\x39\x73\xa0\xa7\xb2\xed\x01\x3e\xc7\xf9\x65\x4f\x78\x43\x7e\x4f\xd2\x69\x82\x5f\x33\x84\xf7\x51\x88\xbc\x19\xaa\x26\x3e\xad\xb2\x23\x7e\x70\x3e\x81\xf2\xa0\x25\x85\x77\x41\x37\x9a\x05\x91\x48\x63\x14\x69\xd8\x16\xf1\x0c\x31\x3f\xfb\xfb\xfb\x8c\x6a\x74\x20\xee\x76\x41\xc0\xd2\x33\x37\x05\x63\xd7\xba\x5d\x5f\x75\x5c\x86\xb5\x7c\x4a\x0e\x87\x2c\x34\x9f\x4c\xe2\x5e\xd9\x75\xe6\x22\xd6\x63\x2b\x7f\xf2\x8c\x11\x8d\xe0\x3d\x5a\x03\x3b\x7a\x46\x81\xaa\x5d\x7c\xf3\x8e\x5b\xfc\x85\xd5\x55\x30\xa6\xca\xab\x12\x02\x4e\xdc\x07\xe5\x11\x5f\x14\x3d\xc1\x7b\xfa\x68\x42\x70\x12\x5b\xa6\x07\xbc\x15\xa3\xa7\x16\xcf\xd1\x3b\x68\x31\x1e\xd6\x35\x8b\x0c\x45\xb2\x15\xf5\x41\x90\x85\xeb\x08\xcb\x61\x6f\x02\x76\x20\x58\x9b\xf8\xe6\x2c\xce\xd4\xc3\x3b\xb2\x22\xaf\x8f\xcb\x89\x54\x0c\x7c\x7c\x2f\x06\xde\x8b\x81\x7f\x6d\x31\x50\xbd\xcf\x63\x8b\xe6\xae\x5e\xe8\xdd\xcd\xdd\x3d\x05\x79\x2d\xd4\x8d\x95\xc6\xca\x70\x4e\xe4\x91\x5a\x84\x15\x32\xfd\xd4\x4e\x91\xdc\xe5\x9a\xc8\xa5\x9f\xc6\xc5\x3d\xf0\x3c\x95\xaf\x24\x83\x4d\x0d\x0c\x7c\xf2\xeb\x41\x09\x95\x21\xb4\x9e\xa1\x95\x60\x9e\x9e\x7d\x45\xac\x1c\x43\xe9\x0a\x1a\x83\xd7\x51\x1a\x4d\xb1\x7a\x9d\x4f\x59\x16\x43\x85\xa1\x0a\x10\x2e\x3c\x14\xb8\xb6\xdf\xcf\x0d\x0c\x39\x15\xe7\xf3\x06\xfb\xf7\x18\x53\x0e\x43\x52\xd3\xbf\xa7\x25\xfe\x8d\xa2\x82\xf9\x5c\xa8\x8a\x44\x31\xc5\xe0\xa5\xd2\xb3\x49\x99\x9e\xe6\x6d\xc7\xa2\xa2\x4d\xb3\x3d\x20\x31\x07\x11\xbc\x8d\xca\x58\x13\x86\x3b\x51\x2d\x7c\x8e\x24\x0e\x69\xc7\x27\x7c\x19\x16\x54\xb0\x91\x29\x4d\x6e\x8e\x99\x33\x4e\x75\x49\xc1\x43\x73\xf0\x6d\x57\x8e\x73\x80\x5e\x53\x56\x4e\x70\xc1\x83\x48\x03\x3e\x1c\x2f\x94\x86\x67\xcf\xd6\x78\x13\x83\xba\x7a\xb3\x4c\x12\xe5\x18\x23\xa0\x52\x24\xfe\x4c\xe0\xda\xcc\x87\xbb\x3f\x66\xfc\xa1\x3b\x0b\xbb\x83\xd6\xbe\x56\xdc\x1d\x07\x93\xad\xa2\xed\xd8\x01\x4e\x64\x28\x19\xf3\x20\x86\x1a\xc2\xc7\xbc\x7b\x7b\xc8\x23\x4c\xd4\xc7\x8e\x51\x68\xe3\xae\x5e\x19\xe1\x01\xd2\xe5\x89\xd3\x46\x13\x03\x3d\xa2\x90\x2e\x96\x0c\xa2\x93\x49\x1e\x74\x68\xb5\xd4\x62\x63\xdd\xc3\x5d\x2b\x28\xc8\xf7\xb8\xd1\x53\xda\x92\x21\xa5\xd3\xc5\x00\x41\x90\xec\xba\x10\x52\xe8\xa9\xfa\xcd\xa8\x1b\x8a\x9c\x51\x76\xa0\x7d\x36\x78\xd6\x77\xb0\xce\xf8\xbd\x8c\x5e\xab\x63\xde\x45\x3c\x73\xc0\x5b\x7f\x56\x34\xdd\x11\x57\xe0\xde\x13\x23\xc5\x0c\x96\xab\xa3\xd0\xde\xac\xc0\xd9\x0c\x1c\x7b\x9e\x7a\x01\x64\x55\xde\xd8\x24\x1c\x17\xbe\x90\x45\xe2\xfd\x94\xa0\xc3\x15\x22\x17\x45\x62\xdd\xb6\x42\x42\xbb\x18\x44\xba\x3b\x56\xbe\x8f\xd8\x5e\x92\x57\x76\xbe\xcc\xe5\x09\x00\xd6\x96\x81\x0e\x08\x79\x46\x80\x21\x79\x4c\xf1\x6b\x41\x84\x3a\x03\x34\x4b\x15\xca\x8c\x3a\xb7\xca\xba\x8a\xc3\x41\x95\x70\x91\xcb\xf0\x69\x4a\x5b\xa3\x5f\x74\x74\xd1\x0c\x31\xb4\xd1\x92\x24\x31\x20\x8c\x0f\x8a\x66\x3a\xfe\x6d\x81\xe1\x7f\x38\x7e\x7e\xbc\xbe\xbe\x0e\xe2\x7d\xb7\x40\xcb\x69\x72\x39\xe0\x51\xc4\xe8\x81\x60\x59\xd0\x3d\xb1\x94\xad\xa4\x9a\x4b\x59\xfa\x5b\xd8\xd5\xc8\x1b\x12\xca\x38\x20\x43\xbe\xb7\xde\x32\x22\x3d\x8d\x7e\x39\xa5\xd9\xa7\x9b\x67\x67\x54\xec\xd2\x3f\xaf\xae\xa4\xdd\xa6\x0d\xca\x7e\x6c\x41\x19\x3a\x96\x3d\xff\x55\x91\x55\x3b\x40\x22\x8d\x0b\x3b\xe8\x95\x88\xaa\xba\x45\x95\x97\xea\xca\xe8\x94\x85\x40\x49\xfd\xcf\xb2\x90\xe3\xe7\x9b\xcb\xef\xea\x34\xbc\x8a\x1f\x68\x64\x45\xb0\xf0\x85\x2a\x30\xce\xea\xd0\x96\x29\x51\xaa\x8b\x29\x75\x3f\x63\xc4\x62\x91\xe6\x75\x1e\xd3\x3c\xbb\x61\x06\xcf\xdb\xd1\x99\x99\x34\x8a\xb4\x0c\x68\xbc\xe1\x54\xcc\xee\x1a\xd5\x54\x0f\xc1\xbe\x86\xe2\xa4\x42\xb3\xda\x7a\x7a\x16\x61\xae\x70\xda\xec\xce\x51\x70\xc8\x65\x8e\xe1\x86\xf4\xdd\xdb\x43\xe9\x81\x89\x99\xb2\x8c\xa3\x54\x0a\x9b\x24\xe5\x4a\x17\xbf\xaf\xa7\xdc\xf5\xf5\x38\x18\x0c\xae\xf5\xf8\x6e\xb6\x2f\x3d\xa5\xc9\x14\x45\x3d\x9c\xb4\xc9\xa7\x7d\xa5\xbb\xf9\x55\x88\x50\xd0\x80\xe9\x93\x5e\x9f\xb5\x2a\x44\xf3\x92\x15\x8a\x9d\xd7\xc2\x06\xa6\xed\xf5\xdf\xb7\xf7\x7a\x9f\x7b\xbd\xcf\x5f\x5b\xef\xc3\x95\x3e\xf1\xe8\x16\x37\x7f\x3e\xbd\x8f\xd4\xd6\xe8\x8a\x1f\xc6\x9c\xa4\x46\xe7\xf9\x33\x83\x8f\xd0\x61\x98\x2e\x3f\x1c\x4d\x05\x8c\xd4\x4a\xde\xab\x09\x14\xb6\xa6\xe4\xa5\xa2\xe7\xb1\xe9\xe7\x17\x5c\xe8\x0b\xb2\xa4\x2b\x4b\x0e\xea\xd1\x9a\xb5\x9d\x85\x03\x39\x6a\x97\x9e\xaf\x83\x96\xbe\x59\xed\xf2\xd5\x01\x8b\x16\xcb\x52\x3e\x5e\x4b\xf1\x05\xc7\x66\x4f\x6d\x97\x54\xe8\x08\x51\x57\xc2\x59\x71\x34\x42\xd4\x8d\x47\x1f\x7d\xb9\x42\x4c\xdc\x91\x7d\x92\x8d\x4e\x71\xbb\x46\x25\x9c\xb7\x51\x5f\xae\x68\x74\xdb\x6d\x74\xb1\x2c\x5f\xe2\xcf\xcd\xc3\x7c\x89\x3f\x57\x8d\xd1\xcc\xaa\x1f\x60\x73\x5b\x0c\xa8\x6a\x68\xfe\xb6\xac\x71\xf1\xcd\xe8\x54\xc1\x89\x89\x08\x14\x92\x03\x3e\xf4\x80\x77\x0b\x80\xcf\x2a\x76\xae\xe7\xcf\xd4\xae\xc5\x68\xa7\x13\xee\xc0\x16\xf5\xe4\x7e\x8b\xba\xdf\xa2\xfe\xda\x5b\x94\xba\x9a\xc0\xe5\xec\x46\xf7\x12\x1c\xf8\x6e\x5f\x25\x56\x44\xff\xf7\x85\xff\xf7\x5d\x82\xf8\xef\x41\x1a\xb6\x4d\x0a\x22\xcc\x91\x2d\xa0\x05\x4f\x16\x60\xe3\xaa\xf6\xc6\x59\x3a\x21\x53\x01\xa6\x85\xc2\xd1\xa1\x45\x64\x15\x01\x76\xc1\x9f\xad\x19\x17\x34\x3c\x51\xc0\xfc\xc8\x42\x81\x5b\xc8\x80\x44\x01\x72\x54\xbc\xbf\x4c\xc7\x6c\x8b\xd1\xa1\x0a\x96\x2a\xc0\x28\x2b\xce\xb1\x0d\xc4\x53\x65\x5d\xcc\x41\x91\x0e\x41\x46\x51\x2a\xb2\x99\xd7\x43\xa7\x3f\x22\x59\x0a\x21\xe0\x33\xad\xcd\x9d\x81\xd4\x79\xf3\x37\x82\xa0\x04\xdc\x3c\xeb\xa3\x07\x0f\x10\xff\x3d\x00\x9d\xe0\xf1\xa4\xd7\xdd\xfc\xdc\x65\xae\x4b\x36\xfb\xe8\x29\xea\xe0\x72\x46\x77\x0f\x08\x4c\xfa\xec\xf2\x65\x54\xcc\x3a\x28\xb4\x93\x99\x46\xb7\xa3\xa4\x04\x2d\xfe\xd3\x8f\x79\x36\x7f\xf6\x1b\xf4\xb4\xcb\xbb\xa4\x85\x15\x7a\x76\x09\x0d\xd3\x4e\x1f\xa4\xf1\x11\x2d\x27\xa3\x79\x79\x21\xd9\x38\x24\xac\x1a\xcf\x32\x1d\x27\xf8\x37\x1a\xc0\x09\x6d\xab\xa1\xeb\x3a\x4c\x65\xa7\xc5\xfc\x68\xe3\x3c\xcc\x96\x69\xab\x6b\xa6\x3b\x18\x87\xb7\x6d\x46\x42\xfa\x50\x2a\xc0\xd8\xa8\x9c\x29\xf8\x0d\xfb\x7f\x22\x1b\xd4\x26\xc3\x99\x04\x1d\xc0\xe8\xb3\xec\xde\x8b\x72\x76\xd7\x07\x84\xd6\x87\x83\x3b\x3a\x1b\x40\x00\xe0\xea\xb3\x01\x53\x7d\x30\x2e\x4e\xb0\xb7\x47\x0b\xbd\x33\x8b\x86\x7e\x2c\x6e\xd0\x05\xed\x8e\x9b\x71\x57\xe6\xff\x02\xe9\x1e\xba\x8f\x9e\x1d\xbc\xb1\x82\x91\x71\x9e\xca\xb4\x32\xec\x01\x2d\xd7\xcd\x5c\xaf\xad\xb1\xde\x0d\x98\x65\x94\x7c\x4b\xf3\xa2\x9c\x29\x6d\x50\x80\xba\x7a\xe8\xe6\x6e\xc0\x87\x39\xc5\x65\x58\xa1\xf3\x14\xbe\x4a\x07\x7a\x41\x3e\x92\x80\xeb\xe9\x8c\xc2\xe7\x51\x62\x44\x19\x1b\x58\x81\xb3\xcf\xa3\xc4\x71\x46\x22\xd3\xae\xd7\x00\x3d\x2b\x0d\x85\xfb\xf9\xbb\xc9\x60\x78\xd1\x9b\x0c\x87\x17\x6d\x39\xa0\x36\x67\x51\xca\x5d\xa2\x04\x2c\x37\x1b\x4f\x4e\x1c\xd0\x3d\x3d\x09\x36\xe5\xe4\xcb\x23\x94\x66\xcd\x69\x5c\xe1\x85\xe8\x54\x09\x54\xec\x7a\x8f\xbb\xd1\xfc\x51\xdd\xe6\xd9\x10\x7a\xe4\x73\xc6\x4f\x24\xb0\x16\x3a\xd2\xba\xc7\x0a\xf5\x6a\x58\x9e\xf4\x59\x23\x81\x2a\x8e\xcd\x59\x1e\x4d\xf1\x41\xd9\xe6\xe4\xcc\x41\x2b\x71\xe4\x83\x90\x87\xda\x1a\x2c\xb1\x75\xc7\x38\x76\x99\xc1\xc9\x72\x15\xb4\x78\x07\xc6\x9d\x3b\x36\x8c\x89\x42\x55\x0e\xc7\xca\xfc\xed\xe7\xdb\x3b\x30\xb1\xea\x9b\xe8\x99\xb1\x23\x6b\x68\x52\x64\xbc\xdd\xb0\x7c\xbd\x0d\x9c\x25\xae\xec\x5f\xe9\xe2\x45\xd7\xab\xd1\x2f\x6d\xa2\x9e\x76\x61\xff\x6d\xc6\x04\x80\x39\x98\x90\x12\xdd\xd7\xc0\x84\x46\xca\xb7\x18\x74\xb0\x56\x41\xd9\xf3\x05\x49\xd8\xe1\xad\x91\xbc\x39\x68\x0d\x8d\xbb\x10\x02\x0f\x9b\xd5\xf4\x67\xcb\x6b\x2d\xe9\xd1\x2e\xe6\x74\xab\x4e\x64\x75\x3b\xb8\x75\xcb\x89\xaa\x9b\x1b\x31\x85\xcf\xf1\x98\xcc\xa3\xa4\x1a\x15\x4a\x0a\x6c\x89\x04\x55\xa0\x82\x28\xff\xb8\x03\x36\x85\xa7\x86\xc1\x56\xc7\x4a\xae\x38\x82\x81\x7c\x5d\x3b\xe8\xe6\x15\xa4\x55\x58\xcf\x3c\x3e\x78\x4e\xa8\x2b\x8d\x49\x96\x72\x06\x57\x75\xf8\xfd\x23\x71\x9a\x9b\xe0\xe9\x1d\x1e\x63\xb2\x68\x41\xe6\x6e\x99\x36\x04\xe0\x82\xde\x96\x02\x78\x8d\xad\x07\xd8\x72\x15\x37\x72\x31\xcf\xe0\x6c\xc0\x36\x14\xc0\xc4\xa2\x3b\x12\x10\x1b\x97\x37\x3d\x20\xbd\x8b\x2e\xda\x2f\x71\xb7\x80\x1f\x11\xb5\x70\x6d\x38\x1b\xc5\x83\x47\x16\x72\x43\x4b\x37\xf5\xb6\x55\x57\x6f\xde\x4f\x7b\xa6\x7c\x6b\xcc\x37\x0e\x32\x4d\x57\x18\x87\x09\x5d\x31\x8e\x4a\xa0\xaf\x3c\x8e\x16\x9d\xaf\xee\xf1\x9d\xcb\xda\x15\x84\xc3\x8d\xbb\xea\x3a\x0a\x81\xff\xbd\x1d\xb5\x72\x6e\xd2\x51\xba\x17\xdc\xd9\x89\xc0\x8c\x90\x5e\x37\x26\x0d\xd2\x3f\x34\x3f\xc0\x4d\x28\xc6\x18\xe1\xad\xb8\xd2\x98\xc9\xa7\x22\xae\x79\xd3\xb4\x31\xe8\x81\x08\x76\x5e\x31\x85\x66\x9d\xbe\xb1\x56\x76\xe4\xd5\xab\x57\x2d\xfb\x90\x54\x52\x90\xac\x69\xa5\x96\xdf\xe3\x7c\x81\x1b\xb7\x27\x89\x01\x06\x5d\x8f\x00\x07\xa6\xa6\x17\xc5\x72\x34\x27\xe5\xcf\x59\xde\x24\x25\x29\xc0\x8a\x95\xee\xcb\xaf\xbf\xfa\x6e\xd1\x2a\x87\xaa\xdc\x8a\x2b\xda\xb3\x8e\x38\xce\xf5\xb7\x52\xfc\x04\x7a\x9a\x54\x74\x18\xa9\x87\x59\x6c\x82\xc1\x12\x36\x52\x40\xf6\xb7\x0a\xf1\x03\x98\x5b\xd2\x16\x1f\x5c\x08\x75\x94\x30\xf2\xb4\x82\x55\xe9\x52\x30\xad\x02\xe0\xb2\x53\x55\xb6\xd5\xa8\x69\xd0\xab\x31\x12\x95\xe8\x8a\x01\x5a\x9e\xb9\x5f\x99\x85\x2a\x4b\x68\xcd\x9b\x2b\xda\xc9\x78\xf5\xea\x95\x0b\xcc\xa8\x5f\xab\x52\x12\xa6\x31\x68\x9a\x00\xdf\xdc\xc2\x81\xc5\x64\x93\xca\xee\x3a\x1f\xcd\x8a\x8e\x28\xe9\x4a\xad\xab\x69\x3a\x2a\x17\x2e\x49\x47\x51\xa1\x2b\x2a\x78\x07\x80\x51\xf2\x75\xcb\x61\x24\xc8\x75\x3f\x58\xa1\x8d\x39\x49\x4d\xeb\x16\xa7\x05\x0e\x71\xc3\xfa\x67\x51\x31\xcb\xa3\xb2\x76\x0c\x15\x30\xad\x76\x86\xd5\x7b\x24\x6e\x67\x6b\x3a\xe4\x07\x69\x3e\x67\xf0\xeb\x60\xf3\x70\xb1\x7a\x0f\xa7\x51\xf1\x36\x27\xe3\x5a\x9c\x55\xc0\xdc\x58\x09\xbc\x7a\x2f\x79\xd8\xa1\xa2\xae\x97\x12\xe6\x86\x6d\x8c\xb4\x2b\xb4\x9a\x66\xaa\xc1\xbe\x12\x0d\x89\x98\x0c\xff\x60\xb6\x36\x75\x7d\xb3\x41\xb5\x16\x75\x16\x62\x5c\xbb\x0c\xc6\xca\x8e\x41\xbb\xd2\x1c\x11\xe3\xc5\x42\x34\x2e\xb3\x5c\x88\x3f\xc2\xf2\x01\xcc\x88\x03\x44\x61\x0d\x5b\x62\x0e\xed\x6b\x6c\x22\x2c\x1d\x9c\xa7\xa8\x81\xf6\xdc\x8e\x41\x1d\xe6\x18\x2c\x95\xe0\xf1\xd8\xa1\x65\x4e\xaf\xc5\x29\x62\xd6\x13\xaa\x1e\x8a\x0a\x11\x6e\xde\xba\xb1\x0b\x84\x39\xc6\x00\x97\xb3\x5e\x3f\x70\x49\xf6\x55\x36\xd5\x64\xe3\x76\x5d\xf2\x0d\x54\x19\x62\xd4\xbb\xd0\x17\xd8\xef\xf1\x02\x83\x69\x92\x8d\xa2\x64\x40\x91\x3a\x88\xdc\x64\x1e\xf3\xcc\xd7\x24\x19\x47\x8b\x37\x37\x6d\x96\x16\x76\x1a\x65\x89\x75\x4d\x6a\x56\x29\xaa\x41\xfb\xe9\x83\x1e\x53\x4a\x14\xe8\xf9\xa7\xa7\xc9\x1b\xd5\x8b\x72\xa6\xec\xf9\x2c\xc3\x9c\x4e\xb8\xf5\x24\xe8\x38\x06\x42\xdc\x40\x5d\x59\xe6\x74\xc2\xed\x6f\x20\x81\xcd\x69\x27\xdc\xfe\x8e\x7d\x4a\x5a\xe8\x84\x3b\xac\x08\x19\x45\x69\x27\xdc\xd9\x09\x4c\xf3\x41\xf8\xe4\x48\xea\x84\xbb\xbb\xf0\x2d\xcc\x88\x3a\xe1\x2e\xab\x9e\x73\xf6\x4e\xb8\xcb\xba\x25\x2e\x7b\x3b\xe1\x2e\x6d\x50\x18\x01\x75\xc2\xdd\x9d\xeb\xb3\x60\xe7\xbb\x7b\x7b\xc4\x7b\x7b\xc4\xff\x8f\xbd\x77\xdf\x6f\xdb\x66\x16\x45\xff\x4e\x9e\x02\xed\x3e\x5f\x23\xd5\xb4\xac\xbb\x1d\x25\xee\x5a\x8e\x6c\xc7\x5e\x89\x63\x6f\xdb\x69\xfb\x6d\xff\xdc\xfc\x28\x11\xb2\xd8\x48\xa4\x16\x49\xf9\xd2\xc6\xfb\x7d\xce\x73\x9c\x17\x3b\x3f\x0c\x2e\xc4\x95\xa2\x7c\x49\xd3\x2e\xfb\x5b\xab\x11\x49\x60\x30\x00\x06\x83\xc1\x60\x2e\xff\x6c\x7b\x44\x97\x31\xe2\xbd\x6d\xe6\xcb\x9b\x09\x96\xb0\x01\x84\x72\x1f\x70\xf6\x98\x26\xf6\xf0\x76\xb1\xc9\x4a\x6e\x5c\x7f\x17\x9b\x95\x12\x26\xf5\x6b\x6b\x6b\x79\x4c\x1a\x5b\x9c\x1b\x96\xb0\x91\xb0\x78\x00\x87\xb3\x31\xf2\x67\xa1\x84\xfb\x23\x1d\x48\xcc\x64\xf4\x9a\xc0\xa3\x66\xac\xbf\xab\x70\x85\x71\xa2\xeb\xc6\x8d\x56\x5c\x85\x96\x10\xf8\x64\xf1\xcb\xd8\xd4\x3e\xe0\xcc\xb2\xa9\xa9\x9b\x97\xbc\xbb\xdc\x9e\x7b\xed\xfa\xd3\x6e\xf1\xb4\x5b\xfc\xb3\x77\x8b\x6f\xd4\x7a\xfd\xe1\x0c\xcd\x4b\xda\xc1\xe7\xa6\x9c\x47\x38\x49\xe3\xc8\x9f\x3c\xd9\x73\x3e\xb6\x3d\xe7\x6d\x39\x0b\xbf\x08\x5f\xe5\x66\x83\x45\xfa\xe1\xbc\xa0\xa9\x22\x9e\xb1\x59\xfd\x64\x2d\x74\x8f\x1b\xca\x70\x4a\x36\x82\x63\xff\xea\x1d\x5e\x74\x73\x21\x17\x7d\xe1\x3d\x7f\xf6\xcc\x86\x9f\xb5\x50\x81\xea\xbc\xfc\x8d\x9c\xbd\x3d\xe5\xa3\x64\xc8\x47\x5f\x94\xbc\xaf\x2e\x7d\x2d\x87\x87\xc7\x78\x18\x5f\xd2\x80\x51\x45\xf7\x58\xbc\x9c\x13\x6f\xb3\x44\x81\x1b\xdf\x3c\x9a\xc4\xc3\xcf\xe5\xa8\x48\x29\x5b\x40\x48\xae\x72\x65\x8c\x21\xcb\x8d\x9f\x73\x14\xd3\x71\x7c\x45\xb7\x84\xc5\x44\xa7\x94\x2d\xe8\x8e\xab\xdc\x83\xdc\xd2\x7e\x9d\xdb\xfd\xbc\x2f\x0b\xaf\xf8\x97\xe9\x8d\xed\x6a\xdc\x3d\x35\x38\x0a\x8e\x92\xf0\xd2\x1f\xde\xf4\xaf\x97\xb0\xa5\x72\xd6\x5b\xd0\xcd\x45\x75\x1e\xbd\xc3\xe5\x17\x56\xb9\x65\x55\xbc\xa8\x96\xb9\xa0\xd6\xa9\xd0\x82\xfc\x7c\x06\x51\x6d\xca\xf1\x05\xb9\x6c\x11\x5f\x70\x94\xfb\x0a\x7c\xe1\x02\x47\xc7\x61\x74\x71\x12\x5e\x44\x2c\x89\x55\xf1\x75\xaa\x52\xba\xa0\x4b\xee\x92\xe5\x2d\xbf\x9d\x88\xdb\x8e\xad\x6c\xf4\x24\xa0\xd2\xae\x98\xbf\x55\x58\xb0\x74\x35\xc9\xb7\x06\xd7\x55\xa2\xfb\xc6\xd1\xb5\xa2\xe4\xdb\x42\x65\x2c\xf2\x0f\x56\x54\x14\x62\x90\xda\x91\x99\xad\x7c\x6c\x7d\xbc\x63\xfe\xd6\x82\xdb\x9d\x7c\x01\xca\x85\x1d\xa7\x6b\xf3\x78\xcd\xa5\xe6\xfc\x8c\x6d\xd1\xf4\x6a\xca\x5d\xfd\xb8\xdd\x78\x3a\x6e\x3f\x1d\xb7\xff\xd9\xc7\x6d\x76\xd6\x4e\xc7\x0e\xe5\xec\x82\x53\x34\x4e\xf0\x7c\x4a\x40\xff\xb4\x40\x97\x3b\x8c\x13\x5c\x0b\x63\xf5\xb8\xbd\x5e\x3a\xfe\x49\x49\x7f\xe9\x45\x6e\xd7\x50\xe8\x64\x3c\x7e\x74\x25\xef\xb7\x73\xac\x26\xdc\xf1\x64\xac\x5c\x52\xe2\x2b\x16\x3b\x7e\xf7\x6b\xdc\xcb\xa6\xe3\xc5\xf7\xb2\xe9\x18\xee\x65\xe9\x96\xbc\xcc\xf5\x6b\xd1\x71\xdd\xbd\x39\x19\x02\x88\xb4\x35\x5d\x5a\x2f\xdc\x99\x00\x92\x8e\xc7\x9f\xec\x05\x54\xdb\x2e\x64\x51\x49\x17\x35\x1a\x46\xa3\xd8\xdd\xa2\xe5\xeb\xfd\x9a\x4b\x71\x76\xe0\x5f\x33\x22\x38\x09\xff\xd0\x6d\x3c\xa4\xb6\x17\x15\x55\x8d\xf7\xee\x82\x48\x18\x1d\xc5\xbf\x14\x23\x60\x2b\x72\xbf\x86\xa7\x7e\xf2\xf9\x34\x99\xa7\x19\x0e\x8e\xb0\x61\xd3\x21\x35\x5f\x5c\xf0\x7e\x48\x44\x98\x48\x8f\x47\x7e\x58\xd0\xbe\xb3\xcc\xfd\x28\xc0\x0f\xa8\x58\x99\x61\xf5\x70\xad\xb5\x5e\x54\xec\x7e\x7d\xa7\xb9\xfb\x16\x76\xbf\xa8\xd8\xfd\x10\x18\xfb\xe9\xc2\xd6\x9d\x65\xee\xd7\xf4\x05\xce\x6c\x8a\x0d\xad\xf1\x82\x52\xf7\x6f\xbe\xc4\xdc\x17\x15\xbb\x37\xdd\x9f\xdc\x4c\x0b\x1b\x77\x15\xb9\x37\xd5\x2f\x6a\xd8\x55\xe4\xbe\x43\x4e\xe4\xb8\x0c\x53\xd0\xbb\x49\x3c\x3d\xf2\xd3\xf4\x2a\x4e\x82\xa2\xf1\x2f\x59\xe7\xde\xeb\x60\xd1\x98\xb8\x8a\xdc\x9b\x0c\x17\x35\xec\x2a\xf2\x10\xac\x67\x51\xdb\x05\xa5\xec\xcd\x8b\x87\xb5\x35\x94\xce\x07\x70\x81\x4e\x33\x93\xcf\xa3\xfc\x79\x1a\xa6\x69\x18\x5d\x3c\x2f\x8d\xed\x2c\x4e\xf5\x1b\x68\x09\x4b\xcb\x57\x8b\x66\x0a\x15\x5f\x1f\xa0\xc5\x97\xd5\x27\xe3\xb1\x94\x07\x50\x33\xa1\x52\x4e\xd1\x9a\x81\x53\xbb\xf9\x74\x86\x7e\x3a\x43\xff\xb3\xcf\xd0\xf9\x95\xf5\xe0\x8f\x3f\xb4\x2b\xeb\xad\x09\xbe\x46\x6f\x70\x82\x2f\xd2\x3f\xfc\xf4\x8f\x10\xbd\xf6\x27\xf8\xfa\x3f\x93\x6c\x94\xd6\xc6\x73\xf5\x38\xdc\x65\x41\x89\x8f\xf1\x08\x27\x38\x1a\xe2\x1e\x22\xed\xa7\xbd\xb5\xb5\x8b\x30\x1b\xcf\x07\xb5\x61\x3c\x5d\xbb\xf2\xa3\xe1\xd8\x0f\xa3\xb5\x8b\x78\x55\xfc\x1e\x4c\xe2\xc1\x5a\x7a\xe5\x27\xd3\xb5\x30\xca\x70\x12\xf9\x93\x35\xd2\x25\x7c\x9d\xf1\x7f\x6b\x17\xf1\xff\x7a\xdf\x6a\x3d\xf2\x0d\x77\x7e\x6d\x7d\x42\xb0\xf9\x87\x1f\xae\xe1\xc7\xdf\xe2\xce\x9a\x1a\xb0\xe3\xec\x2a\x4e\x3e\x1f\x63\x88\x38\x5d\xa4\x8a\xd7\x8b\x9b\xba\xf8\xc1\x1f\x7f\x7c\x2a\x28\x75\x1f\x17\xdb\x9b\x68\xb8\x13\xf9\x83\x09\x5e\x84\xa5\x54\xd2\x8e\xa0\xbd\xc0\x7d\x70\xbb\xf2\x67\x25\x71\xcb\x4b\x3a\x70\xb3\x16\xb8\x07\x6e\x41\x7c\x15\xb1\x60\xe2\x45\x88\xf1\x62\x76\xac\x2c\x5f\xcb\x7b\x8d\x3b\x10\x9b\xcf\x4a\xa0\x45\x0b\xd9\x91\x32\xbe\xdd\x1b\xa5\x04\x67\x49\x88\x2f\x17\x05\x72\xe1\xc5\xec\x68\x59\xbe\xde\x87\xb4\x32\xb2\xdb\x2d\x20\x2a\x52\xc6\x41\x4e\xda\xa7\x7b\x0f\xd1\x05\x2e\x11\x95\xc0\x8e\x8b\xfa\xe1\x1e\x63\x42\x93\xb0\x2c\x08\x75\x6c\xc7\x41\xfd\x70\xef\xd1\x60\x79\x97\x8a\x91\xa1\x85\xec\xf8\x18\xdf\x38\x4a\xed\x25\x6e\x2a\xcb\xdd\x57\xea\x6c\x59\xba\xe6\xcb\xf9\xa1\xf4\x32\x67\x44\xf9\x4b\xce\x07\xe4\x9b\x43\xf5\x99\x53\xbf\x04\x88\x90\xa0\x7c\x31\x29\x5d\x30\xce\xe6\xd2\x83\x24\x8b\x3f\xea\x35\xe3\x38\xbc\x74\xba\xb8\x91\x39\x81\xef\xce\x33\x64\x31\x6c\x8b\x52\x56\x81\x0d\xdf\x1d\xc7\x2b\xcb\xf9\x8a\x08\x4b\xb6\x98\xd5\xd6\x7b\xc9\xd6\xd3\x99\xea\xe9\x4c\xf5\xcf\x3e\x53\xb1\x03\x15\xbf\x20\xfa\xba\xc9\x16\xee\xe2\x1f\xc1\x9d\x1c\xfd\x59\xc8\x85\x71\x9a\xa9\x33\x1b\x17\x19\x92\xd2\xeb\xb2\xc2\xe0\xb2\xbc\x74\x76\x33\x23\xf2\x01\x0b\x24\xfb\xea\xb9\xc4\xc0\xc3\x6c\x38\xae\x90\xef\x7a\x8a\xa0\xa1\x9f\x62\xf4\x82\x50\x7c\x9a\xbd\xe8\x29\x9f\x60\xb2\x92\x8b\xb4\x96\x8e\xc3\x51\x56\xd1\xf2\x02\x21\x23\xc7\x67\xdd\x2c\xc0\x58\x32\x78\xa1\x46\xf8\x8a\x06\x92\xa3\x17\xb2\xaf\x2c\x68\xcc\x70\x14\x84\xd1\xc5\xa3\xe3\x71\x44\xdb\x91\xad\xc6\x6c\x48\xb1\x08\xa0\x26\x36\x1a\x38\xa3\x32\xcd\x93\x74\x2b\x49\x07\xa2\xd4\x62\x83\x60\x06\x4d\x97\x11\x14\x52\xb0\xc8\x4e\x16\xa9\x3a\x0a\xa3\x34\xf3\x27\x93\x52\x2d\x6b\xa5\xed\x21\x2b\xdc\x85\x0a\xf0\xb8\xc0\xd9\xfb\xf8\xa2\x44\x5c\x40\x52\xca\x19\x2a\x83\xb6\xa8\x15\x29\x68\x75\x16\x2f\x0c\xa7\x43\x8a\x2c\x68\xaf\x3f\xf6\xa3\x0b\x5c\xa2\x49\x9b\xf0\x41\x41\xc8\xc6\x5f\xca\xe8\x29\x82\x10\xe9\x98\xd4\x48\x3c\x99\xc8\xf2\xc0\xd2\xfc\x26\x1d\x8f\x6b\xc0\x1a\x0d\x76\x93\x8e\x4d\x76\xe3\x16\x9f\x16\xdc\xd2\x18\x64\x80\x8c\x5b\x1a\xc5\x92\xe0\x41\xd5\xf4\x6e\x62\x44\x36\x4d\xfd\xe3\x21\x62\x92\x2e\x32\xae\x29\x68\xb3\x0c\x07\xbd\xe8\xfd\x9a\xd7\xc8\xf8\x01\xda\x96\x49\xcf\x90\x44\x29\x0e\x38\x1b\xf7\xc8\x7f\x28\xb0\x74\x3c\xee\x91\xff\x50\xe1\xd5\x96\x57\xa5\xdd\x7e\x12\x49\x9f\x44\xd2\x7f\xb8\x48\x9a\xeb\xf9\x79\xa8\x84\x07\x4a\xfa\x4e\xc3\x3c\x1c\xe3\x0b\x32\xcf\x7e\xb2\x35\x08\x1d\xe9\x45\xd2\xb5\xb7\x6a\xd1\xda\xef\x69\x2c\x52\x78\x84\x43\x7f\x26\x03\x71\xc1\xd8\xef\x6f\x1d\x99\x10\x24\x4c\x58\x3c\x09\x66\xec\x8d\x36\xd1\x8b\xfa\xf5\xb0\x1b\xbc\x0c\x9a\xc3\xa0\xdd\x7e\xe9\xaf\x77\xda\xc3\xf6\xcb\x76\xb3\xdb\xc6\x8d\x8d\xfa\xcb\x61\xa7\x8e\x5b\xed\xa0\xdb\xee\x74\x9b\x83\x17\x39\x2e\x36\x30\x7e\xc3\x6f\x34\x1a\x83\x61\x7d\xbd\x3d\x7c\x39\x1c\xf9\xeb\x1b\x8d\x51\x7d\xd8\xda\xc0\xdd\xd6\x20\xe8\x34\x86\x2f\x1b\x83\x0d\x7f\x54\xaf\xbf\x70\xf3\x26\x8a\x63\x4f\x92\x74\xfd\x41\xd8\xb3\x0c\x62\xce\x09\x59\x34\x8b\x9e\xb5\x7f\x74\xa7\xa7\x85\x09\xda\x06\x64\x7d\x5c\x2d\x70\xcd\xee\x52\xa8\x0a\xc7\x2c\x9e\xc5\xef\x7b\x0d\xef\xfb\x05\xf3\xf4\x7d\xaf\x49\x98\x6d\xe7\x89\xd9\x3e\x31\xdb\x7f\x36\xb3\xcd\x79\x2d\x57\x7e\x69\xcc\xb6\xc8\x30\x79\x94\xc4\x7f\xe0\xa9\x1f\xd5\x02\xfc\xd3\x83\x30\x68\x5b\xa4\x09\x2d\xcc\x84\x7e\x41\x6a\x98\xd4\x2a\xb7\xa0\x2c\x4d\x33\xfb\x04\x8f\x52\xda\x10\xaa\x48\x94\xbe\xd3\x17\x4a\x62\x11\xad\x44\xaa\x97\xd0\xb3\x92\xe7\x45\xb5\x2f\x52\x1d\x55\xff\x2c\x55\x51\x3f\x48\x35\x8c\xcb\xdc\x68\x3e\x99\x50\xc9\x92\x8f\x85\x9c\xc4\x56\xbf\xdc\x54\x33\xac\x4f\x95\x21\x32\x40\x27\xd3\xb2\x49\xe3\x1d\xa9\xfa\xef\x9d\xa9\xdf\xd2\xa5\x82\x5c\xfd\xb6\x08\x4c\xda\x08\xff\x87\xed\x2d\xad\x0b\x59\xf3\xbf\x91\x2c\xf9\xce\x7e\x3f\x50\x9e\xfc\xef\x36\x29\xe1\x68\xaf\xc8\x86\x32\x0a\x23\x1c\xdc\x27\x91\x3e\x4f\x00\x9d\xc5\x88\x65\x9f\xcf\xb3\xd2\x43\x56\x7d\x71\xd8\x16\x79\x95\x6b\xe8\x80\x6c\x6c\x21\x4e\x19\x25\xc1\x30\x69\x63\xa9\xa5\xb2\xb7\xe7\xdc\xa7\xf0\x94\x71\xdd\x65\x23\xf5\xe5\xc3\x7c\x32\xb9\x95\x8c\xdd\xc3\x11\xc2\xd7\x61\x0a\xc5\xad\x43\xae\xb5\xe8\xd4\x17\x86\xa3\x3c\x11\x15\x6f\x8d\xa6\xa2\x02\x35\x1b\xcb\xfb\xbe\x8a\x1a\xe7\x55\x4b\x52\x1d\x28\x33\x8b\x67\x95\x2a\xa4\x9f\x67\xf7\x5e\x84\xff\xc3\x7a\x82\xd1\xfa\x4e\x16\x6e\xd4\xe1\xa6\xf6\x0d\x39\x66\x59\x6c\x27\x45\xd5\x0e\xc2\x45\x8c\xec\x15\xef\x85\x93\x1a\xcb\x67\xe1\x87\x3a\x42\x94\x44\x3c\xcf\x50\x9e\x95\xff\x2d\xce\x2a\xd2\xe9\x1c\x47\xf3\x29\x4e\xfc\xc1\x04\xf7\x50\x96\xcc\xb1\xa9\xfa\xf3\xa7\x70\x61\xe5\xce\xb8\x2f\xa5\xe5\x87\xc2\xa0\xbc\x45\x52\x2a\xfe\x74\x41\x2e\xfe\x54\x4b\xc6\x9f\x3a\xb2\xf1\xeb\x45\x5e\x29\x1a\x31\xd1\x7c\xe3\x9c\xe7\x34\x82\x7f\xec\x49\x9a\xe2\xc1\xef\x1e\x94\xf7\xe8\x90\xb1\xbe\x10\xf8\x7e\x7a\x13\x0d\xdf\xc2\x7e\x43\x44\x5e\xe8\x42\xf5\x9c\x0f\x01\x0c\xe2\x16\x2b\x52\x91\xdc\x34\xb4\x6a\xca\x24\x01\x08\x95\x65\xc0\xed\x32\x5a\x01\x1c\x6a\xc3\xb1\x9f\x6c\x65\x95\x7a\xb5\x96\xc5\x1f\x67\x33\x9c\xf4\xfd\x14\x57\xaa\xfc\x33\x24\x47\xaf\x34\xaa\xce\x8d\x87\xcf\xac\x3b\x81\x70\xbe\x71\xe7\xd9\x90\x79\x60\x43\x5e\xe3\x82\x74\xc8\x5c\x31\x42\x40\x51\xf2\xe4\x8b\xb7\xb6\x4c\xf9\xaa\xa2\x87\x2e\x89\x5e\x0e\x88\x6e\xf7\x92\xc6\x26\xcf\xa8\x5f\xd4\x41\x3e\xea\xcb\xf5\x32\xbf\xeb\x77\xc7\xf2\x43\xb9\x99\x93\xb5\x43\xd0\xf2\xb2\xbd\x92\x73\xf7\xe0\x24\xf1\x90\xba\x75\xf0\x3f\x8e\x0b\x2d\x63\x1f\x6c\x56\x53\xba\x3b\xb8\xcd\x87\x8c\x2d\x72\x29\x17\xbf\x2c\x5f\xb8\x52\xe3\x5f\x60\xd7\x76\x92\xef\xbe\xc3\x04\x13\x59\x71\x36\x4f\x30\xfa\xaf\x93\xc3\x0f\xc7\x47\x7d\xc4\x5b\xb9\x1a\x87\xc3\x31\x1c\x9e\xf8\x0e\x14\x46\x68\x00\x3a\x5b\x56\x44\xe3\x88\xf9\x5b\xc1\xf7\x6a\xb5\xda\x2d\xd3\xe0\xd9\xf6\x66\x44\x8e\x84\xc9\x6c\x28\x55\xb5\x72\xc7\xbc\xe3\x0e\xb2\xf0\x6f\x98\x81\x8e\x6e\x36\xd7\x93\xe5\x51\x53\x49\x7e\x76\xae\xaa\xd7\xc9\x34\xb1\x2a\xda\x66\x55\x81\x3d\x51\x16\x05\xc9\x92\xad\x91\x4a\x15\xb1\x4f\x56\xab\xf2\x94\x31\xac\xd8\x44\xf3\x59\x93\xa7\xdd\x35\x75\xac\xa6\x43\xc1\xc9\x07\x48\x3a\x98\x6b\xa1\xb7\xc8\x11\xbb\xfb\x74\xc4\x7e\x3a\x62\xff\xb3\x8f\xd8\x92\x3e\x93\x71\x88\x29\x63\xe9\xea\x49\xfb\xbf\xf0\x68\x94\xe0\x1b\xf4\x4b\x38\x19\x7e\xc6\xe8\xf5\xef\x78\x34\x72\x05\xdd\x5a\x2a\x42\xd7\x81\x9f\x90\x23\xfc\xa1\x1f\x0d\xb1\x0f\x65\x6d\xb1\xb9\xee\x10\xce\x8b\x55\x79\xeb\x5f\xa2\x5f\xe2\x38\x40\xaf\x2f\x9c\x87\xfc\x76\x7e\xc8\xff\x2f\xc6\x4d\x15\xe7\x61\xc6\x62\x8b\x32\x53\x5b\xe2\x4d\xea\xc9\xa4\x6d\x99\xa4\x71\x92\xc4\x5a\x0c\xb0\x35\xfa\x8e\xda\x20\xd0\x6d\x67\x3f\x7b\x91\x92\x8d\x71\x16\x47\x69\x38\x98\x50\x02\x9b\xf9\xe0\x44\x82\xa6\xec\xce\x87\xec\x45\xb3\x24\xbe\x0c\x03\x9c\xa4\xa2\x96\x3f\x49\x63\xb3\x6a\x3c\x99\x90\xaa\x84\xda\xb8\xff\x36\x8a\xe2\x80\x7e\x0d\xa3\x61\x3c\x95\x21\x13\x60\x2c\x35\x08\xbd\x72\xcd\xc2\x29\x26\x8b\x2d\x4c\x51\x03\xa5\x78\x18\x47\x01\xec\x8e\x61\x74\x31\xc1\x59\x1c\xc1\x70\x92\xee\x15\x1c\xf4\x39\xaa\xca\x71\x9f\xbf\x44\x9b\xa2\x2b\x92\x9e\x81\xb4\x0d\x1a\xe0\x5b\xe9\x25\xc7\x45\xd6\x3a\x38\x0f\x7f\x44\x42\x19\x27\x71\x14\xcf\xd3\xc9\x0d\xc4\xd5\x70\xec\xc3\xe4\x93\xe5\x3c\x82\x02\x3f\xf3\x9d\x27\x64\xb5\xb7\x8a\xca\x23\x0a\x94\xce\x13\x30\xf2\x49\xed\x3b\xa5\xf7\x4a\x8e\xd2\x38\x4a\x63\xb2\x75\x11\xa2\xa8\x50\xd2\xa8\xed\x47\x97\xfe\x24\x0c\x8e\x58\xf9\x8a\x2c\xf3\x70\x2f\x6c\x18\x0c\x49\xc2\x57\xf7\x78\x46\xe6\xb5\x2c\x3e\xa2\xef\x00\xa5\x1a\xed\xbd\x07\xdd\x64\xc6\x16\xd2\xf9\x85\x9d\xca\x37\xd5\xb9\xa2\xc2\x2c\x03\xcd\xaf\xca\xa1\x53\xbc\x91\x30\xfd\x99\xa0\x7b\x4c\xa9\x10\x0b\x41\x4d\xea\x66\x36\x4e\xe2\x2b\xa4\x76\x4f\x2f\xaf\x74\x87\x75\x93\x7e\xaa\x95\x3a\xf9\xfb\x4b\xcd\x3e\x48\xb3\x85\x24\xa0\x9f\x4b\x85\xf4\xb3\x98\x18\x00\xb8\x41\x11\x52\x0c\xec\x52\xb4\xc1\x33\xe0\x4a\xb2\x71\x11\x75\x3c\x0c\x21\x98\x73\x4f\xe5\x7e\x06\xb2\x84\x3c\x4f\x3a\x85\x93\x44\x17\xf1\x2d\xbd\xa9\xea\xe6\x36\xe4\x4f\x81\xb3\x0c\x8d\x2d\x1e\x32\xa3\xb6\xdc\xbe\x21\xe4\xb2\xa4\xcd\x0a\x09\xea\x31\x76\xdd\xc7\x06\x1b\x35\x16\x9d\x0c\x48\x81\x37\xe4\xbb\x45\xc9\x44\xeb\x3d\x04\x61\x42\x0b\xdf\x18\x61\x02\x4e\x32\x75\x72\x26\x73\x37\x52\x4c\x1f\x80\x16\x55\x1a\xe4\x7a\x36\x98\x8d\x0a\x6f\xe5\x5e\xa4\x97\x2e\xa2\x3d\xa5\x43\x82\xe8\xd0\x82\xed\x0f\x67\x62\x5f\x25\xd2\x26\x3f\x13\x32\x91\xcf\xa0\xb8\x8c\x4f\x95\x5d\x35\x57\x48\x4b\xa2\xae\xba\xeb\x3b\xb7\xfb\x45\x3b\x77\x46\x8e\x54\x4c\x70\xd1\x11\x25\xdf\x8e\xc4\xa7\x85\x1c\x9b\xa6\xf0\xb8\x05\x68\xfb\xc1\xc2\x25\x63\xf9\x2a\xcc\x92\x38\x26\x59\x1c\xc4\x68\x38\xc1\x7e\x34\x9f\xa1\x08\xe0\x93\x01\x16\xc7\xf6\xa2\xa1\x92\xb0\xb7\xac\x3c\x8a\xa4\x1c\x10\x45\x34\xae\x8e\x25\x11\x8e\xce\x68\xe9\x73\x22\x24\x91\xea\x3d\x44\x81\x84\x41\xcf\x00\xd4\xb3\x81\xec\xe5\x3f\x6f\x79\x6a\xfb\xb5\x35\x7d\xf4\x15\x06\xc0\x04\x30\x75\x37\x67\x08\x55\xc4\x0a\x5f\x30\xb9\xf1\x4c\x08\xa5\x44\x04\x65\x66\xb4\x70\xba\xb9\x08\xc9\x91\x2e\xd4\x75\xc7\xa4\x8e\x65\xce\x8d\xb9\x2d\x1c\x79\x01\x42\x25\x52\xa8\xcb\x3b\x44\x2d\xcb\x2c\x83\xfc\x4a\x1a\x9e\x1c\x7f\x36\x3a\x15\xa6\x51\xfd\x8c\x6f\xd2\x4a\x5e\xb7\xca\xb5\xbc\x9b\x9b\x9b\xa8\x8e\x7e\xf8\x01\xb9\xc6\x90\x10\x53\x72\x4a\xdf\x57\x94\x42\xaf\xd4\x71\xd6\x05\xe0\x82\xf1\xce\x77\x9f\x04\x13\x5e\x40\xe4\x7f\x3e\xec\x53\x3c\x1c\xfb\x51\x98\x4e\xf9\x31\xb4\x98\x39\x00\x80\xe2\xe1\xa5\x6d\xc8\x03\xfb\x19\xe3\x99\x48\x03\xc2\x3b\xbb\xf6\xe3\xef\xe9\x38\x8c\x48\x43\xd7\xc3\x78\x3a\x9b\xe0\xeb\x30\xbb\xe9\x75\xe0\x48\x46\x0a\x10\x82\xa8\x90\xcd\xe1\x33\xbe\xa1\x9a\x02\x31\x9a\xd2\x78\xad\xad\xa1\x04\x4f\xe3\x4b\x8c\xfc\xc9\x04\x7a\x95\x7a\x08\x5f\x0f\xf1\x2c\x03\xb1\x9f\xbd\x92\xcb\x67\x63\x7c\x83\x22\x4c\x47\x64\x80\x59\xfd\x80\xf4\x78\xee\x4f\x26\x37\x68\x70\x03\x43\x46\x86\x87\x65\xf4\x00\x9a\xf9\x85\x6c\x48\x61\x74\x51\xa9\x4a\xfb\x40\xe5\x3b\xa5\x77\xe8\xcb\x17\x82\x6f\x2d\x8c\x02\x7c\x7d\x38\xaa\x80\x9b\x22\x21\xb6\x4f\x2f\xaa\x30\xf9\xab\x0d\x7d\x83\x90\x28\xec\x33\xbe\x39\xaf\x89\x95\xa8\x9b\x43\x9b\x14\x49\xca\x1b\xa6\xc9\x7f\x63\xf2\x84\x53\x26\x99\xf7\x21\xb5\xcd\x45\x71\x54\x86\x27\x50\x93\xda\x22\x9a\x64\x16\xc3\xa6\x0a\xd4\x41\x85\xa8\x4b\xc0\x59\x3a\x93\xe2\x4c\xe9\x3d\x01\x2c\xa9\x22\x3d\x34\xac\xed\x9c\xee\x7d\x3a\x3a\x7c\xff\x7e\xff\xc3\xdb\x4f\xa7\xfb\x07\x3b\x87\x1f\x4f\xe5\xe3\x51\x99\x19\x30\x85\x2a\x45\x62\x7a\x94\xa3\xa3\x29\x93\x11\xbc\x58\xe4\xce\xb3\xf3\x57\xea\xfb\x7d\x70\x37\xe6\xaf\xcb\x2d\x55\x01\xb0\x36\x9b\xa7\xe3\x8a\x4e\xf7\x4c\xc4\x53\x4a\xef\x07\x29\x2d\xfc\x19\xdf\x54\x8d\x31\xc8\x01\x2e\x31\x78\xa5\xc4\x4d\x01\x99\x35\xca\x97\xd4\xd4\x9f\x29\x4c\x32\x04\xb2\x05\x86\x02\x24\x46\x48\x53\x1d\xa6\x03\x7f\x26\xa9\x2e\x24\xbd\xb6\xea\x29\x4e\x05\x57\xe0\x1a\xd5\x3f\xf5\x31\x38\xf0\x67\x67\x50\x2d\x84\x2d\x9e\x8f\xcc\x19\x14\x3f\x97\x3c\xd2\x45\xe3\x8a\xdf\x3c\x5a\x5a\x66\x8e\x55\xa9\x59\x09\x6f\x72\x7a\xb8\x7d\xd8\xe3\x44\x86\x26\xf1\xc5\x7f\xe8\x52\x75\xec\x90\xab\xef\x2b\x49\x97\x50\x16\xa4\xd6\xa3\x23\xfb\x56\x9b\xfa\xb3\x8a\xcb\x58\x81\xff\x81\xfd\x62\x90\x8f\x32\x19\x7b\x76\xd4\x0b\x03\xd9\xf1\x46\x50\xc4\x67\x8c\xd2\x79\x02\x7a\x62\xce\xac\xc2\x14\xa5\x59\x48\xe8\x81\x72\x72\x1c\x20\x7f\x04\x0e\x42\x49\x12\x5e\xfa\x13\x6d\xaf\x55\x60\x92\x01\x01\xb7\x7f\xba\x34\xc2\xe0\x5c\x47\x31\xef\x52\x6d\x98\xdb\x03\xa8\x75\xc4\x17\xa7\xc3\x0c\xd7\x9d\xc8\x9f\x6e\x11\x9e\x30\x3d\xb3\xa5\xc6\xc8\x9f\xa4\x58\xbe\x65\x63\x6e\x4f\x0b\xc7\x94\xd5\xff\xee\x3b\xd6\x26\xba\x03\x0c\x32\x2f\x30\xe3\xd2\xa2\x75\x1c\xfe\x5f\x19\xe3\xf9\x1d\xd4\x2c\x31\x8e\xe5\x15\x03\x48\xa1\x30\xa9\x97\x50\x51\x1d\x25\x6d\xb1\xbb\x87\x49\xc5\xc5\xad\x67\x40\xf2\x25\xa7\x2b\x71\xda\xb1\x1e\x54\x43\xbd\xf1\xd2\x32\xa8\x99\x19\x68\x98\x42\xfa\xfb\x5e\x13\x42\xfb\x30\x65\xf8\xf7\xbd\x16\xb8\xa1\xae\x97\xb9\x23\x63\x31\x37\x71\x96\x85\xd1\x85\xdd\xb3\x17\x18\x53\x20\x25\xa0\x46\x9b\xc2\x67\xed\x95\x51\x22\x0f\x8e\x2d\xec\x83\x5c\x41\x8b\x58\xa3\xac\xdf\x04\xe5\x8d\xa7\x6b\xbd\xa7\x6b\xbd\x7f\xf8\xb5\x1e\x8b\xe8\xcb\x4e\x2d\x77\x89\xea\xbb\xc8\x1c\xd6\x91\xc2\x46\xcb\x60\xb3\x8c\xe1\x2c\x5f\xd2\x0d\x76\x38\xd8\x0a\x82\x14\x86\x4e\xec\x6e\x7e\x04\x6a\xa9\x14\xcd\xa9\xf8\xc5\x9c\xde\x3c\x22\x7c\x85\x19\x44\xca\x43\x90\xc7\x83\x6e\xaa\x74\xb7\x7f\xfe\x5c\x3e\x1f\xb0\xf3\xd9\x73\x5d\x49\x44\xb6\xcd\xe7\xec\xda\x4a\x2a\x27\xf1\x2a\x1a\xa7\x87\xbb\xd2\x91\x72\x71\xc4\x1c\xae\x14\x8e\xc6\xe4\x26\x32\xf6\x16\x55\xa3\x4b\x28\xa2\xfb\x36\xef\x69\x6a\xd9\x2c\x6c\xf6\x38\xfc\x4f\xdd\xb7\xf4\xed\xc9\xa5\xbb\x14\x16\x82\x3c\x10\x11\xa0\xfc\xc3\x0f\x80\x3b\x55\x4c\x85\xd1\x05\x70\xe3\xaa\x02\x91\x5f\x5f\x2c\xca\x4c\x4c\x21\xca\x5e\xca\x77\xed\xa4\x90\x86\x26\x7e\x0a\xcd\x9c\x64\x64\xb2\xbf\xdb\xdc\x34\x06\x9a\xff\x19\x2f\xd6\xd6\x60\x9c\x54\x92\x82\xa5\x96\x25\x73\x22\xb3\x25\x69\x86\xd2\x98\xda\x39\xce\x66\xc0\xba\xe1\xec\xec\x47\x37\x19\x39\xf0\x7b\x68\x80\x47\x84\x01\xd0\x25\xce\xaf\x50\x61\x34\xa8\x92\x51\xfb\x0b\x47\x95\xef\x2c\x58\xff\xf0\x03\xb2\x8d\x7c\xd5\xa8\x8f\xcc\xeb\x06\x82\xaa\xc5\x3d\xda\xd9\xd9\x98\xf2\xcd\x08\x5f\x67\xa8\x7f\xf4\x11\x0d\x6f\x86\x13\xec\x89\x6e\xc2\xb0\x8b\xcd\x06\x7a\x02\x5d\x66\x36\x4b\xb3\x24\x1e\x12\x9e\x95\xd2\xd1\x31\x5a\x91\x8e\xc1\x62\x99\xd8\xe6\xc2\xd2\x11\x46\x1a\x7a\xa9\x5b\x0f\xd5\xcb\xf4\xcf\x32\xac\x94\x14\x5c\xa2\x99\x64\x0c\xf6\x5c\x00\xd0\xcd\xd8\x24\x5d\x6c\xc5\xb4\x83\x72\x64\xed\xd6\x2d\xa1\x6e\xbd\x5c\x08\xdf\x0f\xbc\x9c\x4d\xb0\xf7\xb2\x0e\x89\xea\x0c\x80\xb3\x90\x75\xc2\xed\x24\xf7\xad\xd9\x75\x9d\x29\x73\x8b\x4d\xe6\x35\xf9\x0f\xc9\xba\xa6\x7d\x22\x47\x4b\xca\xa9\x15\xca\x85\x57\x56\xa4\x72\x62\xbd\x4a\x27\x7d\xf8\xe0\x07\x81\xb0\xed\x92\xd2\xf7\x8a\xef\xfa\xf4\x48\x07\x07\x89\xc5\x72\xe3\x2d\x78\x2f\xd9\x8a\x53\x81\x4e\x8c\x84\x6c\xe9\x9b\xb7\x5b\x68\xb1\x18\x8e\xf2\x57\xaa\x56\x2a\x67\x41\xa0\x55\xd0\x90\x2f\x85\x84\x3c\x8b\x6e\x89\xd6\x20\x30\xa1\x72\xae\x48\x73\x50\x2d\x18\x6d\xab\x54\x2b\x10\x72\x1b\xb0\x11\x59\x5d\xcd\x76\x41\x64\xdf\xa7\x54\xc3\x4f\xb2\xef\x3f\x5d\xf6\xcd\x4d\xda\x78\xde\xed\x87\xf2\xd1\xdd\x1f\xf8\x91\x2a\xed\x86\x03\x5f\xb8\xde\xe2\x6b\xaa\xae\x2e\x72\xdd\x3d\x99\xfa\x49\xb6\xc3\x0a\xe6\x6e\xb7\xce\xab\x31\x50\x2b\x41\xb3\xbc\x2f\x9a\xce\x5b\x7a\x2d\x2e\xc1\x4e\xb2\x24\x8c\x2e\x6e\xc1\xb5\xc5\xf6\x9e\x48\xcb\x03\x3f\x92\x3f\xfd\xec\x4f\xe6\xf8\x16\x5d\x92\x7f\xd8\x75\x08\x81\x3c\xc2\x09\x5e\x70\x43\xea\xa9\xe6\x05\x10\xa4\x86\xe1\xa4\x8a\xc5\xd9\xd8\x03\x8c\x88\xb4\xee\xd1\x96\xcc\x2d\x0c\xd4\x6e\x74\x94\x21\x69\xfc\xc0\x8f\x2a\x59\x5c\x65\xaa\x22\xd0\xe1\x90\xcf\x5c\xe5\x53\xb1\x58\x11\x91\x7a\x90\x26\xa2\xf2\x22\xa4\xea\x1b\x0a\x91\xf9\xe9\xbe\x30\xf5\xc7\x0c\xe2\x76\x98\x10\x59\xcc\xe6\x10\xc3\x7b\x74\x1a\x33\xcf\x5e\xb9\x3b\x50\x9d\x41\xaf\x54\xcd\xae\xf1\xf6\x84\x1c\x03\xdd\xb0\x49\xba\xe0\x22\x21\x3c\xa5\x71\x36\x96\x33\xfb\x57\xaa\xd0\x08\xc3\x36\x4a\xb3\x30\x9b\x53\x81\xcb\x34\xff\x0a\xf0\x2c\x4e\xc3\x4c\xc6\x92\xc1\x15\xe8\x01\x98\xe1\x24\xc4\x51\xa6\x5b\x62\x94\x6e\xd8\x30\xb1\x60\x8d\x58\x46\x70\x59\x8c\xcc\xf1\xe3\x2a\xf8\xc2\xab\x64\x41\x7a\xa3\x79\x14\x80\x4d\xe4\x10\x27\x99\x1f\x8a\xe9\x77\x2c\x1f\x31\xb1\xcb\xad\xa3\x47\x5f\x42\x02\xaf\x3b\xac\x25\x36\xf2\x64\x36\xb5\xdc\x62\x92\x6c\x2b\xbc\xd7\xb3\x38\x97\x68\x09\xe8\x1e\x6d\x40\xa2\xcd\xc9\x1c\xf7\xe8\x3f\x5c\xcc\x1d\xaa\x89\x58\x9c\xb3\xc2\x26\x3f\x9f\x14\x88\x6b\x1f\x0e\x11\xe7\x84\x88\x73\x48\x54\x99\xce\xd3\x0c\xb6\x3a\x3c\xc5\x51\x26\xe8\x66\x70\x93\xe1\xb4\xd5\xac\x32\x61\xfc\xbb\xaa\x36\x91\xac\xdc\x83\x4f\x5f\x6a\xcc\x1f\xaf\x4e\x29\x15\xcd\xa3\xf0\xbf\xe7\x18\x85\x01\x8e\xb2\x70\x14\xaa\x9c\xb8\xd4\x5c\xf3\xd1\x29\x31\xc3\xd0\xa4\x9d\x6b\xfa\xb0\xeb\x48\x7b\xd0\x2b\x9d\x08\xf8\x18\x57\xfc\x41\x58\xad\xf9\x19\x61\xac\x35\x3e\xbe\x1c\xf4\x9f\xf7\x25\x02\x43\x56\xe5\xa3\x68\x0d\x82\x60\xee\x87\xdf\xf7\x5a\x44\x74\x25\xcc\xe0\xfb\x5e\xab\x75\x7b\xee\x75\x4a\x65\x3c\x67\xda\xdd\x4e\xa9\x7c\x6d\xaf\x64\x25\x7c\x4c\xe4\x8b\x91\x3f\xcc\xe2\xe4\xc6\xa3\x0a\x65\x32\xb0\xcf\x08\x9b\x26\xa2\x7e\x3c\x42\xa2\x37\x9b\x9b\xe8\x7b\x1a\x90\xe9\x7b\x28\xf3\x6c\x6d\x0d\xf5\xe3\xe9\x34\x8e\xfe\xeb\xe4\xf9\xb3\x67\x46\xe7\xf3\x5f\xac\x01\x8e\x53\xe5\x7b\x32\x0c\x09\xfe\xbe\xea\x21\xe9\x15\x8e\x86\xab\x03\x3f\xc5\xdd\xb6\xf6\x61\x1a\x74\xf4\xa2\x97\xb3\xcf\xc1\x48\x7b\x39\x0c\x67\x63\x9c\xac\x52\xc8\xd5\x57\xcf\x9f\xdd\x3e\x7f\x86\x27\x29\x46\x52\x67\xa8\xc2\x9c\xf6\x85\x0f\xc3\xf7\xe8\x87\x1f\xd8\x87\x9a\x3f\x0d\x44\xdf\xb6\x0e\xb6\x9f\x3f\x7b\x46\x3f\x54\xce\x38\xce\x1e\x52\x51\x85\x67\x82\x21\xfd\x40\x11\x83\xdf\x32\x3e\xe7\x62\x94\x65\xc4\x58\x43\x34\x1a\x06\xaa\x0c\x92\xf8\x2a\xc5\x49\xf5\xf9\xb3\x67\x62\xc4\xe2\x38\xab\xf5\x93\x9b\x59\x16\xff\xd7\x09\xad\x7a\x0b\xa7\x27\x79\xfb\x11\xdf\xd1\x9f\xcf\x9f\x3f\xab\xa8\xc7\x31\xc8\xc4\xbb\xb6\x46\x38\x53\x92\x0d\xe7\x59\x4a\xdf\x90\x65\xd3\x47\x9b\x88\xd7\x7d\x25\xbd\xfe\x34\x09\x07\xe4\x53\x6d\x12\x0e\xa4\xf7\xa0\x0c\xeb\x43\xa7\xc8\x57\x52\xaa\x26\xbd\x53\x20\xf8\x93\x8b\x18\x40\x90\x1f\xaf\x9e\x0b\x2c\xde\xc7\xf1\xe7\xf9\x0c\x65\xfe\x60\x82\x25\x4c\x4e\xde\x1c\xfe\xca\xce\x7c\xe2\xdd\xfe\x87\x9f\x3f\xd9\xde\x9f\x7c\x7c\xf3\xe9\x60\xff\xd7\x4f\x75\xd7\x87\x86\xeb\x43\xd3\xf5\xa1\x65\x6d\xdb\xd5\x8e\xfc\xd1\x68\x4b\xfe\x68\xb4\x27\x7f\xe4\x6d\x8a\xa1\xe9\xc7\xd3\x19\x39\x28\x4e\xcc\x21\xb2\x4d\xa9\x56\x2b\x88\xe7\x03\x22\xf5\x93\x5a\x79\x01\x60\xb1\x32\x16\x48\xb6\x54\x08\x21\x9a\x20\x0a\xd1\x6b\xd4\xec\x74\x5f\xa1\x70\x65\x45\x01\x2f\x64\x44\xf4\x1a\x35\x9a\x1b\xc6\x37\xf2\x17\x9c\x85\xe7\x68\x93\xc0\x78\x8d\x1a\xaf\xd4\xef\xf4\x2a\xb5\xa0\x56\x85\x56\xab\xa2\xdf\x50\xfd\xba\xd1\x18\xe8\xf5\xf3\xc7\xdb\xe7\x4a\xaf\x7f\xf1\x27\x9f\xd1\xdb\xdd\x4a\xf3\xb7\x8d\xaa\xda\xdb\x6b\x1a\x21\x51\x7d\x17\x6a\x2f\x97\x1a\x01\x69\x90\xd3\x41\x7c\xad\x7e\x04\x43\x03\xd2\xe6\x75\x88\x7e\x43\x95\xeb\xbc\x43\xec\x77\x53\xfa\xdd\x92\x7e\xb7\xab\x5a\x67\x01\x4a\x25\xbd\x46\x3f\xfd\xf4\x13\xda\x80\x92\xe9\x35\xfa\x01\xd5\xaf\x47\x23\x3a\x40\xdd\x96\x56\x85\xac\x8e\xb3\x6b\x32\x90\xe9\xb5\xf6\x89\x2f\x9e\xb3\x14\xbe\x5f\xbf\x7a\xee\xec\xd4\x74\x3e\xc9\xc2\xd9\x24\x1c\x82\x96\xc0\xec\xde\x35\x21\xe3\xe0\xec\xfa\xfc\x95\xe5\x5b\x9b\x7e\x6b\x5a\x3f\x6e\xd0\x8f\xed\xf3\x82\xd6\xd3\xf9\x00\x81\x7c\xe3\xa1\x69\x78\x8d\x86\xf1\x64\x3e\x8d\x52\x85\xfa\x65\x98\x44\x52\xa8\x04\xd0\xab\x1f\x09\xcd\xd4\x1b\x7c\xa4\xd8\x63\xbd\x51\xaf\xeb\x43\x2b\x56\x32\x1d\xac\x4a\x06\x13\xd3\xae\xa2\x2f\xe4\x37\x1d\x6f\x47\x95\x86\x5c\xa5\xd1\x95\xaa\x34\xba\xae\x3a\x4d\xb9\xce\x46\x15\xe5\x75\x9a\xc6\xac\x0b\x6e\x40\xeb\x64\x05\x23\x15\x46\x97\xf2\x68\x91\xc7\xd2\x23\x76\xbd\x21\x8d\x0f\x23\xcf\x36\x7b\x55\xe7\x2f\x9a\xca\x90\x16\x8e\xa8\xc2\x1f\x19\x8d\x95\x19\x56\x85\x75\x2a\xf5\x16\x8c\xad\xc2\x56\x95\x8a\x0b\x06\x58\x61\xb9\xac\x62\xd1\x28\xc3\x65\x01\xe8\x81\x71\x62\x72\xc2\xef\xae\xad\x4c\x90\x31\x80\xcd\x25\x38\x20\x54\x69\xa2\xdf\x50\x70\x46\xfe\x77\xbd\x81\x7e\x43\xd7\xcd\xf3\x73\x7d\x21\x41\xd9\x10\xfd\xb6\x09\x05\xaf\x43\xa3\x80\xc2\x24\xe1\xe7\x2d\x9c\x69\xc5\xbe\x72\x94\xe0\x21\xed\x5c\x80\x8e\x87\x71\xc4\x36\x98\x7c\x57\x3a\xee\x1f\x7e\x20\x7b\x44\xfd\xba\x5e\xf7\x50\xfd\xba\xde\x80\xff\x36\xe1\xbf\x6d\xf8\xef\x86\x07\xb4\x40\xfe\xdb\x84\xff\xb6\xe1\xbf\x1b\xf0\xdf\xc6\x80\xfc\xb7\xd5\xcd\x37\xb3\x1f\x7f\x64\x48\xfd\x88\xb6\x76\x4e\x68\x3c\x76\x44\xc5\x21\x44\x04\x82\x24\xcc\xc6\xd3\x1a\x2f\xb3\x96\xa3\x42\x4a\x6f\x32\xf1\xa1\x46\x1f\x24\x09\xa3\x86\xaf\x33\x1a\x3d\x40\x74\xf9\x53\x10\x1f\xe3\x14\x67\x3d\xe4\xd8\x22\xd9\x20\x9c\x7c\x0e\x67\xcc\xf2\x37\x1e\xa1\xe8\x38\x86\xd3\xd8\xd8\x4f\xd1\x00\xe3\x08\xbc\x03\xd8\xfd\x96\x1f\x05\x60\xc2\x17\x84\x01\x8a\xe2\x8c\x99\x61\x9a\xa4\x40\x93\xb9\x70\x48\xdc\x5c\xf4\xd3\x67\x7c\x73\x94\x84\x71\x72\x4c\x2d\x80\x37\x37\xf3\xf7\x56\xd2\xe1\x66\x61\xda\x9c\x9a\x1d\x50\xc5\x37\xfe\xc7\x0d\x0e\x37\xed\xcd\xe7\x6f\x2d\xfc\xf9\x33\xbe\xf9\x25\x4e\xc0\x88\xf1\x33\xbe\xa9\x5d\x91\xdf\xf6\x62\x27\xe1\x1f\x98\x95\x4a\xc3\x8b\x37\x84\x01\xa1\x35\xd4\x2e\x5a\x46\xc2\x0f\x20\x81\x01\x32\xc1\xf2\x91\xe3\x38\xe6\xcf\xbc\xc1\x15\xd4\x2d\xd5\x02\xe9\x7f\x3a\x1c\x63\x72\xfc\x40\x44\x84\xb6\xf4\x21\x3d\x8e\xaf\x08\xec\x0a\x6f\x66\x85\xec\xd2\x3f\x16\xf6\x41\x86\x6b\x1f\x16\xde\xa8\x34\xce\xd2\xbb\x33\x7d\xa9\xe6\x26\xa2\x04\x1d\x2a\x7a\xd0\x9f\xaf\x19\x86\xec\xd9\x22\x85\x20\x46\x76\xa2\x3c\x1d\x24\x6b\x39\xf2\x27\xa1\x72\x06\x75\xce\xe9\xc8\xc2\x8c\xb3\x37\x16\x56\xe3\x66\x58\x48\xda\x4f\x0c\xe0\x10\x4d\x47\x1f\x4a\x19\xed\xef\x18\xe2\xff\x12\x88\x3b\x31\x67\xb3\x70\x1c\x67\x88\x90\xa4\xbb\x50\x26\xef\x01\xea\x16\x50\x08\xf9\x64\x3e\x28\x03\x19\xc4\x27\x0e\xf3\x5c\xda\xdb\xe0\x43\xbe\x53\x31\x19\xed\x5c\xda\xc5\xe4\x12\x1b\x4a\x01\xc0\x94\x41\x66\xaf\x17\x60\x7b\x10\x5e\x03\xdb\x2e\xc2\xf6\xb7\x4d\x60\xe2\x67\x6c\x90\xd7\x72\xea\xf8\x82\xea\x0c\x75\xcb\x64\xa3\x7c\xc2\x81\xb4\xd8\xba\xfb\x09\x75\x09\x3f\xd3\x26\x0c\x6d\x6e\xa2\xf6\xa2\x49\xfb\xe6\x86\xd6\xde\x67\xc7\x88\xbb\xd6\x8c\x41\xeb\x6c\x48\xce\xd1\x6f\x44\x96\x30\x17\xd1\x42\x6e\x2e\xcb\x74\xc5\x6c\x26\x8c\x2e\xdf\x59\x38\x8d\xf1\xda\xcd\x6c\x48\xd1\x9c\xdf\x88\xa7\x9c\xe5\xf0\x57\x0e\xae\x23\x33\x2c\xc6\x47\x57\x45\x1d\x1b\xf1\xc2\x91\x91\x37\xf3\xaf\x02\xa2\x71\xb2\x93\x87\xe5\x4c\x6d\x2b\xb8\x45\x88\xbf\x46\x6d\x70\x64\xa1\x0f\x45\xb4\xaf\xce\xc5\x19\x87\xc0\x24\xcd\x25\x3b\x52\x00\x4c\x15\xba\xd5\x35\x44\x48\x51\x15\xae\x1d\x4b\xe9\x1c\xfd\xe6\x5e\x9c\x8e\x3f\x55\xf8\xb6\xaf\x40\x1d\x81\xd6\x99\xba\x14\xed\x73\xe0\x94\x64\x3d\x69\x7a\x70\x34\x4c\x6e\x66\xd4\x32\x56\x96\xf3\x0e\x3c\x14\x8f\x46\x29\xce\x8c\x99\xa1\x6b\x24\x88\xfb\xa2\x5e\x5e\xd8\x33\xf7\x6a\x2f\x3f\x21\xe6\x3f\x1b\xf9\xcf\x66\xfe\xb3\xe5\x01\x8b\x91\x4f\x19\x0a\xae\x01\x5e\x16\x57\xc2\x35\xaf\xfc\x19\x6a\x46\x01\xc8\x9e\xed\x6c\xec\x10\x62\x08\x7d\x1f\x9c\x51\x30\x44\x7e\xd1\x87\x54\xf9\xa6\x96\x6d\x15\x94\x6d\x59\x8f\x44\x65\x86\x50\xa5\x55\x4f\x25\x50\xf5\xb1\xa1\x3e\x36\xd5\xc7\x96\x27\x14\x16\xc6\xe6\xbd\xb6\x86\xf6\xc9\xc9\xf7\x9b\x18\x23\xfb\xa4\x2b\xc3\x64\x9d\x75\x0f\xdd\x8f\xdc\x6c\x44\xc3\x0e\x04\xa5\x25\x6b\xcb\xc0\xbe\xc5\x2c\x56\x28\x5c\x48\x52\x51\x9d\x60\x6a\xd1\x71\xd5\xa5\xc1\x3a\x87\xd7\xbf\x29\xcc\xb6\x6e\xd3\x00\xa5\x0d\x7d\x3a\xb4\x5a\xc6\xfc\x40\xad\xa6\x5a\xab\xa9\xd7\xb2\x6a\x9b\xd2\x96\x3e\x9d\x5a\xad\x96\x4d\x0d\xf5\x4e\x3b\x3b\xd8\x8f\xfe\xf2\x16\x68\x3b\x31\x1c\x5b\xce\x38\x62\xff\xa5\xa3\xba\x89\x1a\xaf\xd8\xcf\xd7\x7c\x86\xd8\x0b\xc7\xbe\x0b\x73\x1c\x8e\x32\xa0\x74\xcf\xa1\x28\x2b\x9c\x38\x8e\x7a\x46\x26\x4f\x52\xd7\xd4\x85\xe4\xf5\x9b\xa4\xe8\xaa\xa4\x0d\x43\xee\xfa\x4d\x52\x6a\x55\xd2\xa6\x2e\x75\xfd\x26\xe9\xaf\xd2\x96\xf4\xda\xd8\x86\x57\x56\x6c\x1b\x00\x20\xd7\x50\x91\x6b\x38\x90\x6b\x2e\x40\xae\x55\x88\x5c\xfd\x8e\xc8\x35\x55\xe4\x9a\x0e\xe4\x5a\x0b\x90\xab\x17\x22\xd7\xb8\x23\x72\x2d\x15\xb9\x96\x03\xb9\xfa\x02\xe4\x1a\x85\xc8\x35\x17\x22\x67\x25\xdd\x8f\x33\xb0\x21\x4a\x33\x3f\xc3\x66\x01\x60\x27\x59\xdd\xd2\x31\x60\x19\x99\xae\x47\x83\x2f\x64\x2e\xb2\xa6\xed\x0b\x19\x88\x4c\xd7\x8e\x5b\x95\x28\xd6\xf5\xb4\x80\xf7\xc1\xf2\xa9\xd0\x93\x87\xb4\x76\xf4\x53\x8b\x65\xf9\xe8\xc7\x16\x73\x05\x29\xe7\x96\x7c\x09\x55\xcb\x51\x82\x58\x3f\x1c\xbb\x86\x1b\x3b\x73\xfd\x18\xd8\x19\x4b\x48\xc5\xae\x7e\x17\xec\x9a\x12\x76\x4d\x37\x76\xe6\x02\x32\xb0\x33\xd6\x90\x8a\x5d\xe3\x2e\xd8\xb5\x24\xec\x5a\x6e\xec\xcc\x15\x64\x60\x67\x2c\x22\x15\xbb\xe6\x62\xec\x4c\x6a\xc5\x3c\xb0\xb5\x5d\x2e\xa1\xdb\xb0\x65\x1d\xe9\x42\x8e\xb1\x9c\xd4\xcd\xd5\xb2\xaa\x0c\xd1\xa7\xe5\x92\x7d\xd8\x51\xb8\x87\x9a\x9d\xee\x5a\xab\xc9\x34\xd0\x55\x9b\x2a\x98\x4b\x2c\x42\x40\x4a\x99\xe3\x30\x53\x0d\xbf\x48\x59\xbe\x27\x04\x29\xbc\x47\xfe\x10\x0b\x1d\xb1\x00\xf2\x9f\xf8\xda\x9f\xce\xc4\x49\x39\xff\xc0\xe7\x94\xc2\xca\xf0\x75\x26\xdd\x6e\xd7\xb6\x76\x4e\x6a\xec\x1c\x51\x99\x72\x8b\xf4\xcf\xf8\xc6\x43\xc3\xd1\x85\x90\xe6\x73\x28\xb3\x89\x4f\x90\xb8\xce\x90\x0e\x85\x49\xf8\x95\xbc\x1d\x1b\x20\xa6\xd3\xee\x5b\x94\xd8\x9f\x68\xd4\xd4\x3d\x3c\x99\xe1\xa4\xb2\xb5\x43\xaf\xf5\xa9\xce\xfe\xf9\x33\x66\xb3\x22\x37\xf9\xea\xf9\x73\x88\x80\x0b\x06\x24\x8a\x55\x41\xaf\xd3\xf4\xb8\x5d\x42\xaf\x03\xb6\x23\x92\x65\x42\xaf\xd3\xf6\x72\x93\x84\x5e\x07\x5c\x18\xa7\x41\xe7\xfb\x5e\xb7\x71\x7b\xee\x75\x9a\xf7\xb2\x16\xf9\x9a\x66\x22\x8f\x66\xcc\xf1\x15\xcd\x32\xe8\x4a\xf8\x11\x31\x03\x0a\xd2\x3c\x1a\xc6\xd3\x59\x1c\x41\xc8\x75\xf2\x6d\xed\xf9\x33\x31\xef\x93\x70\x50\x63\x45\xbf\x7c\x91\x0d\x00\x84\xd3\xe7\x03\x1b\x77\xf8\x29\xce\xad\x3a\xfc\x14\x4b\xdf\x7e\x89\x93\x00\xdc\xd2\x45\x01\xf1\x46\x86\x30\x1f\x81\xbd\x18\xd0\xfa\x16\xbf\xe5\xc9\x61\x5a\x3f\x2b\x98\x61\xf0\xac\xea\x93\x85\x2a\xbd\xff\x98\x8d\x36\x00\x0a\x8e\x86\x35\xf2\xa0\x61\xdd\x6d\x8b\xaf\xf4\xb1\xc8\x10\x45\x7c\xd9\xb9\x9c\xbd\xdb\xde\xcd\x2f\x9b\xe8\xb3\xf5\x06\x6b\x90\x52\xf3\x3c\xb2\xac\xf8\x2d\x56\x86\xa7\xb3\x89\x9f\xd9\x18\x94\x08\x32\xfd\x67\xc4\x02\xf2\x70\x0d\x2a\x38\x15\x08\x5e\x07\x7a\xbf\xf0\x0f\x5c\xe3\x01\x26\x7b\xa8\x8d\x2a\x8d\xe6\x06\x1a\x84\x59\x5a\x2d\x02\x18\x5e\x5a\xe0\xed\xff\x7c\x57\x70\x9f\x76\x3e\xf4\x3f\xfd\xba\x7b\x78\x7c\xf0\xe9\xe0\x70\x7b\x07\x6d\x41\x68\x83\xcc\x8f\x32\x94\xe0\x59\x82\x53\x1c\x65\x61\x74\xc1\x15\x31\x84\x0c\xa7\x71\x90\xf7\xdd\x0a\x73\x7b\xa7\x14\x4c\xc6\x4e\x0d\x98\xd2\xa5\xa0\x66\x72\x24\x1e\xed\x14\x65\xb9\x24\xcc\x67\x93\xa2\xdb\x07\xb7\xef\x79\x02\x06\x0f\x22\xc7\x87\x5c\x44\x29\x2e\xf5\x4e\xd0\x3d\x99\x03\x74\x3a\xc6\x64\xd4\xb3\x18\xcd\x99\x9b\x00\x61\x01\x88\x14\x06\xd0\x0a\xc8\xb5\xfc\x61\x38\xba\xe8\x01\xe9\x72\x5c\xab\xf2\x8e\x6a\x60\x0b\xdb\x45\x4a\x61\x33\xf2\x0b\x23\xd7\x64\xd8\xd0\xa7\xf6\x98\x12\xee\x84\xf4\x08\xf2\x9f\xf1\x4d\xcd\x5a\x96\x7b\x86\x0e\x47\x17\xa8\x72\x08\xad\xf8\x93\x2a\xd4\x19\xda\x06\xaf\xe4\x18\xa8\x6d\xf1\x38\xa2\x74\x42\x6f\x09\x89\xf0\xde\x11\x42\x19\x16\xf5\x89\x9c\x2b\xc2\xa1\xfb\xbb\x2a\x25\x98\x05\x90\x22\x2d\xc8\x7b\x3c\xbf\x7a\xae\xd1\x6d\x7a\x87\x0e\x73\x9c\x54\xd8\xe5\x19\x0c\xa1\x87\xfe\x44\xe1\x65\x0f\x85\x97\x39\x6f\xbc\x55\x4c\x0f\x94\xf9\x56\x21\xf5\x94\xb0\x50\x4c\x72\xd0\x35\x00\x72\xe2\x10\x5a\x9f\xdd\x38\xab\x6b\xd5\x22\x7b\xe8\x12\x5a\x49\x7a\x72\x2c\xc4\x27\x7a\x7a\x58\x7a\xda\xc6\x0f\x45\x4f\x02\xd2\xfd\xe8\x49\xe5\xd3\x77\xa0\xa7\xfd\x28\xcc\x42\x7f\x12\xfe\x81\x53\xe4\xa3\x08\x5f\x4d\x6e\x18\x86\x01\x1b\x8e\xc5\xb4\xc4\x77\x8d\xeb\x51\x9c\x4c\x0f\xe2\x00\xa3\x1d\xea\xab\x06\x61\x9a\x73\x4e\x17\x27\x32\x9d\x82\x75\x35\xb8\xf9\x71\xaa\x15\x9b\x8c\x9d\x0c\xbf\x39\x92\x7d\x30\xb2\xaa\x98\x1f\x6c\x9c\xe2\x8e\x04\x17\x46\xa1\x62\x61\x23\xa6\x49\x22\x17\x8b\x8a\x7a\x6b\x36\x23\xb4\x00\xa3\xc5\xb3\x4d\xa7\x96\x6b\x06\x32\xc4\x9b\xe2\x27\xdf\x14\x29\x0d\x9a\xa7\xe2\x8c\x48\xce\xd4\xb0\x3e\x4e\xa6\x74\xda\x7d\x9b\xee\x86\xd2\x77\x4e\x52\x9b\x39\x79\xbd\xb2\x95\xa4\x76\x34\x60\x2b\x63\x3d\x8b\x87\x94\xd0\xa9\x07\x80\xad\x1f\x60\x5f\x54\x29\xbd\x70\xc0\x46\x47\xe5\xc3\x10\xcb\x21\x15\x2d\x81\xf6\xec\x9e\xe4\xc3\x96\xa0\x89\x9b\x32\xc3\x49\x19\x23\x2a\x6a\x54\x14\xf8\x99\x8f\x06\x20\x7b\xa9\x25\x1c\xf2\x18\x80\xa6\x99\x2e\xb8\xb7\xb3\x0e\xf8\x08\x27\x30\x97\xc3\x38\x1a\x26\x38\xc3\xab\x6c\x38\x26\xf1\x85\xc2\x94\xa5\x7b\xa9\xe3\xe5\xc6\x1a\xe2\x69\x00\xe6\xd4\xbd\x85\xf1\x14\x1c\x48\x2c\x05\x07\x4b\x6c\x7a\x5f\x52\xe6\x0a\x43\x80\x32\x65\x27\xe1\x0d\xbc\x0d\xd6\x80\x04\xbe\xc4\xce\x25\xf1\x27\x01\x8b\x06\xcd\x62\xc1\x08\xc2\xe8\xe2\x01\xb8\x49\xde\xf9\x4d\x4e\x1e\x0c\x7e\xe5\x05\x69\xf3\x85\x4a\x26\x65\xea\x5d\x71\xcc\x9d\x14\xc6\x4a\xf6\xb4\x28\xaf\x74\xe8\x1c\xdc\x03\x47\x81\x6d\xf6\x7d\xf8\x22\x57\xb7\xd1\x14\x6d\x0f\xf9\x97\x7e\x38\xf1\x07\x13\x4c\xcd\x10\x53\xf7\xb6\xf8\x89\x77\xa6\x34\x55\xed\x86\x11\xdb\xf8\x0a\xf7\x29\x06\x57\xdd\x67\x3e\xc4\x19\xf3\x8e\xa6\x41\xd3\x28\xa4\x7c\xd7\x40\x61\x8a\xf0\x68\x84\x87\x59\x78\x89\x27\x37\xc8\x47\x01\x4e\xb3\x64\x0e\xcf\x1e\x4a\xb0\x1f\xac\xc6\xd1\x10\x97\xda\x67\xca\x52\x2f\xa0\xf1\x58\x34\x4c\x81\x3f\x36\x25\xf3\x91\xac\x94\x27\x62\x51\x65\x59\xea\x17\x15\x17\x93\x3f\x2f\x5a\x9e\xfe\x77\xf3\xb9\x98\x43\x21\xb5\x44\x38\x2a\x04\x80\x0a\x57\x8b\x52\xd4\x72\x51\xb2\x04\x43\x86\x78\x48\x04\x55\xb6\xe0\x70\xc0\xe2\x65\x72\x4e\xbd\x2b\x4d\x88\x75\xf1\x99\xb5\x17\x2a\x9b\x1b\xcd\x8d\xb5\x56\x53\xfe\x44\x55\x22\xb6\x2f\x9a\x1c\xd4\x43\x0d\xe5\xab\x2a\xff\xf6\x50\xb3\xcc\xd9\x29\xb5\xaa\xb2\xfd\xc5\x8a\x6c\xe4\x5c\x9b\xfc\xd4\xc2\x46\xfa\x74\x8c\x25\xa1\x80\x25\xda\xf2\xd1\x18\xb4\xc6\x44\xc8\x2c\xb1\x14\xb9\x08\xbb\x15\x71\x7c\x20\xc0\x00\x5f\xd6\x44\x68\x62\xeb\xda\xd2\xa1\xaf\x70\x58\x62\xd6\xde\xa6\xca\x53\xd3\x91\x1b\xb2\xad\x73\x95\x29\xf5\x7a\x4e\xbf\x29\xf2\x27\x3e\xa5\x78\x82\x87\x19\x6d\xf8\x24\x4b\xfc\x0c\x5f\xdc\x54\x5c\xe6\xda\x92\xf6\x19\xc4\xc5\x4d\xf4\x82\xb2\xd2\x17\x4e\xf3\x30\x36\x1b\x47\x7e\x9a\x12\x36\xf1\xc6\x4f\x71\xa0\x78\xcc\xc9\x7f\xc5\xc6\x61\x0c\xd4\x09\x4e\xe0\xc0\x45\x76\x35\x37\xa4\xe2\x45\xae\xe7\xf6\x63\xf7\x19\x05\x36\xea\x2e\xa4\x18\x39\xc9\x8c\xcd\xbc\x61\x29\xb2\x1b\xcd\x83\x80\xd9\xe7\x41\x5c\xdc\x50\x14\x3d\xe4\xbe\xc0\xd1\xc7\xc0\x73\x58\x7a\x32\xb2\xef\x19\xfd\xd7\xee\x73\xee\x85\xb6\x7a\x53\xe4\xa1\xc2\x1b\x23\x1d\x73\xcb\x84\xea\x6c\x5b\xe6\x92\x95\x2a\xd3\xf0\xda\xaf\xde\x54\x1d\x76\x9a\x25\xd8\x9f\xde\x49\x95\x0d\x32\x14\x53\x3e\xcb\x36\xf8\xad\xe6\xea\x20\xa4\x06\xdb\xea\x89\x86\x4a\x27\x10\xc6\x5a\xd2\x4c\x37\x50\xa5\xd5\x54\x15\xd3\x92\xc2\xf7\x04\xf0\xd3\xd4\xbe\xfa\xcb\x02\x8f\x90\x5d\xcb\x5e\x6b\xdb\x61\xb9\x88\x38\xf3\x13\x38\x6e\xd9\x04\x44\x73\x7b\x83\xe3\x4d\x6e\x5d\xc5\x85\xc6\xef\xbe\x7b\x31\x9a\xcc\xd3\xf1\x8b\x72\xdb\x1c\x85\xe2\xda\xe8\xc4\x30\xf7\x50\xa3\x68\x5e\xe1\x5c\x0b\x59\x4d\x67\xf2\x6d\xa9\xac\x3c\xff\x34\xa5\x67\xdf\x7e\x8d\xfd\xf8\xf3\x76\x31\x85\x28\x1e\x3b\x50\xcf\xa0\x12\xa9\x0d\xe9\x76\x93\x1d\xb4\x0d\xe7\x60\xf6\x5e\x56\x7a\x17\x29\xe8\x65\x15\xe5\x94\x27\xe7\xca\xe5\xeb\xa5\x77\xd3\x2d\xb5\x47\x56\x85\xa0\x9e\x59\x26\x57\xf0\x03\x55\x7f\x85\xfd\x90\xcf\x14\xdf\xee\x40\x0f\xdb\x7f\xd3\x37\x54\xd1\x9c\xa3\x84\x97\xd4\x6b\xe7\x2e\x9a\xe7\x1c\x46\xa1\xae\x50\xd4\xe5\x8a\x26\xa9\xde\x9d\x34\xce\x62\x3a\xf3\x03\xd2\xff\xcc\xe9\xcc\x35\xc1\x4b\x4e\xa7\x55\xf1\x5b\x72\x3a\x45\xdd\x7b\x4c\x67\x91\xc2\xb7\xdc\xd5\xc1\x57\x9d\xce\x7b\x4f\x57\xc1\x12\x58\x30\x5f\xba\xde\xb4\x60\x92\xe8\x66\x22\xf4\xbc\x43\x9b\x58\xc7\xac\xae\x2f\xd1\x26\x0a\x2f\xe5\xd9\x2a\xda\x22\xd8\x8e\x49\xe3\x4a\xf7\xc7\x7e\x18\x41\xca\x13\xd7\x5d\xeb\x1b\xb0\x1b\xf8\xc4\x3b\x8f\x36\xdd\xc1\x07\x74\x15\x9b\xb2\x83\x90\xba\x06\x31\x48\x43\x93\x37\xa6\xed\x12\xe2\x4e\xf4\x55\x11\x47\x79\xd3\xe7\xdb\x81\x76\x12\x92\x9a\x50\xe6\x8e\xf4\xea\x4d\xdf\xb2\xf7\x98\xe0\x69\x13\x47\x22\xfc\x67\xc6\xd5\x18\x94\x4a\xfd\x8c\x19\x75\xd7\xf4\x3a\x06\x0c\x8d\x66\xa9\x74\x24\xb4\x22\x4c\x58\x8a\xb9\x8c\x84\x54\x4e\x88\xac\x37\x24\xcc\x2e\x8b\x00\x61\x3f\xaf\xc6\x98\x45\xde\xa7\xf8\x41\x20\xcf\xb4\x04\x72\xe6\xc2\xb0\x17\x24\x7f\x30\x95\x4c\xd4\xa1\xde\x00\x90\x1e\x0f\xba\x20\x5c\x1b\x74\x59\x56\x9e\x0c\x94\xab\x00\x0d\x33\x79\x15\x8a\xd3\x16\xda\xea\x00\x8b\xf4\x1b\x12\x79\x21\x39\x0c\x67\x73\x21\x56\x68\x72\xc4\x2b\x87\x39\xeb\xaf\x87\xc7\x70\x5e\x66\x44\x67\x96\xb9\x8e\x13\xe8\x57\xae\xe8\xf6\x90\xd2\x2f\x2f\x6f\xd6\x26\xf4\x33\x3c\x64\x5f\x97\x8a\x3e\xba\x56\xcc\x8e\xf1\x14\x83\x14\x0e\xbb\x2b\x25\x01\x76\x15\x05\xa7\x7d\x70\x68\x87\xd7\x66\x75\x2e\xc1\xe2\x4b\x1e\x76\x9e\x32\x53\x9a\x4f\x9e\xe3\x2d\x4c\x01\x9d\x1d\x90\x3d\x77\x16\xae\xdb\x00\x97\x58\xb7\x62\x9f\x7a\x5a\xb7\x4f\xeb\x16\xdd\x7d\xdd\xde\x67\x75\x80\x85\xf0\x38\x4c\x97\x5e\x1b\x56\x4c\x18\x45\x03\x17\xf9\xf5\xf0\xd8\xc9\x01\x64\x0f\x32\x83\x03\xdc\x97\xed\x58\x31\x3b\xcd\x87\x66\x80\x87\xf1\x94\x2d\x1d\xc2\x16\xc2\x78\x9e\x96\x67\x1e\x62\xb0\xca\xb2\x07\x41\x4a\xbc\x1b\x15\x27\xee\x4b\x79\x40\x81\x88\xc4\xa5\x25\x9b\x87\xff\x38\x8e\x53\x8c\xa6\xe1\x35\x91\x85\x2c\xfd\x03\x4f\x50\x53\x48\x43\x32\x21\x32\x29\xcc\x45\x76\xf1\x25\x48\xa7\xe4\xa4\x93\xce\x07\x29\xfe\xef\x39\x8e\x32\xab\x8a\x01\xa9\xa2\x9d\x94\xd5\x43\x1d\x45\xa7\x6a\x50\x46\x49\x9b\x95\xc5\xaa\x7e\xb2\xb3\xd9\xb0\xb2\xc5\x48\xca\x57\x9b\x35\x52\x12\xf9\x83\x09\xcc\xad\xc7\xc3\x73\xf4\xdb\x26\xad\x77\x16\x16\x86\x2e\xc9\x7f\x73\x13\xe8\x37\x7d\x56\x5e\x09\x68\x22\x89\xb6\x47\x7e\x10\x90\x09\x5c\xa0\x00\x99\x41\x96\xab\x7e\x8d\xfe\x6b\x57\x7f\x1c\xbd\xeb\x9f\xa0\xff\xd5\x59\x5b\x47\x33\x06\x34\x65\xba\x3c\x1b\xcc\xa3\xcf\xc3\x74\x1d\xe4\xe4\x99\x1f\xd4\xf8\x53\x81\x6c\x7c\xe4\xf3\xeb\xe7\x79\xca\x43\xe7\x8b\x40\x28\xcc\x5c\x19\xe2\x26\x0b\x3c\x96\xb2\xbf\x02\xc8\xf2\xed\x33\x41\xcb\x5a\xc9\xae\xc7\x63\x21\xa0\xa4\xfb\x48\x00\x94\x8a\x60\x96\x64\x50\x20\x9c\xe5\x23\x1f\x9b\xc5\xe1\x4b\x8c\x2b\xf9\x95\x5f\xaf\x79\x5a\xdc\x2c\xe5\x82\xd9\x0f\xf4\xcb\xb5\x3b\x33\x10\x51\x8d\xc6\x3a\xd9\x94\xc6\xcb\x15\x33\x64\x1e\x65\x82\x76\xc0\xaf\xc8\x84\x1a\x31\x82\x35\x80\xd2\x17\xab\x34\xe5\xb4\x88\xb0\xf2\x2f\xad\x80\xad\x59\x7a\x2f\xc4\xdb\x35\x43\x2f\xd0\x4c\x6f\xf0\x95\xd0\x0b\x44\x40\x51\xb0\xc8\x7d\x5d\x8c\xf7\xcc\xc1\xc5\x78\x0f\x6e\x2d\xca\xdb\x85\x98\x15\x22\x95\x16\x87\x2f\xc8\xd9\x8f\xda\x26\x0a\xd1\x8a\xcb\x2d\x5f\x86\x4e\xc3\xdc\x4b\x6f\x0a\xa4\x57\x0d\x3b\xb4\x99\xdb\xbe\xf3\xc3\xbf\x0c\xda\x53\x51\xb2\x99\x21\x6c\x05\x81\x7d\x10\x60\xae\x87\x71\x34\xf4\x33\x0e\xb3\xb4\x06\xe6\x63\x34\x13\x0c\x05\x96\xec\xd8\x0f\x68\x20\x23\xb6\x50\xbf\x0e\x97\x99\x47\x3a\x9f\xf9\x2a\x1c\x01\x9a\x2d\x71\xe5\x0e\xe5\x74\x96\x60\xe3\x03\x6f\x71\xa6\x24\x2e\x96\x16\x31\xc4\x80\x45\x13\x3f\xcd\xe0\x79\xf1\x9a\xce\xc5\xeb\xb3\x8a\xba\x9c\x57\x51\xa3\x4a\x5d\xcc\xce\x99\x33\x98\xcd\x93\x98\x0a\x0e\x6e\x8a\xc9\xc1\x6d\xea\x6b\x50\xda\x4c\xe9\xb6\xb9\xa4\x9e\xff\xaf\xb8\x08\xb2\xb9\x28\xd8\x6f\x16\x6c\xb7\x0a\x45\xf7\x40\x0f\x67\xf4\x7f\x10\x07\xf8\x96\xaa\x07\x4f\xc5\x69\x8d\x5e\x8a\xc0\x49\x42\xea\x4e\xff\x4d\xdf\x05\x85\xcd\xd5\xad\xa0\x2f\x02\x4b\x17\x36\x4c\x88\x40\xf2\x0e\x02\x07\x3f\x02\x36\x00\x92\xe1\xa4\x46\xe0\x04\x53\xc0\xcc\xd3\x4e\x75\xb4\x6d\xa3\x89\x5b\xc5\x1b\x61\x09\xc3\x40\x3a\xd1\xea\xc7\xbe\x64\x7d\x58\x6c\x03\x58\x10\xe0\x4c\xb5\x0f\xb5\xf8\x71\x82\xdc\x4c\x46\x40\x51\x8b\x22\x55\xb1\x4b\xbe\x4f\xc1\xf6\xd3\x81\x7f\x3e\xb1\xe6\x61\xc0\xb0\x25\xe5\x92\xb6\x6a\x5c\xe2\x3c\x31\x10\xa8\xb0\x25\x82\x46\x03\x4e\xe5\xda\xdd\x8c\x5d\xda\x5f\xfb\xb1\xb8\x79\xd5\x7a\xa5\x8a\x7e\x5c\x5b\x1a\x03\xa1\x6a\x71\x9c\x65\xde\x61\x3c\x43\x7e\x86\x26\x98\x70\xc1\x38\xe2\x2b\x80\x65\xf9\xa0\x96\xa0\xb0\x5f\x03\xc3\x35\xf9\x16\x12\xe7\x9b\x69\x18\x51\x23\x51\x76\x88\x37\xc2\x25\xaa\x8f\xac\x12\x9d\x3e\x09\x7f\x4a\x48\x53\xb0\x3f\xa6\x47\xde\xf0\x12\xfd\xf0\x83\x55\x1f\xaf\x07\xea\x38\xba\x93\x2e\x23\xc7\x44\x55\xa6\x38\xcf\xe7\x7a\xb3\x65\xaf\xa4\xdd\x22\x69\x2e\x92\x08\x43\x69\xf6\xca\x42\xd0\xbc\xb9\x87\x25\xe4\xb5\x35\x72\x90\xa1\xe9\xbe\x5c\x22\x17\xc8\xeb\xcc\xf4\x0b\x24\x70\xf8\xbd\x50\x07\xc1\xaf\xe2\xa9\x8d\xa0\xeb\x94\x7c\xa7\xcb\xf8\xc7\x5b\x56\x8f\x8b\xb7\xb5\x3d\x90\xfc\x16\xcc\x00\x95\x8f\x6c\xed\x2d\xb2\xfc\xbb\xa7\xa5\x02\x98\xde\x31\xd9\xc3\x6e\x86\x82\x86\xf1\x64\x82\x29\xfd\xc7\x23\x2e\x1a\x80\xa8\x89\x21\x97\x5e\x91\xe8\x21\x89\xa2\x92\x93\x37\xd9\x46\x13\xff\x4a\x7a\x65\xf5\x4b\xb4\xbb\x7e\x50\x07\x74\x21\xa4\x94\xa9\x9d\x5f\x3c\x42\x86\x07\xc6\x05\x69\x7d\xb2\x3e\x0d\x73\x5c\x17\xa0\xd4\x9f\x50\xec\xe1\x07\x00\x03\x95\xa4\x4f\xc3\x8f\xe2\x24\xbc\xa4\xb2\x0a\xe7\x18\x56\x80\xfc\x2a\x35\x97\xf3\x25\xcb\x41\x33\xd6\x6a\x39\xb9\xe6\x2e\x3d\x2b\x96\x6f\x86\x63\x3c\xbd\x1b\x5c\xbb\xc0\xc9\x54\xe6\x60\x31\x3d\x92\xe0\x59\x41\xd0\x9c\x8c\xb7\x79\xce\x46\x7a\x8a\xa1\x22\x16\x7f\xab\x8b\x61\xc3\x38\xba\xc4\x49\xa6\xc8\xb0\x34\xdb\x1d\x37\xa6\x04\x8b\x4f\x6a\xfd\xe7\x76\x5b\x3d\xa2\x55\x54\xe7\x55\xf1\xb2\xa4\x3d\xcc\x62\x17\x2b\x15\xb5\xc5\xc7\x3a\xe1\xdd\x24\xe3\xa3\xd9\x89\xfa\x91\x48\x62\x35\x8b\xd3\x34\x1c\x4c\xb0\x7b\xc5\x5a\x9a\x5a\xce\xb9\x29\x1f\x28\xd3\x1e\x94\x7e\xe3\x27\xf0\x3f\x0d\x28\x48\xa8\xcf\xc9\x0a\xee\x49\xbf\x73\x87\x27\x6b\xa5\xcf\xf8\xa6\xa7\xfa\x45\x59\x8b\x69\x9e\x52\xf6\x42\x64\x19\xf7\xe0\xbf\x0b\x0a\x8a\x55\xd9\x33\xdd\xb9\xec\x35\x98\x08\xaf\x5b\x26\xd8\x0b\x0b\xb9\x5e\x3d\x3a\xbf\xeb\x9f\xac\xdb\x2b\x48\x2c\xbc\x6d\x2f\x21\x16\x8e\x04\x94\xbe\xab\x1d\xce\x70\x74\x72\xf2\xde\xa8\x56\xde\x99\x4c\x9e\x7e\xbb\xe0\x35\x0d\xaf\xf7\x23\xb5\x5c\x69\xd3\x23\xba\x8a\xd3\xe5\x96\x31\x72\xae\x1b\x93\x95\x68\xbe\x81\x0e\x6e\x42\x0e\x75\x6e\xe0\xdc\xc0\x96\x7b\x65\xc0\xae\x00\xbf\xc3\x51\xa8\xaf\xf1\x02\x38\x90\x04\x2c\xa5\x19\xc0\x20\x7b\x1c\xce\xbd\x28\x73\x8c\xa3\x98\xbe\xd1\x18\x20\xcb\xd9\x8f\x8b\xb8\x47\xd9\x25\x4d\x91\x17\xd7\x74\x6c\x6d\xaf\xa0\x17\x2f\xec\xbe\x15\xd6\xf2\xb5\x2c\xa6\xf9\x86\x5c\xae\x1c\x0b\x6a\x39\x48\xd5\x49\x98\xbc\xa2\x4c\x9c\x62\x6c\x5c\x56\x55\x79\x09\xf4\xe5\x0b\x25\xd7\xbc\x4e\x8d\x4f\xe2\x0d\x3f\xf6\x1a\x3a\x1a\xab\x9c\x44\xa9\x6c\xd1\xbd\x06\x6d\x07\xae\x36\xc4\x4f\xfb\xed\x06\xeb\xb9\x8d\x38\x6d\xa0\x59\x71\x91\xca\x18\x76\x2f\x75\x10\x8b\xaf\x3b\xc4\xaa\xf3\xdd\x4b\x2e\xe2\xcd\xac\x0e\xe3\xe9\xcc\xcf\x60\x7b\x29\xbb\x0c\xe5\x6d\x41\xdb\xc4\x24\xf1\xa7\xec\x9e\x68\x5b\x7e\x77\x41\xee\xa1\x0c\x07\x63\xda\xf6\x09\x27\x6f\x07\x21\x4b\xd4\xe5\xe2\x8d\x0a\x7d\x8b\xe2\xa5\xb9\xef\x02\xb5\x8c\x1c\x69\x49\x59\x82\xf9\x17\x5b\xa0\x46\x22\xee\x6a\x15\xc8\x3b\xdb\x31\x16\xfa\x6b\x1e\x62\x49\x71\xa7\xaa\xe5\x4a\x8a\x56\x63\x68\xef\xcf\xea\xd7\x9d\x56\xb7\xd1\x1d\xae\x43\x62\x83\x6e\xa7\xdb\xee\x8c\x3a\xa3\xf3\x2a\x57\xc5\x03\x68\xfe\x90\xf7\xc3\x71\x8e\x2c\x81\x82\x73\x2c\x1c\x87\x2f\x51\x37\x67\x64\x34\xac\xcd\xf2\x7b\x5e\xd1\x1a\x93\xfd\x95\x96\x15\x1e\xf9\x3a\xc9\xe9\xf4\xce\x4b\x46\x8d\xd9\xc0\x17\xf4\x1d\xd6\xf0\xc3\x06\x70\x30\x85\x51\x6d\xe9\xcd\xfc\x24\xc5\x15\x65\xa1\x16\x5c\x4c\x26\xa9\xa2\xf8\xc9\xab\x59\xbd\x12\x48\x71\x44\x63\x78\x2d\x58\x74\x94\x30\x0c\x64\x8a\xd4\xab\x45\x10\xf9\x65\x9c\x74\x18\x66\x49\x21\x0c\x70\xa7\x38\xcd\xa8\x6d\x83\x3f\xb1\x2c\x50\x0d\xe6\x59\xfd\x1c\x6d\x6e\xa2\x7c\xed\xa1\x1f\x7e\xd0\xdb\x3d\x6b\xb0\x32\x7c\x4d\xba\x54\x50\x3b\xd7\xf4\x02\xc3\x6c\x19\xa9\x1c\xc6\x58\xfc\x5a\x8b\xcc\x94\xa7\xe9\xa1\x76\xb5\xc0\xba\x2e\xbe\x64\x47\x74\xb8\x0a\xca\x61\x98\xe5\x0d\xf8\x33\x68\xa0\xae\xdf\x5a\x1b\xc5\x95\x5b\x9d\x46\xb7\x1c\xa3\xb0\x1e\x8d\x1c\xc7\x20\x4f\x3a\x9d\xa8\xa2\x79\xe1\x5d\x11\x5f\x84\x57\x89\x3f\x9b\x81\x1c\xe9\x67\xac\x79\x59\x65\x82\x7c\xb2\xd3\xa7\x92\x57\x5a\xe1\xea\x55\x5c\x7d\x0c\x57\xb6\xdc\xe1\xc7\xf6\xa9\xac\x03\xc9\x9d\x2f\x7b\x84\xd0\xc3\x65\xfc\x22\xa9\x9e\xeb\x08\xe4\xde\xb2\xce\x52\x87\xd0\x28\xa0\x54\x23\x0e\x18\xf9\xc5\x8e\xe5\xe0\x54\x14\x22\x4a\xf7\x5e\x04\x84\x7a\x86\xa8\x26\x4d\x6c\x61\x50\x29\x76\xed\x40\xe6\x8d\x79\xd3\xdd\xc7\x43\x35\x57\x3e\x59\x8e\x3a\x05\xde\xe7\xac\x69\x6a\x83\xc2\x7e\xe7\x7e\xe7\x7f\x93\x18\x2e\xf6\x2d\x6c\xeb\xaf\xdd\xc0\xc8\xb2\xb4\x6b\x54\xcc\x65\x25\xfc\x2b\x4d\x6d\x84\xe2\x6a\xe9\x38\x85\x3d\x5e\x83\x79\x90\x1a\x5d\x9d\xf0\x55\x1b\xf7\xc4\x6a\x73\x48\x03\x05\xca\x0e\x8b\x73\xac\xdb\x8b\xf5\x6e\x21\x74\x96\x8a\x9e\xb3\x63\xb3\x5f\x97\xa2\x1b\xc4\xb9\xf3\x89\x2d\x00\x9a\xd5\x67\xd5\x10\x4b\x72\xcf\x0c\x11\x20\x81\x75\xf6\x2e\x92\x49\x1f\xfa\x97\xc3\x84\x2b\x60\x03\x0a\xb3\x37\x22\x1c\x57\x38\xe6\xba\xf6\xa3\xf2\xdb\x69\xd1\xa6\xad\xec\xaf\x66\x41\xae\x5a\xb4\x7c\x22\x64\x25\xfa\x56\x0b\x2f\x2d\x45\x24\x1d\x21\xa3\x17\xb3\x0c\xd5\x0a\xe6\x80\xe0\x42\xd4\x2c\x26\xf4\x81\x79\x49\xf6\xca\x52\x58\xd2\x05\xea\x16\xd6\x96\xd2\x92\x5e\x90\x90\xde\xc8\x72\x5c\xbb\x2d\x7d\x6c\x61\xf7\xd0\xa9\x98\x38\xa1\xf8\x92\xaf\x65\xd0\xa3\x6d\x4f\x32\x01\x88\x1d\x4a\xbb\x68\x92\x1e\x21\xb5\xf7\xdf\x71\x9f\xd2\x02\xb4\x88\x48\xc7\x5f\x61\x6f\xca\xa3\x2a\x2f\x66\xd3\xdc\x7b\xde\xc2\xa6\x39\xd9\xb1\x30\x0a\x92\x47\xfd\x9d\x59\xf6\x43\xa3\xa8\xef\x4b\x0f\xb8\xa5\x38\x63\x17\x38\x22\x0c\x7c\x85\x5d\x85\x69\x1c\x24\xd5\x82\xbc\x98\x34\xc0\xf2\x4e\xc1\x6e\xbf\xe1\xfc\x2a\x23\x9f\x73\x13\x5b\x73\x8c\x53\x98\x1b\x86\x3c\x79\xca\x26\xa6\x44\x5d\xa4\xc3\x92\xef\x4d\x12\x93\x51\x14\x3e\xd6\x6d\x42\x34\xb1\xb4\x36\xc6\xca\xd6\xf4\xb1\x52\xef\x5f\x40\xc7\xe4\xa7\xe9\x7c\x8a\x03\xf5\x3e\xd1\x9f\x24\xd8\x0f\x6e\xa4\xfd\x4e\x39\x90\xcd\x23\x9a\xb6\xb2\x44\x44\xb3\xe5\xd8\x9e\x9d\x7f\x2d\x75\x68\x22\x8c\x0b\x4c\xd4\x93\x14\x2f\xcd\xeb\xdd\xfa\xa2\x79\xb4\x2c\xac\xbf\x50\xe2\x36\x48\x9e\xaa\x90\x0e\x39\x15\x20\x41\xfc\x76\x1e\xf0\xc9\xd0\x29\xc9\xab\x87\x55\xb6\xa5\xf2\x66\xb1\x6b\xe4\x45\xb8\x20\x84\x0d\xb7\x09\xa1\xec\xc9\x5c\xaa\xfa\xc5\x06\x2a\xd4\x8e\x32\x68\x05\x4a\x51\x43\x33\x61\xbd\x21\x79\x67\x37\x91\x58\x74\x65\xf2\x39\x18\xc1\x7d\x09\xfd\xb7\xf8\xb2\x64\x91\x15\x86\x79\x61\xf2\x8e\x42\x27\xad\x94\xbb\x27\xd9\x26\xe0\xe1\x4e\x9f\x34\x46\xd6\xf2\xfe\xcf\x5c\x61\x30\x63\xf1\x82\xca\xab\x63\x79\x0d\x66\x79\xc1\x1e\x40\x4e\x21\xcd\x00\xe0\x62\xaf\x90\x3c\x50\x39\xa6\xb6\x15\x61\xc4\x2c\x79\x99\x1d\x00\x33\x99\xb9\xc0\x11\x18\xf3\x16\x43\x13\x51\xca\x1d\xc0\x68\xe8\xec\x62\x58\xa6\xce\x00\x54\x58\x92\x90\xb4\x85\xba\x6d\x30\x39\x86\x0f\xdc\x7e\x76\x7f\x84\xe2\x69\x48\x64\x04\x0f\xf9\xf4\xd3\x55\x38\x99\xa0\x01\x16\x0d\x06\x28\xf1\xa3\x20\x9e\x4e\x6e\x1e\xe8\x70\x4f\xad\x26\xd8\x30\x79\x68\xff\x67\x0f\xa6\x94\x34\xfe\x15\xb8\x10\x9d\xe4\xc0\x64\x41\x12\x35\xd6\xf0\x35\x1e\xce\x33\x5c\x79\xc1\xa3\x51\xbd\xf0\x58\xe2\x0e\x8f\x99\x6f\x39\xc4\xa2\x07\x82\xee\xa1\x17\x64\x38\xc8\xff\xbf\x70\x9f\x99\x29\x18\x99\xbb\x71\x6a\xf6\x38\x89\x7a\x8c\xba\xa8\x62\xd3\x6e\xd4\x4f\xa7\x99\xcd\xb2\x43\x51\xfd\x9d\xf3\x2a\xc9\x50\x22\x53\x38\x95\x6e\x7b\xcd\x48\x6b\x6e\x71\xab\xa3\x4b\x5b\x5a\xd7\xa6\xb4\x42\xe3\xcd\xd2\xc4\x03\xb9\x02\x57\xc4\xb8\xcb\xd3\x20\xb3\x85\x74\x5b\xad\xb1\x44\xde\xd2\x78\x00\xfe\xd6\x80\xb5\x84\x36\xb3\x62\x0c\xc0\x6e\xda\x50\x93\x8b\x64\xd0\x4c\x41\xce\x93\xc9\xf2\x31\x47\x3f\x9a\xfa\x6c\x25\x35\x74\x9e\xc2\xd9\xee\x2c\x75\xcc\x44\xa9\x25\x0f\xe3\xf9\x91\x5a\x48\xd1\x77\xd3\x6a\xdb\x34\x03\x8a\x8a\x3b\x60\x7c\x99\xb3\x3c\x8d\x25\x7b\x02\x96\x43\xfc\xba\xbb\x3e\xdc\x12\x25\x4e\x28\xc4\xed\xdf\x6c\x1a\xae\x47\xd4\x8f\xbf\xdb\xde\xbd\x45\x64\xfb\xe4\x16\x94\xb6\x5d\x38\x97\xf2\x38\xb3\x2d\xde\xe2\x96\xd2\x8a\x5b\x3a\xec\x76\x7e\xf8\x1c\x8c\x7a\xd2\xf6\x2c\x51\xc8\x92\xea\x71\xe6\x52\xb5\xcc\xbe\xfc\x6d\xe8\xcb\x0b\xa5\x83\x6f\x40\x1d\xf1\x37\x51\x9b\x5b\x16\x5f\x29\x4d\xf2\x0b\x3e\xd4\xae\xb0\xb2\x8f\xdf\xb0\x87\xfe\x7c\x64\x0d\x76\xbe\x1d\x7d\x25\x85\x83\xb6\xbb\xc6\x99\x4b\xb9\x6b\x93\x5d\x08\x78\x22\xb6\x70\x71\x45\xc2\x9e\x0e\xaf\x90\x31\xd8\x33\xdd\xf6\x5c\xde\x9d\x54\x8c\xa5\x7d\x33\xba\x54\x83\x2d\x56\xc1\xa0\x66\x0d\x49\xe0\x54\xcc\x2b\xfa\x12\xf7\x75\x86\x1c\x00\xc2\x98\x1f\xb5\x7d\x49\x8f\x6f\xa0\x71\x10\x5e\xd3\x64\x20\x50\xc1\x3a\xa4\xd2\xd9\x9a\x1a\x66\x2a\xd0\x5d\x7a\x13\xeb\x89\xef\x1e\xfa\xe0\xbf\x80\x1f\x3f\xb0\x82\xf8\x5b\x67\xcc\xdf\xa2\x9e\xd8\xc6\x0c\x97\x55\x14\xdf\x8b\x31\x3e\x38\x8a\xa6\xa2\xf8\xa1\x18\x77\x49\x3d\xf1\x57\xe7\xdd\x5f\x5d\x59\xfc\xf5\xb7\x0a\x4f\xb1\xed\x71\x9c\xd0\x1e\x6e\xef\x28\xa5\x0f\x77\xdf\x5f\xd8\xb6\x0e\x79\x7c\x4b\xee\x1e\x45\x0a\xf2\x5c\x95\x27\x32\x5d\xca\x29\x2d\x59\xfe\xca\xdb\x73\xaf\xd3\xfa\x56\x93\x52\x3e\x78\x0e\xca\x65\x73\x4f\x2a\x39\x27\x0d\xc4\xcc\xf4\x93\x5a\xda\x49\x5e\xd1\x91\x78\x12\xf4\xa3\x39\x70\xf1\x53\x4d\x3e\x79\xe0\x67\x63\x0f\x59\x52\x50\xe6\xc7\xeb\xf7\xf1\xd0\x9f\xa0\x59\x3c\xb9\x19\x85\x13\x14\x8f\x10\xdd\xb4\xd8\x29\xde\x72\xe4\x65\xb1\xed\x37\xd5\x82\x5a\xc3\x0a\x63\x12\xaf\x77\xc9\xfb\xdb\x57\x66\xec\x20\xc9\xd6\x72\xf0\xbb\xc1\xd4\xc0\x46\x70\x3e\x20\x33\xa8\x13\xf1\x6e\x6d\x96\xc4\x59\x4c\x3e\xa1\x4d\x72\xfa\xd0\x0b\xb0\x7a\x68\x13\x45\xf8\x8a\x20\x50\x0c\x21\x9a\x4f\x26\x8e\x85\x22\x30\xc8\x97\x89\x14\xef\xc8\x16\xc9\x93\xcf\x49\xb1\x92\xdb\xa9\xd8\x7e\x1f\x0e\x12\x3f\xb9\x59\xa4\x23\x97\xf2\x83\x3a\x41\x41\xb6\x50\xa6\xf5\x24\xc2\x05\xef\xb2\x3f\x41\x61\x34\xc6\x49\xa8\x04\x70\x55\x22\x3a\xe8\x79\x46\xcd\x08\xa3\xe6\x74\x96\x08\xfb\xc7\x63\x0c\x83\x7b\x9c\xf0\x33\x18\xfb\x19\x47\x88\x85\xf2\xa0\x62\x90\x71\xaa\x44\xa8\x28\x0e\x20\x97\xbb\xe2\x4b\x9c\x24\x61\x80\x53\x74\x44\x15\x22\x21\x4e\x29\x03\x9f\xdd\xa0\x30\x62\xd9\x8c\x73\x04\x4a\xb4\xa0\xe7\x6a\x38\x5d\x16\x80\x21\x73\x39\xca\x2d\x13\x35\x90\x4c\xd4\xc1\xcd\x29\x25\x61\x45\xba\x29\x30\x49\x94\xfd\xc5\x42\x3c\x09\x7a\xe8\x05\x64\xca\x7a\xa1\x1b\x8e\xd8\xdb\x24\x7f\x53\x9c\x8d\xe3\xa0\xd0\x47\x5e\x2a\xad\xc7\xc8\xb7\x39\x9e\x21\x64\x86\x33\xa4\xe8\x2b\x06\xd9\x7c\x5e\x9d\x41\x0c\x67\xfe\x55\x64\x7e\x91\x18\x09\x11\x16\xf2\xb4\x7a\x2e\x73\xe2\xad\xf9\xc5\x14\x47\x16\xd3\x61\xb2\xa3\x14\x63\x81\x72\xe6\xc3\xce\x5d\x79\x79\x6b\xfa\x07\x2b\x02\xcc\x4c\x8a\xbb\x7e\x85\xc2\xb1\x34\xb1\xe3\xf4\x1d\x6f\x72\xec\xa7\x87\x57\x11\x23\xfb\x9b\xca\x0b\x52\xf3\x45\x55\xf8\x3c\x91\x47\xd8\x04\x79\x79\xf2\x62\x61\x3f\x68\xad\xc2\xe9\xb6\xd4\xfa\x7f\xd2\xf9\x8c\x88\x5a\x51\x98\xd5\x7c\x22\x9c\xb2\xad\xcf\x4f\x2e\xe6\x64\x74\xad\xe3\x81\x2c\x19\x14\x0a\xc6\x29\xf7\xb8\x4d\x5e\xa4\x28\xe7\xe8\x21\x55\x0a\xf3\x49\xa7\xab\xd4\x84\x20\x77\x50\xd9\x0f\x1c\xdb\x0e\xe2\x8a\xf1\x11\x4e\x70\x34\x24\x0d\xc0\x38\xcf\xf4\xf5\x6a\x0c\x03\x93\x8b\x6d\x00\x9d\xfb\x0c\xb2\xa5\xc6\xb0\x31\xd5\x1d\x58\x29\xa9\xcc\x34\xa9\xca\x7b\x1e\xd1\x71\x80\x09\xa4\xab\xd6\x0c\x81\xba\xc5\xe7\x23\xcf\x60\x53\xa9\x8a\x6b\x38\x22\x4a\x43\x48\x39\x00\x52\xa9\xfe\x9d\x79\x25\x8f\x58\x8e\x36\x19\xdb\xe4\x77\x16\x0b\x79\x11\x2d\x57\xcc\xf1\xcc\x46\x60\xc9\xe5\x71\xb2\xcd\x95\xcb\x23\xa8\x4b\x6b\x84\xbf\x53\xd7\x89\x93\x6a\x78\xf1\xbb\x90\x4d\x91\xbb\xba\x63\xae\xd0\x21\x63\x66\x2c\x49\x00\x90\x14\x98\xd0\x07\x01\x4a\xe3\x29\xa6\xa9\xa7\xd0\xd5\x18\x47\xe8\x26\x9e\x27\xc2\xcc\xde\x27\xe2\x2c\x05\xfe\xc0\xb1\x73\xef\xbb\x0b\xea\x8e\xce\x45\x7b\x19\xa2\x0c\xa0\x56\x33\x47\x46\x0c\xfd\x1d\xb7\xbb\x85\x68\x94\x9a\xd3\x7e\x3c\x23\xc2\xce\x2c\x97\x7b\x98\xbc\x73\x0f\x71\x4a\x02\x06\x1a\x26\x45\xa6\x9a\x82\x26\xf2\x81\xa7\x94\xad\x4e\xba\x7f\x96\x95\x5f\xee\x38\xee\xd0\x88\x72\x89\x2d\xfa\x67\x5d\xe3\x22\xe2\x21\xbf\x6c\xfb\xe0\x4f\xc1\x68\x62\x41\x3d\xc4\xb6\xea\xbc\x98\xbe\x59\xcb\x00\xab\x85\x5b\x2c\x99\xce\x33\xb9\xf8\x39\xda\x94\xda\x57\x3f\x2d\x91\xba\xc8\xb1\xc9\xee\xa0\xab\x38\x7a\x91\x51\xf9\x99\xbb\x3b\x4a\xc1\x0b\x27\x71\x3c\x43\xfe\x20\xbe\xb4\x6c\x83\xc5\x5d\x7e\xc1\xa1\xbd\x70\x77\x18\xb8\xa8\x68\x55\xee\xa7\x78\x5b\x22\xaf\x56\xa9\xc5\x23\x0e\x27\xd0\x53\xb0\x7f\x59\x66\xdd\xd8\x36\xbe\xe1\x24\x8e\xf0\x23\x70\x3c\x80\x8b\x36\xf3\x3d\x04\x5e\x94\xd8\xc9\x48\xb1\x85\x1b\x99\x9c\x8b\x44\x15\x8e\x38\x3f\xb5\xda\x93\xd9\xcf\xc8\xd6\xdb\xfd\x08\xf9\xe0\x79\xab\xc5\x22\x2c\x8c\x2c\x64\xc4\x79\x2f\x06\x61\x0b\x4f\x23\x8c\x1f\xd4\x70\x88\x69\x78\x11\x85\xa3\x70\xe8\x47\x19\x0b\x28\x19\xd2\xde\x03\x48\xda\x8e\xed\x98\xfc\x8b\xe4\x41\x4c\xcf\xca\xf2\x9b\x07\x08\x1b\x63\x36\xaf\x93\x85\x23\x0c\xbe\x6c\x7a\xb5\x60\xac\x91\xd5\x2c\x4c\x8c\x94\x76\x83\xb1\x70\xd0\xf0\x83\xa5\x7a\x91\xfd\xb3\x95\x8d\xdd\xb0\x85\x71\x68\xff\xcb\x03\x38\xab\x5f\xd7\xeb\xf5\x46\xbd\x59\x6f\x79\xa8\x7e\x5d\x6f\xd7\x3b\xf5\x6e\x7d\xfd\xfc\xd1\x00\x7b\xa8\x5b\x3a\xf4\x0a\x0b\x5f\xc7\x67\xc4\x58\xb1\x57\xcc\x21\x18\x96\x2b\x7f\xa0\xff\x7e\xf9\x02\x31\x7b\x35\x51\x63\x84\x2a\x62\x7a\xbf\xdb\xb4\x28\x0a\xe5\x3f\x80\x2a\x19\x0d\xf1\x9f\xa5\x8d\x49\x75\x00\x94\x3c\x26\x38\xba\xc8\xc6\xd4\xf4\xc8\xc9\x45\xca\xc7\x8c\xc9\x17\xca\x72\x91\x62\x76\xa2\x61\x1c\x10\x7a\xc7\xf4\x87\x4e\xee\xf0\xba\x38\xf6\xa7\x20\x00\x1c\x0d\x6b\x7b\xf8\xda\xdd\xe6\xa2\x00\x32\xa5\x56\xfb\xd2\xc1\x5d\x72\x62\x2d\x11\xd9\xc5\x12\xd7\x60\x51\x58\x17\x4b\x15\x65\x48\x3e\x66\xa3\x8d\xa5\xa2\xb9\xb0\xa9\x70\xc6\x72\xe1\x53\xf5\xe5\x0b\xda\xc3\xd7\x85\xe1\x5b\x16\x10\xd0\xd0\xcf\x70\xc4\xf6\x7c\x95\x82\x1c\xcc\xdf\x4d\x48\xd2\x3d\x6c\x3e\xe0\xa7\x8c\x1b\x4a\x94\x09\x69\x7e\x97\xbd\xd7\x2d\x8b\x4b\x19\xda\x10\xd8\x35\x78\xfc\x0c\xf1\xa6\xe9\x4e\x69\x06\x25\x75\xa6\x44\x03\x3b\x2f\x17\x8e\x84\x0c\xec\x2f\x06\xc3\xb2\xf8\x2a\x66\x63\x5f\x84\x3a\xc8\x49\xcc\x5d\x3a\x4c\x4f\x72\x1e\xa3\xf0\x1c\x07\xf0\x13\x95\x25\x51\xf8\x79\x1d\xa3\x53\xfd\x89\x3f\x9d\x21\x7c\x0d\x91\x24\x07\xa1\xde\x39\x7a\xaf\x4a\xca\x98\xb7\x0d\xf4\x3e\x75\x68\x0b\x92\xa2\x20\xfe\x2f\x47\xa0\x74\xa8\x4f\x44\xd2\x08\xc3\x56\x8b\xfc\x0c\xf9\x28\x0b\xa7\x16\x89\xdb\x16\x92\x5d\xee\xae\x3b\x29\x84\x3c\x38\xa4\x28\xda\x24\xe8\xb1\x59\x38\x0b\x79\x54\x6c\xf2\x4f\xa5\xd9\x46\xab\xa8\x12\x52\x8c\x7f\x44\x1b\xd5\xaa\x88\x96\xed\x94\xe2\x29\x1c\xb5\xc7\x2b\x28\x14\xe1\xb6\xbf\x6c\xe6\x4d\xbf\x7e\xcd\xdb\xb0\x94\x17\x8d\x96\x10\xfc\x9d\xdb\x92\x3c\xa6\x74\x71\xdd\x6b\x4c\xdd\x51\xee\xcb\x76\x7f\x13\x99\x83\x5d\x26\x63\xb0\x49\x85\x62\xb3\x5d\xd9\x54\xd1\xb4\xe5\x58\xf1\xc3\xc8\x1f\xe8\x27\x0f\xe9\x00\x50\x96\x9d\xd2\x18\x1c\x44\x08\x54\x04\xc3\x30\xbb\xaf\x28\x98\x2f\x4e\xb1\xba\x1c\x4c\x8a\x7c\x2e\x1b\xba\xd7\xc2\x9a\x4c\x39\xca\x16\x17\xc9\xc9\x64\xec\x0c\xc3\x22\xaa\x9d\x09\x18\x3c\xce\xfc\x26\x2c\x1d\xfa\x07\xa4\xdf\x6a\x12\xd2\x4f\x15\xbe\x60\x21\x78\x45\x94\xda\x44\x07\x7e\x36\xae\x0d\x71\x38\xc9\x6b\xae\xa1\x25\x22\x12\xd9\xcf\xbf\xa5\x76\x1e\x87\x39\x92\x71\xfc\xbd\xab\xdd\x27\x3b\xee\xca\xb4\x60\x9c\x77\x55\x5a\x58\x74\xce\x95\xc1\xc2\x49\x8d\xe2\x2a\x47\x3f\x37\x4f\xce\x35\x93\x46\x98\xf9\x7d\xdd\x69\x52\x47\xea\x2d\x3f\x05\x92\xd8\x30\x0a\x27\x13\x1e\x76\x96\xb9\x49\xc0\x79\x6b\xb1\x50\xc2\x0f\x73\x91\xed\xd0\x2b\x83\x72\xba\xf8\x94\x9a\x65\x06\xa9\x14\xa1\x3c\x94\xf1\x59\x89\x23\x18\x73\x05\x69\xb8\x4f\x5a\xb4\x84\x4c\x26\x91\xfd\x88\x25\xb3\x07\xf3\x40\x45\xbe\x26\xea\x0d\xf9\xf4\xd3\x95\x3b\xca\xfc\xa7\x2b\xb4\x49\xfe\xeb\x48\xa0\x36\xfd\xf4\x07\xd9\x66\xae\x5b\x7e\x80\xbb\x1b\x03\x3d\xfc\xba\x28\xe6\xa7\x9f\x91\xcc\x39\x0a\xee\x09\x4a\xdc\xdd\xd1\x56\x2b\xf5\xeb\x97\xf5\xee\x4b\xf4\x23\xe9\xc2\x1f\xb0\xa7\xef\xee\xee\xee\x56\xd1\x0a\x7d\xf1\xd3\x4f\xa8\x7e\xdd\xa8\xc3\x76\x4f\x10\x70\x6c\xf7\xb4\x8b\x95\xfa\x75\xbb\xdb\xa9\x53\x60\x57\x3a\xb0\xab\xb2\xc0\x60\x78\x71\x3a\x07\x4f\x9f\x0a\xa0\xf1\xfa\x35\xad\x89\x56\x10\x8c\x74\x61\x7d\x56\x77\x6d\x13\xea\xb0\xbf\xe2\xb2\x2b\x9b\xa8\x5e\xeb\x38\xcb\xc0\x98\xb2\xa2\x3f\x52\x7b\x1b\x4e\x6d\x55\xf4\x13\xaa\x75\xd0\x7f\xa0\x06\xea\xa1\xd5\x46\x19\x11\xc5\xe0\x1c\xaa\xb8\xe1\xa1\x64\xe8\x0f\xc7\x98\x65\xd7\x59\x2c\x70\x90\x9a\x9f\x08\x3d\x26\x95\x0a\xad\x4a\x8e\x4a\x0a\x92\x64\x37\x91\x06\xc3\x7e\xc5\x44\xab\x6e\xa2\x4f\x49\x85\x96\x07\x82\x5c\x1f\xac\x5b\xfa\x74\x95\xe7\xf0\xa9\x88\xf2\x39\x7c\xf4\x05\xd5\x4b\x86\x35\x8f\xf0\x95\xe4\xec\x04\xb7\x8e\x4c\x01\x12\xf1\xf4\x3d\xcf\xb4\x91\xb4\x3b\x9f\xb2\xa3\xfd\x22\x43\x1a\x1c\x0d\xc1\x90\x86\xfe\x6b\x37\xa4\xd9\xc3\xd7\xa6\x26\xc0\x06\x8e\x14\xdc\xa4\x40\x6b\xf4\x77\xb9\xf8\x9b\xba\xfa\x62\x8c\xaf\x4b\xab\x30\x4a\x9c\x3c\x97\x8c\xaa\x59\xaa\xf5\x87\x62\xe4\x63\x7c\x6d\x86\xd0\x64\xe3\x27\x1d\xed\x17\x27\x12\xb2\x06\xce\xbc\xeb\x31\xf5\xaa\xf4\xc9\x33\x5d\xf6\x18\x49\x67\xdd\x04\x34\xc6\xd7\xfd\xb1\x9f\x94\xce\xb3\x95\x2e\x3c\xd0\x41\x8e\xb4\x90\x1e\xe4\xae\xee\x79\x88\xe3\xd8\xb1\x35\x0e\x60\x09\x90\x76\x35\x57\xfb\x34\xba\x55\x1b\xbf\xb3\x55\x25\xed\xd4\x47\xc5\x75\x1d\x0c\x42\x80\xfb\x3d\x0e\xa3\xca\x8b\x17\x77\x88\xb8\x29\x51\x38\x5d\x6f\xcb\x68\x7a\xf8\x4a\xa1\x84\x5b\x7e\xc1\x38\x84\xa7\xbf\x5e\x6a\xe2\x8b\x8d\xda\x6c\x8b\xf5\x58\x3e\x52\x26\xad\xb2\x5c\xa2\x14\x5a\xe7\x3d\x3f\xba\xd0\x47\x76\x94\x59\x66\xd5\x5c\x2d\x93\x9a\x4e\x6e\x94\x6d\xa1\xcd\x82\xfc\x98\x74\xb5\xb4\x40\x33\x01\x9d\xde\x8f\x32\xd6\xd9\x5a\x3a\x1f\xa4\x59\x52\x09\x3d\xd4\xac\x7a\x90\x84\x2f\x57\x59\x90\x15\xb5\x51\xb5\x39\xe0\x2e\xbd\xe7\x29\xc3\xb4\x86\x9a\x65\xdd\x67\xdf\xfb\x59\x18\x35\xca\x6d\x5a\xac\x2c\xdf\xb7\xc4\xe3\xdd\xb6\x2e\x56\xfd\xaf\xdb\xbd\xca\x22\xf0\x50\x6b\x6a\x02\xed\xd9\xf7\x30\x8a\xcb\xff\xa8\x6d\x8c\x0e\xc7\x37\xbc\x93\x49\x08\xd2\x1d\x89\x4e\x5d\x6d\x94\xc4\x53\xf2\xb6\x1f\x07\x18\x36\xa9\xb2\x1b\x92\x0c\xf0\x1e\x7b\x92\x42\xb7\x77\xdf\x96\x04\x39\x2e\xb5\x18\xbe\xe9\xcd\x89\xad\x22\xba\x3f\xc9\xcb\xad\xfc\x16\x25\x6a\x2d\xb7\x4b\x89\x6a\x62\xa3\x12\x6f\x1e\x7b\xaf\xd2\x9a\x5e\x94\xcb\x39\x94\xb4\xe8\x79\x6f\x6b\x43\x46\xd0\x5b\x59\x25\xe4\x6b\x42\xdf\xaa\xec\xba\xc5\xa5\xb7\x2a\x0d\xe1\xb2\x3b\xd5\xc7\xd3\xdd\xd5\x8d\x72\x1b\xd5\xc7\x6c\xb4\x21\xb6\x29\xf6\x70\xb7\x4d\x8a\x36\xfa\xd7\xed\x51\x25\xdb\x7f\xa8\x95\x35\xcf\x46\x1b\xf6\x0d\x8a\x8c\xe2\x63\x6e\x4f\x59\x72\x53\x60\x60\x14\x60\x72\x44\xff\x78\xbc\xdf\xe7\x9e\x4e\x15\x9c\x0e\xfd\x19\xae\x14\x6c\x9c\x26\x5b\x46\x43\x3f\x1b\x8e\x51\xc5\x4c\x1f\x0d\x28\x8c\x93\xf8\x0a\xe8\x16\x32\xae\x54\x5e\x1c\xf8\x93\x51\x9c\x4c\x71\xc0\xa6\x21\xf0\x33\xdf\x4c\x41\xb7\x3c\x03\x97\x27\xf5\xee\xfc\x9b\xcd\xd5\x32\x64\xf2\x4d\x33\x6f\xa0\x30\xca\xba\x73\x32\x2c\xcf\xb8\x59\x1d\x97\x31\x80\xb2\x35\xcc\x23\x46\x3d\xd4\x42\x40\xa1\x2b\x0e\xa7\x5a\x3a\x00\x8d\x48\xc1\x0b\xb9\x30\x71\xc0\xb2\x99\x49\x5e\xe8\xce\x4c\xbc\x92\x9d\xec\x8d\x94\x12\x6d\x3a\x4f\x33\x34\xc0\x28\x24\x23\x3a\xc5\x51\x46\xf3\xac\xf9\x70\xbd\x9e\xe0\x4c\x78\x2c\x94\xca\xed\xab\xe5\xe9\x54\x95\xfb\x34\xc7\x21\x75\xad\xca\x13\xc4\x7f\xc6\xb3\x0c\xcd\xa3\x19\x4f\x1a\xa8\x66\x07\x95\x6c\x5a\xea\x16\xee\xfb\x86\x8d\x03\x64\x1a\xdc\x12\xa3\x20\xbc\xc4\x5c\x9f\x4b\x9a\xc1\x41\x76\x57\x66\xcd\xa3\x8d\xf4\x0b\x96\x44\x9b\x25\x31\xcd\x62\x14\x66\x29\xf7\x8a\x41\x84\x82\xef\x7b\xc7\x34\xb0\x22\x4f\x13\xe2\xba\x2f\x99\x4a\x65\xdd\x65\xe6\x7d\x08\xac\x94\x6d\x36\x03\x90\x81\x93\x79\x2a\x6a\x3b\xab\xce\x94\x68\xf9\x68\xdb\xcf\x7c\x2e\xac\xd7\xcb\x4a\x9a\x5b\x41\x90\x42\x1b\x3c\x2f\xb8\x63\xa4\x19\x2d\x94\xdf\x14\x45\x90\x05\x23\xf3\x38\x33\x76\x41\x74\xcd\x33\x27\x00\xca\x2f\xa9\x4f\x89\x2f\x59\x50\x52\x7b\x62\xe0\x78\x8f\x33\x99\x9f\x28\x3a\x95\x17\x26\xbf\x2f\x55\x6f\xf1\xde\xc8\x4a\x96\x49\x66\x6e\xba\xd7\xe7\xe9\xe8\xe4\x80\xa2\xd2\x00\xb1\x60\xa2\x2a\x28\xd9\xc7\x19\xc8\x68\x41\x9c\x48\x46\x6b\x12\x53\x06\x0c\x17\x47\x4a\xdb\x82\xae\xb9\xc8\x97\x9b\x12\xd9\x80\x19\x44\xbb\xb2\xa9\x26\x49\x2f\x4b\xc1\x3c\xd7\x69\x8a\xfc\x4b\x3f\x9c\x40\xc4\x2e\xca\x17\x80\xd9\xb9\xa9\xe6\x54\x72\x56\x09\xa3\xcb\xf8\x33\x4e\xf5\x24\xc3\x15\x96\x1c\xd8\x43\x57\xe3\x70\x38\xb6\xb2\xea\xc1\x4d\x01\xab\x36\x5b\xe5\x0b\x65\x10\xc7\x13\xec\x47\xb7\x28\x88\x77\x27\xf3\x74\x8c\x7e\x19\xe3\x8c\xc6\x33\xe1\xb9\x68\xc1\x5d\x6b\xe6\x27\xc0\x28\xd8\xab\x9c\x6b\x0b\x76\x7d\x87\x70\x20\x82\xd3\xc3\x88\xdf\x7f\x9b\x17\x00\xb7\x29\x21\xb9\xd6\x0c\x4f\x95\xeb\x8a\xcb\xb1\x24\x18\x7b\xa6\x60\x35\xd6\x2a\x2d\xaa\x2c\x3e\x3a\xe0\x4b\xea\x4c\xd8\x12\xc9\x89\xdb\xa2\x2d\x21\xaf\xb9\x71\x1a\x8c\xac\x4b\xad\x42\x3e\x4a\x86\x66\x2e\xba\xe7\xc5\x73\x59\x61\x53\x4b\xc9\x5c\x54\x98\x43\xcf\x6b\xdb\x23\xfa\xf5\xe3\x79\x94\x71\xfa\xb2\x30\x13\x02\x34\xa2\x89\x84\x8f\x21\x6e\xf1\xa6\x8a\xff\x9a\xd6\xe4\x2b\x93\x17\xb9\x86\x9c\x61\x70\x1c\xcf\xa3\x00\xcd\x67\xd4\xa1\x70\x38\x99\x07\x58\xa3\x7b\xb3\x9a\x86\x51\x6e\xe4\x22\x7f\x28\x1f\xdb\x56\x60\x11\xc4\x57\x91\x8c\x47\x1c\x4d\x6e\xd0\x68\x2e\x16\xa5\x25\x92\xfe\xda\x1a\x9a\xe0\x94\x3a\x55\xda\x65\x2d\xe0\x1b\x09\x9e\xfa\x61\xa4\x0a\x57\xe5\xfa\x35\xf5\xaf\x2b\x4a\xbf\xe0\xe2\x14\xad\xda\x32\xb3\x7b\x8b\xaf\x54\xc5\x9c\x53\xcd\x83\x6b\xca\x81\x92\x39\x1e\x4a\xeb\x3f\x22\x89\x00\x5d\xf4\x04\xb4\xe1\x24\x27\xf2\x55\xed\x63\x18\x55\xe4\x26\x7f\x44\x6d\x4f\xa1\x33\x9b\xf9\x24\xcf\xe0\x6d\x23\x12\x42\x77\x12\xc0\x62\xb7\x2d\xca\xe7\xa9\x9a\x85\xfd\x7e\x2d\x8f\x80\x78\xbb\x22\xad\x27\xa7\xd1\x04\xc1\x0c\x27\xe4\x34\x29\x36\x86\xd5\xfc\x80\x00\xce\x90\xf6\x8a\x8c\xbb\xa8\x7b\x90\xe0\x2a\xb6\x5c\xf5\xae\x39\x46\x4a\x0a\xac\x9c\xe1\xc3\x94\x9b\x45\x15\xee\x2b\xb3\x30\x3d\x19\x96\x3c\xa2\x16\x34\x14\x4e\x86\x56\x37\xe5\x99\x5e\x4c\x95\x3c\xb6\x68\x11\xb6\x6e\x85\x93\x8a\xbf\x27\x37\x7d\x5f\x63\xb7\xd2\x59\x28\x0b\x9d\xbc\xee\x69\xe5\xe6\xd8\x0d\xff\x22\x93\xb7\x4f\xc6\x86\x98\x63\x62\x9d\xb1\x42\x8b\x37\x95\x87\x89\x93\xa6\x23\x13\x3d\x3f\x83\x8f\xfd\x14\x32\xe4\x3a\x4f\xdc\x0b\x53\x91\xe7\xec\x5a\xf6\x81\xa2\x93\xce\xa0\xd3\xb0\x6b\x38\x45\x71\x24\x1d\x85\x1b\x5d\x54\xe9\x34\x9a\x60\xc9\x5a\xb5\x1c\x8b\xf7\x68\x65\x7e\x0c\x16\x8f\xf6\xf3\xf0\x83\x44\x7d\x2d\xca\x40\x56\x18\x30\xb5\xc8\xd5\x8c\x0e\xc2\x12\x39\xc9\xef\x1a\xdd\x8e\x34\x84\x68\x88\xe4\x45\x41\xee\x4a\xdb\x90\x88\x39\x50\x42\xb7\x9d\xec\x6d\x35\x3b\x5d\xbb\x93\x58\x51\xaa\xeb\x3b\x47\x58\xe3\xb1\xd5\xca\x87\x59\x3b\xc1\x22\xbc\x87\x5b\x43\x60\xaa\x21\x16\x58\x62\xe7\x9a\x14\xbe\x70\x1e\x5e\x65\xc2\xe8\xe5\x21\x54\x24\x80\xb0\xac\xe2\x51\x4b\x38\x56\x12\x80\x56\x98\x97\x29\x35\xe8\x7b\x33\x1b\x0e\xcb\xc6\xcc\x37\xe4\xe3\xe5\xc6\xfa\xe3\x2c\x00\x96\x21\x0f\x36\x4d\xcb\x5f\x3e\x63\x9f\x33\x82\x30\x05\xae\xc7\x11\x2e\xed\x42\x44\x59\x11\xf3\x1f\x5a\xb8\xbc\x97\x98\xf3\x39\xe0\x55\x79\xc1\x90\xb2\xe9\x52\xd4\x92\x8b\x55\x27\xb4\xa0\x4c\x28\xca\x18\x38\xd6\xa3\x43\x23\xc1\x14\x36\x2a\x04\x0b\x79\xb0\xf1\x25\x42\x3a\xc1\xd7\x06\x4a\x3a\xc7\x9a\xe2\xef\x83\xf9\x4e\xec\xb2\x24\x37\xa9\xc0\xc5\xc9\x20\xd1\x87\x18\x50\xf6\x33\x9a\x2f\x9e\xd5\xcc\x63\x86\xa2\x30\x45\x78\x34\xc2\xc3\x2c\xbc\xc4\x93\x1b\xe4\xa3\x00\xa7\x59\x32\x87\x67\x0f\xe4\xf4\xd5\x38\x1a\xe2\x52\x51\x46\x4b\x52\xa8\x92\xe8\x01\x50\xca\x03\x72\x43\x89\xe5\x35\x17\x64\x10\x1e\x68\x67\x40\x9b\x9c\x1c\x45\x32\x21\x87\x5a\xc2\x51\xba\x88\xd0\x0b\xaa\x2d\xa6\x7a\x5e\x74\x29\xba\xdf\xb5\x8c\xaf\x79\x20\x2a\x06\x83\x16\xad\x95\x45\x02\xfc\x12\x9c\x55\x1a\x21\xce\x64\x77\xa5\x79\xb0\x2e\x1e\x52\xde\xb5\x78\xa4\xe4\x77\x9d\x46\x73\xad\xd5\x2c\x27\xe6\xa7\x4c\xe3\xa3\xc4\xbf\xf7\xd9\xa4\xbd\x10\x81\x93\xc2\x28\xc3\xc9\x48\xb2\x16\x46\xce\x55\xc1\xf9\x2b\xeb\x3a\xa7\x5a\xba\xdd\xb2\xf8\x88\x3e\x1a\xe3\xc9\x0c\x27\x44\xfc\x29\xb1\x08\x76\x19\x6e\xcc\x37\x58\x47\xf9\x2b\xdc\xe3\x51\x99\x49\x77\xaa\xa0\x5d\xad\x7d\xa2\xbd\xda\x83\x2e\x55\x6c\xc2\x96\x5b\x3f\x27\x57\x55\x8c\x07\x01\xb4\xeb\x7e\xcf\x58\x17\xf6\x00\xb8\x48\x3d\x2f\xb2\x95\x08\x87\x45\x35\x8b\x58\x9e\xe1\x52\xa5\xf0\xe5\x8f\x8d\x56\x7a\x22\x2c\x79\xef\x60\xab\xff\xf0\xf4\x44\x44\x68\x1e\x94\x82\xb4\xc0\xe8\xea\x6f\x41\x53\x7b\x53\x7f\x58\x8a\xae\xa6\xfe\xf0\x3e\xb4\x25\xaa\xdf\x8b\xbe\x3e\x63\xbb\x0a\x49\xa2\xaf\xfe\x27\x40\x8b\xcc\x03\x25\x32\xda\x08\xad\xbb\x1c\xb1\x15\x1e\x7f\x85\x26\x69\x81\x0f\x03\xc1\x06\x9c\x18\xd8\x8f\xdc\x8b\x81\x67\x6a\x81\x90\xbe\x07\x7e\x36\xa6\x61\x7d\x9f\xf1\xf7\x6c\x98\x5f\xe5\x91\x7e\x6f\xcf\xbd\x4e\xfb\x5b\x0d\xef\xcb\x90\xa9\xf0\x70\xc4\xd5\x07\x8f\xf7\xcb\x21\x2f\x1b\xf7\x57\x60\x28\xc7\xff\x75\x05\xfd\x15\xdf\x21\xf8\xaf\x2d\x80\xae\x79\x45\xc1\xa3\xc6\xe6\x53\x26\x11\x80\x14\x0d\x56\x7a\x5f\x10\x9e\x46\xa9\x2d\xb9\xc0\xb8\xc2\xc8\x76\xdb\xe5\x4c\xb4\x58\x59\x6e\xa4\x25\x1e\xef\x66\xa6\xc5\xaa\xff\x75\x76\x5a\x65\x11\x78\x28\x4e\x39\x80\xf6\xec\xa6\x5a\x14\x97\x7f\x80\x2d\xb1\x51\x7e\xea\xcf\x84\x70\x38\xf5\x67\xcb\xc7\x5e\xb0\xb8\x88\x9b\x20\x5c\x56\x99\x74\xcc\xef\x6a\xb0\x8c\x56\x36\x51\xcb\x6d\xb3\x7c\x93\xe1\x86\xc5\x68\x99\xfe\xb9\x4c\x97\xe9\x9f\xd3\x80\x99\x03\x6e\xe6\x80\x2b\x21\x5a\x41\x8d\xaa\xc5\x26\x9a\x7f\x29\x63\x19\xcd\x01\xb7\x34\xc0\x4d\x27\xe0\xa6\x15\xb0\x1d\x72\x96\x84\xb3\x09\x5c\xbd\x54\xe8\xb0\xbc\x7e\x0d\x7e\x13\x5f\xe8\x73\x93\x3c\x6f\x90\x47\x40\xc1\x06\x45\x4c\xc5\xef\x74\x2a\x2a\xbf\xa3\xd7\xa4\xf5\x1f\x7e\x40\x80\xcd\xef\xe8\x47\x54\xaf\xad\x77\xa4\x19\xaa\xbe\x42\xbf\x17\x84\xbb\x90\xe6\x9e\xda\x82\x4f\xfd\x19\xd8\xcc\x6e\x65\x95\x0a\x47\x18\x3a\xdd\x45\x3f\xa2\x4a\x0b\xad\xa2\xdf\xab\xac\xa7\xad\x91\xd5\xdb\xc9\x88\xcf\x60\x2a\x2e\x82\x80\xa7\xfb\x36\xa9\x91\x7d\x20\x28\xa1\x4d\x24\xa1\xd3\x35\x9c\x49\x20\xb6\x5e\x5e\xdc\x6e\x1c\x3c\x0e\x27\x18\x55\xe4\x7e\xb2\x70\x01\xae\x58\x23\xd6\x61\x91\x9b\x59\xbe\xcf\x8c\xb3\xca\x50\xef\x61\x27\xaf\xf0\xe4\xbb\xdb\x59\x0a\x56\xbb\x14\xa3\xff\xa6\x4d\x2d\xd9\x0e\x41\xed\x7a\xe4\xad\xa4\xbc\xb9\xa5\xa8\xb5\xe4\xe6\x20\xea\x09\x43\x79\xf1\x46\x18\xca\x2f\xe6\xfb\x46\x89\x04\x5f\xe2\x24\xc5\x07\x52\xc1\xfc\x95\x2d\xae\xd9\x77\xf9\x67\x27\x75\x17\x02\xb5\x6d\x01\xfc\x4f\xe7\x3f\x84\xfd\x90\x15\xca\x3a\x58\xc8\x69\xd4\x86\xcf\xf8\xc2\x66\xb6\xf9\xbf\x57\xcf\xd1\x26\xfa\xbd\x5c\xac\x4e\x0b\x4b\xd9\xbf\x88\xe2\x04\x7f\x35\xae\x22\x81\xdc\x8f\x02\xf0\x73\xce\xa7\x3b\x24\x6f\x0e\x47\x8b\x78\x86\xd4\x0e\x85\xf1\xdd\xe6\x26\x5a\x6d\x2c\xe0\x49\x32\x85\xc9\xb5\xef\xc4\x88\xad\x22\x41\x22\xd2\x5e\xa6\xf8\x7d\x1c\xcf\xf2\x25\xe1\xe9\x38\x78\xd2\x8c\x2a\x22\x87\x76\xe3\xe9\xcf\x7a\xe8\xc5\xd6\x9b\xfe\xf6\xce\xee\xdb\xbd\xfd\xff\x7a\xf7\xfe\xe0\xc3\xe1\xd1\xff\x3e\x3e\x39\xfd\xf8\xf3\x2f\xbf\xfe\xfb\xff\xf8\x83\x61\x80\x47\x17\xe3\xf0\xf7\xcf\x93\x69\x14\xcf\xfe\x3b\x49\xb3\xf9\xe5\xd5\xf5\xcd\x1f\xf5\x46\xb3\xd5\xee\x74\xd7\x37\x5e\xae\xac\x6d\xb2\x08\xb7\xe2\x68\x27\x16\xed\xd2\xa8\xe6\x43\xec\xf0\x4a\xc9\x2d\x37\x14\x0b\x53\x9b\x28\xa4\xb5\x63\x73\x53\x21\x33\x1d\x3a\xf6\x1b\xe6\xd8\x95\x12\x21\x49\x5a\x1e\x39\x35\xc9\x0e\x2c\x68\x15\x35\xaa\xe7\xe0\xbd\x92\x0b\x4c\x4d\x93\xb8\x38\xd0\x66\x19\xa0\xd5\x73\xbe\xc1\xcb\x62\x98\x05\x2a\x15\x88\x22\x25\x72\xcf\x17\x22\xcc\x00\xfa\x5f\x68\x8b\xb2\x6f\x4d\x54\x1c\xbc\x07\xb1\x21\x5e\x59\x51\x3e\x08\xb2\x15\x3f\x18\x45\x1a\xb1\x25\xad\x61\x11\x6e\xf3\xdc\x3d\xfa\x21\x5f\xda\x23\x5e\x39\x33\xfb\x74\x9e\x8e\xfe\x4f\x47\x7f\x71\xf4\xff\x78\xba\xbb\xda\xe8\xa2\x37\x3b\xa5\x1d\xb4\x1a\xdd\x37\x3b\xb2\x8f\x56\xa3\xab\x3e\xc1\xd7\xbb\x3b\x6d\x51\x64\xfe\x5a\xc7\xad\x92\x38\x3c\xa0\xf3\x56\xa3\xeb\xf4\xde\x6a\x74\xff\x01\x1a\x81\xf2\x87\x75\x18\x8c\xfb\x9c\xd5\xed\xfe\xfe\x60\x19\x15\x07\xf8\x28\x0e\xa3\xcc\xe5\x64\xdc\xe8\x3a\x9c\x8c\xad\x87\xe9\x1c\x53\xb7\x97\xb1\x68\xb2\xac\xab\xb1\x04\xf4\x1e\x27\x28\x9d\x88\xef\xe5\xac\x06\xb4\xb9\xec\xda\xf8\xa6\x8f\x51\x74\x55\x09\x97\x35\xbe\xf8\x96\xf2\x59\x83\x4a\xcb\xf9\x1a\xf3\x5a\x42\xbe\xe5\x2f\x1e\xdb\xd3\x58\x6d\xb8\x9c\xa3\x71\x03\x64\x1f\x81\xa1\xea\x66\x4c\x44\xa0\x7c\xb1\x34\xc9\x62\xd1\x82\xb0\xb9\x29\xdc\x25\xe5\x68\xa3\xf3\x63\xf9\x50\x18\x8c\x2c\xdf\x97\xd8\xc3\xa4\x7d\xea\xfd\xbd\xf7\xa9\xf7\xdf\xc0\x3e\x55\x06\x87\x87\xde\xa7\xac\xcb\xe9\xfd\xce\xd3\x36\x25\xfe\x1e\x6c\x9b\x4a\xaf\xfc\xd9\x4e\x14\x84\x7e\x54\x59\x76\xc7\xb2\x1d\xc9\xbf\xfd\x2d\xeb\xfd\xe3\x6c\x59\x65\x96\xc9\xb7\xbf\x65\xbd\xdf\xd1\x36\xad\xa7\x1d\xcb\xd8\xb1\xa4\x15\xb3\xd4\xe6\xf5\x55\x77\x2f\x31\x2f\x12\xb6\x04\x90\xd2\x47\x1e\x0d\x1f\xbe\xb0\xbb\x13\xba\xb8\xeb\x75\xf2\xff\x70\xb1\x42\x3f\x92\xee\xb3\xaf\xf4\x5b\xbe\xfc\x17\xa9\x0b\x80\xb0\xdc\xda\x82\xee\xbd\xb4\x05\x2c\x47\xed\xd7\x54\x1a\x78\x48\x7a\x95\x8e\xfd\x86\xf6\x6a\x3c\xf5\x87\x8f\xa8\x5a\xf0\x10\x6f\x16\x7e\x41\x6b\xff\x04\x75\x83\x91\x2f\xf6\x0e\xaa\x08\xc5\x88\x45\xfa\x72\xb0\xdd\x81\x9a\x60\x72\x73\xb0\xdd\xb1\xc9\x78\x60\xe2\xfc\x19\xdf\xd0\x2c\xd8\xd4\x0e\x56\xf4\x15\x9c\x7f\xfd\x28\xe3\x49\xbc\xe3\x64\x4a\x6d\xb4\x77\x7e\x3e\xfa\x04\x9b\xee\x69\xfc\x0e\xe7\xc2\x20\xba\xba\xba\xaa\xc5\x33\x1c\xa5\xe9\xa4\x16\x27\x17\x6b\x41\x3c\x4c\xd7\x20\x09\x77\xbc\xa6\xd5\x19\x67\xd3\x89\x45\x11\xb2\x73\x39\x7b\xb7\xbd\x9b\xa3\x2d\x9e\x4b\x06\x43\x58\xec\x03\xa2\xed\x71\x86\xf7\x0b\x4b\x79\x0e\x7b\x14\x19\x98\x94\x3c\x84\x11\x77\x7b\x91\xc2\x3d\xe7\xae\x2e\x6d\x54\x69\x34\x37\x14\x4f\x17\x03\xbe\xc3\x48\x4d\x0e\x8b\xa1\x27\x48\x39\xd8\xee\x2c\xc2\x36\xcc\x98\x2d\xb2\x1e\xa4\x5a\xfa\x90\xc5\x68\x46\xad\x4e\x65\xef\x1c\xc7\x0e\x67\xf8\xc5\x68\xbb\x03\x1b\x9e\x1e\x6a\x34\x37\xc0\x84\x54\xf9\x4a\x3b\x07\x98\x6b\x5f\x72\x7c\x94\xb6\x6f\xef\xec\x76\xe3\x20\xda\xc7\xf6\xc3\xc1\x52\xa3\x0f\x60\x66\xfd\x39\x18\x19\xde\x37\x94\xe6\x17\xa4\x68\x5a\x5c\xf1\xcf\x7c\xae\x36\xb4\x7c\x7e\x77\x05\xe3\xa9\xd3\x58\xaf\xd7\x75\xc0\x4b\x7a\x07\x2d\xf4\xfb\x29\x27\xef\x6e\x43\x0a\x7f\x42\x23\x84\x2a\x20\x11\xb6\x0f\x19\x58\xc9\xa2\xbd\x8b\x95\x3e\xaf\x4b\x63\x01\xd8\x00\x15\x54\x4e\xfd\x49\x86\xb6\xe0\x9f\xe5\xc5\x62\xa0\x2e\x4a\xde\x0f\x41\x5e\x98\x6c\x1e\x9f\x83\x51\x8d\xba\x45\xe0\x0a\xef\x8c\x07\xf8\x15\xe4\xad\x81\xe2\x4a\x7e\x47\xb5\xe6\x52\x02\xaf\x3a\xc5\x16\xf1\x96\xac\x74\xc6\x3d\xcc\xda\xc2\x4b\x8d\x90\x07\x33\x51\xce\x57\x87\x15\x96\xcb\x2d\x0c\x42\x0b\xd0\x21\x7e\x07\x63\x63\x4b\x89\xb6\xcc\x19\x39\x07\x26\x7c\x82\xc5\x1b\xe7\x71\x99\xef\x31\xb4\x47\xec\xc9\x52\x4e\x62\xe2\xb4\x68\xfe\xc2\x82\xe5\x5b\xb6\x31\x11\xf0\xea\x47\x66\xcc\xa2\xe1\xca\x0d\x5a\x5e\x73\x7c\xac\x47\x01\x22\xc6\x81\xe7\x80\xf3\x82\x59\x75\x59\xa2\x65\x17\x5f\x2b\x23\x39\x18\x43\xee\x04\xc2\xa0\x70\x62\x93\x8c\x82\x0d\x7a\x55\x9b\x17\xfe\x74\x66\x09\x42\x13\x62\xe0\xcc\xcf\xca\x41\xa9\x41\x0f\x4a\xd2\x40\x17\xa6\xfd\xd1\xb0\x17\xc8\x3a\x47\xc1\x86\xb1\x65\xa8\xcc\x77\x12\x59\xb1\x98\x31\xd6\x36\xb4\x51\x96\x6a\x49\x3a\x1a\x4e\x7f\x96\x68\x17\x22\xc0\x1c\xaf\x57\xd6\xe6\xba\x14\x0f\x96\xfd\x8e\xef\xc4\x7b\x97\xe4\xbb\x0f\xe8\x7d\x6b\xf1\x2b\x93\x7a\x53\x9e\x9b\x4b\x95\x14\xed\x86\xf4\x5e\xe5\xee\xf9\x07\xa4\x70\x75\xb1\x69\xd3\xfd\xda\xc5\xd9\x97\xab\xe6\x21\x87\xd8\x70\x1f\x30\x85\x62\x83\x50\x21\xe7\xb2\xbe\x6b\xcf\x31\x5d\x58\xd8\xb0\xab\x12\x0b\x38\xae\x14\xef\x77\xb7\xaf\x0a\x8e\xef\x14\x9a\xfd\xec\xee\xf1\xc3\x67\xaf\xb3\xe1\xf1\x23\x69\x6f\x7d\x9d\x9c\xe9\xd7\xff\xd6\x67\xfa\x61\x38\x1b\xe3\x64\xf5\x91\x4d\x04\xe0\xf4\x2e\x37\xf5\xd7\x1c\xe2\xcd\xcc\x9d\x0f\x72\x9a\xef\x43\xc7\x8e\x08\xc7\x49\xc5\xa1\x5d\x7e\xe9\x36\x21\x10\xef\xb5\x4c\x18\x4a\x0d\x72\x86\xf3\x33\xa8\x44\x7f\x72\x46\xcc\x2a\xee\xc2\xcb\x8c\x45\x55\xa0\x45\x96\x48\xa7\x41\x4e\x37\x74\x6e\x32\x7c\x9d\x91\x53\xa4\xcf\x9e\xd1\x8c\xf6\x89\xf9\x66\xf1\x54\x1b\x7e\x80\x87\xe1\xd4\x9f\x4c\x6e\x58\x1a\xd0\xa0\xf4\xcd\x8d\x3c\x2a\xb7\xac\x15\x36\x70\xa7\x02\x0d\xb5\xd9\xe5\x93\x71\xdc\x05\xbf\x47\x4d\xcf\x91\x4f\x89\x74\xab\x23\x77\x7e\xb9\x8b\x1d\xa5\xa6\xc3\x51\x4b\x2e\x53\xcb\x67\x37\x4f\x20\xb1\x87\xaf\xef\x98\x09\xc2\x32\xbc\x12\xf9\xc8\xf7\x0d\x4b\x4e\xa7\x76\xf3\x10\x46\xb3\x79\x76\x9f\x39\xe5\xe4\xa1\x12\xdd\x1d\xe8\xec\xa1\x88\x63\xa8\x31\x0a\x0b\x7d\xdc\x39\xa9\x04\x8c\x96\x3d\x84\x4d\x3e\x39\x9b\x28\x6f\x83\x56\x78\x65\xa5\x9e\xbe\x42\x3d\x5c\x23\x90\x03\xea\xc9\x40\x6f\xed\xba\x79\xf7\x4e\x9b\x77\x57\xdb\x6d\xa5\x0d\xa2\xd7\x69\x7a\x9a\xf2\x7c\xe3\xc9\xd4\xee\x9f\xae\xfb\x76\xed\x8e\x46\x24\xf3\x22\x4d\xb8\x79\x48\x01\x07\x60\xa1\x71\xb5\x26\xa2\x22\x25\x36\x65\x47\xd5\x87\x49\x48\x0f\x2e\xaf\x0b\x39\x5e\x69\x25\x71\x49\x55\x14\x91\xd5\xc1\x79\x19\x0f\x13\x9c\x3d\x90\x52\x89\xc8\xbf\x7b\xf6\xc0\x41\xd0\x4b\xc6\x26\x6c\x9e\xc8\xd4\xd1\xb7\xac\xc6\x50\x76\x0e\x76\x04\x08\xb6\xea\x8c\x84\xbe\x88\xfa\x28\x88\x47\xdd\xc3\xbd\xc0\xdb\xed\x21\xe3\xcb\xc2\x81\x69\x41\x78\x59\x7a\xa8\x92\xa2\xcb\xea\xe3\x64\x37\xc4\x2f\x50\x4c\xd1\x8e\xbe\x91\xe2\x62\xb2\xae\x17\x45\xc6\xd4\x2a\x71\x7d\x81\x0e\xcb\x1e\x25\x73\x6b\x32\x89\xaf\x90\x9f\x0c\xc2\x2c\xf1\x93\x1b\xc4\xd4\x4b\x9f\xf1\x8d\x25\xee\xe0\x67\x59\x23\xf1\x93\xb5\xe1\x82\x81\xd2\xd5\x2d\xe5\x46\x6b\x81\x33\x24\x41\xa9\xc0\x0d\x12\xe2\xbf\x81\x6e\x23\x4e\x50\x18\x45\x38\x81\xe8\xb3\xf1\x3c\x03\x01\x42\x8f\xc2\x07\x31\x13\xa9\x8e\x91\x92\x21\x7b\xa0\xad\x18\x01\xe9\xb8\xc6\x4f\xae\x11\x5a\x6a\x2c\x43\x02\xb1\xa4\x95\x8c\x8b\xf4\x91\xa1\x54\x30\x94\x0a\x1a\x8d\xfd\x7a\x78\x0c\xf3\x49\xaf\x01\x67\x7e\x80\x86\x71\x94\x66\x7e\xa4\x37\x6f\x4d\x22\xa5\xce\xb1\x5b\xb1\x26\xf0\x3e\x0b\xcf\xd1\x6f\x9b\xa8\x7e\xdd\x19\xd2\xff\xd9\xdc\x61\x8c\xc2\xad\x2e\xfd\x5f\xb1\x66\x2c\xd6\x74\x62\xa1\xf6\x6c\xa3\xc8\xbf\x20\x0e\x19\xec\x40\x8f\x11\x85\x4c\x30\xf1\x07\x89\x44\x56\x90\xaf\xcc\xc6\x8c\x2d\x03\x09\x9d\xb6\xf1\x71\x87\x9e\x54\xd5\x17\xe7\x0b\xe6\x6e\x11\xc8\x60\x98\xbf\x99\xf8\x63\x07\x5b\x7d\x16\x7d\x0c\xf0\x0a\x61\x89\x15\x46\x42\x59\x72\xca\xcb\x04\x22\x33\x4a\x3f\x7c\x30\x32\x99\x24\x78\x2b\x0b\x83\x8f\x3d\x56\xf4\x30\x18\xea\xff\xe9\xd1\xc3\x16\x88\xa9\xcb\x88\x88\x84\x87\xe6\x34\xb4\x30\x82\x98\xbb\xc6\xc2\x28\x62\xee\xaa\x8f\x14\x49\xec\xfe\xdc\xae\x4f\xd5\xd3\x30\xde\x96\xfd\x98\x48\x17\x7b\xf6\xe0\x68\x85\x01\xc7\x0a\x39\xa6\x3c\x56\x1a\xd0\x5c\x42\xe1\x92\x06\xbf\x64\x12\xa8\x54\x9d\x21\xc7\xa6\xfe\xd0\x7e\x49\x24\x0e\xfe\x0e\x23\xb8\x97\x7f\x6b\x85\xf9\x75\xb7\xbd\x6a\x79\x3d\x09\x07\xab\x04\x95\x00\x6c\x5b\x53\xed\x2b\x8e\x86\xab\x60\xd3\x68\x79\x4f\xdd\x2c\xb5\x0f\xd3\xa0\xb3\xd8\xf8\x2e\x1d\xfb\xcd\x8e\x0e\x92\xbc\x6c\xea\xe0\xd2\xb1\xdf\x69\x34\xcd\x97\xad\x0d\x4b\xc9\x96\xf6\x2a\x09\x67\x78\x1a\x34\xba\x75\xab\xed\x9f\xf2\x6a\x36\xf8\x1c\x8c\xf4\x76\xf0\xe5\xec\x73\x30\x2a\xba\x77\x50\xbb\x1e\x07\x78\x75\x38\x1a\x58\x5f\x67\x89\xe3\xf5\xea\xc5\xc4\x0f\xa6\x7e\x64\xfb\x1c\xdb\x81\xe1\xa1\xfe\x7a\xe6\x07\xab\x7e\x94\x86\xd7\x2f\x9b\xfa\x20\x90\x4f\x61\x1a\x37\xea\x8d\xa6\x3e\xe2\xec\xd3\xcb\xf5\x97\xeb\xfa\x0c\x91\x4f\x7f\xe0\x24\x66\xae\xd7\x96\xaf\x91\xe3\x1b\xd5\x91\xad\x8e\xf1\xb5\xf6\xc1\xc7\x3a\x71\xd1\xb8\x1b\x81\xf1\x3e\x19\xea\x93\x9b\xf8\x83\x41\x98\x59\x5f\xae\x4e\xf0\x85\x3f\xbc\x79\xec\x3b\x20\xb1\x7a\xe0\x49\x5f\x34\xf0\x32\x5f\x2b\xe2\x91\x2d\x11\x78\x26\x2b\x43\x33\x0b\x65\xeb\x40\xfc\x6e\xb6\xc5\x6f\x42\xf5\xfc\x37\x21\x76\xf1\x9b\xfe\xca\x49\x3b\xb7\x2f\x85\x5f\x8c\x90\x29\x06\x94\x7e\x8d\x3b\x2c\x8a\x0e\xa7\x56\xe9\x29\x4b\xd4\x27\x41\x9b\xf9\xdb\x58\xa9\x41\x28\x91\x36\x2b\x13\xa0\x78\x23\xe8\x4e\x7e\x43\xc9\x4d\xbc\x91\xa9\x4c\xbc\x8c\xd4\x57\x12\x4d\xc1\x33\x21\x25\xf8\x91\x53\x10\x1d\x95\x21\x1b\x28\x46\x2f\xd2\x6f\x4e\x26\xcb\x2a\x22\x15\x05\xa4\xcc\x6b\x97\x57\x4c\xba\x43\xb1\xb1\x2e\xf5\x3a\x0d\xaf\x58\x9b\xec\xa9\x74\xd5\xeb\xb4\x3d\x85\xf0\x7a\x9d\x8e\x97\x4f\x7c\xaf\xd3\xf5\xd4\xd1\xeb\x75\xd6\xf5\x1b\x61\x9d\x94\x7b\xdd\xba\xc7\xa8\xb5\xd7\x05\x7c\x04\xa5\xf4\xba\x4d\x4f\xa6\x95\x5e\xb7\xed\xd9\xa8\xa5\xd7\x6d\x79\x32\x85\xf4\xba\x1d\x4f\xa6\x9f\x5e\x17\xf0\x52\x68\xa6\xd7\x5d\xf7\x74\xaa\xe9\x75\x37\x3c\x9d\x6e\x7a\xdd\x97\x9e\x41\x24\xbd\xf5\xba\x67\x21\xa7\xde\x3a\xe0\xcf\x96\x44\x6f\x1d\xb0\x67\xa4\xd1\x5b\x6f\x7b\x06\x71\xf4\xd6\x01\x71\x42\x46\xbd\x75\xc0\x39\x5f\x67\xbd\xf5\xae\x7c\x81\xee\xe5\x4b\xb6\xb7\xce\xaf\xd6\xc9\x62\xee\xad\xbf\xf4\xf8\x52\xed\x6d\xd4\xbd\x7c\x09\xf7\x36\x1a\x5e\xbe\xb8\x7b\x1b\x80\x4e\x4e\xc1\xbd\x0d\x68\x5c\x30\x9a\xde\x46\xfb\xf6\xdc\xeb\xd6\x9f\x2e\x0f\xfe\xfa\xcb\x83\xfe\x18\x0f\x3f\x93\x4e\xc1\x4a\xa1\x6e\x40\x34\xcd\x59\x3a\x9f\x91\x81\xc1\x2c\x3e\xb5\xd4\x6f\x90\xe3\x69\x48\x73\xf4\xdd\x26\x7a\xc1\x21\xbf\xb0\x58\x84\x08\x27\x8d\x07\xbc\xae\x28\x34\xc7\x17\xed\x1c\xe3\x11\x4e\x30\x1c\xf4\x92\xf0\x02\xce\x64\x61\x14\x66\x39\x98\x74\x3e\xc3\x09\xa8\xae\x37\xb5\xf4\x1c\x12\x94\xad\xf9\xc5\x14\x47\x99\x56\x00\x65\x31\x1a\xfb\x51\x30\xc1\xca\xb8\xc9\xb0\x07\x56\xc8\x8a\x4d\x0d\x54\x35\xdd\x01\x25\xdd\x37\x8d\x25\x4f\x4d\xa0\xc2\x28\xdb\x90\x34\xf4\x23\xb9\xbe\x50\x4c\xa8\xb3\x63\x1e\xf3\xf3\x1a\x54\x09\xff\x91\x40\x85\x17\x32\x36\xca\x21\xc2\x8a\x58\x4c\xd3\x7f\x01\xa4\xcb\x10\x5f\xb9\x50\x74\x36\x2f\x21\xbc\xcf\x51\x40\x5f\xbe\xa8\xe5\x39\xc1\x01\x96\xa0\x33\xe6\xd5\xbf\x23\x6b\x4e\xd8\x8e\xc0\xa2\xb3\x03\x37\xaa\x56\x8d\x56\x9c\x58\x35\xba\x76\xb4\xdc\x2d\x2d\x57\x63\x3f\xca\x5a\xcd\x65\x9b\x58\xae\xc6\xee\x24\xf6\xef\x52\xa5\xdb\x86\xf7\x79\xf9\x3b\x92\x52\x8d\x52\xb0\x87\xe4\x57\x37\x19\x3e\x84\xe4\x40\xc6\x6b\x5b\xde\x65\x85\xfe\xf6\xe8\xa2\xcb\xdb\x2a\xb3\x22\xf2\xd2\xcb\xa9\x10\x72\x68\x6f\x04\x6e\x68\xd3\x8e\xb3\x45\xb3\xb0\x73\xcd\xb2\xaf\xde\x64\x36\xe3\xe7\xa5\xdc\x05\x6d\xa8\x2c\x93\x4f\x3b\xaf\x7f\x16\x9e\xdf\x29\x79\x76\x6e\xce\x1d\xfe\x81\xa9\xaa\x36\x77\x1c\x55\x8b\x0a\xc6\x9a\xa7\xb6\xf0\x10\x73\x23\xb4\x75\x44\x99\x6f\x6b\xd6\x33\x32\x9a\xe4\x35\x81\x87\x22\x22\xf5\xc9\xcc\xdc\x6c\xd7\x9f\xcd\x26\x37\xac\x61\x3f\xb9\x98\x13\x16\x9e\x16\xf9\x2b\x32\x7e\x5d\x9b\x25\x71\x16\x13\x1c\x65\xce\x5d\x64\x38\xa1\xef\x3e\x76\x05\x4b\xb7\xf1\x24\xeb\xfc\x35\xb2\x0e\x04\x8c\xfe\x0b\xe2\x12\x59\x73\x2a\x95\x30\x91\x80\x2d\x96\xde\xe3\xa1\x2c\xd7\xad\x93\x2a\xa7\x8c\x59\x48\x25\xa9\xea\x52\xbb\xf9\xb3\x49\x7a\x2e\xbe\xd2\x6d\xdb\xb9\xc8\x29\x61\x13\x9b\x74\xf8\x6a\xfe\x20\xa5\x3f\xd2\x30\x62\xc1\x58\x09\xcb\xa8\x5f\x37\xea\xec\xaf\x8a\xbe\xa8\x69\x7c\xd9\xf2\xaa\x54\xad\x16\xea\x07\xdb\x1d\xcd\x9a\xc2\x66\x00\xa2\x7b\x4d\xa2\x4d\x36\xaa\x16\x03\x10\x9e\xf6\xa6\xf0\x76\x2c\xd7\x04\xdb\x73\x15\x9f\x99\x9c\xb4\x7e\xdd\x5d\x6f\x77\x9a\xad\x7a\xc3\x43\xf5\x6b\x3c\x1a\x06\xfe\x60\xe3\xa5\x25\xaf\x62\xfd\xfa\xe5\xc6\xc0\x0f\x86\x23\xec\xc1\xc0\xb4\x9a\x9d\xf6\x7a\x57\x2d\x77\xee\xbc\x11\xd3\xd2\xe8\xc9\xbd\x38\x10\x99\xf4\x6c\x7b\xd7\x95\x3f\x43\x18\xdc\xab\x17\xef\x21\x8d\xae\x7b\xc7\x70\x5f\x5f\xf3\xd9\xa0\x48\x7c\x22\xf0\x78\x7a\x41\x14\x3a\x22\xf0\x1e\x7c\x92\x4a\x1f\x9c\xf1\x87\x73\x9b\x4b\x88\xf4\x99\x10\x9c\x59\x80\xfc\x55\x2a\x15\x09\x26\xf5\x14\x47\x5f\x90\xfc\x12\xf6\xba\x76\x55\xf3\x11\x47\x5f\x4a\x02\x6c\xb6\xab\x16\x80\x10\xca\x58\x71\x49\x37\xc1\xdd\xcf\x38\x64\x4f\xb9\xa1\xb0\x5f\xf7\x2b\x43\x5a\x47\xd2\x98\xa2\x15\x54\xd7\xc5\x07\xa5\x74\x43\x2b\xdd\x28\x2c\xdd\xd4\x4a\x37\x0b\x4b\xb7\xb4\xd2\xad\xc2\xd2\x6d\xad\x74\xbb\xb0\x74\x47\x2b\xdd\x29\x2c\xdd\xd5\x4a\x77\x0b\x4b\xaf\x6b\xa5\xd7\x0b\x4b\x6f\x68\xa5\x37\x0a\x4b\xbf\xd4\x4a\xbf\x2c\x9e\x9d\xba\x36\x3b\x0b\x26\xb3\xa1\x15\x2f\x9e\xcd\x46\x53\x2b\x5e\x3c\x9d\x8d\x96\x56\xbc\x78\x3e\x1b\x6d\xad\x78\xf1\x84\x36\x3a\x5a\xf1\x8e\xc1\x0d\xd6\xd6\x08\x43\xfe\x1c\x46\x17\xa4\x6a\xe8\x4f\x06\x36\xb1\xd9\x27\xdb\xc0\x99\x75\xa0\x06\xf0\xc9\x3a\x28\x43\xf8\x64\x1d\x80\x00\x3e\xb5\x6c\xe8\xf4\xf3\x3b\x68\xf5\x1b\x41\x62\x77\xb7\xe2\x7b\x68\xe0\xa1\xa1\x87\x02\x4f\x5a\xa0\x1e\x42\xeb\x1e\xd9\x42\xeb\xe7\x3a\x6f\x08\x68\xbd\xc0\x43\xa2\x6a\x3e\x42\x1e\x42\x8d\xa6\x87\x4e\xcf\x1a\x46\xbd\x21\xad\x47\x5b\xa2\x55\xf3\x45\x4b\xea\xad\x93\x7a\x4d\xa3\xde\x80\xd6\x13\x48\xfa\x52\xbd\x96\x87\x50\x13\xda\x6b\x19\xf5\x8a\xfa\xd7\x16\xfd\x6b\x2f\xd5\xbf\x8e\xe8\x5f\x67\xa9\xfe\x75\x45\xff\xba\x4b\xf5\x6f\x5d\xf4\x6f\x7d\xa9\xfe\x6d\x88\xfe\x6d\x2c\xd5\xbf\x97\xa2\x7f\x2f\x97\xea\x5f\xa3\xee\xb1\xfe\x35\x4c\x82\x29\xea\x60\xa3\xe1\xb1\x0e\x36\x4c\x8a\x29\xea\x21\xc1\x92\xf6\xb0\x61\x92\x4c\x21\x89\xb6\x3c\x4e\xa2\x26\xcd\x14\xf6\xb1\x2d\xfa\x68\x12\x4d\x61\x1f\x3b\xa2\x8f\x40\x35\x66\x27\xdf\xbe\x75\x74\xd2\x43\xa8\x43\x3b\x69\xd2\x4d\x40\x2b\x5a\x3b\x49\xe8\xed\x25\xad\x68\x12\xce\x90\x56\xb4\x77\xb2\xe1\x21\xd2\xd1\xd3\xb3\x86\x49\x39\x03\x5a\xd1\xda\x49\xc2\x31\x9a\x75\xa8\x68\x92\x4e\x51\x1f\x3b\xa2\x8f\x4d\x3b\xaf\x71\xf5\x91\xd0\x1c\xed\x63\xd3\xce\x6c\x9c\x7d\xec\xf0\x3e\x36\xed\xdc\xc6\xd5\xc7\xb6\xe8\x63\xd3\xce\x6e\x5c\x7d\x7c\x99\xf7\xd1\xce\x6f\x9c\x7d\x6c\x8b\x3e\xda\x19\x8e\xab\x8f\x84\x31\xb2\x3e\xda\x39\x8e\xab\x8f\x1b\x79\x1f\xed\x2c\xc7\x49\xab\x2d\x8f\xf7\xd1\xce\x73\x5c\x7d\x6c\x0a\x5a\x6d\xda\x99\x8e\xab\x8f\xeb\xa2\x8f\x2d\x3b\xd3\x71\xf5\x91\x2c\x7f\xda\xc7\x56\xc3\xbe\x20\xf7\xf6\xdc\xc4\xda\x06\x5c\x5b\x76\xae\xb3\xb7\x67\xef\x24\x19\x56\xb2\xb6\x4e\xcf\x5a\x76\xae\xb3\xb7\x57\xb0\x20\xbb\x50\xd1\xce\x75\xf6\xf6\x1c\x9d\x6c\x7b\xa8\xd9\x82\x8a\x26\xe9\x14\xf5\xb1\x91\xf7\xd1\xce\x74\x5c\x7d\x6c\xe7\x7d\xb4\x33\x1d\x57\x1f\x61\x22\x69\x1f\xed\x4c\xc7\xd9\xc7\xba\xe8\xa3\x9d\xe9\x38\xfb\xd8\xf2\x58\x1f\xdb\x76\xa6\xe3\xea\x63\x5d\xf4\xb1\x6d\x67\x3a\xae\x3e\xb6\x44\x1f\xdb\x76\xa6\xe3\xea\x23\x61\xe5\xb4\x8f\x6d\x3b\xd3\x71\xf5\xf1\xa5\x98\xc7\xb6\x9d\xe9\xb8\xfa\x48\x96\x07\xeb\xa3\x9d\xe9\x38\x69\xb5\xc3\x69\xb5\x6d\x67\x3a\xae\x3e\x36\xf3\x3e\xae\xdb\x17\xe4\xfe\xbe\x5b\x50\xed\xd2\x4e\xda\xb9\xce\xfe\xbe\xbd\x93\x40\x73\xc0\x03\xda\x76\xae\xb3\xbf\x5f\x20\x06\x74\x40\x04\xb4\x73\x9d\xfd\x7d\x7b\x27\x09\xef\x68\xc2\xb0\x76\xec\xa2\x8e\xab\x8f\x64\x3e\x68\x1f\x3b\x76\xa6\xe3\xea\x63\x4b\xf4\xb1\x63\x67\x3a\xce\x3e\xd6\x45\x1f\xed\x4c\xc7\xd5\xc7\x46\xde\x47\x3b\xd3\x71\xf5\x71\x43\xcc\x63\xc7\xce\x74\x5c\x7d\x04\x9a\xa3\x7d\xb4\x33\x1d\x57\x1f\x41\x24\xa7\x7d\xb4\x33\x1d\x67\x1f\x5b\x1e\xef\xa3\x9d\xe9\xb8\xfa\xd8\x16\x7d\xec\xda\x99\x8e\xb3\x8f\x0d\xde\xc7\xae\x9d\xe9\xb8\xfa\xd8\x14\x7d\xec\xda\x99\x8e\xab\x8f\x2f\xc5\x3c\x76\x5b\xe6\x82\x84\x6b\x94\x0c\x27\x53\x1c\x84\x7e\xc6\x9c\xca\xc0\x5d\x41\x2d\x47\x8e\xb8\x68\x13\x55\xe0\xdf\x15\xe4\xeb\x1a\x56\x5a\xa6\xc1\xca\x34\x48\x99\x81\xbd\x4c\x93\x95\x69\x92\x32\x43\x7b\x99\x16\x2b\xd3\x22\x65\x02\x43\x9b\xab\xa9\x2a\x77\x2d\x96\xba\x4b\x06\xb4\x85\x4c\xe9\x22\x9b\xae\x9f\xf9\xb6\x83\xb9\x9f\xf9\x22\x94\x8f\x9f\xf9\x6e\xe5\x58\xf4\x26\xcc\xd2\xd3\x38\xf3\x27\x02\x66\xb4\xed\x67\x3e\xf5\x20\xf9\x11\x6d\x58\xa0\x43\x9d\xf7\x78\x94\x71\xe8\xc2\xe3\x04\xca\x1b\x9d\x71\xa6\xbc\x12\x68\x9e\xe5\x20\x7f\xfa\xe9\x27\xd4\x81\x8b\xb7\xfa\xf5\x46\x3d\xbf\x6f\xcb\x4b\xfc\x0b\xb5\x9a\x06\x71\xa8\x7d\xd9\x43\x9b\x08\xd4\xee\xa3\x49\x1c\x27\x15\xa9\x93\x6b\x8a\xee\xdd\xd5\x39\x28\xfb\x1e\x6d\x4a\x4f\xfa\xc2\x11\xa8\x57\x2a\x95\x1c\xb7\x15\xd4\x6d\xd3\x7c\x69\x2f\x21\x98\x68\xbb\x4a\x15\x36\x76\xfd\x2c\xaf\xca\x70\xce\x95\xb3\xf2\xdb\xf2\xda\x59\x13\x1c\x53\xcd\xea\xe0\x16\xe9\x66\x0d\x2e\xb1\x4c\x67\xdb\x65\x3a\xfb\xde\xda\xd9\xf7\x77\xed\xec\x7b\x6b\x67\xdf\x97\xed\xac\xd9\x5b\xd9\x89\xaa\x22\xba\xcf\x83\x4d\x41\x4e\x3d\xbb\xff\x20\x18\xbc\x53\x37\x06\xf0\x51\xb4\x79\x52\x15\xe6\x95\x5f\xe0\x0d\xa9\xe8\xbc\x2d\xe4\xbb\xc7\x0c\xe3\x9d\xde\x6f\x4b\xdd\x7b\x38\xae\xb8\x50\xd1\xf5\xbf\xc0\x04\xae\x30\xf6\xce\xec\x77\x17\x7b\xec\x96\xac\x52\xd9\x53\xae\x25\xf6\x96\xbe\x8f\xa0\xb4\xb0\xa7\xdc\x45\xec\x39\x2f\x21\x16\xdf\x38\x1c\xb3\xdc\xc0\x30\x87\x2c\x02\x4f\x00\x63\xaa\x16\x2d\x91\xac\x1c\xdc\x10\x0a\x59\x3d\x28\x58\xc1\x29\x53\xdc\xd0\xc1\x63\x7e\xfd\x6f\x6c\xbc\xf0\xf9\x93\x41\x0b\x2e\xef\x4a\x1e\x41\x83\x7c\xb5\x7b\x38\xd0\x5f\x02\x49\x4d\xf5\x75\xed\xa1\xd4\x43\xea\x15\x1a\xf0\x49\xb4\x89\x7c\xb4\x82\x2a\x95\x01\xfa\x81\x6e\x8e\x95\xff\x4b\x7e\x06\x55\xc2\x06\xae\xd1\x0a\xca\xa4\xf6\x44\xc0\xe2\x88\x4c\x53\x4a\x57\x2a\x8d\x53\xde\x6a\xa2\x55\x94\x56\xa1\xda\x40\x33\x7a\x13\x58\x69\xe7\xff\x72\x58\xc1\x76\x5c\x19\xa2\x1f\xd0\xff\x7d\x1c\xac\xb4\x43\xd0\x42\xac\x06\xe8\x37\x34\x44\xbf\x11\xc4\x1e\x1e\x19\x4d\x00\x5c\x88\x0c\x41\xa4\x32\x40\x5f\x1e\x78\x70\xe4\xdb\xea\x13\x57\x9a\xf4\x85\x89\xf7\xcb\x04\x59\xe3\x7e\x62\x9a\x8b\x22\xac\x06\x13\x8c\xc3\x59\xcc\x51\xfa\xae\x61\xcd\xd8\xba\x14\x46\x2e\x07\xdb\x1d\x8b\xef\x57\x71\x79\xd3\xe1\x2b\x8f\x2f\xa6\x5c\xe6\xab\x19\xf9\x0f\xb6\x3b\x56\x93\x01\xe7\x24\x2c\xc8\x55\xff\x50\x53\x70\xa7\xd0\x0e\x8b\x27\x4e\xf6\xf2\x7b\x88\x89\xa3\x4e\x65\x62\x22\xf6\xa6\xfe\x90\x4c\x86\x92\x19\xde\x9c\x0f\x56\xcc\x9c\x93\x3c\x9b\x3d\x9d\x97\xc2\x0c\xec\x2c\xb2\xb5\xc3\x02\xaa\xf9\xb7\x76\x31\xfb\xe7\xc7\x64\xa3\x8b\xed\x47\x16\x67\x08\xed\x62\x1c\x0c\xfc\xe1\x67\x16\x57\x73\x1a\x07\xb0\xa4\x08\xcd\x88\xf9\x86\x97\xfd\xdd\x37\x44\x04\xb2\x88\x07\x60\xe6\x04\x5f\x15\x6b\x39\xb0\x70\xa1\xad\x1c\x10\x00\xcc\x98\x47\xac\xfa\xfe\xee\x9b\xda\x4e\x44\x63\x95\x83\x01\xd5\xee\x1b\x8b\xc1\xcf\xcc\x61\x2e\xc3\xcc\x0c\x0b\x4c\x66\xdc\xa2\x29\x0b\x41\xc5\x05\x12\xfa\x68\xbb\x67\x96\x42\x79\xd0\x42\x72\x28\x0f\xb5\x3c\x8f\x51\xfe\x0e\xdf\xa4\x59\x82\xfd\xe9\x56\x14\xb0\xde\x59\xac\x23\x63\x66\x16\x2b\xc0\x79\xac\x01\x9b\x90\x7d\x8c\xa7\x18\x82\x8c\x83\x31\x26\x9d\x27\x16\x2b\x13\xfc\xe7\x23\x7c\x9d\xd1\xd7\x76\xf1\x1d\x5f\xbe\x61\x31\x53\xa1\xf5\x5a\x3a\x09\x87\xb8\xc2\x51\x10\x37\xf5\x02\x17\x9b\xfd\xa4\x32\x6b\xdb\xf8\x9f\x32\x6b\xf7\x18\x5d\x30\x1c\x1e\x87\xe9\xd2\x63\xfb\xd5\xe8\xe6\x34\xef\xd0\x00\x0f\xe3\x29\xf3\xba\x27\x04\x11\xc6\xf3\xb4\x1c\xc9\x88\x2e\x96\x12\xc7\x0b\x7a\x53\x59\xd8\x05\xcd\x37\xc2\x3c\xb0\xc1\x79\xef\x32\x0f\xd6\x72\xf9\x4a\x35\x1a\x97\xc3\x31\xd3\xe6\xf3\xcf\x90\xd9\xf5\xd2\x7a\xa4\x11\xa5\xd1\x26\x0a\x2f\xd9\x14\xd6\x1d\x2b\x31\xbe\xc4\x68\xff\x67\x38\x7f\xa6\xf3\x41\x8a\xff\x7b\x8e\xa3\xac\xe0\xf4\x0c\xf8\x0a\x07\x86\x85\x06\xd0\x3a\x3e\xda\x84\x98\x93\x40\xfe\x18\x95\x63\x3a\xd0\x50\xb0\x22\x80\x78\x48\xed\xca\xda\x1a\x62\x33\x92\xbf\xb3\x66\xcb\x2d\x8e\x1a\x43\x4d\xcf\x73\x0b\x41\x88\x04\x23\x1a\x85\x73\xb4\x41\x2f\x0c\x0b\x2e\x4e\xec\xbe\x29\x32\xb8\xe6\x9b\xce\x32\x71\xea\xba\xad\x27\xe1\xe3\x5b\x17\x3e\xd0\x7f\xce\x12\x9c\xe2\xe4\x12\x53\x31\x24\x9e\x13\x51\x5e\x12\x3f\x40\x8d\xe1\x67\xe1\x60\xc2\x38\x30\xda\x4e\xd0\x9b\x24\xf4\x23\xf4\x96\xba\x67\xa2\x51\x38\xc1\x38\x1a\xd6\x86\x00\x82\x87\x7c\x86\x08\xd8\x1a\xfd\x9c\x1e\x43\x91\xff\xf2\x23\xb4\x97\xcc\x07\x37\xe8\xf7\x31\xf9\xa7\x76\x85\x07\xff\x79\x31\xf5\xc3\x49\x6d\x18\x4f\xed\xf2\xce\xe9\x31\x6f\xae\x40\xec\x91\x0b\x95\x96\x7e\x9e\xe5\xf9\x5e\xa2\x21\x39\x28\xd0\x94\x49\xcf\x9f\x3d\x23\x83\x0e\xa4\x27\xd2\x21\x81\x92\x88\x2a\x85\xaa\x30\xeb\xf4\xd7\x9f\x68\x6d\x2d\xbe\xc4\xc9\x68\x12\x5f\x91\x3a\xb0\xf1\x35\x78\x3a\x50\x52\xaf\xd1\xad\xfe\x40\xca\xbe\x12\x9f\x9b\xf2\xe7\x0d\xfd\x6b\x8b\xed\x61\xac\x31\xc0\x13\x50\x21\x60\x45\xbb\x6b\x6b\x88\x37\x8b\x06\x0d\x52\x04\x50\x86\xa6\xeb\xaf\x44\x95\x66\x5e\x45\x94\x79\x06\x08\xd0\x42\xb4\x54\x4b\x2d\xc5\x8a\x3d\x03\x54\x58\xb9\x5b\xf8\x2f\x21\x48\xb9\xc4\xca\xca\xa0\x25\x7d\x87\xff\xf0\x32\xb4\xc8\xca\xca\xa0\xf9\xea\xb9\xbb\xc0\xca\xca\xa0\xc1\xbe\x93\xff\x42\xc7\x79\xa3\xf0\xb0\xb2\x09\x3d\x7f\xfd\x9a\xe5\x83\x94\x5f\x37\xa9\x0a\x50\x79\xcb\x10\x32\x5b\x12\xd5\xea\xd7\xf5\x06\xd3\xfa\xe5\x45\x19\xd7\x23\x85\xc8\xcb\x5b\x9d\x3a\xd8\xf2\xa8\x0c\xe9\xbf\x2a\x8d\xb0\x97\xf4\x06\x89\x93\x52\xfe\xb2\xca\x08\x46\x9a\x82\xb5\x35\x44\x76\x09\xb8\x89\x41\xa1\xb4\x90\xe8\xe2\x31\x56\xda\x8b\x14\x01\xbc\x14\xc5\xd1\xe4\x86\x2e\xc7\xed\x5f\x0e\x8f\xb7\xd1\xef\xe8\x35\xda\x00\x98\xbc\xc1\x86\x0d\x0b\x7a\x17\xa7\x76\x96\x7d\xe3\xfd\xe5\x6b\x49\x39\x0b\x88\x75\x55\x73\xbc\xfe\x0b\x65\xce\x65\x45\x4e\xa3\xb8\x26\xc3\x98\xad\x32\x9e\x28\x9a\xe5\x03\x66\xa0\x5e\x24\xf1\x20\xb7\xd4\x03\x42\x83\xbd\x91\x62\x19\x08\xdd\x41\x0e\x42\x8b\x65\x21\x2e\x1d\x10\xc2\x36\x69\x9e\xb2\xa2\x67\xba\x68\xc4\x3e\x4b\xb8\xaa\xaa\xe7\x65\x84\x22\xe4\x10\x8c\xd0\xdd\x84\x23\xb4\xa4\x80\x84\x54\x79\xce\x3c\x74\xe5\x74\x2f\x9f\xbd\xc4\xd2\x78\xa5\x49\x56\xa2\xb8\x24\x60\x39\x45\x2c\xa9\xf0\x12\x92\x56\xfb\x49\xd2\xfa\xd6\x25\x2d\x87\x7c\xe5\x50\xef\x9c\x1e\x17\xcb\x39\xcb\xaa\x77\x2c\x2c\x5d\xe7\xe5\x4f\x4c\xfc\x9f\xc7\xc4\x0b\x4f\xb3\x8f\xc0\xb2\xf7\xa3\x61\x82\x21\x72\x03\x03\xae\x81\x64\x72\x48\x3e\xb9\xab\x88\x1a\xd3\x38\xbe\xc0\x6d\xf9\x17\x54\xff\x5b\x6d\x0e\x65\x77\x85\xc5\xe7\x6d\x52\x66\x89\x5d\xa0\xf3\xb4\x0b\xfc\x2d\x76\x81\x9d\x09\x1e\x66\x49\x1c\x85\x43\xd4\x8f\x03\x3c\x88\xe3\xc5\x0a\xff\x9d\x7e\x91\xc2\x9f\x7e\x5d\x6a\x47\xd8\xe9\xab\x0a\x7f\xf2\xfc\x50\x3b\x80\xcc\xda\x55\x06\xa2\xd6\x2b\xd2\x62\x12\x7c\x94\x85\xf4\x58\xf8\x05\xf8\x4e\xf8\xf1\xd4\x4b\xfd\xc5\x7a\x33\x28\xb3\xc4\x3a\xfe\x7b\x27\x47\xfe\x9f\xb3\x8e\x0f\xe7\xd9\x6c\x9e\x95\xbf\xb4\x3b\x2c\xbc\xb4\x3b\x5c\xfe\xd2\x4e\x97\xea\x0e\xb5\x4b\xbc\xc3\xbf\xf6\x3a\xe8\xd1\xa5\x3a\x53\x37\x2f\xde\x3c\xac\x64\x57\xd0\xd0\xb7\x22\xdd\xfd\x93\x4e\xd8\x87\xda\xb5\xa6\x4b\x88\x3a\x2c\x71\x69\x71\xb8\xe4\xa5\xc5\x53\x16\xbb\xbf\x07\xf3\xdd\xfa\x70\xb2\x8f\x7e\xad\xbd\x6c\xb6\xb8\x81\x38\x4a\x33\xb2\xbc\x2f\x6e\x0c\xee\x3b\xf3\x83\xda\x56\x94\x86\xbf\x92\xd2\x22\x17\xdc\xcc\x0f\x64\xf6\x17\xf8\x99\x2f\x5d\x84\xba\x2e\x40\x53\xf5\x06\x94\xd4\x3a\xc9\x0d\x7e\x15\x03\xe0\x57\x6a\xd1\x81\x9e\x56\x64\xe0\x4a\x28\x02\x44\x31\x8f\x32\xd1\x33\x2d\x98\x15\xd8\xe2\x1d\xd1\x6f\x06\x30\xfa\x62\x55\xc5\xec\x5f\xda\x77\xa3\x35\x1a\xd3\x66\xe2\xa7\x34\x72\x16\x9a\xc5\x69\xa8\x7a\xe0\x93\x46\xc9\x77\x52\xff\x28\xe6\x9d\x15\x2d\xac\x68\x18\xad\xa2\x86\xd6\xc8\x91\x1f\xe4\xcf\x30\x50\x22\xdb\x88\xfa\x9a\xb2\x12\xb9\xad\x3c\xa4\x96\xda\x48\x1e\x52\x4b\x2e\x6d\x0b\xae\xa5\x5a\x66\xaf\x68\x80\xb8\x1d\x22\xb7\xc0\x9d\x47\x16\xe2\xd0\x29\xe2\x2d\xce\xa4\x84\xf3\xca\x54\x51\x05\xbe\x18\xcd\xe2\x99\x93\xfa\x5c\x51\xd1\x5c\x25\xc7\x5f\xd6\xf7\xfc\x22\x48\x42\x81\xed\x2b\x86\x87\x84\x06\xc6\xd1\xdb\xe7\xcf\x6e\xad\x7c\x93\x2f\x97\xeb\x97\xcd\xd6\x52\xbc\xf3\x7e\x89\xc9\x9e\x78\xe7\xd7\xe2\x9d\xfb\x27\x87\x08\x42\xe2\x96\x63\x9d\xfb\x2c\x80\xee\x7d\x59\xe7\x5f\xce\x0e\xf3\x25\xb1\x80\x1f\x5a\x58\x15\x4d\x07\x60\x8f\x40\x57\x4b\xfc\x28\x88\xa7\x15\x83\x03\x56\xab\x35\x4d\x52\x2a\x86\xc3\x52\x87\x9d\x19\x5c\xae\xd9\x3e\xf7\x08\xb8\x27\x46\xa5\x33\x2a\x4e\x9c\x4b\x31\xaa\xbf\x77\xe6\x85\xff\x51\x8c\x6a\x6d\x7f\xa7\x8f\x5e\xae\xbf\x5c\x5f\x6d\x20\x46\x1b\xe8\x00\x67\xe3\x38\x40\x4d\x17\xb7\x82\xd0\xde\x77\xe5\x56\x5b\x41\x40\xfd\x07\xd5\x05\x51\x82\x0b\xf0\xd5\x4b\x6a\xd3\x3f\xbe\x68\x95\x06\xfe\x0f\x4e\x62\xc8\x1d\x96\x8d\x31\x4a\x70\x2a\xf1\x45\xa5\x23\xa4\x1c\xeb\x31\x79\x36\xf0\xbe\x13\x2f\x60\x0b\xf1\x0f\x86\x83\xba\x1a\x9d\xcd\x03\x68\x0a\xcf\xbe\xb0\xe3\x08\xa3\x69\x9c\x60\x2a\x3c\xae\xae\x42\xdf\x5c\xa3\xc8\xd7\xfb\xea\x6a\xc9\x05\x0e\xf3\xb9\xcc\x02\x5f\xbf\x5f\x94\xf3\xa7\x05\xfe\xd5\x4e\x71\x28\x8a\xe3\x59\x39\x31\xe4\x03\x27\x47\xe7\xca\x16\xc4\xee\x5e\x13\x79\x91\x22\x9a\x13\x4d\x2d\x45\x74\xf7\x0b\x37\xfb\x44\x74\x5f\x8b\xe8\xfe\x8f\xc4\xfc\x8a\x49\x4e\xe2\x81\x7f\xa1\xf0\x5b\xfa\xe0\x2c\x9f\x6f\x0d\x01\xb8\x52\x29\x16\x81\xab\xe8\xcb\x17\xfd\xd5\x9d\xb6\x18\x7b\x8f\x17\xc7\x15\x58\x5b\x43\x1f\x09\x7c\xb5\x5e\x68\x44\x0a\x00\xcd\x82\x28\x73\x35\x0e\x27\x18\x55\xbe\xab\xe4\xbe\xd6\x79\x0c\x6e\xf0\x38\x34\x62\x6e\x0b\x13\x4e\x43\x91\x19\x8a\x2d\x09\xa9\x2a\x4a\xdd\xb1\x1b\xe2\xf1\x96\xd9\xbd\x24\x0a\x5a\x8a\x97\xfc\xbd\x1d\xb7\x2c\x39\xba\x68\x92\xac\xc7\xe5\x2b\x79\x26\x24\x68\xed\xaf\xcf\xf3\xf1\xb8\x49\xc2\xcb\xc5\xc4\x36\x62\x5e\x8b\x2f\x27\x7b\x5b\x8d\x3c\xd6\x33\x79\x92\x3e\x9a\x89\xc0\x6d\x0e\xa2\x47\x7e\x9a\x92\x85\xbc\x4a\x50\x0b\xd0\x3b\x7c\x83\xb6\x71\x12\x5e\xd2\x9c\x90\xbb\x7c\x50\x9a\xc5\x31\xa7\x8f\xde\xbc\xdb\xde\x6d\xe6\xad\x89\xe7\x92\x89\xc7\xfb\x71\x34\x0a\x2f\xe6\x2c\x13\x65\x0c\x59\x21\xd3\xa2\xfc\x92\x49\x3c\xc3\x49\x76\x83\xfe\xa4\xc7\x62\xf0\x26\x05\xe6\x7b\x3a\xa6\x39\x8e\x53\xf2\x10\x46\x2c\x5d\x40\x16\x0b\x5f\x9a\x1a\xda\xc6\x23\x7f\x3e\xc9\x7a\xa8\x8d\x2a\x8d\xe6\x06\x24\x52\xae\xba\xe0\x3b\x12\x9a\xe3\x84\x27\x32\xcf\xc1\x91\xf1\x5f\x84\x66\x98\xb1\xe4\x99\x29\x80\xca\x0f\xf5\xd2\x87\x2c\x46\x33\x9c\x8c\xe2\x64\x2a\x01\x57\x20\x4b\xe9\x1f\x87\xa3\x8b\x9e\x6b\x94\x11\xbd\xf8\x3a\x81\x98\x33\x8d\xe6\xc6\x5a\xab\xa9\x85\xe0\xa6\x5d\xa1\xa8\x6b\x9f\x72\x84\x94\xc6\x6f\xab\x45\x09\x49\x8b\x12\xc8\x93\x59\x09\x72\xd2\xe2\xeb\x6d\x71\x16\xd1\x43\xe0\x73\xb7\xa4\xab\x72\xc6\x50\x32\x7e\x43\x1b\xdd\x70\x7f\xb3\x51\x9c\xc0\x29\x26\x6f\xf4\x01\x12\x83\x7e\x0e\x46\x46\xd2\x78\x4a\xed\xfc\xf4\xa8\x98\x61\x2d\x53\xf1\xcf\x7c\xb2\x36\x68\xfa\xc9\x7b\x83\xf1\xd4\x69\xac\xd7\xeb\x3a\xe0\x82\xec\xf5\xc3\xd1\x85\xdd\xf0\x82\x4c\xc4\xa6\xf8\xc9\x09\x8f\x14\x77\x05\xc3\x30\xd7\x3b\x5c\x57\x50\x0f\xba\xb2\x2c\xe8\x2e\xf9\x66\x67\x0c\x36\x50\x0b\x7f\xa8\x95\xac\x9c\xfa\x93\x0c\x6d\xc1\x3f\xcb\x27\xa2\xe5\x6e\x34\x92\x5f\xfb\x7d\xc8\x8e\x26\x52\x0f\x46\x35\x16\x95\xa4\xc2\x3b\xe3\x01\x7e\xce\x49\x65\xc5\xe5\x79\xd5\x6a\x2e\x95\xdb\x45\x9d\x7a\xab\x01\x61\x98\x39\x92\xc2\x32\x2f\x7b\xf0\xdd\x67\xb4\x4a\xc8\x87\xf2\x20\x4f\xcc\x8e\xdd\x2c\xd1\x9d\xa0\x1c\x64\x53\x3a\xd8\x34\xdd\xbc\xa1\xcf\xb1\x85\x7a\x02\x39\x79\x3f\x0a\xf0\xb5\xad\xc6\x59\xfd\x9a\x29\x80\x2c\xd1\x3a\x17\x84\xe8\x12\xa8\x08\x61\x59\xbc\x71\xe6\xaf\xcf\xb1\xe1\x95\xf2\x37\xce\x4a\x7c\xcb\xdb\x24\xb3\x52\x63\x4f\x36\x23\x8c\x7c\x6b\xa1\x45\xf3\x17\x0b\x8c\x2c\xd4\x8f\x4c\x50\xd7\x3a\xc8\xe3\x22\xbd\xe6\xf8\x58\x8d\x0b\x44\x27\x59\x9e\x63\x9e\x2c\x1b\x28\x30\x4f\xe3\x9b\xf7\x5a\x9f\x33\xc4\x32\x7a\xe7\xa9\x81\xcd\xef\x8b\xb3\x31\x00\x7c\x65\x88\xad\xa3\x6b\x16\x17\x59\x8c\xf2\x57\xac\xe3\x0e\x44\xf6\xc5\x18\xdb\x41\x87\x72\x34\x3b\x06\xd6\x82\x85\x62\xcb\xd1\xa0\xb6\x1c\xd2\xf4\x39\x8d\x39\x10\xf0\x73\xa5\x09\x18\x3d\x31\xd2\xf2\x47\xdb\x58\x97\x19\x6f\xb4\x28\x14\x94\xad\xb3\x7c\xf4\xe5\x77\xf6\x80\x55\x52\x13\xbf\x1e\x1e\xab\xdd\x01\xd7\x29\x8b\xc7\xb5\x31\x6e\xbf\x53\x1b\x98\xdf\xb9\x0d\x8c\x34\x9b\xaf\xd0\xef\x05\xa3\x47\xfe\xf2\x1a\x67\xbf\x83\x39\x8c\xd1\x91\xb3\xdf\x75\xb3\x18\xfe\x77\x6b\xbe\xd6\x03\x4e\x91\x3f\x89\x39\x30\xdd\x34\x34\x6a\x9b\x12\x8d\x49\x9c\xd5\xcf\x57\x56\x8a\x4d\x8a\x24\xe0\xd2\xd1\x97\xf3\x0d\x4b\x10\x33\xb6\x97\xe5\xf5\x8a\x0c\x28\xe5\x63\xc4\xbd\x36\xf4\x32\xc1\x66\x0a\x37\xf2\x25\x37\xf1\x87\x12\x2d\xc3\xd4\x96\x6e\x7f\x71\xf4\x1a\x8b\x68\xf0\x00\x41\x6c\xa8\x88\x20\x24\x43\x2a\x14\xba\xc4\x84\xe5\xaa\x79\xc8\x21\x9b\xde\x07\x4c\xa1\x6c\x9a\x07\xd9\x11\x47\x49\x97\x00\xe3\x21\x5d\x50\x65\xc3\xae\x8a\xc5\xa4\xd0\x02\xe1\xe9\xb6\xc8\x16\x8d\x42\xb3\x07\xea\xd1\x53\xe8\xf2\x9c\xb0\xb7\xe7\xde\xfa\xdf\xdb\x87\x7e\x89\xb4\xee\x8b\x93\xa3\x3f\xae\xee\xc8\x99\x5e\xdb\x95\xf5\xfa\x9f\xa0\x5d\x3a\x01\xe3\xcc\x3e\x37\xde\xa5\x4a\x24\xf9\x65\x91\x1e\x49\xe0\x71\x8c\xe7\xa9\x3f\x98\x60\x16\x0e\x4c\x42\xe7\x04\xc9\xa9\x16\x29\x14\xfd\xcd\x5b\xa4\x66\x58\x93\xb6\x85\x63\xc8\xa6\x8c\x98\xa1\x2d\xb3\x31\x36\x35\x49\xa2\x3c\xc4\x58\x09\x53\xe4\x23\x9a\x80\x19\x5d\xe2\x24\x85\xa8\x65\x63\x3f\x43\x11\xbe\x98\xe0\x61\x86\x03\xc2\x86\x87\x2c\xa5\x6a\xc6\x14\x3e\x59\x8c\x26\x61\x96\x4d\xf0\x2a\x0d\x70\x59\x53\x81\xe2\x24\x89\x13\x14\xc4\x38\x8d\x5e\x64\xc8\x1f\x8d\xf0\x90\xd6\xa5\x48\xbd\x48\x51\x8a\x87\xf3\x24\xcc\x6e\x3c\x51\x71\x30\xcf\x50\x98\x41\x25\x5e\x23\xcc\x52\x11\x50\x21\x9c\x84\x19\x73\xe2\xa6\x79\x5d\x43\xc2\x9f\xa7\x38\xa2\xfb\x41\x6a\x53\x94\xd1\x01\x79\x4f\x3b\x27\xd4\x65\xda\x5b\x79\xfe\xee\x9a\xb4\xad\xf8\x90\xf2\x4e\x36\x83\x76\x1e\x30\x72\xeb\x6d\x38\x35\x5c\x16\x9d\x16\x42\x76\x42\x23\xbb\x17\x76\x9e\xd3\x7e\x15\xed\x92\x5f\x96\xc4\x71\xef\xce\xea\xe7\x1e\xaa\xbc\x3b\x6b\x9d\xb3\x60\x01\xe8\x0b\x79\x64\x57\x01\x8d\x6e\xd5\x92\x44\xee\xdd\x59\x83\x56\xaa\xab\x95\x5a\xc5\x95\x9a\xb4\x52\x43\xad\x54\x2f\xae\xd4\xa2\x95\x9a\x6a\xa5\x86\xa8\xa4\xd6\xb1\x65\x47\x32\x86\x8c\x7b\x19\xba\x06\xad\x2f\x06\xad\x6f\x1f\x34\x13\x1f\x69\xb8\x58\x9f\xe8\x85\xc9\x68\xc4\xd3\x0e\x52\xa4\x69\x90\xd5\x7a\x9d\x7c\xb1\xf5\xd7\x9c\x88\x96\x0a\xb9\x61\x85\xdc\x2c\x05\xb9\xee\x1c\x78\x09\x86\x06\xb9\x55\x0a\x72\xc3\x35\x3b\x9e\x04\x43\x83\x5c\xd7\x20\x2f\x9e\xc8\xbe\x9f\x24\x37\x68\xa0\xa7\x53\xa5\x53\x35\xa0\xf1\x2f\x4c\x4d\x46\x46\x27\x9f\xb0\x9e\xf4\x26\xcd\xf0\x14\x8d\xe2\x79\x82\xb2\x70\xaa\xcf\xfd\x92\x41\x79\x23\x7c\x9d\x9d\x90\xd5\xe7\x8e\x1f\x6b\x89\x78\x7b\x10\x07\xe1\xe8\x86\x72\x42\x4a\x87\x25\xb0\xd8\x70\x63\xd1\x3f\xa3\x8e\x03\xbf\x9e\x41\xca\x4b\x88\xb6\x62\x64\x8a\xb3\x25\xc9\xfd\x19\xa5\x38\x9b\xcf\xd4\x0f\x05\x1e\x1d\x8b\x0f\xfb\xfb\x3f\x53\xd7\x8e\xa2\x13\xfe\xfe\xcf\x9f\xea\x68\x13\xed\xff\x6c\xa6\x46\x93\x8a\x34\x68\x91\x86\x35\x9a\xb1\xbc\xa4\x61\x2a\xd3\xf9\xe0\x12\x13\x51\xc1\x75\xf4\xaf\xd3\xe0\xc7\xd0\x36\x8d\x7e\xfc\x05\xd1\x27\x57\xf4\x63\xb9\x38\x0b\x73\x2c\xca\xe7\xd7\xa1\xf6\x30\xc7\xa2\xd9\xa6\x68\xb6\xa1\x34\xdb\x58\xd4\x6c\x43\x6d\xb6\xb1\x5c\xb3\x10\x46\x27\xac\xf3\x25\x48\x80\x84\x4d\x75\x05\xba\xaa\xb6\xa0\x6a\x93\x2f\x66\xa8\x5a\x57\x97\xa9\x63\x46\x18\x59\x17\xb1\x56\x04\xd4\x5a\xa7\xe7\x7a\x3d\xb6\x3f\xfd\xd8\xa0\x1f\x1b\xd6\x8f\x4d\xfa\xb1\x69\xfd\xd8\xa2\x1f\x5b\xd6\x8f\xed\xa2\x36\x3b\x45\x6d\x76\x8b\xda\x5c\x17\x6d\x16\x68\xa4\x4a\x71\x1e\xb4\x3c\xf7\x41\xe5\x38\x10\x32\x95\x14\xb2\x1f\xd1\x83\x24\x77\x75\x2a\xaf\x25\xe9\xa3\x14\x67\x56\x8b\xd8\x7b\xe7\xde\xde\x61\x70\x73\x2f\x33\xe0\x42\x6a\xe9\x13\x1a\x6a\xe8\x57\x20\x42\x54\xf9\x95\xcc\x3d\x5f\x25\xf0\x2c\xf6\xde\x57\x7a\xc5\x06\xad\xd8\x64\x15\xd7\xb5\x8a\x1d\x67\xc5\x26\xad\xd8\x66\x15\x1b\x5a\xc5\x75\x67\xc5\x16\xad\xd8\x3d\x17\xa8\x29\x15\x1b\x79\xc5\x7b\xed\x62\x45\x51\xea\x29\x22\x3c\x76\xfc\x09\x4b\xc9\xce\x82\xc7\xc3\xe3\x5d\xa2\xc7\x73\x38\x8c\xc1\x09\x38\xb6\xf8\xf1\x56\x7c\xad\x4e\x78\x48\xca\xd1\x2b\xbc\xe9\x4e\x8a\xbd\xe8\x64\xea\x17\x76\x3c\xf9\xcd\x6d\xfe\x31\xbc\xa4\x5f\xba\xed\xb5\x56\x53\x57\xcb\x89\x65\x22\x08\xb6\x52\xd2\x15\x4a\x59\x1f\xca\x17\x49\x04\xd5\x0c\x7e\x4e\xfc\x4b\x8c\xe2\x49\xe0\x64\xb5\x4b\xc8\x0f\xfd\x4f\x74\x72\xfb\x7a\xbc\x43\xa5\xc5\xbe\x3f\x19\xce\x27\x64\x85\x45\xf8\xca\xd9\x6c\x9f\x25\x82\xe9\xd3\x44\x30\xf5\xeb\x76\xd0\x82\xff\x43\x2b\x5c\x42\xd3\xf3\xb5\xf4\x59\x5e\x98\x3e\xcd\x0b\x53\xbf\x66\x35\x5a\x10\x53\xbe\xcf\x05\xd4\x7a\x15\xbd\x46\x95\xfe\x27\xe9\xf9\x3f\x50\x03\xf5\x50\xbd\x6a\x42\x6c\x32\x88\x4d\x0a\x91\x01\x6c\x33\x88\x0d\x0d\x62\xa3\x04\xc4\x16\x83\xd8\x32\xba\x55\xa1\xed\x28\x10\x9b\x25\x20\xb6\x19\xc4\xb6\xb5\xd7\x2d\x0d\x62\xab\x04\xc4\x0e\x83\xd8\xb1\xf6\xba\xad\x41\x6c\x97\x80\xd8\x65\x10\xbb\xd6\x5e\x77\x34\x88\x9d\x12\x10\xd7\x19\xc4\x75\x6b\xaf\xbb\x1a\xc4\xee\x42\x88\xb9\xd8\x4f\x81\x2a\xd5\xd7\xf5\xea\xba\x77\x8c\xa0\x69\xb2\xfb\x5c\xac\xde\x63\x11\x91\x52\x17\xd7\xc0\xab\x43\xd2\xb5\xbe\x25\x09\x07\x4f\x97\x9f\xcc\x87\x19\x1a\x87\x17\x63\xe4\x47\x01\x9a\xc4\x57\xc8\x4f\x2e\xe6\x10\xfe\x05\xdc\x9c\xff\x7b\xee\x27\x46\xe2\x1e\x68\xc0\x47\x9b\xa4\x15\x2e\xc5\x59\x94\x07\x17\x03\x5a\x84\xee\x12\xd6\xe3\x13\xef\xb3\x82\x41\x82\xd3\xf9\x24\x43\xf1\xa8\xa8\xf9\x31\xdd\x02\x2a\x17\x3e\xfa\x11\x5d\xf8\xd4\x75\xa5\xb1\x5e\x45\x2b\x88\xbe\x1a\xb0\x57\x1d\x78\x35\x80\x57\x36\x24\x27\x14\x90\xd4\x15\x7a\x24\xfc\x11\x5d\x5c\xc3\x0c\x57\x81\x20\x78\x01\x21\x76\x4a\x05\x6c\x89\x60\x48\x87\x7e\x3d\x3c\x46\x10\x4e\x52\xfe\xf8\x96\x72\xb8\x8b\x31\xfa\x0d\x5d\x4c\xca\x32\x39\xbb\x52\xe5\x57\xc6\xe2\xde\x52\x16\x57\xa9\xbc\xcd\xb7\x6f\xb2\x93\xbd\x95\xc4\x82\x2a\x2b\xd0\x55\x0b\x74\xf3\x02\x3a\x3d\xff\xca\xb8\xe1\x5b\xca\x0d\x2b\xb4\x99\x7c\xbf\x7d\xcb\xf9\x1f\xec\xb7\x2b\x88\xb4\x66\xc2\x68\x32\x18\x4d\x0e\xa3\xa1\x22\xd0\x30\x30\xac\xab\x05\xea\x45\x18\xb6\x18\xf4\x16\x87\xde\x54\x31\x6c\x6a\x18\x36\x2c\x18\xb6\x19\x8c\x36\x87\xd1\x52\x11\x68\x19\x18\x36\xd5\x02\xcd\x22\x0c\x3b\x0c\x7a\x87\x43\x6f\xab\x18\xb6\x35\x0c\x5b\x16\x0c\xbb\x0c\x46\x97\xc3\xe8\xa8\x08\x74\x0c\x0c\xdb\x6a\x81\x76\x11\x86\xeb\x0c\xfa\xfa\xb9\x42\x22\x02\xc3\xae\x86\x61\x47\xc1\xb0\x54\xe2\x8f\x94\x27\x9d\x10\xba\xd6\x12\x69\x27\x16\x5d\x77\x51\x58\x19\xbe\xce\xe4\x7b\x27\x59\x93\xca\x43\x29\x28\x69\x1c\xe8\x6d\x91\x79\x7f\x35\x9b\xf8\x04\x9b\xeb\x0c\x39\xc1\xb1\x38\x33\x95\xbc\x65\x1b\x44\x71\x71\x55\xa4\xd4\x55\x93\x77\xc8\x25\xab\x45\x77\x50\x72\xc1\xd2\xc6\xc8\x9e\x7a\x37\xd2\xeb\xb4\xbd\xfc\x52\xa4\xd7\xe9\x7a\xec\xae\xa4\xd7\x6d\xdc\x9e\x7b\xeb\x7f\xef\x48\x84\x4f\xf7\x55\x4f\xf7\x55\x8f\x76\x5f\xa5\x2d\xf1\xfc\x3e\x47\xbf\xc9\xf9\x7b\xdd\xe1\x3c\x54\x56\xb8\x77\xe2\x68\xfe\x4e\x3d\x9a\xbf\xbb\xeb\xd1\xfc\x9d\x7a\x34\x7f\x57\x74\x34\x5f\xa4\x60\x7e\xba\xa9\x7a\xba\xa9\x7a\xba\xa9\x52\xbe\x3c\xdd\x54\x3d\xdd\x54\x3d\xdd\x54\xe5\xcd\x3e\xdd\x54\xe9\x1f\x9f\x6e\xaa\x1c\x8f\x4f\x37\x55\x4f\x37\x55\x4f\x37\x55\xf0\xf7\x74\x53\x55\x4e\x89\xfb\x74\x53\xf5\x74\x53\xf5\x74\x53\x25\xfd\x3d\xdd\x54\x3d\xdd\x54\x3d\xdd\x54\x3d\xdd\x54\xfd\x4f\xbe\xa9\x7a\xb0\x3b\xaa\xbb\xdd\x4e\x95\xb9\x97\x2a\x71\x23\xf5\x58\x77\x51\x7f\xef\x7c\x28\x4f\x77\x51\xff\xfc\xbb\x28\xf9\xee\xa8\xdf\x5e\xe8\xe8\x24\xdf\x1c\xf5\xdb\xd2\xb5\x11\x3c\x3c\xfe\x9d\x11\xf5\xd2\x14\xb7\x46\xf6\xa0\x02\xdc\x43\xbb\xe8\x5a\x09\xdc\x38\x65\x8f\x62\x29\x66\xba\xa9\xaf\x88\xc2\x0c\xa5\x83\xf8\xda\x84\x73\x22\xd0\x39\x91\xaf\xe9\xf8\x9f\x4d\x9a\x6c\x76\xba\xee\x43\x39\x3b\x74\x87\x8b\xd5\xb8\xef\xf0\x8d\x4d\x8f\xab\xb6\xe8\x71\xff\xf1\x85\x0d\xb3\x41\x21\x43\xc0\xa3\x4a\x84\xe8\x5f\xf2\x38\x39\x54\x87\xac\x12\xd9\xda\xf8\xd8\x9f\x29\x80\xcc\x48\x68\xca\x67\x23\x28\x9a\xed\xec\x4f\x7a\x51\xf9\x1d\xad\xd0\xf1\x59\xe1\x8d\x56\xd1\xbf\xa0\x57\x8e\x58\x0a\x57\xfe\xcc\x8e\x33\xec\x1b\xa6\x86\x40\x9a\x80\x13\xbb\x63\x3c\x79\x4d\x66\x7c\xf1\xf4\xf4\xad\x2a\x7e\x96\x55\x43\x10\xcd\xef\x2c\xcb\xac\x00\x74\x6f\xb5\x1c\xd7\x84\x80\x16\xc4\xc8\xbf\x4e\xa6\xc7\xae\x32\x54\x5a\x16\x4e\xce\xcd\x4e\xd7\xa1\x10\xa9\x3b\x95\x21\xd6\x46\xcb\x2a\x46\xa4\xf5\xa4\x29\x46\xf2\x41\x0b\xb5\x2f\xbf\xe7\xc3\xb9\x30\x03\x3c\x28\x07\xd5\xea\x9f\x65\x3c\xb5\xf9\x10\xab\x29\xa2\xcb\x28\xa2\x2a\xb5\xc8\xb2\x88\x42\xd0\xa0\xd3\x84\x71\x8c\x2a\x95\xef\x0a\x09\x3b\x08\xd7\x4a\xb4\x05\x04\xeb\x26\xd6\x9c\x50\xd5\xf7\x6a\x67\xbf\x90\xba\x15\xb6\xa6\x48\x15\x86\xd7\x79\x9e\xd7\x20\xd2\xf3\x18\x68\xc7\xa7\x8f\x10\x07\xc5\x72\xa3\x95\x93\x7a\x68\x9c\xdd\xc9\x58\x28\x73\xc5\xc4\x32\x05\xbb\x6f\x55\xee\xed\xb7\x1f\x42\xe8\xed\xb7\x97\x96\x78\xcd\x3d\x56\x13\x77\xfb\x6d\x6b\x6c\x0b\xb8\xa1\x09\x71\x70\x87\x1d\x7e\x3b\x89\x67\xca\x2e\xcf\x5e\xc0\x20\x7c\x85\xa8\x78\x01\x69\x4e\x0d\x34\xa7\xe9\xf9\xc9\xc4\x93\x52\x22\xd4\x1c\x6a\xbc\x6c\xca\x60\xf5\x58\x73\x04\x75\x29\xea\x97\xb6\x8a\x09\xa8\x9e\x0a\x42\x8d\x18\x57\x4a\x88\x21\x6d\xf0\x82\xc5\x77\x18\x64\x3c\x0b\x36\x70\x61\xf8\x42\xf0\x22\xbb\xf8\x4f\xb0\x99\xaf\xae\x5a\xf7\xf0\x25\xd8\x3d\x5a\x90\x00\xe9\x1b\x5a\x6d\x64\x88\x1e\x66\xc5\x01\xa4\xe5\x57\x1d\xa3\xf9\xe2\x95\x47\x0a\x15\x9f\x34\xfb\xed\xc7\x3a\x66\xde\x2f\x5d\xdf\xd7\x3c\x5f\x3e\xda\x29\xf0\xeb\x06\x71\x26\xac\x0a\xa7\x38\xb9\xc4\xcf\x9f\x55\x86\x55\xd4\xac\x37\x9a\x68\x70\x83\xfa\xff\xdf\xff\x1b\x24\xe1\x10\x1d\xe0\x34\x0a\x27\x35\xb4\x35\x99\xa0\x24\xbc\x18\x67\x29\x62\xe5\x83\xda\xf3\xe7\xcf\x8e\x71\x10\xa6\x59\x12\x0e\xe6\x00\xdf\x8f\x02\x08\xca\x13\x46\x28\x8d\xe7\xc9\x10\xc3\x9b\x41\x18\xf9\xc9\x0d\x61\x07\xd3\xd4\x63\x51\x1a\x12\xf8\x37\x9e\x67\x68\x0a\x3c\x7d\x08\x9c\xd5\x43\x7e\x82\xd1\x0c\x27\xd3\x30\xcb\x70\x80\x66\x49\x7c\x19\x06\x38\xa0\x41\x27\xc8\x3a\x1d\xc5\x93\x49\x7c\x15\x46\x17\x68\x18\x47\x41\x48\xd7\x30\xa9\x34\xc5\x59\x8f\xad\xf8\x55\xa4\xa2\x95\x82\x62\x98\xe2\x33\x8c\x03\x8c\xa6\xf3\x34\x23\x1b\xb5\x1f\x46\x00\xd4\x1f\xc4\x97\xe4\xd3\xec\x06\xba\x88\xa2\x38\x0b\x87\xd8\xa3\x71\x85\x26\x61\x0a\x9a\x65\xb9\xbd\x28\xd0\x90\x09\xc2\x74\x38\xf1\xc3\x29\x4e\x6a\x2e\x1c\xc2\x48\x1e\x08\x8e\xc3\x2c\x89\x83\xf9\x10\x3f\x38\x1a\x88\x75\x2d\x88\x87\x73\x11\x07\x83\xd4\x58\x8b\x13\x16\x23\x63\xea\x67\x38\x09\xfd\x49\x9a\x0f\x33\xcc\x0d\x54\x93\x50\x27\xf3\x7c\xba\xb7\x7f\x82\x4e\x0e\x77\x4f\x7f\xd9\x3a\xde\x41\xfb\x27\xe8\xe8\xf8\xf0\xe7\xfd\xed\x9d\x6d\xf4\xe6\xdf\xe8\x74\x6f\x07\xf5\x0f\x8f\xfe\x7d\xbc\xff\x76\xef\x14\xed\x1d\xbe\xdf\xde\x39\x3e\x41\x5b\x1f\xb6\x51\xff\xf0\xc3\xe9\xf1\xfe\x9b\x8f\xa7\x87\xc7\x27\xe8\xfb\xad\x13\xb4\x7f\xf2\x3d\x7c\xd8\xfa\xf0\x6f\xb4\xf3\xeb\xd1\xf1\xce\xc9\x09\x3a\x3c\x46\xfb\x07\x47\xef\xf7\x77\xb6\xd1\x2f\x5b\xc7\xc7\x5b\x1f\x4e\xf7\x77\x4e\x3c\xb4\xff\xa1\xff\xfe\xe3\xf6\xfe\x87\xb7\x1e\x7a\xf3\xf1\x14\x7d\x38\x3c\x45\xef\xf7\x0f\xf6\x4f\x77\xb6\xd1\xe9\xa1\x07\x8d\x9a\xd5\xd0\xe1\x2e\x3a\xd8\x39\xee\xef\x6d\x7d\x38\xdd\x7a\xb3\xff\x7e\xff\xf4\xdf\xd0\xde\xee\xfe\xe9\x07\xd2\xd6\xee\xe1\x31\xda\x42\x47\x5b\xc7\xa7\xfb\xfd\x8f\xef\xb7\x8e\xd1\xd1\xc7\xe3\xa3\xc3\x93\x1d\x44\xba\xb5\xbd\x7f\xd2\x7f\xbf\xb5\x7f\xb0\xb3\x5d\x43\xfb\x1f\xd0\x87\x43\xb4\xf3\xf3\xce\x87\x53\x74\xb2\xb7\xf5\xfe\xbd\xb5\x97\x04\x77\xa5\x8f\x6f\x76\xd0\xfb\xfd\xad\x37\xef\x77\x68\x4b\x1f\xfe\x8d\xb6\xf7\x8f\x77\xfa\xa7\xa4\x3b\xf9\xaf\xfe\xfe\xf6\xce\x87\xd3\xad\xf7\x1e\x3a\x39\xda\xe9\xef\x93\x1f\x3b\xbf\xee\x1c\x1c\xbd\xdf\x3a\xfe\xb7\xc7\x60\x9e\xec\xfc\xef\x8f\x3b\x1f\x4e\xf7\xb7\xde\xa3\xed\xad\x83\xad\xb7\x3b\x27\xa8\xb2\x60\x48\x8e\x8e\x0f\xfb\x1f\x8f\x77\x0e\x08\xce\x87\xbb\xe8\xe4\xe3\x9b\x93\xd3\xfd\xd3\x8f\xa7\x3b\xe8\xed\xe1\xe1\x36\x0c\xf4\xc9\xce\xf1\xcf\xfb\xfd\x9d\x93\x57\xe8\xfd\xe1\x09\x8c\xd6\xc7\x93\x1d\x0f\x6d\x6f\x9d\x6e\x41\xc3\x47\xc7\x87\xbb\xfb\xa7\x27\xaf\xc8\xef\x37\x1f\x4f\xf6\x61\xd0\xf6\x3f\x9c\xee\x1c\x1f\x7f\x3c\x3a\xdd\x3f\xfc\x50\x45\x7b\x87\xbf\xec\xfc\xbc\x73\x8c\xfa\x5b\x1f\x4f\x76\xb6\x61\x74\x0f\x3f\x40\x57\x4f\xf7\x76\x0e\x8f\xff\x4d\x80\x92\x31\x80\xc1\xf7\xd0\x2f\x7b\x3b\xa7\x7b\x3b\xc7\x64\x40\x61\xa4\xb6\xc8\x10\x9c\x9c\x1e\xef\xf7\x4f\xe5\x62\x87\xc7\xe8\xf4\xf0\xf8\x54\xea\x23\xfa\xb0\xf3\xf6\xfd\xfe\xdb\x9d\x0f\xfd\x1d\xf2\xf5\x90\x40\xf9\x65\xff\x64\xa7\x8a\xb6\x8e\xf7\x4f\x48\x81\x7d\xda\xec\x2f\x5b\xff\x46\x87\x1f\xa1\xcb\x64\x8e\x3e\x9e\xec\xd0\x9f\x12\xc5\x7a\x30\x93\x68\x7f\x17\x6d\x6d\xff\xbc\x4f\xd0\x66\x85\x8f\x0e\x4f\x4e\xf6\x19\x9d\xc0\x90\xf5\xf7\xd8\x70\xd7\x9e\x3f\xfb\x71\x4d\xd5\x79\x1d\xf8\xd9\xf8\x61\xf5\x5e\xe5\xa2\x4e\xd3\xc0\xc7\xa2\x08\x7d\x2c\x65\x9d\x0d\x17\x76\x7e\x94\xa5\x28\xf3\x07\x5c\x62\x21\x55\x3e\xfd\x31\xb1\x06\xdb\xcc\xe5\xa8\xba\x87\x50\xc3\x43\xa8\xe9\x21\xd4\xf2\x10\x6a\x7b\x08\x75\x3c\x84\xba\x1e\x42\xeb\x1e\x42\x1b\x1e\x42\x2f\x3d\xd4\xa8\x7b\xa8\xd1\xf0\x50\xa3\xe9\xa1\x46\xcb\x43\x8d\xb6\x87\x1a\x1d\xc9\xc2\x72\x9d\xd6\x25\xdf\x08\x3c\x52\x9e\xc0\x68\x74\x28\x5c\x52\x0f\xda\x7a\xc9\xe0\x37\x19\x8c\x06\xb4\x91\xc3\x69\xb1\xb6\xda\x0c\x97\x97\x0c\xc6\x86\x84\xe7\x3a\x83\xd5\x65\xb8\x34\x28\xcc\x86\x1c\x6b\xb9\xc1\xea\x72\x5c\xea\x14\x06\xe0\xc1\xf1\x6c\x51\x58\x04\x7e\x43\xee\xb7\x0c\xa7\xcd\xea\x76\x18\xee\xeb\x0c\x46\x53\xc2\xb3\xc1\x60\x6d\x30\x5c\x58\xbf\x1b\xad\xf3\xea\x2b\x79\x2e\x92\x05\x73\xc1\xf1\x58\x97\xc6\xaa\xc9\x60\x72\x9c\xbb\xea\x78\x40\xdf\x5a\x5a\xdf\xbb\xac\x4e\x2b\x87\x05\x75\x3b\x39\xce\x1c\x06\x1f\x0f\x68\xab\xa1\xf5\x1d\x0a\x75\xa4\x0e\xae\x33\x04\xbb\xf9\xe0\x0a\x20\x4d\x69\xa0\x29\xb2\x39\xa0\x0d\x56\x47\x1a\x2c\x98\x98\x4e\x3e\xb8\x02\x46\x4b\x1a\x68\x8a\xac\x84\x50\x93\x8d\x6c\x5d\x02\xc6\x47\x63\x5d\xcc\x9e\xa0\x50\xc4\x46\x87\x22\xab\xce\x46\xba\x68\x65\x50\x14\xd9\x58\x01\x7a\x72\x4b\x9c\xb6\x5a\xd2\x78\x76\xf3\x6f\x0a\x4d\xaf\x7b\xf0\x09\x86\x8a\xd3\xeb\xcb\x9c\xf6\x38\x4d\x35\x3a\xd2\xb0\xae\xb3\xb2\xca\x7c\x34\x72\x22\x10\x73\xf1\x92\x15\xe4\xc4\xb3\x21\x95\xe1\x88\xaf\xc3\x6f\xf9\x2c\x25\xd6\x72\x3b\xaf\xca\xdb\x17\x6b\x5e\x5e\x13\x1b\x0a\xc8\x1c\x14\x5f\x9f\x9d\x9c\xf6\x45\x3f\x9b\x39\x0a\x62\x9c\x18\xc9\x50\xb8\x48\x9b\x92\x45\x0b\x84\x21\xa6\x0c\x7e\x27\x47\x00\xfa\xb9\x9e\x2f\x44\x68\xb0\xcd\x10\xe9\x6a\x48\xb7\xd4\xc1\x17\x9d\x6e\xe4\x70\xc4\xd8\x89\x05\x0d\xdf\x15\x38\x82\x81\x34\xa4\x41\xea\xe6\xed\x8a\x85\xc7\x16\x70\xa3\x65\x99\x0f\xd1\x01\x0d\x71\x0e\x48\x2c\xb8\xa6\xf4\x6f\x47\xac\x62\x75\x80\x3a\x96\x72\x6d\x75\x66\xc4\x4c\xe6\x9d\x42\x8d\x06\x3a\x57\xb2\x64\x7f\x1a\x93\x15\x62\x99\x0f\x24\x42\x35\xd7\x3d\x54\xbf\xee\x6c\x6d\x34\xd7\x5f\xbe\x7c\x49\x7e\x77\x77\xb6\x5f\xee\xbc\xd9\x6a\x90\xdf\x1b\xbb\x8d\x37\x6f\xfa\xdb\x7d\xf2\x7b\xeb\x65\xa7\xb5\xbb\xdd\xde\x51\xe7\x7b\x9c\x38\x1b\xe8\xd4\xb7\x9a\x1b\x6f\x76\xba\xd0\x40\xbf\xbd\xbd\xdd\x68\xb6\xa1\x81\xed\xf5\x7a\x6b\x67\xb7\x45\x7e\xaf\x6f\x75\xb7\xd7\xbb\x3b\xd0\x30\x47\xe8\xdc\xaa\x0f\x38\xde\x3f\xda\x39\xd8\x6e\x74\xeb\x10\x7e\x7f\x81\x0e\x49\x94\xcd\xb5\x48\xd2\x2b\xba\x2b\xdf\xf5\xae\x88\x2a\x13\x01\x09\x47\x10\xec\xee\x7a\xbb\xd3\x6c\xd5\x61\x04\x77\x76\xfb\xdb\x5b\x6f\x36\xa0\x83\x2f\x37\xde\x6c\x6d\xf7\x77\x77\xc8\xef\x46\xbd\xd5\xec\xb4\xd7\x61\x70\xfa\xad\xed\xe6\x4e\x63\xb7\x7e\xee\x54\x8d\x97\x55\xca\x5b\x15\xbb\xa5\xbd\x94\x1a\x05\x37\x35\x8b\xcd\xf1\x29\x16\xa0\x7b\xcd\xcd\x22\x1d\xd7\x37\x07\x9f\xa4\xd2\xfc\xf2\xe0\x93\x69\xc8\x84\x8a\xee\x54\xa4\x7a\x68\x13\x55\xcc\x02\x88\x1a\x80\x4a\x8d\xe5\x86\x0f\xd2\xcb\xe5\x8c\x4a\x0d\x80\xcc\xae\x54\x03\x68\x5a\x97\x9a\xe0\x0a\x54\x63\x68\x91\xad\xf3\x1e\x12\xf7\x0f\x84\x14\x9d\x57\x8e\xc0\x00\x3e\x8d\x27\xee\x02\x09\x14\x48\x9c\x05\x40\xfc\xfc\xf4\x87\x1b\x02\xc8\x44\x9f\xfe\x70\x43\x80\x6d\xfa\x53\xea\x86\x00\x9b\xc6\xa7\x34\xb1\x47\xb4\x5e\x5b\x23\xab\xec\x33\x39\x34\x5f\xfa\x49\x48\xa4\x63\xcb\x25\xad\x3f\xf1\xd0\x60\xe2\xa1\xe1\xc4\x43\xc1\xc4\x43\x78\x62\x69\xc8\x4f\x3c\x34\x48\x3c\x34\x4c\x3c\x14\x24\x1e\xc2\x89\xde\x98\x4f\x50\xf1\x09\xc2\x7b\xa6\xcb\xc8\x20\x81\xa0\xe3\xf0\xb1\xa1\x7f\x1c\x92\x8f\x43\xfa\xb1\xa9\x7f\x0c\xc8\xc7\x80\x7e\x6c\xe9\x1f\xe1\xc0\x80\xe9\xc7\xb6\xfe\x51\xa4\xa9\xf6\xd5\xbc\xd4\xbc\x4b\xfa\xad\xa0\xd5\x94\x10\xfe\x5d\xd9\x44\x0d\xeb\xda\xce\xc8\xf2\xf1\x27\x68\x25\x5f\x53\x2b\x7f\x4c\xce\xc2\xf3\xf3\xea\x17\x9b\x13\x03\x78\xed\xbc\x6e\x74\xab\x7f\x3e\x7f\xa6\xb2\x46\xd2\x06\x1a\x35\x2a\x83\x89\x37\x9c\x78\xc1\xa4\x8a\x56\xd0\x78\x62\xf7\xbd\xb9\x45\x42\x21\x17\xbe\x6e\x35\xa9\xaa\xcd\x02\xad\xa9\x43\x33\x46\xde\x80\xd6\xde\x70\x42\x6b\xe9\xd0\x8c\xa9\x32\xa0\x75\xdb\x4e\x68\x6d\x1d\x9a\x31\xb7\x12\xb4\x3f\xd7\xd6\x18\xc4\x8d\xba\x13\x62\x47\x87\x68\x10\x04\xb2\x87\x49\x27\x93\x98\x59\xa7\x8b\x7c\x41\x49\x9c\x4d\x2a\x99\x97\x92\x69\xb5\x39\x6d\x00\x0d\x64\x2b\x78\x62\x9f\x72\x58\x11\xc6\x92\x22\x7f\x40\xb7\x81\xed\x0b\x90\x3b\xb4\x4b\xd6\x64\xc3\xea\x06\x04\xeb\x65\x60\xab\x0d\xcb\xcc\xb8\x49\x14\xa8\xfa\x09\x5a\x91\xa8\x35\xb9\x3b\xb5\x76\x2a\x83\xc4\x1b\x26\x5e\x90\xc0\x88\x27\xf7\xa3\xd6\xb6\x0e\xed\xbe\xd4\xaa\x42\xbb\x17\xb5\x36\x75\x68\xf7\xa6\xd6\x86\x0e\xf1\x81\xa9\x35\x81\x5b\xeb\x02\x72\x4d\x1c\xe4\x0a\x1c\x35\xb1\x91\x2b\x30\x62\xdb\x17\x60\xd1\x94\x5c\x13\x27\xb9\xc2\x06\x60\xab\x0d\x5b\x83\x69\xa1\xa1\xb3\xf2\x7d\x39\x1d\x03\xc8\x90\x60\xf5\xab\x49\x98\xe4\x3f\x9b\xa8\xb2\x47\x4d\x73\x87\x84\x33\x07\x96\x9e\xee\x31\x13\xde\x3d\x6a\x7e\x1b\x90\x72\xb6\x11\xd9\x63\x66\xba\x7b\xd4\x90\x16\x93\x72\xbe\xb5\x5c\x8b\x95\x03\x63\x59\xd8\x11\x06\xd6\x72\x6d\x56\x0e\x0c\x93\x07\xa4\xdc\xd0\x5a\x0e\x0c\x98\x95\x61\xd1\xc5\xda\x5d\x96\x5a\xe3\x1e\xe6\x59\x81\x9f\xf9\x42\x18\x22\x0f\x96\x8d\x7f\x71\x1a\x46\x5e\x32\x7a\x13\x66\xe9\x69\x9c\x01\xc7\xa3\x30\xa3\x6d\x3f\xf3\xa9\xd5\xd6\x8f\x68\xc3\x02\x1d\xea\xbc\xc7\xa3\xcc\x48\xda\x08\xe5\x8d\xce\x6c\x05\x81\x99\x85\x18\xb1\x7c\x8b\xd4\x98\x29\x07\x49\xa4\xc9\xce\x39\xfa\xb2\x49\x13\x0b\xe7\x36\x12\xa2\xc4\xbf\x50\xab\xa9\x53\x6b\x0e\xa9\x52\xa9\xe4\x45\x57\x10\xe1\x0f\xff\x3f\x7b\x6f\xbf\x1d\xb7\x8d\x2c\x88\xff\x1d\x3f\x05\x26\xbf\x33\x76\x2b\x6a\x4b\x04\xf8\x05\xda\x56\x7e\xeb\x38\xf6\x55\x36\x76\xec\x63\x6b\xd6\xb9\xc7\xc7\xce\x80\x24\xa8\x66\xdc\xea\xd6\xed\xa6\x2c\x69\x26\x9e\xb3\xaf\xb1\xaf\xb7\x4f\xb2\x07\x05\x90\x04\x49\x00\xa4\x64\x39\x77\x32\x63\xcd\x19\xa7\xbb\x59\xa8\x2a\xd4\x17\x8a\xf8\x28\x08\x94\xc9\x8e\x40\x15\x08\xbf\xc5\x81\x25\x81\xae\x9b\x4a\x71\xb4\xc9\xb3\xf6\xe3\xf4\xe4\x79\x80\x4c\x25\xce\x3d\x64\x63\x89\xb3\xa9\xa3\xfa\x3d\x1d\xed\x7d\x98\xf5\x1d\x3b\xb0\x39\xc6\x70\x6d\xc7\xfe\x3e\xbc\x09\x22\xb8\xdd\x45\x1e\xc8\x32\x6e\x9c\x3a\x95\x6f\x5e\xc3\xdb\x5c\xdc\x66\x09\xde\xad\xe7\xe8\x06\xc3\x39\x44\x07\x48\x4f\xdf\x3f\xed\xfd\x2d\x9c\xf4\xfa\x66\x7e\x23\x3b\x84\x57\xb1\x43\xc3\x61\x12\xe4\x7a\x07\x3b\x6c\x8e\xeb\x1d\x76\x5e\xaf\x0e\xaf\xfc\x5e\x25\x2d\xe4\xb0\xf3\x4e\x75\x68\x7d\x99\x1a\xdf\x0a\xf7\x52\xae\x84\x4b\xe5\xaa\x1b\x2c\x72\x10\x76\x17\x54\xad\x98\xf7\x14\xd4\x09\x53\xd9\x72\xbd\x72\x07\x28\xd8\x4a\x20\xa0\xda\xd9\x05\xf8\x6a\xdf\x06\x21\x1f\xff\x32\x30\x12\xd9\x6e\x68\x6b\x8a\x4d\x78\xda\xd9\x17\x05\x1f\x3f\xca\xd5\x7f\xa4\xef\x88\x2b\xf0\xec\x62\x8e\x2e\xe7\xe8\x6f\xa6\x6b\x3e\x66\xb3\x0b\x38\xd9\x79\x09\xff\xfe\xad\xbd\xad\xfd\xe3\x00\x0f\x71\xe3\x99\x5d\xec\xdc\x9e\x5d\xee\xc8\xe3\xe4\xff\x10\x5f\xfe\xb6\xb3\xb3\x73\xdf\x86\xcd\x1f\xc5\x26\x10\xfd\x43\x60\x6c\x59\xb3\xe0\x0a\xc6\x71\xdd\x06\x0c\xc0\xdb\xe5\xce\xed\xd9\x3f\x80\x39\x3b\xc6\x70\x8a\xcc\x84\xd0\x7e\x6b\x51\x59\x70\x41\x2a\x71\x31\x5f\x19\x31\x5d\x3c\x78\xb0\x02\xae\x2e\xbe\xfd\xf6\xdb\x99\x4f\xee\xae\x74\xa6\xe4\x07\xe7\x6e\x98\x7a\x33\x8c\xbc\x07\x6e\xda\x66\x18\xeb\x6d\x3f\x6a\x7f\x0b\xec\x79\xaa\x3f\x57\x6b\x19\x99\x86\x68\x2c\xf7\xf3\x58\xa0\xaf\x7b\x31\x8f\xf2\x8c\x76\x27\x4b\x3d\x81\x37\xbb\xa3\x58\xbc\x63\xd8\x85\x63\x6f\x75\x5e\x73\x6b\xda\x6e\x33\x9c\x1c\xec\x6d\xb5\xa9\x01\xa6\x6d\x55\xaa\x95\x73\xf8\xec\xe1\xa3\xdf\x41\x35\x8e\xe6\xef\xf9\x25\x34\xdd\xf2\x6c\xc3\x2b\xcb\xdd\x49\x16\x85\xc2\x95\x83\x37\xa8\x50\x79\x91\x61\xa3\x9a\xc3\x13\x96\xb5\xea\xd1\xb7\x58\x19\x34\xd4\x01\x1e\x6a\xe9\x84\x65\x06\x4d\x7d\xf5\x51\xae\x03\x5b\xb6\x46\xd5\x90\xe6\xdb\x89\x3e\xbe\x9d\xc7\xf1\x97\x2d\x4e\xff\x0a\x47\x56\x3e\xf7\xd2\x7d\xaf\xb0\x9a\x46\xd8\x5a\x32\xed\xd5\xe1\xc3\xbb\x78\xc2\x4a\xc6\xf0\xae\xea\x9b\x5c\xbf\x38\x80\xd3\xa7\xed\x12\x46\xb9\x2a\xab\x99\xa1\x00\x55\x77\x49\x83\x17\x59\xce\x52\x9a\x18\x6a\x33\x79\x17\x09\x4d\x59\x9e\x15\xbc\xb3\xc6\x61\x02\xcc\xfc\x9c\x70\x5c\x78\xdd\x67\x9f\xbe\x04\x62\xcb\xd0\xcd\xc9\xf7\x70\x06\x7d\x80\x60\xca\xdc\xb3\x79\xba\x58\x3c\x4a\xcd\x93\xc5\x90\x30\x9a\xa7\x8a\xe1\x75\xd5\x3c\x51\x2c\x1e\xf1\x66\x9a\x78\xc0\xa9\x75\x9e\xd8\x3a\x27\x6c\x79\x5b\x80\x79\x1f\x24\x4f\x98\x5a\x6a\xc1\xbc\x96\x89\x7f\xb7\x04\x46\xf7\xec\x69\xfd\x57\x4f\x28\x99\x11\xd5\xe7\x1c\x5e\xbf\x29\xd1\x5d\xe4\xbf\x45\xef\xd4\x47\xda\x7e\xc4\x81\xf6\x39\xb2\xdd\x1d\xa9\x58\x9a\xad\xe0\x70\xac\x7c\xb7\x84\xd7\x07\x1f\x9b\xcb\xd4\x98\xdf\x84\x60\x6a\x69\xc6\x04\x92\x10\x90\x30\xf9\x26\x13\xc3\x01\x59\x8e\x76\x81\x90\x6d\xa2\x11\x3d\x40\xc4\xb3\x4a\x0d\xa6\xcd\x66\xb3\x14\xdd\x46\x99\xcc\x73\xc5\xc7\x1c\x30\x7b\x17\x21\x93\xab\xb0\x23\x53\x7c\xe8\x01\x0a\xc6\x48\xa4\xe8\x1d\xca\xd0\x3b\x94\x4b\xcc\x11\xcf\x13\x9e\x32\x53\xd1\xa1\x1e\xe6\xe8\x0a\xcc\x4b\xde\xc5\xa7\x4c\xf5\xe2\x2e\xf2\x2e\x62\x8f\x07\x81\x4f\x02\x3b\xad\xfd\x6f\x1a\x72\xd4\xdb\x41\xdf\xec\x4f\xee\x8b\xc0\xef\x87\x49\xee\x73\xd2\x9f\xe5\x41\x16\x95\x0a\x7f\xc9\x4d\xd3\x7d\xe8\x00\x65\xa6\x29\x3e\x04\x24\x1f\x3c\x40\xbe\xa7\x7a\x09\xea\x37\xde\x2d\x8a\x0e\x90\x89\x0f\x36\xed\xb4\xd6\xa4\xc9\x40\x35\x89\x56\x4f\xb6\xb1\xfe\x09\x6f\xd4\x99\x08\x84\x09\xc3\x41\xe5\x13\xd4\x99\x04\x84\xc9\xc2\xcc\x0c\xe3\xeb\x13\x85\xb9\x19\x26\xd0\x27\x09\x79\x1f\xe6\xcb\x04\xdf\x3f\xeb\x04\x9f\xc8\x85\xf7\x8a\xe5\x7a\xbd\xd1\xe7\xdc\xf6\x61\xa0\x56\x7f\x9f\x44\x04\x6a\x21\xb4\x98\x47\xe6\xe9\x06\xd3\x74\x9f\x69\x86\xee\x8a\xf3\x40\xc6\xe9\xba\x3f\xe2\x6c\xd0\x97\x29\x84\xc1\x64\x80\x48\x9f\xaf\x34\x7b\x00\x0d\x5c\x13\x07\xdd\x84\xbc\x3b\x67\x20\x9e\x7d\x99\x2e\xb8\xd1\xe9\x02\xd0\xc7\x84\x99\x02\xb3\x5a\xda\x49\x02\xa5\x1a\xfb\xb1\x29\x01\x60\x9f\x16\xa0\x7f\xe8\x02\x1b\xdb\x05\x23\x61\xf4\xb9\x6b\x63\x28\x2a\xff\x3e\xd3\x07\x83\xe9\x01\xfd\x1d\x9e\x84\x51\xe7\x2d\x5e\x3b\x85\xdd\x9f\x15\x20\x24\x98\x36\x2f\x20\x00\x3b\x38\xe1\xbb\x44\xfe\xbb\xce\x0d\x64\xd8\x0b\x13\x9e\x53\xf1\xca\xef\x47\x71\x96\x87\x5e\x0c\x9f\xbd\xd8\xcb\x73\x0c\x9f\x8b\xd8\xe3\x61\xe2\x9b\xe7\x0c\x8a\x22\xf3\xbc\xd4\x87\xc9\x85\x88\x86\x14\x87\x58\x7e\x0e\x8a\x84\x16\x0c\x10\xa4\xbc\x60\x41\xc1\x82\x2b\x4c\x17\x4c\xca\x3c\xb5\xb0\xaf\x44\xa7\xb5\x74\x9c\xa2\x85\x88\xda\xa4\x33\x77\x0f\x86\xc9\x8b\x65\x61\xe9\xcb\x10\x3d\x32\xe2\x12\x12\x5c\x75\x90\x16\x4d\x46\x86\xe9\x8e\x77\x0c\x06\x6a\x42\xcc\x87\xd8\xbf\x0c\xd5\x9f\x30\x54\x0b\xad\x4c\x1b\xac\x8d\xca\xe9\x0c\xd7\x52\x41\xce\x01\x9b\x90\xfe\x51\x67\xed\x5c\xb3\x1a\x8e\xee\xc5\x89\x18\xc0\x93\x2f\xf3\xfa\xff\x3d\x03\xf3\x1f\xef\x58\xde\x0f\xf2\x12\x87\xf2\x6f\xcd\xa9\x5c\xb4\x59\x9f\xad\x72\x94\x75\xcf\xeb\x69\x3d\x38\xec\x5f\x9d\xf2\x63\x77\x19\xa0\x9e\xa8\xe5\x2d\x0e\xf9\xc4\x94\xc1\x20\x7d\x49\xb9\xdc\xbe\xd8\x94\x27\x7c\xb6\x32\x0e\x63\xdb\xff\xda\x54\x3f\xd5\xef\xf9\xe2\xcb\x6c\xd5\x7f\xcf\x6c\x26\x82\xa5\x3a\xd1\x01\x22\xf7\xeb\xcf\x0f\x0e\x24\x86\xfa\x07\xc7\xdc\xf0\x9f\x66\x2b\xf4\x67\x05\xb6\x63\x9d\x2f\x54\x3e\x5a\xb0\xe5\x96\x8f\xef\x0a\xec\xcf\x8f\xd5\xef\xe3\x9b\xb3\xee\x1b\xae\x41\x2c\xc7\xbc\x7a\xb2\x61\xf0\x99\x2d\xbf\x2b\xab\xad\x41\x40\xcd\x12\xfe\x0a\xdd\x45\xb3\x15\x54\xf6\xdc\x41\xdf\x74\x26\x3f\xfa\x33\x59\x1a\xad\x7a\x96\x5a\xaf\xcc\x0e\xbf\x81\x42\x7a\xf5\x7b\xce\x17\xe5\x92\xa3\x99\x7a\xf6\x00\xa9\x2d\x99\x7d\x29\xb6\xda\xb4\x0a\xba\x41\x41\xad\x52\x3e\x7c\x23\x81\xa0\xec\xe8\x40\x10\x60\x0b\xa7\xeb\xf3\xd9\x6a\x8e\x30\xda\x47\x64\x67\x42\xc5\x76\x04\x37\xa1\x5c\x05\xad\xbf\x63\x2c\x9e\x2d\x51\xec\xee\x8e\x4c\x85\xae\x3a\x10\x75\x86\x34\x6b\x71\x5e\x7f\x8d\x4d\x24\xde\xd3\xb2\xe9\x61\x86\xfe\xd9\x57\xda\x0e\xf7\xb6\xcb\x32\xe3\x33\x6f\xe7\xcb\xaa\xd7\xe4\x55\xaf\xc1\xa3\x02\x1e\x85\xa6\x47\xc7\xf0\x68\xb0\x60\x04\x39\x0b\x3c\x8a\x3f\x79\x19\x2d\x72\xd4\xba\xff\xbd\x97\xd1\x8e\xd9\xc9\x09\xf3\x2e\x9a\xc5\x34\x3c\x10\xca\x10\x1a\x36\x1a\xcf\xea\x96\x0f\x1e\x20\x22\x17\xbd\xea\x5f\xbe\xfd\xf6\x5b\x14\xef\xec\x20\xf4\xce\x8c\xa9\xfb\xd7\xc1\x84\x83\x01\x26\x4c\x77\x76\xa6\x61\xea\xb6\xf3\x8d\xe1\xa5\xd3\x13\xdc\xf6\xdb\xb8\x49\xbe\x0b\xac\x75\x1b\x4b\x66\xb5\x6e\xe3\x8b\xba\xde\xf4\x44\x66\xbb\x98\xfc\x21\xa6\xe4\x8a\xdd\xae\xdb\x99\xef\x24\x40\xad\xe1\x28\x25\xee\xaa\x9e\x43\x91\x5f\xd5\xc3\x5d\xe7\x82\xa9\x6d\xf5\x33\x83\x53\x8d\x33\x8e\x6e\xa3\x02\x36\xbb\xfd\x43\x7c\x3c\xb6\x5d\xe1\x72\xc2\xa0\xc2\x1c\x43\xb7\x51\x0a\xe0\x4c\xae\x0e\xbe\x43\x6a\x9d\xd0\xc4\x3f\x24\x2b\xe5\xb1\x60\xbc\x59\x6a\x55\x8b\x6d\x6a\xad\x55\x6e\xfd\x93\x4f\x70\xa2\x3d\xc1\x7e\xe7\x51\xa7\x91\x79\x6c\x6b\x88\xc1\x3d\x35\x33\x0e\x36\x2e\x2b\x27\x73\x68\x17\x29\x8c\xf2\x09\xd6\x9e\x60\xac\x3f\x8a\xe5\xce\x56\xf9\x88\x84\xe6\x11\x0f\x16\x90\x05\xa5\x05\xda\xad\xc9\xee\x0a\xa1\xee\xca\x8b\xde\xac\x8b\xc7\xd0\x90\xa0\x83\x5a\x30\xbb\x42\xb4\x26\x0a\x22\x70\x1d\x1b\x10\x88\x58\xd7\xaf\xd3\x2e\xfe\x44\x78\x34\xa5\x5f\x50\x3b\x13\x6e\x4b\xc0\xa6\x65\x3e\x34\xb2\x44\xda\xaf\xb6\x8e\x46\x96\x43\x67\x95\x10\x44\x45\x4c\xb4\xfe\x5d\x96\x46\x25\x4c\xa8\x60\xa0\x64\x78\x61\x86\x89\x14\x0c\x94\x04\x3f\x36\xc3\xc4\x0a\x06\x7c\x7e\xf1\x65\x19\xf6\xcb\x32\xec\x97\x65\xd8\x61\xb6\xf9\x65\x19\xf6\x9f\x72\x8e\x37\x8c\xae\x3c\xc7\x1b\x46\xa3\x73\xbc\xfa\x3b\xdb\x70\x8e\x37\x8c\xbe\xcc\xf1\xde\xf8\x1c\x6f\x18\x4d\x9d\xe3\x35\x29\xa7\x3b\xc7\x0b\x0a\x72\x6f\xda\x6e\xd6\xce\xcc\x4b\xb3\xd4\xfb\x43\x2f\xcd\x5e\x44\xc1\xef\x72\x71\x41\x43\xe7\xcb\x2c\x70\x77\x16\xf8\x22\x82\x35\xd5\xbd\x8b\x28\xd0\x7e\xff\x39\x0a\x54\x95\x6e\x80\xd8\xd3\xea\x44\x5f\xa9\xa6\x9b\xd6\xbf\x97\x87\xcf\x7f\x79\xfe\xe4\xc9\xab\xc7\x47\xaf\xfa\xb3\xc5\x2f\x7e\xf8\xe5\x87\x9f\xbe\x7f\xfc\xf3\xe3\xe1\xad\xdc\x2f\x9f\xff\xe5\xa7\xef\x7f\x79\xf4\xfc\xa7\x57\x47\x0f\x7f\x6a\x5a\x6a\xe4\xe4\xb4\xf2\xa3\x69\xd3\xca\x5a\x8b\xcd\x62\x5d\x17\x6d\xe9\xcd\x49\xd7\xa4\xc5\xdb\x35\x9e\xa3\x4b\x5b\xa9\xf2\x4a\x4e\x89\x54\xe8\x01\x22\xc1\x7d\x54\x19\xa6\x44\xb4\x3e\xbf\xb9\x40\xbb\x28\x44\xdf\xa0\x4b\x79\x7a\xb0\xaa\x0f\x69\xc2\x27\xb2\x03\x33\x95\xe8\xcf\x28\x1a\xe4\x22\x90\x06\xf2\xf3\x9f\xd1\x01\xba\x44\x7f\x46\xa1\x29\x4b\xe4\xe7\xff\x29\xb0\x12\xf4\x0d\x12\x74\x7c\x41\x67\xc7\x00\x7c\x21\xa7\xe5\x7e\xee\xfd\x7c\x29\x7f\xfe\x4f\xcb\x54\xb0\x26\xb6\xd3\x12\x95\x70\x9d\x80\x41\x68\x8d\x64\x2e\xa4\x64\x2e\xe4\x01\xcd\x0b\x83\x60\x1a\x50\x29\x5d\x74\x29\x41\x2f\x2d\xd3\x4a\xad\x81\x74\xc5\x78\x09\x17\xfc\x0c\x7b\x2d\xe4\xda\xef\xfa\xc7\xd1\xbe\xf5\x56\x39\xba\xd6\xf0\xf4\xc9\xab\x97\x82\xd7\x0b\x0f\x9b\x8c\x41\xbf\x77\xc2\x32\x3f\x26\xc0\x80\x44\x6d\xac\xcf\xb6\xe7\x3d\xdb\x32\x82\x3d\xad\xc1\x2c\x22\x54\x37\x4f\xfc\x8a\x1e\xa0\xf8\x3e\xfa\xd5\x31\x33\x07\x7d\x80\xa3\xa9\xe6\xaa\x28\x35\xf9\xb4\xac\x5e\xac\xb7\x50\xc7\x55\x58\x15\x5c\x96\xfb\xeb\x0e\xba\x8b\x4c\xbb\xa9\x6b\xe4\x7a\xa3\x07\x48\xd5\x8b\x30\x01\x8b\xbf\x41\x07\xdf\x1d\x20\x20\xa3\x61\xb1\xd0\xea\xee\xa8\xd6\xa9\x7e\x7b\x00\x64\xed\x9b\xab\x07\x94\x9f\x69\x94\x3b\xa8\xee\x1a\xde\x7b\x1a\x06\xa6\x4d\x2d\x69\x86\xb5\xe2\x17\x15\x18\xd0\x88\x5a\xa8\x7d\x27\xfa\xfe\x3e\x7a\xb1\x29\x4f\xca\xaa\xfc\xc0\xd1\xe9\x7a\x79\xb9\x5a\x9f\x94\x6c\x89\xd6\x1f\xf8\x06\xfd\xc7\x93\x19\xd9\xb9\x87\x2e\xde\x51\xb4\x8b\x2e\xde\x45\xf0\x6f\x08\xff\x06\x22\xcc\x98\x51\x2a\x8b\x96\xe4\xe5\xf9\x81\x77\xc8\xbb\x88\x1d\x5b\xe6\x2d\xcc\x29\x0c\x07\x46\xfb\x18\x59\xf4\xea\x05\x78\x39\xc7\xa7\x86\x9f\xba\xc0\x58\x5f\x67\xf3\x81\xfd\xec\x5c\x75\x35\x65\x0b\xfe\x53\xf1\x93\xd3\xf5\x86\x6d\x2e\x3b\x37\xd1\x09\x17\x38\xd2\x07\x22\xeb\x2a\xa5\xf1\xd6\x19\xb3\xf7\x1f\x19\x7b\x36\xc6\x77\x6f\x6d\xc7\x9f\xb6\xb2\xe3\x77\xd6\x75\x7c\xd7\xaa\xce\xcd\x5f\x25\xb0\x3e\xab\x4e\xcf\xaa\xa7\xf0\x6a\xdd\x81\x45\x90\xa4\xe7\x7c\x5b\x6e\x78\xae\x5d\x34\x90\x96\xd5\xb6\x2e\x08\x2d\x1b\x77\xde\x16\xea\xc6\xcf\x57\xcb\x5a\x4d\x5a\x0d\x6e\xb6\xe1\xf7\x10\x21\xc1\x1c\x91\x30\x9a\x23\x9f\x06\x73\x14\x62\xd2\x6f\xac\xee\x2c\xb8\x27\x9e\xe9\x8f\xfa\x97\x16\xd4\x2f\xcd\xd6\x7b\x0b\xf4\xde\xf5\xb0\x5d\xe3\xfe\x02\x98\xa9\x85\x9b\x10\xeb\x77\xef\xfa\xdb\x9b\xb7\x96\x68\x3f\xc1\xd4\xc4\x1f\xe0\x91\x26\xb7\xe2\xe7\x8d\xd9\xc1\x22\xdc\x58\x29\x01\xe0\xa4\xb9\xad\x17\x46\x80\xc8\xf3\xd0\x5d\x24\x06\xda\xe6\xa6\x04\x5d\x12\x22\x7b\xf1\xc9\xe7\x5a\xd1\x33\x4c\xcc\x19\x84\x66\x9c\x3c\xab\x3b\xf1\x94\xad\x60\xee\xa7\xd7\xb5\x7d\x44\x4c\x73\x68\xe9\x76\xbd\x49\xc7\xe5\xdf\x43\xff\x29\x95\x04\x9f\x91\x12\x75\x17\xc5\x84\xac\xad\xd3\xe6\xcf\x08\xdc\x41\xdf\x07\x17\xb1\xde\x55\xcc\xc2\x7a\x05\xb5\x20\xef\xac\x27\x48\x3a\x85\x04\xc9\x75\x2a\x08\x92\x4e\xe9\x40\x72\xfd\x9a\x81\x8a\x61\x3c\xc6\x31\xee\xb2\x8c\xaf\xc5\x33\xee\x32\x8d\xaf\xc2\xb5\x51\x0f\xd2\xb8\x9a\xa9\x91\x72\x55\xad\xa5\x35\x9b\x35\xbd\x64\x30\x99\x57\xbb\xb3\x41\x14\x02\x62\x0f\xee\x9b\x7d\x77\x00\x72\xb1\xc1\x2c\xd7\xe7\x48\xc1\x8c\xaf\x46\xbc\x14\x03\xec\xd6\xe2\x03\x32\x51\x06\x3f\x90\x1f\x65\xd2\x0b\x9f\xed\x2e\x70\xb4\xe0\x15\x1b\x3e\xb9\xc2\x5b\x83\x86\xec\x59\x29\x5e\x41\x96\x67\x27\x2b\xe8\x9c\xc1\xad\x6a\x09\xd6\x69\xf6\x1c\xb5\x99\xb4\x11\xf8\x8a\xef\x24\x3a\x8d\x8e\x96\xda\x37\x14\x0b\x21\xf1\x57\xa7\x9e\x8d\xf6\x5c\xb0\x4f\x35\xd8\xe5\xfa\xdc\x9a\x97\x5a\xa5\x75\x64\xcc\x73\x4c\x3d\x39\x12\x5a\x38\x7a\x73\x61\xe3\xfd\xe8\x42\xda\xda\x01\xf4\xc0\x0e\x04\xc6\x76\x00\xac\x4f\xdb\x7d\x73\x3d\x33\x70\x84\xd5\xb6\x47\x01\x74\x69\x26\xf4\x12\xc0\xeb\xa1\x6b\xb1\xfc\xe8\x02\xb7\xe0\x78\x0a\xb8\xb4\xaf\xa3\x0b\xec\xd2\xa3\x82\x7d\xda\xc0\x82\x1e\x9d\xe6\xbd\x3d\xdb\x80\x47\xc9\xeb\x44\x84\xa9\x8f\x5b\xf9\xd1\x45\xa0\x62\x01\x9a\xcd\x14\x6f\xcd\xd1\x60\x45\x5f\x9d\x0f\xb6\xbd\xde\x00\xb6\xa7\x0d\x36\x19\x35\x24\xb6\xa7\x3d\x6c\xcf\xc6\xb1\xfd\xae\x4e\xd5\x09\x85\x0e\xfb\x44\xfd\x90\x68\x31\x53\x74\xa5\xb7\xbd\x97\x8b\x35\x7a\x51\x3a\x2c\x5b\x90\xac\xef\x7c\xc4\xf7\xb5\xaf\x32\x95\x6b\xbe\x7f\xb2\xc9\x77\x24\xd7\xa0\x75\x99\xb1\x00\x92\x16\x34\x16\x90\x6a\xe8\xa7\x2d\xb4\x3d\x24\xc1\x60\xb1\x58\x3f\x97\x59\xca\x41\x67\x3e\x4c\xe7\xcb\xda\xd9\x97\x6b\x48\xf4\x1c\x21\x5e\xbc\x40\xb7\x24\x46\x27\x1e\x34\x5f\x99\xd5\x9d\x7e\xf0\xa0\x65\x12\x4c\xbb\xee\x1f\x5c\xa5\xe9\x13\x74\x57\x7b\x6e\x33\x74\xd4\x75\x9d\x06\x87\x11\xf9\xb3\x2b\x22\xef\xce\x79\xb4\xdd\x9d\x34\xe3\xd1\xef\xb2\xe2\x4a\x43\x03\xb3\x1d\x43\xe6\xa2\xe0\xda\x3d\x7f\x36\x42\xe3\xe9\x15\x69\xb8\xc6\xb6\x0d\x5b\x6d\x4f\xd7\x5b\xa7\x95\x40\xf8\x7d\x51\x3e\x95\x8e\x71\xf4\x46\x9b\x50\x6c\xed\xd0\x3a\xe6\xc9\x86\x53\x06\x3e\x05\x39\x36\xfa\x59\xe3\xc7\x69\x89\x58\x05\x43\x20\xc4\x4b\x73\x4e\x78\xe4\x41\x1f\x8c\x45\x5b\x9b\x97\x23\xaf\x09\x00\x46\xb8\x23\xaf\xee\x8e\x84\xb6\xb9\xfc\x91\x57\x77\x46\xc1\x59\xc6\xad\xfd\x7d\xf4\x68\xe1\x0a\x7e\xd3\x87\xf5\x6b\x0e\x19\xe3\xa1\x11\x69\xe1\xab\x8e\xc3\xcd\xb8\x32\x62\xdc\x57\x0b\xa9\x75\xab\xa3\xc6\xe0\xa6\x37\xb9\xc0\x4d\xa3\x99\x96\x84\xec\x4c\x19\x00\x25\x02\xd2\x43\x40\x06\x08\x9c\x52\x14\xb9\xc7\x66\x7d\xee\x10\xe2\x52\xf3\x86\xa3\xd6\x35\xde\xa1\xd9\x3f\x14\xfb\xf2\x87\xdb\x35\x33\xf0\xd5\x15\x3f\x96\x9a\xd7\x1c\xb5\x2e\xa4\x63\x84\x1f\x5a\x8c\xcb\xf5\xf9\xa7\x4f\xd0\xfe\xb0\x36\xbd\x91\x0c\xf4\x6d\xf5\xb4\xce\x34\xa4\x18\xdf\x7a\x93\x99\xf0\x7c\xf4\xa5\xad\x83\xc5\xe6\x88\x9d\x7c\xa5\xdb\x42\xb8\xa4\x63\xb1\xe3\x9f\x6b\x5b\x94\x61\x92\xe6\xc6\x77\x45\x0d\xe0\x9b\x19\x1f\xd1\x6e\x38\x0d\xf4\x0d\x4c\x5e\x0d\xe7\x81\xae\xbb\x97\x0a\x5f\x67\x2b\x15\x6c\x92\xca\x78\xb9\xec\xee\x77\xc2\x3b\x68\xbf\xcb\xff\x0e\xfa\xa6\xff\x03\x10\x87\x05\x9a\x66\x37\xd7\x3f\xc9\x26\xa8\x4f\x9e\xc3\xd3\xa7\x19\x6b\xe6\x8d\x73\x90\x68\xdf\xa8\x7a\x1d\xa4\x9e\x05\x1c\xe2\xdc\x37\x6e\xa6\x7b\xf5\x5f\x67\x9c\xff\x8d\x0f\x91\x2e\xd8\x76\x51\x1b\xf7\xa4\xbb\xe8\x07\x5c\x7c\xca\x64\xe1\xf8\x9c\xd0\xf4\x94\xde\x96\xce\x5f\x7d\x0e\xb1\xa5\x67\x9f\x95\xd3\x52\x43\x35\x31\xa7\x27\x9c\x57\x9a\x9b\xd3\x50\xa9\xe9\x39\x1d\xd5\x75\xe7\x15\x5b\x51\xb8\x3b\xf1\x74\xd0\x89\xa7\xd7\xed\xc4\xd3\x41\x27\x9e\x5e\xad\x13\x66\x55\x49\xd3\x55\x4e\x56\xad\xd1\x86\x57\x9b\x92\x7f\xe0\x86\x0d\x88\x48\x1d\xee\x96\xf1\xe0\xf4\x6c\xbb\xa8\xd9\x30\x89\xc8\x00\xf9\x6c\x08\xf9\xe9\xe5\x89\x0d\xa7\x87\x1a\xd2\xf3\xa1\x0b\x5b\xcf\x13\xdd\xd0\xae\x49\x7b\xfc\x52\x5b\x28\x0d\xe1\xac\x39\xec\x34\x21\x42\x4c\x5c\xcc\xa9\x3f\xb6\xfb\x33\x9d\x62\xff\xb2\x5d\xf3\x9a\xdb\x35\xfd\xab\x6e\xd6\xf4\xc7\xb6\x6a\xfa\x8e\x8d\x9a\xfe\x97\x6d\x9a\x37\xbd\x4d\xd3\x9f\xb8\x49\xd3\xa0\x96\xce\x16\x4d\x7f\xca\x06\x4d\xdf\x7e\x0c\xbf\xd9\x78\x78\x8f\x06\x1f\xdf\xce\x29\xfe\x17\xd9\xae\xd9\x2f\xb0\x13\x62\xf2\xbb\xed\xe1\xac\xcb\xed\x08\x9a\x7f\xac\x72\x3b\xd7\xda\x6d\xa9\x1e\xb7\xbb\x3d\x6b\x98\x2b\x15\xe4\x09\x31\xe9\x6c\x0b\x09\x31\xb1\x6e\x33\xa1\x13\x0b\xf2\x08\xc0\xce\x56\x13\xaa\xaa\x5a\x84\x98\xdc\xd8\x11\x62\xbd\xfb\xd6\x9a\x3c\x83\x4d\x0e\xde\x45\x96\xa6\x69\x92\x87\xf9\x5c\x2b\xd8\xb3\x33\x37\x41\x46\x24\x61\x24\x21\x4c\x2f\xe7\xb3\x63\xa8\xdb\x63\x68\x9a\xe0\x30\xf1\x70\xc8\xf4\xea\x3f\x66\x22\x38\x24\x05\xcf\x64\xcd\xa0\xba\x36\xd0\x44\x22\x51\xec\xfb\x24\x8a\x64\x59\x21\x55\x39\xc8\x4c\x84\xf2\x34\x08\x18\x8d\xf5\xba\x42\x13\x89\xe4\xa9\x97\x11\xee\xe5\x7a\x19\x22\x33\x91\x20\x4e\xc3\x80\xe2\x5c\x2f\x52\xd4\x4b\x4d\x6f\xba\x4a\x91\xb0\xa7\x6b\x56\x29\xc2\xd1\x97\x32\x45\x37\x94\x13\xd1\x2b\x97\x29\x12\x4d\xc6\xf2\x22\x3d\x66\x0c\x33\x23\xfa\xa5\x4c\xd1\xcd\xe7\x46\x74\x6a\x99\x22\xa3\x72\xba\xf9\x11\x1d\x2d\x53\xe4\x53\x77\x99\x22\x31\x8c\xdf\xa3\xc4\x94\x2d\x91\x7f\x91\x6c\xe9\x5f\xfa\x70\xcb\xcd\x1e\x6c\xf9\x4c\x47\x56\xae\x9f\x44\xc9\x47\x4d\x77\x15\xa2\x5f\xea\x1d\xbc\x86\xbb\x6e\xba\x9b\x7c\xf7\xd8\xe9\xe9\xf2\x72\xa6\x7e\x9c\x23\xb6\x39\x3e\x3b\xe1\xab\x6a\xdb\xbf\x93\x47\x3f\x3e\xd3\xf2\x03\xa5\x94\x5a\x12\x3d\xf2\xde\x45\x40\x28\x23\x45\x02\x79\x45\x1e\x13\xca\x38\x21\x3b\xf3\x21\x5c\x8c\xfd\x38\x08\x12\x28\x33\x48\x7c\x5e\x44\x61\x96\xeb\xa9\xc1\xa0\x41\x1a\x66\x5e\x91\x66\x05\x5c\x80\x90\x05\xb9\x9f\x92\xc2\x84\x98\x27\x69\x98\xa7\x2c\x84\xdb\xb3\x31\x4d\xf2\x34\xcd\x9c\x88\xfd\x24\x8c\x32\x12\xa6\x90\xce\xf8\x01\x4d\x43\x9f\x9a\x10\x87\x49\x81\x31\x2e\x80\xe3\x34\xf2\xc2\xdc\xc3\x89\x13\x71\x42\xfc\x82\x12\x06\x57\x6e\xb3\x02\x27\x41\x91\xa4\x26\xc4\x2c\xc5\x59\xc8\x73\xe0\x38\x67\x51\x4e\x31\xa6\x4e\xc4\x39\xf5\x62\xc6\xa4\x8c\x99\xef\xf9\x1e\x09\x8c\x32\xc6\x84\xfa\x61\x2a\xef\x8c\x08\xc2\xd8\x8b\x8a\x94\x3b\x11\x93\xc0\xc7\x34\x4c\xe1\xee\x88\x80\xf3\x20\x25\x34\x33\x8a\x22\xf4\xb2\x38\xcf\xe0\x02\xf1\x3c\x2c\x8a\x34\xe0\xc4\x89\x38\x26\x29\x0f\xf3\x18\x44\x51\x90\x38\xa5\x49\x64\x54\x1e\xf5\x72\x9e\x62\x79\x79\x85\x9f\xe2\x28\x89\x52\xec\x96\x71\x9a\x67\x5e\x24\x2b\x54\x92\x30\x8b\x31\xf1\x43\x13\xe2\x0c\x27\x69\x81\x25\x03\x59\x11\x25\x24\x4a\x02\x27\x62\x1e\x24\x69\x94\x64\x20\xbb\x84\x17\x38\x60\xb9\x51\xc6\xbc\x48\x79\x10\x53\xb8\x46\xdc\xa7\x41\x41\x42\xee\x3b\x11\x7b\x45\x86\x93\x3c\x83\x06\x34\xa5\x59\x1e\xa6\x46\x8e\x49\xe0\x65\x0c\x67\x19\x5c\xd2\x1e\xb3\x2c\xc9\xa2\xd0\xad\xbc\x9c\x27\x24\x8b\xc0\x41\xc2\x84\xa4\x1e\x89\x8d\x88\x03\x16\x07\x34\x60\xf0\x8e\x10\x71\x16\xf1\x80\xba\x39\x0e\xb3\xd4\x63\x49\x0e\x9c\xa4\x79\x80\x8b\x34\x0f\x8c\x2e\x1d\x15\x09\xa5\x39\x20\xa6\x3e\xc6\xa1\x9f\xba\x39\x4e\xa8\xcf\x43\x1c\x12\x70\x69\x1e\x45\x79\xc1\xcc\x0e\x42\x7d\x9c\x45\x11\x64\xf8\x24\x4f\x03\x9f\x60\xcf\x1d\x2b\x3c\xcf\x27\x71\x46\xe5\x9d\xef\x45\x4a\xb0\x6f\x34\xb7\xb4\x08\x93\xb8\xc8\x54\x7d\x53\x5e\x78\x9c\xbb\xad\x22\x8b\xb8\xe7\xa5\x05\x18\xbe\x9f\x33\x4a\x8b\xcc\x68\x15\x79\xc8\xe2\x04\x07\x80\x38\xf1\x3d\xc6\x62\xe2\x16\x85\x17\x65\x2c\xf2\x43\x79\xbd\x8b\xe7\xf9\x94\x98\x1d\x04\x07\x24\x21\x89\x7c\xf7\xf2\x98\xc7\x23\x1e\xbb\x45\x41\xe2\x34\xf6\x18\x85\xe0\x12\x44\x39\x21\x45\x61\x74\x69\xc2\xb1\x10\x13\x88\x2c\xcc\x48\x94\x25\x24\x72\x22\x0e\x72\x92\x45\x79\x01\x56\x11\xb2\x2c\x20\x8c\xe7\xc6\x58\xe1\xfb\xd4\xcb\x31\x88\x2c\xc9\x93\x30\xf5\xf3\xc2\x89\x38\x0a\x3d\x16\xfb\x61\x20\x1d\x84\x15\x91\x9f\x73\xb3\xb9\x45\xcc\x63\x29\xc4\x6d\x3f\x8b\xe3\x94\x30\x77\xd8\xa4\x38\x23\x59\x42\x64\x74\x8b\x79\xce\x38\x8f\x4c\x88\x13\x12\x13\x92\x49\x91\xe1\x80\x12\x3f\xf4\x53\x27\x62\x46\xd2\x82\x53\x26\xe3\x6c\x56\x60\xcf\x8f\x8c\x0e\xc2\x28\x66\x51\x14\x00\xc7\x69\x16\x10\xdf\xf3\xdc\xd1\x2d\x23\x41\x4a\xd3\xd8\x83\x38\xeb\x15\x34\x89\x13\x6c\x8c\x6e\x71\x94\x85\x98\x81\x8c\xbd\x28\x0c\x52\xee\xbb\xad\x22\xc7\x09\xe1\x14\x27\x80\x38\xe2\x45\x48\xb0\x71\xcc\xcb\xa3\x24\xf1\x22\x02\xba\x08\xc3\x28\x64\xc9\x88\xe7\x15\x81\xc7\xfd\x50\xca\x2e\x8c\x63\x4c\x3c\xc2\x8c\x76\xec\x45\x8c\x79\xb2\x67\x3e\x49\xd3\x1c\xa7\x6e\xe5\xe1\x84\x05\x19\xc6\x10\x36\x53\x9a\x93\xdc\xcb\x8c\x1c\x63\xee\xc7\x51\xe6\x49\x3b\xc6\x01\x66\x69\xe8\x8e\x6e\x24\x0e\x68\x1c\x07\x60\xc7\x79\x41\x39\x4f\x93\xc4\x84\xd8\x0f\x52\x2f\xcd\x52\xe8\x19\xc7\x49\x1a\xd0\x11\x73\xf3\x13\x9c\x79\x59\x0a\x4a\xc9\xc2\x2c\x09\x59\xe4\x1b\xe3\x31\xcf\x29\x63\x01\x84\x4d\xee\x07\x98\xb2\xcc\x6d\x6e\x61\x9a\x64\x19\x0b\x0a\x39\x32\x44\x3e\xf7\x63\x23\xe2\x88\x12\x1e\x15\x32\x58\xe5\x51\x4a\x52\xca\xdc\xa2\x88\x03\x5a\x50\xc2\xc1\x41\xc2\x9c\x17\x29\x31\xc7\x8a\x98\xb2\x30\xf2\xe5\x48\x13\xf8\x38\x26\x45\xe4\xb6\x0a\x1a\x64\x34\xa6\x58\x66\x42\xb8\xf0\x58\x1a\x1b\xc3\x26\xcd\xb2\xd8\x23\x52\x79\x98\x45\x81\x9f\x70\x77\xee\x96\x78\x29\x2f\x8a\x82\xc9\x2c\x32\xf2\x31\x27\x46\xab\x60\x41\xe8\x45\x19\x07\xcf\xcb\x39\x25\x69\xce\xdd\xb9\x5b\xca\x8b\x84\xf9\x85\x1c\x19\x48\x16\xc5\x09\x36\xe7\x15\x51\x8c\x63\x5a\xc8\x21\xcc\x8f\x49\xe8\x13\xb7\xf2\x32\x46\x62\x9f\x67\x20\x63\xce\x48\x14\xe1\xc4\x28\xe3\x1c\xd3\x28\xa5\x72\x68\x22\xc2\x90\x48\x77\x12\x70\x98\x88\xb0\x9c\xc5\x79\x0e\x0e\x92\xe5\xdc\xe3\x29\x36\x86\xcd\x22\x8c\xf3\xa0\x88\x0b\x35\xe8\xf2\x1c\xc7\x6e\x3b\xf6\xa2\xc2\x8b\x62\x99\x2f\xc4\x04\xc7\x51\x91\x1a\x5d\xda\x63\x91\x1f\xe7\x19\x38\x08\x23\x19\x4d\x28\x73\x8f\x20\x18\xfb\x45\x42\xbd\x40\x4d\xdc\x25\x5e\xce\x8c\x1c\xe3\x34\xc6\x5e\xea\xcb\x78\xec\xe3\x2c\x88\xb1\x5b\xc6\x84\xe6\x69\x1c\x17\xa1\xb4\x0a\x2f\x88\x73\x6a\x8c\xc7\x3e\xc9\x18\x4b\x63\xb0\x8a\xc0\xcb\x62\x12\x24\x6e\x07\xf1\xb3\x84\xa7\xdc\x03\x51\xe0\x30\x4b\x52\x9e\x1a\x95\x17\xf8\x38\x8f\xe2\x0c\x7a\x96\x64\xd8\xf3\xf2\xc0\x6d\xc7\x41\x96\x85\x79\x20\x13\xef\x2c\xf5\x79\x40\x52\xe3\xd0\x24\xd2\x15\x92\x24\x10\xac\x8a\x2c\x0a\x63\x2e\xc2\xab\x2b\x56\x14\x59\x1a\x15\x4c\x0e\x92\x2c\x8f\x0a\xc6\x8d\x1c\x47\x59\x10\xe0\x84\x02\xe2\x80\x05\x71\x48\x71\xac\x26\x51\xdf\x3a\x8e\xad\xb6\xef\x85\xaf\xaf\x7b\x42\xd5\x76\x0d\xda\xeb\xce\x09\xd5\x5f\xae\x77\x42\x35\xc4\x64\xda\xd2\x81\x61\x39\xe2\xe6\xab\x8f\x5e\x77\xe9\x20\x62\x5e\xc2\xeb\x09\x77\x3f\xcd\xb2\xc4\xb3\x2c\x1d\xa4\x69\x14\x33\x2e\x87\x5f\x1a\x64\x8c\xc5\xdd\xd4\xc5\x41\xc4\xcf\x22\x5e\xf8\x31\x44\xb2\x82\x27\x41\x41\x45\x24\x33\x41\xb2\x30\x28\x8a\xd0\x07\x2f\x08\x0b\x9c\xfb\x51\x31\x75\x56\x3f\xc4\x1e\x0f\x89\x0c\x3e\x2c\xe7\x11\x25\xb9\x65\xe9\x20\x49\xbd\x30\xa2\xd2\x20\x49\xea\xf3\x28\xc3\xc5\x44\x22\xb8\xa0\x7e\x9e\x48\x9b\x2f\xd2\x00\xa7\x79\x64\xe9\x49\x98\x72\x2f\xcb\x65\x1a\x84\xfd\x98\x13\x1c\x27\x57\x59\x3a\xb8\xe9\x73\xa4\x53\x4a\xc3\x02\x9c\x67\xaf\xfc\x7a\x88\xed\xa5\x5f\x0f\x89\xbd\xf6\xeb\xa1\x6f\x2f\xfe\x7a\x18\xd8\xab\xbf\x1e\x86\xf6\xf2\xaf\x87\x91\xbd\xfe\xeb\x61\x6c\x29\x00\x2b\x3b\x08\xe5\x61\x8d\xfb\xc0\xe5\xf3\xa5\x7c\x3e\x3c\xec\x21\x65\x00\xcd\x8d\x47\xa0\xe4\xf3\xa5\x7c\x6e\x69\x4e\xa0\x39\xb1\x36\x27\x4b\xf9\xdc\xd2\xdc\x87\xe6\xbe\xb5\xb9\xbf\x94\xcf\x2d\xcd\x03\x68\x1e\x58\x9b\x07\x4b\xf9\xdc\xd2\x3c\x84\xe6\xa1\xb5\x79\xb8\x94\xcf\x2d\xcd\x23\x68\x1e\x59\x9b\x47\x4b\xf9\xdc\xd2\x3c\x86\xe6\xb1\xb5\x79\xbc\x94\xcf\x0d\xdb\xfa\x26\x16\x3d\x96\x96\x61\x42\xce\xa4\x51\xf4\x2b\xee\xc1\x96\x5b\x69\x10\xa6\x56\xa9\xb4\x05\x53\xab\x4c\xda\x81\xa9\x55\x26\x4d\xc0\xd4\x2a\x97\xea\x37\xb5\xca\xa5\xe6\x4d\xad\xb8\xd4\xba\xa9\x15\x97\x0a\x37\xb5\x2a\xa4\xb2\x4d\xad\x0a\xa9\x67\x53\xab\x63\xa9\x63\x53\xab\x63\xa9\x5e\x53\xab\x85\x54\xad\xa9\xd5\x42\x6a\x75\x69\xaa\x3b\xe8\x3a\xba\x3b\xf1\x3a\x54\x6b\x3d\xed\x9a\xfe\xeb\x52\xd6\x1e\xb6\x1d\x37\x7f\x0c\x23\x78\xbd\x7c\x36\x04\x99\x50\x28\x5a\x92\x11\x22\x78\x5d\xd6\xa7\x0d\xf4\xaa\xd1\xe8\x1b\x44\xde\x02\xa4\xb9\x96\x6b\x8b\x63\x29\x71\xa8\xf3\x05\x7d\x1c\x70\x6a\xfe\x5a\x15\xa8\xf7\xf7\xd1\x7f\x40\x35\x62\x3b\xf1\xba\xa4\xf3\x95\x2a\x54\x5f\x2c\x9a\x3a\xc7\x17\x63\x67\xf1\x14\xd8\x52\x6b\xe1\x3e\x8f\x27\xa1\x16\x9d\x2a\xd8\x0b\x59\xfc\x57\x2f\x5e\xbd\x84\x12\xc5\x75\x39\xe0\x0e\x1c\x1d\xc0\xc1\xa6\xd7\x77\xa8\x0b\x16\xbb\x4e\x98\x4a\xc8\x65\x87\x8b\xe5\x90\x8b\x85\x89\x8b\xe5\x90\x8b\x85\xce\x45\x17\x2e\x1e\xc2\x59\x2a\x19\xeb\x2a\xb5\xd4\xcc\xf9\xa0\xd5\xde\xbe\x4a\xf1\xed\x56\xa3\x78\x9a\x46\x71\xab\x51\x3c\x49\xa3\x78\xd1\x29\xf0\xbd\xa8\xab\x70\x6b\x85\xb9\x97\xaa\x56\xb7\x26\x24\xac\x24\xdc\x05\x83\x7d\xcc\x89\xa6\xd2\x1a\x5f\x34\xaa\x52\xbc\xec\xb0\xb1\x34\xb0\xb1\x30\xb1\xb1\x1c\xb0\xb1\xe8\xb0\xd1\x45\x18\x0d\xf0\x91\xc8\xa9\xd3\x2b\xd5\x0e\x77\x85\x92\xb8\x55\x7b\xec\x52\xfb\xeb\x32\x96\x91\xcb\x38\x30\xf7\x20\x97\x0a\xd2\x71\x26\x5c\x42\xe2\x48\x0b\x24\xd6\x5b\xa1\x6b\x58\xc9\x00\x36\x66\x16\x7d\xd8\x65\x0d\x3b\xca\x43\x1b\x69\x96\x42\x68\x65\xdc\x1f\xb9\xba\xe0\x6d\x28\x5b\x48\xf0\x05\xd4\x6c\x13\x78\x84\x26\xbd\x1d\xf4\xa0\xf6\xce\xe6\x97\xff\x1f\x61\x74\x0f\x0d\xb6\x4d\x0f\xf9\x10\xff\xd6\x1a\x1c\x67\x43\xfc\xbb\xdb\x78\x8b\x85\x0b\x7c\x5d\x2e\x40\x8a\x13\x79\x90\xda\x19\x72\x20\x35\x31\xa0\x6f\x46\xda\x8e\x8a\xaf\x4b\x9b\x7a\xdb\x51\xef\x75\x69\x62\xce\x5e\x13\x5f\x15\xc5\x5f\xa0\xdb\xa8\x58\xa8\xb2\xf8\xe2\x8b\xf9\x1c\x9f\x6c\x23\x7d\x9f\x2f\x45\x9b\xa5\x6a\x23\xbe\x1c\x2f\x1d\xc5\xf4\x17\x50\x4d\x5f\xa0\x4e\x25\x1d\xf8\x9c\xc9\xcf\xa9\xfa\x6c\x6f\xbe\x84\xe6\x82\x4a\x2a\x49\xc2\xe7\x4c\x7e\x4e\xd5\x67\x77\x49\xfe\x85\xac\xc9\xaf\x02\x8e\x1c\x57\xd8\x52\x96\x97\xde\x91\xc5\x0f\xd8\xa2\xae\xd8\xaf\x1e\x76\x6a\xf6\x2f\xb4\x5b\x24\x58\x3d\xea\x38\x2b\xf3\xc3\xdb\xd4\xac\x41\xa4\x68\x2e\xba\x34\x97\x1d\x9a\x8b\x2e\xcd\xa5\x4e\x73\x31\x85\x26\x96\xfd\xe4\x6a\x68\x90\xe7\x4d\xb8\x1c\x14\x68\x5d\xf6\x7f\x51\x5f\x5a\xa1\x3d\x0c\xda\x87\x82\xa6\x5f\x3f\x93\x65\xb8\xdd\x34\x65\x3f\x15\x70\x4d\x73\xd1\xa5\xb9\xec\xd0\x5c\x74\x69\x2e\x75\x9a\x8b\x96\xa6\x31\xeb\x1c\xbf\x87\xc0\xcc\xeb\x8f\x50\x7d\xe9\x47\xfb\x61\xaa\x1f\xc1\x79\x7f\x2c\x5d\xc7\xa8\x7e\x84\x60\xf0\x63\x69\x0b\xa1\x1f\xe0\xa2\x04\x01\xb3\x58\x36\x2c\x9a\x9c\x52\x02\x0a\x82\x8b\xb6\x2f\x32\x5c\x54\x58\x0f\x17\x8b\x29\xb1\xaa\x25\x2b\xfe\x15\x12\x71\xd3\xac\x80\x54\xb6\x30\x11\xcc\xae\x45\xf1\x47\x63\xe8\xe9\x53\xfc\xb1\x34\x51\xfc\xb1\xbc\x0e\x45\x73\xb0\xeb\x53\x7c\x6d\xa4\xf8\xda\x44\xd1\x6c\x6d\xfd\xcb\x2b\x2c\x24\x61\xf2\xa2\x76\x7b\x00\xb4\x72\x07\xf3\x20\x75\x54\xda\x95\xe1\x11\x58\x24\x3a\x8b\x35\xae\x69\x6c\xfe\xe5\x34\x67\x15\x47\xe7\xee\x37\x7d\xf1\x07\xef\x9b\x46\xfb\x86\xd7\xcd\x63\x13\xdb\x30\x00\x15\xa6\x36\xf0\x62\x5b\x98\xda\xc0\x3b\x34\x37\xb5\x81\x57\x68\x6e\x6a\x03\xaf\xe4\xb3\x7c\x09\xd7\x77\x2c\x6d\xf7\x77\xc0\x3b\xfd\x2c\x5f\x00\x94\x14\x1d\xd7\x25\x97\x0f\x84\x66\xbd\x09\x44\x60\xca\x4c\x3c\xc2\x94\x42\x66\xe2\x11\x66\x2f\x52\x53\x1b\x98\xbc\x48\x4d\x6d\x60\x9e\x84\x99\xda\xc0\x34\xc9\xe0\x36\x03\xf1\x07\xd3\x2e\x33\x69\xea\x15\xb1\x0a\x03\x26\x6e\x66\x52\x0e\xc2\xb2\x76\xdb\x11\x47\x4a\xa3\x1a\x26\x3b\x37\x7a\x59\x89\x36\x67\x08\x99\xc1\x21\xd8\x3f\x1b\x64\x03\x87\x4d\x31\x8a\xd9\x21\xd8\x3d\x93\xcc\x1e\x7a\x3a\xb7\x6c\xc8\x6c\x1f\x8f\x36\xcb\x28\x09\x82\x88\xd2\x21\x41\xdc\x12\x04\xf1\xa4\x8a\x60\x27\x12\xa4\xe3\x04\xb5\x79\x49\x49\x90\x40\x88\x1d\x12\x24\x2d\x41\xb2\xa8\xc7\xa5\x19\xc0\x6b\xe1\x75\x9c\xa0\x36\x93\x29\x09\xfa\x82\x60\x3e\x24\xe8\xb7\x04\x7d\x41\x2b\x57\x04\xfd\x11\x77\xe8\xe3\xd1\xe6\x3e\x25\xc1\x40\x10\xe4\x43\x82\x41\x4b\x30\x10\xb4\xb8\x22\x18\xe8\x04\xf9\x38\x41\x6d\xb6\x54\x12\x0c\x05\xc1\x62\x48\x30\x6c\x09\x86\x82\x56\xa1\x08\x86\x3a\xc1\x62\x9c\xa0\x36\xbf\x2a\x09\x46\xf0\x52\x31\x24\x18\xb5\x04\x21\x7b\x3f\x56\x04\xa3\xce\x4b\xc4\x38\x41\x6d\x46\x56\x12\x8c\x05\xc1\xc5\x90\x60\xdc\x12\x84\xd7\x26\x35\x26\x0b\x78\x57\x12\xf0\xc9\x67\x2f\xbe\x5c\x8a\x73\x73\x97\xe2\x60\x91\xdc\xab\x9b\xcd\x04\x32\xa8\xc3\xe2\x7b\x37\x7d\x2d\x8e\x99\x0c\xfe\xa7\xbc\x18\xe7\xd1\x7a\xf5\x81\x6f\x64\x95\x5f\x54\xad\x91\x4f\xee\xa6\x65\x25\x12\x94\x1c\x31\xd8\x9f\x9d\xf2\x62\xbd\xe1\x6a\x3b\xf5\x40\x6b\xda\x59\x13\x6d\xed\xae\x5a\xff\xec\x93\x9b\xb8\x88\xe7\x8f\x7a\x05\x8f\xce\x67\x53\x1f\xe4\x1e\xc2\x1e\x09\xf6\x7d\x55\xa7\xf8\xcb\xe9\x26\xeb\x51\xa5\x10\x93\xab\x9e\x6e\x12\x4d\x46\x4e\x37\x75\xb6\x35\x0c\x4e\x37\x85\x98\x7c\x39\xdd\x74\xd3\xa7\x9b\x84\x56\xa6\x9d\x6e\x32\x2a\xa7\x73\xba\x49\x2a\xc8\x79\xba\x49\x9e\xa3\x9d\x78\xfa\xdb\xff\x43\x9f\x67\xe2\xab\xec\x6e\xca\xb6\x3c\x0a\x7a\x0f\x4e\xf2\xb0\x0f\xfa\xe1\xf4\x7d\x5e\xf4\x7e\xcc\xca\xd3\x05\xdf\xfc\x2e\x47\xa2\x34\x56\xe1\xbb\xe0\x50\x3e\x90\x8c\xc1\x67\x9d\x9f\x7f\x85\xa3\x53\xaf\x27\xdd\x09\x04\x9b\x67\x1e\x41\xd7\x1b\x38\xed\xb7\xf1\xa3\x50\xfb\xfb\xe8\x05\xdf\x9c\xc0\x28\xfa\x68\xb1\x2e\x33\x8e\x70\xff\xda\x14\xd1\xfc\xc5\x23\xdc\x3d\xbb\x14\xc6\x73\x14\x24\x73\x14\xe0\x39\xf2\xfd\x39\x22\xe1\x1c\xe1\x78\x8e\x92\x39\x42\x58\xdb\x6a\x14\xd2\x39\x0a\xbd\x39\x0a\xc8\x1c\xf9\xc1\x1c\x91\x68\x8e\x30\x9d\x23\xec\xcd\x11\xd1\xe1\x92\x39\x0a\xf1\x1c\x05\xfe\x1c\xf9\xe1\x1c\x91\x78\x8e\x70\x32\x47\x58\xe0\xd7\xe0\x22\x6f\x8e\x42\x32\x47\x41\x30\x47\x7e\x34\x47\x91\x3f\x47\x61\x38\x47\x41\x3c\x47\x7e\xa2\x01\xfa\x78\x8e\x88\x3f\x47\x38\x9c\xa3\x78\x8e\x50\x44\xe6\x28\x0c\xe6\x28\x80\xab\x05\x74\x40\xc1\x09\x99\x23\x1c\xcc\x51\x24\x00\xf1\x1c\x85\xfe\x1c\x05\xe1\x1c\xf9\xb1\x06\x48\x92\x39\x22\x78\x8e\xb0\x20\x39\x47\x88\xd0\x39\x22\xde\x1c\x61\xc1\x8e\x04\x7b\xeb\x90\x2b\x31\xcb\x95\x74\xe5\x2a\xb8\x10\x72\x14\xfd\x26\xe2\xf3\x1c\xa1\x50\xe7\x56\x11\x16\xdd\x12\xdc\x02\x43\x9e\xce\xa5\xaf\x04\x27\xb8\x12\x00\xd1\x1c\xe9\xdd\xc5\x91\x94\x87\x10\x30\x70\xef\x77\x15\x21\x14\x2a\x04\x2c\xe4\xe7\xc7\x52\xb0\x61\xd8\x93\x57\xe0\x29\x6d\x85\x52\xfb\x81\x4e\x41\xa8\x46\x98\x86\x2f\x54\x1a\x49\xb5\x87\xba\x0e\x85\x0a\x84\x3d\x08\xbb\x10\x3a\x14\x82\xad\xb3\x9a\xce\x8d\x50\x67\x27\x67\x4b\x06\xd7\xa4\x88\xa4\x72\xbb\x28\x8b\xc1\x0d\x4f\xe0\x05\x3f\x1c\xfd\xf2\xea\xf0\x87\x27\xf2\x4e\x29\x21\x31\x32\x47\xd0\x79\x21\x21\x2a\x2c\x52\xa9\x09\xa4\xab\x2c\x15\x2b\x75\x12\x65\xbd\x20\x10\xaa\xd3\x7f\xf5\xdd\xf3\x9f\xf9\x16\xb1\x55\xae\x6a\xa3\x9f\x82\x4a\xe5\x7d\x1a\x06\x3e\x04\xfc\x2f\x2f\xba\xfa\xec\xa5\x94\xde\x85\x77\x0f\x5e\x46\x28\xf1\xbc\x79\xff\x59\xfd\xae\x20\x41\x0c\x00\xa4\x03\x40\x3d\x8f\x0c\x40\x7c\x0d\x64\xf8\x34\xd0\x9f\x1a\x08\x84\x5d\x02\xc4\x40\x20\xea\x32\x69\x02\x89\x7b\xfd\x30\x10\xa2\x1d\x46\x86\x28\x92\x3e\x95\x21\x0a\xa6\x83\x98\x00\xd2\xbe\xb4\x86\x20\x59\x8f\xcc\x00\x20\xef\x77\x65\x08\xc2\x35\x90\x21\x85\xa2\xcb\xe5\xb0\x39\x75\xb5\xc6\x74\x54\x1f\x84\x8e\x10\xf0\xe9\x88\x55\x05\x7d\x22\x06\xbb\xa0\x6e\xbb\x89\xe8\xa8\x61\xc6\xd4\x65\x98\x94\x8e\xea\x3b\xa1\x23\xfa\x66\x7d\x26\x0c\x26\xd1\x27\x33\xe4\x24\xa3\xa3\x1a\xcf\xe9\x88\xd5\x70\xea\xb6\xee\xa2\x4f\xc3\xa0\x79\xab\xba\x54\x94\xc0\x66\x41\x12\xed\xa9\x45\x99\x7e\x07\xc4\x48\x3d\xe8\x62\x31\xf5\x31\xd4\x41\x8c\x36\xa1\xf3\x69\x78\x1e\x77\xd9\x70\xf8\x06\x76\x98\x7f\xd2\xe7\xd4\x1a\x28\xb0\x43\xa3\x69\xb7\x33\x06\xab\xe8\x74\xc6\x1a\x27\xb0\xc3\x7e\x79\x0f\xc4\x16\x2a\xb0\x39\x14\xd0\x51\x51\x60\x3a\x2a\x0a\x42\x47\x55\xef\x53\xb7\xda\x82\x1e\x0a\x5b\xac\x70\x89\x3b\xa2\x2e\x13\x8e\xe9\x88\x32\x28\x1d\x91\x64\x42\x47\x4d\x8b\x51\xb7\x42\xd3\xbe\xbc\x0d\x83\x47\x9f\xca\x10\x24\xa7\x2e\x95\x72\x3a\xe2\x42\x45\x5f\xa3\xfa\x1d\x55\xf3\xb1\x2c\x23\xf0\x3c\x1a\x78\xd8\x1a\x41\x14\x8c\x35\xcd\x68\x14\x68\x8b\x20\x35\x11\xcf\x44\x24\xe8\x12\x31\xc2\x84\x5d\x3c\x46\x66\xa2\x2e\x1e\x23\x4c\xdc\xc2\x18\xa8\xe8\xc1\xd6\xd8\x3c\xe9\x93\x30\x20\x61\xfd\xee\xd8\x13\x0e\x45\xc8\x80\x24\xeb\x08\xd6\x00\x90\xb7\x00\xd6\x00\x22\x59\x30\x34\x2e\xfa\x5a\xb1\xe6\x5d\x4e\x61\x62\x3a\xd2\x0b\x42\x5d\xd2\xf6\xfb\x24\x4c\xb6\x41\x7b\x7a\x37\xd9\x06\x1d\x17\x78\x44\x47\x0c\x35\xa6\xe3\x86\x4a\xe9\x88\x52\x12\xea\x50\x0a\xa3\x6e\x5f\x4a\xfb\x1c\xd8\x03\x89\xd3\x55\x72\x3a\x62\xc4\xbc\x2f\x53\x7b\x3c\xb1\x5a\x90\xfe\x02\x62\x78\x8a\x27\xb8\x3d\x26\x13\x9c\x09\xfb\x13\x1c\x1f\x07\x13\xec\x19\x87\x4e\xd7\xc7\xd1\x98\x4b\xe2\x78\x24\x18\xea\x29\xb8\x19\x43\x32\x16\x2e\x31\x1b\xf3\x7b\x9c\x4e\x88\x96\x38\x1b\x0b\x64\x38\x9f\x10\x2c\x31\x9f\x10\xca\x70\xd1\xd7\x90\xd1\x5c\xc6\x42\x05\xc6\x63\x1e\x8a\xc9\x04\x07\xc1\xfe\x88\x97\xe1\x60\x4a\x60\x0b\x27\x84\x1d\x1c\x39\xa3\x1b\x8e\x27\x84\x25\x4c\x27\xf8\x22\x4e\x26\x78\x3d\x66\x13\xa2\x29\x4e\xc7\x22\x18\xce\x5c\x21\x0c\xe7\x63\x61\x81\x4f\x08\xa3\xb8\xe8\x45\xa8\xab\xa4\x2a\xd8\x0b\x2c\xc1\xc8\xcc\x32\xe9\x48\x05\x5b\x53\x14\x89\xdb\x84\x3d\xd0\x9e\x7b\x86\xe7\x61\x4f\x39\x43\x88\xa8\x23\x34\x13\x8d\xb8\x03\x31\x3e\x1c\xdb\x73\x93\x96\x8a\x2d\x33\xa9\x7b\x6a\xcb\x4a\x5a\x2e\x86\x7c\x66\x3d\x69\x0e\x21\xf2\x8e\xb4\x6c\xa9\x09\x60\xb0\xa4\x25\xaa\xad\x59\x02\xae\xee\x61\x3a\xc6\x3e\xa1\x76\x43\xf1\xe9\x98\xa1\x04\x74\x4c\xd1\x21\x75\x77\x3e\xa2\x6e\x53\x8a\xb5\xe7\xc3\xa7\x94\xda\x45\x97\x50\x97\xe8\x18\x1d\x33\xaf\x94\xba\x9d\x20\xa3\x6e\xd3\xc9\xe9\x98\x61\x70\x3a\xe6\x04\x05\x1d\x33\xf1\x4e\x5a\x61\x31\x02\x3c\xe2\xae\x98\x8c\x58\x28\xf6\x47\x43\x06\x0e\x9c\x96\x8a\xc3\x51\x87\xc7\xd1\x68\xd4\xc0\xb1\x2b\x12\xd3\x51\x4f\xc4\xc9\x68\xc8\xc0\xcc\xe1\x8d\x38\x1d\x09\x17\x38\x1b\x8d\x5a\x58\x0f\x07\x06\x12\x7c\x24\xf6\xe2\x62\x34\x24\xa9\xd4\xc2\xd9\x4d\xec\xf4\x2b\x4c\xc6\x43\x8b\xef\x88\x1c\x38\x18\x71\x6b\x1c\x8e\xc6\x16\x1c\x39\x1d\x18\xc7\xa3\xb1\x0d\xd3\x91\xe0\x83\x93\x51\x0f\xc4\x6c\x24\x0c\xe0\x74\x34\x06\xe2\x6c\x34\x14\xe0\x7c\x34\x1e\x61\xee\x08\x76\xb8\xe8\x46\xa3\xab\xe4\x0f\xd4\x93\x24\xcd\xb1\xa5\xce\x3e\xb1\x17\x58\x52\x89\x9a\x69\xc3\x73\xbf\xc5\x10\x98\x0d\x31\xb0\x1b\x51\xd8\x95\x88\x39\x87\x68\x92\x63\x13\xf9\xd8\xeb\xa4\x7f\xf6\xf1\xb3\x5e\x51\x31\x67\x10\xad\x6e\xcd\xf9\x83\x7c\x6e\xce\x1d\x5a\xf1\xd9\x56\x50\x5a\xf1\x18\x70\xe4\x9a\x97\x5a\x32\x87\xda\xbc\xcd\xb9\x43\xab\x60\x4b\xff\x9d\xfa\xc5\xd4\xde\x3d\x42\xc7\x98\xf7\xe9\x98\x00\x02\xea\x56\x71\x48\xc7\xba\x10\x51\xab\xfd\xc4\x74\xcc\xf8\x28\x75\xc9\x2f\xe9\x12\xb7\x25\x11\x0e\xeb\x48\xa9\x4b\x7b\x19\x1d\xb3\xbe\x9c\xba\xed\x97\x53\xb7\xfb\x15\x74\xcc\x43\xb0\x37\xe2\x22\x18\x8f\x78\x21\x26\xa3\x6e\x88\x7d\xd7\x48\xe1\xb4\x70\x1c\x8e\xba\x08\x8e\xbc\x31\x3d\xe1\x78\x34\x92\x61\x3a\xea\x2d\x38\x19\x0d\x17\x98\x8d\x06\x3c\x9c\x8e\xc4\x4c\x9c\x8d\xc6\x0d\x9c\x8f\x84\x25\xcc\x1d\x71\x09\x17\xce\xb0\x21\xb3\x07\x77\x1f\xf0\xa8\x5f\x62\x62\x77\x4c\xec\x8f\xb8\x3d\x0e\x46\x0c\x1f\x87\xa3\xbe\x83\xa3\xf1\xe8\x16\x3b\xc2\x1b\xa6\xe3\xce\x93\x38\xe3\x07\x66\xa3\xf1\x0f\xa7\xa3\x41\x14\x67\xce\x20\x82\xf3\xd1\x28\x85\xf9\x48\x98\xc2\x45\x37\x8e\x5c\x2d\x79\x30\xc6\x94\x9a\x5f\xdb\x0a\x49\xc3\x8d\x31\x65\xb8\xa7\x6d\xd7\x30\x66\x0c\x0a\x00\xe6\x53\x8c\x79\x43\x93\xf3\x19\x9e\x47\x35\x02\x1b\x40\xdc\x32\x68\x78\xaa\xeb\xdc\x96\x32\xb4\xfc\x59\x72\x86\xb6\x87\x06\x0a\x69\xcb\xa0\x99\x85\xac\x03\x60\x1a\x38\xac\xbe\xc7\x75\xe5\x18\x50\x17\x1d\xe1\x98\xe7\x1c\x5c\xed\x31\x1d\x11\x2e\xa1\x9e\xcd\x70\x7c\xea\x36\x9c\x80\xba\x0c\x27\xa4\x23\x76\x11\xd1\x11\xa9\xc5\x74\xc4\xf4\x28\x1d\x51\x6d\x42\x6d\x72\x67\x74\x44\xa7\x29\x75\x5b\x6d\x46\x47\xac\x26\xa7\x23\x9a\xe3\xd4\x6d\xb8\x05\x75\x99\x3d\xf6\x9c\x6e\x8b\xb1\x67\xd5\x2b\x26\x63\x3e\x8d\xfd\x31\x9f\xc4\xc1\x88\x57\xe3\x70\xcc\x29\x70\x34\x16\x39\x70\x3c\xe2\xdb\xcd\xb8\x67\x55\x23\x4e\xc6\x1c\x08\xb3\x91\xf8\x88\xd3\xb1\x08\x82\x33\x67\x84\xc2\xf9\x58\x84\xc1\xdc\x3e\x38\x17\x23\x11\x02\xf2\x03\xb7\xae\xf0\x88\xa5\x61\x32\xe2\xe9\xd8\x1f\x73\x66\x1c\x8c\x39\x2b\x0e\xc7\x42\x55\x64\x0f\x45\x38\x1e\x0b\x16\x98\xba\xdd\x25\x19\x73\x78\xcc\xac\xc1\x02\xa7\x63\xbe\x8c\xb3\x91\x70\x81\x73\x67\xb0\xc4\x7c\x2c\x94\xe1\xa2\x17\x70\xae\x92\x15\x28\xb6\xa9\x29\x8a\xd4\x38\x4d\x79\x81\x6c\x4b\xcc\x7d\xf6\xdb\xe7\xc4\x84\x3b\x68\x25\x62\xc4\x1f\xea\xfd\x31\x65\x05\xcd\xd3\x21\xee\xb8\x63\xd0\xd6\x51\xd1\x98\x0d\x68\x4c\x0d\x11\xb3\x9a\xac\x91\xe5\x54\x19\xa8\x29\x03\xd0\x64\x35\x7c\x9e\x6b\x68\x87\x4f\x79\xd3\xd7\xe1\xb3\xa2\x23\x65\x53\x4f\x9d\x4a\xc2\xd4\xad\x24\x42\x2d\x3d\xf2\xa9\x4b\x3b\x01\x75\xf5\x27\xa4\x6e\xab\x8b\xa8\xdb\x32\x62\x6a\x97\x07\xa5\x2e\xbb\x48\xa8\xdd\x9e\x19\x75\xab\x3e\xa5\x6e\x1d\x66\xd4\x62\x53\x39\x75\xab\x88\x53\x97\x4d\x15\xd4\x6d\xca\xd8\x1b\xf1\x23\x8c\x47\x8c\x0f\x93\x11\x4f\xc5\xbe\xc3\x00\x71\xe0\xf4\x53\x1c\x8e\xb8\x22\x8e\xbc\x91\x18\x14\x3b\x7d\xae\xc9\x60\x2d\xbc\x27\xd6\xa8\xcd\x6c\xde\x8a\xd3\x91\xd0\x86\x33\x47\x5c\xc4\xf9\x48\x0c\xc1\x7c\xc4\x67\x71\xe1\x0c\x6e\x62\x44\xb7\x30\x8e\x9d\xa6\x84\x89\xd3\x69\xb1\x3f\xe2\x97\x38\x18\x71\x4c\x1c\x3a\x3c\x13\x47\x23\xb1\x06\xc7\xa3\xc1\x6a\xc4\x93\x70\x32\xe2\xa3\x98\x39\x02\x00\x4e\x9d\x51\x0b\x67\xce\xd0\x82\x73\x9b\xff\x63\x3e\xe6\xc2\x45\x37\xf4\x5c\x7d\xe8\x36\xd8\x48\xcd\x6a\xe0\x61\xc3\xd0\xad\x52\x0d\xc3\xa0\xad\x90\x9a\x9a\x05\x4d\x92\x63\x7a\x1a\x5a\xba\x1f\x49\x94\x86\x31\xba\x4d\x99\x86\x4f\xa9\xd6\x01\xd3\x30\xdd\xf4\x7d\xd8\x94\x69\x46\x3e\x7c\x9a\x6a\x9d\x30\xbd\xaa\x6b\x79\x9c\x61\x98\x96\x72\x1b\x62\xe5\xad\xdc\x4c\x2f\xe9\x5a\xe6\x3b\xec\xa9\x4b\x0c\x98\x9a\x85\x4a\xa8\x4b\xbf\x3e\x75\xf5\x31\xa0\x0e\xc3\x09\xa9\x4b\x78\x11\x75\xf5\x24\xa6\x36\xf1\x50\xea\x30\xab\x84\xba\x54\xcd\xa8\x4b\x23\x29\x75\x18\x42\x46\x6d\x66\x9e\x53\x97\x25\x73\x6a\xb6\xd8\x82\x3a\x94\x8c\x3d\xa7\x96\x31\x76\xba\x2b\x71\xfa\x2b\xf6\x9d\xbe\x82\x03\x97\x3b\xe0\xd0\xe9\x4a\x38\x72\x3a\x04\x8e\x5d\x11\x41\x8d\x37\xc6\x47\x89\x33\x5a\x60\xe6\xf2\x18\x9c\x5a\x82\x06\xce\x6c\x41\x36\x77\x7a\x2e\xe6\xce\xa0\x80\x0b\x6b\x44\xc4\x9e\x53\xeb\xd8\xe9\x88\x98\xb8\xbd\xdb\xb7\x58\x1a\x0e\x9c\x8e\x86\x43\x97\x0b\xe3\xc8\xea\x87\x38\x76\x46\x06\x4c\x9d\xde\x8f\x13\xa7\x2f\x62\x66\x09\x56\x38\x75\xba\x1b\xce\x5c\xd1\x01\xe7\x56\x2f\xc6\xdc\x19\x39\x70\xa1\x05\x87\xab\x8c\xa9\x54\x0c\xf0\xc4\x80\xb0\x11\xce\x30\x1e\xdf\x6b\x17\x37\x86\xe1\x58\xb6\x1b\x06\x62\x85\xcf\xf0\x28\x94\xf8\x88\x91\x8f\xa8\x79\x68\x0a\xc2\x8a\x13\xf3\x38\x43\x3d\x33\xff\x49\xd3\x6f\x53\x08\x96\x7c\x9a\x1e\xa5\x0d\x52\x03\x9f\xd9\x3d\x79\xd8\x63\x18\x7e\xcd\x76\xc2\x1b\x21\x1a\xda\x14\x8a\x09\xc3\xa3\x7a\x51\xc9\xda\x73\xf9\x18\xbb\x64\xaa\x60\x88\x4b\xff\x0a\xc6\x77\xe9\x5a\xfd\x1e\xb8\x84\xad\x60\x42\xbb\x58\x15\x44\x34\xda\xe7\xd8\x62\x5a\xea\x31\x75\x49\x54\xc1\x24\x36\x2d\xa9\xe7\xcc\x6e\xa5\x0a\x22\x75\xd9\xa3\x82\xc9\xcc\x2a\x57\x4f\x73\x97\x19\x29\x18\xee\x32\x51\x05\x53\xd8\x3d\xb4\xce\x88\x8d\x8e\x8d\x5d\x3d\xc0\xc4\x22\x64\xec\xdb\x2c\x0e\x07\x2e\x66\x71\xe8\x52\x0b\x8e\x5c\xc2\xc0\xb1\xa3\x8b\xb6\xf8\x9b\xd8\x55\x88\x99\xcb\x52\x71\xea\x8c\x87\x99\xcb\xa3\x70\x6e\xb7\x6f\xcc\x6d\x46\x87\x8b\x71\xef\x6a\x5f\x6e\xac\x10\xd8\x1d\x0b\x30\x19\x37\x38\xec\x8f\x79\x1f\x0e\x9c\xde\x87\xc3\xf1\x20\x50\x2b\xdb\xd9\xdd\x78\x3c\x28\x61\x3a\x1e\xdc\x70\x32\x1e\x0d\x6a\x73\x70\x79\x99\x34\x0a\xeb\xd3\x6c\x2c\xac\x49\xc3\x70\xf0\xc9\xc7\x22\x4e\x6d\x24\x40\x45\x1b\xd9\xe5\x47\xbd\xae\xc1\x33\xb6\x7d\xbf\x45\xd5\x82\x55\x68\xcb\x97\x3c\xab\xa0\x1e\xd1\xab\xef\x9e\xff\x8c\xca\xd5\x69\x7d\x4d\x44\x53\xd1\xe0\xd9\xc3\x57\xbd\x8b\x8b\xdb\x83\x89\x73\xd4\x6e\xfc\x87\x0b\x14\xd5\x17\xf8\xac\xbe\xcc\xf5\x86\x9e\xfa\x55\x02\xc8\x2f\xf5\x67\xf1\x65\xae\xf5\xa7\xcf\xb9\x56\x55\xe9\xfb\xc7\xaf\x64\x61\x2c\x24\x0b\xbf\xb8\xef\xa8\x12\xd0\xcd\x05\x55\xf2\x8b\x56\x25\xe5\xba\x57\x54\xb9\x4b\xeb\xbd\xe7\x97\x4d\x09\xb0\xf7\xfc\xd2\x50\xfa\xee\x3d\xbf\xac\xeb\xea\xbd\xe7\x97\xe6\xb2\x7a\x82\x86\x54\x51\x18\xa1\xb4\xac\xb6\x88\x65\xd9\x7a\x93\x97\xab\x63\x54\xad\xd1\x8b\x47\xd8\x88\xf7\xbb\x12\x4a\x01\xbd\xe9\xd7\x40\x36\xdd\x1d\x12\x46\xf6\xbb\x43\x5a\x74\x2f\xd6\x02\xe1\x8b\x47\xf8\x4d\xf9\x16\xdd\x45\xd8\x50\xa3\x54\xd1\x95\xe5\xf9\x67\x75\xef\xde\xb4\xed\x55\x39\x3e\xf1\x9f\x99\x8f\xd1\x5d\x0d\x35\xd4\xe1\xdb\x41\xb7\x07\x88\x0d\x05\x4b\x1f\x6e\xb7\xfc\x24\x5d\x72\x84\x23\xb4\x3d\x4b\xdf\xf3\x4b\x83\xf8\xb7\x67\xe9\x8f\xfc\x72\xdb\xa8\xa0\xfd\x6e\x17\xca\xea\x15\x00\x49\xd1\xd4\x5f\x1e\x20\x1c\x35\xdf\xec\x57\xac\x3c\x82\x8a\x53\x8a\x1f\xb3\x20\xb7\x35\x76\xc5\xcb\x1b\x85\xf4\xad\x62\xca\x88\xd7\x7d\x75\x4b\x5a\x56\xaf\xa0\x2a\xca\x81\x56\x04\xa5\xc1\x6b\x43\x29\x0d\x2a\xa0\x46\x83\x22\xc3\x36\x26\xab\x21\x81\xdd\x6a\xba\x74\x8a\xcd\xfa\x04\x02\xcc\x92\x17\x15\x22\x14\x3c\x43\x50\x36\x37\x94\xc2\x79\x33\x2b\xd1\xbe\xbc\x1b\xc2\x83\x02\x8e\xb5\x71\xcd\x66\x2f\x1e\x11\x65\x83\x3b\x68\xb7\x91\xc0\x0e\xfa\x33\x22\xf4\x2d\xd4\x78\x04\xdb\x2a\xd1\x9f\xe1\x8e\x8b\xc9\xec\x6d\xca\xe3\xc5\x74\xfe\x02\x28\xdf\xd9\x32\xb9\xd3\xe1\x92\x50\x78\x2c\x79\x45\xbb\x88\x04\x16\x86\x77\x0c\x1c\x0f\xc8\x9a\x2a\xfb\x8b\x0e\x94\xab\x8c\x23\xce\xb2\x85\x32\x3b\x54\x6e\x11\x3b\x3d\x5d\x96\x3c\x17\xba\x64\x2b\xc4\x2f\x4e\xd9\x2a\xe7\x79\x5d\x97\x11\xc2\xfb\xdc\x88\x4d\x88\x40\xa1\xc9\xd8\x0a\xa5\x1c\xa5\x9b\xf5\x7b\xbe\x42\xe5\xaa\x5a\x23\x2a\x8b\x02\x6f\xd1\x36\x63\x4b\x89\x5e\xa2\xdc\x9a\xb1\x9d\x2f\xca\x6c\x81\xd8\x72\xb9\x3e\xdf\x02\x6a\x81\xb7\x5a\x0b\xb4\x67\x5b\x9e\xa3\xf3\xb2\x5a\xac\xcf\x2a\xc9\xe0\xb6\x5c\xaf\x86\x58\x94\xa0\xa1\xbc\xe6\xac\xfd\xf2\xe0\x81\xba\x56\xa6\xfd\x49\x04\x14\x1f\x9b\x24\xd7\xb1\x5c\x2c\x2d\x37\x76\x1b\xae\x42\x0b\x41\xac\xfd\x0c\x31\x6b\x56\x4a\x25\x7e\x83\x84\xf6\x7d\xb3\xaa\x6c\xfd\x88\xf5\x7e\xc4\x6f\x55\x61\xcf\xdf\xf4\x9f\xe0\x52\x80\xc1\x55\x3b\x86\x08\xf8\x48\x16\xbe\x44\xe5\xea\x03\xdf\x6c\xb9\x3d\x0a\x96\xab\x0f\xaf\x7a\x81\xb0\xf3\xd3\xa4\x01\x02\x3b\x06\x88\x16\x9b\x2e\xb1\xed\x1b\x1c\x0a\x83\xee\x63\xff\xd8\x99\x70\x68\xbf\xf0\x55\xb6\xb9\x3c\xad\xae\x70\x15\xa0\xaa\x58\xbb\x7e\xd4\xb4\x6b\x81\xe7\xdd\x90\x6f\x2d\xa1\x9b\xf3\xcf\x41\xb5\x95\x88\xab\x76\xef\x23\x37\xe5\x79\x2d\x48\x53\xd2\xf1\x1f\xbc\xd2\xf3\xb4\x2e\x73\x4b\x40\xaa\x5d\x8d\xd5\xd7\x81\x04\xdb\xf4\xc1\xe0\xe6\x2c\x43\xf6\xf1\xc3\xaa\xac\x4a\xb6\xd4\x4b\x5f\x75\x61\xf8\x45\xb6\x60\xab\x63\xfe\xf4\x65\x5b\x16\x55\x56\x1e\xf3\x2e\xbc\x42\xfe\xaf\x6f\xd2\xe6\x36\xf2\x7e\x6a\x78\x63\x2d\x0a\x6b\x9b\x97\x4f\xf5\x36\x04\xe8\xf8\xea\x6f\x5a\x1b\x2a\x79\xf3\x8a\x42\xfc\x7f\x22\x6f\xd0\x26\x54\x7f\xc6\xca\xb4\xae\xab\xda\x64\xf9\x30\xf0\x28\xf9\x51\x7a\x15\x7c\x1e\xbf\xb6\xcd\x30\x12\x19\xf3\x09\x40\x67\xbb\xf6\xa2\x31\x0c\xdd\x4e\x2c\xb0\x9b\x2e\xec\x46\xc1\x1a\x99\x7c\xc2\xcb\x6d\xc5\x97\x8d\x15\x9b\x31\x16\xd0\xf9\x69\xa9\x05\x75\x07\xe8\x42\x0c\xb4\xb2\xd4\xda\x9b\xf2\xed\x9b\xd9\x4c\x71\xfb\xae\x0d\xd7\x22\x91\x6c\x5e\x5d\xe0\x3b\x94\xd5\x36\x89\xc6\x10\xb0\x7b\x8e\xb4\xb1\x49\xaa\xe7\x49\xcb\x9a\x8d\x62\x3c\x80\xff\x65\x95\xaf\xd1\xf6\x9c\x9d\xca\xf4\x63\xc9\xb6\x95\x34\x86\x61\x08\xaf\xdc\x2a\xeb\x31\xdb\x55\x98\xcb\xf1\x2b\x83\x0d\x43\x45\xf1\xab\xba\xfa\xc0\x35\x6e\xcc\x05\xaf\xe3\xea\xd7\x09\x29\x23\xa1\xcb\xf0\x46\x56\xa1\xf5\x59\x35\x88\xc0\x4d\xc8\x75\xab\xac\x13\x72\xed\x3a\xeb\x0c\x19\xef\xf9\xa5\x2c\x01\x1d\x05\xfb\x3e\xd1\x9f\x94\x1f\x2c\x0f\xb4\xba\xd1\x91\xb1\x6a\xf4\x3e\x7a\x25\x2c\x50\xbd\x04\x6c\xd6\xdb\x6d\x9b\xa6\x43\xcd\x43\x48\x88\xe1\xb5\x54\xb6\x68\x06\xaa\x56\x70\xb3\x7a\xbc\x3a\x61\xdb\xf7\x1d\x97\xad\x6d\x77\x36\xeb\x98\xa8\x70\xc4\x7a\x74\x7d\xd7\xe9\xba\x70\x5a\x81\x45\x13\x41\xc7\x64\xdf\x81\xcd\x7e\x65\x34\x7c\xf1\x4c\x64\x54\x12\xb3\x82\xaa\xfd\x6e\xc0\xf6\xcb\xa7\xd3\xd9\xde\xd8\xd9\x5e\xba\xd9\x5e\x3a\xd8\xde\x4c\x60\xdb\x59\x44\x7a\x5b\x57\x91\x96\xd3\x1f\xd3\xea\x48\x8f\x15\x61\x96\xb8\x2a\x7e\x51\xe9\xa5\x98\xbf\x7f\xfc\x6a\x4f\x25\x68\x9d\x5a\xcc\x73\x94\x15\xc7\x86\xe2\xda\xa7\x4b\x26\x98\xb8\xa8\x50\x1f\x8b\x4a\xb8\x66\x2d\x1d\x13\xa2\xa6\xb2\xf3\x70\xa2\xa6\x5b\x74\xfb\xfb\xc7\xaf\x8c\x15\xb7\x8f\x36\xe5\xe9\x92\xdf\xbd\xda\x14\x91\x6c\xd4\x99\x28\xd2\x7f\xfa\xe3\x4c\x17\xa9\x89\x08\xc1\x76\x09\x15\x4a\xb3\xfe\xf5\x40\x2a\x8b\xe5\x5b\x8c\x0e\x04\xdc\x9e\x94\xea\x63\xa9\xe3\xf5\x66\xd6\xde\xb3\xae\x2e\x8e\xaf\x49\xef\x6d\x97\x65\xc6\x67\xde\x1c\x91\x9d\xc1\x5d\x18\x0d\x5a\x72\x4d\xb4\x64\x8e\x02\x07\x5a\xff\x9a\x68\x83\x39\x8a\x76\xec\x17\x69\x5c\xfb\xdd\x83\x6f\xf1\x9e\xde\x58\x6b\x61\x95\xcc\x9e\xfe\xce\x31\xa1\x81\x3f\x81\xc2\xcd\xbc\xd3\x08\x5a\x57\x64\x8e\x5c\xb5\xfb\x78\x02\x05\xf3\xa8\x87\x13\x72\x63\xc3\xde\x3f\x49\x58\x6d\xa2\xcb\x0d\x04\xd7\x16\xd7\x15\x43\xac\x2d\xc4\x75\x03\x6d\x03\xe5\xac\x9f\xdf\x40\xf5\x4a\xe8\x6b\x85\xd9\xef\x85\x64\xde\xab\xaa\xaf\x15\x77\xbf\x17\x06\xf3\xb6\xaa\xfb\xbd\x30\x9a\xab\x62\xef\xf7\x22\xfc\xf1\xed\x9c\x06\x9f\x54\x70\xff\xf7\xac\xb4\xff\xd9\xea\xe1\xff\xf7\x54\xb6\x87\x9b\x0a\xca\x15\xcf\x6f\xb6\xc4\xfd\x77\x6c\xcb\xdb\xaa\xf5\x6c\xcb\xb5\x67\x3f\xfb\xc4\x59\x01\x7f\xe8\xcb\x17\x51\x80\x56\xec\x84\x6f\x4f\x75\x2f\xdd\xd7\xd9\x10\x20\x82\x0d\xf9\xdf\xbf\x7f\x34\xa1\x79\x88\xa2\xa0\xb9\xc2\xc6\x84\xe6\xe7\x28\x10\x7c\x00\x53\x17\x51\xb0\xa7\xbe\x08\xfe\x0d\x99\x41\x8b\x5a\xa2\x57\xd3\x29\xe5\xdf\xf8\x16\x31\xb4\xe2\xe7\xcb\x4b\x24\x7d\x2d\x37\x11\xd6\x03\x0a\xea\xdc\xe6\xb1\x3a\x3b\x49\xf9\xe6\x23\x82\x5b\xa5\xe0\x56\x15\xf1\xc1\x27\x90\xce\xef\x39\x9b\x2c\xd7\xe7\xd0\x42\xfc\xd7\xd4\xa0\xdb\xb8\x1b\xdd\x86\x00\xb5\x5c\x2e\x5a\xb9\xd4\x11\xa1\x16\x4f\x3d\x30\xcb\xd5\x3f\x8f\x78\x3e\xbc\x95\x05\x5e\xe8\x45\x5e\x77\xbe\xb3\x96\x34\x84\xf8\x55\xd9\xc9\xa8\x44\x0f\xe7\x82\x6b\xf3\x18\xa6\xee\xd7\x32\xdc\xea\x09\x8f\x45\x6f\x0f\x50\xf7\xf6\x6d\xfd\xcd\xbc\xaf\xa9\xef\xca\xea\xbc\xdc\x72\xf4\xd3\xf3\xa3\x2d\x60\x18\x53\x4c\x7d\x51\x8a\x32\x90\x8f\xe8\xa1\xd0\xaf\x90\xcb\x5d\x10\x8c\x1a\x49\x58\x51\xf1\x0d\x5a\xf1\x63\x56\x95\xab\xe3\x1b\x10\x3c\xa0\xe2\x42\xf0\x4a\x05\x7b\xab\x75\x35\xb3\x4a\x75\x7f\x1f\xad\xd6\xa3\x99\x2a\xdc\xc9\x22\x05\xfa\x8f\x46\xba\xf7\x8d\x60\x52\xb0\xff\xa8\x85\x6c\x48\x49\x95\x64\x94\x60\x6a\x6b\x68\xd5\x79\xbf\xc3\x5d\x27\x03\xb0\x69\xe5\xe1\x4f\xdf\x6b\x5a\x81\xe5\x04\x18\xb7\x4f\xd9\x16\x96\x17\x26\xf9\x50\xa3\x29\xc0\x21\x5c\xa2\x51\x56\xb5\x16\x24\x6a\xbc\x37\xac\xfc\x87\x3f\x7d\x7f\x33\xaa\x97\x6b\x3b\xad\xe2\xd9\x2a\x9f\xb1\xd5\xba\x5a\xf0\x8d\x62\xc4\x65\x06\x6c\x95\xeb\x66\x20\x7a\x38\x62\x0a\xad\x9f\xdd\x96\x02\x19\xb3\x8a\xc6\xf3\x14\xfc\xef\x66\x1f\xcf\x5f\x7e\x6e\xf3\x78\xfe\xf2\x33\x59\xc7\xf3\x97\x37\x63\x1c\xeb\x4d\xc7\x36\xd6\x9b\x2b\x98\xc6\x7a\x73\x6d\xcb\xf8\xed\x8a\x96\xf1\xdb\xef\x6c\x19\x3f\x7f\x7e\xd3\xf8\xf9\xb3\xd9\xc6\xcf\x37\x65\x1c\x17\x3d\xeb\xb8\xb8\x92\x79\x5c\x7c\x82\x7d\xbc\xbb\xa2\x7d\xbc\xfb\x9d\xec\x03\x16\xe5\x75\xcb\x58\xc9\x99\x51\xf5\x42\xb8\xe4\x45\x35\x3d\x2b\x5b\x81\x4d\xc8\x6f\x68\x5d\x34\x98\xe0\x0a\x9b\x9b\x32\x06\x40\x76\x33\xe6\x00\xa8\x3a\x06\x01\xbf\x3c\x9d\x91\xd0\x65\x07\x12\x48\x37\x85\x95\xc9\x0e\xc4\x2b\xd0\x0a\x3d\x40\x3e\xb1\xad\x74\x69\x96\x32\x6b\x4d\xe5\xc1\x03\xb4\x82\x25\xf2\xc6\x18\xe4\xd6\x21\x82\xee\xa2\x95\xf1\xb2\x7a\xb3\x09\x09\x3c\x43\x5b\xfb\x88\xea\x97\x27\x37\x43\x3a\x9a\xd9\x0a\xdd\x35\xdc\x18\x3a\x20\xdd\x5f\xea\x12\xe4\xfe\x3b\xad\x17\xa6\xf2\xff\xed\xcc\xf7\xe5\xcc\xfe\x72\x51\x5b\xef\xcb\x1b\xb2\x5e\xa9\xf7\xae\xa5\x6a\xc6\x5b\xdb\xf3\x04\xe3\x1d\x44\x4c\x40\x75\x0d\xfb\xd5\xbc\xa0\xc1\x33\x6e\xc0\x8a\xfc\xef\x6e\xc1\x2f\xd7\x15\xab\xf8\xe7\x0e\xc0\x1b\xa0\x72\x53\x26\x0c\xd8\x6e\xc6\x84\x25\x63\xba\x09\x6f\xd6\xa3\xf1\x57\x80\x8c\xda\xaf\xea\x11\xd8\x81\x8a\xea\xab\x1d\x91\x0e\xb6\xbf\xbc\x9c\x45\xc1\xc0\x2c\x3f\x55\x61\x37\x14\x73\xfe\x58\x1a\x1b\x09\x39\x02\xe2\xea\x0a\x7b\x39\x50\xd8\xd3\xeb\x28\xec\x61\x9e\x7f\xee\xcc\x97\xe5\xf9\x67\xca\x7c\xe5\x95\xdf\x37\xf1\xce\x9c\xf7\xde\x99\xf3\x2b\xbd\x33\xe7\x93\xdf\x99\xfb\x23\xc2\x6e\x93\xc8\xc2\x86\x51\x73\xf2\x9b\xb1\xcd\xe6\x52\x34\xab\xc7\x10\x79\x31\x7c\x67\x58\x69\xaf\x87\x37\xe3\x18\x26\x52\xbb\x6d\xce\x8d\x76\x25\x0d\xc5\xc3\xa7\x46\x74\xf9\xcd\xbc\xba\xf2\x70\xa5\xae\x00\x5f\x17\xfa\xdc\xe6\xd6\x74\xc3\xf1\x66\x7d\xca\x37\xd5\x25\xfa\xbb\xba\x62\x18\x00\xc1\xbc\x1a\x14\x83\x69\x45\x65\x20\xdb\x3d\x13\x9e\x3a\xac\x34\x77\xa2\x77\xa3\xcb\xb6\x3c\x5e\x95\x45\x99\xb1\x55\x85\x52\x78\x5e\xae\x34\xdf\x00\xa2\x8e\xd9\xdf\x76\x5e\xba\x66\xa6\xfe\xe5\x06\xe6\x81\x87\x1c\xd8\xdd\xb1\x23\xae\xd9\xf3\x53\x61\x96\x6c\xb9\xd3\x91\xfd\xa8\xe0\x90\x31\x20\x37\x92\xd3\xd0\x4e\x12\x22\xef\xaa\xf9\x13\x7c\xf5\x5c\x17\x75\xbf\x17\x9d\x35\xdf\xae\xcf\x7e\x22\xb2\x37\x83\xf6\xe2\xef\xaa\xd3\xda\xf3\xab\x62\xc1\x14\x27\x98\xe1\x14\xce\xd4\x64\x38\xc7\x1c\x17\x3b\x03\x24\x6f\xff\x8d\xba\x3a\x47\xd8\x9b\xbc\x3c\x00\x46\x37\x6f\xcc\x76\x10\x96\xcf\xd5\xe6\x09\x08\x8b\xf5\x17\xf9\xdf\xdf\x7e\x33\x1c\xc0\x10\x79\x7f\xe3\x03\x7f\x3a\x40\xc3\x55\x30\xfd\x4f\x8e\xcd\x35\xf8\x41\xc3\x46\x7f\x2f\xa0\x35\x69\xef\x23\x90\x3e\xb4\xe4\xab\xe3\x6a\x81\xbe\x41\x74\xe2\x56\xea\x7e\xa0\x79\xb4\x5e\x7d\xe0\x9b\xfa\xd5\x50\x0b\xc3\x2a\x3e\x88\x41\xbb\x3e\x1d\x30\x29\xf0\xd4\xa3\x76\xa3\xdd\xce\xca\xdc\x47\x74\xd4\x0d\xa2\x77\xb6\x28\x67\x15\x43\x6c\x7b\x45\x3a\x93\x67\xb2\xba\x2b\x85\x17\x5a\x80\xde\xab\xd6\x3f\xfb\xc4\xbe\x14\x02\x8f\x3f\x61\xcf\x8e\xa2\xd5\x35\x2a\xc3\xce\x9d\x1a\xee\xa9\x54\x66\xc3\x64\xad\x5e\xd3\x2e\x1e\xa9\x36\x03\x2e\xd9\xdd\xc9\x9b\xf7\xbb\xb4\xdd\x27\xbd\xda\x25\xbc\xba\xd5\x9b\xc1\x16\x7e\xf1\x57\xf3\xb0\x77\x7a\xb6\x5d\xcc\xea\x44\x4a\xe4\x08\xa6\xf7\x4a\x33\x74\x2f\x97\x40\x86\x7d\xb2\x75\x2a\xa2\x29\xb8\x8e\x20\x35\xce\x79\xd7\x6d\xac\x1b\x49\x06\x5e\x01\x68\x84\x49\x66\xeb\x53\x18\x24\x2d\x63\x3f\x1a\x4d\x5b\x1b\xb3\xe7\x28\x5b\xae\x57\xae\x37\x95\xa9\x26\x0d\x78\xfa\xb6\x0c\x3f\xda\x6d\x19\x1e\x3b\x6d\x59\xc7\x0c\x59\x8a\x64\xb7\xd9\xf9\x6a\xda\xe9\xfa\x08\xe0\xbf\x06\xc3\xfe\x5a\x4a\x66\x88\xb4\x8e\xa5\x12\xdf\x30\xcc\xd6\xbb\xc6\xec\x04\xe0\x0c\x53\xbd\xb0\x2e\x93\x13\x0b\x99\xc6\x85\xce\x3b\xfe\x33\xea\x06\xe7\x53\x7c\xe0\x5c\x99\x7c\x8d\xfe\x4d\xf9\xd6\x24\x76\xbb\xa9\x02\x70\x67\x7d\xb9\x49\x8f\xad\xfb\x66\x7a\xbb\x65\xd4\xd6\x98\x8f\x6f\xe7\x34\x9c\xb2\xdf\x65\xff\x9b\x3f\xa1\x45\x55\x9d\x6e\xef\xed\xef\x9f\x54\x8b\xed\x5e\xca\xf7\xcf\xaa\x82\xfe\xba\x45\x1f\xc8\x1e\xde\x23\x28\xbd\x44\xff\xe3\x84\x55\x8b\x92\x6d\x85\xc5\xb4\x1b\x64\x60\x57\x88\xdc\xec\xb1\xbf\x8f\xbe\xe7\x95\x3c\x0e\xc7\xb9\x10\x77\xc9\xd2\x25\xdf\xa2\xbf\x2a\x4a\x7f\xbd\xf5\x15\x6c\xe3\xdf\x70\xfe\xb8\xd9\xff\x32\xd8\x49\x83\xee\x48\xe5\xdd\x41\xb7\x6f\xd7\x3f\xdf\xb7\xa3\x47\x7f\x95\xdd\xd1\x90\x3f\x83\x1f\x5a\xdc\x27\xea\x7b\x17\xb5\xfa\xf5\xf6\x6d\xc3\xfe\x9c\x83\x0e\x93\x0d\xb0\x93\x8d\x63\xd8\x39\xf3\xd7\xb9\xdc\x8d\xff\xd3\x3a\xe7\x7b\xbf\x6e\xd1\x7a\x83\xbe\x93\x5b\x69\xca\xa2\xe4\x39\xca\xd6\x39\x9f\x03\x16\xb6\xca\xd1\xd9\x96\xa3\xb2\x12\xe3\xda\x5f\x85\x1c\xb5\x3e\xa8\x7d\x38\x4d\x1f\x8e\xd5\xf7\x6e\x1f\xe4\xaf\xf7\xe5\x9e\xa4\xb6\xd9\x5e\x03\x7d\xa0\x23\xfb\xed\x37\xed\xdb\xde\x79\xb9\xca\xc5\xdb\x65\x07\x46\x6e\x1d\x12\xbc\x20\xfd\x67\xd8\xec\x73\xeb\xab\xfd\x6f\xee\xde\xd8\xdf\x37\xfb\xb7\x64\x6f\xb7\xd5\xa6\x5c\x1d\x3f\xd9\xac\x4f\x1e\x2d\xd8\xe6\xd1\x3a\x17\x9a\x7b\x05\x3f\xee\x15\xda\xaf\x4a\xf8\x47\xec\x3d\x5f\x49\x19\xf7\x4d\xf6\xf4\x6c\x75\x29\xe4\x7b\xeb\xab\x26\x82\x9d\x65\x5b\x92\x73\xf1\xe3\x4c\xd2\x91\x1d\x84\xa5\x4d\xd8\x7c\x5f\x0f\x81\xf0\x53\xb6\x3e\x5b\x55\x7c\xa3\x66\x2e\xe1\xa7\x65\x1d\x2b\x64\xf3\x36\x58\xc0\x53\x38\xcf\x58\x7f\xe1\x17\xd5\x86\x89\x2f\xe7\x8b\x72\xc9\xd1\xac\xc6\xf6\x40\x21\x91\xa4\xbf\x82\x36\x2d\xc2\x4c\x75\xef\x61\x55\x37\xd8\xdd\x15\xae\xfe\x15\xe8\x54\x02\x7f\x7b\x80\xbc\x8b\xef\xa9\xe7\x09\x9d\xcb\x9f\x1e\xc0\x4f\xdf\x3d\x79\x22\x7e\xb2\x50\x12\xe2\x82\xd7\xf5\xed\xd9\x66\xb3\x3e\x66\x15\x9f\x83\xd5\x55\x0b\xbe\xe1\x70\xce\x13\xad\xf8\x45\x85\x04\x0b\x2c\xab\xf8\x06\x1a\x41\x37\xa6\xf0\x07\x0c\xce\x24\xf8\x6d\xe4\x5d\x3c\x79\xe4\x79\x3b\xc2\x42\xbd\x8b\xef\xe1\xe3\xdf\x45\x70\x5e\xae\xcf\x5b\xfa\xd0\xec\x2b\x29\x79\x39\x94\xcf\x54\x17\x05\x02\xff\xc9\x93\x1d\x38\x9a\xe9\xed\xa0\x5d\xa4\x61\x86\x07\xbb\x75\xc5\x21\x45\xbd\xcd\x82\x55\x57\xcf\x56\x27\xac\xca\x16\x3c\x6f\xe9\xdd\x47\xeb\xd5\xf2\x12\xb1\xd3\x53\x0e\xfd\x2e\xb7\xe0\x80\xe8\x6c\x55\x56\x73\xf1\xa2\x99\xb1\x2d\x87\xb7\x4d\x21\x88\x06\x53\x03\x23\x84\x54\xd5\xfb\xa2\x1a\xac\x62\xa8\x67\xda\xd7\x53\x56\x6e\x86\x3d\x83\x7e\x29\x5e\xbf\x52\xa2\xbb\x7b\x57\xf1\x7e\xab\xdf\x01\x4b\x4b\x01\x28\xfe\xaf\xe2\xbd\x84\xaa\xbd\xf1\x3a\xce\xc0\x57\xe0\x0c\x30\x0a\xb7\xbe\xd0\x58\xb9\xcc\x5b\xba\x46\x5e\xae\x72\x7e\x81\x0e\xd0\x5d\x6c\x34\xfb\xc6\x8f\xee\xdc\xd1\x8c\x7f\x77\x57\x36\xb3\x18\x3f\xd0\x79\x03\x20\x6f\xfb\xc6\x2e\x4c\xe9\x89\xd0\xb8\x94\x8c\xfc\xf5\xee\x41\xad\xfe\xfb\x9a\xbc\xd0\xee\x81\x21\x7e\xd4\x88\xbe\xfd\x16\x61\xaf\x36\x20\xf4\x9b\xf2\x21\xa5\x92\x9a\x13\x69\xac\xe8\x37\xd4\xb1\xc3\x46\xf8\x13\x08\x01\x42\x9b\x92\x1a\xe1\x67\x0b\x9e\xbd\x7f\x95\xb1\x25\xdb\xfc\x2f\xd1\x6a\x26\xf4\xf0\x62\x5d\xae\xe4\x6e\x6a\x10\x40\xf3\x53\xd7\xe3\xdb\x9f\xa5\xd7\xb7\xc2\xa9\x16\x9b\xf5\x39\x7a\xbc\xd9\xac\x37\x33\xe8\xd5\x9d\xa7\x22\x15\x6a\x4d\xf3\x2f\xbb\x77\xd0\x6e\x8b\x60\xaf\x5a\xcb\xc8\x3a\xc3\xd1\xce\x5e\xb5\xfe\xcb\xe9\x29\xdf\x3c\x62\x5b\x3e\xdb\x41\xbb\x12\x81\x30\xf9\xd5\xba\x12\x06\x0e\xcc\x4a\xb9\xdc\x11\x0f\xeb\x8e\x7e\xfc\x0c\x23\x41\x2b\x27\xc8\xaa\x45\x26\xde\x8a\x63\x2e\x97\xd9\xd4\xe0\x24\xa5\x6c\xd0\xc6\x4c\x17\xe0\xb7\x75\x1b\xa9\x51\x98\xaa\xbc\xa0\xde\x4e\x5f\x2f\xd2\x21\x1e\xd5\x0d\x4d\x6a\xd1\xd0\xde\x56\xc6\xf9\xe4\x09\x55\xb1\x4e\x85\x39\x7c\x37\xbd\xac\x38\xda\xf2\xff\x3a\xe3\xab\x0c\x02\x9d\x9d\xd1\x96\x46\x6d\x3a\x30\x10\x5e\x9e\xa4\xeb\x65\xe3\x48\x36\xca\xd4\xeb\x52\x26\x43\xca\x0d\xa6\x71\x21\x45\x52\x40\x58\x09\xe8\x91\xd7\xb0\xd4\x6c\x3c\x36\x30\x01\x61\x58\x67\xc2\x1f\x32\xe1\x30\xf8\xfb\x57\x64\x12\x13\xc9\xa5\xa7\xb8\x7c\xec\x75\x50\xec\x1e\x58\xac\x26\x9a\xd0\x99\xc7\xde\xa0\x33\xc1\x27\x49\x14\x53\xc5\x6c\x2c\x99\x7d\x32\x91\x59\x4c\xae\xda\xa9\x16\xd2\xc4\x55\xb7\xa3\x5d\x0f\x68\x6c\x13\x30\xf4\x5d\x42\xa4\xfe\x6a\x9c\xe8\x27\x4d\x0d\x52\x91\xba\x0f\x93\xab\x41\xd6\xd4\xc2\x8f\x0e\x2a\x0d\x68\xfd\x83\x50\x82\x8c\x56\x13\x07\x97\xb6\xc7\x3a\x61\x7d\x94\xd1\x50\xee\x1e\x38\x5c\xbf\x17\xd1\xdb\x66\x9f\x2b\x11\x6e\x64\xbf\xe1\x2c\x7f\xb4\x5e\x55\xe5\xea\x0c\x0e\xcf\x82\xf6\xdb\x50\x24\x38\xf9\x01\xfa\xfe\xed\x01\xb0\xf5\x48\x24\x16\x86\xd1\xe0\xce\x0f\xab\x0f\x6c\x59\xe6\x00\x24\xa5\x7d\x47\x75\xab\x91\x77\x97\x0a\x92\x08\x61\xa2\xe0\x4d\x43\xe7\xad\x72\x13\xd1\xb4\xf9\x71\x77\x57\x24\xe3\x75\x84\xea\xa1\xb9\x2d\xc3\x88\x4c\x04\x45\x94\xfc\xbb\x16\x0c\x8d\xd0\xfe\x93\x86\xb1\xfd\x7d\xf4\x43\x81\xce\x39\x12\xf9\xda\xd9\x29\x12\x99\xea\x1c\x95\xd5\xff\xfd\xdf\xff\xa7\x1e\x96\x74\x14\xc0\xf1\x2d\x4b\xcf\x07\x80\x77\x06\xc1\x5f\x5a\xef\x2b\xf0\x82\x59\x6b\xe5\x02\x18\xeb\x66\x48\xf4\x2f\xbe\xfe\x25\x30\x98\xef\x50\x57\x9f\xa0\xaa\x2e\xa6\x83\xa1\xd6\x95\x64\x0b\xb6\x84\xc3\x0f\x8d\x1c\x5f\x72\x96\xa3\xa2\xdc\x6c\xab\x5a\x4a\xd0\xad\xab\xab\x79\x38\xba\xa1\xd9\x6a\x3d\x14\xef\x76\xa7\xb6\x09\x49\xe8\xb6\xd2\xbf\x8a\xac\x1a\xaf\x8d\x7c\x6b\x5e\x87\x63\x58\x0f\xcf\xe3\xda\xa0\x1e\xd5\xa8\x40\x2d\xe8\xc0\xe2\x30\xf7\xfb\xf1\x40\x47\x86\xe5\x6b\x06\xd4\xdc\x69\xb4\x6b\x4a\xc0\x1a\xeb\x6d\xcd\x57\x8b\x51\xdd\x04\xfe\x0a\x26\x58\xa7\xf5\xb2\xef\x7e\x5f\xb6\x27\xec\x12\x95\xab\x6c\x79\x06\x2f\x21\xe2\xe5\x42\x7f\xa5\x31\x49\xf9\x49\x2d\x9d\xc7\x57\x90\x0e\x98\xf2\xf5\x04\xe8\xa9\xf7\x34\x02\x7b\x93\x24\x2d\x5d\xa0\xbe\x4d\xa0\x1e\x24\x2f\x52\x60\x63\xf9\xc1\xe7\x94\xf9\x70\x84\xef\x4b\x94\x2a\x89\x3e\xb9\x59\x89\x42\xc8\xb8\xa6\xd0\x63\x10\xba\x77\xd1\x17\xbb\x77\xe1\x3d\xda\x41\xbf\x81\x44\x66\x92\x07\xf9\x6b\xa3\x8f\xc0\xaa\x0f\x78\xa3\x32\xbc\x63\x60\x4f\x7f\x05\x33\x6b\xa2\x96\xa7\x51\x0b\x7f\x39\x7a\x72\x97\xa2\x1c\x66\xca\x78\xde\x44\xde\x3a\x6c\xaa\x13\x58\xcd\x77\x08\x68\xda\x77\x88\x3f\xf7\x7b\x39\x89\xca\x35\xda\xd1\x58\xf2\xd7\xe0\xeb\xa6\x24\x1a\x58\x1d\xd5\x80\x8a\x1e\x00\xb5\xa4\x44\x8b\xb1\xed\xec\x4f\x27\xdd\x69\xe7\x89\xaa\x93\x53\x2d\x1b\x99\x55\x27\xa7\xe8\xa0\x37\x96\xec\xa0\x3f\x1d\x1c\xc8\xa0\xdc\xcf\x4e\xd4\x22\x46\x75\x72\xda\xcf\x33\xb4\x17\xf4\x16\x7a\xe7\x73\x4e\xbe\x09\xb1\xa2\x03\x60\xf0\xce\x07\xbe\xd9\x96\xeb\xd5\x9d\x7b\xe8\x0e\x4c\xfa\xde\x99\x8b\x5f\x25\x3f\x77\xee\x69\x59\x21\xfc\x2e\xbb\xab\x7e\x97\x5f\x6e\x7d\xf5\x51\x4d\xd2\xbd\x5a\x9f\x70\xf4\xf0\xd9\xf7\x28\x3d\x2b\x97\x39\x5a\x9f\x56\xe5\x49\xf9\x37\xbe\xd9\xce\xd1\xb2\x7c\xcf\xd1\x66\xef\xd7\xed\x5c\xbe\x12\xc3\x4c\xfb\xf6\x94\x67\x65\x51\x66\xc2\x79\xf3\x12\x14\x7e\xca\xaa\x8a\x6f\x56\x5b\xc0\x07\x8d\xaa\x05\x47\xc5\x7a\xb9\x5c\x9f\x97\xab\xe3\x7b\x72\xce\x53\x98\x5f\xef\x5c\x24\xba\x53\x1b\xcd\x1d\x39\xb9\xdb\x01\xd8\x63\x27\x79\x6f\x16\xb5\x39\x22\x29\x9e\xdd\xfa\x4a\xaa\x4b\x1d\x9a\x6c\xa6\xb9\xbb\x03\x98\xe8\x33\xe8\x0e\x94\xd3\xbe\x5d\xf4\x66\x8d\xff\xa4\x7d\xdf\x5b\xad\x73\x7e\x74\x79\xca\xdb\x64\xae\x9d\xab\x56\x2f\x1e\xe5\x4a\x9f\x37\x7e\x59\xae\x8e\xd7\xff\xf3\x15\xfa\xe0\xed\xd1\x3d\x0f\x5e\xcf\xdb\x16\xda\x59\xd2\x86\x19\x15\x1a\x6b\x4c\x6c\x73\xbe\x60\xcb\x1e\xa6\x78\xcf\xbb\x2b\x27\x62\x36\xf5\xde\x28\x79\x8a\x51\xfd\xb6\x60\xdb\xe7\xe7\xab\x17\xf5\x16\x98\x03\x05\xb4\xd7\xfd\x1d\xc0\x9b\x25\x12\xa8\x1a\x27\x85\x52\x47\x8c\x2e\xb8\x5c\x1f\x12\xcf\xe1\x20\xf1\x8e\x90\x8d\x2e\xab\x37\xef\x65\x01\x43\x01\x01\x9f\x3b\x93\x5f\xbd\x7e\xbd\x5c\x94\xab\xb5\xe8\x15\x43\xe7\x3c\x45\xea\xa0\xaa\x9a\xb5\xde\x53\x06\xad\x64\xf2\xf1\x96\x3a\xa2\x0a\xcb\x26\x1f\xe7\x7f\xff\xf8\x76\x4e\xa3\x29\x4b\x22\x83\x13\xbb\x3f\x3f\x7b\x7a\x58\x55\xa7\x2f\xc5\x90\xb1\xad\x5a\x6c\xf1\xb4\x05\x16\x74\xce\x56\x42\xaf\xdf\xec\xdf\xaa\xd7\xa2\x9e\xf1\x6a\xb1\xce\x61\x30\x90\x47\x7f\xef\xec\xed\xed\x9f\xc0\x8f\x22\x5e\xd6\x60\xc5\x7a\x73\x02\xbe\xb0\xed\x81\xb6\x0f\x04\x38\xc0\x37\xe1\xf2\x35\x5b\xcd\xce\x79\xea\x0b\x7d\xd4\x0b\x49\xaa\x6a\x88\xec\xc0\x33\xb6\x62\xc7\x30\xd5\x2d\xc0\xfa\x3f\x2b\x74\x35\x0b\x5b\xbe\x2c\xd4\x6a\x9c\xf6\x44\xb2\xba\x9d\xed\xec\x15\xeb\xcd\x63\x96\x2d\x5a\x6f\x91\x8f\x74\xe2\x6d\x83\x3d\x56\x55\x2c\x5b\x1c\xad\x9f\x83\x65\xcd\x04\x72\xd5\xdd\x1e\xe0\x96\x57\x2f\x3b\x6c\x01\x6c\x9f\x57\xad\xed\xc7\x1d\x8d\x3d\xb5\x8d\xab\xe4\x46\x0e\x4f\xfb\xcc\x9d\x4e\xe0\xeb\xf4\x7a\x2c\xa9\xc5\xbe\x5a\x9a\x4a\x6e\xe8\xa0\xb7\xd0\xaa\x4b\xfc\x98\x57\xaf\xd9\xea\x61\x9e\x6f\xf8\x56\x40\xae\xf8\xb9\xb2\x97\x59\x97\xed\x15\x3b\xe1\xf7\xd0\x9d\x0e\xfc\x9d\x79\x07\x44\x38\xdf\x3d\x74\xe7\x9c\xad\x7e\x71\x81\xc1\xce\xad\xed\x3d\x84\xbb\x3f\x43\x91\xb8\x27\xb5\xa9\xdd\x43\x6f\x5a\xb3\xdb\x83\x67\x0a\x5b\x03\xf2\xd6\xac\x0e\xd9\xab\x15\xdf\xb0\x8a\x3f\x5f\xf1\xa3\xf2\x84\x5f\xa5\x7b\xa6\x86\xae\x7e\x4e\x80\x9f\xda\xe1\xd5\xd9\x72\xe9\xec\x54\xf5\xfc\xe8\xe1\xb3\xf2\xe2\x15\xaf\xa6\x69\xaa\x01\x77\x2a\xca\x06\x55\xb3\x4d\x26\xb0\x3d\x47\x23\xcc\x67\xb2\x08\xe4\xf3\xa3\x87\x2f\x5e\xa8\x72\x8e\x63\x1d\xe8\x37\xb1\x77\x62\x04\x72\x6a\x47\xc6\x0c\x6e\xb4\x93\x52\x98\xdf\xb1\x25\x13\xef\x57\x53\x55\xa4\xe0\xc7\x74\x64\x06\xbb\x9a\x92\xfa\x3d\xfc\x9e\x17\xec\x6c\x29\x2b\x9c\xfc\x04\x3b\x25\x5b\xef\xea\x22\x94\x6b\x0d\x1a\x46\x0d\x95\x7c\xf6\x5d\x79\xdc\x43\xe1\x14\xd4\xab\xb3\x53\x31\x68\xbd\x66\xab\x47\xeb\x72\xd5\xf6\xaf\x6b\x19\xc8\x2a\x38\x6b\x7b\xa7\x1c\xa7\xb6\xaa\xc5\xea\xcd\xa7\x74\xe2\x55\xc5\x4e\x4e\xaf\xdd\x85\x7e\xeb\x29\x1d\x18\x69\x33\xc6\xbe\xca\x2e\xdf\x74\x09\xf5\xbc\xa8\x8b\xb2\x13\xd1\xfb\x8f\x4c\x41\x70\xd0\xbc\x89\x33\xa6\x27\xaa\x23\x83\x47\x56\x85\xd9\x20\xfb\x92\x69\xe1\xde\xaa\x01\xb2\xcd\x78\xda\x91\xdb\x3a\x46\xd6\x92\x6a\x1b\x0f\x12\xb6\xd7\x6c\xa5\x36\xc4\xe8\xa9\xd2\xd7\xf7\x7c\x6f\xfe\x75\x93\x68\x7d\x7d\xcf\x8f\x3e\xbe\x9d\x7f\x9d\x96\xc7\x72\x4b\xf2\xde\xaf\xdb\xaf\xa7\x64\x74\x77\xce\xb6\x1c\xa6\xdd\xb3\xea\xce\xfd\x5b\x43\xea\x8d\xdb\xdd\x17\x69\xeb\xaf\xdb\x85\x78\xb5\x2e\x8f\x57\xeb\x0d\xbf\xb7\x2c\x57\xfc\x56\x9d\x40\x7e\x2d\xf2\xaf\x49\x24\x85\x68\x5e\xf3\xd4\xef\xa4\x81\xfb\xcb\x32\xdd\x17\x28\x20\x07\xdc\xdf\x47\xf9\x7a\x55\xa1\xf5\x07\xbe\xd9\x94\x39\xaf\xb7\x8d\xd4\xbb\x54\x6e\x69\x85\x64\xd4\xfe\x0f\xf1\x9a\x7a\xa7\xd9\x96\x0a\xbb\x4a\x3a\x00\x7b\x92\x64\x17\xaa\x56\x45\x07\x04\x98\xbb\x7f\xeb\xa3\x41\x1a\xf2\x89\xda\x9e\x54\x73\xfc\xf5\x3d\x42\x3e\xbe\x15\x52\x98\xbf\x91\x52\x78\xbb\x73\x6b\x7f\xff\xff\x43\xdb\xf5\xd9\x26\xe3\xcf\xd8\xe9\x69\xb9\x3a\xfe\xcb\xcb\xa7\x07\xe2\xe1\xdd\x25\x1c\x05\xfa\x75\xbb\x77\xc2\x4e\x6f\xfd\xbf\x00\x00\x00\xff\xff\x3f\x3c\xdd\x2f\xd5\x2c\x06\x00")
+var _web3Js = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xec\xbd\x69\x77\x1b\x47\xce\x28\xfc\xf9\xea\x57\xb4\x75\x9f\x1b\x92\x31\x4d\xad\x76\x1c\x3a\x4e\x46\x5e\x92\x68\xc6\xb6\x7c\xbc\x24\x33\x8f\x46\xe3\xd3\x22\x9b\x52\x27\x54\x37\x5f\x76\xd3\xb2\x62\xeb\xbf\xbf\x05\xa0\x16\xd4\xd2\x0b\x25\xd9\x71\x3c\xd2\x39\x89\xd9\x55\xa8\x0d\x85\x42\x01\x28\x14\x6a\x9e\xfc\x7f\x8b\x74\x9e\xdc\xef\x4e\x16\xd9\xa8\x4c\xf3\x2c\x4a\xba\x65\x3f\xeb\xcf\x7b\xef\x75\x4a\xd1\xcd\xfb\x8b\xde\xfb\x74\xd2\xbd\x91\xed\xe7\x07\xf4\xab\xc4\x5f\x6f\xe3\x79\x14\xdf\x2f\xcf\x66\x49\x3e\x89\xe6\xb2\xae\xfb\xab\xaa\xe8\xea\x57\x5f\xc9\xc4\x7b\x50\x66\xf1\xd5\x57\x71\x6f\x9e\x94\x8b\x79\x16\xc5\xa2\xd2\x1b\xeb\x3d\x48\x4f\x55\x5a\x2a\xd3\xa0\xd6\xc9\xfd\x2c\x39\x8d\x1e\xcf\xe7\xf9\xbc\xbb\xfa\x30\xce\xb2\xbc\x8c\x26\x69\x36\x8e\x4e\xf2\xf1\x62\x9a\x44\x9d\xd5\x9b\xf9\xcd\xd5\xce\x6a\xef\x5e\x79\x3c\xcf\x4f\xa3\xc9\x60\x94\x8f\x93\xfb\xab\x4f\xf7\x1e\xbd\x7e\xf2\xf8\xcd\xb3\xbd\x57\x6f\x7e\xdc\x7b\xfd\xec\xd1\x6a\x7f\x72\x0e\xf5\x4d\xef\x43\xdf\xef\xbf\x4f\xde\xcd\xf2\x79\x59\x0c\xdf\x9f\x9f\xdf\x83\x31\xec\xaf\x1f\x0c\x46\xf1\x74\xda\x9d\x0e\x64\x56\x5f\xf5\xbe\x9b\xd0\x00\xb3\xfb\x08\xb8\x71\xb0\x9f\x1c\xdc\x93\x5d\x2d\xba\xd9\x0f\xd9\x30\xe9\x9d\xf7\xa7\x7d\x53\x32\xe9\x13\xee\xce\x25\x14\x34\xa9\x32\xb1\x17\x69\x2b\x5c\x4d\xc4\x98\x01\x3a\xbf\xbf\x7e\x2f\xff\x6e\x3e\x98\x26\xd9\x51\x79\x7c\x2f\xbf\x79\xb3\x57\x74\xe7\x80\x78\xdd\x8d\xf3\x5e\xf7\xfd\xc6\x70\x5f\x77\x59\x56\xd1\x27\x2c\xf5\x65\xdb\xbd\xf7\x2b\x94\xa0\x3a\x73\x7f\x7f\x25\x8a\xde\x8b\xff\xa2\x68\x75\x94\x67\x45\x19\x67\xe5\xea\x30\x2a\xe7\x8b\xa4\x4f\xa9\x69\x36\x5b\x94\x85\x48\xdb\xc7\x6f\x05\x8d\x79\x59\x7c\x92\x88\x9c\xd5\x37\xf9\x69\x96\xcc\x57\xfb\x26\x07\x46\x07\x39\xf1\x78\x3c\x4f\x8a\x62\x55\xe6\x9c\xe3\xbf\x07\xb2\x6a\x55\x1c\xff\x95\x69\xf9\xa2\x6c\x6e\x2f\x7f\xc3\x8a\x58\xed\x1d\x9e\x95\x49\xb1\xb5\x19\x6e\x4f\x01\x69\x4c\x8b\xe4\xf3\xfe\x95\x20\xe0\x42\xfd\xd1\xc3\x61\xd8\x6b\x87\x80\xa5\x51\xfd\xb9\x0e\x5d\xb4\x5b\x26\xa2\xd9\xcb\x0e\xfe\x2f\x39\xef\x30\x63\x9f\xcd\xb4\x4f\xe2\x69\xf1\xe9\x86\x2e\xba\x9c\xcc\xdf\x86\x56\xfd\xe7\x3e\x69\xc5\xe2\xf0\x45\x72\x94\x16\xe5\x3c\xfe\x2f\x98\xbc\x7e\x5d\x1d\xc9\xe9\xde\xa5\xf8\xbe\x40\x61\x56\x4c\x82\xac\xef\xaf\x82\x83\xb9\x43\x0a\xcb\x23\xa1\x48\xca\x97\xf5\x24\x75\x65\xb8\x70\x9b\xfe\x24\x8d\x7e\xe4\x09\x88\xdb\x20\xbe\xae\x82\xd9\x3c\x3d\x89\xe7\x67\xc1\x7e\xe4\xf9\xb4\x71\xf2\x76\x64\x5b\x7f\x5d\x14\xda\x7b\x70\x6d\x35\x55\x48\x78\x58\xb9\x8d\x7f\x4e\x48\x08\xf6\x7e\x9c\x16\x42\xfe\xba\x44\xcf\xe3\x2c\xcf\xce\x4e\xf2\x45\xb1\x44\xd7\x85\xfe\x92\xbc\x4b\xc6\xd6\xde\x75\x65\x13\x6b\x2a\x67\xdd\xb1\x6b\x3f\x4d\xb3\xcb\x30\xee\x9d\x05\x62\xe2\xb1\x68\x68\xbc\xea\xa0\x29\x79\x0b\x84\xf0\x05\xe0\xe8\x30\x1d\x8f\xdb\xe1\xe8\x62\xf5\xbf\x8d\xa7\x8b\x60\xf7\x17\x69\x56\x6e\xde\xbe\x53\x3f\x05\xcf\x92\xd3\x07\xe9\x9f\x88\xfc\x4b\xad\xb9\x87\xc7\x71\x76\xf4\x67\x92\xce\x95\x50\x4e\x45\xdd\x4c\xaa\xaf\xa5\x9a\x20\x66\x9e\xd3\x6e\xd4\x88\xa0\x95\x83\x95\x95\xf3\xfe\xfb\xf3\x83\xfe\xe6\x9f\xa6\xf4\x7f\x41\x3a\xef\x9f\x24\x3b\x8a\x9e\x8d\x2f\x4c\x2a\x97\xde\xb8\xae\xd5\xde\x4f\x2e\x6c\x5c\xa9\xda\x7b\xad\xf4\x7d\xce\x3a\x47\x70\xf0\x52\x5f\xf8\xdc\xa4\xcd\x8f\xbb\x99\x9b\xbd\x6a\xeb\xca\xf6\xaa\x65\xe7\x7d\x32\xcf\x4f\x2e\x39\xed\x65\x7e\x49\x55\xf3\x72\x02\xdf\x9f\xbb\x6e\x3e\x07\xfc\x09\x32\x16\x04\x33\x2a\x77\x83\x7b\xe6\x12\x3d\xb9\xdc\x44\xa4\xa3\x78\xf6\xea\x4f\x9d\x8c\x30\x26\xdb\x69\xbb\xc9\x2c\x2f\xd2\x3a\x45\x7d\x16\x9f\xc5\x87\xd3\xc4\x16\x0a\xfe\x14\xae\x54\x45\x73\x57\xa2\x7e\x5d\x8e\x06\x76\xd4\x78\x1f\xd9\xf8\xfc\xf4\x9a\xcc\x95\x20\xa9\xa2\xee\x76\x74\xf6\x27\xa0\xff\xb3\xc5\xfa\x55\xe8\x8f\x17\xe6\x93\x1f\x1b\xeb\x2e\xd3\xbb\x46\x7b\x4b\xb4\x5f\x7a\xe3\xfa\xd8\x33\xbb\x1b\xd8\xd2\xea\xe4\xb8\xed\x36\x72\x1c\x3a\x6f\x44\xf7\x95\x87\x43\xb7\x33\x58\x9b\xe4\xf3\x93\xb8\x2c\x93\x79\xd1\xe9\xdd\x43\x80\x97\xf9\x34\x1d\xa7\xe5\xd9\x2b\xd1\x9a\x0d\x0b\xed\x03\xd4\xca\xda\xd7\x5f\xaf\x44\x5f\x5b\x90\xd2\xe6\x1e\xa5\x45\x14\x47\xb3\x79\x9e\x03\x70\x54\x1e\xc7\xa5\xa8\x61\x06\x4a\x56\x56\x8a\x2c\x09\x05\x99\x50\xc3\x6e\x19\x89\xd6\x47\xc7\x49\x31\x84\x4f\x99\xcd\x7e\xee\x1f\xf0\x8f\x6d\xeb\xeb\xc0\xce\xdc\x72\xbe\x0f\xf6\xef\x88\x94\x7e\x34\x18\x0c\x44\xf2\x9a\x37\x36\xd5\xe3\xfb\x91\xf6\xa6\xe9\xf6\xe4\x14\x97\xc7\x69\x31\x78\x83\x0b\xe3\x47\x85\x20\x00\x1c\x10\xba\x76\x21\x63\x37\x2b\xef\x31\x60\xda\xb7\x43\xd0\x7b\x98\x23\x9b\xbb\xb7\x72\x2e\x10\x18\xe8\xc7\x40\x20\xad\x24\xac\xdd\x8f\xc0\xbf\x86\xc3\x74\xdf\x9f\x0b\xbc\xd7\x96\x1a\xa0\xf4\x32\x5f\x8c\xca\x1c\x1a\x0f\xc0\x36\xb5\x3b\x48\x0b\x39\xe7\x06\x21\x40\x8e\x0a\x29\xd2\xaf\xe5\xc6\x0d\x48\x1c\xe0\xbc\x75\xd7\x24\xb6\xbb\xff\xde\xef\xee\xaf\xdf\xfa\xf6\xe0\xeb\xde\xbf\x0f\x7a\x3f\xac\xf5\x68\x9c\xb6\xe2\x50\xd9\x2d\x41\xc2\xab\x9c\x14\x57\x87\xdf\xf6\x57\x89\xde\x56\x87\x1b\xdb\x82\xbc\x6f\xff\xc9\xe4\xfd\x20\xcf\xa7\x0d\xb4\x0d\x47\x53\x55\x84\x0d\x79\xea\x5f\xa2\x52\xfc\xb5\x6d\x7e\x1e\xb0\xe4\x2d\xfe\xd1\x44\xc6\xd8\xb3\x8b\xd2\x30\x14\x5e\x86\x88\x09\xde\xa5\x60\x48\x5d\x92\x7c\xed\x22\x35\xb4\x4b\x2d\xd6\x95\xbd\x08\xd5\xfe\x07\x50\x6b\xd3\xec\xd7\xff\xd3\x8a\x68\x65\x7f\x9a\x29\xf6\xce\x9f\x4d\xb1\xb0\x87\x69\x92\x2d\xc3\x34\x5b\x1e\x27\x11\x6e\x76\x48\xb8\x83\x10\xe5\x42\xae\xfe\x21\xe9\x12\x7f\x6e\xb3\xdf\x07\x3c\x63\xcb\xfa\xb2\xe9\x37\x92\x5b\xab\xfe\x79\xd7\xaa\x47\x16\x0d\x50\x39\x76\xf2\xc2\x64\x0e\xa5\x97\xa2\x73\x2a\xe0\x11\x3a\x24\x2f\x4b\xe9\x76\x99\x3a\x52\xa7\x46\x6b\x4b\x5f\x8c\xd8\xa1\x12\x22\xf5\xf7\x1b\xfd\xf3\xde\xc5\x08\x5f\xf6\xae\x99\xf2\xbf\x69\x43\xf9\x6b\x5f\x63\x87\x5f\x89\xe9\x88\x26\xe9\x34\x01\x4a\x9d\xc5\xf3\x32\xca\x27\xd1\x69\x72\xb8\x35\xf8\xad\x18\xac\x20\x88\xfc\x02\x80\xc9\x3c\x49\xa2\x22\x9f\x94\xa7\xf1\x3c\x19\x46\x67\xf9\x22\x1a\xc5\x99\x18\xf5\x18\x9c\x26\xd2\xc3\x45\x29\xea\x29\xa3\x38\x1b\xaf\x09\xec\x8a\x36\xd3\xc9\x19\xd6\x21\x12\x17\x42\x60\x9b\x23\xc1\x8b\x5e\x9f\x14\xd0\x0e\x7c\xfc\xf4\xec\x75\xf4\x44\xec\x42\x22\xef\xa7\x24\x4b\xe6\xf1\x34\x7a\xbe\x38\x9c\xa6\xa3\xe8\x49\x3a\x4a\xb2\x22\x89\x62\xd1\x31\x48\x29\x8e\x93\xb1\x20\x53\x49\x45\x49\xf4\x23\x74\xe6\xa5\xec\x4c\xf4\x63\x2e\x1a\x88\x61\xcc\xfd\x28\x49\x45\xfe\x3c\x7a\x2b\xb0\x03\x33\xb4\xa5\xda\x92\x35\xf6\xa3\x7c\x8e\xb5\x74\xc5\x62\x14\x63\x98\x47\xf9\x0c\x0a\xf6\x44\xc7\xcf\xa2\x69\x5c\x9a\xb2\x3e\x0a\xcc\x48\xc7\x51\x9a\x61\xb5\xc7\xb9\x5a\xd9\x62\x98\xa7\xe9\x74\x1a\x1d\x26\xd1\xa2\x48\x26\x8b\x29\x09\x8e\x02\x3a\xfa\x75\xf7\xd5\xcf\x7b\xaf\x5f\x45\x3b\xcf\xfe\x15\xfd\xba\xf3\xe2\xc5\xce\xb3\x57\xff\xba\x27\xa0\xcb\x63\xb1\x16\x22\x90\x28\xb1\xae\xf4\x64\x36\x4d\x45\xd5\x62\x4c\x42\xf8\x2c\xcf\x44\xd7\xb1\x8a\xa7\x8f\x5f\x3c\xfc\x59\x94\xd9\x79\xb0\xfb\x64\xf7\xd5\xbf\xc4\x08\xa2\x1f\x77\x5f\x3d\x7b\xfc\xf2\x65\xf4\xe3\xde\x8b\x68\x27\x7a\xbe\xf3\xe2\xd5\xee\xc3\xd7\x4f\x76\x5e\x44\xcf\x5f\xbf\x78\xbe\xf7\xf2\xf1\x20\x8a\x5e\x26\xd0\xb1\x04\x6b\x68\x46\xf4\x04\xe7\x4c\xe0\x72\x9c\x94\x71\x3a\x55\xf3\xff\x2f\x31\xcb\x85\xe8\xe5\x74\x1c\x1d\xc7\x6f\x13\x31\xdb\xa3\x24\x7d\x2b\xfa\x18\x47\xa3\x7c\x76\xd6\x7a\x22\xb1\xb2\x78\x9a\x67\x47\x38\x6c\x4d\x65\x51\xb4\x3b\x89\xb2\xbc\xec\x47\x85\xe8\xee\x77\xc7\x65\x39\x1b\xae\xad\x9d\x9e\x9e\x0e\x8e\xb2\xc5\x20\x9f\x1f\xad\x4d\xa9\x82\x62\xed\xfb\xc1\x8a\xe0\x4d\x92\xd9\xfe\x0d\xc9\x16\x3c\x9b\xe7\xa2\x1a\x4c\x89\x17\x02\x9d\xf3\xe8\xa9\x20\x88\xdf\xa3\x7f\xe4\x65\x72\x9a\x8e\xfe\x88\xbe\x3b\x81\xef\xbf\x25\xe5\xf1\x38\x79\x2b\xd6\xff\xc9\xf7\x08\x2c\xa8\x25\x89\x36\xd7\x37\x6e\x23\xc3\x6b\xde\x0a\x6a\x04\x58\x56\x46\xca\x63\xa1\xbd\x43\x4a\x0a\x0c\x18\x76\xc1\x10\xa4\x10\x6d\x6d\x40\xa1\xb1\x84\xe0\x5e\x7b\x80\x8b\x0a\xc8\x47\x67\x82\x23\xa5\x23\xc5\xc6\x59\x89\x31\xe5\x20\x8f\x0a\x95\x7c\x29\xc8\x5d\x4c\x99\x55\xa6\xc0\xb4\x10\xf4\x8b\x24\x76\xc6\x38\x17\x29\xc1\xbe\xfb\xa0\x8b\x2a\xd8\x40\xb7\x75\x7f\x11\x38\x2d\xe4\x00\x2d\xce\x5c\xb0\x2a\xfa\xb8\xc3\x4a\x3e\xad\x3c\xc4\x59\xfe\x40\x57\x81\xdb\x08\x01\x7f\xf8\xa0\xb5\xc7\xa8\x02\x7a\x47\xac\xd3\x33\x02\x27\x26\xee\x88\x02\x0f\x81\x3e\x99\x04\x20\x57\x12\x71\x88\x71\x54\xe6\x51\x92\x01\x0d\xaf\x8d\x13\xf8\x47\xb7\x02\xcc\x38\x26\x36\x09\x5c\x49\xca\xb5\xf6\xc6\x4c\x75\xf3\x11\x03\x58\x61\xef\xcc\x98\x24\x80\xf0\x5f\xab\x8b\xc8\xfb\x4f\xc4\xb2\xc8\xc7\x81\x6e\x91\x71\x5d\xac\x80\x88\x24\x97\xdc\x9a\x11\x51\x1e\x17\x91\x2c\xfe\x46\xce\x8c\xcc\x8a\xfe\x86\xbd\x8f\xde\x13\xf1\x9c\x6b\xb1\xfc\x6f\x84\xf9\x42\xe4\xb0\xca\xce\x31\x0b\xef\x2a\x88\x1c\xbc\xd7\x20\x8a\xd0\x67\x0a\xbc\x81\x24\x22\x20\x43\xec\x0b\xec\x44\xc0\xee\x11\x21\x16\x32\xd8\x4e\xcd\xbb\xe4\xe1\x48\xa1\x08\xb0\x59\xd8\xe2\x1d\xc3\xda\x40\x70\x18\xb1\xfa\xbb\xac\x6c\x8f\xd9\x20\x24\x15\x95\x52\x28\x50\x44\x80\x36\x85\xde\xfe\xfa\xc1\x3d\xe2\x9f\x62\x04\xdd\x1b\xbc\x11\x5e\x07\x5d\xd0\xa0\xab\x1c\x9d\x34\x7b\x1b\x0b\x30\x43\x03\x50\xe3\x8d\x61\xd4\x89\x6e\x46\xbc\xf2\x15\x2e\x6b\xf0\x9a\x6d\x0a\xac\xa0\xb4\x68\x36\x8d\xc5\xce\x85\x33\xe4\x4c\x23\x01\x3c\x97\x39\xd5\xb3\x28\xd3\xf7\x0e\x7f\x4b\x46\xe5\xb9\x53\xa1\x9a\x64\x53\x8e\xaa\x1d\x3b\x70\xd5\x53\xc7\xba\xe1\xcd\x5c\x9f\xca\x3b\x02\x17\x4e\x1a\x2b\x26\xa4\x2d\x00\x16\x62\xef\x3e\x82\x1f\xf4\xda\xa1\x46\x48\x19\x28\x01\xd1\xe2\xab\xc6\x4e\xc1\xd1\x80\x2c\x80\xb0\x13\x4a\x9f\x99\x02\x55\x88\xf1\x9a\x6d\x85\x9b\xc2\x5f\xfa\x12\x3b\x45\x15\x7d\x17\x8a\xc0\x8f\x92\x92\xaf\xc0\x42\x72\x0e\x49\xb2\x50\x4c\xf6\x0d\x4a\x58\x35\x08\xa9\x76\xd6\xad\xe2\xb1\x68\x95\x0b\xac\x11\x8b\x77\x52\xcd\x5d\xea\xe9\x3e\x16\x39\x20\xf6\xac\xbe\xf4\x2a\x62\xfd\x91\xfb\xd4\xde\x64\x52\x24\xa5\xd7\x29\x21\x82\x2e\x46\x09\xeb\x57\x3c\x1a\xf5\xa3\x86\xce\x21\x76\x4a\x21\x34\x8e\x04\x3e\xcb\x27\x78\x93\xc8\xa9\x79\xe0\xe6\x77\x03\xfd\x54\x75\xcd\x81\x29\x25\xe3\x97\x7e\x95\x4f\xe3\xf2\x78\x30\x99\xe6\x62\x95\x77\xbd\x16\x6f\x46\x5b\x1b\xbd\x68\x2d\xda\xda\xec\x09\x22\xd9\xda\x94\x83\x66\xe8\x13\x83\x11\x50\x5d\xbd\xe9\x58\x58\xaf\x40\x61\xf4\x03\xdb\xbb\x22\x51\x6d\x34\xb4\x12\x2a\x3a\xab\x50\xdf\x8f\xd6\x39\xf6\x85\x4c\xb3\x98\x96\x8a\x7a\x68\x06\x9f\x8a\x94\xf4\x57\x21\xca\xd1\x9c\x28\x0a\xb4\xfa\xd6\xd7\x74\xd4\xb7\x67\x50\x55\x2e\x47\x48\xf5\xdb\x1a\x5f\x98\xf4\x9d\x56\x43\x6b\xa0\x65\x0f\xd8\x1a\xd1\xc3\x5b\x5d\xbd\x67\x16\x4e\x32\x9d\xc8\x11\xcb\xce\xca\x5d\x21\x9f\x3f\x8e\x85\x62\xe7\x32\xa6\x94\xd3\x16\x70\xfd\xca\xf9\x32\x73\x75\xd0\xe3\x85\x08\x21\xd8\x95\x9b\xbe\xb5\xb3\x6b\x77\x5f\xad\x23\x46\x84\x7a\xed\x02\x15\x8b\xde\x4b\x10\x77\x8e\xb0\x03\x7e\x97\x14\x9e\xf0\xc3\x9d\x2c\xde\x84\xbd\x14\x45\x4f\x13\x79\x0d\x4f\x10\xf1\xa6\x01\x15\xfc\x6d\x2a\xd4\x0a\x7b\x78\x6b\x6b\xd1\x38\xcf\x3a\x25\x98\x89\x23\x59\x4a\xf0\x5f\xab\xca\x81\x50\xa4\x3a\x85\xd0\x15\x84\x3c\x38\x3e\x13\x22\xfe\x22\x13\xea\x56\x05\x96\x3e\xd2\x38\xcf\xcd\x22\x14\x5d\x7e\xb5\xf7\x68\x6f\x28\xf4\xe4\x23\x21\xa2\x46\xa0\xb0\x65\x49\x01\x1a\x20\x68\x69\x67\x85\xcd\xac\x3e\x05\x91\x7c\x3e\x93\x6c\x4f\x46\xc2\x11\xa8\xb0\x42\x20\xde\x32\xd7\x68\x9d\x27\x93\x18\xcd\x31\xa7\xc7\xb9\xd0\xe8\xb0\x87\x62\x7b\xbc\xd1\xc0\x08\x6a\x78\x80\xcb\xf9\xe5\xa0\x85\xe2\xef\xad\x7c\x6b\x91\xab\x39\x69\x14\xf5\x03\x5b\x5c\xd7\x37\x8d\x31\x02\xa2\x86\x85\x5a\x6f\xc8\x5a\x7c\x7b\x73\x4a\x64\xf5\x2c\x3e\x49\xdc\x7d\xc8\xe4\x70\x39\xd3\x2f\x1b\xd8\x7c\xea\xf7\x33\x53\x71\xa0\x4e\xcd\x17\x25\x06\x8d\x54\xab\xfe\x6a\x86\xad\x2a\x99\xcd\x93\xb7\x69\xbe\x28\x74\x87\x36\xef\x01\x4a\x84\x28\x28\x94\x55\xaf\x44\x13\xfe\x59\x7f\x43\x0d\xc2\x1f\x18\x32\xf0\x92\x70\x2a\x1a\xdb\xb8\x27\xfe\xf9\x4e\x0d\x40\xdd\x17\x8e\xd2\x9b\x37\xab\x8a\xc3\x9f\xd3\x67\x41\xda\x37\xbb\x0a\x07\x69\x74\x2b\xda\x38\x00\x09\x5f\x28\x88\xd1\xfa\xbd\xca\x4a\x6a\x58\xb9\xa4\x87\x9b\xa2\x6f\x5f\x57\xcd\xdc\x4d\xb7\x17\x20\x1c\x54\xb1\x7d\xf5\x77\xee\xa5\xda\x29\xe7\xbd\x6e\xcf\x99\x42\x31\x17\x93\x74\x2e\x24\xd0\x64\x9a\x9c\x24\x59\x09\xfa\x15\xa1\x49\xac\xa3\xdf\xd3\x99\xe0\xc6\xcb\x4c\xb9\x85\xfd\xf5\x10\xf6\x01\x7f\xb5\x33\x80\x57\xe7\xc7\x82\x02\x44\x23\xf1\x54\x2f\x72\x89\x4f\x8f\xff\xf8\xf8\x0e\xf3\x45\x43\x3a\x15\x0c\x42\x4c\xed\x4d\x31\xb5\x8a\x4f\x88\x0f\xaf\x1b\x01\xb4\x37\x22\xd8\x61\x7e\x01\x48\xb9\x55\x06\x68\x9f\xa8\xe2\xc2\xac\xe7\xb3\x66\x2a\x20\x6c\xd9\x98\xba\xe4\xe2\x6f\xa0\x4c\xd5\x01\x8f\x21\xad\xd7\x31\x24\xf8\x6b\xa6\xe9\x46\x8e\x22\x1a\x18\xc5\xd3\xd1\x02\xec\xcb\x4a\xf0\x01\x95\x4f\xf6\x45\x2c\xb2\xe4\xe4\x12\xec\x08\x58\xd1\xfe\x5f\x88\x29\xf5\x5c\xd8\xf3\xa5\xf6\x95\x4b\x4e\xc8\x9f\xc7\x60\x38\x73\xf9\xe8\xbc\x85\xe1\x55\xc2\xc9\x7e\x34\x58\x43\xa4\x2d\x52\x9e\x4c\xe6\x35\x16\x23\x82\x6c\x6d\x31\x52\xe9\xfa\x54\x53\x9b\x44\xc2\xb6\xa4\x6a\x3b\x08\x6b\x38\x20\xfe\x61\xfd\x21\x1b\x11\x2b\x66\x6c\x44\x04\x8d\xb2\x4d\x1b\xb4\xd4\x1a\x89\x2a\x10\x52\x65\x23\xaa\x46\x88\x2c\x81\x1a\x06\xb6\x66\x10\x53\x6f\x21\xe2\x43\x0c\x69\xc7\x16\x6e\x96\x37\x10\xa9\x52\x44\xc5\x1c\x9e\x88\xb8\x08\x6a\xe1\x8e\xba\x7f\xc5\x16\x25\x1a\x72\x17\x47\xa6\xd6\x97\x31\x8b\x58\x76\x11\x55\xa3\xb1\x30\xd5\x99\x1c\xcc\xa8\x1a\xed\x0c\x1c\xa3\xc4\x81\xa0\xcc\x55\x8f\xb4\x8d\x39\x4a\x6b\xa2\x21\x39\x58\x76\xad\x92\x49\x8a\x6d\xaa\x58\x9c\xd0\x09\x5d\x60\x97\x92\x22\xa2\x86\x97\xd5\x09\xbe\x06\x5c\x51\x7f\xe1\x96\x14\xe2\x3f\xaa\x79\x1b\x11\x4a\xda\xf4\x50\x20\x7a\x33\x4f\x4e\xf2\xb7\x78\x8c\x19\x8d\x16\xf3\x39\xc8\xa7\x5a\x38\xcd\x31\x59\x76\x53\x48\xad\xd0\xf3\x40\x6f\x8b\x65\x2c\x7e\x0a\x99\xad\x2d\x7f\xd6\xc8\xc4\xc0\xdd\x72\xad\x29\xed\xa5\xb3\x0e\x2b\x8e\x75\x82\x5a\xab\xe2\x71\x01\x2a\xe3\xe7\x1d\x9c\xac\x60\x07\xe3\x87\x24\xf6\xf9\x05\xf5\x16\xda\x1a\xc9\x53\x26\xee\x6a\x81\xbd\xef\x92\xbf\xaa\xeb\x82\x21\x8f\x45\xbb\xbd\x7e\x30\x1b\x4e\x41\xab\xf2\x40\x08\xa9\xc8\x7a\x5d\x93\xc7\x0f\x37\x2b\x9b\xad\xcb\x24\x2e\x5c\x95\x0b\x07\x95\x95\xdd\xa2\xcc\x15\x20\x08\xdf\x7f\x03\x51\xa7\x7d\x36\x94\xe3\xec\x70\x1b\x1c\x36\x30\x92\xc2\xf0\x36\xfe\x84\xbe\xad\x0e\xef\xc0\x6f\x7e\x1c\xbb\x3a\xbc\xdb\x0f\xf9\x7a\xa4\x70\x45\x62\x63\x1d\x7e\xc2\x89\xa9\xf8\xbd\x09\xbf\xe9\x54\x56\x7c\x6d\xc1\xd7\x82\xa0\xb0\x81\x85\x04\xbb\x73\x7e\xd0\xbf\xfb\x29\xfd\xa2\x1a\x8e\xa1\x2f\xe6\x4d\xc4\x2b\x59\xc6\xa9\xc8\x2e\xe7\xfa\x16\xf1\xdc\x25\x5d\x8c\xc2\x45\x6b\x3c\x8d\xec\x9e\xb4\xa9\xeb\x12\x7e\x47\x15\xce\x46\xad\x1a\x65\x47\xe2\xc1\xe9\x52\x6c\x67\xbe\x48\x5a\xb8\x30\x39\xc3\x6e\xf6\x64\xfa\xf6\xda\x93\xe9\xda\x93\xe9\xbf\xc5\x93\xc9\x2c\x84\xab\x72\x67\x7a\x90\x1e\x3d\x5b\x9c\x1c\x22\x2b\xd4\xdc\xf9\x30\x15\x9d\x81\x44\xd1\x8a\xe2\xe4\x8b\x52\x20\xc9\x62\xe1\x62\xcf\x80\x34\xfa\xbf\x02\x1b\x05\x41\x04\x9b\x9b\xa4\x9e\x33\x90\xd2\xcc\xd8\xae\x80\xba\x0b\x6e\x0b\x6a\xe0\xc4\xab\x05\x35\x02\x5b\x8f\xf0\xc2\x06\xe8\x59\xc0\xb7\x8c\x97\x2c\x2e\x05\x98\x1b\x34\xce\x7c\x0d\x38\x26\x48\x41\xc3\x59\x72\x24\xb2\xdf\x8a\x85\xa0\xc2\x64\xc2\xc1\x51\x79\x9a\x8b\xff\x0b\xcc\xcc\x94\xb4\x8a\xa5\x60\x6e\x75\xc9\xc9\x34\x17\x25\xc5\x0c\xce\x72\x51\x6f\x9f\x8e\x43\x81\xec\xc7\xf9\x69\xe6\xe8\x74\xb6\x99\xc4\x57\xdf\x3e\x10\x96\x3f\x68\x7c\x9f\xab\xb1\xc0\x52\xca\x12\x38\x45\x87\x61\x99\x39\x1e\x87\x9d\x61\x10\x69\xe7\xda\xcf\xc7\x6e\xd7\x62\xc0\x58\xbf\xe2\xc2\xba\xdd\x01\xcd\x45\x77\x34\x78\xfc\xea\xe7\x37\x0f\x76\x05\x25\x3f\x7d\xf0\xf8\xc5\x9b\x17\x10\xed\x73\xf7\xd9\x4f\x6f\x9e\xee\x3d\x7a\xcc\x74\x38\x6d\x89\xc3\x99\x1c\xcc\xe2\xf1\x93\x64\x52\x76\xe9\xab\xcc\x5f\x9d\xe6\xc5\x43\x8d\x45\xd9\xa6\x48\x97\xe2\xd2\xc6\x9d\x5e\x3f\xba\xb3\x6d\x9f\xf0\xf0\xdd\x12\x87\xd3\xa5\x46\x6c\x07\x0c\x7b\xe2\x95\xf2\x5b\x81\xf3\x07\x5a\x37\xb6\x95\xe6\x65\x71\xe8\x4b\x1d\x16\x16\x03\x08\x29\xf3\x9f\x93\x77\x6a\xdc\xc5\xe2\x50\x30\xcc\xee\x26\xc3\xdf\xd4\x39\xda\xa7\xe2\xca\xca\x7d\x33\xba\xb3\x05\xe7\xfa\x0c\x45\x2e\xba\x5f\xa4\x47\xc7\xa5\x2c\xd6\x17\xd5\x7d\xfd\x91\xf1\x29\x77\xe0\x2b\x45\x6b\xa5\x4c\x77\x69\xec\x2a\xf5\xcc\x46\xab\xb6\xce\xfd\x69\x33\xe0\x98\x4d\xa9\xb1\xde\x80\xd6\xfc\xcd\xa8\x79\x82\x9a\x38\x1d\x49\xf2\xd5\x2b\xe2\xa5\xca\xbf\xec\xdc\x69\xe7\xce\xf6\xb3\x06\x17\x0e\x5f\x97\x93\xbb\xd7\x13\x17\x98\x38\x79\xcf\xa8\x8a\x91\xc9\x5b\x48\x6a\xd2\xe0\x3b\x89\xb3\xe5\x19\x99\x7b\xe5\xa8\x7a\xce\x3a\xeb\x97\xfb\x03\xd7\x43\xaa\x3e\xfa\x21\x8a\x3a\x1b\x9d\x68\x28\xea\xec\x5c\x9e\x47\x35\x61\x12\x34\x56\x28\xf5\x0b\xc0\x15\x11\x08\xc6\x27\xe0\xf9\x43\x42\xe5\xe1\x59\xb4\xf9\x9f\x13\x10\xcf\xb5\x0f\x5d\x0c\x35\x97\xc9\x91\x90\x9f\xaa\x67\xe0\x85\xac\xb5\x69\xff\x5e\x76\x46\xa4\x2f\x73\xc5\x8c\x48\x34\x39\xd4\x07\x58\xd3\x2d\xea\xcd\x35\x3d\x11\x2a\x9b\x9d\xb5\xd9\x1b\xcc\xf2\xd3\xee\xc6\xe6\xdd\x5e\xcf\x46\xe9\xc3\xe3\x64\xf4\x3b\x1c\x9a\x71\x9c\x32\xb1\xc8\x41\x44\x21\x44\xbf\x64\xbc\x5b\x3c\x33\xd9\x9e\x21\x5a\xd7\x71\x9c\xbc\x93\x3d\xb6\x91\xa1\x88\x16\x95\x3e\x6c\xbb\xb4\x25\xb1\x1c\x54\x96\xd3\x14\xc4\x70\xb8\x99\xab\xd1\xe5\xb6\xde\x88\xaf\x10\x86\x14\xbb\x59\xef\x47\x1b\x42\xfa\x10\x22\x88\x91\x47\x36\x7b\x56\x6e\x2f\xba\x7f\xff\x3e\x90\x6c\x90\x0a\xe7\xc0\x3e\x6e\xc5\x53\xec\x54\x44\xa6\x03\x73\xe0\x41\xa2\xa6\x4f\x44\x64\x48\x70\x85\x40\x8b\x3c\x3c\x3f\x58\xc0\x99\x11\x0c\x6b\xda\xd5\xc2\x21\x2e\x8b\xf4\x28\x22\x39\xdd\xa1\x37\xdd\x05\x0b\x7f\x96\x53\x2c\x00\xd3\x3c\xde\xa7\xde\x30\x5b\x66\x17\xbc\xda\xa3\xd5\xf5\x55\x69\x23\x86\x63\x49\x4d\x45\x20\x3c\xab\x89\xd4\xad\x13\x50\x5a\xd2\x44\x6b\x49\xdb\x17\xb2\x23\x79\x7e\xeb\xcc\xb3\x9c\xdb\x80\x09\x32\x30\xbf\x34\xa5\x27\x69\xb6\x70\x57\x41\x67\x72\xc9\xbf\x0e\xd6\xad\x2a\xdf\xd0\xc7\x63\x2d\x3a\x74\x01\x0a\x5a\xd4\x93\xd0\xeb\x5a\x1a\x0a\x51\x4f\xb2\x14\xf9\xc8\xe6\x7d\xc2\x79\x7d\x15\x94\xf3\x71\x50\x26\x59\x7e\x15\xca\x3c\xde\xdd\x88\x32\xc4\x18\x13\x89\x6d\x14\xc9\xe6\x7c\x14\x79\xcc\x3c\xe4\x71\xee\x2c\x46\x09\x33\x18\xa7\x6f\x53\xb1\x1b\x3d\x38\xab\xe1\xe1\x17\xa1\xa6\x06\xdc\xbc\xbe\x6a\xe4\x2c\x2a\xb1\xf3\x7a\x69\xf4\xbc\xbe\x0c\x7e\xfc\x53\x58\xaa\x5a\xa3\xa8\x4a\xe2\x32\x17\xa6\x5b\xe3\x45\xed\x6c\xf6\x5c\x54\xe2\x48\x36\xed\xa3\xc8\x93\xcf\x42\x18\x0a\x2c\x2f\xdc\xaf\x2e\x29\xb0\x09\x09\xed\x07\xda\x9a\x65\x64\x8c\xe5\xb0\x59\xa9\x39\xb2\x7b\xb9\x35\x7b\xdf\x34\x99\x18\xc4\x81\x04\x51\xa3\xdb\x78\xa2\x07\xd8\xae\xe9\x82\x0f\xfc\x72\x44\x30\x09\x03\x75\xea\x1a\x02\x98\xf7\x94\x50\x6c\xa3\x1f\x71\x63\x39\x14\x92\x57\xac\xc1\xbc\x5f\x71\x53\xf7\xeb\xde\x1a\x53\x69\x8a\xf4\x0f\xc9\x13\x0b\x3c\xa5\x92\xe5\xf7\x37\x0e\x6c\x51\xb8\xb3\xfe\x0e\x44\x66\x7f\x72\x07\x05\xd8\xfe\x40\x32\xd9\x14\x03\x83\xea\x96\xa4\xf3\x86\x99\xe1\x5a\xf8\x95\x4d\xd0\xb2\xe8\xaf\x34\x05\x78\x9b\x8c\x56\x11\x1d\x3e\x40\x88\x93\x87\x60\x2e\xe6\x84\x82\x27\xf7\xf0\x32\x97\xf0\x70\x63\x62\xb3\x7a\x06\x9c\x8a\x48\x3a\xbc\xb3\xdd\x97\xed\x2f\x37\x05\x35\x5a\x39\x0d\x3f\xa0\x96\x5f\x29\xf6\xe3\x62\x94\xa6\x75\xf8\x0f\xa8\xf3\x9f\x10\xf3\xca\xaa\x83\xd6\x81\x76\xf8\x5f\x6e\x02\x4c\x78\x9a\xaa\x19\xd8\x31\x01\x6c\x2a\xa6\xa0\x92\xb7\x57\xa0\x5c\x57\xe8\x63\x3b\x14\xc0\x66\x09\x69\xca\xc2\xdd\xea\xfa\xbb\x55\x41\xb5\x52\xc7\x41\xb4\xd3\x6f\xed\x56\xb0\x2d\xa6\x81\x27\x55\xc5\x0c\x78\xaf\x5c\x3f\x98\xae\x39\x74\xbe\xfb\x2e\x0c\xae\xd8\xa1\x97\xe2\xc1\xf1\x05\x3e\xac\xca\xf0\x4a\x11\x32\x87\x7e\x92\xdf\x0f\xb1\x73\x0e\xdd\x04\x0f\x0a\x24\x90\xa1\x9b\xc0\xa1\xb4\x58\x36\x74\x13\x7c\xa8\xd7\x1e\xd8\xeb\x20\x1c\x6f\xd4\xa4\x04\xea\xf3\x01\x5f\x87\x21\xf9\x60\x4d\x4a\x00\x8e\x63\x9b\x25\xf9\x90\xa1\xe9\xf1\x73\xfc\x72\xf6\x04\xf1\x34\x1f\x56\x52\xfd\x30\xb8\xee\xce\xd5\xb1\xae\x7d\x38\xb4\x3a\xdc\x00\x4f\x06\xeb\x50\x69\x75\xb8\x89\x1e\x0c\xb8\x30\x04\xc0\x46\x7f\x95\x1f\x4d\xad\x0e\xed\xcf\xf3\x83\xfe\xc6\xfa\x9f\x1c\xd2\x65\x97\x7c\xe3\x6b\x62\x10\x09\xad\xab\x2a\x04\x91\x3c\xbd\x12\xff\x50\x74\x16\xf8\xb1\xad\x7f\x1d\x98\xc4\x2d\xf6\xdb\x09\xde\x22\x92\x28\x74\x8b\xf8\x71\x67\x5b\x83\xdd\x35\x15\x6d\xde\xbe\x53\x51\x17\xc0\x37\x84\x32\x72\x55\xc3\x8f\x18\x8d\x0b\xc1\x5d\x37\x04\x91\xb8\xa4\xe7\x85\x55\xa2\xc6\xe1\x02\x9b\xab\x29\x79\x21\xf7\x0a\x81\x54\x25\x2a\xfe\x70\xa1\x90\x2e\xd4\xab\x66\x37\x88\x8d\x56\xaf\xd8\x5d\xfb\x41\x5c\xfb\x41\xfc\x75\xfd\x20\x22\xe3\x08\x41\xa2\xd2\x15\xf9\x40\xb4\x70\x6d\x70\x59\x3d\xb9\x2e\xe4\xe8\x90\x6e\x22\x77\x0c\x02\x12\xea\xe9\x71\x92\xe9\xfb\x8a\x7d\xf2\xfd\x06\x01\x5c\x07\x70\x50\x92\xe5\x5a\xd0\x37\xc2\x31\x7f\xbb\xd7\x13\x91\x93\x2a\xf9\x91\xfe\xfd\xf0\x21\xea\x74\x18\x9f\xcd\xd5\xcd\x05\xfa\x71\x8f\x5d\x35\x14\xa4\x4c\xad\xb7\x8e\xf8\x71\x94\x94\xdc\xe5\x17\x1d\xc8\x3b\x85\xba\x08\x8a\xbc\x04\x2a\xe1\xb2\x37\x93\xef\xc9\xd9\xd5\x96\xa2\x95\x99\xc9\xd4\xca\x2b\x8b\xba\xaa\x8f\xbd\x15\xee\xd0\x8e\xe8\xe1\x0e\xed\x6e\x23\xb5\xae\x68\xe8\xe5\x6f\xa9\x1d\xfc\xf8\xb1\x35\x32\x46\xf3\x04\x88\x49\xad\x07\x3b\x2c\x0b\x91\xbb\xe0\x85\x93\x04\x1d\x92\x09\xe5\x8e\x5e\x72\xaa\xef\x85\x70\x75\x44\xa1\x44\xce\x92\xf2\x5d\xce\x82\x4a\x88\xad\xba\xc0\x76\x18\xea\x47\x3c\x23\x0e\xa3\x7b\x51\x8d\xca\xd3\xf0\x9d\x59\x9b\xee\x2a\x4f\xf5\x0c\x41\x6a\x52\x5d\x06\xa3\xf9\xc9\x61\x9a\xf9\x11\x6e\xca\x5c\x10\xde\x31\x9e\x73\x44\xc9\xd1\x80\x16\x95\x18\x53\x92\xe1\x5a\x12\xbb\x11\xde\x81\x70\xb0\x2b\x6b\x6b\x3a\x87\x91\x8c\xe9\x38\x1d\x01\x7b\x52\xbd\x6a\x2e\x2c\x0f\x50\xc5\xcc\x13\x2c\xee\x43\xb5\xa8\x55\xc3\x6b\xb2\xfb\xd5\xa1\x55\xdb\x2d\xe8\xc8\xe4\x5e\xd4\x8c\x5d\x78\x9f\x57\xe2\x57\x1d\xe3\xd0\x88\x8e\x63\xb3\x74\xc1\xdc\x44\xc1\x05\xf1\xb8\x4e\x64\xf5\xe1\x5f\x45\x68\x18\xfe\x37\x70\x6e\xc7\xf1\xad\x7d\x41\xc3\x36\xd3\x5a\xd4\x84\xa3\x33\x85\x16\xae\x5c\x15\x37\xee\xdf\x47\xe9\x62\x22\x10\x3a\x6e\x41\x62\x06\x09\x26\x6a\x91\xac\x4a\x9e\x30\xbb\x46\x03\xca\xdd\x29\x1e\x54\xab\xfe\x64\xf1\xf5\x2d\x34\x2d\x96\x99\x55\x7b\xdd\xa0\x6f\xe0\xa8\x4d\x08\x80\x5e\xf4\x83\x60\xf0\xd1\xb0\x9d\x43\x16\x43\x59\xd0\x2d\x6b\x09\xbc\x01\xef\x27\xe3\x84\x96\x19\x43\xd7\xbd\x8c\xfd\x22\x8c\x33\xb5\xf7\xa8\x53\xe1\x98\x33\x7c\xb0\xc3\x1a\x22\x43\x12\xaf\xc5\xa2\x69\x2c\x88\x42\x8c\xab\xe4\xe2\x2f\x14\x9f\x49\x2f\x2f\xb1\x9e\xf0\xc8\x2f\xaa\xa0\x3b\x39\x61\xdd\xe5\x51\x67\x6d\x6b\x15\xb8\x63\x9b\x52\x18\x79\x2a\x21\x56\x87\xf0\x2d\xb0\x98\x9c\xcc\x04\x27\x65\x26\xc1\x16\x9b\x68\xe3\x2a\xb4\xe9\x91\xb1\xa7\x21\x4a\x1f\x4b\xe0\x46\x45\x9c\xaa\x8c\x35\x15\xc4\x44\xed\x40\x64\x95\x4d\x63\xb0\x0e\x56\x6e\x06\xc4\x82\x8b\x8c\xc3\x5c\xc6\xab\x8e\x0f\xf5\x44\xa8\x13\x7c\x63\xc1\x7b\x7c\xfb\xd6\x68\x0e\x02\x41\xa1\x6a\x47\x6f\x6a\xf6\xb7\x17\x7d\x2f\x48\x9d\xd4\x2f\x66\x63\xf2\x6c\x95\xf7\xe0\xb4\x29\x52\x20\x90\xdd\x75\xa5\xc2\x4a\x38\xa2\xb8\x43\x0a\x92\x4a\x3e\x57\x57\x08\x25\xa8\x7d\xad\xc8\xda\xdb\xf4\x7a\xa4\xed\xab\x62\x41\xb2\x39\x42\x38\x37\x58\x88\xdd\x3c\xdc\x6f\x32\x3d\x36\xb0\x7c\x18\x37\xef\xfb\x86\x7e\x79\x5b\xcb\xbf\xa1\x45\x90\x46\x04\xea\xe6\xfc\xd6\xad\xbe\x35\x27\x50\x28\xa7\x5b\xe8\x70\xf3\xb3\xf2\x18\x63\x91\xb0\x7a\x38\x76\xfc\xc0\x53\xca\x23\xcd\xc3\x8f\x75\x53\x37\x7c\x42\x21\x7b\xd2\xbf\xda\x26\x7c\xa3\xb3\x68\xa1\xd3\x51\xc6\xf7\x1a\x23\xc5\x73\x9a\x25\xc7\xa6\xa7\xcd\x77\x60\x9a\x68\xf5\xd6\xde\x47\xb4\xc9\xe1\x69\x74\xbd\x51\x0e\x8e\xb2\xab\xac\x72\xca\xcd\x0c\xfe\x25\x53\x19\xfe\xda\x36\x3f\x0f\x58\xf2\x16\xff\x70\x6c\x73\x90\x46\xc6\x39\xf8\xa5\xac\x73\xf0\xfb\x2e\xab\x8e\xd9\xe7\xbc\x1a\x5b\x58\xe8\xbc\xb3\xf7\x65\x4c\x74\x50\x78\x19\x1b\x1d\xc1\xbb\x46\x3a\x48\x5d\xd2\x4a\x67\x17\xa9\x31\xd3\x51\x8b\x75\x65\x2f\x62\xa8\x03\xdc\x56\x18\xea\xda\x05\xca\x97\xdd\x6a\x61\xa8\x6b\xf5\x9a\xd7\xc7\xba\x5c\x17\x38\xfd\x5b\x86\x3c\xa8\xf8\x32\x04\xa2\x4a\xb8\x24\x42\xe9\x4b\x12\x89\x5b\xa8\x86\x4c\x54\xbb\xf5\xe5\x2f\x64\xd3\x25\x49\xaa\xcd\x9d\xb9\x40\x7b\x57\x7b\x5b\x4e\x8f\xb2\x05\xdd\x5d\xfd\xeb\x23\xca\x46\x15\xbc\xbf\x13\xe0\xc3\x2c\xc4\xad\xe0\xc6\xad\x63\xdb\x8e\x92\x79\x09\x71\x0d\x24\x7b\xf6\x39\x9e\x85\x48\x3a\x4d\x6a\x20\x6a\x02\x1a\xd8\xe9\xf5\x64\x2d\x8b\x38\x19\x8d\xd1\x20\xc0\xec\x0d\x2a\x7f\x3a\xc1\x9a\xed\x7e\x8f\x65\xd4\xda\xe8\x28\x05\x53\xb0\x74\x69\xb1\x55\xea\x57\x15\xe1\x31\x1c\xff\x17\x52\xb3\x8d\xc7\x2d\x62\x99\x2a\xf7\xda\x0d\xfb\xdf\x72\x88\xf6\x4b\x84\x82\xd3\xae\x96\x41\xe1\x38\x17\xc2\xd1\xfc\x74\x9e\x0a\x4c\xa1\xbb\x17\xf5\x0a\x0e\xb1\xa1\x8a\xd6\xb8\x3b\x45\x2b\x7b\xc1\x2f\xf2\xa3\x17\x84\x51\x45\x05\x75\x10\x0a\x25\x8d\x84\xf5\x51\x66\x7e\x6c\x42\xa6\x6f\x95\x74\x9a\x33\x56\xdb\x0a\xbc\x05\x42\x08\x84\x31\x28\x64\x49\x30\x85\x83\x63\x53\x5a\x60\xd4\x43\x69\xcd\x82\xf1\x02\x27\x48\xe8\x8c\x61\x9a\xfe\x2e\xb4\x8b\xa8\x10\x1d\x86\x08\x78\x32\x0e\x17\x42\x0e\x2c\x97\x68\xa4\x60\x0a\x33\x43\x61\x39\xa8\x35\x08\xfe\xd3\xd9\xdf\x38\xe8\x1c\xf4\xb4\x30\xd8\x10\x06\x40\x76\xcf\xc6\x3b\x7c\xf1\xd0\x86\x15\xa2\x3b\xf9\x40\x11\x2a\xd0\x57\x61\xa3\x1f\xdd\x42\x7f\xec\x75\xec\xcb\x06\x0f\x44\x63\x3a\xe4\x09\xb2\x2a\x50\x43\x5f\x85\x76\xa8\xd2\x16\x54\x40\x87\xaf\x15\xa0\x69\x40\x8c\x21\x86\x03\x9a\xb8\x10\x33\x88\xf1\x0f\xf0\xb2\xc0\xd6\xa6\x34\xe0\xc0\xf1\x84\xe9\x4d\x1f\xa2\x15\x2d\xb5\xb0\x25\x47\x53\x1a\xb8\xb2\x45\x46\xc9\x11\x0a\x90\xf8\x28\xd4\xfe\xc1\x6a\x74\xff\x7b\x5c\x9f\x26\x6d\x9b\x12\x6b\x8d\x69\x3b\xaa\xb6\x65\x39\xc0\x71\x52\xd9\xb3\x9a\xd5\xce\x5b\xad\xa4\x59\x13\xf6\x4b\x05\xa2\x08\x13\xdd\x3d\x67\x1b\x65\x45\xbe\xfa\x2a\xe2\xdf\xfb\xec\x37\x0b\x01\x77\xa0\x76\x5d\xfd\x32\xc6\xda\xd1\x85\xe6\x46\x2e\xdf\xba\xa9\x51\xb3\x60\xcf\x8d\x9c\x30\x7b\x6a\x58\xc4\xb5\x4b\xce\x8c\xd3\xaf\x9a\x89\x61\x6d\x7e\xec\x79\xb9\xca\x89\xb1\x43\x9f\x18\x46\xca\x66\x02\x75\xa3\x55\xf4\x45\xd8\x24\xa4\x93\x92\xb4\x2a\x9d\x15\x36\xe4\x54\x6c\x6c\x6b\xc0\xcd\x03\xa1\x16\x11\xa8\x4a\x23\x10\x0d\xb1\x71\xe0\x24\x98\x6f\x7f\x77\x40\xac\x5e\x60\x7b\xe0\x63\x91\x43\x6c\xde\x13\x58\x63\x57\x34\x91\x60\x56\x61\x59\x8c\x43\xba\xfc\xf8\x82\x13\x8b\x0c\x3b\x74\x1b\x62\xa3\x66\xca\xe5\x26\xa1\xb4\xea\xd0\x3c\xe3\xbc\x85\xa6\x7a\x55\xbf\xbf\xe7\x4d\xb8\xfc\x5c\xb5\xe6\xdd\xaa\x68\xdf\xae\x9c\x4f\xb7\x89\xbe\xd6\x6a\x9e\x75\x06\x0d\x45\xe6\x57\xce\xaf\x15\x45\xb1\x72\xb7\x97\xd1\x8a\xa6\x71\x51\x46\xfb\x07\x20\x4c\x50\xbd\x17\x9a\xf6\x1b\xe1\x79\xd7\x73\x80\x72\x16\x4d\xdb\x8a\x93\xce\x6e\x42\xe1\xa7\xa6\x81\x36\x44\x52\xe3\x1c\xcb\x54\x18\xcd\x81\xdd\x93\x26\xb8\x9d\x37\x4e\x26\xf1\x62\x8a\x86\xd0\x62\x01\x72\xaa\xde\x98\x57\x65\x98\x9a\xbe\x7c\xe6\xd1\x9d\x45\x4b\x8d\xba\x00\x03\x36\x3b\xe2\x92\xa2\x70\x4b\xed\xad\xd2\xa9\x5e\xc5\x6a\x57\x36\x62\xb6\x44\x0a\x77\x8d\x20\xc5\x4b\x52\xde\x5f\x05\x8a\x17\xe8\x80\x45\x00\xff\x1c\xac\x1e\x18\x6a\x97\x10\x2c\x0d\x0b\x65\x8b\xa9\x7b\xed\x81\xcd\x66\x2b\xb4\xb9\x01\xe6\x9c\xfe\x36\x2c\x04\x45\xc3\x35\x2b\x81\xf6\x06\x19\x2c\x8f\x66\xbd\xc0\x13\x5e\x18\x8e\x35\x5e\xf8\x3f\xae\x7a\x87\x88\x25\xb7\x12\xea\x38\x69\xe3\x42\x19\x6f\x16\x11\xa4\xf1\x56\x47\x7b\xa8\x3e\x77\x90\x18\x52\xa1\x07\x15\x74\x7b\xd6\xa1\xee\x9e\xfa\x67\x59\x0e\xce\x02\x87\x16\xf2\x1c\xdd\xdd\x18\x82\xf1\xa8\xed\x53\x19\x19\x0a\x55\x05\x41\x75\xd9\x42\x43\x34\x58\xed\xff\xcd\x9d\x89\xef\x45\x95\x7f\xe1\xc0\xa8\x7e\x5c\x59\x08\x27\xc6\xe2\xc9\x4a\x56\xe6\x15\xd2\x37\x93\xf7\x0f\xee\x39\xa2\x3e\x5e\x00\x9f\x2d\x8a\xe3\xae\x17\x99\x54\xdd\xd4\x56\x61\x46\xfd\x9a\x61\x2c\x13\x2f\xe6\xfa\xdb\x50\x00\x50\xde\x12\x8b\xe3\xd9\x7d\xdb\x67\x43\xea\xb9\xe1\x49\x2f\x15\xd4\x57\x4e\x20\x0f\xe6\x7b\xe9\xf9\xc3\xae\x7b\x52\x87\x44\xfc\xa7\x9f\xbf\x50\x44\xd6\x86\x48\xac\x95\x13\x01\xb3\x89\xa1\x52\x6b\xe6\x63\xe9\xd9\xb0\xf6\xc8\x90\xed\xc8\x5a\x92\x2c\xa2\x6d\x9b\x98\xa0\x74\x3e\x0a\xb1\x11\x82\xee\x06\x04\x15\xe2\x2d\x87\xee\x85\x1d\xc7\x41\xc8\xf2\x0c\x5a\xe2\xc2\x94\x62\x6a\xd4\x72\x0b\x16\x25\x07\xc2\x59\x94\x15\x4f\xb3\x81\x55\x7d\x14\x5e\x85\x7b\x13\x3f\xb1\x24\x41\x57\x5e\xf1\xc1\xee\x29\x41\x47\xa2\xeb\xeb\x68\x13\x9d\x1f\x7a\x2a\xa2\xb3\x44\x5e\xd5\x22\xaa\xad\x93\x37\xef\x55\x1c\x5a\x51\x58\x40\x2c\x72\x3c\x63\xe7\xa5\x6f\xc2\xf1\x2a\x9a\x2a\x70\xb7\x2d\x00\x44\x88\x43\x13\x30\x3f\xaa\x45\xc2\xfb\x7b\xa1\xb5\x1a\x2e\x13\x8a\xdd\x6d\x38\x07\x3b\x30\xe5\x31\xbb\xa3\x5a\xec\xc9\xa7\x63\x9e\x55\xd5\xcf\xe3\x7a\x54\x86\x91\x76\xdf\xbc\xf1\x1a\xe1\x8c\xa6\x25\xa7\x81\x83\xb4\xba\xbe\x01\xec\xcd\xfb\xf5\x30\xad\x18\x95\x1d\x70\x96\x4d\xfb\xcd\x28\xbd\x5a\xae\xf5\x59\xec\x21\xfc\xaf\x25\xf5\x2b\x87\xd4\x96\x7f\x9f\x15\xf1\x5f\xd3\x3e\xfb\xfb\x53\x68\x1f\x7f\x79\xa4\xcf\x1f\x68\xbc\x28\xe9\xbb\xcf\x88\x2d\xb9\xa9\x78\xc4\xea\xd6\xdf\x6e\x67\xb1\x7b\xb1\x4c\xfd\x72\xfe\x82\xf4\x36\xf5\xe8\x2b\xbc\xfe\xaa\x97\xf0\x4c\x9e\xfa\xf9\x4e\xaa\x4d\xdd\xef\x46\x1b\x10\x41\x89\xf7\x0e\xa8\x1b\x2f\x8e\xae\xf7\x5c\x51\x9f\x45\x20\x76\x0e\xdd\xac\x0b\xdb\x41\xb2\x30\x8f\x92\x69\x5c\x3b\x1e\x5f\x16\x17\x5b\x59\x72\x6c\x7c\xce\xf5\xca\xda\xec\xd9\x66\xd5\xe0\x41\xa2\xd3\xf5\xc6\x03\xde\xea\xa3\x5d\x7d\x27\x0e\xce\x76\x6f\xff\xc9\x1e\x36\xaf\x9b\xaf\xbd\x2d\x6a\xee\xbd\xc9\x48\x24\xf8\x2f\xf9\xba\x2c\xcc\xd5\xb7\x05\xbb\xfb\xb6\xe0\x17\xd6\x16\x81\xdb\x6f\x0b\x7d\xfd\x6d\xc1\xee\xbf\x2d\xd8\x05\xb8\x85\x7d\x03\xce\xab\xb1\x85\x87\x8d\x17\x1f\xe5\x23\x5e\x82\x7b\x1d\xbc\x05\xf7\x7a\xf9\x6b\x70\x76\x91\x1a\xcf\x89\xd7\xfe\x45\x38\xa7\xec\x85\xbc\x26\x16\x97\xbe\x0a\xf7\xba\xf5\x5d\xb8\x3b\x7f\xf6\x72\x68\xe1\x71\xb6\xa8\x73\x39\x53\xa1\x55\xe8\x87\x24\x4e\xe6\x75\xb6\xe0\x6e\x67\x0b\xcb\x4b\x6c\x11\x72\x3c\x5b\x18\xcf\xb3\x05\x77\x3d\x5b\x70\xdf\xb3\x85\xe3\x7c\x16\xa8\xb7\xcd\xe2\xf8\xa4\xfe\x67\xaf\xc3\x0e\x68\xaf\x2f\xe0\x81\xf6\xba\xb5\x0b\xda\xeb\x80\x0f\x9a\x5b\xfa\x62\x6b\xa4\xc6\x0d\xad\xed\x22\x69\xef\x88\xf6\x4d\x9b\x55\xd2\x59\x08\xf9\x0a\x0c\xb3\xa3\xb2\x43\x0f\xf2\x1d\x81\xf9\xf4\x6d\x34\xce\x13\xf4\x56\xc0\xdb\x81\x10\x64\x0f\x62\xd8\x46\xff\x7c\xfa\xe4\xe7\xb2\x9c\xbd\x10\xd5\x09\x21\x6f\x05\x05\x33\xd1\x64\x3e\x71\x72\x28\x8e\x8d\xbe\xbf\xd1\x51\x78\x91\x0d\x0f\x5c\xe8\xe8\xbd\x18\xb9\xf5\x58\x64\x25\xa4\x9d\x80\x92\xfa\x6f\xc5\x31\xec\x3e\xe9\x51\x96\xcf\x93\xe1\x14\xae\xf5\x9c\x93\xc7\x2a\xe0\xa1\x55\xb4\xfb\xeb\x9b\xb3\xd7\x37\x67\xff\xc2\x37\x67\xe9\xd6\xac\xf4\x61\xb3\xae\xcd\xd2\x86\x13\x5d\xec\xf6\xac\xdc\xfb\x5e\x83\xb7\x37\xfc\x20\x7b\x26\xae\x1d\xba\x9e\xe4\x81\x81\x6b\x9f\x5a\x6d\xa6\xc8\x68\x1a\x17\x45\xb4\x8f\x45\x0e\x64\x37\x29\xc3\x30\x61\xaa\x4a\x70\xa0\x35\xb5\x0d\x4b\xe3\x2a\x70\x90\xa8\x26\x98\x75\xfb\x38\xe7\x08\xf9\xeb\xce\xb3\x37\xaf\x9f\xed\xbe\x7a\x09\xba\x35\x4e\x42\xe7\x34\xcd\x3a\x44\x9a\x9d\xdf\xd9\xef\xa7\xec\xf7\x4f\xec\x77\xf1\x47\x7c\x98\xab\x0f\xd1\xbb\x2c\x39\xd3\x5f\xc9\x49\x99\x9f\xc6\x1a\x74\x96\x8e\xf8\x67\x16\x67\xfc\x53\xe8\x4f\x73\xfb\x7b\x3a\x4d\x1d\x70\x0b\xd6\x02\x54\x1f\xac\xc0\x91\x58\x24\x63\xd3\xfd\x98\x75\xdf\xfc\x7e\xc5\x7e\x3f\x67\xbf\x1f\xb3\xdf\xff\xcb\x7e\xff\x8b\xfd\x7e\xc6\x7e\x3f\x62\xbf\x7f\x61\xbf\x5f\xc3\xef\x95\x83\xea\xc0\x34\x30\x97\xcf\x77\x1e\xc1\x04\x0e\x85\x86\xda\xd7\x89\x2f\xc5\x04\xef\xbc\x7a\xfd\xe2\xf1\x9b\x27\x8f\x9f\xfd\xf4\xea\xe7\x61\xb4\x4d\x99\x7a\xce\x86\xe6\xa7\x29\x56\x41\x17\xc3\xe8\x7d\xe4\x24\x98\x28\xe9\x98\xf1\xe6\xd1\xde\xaf\xcf\xa2\x73\x53\xd3\xf3\xbd\x27\x4f\x00\xfa\xd5\xee\xd3\xc7\x82\x3b\x0d\xa3\x8d\xf5\xf5\xf5\x35\xd9\x43\x79\x9e\xfd\x60\x9a\x8f\x7e\x1f\x46\x1d\x60\x8c\x45\xd9\xb1\xf2\x76\x46\xf8\x50\xf1\xd0\xdc\x5c\xa4\xeb\x15\xb0\x5b\x37\x45\x36\xb9\x7e\xe8\xe2\x7a\x9b\xfa\xd2\xb7\x29\xe4\xf9\x48\xb7\xc5\x71\xbc\x75\x55\xf1\x1d\x1e\xce\xcf\x66\x65\xfe\xf7\x97\x9c\xf5\x8f\x30\xed\x96\x79\xdf\x02\x1a\x0c\x02\xac\x41\x4e\x27\xf8\x76\x93\x1f\x9c\x01\x29\x4e\x3b\xe2\x80\xf8\x2b\x93\xc0\xaf\x4e\xfe\x1c\xa8\x68\x10\x24\x04\x1f\x27\xef\x3a\xdc\x0a\x09\x85\xac\xb8\x5e\xdf\x47\x9b\x50\xda\x8d\x6d\xbc\xa9\x82\x41\xda\xc5\xe1\xcf\x0a\x08\xe1\x46\x5f\x87\x3f\x7e\x97\x8e\x40\x15\x8e\xa0\x73\x83\x9f\x93\x77\x03\xb4\x4d\xca\xb8\xbc\x21\x0f\x22\xc0\x8a\x1a\xb6\x69\x9d\xf4\x35\x32\xae\x0d\x23\xa1\x4c\x52\x49\x76\xf5\x58\xdd\x28\x03\x96\xa7\x71\xbc\x3a\xbc\x2d\xd4\x13\x1b\xe5\xab\xc3\xbb\x70\x7b\x6c\xb3\x55\x44\xa7\x6b\xbe\x77\xcd\xf7\xfe\xba\x7c\xcf\xb0\x3d\xba\xc5\x7f\x05\x7c\xcf\x91\xcc\x97\x17\xcc\x03\x72\xb9\x2a\x18\x12\xcb\x97\x7a\x4b\xc8\xe5\xb5\x83\x35\xc9\xee\xcd\x63\x43\x93\xbb\x1c\x00\xbe\xb5\x80\xbe\xc8\xd2\xf2\x69\x3c\xd3\xe2\x62\x87\x64\xe6\x21\x71\xa0\xce\xba\x96\x7b\x53\x9d\x88\xdf\x1b\x96\x0c\x3f\x64\x19\xf0\x88\x81\xcc\xfb\x47\x4d\xde\x61\x7c\x78\x18\x1f\x25\xba\x25\x9e\xa7\x05\xfb\xa1\x9b\x73\x12\xa8\x91\x65\x3f\xad\xcf\x9e\xe6\x6f\x93\x69\x3c\x52\x8d\xba\xd9\x4a\x7f\x18\x86\x32\x8f\xc2\x55\x33\x88\x9f\x1a\x21\xc4\xc4\x64\x59\x9e\x59\x63\xb6\x21\x94\xce\x32\xac\xc9\x6f\x68\x03\xf5\xa5\x61\x00\x82\xa3\x51\xe9\x42\xc3\x7a\x90\xa6\x7a\xa4\x3e\x36\x0c\x02\x59\x95\x49\x5d\xcb\x1d\x57\x00\x2c\x3c\x3c\x1b\x90\x91\x68\x00\x8a\x01\xfe\xce\x21\x43\x80\x0c\x96\xf4\xb9\xda\x6a\x79\x5f\x5b\xd4\xcc\x2b\x6f\x07\xce\x4a\x94\xad\x4b\x40\xa1\xb0\xd3\x94\x09\xa8\x30\x8b\xc7\xd2\x25\x14\x5d\x36\xdf\xcd\x92\x11\x6c\x53\xda\xcd\x9e\x3b\x4f\xc9\x28\x28\x21\x0f\x28\x53\xc5\x21\x44\x99\x18\x8f\xe5\xcb\x5a\x8e\x2f\xd5\xe8\x58\x7c\x8f\xc0\x54\xaa\x4c\xf5\x78\xbe\x2e\x4b\xb3\x1d\x23\xd8\x86\x60\x72\x7d\xe6\xd3\x1c\xad\xdb\x9e\x56\x3a\x7c\x07\x44\x96\x8d\x54\x64\x59\x27\x4a\xaf\x1c\x83\x25\x57\x12\x48\x1f\x7b\x07\xaf\x09\x8b\x82\x81\x58\x41\xe4\xe9\x81\x30\xf8\xb8\x2c\xde\x3a\xd5\x27\xbd\x1b\xbd\xc1\x6f\x79\x9a\xe1\xa3\x0b\xd1\x0f\x58\x47\x34\x84\x20\xfd\x10\xf4\xa2\x60\xf7\x73\xaf\x6e\x2e\xf0\xe1\x9d\xbf\xfa\x64\xe0\x20\x96\x9c\x0d\xd9\xc3\x9b\x14\x39\xf9\xa2\xf3\x52\xe5\x50\x68\x07\x94\xc1\x4d\x12\xdd\x09\xf1\x51\x0e\x3f\x56\xb4\x33\x2f\x14\x75\xb9\x66\x56\x84\x00\x28\x2a\x09\x23\x8b\xc7\x95\x0e\x04\x3c\x82\xc6\x8d\xfb\x20\x45\x12\x79\x4d\x9b\xb8\x16\xde\x8f\xe9\xdd\xe6\xb5\xb5\xe8\xc7\x34\x1b\x47\x74\x49\x4b\x76\x34\x52\xef\x2e\x83\xec\xb0\xba\x6a\x4e\xe4\xd1\x87\xa5\x8f\x4f\x41\x89\xf2\xca\x15\x59\x6b\x57\x90\x46\xfa\x0d\xe8\x16\xd5\x5a\x11\x54\xb3\xc9\xef\xaf\xa0\x93\x8c\xf4\x8d\x01\xe7\xd3\xfb\x9b\x1c\x5c\xbd\x69\xcc\x7d\x34\x4c\x53\xdd\x14\xda\x91\x4f\x58\xe8\x16\x26\x70\xf7\x18\x8a\x88\xe6\xd7\xcd\x8d\x46\xf8\x3b\x9c\x27\xf1\xef\x06\x14\x46\x79\xf3\x7e\x24\x6f\x87\xc3\x0c\x3e\x14\x24\x02\xef\x25\x63\x0d\x41\x75\x0b\x26\x5b\xb9\xd5\x88\xf2\xed\x28\x84\x26\x6d\x29\x12\xd9\x81\x22\x9f\x8e\x46\xb0\xb9\xff\x1e\x22\xb9\xc8\xcc\x8b\x32\xad\x26\x3b\x10\x2b\x1e\x2e\xcf\x4e\xd2\x77\xf4\x18\xd6\xba\x40\xac\x98\x05\xe4\x1a\xe1\x30\xf5\xf2\xd5\xb6\xea\xd9\x0f\xfa\x20\xa3\xb2\x29\xd4\x23\xe0\xe6\xb5\x8f\x02\x84\x5e\xeb\xb4\x62\xe6\xcb\xf0\xe9\xb2\x1b\x9c\x2a\x80\xe2\x25\xe6\x69\xf6\x71\x39\x48\xf7\x6b\x5a\x0e\x6a\x46\xa0\x25\x43\x1d\x62\x06\x5d\x57\x38\x01\x5e\xf5\xb2\x3d\x9b\x51\x80\x1a\xc9\xb9\xd9\x29\xbb\xe9\xc5\x56\x3a\x7a\x19\x46\xf4\xc8\xb7\x15\x7d\xcd\x80\x40\x7f\x05\x49\x64\x6a\x5f\xf8\x2e\xda\x84\x00\x6f\xf8\xaa\x1a\xec\x07\x59\x88\x26\x64\x28\x78\x51\xf6\x2a\x49\xc3\x79\x3b\xc0\xa5\x8d\x06\xd6\xf0\xc9\x88\xc3\xe3\x19\x8c\x3a\x3e\x0d\x05\xfc\x69\xd3\xea\x44\x1e\x9d\x2c\xa6\x53\x8d\x86\x35\xb0\xba\x94\xe4\xf0\x8f\x2c\xff\xb7\x42\x20\x32\x3e\x4c\x5d\x1e\xaf\xc2\x1f\xbe\xca\x7f\x14\xe5\xdd\xbb\x90\xea\x52\x00\x94\xbe\x45\xa5\xfd\x4b\x4d\xd4\xb0\xd7\xae\x61\xec\x7e\x1b\x96\x20\x05\x95\x73\x23\x29\x7c\x0f\xd0\x15\x42\x30\xfd\xe4\xdd\xde\xa4\xdb\xe9\x0a\xd6\x0c\x31\x1e\x6f\x6d\x04\xae\x35\x6a\x78\xcf\xdf\x0f\xbc\x0d\x64\x73\x08\x84\x54\x64\xc7\x25\x13\x53\xf9\x5e\x3d\x05\x3c\x00\xf8\x7b\x60\xa0\x44\x51\xcc\xf6\xe0\xd3\xad\x88\x39\x11\xfd\x81\x10\x71\xaa\x76\x91\xd0\xeb\xb4\x5a\x7b\xe3\xb4\x98\x4d\xe3\x33\x9a\x17\x8c\x15\x9a\x95\x20\xdb\x6a\x6c\xb8\x77\xcf\xde\x61\xf6\x23\x2a\x56\x77\x53\x15\x6a\xb3\x27\x3f\xbc\xbc\xac\x1e\xc0\x96\xe6\x50\x0c\x5c\xb7\x93\xef\x26\x9e\x75\xa5\x7b\x5c\x2f\xba\xf5\xbd\x4e\xd4\xd3\xea\xf7\xad\xf6\x02\xb3\xf6\xbd\xf4\x66\xd6\x42\xb3\x04\xa3\xc9\x16\x8b\xc4\xb9\x9b\x2a\xef\x75\xc1\x9a\x31\x41\x43\x84\x7c\xa0\x07\x0a\x51\x46\xa6\x79\x0c\x37\x58\x94\xc9\x37\xb0\x26\xf8\x00\x5e\x19\x22\xa9\xba\x9b\xb8\x16\xbd\xda\x7b\xb4\x37\x8c\x4e\xe2\xdf\xd1\x08\x9c\x66\x6f\x17\x53\xb0\x03\x1e\x4e\x93\xab\x1c\xa0\xd1\x06\xdc\x5b\xb8\x1b\xe2\x3f\x96\xdd\xeb\x0d\x04\x8f\x04\x53\x90\xa0\xc4\x0e\x06\x67\x03\x6d\x61\xd5\x7e\xec\x31\xcf\x04\x32\xca\xc2\x3c\x9d\x89\x72\x9f\x90\x1b\xd3\x13\xc1\x85\x1d\x26\x9b\x66\x61\x66\x5f\xe6\x8f\xa8\x80\x4f\x79\xb5\xcf\x60\xda\xe1\x09\x49\xc0\x93\x35\xf9\x87\x25\x6e\xc0\xa3\xdc\x7f\xe7\x52\x3f\x23\xd3\x66\x8c\x8d\xb2\x2d\xf0\xc4\xab\x1a\x17\xab\xab\x7a\x64\xb8\x4b\x28\x7b\xa8\x3f\x3e\x23\xb2\x68\x57\x7e\x69\x28\xb5\x77\x14\x4b\xd9\x27\x80\x69\x52\x14\xaf\x8e\xe3\xac\xbb\x8e\xc1\x60\x6f\x91\xf7\xb8\xf4\xc2\x97\x84\xb5\xd1\xc3\x67\x58\x59\x8e\x85\xc5\x9d\x05\x86\x5b\x96\xa8\x84\x93\x02\x0a\xa0\x23\xc3\x8a\x66\xd5\x68\x1d\x48\xbc\xee\xc0\xc3\xaa\x50\x84\x68\x28\x9d\x9c\x15\x32\x20\x7a\x21\xb8\xa0\xe0\x33\xc9\xc0\xa3\xab\x9f\xa5\xea\x50\x8f\xfb\x0f\x72\x0f\x6a\x20\xad\x9f\x71\x9f\xb7\x90\xaf\xd7\xef\xd7\xd2\xe5\xeb\x24\x7e\x47\xcf\x4f\xbe\x4b\xcb\xb3\x61\x74\x17\x8d\xd5\x6a\xd7\x49\x0b\x19\x9a\x18\x8b\xf6\xdc\x4d\x86\x4d\x72\xf7\x26\x40\xdc\xb3\x8a\xf2\xe9\xac\x2f\xec\x94\x95\x0e\x71\x6f\x81\x76\x40\x0b\x21\xa4\x75\x82\x55\xe0\x3b\x49\x7f\x7f\xb9\xf7\x6c\xa0\xb1\x4c\xed\x99\x40\x94\x18\x02\x16\x5c\x4d\x9d\x57\x39\x21\xf6\x63\x51\x00\xef\x82\x18\x4d\x8b\xa3\x63\x7b\x05\xe8\x81\x48\x5a\xc3\x5a\xfd\x63\x48\xc3\xd5\x6e\xa1\xb6\x14\x90\x79\x2b\x47\xac\x00\xe4\x1d\x0d\xbb\xba\x86\xda\xde\x2a\x3f\x50\x56\x41\xd4\xbd\x91\x16\x42\x2d\x4c\xcb\xc4\x41\xba\xd3\x0d\x94\x10\x59\x27\x6c\x29\xcb\xef\x28\x5b\x17\x2f\xe4\xa6\x42\xeb\x00\xf4\xa5\x0c\xb9\x7f\xf4\x6b\xea\x32\x14\xb1\xbb\xe3\xcb\xc3\x7b\x93\xd7\x02\x26\xb4\x4b\x63\x59\x38\x4d\xa2\x1f\x7a\xc1\x89\x9f\x7d\x6d\x9d\xd2\x81\xcd\xcd\xce\xcd\x5e\x5f\xd4\xfd\xd0\xd5\x74\xa9\x33\x3d\x2c\x82\xe1\xb7\x8a\x28\x99\xcf\xc5\xbe\x9e\x32\x28\x78\x6e\x37\x2f\x45\x83\xf3\xb9\x20\xae\xe1\xa9\x5e\x37\x76\xaf\xad\x05\x04\x05\x15\x09\x2c\x28\x13\xff\xf9\x01\xff\x11\x6c\xe9\x49\x7e\x9a\xcc\x1f\xc6\x45\xd2\x45\xe6\x02\xd6\x5e\xc3\xc5\x00\xe6\x17\x79\x9a\x2c\x8f\x68\xf6\xe1\xdf\x03\xa3\x88\x33\x10\x1e\xbd\x9b\x93\x1d\xc5\x13\x03\xe3\xd9\x63\x18\x53\xb7\x83\x47\xba\xd8\x0d\xf4\x38\xfd\x77\x47\x60\xec\x5d\x5a\x94\x85\xa0\x6f\xb1\x76\x0b\x14\x8a\x71\xdc\x79\xa6\x11\x35\xc9\xa7\xd3\xfc\x14\xcc\x10\x50\xb2\x00\x1e\xe8\x2c\x22\xd9\xc3\x3e\x46\x49\xe8\x9b\xcb\x1b\x35\x6f\xbd\xea\xde\x63\x0c\x2b\x3b\x36\xb0\x90\x12\x70\x09\x12\xbb\x87\x43\x67\x31\x4b\xe0\x15\xab\x59\x2c\x11\x00\x30\x5a\x8c\x38\x11\x09\xe4\x11\x5a\x25\x55\x3d\xcf\x8b\x22\x3d\x9c\xd2\xf4\x61\x18\x0c\xe9\x98\xf7\x72\x17\x64\x4b\x41\x34\xf8\x13\xc4\x69\x85\xab\xc7\x93\x49\x7a\x74\x26\x3f\xf6\x14\x19\xdd\x8a\xe0\x7c\x4b\xa6\xaa\x63\x29\xfc\x90\xe7\x57\x04\x74\x62\x80\xe4\x21\x12\x7d\xa8\x03\x27\x82\x3a\x32\x50\xf2\x9c\x87\x3e\xe4\xb1\x90\xc9\x21\xf0\x5b\xb7\xf4\x72\x54\xe7\x35\x04\x0e\x27\x3c\x56\x4e\x00\x9e\xce\x5b\xa8\xdb\x78\x48\xc3\x73\x08\x5e\xb7\xcf\x0a\xd2\xa7\x1a\xb9\x02\x88\x9c\x7c\xf6\x81\x87\x26\x0a\x09\x71\xa6\x06\xaa\x7e\x95\xf4\xcb\x15\x1d\x68\xfd\x3b\x1a\xee\x07\x2d\x8f\xd3\xc4\x83\xfb\xc0\x61\xa2\x09\xa1\xaf\xd2\xa5\x4d\x4c\xac\xd1\x38\xfa\xf9\xf1\x3f\x51\xd9\x56\x52\xd9\x85\x19\x87\xec\xb1\xb3\x43\xaa\xcd\x33\xfa\x15\xa2\xc7\xd1\xa6\x1e\xb3\xa3\x59\xf9\x66\x09\x0b\xed\x0f\xeb\x26\x2e\xa2\xd3\x04\x16\x82\x09\x87\xa2\x06\xb1\xc2\x64\x1e\x81\x04\xae\x78\xab\x51\x72\xc6\x41\x02\x0d\xd4\xac\x98\x01\x17\x7d\xa8\x04\x7f\xe3\xd5\xe6\x47\xc4\x83\x1c\xa9\x87\x6f\xb6\xb2\x02\xc1\x93\x78\x23\x43\xfe\xc5\xe4\xa7\xc6\x65\x1a\x13\x86\x03\x2b\x55\x10\xfe\xf5\xd2\xbc\x28\xfc\xe7\xbb\x94\xcb\xbc\x6e\x21\x33\xf9\xff\x93\x2d\x69\x58\x57\x7f\xda\xa2\x46\x74\x5c\xcd\x92\x2e\xd3\x93\xa4\xf8\x33\x96\x73\x26\x4d\x85\xb0\x88\xb5\xe1\xa9\x20\xa5\x1d\xb7\xdc\x48\xfb\xa0\x78\xc4\xa0\x7b\xd2\x8a\x24\x0c\x11\xa8\x93\x40\x98\x79\x43\x09\x76\x6d\x3a\xf6\x94\xa9\xc0\x00\x68\xfc\x73\xa7\x19\x67\x16\x1a\xb4\x99\xdb\x20\xd2\xe1\x22\x57\xe5\xe5\x8f\x0f\x1f\xa2\xf5\x7b\x41\x55\x45\xd6\xeb\xa9\x1a\x94\x6e\xe9\x37\x12\xe7\xaa\x27\x5f\x7d\x15\xc9\xdf\x21\x11\x1e\x9a\x74\x73\xb9\xbe\x10\x8a\x4c\x66\xc9\x56\xb2\xb0\xb6\x70\x88\xb2\xfd\x4e\x87\x1f\x9f\x38\xf1\xcb\x42\xa5\x39\xa1\x54\xca\x68\x99\x7a\xd1\x35\x40\x2a\x9a\x4e\x08\x4c\xbd\xad\xa1\x15\x2b\xe9\xee\x88\xb0\xe5\x69\x2e\xd4\xe3\x91\x7e\x69\xc5\x23\x2d\xfb\x21\x96\x36\xf4\x55\x41\x35\x4c\xe5\xb5\xeb\xb4\xcd\x57\x16\xc9\x00\xf8\x21\xa3\xac\xd0\x82\xc5\x8b\xd8\xca\xe4\x09\x93\xae\x0b\x70\x03\x43\xe3\xd4\xad\x4e\x2e\xf9\xb7\x8a\xb3\x3c\x98\x4d\x17\x85\xe9\x82\xfc\x0e\x06\x1d\xd4\x40\xb6\x5d\x08\xde\x36\x2a\x94\x12\x44\x3c\x52\x1d\x5a\x16\xf2\x0a\xdb\xf4\x0c\xa3\xeb\x06\xdf\x08\x26\x92\x1f\x05\xdf\x07\xb6\x5f\xfa\x65\x0d\xc0\xfa\x8f\x0d\x3c\x0f\x37\x87\x4f\x1e\xc9\x99\x73\xea\xb6\x26\x4e\x56\xea\xd8\xc3\xd6\xfe\xb3\xfe\x0e\x2e\xff\xc5\xb7\x26\x07\xef\xb7\xd7\xcf\xff\x67\x2d\x1d\xc0\x7d\x02\x0d\xbe\xc4\xd8\x6b\x86\xfc\x71\x06\xdb\x62\x98\xa8\xcd\xaf\xfd\xa7\xbb\xfe\xae\xf7\x43\xed\x38\x39\xfd\xad\xad\x99\x17\xac\xe8\x8d\x2a\xec\x1d\xc5\xf5\x95\xce\x82\x27\x78\xbb\x16\x76\x63\x36\x6a\x9b\x7e\x65\x78\x3e\xa4\x2b\x63\x5b\x08\x76\xc6\xee\x0b\xb0\x39\x06\xb6\x73\xeb\xc7\x20\x98\xd3\x65\x7c\x50\x4b\xf0\x0a\x08\x41\x5c\x9c\xc0\xff\x47\xf1\xac\x40\xc9\x41\xfc\x56\xdf\x7d\x6d\x09\xc5\x80\xb3\x76\x8f\x29\x1c\x3c\xeb\xb0\xd5\xc0\x9e\xde\xde\x09\x3b\x49\x3c\x3a\x16\x75\x16\x5e\x35\x69\x41\x84\xb2\x38\x91\x33\xc4\xa8\x89\x56\x59\x7b\x9a\xa2\xb6\x44\x55\x27\xe0\x3b\x5b\x41\x5e\x4e\x73\x57\x4c\x66\x4e\xed\x75\xe4\x26\xb0\xf4\x30\x80\x9b\x58\x97\x94\xbf\xbc\x1d\xc8\xd8\x37\x24\xc4\xcf\x71\x81\x21\x62\xc0\xfd\x5d\x15\xb2\x2c\x23\x4a\xda\x09\x85\x61\xd9\x5e\xc7\x13\xc7\xc8\xa1\x10\x0c\xa9\x5d\x1e\x47\xd3\x04\x2f\x39\xb3\x77\xf1\x66\xb3\x64\x0e\xbd\x55\xd3\x90\xe1\x9b\x82\x47\x29\x3d\x3b\x27\x32\x4e\xe2\x19\x4c\xc7\x86\x65\xb7\xeb\x6a\x7f\x04\xd6\x69\x0c\x96\x26\x18\x6d\xf4\x7d\xf4\x0d\x6c\xe7\x32\x4b\x24\x3b\x96\x1d\x38\xa7\x30\x99\x48\xf4\xf5\x15\x7e\x77\x3f\x50\xe3\x6b\xe8\x7a\xb8\xc6\x8a\x08\xde\x6c\x59\x1a\x96\x6f\x31\x7e\xf3\x2a\x82\xa6\xd2\xa7\x52\xa5\x53\xd4\x58\x41\x8a\x65\x7e\x95\xa4\xa8\x6c\xd1\x7a\x5f\x5e\x82\x12\x99\x05\x58\x53\x9f\x7b\x17\x5a\xb6\x03\x0f\x69\x55\x90\xa9\x35\x63\x17\x22\x5a\x06\x34\xf2\x7a\x0f\x15\xd5\x90\xb1\xea\x05\x3b\x44\x77\x49\x1a\x99\xde\xb1\x71\xe4\x03\x22\xfd\x16\xd4\x9e\x09\x85\xb5\x57\xe7\x6a\x9a\xc2\x2d\x02\x6e\x24\x37\x9b\x84\xdc\x01\xdc\xbc\x5f\x49\x8b\x8c\xbe\x5c\x4e\xda\x58\x53\x2d\x6d\x3a\x25\x6d\xd1\xd3\x88\x9a\x16\x1f\x15\xe8\x10\x22\x65\x3a\x8e\x36\xd7\x29\x94\xcf\x2d\x79\xde\x47\x6d\xd2\xc5\x82\xf5\x77\x11\xf9\x6a\x78\x24\xdd\x4c\xca\xe1\xc3\x7b\x19\x14\x80\xb3\x6d\xed\x8f\xd5\x8e\x80\x1d\x79\x26\xb8\xf3\xa9\x58\xb7\x0a\x1f\x84\x31\x5d\xc5\xda\x7f\xea\x76\xda\x40\x45\xf2\x2c\x2b\x54\x9f\x0d\xa1\x5e\x44\xc4\x13\x22\x5d\xa3\xb9\x06\xd5\x17\x6c\x38\x6c\xf4\x57\xf1\x81\x95\x3a\x5d\x18\x31\xb7\xf9\xed\xcb\xa0\x2a\x19\x3a\xc9\xb2\xde\xc3\xa4\x9d\x2c\xa4\x04\x46\x5d\xea\x86\x23\x18\xaa\xbe\x65\x45\x19\x67\x23\x60\x23\xa6\xf0\x87\x0f\x1a\x69\xb2\x30\x5e\x3b\xc3\x5f\x56\x3c\x8b\x60\x2a\xb9\x3a\x20\x33\xd2\x55\x76\xda\x22\x4a\x29\xc4\x4d\x58\x7a\xe9\xf9\x0a\xb5\x44\xd1\x4b\xff\xe5\xa2\x20\x7e\x24\x6f\x95\x3d\xc3\xd1\x50\xdf\x3a\x57\x87\x1e\xea\x4b\x6b\xdc\xa8\x1e\x37\x63\xe7\x47\xe3\x13\xb2\x2c\x7e\x54\xd1\x0b\x61\x48\x95\xe8\xb4\x1c\x11\xf4\xa9\x6c\x1e\x0e\x75\xfd\x02\x83\xd9\x93\x7d\x6b\xb9\x18\x40\xbe\x80\x73\x1c\x7c\xbc\xa0\xeb\xaf\x10\xf4\xc3\x46\x1b\x46\x60\xec\xf2\xf8\xb5\xe5\xc8\x0f\xa9\xb3\x2d\xd8\x01\x01\x5e\x84\x19\x50\xd2\x85\x26\x52\x76\xaf\xed\x68\x30\x32\x5f\xf3\x58\xbc\x87\x2e\x5a\x8e\xc4\x7f\xac\xa2\x25\x4b\xc3\x82\xb5\x63\x70\xf5\x11\xda\x47\xd1\xe5\x2d\x57\x54\x67\x0f\xe2\xef\x85\xbd\xae\x8c\x43\x41\xed\x18\xa0\x98\x7b\x09\x40\x47\x34\x9a\x9f\xf9\xfb\xd4\x8d\x1b\x78\xd4\x48\xf7\x67\x8d\x6f\xea\xb9\x10\xd7\xe1\x35\xa6\x6e\x12\xd8\xdb\xb8\x46\xba\xcc\xa8\x63\x39\xee\xc7\x30\x81\x89\x90\x7e\x0f\x21\xf8\x40\x74\x9c\xc4\x70\x3b\x55\x7c\xe4\x27\xae\x13\x86\xa0\x2f\x48\x0e\x09\x0d\xe0\xeb\xa1\xc2\x95\x63\x59\xb8\x4a\x5b\xba\xd6\xcc\x00\xc1\x22\xac\x15\x15\x18\x52\x6a\x55\xfd\xdb\x1b\x9b\x46\xd7\x27\xf0\x06\xbc\x54\x6b\xea\x54\x17\xaf\x2a\xa4\xa8\xfb\x50\xa1\x87\x80\x8d\xa2\xbd\xe2\xf7\xe3\x02\x33\x33\xcd\x41\xcc\x9b\xa5\x23\x7f\x1e\x5e\x41\x72\xd3\x3c\x94\x1a\xa8\x66\x02\xb0\x26\x6b\x02\xb0\x58\xed\x04\xdc\xd9\x36\xf8\x27\xe8\x0b\xe3\x1f\xab\xe2\x35\x85\xd0\xef\x01\x5d\x08\xfb\x15\x11\x43\xa4\xc8\x37\x54\x3f\xfa\x2a\x15\xef\xf0\x0c\xf5\x2f\x4a\x47\xc1\x70\x48\xff\xa8\x14\xe9\x10\x32\x34\x3f\x29\x87\x39\x8b\x0c\xf9\x87\x2a\x07\x0e\x3a\x43\xf9\xaf\x4a\x43\xf7\x93\xa1\xfa\x61\xea\x21\x58\xf5\xcb\xa4\x4b\x78\xfd\x53\xd6\xe3\xfb\xd0\x0e\x43\x89\x04\xed\xbb\x66\x0e\x03\x69\x16\xac\xf2\x82\x1c\xba\x09\x6a\x1c\xbf\xa6\xd9\x90\xfe\x31\x7d\xc5\x34\xf9\x43\xc1\x69\x69\x71\xc8\x3f\x54\xae\x6d\xb3\x1e\x7a\x29\x1a\x6b\x24\xa8\x0f\xcd\x4f\xca\x61\xd2\xf1\x90\x7f\xa8\x5c\x4b\x13\x19\xba\x09\x0a\x8a\xe5\x3b\x39\x8e\xee\x3e\xf4\x93\x54\x0f\x3d\x48\x2f\x49\xd5\xa9\x84\xb1\x21\xfb\xcd\xfb\x9b\x1d\x0d\xf5\x2f\x95\x4e\x7b\xea\x50\xff\xd2\xa3\xa7\xf5\x3e\x34\x3f\xf5\x98\x60\x97\x1c\xaa\x1f\x2a\x15\x36\xac\xa1\xfc\x57\xd7\x01\xfc\x6e\xa8\x7e\xa8\x54\x64\x1b\x43\xf5\xa3\x8f\x0b\x8c\xe2\xc6\xc9\xeb\xd8\xab\xc3\x8d\x6f\xfb\xb5\x81\x69\xfa\xab\x70\x13\x63\x75\x78\xf7\x36\x84\x6a\xd8\x68\x13\xaa\xc1\x5e\xc2\xf7\x69\x01\xaf\xca\x08\x05\xab\x70\x01\x6e\xb0\xb9\x3e\xd8\x58\x85\x10\x6d\x14\xa1\x6d\xb3\xd5\x03\xc2\xd7\x21\x20\xae\x43\x40\x7c\x09\x21\x20\x64\x2d\x98\x60\x87\x68\xfb\x7b\x32\x11\xf4\x7a\x16\xfd\x9a\x4e\x47\xbf\x8b\x8a\x7f\x13\xdf\x6e\x1c\x88\x96\x81\xdc\x10\x2c\x15\x94\xbe\x07\x12\x77\x8c\x50\xe2\xd3\x07\xfb\x31\x3e\x04\xb0\x5f\xf2\x23\xb1\xfd\x97\xc9\x74\x2a\xb0\xf1\xdd\x04\x13\x7d\xe0\x9f\xe2\xb7\xd1\xaf\xb9\x10\x6e\xbe\x3b\xaa\x8c\x4f\xb1\xbd\xa2\xe3\xf2\xc8\x10\x8d\x4f\xe3\x2c\x3e\xb2\x83\x46\x0c\xd6\x00\x0b\x6b\x73\x02\x38\x21\x00\x15\x1c\x62\xf7\x10\x95\x23\x17\x38\x15\xc9\x0a\xe4\x31\x7a\xe5\xbb\x10\x24\x79\x15\x6b\xe2\x1f\x05\xf8\x6b\xb0\x2a\x05\x78\x6a\x6a\x7c\xf4\xa0\x06\x6e\x7c\xa8\xe3\xc5\x1e\xd7\x35\x5c\x1c\xeb\x86\x9f\x61\x64\xf1\x2a\xc0\x2c\x29\x15\xe0\x73\xb1\x6e\xf1\x0a\x55\x35\xf4\x4c\x82\xe8\x4e\x88\xb5\x76\x52\xd7\x0d\xc8\xd7\xc0\x49\x59\xe2\xab\x4b\x3e\x7c\x21\xb3\x14\xa8\x62\x3f\x16\xa4\xe2\x2b\xa0\x7a\x99\xf0\x1d\x62\xb1\xd9\x60\xec\x59\xf8\xea\x28\x20\x04\xa4\xe2\x2d\xc9\x0b\x1b\x49\x36\x0e\xf4\x8d\x32\x14\xd8\x03\x54\xad\x7c\xa8\x43\x48\xd7\x98\x9c\x0b\xb6\x36\x2f\xcf\x02\x70\x33\x99\xa5\x40\x21\x80\xa8\x00\x07\x1f\xb5\x10\x5d\xc2\x8a\x9e\xc9\x6c\x4d\x95\xb3\x51\x4d\x89\x74\x36\x72\x0b\xb4\x8b\x48\xb8\xb2\xa2\x85\xfa\x5f\x45\x45\x70\x6f\x8f\xaa\xb1\xa3\xea\xce\xdd\xa5\x04\x67\xf4\xf6\xfa\x32\x25\x59\x80\x5d\xf9\x54\x2a\xeb\xb9\x82\x62\x40\x09\xdd\x71\x01\x9f\xdc\xf2\x18\x03\xed\xf3\x2a\x4e\x71\x09\x41\xae\x58\x4c\x5e\xee\xf8\x50\x66\x3e\x7a\xe0\xe5\x15\xc7\xaa\x5e\xb1\x6c\xbc\xdc\x0c\x57\x08\xe4\x8a\xb5\xe2\xe5\xce\xcc\xb2\x00\x10\xb5\x4a\x3c\xb8\xc3\x3f\xfe\x50\x6d\x00\xd5\xfb\x7d\x30\xe4\x8f\x40\xf2\xb3\xcb\x61\x0c\xdd\x1b\x8d\x25\x9e\x09\x89\x5d\xd1\xbe\xfc\x97\x34\x17\xde\x49\x89\xcb\xc2\x2a\xca\x89\x6b\x68\x7d\xf5\x35\x0c\xa3\xa6\x21\xff\xf0\x9a\x78\xa3\x57\x08\xfd\xf0\x06\x28\x01\xba\xa6\x7d\x49\xec\x69\x22\x24\x58\xf3\xbb\x4b\x4e\x41\x10\x15\xf9\x1c\xe3\xfc\x82\x64\x55\x24\x91\x19\x43\x4e\x1b\x3a\xc6\x04\x5a\x01\x62\x0c\x8c\xaf\x69\x6c\x95\xe3\x02\xd9\x53\xd5\xa9\x5e\xcb\x4c\x38\x5d\xf2\x17\x81\x9b\x57\x00\x2f\xbc\x34\xe1\x07\xfa\x02\xf7\x5f\x6c\x7b\xe4\xef\x49\x32\xdb\x2d\x5e\x9e\x65\x23\x41\x2f\xb5\x5d\xc1\xb2\x0e\x7c\x3b\x0a\x0c\x74\x84\x73\x8d\x07\xc6\x0f\xc6\x81\x52\x97\x63\x28\x9c\x14\x7e\x05\x60\xd4\x45\x5a\x05\x85\xdf\x01\x38\x79\x1b\x5b\x81\xc1\xa7\x07\xc5\x2f\xe6\x12\xa0\x4e\x09\xc2\x5a\x75\xaa\x84\x40\xdb\xe6\x96\x95\x6a\x5e\xa6\x04\x6b\x75\xa1\x59\x5a\xa0\x6e\x8e\x4b\x55\x7b\x1d\x4e\xc9\x55\x52\x41\x8a\xaf\x60\x1f\x38\x94\xfc\xf6\xe0\xb8\x8b\x0c\x41\xea\x94\x00\xac\x7f\xba\xac\xca\x3c\x74\x0f\x1d\xbd\x3e\x57\x95\xf5\x72\x02\xed\xee\x3e\xd8\x79\xc6\x1a\x83\x4f\x0f\xca\x04\xad\x21\x30\xf9\x1d\x82\x53\xf1\x54\x34\x20\x26\xc0\x1e\xe7\x2e\x7c\x12\x0c\xf8\xfd\x6e\x3a\x5b\xb2\xa3\x2b\xda\xa7\x7e\x78\xb6\xed\x42\x56\x9d\xc2\x8e\xd3\x39\x5a\x9c\x47\xf1\x0c\x6f\x65\xb0\x73\xd0\xc0\x8c\xee\x3e\xdc\x79\x6e\xad\x7d\x28\xc7\x3d\xed\xd2\x43\xbd\x07\x82\x70\x4a\xf9\xd6\x15\x50\x80\x18\xc8\x66\xd4\xca\xc6\x30\x33\x9a\xff\x5a\x0d\x38\x43\xdc\xd7\xbc\x1b\xf7\x3a\x29\xac\x30\x96\x8e\x59\x68\xca\xe9\xa8\x3d\x29\xcb\xc7\x49\xa7\x6f\x41\x1c\xa1\x7b\x09\x5c\xdc\x11\x63\x7c\x33\x12\x6a\x59\x56\xfe\x42\xe0\x1d\x73\xb4\xdd\xeb\x5f\xa4\xb5\xa4\x3c\xcd\xe7\xbf\x57\x35\x28\xb2\xdf\x48\x50\x07\xc4\x7e\x0e\x60\xe8\xae\xf2\x4b\x76\x2b\x91\x76\xf1\xaa\x7e\x89\xfc\x37\x38\xd7\xa3\x7c\xfa\xcb\x9f\xd0\xbf\x53\xb1\x01\x08\xb0\xaa\xee\x09\x01\xe9\xd2\x68\xc3\x9f\x07\xc1\xbd\x44\xf0\x8f\x3c\xcb\x28\x92\x13\x5b\x6e\x3d\x8b\xf6\xba\xc1\xed\x12\x8e\xd2\x02\xe9\xbc\xca\x6e\x2f\xbc\x83\x51\xec\x02\x25\xb1\x57\xd2\x3c\x3a\x99\xe2\xbb\x08\x52\xa2\x09\x9a\x7f\xa1\x6e\x15\x4d\x94\xeb\x0f\x64\xb9\xb2\xd4\x8e\xd5\xe1\xe6\x3a\x24\x71\x85\x45\xa4\x6d\x40\x9a\x51\x16\x44\xca\xb6\x4e\x21\xd1\x49\x24\xdd\xd5\x49\x5c\xf4\x5f\x1d\x6e\x6d\xea\x0c\x58\xe1\x22\x61\xcb\x24\x18\x91\x5f\xa4\x9b\x4a\x8d\xd2\x28\x92\xbf\xf1\x92\xc5\x3f\x22\xfd\xae\x97\x7e\x0a\xd5\xdf\xf5\xe1\xc5\xd2\x12\xf0\xdf\x7a\xe9\x4a\x40\x5e\x1d\x6e\xaf\x7b\x99\x82\xb0\x44\xfa\x86\x9f\x0e\x32\xb2\xc8\x31\xc3\x52\x9a\x91\x48\xbc\xad\x13\x6d\xbd\x5c\x64\xdd\xd1\x59\x4a\x9a\x11\x89\xdf\xd4\x9b\x0e\xc1\xb0\xb7\x75\x6d\xd8\xbb\x36\xec\xfd\xb7\x18\xf6\x20\x2c\x08\x84\xa3\xb8\x5c\x7c\x57\x66\x3f\xf3\x0c\x28\x21\x0b\x8a\x7a\x1c\xe6\xf1\x5b\xba\x31\xc0\x2c\x13\xd8\x1b\x05\x67\x9e\x8a\x89\x1a\xde\x44\xf2\xad\x3b\x41\xf3\xce\x8f\x78\x76\xec\x54\x86\x69\x0a\xe2\x94\x5e\x3c\xb2\x41\x0c\xbf\xc3\x4c\x1d\xf5\x75\x67\x3a\xb5\x86\x62\x0b\x4c\xb6\xca\xd5\x47\x47\x80\x7e\xe4\x78\xae\x55\x98\x4a\xec\x04\xae\x3a\xff\x46\x67\xff\xf0\x0f\x4f\xf6\xdc\x31\x69\x73\xf2\x7a\x58\xff\xb8\xb7\x23\xcd\xaa\x38\xe1\xf7\xf5\xaf\x0f\x1f\xf0\xd5\x1b\xc8\xb4\xa2\x44\x40\x22\xa6\xee\x77\x40\x3a\xc5\xf7\x06\x20\x20\x88\x50\x5e\xf0\xe7\xc1\x80\xd0\xcc\x5e\x39\x9c\x04\x0e\x5b\x65\x33\xfb\x93\x03\xf4\x9f\xd1\xfe\xa7\xf2\x00\xb6\x17\x78\x2a\xdb\xa9\x06\xfa\x03\xc5\xef\x33\xe2\xa1\x80\x3b\xd8\x11\xd2\xa3\xcc\xd3\x77\xa6\x41\xe5\x12\x61\x2e\x86\x61\xe4\x0a\x83\x57\x0b\xcf\xf6\xed\x31\x1e\xcb\xa2\x0e\xe3\x81\xb7\x8a\xc5\x12\x8a\xd5\x08\xe0\xf7\x00\xfe\x27\x20\xcc\x6f\x81\xf2\x8e\xbc\xd2\x8e\x89\x78\x52\x5d\x28\x10\xf9\x25\x80\xf4\x9b\x9b\x68\xa3\x84\xa6\xd5\x11\x3c\x03\xdc\x5f\x3f\x18\x14\xc0\x10\x74\xe8\x75\x80\x3e\x91\x82\x8f\xa1\x30\x7f\xba\x7e\x0b\x4c\x17\x6f\xe5\xbe\x74\xe5\x95\x52\x9f\x7f\x26\x2c\x6a\xd0\x38\x16\xdd\x60\x17\xbb\x6e\x60\xfb\xa2\x7e\xbc\x4c\x01\x37\x45\x4b\xb8\xa2\x39\xa6\x7e\x81\xf3\x02\x36\xf4\x83\x6e\x1e\x3c\xe0\x72\x21\x73\x09\x36\x9f\x75\x3b\xbc\x04\xc5\xd6\x01\x56\x3c\xcd\x8f\x94\xd3\x31\xeb\x31\xa0\xc2\x8b\xcf\x94\x48\x36\xc4\xdf\xe3\xc2\xb9\xeb\x86\x56\x67\x9f\xba\xd5\xb7\x09\xc2\x56\xad\xb0\x46\x15\x1a\x11\xa7\xb8\xc5\x72\x7c\x97\x8c\x40\x02\x08\xac\xc7\x3e\xc6\x6f\x3a\x8c\x47\xbf\xeb\x97\x43\x31\x74\x81\x54\x82\xd5\x69\x6e\x37\x9e\x1f\x2d\xf0\xae\xc9\xbe\xfe\xc5\x62\xf7\xd8\x4e\xee\xaa\x46\xe0\x18\xf5\xc5\x0c\x12\xd3\x49\xd7\x83\x80\x89\xdf\xb0\xa3\x5e\x18\xb6\x01\x0e\x70\x1e\xba\x73\x45\x69\x32\x56\x9e\x51\x8c\x15\xc4\x84\xbd\xad\x46\x16\x5b\xc4\xe4\xe0\x30\xb5\x8d\x89\x4c\x2c\x27\xce\xde\xcd\xfb\xa8\x40\x75\xfa\x41\xc6\xda\x57\xec\x1d\x94\xb0\x6e\xaf\x6f\x1a\x62\x18\x6e\x9e\x29\x51\x42\xf0\xa0\x57\xf9\x43\x15\x36\x8b\x4f\x99\x8a\xa5\xc5\x75\x71\x33\xb5\x34\x6e\xfa\xf4\x86\xa3\x8a\x0e\x44\x57\xf4\x7e\x22\x81\x2b\x74\x0d\xaf\x9b\x5a\xf1\x08\x68\x1e\x41\xd5\x03\x49\x55\x7c\xa0\x14\x4f\xab\x5e\x7c\xa1\xec\xce\x5f\x6a\xdb\x42\x60\x67\x23\x14\x02\xf5\x16\x88\xcc\xdb\xd7\x22\xf3\xb5\xc8\xfc\x65\x8b\xcc\xec\x19\x18\xd4\xc9\xaf\xea\x1d\x18\xf0\x88\x99\xcf\x46\xb6\xbc\xf9\x1b\x25\xea\x03\x47\x88\x3a\xe3\x88\xa4\x94\xa6\x25\x51\xdf\x74\x01\x83\xb5\x84\x4c\x4f\xc6\x44\x74\xbc\xa9\x94\x34\x65\x06\xb4\xbb\x7f\xd0\x18\x56\x50\x6c\x83\x2a\x02\x24\xb0\x63\x59\x18\x83\x6a\x63\xd7\x64\x82\xe3\x70\x28\x4a\x05\x5c\x78\x23\x39\x7e\x2a\x54\x1a\xe7\xd9\xb5\x15\x1c\x27\x67\xc5\xe3\x71\x48\xe6\x0e\x0d\xbc\xa0\xb7\xc0\x15\x44\xe3\x88\x14\xd3\xae\xeb\xbf\x84\x09\x76\x2d\xb4\x9f\xdb\xd1\x0b\x34\x7e\xad\x6e\xfa\x53\x60\xce\x8f\xb2\x31\x99\x9f\x14\x6c\x9f\xd5\x2d\x28\xa2\x2f\x25\xd1\xc2\x97\x89\x89\x7c\xe8\x97\x14\x16\x0d\x80\xc4\x0f\x0f\x48\xa9\x51\x09\x57\xcb\xc7\x14\x00\x99\xef\xf1\x96\xe4\x5b\xec\x23\xcc\x01\x57\x0f\x48\xd0\x0b\x08\xec\x54\xa4\xa2\x5a\x10\x6b\x54\x7f\x64\xa5\x03\x4f\xf6\x71\x0b\xdc\x90\x8b\x48\x88\x42\xbf\x80\x83\xeb\x8b\xa4\x98\x09\x81\x22\x91\x4d\x79\x77\xfb\xbc\x31\x84\xdb\xeb\xd2\x1a\x1b\xec\x66\x6f\x83\xb5\xde\xf3\x2a\x3d\xf7\xfb\x57\x59\x39\xc5\x78\xf2\x06\x4b\x7b\x2e\xc6\x9e\x08\x65\xc8\xc6\x07\xb2\x0f\x18\x95\x82\x27\x38\x9d\x3a\x67\x53\xa1\xf5\x9b\x28\x2c\x4a\x20\x65\x19\xbb\x25\x0d\x5e\x88\x02\x68\x47\x93\x2b\x52\xc8\x01\xe8\x54\xd7\xea\x6d\xef\xeb\x0d\xff\x7a\xc3\xff\xeb\x6e\xf8\x66\xbf\xd7\x62\xf9\x15\x99\xc8\x5a\xda\xaa\x40\xe3\x99\x3b\x60\x85\x94\xf5\xd7\x30\x73\x59\x73\x9a\x82\x63\xd7\xb2\xb8\x1d\x4c\xdd\xb7\x92\xd0\x3b\x4c\x09\x61\x60\xda\x62\x68\x3d\x34\x1e\xda\x3f\xc9\xbc\xc4\xef\xb0\xe1\x36\xef\x5e\x83\x26\xb8\x87\x06\xec\xb9\x82\xd2\x72\x01\xfa\x12\x9f\x47\x2a\xf2\xb3\x9d\x1a\x6c\x40\xc6\x06\x0e\x57\x6d\xdf\x24\x3d\x4c\xfb\xb2\x9c\x73\xda\x23\x72\x02\x66\x10\x16\x25\x9a\xc1\xa2\xcd\x84\x9e\xf5\x86\x2b\x4f\xac\x4f\x1d\x38\x97\xe2\x61\x9f\x99\xde\x4c\xcd\x5a\x6f\x03\x88\x2d\xd3\xde\x86\xeb\x1a\x0c\x04\x92\x86\x50\x8c\x22\xb1\x62\xbb\xc6\x3c\x8a\x2f\x1d\xd8\x19\x9c\x8a\x00\xac\x08\x34\x8d\xb4\x27\x95\x77\x42\x28\x81\x6a\x34\x32\x2b\x8f\xb2\x5e\xb5\x90\x1e\xf5\x43\x5f\x32\x10\x15\xa9\xd6\xc1\x8b\x53\x02\x5c\xd1\x70\x61\xd4\x70\x4e\x1b\x2a\xed\x5c\xd7\x54\x49\x4f\x44\x2a\xe1\x2a\x6b\x35\x7b\xa3\xae\x5f\x9c\x50\xd8\x25\xc7\x65\x66\x9f\x5b\xaa\xb4\xf5\x47\xf7\xe7\x8d\xc0\x92\x32\x37\x9b\x4e\xda\x51\x35\x1a\x4d\xa9\x13\xcf\xcc\x61\x10\x60\x4c\xa5\x2d\xe6\x95\xb8\x45\xab\x49\x25\x7e\x73\x75\x33\xea\xd6\xd7\x6c\xa8\x51\x0c\xef\x2a\xe6\x96\x78\xaf\x63\x56\x96\x9c\x55\x5a\x1b\x35\x8f\xb5\x27\xcf\xb7\x5b\x51\xc7\x2a\xa7\x53\xd4\x53\x3b\x5d\x08\x24\x0f\x7a\x96\x26\x30\x32\x85\x36\x74\x70\x79\x6a\xb3\xee\x1c\xfa\x46\xb5\x2a\x6a\xab\x23\x37\x1d\x88\x07\x7d\x02\xd5\xa4\x1f\x25\x62\x4a\xe9\xcc\x78\x0a\x0f\x30\xcd\xa6\xf9\x99\x80\x97\xae\x83\x78\x3b\x71\x74\x1c\x9b\xd0\x8a\x8a\x20\xb1\xb6\x1f\xf3\xb9\xea\x51\x20\xb4\x81\x52\x58\x43\x24\xa9\xd6\xe5\xb9\x36\x2d\xb2\x2b\x73\x45\xf4\xfe\xb5\x3a\xdc\x30\xb7\xe6\x2a\x5a\xe4\x8e\x10\xaa\x25\x66\xb0\x34\xe4\x8b\x0f\x39\x43\x48\x0c\xe3\x98\xa1\xa0\x7e\xc4\xb7\xc9\xef\xab\x7b\x6b\x98\xbf\xb6\x26\x84\xc0\x94\xcc\xe5\x28\x72\xcd\x4a\x63\x82\x55\x27\x65\xf6\xbc\xcb\xa5\xa0\x1f\x92\x36\x1d\x4b\x7a\x66\x36\x51\x29\x4b\xf0\xe6\xb3\xd5\xbe\xbb\x2b\x61\x7f\x6f\xde\xbc\x67\xab\x4d\xa2\x5b\x45\x99\xcf\xc8\x56\x0b\xd6\xff\x78\x02\x5d\xb9\xbd\x4e\x73\x55\x88\x9d\x29\x3d\x49\x84\x4c\x6b\x5b\x9d\xe9\x09\x14\x40\xc0\xf7\x02\x36\xa8\x2c\x52\xef\x07\x50\xfb\xaf\xb2\x72\xf3\x02\x83\x28\x71\x1e\xd0\xe9\x5c\x04\xd2\x7d\xc0\xa0\x1e\xaa\x67\x24\xa8\x6d\x1a\xcd\xcf\x04\x12\xd6\x8c\x09\xcf\x4b\x62\x5a\x19\x23\x20\x6c\x0c\x2a\x7c\x08\x12\xb3\x50\x3a\x5c\x0c\x74\x42\xca\x67\x32\x65\x01\x4b\xf8\x9f\x17\xbe\xf8\xc2\xad\xc2\xf2\xe3\x35\xeb\x70\x25\x3e\xd6\x2c\xaa\x11\x2b\xf0\x95\x69\xea\x05\x91\x9a\xe1\x28\xac\x1b\x10\x54\x85\x13\x55\x5f\x11\x66\xef\x7d\x70\x6c\xe9\xa4\x2b\x01\xc2\xd4\x16\x2c\x14\xec\x20\x3e\x78\x24\xeb\x1a\x8c\xec\x05\x68\xf7\x08\x5f\x45\x0a\x77\x47\xfd\x55\xc5\x7a\xbf\xa3\x63\xbd\x57\xfd\x89\x01\xd9\x14\x27\x04\xa8\x1b\xd8\x62\x6d\x31\xf8\x23\xce\x12\xa0\x4d\xfe\x77\x91\x25\xc0\xff\x5a\x2e\x07\x77\x48\x70\x7a\xa3\x9f\x8e\xde\xaa\x9d\x19\xf5\x07\x67\x81\xf2\x9c\x6f\x9a\x1f\x31\xaa\x45\xb5\x42\xb1\xf1\x1b\x9d\x5e\x43\xf3\xd8\x6b\x67\x5f\x42\x95\x26\x38\xd5\x2d\x2a\x13\x1d\xa3\xcd\x4a\x89\x0b\x10\x98\x51\x9e\x8c\x44\xf1\x51\x0c\x51\xb6\x71\xe5\x9c\x26\xf2\x3d\xc0\x86\xbf\xb0\xec\xe9\x6e\x80\x0d\x35\xb8\xb2\x8e\xb7\xfd\x37\x0c\x69\x44\x41\xdf\xe4\xa1\x20\x6c\x09\xb0\x3b\x16\x89\xa8\x46\xec\xad\xe9\x49\xf3\x30\x18\xe9\x36\x13\x2b\xfc\x39\xb6\xb0\xb6\x1d\x0e\x06\x49\xba\xe2\x0e\xf1\x58\xef\x4c\x94\x90\x1a\x2d\xe3\x94\x62\x21\x89\x85\xa0\xe3\xbe\x93\x04\x82\x16\x9f\xa3\x58\x50\xc3\x09\x6c\x48\x83\x20\xbf\x76\xff\x2a\xf9\xb7\xfb\x17\x08\x47\x7f\x15\x5d\xac\xef\xe1\x79\x65\x6e\x15\xc7\xf0\x4b\xb8\x90\xcc\x3a\xd9\x56\xa1\x60\x47\x0c\x8a\xd0\x7f\x8c\x41\xcd\x3e\xd3\xa1\x3a\x1c\x29\xce\x01\x6b\x50\x1a\xdc\x4a\xb9\xc2\x80\x5a\x95\x00\xb6\x0e\x17\xe8\x1e\x52\xa2\xd4\x77\x4c\x13\x30\x70\xaa\x7e\x98\x2a\x39\xeb\x6b\xb2\x84\x36\x8f\xc9\x68\x48\x10\x4b\xd9\x88\xa6\xb2\xb8\xaa\x45\x8d\x4d\x14\xd1\x49\x6a\x4c\xd9\xd9\x39\x27\x06\xc7\x36\xb2\xa1\x7c\x0a\xe2\x69\x6f\xc9\xa2\x9b\x35\x45\x5d\x39\xd8\x92\x2c\x64\x28\x15\x65\x28\x1e\x7b\xa5\x43\x92\xb2\x84\xd0\xd2\xb2\x51\xff\x54\x92\x6e\xcb\x4c\x3c\x16\x5a\x33\x13\x01\x28\x0d\x1d\xbf\xd0\xa4\x84\xb7\xe8\x6f\x54\x0d\xf0\xa7\x7f\x90\x74\xad\xb5\x48\x4e\x7f\x5d\x45\x07\x7d\x3d\xfb\x9c\x83\x09\xd6\x8a\x61\x57\x8c\x97\x85\x55\xa9\xf1\x95\x78\x7f\x7e\x0f\x80\x15\x96\x6e\xd8\x6d\x4b\xc4\xb0\x8a\x51\xc7\x9d\xe3\x1d\x1d\x24\x64\x7e\x59\x01\xcc\x99\x78\xd4\x60\xfc\x35\xd8\xda\xab\xf2\x1d\x41\xff\x8f\x6a\x8f\x11\xf8\xb3\xbd\x46\x8a\xc1\x2c\x9f\x59\xf1\xeb\xec\xee\x4d\xe3\xa2\x94\x90\x5e\xd5\xe1\xee\x10\x21\x75\xa1\x20\xc6\x46\xba\xa1\x6f\xa2\xe0\xb3\xb5\x98\xee\xf6\xc9\xa0\xb0\xa1\x4b\xd0\x90\x04\x1e\xd0\x1b\x26\xdf\x47\xeb\x6e\x6d\x72\xa6\x15\xed\xef\xa8\xb5\xdc\x6c\x05\x50\x7f\x97\x32\x09\x32\x34\x39\xcc\x52\x99\xd3\xb4\xab\x1d\x1f\xd6\x0d\xbb\xcb\x83\x59\x7c\x06\xaf\x82\x85\xba\xe7\xab\x03\xe4\x3f\x05\xa7\x8b\xe6\xfd\xaa\x2c\xcf\x6e\xc9\x4a\x38\x3a\xdc\x6d\xe2\xbc\x6a\xea\x31\x74\x20\x70\xc6\xb0\x09\xb6\xab\x96\x4a\x1f\x47\xcc\x6a\x55\x13\x84\xbe\x6f\x37\xef\x53\x45\xf7\xec\x49\xac\x3c\x11\xe4\x1a\xad\x65\x03\x50\x72\x5f\x3c\xce\x5c\x2b\x81\xd8\x2e\x4f\xe3\x42\x0b\x94\x56\x7d\x6a\x69\xe3\xd1\x2b\x53\x61\x8c\x63\x96\x73\xfe\x7a\x2c\xf4\x97\x10\xd2\xa1\xd7\x02\xa0\xea\x24\x92\x1f\x39\x86\xce\x15\xeb\x24\x1e\x10\x47\xc7\x63\x3a\xf6\x62\x5c\x17\x7a\x12\x6e\xab\x42\xed\x12\x93\x05\x65\x2a\x84\xaf\x4a\x09\x71\x92\xce\xe1\x9c\xbf\x4a\x40\x5c\x52\xc6\xab\xb0\x80\x84\xcc\x1e\xa1\xe3\x57\xeb\xab\x29\xae\x26\xbe\xcb\x49\x03\x6f\x9a\x67\xa7\xb1\xb6\x28\x6f\x44\xf5\x32\x43\x0f\xf3\x34\x6d\x93\x27\x20\x69\x2b\x93\xd8\x55\x9b\x20\xe8\xb7\x8f\xc8\xaf\x14\x93\xe4\xa7\xe5\x80\x76\x61\xc1\xcb\xb5\x9a\x35\x59\x67\x03\x9b\xba\xac\xe9\xdc\x96\x81\x26\xba\xfe\xb5\x95\xb5\x35\x67\x07\xb6\x0e\x70\x4c\x44\x65\x66\xbe\x74\x2a\xef\xd2\xbe\xbc\xb6\x66\x85\xea\xad\x7c\xa5\x7a\x34\xc2\xa8\xbb\x39\x3d\xec\x04\x56\xa8\x6a\xd9\xcc\x36\x63\xdb\x23\xa7\x49\x3c\xf7\x39\x11\x17\x87\xea\x44\xa1\xe8\x3d\x93\xba\xda\x4a\x44\x70\xda\x69\x6a\x00\xf6\x06\xef\xae\x09\x25\x00\xaa\x30\x91\xf5\x00\xa2\x60\x4b\xda\xe6\x65\x9a\xf0\x70\x06\x1c\x74\x5a\xee\x90\xf6\x7c\x28\x9b\x09\xe7\xd2\x6a\xbd\xb5\x97\xa1\x8c\xa7\xbc\xb1\x56\x96\xc7\x2c\x72\x25\x3e\x7a\x83\x0f\x8a\x29\xa7\x02\xdd\xa5\xbe\x2a\x70\x98\x8c\x62\x78\x8a\x4b\x68\xe2\x63\xd1\x14\xbc\x64\x82\x3e\x49\xc5\x2c\x4f\xa7\x74\x1a\x9e\x89\x4d\x7d\x12\x8f\x74\xec\xed\x16\x9a\x78\x1b\x6d\xdb\xdd\xa6\x9a\xf9\xa1\xe1\x60\xde\x9a\x66\x6b\xf3\x27\x30\x66\x43\xc7\x61\x7f\xec\x47\xa7\xc7\xe9\xe8\x18\x9d\x06\x60\x79\x8b\x91\xd1\x36\x16\xc1\x43\x05\xcd\x47\xaf\x92\x0f\x34\xcc\xaf\x61\x1e\x61\x47\xa6\x06\x11\x76\x79\x39\x55\x17\x6b\x96\x1f\x2f\x23\x3b\x56\xcb\x8d\xcc\x59\xf9\x42\x72\x4c\x9d\x0c\x63\xdf\x74\x18\x90\x43\x7a\x7b\xe6\x1b\xd0\x7a\x82\xea\x6e\x8b\xe3\xf3\x2a\xd6\xe4\x29\xc3\x41\x2d\xb8\xe2\x26\x4b\x48\x1f\xf6\x77\x3f\xe3\x17\x4e\xf8\xf3\x2f\xaf\x98\x3c\xa3\x4c\x07\xdd\x96\x1c\xba\xbd\xa7\xdd\x9f\x6d\x5f\x89\xd5\xe1\x37\x55\x1e\xd0\xda\xa3\x41\x24\x6c\xf9\x2e\xd1\x72\xe4\xab\xc3\xad\x0d\x70\x7d\xba\x73\xed\xfa\x74\xed\xfa\xf4\x65\xbb\x3e\x31\x5f\x67\xe9\x02\x79\x05\xce\xce\x15\x51\x32\xa5\x73\x25\xdd\xcb\xda\x9b\xa8\x33\xe7\x1d\xc1\x4c\x87\x15\x96\x1b\x26\xe3\x49\x0d\x56\x56\xc2\xdf\xd0\x53\xdb\x09\xb0\x4f\x55\xa9\xae\x4f\x69\xb1\xe7\xfd\x50\x1f\x5e\x3c\x7f\x48\xcc\xfd\x32\x1d\xa0\xf7\x9c\x90\xd5\x26\x78\x81\x51\xf4\x43\x54\x2c\xcf\x09\xc2\x1d\x90\xd7\xd4\x53\x88\x95\x68\x5a\x3e\xce\x0b\x7e\xfa\xe5\x37\xfe\x70\xef\xd9\xb3\xc7\x0f\x5f\xed\xee\x3d\x8b\x1e\xbf\x78\xb1\xf7\x62\x28\x78\xa2\x32\xff\x8e\xa8\x4a\xd2\xe8\x85\xa6\xdb\xb9\x19\x41\x7d\xd1\xcd\xce\x20\xdc\x07\x13\x09\xa7\xed\xd8\xf5\xfd\x7b\x32\x22\x94\xb0\x7a\x52\xaa\x2c\xdc\x84\x72\xa4\x1d\x5a\x7e\xdb\xe8\xbe\x6a\xda\xc1\x6b\x69\x82\xe0\xe3\x23\xf0\xa0\xbe\x71\x43\xde\x34\x84\x6d\x5d\xfe\x1e\xd0\x03\xb3\x5e\xca\x40\x15\xfb\x21\x0a\x26\x0f\x23\x3d\x5d\x10\x14\x19\x67\x65\xae\xbb\x14\x78\x96\x55\xf6\xcd\xbb\x4b\x66\x70\x20\xab\xb6\x47\x6b\x66\xf3\x15\x1d\x57\xf3\xf1\x9e\x14\x6d\xa7\xf4\xd5\xee\xd3\xc7\x82\xb5\x0d\x23\x79\xe8\x0d\xc4\x05\x9d\x14\x4c\xfe\xa6\xf8\x21\xfe\x89\x47\xc7\xc0\x31\x34\xb2\x55\xcc\x4c\xd8\xad\xbe\xb9\xde\xad\xae\x77\xab\x2f\x7b\xb7\x62\x9b\x15\xde\xba\xfc\x5c\xbd\x74\xdb\x5f\x66\x6f\x75\x87\xfe\x0a\xaf\xb2\xab\x58\x44\xb0\xfe\xb5\x72\xc6\x5f\x7c\x2a\x2c\x35\x44\xde\xb1\xc5\xb6\x8c\xb2\xe0\x3a\x21\x7f\xec\x7b\xf0\x33\xe5\xca\xcb\x0c\xe9\x3c\x9f\x5e\xc6\x88\x6a\xae\x23\x83\xf9\x9e\x97\xa8\xd3\x42\xde\xc4\x59\x9e\x9d\x9d\xe4\x0b\xdd\xa2\x4e\xa8\xd0\xde\x14\xd2\xc0\x9a\x44\xb8\x12\xdf\xa4\x7a\xa1\xb7\xac\xa3\xb7\xa9\x34\xad\x9e\x41\x5c\xe5\x73\x7c\xbc\x75\x8c\x11\xce\x69\x93\x48\x08\x72\xcc\x66\x07\xef\x87\x24\x63\x2b\x1e\xbb\xd2\xf8\xf0\xad\x03\x58\x94\xb2\xf6\xb5\x15\x6b\x9a\x78\x54\x2e\x80\xb0\x03\x4a\x51\xbd\xee\xfb\x0e\x0c\xf9\xfe\x91\x48\x1a\x10\x15\xd2\x81\xac\x09\xcf\x37\xe4\xef\x0a\x77\xd7\x70\x79\xe3\xaf\x5c\x79\x42\xcc\xb1\x4d\xf4\x39\xa6\x08\xe3\x18\xdd\xc8\xc1\xf5\xd8\x04\x1f\xb7\xb0\xa6\x62\xdd\x07\x2b\xa8\x46\x1d\xab\xae\x2e\x78\x10\x51\xa2\x1f\xff\xbc\x6b\xe8\xb5\xfd\xe8\x70\x85\x06\x86\x56\xca\x70\xe9\x35\xe3\xe2\x45\xeb\xe9\x61\xa9\x11\xa9\x40\xed\x17\x1a\x4e\x91\x1e\x65\xb1\xa8\xd3\x1d\x0e\x4f\xaf\x1a\x0f\x87\xa9\x1e\x8f\x86\xaa\x1b\x10\x06\x30\x68\xdf\x7f\x79\xc3\x41\x91\xb7\xe4\x48\xe0\x0d\xa4\x4c\x4b\x02\x08\x1e\x3d\x9e\xa4\x10\x15\x34\xe8\xf5\x4c\x75\x84\x7c\x4a\xf9\xba\x76\xb2\x64\xf5\x16\x52\x54\x9e\x40\xca\xfc\xac\x3c\x26\x8b\xf5\xc9\x61\x8a\x2c\x23\x3f\xc2\xa8\x63\xd8\x37\xf9\x8a\x43\x2d\xb6\x02\xa1\x41\x64\x77\xbc\x88\x77\x7a\x71\xeb\x5f\xec\x12\xe0\x75\x00\x11\x13\xae\x43\xc7\xe7\x68\x8a\x2c\x82\x58\x85\x33\x5d\x67\xe7\xf1\xfb\x29\x2b\xa7\x8b\xad\x32\xe6\x20\xc5\xef\x54\xd4\xde\xed\xf9\x26\x4d\x39\x4f\xaf\x54\xc7\x2e\xcb\xd2\xd9\x0b\x19\xb5\xfc\x1c\xc3\x35\xcb\xc7\xba\x65\xf9\xfd\x14\x5f\xb0\x72\x9c\x01\x24\x10\x9f\x3f\xa0\x1b\x93\x0c\xe6\xc3\x8a\x1b\x2e\xc6\xb2\xc8\x46\xad\x2a\xb6\x0d\xc2\x58\x5f\xd5\xc3\x8f\x98\xe9\x8c\xe6\x6d\xcd\x69\xbf\xc4\xbe\x77\x37\xa6\x4b\xdb\x57\x3f\x7a\xeb\x9e\x9c\x5b\xe7\x79\x4b\xd7\x86\x7d\x6f\xa0\x38\x9b\x48\x46\x79\x36\x8a\xcb\xae\x35\xfb\x3d\xdb\x28\xcd\xe3\xd8\x54\x72\x3d\x19\xc4\xa6\x9a\xeb\xb9\xbb\x6d\x52\xc5\xe1\xc6\x74\xf6\xe0\xf3\x30\xc9\x15\xa4\xe3\x10\x6a\x08\x54\xcb\x58\x37\x2b\xd4\x7e\xb0\x37\xd8\xbd\xa8\xdf\xa6\xab\x83\xef\x20\x0e\xae\x30\xfa\x4e\x3c\x3f\x72\x56\x97\x11\x1f\x7f\xb0\x4a\x0e\xf9\x97\x8c\xcc\xa3\xde\x97\x66\x6b\x55\x9e\xbf\xe8\x7a\xdd\x9b\x5a\x34\x99\xd1\x7b\x2d\xa5\xf1\xfa\x36\x7b\xf7\x60\x65\xfe\x96\xa7\x59\x77\x75\xd5\xaf\x5c\x5f\x8a\x23\x72\x23\x3c\xf1\xe3\x0d\x94\x0d\xbb\xb0\xcc\x7b\x7d\xde\x23\x7e\x54\x23\x26\x63\xd7\xea\xab\x46\x61\x20\xde\x90\x01\x6e\xd9\x36\xde\x5a\x10\x8d\xdb\xad\xf0\xf6\xf5\x46\xc2\xb8\xb6\xd0\xa6\x05\xdb\x7e\x92\x1f\x19\x76\x2d\x63\xf1\xb0\xd5\xa2\x82\xff\x50\x9c\x19\x26\x96\xd9\x60\x86\x5b\xe3\x98\xdc\x30\x50\x04\x43\xa7\x5c\x16\x3f\x15\x52\xff\x42\x8c\x9e\x1d\x8f\x8e\x46\xfd\x48\x86\xa8\xe4\xfc\x44\xa4\xef\xcb\x64\xe2\x89\x80\x14\xf9\xad\xa8\xfc\x07\x7b\xca\x06\xc5\x71\x3a\x29\xbb\x70\x0b\xdc\xc5\xa8\xca\xf2\x8c\x56\xa2\x19\x65\xa5\x22\x67\x6f\x22\xed\x64\x9a\x94\x89\x1a\x87\x09\x92\x64\xa7\x13\x55\x5d\x80\x65\xb0\xf3\x2b\x79\xaf\x44\x2e\x6d\xd8\xea\x49\x8c\xab\x0c\xac\x70\x55\x72\x91\xd5\x70\xbd\xf0\x13\x08\xdc\xe0\x4a\x3f\x9f\x7b\x44\xa6\xcd\xe6\x88\x4c\x75\xc5\x37\xaa\x7d\xec\xec\x0a\xb0\x12\x2d\x01\x5a\xb1\x1f\x5c\xb1\xc3\x0d\xfb\xe4\x09\x94\x86\x3f\x7c\x06\xf1\x9f\x2a\x69\x99\xbb\xfe\xb7\xb8\xd4\xe8\xdc\x6a\x0b\xde\x68\xac\xa5\xc6\x8f\x15\x6c\x0a\x40\x6d\xcd\xb5\x8a\x03\x2a\xff\x42\x6c\x69\x9f\x01\x1c\x58\xd4\x1b\x02\xe0\xf1\xab\x0c\x51\x04\x41\xf7\x35\xbb\xa7\xb6\x0f\x2a\x07\x60\x79\x4d\x04\xcf\x84\x2d\x5c\xb2\x88\x58\x75\x47\xc2\x4d\x71\xb2\x2e\x18\x1a\xeb\x6e\x1b\x03\xfc\x65\x63\x5d\xae\x85\x6d\x93\x4f\x89\x06\x03\xf6\xbf\xfa\xe7\x47\xf8\xcb\x23\xf0\xea\xc2\xab\xbd\x47\x7b\x10\x68\x84\x1c\xb2\xfa\x51\x91\x4b\xd7\x19\x7d\xc4\x65\x7c\x71\x62\xb2\x74\x0d\xa0\x5c\x5a\x76\x0a\xb1\x38\xc1\x93\x2a\x9e\x9f\xc1\x62\xc1\x17\xb6\x0b\x20\xb7\x0e\xc6\x31\xc6\x28\xd2\x11\x44\xe9\x26\x29\xef\x44\xec\x25\xe9\x6c\xca\x5e\x78\xb0\x5f\x5c\x09\x87\x37\x5a\xfb\x3a\x0a\xfa\x72\xdf\x56\xae\xdc\x54\x87\xed\xc6\xa0\x9a\xb7\x7c\x48\x4d\x63\xc9\x98\x36\x0e\x5b\x33\xd1\xa5\x06\x0a\x47\xa1\x00\x36\x37\xa0\x73\xfb\x3e\xec\x41\x48\x7f\xa8\x82\x75\xd8\xa9\x59\x3d\xea\xcf\xee\xa7\x8c\xe7\x14\xaa\xa9\xc1\x0d\x32\x58\x4f\xa5\x0a\x65\xea\x97\x04\xef\x8e\xc9\x03\x08\xa8\xdc\x94\x13\xc2\x06\xe5\x68\xa6\x27\x5d\x7a\xec\x2e\x06\x3c\x3f\x65\xb1\x22\x29\x9d\xf7\x65\x42\x31\xae\x6a\x15\xb1\xfa\xe9\x4e\x93\x9a\xf1\x1a\x98\xc0\x90\xab\xe8\x05\xfe\x54\xde\xd2\xc3\xd6\x05\xaf\x60\xe0\xf8\x7f\x75\x53\xfc\xdd\xc0\x62\x47\xe6\xe3\x5e\xa4\x00\x14\x17\xc2\x7f\xef\xa9\x54\xcd\x72\xe8\x87\x4e\x67\x8c\x46\xfd\xb4\xe5\x48\x78\x0c\x84\x3c\x33\x3d\x6e\x4e\x68\x30\x91\x89\x2a\xf8\x73\x05\x47\x16\x5f\x18\xc1\x88\x07\x0d\x07\xc6\xfc\xed\xf5\xc9\xe8\xf5\xc9\xe8\x97\x7d\x32\x2a\x8f\x45\xe5\x95\xdf\xff\x8a\xe7\xfb\xae\x34\x62\x38\x2a\x01\x5f\x83\x23\x87\x20\x74\xb8\xe7\x2b\x5f\x54\x46\x3d\x18\x75\x01\x7c\x16\x59\x3d\xf0\x02\x04\x1c\x4f\x85\x70\x24\x34\x89\xfc\xb4\xa0\xd7\xdf\xd1\x50\x27\x88\x04\x2a\x52\x82\xff\xd3\x54\xe8\x3d\xe7\x94\xb5\xe2\x9f\x6b\xac\xc8\x13\xd5\x32\x77\xdf\x50\x96\x26\x4b\xad\x70\x76\x6d\x93\x28\xd8\xa1\xe4\xfb\xeb\x26\xa3\xa3\x6d\xa8\x1d\xa3\x13\x5a\xc6\x52\x29\x22\x91\x61\x96\xfa\xd0\x55\xf6\x45\xab\x21\xb2\x43\xec\x4d\xd0\xd4\xdc\xc3\xda\xd7\xbd\x3a\x11\x52\xf2\x7d\x7e\xa3\xd5\x3a\x33\x52\x8f\x2b\xe9\x76\x50\xe5\x02\x51\x13\x38\xad\x3a\xfc\x71\x3d\x61\x73\x6d\xdf\x97\xca\x56\x1f\x26\x41\xcc\x8d\x60\xa0\xe0\xf8\x2c\xd6\x5c\x0c\x1c\x87\x3d\x60\xf3\xd5\x57\x82\x55\xfd\x21\xf8\x55\x1e\x8f\xa3\xf1\x59\x16\x9f\x08\x84\xe6\x59\x52\x60\x6b\x72\x42\x4c\x43\xf2\xad\xec\x5c\xdb\x09\x10\x4a\xf9\xd7\xab\xc6\x3d\x28\x98\xad\xa3\xa4\xdc\xd3\x4a\x72\x20\x82\x33\x4c\x8c\x11\x58\x9b\xc2\x03\x70\x63\x82\x9c\x12\xa5\x26\x57\x9f\x7a\x58\x96\xfe\xca\xa3\x17\xca\x2e\x04\x77\x81\x77\x4c\x58\xaf\xe0\x6f\x04\x37\x84\x51\x83\x1e\x7a\x37\xab\x48\x78\x87\x23\x34\x32\x04\xf5\xf1\x00\x6f\x44\xab\x83\x94\x63\xb1\x1c\x42\x66\x03\x63\x35\x75\x12\x9c\x48\x94\x2d\x8a\x58\x96\x4f\xf9\x6a\x77\xa5\x51\xde\x31\xf8\xab\x37\xbe\x7f\x90\xaf\x9f\x43\x55\x72\x91\x81\x0d\x4d\xfe\x94\x60\xbe\xac\x14\x3a\x26\x08\x5c\xaf\xc3\x6e\x0e\x9d\x6e\xf7\x3d\x38\x58\x02\x06\x0a\xbe\x7c\x98\x32\xe7\xf5\xf8\xf9\xb1\x7a\xf4\x59\x01\xa9\xbb\x17\xc1\xd6\xf0\xfc\x6a\xb8\xd4\xb1\xd6\x40\x97\xeb\x85\x7a\x77\x91\x1a\x65\x29\xdb\x3e\xc5\x14\x1d\xa2\x40\x78\x95\x74\x18\x42\xb8\x43\xea\xca\x54\xb3\x33\x1e\xd3\xb5\x08\x6d\x44\x83\x03\x89\x02\x2e\x4b\x2c\x66\x98\x21\xb5\x11\x64\x19\xa9\xe8\x10\xec\x1d\xf9\x5b\x29\x6c\xc9\x30\xa6\x62\x2f\x67\x57\x23\x9e\xe4\x47\xc5\x4e\xf9\xb2\x14\xf2\xde\x8a\x6b\x69\x2c\x92\xe9\x44\x27\x2a\x72\x64\xf7\x97\x25\x0b\xb7\x6b\xe1\x9c\x1c\x2a\xf0\x62\xf8\xa8\x4b\x76\xa2\x18\xd9\xb3\xa0\xb0\x73\xd3\x0e\xed\x0b\x66\x98\x05\x76\x0f\x0a\xa3\xe6\xe9\xf0\x0c\x68\x65\x10\x62\x1c\x98\x21\x9a\xe1\xf1\x98\xe0\xce\x8f\xf4\x64\xf4\xb4\x1c\xd1\xb3\xb1\x50\xd5\xe5\x2d\x51\xe8\xa3\x44\x1b\xba\x4d\xe2\x41\xb7\x4a\x54\x6e\x47\x18\x09\x63\x30\x18\x44\xbf\x2d\x28\x10\x30\xb4\x09\xbc\xd7\xd3\x97\x2b\x2e\x46\xd6\x5c\x8a\x74\x02\xad\x8a\x0d\xca\x5e\xe9\x7a\x18\x61\x1d\x93\x32\x83\x8a\x29\x39\x72\x36\x5d\xd3\xa4\xcb\x3b\xb6\xdb\xa7\xd5\xbf\xe6\x38\xac\x1f\x8f\x74\x67\xf9\x74\x4a\xe4\x13\x26\x5b\x49\x9b\x06\xcc\xa5\x4b\x6d\x12\x41\xb3\x6d\xf6\x54\xbb\xe1\x5a\xc4\x92\x57\x90\x8b\x9c\xd1\xdc\x9b\x53\xe5\x64\x01\xa4\xa7\xc6\x1a\x9a\x84\xd0\xb5\xa9\x10\x4d\xe4\xad\x35\xf9\xb6\xd4\x71\x31\xca\x30\x6e\xca\x38\x34\x63\xbc\xfe\xc1\x49\x30\x95\x0c\x55\x21\x9f\x74\x2e\x85\x1e\x0b\x45\x4b\x13\x69\xa5\x3d\x00\xfb\xe4\x06\xa3\x06\xc6\xf3\x5c\xd0\x10\xf0\x19\xd3\x13\xa2\xc1\x21\x15\x01\x3d\x2d\x81\xc3\x05\x54\x39\x07\x40\x71\x38\x34\xb3\x97\xcc\xa4\xa3\xfd\x3e\x61\x0a\xc9\x71\x77\x7c\x40\x00\xe7\xfd\xc8\x4a\xee\x1b\x62\x94\x39\x3c\x8c\x11\x19\x88\x91\x7e\x61\xb3\x56\xaf\x19\x7a\x24\xee\xc8\x64\xfd\xc8\x3d\x23\xd0\xcb\x2d\x70\x3a\xd0\x97\xda\xd4\x43\x38\xd1\x12\x95\xa0\xbf\xf6\xc3\xc0\x45\x47\x64\x76\x64\xc1\x66\x07\xae\x16\x3e\x98\x85\x6f\x19\x53\x59\xa5\xad\xab\xd2\xd8\x13\x36\xaa\xd9\x9d\x61\xbe\x04\xf0\x6f\x38\x62\x7c\xa5\x37\xa6\x11\x36\x8d\x18\xec\x88\xbe\x0c\xda\x43\x83\x9d\xc0\x20\xd5\xcc\xfb\x57\xc6\xf4\x0a\x91\x9e\x35\x3a\x43\x6e\xb9\x0f\xab\xf2\x0d\xdb\xf3\xb2\xf8\xb1\x93\xfe\x5d\xd9\x6f\xb8\x58\x2d\x4f\x9d\x38\x0e\x78\xac\x30\x62\x92\xe9\xd8\x0a\x8d\x17\x66\x8e\x0d\xcb\xbe\x3b\x3a\x0c\x88\xb9\xa3\xc3\x46\x3e\x48\x6d\x52\x30\x57\xa1\x17\xd6\x50\xaf\x13\x90\xd4\x67\x2f\x35\x65\xc3\xdd\xa8\xb7\x3b\x5b\x4b\x1b\x88\x60\xec\xc7\x01\xe4\xa1\x1a\xe6\x09\x1e\x32\x80\xce\x31\x13\x42\x08\x3a\xb8\xe9\x49\x86\x40\x46\x78\xdf\x15\x28\x54\xc7\x82\x31\x15\x89\xa1\x03\x30\x14\x02\xa9\xa7\x47\xc6\x15\xdd\xfa\x22\x8b\x0b\xf0\x99\x82\x83\x0b\xaf\x8f\x2e\x45\x85\x78\x72\x68\x76\xb4\x8c\x25\x72\xeb\x27\x28\xd8\x0c\x77\x19\x63\x23\x31\xfe\xc4\xf0\xc4\x91\x91\x0e\xe3\xa9\x98\x98\xf1\x99\xb9\xaf\x6e\x04\xc5\xe2\xf2\x94\x66\x0b\xb2\x4a\x7a\x6d\x1a\x97\x20\x76\xa7\x35\x1d\x03\x6e\xdd\x8f\xc8\x65\x56\x26\x71\xf1\x50\x58\x48\x90\xa2\x85\x14\x44\x63\x4b\x4f\x4e\x92\x71\x2a\xa4\xa1\xe9\x99\xdb\xac\x24\xf7\x51\x5b\xda\xb6\xa5\x13\xac\xbe\xcb\x4e\x84\xad\x41\xba\x9b\xa4\xe3\x44\x45\x2c\x1f\xb6\x1f\x1a\x0c\x3b\xcb\x3d\xf5\x5e\xe9\x70\x37\x73\x9b\xb7\x91\x63\x1f\xeb\x87\x32\xc7\x58\xcc\x91\x8f\xc6\x99\x27\x79\x5c\xea\x0f\x84\xe1\x9a\x9d\x09\x9f\xb7\x1d\x08\xdf\x77\x9b\xc7\xe3\x49\x0e\xf9\x4c\x09\x0e\xf6\x40\x1a\xf8\x3b\x5e\x2d\x3f\x11\x92\xaf\x5a\x00\x71\x71\x96\x8d\xb4\xf2\x13\x12\x8c\x42\x7c\x7b\x91\xe1\xdd\x69\x0b\x01\x4c\xc6\x70\xb0\xe5\xf1\x2e\xee\x08\xbf\x4c\xcd\x96\xe0\xef\x61\xf4\xc8\x79\xca\x3d\x14\x3c\xc1\x93\x29\x82\x2e\x27\xba\xa4\x2b\x94\x3b\x3b\x6a\x85\x1f\xa5\x40\xf2\xee\xc4\x70\xc6\xb4\xd0\xf7\xfa\xce\x12\x19\x7e\x05\x2c\xf8\x26\x4a\x97\x29\x77\x7a\x9c\xa0\x33\x86\x1c\x7d\x2f\x22\xa6\x5a\x08\x78\x9b\xad\x06\x37\x6a\x8f\xd8\xf5\x32\x0b\xed\x1e\x21\xf4\xcb\x1a\xdd\x09\xe5\xfb\x31\xbe\x90\x12\xe0\x6f\x1f\x31\x3e\x8f\x23\x8f\x8b\xf6\x1d\xef\x3d\xc1\xc6\xab\xda\x85\x3f\xce\x98\x6a\x74\x0b\xac\x65\x08\x7b\x82\xdf\x3f\xec\x63\xf8\xc1\x12\x1b\xc4\x53\x00\xdc\x81\xb5\xa7\x23\x2f\xcc\x94\x14\xc4\x77\x1f\x51\xc2\x50\xd2\x58\xb7\x37\xa0\x1d\x79\x14\xab\x80\x85\x18\x56\x05\xee\xb8\x88\xd5\x2d\x6a\xc9\xf2\x45\xa1\xa3\x17\x4a\xc7\x00\xd8\xed\xdd\x48\x44\x54\x8d\x14\x76\x3b\xde\x58\xe0\x0f\x83\x4a\xe4\xc6\x4b\xaf\x0d\x01\xf9\x3e\x74\x50\x43\xf3\x1c\x5e\x62\xde\xce\x1b\xf8\xb1\x77\x44\x4a\xb8\xf5\xde\x7e\xab\x39\x20\x3d\x3f\xe8\x6f\xad\xb7\x39\x02\xed\x40\xfc\x14\xb0\x78\x8f\xca\xce\xca\xf5\x81\x68\x74\x7d\x20\xfa\xd7\x3d\x10\x35\x57\x45\x99\xc9\xfa\x22\xf7\x45\x25\xf0\x12\x27\x99\xa1\xb7\xdf\x5a\x5f\x31\xcd\x26\xe9\x51\x10\x8e\xb2\x14\xe0\xee\x61\xec\xbc\xe9\x02\x0f\xc0\x07\xde\x69\x41\x6b\x32\x3d\x34\x45\x3e\xd2\x74\x98\x79\x28\x5a\xa1\x08\x06\x8e\x17\x23\x01\x3d\x48\x8f\x1c\xa3\x3e\xf7\x66\x24\x8b\xf3\x07\x82\xf8\xa0\x61\xcf\xed\xa0\x55\x26\x9d\x7b\xe2\xa2\x9d\xb1\xa2\x0d\x4b\x2a\xa2\xde\x05\xaf\xa1\x95\xb9\x2e\xab\xc0\xee\x69\x23\x50\x5a\x3c\x17\xdc\x86\x8e\x41\xd9\xe9\x84\x55\xf7\xa1\x49\x77\x1a\x38\xe4\x25\x40\x91\x90\xd1\xef\xe1\xf8\xca\xcb\x93\xba\x69\x38\x33\x89\xe7\x62\x9d\x16\x65\x87\x75\x0d\x4e\x4c\x1e\x25\x93\x78\x31\x0d\x1e\x9c\x34\xf5\x11\x76\x64\xb7\x1d\x7d\xe4\x1b\x7c\xbd\x05\x48\x66\x30\x66\x2d\x72\xdf\x4c\xfe\x50\x4c\xf0\x1c\x87\x77\xc1\x19\xc5\xa7\xe8\xbe\x7b\xa3\x8b\x04\x14\xa8\xa5\x62\x8e\xad\x46\x03\x15\xb2\xec\x00\x1e\x14\x6d\xfd\x9c\xbc\x0b\x8c\x5c\xae\x2a\x1a\x6c\xc1\x9c\x22\x21\x02\x89\x15\x1a\x10\x4c\xfa\x23\xed\x47\xa0\xfd\x02\x60\xdd\xfd\xfc\xf8\x9f\xce\x72\xc3\x3a\x40\x2e\x0e\x2e\x34\x65\xcb\xb7\x42\xec\x7a\x8e\xef\xea\x88\x5c\x59\xfb\xfd\x3a\x43\x26\x51\xe5\x5a\xc0\x8f\xff\xd8\x11\x2c\x7e\x0a\xba\xb6\x69\x68\x67\x84\xcf\x2e\xb0\xc0\x67\x1c\x3e\x10\x71\x4b\x55\x8b\x7d\x92\x61\xa8\xc2\x47\x8f\xb6\x1c\xc4\xab\x2e\x73\xf9\x5e\xb4\xd8\x3c\x4f\xd2\xa3\x63\x12\x1c\x75\xf0\x62\x69\xa4\xf2\x5a\x2e\xf3\xc6\x76\x45\xcd\x56\xab\xfb\x9d\xa3\x58\x90\x96\xd8\x4c\xe0\x3e\xa2\xf8\x0d\xff\xe0\xf4\xc1\x8f\x2c\xcf\x44\x46\xe0\x76\xe2\xef\xc9\x59\x80\xda\xd4\x75\x41\x91\xdb\xf6\x86\x22\xd6\xe4\xe1\x90\x6a\xb8\xcf\xfc\x29\x1e\x25\xa3\xf4\x24\x9e\x76\x39\x80\x7f\x43\xcc\x3e\x44\xfd\xd8\x44\xcc\x42\x69\x5e\x35\x2d\x87\xaa\xbe\x7a\x92\xbe\x28\xd5\x5e\xd3\xeb\xa7\xa4\x57\x29\xc4\x78\x04\x8b\xe7\xa5\xea\xed\x1f\x49\xad\x41\xd1\xa6\x35\x9d\xbe\xb3\xc5\x19\x99\xbe\x62\xc9\x30\x8d\x94\x59\xbe\xeb\xa9\xcd\x4f\xfc\x1e\xf0\xed\xef\x86\xd2\xa6\x8d\x05\xcb\x06\xd0\x11\x2d\x72\x85\x3f\x1b\x40\xdf\xd9\x80\x74\x86\x0b\xbc\x1d\x16\xae\xde\x83\x0a\xb6\xe1\x42\x29\x63\xdd\xbb\x01\x92\x54\xb8\x10\x66\x19\x48\x78\x6f\x20\x08\x27\x32\x2c\x28\x24\x5f\x06\x6a\x44\x3b\x96\x6f\x4a\x28\xe7\xb5\x20\x38\xbf\x2a\xab\x34\xe9\x77\x4b\x93\x92\x7a\x65\xe6\x22\x24\x25\x1f\xbc\xa9\xa5\x2c\xfd\xfe\xd2\x32\xe4\xe5\x56\xec\x84\x5d\xe1\x0f\xe7\x98\x87\x72\x1a\xe8\x2d\x04\xe5\xcf\x59\x00\xca\x50\x9e\xca\x6c\x41\x7e\x95\xa0\xd5\x4d\x56\x10\xa2\x7e\xcf\x65\x71\xb2\x10\xf2\xb9\x50\x35\x7f\x8a\x8b\xd7\x05\xde\x4b\xab\xaa\xca\x83\x75\xea\x3a\x6a\xac\xe1\x48\x97\x53\x83\xb7\x5d\x0d\x14\x1c\x98\x8c\x1c\xe9\xd3\x64\x44\xf7\xad\x4f\xdb\xc5\x0d\xec\x43\x41\xc3\x52\xe0\x76\x2b\xc0\x36\x59\x91\x64\x4b\xad\x16\x00\xce\x6e\x7b\x92\x47\xa9\xbb\x96\xca\xb1\xc2\x16\x34\x6e\xd7\xc4\xfd\x53\xb0\x06\xed\xa0\x02\x31\x81\x55\x68\x1f\x8c\x71\x27\xb5\x57\xf1\x87\x80\x30\x3f\x4f\xd2\x93\xb4\x0c\x4c\xa1\x0d\x20\x71\xa5\x13\x2b\xe6\xdd\xca\xb7\xca\x14\xe9\x1f\x21\x26\x68\x32\x2d\x68\x08\xe6\x56\x94\xf1\xc9\xac\xb2\x88\x86\x30\xeb\x8a\x32\xb2\xaa\x95\x6b\x65\x57\x55\xab\x55\x63\xd6\x99\x71\x3a\x99\xa4\x23\x21\xf6\x9c\x05\x79\xa8\x0b\x64\x0f\x24\x2f\xe3\xe9\xa3\x36\x15\x38\x90\x5c\x48\xb2\xd7\x8c\x04\x37\xcb\xdc\x5e\x39\x7e\xb6\x2f\x82\xa4\x65\x72\xe2\xac\x1a\x81\x3f\xd7\x3b\x0e\xa1\xfc\x73\x48\x6b\x7d\x85\x36\x73\x2a\xd8\xb4\xd0\x0e\x49\xb5\x6e\xb1\xcc\xc4\xb2\x0d\xae\x27\xbe\xb6\x43\xab\x49\x94\x33\x56\x14\x7f\x49\x61\xbd\xd6\xb2\xe2\x15\xf2\x45\xc5\xac\xd7\x02\x4d\xe2\xcb\xe2\xeb\x7a\x9c\x4e\x46\x80\xc6\xdc\xa2\xf7\x78\x9d\x1e\xe3\xb6\x2a\x6e\xb1\x03\x04\x2b\xb1\x9a\x10\xff\x05\xaa\x56\xa9\x15\x55\xea\x42\xb6\xd0\x89\x16\xf9\x66\x05\xe9\xf4\x38\x2d\x80\x2f\xce\x20\xac\xe6\xf2\x1a\xd2\x73\x51\xac\xbd\x6a\x54\x3d\xd9\x96\x6e\x64\x55\xca\x27\x1a\x3a\xc9\x99\x27\x7c\xc3\x5b\x21\xe8\x1e\x7f\xdf\xb2\x76\xf0\x2c\x89\x64\x4c\x2a\xcb\x69\x50\x8c\x57\x99\x1c\x16\x2e\x76\xbe\xca\x21\x40\x68\x52\x5d\x86\x01\xf1\xb2\xb3\x79\x9a\xcf\x53\xc6\x51\xbc\x82\x0a\x42\xcd\x9b\x18\xcf\x84\xbf\x1f\x64\x39\xc4\x12\x6b\xa1\x4e\x52\xb4\x0c\xce\x53\x58\xba\x68\x8f\x7f\xfd\x10\xed\xb3\xcf\x83\x68\xa8\x4f\xa3\xcf\x4d\xab\x64\x4a\x25\xab\x2a\xdc\xfd\xc0\xdb\x01\x4a\x8d\xad\xab\xbe\xde\x9b\x9d\x5e\xc0\x03\x62\x8a\xf2\x6c\x7a\x46\x61\xfd\x4b\xcb\xc9\x5e\x39\xba\x93\x43\x7b\xe8\x7e\x86\xf2\x76\x17\x3d\x77\xee\x5e\x70\x3f\x77\x57\xe3\x82\x3e\xb6\xe2\x5d\xda\xac\x8f\xf4\x2f\x9d\x0d\x83\xdc\xac\x89\xd2\xc3\x64\x6d\x9b\x87\xeb\x09\x5b\xd2\x35\xe2\x37\x79\x37\x4b\xe7\x67\x81\x15\xcf\x72\x39\xb9\x15\x14\x05\x24\x08\x0d\x79\x55\x4b\xc0\x01\x0d\x2c\x00\xa4\xec\x90\x2c\xe1\x40\xf4\xee\x85\x56\xe5\x8b\xf8\x54\x91\x8c\x4c\x09\x82\xb1\xea\x77\x8a\x51\x9a\xba\xcb\x57\x95\xe1\xbb\xed\xdf\x0b\x89\x38\x0d\x67\xde\xd1\x30\xab\xc2\x34\x80\x61\x71\xf1\x2d\xab\x10\x73\x10\x65\x96\x58\x11\xb8\x36\xf9\x6a\xac\x5c\x8c\x66\xb9\x5d\x62\x25\x39\xd6\x5c\x42\x51\x3b\xfa\xd7\x4c\xd5\x35\xc0\x58\xca\x96\x13\xcd\x12\x0b\xd1\x69\x0c\x9c\x80\xc3\xc1\x4c\xd7\x3c\x67\xa1\x10\x00\x10\x62\x1e\x7e\x81\x58\xc4\x5d\x7c\xb2\x40\xa6\x3c\x4a\xe7\xe0\xa1\x19\x32\x57\xcb\xc8\x50\x08\x28\x6b\xd4\xaf\x16\x18\x5b\x38\x17\x74\xcc\x4b\x32\xaa\x07\x81\x6a\xad\xe0\x63\x81\x8a\x5a\x54\x21\x7b\x66\xd7\x24\x8f\x5d\x1d\x57\x00\x19\x1e\x5a\xc1\x76\xa4\x39\x9d\x2d\xf4\x97\x67\xd9\x48\xd0\x4c\x70\xad\xcb\x78\xcc\xec\x00\xe1\x86\x9d\xc4\x3a\xa5\xc2\xd3\x30\x86\x2d\x83\xd9\xa0\x2f\x99\x68\x02\x55\x8d\xa0\x86\xe7\x83\xd9\x21\x81\x64\x74\x9e\x86\x0a\x38\x94\x5d\xfe\x38\x3d\x3a\x16\x22\x7e\x43\x79\x0e\xc5\x68\x47\xe6\xfe\x9e\xe5\xa7\xd9\xcb\x12\xce\xa3\x02\xe1\xe1\x58\x6e\x75\x03\xbc\x8a\x7b\x6e\x0d\xb3\x05\xdc\xbc\x6b\xaa\x82\x43\x55\x28\x9d\x26\x4a\x50\x45\x20\xf8\xa6\x73\xb0\x61\x23\x44\xdf\xd4\x53\x53\x41\x43\x49\xeb\x88\x64\x18\x48\x63\xb0\x21\xdd\x60\x58\x9d\xc5\x4a\xba\xbc\x64\x18\x4e\x66\x25\xac\x9d\x6f\x18\x48\x23\xd8\xaa\x53\xd4\x61\x65\x0e\x2f\x17\x1e\x50\x75\x5e\x45\x59\xd7\x2c\x15\xa8\xc2\x05\xb1\x7a\x6f\x29\xff\xc3\x60\x2a\x87\xe7\x5a\xcd\x30\x90\xc6\x61\x1d\x34\x06\x12\x39\xb4\xcb\x85\x86\x15\xe9\xc4\xbd\x2c\xd7\x1b\x3a\x4f\x58\x1d\x6e\xdc\xad\x0a\x28\x03\x2c\x7c\x75\xb8\x05\x51\x62\xb6\x36\xae\x83\x11\x5c\xfb\xde\xfc\xd7\xf8\xde\x48\x4a\xbf\x8a\x57\x45\x96\x0b\xc1\xde\xd2\xe1\x86\x1e\x3d\xb1\x3d\x69\x28\xed\x23\xc4\x72\x6f\x1f\x7d\x5d\xec\x42\x6b\xce\xfb\x84\x78\xad\xd2\x7d\xdd\xc4\x8f\xc9\xae\x7c\x83\xed\x2b\x7c\xf0\x90\x53\x4d\x2c\xf6\xd0\x4b\x4e\x6f\x68\x73\x93\xb1\xc2\xf9\x13\xa6\xcb\xc7\xf1\x36\x95\xca\xdd\x81\xd7\x4a\x49\x97\xab\x16\x9f\x3f\x8b\x51\xad\xc3\x3a\xd5\x37\x87\x51\x4f\x9c\x4a\x10\xf9\xc9\x21\xae\x24\x8e\x3c\xec\x0f\xee\x64\x58\x11\x07\xf1\x18\xd8\xdc\xc4\xe1\x9a\x05\x3c\x2b\xc6\x64\xdd\x0b\x3c\xdc\xab\x64\x7c\xf3\xca\x19\x5e\xe7\x47\x5e\x4f\x77\x47\x20\x70\x25\x06\x8b\xbf\x21\x85\xb7\x76\x1d\xe6\xc2\x58\x6d\xa7\x79\xf7\xbe\xf7\x48\x49\xe5\xf0\x37\xdb\xe4\x25\x35\x7f\x70\xe1\xb1\xb9\x57\xd0\x6b\xc4\xc4\xae\xc1\x43\x4b\x44\xd4\xbd\xcc\xc6\xdf\x92\x0d\x3d\x3f\x94\xc2\x33\x7c\xf3\xb9\x1f\x19\x10\x35\x20\xc1\xcc\xe0\xa5\xa3\xd0\x63\x6d\xe6\xb5\x68\x91\x87\x1a\x54\x11\xbd\x47\xfd\xe9\x1c\xda\x13\xfb\x16\xb9\xe6\x5b\xc1\x03\x03\x53\xc5\xdb\xa9\x9c\x23\xa3\xe4\x12\x14\xa2\xc8\x73\x11\x88\x0d\xe2\x41\xc7\x27\x7f\x6e\x34\x08\x59\x51\x39\x70\xf3\xc4\x58\x70\xe6\x11\x26\xd8\x6a\x63\xbc\xc1\x25\x46\xa7\xee\x23\xc9\xb9\xbb\xd1\xf5\xde\xf0\x8b\x7b\x3a\xb2\x34\xe8\xc5\x9e\x89\xde\x39\x31\x90\xe0\x14\x25\xb6\x16\xde\x1c\x4a\x58\x65\x9c\x02\xce\x65\x48\xd4\xd5\x15\x76\x14\x21\xc2\xf9\x4b\x05\x21\xdb\x17\x50\x40\xf7\x95\xcf\x68\xd5\x3c\x76\x65\x9e\x1e\x6c\x13\x49\x55\x19\x65\xd0\x25\x85\x93\x8a\xf5\x16\x40\xfe\x9c\xc0\x02\xe4\xe5\x6d\x1a\x26\x82\x72\x45\x54\x55\x21\x1c\xa8\x56\x8d\x87\x4e\x2d\xe5\xe9\xf6\x6b\xc9\xce\x7a\x3d\x72\x79\x86\xb1\x2c\xbf\xb0\x1f\x7d\xac\x78\x55\xf2\xdc\x70\x73\xbe\x7c\xba\x81\x27\x1e\x4b\x1d\xd6\xd4\x0e\xe0\x6e\x3d\x01\x79\x3f\xaa\x08\xce\x1e\x8a\xf1\x2d\xaf\x1f\xb0\xe1\x5a\x6f\x4b\xd6\xb8\xc4\x54\x3c\x3e\xa2\x50\x7f\xb1\x47\x16\x82\xc5\x6b\xe7\xfd\x42\x4f\x2d\xc8\x28\xd1\xeb\xfd\xe8\xae\xb2\x06\xd5\x34\xb1\xc8\x66\x62\x9f\xdc\x23\x3b\xb4\xe5\xf4\x85\x49\x96\x6d\xc8\x4e\x32\x5d\xb0\xec\x14\xb9\xaa\x8a\x7e\x68\xd2\xbb\x1f\x6d\x46\x3f\xa8\x44\x15\xc8\x3a\x52\x7a\xe0\x3d\x4d\xc5\x3a\xfc\x74\x55\x1c\x6b\x2e\xe4\xf4\x65\x71\x7b\x46\xa5\x2d\x85\x47\xe1\xd5\x4f\x98\xc1\xd3\xef\xc3\x50\xac\xe5\x87\xf8\x82\x6f\xcc\x1e\x4d\x56\xc8\x72\x26\x19\xb6\x2c\xbe\xb8\x07\x83\x81\x5a\xdf\x0f\xdd\xb2\xce\xe6\xe3\x45\x39\xd9\xa5\xed\x0e\x5f\x67\x55\xa0\xb0\x1b\xc5\xba\x86\x7e\x64\xbd\xbe\xae\x92\x29\xa4\x17\x5e\x21\x53\x4a\x57\x6c\x5d\xf2\x81\xf3\x33\x2b\x14\x86\x02\xa3\xe7\x8b\x49\x33\x82\x3a\xe8\xed\x37\x00\x97\x68\x0b\xd2\xae\x9c\x55\x7c\x7e\xb5\x89\x6a\xb1\x57\x55\x4f\xdc\x2e\xf3\x7e\x6d\x78\xdf\x54\x32\x98\xe3\x44\xa7\xf7\x18\x54\x64\x8c\xfc\x27\x23\xdf\x5a\x62\x21\x67\x3f\x18\xbd\xd7\x96\xbe\x78\x11\x2e\xfe\xb8\xc5\x8c\x41\x9f\x22\xee\x2a\x2e\xad\xe0\xcc\xb1\xfc\x8d\xd0\x8d\x4a\x63\xa3\xd5\x8c\x0f\x1e\x4e\x47\x24\xc8\xaa\xfb\x56\xd7\xfc\xfb\x53\x58\x8a\x2f\xe1\xae\xb5\x1e\x58\xcc\x68\xef\xc6\x63\x9b\x06\xfb\x5c\xc9\xa1\x2b\xf8\x3e\x0f\x60\x51\x4a\x94\x63\xbc\x75\x97\xbd\x4f\xce\x15\xec\x0e\x32\x2a\x90\xd8\xe9\xc4\x8e\x47\x80\xfd\x9a\x9f\x39\xd7\xbf\x18\x28\xde\xf8\xaa\x1e\xaf\xd9\x71\x22\x5c\x1d\x70\x47\xb8\xeb\x45\x1a\x21\x8a\x17\x0a\x19\x2f\xc4\x4a\xd9\x9d\x57\xad\x33\x49\xa6\x76\xa3\x68\xa3\x57\xba\xdb\x86\x5d\xa4\x71\x57\x70\x5a\xf8\x4b\x2d\xb5\xc6\x35\xa3\x48\x4a\x02\x4a\x8f\xc4\xef\xa3\x75\x54\x6a\x2c\x5d\xd3\x97\x0e\xf9\xc3\x8f\x71\x46\xd7\x6d\xb3\xb1\x0c\xc9\x87\x2f\x77\x66\xb7\x94\xa2\xea\x3d\x61\xda\xb0\x5c\xad\x67\xbb\x60\xdd\x38\xf3\xd0\xb5\x4f\xf1\x64\x75\x8d\xe0\xed\xe3\xa5\x27\x45\x99\x0a\xc5\x0b\x3c\xec\x40\x33\x6a\xa2\x2a\x06\xde\x44\x51\xbc\xe6\xab\xa0\xa6\x8f\x4f\x1d\xed\x66\x88\x8d\xab\x69\x76\x02\xa0\x55\x33\xf3\x42\x35\x93\xa8\x17\xa0\xe8\xcd\x78\x69\x0b\x94\xf2\x01\x9f\x0a\xf3\x3a\xbc\xbf\xb3\xba\xd5\x34\xcd\x55\xd3\x1b\xf2\x17\x9b\xa7\x56\x88\x57\x27\xba\x72\x65\xd6\x3c\xb8\x1e\x94\x9a\x2f\xf1\x8e\x1c\x2f\xaa\xf0\xcc\xc8\xbe\x16\x61\x9f\xf6\x51\x39\x5d\xff\x85\xde\x95\xd3\x85\x96\x1d\xe4\xc7\x7c\x64\xce\xd8\x68\x60\x80\xf3\xd9\x48\x45\x2f\x2a\x48\x6b\x6e\x62\x44\x12\xba\xda\x2b\xa5\x66\x5c\xb2\xec\xe7\xcd\x95\x14\x9c\xf4\xd5\xb8\xef\x8b\x65\xd6\x9b\x11\x7c\x4e\x0c\xf3\x52\xe1\xbe\x1c\x8b\xeb\x0f\x18\x43\xf4\x0d\xf4\xb8\x03\x0f\x2e\xc3\x87\xb3\x93\x74\xfa\x9e\xf0\x32\x34\x61\xb6\x74\x9e\x0e\x10\x26\x87\x73\x60\xb2\xa8\xe3\x2a\xfc\x24\x31\xc8\x46\x62\x50\xaf\x63\x54\x6d\x3f\xfa\xdd\x99\xfa\xad\x27\xf0\x44\x0c\x9f\xe0\xd2\x12\x74\x6e\xd8\xd8\x31\xde\x38\xb8\xcd\x97\x5c\x86\x92\x1b\x05\x4b\xab\xda\x2a\x1c\x74\x0e\xe2\xd9\x6c\x7a\x26\x03\xbe\xb4\x22\x2c\x7b\x4f\x91\xa1\x27\x57\xdc\x66\x20\xf1\x42\x75\x37\xcc\x83\x7c\x46\xc5\x30\x1e\xf3\x92\xca\xca\x65\x9f\x50\x09\x4c\xd8\xc7\x7a\x45\x45\xa5\x9b\x15\xcf\x23\xde\x54\x82\x4b\x65\xd3\x60\xb8\x0a\xd0\x97\x9a\x83\x93\x5f\x55\xdc\x16\x91\x68\x24\x26\xa9\xb2\x98\xd9\xad\x55\xa4\x16\xf8\xfc\x4b\x3e\x21\xa3\xca\x22\x81\xa7\xf3\xd1\x62\x1a\xcf\x6f\x88\xbf\xfa\x87\x63\x14\x05\xdd\xbb\x92\xa7\x63\xc8\xfa\x2b\xbe\xee\x84\xe3\x70\x6c\x5e\x9f\xfe\x5f\x9f\xfe\x7f\xd9\xa7\xff\xf2\xe8\x1f\x60\xd5\xd3\x3e\xe1\x07\x09\xfe\xb4\xa7\x06\x42\x9e\x05\xf5\x8e\x00\xf0\xc4\x11\xb8\xef\xc6\x73\x20\x65\xd8\xc1\x20\x54\x0e\x1f\x22\x10\x1c\xdc\x9b\x49\xc0\x03\x50\xe8\x4b\x6f\x0b\x2c\x74\x08\x87\x79\xc9\x7c\x85\xc5\x65\x3c\x15\xac\x35\x3f\x45\x8b\x05\x0b\xd8\x0f\xc6\x03\xca\x19\xfc\xf3\xe9\x93\x9f\x45\xcd\x32\xe4\x27\x71\x4d\x3b\x4d\x74\x33\x08\x8b\xac\x4f\xc6\x9b\x4f\x8f\xb2\x1c\x18\xc1\x54\x54\x0f\x3d\xc9\xe0\x65\x4e\x16\xe4\xc9\xab\x51\x0f\xfc\xdd\xc9\x14\x46\x26\x37\xb6\x4e\xaf\x6d\x23\xe7\x84\xc9\x7f\xfe\xfc\x62\xd3\xaa\xee\x78\xbe\xd9\xe9\x55\x96\x52\x92\x03\xb4\xf0\x5c\x21\xd3\x77\x89\x40\xf9\x09\x44\x7b\x8c\x72\x48\x31\x91\xa1\x97\xda\x01\xc2\x2a\xcf\xb7\xfc\xe3\xbc\x80\xd7\x03\xd2\x93\x44\xac\xe6\x3e\x54\x38\xef\xa3\x91\x59\x4c\xa8\x0c\x3b\x81\x9b\x09\xc0\x89\x82\xf8\x0f\x3c\x05\x2a\x89\x7d\x9a\x8b\x16\x21\x71\x78\xf7\xf6\xf6\x6d\x7c\x1f\x94\xf6\x1e\xaa\x10\x76\x42\xf9\x4b\x14\x5a\xd7\xd9\xd0\x0c\xf8\x12\x14\x14\x6b\x82\xe2\x91\xca\x46\xc1\x9d\x59\xfe\x6c\x14\x9e\xc4\x34\xcc\xf0\xc1\xad\xe4\xd4\x99\x32\x47\x76\x92\x80\x2f\x98\x2e\xc3\x9e\x97\x4f\xe2\xec\x1c\xcf\x58\xe9\x7d\x79\x9a\x73\x83\x65\x19\x5d\x8f\x29\x7c\x76\x5b\x56\x08\x17\x8e\x69\x26\x77\xd9\x1d\xb0\x0f\x02\xa1\x7a\x42\x35\x9d\xa0\x10\x21\x01\x0f\xc3\x13\x40\x93\x1d\x45\x46\xbc\xc2\x21\x0b\x62\x32\xcf\x73\x92\xa4\xc5\x30\xcf\x26\x02\x60\xac\x18\x66\x4e\x45\xd6\x78\xa8\x4a\x94\x87\x55\xad\x62\xa3\xc8\xba\x1d\xc1\x9c\x5f\xa9\x17\x05\x89\x70\xa8\x73\xf7\x56\x58\x40\x34\x9c\x5b\xb1\x5e\xad\x49\xb5\x94\xbe\x05\x3a\xd4\x74\x1e\xc4\x85\xe0\xb1\x70\x1a\x08\x5d\x78\xb0\x00\xf6\xc0\xaa\xb8\x19\x75\x86\xfa\xa8\x50\xd7\x23\xd4\x37\x79\x69\xad\x73\x18\x17\xc9\x9d\xed\x8e\x33\x7e\x13\x0e\xf8\xe7\x24\x16\x73\xd0\xed\xec\x20\x5f\x4d\xff\x88\x49\xdb\xc2\xf6\x69\x84\x35\x85\x40\x3e\x16\xbc\xea\x16\x28\xda\x10\x2e\x00\x24\xff\x74\x84\x55\xac\x81\xa3\x0b\x35\xab\x4f\xac\xe4\x94\x35\x90\x2b\x3d\x1c\x21\x30\xc6\x94\x6a\xd7\x92\x1c\x3a\x78\x9e\xb1\xe3\xeb\xf0\x13\xc1\x75\xa4\x57\xb8\x4f\xc6\x29\x5b\x9a\x4b\x72\x8a\x66\x6c\x5a\x95\x6f\xce\x22\x3d\x1a\xeb\xbe\xc1\x9c\x10\x85\xf1\x42\x04\x3d\x06\x22\xe4\x26\x5d\xbf\x44\xb2\x32\xe4\xf3\x68\xbf\x21\x6f\x00\x81\xf2\x2c\x91\xd7\x16\x14\x85\x19\x4a\xb4\x0e\x17\x55\xeb\x2a\xee\xfe\xab\xe4\x5d\xe9\x75\x50\xc2\xb2\xdb\x1a\xd2\x3b\xde\xee\x56\x5d\x97\x5e\xc8\xfa\xbb\xa1\x06\xf9\x2a\x69\xff\x04\x2c\xd2\x40\xdc\x40\x04\x3b\x9a\xe3\xd4\x50\x82\xcc\x52\x22\xba\x79\x74\x41\x60\x21\x3d\x3a\x82\xd7\x07\xe1\xa5\x18\x98\x7d\x14\x5b\x74\xd8\x47\xc0\x41\x13\xc1\x60\x0f\x42\x54\x63\x3f\xf8\xda\x86\x7e\xd0\x79\xe5\x9e\xc5\x4d\x32\x0c\xd1\x5b\x80\x9f\xfc\xe8\x38\xce\x8e\xc2\x06\x04\x72\xef\x57\x88\x17\x05\xd0\xb1\x1e\x4f\x84\xb7\x81\xc3\xb8\x2c\xef\x86\xfd\x60\x6b\x0b\x8a\x31\x80\xea\x54\x89\xbd\x04\xe4\x1e\x66\x35\x50\x14\xea\x64\xc1\x53\x2b\x7d\x62\xb5\x24\x6d\x31\xab\x84\x7f\xb0\x65\xb5\x0c\xba\xe0\xb9\x83\x62\xb3\x11\xf8\x98\xb5\x2b\x2b\x06\x66\xe9\xbd\xa2\x52\x5d\xbe\x79\xc8\x85\x7c\xb5\x1c\xc0\xed\x42\x1d\x13\x90\x2b\x8d\xaf\x2b\x73\x58\x3e\x3d\x03\x7a\xa7\xbb\xa5\xb8\xb8\x80\xaa\x2b\xd6\x56\x5a\xc8\xfa\x13\x9b\x99\x6a\x21\x40\x19\x38\x5d\x61\x00\xf4\x07\x78\xf5\x63\x22\xd4\x8b\x64\x3c\x88\xf6\x40\x4f\x3b\x4d\x41\xf7\x88\xf1\x71\xa7\xea\xd5\xc4\xda\x0c\xcc\x85\xc1\xa7\xb6\x67\xe8\x47\x04\xd2\xf1\x30\xfa\x56\xff\x29\x7b\x1f\xec\x3e\x42\xd4\x1b\x46\x9d\xcd\xc1\xba\x36\x1e\x2a\xfb\x63\x27\x4b\xca\x37\x42\xe5\x14\x9b\x17\x04\x5f\x53\xd9\xda\x7a\x78\xa0\x8f\x41\x69\x0e\xc9\xc0\x35\xa7\x97\xaa\x7c\xf2\x55\x5e\x85\x30\x48\x33\x09\x9e\xb9\x80\x0f\x5d\x99\x0a\x2c\x6d\x1f\xc4\xdc\xd5\xe1\x5d\xf8\x65\xc9\xcf\xab\xc3\x8d\x6f\x40\xf3\xdf\xba\xd6\xfc\xaf\x35\xff\x2f\x5c\xf3\x37\x8e\xff\x78\x69\xf1\x8a\x9c\xfe\xb5\x23\x27\xd7\x2a\x0f\x85\x12\x89\x89\xa2\x15\xd4\xd0\xe9\x1c\x64\xfc\x24\x99\xd8\x1b\x82\x7e\xb2\xef\x8c\x5d\x9c\xb3\x36\x4e\x82\x20\x76\x71\x7a\x0c\xbd\xef\xda\x0e\x5a\xdf\x51\x61\xd1\xbd\x4d\xff\xe6\x1d\x7a\xfc\x81\x14\x6f\xdf\x3f\x64\xac\x25\x20\x34\x3d\x97\x5a\x9d\x58\xa6\xbb\x0f\x76\x9e\xc9\x49\x1e\x47\xdf\x7e\x13\xc1\x23\x71\x0b\xf9\x5c\xc6\xe1\x99\x48\x7d\x0b\x17\x6a\xcd\xa3\x50\xdb\x10\x33\x79\x8e\x7b\x01\x9d\xcc\x8e\xc9\x95\x4a\xb9\xab\x2b\xe8\x69\x42\x97\x16\xca\x1c\x1a\x24\x5c\x15\x51\x77\x47\xf4\x77\x63\xbd\x1f\x3d\x80\x7f\x37\xfa\xd1\x60\x30\xe8\x47\xff\x2b\x3e\xb6\x6e\xf7\x40\xd9\x89\x8a\x59\x32\x4a\x27\x29\x2d\xa4\xdd\x97\x7b\x1b\x5b\x77\x36\xee\xb8\x2e\x66\x69\x91\x63\xba\x1c\x07\x17\xd6\xd4\x49\x21\xde\x89\x85\x8e\xc0\x00\xed\xa3\x35\x7e\xb2\x2c\x25\xa9\xb1\x02\x93\xa1\x0d\xec\xfa\xad\x09\x85\x8a\xf9\x3c\xc2\x88\x3a\x3b\x9d\x01\xa0\xe5\x61\x3e\x4e\x76\xca\xee\x3a\xb3\x5a\xc3\xd8\x3a\xff\xeb\x65\x13\x03\xa4\x7b\xbb\x48\xac\x65\xfe\x7a\x36\x4b\xe6\x0f\x85\xca\xa5\x4d\xd9\x2c\xbb\x58\x1c\x0a\x3a\xe9\x6e\xf7\xd4\x8d\x5c\x99\xb0\xde\xdf\x76\x4e\xcc\x28\x57\x30\x8c\xb2\xdb\xe9\xf4\xec\xcb\xca\x19\xbb\xa8\xac\xee\xb3\x80\x48\x16\xea\xbc\x92\x0f\x11\xe6\xfb\xfb\x62\x9c\x42\x20\xc4\x8f\xef\xee\x47\xff\xeb\x84\x3c\x59\x5b\x8b\x02\x33\x2b\x27\xd6\x82\xd4\xb1\x41\x45\x45\xb7\x44\xa1\x9b\xa2\x10\xf3\x33\x0a\x85\x37\x57\x4f\x48\xba\x3e\x4c\xe7\xbd\xc1\x6f\x79\x9a\xc1\x30\x5d\x4f\x45\x30\xbe\xc3\x92\x81\x29\x7e\xba\xf7\x08\x08\x7b\x63\x5d\x31\x25\xe9\xe1\x87\x94\x1f\xa0\xb8\x6f\xd6\xef\x6c\xbb\x04\x27\x16\xc7\xb7\xdf\x6c\xac\x57\x11\x9a\x4d\x5f\x26\x1c\x2d\x51\x93\x2c\x5c\x4b\x45\xf3\xe4\x24\x4e\x33\xb2\x1d\x41\x9e\x39\x7b\x94\xc1\x5d\x6c\xf6\x20\x81\x8d\xdf\xf2\x26\x9b\x90\x43\xe9\xa6\x67\xc0\xb4\xc7\xeb\xb7\x96\x8b\x9c\x69\x12\x65\xed\xdd\xac\xa4\x88\x38\x7d\x81\xac\x5e\xf4\xff\x00\x6b\x37\xbd\x5a\x28\x28\x8e\x74\x3f\xb7\x0e\xf6\xb4\xfb\x83\xac\x4b\x97\x34\xf5\x59\xf3\xd4\x7c\x0f\x89\x5c\xd0\x71\x1d\x48\x87\x7f\x3c\x50\xc7\x04\x79\xd7\x41\xb2\x4f\x35\x5f\xe1\xc9\x59\xe3\x41\x8b\xc3\x93\x20\x3d\xa1\xcd\x92\xf3\xbb\xea\x3d\x16\xda\xd4\xcf\x04\xdf\x92\x59\x9c\xa8\xdb\x39\x0e\x51\x01\xcc\x8e\xce\xf1\xfa\x1e\xb3\x2c\x25\x46\xc3\x10\xce\x35\x5b\xe3\x56\x31\xc8\x19\xb0\x1a\x9b\x62\x06\xc4\xc5\x03\x15\x33\x80\xdd\x54\x90\x8f\x3a\x46\x1b\x77\x18\x0b\x03\x33\xd0\xd6\x1d\xf0\x63\x17\x65\x8c\x79\x68\x8b\xc3\x88\xad\x6f\x9c\x90\x65\x11\xf7\xc0\x2e\x15\x12\x35\xdd\xb6\x25\x61\xdd\xcf\x07\x10\xa9\x80\x8a\xd9\xcc\xcf\x59\xcc\x32\x2c\x09\x5b\xb8\x0f\x60\xe8\x70\xb0\xcb\x76\x2f\x98\xbe\x08\x23\x69\x0a\x71\x5f\xae\x68\x32\x26\xa1\xbf\xee\x73\x0a\xe9\x2f\x04\x0a\x29\x94\x7d\x97\x7e\xbf\x7a\x84\x12\x09\x85\xbb\x99\x18\xa4\x16\xc7\x31\x49\x6b\xb8\xbf\xbd\x1b\x4d\x17\x45\xfa\x56\xbf\x40\x98\x1e\xa6\xd3\xb4\xd4\x02\x8e\x18\xc4\xef\x6b\x87\x62\xf3\x13\x42\xbd\x90\x72\xde\xa6\x23\xb5\x01\xc6\x14\xd8\x73\xf5\xbb\xb5\xf4\xfb\x81\x4b\x43\xfa\x35\x80\x42\xed\x42\x13\x21\xcc\x83\xf8\x34\x3d\x82\x48\x33\xc7\x27\x42\xde\x2a\x46\x42\xa6\x24\xb6\x24\xe5\x9f\x24\x1b\x9c\xa6\xbf\xa7\x33\x78\x8b\x06\x85\x20\xf8\x5a\x13\x6b\x2d\x99\x67\x31\x5d\x9d\x78\xf3\x40\xf4\xe8\x8d\x8c\x2a\xfa\x86\xe6\xf5\xff\xfe\x24\x47\x9a\x1d\xbd\x81\x21\xbe\xc1\xbb\x44\x6f\xc6\xe9\x51\xea\x5d\xe5\x50\x53\x13\xa2\xc8\x43\xb5\xa7\xaa\x19\x50\x31\x63\xc4\x4f\x7f\x9b\x6d\x41\xab\x0f\xdc\x15\x79\xe8\xb0\x45\x39\xa3\x0f\x69\x9f\xea\xfc\xf3\x71\x47\x3f\x22\x6c\xf3\x4c\xc9\x63\xbb\xce\xce\xdd\xe5\x15\xdc\x14\x92\x12\x8a\x4a\xd8\x0a\x77\x77\x01\x74\x3c\x02\x6c\x88\x9a\xba\x24\x4e\x75\xbf\xbd\x2b\xf6\x27\xdd\x44\x4f\x5d\x1b\xb8\xb5\xe9\xec\xb7\x3a\xea\x86\xdd\x14\xab\x53\x36\xd8\x60\xb6\x92\x4c\x84\xe1\x0a\x09\x9b\xde\x81\x86\xc7\x5e\xd2\x72\x41\x2e\xf3\x42\xdc\x12\x6a\x60\x56\xc2\xa6\xe5\xc6\x4f\xa7\x5a\x76\xb3\x71\x3a\x4f\x6c\x07\x06\xfb\x8e\x4d\xd1\x57\xb2\xac\xbe\x64\x83\xb7\xa6\x56\x59\x4b\xab\xd8\xd4\xaa\x69\x6b\x75\x19\x5e\x64\xf7\xc4\xbe\x3a\x21\xe3\xe5\xda\x2a\xb0\xcd\x19\x3a\x8f\x5f\xfd\x0c\xf3\xa0\x6e\xb7\x70\x0c\xb0\x54\xdd\xb7\x9e\xc3\xaf\xf3\x3a\x7e\xad\xae\xd2\x11\x72\xe5\x23\xcb\x62\x75\x52\xac\x10\xc6\xc7\x3d\xb9\x13\x23\xa8\x54\xca\x9b\x7a\x2f\x0a\x18\x3e\x94\xc1\x83\xae\xd3\x51\x4b\x5a\xe8\xdc\x65\xf1\x59\x6a\xe5\x84\x34\x62\x71\x5e\x52\x22\x2b\x2e\xfc\x99\x20\x2f\x8d\xb6\x72\x8d\x05\xec\x7a\xe5\xed\x41\x3e\x66\x13\x5c\x3f\x38\x2a\xa1\x43\x07\xac\x36\x38\x36\x6e\xad\xf1\x47\xe9\x50\xc2\xda\x7f\xfe\xf9\x78\x7f\xfd\xd6\xb7\x07\xef\x37\xcf\xbb\x82\x0e\xe0\xf7\xce\xad\xff\x3d\x78\xbf\xb1\x75\xfe\x41\x7f\x6c\xad\xf7\xb7\x36\xce\x7b\xff\xb3\x36\x28\xd1\x08\xaa\x37\x70\xeb\x5e\x5e\x15\x63\x60\xe0\x74\x3d\x6f\x63\x49\x84\xc9\x2b\x98\xa8\xfd\x07\xd1\xf6\xc8\x2c\xc1\xab\xc1\xdb\x23\x7f\x25\x39\x88\x33\x83\xe2\x97\x7b\xb6\xb6\xf1\xb9\xf6\x70\xde\xed\x0b\x0e\x5b\x7c\x66\x15\x03\xb7\xb8\xcf\xd5\x0c\x3d\xc8\x46\x5a\x0d\x7e\x73\xbd\x95\xd7\x26\x89\x94\x30\xd2\x62\x71\x02\x80\xaf\x0b\xa9\x3e\x08\x02\xba\xf5\xed\x37\xb7\x84\xfc\xae\xb2\x51\xc7\xc5\xde\x8d\xf2\x69\xd4\x15\x2a\xc3\xda\xee\xe3\x87\x11\xe8\x0d\xc3\xcd\xf5\xf5\xad\x9e\xcb\x93\x59\xb5\xbe\x16\xca\x72\xbd\x81\xab\xbc\x96\xc3\x96\x3a\xe1\x66\x3f\xda\x6c\xe7\xab\xca\x99\xaa\xb5\xa5\x44\xc9\xd1\x20\xfa\xe7\x8b\xc7\x3f\xb9\x93\x6b\x0a\x84\x47\x53\x59\xa3\x3f\xa9\x0c\xb2\x69\x78\x86\x00\xba\x18\x65\xce\x1b\xf2\x37\xfd\x68\x1b\x2e\xcb\x75\x3a\xad\xc6\x3d\x9a\xa6\x78\x91\x4c\x77\x10\x8d\x4f\x69\xe6\x8e\x0f\xb0\xf0\xd3\xce\x2f\x7b\x3f\xfe\x6b\xef\xc5\x3f\xdc\x59\xc5\x3a\x2a\xe6\xd4\xad\x3f\x38\xb9\x04\x74\xe9\xb1\x6f\x6c\x2c\x3f\x72\xb9\x9a\xc2\x7a\x89\xaf\x78\xf8\x43\xf3\x2a\xf0\x86\x17\x07\xf4\x10\x7e\xee\x24\x07\x17\x0a\x40\x66\x29\x1d\xfe\x80\xb7\x99\x12\x5b\xa9\xca\x28\xfd\x43\x6b\x29\x96\x86\x4a\x3a\x8a\xad\xcf\x08\x9d\x47\x2c\x1c\x65\xfe\x38\x8f\x2c\x29\x4f\xa1\xd7\x19\xa4\x2a\xdc\x6e\x81\x56\x44\xa5\xda\xc5\x2c\x32\xea\x73\xbb\xe2\xea\xd0\xfe\x04\x33\xfe\xf6\xb5\x19\xff\xda\x8c\xff\x85\x9b\xf1\xa5\x09\x7f\x36\xaa\xf7\xdf\xbb\x84\xc7\xdd\xaa\x79\x69\x6e\xf5\xde\x52\x2f\x64\x35\xf8\xe9\x91\x9d\x69\x36\x0a\x7a\x82\xcd\x62\x88\x83\x93\x25\x96\xf7\xf7\x1b\xb4\x5c\x78\x17\x4f\xd5\x51\x35\x7f\xa3\x57\x45\x2d\x90\xfe\x3a\xe8\xe3\x03\xff\x50\xaa\xc9\x1a\xe9\x13\x5e\xe4\x8a\xa5\x4a\xe8\xbe\x07\xe8\xa1\x29\xaf\x43\x51\x3a\xc5\x06\x82\xb9\x74\x70\x54\x1d\xfe\xa6\x25\x33\x31\xc2\x05\x83\x1c\x98\x18\xdd\x25\xdc\x7d\xfe\x30\x32\xe7\xca\x74\xc3\x50\x14\xe5\x4f\xc1\xbf\x21\x36\x28\x0f\xbc\x75\x0c\x8a\xda\x1e\x64\x63\xde\x3e\x6b\xbe\xb2\xb2\x68\x45\xdf\x31\x78\xb2\xfb\xf2\xd5\xe3\x67\xb8\x82\x1e\xee\x3d\x7b\xf6\xf8\xe1\xab\xdd\xbd\x67\xd1\x8b\xc7\x2f\x9f\xef\x3d\x7b\xf9\xf8\x65\x65\xab\x70\x6b\x8b\x37\x0b\xdf\x7c\x73\x5a\xfb\x5a\xba\x11\x9e\xc4\xef\xe0\xb0\x64\x9a\xbc\x4b\xcb\xb3\x61\x74\x07\x29\xcb\xe9\x21\xda\x42\xb5\x1f\x02\x54\x65\xf6\x9b\x5e\xe0\x41\x13\xe9\x73\xf0\xde\x54\xa4\xe8\x24\x35\x37\x81\x5d\xcb\x3b\xec\xeb\xc8\x5f\xc6\x60\x20\x1e\x1d\x8b\x9e\x95\x42\x72\x24\xf1\x95\x36\x21\x60\x68\x63\xab\x5c\xe0\xfd\x00\x6c\x3a\xfc\x60\x31\x1e\x47\x79\xbd\x45\x0f\x04\x1f\x5c\x56\xff\x26\x4c\xc8\xfb\x78\x37\x2e\x1d\x1f\xf8\xe1\xd0\x75\x61\x18\xab\x04\xbb\xe7\x41\xb9\x4f\x3f\x57\x3e\x89\x8a\xd5\xc8\xbe\xbb\x15\x9d\x7b\x58\x9c\x88\xd5\x6c\x45\x04\x70\xd1\x55\x35\x1e\x18\x4a\xa0\xf5\x1a\x70\xf3\x3e\x2b\x77\x6d\x31\x7f\xe3\x64\x9a\x08\x7e\x55\x53\x83\x3b\x18\x17\x37\xfc\x16\xf6\xaf\xb0\x6b\x21\x21\x4a\x82\xa0\xfa\xd0\xb8\x43\xbe\x52\x19\x05\xcb\x81\xbb\xeb\xc0\xf1\xe1\x0d\xdd\x15\x25\x0c\xda\x24\xbc\xe2\x9a\x3d\xf0\x46\x26\x08\x7f\x86\xe7\x31\xf1\xc8\x2e\xcc\x98\xa1\xb5\xaa\x5c\x36\x68\xce\x3e\x88\x78\x7f\xa1\xc8\xe6\x26\x40\xaf\x5a\xe2\x8f\x1e\xdf\x7a\xf8\xf3\xeb\x67\xff\x78\xfc\x42\xd7\x33\x4e\x46\xc7\x8b\xec\xf7\x64\x2c\x6f\x95\xd0\x8d\x51\xf9\x27\x70\x39\x9b\xc6\xa3\xa4\xbb\xf6\xef\xf3\xfd\x7f\x67\xff\x9e\x1f\xfc\xf0\xef\xf7\x6b\x47\xfd\xce\xf9\x87\x5b\xb7\x3e\xbc\xef\xf4\x30\xb4\xf0\xfb\x20\xfc\xbf\x0f\x54\x89\x7d\x59\xe6\x00\x0a\xed\xab\x52\x07\xfb\xe1\x72\x6e\x29\xab\x50\x45\x19\xd3\x16\x6b\x49\x37\xc4\xca\xc8\x63\x3e\xc8\xee\x28\x4e\x6a\x61\xc0\x5f\xb3\x88\x78\xc6\x5f\x20\xba\xb3\x68\x3a\x91\xe1\x30\x30\xd2\x06\x56\xc0\x0e\x01\x25\xe9\x43\xde\x43\xc8\xb2\x57\xae\x8c\x3b\xe3\xc0\x44\x37\x23\xba\xff\x6a\x89\xea\xfa\xcc\xda\xe1\x64\x7e\x50\x83\x90\x2f\x18\xf6\x9d\x95\x92\xde\x34\xe4\x4c\x63\x95\x76\x3b\x73\xdf\xed\x8c\x1c\xba\x7c\xe6\x5d\x3a\x72\x61\xe8\xbb\x6c\x94\x4c\x31\xa0\xbe\xba\xc4\x69\x95\x19\x4d\x93\x78\xae\x5c\xb8\x9c\x56\x64\xb2\xb3\xa0\xc3\x40\x18\xab\xa1\x54\x15\x85\xf6\x38\xbb\xbc\xbb\xd7\xf1\xbf\x5a\xbf\x4a\x89\x33\x0e\x7f\x0e\xe7\x71\xeb\xeb\x42\x2e\xc2\xc3\x99\xc0\x59\x2b\x3f\x69\xe5\x94\x22\xb1\xa3\xf0\x05\x1c\x04\x9e\xeb\x46\x7a\xa1\x47\x51\xe4\xbd\xbe\xe5\x51\xe5\xcf\x98\x43\x22\xf8\xda\x8f\xf4\xac\xf4\x3a\x4c\x2c\x82\xde\xf5\xb6\xfd\xf6\x1c\x6b\x3d\x07\x97\xc1\x7f\x80\x47\xfe\x24\xb7\x50\xa1\xb4\x16\xfc\xc1\x67\xe9\xe5\xe0\x4b\x63\x64\x1e\xee\xaf\xd0\x86\xab\x14\x03\xa9\x6b\xa7\x14\xae\x5e\x72\xbd\x13\xf5\x7a\xbd\xc5\x7d\x77\xc7\x54\x4a\x70\x5e\x21\x70\x73\x86\x2b\xfa\xf0\xc2\x69\xaf\x86\xc3\x06\xa0\x39\x9f\xad\xf0\x29\x65\x32\x85\xba\xad\x2e\x3e\x3e\x7c\x50\x5f\xfb\xeb\x07\x7a\xcb\xc4\x23\x6c\xea\x9b\x81\x96\x09\x76\x09\x4a\xe4\x12\x5d\x70\xf3\x12\xd5\xa8\xde\xd4\x49\xbc\x00\x3a\xd0\x0d\xab\xba\xd5\xae\xa6\xd6\x11\x5f\xa9\xf4\x2c\xbc\x74\x4b\x21\xb1\x1c\xb5\x2f\xb4\xb8\xc3\xfe\x3e\x66\x68\x96\x44\x5c\x83\x5a\xdf\xbf\x53\xab\xf6\x82\xad\x76\x01\x2f\xbf\x27\x67\xa4\x29\x86\x06\x60\x07\x81\xe9\x86\x81\x06\xc7\x71\xb1\x77\x9a\x3d\xc7\x27\x8f\xca\x33\x7c\xd2\xce\xe1\x02\x15\xe8\x81\x57\xec\xaa\x7d\x3b\x3b\x42\x0e\x17\x62\x79\xc7\x7d\x8b\x5d\xca\x16\x35\x75\x7a\x6e\x16\x66\x99\x3c\xe4\x31\x08\x65\xb8\xf1\x88\xa9\x1b\x02\xcd\x45\x99\xd2\x63\x24\xe9\x98\x11\x35\x77\x0b\xad\x44\x78\xd8\x8f\xb3\x5b\xad\x2d\x29\x39\x00\x76\x0f\xa1\xb9\x52\xe1\x3e\x51\x81\xdd\xab\xa3\x3c\x4b\xa4\xe5\xa9\x7b\xe3\x8d\x2b\xf6\x9f\xce\xd3\x12\xe3\xa5\x38\xdc\x88\x81\x38\x2a\xd4\x1b\x5f\x87\x52\x21\x06\x6f\x54\xd5\x2e\x0d\x48\xc1\xa1\xd7\xdd\x20\x50\xfd\x0e\xd5\x9b\xbc\x64\x57\x57\x14\x92\x94\xc3\x17\xe7\xdc\x4b\xa0\x48\xa1\xa9\x19\x4b\x9a\x38\xab\x11\xe5\xe3\x49\xf5\x72\xcc\xae\xfd\x86\x46\x55\xe3\xf1\x7c\x31\xb1\x41\x53\x35\x97\x1a\xec\xa1\x34\xee\xa3\x91\xf3\x17\xda\x27\x1d\xb7\x3b\x69\x4d\x74\x74\x14\x2f\x1c\x0f\x58\x62\xc0\xcb\xda\xdc\x36\x5b\xed\xdc\x74\xd9\xed\xcd\xce\x6a\x54\x70\xc7\xec\x01\xf7\xd3\x36\x7c\x10\x6f\x6c\x41\x70\x9f\xc5\x0c\x6c\x82\x68\x5b\xa3\x93\x5a\xa1\xa8\x6b\xab\x8a\x09\xec\x53\x4f\x98\xad\x6f\x2a\x5c\x64\x31\x36\x53\xd9\x52\x14\x16\x54\xeb\x91\xa6\x6a\x3d\xe8\x59\x40\x5d\x77\x37\xad\x8c\x76\xe3\xdb\x71\x0d\x06\xbd\x20\xed\x15\xb7\xb4\xc1\xa4\x7b\xfb\xda\xa4\x7b\x6d\xd2\xfd\xaf\x30\xe9\xca\x8b\x15\x97\xba\x8e\xbd\x13\x0b\x66\x17\xfd\x63\x71\x12\xbf\x15\xd3\xfb\x5d\x0c\x9f\x7f\xfb\x9d\x3e\x05\x9b\x0c\x9a\x7b\x05\x57\xda\xcd\xd2\x32\x15\x0c\xfd\x8f\x24\xfa\x3b\xf5\x02\x08\x35\x8e\x0a\xf4\xc4\x52\x0e\x37\x38\x50\x58\xaa\x56\x90\xf3\x01\x5a\x75\x55\x31\xf5\x8a\x88\x7c\x80\x6a\x77\x3c\x8c\xd6\x9b\x4e\xde\xc8\xdb\x03\x86\xef\x86\xd5\x0d\xba\x99\x04\xc3\xe9\x9a\x5b\x68\xea\xad\xab\x89\x42\x28\xb6\x64\x1c\x7a\x78\x29\x19\x84\x97\x6e\xa7\xf4\x99\xa9\x67\xa2\xaa\x51\x59\x2a\xf6\xae\x7d\xec\xa7\xbc\x86\xcc\x08\xa0\x3d\xaf\xf7\x6b\x2b\x12\x3d\x15\xa1\x78\xa9\xad\xbe\x6c\xcc\x0a\x9a\x4a\x59\x86\xdd\x3b\xa6\x65\xd5\xa4\xe7\xcc\xa3\xdd\x76\x6f\x68\xd3\x87\x98\xe7\x78\xfc\x16\x74\x54\x35\x3b\xd1\xee\x23\xcc\x51\xbd\xd3\x93\x76\xf3\x66\x55\x9c\xa1\xf0\xed\x1f\xba\x2b\xe4\x55\x63\xb2\xd5\xf5\x20\x39\x52\x9d\xae\xee\x05\xd1\xbf\xa0\x30\xa9\x1b\x42\xed\x1d\x2f\x14\x0e\xb4\xf2\xa8\x1c\x88\xc2\xae\x3a\xda\x58\xe7\x7b\x7f\x35\xb8\x64\x78\x0d\x84\xdd\x33\x14\x1a\x9c\xf6\xac\x19\x57\x55\x39\x87\xa1\x14\xd3\x87\xd9\x9c\x25\xd0\x0f\xba\xed\x81\x60\xae\xf3\xb3\xae\x8a\x86\xfc\x52\x6c\xd7\xd3\xe4\x29\x21\x1c\x0e\xba\x83\x19\xa6\x26\x39\xad\xba\x23\x61\x70\x39\x81\x5a\xd1\xd2\xc2\xbb\xa2\x1b\xe6\x41\xa4\xd2\x88\x22\x2d\xdf\x22\x99\xa1\xe6\x07\xfc\x4d\x90\x6a\x38\x90\x0c\xbb\xa0\x60\x41\xe7\x06\x30\x3a\xd6\xed\x84\xaa\x93\x32\xac\x13\x53\x52\x10\x37\x3e\xcd\xa7\x93\x64\x54\x76\xce\x5c\xd4\x7a\x8c\xdc\xcb\x7c\x21\xea\x22\x46\x74\x88\xf7\xd1\x1a\xd8\xd1\x03\x00\xaa\x0e\xf1\x2d\x3b\xee\xf0\x17\xaa\xab\x20\xa6\x2a\xab\x52\x02\xce\xb8\x87\xc6\x23\xb9\x28\xba\x8a\xf7\xf4\x84\x6c\x93\x4c\xc7\x8e\xeb\x81\x6c\xc5\xea\xa9\xc3\x73\x78\x07\x1d\xc6\x43\x5d\x73\xc8\x50\x25\x3b\xaf\x3e\x28\xb2\xf0\x03\x61\x79\xec\x4d\xc1\x0e\x14\x6b\x53\xdf\x92\xc5\xd9\x76\x78\x4f\x56\x94\xf5\x49\x39\x11\xc4\xc0\x3b\xd7\x62\xe0\xb5\x18\xf8\x65\x8b\x81\xe6\x7e\x1e\x2d\x9a\xab\xba\xa1\x77\x35\x67\xf7\x00\xf2\x54\x99\x1b\x2b\x9d\x95\x51\x4f\x94\x2f\xb5\x28\x2f\x64\xf8\x64\x5a\xa4\x0c\xb9\xa6\x72\xe1\xd3\x3a\xb8\x47\x9e\x67\xf2\x8d\x64\xb0\xce\xc0\x30\x26\x3f\x7f\x94\xd0\x38\x42\xf3\x0c\x56\x82\x22\x3d\x87\x8a\x38\x39\x96\xd1\x15\x2d\x06\x4f\xe3\x4c\xb0\x2e\x73\x3b\x1f\x58\x16\xa1\xc2\x32\x05\xa8\x10\x1e\x06\x9c\xed\xf7\x27\x16\x86\xbc\x8a\xe7\x27\x0d\xfe\xef\x82\x7c\x13\xb8\x3e\x68\xc7\xf7\x74\xc4\x3f\xf0\xea\xc2\x98\x0b\x55\x2f\x51\x1c\x25\x18\xa5\x32\xb0\x49\xd9\x91\xe6\xdd\xc0\xa2\xaa\x4d\xbb\x3d\x24\x31\x0f\x11\xb2\x0d\xfb\x86\x0d\x7b\x6b\xc2\x0a\x27\xca\x9e\xcf\xd1\xc4\xa1\xfd\xf8\x54\x2c\xc3\x02\x04\x1b\x9d\xd2\x14\xe6\x98\x82\x71\x9a\x43\x0a\xf9\x34\x87\xdc\x76\xf5\x38\x07\xd1\x53\x60\xe5\x70\x71\x9f\x1e\x91\x46\x7c\xac\xb8\x51\x28\xad\xc8\x9e\xad\xf1\xa6\x06\xf5\xe1\x99\x20\x18\x13\x18\xa3\x0f\x52\x64\xf2\x2e\xc5\x63\xb3\x10\xee\x3e\xcf\xf7\x87\xae\xec\xd9\x9d\x88\xe3\xf6\x4a\xdf\xdd\xf1\x30\xd9\xea\xb5\x1d\xf7\x81\x13\xfd\x94\x8c\xad\x88\xd1\xb2\xad\x7e\x3e\xe6\xc5\xf3\x87\xf2\x85\x89\xfa\xb7\x63\x0c\xda\x64\xa8\x57\x22\x3c\x44\xba\xd6\x38\x5d\x34\x11\xe8\x2e\x40\xfa\x58\xb2\x88\x4e\x27\x05\xd0\xc1\x6a\xa9\xc5\xc6\x8d\x00\x77\xad\xa0\xa0\xd0\xe5\xc6\x40\x69\x47\x86\xd4\x41\x17\xfb\x11\x3e\x92\x5d\xf7\x84\x94\xe0\x03\x13\xeb\xb1\xa8\x7d\x2c\x72\x00\xec\x80\x7d\xfa\x3e\x1d\xf5\x58\x27\x7e\xaf\x5f\xaf\xe5\x98\xf7\x11\x4f\x01\x78\xeb\x75\x45\x3b\x1c\x71\x05\xee\x03\x6f\xa4\xd8\x8f\xe5\x72\x14\xba\x9b\x15\x06\x9b\x41\xb5\xe7\x87\x20\x80\xae\x2a\xf8\x36\x89\xc4\x45\xe8\xc9\x22\x75\x7f\x4a\xd1\x61\x1b\x53\x8b\x59\xa0\x45\x7b\x24\xb4\x7b\x83\x88\x87\x63\x95\xfb\x88\x1b\x25\x79\xe9\xe0\xcb\x52\x9e\x40\x60\xb6\x0c\x38\x20\xe6\x59\x0f\x0c\x69\x35\x25\x6c\x05\x51\xe6\x0c\xb4\x2c\x55\x18\x33\xea\xc2\x2a\x73\x13\x87\x87\x2a\x15\x22\x97\xf0\x69\x4b\x5b\x87\xbf\x71\x74\x41\x86\x1a\xda\xe1\x22\x9d\x8e\x11\x61\x72\x50\x90\xe9\xc5\xb7\x45\x86\xff\x6a\xef\xd1\xde\x8d\x1b\x37\x50\xbc\xef\x14\xd1\xe2\x08\x4e\x4a\xe8\x05\x07\x50\x08\x16\x70\xb7\x23\x2d\x75\x2b\x19\x0b\x29\x0b\xbf\x95\x5f\x8d\x3e\x21\x01\xc6\x81\x19\xfa\xbe\xf5\x06\x5f\xd6\xa2\xd3\xfb\x90\xbd\xbf\x7e\x00\x87\xdb\xd6\xa7\x10\xeb\x94\xdf\xa6\x0b\x4a\x3f\x36\xb0\x0c\x8c\x45\x39\x9e\x3b\x47\x45\x4e\xed\x08\x19\x31\x2e\xec\xa1\x57\x23\xaa\xea\x14\x55\x1f\xaa\x1b\xa7\x53\x7a\x02\x25\x0b\x5f\xcb\x32\xa4\xa6\x08\x5c\xca\xef\x46\x1b\x5e\x26\x0e\xb4\xc2\xa8\x3a\xdc\x0a\x3d\x55\xc0\x88\x52\xb6\x65\x4b\x94\xe6\x60\xca\x9c\xcf\x58\x6f\xb1\x68\xf7\xba\x80\x6b\x9e\xdb\x30\xc1\xcb\x76\x38\x33\xd3\x4e\x91\x8e\x03\x4d\xf0\x39\x15\xbb\xbb\x56\x35\xd5\x43\x70\x8f\xa1\x24\xa9\x40\x56\xdb\x48\xcf\xea\x99\x2b\x51\xa4\xd9\xa4\x28\x39\x24\x3c\x99\x05\xe7\x61\x62\x63\xd7\x11\x98\xc8\x95\x05\x2c\x03\x4a\xd8\x14\x9a\x32\x19\x5d\xc2\xb1\x9e\x78\xdc\x7a\xf3\xd6\x93\x32\x36\x87\x38\x27\xb3\x64\xaa\xa2\x01\x4e\xda\x14\xd3\xbe\x32\xdc\xfc\x32\x44\xa8\x68\xc0\x8e\x49\xcf\x67\xad\x0a\xd1\xb2\x64\x85\x61\xe7\xa9\xf2\x81\x69\x7b\xfc\xf7\xcd\xb5\xdd\xe7\xda\xee\xf3\x65\xdb\x7d\xa4\xd1\x67\x7c\x78\x89\x93\xbf\x90\xdd\x47\x5b\x6b\xb8\xe1\x87\x98\x93\xb6\xe8\x3c\x7a\x60\xf1\x11\x18\x86\x1d\xf2\xc3\xb3\x54\xe0\x48\x9d\x64\x16\x75\xc0\x7b\x28\x4c\x9e\x06\x42\xab\x42\x3f\xf2\x3d\x6e\xe5\x01\x57\xf4\xde\x78\x8b\xc8\xfd\xc0\x96\x83\xba\x50\x33\xdb\x59\x24\x90\x67\x76\xe9\x86\x3a\xe8\xd8\x9b\xcd\x2e\x5f\xfd\x60\x11\xbc\x2e\xaf\x2e\xaf\x81\x23\x0a\x61\xb3\x6b\xb6\x4b\x10\x3a\x86\x51\x47\xc3\x39\xef\x68\x88\xac\xf1\xe1\x9b\x50\xae\x12\x13\xb7\x74\x9f\x74\xa3\x42\xe6\x6d\xd5\xa8\x86\x0b\x36\x1a\xca\x55\x8d\x6e\xfa\x8d\x8a\x3e\xfe\x9c\xbc\x6b\x1e\xa6\x00\xaa\x1a\xa3\x9d\x55\x3f\xc0\xe6\xb6\x08\xa8\x6a\x68\xe1\xb6\x9c\x71\xc9\xcd\x68\xdf\xc0\xa9\x89\xe8\x1b\x24\xf7\xe5\xd0\xfb\xb2\x5b\x08\x7c\x50\xb1\x73\x3d\x7a\x60\x76\x2d\xa2\x9d\xd5\xe1\x16\x6e\x51\x77\xaf\xb7\xa8\xeb\x2d\xea\xcb\xde\xa2\xcc\xd1\x04\x3c\x76\x76\x91\x73\x09\x09\x7c\xb5\xb7\x12\xcd\x0b\x1e\xce\x2e\x67\x32\xd4\xe9\x46\xe8\x10\x24\x7c\x0e\xd2\xb0\x6d\x02\x88\x72\x47\x76\x80\x66\x32\x59\x81\x8d\xaa\xda\x1b\xe5\xd9\x24\x3d\x52\x60\xec\x29\x1c\x0e\xad\x5e\x56\x51\x60\xa7\xf2\xda\x9a\x75\x40\x23\x13\x15\xcc\x8f\xf4\x14\xb8\x83\x0c\x4c\x54\x20\xbb\x05\xf8\x44\xd2\x16\xc3\xa1\x0a\x4a\x55\x60\xc0\x8a\xe7\x89\x0b\x24\x53\x75\x5d\x14\xa0\x88\x43\xc0\x45\x6e\x95\x4d\x51\x0f\xbd\xfe\xa8\x64\x2d\x84\x60\xcc\xb4\x36\x67\x06\xda\xe6\x2d\xef\x08\xa2\x11\x70\xfd\x00\x22\x9a\x44\xf2\xf7\x00\x6d\x82\x7b\x93\x2e\x3c\xf4\x4c\xa1\x4b\xd6\xe1\x40\x61\x15\x5e\xbe\x12\x6c\x1e\x1f\x26\x7d\x70\xf6\x73\x5c\x1c\xaf\x46\x43\x37\x99\x2c\xba\xab\x46\x4a\x60\xef\x3f\xfd\x08\xb1\x77\x3e\x41\x4f\x3b\xb2\x4b\xec\x59\xa1\x07\x67\xd8\x30\x74\x7a\x27\x1b\xef\x42\x39\xfd\x9a\x57\x10\x92\xc6\xa1\x61\xcd\x78\x44\x9f\xa7\xc9\x27\x1a\xc0\x6b\x68\xab\xa1\xeb\x1c\xa6\xb2\xd3\x6a\x7e\xd8\x38\x1f\x42\x34\xa9\x4f\x34\x8e\x60\xdb\x44\x42\x7c\x28\x15\x60\x34\x2a\x6f\x0a\x3e\x61\xff\x5f\xeb\x06\xd9\x64\x78\x93\xc0\x01\xac\x3e\xeb\xee\x3d\x2e\x8f\xaf\x5a\x41\x68\xad\x1c\x5c\x91\x6e\x80\x0f\x00\x57\xeb\x06\x64\xfa\x20\x2e\x9e\x26\xc1\x1e\xcd\x78\x67\x66\x0d\xfd\x98\x5d\xa0\x0b\xec\x8c\x9b\xb8\x2b\xc5\xbf\xd0\xa9\x60\xc8\x82\xc0\x58\xce\x63\x64\x92\xa7\x92\x55\x86\x2e\xd0\x4a\xdb\xcc\xf9\xca\x0a\xf5\x6e\x40\x9e\x51\xfa\x2e\x8d\x98\x4f\x63\x0d\xea\x0b\xf1\x9a\x3d\xdd\xdc\xe9\xcb\x61\x0a\xf2\x18\x56\xd8\x3c\x55\xac\xd2\x01\x2f\x28\x47\xd2\x97\x76\x3a\xab\xf0\x5b\x70\x61\x62\xb7\xe4\x07\xce\xc3\xd9\x22\xff\x9e\x5b\xbb\x4e\x3b\x5f\x41\xf4\x2c\x35\x14\x19\xe7\xef\x22\x83\x91\x45\x2f\x32\x1c\x59\xb4\xe5\x80\xda\xe8\xa2\xc0\x5d\xe2\x29\x7a\x6e\x36\x6a\x4e\x12\xd0\xd7\x9e\x14\x9b\xf2\xf2\xb5\x0a\xc5\xbc\x39\xad\x23\xbc\x61\xb4\x6f\x04\x2a\x3a\xde\x93\x61\x34\x7f\x34\xa7\x79\x2e\x04\x7f\xf9\x9c\xf8\x89\x06\x66\x4f\x47\x3a\xe7\x58\x43\x5e\x0d\xe5\xe9\x98\x35\x1a\xa8\x42\x6d\xce\xe7\x62\x29\xed\x94\x6d\x34\x67\x09\x5a\x89\xa3\x10\x84\x56\x6a\x6b\xb0\x44\xeb\x8e\x38\x76\x99\xa3\x66\xb9\x0c\x5a\x82\x03\x93\xc1\x1d\x1b\xc6\x04\x50\x95\xc3\x71\x32\x3f\xfd\x7c\x07\x07\xa6\x56\x7d\x13\x3d\x13\x3b\x72\x86\xa6\x45\xc6\xcb\x0d\x2b\xd4\xdb\xbe\xb7\xc4\x8d\xff\x2b\x2c\xde\xe8\x7c\x39\xfa\x85\x26\xea\x69\x17\xf7\xdf\x66\x4c\x20\x98\x87\x09\x2d\xd1\x7d\x0c\x4c\x30\x52\xbe\xc4\xa0\xfb\x2b\x15\x94\x7d\x32\x13\x2a\x25\x2a\x6f\x8d\xe4\x2d\x41\x6b\x68\xdc\x87\x50\x78\x58\xaf\xa6\x3f\x57\x5e\x6b\x49\x8f\x6e\x31\xaf\x5b\x75\x22\xab\xdf\xc1\x8d\x4b\x4e\x54\xdd\xdc\xa8\x29\x7c\x94\x8c\xd2\x93\x78\x5a\x8d\x0a\x23\x05\xb6\x44\x82\x29\x50\x41\x94\x9f\xef\x80\x6d\xe1\xa9\x61\xb0\xd5\x6f\x25\x57\xa8\x60\x28\x5f\xd7\x0e\xba\x79\x05\xb1\x0a\xeb\x99\xc7\xab\x80\x86\xba\xd4\x98\x74\x29\x6f\x70\x55\xca\xef\xe7\xc4\x69\x2e\x82\xa7\x17\x60\x51\x9b\xb5\x20\x73\xbf\x4c\x1b\x02\xf0\x41\x2f\x4b\x01\xb2\xc6\xd6\x03\x6c\xb9\x8a\x1b\xb9\x58\x60\x70\x2e\x60\x1b\x0a\x20\xb1\xe8\x8a\x04\xc4\xc6\xe5\x0d\x0a\xd2\x8b\xf8\xb4\xfd\x12\xf7\x0b\x84\x11\x51\x0b\xd7\x86\xb3\x01\x1e\x02\xb2\x90\xff\xb4\x74\x53\x6f\x5b\x75\xf5\xe2\xfd\x74\x67\x2a\xb4\xc6\x42\xe3\x48\x8f\xb2\x25\xc6\x61\x43\x57\x8c\xa3\x12\xe8\x23\x8f\xa3\x45\xe7\xab\x7b\x7c\xe5\xb2\x76\x05\xe1\x48\xe7\xae\xba\x8e\x02\x48\xb8\xa3\x4e\xce\x45\x3a\x0a\x7b\xc1\x95\x69\x04\xf6\x0b\xe9\x75\x63\x62\x90\xe1\xa1\x85\x01\x2e\x42\x31\xd6\x08\x2f\xc5\x95\x46\x24\x9f\xaa\x77\xcd\x9b\xa6\x8d\xa0\x07\xea\xb1\xf3\x8a\x29\xb4\xeb\x0c\x8d\xb5\xb2\x23\x4f\x9e\x3c\x69\xd9\x87\x69\x25\x05\xe9\x9a\x96\x6a\xf9\x65\x32\x9f\x25\x8d\xdb\x93\xc6\x00\x41\xd7\x23\xc0\x83\xa9\xe9\x45\xb1\x38\x3c\x49\xcb\x5f\xf3\x79\x93\x94\x64\x00\x2b\x56\x7a\x28\xbf\xfe\xe8\xbb\x45\xab\x12\xaa\x72\x2b\xae\x68\xcf\x51\x71\xbc\xe3\x6f\x63\xf8\xe9\xf3\x34\x6d\xe8\xb0\x52\xc1\x5e\x60\x25\xe0\x12\xb6\x52\x50\xf6\x77\x0a\x49\x05\xcc\x2f\xe9\x8a\x0f\x3e\x84\x51\x25\xac\x3c\x56\xb0\x2a\x5d\x0b\xa6\x55\x00\x52\x76\xaa\xca\x76\x1a\xb5\x1d\x7a\x19\x23\x31\x89\xbe\x18\xc0\xf2\xec\xfd\xca\x2e\x54\x59\x82\x35\x6f\xaf\x68\x2f\x43\xac\x35\x1f\x98\xa8\x9f\x55\xa9\x09\xd3\x1a\x34\x24\xe0\xb7\xf4\x70\xa0\x37\xd9\xb4\xb1\x3b\x68\x7c\xf4\xe8\x08\x48\x57\x5b\x5d\x6d\xd7\x51\xbd\x70\xd3\x0c\xee\x21\x31\x22\x95\x1d\x40\x46\x29\xd7\xad\x84\xd1\x20\xe7\xbd\xfe\x12\x6d\x9c\xa4\x99\xed\xdd\xe2\xb5\x20\x21\x2e\x58\xff\xb1\x50\xe0\xe6\x62\xde\xeb\x5a\xa8\x80\x69\xb5\x33\x2c\xdf\x23\x75\x3a\x5b\xd3\xa1\x30\x48\xb3\x9e\x21\x8f\x83\x6d\xe5\x62\xf9\x1e\x1e\xc5\xc5\xf3\x79\x3a\xaa\xc5\x59\x05\xcc\x85\x8d\xc0\xcb\xf7\x52\x3e\x3b\x54\xd4\xf5\x52\xc3\x5c\xb0\x8d\x43\x76\x84\x56\xd3\x4c\x35\xd8\x47\xa2\x21\xf5\x26\xc3\x2f\xe4\x6b\x53\xd7\x37\x17\x94\xb5\xc8\x59\x88\x75\xec\x32\x18\x19\x3f\x06\x76\xa4\x79\x98\x5a\x37\x16\x44\x7e\x3e\x57\xe2\x8f\xf2\x7c\x40\x37\xe2\x7e\x04\xb0\x96\x2f\xb1\x84\x0e\x35\x36\x51\x9e\x0e\xde\x55\x54\x13\x89\xa8\x2f\x2f\x35\x3c\x04\xb7\x6e\x91\x85\x97\xc7\xd4\x45\x11\x87\xc5\x41\x77\xc8\x7b\xc2\xd4\x03\xa8\x50\xcf\xcd\x3b\x27\x76\x7d\xe5\x8e\x31\x10\x30\xdd\x5e\xdf\x27\xd9\x27\xf9\x11\x93\x8d\xdb\x75\x29\x34\x50\xe3\x88\x51\x1f\x42\x5f\x61\xbf\x2b\x0b\x0c\x8e\xa6\xf9\x61\x3c\x1d\x00\x52\x07\xb1\x9f\x2c\xdf\x3c\x0b\x35\x99\x8e\xe2\xd9\xb3\x8b\x36\x0b\x85\xbd\x46\x29\xb1\xae\x49\xe6\x95\x62\x1a\x74\xaf\x3e\xf0\x37\xa5\x54\x81\x6e\x78\x7a\x9a\xa2\x51\x3d\x86\xc0\xe0\xca\x9f\xcf\x71\xcc\x59\x1d\x6e\xdc\xed\xaf\x7a\x0e\x42\xd2\x41\xdd\x78\xe6\x88\x84\xdb\x98\x40\x73\x2a\x3e\xbf\xa5\x4f\x4d\x0b\xab\xc3\x2d\x2a\x02\x67\xb9\xe2\x63\xab\x6f\xbb\x0f\xe2\xa7\x44\xd2\xea\x70\x7b\x1b\xbf\x95\x1b\x91\x48\xa0\xea\x25\x67\x17\xdf\xd4\x2d\x75\xd8\x2b\x12\xa0\x41\xe5\x04\x24\x3e\xb7\xc0\x1f\xf1\xdb\x6b\x7f\xc4\x6b\x7f\xc4\x2f\xdb\x1f\xb1\xca\x19\xf1\xd2\x3e\xf3\xed\xdd\x04\x5b\xf8\x00\x22\xdc\xb3\xc4\xde\x10\x3f\x86\x07\x4d\xb3\xcb\x8a\x71\xae\xbf\x88\xcf\x4a\x0b\x97\xfa\xb5\xb5\x35\x13\x93\x26\x14\xe7\x46\x3e\xd8\x08\x2c\x1e\xab\x13\x93\x11\xc5\xb3\x94\xf5\xfd\x23\x29\x24\xfe\x63\xf4\x8e\xc0\x63\xbf\x58\x7f\x51\xe1\x2a\x11\x9b\xba\x63\x1b\xf7\x5a\xa9\x02\x5a\x42\xe0\xe3\xe2\x97\xb7\xa9\x09\x5a\x0b\x6c\x6a\xf6\xe6\xc5\x77\x17\xb1\x5b\x6c\xaf\x5f\xef\x16\xd7\xbb\xc5\x97\xbd\x5b\x7c\xa6\xde\xeb\x57\xe7\x68\xde\xd2\x0f\xde\xb8\x72\x3e\x17\x29\x10\x51\xec\xda\x9f\xf3\x63\xfb\x73\x9e\xb7\xf3\xf0\x13\x1b\x8c\x71\x1b\xac\xb3\x0f\x1b\x40\xdf\x44\x3c\x93\xb3\xfa\x26\x08\x74\x89\x13\x4a\xc1\x67\xc4\x46\xf0\x22\x3e\xfd\x47\xd2\x74\x72\xc1\x41\x45\xe3\xff\xe7\xff\x84\xfa\x17\x04\xaa\x31\x9d\xb7\x3f\x91\x0b\xb7\x67\x65\x32\x47\x3e\x4a\x68\x79\x5e\xdd\xfa\x58\x2e\x19\xbd\x48\x46\xf9\x5b\x0a\x18\x55\x77\x8e\xa5\xe0\x2a\xfb\xed\x43\xd4\x5c\xe3\x5b\x64\x60\x50\x6a\x47\x45\x16\x6c\x0d\x21\x55\xc1\xb5\x71\x86\x6c\x87\xbf\x4a\x2c\x8a\x4d\xe4\x94\xb6\x84\x66\xa2\xb3\x60\x6b\x86\x53\x05\x77\x25\xa7\xb4\x9f\xe6\x74\xdf\x8c\xa5\xf1\x88\x7f\x99\xd1\x84\x8e\xc6\xab\xa7\x46\x34\xfd\x7c\x9e\xbe\x8d\x47\x67\x0f\xdf\x2d\xe1\x4b\x55\x59\xae\x61\x98\x4d\x65\x3e\xfa\x80\xdb\x2f\xac\x76\xcb\xaa\x7e\x51\x2d\x73\x40\xed\x52\x61\xa0\xf3\x8b\x19\x46\xb5\x69\xc7\x17\x38\x6c\x1d\x5f\xa8\x80\xfb\x04\x7c\xe1\x28\xc9\x5e\x08\x3d\xed\xa5\x60\xe9\xf2\x11\xab\xfa\xe3\x54\x0b\xba\x66\x48\xd5\x90\xed\x3d\xbf\x2b\x3b\x1e\x52\x5b\x25\xf6\x58\xa5\x6c\x57\x34\xa9\x16\x0b\x66\x47\x93\x6a\x6b\xa8\x3a\x4a\xac\x3e\x71\xac\x5a\x51\xfc\xb4\xd0\xc2\x85\xc9\x08\x76\xc5\x22\x06\xd6\x0e\x67\xb6\x5c\x6d\xfd\x78\x6a\xfe\x4e\xc3\xe9\x8e\x59\x80\x1c\xb8\x42\xbb\xf6\xd5\x6b\x25\x35\x1b\x1d\x3b\x60\xe9\x75\x8c\xbb\xae\xba\xbd\x71\xad\x6e\x5f\xab\xdb\x5f\xb6\xba\x2d\x75\xed\xe2\xb8\xc2\x38\xdb\xa0\x45\x27\xf3\x64\x71\x02\x55\x7f\xdf\x60\xcb\x1d\x09\x74\x0c\xd2\xdc\x56\xb7\xbf\x69\x1d\xff\xa4\xe5\x7d\xe9\xa6\x6b\xd7\x08\xf4\xf2\xf8\xf8\xa3\x1b\x79\x3f\x1f\xb5\x1a\xb8\xa3\x18\x31\x3f\xa4\x4c\x4e\x65\xec\xf8\x1f\x3f\xc5\xb9\xac\x20\xad\xc6\x73\x59\x01\x03\xe7\xb2\xb4\x25\x2f\x73\xfc\x5a\xa7\xae\x57\x6f\x4e\x9e\x00\xc2\xb6\xa6\xb7\xc1\x03\x77\x29\x80\x88\x7e\xbe\x09\x03\xd8\xbe\x5d\x84\xfc\x7e\xeb\x46\xd3\x6c\x92\x57\xb7\x18\xc8\xbd\x5c\x73\x82\x88\x9e\xc6\xef\x24\x11\xbc\x4c\xff\x70\x7d\x3c\x58\xdb\x4d\xa0\xb6\xf3\xde\x45\x3a\x92\x66\xcf\xf3\x5f\xeb\x3b\x10\x02\xb9\x5c\xc3\x82\x2f\xfd\xfe\x6a\xbe\x10\x1c\x6d\xfc\x3c\xf1\x7c\x3a\x58\xf3\xf5\x80\x97\xeb\x84\xc8\x17\xd2\xd6\xf3\x38\xad\x69\xbf\x12\xe6\x72\x14\x10\x8f\x49\xac\x2c\x13\x5b\xb9\x76\x5a\xaf\x03\xbb\xdc\xd8\xe9\xed\xbe\xc6\xe1\xd7\x81\x5d\xae\x03\xc7\x71\xd1\xd8\x7a\x25\xcc\xe5\x9a\x16\x22\x6e\xc8\xb0\xe1\x34\x5e\x03\x75\xf9\xe6\x5b\xcc\x7d\x1d\xd8\xa5\xe9\xfe\xe5\xd9\x49\x6d\xe3\x55\x20\x97\xa6\xfa\xa6\x86\xab\x40\x2e\x8b\x72\x90\xe3\xe0\x31\x40\xa8\x1a\xdc\x60\x9f\xc7\x45\x71\x9a\xcf\xc7\x75\xf8\x6f\x59\xe6\xd2\xeb\xa0\x09\x27\x55\x20\x97\x26\xc3\xa6\x86\xab\x40\xae\x82\xf5\x34\xb5\x5d\x03\x15\x6e\x5e\x7f\xc0\x5b\xcc\x8b\x43\x3c\x40\xa7\x97\xc9\x17\x99\xf9\x3e\x49\x8b\x42\x68\xeb\x2b\xad\x7b\x3b\xcb\x0b\xf7\x04\x9a\xf5\x32\x90\x1b\xb0\x4c\xc1\x5f\xcd\xf1\x01\x8d\x80\xab\xd3\x9e\x36\x2d\x24\x48\xf6\x0e\xa0\xe3\x42\x65\x69\xd1\x8e\x83\xd3\xf6\xe6\xb5\x0e\x7d\xad\x43\x7f\xd9\x3a\xb4\x39\xb2\x3e\xfc\xe3\x0f\xe7\xc8\x7a\x67\x9a\xbc\x8b\x1e\x80\xcb\x60\xf1\x47\x5c\xfc\x91\x46\xdf\xc5\x22\xe5\x6f\xf3\x72\x52\x0c\x8e\x17\xb6\x3a\x7c\x47\x06\x25\x7e\x91\x4c\x44\x81\x6c\x24\x88\x1a\xda\x2f\x44\x07\x8e\x44\x1f\x17\x87\x70\x60\x2d\xd6\x57\x36\x3a\x8e\xd3\x6c\xed\x28\xbf\xa5\x7f\x1f\x4e\xf3\xc3\xb5\x42\xcc\xd3\xc9\x5a\x9a\x09\x92\xc9\xe2\xe9\x1a\x0c\x29\x79\x57\xaa\x7f\x07\x47\xf9\xff\x7d\xb2\xb5\xf5\x91\x4f\xb8\xcd\xb1\xf5\x4b\xe8\xcd\x17\xae\x5c\xe3\x8f\xbf\xc4\x99\x35\x39\xb0\x27\xa5\x90\x1f\x7e\x7f\x91\x60\xc4\xe9\x3a\x53\xbc\x0b\xee\xdb\xe2\x05\xa9\xbf\xa9\x81\xba\xcc\x15\xdb\xb3\x6c\xf4\x38\x83\x87\x73\x9b\x7a\xc9\x20\xc3\x1d\x0c\x03\x5c\xa6\x6f\xa7\xf1\xac\x65\xdf\x0c\x64\x45\xdf\x82\x00\x97\xe8\xdb\x38\x3f\xcd\x64\x30\xf1\xba\x8e\x29\xb0\x70\xaf\x02\xb9\xed\x6f\x8d\x57\x74\x6c\x31\x6b\xd1\x2d\x02\x0a\x77\xca\xcb\xbb\x74\x97\xe6\x09\x3c\x50\xfd\xb6\x29\x90\x8b\x02\x0b\x77\x2b\x90\x7b\x19\xd2\x2a\x61\xb7\x6b\x20\x2a\x80\xa9\x20\x27\x27\xeb\xd2\x28\x3a\x4a\x5a\x44\x25\x08\xf7\xc5\xce\xb8\x04\x4e\xe8\x11\x96\x86\x50\xc7\xe1\x3e\xd8\x19\x97\xc6\x86\x7c\x77\xa9\xbe\x33\x04\x14\xee\x8f\x97\xa7\xba\xb4\xbd\xc4\x49\x65\xbb\xf3\x4a\x97\x2d\xb3\x63\x3e\xc3\x0f\x59\xa2\x61\x44\x26\x51\xf1\x01\x7e\x72\x68\x7f\x2b\xea\x67\x15\x01\x09\xf2\x83\x49\x76\xc0\x28\x06\x66\x3e\x98\x2c\xfe\x51\x8f\x19\x8f\xd3\xb7\x95\x57\xdc\x60\x4e\x30\xbf\x52\x87\xac\xaf\x3b\x60\x94\xb5\xea\xc6\xfc\x0a\xf5\x2a\xa0\x5f\x81\xb0\x14\x8a\x59\x1d\x3c\x97\xdc\xba\xd6\xa9\xae\x75\xaa\x2f\x5b\xa7\x92\x0a\x95\x3a\x20\xfa\xb4\x8f\x2d\x5c\xe4\x7e\x84\xba\xe4\x08\xd7\x24\xa4\x30\x4e\x2f\x75\x96\xc7\x95\x42\xb9\xe0\x32\x74\x5c\x56\x1b\x5c\x56\x41\xc3\xe1\x9d\x00\x92\x81\x64\xef\x19\x13\x52\x21\xb0\x2d\x54\x0e\xc8\x77\x9f\x08\x1a\xc5\x62\xda\x3b\x40\xf1\x45\xd9\x19\x5a\x59\x38\x59\xf0\x9e\x5e\x71\x9c\x4e\xca\x2e\xd3\x42\xd4\x9f\xfd\xc6\xe7\xba\x0f\x20\x59\x32\xde\x42\x15\x63\xa1\x40\x72\x74\x20\x7b\x2f\xd0\x8d\x59\x92\x8d\xe1\xd6\xc6\xc7\xee\xc7\x73\x6a\x87\x7b\x8d\x85\x3a\x25\x23\x80\xfa\xbd\x71\xaa\xf3\x0a\xd3\x3b\x49\xe7\x4c\x3a\xd0\x50\xcd\x0e\xc1\xb2\x36\x57\x46\xb0\x48\x21\x20\x3b\x05\xa4\xea\x2c\xcd\x8a\x12\x62\x9e\xb4\x69\xd9\x81\x0e\x87\xac\xa8\x06\xaa\xe9\x87\xd8\xf5\x9e\xe4\x47\x2d\xe2\x02\x02\x54\x65\xa8\x0c\x6a\xd1\x01\xa9\x69\x75\x96\x37\x86\xd3\x01\x90\x86\xf6\x1e\x1e\xc7\xd9\x51\xd2\xa2\xc9\x90\xf0\x41\x55\x70\xe7\x2f\x0b\x7b\x96\x20\x04\x03\x63\x8d\xe4\xf0\xac\x6b\xa4\xe5\x81\xa5\xf9\x0d\xb8\x6b\x20\x6b\xf4\xd8\x4d\x71\xec\xb3\x9b\x6a\xf1\xa9\xe1\x94\xc6\x23\x03\x86\x47\x79\x4a\x63\x79\x12\x5c\xa9\x99\xbe\x9a\x18\x9d\x5e\x90\xa5\xfe\xe3\x75\xc4\x27\x5d\xa7\x03\x9a\x9c\x64\x1f\x5c\xd0\xcb\x35\xef\x90\xf1\x15\xb4\xcd\x49\xcf\x93\x44\xa9\x0f\xa2\x3f\x43\xf8\x1f\x55\x26\x1a\x1a\xc2\xff\x48\x78\x0d\xbd\xab\xb2\xbd\x7d\x2d\x92\x5e\x8b\xa4\x5f\xb8\x48\x6a\xec\xfc\x2a\x54\xc2\x15\x3d\xfa\x4e\x61\x1e\x5e\x24\x47\x30\xcf\xf1\x7c\xe7\x30\xad\x78\x5e\xa4\x58\xfb\xc9\x06\x1d\xc0\x2b\x84\xca\x3c\x0f\x81\x1b\x78\x25\x55\x75\xec\x3e\xdc\x79\xee\xd7\xc0\x7a\x22\xe3\x49\x48\x67\x6f\xd1\x97\xce\xfa\xbb\xd1\x9d\xf1\xb7\xe3\xcd\xd1\x78\x7b\xfb\xdb\xf8\x9b\xdb\xdb\xa3\xed\x6f\xb7\x37\xef\x6c\x27\x1b\x77\xd7\xbf\x1d\xdd\x5e\x4f\xb6\xb6\xc7\x77\xb6\x6f\xdf\xd9\x3c\xec\x98\xbe\x84\xaa\x89\x37\xe2\x8d\x8d\x8d\xc3\xd1\xfa\x37\xdb\xa3\x6f\x47\x93\xf8\x9b\xbb\x1b\x93\xf5\xd1\xd6\xdd\xe4\xce\xd6\xe1\xf8\xf6\xc6\xe8\xdb\x8d\xc3\xbb\xf1\x64\x7d\xbd\x53\xcd\x9b\xa8\x8f\x43\x26\xe9\xc6\x87\xe9\x30\x80\x44\xc3\x09\x65\x34\x8b\x61\x70\x7c\xb4\xd3\x13\x30\x74\xdb\xab\xd9\xc5\x6b\xa0\x5e\x7f\xb8\x54\xab\xc5\x31\xeb\x67\x71\x75\xb8\xd1\x5f\x6d\x98\xa7\xd5\xe1\x26\x30\xdb\xdb\xd7\xcc\xf6\x9a\xd9\x7e\xd9\xcc\xd6\xf0\x5a\x65\xfc\x72\x98\x6d\x9d\x63\xf2\x64\x9e\xff\x91\x9c\xc4\xd9\x60\x9c\x7c\x7f\x25\x0c\x3a\x14\x69\xc2\x09\x33\xe1\x1e\x90\x7a\x2e\xb5\xd6\x29\xa8\x7c\xa6\x59\x66\xe1\xe7\x3d\x93\x4b\x86\x44\x96\x4f\x09\x0c\xa2\x70\x21\x0a\x17\xc2\x7d\x95\xdc\x80\x3a\x39\xac\x8c\x6d\x7f\x66\x45\xec\x0c\x56\xc2\x3b\xcc\x05\xfb\x34\x49\x96\x0a\x17\xfc\x11\x5b\xf7\x70\xd3\xc2\xd3\xfc\xc4\x42\x91\x57\x35\x18\x48\xdb\x3d\x1a\x6f\x9e\x48\x6f\x78\x30\x1e\x1f\x17\xf7\x9f\x4b\xb7\xde\xfb\xd5\x49\x6b\xa1\x21\xb1\x7a\x5c\x03\x4e\x28\x02\x93\x83\xe1\x1f\x42\xa9\x54\x76\x08\xfd\x6f\x3b\xe0\xdc\x7a\xe2\xb9\x61\xd0\xf4\x24\x30\x1f\xb5\x79\x0c\x3e\xfc\x40\x7c\xe5\xb8\xa9\x2a\x7b\x16\xad\xa7\xaa\xf9\xd8\x5d\x8a\xfc\xea\x2b\xf9\xae\x75\x74\xe3\x3e\x11\x8e\x93\x04\x1b\x0a\xbc\x1c\x33\x56\x78\x72\x6a\xd0\x6d\x0d\x65\xa9\x0a\x74\xe1\x03\xd0\x02\x59\xf2\xf5\x79\xf3\x2a\xbd\xe0\x13\x27\x46\xd9\xd6\xef\x2a\x0f\xa2\xa7\xb0\xb1\xc1\x41\x04\x51\x12\xa2\xc9\xc1\xa5\xf3\x94\xbd\x4f\x44\xa6\x3e\x0b\xaf\x3f\x4a\x4c\x7d\x78\x26\x06\x7c\xce\x9c\xdd\xd3\x89\xa8\x53\xec\x3d\x45\x15\xca\x9d\x16\x2b\xed\x85\xf0\x1e\xb9\x7a\x88\x4a\xb5\x46\x4f\x51\xa1\x99\x4d\xbe\xfb\x7e\x2b\xda\x38\xe8\x05\x1e\xd5\x41\x98\x59\x3e\xeb\xf6\xf0\xf9\x79\x79\xee\x05\xfc\x1f\xd7\x13\x62\xeb\x06\x17\x6e\x6c\x74\x93\x7f\x83\xe9\x99\x40\x7b\x90\x14\x6d\x3f\x88\x2a\x62\x94\x49\x6a\x14\x95\xd4\x68\xd7\x66\x73\xdf\xc3\xdf\xac\x97\x62\xa1\x8c\x16\x25\x51\x9c\x84\x87\x82\x90\xbc\xf0\xb1\xf9\x9f\x92\xb2\xcb\xb4\xf3\x24\x13\x73\x38\x87\xc3\x31\x08\xb1\xbf\x48\x7c\xd3\x9f\x60\xdd\x85\xf4\x4f\x41\x3e\x3e\x28\xc4\x06\x5f\x8a\x2d\xa2\x23\x4d\x99\x0a\x0a\xb8\x23\x00\xa3\xf1\x56\x4d\x14\xa6\xa8\x39\xf9\x3e\xda\xe0\x33\x22\xba\xbe\xaf\x4a\x1c\x00\x37\xb6\xbe\x3f\x7c\x88\xde\x9f\xdf\xe3\xc0\x2e\x88\xc9\xb4\x9a\xdf\x90\x19\xe7\x34\x88\xf0\x23\x4d\xa2\xa2\x3e\xc2\xf7\x09\x65\x72\x2c\x50\x7f\x0c\xa7\x88\x3f\xe1\x7e\x03\x22\x2f\x0e\xa1\x77\xa0\x50\x80\x48\xdc\x91\x20\x5d\x76\x4d\xc3\x29\x66\x4d\x12\x56\x61\xb3\x0c\x3c\x5d\x8e\x6e\x62\x1f\x06\xa3\x63\x21\x78\x97\xdd\xf5\xde\xa0\xcc\x5f\xcf\x66\x60\x25\x2d\x92\x6e\x4f\x65\xe3\xe3\xe8\xdd\x8d\x5e\xe5\xc6\xa3\x66\xb6\xd2\x12\x3f\x33\x1b\xb7\x79\x0d\x59\x05\x36\x54\x25\x8e\x60\x40\xfe\x8a\xd1\x02\x0a\xe7\x89\x5d\x9d\x6a\xef\x63\xf8\x24\x98\x63\xe8\xa1\x25\x31\x34\x15\xd1\x76\xcf\x2c\x36\xca\xdf\xa7\x7e\x80\x0a\xeb\xcb\x8d\xd2\x9c\xf5\x57\xc7\xf2\x83\xbf\xba\x01\x61\xcb\xcb\x8e\x8a\xbf\xdd\x93\xcc\xe7\xfd\xc8\xde\x3a\xd4\x9f\xea\x0b\xc1\x84\x91\x2d\x4b\xb2\xb3\x83\x73\x83\x32\xb9\xc8\x55\xbf\x15\xa1\xca\x4f\x7a\x84\x4d\x3e\xbf\xc6\x66\xf5\x28\xa9\xda\x4e\xcc\xee\x3b\x82\x9b\x49\x42\x42\x5d\x08\x91\xfa\xef\x2f\xf7\x9e\xbd\x78\xfe\x30\x52\xad\x9c\x1e\xa7\x82\x0f\x82\xf2\xa4\x76\x20\xa1\x4c\x1c\xa2\xcd\x56\x82\x38\x1c\xd1\xa4\x6a\xbe\x37\x18\x0c\xce\xa5\x05\x2f\xb4\x37\x47\xa0\x12\xce\x67\x23\x56\x34\xc8\x1d\xcd\xc0\x2b\xc8\x22\x3e\x93\x0e\x3a\xae\xdb\xdc\x90\xcb\xa3\xbe\x91\x7c\xff\xc0\x36\xaf\xc3\x34\xc9\x22\xce\x66\xd5\xc5\x3d\x91\x8b\x82\xb0\x64\x07\x50\xa8\xab\xf7\xc9\x5e\x8f\x4f\x99\xec\x95\x9c\x68\x35\x6b\x7c\xda\xab\xa6\x4e\x96\xac\x30\x70\x2a\x04\x31\xc5\xdc\x09\xbd\x05\x2a\xf6\x9d\x6b\x15\xfb\x5a\xc5\xfe\xb2\x55\x6c\x66\xcf\x94\x1c\xe2\x44\xb2\x74\x5b\xd3\xfe\x7b\x32\x11\x34\x7b\x16\xfd\x9a\x4e\x47\xbf\x8b\xca\x7f\x13\xdf\x55\x41\xb7\x96\x8a\xd0\x25\x80\x41\x85\xdf\x83\x48\xf7\x31\xc2\x86\x62\x73\x5d\x20\x9c\x97\x2c\xf2\x53\xfc\x36\xfa\x35\x17\xac\xf5\xbb\xa3\x4a\x25\x7f\xdb\x28\xf9\x7f\x97\xdc\xd4\xba\x3c\x2c\x59\x6c\xdd\xcb\xd4\x81\x78\x93\xee\x63\xd2\xa1\x97\xa4\x13\xb8\xce\xea\x5c\x55\xa6\x34\xf2\x41\xa0\x6d\x67\xb7\xec\x14\xb0\x31\xce\x84\x1a\x9e\x0a\x29\x14\x09\x6c\x16\xe3\x25\x12\xc1\xa4\xe9\xcc\x07\xf6\x22\xc1\x5a\xdf\xa6\x82\x3b\x14\xba\x54\x3c\x2d\x72\xbf\x68\x3e\x9d\x42\x51\xa0\x36\x75\x7f\x5b\x10\xce\x98\x72\xd3\x4c\x20\x88\xd7\x0c\x95\xc9\xa7\x41\xe8\xc8\xb5\x4c\x4f\x12\x58\x6c\x62\x15\x6f\x08\x5a\x13\x43\x1a\xe3\xee\x28\x0a\x4d\x93\x32\xcf\x10\x9d\x30\xbc\x1a\x45\x5f\x75\xd5\x52\xf7\x55\xa2\x80\x54\x3f\x99\x9d\x01\xda\x46\x0b\xf0\x39\x4b\x54\x7d\xe1\x56\x87\x4a\xe5\x0f\x24\x94\xe3\x79\x9e\xe5\x8b\x62\x7a\x86\x71\x35\x2a\xf6\x61\xc8\x0a\xe8\x23\x91\xa0\x98\xb8\x52\x43\xb6\x47\x6b\x99\x3c\xb2\xb1\x35\x78\xa8\x86\x6b\x6a\x37\xac\xd1\x5b\x6f\x94\x8a\x79\xcb\x61\xeb\x02\xa2\xe8\x12\x69\x0c\x76\xb3\xb7\xf1\x34\x1d\x3f\x97\xf0\x5d\x2e\xf3\xa8\x5b\xd8\x88\x0c\xdc\x49\x57\x02\x7b\xbc\x24\x73\x21\x49\x3f\xa7\x34\xec\xd2\x80\x46\xdf\xc7\x61\x4a\x67\x0b\xa6\xbf\x48\xad\xfc\xbe\x3d\x57\x24\xcc\xca\xaa\xd5\x51\x39\x0e\x4a\x35\x92\x16\xbf\x40\x77\x5f\x10\x15\x26\x5a\x50\x63\xc3\x2c\xc5\xac\x9c\x46\xf6\xf0\x5c\x78\x6b\x38\x72\x98\x94\x35\x68\xa5\xf9\xc7\x4b\xcd\x3e\x4a\xb3\xb5\x24\xe0\xea\xa5\x5a\xfa\x69\x26\x06\xac\xdc\xa3\x08\x16\x03\xbb\x15\x6d\xa8\x17\x70\x99\x6c\x5c\x47\x1d\x57\x43\x08\xfe\xdc\x93\xdc\x2f\xab\x6c\x21\xcf\xc3\xa0\x44\x96\x2b\xe2\x07\x46\xc3\x45\x79\xfd\xcb\xaa\x67\x19\x1a\x6b\x46\x99\x57\x9a\xb7\xef\x09\xb9\xf2\xd1\x66\x8b\x04\xdd\x18\xbb\xd5\x6a\x43\x88\x1a\xeb\x34\x03\x00\x78\x00\xf9\x01\x23\x13\x95\xbb\x0a\xc2\xc4\x16\x3e\x33\xc2\xc4\x3e\x71\xea\x54\x4c\xe6\x62\xa4\x58\x5c\x01\x2d\xda\x34\xa8\xec\x6c\x38\x1b\x5d\xd5\xca\xa5\x48\xaf\x68\xa2\x3d\x6b\x40\x9a\xe8\xa2\x86\xed\x4f\x68\xf8\x7a\x8b\x15\xd2\xa6\xd2\x09\xa5\xc8\xe7\x51\x5c\xa9\xa6\x2a\x6c\x9a\xab\xa5\x25\x5d\xd6\xde\xf5\x2b\xb7\xfb\xa6\x9d\xbb\x04\x95\x4a\x0a\x2e\x6e\x47\x21\xef\xb9\xce\x6a\xe4\xd8\xf4\x84\xc7\x39\xd6\xb6\x3b\x6e\x5c\x32\x81\x5c\xed\x96\xa4\x7a\x52\xe6\xe3\x3c\x1a\x4d\x93\x38\x5b\xcc\xc4\xde\x0b\xf5\x03\x82\xb5\xda\x5e\x87\x2a\xd6\xfb\xc0\xca\xa3\x4e\xf2\x80\x28\xba\x71\x1b\x97\x20\x1c\xed\x13\x34\xd8\xe1\xde\x43\xf1\x61\x44\x95\xa4\x60\x82\x71\x2a\x1a\x86\xaa\x1c\x9a\x9f\xe7\xea\x69\x7b\xb8\xca\xfc\xff\xb3\xf7\xf6\xfd\x6d\xdc\x38\xa3\xe8\xdf\xed\xa7\x98\xee\xbd\xbb\x91\x1b\xc5\xd1\xcc\xe8\xd5\xa9\xf7\x39\x8e\xe3\xd4\x7e\xe2\xc4\x39\xb6\xd3\x76\x4f\x7e\x69\x7e\x23\xcd\x28\x56\x2b\x4b\x7e\x24\x39\xb1\x77\x9b\xfd\x3e\xe7\x73\xdc\x2f\x76\x09\xf0\x9d\x04\x47\x23\xd9\x49\xd3\xae\xd5\x26\x91\x66\x48\x10\x04\x41\x10\x04\x41\xc0\xa6\xbe\x25\x00\x84\x02\x66\xaf\xe6\x02\xa1\x9a\x9a\xe1\x4b\x06\x77\x7a\xa1\x94\x52\x50\x41\x85\x1b\x2d\xee\x6e\xde\x8d\x60\x4b\x37\x72\x6d\xc7\x50\x87\x18\x73\x6f\x6c\x4b\x29\xaf\x40\xd8\x4c\x8a\x75\x65\x87\xb8\x67\x19\x41\xe4\x47\x06\x79\x34\xfe\x82\x3a\x35\x61\x51\xfd\xb5\xb8\x9e\xd7\x74\xdd\x0d\x69\xe5\xdd\xde\xde\x8e\x1a\x70\xd4\x11\xa2\x21\x30\xd3\xec\x94\x3f\xaf\x59\x85\x1e\xd9\x74\x76\x15\xe0\x12\x7a\xeb\xd5\x87\x49\x0e\x26\x0b\x40\xff\x97\x64\x3f\x2f\x06\x67\xd9\x64\x34\x3f\x97\xdb\xd0\x72\xe1\x80\x00\xca\xc9\xcb\xdb\x30\x09\xfb\x6b\x51\x5c\xa8\x34\x20\xb2\xb3\x0f\xbf\xfd\x65\x7e\x36\x9a\x40\x43\x57\x90\x20\x6b\x5c\x5c\x8d\x16\xd7\x5b\x2d\xdc\x92\x41\x01\x60\x88\x1a\x2c\x0e\x8c\x96\xdc\x52\xa0\xa8\x69\xd0\x8b\x0d\xc3\xac\x38\x9f\xb2\x6d\x35\xb8\x51\xe3\xeb\x7a\x54\x5c\x0d\x8a\x8b\x05\xaa\xfd\xe2\x91\x59\x9e\xf5\xf2\x3a\x9a\x14\x9c\x22\xfd\x42\xd4\xcf\xa1\xc7\x97\x0c\xc8\x75\xd4\xbf\x46\x92\x01\x79\x44\x46\x0f\xe4\x99\x1f\x61\x41\x82\x44\x26\x1b\xc6\x3a\x50\xfb\xc6\xea\x1d\x58\xe6\x19\xbe\x9b\xa3\x49\x5e\x5c\x1d\x0d\x6b\x78\x4d\x11\x98\xed\xed\xbd\x0d\x1c\xfc\x07\xb1\xbb\x40\x18\x1c\xc6\x6a\xbe\xd9\x54\x33\xd1\x75\x87\xf6\x39\x12\xca\x7b\xae\xc9\x7f\x60\xf6\xc4\x5d\x26\x8c\xfb\x80\xfb\xe6\x46\x8c\x7b\x2a\xc8\x04\xee\x52\x5b\xc6\x93\xc2\x63\xd8\x37\x81\x06\xb8\x30\x6a\x03\x38\xa2\x33\x8c\xb5\xad\xde\x03\x60\xc3\x14\xc9\xc4\xec\xe6\xde\xe9\xfe\xdb\x97\x47\x87\x87\x07\x2f\xbe\x7f\x7b\x7a\xf0\x7c\xef\xe8\xd5\xa9\xb9\x3d\xaa\x32\x02\xbe\x52\x65\x69\x4c\x9f\x64\xeb\xe8\xeb\x64\x80\x97\x88\xdc\xf9\xfa\xcd\x23\xfb\xf9\x01\x5e\x37\x96\x8f\xab\x4d\x55\x05\x70\xf3\xe2\x72\x7e\x56\x73\xf9\x5e\xa8\x78\x56\x69\xd6\x0c\x2f\xcc\x4a\x6c\x78\x34\xd0\x00\x57\x20\x5e\x25\x75\x53\x41\x16\x8d\xca\x29\x75\x9e\x5d\x58\x42\x72\x84\x6c\x8b\x02\x05\x59\x0c\x58\xd3\x26\xd3\x73\x56\x43\x9b\x2e\x0c\xbb\xb6\x7d\x53\x9c\x2b\xae\x28\x35\x36\xfe\xe5\xd2\x80\xc1\x78\x8d\xd5\x46\xb8\xc4\x4b\xca\xbc\xc6\xe2\xf2\x40\x6f\x43\xae\xdb\xde\xbd\x79\x2d\x64\xaa\xea\xcc\x53\x5b\x6b\xb6\xc2\x9b\x9c\x1e\x3d\x39\xda\x92\x4c\x16\x8d\xa7\xef\xfe\xcb\xd5\xaa\xa7\x01\xbd\xfa\xa6\x9a\x74\x05\x63\xc1\x9c\xdc\x3a\x8a\x77\x9b\x6c\xf4\x6a\x21\x67\x05\xf9\x41\xff\xc5\x5c\x53\x19\x68\x2f\xb6\x7a\x23\xb5\xf6\x1b\xe4\x38\xcf\x7e\x2d\xa2\x39\x1c\x02\x01\x5f\x08\x61\x35\x9a\x33\xe1\x0b\x76\x6c\x21\xc9\xc1\xd6\x3b\xc4\x0b\x42\x33\x88\xaa\x34\x76\xd6\x5a\x0b\x26\x10\x04\xaf\xfd\xf3\xa9\xc1\x1a\x75\x51\xd4\x5d\xda\x54\xde\x0c\xdb\x91\x5d\x47\xbd\x09\x5e\x98\x91\xb6\x13\xf3\xd5\xc7\xa8\x18\x0b\x3b\x33\x51\x63\x98\xb1\x97\xe6\x29\x9b\xb8\xf6\xb4\x94\xa6\xa2\xfe\x37\xdf\x88\x36\xa3\x35\x60\xc0\xb8\xe0\x88\x1b\x93\x36\xb0\xf9\x7f\xe4\xd1\xf3\x1b\xac\x59\x81\x8e\xd5\x0d\x03\x88\x3d\xd5\x4b\xac\x68\x53\xc9\x99\xec\x61\x32\xd9\xb8\x84\xed\x0c\x1c\xb0\x6d\x73\xf0\xce\xba\xec\xc5\xd0\x3b\xf1\x72\x32\xa8\xf9\x19\x68\x84\x41\x9a\xfd\xc2\xd0\x3e\xc2\x18\xfe\x97\xad\x14\xaf\xa1\x76\xaa\x9c\x91\x89\x98\x9b\xc5\x62\xc1\x54\x10\xfa\x66\x2f\x0a\xa6\xdc\x48\x40\x0d\xae\xc2\xe2\xce\xda\x23\xaf\x84\x0e\x8e\xad\xfc\x83\x42\x41\x8b\x44\xa3\xa2\xdf\x80\x72\xf7\xee\x58\xef\xee\x58\xef\x4f\x7e\xac\x27\x22\xfa\x8a\x5d\xcb\x3a\x51\x7d\x97\xb9\xc3\x06\x52\xd8\x38\x19\x6c\x56\x71\x9c\x95\x53\x3a\x16\x9b\x83\x9d\x9c\x29\x97\x40\x3a\xb5\xba\x41\x20\x35\xa6\x79\xcf\xa3\x4b\xae\x7e\x89\x4b\x6f\x75\x50\xbe\x46\x0b\x8c\x94\x17\x61\x1e\x0f\xbe\xa8\xf2\xd5\xfe\xeb\xaf\xcd\xfd\x81\xd8\x9f\x7d\xed\x1a\x89\x60\xd9\xfc\x5a\x1c\x5b\x19\xe5\x0c\x59\xc5\xe3\xf4\xc8\xab\x74\x50\x6e\x3a\x11\x17\xae\x2c\x89\x26\xf4\x26\xa0\x3d\x61\x6a\x0c\x29\x45\x7c\xdd\x96\x3d\x9d\x13\x8b\x05\xe5\x8f\x23\x3f\xf6\xba\xe5\x2e\x4f\x21\xdb\xa5\xf2\x10\x94\x81\x88\x10\x65\xb6\xeb\x83\x2f\xdc\x30\xc5\x48\x80\xd2\x78\xc3\x82\x28\x8f\x2f\x96\x65\x26\xe6\x10\xcd\x5b\xca\xeb\x76\x52\x69\x43\xe3\x6c\x8e\xcd\x9c\x2c\x60\xb0\xc1\x43\xd4\x25\xb4\xfc\x78\x0f\x98\x96\x06\xe0\x6d\x96\xc2\xa9\x06\x4e\x7d\x4c\xca\xcf\x98\x26\x3f\x9f\x72\x3f\xc7\x8b\x0b\x14\xdd\xb8\x77\x66\x62\x6f\x01\x1b\xfe\x3a\x13\x5c\x43\x10\x00\x7c\x8a\xcb\x23\x54\xa4\x06\x37\x32\x3a\x1f\x30\x0b\x10\x58\x33\x0a\x53\x94\xdf\xf0\xea\x5b\x43\xcb\xd5\x00\x40\x95\xb8\x1e\x1d\xec\xec\x94\xcb\xcd\x49\x71\xb5\x88\x76\x5f\xbe\x8a\x06\xd7\x90\x07\x5e\x75\x13\xc9\xae\x16\x1b\xec\x09\x76\x59\xf8\x2c\xb1\x9d\xc2\x00\x64\xd6\x9c\x53\xc7\x6b\xc5\xd8\x06\xab\x69\x42\x8d\x05\xd1\x11\xc1\x1a\x6e\xa9\x8f\x75\xb6\x73\xab\xd0\x3f\x82\xac\x9c\x15\x42\xaa\x99\xe1\x0c\xf6\xb5\x02\xe0\xba\xb1\x19\xb6\x58\xc3\x93\x4d\xfa\x41\x05\xb2\x76\xbb\x9e\x50\x1f\xeb\x5a\x09\x07\x8b\xab\x12\x13\xe2\xb9\x69\x43\xe2\x36\x03\x94\x2c\x30\x4f\xa4\x9f\xe4\x01\x99\x5d\x37\x98\x32\xb7\xdc\x65\xde\xd1\xff\x54\x49\x8e\x1f\xe8\x59\xda\x38\x75\x9f\x4b\xe1\xfb\xf7\x8d\x72\x6a\xbe\x1a\x3b\x7d\x7c\x91\xe5\xb9\xf2\xed\x32\xd2\xf7\xaa\xf7\xee\xf0\x18\x1b\x07\x43\xc4\x4a\xe7\x2d\x7c\x6e\xf8\x8a\x73\x85\x4e\x51\xc2\xf4\xf4\xd5\xed\x96\x7a\x2c\xb2\xe9\xa7\x1e\xd9\x56\x29\x2d\x82\xd0\xaa\xe0\x20\x5f\x09\x09\x73\x14\xc3\x1a\xad\xc7\x60\xca\xe4\x5c\x33\xc6\x60\xa3\x84\xda\xa4\x56\xab\x10\x0a\x3b\xb0\x81\xae\x6e\x67\xbb\x00\xdd\xf7\x2e\xd5\xf0\x9d\xee\xfb\x67\xd7\x7d\xb5\x4b\x9b\xcc\xbb\x7d\x5b\x77\x74\x0f\xfa\xd9\xc4\xd6\x76\x21\x4d\xb8\x72\xe6\xba\xe2\xe6\xea\xb2\xab\xbb\x27\xac\xad\xc5\x9e\x28\xa8\xaf\xdd\x06\x8f\xc6\xd0\xac\x84\xcd\xca\xbe\x38\x36\x6f\xe3\xb1\x3a\x04\x3b\x61\x5c\x38\x79\xf7\x11\xaf\xb6\x50\xcf\x41\x5b\x66\x20\xcd\x57\x3f\x64\xe3\xcb\xe2\x23\x18\x2d\x98\x26\xc4\x8f\x43\x00\x32\xc4\x20\x5d\x72\x42\x5a\xb7\xdd\x0b\x30\x48\x8d\xc0\xc9\x56\x8b\x17\x67\x75\xc4\x08\xb4\xf5\x3a\x6f\xc9\x5f\xc2\xd0\xec\xc6\xa9\x8c\x49\xe3\xd9\xd7\x1a\x5c\x3c\x88\xb4\xc1\x1b\x5e\x4b\x93\x4f\x8d\xf0\x22\x82\x7a\x98\x26\xa2\x76\x6f\xc4\xcd\x37\x1c\xa2\xb8\xa7\x7b\xcf\xb7\x1f\x0b\x88\x4f\xd8\x70\x31\x5d\x8c\xba\x10\x23\x7b\x74\x3a\x15\x37\x7b\xcd\xee\x60\x75\x01\x1d\x32\x58\xb8\x5d\x93\xed\x29\x3d\x06\xbb\x41\x69\xba\x78\x45\x42\xdd\x94\x86\x80\x42\xc6\x9d\xe2\xda\x06\x36\x22\xb0\x9d\xcc\x17\xa3\xc5\x25\x57\xb8\x7c\xf7\xaf\xbc\xb8\x98\xce\x47\x0b\x13\x4b\x01\x57\xa1\x87\x60\x06\x4c\xa0\x4c\x16\xae\x27\x46\xe5\x86\x3d\x17\x0b\xd1\x08\x41\xc1\x55\x31\xf2\xe9\x27\x4d\xf0\xa5\x47\xc9\x8a\xf5\x18\x62\x39\xfa\x44\x0e\x8a\x19\x93\x58\x6a\xf8\x03\xd3\x47\x0d\xec\x6a\xf3\xe8\x93\x4f\x21\x85\xd7\x1a\x73\x49\x50\x1e\x46\xd3\xc9\x2d\x66\xe8\xb6\xea\xf6\x3a\x03\xa4\x1e\x02\xe8\x2d\xde\x80\xc1\x9b\xac\x91\x2d\xfe\x8f\x54\x73\x8d\x21\x2a\x1d\x15\x31\xf8\x7a\x50\x30\xae\x3d\x13\xff\x52\x12\x46\x52\x42\x46\xb5\xf3\x4b\x38\x4f\x81\x13\x38\xb8\x0f\xa0\xf8\xa6\x7f\xbd\x28\xe6\x69\xb2\x21\x94\xf1\x6f\x36\x9c\x81\x14\xe5\x6e\x7d\xf8\xe6\xde\xf8\xc9\xea\x9c\x53\xc1\x61\x82\xa9\x78\xd1\x28\x67\x3f\xe0\xbe\xe0\x6c\xe5\xb1\x96\xd4\xa9\x30\xc2\xd8\x24\x2d\x35\x33\x5c\x75\x8c\x35\xe8\x91\xcb\x04\x92\xc6\x35\x56\x94\x4d\xe9\x05\x08\xd6\x4d\x49\x5f\x09\xfa\x5f\x37\x65\x02\x4f\x57\x95\x54\x24\x83\x20\xf8\xeb\x21\xd3\x54\x41\x75\x05\x61\xc0\xbe\x42\xf8\xf8\x56\xa5\x8c\xe7\xc2\xba\xdb\xaa\x94\xaf\xed\x91\x69\x84\x9f\x82\x7e\x31\x64\xe8\x4c\x67\xd7\x75\x6e\x50\x06\xc2\x7e\x05\x62\x1a\x54\xfd\x29\x5c\xca\x14\xbd\xd9\xde\x8e\xfe\xc2\x03\x32\xfd\x05\xcb\x7c\xc5\xf6\xd9\xbb\xd3\xf3\xf3\xe9\xe4\xbf\x4f\xd8\x2f\xaf\xf3\xfa\x9b\x68\x40\xe2\x54\xfb\x0b\x90\x61\x56\xfc\x65\xa3\x1e\x19\x8f\x8a\xc9\xe0\x41\x3f\x9b\x17\xed\xa6\xf3\xe2\x3c\x6f\xb9\x45\xdf\x5f\xfc\x9a\x0f\x9d\x87\x83\xd1\x05\x53\x6f\x1f\x70\xc8\x6c\x44\xbe\xfa\xf8\xf5\x57\x70\x96\x13\x19\x9d\xe1\x06\x73\xde\x17\x49\x86\xbf\x80\x51\x82\xbf\xd8\xcc\xce\x73\xd5\xb7\x9d\xe7\x4f\xd8\x17\xfe\xa2\xf6\x5a\xe2\x5c\x8f\x6c\x54\xf1\x37\x60\xc8\x5f\x70\xc4\xf0\xbb\x89\xcf\x1b\x45\x65\x13\x31\xd1\x10\x8f\x86\x11\xd5\xfa\x6c\x09\x67\x7a\xe2\x06\x7b\xaa\x28\xc6\x06\x68\x73\x77\x76\x7d\xb1\x98\xfe\xf7\x09\xaf\xfa\x11\x77\x4f\xe6\xf2\xa3\xde\x83\x81\xee\xab\x9a\xbd\x1d\xc3\x4c\xbc\xac\x11\x26\x99\x66\x8b\xc1\xe5\x62\xce\x9f\xc0\xb4\xd9\x65\x43\x23\xeb\x3e\x32\x1e\xbf\x1d\x8f\xfa\xf0\x6a\x93\xfd\x6b\x3c\x47\x63\xd8\x2e\x76\x0a\xde\x42\xa9\x4d\xe3\x99\x05\x21\x1b\xbf\x9b\x22\x08\xf8\xc2\xb8\x5f\x62\x71\x38\x9d\xfe\x0a\x06\x4c\xb8\x50\x6a\x60\x72\xf2\xf8\xe8\x27\xb1\xe7\x53\xcf\x0e\x5e\xfc\xf0\x96\x7a\x7e\xf2\xea\xf1\xdb\xe7\x07\x3f\xbd\x6d\x84\x5e\xc4\xa1\x17\x49\xe8\x45\x4a\xb6\x1d\x6a\xc7\x7c\xe9\xb5\x65\xbe\xf4\xda\x33\x5f\xca\x36\x15\x69\xd8\x54\xba\x80\x8d\xe2\xd8\x27\x11\x35\xa4\x4e\xad\x7c\x7a\x09\x57\x1c\xb0\x96\x2e\x80\x22\xd6\xc4\x02\x85\x9b\xf4\x54\x18\x61\x34\x41\xf6\xcf\x77\x51\xd2\x6a\xb3\x2f\xf7\xef\x5b\xe0\xe1\x83\x3a\x22\x2b\x10\x27\x5d\xef\x1d\x7c\xf2\xd7\x23\x38\x8f\x67\x45\xbe\x03\xe3\xb5\xf5\x9e\x1f\xa5\x96\xd4\xaa\xf1\x6a\x1b\xd1\xcf\x51\xe3\x2a\x8e\xfb\x6e\x7d\xfd\xf3\xe3\xd7\x56\xaf\x7f\xcc\xc6\xbf\x46\xdf\x3f\xad\x25\x3f\x77\x37\xec\xde\x5e\xf1\x08\x89\xf6\xb3\x91\xf3\x70\x25\x0a\x18\x44\x9e\xf7\xa7\x57\xf6\x4b\x74\x34\x80\x36\x59\x1b\x3f\x47\xb5\x2b\xdd\x21\xf1\x3d\x31\xbe\xa7\xc6\xf7\xe6\x86\xd3\x59\x84\x52\x63\x7f\xff\xfd\xef\x7f\x8f\xba\x58\x92\xfd\xf8\x1b\x23\xcc\x70\xc8\x09\xd4\x4e\x9d\x2a\x30\x3b\x5e\x5f\x01\x21\xe7\x57\xce\x2b\x39\x79\x5e\xcf\xf1\xfd\xd5\xa3\xaf\x83\x9d\x3a\xbf\x1c\x2f\x46\x6c\x77\x3d\x40\x2b\x81\xdf\xbd\x2b\x60\xe3\xfc\xf5\xd5\x9b\x47\xc4\xbb\x26\x7f\x97\x90\x2f\xbb\xfc\x65\xf3\x4d\x49\xeb\xf3\xcb\x7e\x84\xfa\x4d\x3d\x3a\x1f\x5d\x31\x5d\x68\x7c\x79\x3e\x99\x5b\xdc\x6f\xc2\x04\x4d\xa1\x96\x63\xaf\xbe\x05\x9e\x69\xc4\x92\x52\xe2\x27\xfb\xaf\xe1\x92\x56\xcd\x64\x4e\xac\xda\x02\x07\xa6\xb9\x11\xfd\x06\xdf\x39\xbd\x03\x55\x62\xb3\x4a\xdc\x36\xaa\xb0\x1f\x81\x3a\x89\x59\x87\x8d\xa4\xae\x93\x78\xa3\xae\xa4\x01\xaf\xb3\x28\xa1\x14\xdb\xd0\x99\xd4\x82\x9f\x95\x29\xc6\x86\x42\xd3\x47\xb0\x67\x53\x3c\x6a\xc8\x07\x89\x45\xd2\x52\x8a\x5a\xf2\x51\xf0\x58\x15\xb2\x5a\xa2\xd3\xaa\xb7\x84\xb6\x96\x58\xb5\x2a\x2e\x21\xb0\x25\x72\x45\xc5\x32\x2a\xe3\x61\x01\xda\x81\x99\x36\xeb\x49\xc2\x6f\xae\x48\x21\x28\x04\xc0\xf6\x0a\x12\x10\xab\x24\x8c\xd0\xf9\x6b\xf8\x8f\x0d\xd0\xcf\xec\xf7\x9b\x37\xee\x44\xc2\xb2\x4c\xb6\x6c\x63\xc1\xab\x91\x57\xc0\x12\x92\xf8\xf5\x23\xee\x69\xd5\xba\xf2\x92\xed\xec\x79\xe7\xf2\xe8\x98\x69\x9f\x62\x81\xd1\xab\xd2\xf1\xee\xd1\x0b\x58\x23\x1a\x57\x8d\x46\x9d\x8d\x79\x23\xc6\xbf\x13\xfc\xbb\x89\x7f\x77\xeb\xc8\x0b\xf0\x77\x82\x7f\x37\xf1\xef\x2e\xfe\x1d\xf7\xe1\xef\xb4\xad\x17\x33\xb6\x17\xe2\x48\x7d\x1b\xed\xec\x9d\xf0\x78\xec\x11\x57\x87\x22\x50\x08\x66\xa3\xc5\xd9\xf9\xa6\x2c\xf3\x50\xa3\x02\xa5\xb7\x85\xfa\xb0\xc9\x7f\x18\x1a\x06\x5c\xaa\xe6\xd1\x03\x54\x97\xdf\xe6\xd3\x63\xf0\xb5\xdd\x8a\x02\x4b\xa4\x20\xc2\xc9\xaf\xa3\x0b\xe1\xf9\xcb\xb4\xc0\xc9\xf1\x14\x77\x63\x67\xd9\x9c\xed\x75\x8a\x09\xde\x0e\x10\xe7\x5b\x70\xce\x0a\x2e\x7c\xf9\x28\x07\xeb\x9b\x70\xc3\xf4\x59\x81\x27\x73\x91\x90\xa4\xbb\xe8\x5b\x56\xf5\xe5\x6c\x34\x9d\x1d\x73\x0f\xe0\xed\x6d\xfd\x9c\x64\x1d\xe9\x16\xe6\x8c\xa9\xdf\x01\x5b\x7d\x93\x1f\xe9\x70\xb8\x4d\x37\xaf\x9f\x12\xf2\x99\x3d\xfd\x71\x3a\x43\x27\x46\x70\xd7\x85\xcc\x6a\x73\xba\x18\xe4\xbd\x14\xa5\xe6\xa3\x77\x8f\x41\x00\x45\x0f\xa3\x66\xd9\x34\x52\xf7\x00\x66\x48\x20\x1f\xac\xa4\x9c\xc4\x51\xff\x96\x0d\xde\x8f\xda\x95\x5a\x80\xfe\xcf\x07\x67\x05\x6c\x3f\x22\x50\xa1\x89\x3e\xcc\x8f\xd9\x73\x90\x19\xb2\x99\xfb\xb0\x4a\x7f\x5b\xda\x07\x13\x2e\x4d\x16\xd9\xa8\x41\x67\xe3\xd9\x6b\x77\xaa\x6a\x17\x51\x40\x87\xab\x1e\xfc\xeb\x77\x02\x43\xf1\x9b\xd0\x42\xe0\x03\x6c\xa7\xca\x73\x22\x91\xe5\xe0\x63\xa0\xf2\x1a\xeb\xbc\xe1\x94\xc5\x11\x17\x4f\x08\x51\x13\x16\x58\xb2\xdf\x0b\x31\x42\x16\x70\x8c\xa6\xe3\x92\xd2\x44\xfb\x1b\x81\xf8\x5f\x15\xe2\x41\xcc\xc5\x28\x1c\xb3\xb9\x07\x2c\x19\x2e\xb4\x30\xd7\x00\x7b\x09\x28\x85\x7c\xc2\x16\xd1\x0a\x90\x51\x7d\x92\x30\xdf\x18\x6b\x1b\xbe\xd0\x2b\x95\xd0\xd1\xde\x18\xab\x98\x59\xa2\x6b\x15\x40\x4c\x05\x64\xf1\x78\x09\xb6\xcf\xd9\x1a\x0f\x62\xbb\x0c\x5b\xb6\x3a\x80\x10\x7f\x2d\x88\xfc\x50\x73\xc7\x6f\x51\x43\xa0\x4e\x0c\x36\x7c\xc4\x80\x23\x6b\x89\x79\xf7\xf7\xa8\x0d\xf2\xcc\x19\x30\x26\xcb\x98\xd2\xba\x64\xd0\xbe\x38\xd2\xd2\x7d\x0e\x50\x3c\x34\x67\x3c\x5e\x17\x24\x79\xc3\xd6\xec\x05\x35\x89\x96\x4a\x73\x53\xa7\x2b\x17\x33\xac\xc4\x33\x42\xd2\x78\x8f\xc3\xc2\x06\x8a\x6a\x79\xa3\x7e\x69\x91\x23\x1f\x05\xa4\x8e\x29\xb0\x84\x1c\x7d\xa0\xea\x50\xcc\x8b\x5b\x46\xd9\xcc\x5f\x4b\x98\x26\x28\x4e\x6e\x57\x32\x35\x49\x70\xcb\x10\x67\x5b\x34\xbc\xc8\xc2\x7f\x94\xf1\xbe\x3d\x16\xaf\x25\x04\xa1\x69\xae\xd8\x91\x12\x60\xb6\xd2\x6d\xcf\x21\x60\x45\x5b\xb9\x0e\x4c\x25\x56\x30\x3c\x39\x03\x1f\x5b\xf9\xa6\x67\xa0\x8b\x40\xfa\xda\x9e\x8a\xf4\x18\x04\x35\xd9\xba\x31\x3c\xc5\x64\x00\x46\x2a\x54\x04\x4d\x3d\xef\x79\x9d\xad\xfe\x43\xa6\xe7\x78\x23\xc3\xe7\x48\x3e\xdd\x55\xf5\x74\xe1\xba\xbf\x56\xd7\xf5\x0e\x51\x7f\x8d\xf5\xd7\x44\x7f\x4d\xeb\x28\x62\xcc\x5d\x86\x85\x6b\x5e\xac\x8a\x2b\x48\xcd\x0f\xd9\x45\x94\x30\xbd\x13\x74\xcf\xe6\xe2\x2c\xa0\xc4\x00\x7f\x3f\x7f\xcd\xc1\x80\xfe\xe2\x92\xd4\x7a\x67\x97\x4d\x4b\xca\xa6\xe4\x96\xa8\x0a\x09\x6d\x5e\xad\xdb\x0c\x6a\xff\x8c\xed\x9f\x89\xfd\x33\xad\x2b\x83\x85\xb7\x78\x33\xfa\x1c\xc0\xce\xf7\x8b\xa0\x11\x3d\xe8\x16\x99\xc8\x51\xaf\x47\x37\x63\x37\x8a\x69\xc4\x86\xa0\xb2\x66\x4d\x10\x16\x62\xa5\x61\x84\x47\x3c\x90\xe4\xaa\x3a\x60\x4a\xd8\xb8\x1a\x06\xb1\xde\xe0\xe3\x9f\x2d\x61\xdb\xa0\x2c\x40\xf3\xd8\x1d\x0e\xa7\x96\x37\x3e\x58\x2b\xb1\x6b\x25\x6e\x2d\xd2\xda\x34\x4f\xdd\xe1\x74\x6a\xa5\x94\x19\xea\x99\xb3\x77\xa0\xb7\xfe\xe6\x12\x48\xed\x18\x8e\x89\x3d\x8e\x5a\x7f\x39\x55\xc1\x36\x20\xbe\x7e\x27\x47\x48\x3c\x08\xac\xbb\x38\xc6\xa3\xe1\x02\x39\xbd\x1e\x30\x94\x95\x0e\x9c\x44\x7d\x01\x83\x67\x98\x6b\x1a\x4a\xf3\xfa\xd9\x30\x74\xd5\xd8\x68\xb9\x7a\xd7\xcf\x86\x51\xab\xc6\xc6\xc5\xd1\xba\x7e\x36\xec\x57\x8c\xfe\xfa\xb1\xb7\x0c\xdf\xbf\x4f\x2d\x00\x88\x5c\x6c\x23\x17\x07\x90\x4b\x96\x20\x97\x96\x22\xd7\x58\x13\xb9\xc4\x46\x2e\x09\x20\x97\x2e\x41\xae\x51\x8a\x5c\xbc\x26\x72\xa9\x8d\x5c\x1a\x40\xae\xb1\x04\xb9\xb8\x14\xb9\x64\x29\x72\x24\xeb\xbe\xba\x40\x1f\xa2\x39\xb8\x3f\xfa\x05\x50\x9c\x2c\x1a\x44\xc7\x50\x64\x2c\x5c\x3b\x1a\xbe\x81\xb1\x58\x24\xd4\x1b\x20\xc4\xc2\xb5\x8e\x93\x46\x14\x72\x3e\x2d\x91\x7d\x38\x7d\x6a\x7c\xe7\x61\xcc\x1d\x77\xd7\x42\x4c\x1f\x77\xdb\xe2\xcf\x20\x6b\xdf\xa2\xa7\xd0\x46\x35\x4e\x50\xf3\x47\x62\x17\x87\xb1\xf3\xe7\x8f\x87\x9d\x37\x85\x6c\xec\x1a\xeb\x60\x97\x18\xd8\x25\x61\xec\xfc\x09\xe4\x61\xe7\xcd\x21\x1b\xbb\x78\x1d\xec\x52\x03\xbb\x34\x8c\x9d\x3f\x83\x3c\xec\xbc\x49\x64\x63\x97\x2c\xc7\xce\xe7\xd6\x42\x06\xb6\xa6\xf5\x12\xbe\x0c\x13\xf3\xc8\x55\x72\xbc\xe9\x64\x2f\xae\xc4\xac\xf2\x54\x9f\x34\xa4\xfb\x88\xad\xf0\x16\x9c\x9f\x3d\x4c\x13\x61\x81\xde\xa0\x4c\xc1\x52\x63\x51\x0a\xd2\x5c\x5c\x1c\x16\xa6\xe1\x7b\x73\x91\xef\x29\xc2\x14\xde\xc3\x6c\x50\x28\x1b\xb1\x02\xf2\xbf\x8a\xab\x0c\x1c\x64\xbc\x17\x72\x4c\x39\xac\x05\x98\xf1\xf5\xe9\x36\xd8\x92\x37\xc5\x3e\xa2\x76\x2e\x3d\xd2\x19\xee\xf5\x68\x30\x7c\xa7\xb4\x79\x0d\xe5\x62\x9c\x01\x12\x0c\x88\x0b\x45\x68\xf8\x35\xdd\x0e\x05\x48\xd8\xb4\x77\x09\x23\xf6\x5b\x1e\x35\x75\xbf\x18\x5f\x14\xb3\x1a\x7b\x8f\xc7\xfa\xdc\x66\xff\xf5\x57\xc2\x67\xc5\x6c\x12\x7c\xe3\xe1\x2d\x3a\x90\x58\x5e\x05\x5b\xad\xa4\x2e\xfd\x12\xb6\x5a\xe8\x3b\x62\x78\x26\x6c\xb5\x9a\x75\xed\x92\xb0\xd5\xc2\x2b\x8c\xe0\xa9\xb0\xd5\x8e\xc1\x5b\x24\xb9\x91\xb7\xc8\xe7\x74\x13\xf9\x64\xce\x1c\x9f\xd1\x2d\x83\xcf\x84\x6f\x23\xe1\x40\x01\xcd\x47\x70\x2e\x33\x9d\x60\xc8\x75\x78\xc7\x98\x46\x8d\x3b\x38\x57\x88\xa2\xbf\xfd\x66\x3a\x00\xa8\x4b\x9f\xb7\xec\xdc\x01\x19\x0a\x95\x57\x47\x06\x77\x15\xd4\x3b\xb0\x19\xe3\xb5\x74\x55\x40\x3d\x31\x21\x5c\x0e\xd1\x5f\x0c\x79\x7d\x47\x9e\xf2\x68\x98\xe4\x6b\x0b\xb3\x02\x6f\x56\xed\xc2\x44\x35\x9e\xbf\x5a\x0c\xbb\x08\x85\x3d\xde\x84\x1f\x0e\xd6\xed\xa6\x7a\xcb\x7f\x96\x39\xa2\xa8\x37\x7b\xef\x2f\x9e\x3d\x79\xaa\x0f\x9b\xf8\x6f\xf2\x04\xab\x3f\xe7\xee\x79\x30\xad\xe4\x29\xd6\xa2\x60\x42\x88\xcd\x62\x42\x40\xa9\x20\xd3\xff\x9a\x88\x80\x3c\xd2\x82\x8a\x97\x0a\x94\xac\x43\xbb\x1f\x7b\xbc\x29\x03\x4c\x6e\x45\xcd\xa8\x16\x27\xdd\xa8\x3f\x62\x53\xaf\x0c\xe0\xe8\x3d\x01\xef\xe0\x87\x75\xc1\xbd\xdd\x7b\xb1\xfb\xf6\xa7\xa7\x47\xc7\xcf\xdf\x3e\x3f\x7a\xb2\x17\xed\x60\x68\x83\x45\x36\x61\xba\x52\x71\x01\xa7\x66\x13\xb8\x0c\x27\x0d\x31\xc0\x86\x6c\x16\xeb\xbe\x93\x30\x9f\xec\x55\x82\x29\xc4\xa9\x07\xd3\x38\x14\x74\x5c\x8e\xd4\x4f\x9a\xa3\x88\x43\x42\x3d\x9a\x1c\xdd\x5d\xbc\xf6\x7d\x39\x43\x87\x07\x95\xe3\xc3\x2c\x62\x15\x37\x7a\xa7\xf8\x1e\xc6\x80\xd1\xbf\x00\xaa\xb3\x45\xec\x52\x5c\x13\x00\x11\x10\x41\x61\x04\x6d\x81\x7c\xa8\x7f\xb0\x95\x62\x0b\x59\x57\xe2\xba\x61\xae\xa8\x1e\xb6\xb8\x5c\xcc\x39\x6c\xc1\x7e\xa3\x49\x68\x30\x28\xf4\xb9\x3f\xa6\x81\x3b\xb0\x1e\x20\x0f\x07\x7a\x64\x59\x79\x33\x94\x61\x1a\xd5\x8e\xb0\x95\x6c\xbc\x81\x75\x06\x14\xf1\x2a\xd2\xc0\x6e\x4b\xc6\x11\xe5\x03\xfa\x11\x58\x44\xf6\x0e\x18\x65\x50\xd6\x27\xd8\x57\x8c\x06\xe1\xf7\xb6\x96\xe0\x17\x80\x8f\xd6\x16\xcc\x35\x5e\x1e\x3d\x6f\xf2\x65\x7a\x8f\x93\x79\x3a\xab\x89\xc3\x33\x24\x61\x3d\xfa\x17\x63\x80\x2d\xf6\x47\xcb\xc6\x8f\x96\xeb\x81\x35\xde\x36\xa4\x2d\x2b\x2c\x94\xd0\x1c\x5c\x0b\x80\x99\x38\x84\xd7\x17\x27\xce\xf6\x5c\x25\x74\x8f\xc8\xd1\xd0\x2a\xf2\x53\x60\x22\xde\xf1\xd3\xed\xf2\xd3\x93\xe2\xb6\xf8\x49\x41\xba\x19\x3f\xd9\x72\x7a\x0d\x7e\x3a\x98\x8c\x16\xa3\x6c\xcc\x96\x9e\x79\x94\xc1\x0d\x94\xf1\xb5\xc0\x30\x17\xe4\x58\xce\x4b\x72\xd5\xb8\x82\x5b\x7b\xcf\x21\x1a\xf3\x1e\xbf\xab\x86\x61\x9a\xb5\xa4\x9b\xce\x4c\x3e\x45\xef\x6a\xbc\xe6\x27\xb9\x56\x2d\x32\x34\x1b\x7e\x71\x2c\x7b\x6b\x6c\x55\xf3\x5f\x50\x92\x62\x4d\x86\x1b\xb1\x21\x36\xb9\x4c\x0d\x93\xc1\x2e\x84\x89\x7a\xe7\xe2\x02\x78\x01\xa9\x25\xb3\x4d\x3b\x56\x52\xce\x8f\xc3\x77\xd2\x58\xcd\xbe\xca\x45\x91\xf3\xa0\xbf\x2b\x5e\x80\xe6\xcc\x1d\xeb\x19\x16\x7c\xd8\x33\xca\x76\xc3\xf9\x5b\xb3\xd4\xb6\x66\xaf\x47\x54\x49\xee\x47\x83\xbe\x32\xe4\x5e\x7c\xc4\x19\x9d\xdf\x00\xa0\xfa\x81\xfe\x45\xb5\xca\x13\x07\x7d\x74\x6c\x39\x8c\xb1\x1c\xe6\xaa\x25\xb4\x9e\xdd\x90\x7d\xc4\x14\xf4\x71\xb3\x46\x78\x56\xc5\x89\x8a\x3b\x15\xc1\x6d\xff\xa8\x8f\xba\x97\x5d\x22\xa0\x8f\x21\x68\x9e\xe9\x42\xde\x76\x76\x01\xbf\x64\xbb\x7d\x18\x4b\xc6\x2a\x8c\x9d\x17\xc5\x03\x41\x8e\xf1\xf4\x9d\x25\x94\x23\x7d\x2e\x75\xbc\x1a\xad\x31\x9e\x06\x62\xce\xaf\xb7\x08\x99\xc2\x44\x94\x16\x29\x45\xbe\xc2\xa2\xf7\xdb\x5c\x5c\x85\x01\xa0\xc2\xd8\x09\xb2\x41\xb6\x21\x1a\x30\xc0\x57\x58\xb9\x0c\xf9\xa4\x60\xf1\xa0\x59\x22\x18\x01\x5c\xc3\xbe\xb9\x34\xd1\x9d\xdf\x96\xec\x21\xe0\xd7\xee\x41\x9b\xf7\x6c\x36\xa9\x52\xef\x83\xc4\x3c\xc8\x61\xa2\xe4\x96\x13\xe5\x95\x93\x2e\x20\x3d\x20\x12\x3d\x31\xfa\x19\xbe\x31\xab\x53\x3c\xc5\xdb\x8b\xb2\xf7\xd9\x68\x0c\xbe\xb1\xdc\x0d\xd1\x99\xb7\xe6\xb2\xf8\x56\x76\xa6\x32\x57\x3d\x1d\x4d\xc4\xc2\x57\xba\x4e\x09\xb8\xf6\x3a\xf3\x62\xba\x10\xb7\xa3\x79\xd0\x34\x0e\x49\xaf\x1a\x70\xa9\xba\x60\x13\x8a\xd1\xea\x7d\xc1\xc4\x68\x06\x49\xd0\x17\xb3\x4b\xfc\x0d\xf7\x5e\xb2\xfc\x01\x9b\x2e\x45\xa5\x75\xa6\x2a\xf7\x22\x1a\x9f\x8a\x87\x39\xf0\x4f\xcd\xc9\x92\x92\xb5\xea\x4c\xac\xaa\xac\xca\xfd\xaa\xe2\x72\xf6\x97\x45\xab\xf3\xff\x53\x3d\x16\x97\x58\xc8\x2e\x01\x76\xb0\x12\x00\xf0\x09\xcf\x16\xab\x28\x71\x50\xb2\x82\x40\xc6\x78\x48\x80\xaa\x98\x70\x45\x2e\xe2\x65\x4a\x49\xfd\xd4\x18\x10\x72\xf2\xf9\xb5\x97\x1a\x9b\xe3\xa4\xfb\x30\x4d\xcc\x57\xdc\x24\x42\xbd\x71\xf4\x20\x56\xc4\x7a\x6b\xeb\xbf\x5b\x51\x52\x65\xef\x34\x27\x4d\xd9\xd9\x72\x43\x36\x82\x21\xe7\xa6\xdc\xb5\x08\x4a\x9f\x9e\x15\x86\x52\x20\x12\x6d\x65\xd1\x19\x5a\x8d\x41\xc9\xac\x30\x15\xa5\x0a\xbb\x33\x91\xf8\x60\x80\x01\x39\xad\x41\x69\x12\xf3\x9a\xe8\xd0\x67\xd8\x2c\x09\x6f\x6f\xdf\xe4\xe9\xd8\xc8\x3d\xdd\x36\x38\xcb\xac\x7a\x5b\xc1\x7b\x53\xf0\x51\xaf\xe6\xc5\x98\x91\x86\x37\x7c\xb2\x80\x38\x5a\xef\xae\x6b\x21\x77\x6d\xc3\xfa\x8c\xea\x22\x04\x90\x41\x51\x7a\x2f\xe8\x1e\x26\x46\xe3\x65\x36\x9f\x83\x98\x00\xbb\x4f\x6e\xdd\x98\x33\x3f\xe5\xce\x61\x02\xd4\x49\x31\xc3\x0d\x17\xac\x6a\x61\x48\xe5\x93\xdc\xcd\xed\x27\xce\x33\x4a\x7c\xd4\x43\x48\x09\x76\x32\x05\x9b\x7f\xc2\x52\xe6\x37\xaa\x83\x80\xd1\xe3\xa0\x0e\x6e\x38\x8a\xf5\x28\x7c\x80\xe3\xd2\xa0\xfe\x35\xfd\x42\xb0\xfd\x96\xd7\x7f\xe7\x3c\xe7\x46\x68\xdb\x27\x45\xf5\x88\x6c\x21\xe8\xa2\xea\x0f\xa8\x2b\xb6\x4d\x29\x59\xdb\x10\x16\x5e\xfa\xe8\xcd\xb6\x61\xb3\xef\x05\x13\x39\xeb\x98\xb2\x51\x87\x12\xc6\x67\xd3\x07\x3f\x4d\x1e\xf4\x47\xdc\x61\xdb\xde\xd1\x70\xed\x04\xc3\x58\x1b\x96\xe9\x38\xaa\xa5\x89\x6d\x98\x36\x0c\xbe\x27\x88\x9f\x63\xf6\x75\x1f\x96\xdc\x08\x79\x4a\xac\xb5\xd4\x0a\x2b\x55\x44\x88\xef\x03\xdb\x2d\x4a\x41\xf4\x97\x37\xdc\xde\x68\xef\x2a\xa9\x34\x7e\xf3\xcd\xbd\xe1\xf8\x72\x7e\x76\xaf\xda\x32\xc7\xa1\x84\x16\x3a\x45\x66\x46\xaa\xb2\x71\xc5\x7d\x2d\x66\x35\xbd\x30\x4f\x4b\x4d\xe3\xf9\xdb\x73\xbe\xf7\xdd\xdd\x14\x5f\x20\x0e\xf3\x32\x0e\xb1\x6e\xec\x60\x3d\x8f\x4b\x8c\x36\x8c\xd3\x4d\xb1\xd1\xf6\x2e\x07\x8b\xe7\xa6\xd1\xbb\xcc\x40\x6f\x9a\x28\xcf\x65\x72\x2e\xad\x5f\xaf\xbc\x9a\xee\xd8\x3d\x22\x0d\x82\x6e\x66\x19\x6d\xe0\x47\xae\xfe\x0c\xeb\xa1\x1c\x29\xb9\xdc\xa1\x1d\x76\xf7\xf1\xae\x67\x8a\x96\x12\x65\xf4\x9e\xdf\xda\x59\xc7\xf2\xac\x61\x94\xda\x0a\x55\x5d\x69\x68\x32\xea\xad\x65\x71\x56\xc3\xa9\x37\x48\xff\x99\xc3\xa9\x2d\xc1\x2b\x0e\x27\x69\xf8\xad\x38\x9c\xaa\xee\x0d\x86\xb3\xcc\xe0\x5b\xed\xe8\xe0\xb3\x0e\xe7\x8d\x87\xab\x64\x0a\x2c\x19\x2f\xd7\x6e\x5a\x32\x48\x7c\x31\x51\x76\xde\x01\xa5\xd6\x09\xaf\xeb\xf7\x70\xdf\xfe\xbd\x39\x5a\x65\x4b\x84\x58\x31\x79\x5c\xe9\xdd\xb3\x8c\xe1\x04\x29\x4f\x42\x67\xad\x8f\xd1\x6f\xe0\xad\xec\x3c\xf8\x47\x85\x82\x0f\xb8\x26\x36\x6b\x05\x81\xba\x1e\x33\x18\xa4\xd1\x8d\x39\xab\x84\x3a\x13\x7d\x54\x26\x51\x58\xcd\x42\x0d\x4b\xa0\x09\x6b\xec\xa0\x57\xec\xb7\xbf\xf6\xf8\xe0\x79\x13\x2f\x55\xf8\xcf\x85\x34\x63\x70\x2e\xcd\x16\xc2\xa9\x7b\xd3\xad\xe3\xc1\x70\x78\x96\x6b\x47\xca\x2a\x22\x94\xa5\xa9\xd4\x91\x22\x5b\x12\xda\x20\xa4\x02\x26\xfc\xb2\x00\x88\xf8\xfa\x01\x92\x58\x22\x92\x1c\x3f\x0c\xe4\x39\xaf\x80\x9c\x3f\x31\xe8\x82\xf0\xc1\xa1\x14\xaa\x0e\xbf\x0d\x80\xe9\xf1\xb0\x0b\xea\x6a\x83\xab\xcb\x9a\x83\x01\x1f\xb3\xbe\x39\x2d\x6c\x28\x41\x5f\x68\xf2\x02\x2c\x7c\xac\x13\x12\x73\x22\x05\x1c\x67\xb5\x12\xab\x2c\x39\xea\x51\xc0\x9d\xf5\xa7\xa3\x63\xdc\x2f\x0b\xa6\xf3\xcb\x5c\x4d\x67\xd8\x2f\x6d\xe8\xae\x47\x56\xbf\xea\xba\x59\x4a\xe9\x17\x78\x98\x77\x5d\x6a\x2e\x75\x49\xcc\x8e\x8b\xf3\x02\xb5\x70\x5c\x5d\x39\x0b\x88\xa3\x28\xdc\xed\xe3\x85\x76\x7c\xec\x57\x97\x1a\x6c\xf1\x5e\x86\x9d\xe7\xc2\x94\xe7\x93\x97\x78\x2b\x57\xc0\x60\x07\xcc\x9b\x3b\x4b\xe7\x6d\x5e\x54\x98\xb7\x6a\x9d\xba\x9b\xb7\x77\xf3\x56\x96\x5d\x63\xde\xde\x64\x76\xa0\x87\x30\xab\xb6\xf2\xdc\x20\x31\x11\x1c\x8d\x52\x84\x49\x93\xa0\x04\x30\x6f\x90\x79\x12\xe0\xa6\x62\x87\xc4\xec\x54\x93\xa6\x0f\xa1\x22\xc4\xd4\x01\xb1\x30\x9a\x5e\xce\xab\x0b\x0f\x45\xac\xaa\xe2\x41\xb1\x92\xec\x46\x2d\x88\xfb\x4a\x37\xa0\x50\x45\x92\xda\x12\x75\xc3\xff\x6c\x3a\x65\x1c\x70\x3e\xba\x02\x5d\x88\xe8\x1f\xde\x04\xf5\x95\x34\x09\xbf\x2f\x3a\xec\x03\x17\x0d\x1c\xf3\x74\x6c\x4c\x3b\x85\x9d\xce\xfc\xb2\x3f\x87\x58\xc8\x93\x05\x69\x62\xd0\xc4\x44\xbc\x8d\xac\x1e\x36\x15\x83\xa6\x41\x13\x25\x67\x54\x96\x9b\xfa\x61\x65\xa3\xb0\xa2\x62\x24\xe9\xd9\x46\x46\x4a\x82\x0f\x0e\xa0\xf6\x1e\x1f\xbd\x81\xcb\xe9\x58\xef\xf5\xa8\x34\x74\x89\xfe\x2e\x5d\xa0\x1f\xef\x8a\xf2\x56\x40\x13\x43\xb5\x7d\x99\xe5\x39\x0c\xe0\x12\x03\xc8\x05\x66\xb9\xda\xdd\xe4\xff\xd2\xe6\x8f\x97\xcf\x76\x4f\xa2\xff\xa7\xf5\xb0\x13\x5d\x08\xa0\x73\x61\xcb\xa3\x60\xbe\xfc\x75\x30\xef\xa0\x9e\xcc\x4a\x6f\xca\x5f\x25\xba\x31\xc3\x54\x1c\x3f\x5f\xce\x65\xe8\x7c\x15\x08\x45\xb8\x2b\x63\xdc\x64\x85\xc7\x4a\xfe\x57\x08\xd9\x3c\x7d\x06\xb4\xc8\x4a\xb4\x1d\x4f\x84\x80\x32\xce\x23\x11\xd0\x5c\x05\xb3\x04\xa2\x60\x38\xcb\x4f\xbc\x6d\x56\x9b\x2f\x45\x57\xf8\xa6\x8f\xd7\xea\x4e\xdc\x2c\xeb\x80\x39\xcb\xdd\xc3\xb5\xb5\x05\x88\xaa\xc6\x63\x9d\x6c\x1b\xf4\x0a\xc5\x0c\x81\xfc\x1d\x92\x77\xf0\x5e\x91\x0f\x75\x22\x18\xd6\x03\xca\x1f\x3c\xe0\x29\xa7\x55\x84\x95\xbf\x3a\x05\xa8\x66\xf9\xb9\x90\x6c\xd7\x0f\xbd\xc0\x33\xbd\xe1\x5b\xe0\x17\x8c\x80\x62\x61\xa1\xef\xba\x78\xcf\xc5\x05\x17\xef\x39\x5e\x6b\xb1\x9e\x2e\xc5\xac\x14\x29\x19\xed\x7d\xa9\xf8\xb1\xdb\x64\x8f\xee\x87\xae\xe5\x9b\xd0\x79\x98\x7b\xe3\x49\x89\xf6\xea\x60\xc7\x9a\x56\xb3\x4c\x6e\xfe\x4d\xd0\x75\x1b\x25\xca\x0d\x61\x27\xcf\x69\x22\xe0\x58\xc3\xf9\x6a\xb6\x90\x30\x2b\x5b\x60\x5e\x4d\x2e\x94\x40\xc1\x29\x7b\x96\xe5\x3c\x90\x91\x98\xa8\x9f\x47\xca\x5c\x4e\x5c\x39\xf3\x59\x24\x02\x36\x5b\xe1\xc8\x1d\xcb\xb9\x22\x81\x92\x03\x70\x0d\xda\x4c\x5c\x6c\x4c\x62\x8c\x01\x1b\x41\x32\x07\xfc\xbd\x7c\x4e\x6b\xf5\xfa\x75\xcd\x9e\xce\x0f\x20\xe2\x10\x5e\x31\x7b\x23\x2e\x83\x51\x37\x89\xb9\xe2\x10\xe6\x18\x0d\x6e\xdb\x9d\x83\xc6\x62\xca\x97\xcd\x15\xed\xfc\xbf\xc7\x41\x10\x75\x45\x81\x3e\x59\xa0\x4e\x15\xca\xce\x81\x6e\xcf\xe9\x1f\xac\x52\x1f\xb9\x79\xf0\x54\xed\xd6\xf8\xa1\x08\xee\x24\x8c\xee\x30\x9d\x25\x04\x45\x8c\xd5\x47\xc5\x5f\x00\xcb\x55\x36\x7c\x88\xc8\xf2\x01\x06\xc7\x7b\x04\x82\x00\x86\xe3\xa4\xc3\xe0\x80\x29\x62\x56\x77\x76\x75\xbc\x6d\xaf\x89\x8f\xd6\x6d\x84\x15\x1c\x03\xf9\x40\xdb\x2f\x77\x0d\xef\xc3\x72\x1f\xc0\x92\x00\x67\xb6\x7f\x28\x71\x8f\x13\xf5\x66\xa0\x80\x65\x16\x95\x6f\x85\x61\x17\xde\x9f\xa3\xef\x67\x00\x7f\x3d\xb0\xfe\x66\xc0\xf3\x25\x95\x9a\xb6\xed\x5c\x12\xdc\x31\x00\x54\x5c\x12\xd1\xa2\x81\xbb\x72\xe7\x6c\x86\xd6\xf6\x1f\x7e\x5b\xde\xbc\xed\xbd\xb2\xc1\xd8\x63\x65\x0c\x94\xa9\x25\xb0\x97\x79\x56\x14\x17\x60\x3c\x19\x17\x20\x05\xa7\x13\x39\x03\x44\x96\x0f\xee\x09\x8a\xeb\x35\x0a\x5c\x5f\x6e\xc1\x87\x63\x7b\x3e\x9a\x70\x27\x51\xb1\x89\xf7\xc2\x25\xda\x3f\x45\x25\x3e\x7c\x06\xfe\x9c\x91\xce\xd1\xff\x98\x6f\x79\x19\x03\xfc\xed\x6f\xa4\x3d\xde\x0d\xd4\xf1\x72\x2d\x5b\x86\xc6\xc4\x36\xa6\x04\xf7\xe7\x6e\xb3\x55\x8f\xa4\xc3\x2a\xa9\x56\x49\x94\xa3\xb4\x78\x44\x30\xb4\x6c\xee\x76\x19\x19\x8e\xcc\x99\xa2\x81\xe9\xbe\x42\x2a\x17\xea\xeb\xc2\xf5\x0b\x35\x70\xfc\xbe\xd4\x06\x21\x8f\xe2\xb9\x8f\x60\x68\x97\xbc\xd6\x61\xfc\xa7\x9b\x56\x9f\x16\x6f\xb2\x3d\xd4\xfc\x96\x8c\x00\xd7\x8f\xa8\xf6\x96\x79\xfe\xdd\xd0\x53\x01\x5d\xef\x84\xee\x41\xbb\xa1\x40\xd8\x12\x70\x97\xc1\x25\x78\x28\x55\x03\x54\x35\x0b\xcc\xa5\x57\xa6\x7a\x18\xaa\xa8\x71\xc9\x1b\x96\xd1\x59\xf6\xc1\x78\x44\xde\x4b\xa4\xaf\x7e\xf0\x0b\xe8\x4a\x49\xa9\x52\x5b\x1f\x3c\x62\x86\x07\x21\x05\x79\x7d\x98\x9f\x9e\x3b\x6e\x08\xd0\x3c\x1b\x73\xec\xf1\x0b\x02\x43\x93\x64\xc6\xc3\x8f\x16\x90\x25\x19\x09\x25\x25\x06\x09\x50\x1e\xa5\x6a\x3d\xdf\xf0\x1c\xf4\x63\xad\x56\xd3\x6b\xd6\xe9\x59\xb9\x7e\x33\x38\x2b\xce\xd7\x83\x4b\x2b\x9c\xc2\x64\x8e\x1e\xd3\x43\x03\x1e\x09\x82\xe7\x64\xfc\xa8\x73\x36\xf2\x5d\x0c\x57\xb1\xe4\x53\x57\x0d\x63\x5b\xb3\xf7\xac\xba\xa5\xc3\xf2\x6c\x77\xd2\x99\x12\x3d\x3e\xb9\xf7\x5f\xf8\xda\xea\x4b\x5e\xc5\xbe\xbc\xaa\x1e\x56\xf4\x87\x59\x7e\xc5\xca\x46\x6d\xf9\xb6\x4e\xdd\x6e\x32\xf1\x71\xfc\x44\x21\xeb\x97\x20\xee\xc5\x74\x3e\x1f\x81\x0f\x7d\x70\xc6\x12\x4d\xad\x76\xb9\x49\x13\xca\xf7\x07\xe5\xef\xe4\x0e\xfc\x5f\x1e\x14\xf8\xe8\x19\xbc\x65\x7c\xd7\x17\x9e\xc8\x4a\x6c\xa6\x6d\xd9\xf7\xa2\xc8\x62\xce\x4d\x29\xba\x10\x4c\xe3\x2d\xfc\x7b\x49\x41\x35\x2b\xb7\xfc\xeb\x5c\x74\x0d\xa1\xc2\xbb\x9e\x09\x74\x61\xa5\xd7\xdb\x5b\x67\xb6\xe5\xef\xd0\x15\x0c\x11\xde\xa4\x4b\xa8\x89\x63\x00\xe5\xcf\x36\x8f\x2e\x8a\xc9\xc9\xc9\xa1\x57\xad\xfa\x65\x32\x73\xf8\x69\xc5\xeb\x7c\x74\x75\x30\xb1\xcb\x55\x76\x3d\xe2\xb3\x78\xbe\xda\x34\xc6\xba\xe4\xbc\xf1\x45\x89\x73\x37\x30\x20\x4d\x60\x53\x17\x06\x2e\x1d\x6c\xe5\xad\x0c\x5c\x15\xf0\x3b\x64\xd5\x71\xe6\x78\x09\x1c\x4c\x02\x36\xe7\x19\xc0\x30\x7b\x5c\xa1\x6f\x51\x6a\x8c\x27\x53\xfe\xc4\x11\x80\xe0\x79\x33\x8f\x9c\xf3\xd1\x35\xa7\x34\x47\x5e\x1d\xd3\x89\xb9\x7d\x3f\xba\x77\x8f\xbe\x5b\x41\x96\xdf\x5c\x4c\x79\xbe\xa1\xd0\x55\x8e\x25\xb5\x02\xac\x1a\x64\x4c\x59\xd1\x64\x4e\x45\x9b\x90\x57\x95\x2e\x01\x71\x3a\x90\x5d\x75\x9d\x4d\x39\x88\xd7\x72\xdb\xeb\xd9\x68\x48\x3d\x89\x73\xd9\xb2\x73\x0d\xde\x0e\x1e\x6d\xa8\xaf\xf4\xe9\x86\xe8\x39\xc5\x9c\x14\x68\x51\x5c\xa5\x32\xc6\xd5\xcb\x26\x62\xf9\x71\x87\x9a\x75\x59\x78\xca\x4d\x64\x33\x0f\x20\x0a\x0a\x5b\xff\x61\x79\xa9\x3a\x0d\xcd\x65\xc1\x59\xc4\x0c\xf5\xa7\xea\x9a\x48\x4d\xbf\x75\x90\xbb\x2d\xc7\xc1\x29\x6f\xfb\x44\xb2\x77\x80\x91\x0d\xee\x0a\xc9\x46\x8b\xbf\x55\xf1\xca\xd2\x77\x89\x59\xc6\x8c\xb4\x64\x4d\x41\xfd\x86\x30\xc7\xa0\xba\xeb\x54\x80\x67\xd4\x36\x16\xfb\xeb\x6f\x62\xa1\x78\xd0\xd4\xa2\x0c\xc3\x94\xf5\xfe\x75\xe3\xaa\x95\xb6\xe3\xf6\xa0\x83\x89\x0d\xda\xad\x76\xb3\x35\x6c\x0d\xdf\x6c\x48\x53\x3c\x82\x96\x3f\x74\x3f\x02\xfb\xc8\x0a\x28\x04\x69\x11\xd8\x7c\xa9\xba\x5a\x90\xf1\xb0\x36\xab\xaf\x79\x65\x73\xcc\xbc\xaf\xb4\xaa\xf2\x28\xe7\x89\xe6\xd3\xb5\xa7\x8c\x1d\xb3\x41\x4e\xe8\x35\xe6\xf0\xed\x06\x70\xf0\x95\x51\x67\xea\x31\xb4\xe6\x45\xcd\x9a\xa8\x25\x07\x93\xac\xac\x39\xe7\x74\x35\xf2\x56\x02\x14\x8f\x78\x0c\xaf\x25\x93\x8e\x33\x86\x87\x4c\x99\x79\xb5\x0c\xa2\x3c\x8c\x33\x36\xc3\x22\x29\x84\x07\xee\xb4\x98\x2f\xb8\x6f\x03\x9b\x2f\xfe\x04\x75\x60\xbe\x6e\xbc\x01\xdb\x8a\x9e\x7b\x60\xad\x73\xcb\xc4\xa2\x8c\x9c\x93\x21\x13\xd4\xde\x15\x3f\xc0\xf0\x5b\x96\x3d\x12\x12\xc6\x9b\xfc\x4e\x8b\xc2\x95\x27\x81\x43\xe5\x12\xef\x3a\x38\x8f\x41\x88\x78\x14\xa4\x61\xf8\xe5\x3d\xf8\x17\xd8\x40\xc3\x3d\xb5\xf6\x8a\x5b\xa7\x3a\x71\xbb\x9a\xa0\x20\xb7\x46\x81\x6d\x50\xdd\xd8\x9d\xd8\xaa\x79\xe9\x59\x91\x9c\x84\x1f\x66\x70\x89\x74\xc6\xcf\x1a\x79\xf3\xa6\xc9\x04\x52\x51\x33\x15\xda\xb8\x95\x56\x3a\x7b\xad\xab\x3e\xde\x55\x36\x7d\xe1\x87\x7a\x55\xf5\x02\xc9\xda\x87\x3d\x4a\xe9\x91\x3a\x7e\x99\x56\x2f\x6d\x04\x66\x6f\x45\x67\xf9\x85\xd0\x49\xce\xb9\x46\x6d\x30\xf4\xc1\x0e\xb1\x71\x2a\x0b\x11\xe5\xde\x5e\x44\x84\xb6\x3c\x55\xcd\x18\xd8\xd2\xa0\x52\xe2\xd8\x01\xc6\x4d\xdc\xa6\xbb\xc9\x0d\x55\x6d\x7c\x22\xb6\x3a\x25\xb7\xcf\x45\xd3\xdc\x07\x45\x7c\xd7\xf7\xce\xff\x20\x31\x5c\xe8\x25\x6c\xe7\xf7\x5d\xc0\x60\x5a\xd2\x16\x15\x7f\x5a\xa9\xfb\x95\xbe\x35\xc2\xba\x6a\x19\xd8\x85\x7d\xba\x06\x75\x90\x1a\xd7\x9c\xf0\x59\x1b\xaf\xab\xd9\x16\xd0\x06\x4a\x8c\x1d\xc4\xe5\xd8\xf0\x2d\xd6\xf5\x42\xe8\xac\x14\x3d\x67\x8f\xf2\x5f\x37\xa2\x1b\x4c\xf5\xe5\x13\x2a\x00\x1a\x79\x67\xd5\x53\x4b\xf4\xcd\x0c\x15\x20\x41\x74\x76\x1d\xcd\x64\x17\xfb\xa7\x61\xe2\x11\xb0\x07\x45\xf8\x1b\x81\xc4\x55\x17\x73\x43\xeb\x51\xf5\xe5\xb4\x6c\xd1\xb6\xd6\x57\xbf\xa0\x34\x2d\x12\xaf\x80\xad\x54\xdf\x36\x19\x83\xf9\x45\x0c\x1b\xa1\xe0\x17\xbf\x0c\xb7\x0a\x6a\x40\x78\x20\xea\x17\x53\xf6\x40\x5d\x52\x3c\x22\x0a\x1b\xb6\x40\xd7\xc3\x9a\x28\x6d\xd8\x05\x81\xf5\x86\xc4\x76\xed\x63\xe5\x6d\x8b\x38\x87\x9e\xab\x81\x53\x86\x2f\xf3\x58\x06\xcb\x7e\x92\xe5\xc9\x64\x00\xb5\x42\x39\x07\x4d\xc6\x4f\x4c\xed\xfd\x47\x5c\xa7\x9c\x00\x2d\x2a\xd2\xf1\x67\x58\x9b\x74\x54\xe5\xe5\x62\x5a\xde\x9e\x27\xc4\xb4\x64\x3b\x11\x46\xc1\xb8\x51\xbf\xb6\xc8\xbe\x6d\x14\xdd\x75\xe9\x16\x97\x94\x60\xec\x82\x40\x84\x81\xcf\xb0\xaa\x08\x8b\x83\x61\x5a\x30\x27\x93\x03\xd8\x5c\x29\xc4\xe9\x37\xee\x5f\x4d\xe4\xb5\x34\xa1\x9a\x13\x92\xc2\x5f\x30\xcc\xc1\xb3\x16\x31\x2b\xea\x22\x27\x8b\x5e\x9b\x0c\x21\x63\x19\x7c\xc8\x65\x42\x35\xb1\xb2\x35\x86\x14\x6b\x2e\xad\xec\xf3\x17\xb4\x31\x65\xf3\xf9\xe5\x39\xab\x64\x1d\x1d\x66\x63\x08\x00\x75\x6d\xac\x77\xd6\x86\x8c\x71\x06\xa6\xad\xac\x10\xd1\x6c\x35\xb1\x47\xcb\xaf\x95\x36\x4d\x20\xb8\xd0\x45\x1d\xcc\x1c\xab\xca\xfa\xb0\xbd\xe8\x72\xb2\x2a\xac\xdf\x51\xe3\xf6\x58\x9e\x9b\x90\x8e\x24\x17\x44\x8a\xf9\x69\x19\xf0\xd6\xb3\x29\x99\xb3\x47\x54\xa6\x52\x79\x8b\xd8\x35\xe6\x24\x5c\x12\xc2\x46\xfa\x84\x70\xf1\xe4\x4f\x55\xf7\x60\x03\x3e\x61\xeb\xa8\x80\x56\x62\x14\xf5\x2c\x13\xe4\x09\xc9\x33\xda\x45\x62\xd9\x91\xc9\xaf\xf9\x10\xcf\x4b\xf8\xbf\xe5\x87\x25\xcb\xbc\x30\xfc\x03\x93\x67\x1c\x3a\xb4\x52\xed\x9c\xe4\x09\x80\xc7\x33\x7d\x68\x0c\xe6\x32\xdc\x18\xe2\x06\x83\x0b\x11\x2f\xa8\xba\x39\x56\xd6\x10\x9e\x17\xe2\x07\xea\x29\xd0\x0c\x02\x2e\xbf\x15\xa2\x03\x95\x17\xdc\xb7\x62\x34\x11\x9e\xbc\xc2\x0f\x40\xb8\xcc\xbc\x2b\x26\xe8\xcc\x5b\x0e\x4d\x45\x29\x0f\x00\xe3\xa1\xb3\xcb\x61\xf9\x36\x03\x34\x61\x19\x4a\xd2\x4e\xd4\x6e\xa2\xcb\x31\xbe\x90\xfe\xb3\x07\xc3\x68\x7a\x3e\x02\x1d\xa1\x0e\xc6\x17\x78\xf5\x61\x34\x1e\xc3\x5d\x16\xd9\x20\x13\x9c\x8c\xe2\xd3\xf3\xf1\xf5\x2d\x6d\xee\xb9\xd7\x84\x20\x53\x9d\x75\xaf\x8e\x43\x0a\x8d\x7f\x06\x29\xc4\x07\x39\xf7\x45\x90\xc1\x8d\x6c\x19\x2f\xd8\x4e\xab\xa8\xdd\x93\xd1\xa8\xee\xd5\x45\xe2\x8e\xba\x70\xdf\x0a\xa8\x45\xb7\x04\xbd\xce\x64\x0d\x23\x07\xfc\xb9\x17\xde\x33\x73\x30\xa6\x74\x93\xdc\x5c\x97\x2c\x5a\x17\xdc\xc5\x0d\x9b\xb4\x53\x3f\x1f\x66\x31\xca\x01\x43\xf5\x37\xc1\xa3\x24\xcf\x88\xcc\xe1\xd4\xda\xcd\x87\x5e\x5a\x73\xe2\x5a\x1d\x9f\xda\xc6\xbc\xf6\xb5\x15\x1e\x6f\x96\x27\x1e\xd0\x06\x5c\x15\xe3\x4e\xa7\x41\x16\x13\xe9\x23\x9c\x4b\x61\xde\x50\x83\x1e\x88\x3f\x19\xb0\x16\x78\x73\x51\x8e\x01\xfa\x4d\x7b\x66\x72\x95\x0c\x5a\x18\xc8\x65\x32\x59\x49\x73\xb8\x02\xe5\x52\xc0\x4a\x0d\xad\x53\x38\xd3\x97\xa5\x8e\x85\x2a\xb5\xe2\x66\x5c\x6f\xa9\x95\x16\xbd\x9e\x55\x9b\xb2\x0c\x58\x26\xee\x5c\xc8\x65\x29\xf2\x1c\x91\x5c\x57\xb0\x02\xea\xd7\xfa\xf6\x70\x22\x4a\x9c\x32\x88\xd3\xef\x28\x0b\xd7\x27\xb4\x8f\x33\x5e\x65\xeb\x04\x5b\xe8\xa4\x07\x25\xb5\x0a\x6b\x2d\x4f\x0a\xdb\xf2\x25\x6e\x25\xab\x38\xd1\xe1\xf0\xe5\x07\x86\xe9\x96\xb1\x3c\x1b\x1c\xb2\xa2\x79\x5c\x5c\xa9\x5a\x65\x5d\xfe\x32\xec\xe5\xa5\xda\xc1\x17\x60\x8e\xf8\x83\x98\xcd\x89\xc9\x57\xc9\x92\x7c\x4f\x92\x3a\x14\x56\xf6\xd3\x37\x0c\x66\x87\x4f\x6b\xc1\xd6\xcb\xd1\x67\x32\x38\x38\xab\xeb\x74\x11\x32\xee\x52\xba\x0b\x80\x07\xb5\x45\xaa\x2b\x06\xf6\x9c\xbc\x4a\xc7\x10\xbf\xf9\xb2\x17\xba\xdd\xc9\xd5\x58\xde\x37\xaf\x4b\x9b\xb8\xc4\x5a\x18\x6c\x92\x21\x09\x82\x86\x79\xcb\x5e\x12\x3e\xce\x30\x03\x40\x78\xe3\x63\xb7\x6f\xd8\xf1\x3d\x34\x20\xdb\x3c\x26\x03\xc1\x0a\x24\x49\x8d\xbd\x35\x77\xcc\xb4\xa0\x87\xec\x26\xe4\x8e\xef\x06\xf6\xe0\xdf\x41\x1e\xdf\xb2\x81\xf8\x4b\x17\xcc\x5f\xa2\x9d\x98\x12\x86\xab\x1a\x8a\x6f\x24\x18\x6f\x1d\x45\xdf\x50\x7c\x5b\x82\xbb\xa2\x9d\xf8\xb3\xcb\xee\xcf\x6e\x2c\xfe\xfc\x4b\x85\x49\xe1\xcd\xd0\x0e\xed\xf6\xd6\x8e\x4a\xf6\xf0\xf0\xf9\x05\xb5\x74\x98\xf4\xad\xb8\x7a\x94\x19\xc8\xb5\x29\x4f\x65\xba\x34\x53\x5a\x8a\xfc\x95\x90\x94\x32\xfd\x52\x93\x52\xde\x7a\x0e\xca\x55\x73\x4f\x5a\x39\x27\x3d\xc4\xfc\xf4\x93\x4e\xda\x49\x59\x31\x90\x78\x12\xed\xa3\x1a\xb8\xfa\x6a\x27\x9f\x7c\x9e\x2d\xce\xea\x11\x91\x82\x52\x6f\xaf\x0f\xa7\x03\xc8\xf2\x30\x1d\x5f\x0f\x47\x63\x30\xf5\xf1\x45\x4b\xec\xe2\x89\x2d\xaf\x88\x6d\xbf\x6d\x17\x74\x1a\xb6\x04\x93\x7a\xfc\x14\x9e\x7f\x34\x19\xd1\x0b\x6b\xce\x06\xdd\x13\x6a\xe8\x23\x78\xd9\x87\x11\x74\x99\xf8\x29\xdc\xef\x5d\x4c\xe1\x15\xc3\x88\x55\x76\x0b\x88\x7a\xec\xe5\xa4\xf8\x00\x08\x94\x43\x98\x5c\x8e\xc7\x81\x89\xa2\x30\xd0\xd3\xc4\x88\x77\x44\x18\x2e\xd4\x98\x94\x1b\xb9\x83\x86\xed\xc3\x51\x7f\x96\xcd\xae\x97\xd9\xc8\x8d\xfc\xa0\x41\x50\x98\x2d\x54\x58\x3d\x41\xb9\x90\x5d\x66\x23\x3f\x9a\x30\xc1\x31\xb2\x02\xb8\x5a\x11\x1d\xdc\x3c\xa3\x7e\x84\x51\x7f\x38\x2b\x84\xfd\x93\x31\x86\xf1\x7a\x9c\xba\x67\x00\xc6\x1c\x81\x90\x08\xe5\xc1\xd5\x20\x6f\x57\x89\x40\x3c\xa0\xae\xde\x35\x65\x0b\xd7\x6c\x94\xb3\x66\x5e\x72\x83\xc8\xa8\x98\x73\x01\x7e\x71\x0d\xb9\x1e\x78\x36\x63\x8d\x40\x85\x16\xdc\x5c\x0d\xa7\xab\x02\xf0\x74\xae\x40\xb9\x55\xa2\x06\xc2\x40\x3d\xbf\x3e\xe5\x2c\x6c\x69\x37\x25\x2e\x89\xe6\x7d\xb1\x51\x31\xce\xb7\xa2\x7b\x98\x29\xeb\x9e\xeb\x38\x42\xb7\x09\x9f\xf3\x62\x71\x36\xcd\x4b\xef\xc8\x1b\xa5\xdd\x18\xf9\xd4\xc5\x33\x7c\xee\x84\x33\xe4\xe8\x5b\x0e\xd9\x72\x5c\x83\x41\x0c\x2f\xb2\x0f\x13\xff\x8d\x21\x48\x40\x59\xd0\x69\xf5\x42\xee\xc4\x3b\x97\xef\xce\x99\xd4\xf5\xdf\xc1\x8a\x52\x8e\x05\x7c\x44\x5b\x62\xdf\xa5\xcb\x13\xce\xc5\xae\x49\x5a\x20\x20\xdc\xa4\xe4\xd5\xaf\x91\xba\x58\x3a\xa3\x71\xfa\x46\x36\x79\x96\xcd\x8f\x3e\x4c\x04\xdb\x5f\xd7\xee\x41\xcd\x7b\x1b\xea\xce\x13\xfc\xc4\x45\x50\x96\x87\x07\x4b\xfb\xc1\x6b\x95\x0e\x37\x51\xeb\xff\x9d\x5f\x5e\x80\xaa\xc5\x2a\x6f\x66\xa0\x9c\x8a\xa5\x2f\x9b\xbd\xbb\x04\xea\x92\xf4\x40\x9a\x54\xa7\x93\xbe\x71\x0b\xd9\x5c\xb4\x44\x1f\x71\xa3\xb0\x1c\x74\x3e\x4b\x7d\x08\x66\x07\xad\xf5\x20\xb0\xec\x88\x56\x8f\x0b\x48\xa0\x06\x49\x9d\xb0\x8b\x50\x32\x0c\x9b\x93\x41\xe8\xc5\x14\xc0\xe0\x3a\x83\xfd\x76\x53\x63\x50\x42\x75\x0f\x67\xca\xdc\x14\x9a\xdc\xe4\x7d\x39\xe1\x74\xc0\x01\xe4\xb3\xd6\x0f\x81\xba\x23\xc7\x43\x67\xb0\x61\x03\x2c\x8f\xe1\x40\x95\xc6\x90\x72\x08\xa4\xb6\xf1\x47\x96\x95\x32\x62\x39\x1b\x0c\x2e\x36\xe5\x99\xc5\x52\x59\xc4\xcb\x95\x4b\x3c\xbf\x11\x9c\x72\x3a\x4e\xb6\x3f\x73\x65\x04\x75\x63\x8e\xc8\x67\xf6\x3c\x09\x72\x8d\x2c\xbe\x0e\xdb\x94\x5d\x57\x0f\x8c\x55\x74\x24\x84\x99\x48\x12\x80\x2c\x85\x2e\xf4\x6c\x97\x34\x9f\x9e\x17\x3c\xf5\x14\x04\xcd\x9d\x44\xd7\xd3\xcb\x99\x72\xb3\xcf\x40\x9d\xe5\xc0\x6f\x39\x76\xee\x4d\x57\x41\xf7\xa2\x73\xd9\x5a\x86\xa4\x7c\x18\x6d\x6e\xfa\x94\x91\x9f\x75\x97\xbb\xa5\x68\x54\x1a\xd3\xdd\xe9\x05\x28\x3b\x17\x5a\xef\x11\xfa\xce\x0d\xd4\x29\x03\x18\x5a\x98\x2c\x9d\xea\x1c\x2d\x91\xb7\x3c\xa4\x62\x76\xf2\xf5\xb3\xaa\xfe\xb2\x26\xdd\xb1\x11\xeb\x10\x5b\xf5\x8f\x9c\xe3\x2a\xe2\xa1\x3c\x6c\x7b\xc1\xf4\x74\x30\xc6\x2e\xa9\x07\x1f\x58\xaa\x75\x31\x77\xb1\x36\x01\x6e\x94\x2e\xb1\x30\x9c\xaf\xcd\xe2\x6f\x18\xeb\x6b\xc0\xf6\xab\xd0\x2a\x5b\x7d\x91\xdd\x8b\x3e\x4c\x27\xf7\x16\x5c\x7f\x96\xd7\x1d\x8d\xe0\x85\xe3\xe9\xf4\x22\xca\xfa\x4c\xd1\xa1\xb5\x93\x70\x97\xef\x49\x68\xf7\xc2\x1d\x46\x29\xaa\x5a\x35\xfb\xa9\x9e\x92\x0a\xc3\x1a\x93\x47\x6d\x4e\xb0\xa7\xe8\xff\xb2\xca\xbc\xa1\x16\xbe\xc1\x98\x6d\xe0\x3f\x81\xc4\x43\xb8\x10\xdb\x58\xae\x21\xf8\xa0\xc2\x4a\x06\xc5\x96\x2e\x64\x66\x2e\x12\x5b\x39\x92\xf2\x94\xf4\x27\xa3\xf7\xc8\xe4\xe9\xfe\x84\xad\x08\x70\xf3\xd6\x89\x45\x58\x1a\x59\xc8\x8b\xf3\x5e\x0e\x82\x0a\x4f\xa3\x9c\x1f\xec\x70\x88\xec\xf1\x64\x34\x1c\x0d\xb2\xc9\x42\x04\x94\x1c\xf1\xde\x23\x48\xde\x0e\xb5\x4d\xd6\x59\x99\xe5\x5e\xd9\x7c\x72\x0b\x61\x63\xfc\xe6\x5d\xb6\x08\x84\xc1\x37\x5d\xaf\x96\xd0\xda\x86\xe2\x51\xca\x39\xc1\x58\x4a\xb4\xe2\xd6\x52\xbd\x98\xf7\xb3\xad\x85\xdd\xf3\x85\x09\x58\xff\xab\x03\x78\xdd\xb8\x6a\x34\x1a\x71\x23\x69\xa4\x70\xe1\xbc\xd1\x6c\xb4\x1a\xed\x46\xe7\xcd\x27\x03\x5c\x8f\xda\x95\x43\xaf\x88\xf0\x75\x72\x44\xbc\x19\xfb\x41\x5c\x08\xc6\xe9\x2a\x7f\xf0\x7f\xd9\xc6\x0f\x62\xf6\x3a\xaa\x06\xdc\xcd\x97\xc3\xfb\xcd\x36\x61\x28\x34\x3f\x08\xd5\x70\x1a\x92\x5f\x2b\x3b\x93\xba\x00\x38\x7b\x8c\x8b\xc9\xbb\xc5\x19\x77\x3d\x0a\x4a\x91\xea\x31\x63\xf4\x44\x59\x2d\x52\xcc\xde\x64\x30\xcd\x81\xdf\x0b\xfe\xc5\x65\x77\x7c\x5c\x1e\xfb\x53\x31\x00\x2b\xbb\xb9\x5f\x5c\x85\xdb\x5c\x16\x40\xa6\xd2\x6c\x5f\x39\xb8\x8b\x66\xd6\x0a\x91\x5d\x88\xb8\x06\xcb\xc2\xba\x10\x55\x2c\x92\xbc\x5a\x0c\xbb\x41\x5e\xa7\xa2\xb9\x88\xa1\x08\xc6\x72\x91\x43\xc5\x98\x9b\x91\xbb\x34\x7c\xcb\x12\x06\x1a\xb0\x21\x9d\x88\x35\xdf\xe6\xa0\x80\xf0\x0f\x33\x92\x71\x0e\xab\x09\x7e\x2a\xa4\xa1\xc1\x99\x98\xe6\x77\xd5\x73\xdd\xaa\xb8\x54\xe1\x0d\x85\x5d\x2c\xe3\x67\xa8\x27\x49\x70\x98\x78\x49\x57\x28\xf1\xc0\xce\xab\x85\x23\x01\xc2\xfe\xe8\x09\x2c\xe2\xae\x22\x58\x85\x7f\x34\x84\x19\x67\xb1\x70\xe9\xd1\xfc\x44\xcb\x18\x4b\xe6\x04\x80\x9f\xd8\x22\x89\xc3\xd7\x75\xbc\x4e\xed\x8e\x19\x71\xa3\xe2\x0a\x23\x49\x42\x28\x64\xbb\x04\x3f\x57\x85\x32\xfe\x69\x03\x3f\x4f\x1d\x50\x41\x52\x2c\xc4\xff\x1a\x08\x94\x8e\xf5\x41\x25\x85\xb0\xae\xd7\xe0\xa7\xb7\x60\x1c\xbb\x18\x9d\x13\x1a\x37\x15\x92\xdd\xec\x6e\x38\x29\x84\x49\x1c\x28\x0a\x36\x7f\x35\x0a\xaf\x47\x32\x2a\x36\xfc\x53\x4b\x9a\xd1\x83\xa8\x36\xe2\x18\x7f\x1b\x75\x99\xfe\x2e\xa3\x65\x07\xb5\x78\x0e\xc7\xee\xf1\xfd\x68\xa4\xc2\x6d\xff\xb6\xad\x9b\xfe\xee\x3b\xd9\x06\x51\x5e\x35\x5a\x41\xf1\x0f\x2e\x4b\x26\x4d\xf9\xe4\xba\x11\x4d\xc3\x51\xee\xab\x76\x7f\x3b\xf2\x89\x5d\xa1\x83\x04\x17\xaa\xc5\xf6\xfe\xb6\x8d\x26\xc1\x96\x90\x6a\x0e\x0e\x9b\x49\x51\xcb\x6d\x96\xd5\xc4\x29\x8f\xc1\x01\x4a\xa0\xa5\x18\x8e\x16\x37\x55\x05\xf5\xe4\x54\xb3\x2b\x20\xa4\xe0\x75\xd5\xd0\xbd\x84\x68\xf2\xf5\x28\x2a\x2e\x52\x50\xc8\xd0\x02\x83\x50\xd5\x5e\x2b\x18\x32\xce\xfc\x36\x4e\x1d\xfe\x41\xd6\x4f\x13\x60\xfd\xb9\x25\x17\x08\x86\xb7\x54\xa9\xed\x08\xce\x7d\x37\x07\xc5\x68\xac\x6b\x3e\x8c\x56\x88\x48\x44\xef\x7f\x2b\xad\x3c\x01\x77\x24\x6f\xfb\xbb\xae\xdf\xa7\xd8\xee\x9a\xbc\xe0\xed\x77\x6d\x5e\x58\xb6\xcf\x35\xc1\xe2\x4e\x8d\xe3\x6a\x46\x3f\xf7\x77\xce\x9b\x3e\x8f\x08\xf7\xfb\x46\xd0\xa5\x0e\xea\xad\x3e\x04\x86\xda\x30\x1c\x8d\xc7\x32\xec\xac\xb8\x26\x81\xfb\xad\xe5\x4a\x89\xdc\xcc\x4d\xa8\x4d\xaf\x09\x2a\x78\xc5\xa7\xd2\x28\x0b\x48\x95\x18\xe5\xb6\x9c\xcf\x2a\x6c\xc1\xc4\x55\x90\x38\xbc\xd3\xe2\x25\x4c\x36\x99\xd0\x5b\x2c\x53\x3c\xf8\x1b\x2a\x78\x3b\xb3\x4f\xc8\xcf\xdf\x7e\x08\x47\x99\x7f\xfb\x01\x22\xa5\xbf\xfd\x40\x48\x77\xfe\xfe\x9f\xb0\xcc\x5c\xa5\x59\x5e\xb4\xbb\x7d\x37\xfc\xba\x2a\x96\xcd\x7f\x8d\x4c\xc9\x51\x72\x4e\x50\xe1\xec\x8e\xb7\x5a\x6b\x5c\xf5\x1a\xed\x1e\x23\x74\x0d\x1e\xc0\x9a\xfe\x94\x7d\x36\xd8\x4a\x85\x0f\x98\xc0\x6a\x5c\xc5\x0d\x5c\xee\x01\x81\xc0\x72\xcf\xbb\xc8\x80\x35\xdb\xad\x06\x07\xf6\xc1\x05\xf6\xa1\x2a\x30\x24\x6f\x31\xbf\xc4\x9b\x3e\x35\x44\x83\xc9\x48\xac\xc9\x20\x21\xa5\x4b\xeb\x8b\xba\x0f\xb7\xb1\x8e\xf8\x94\x97\x65\x0b\x67\x63\xb3\x15\x2c\x83\x34\x15\x45\xbf\xe5\xfe\x36\x92\xdb\xd8\x72\x1e\x6d\xb6\xa2\xff\x8a\xe2\x68\x2b\x7a\x10\x57\x51\x51\x3c\xc9\x61\xab\x1b\xf5\x68\x36\xc8\x06\x67\x85\xc8\xae\xb3\x5c\xe1\x80\x9a\x6f\x81\x1f\x67\xb5\x1a\xaf\x0a\x5b\x25\x0b\x49\x58\x4d\x0c\x62\xd0\x47\x4c\xbc\xea\x36\x83\x55\xe3\xe5\x91\x21\x3b\xfd\x0e\xd1\x27\x2e\x0a\x31\x87\x4f\x4d\x95\xd7\xf0\xa3\xdf\xa2\x46\xc5\xb0\xe6\x70\x54\xa8\xe7\x30\x9e\x3a\x0a\x03\xc8\x44\xa6\xef\xd1\xb2\x94\x7f\xa5\x2f\x9f\x8a\xad\xfd\x32\x47\x1a\xb6\x9b\x44\x47\x1a\xfe\x2f\xed\x48\xc3\xb6\x99\xbe\x25\x80\x02\x07\x05\xb7\x39\xd0\x4d\xfe\xbd\xfc\x7a\x8f\x8c\xbf\xe9\x9a\x2f\xce\x58\xdd\xaa\x26\x8c\x0a\x3b\xcf\x15\xa3\x6a\x56\x6a\xfd\xb6\x04\x39\x6b\xcc\x0f\xa1\x29\xe8\x67\x6c\xed\xf5\x7e\x33\x24\xcc\xc9\xc0\x99\xeb\x6e\x53\x4d\x33\x5a\xf9\xce\x73\xbe\xea\x36\x92\x8f\xba\x0f\x88\x11\x82\x69\xe3\xb3\xca\x79\xb6\xe6\x4b\x37\x74\x98\x23\x6d\xc4\x37\x72\x1f\x6e\xb8\x89\x93\xd8\x89\x39\x8e\x60\x01\x48\x73\x43\x9b\x7d\xd8\x4a\x4b\xc9\x3b\xaa\x2a\xb4\xd3\x18\x96\xd7\x0d\x08\x08\x05\xee\x97\xe9\x68\x52\xbb\x77\x6f\x15\x83\x8f\x9c\x6f\x9a\xc3\xf9\x7c\x5b\xc5\xd2\x23\x67\x0a\x67\xdc\xea\x13\x26\xa0\x3c\xfd\xfe\x5a\x93\x9c\x6c\xdc\x67\x5b\xcd\xc7\xea\x91\x32\x79\x95\xd5\x12\xa5\xf0\x3a\x87\x72\xeb\xc2\x7f\x8a\xad\xcc\x2a\xb3\xc6\xd4\xca\x96\x4e\x19\xb3\x51\xb1\x84\x26\x25\xf9\x31\xf9\x6c\x49\xd1\x32\x81\x9d\x3e\x98\x2c\x44\x67\x37\x21\x5b\xe8\x62\x56\x1b\xd5\x19\x84\x3a\x26\xe1\xd3\x26\x0b\x98\x51\xdd\x0d\xea\x02\xee\xca\x6b\x9e\x45\xa6\x87\x51\x42\xad\x7e\xa4\x17\x29\xe3\x97\x49\x5c\x6d\xd1\x12\x65\xe5\xba\xa5\x7e\xae\xb7\x74\x89\xea\xbf\xdf\xea\x55\x15\x81\xdb\x9a\x53\x63\x6c\x8f\x5e\xc3\x38\x2e\xff\x51\xcb\x18\x27\xc7\x17\xbc\x92\x19\x08\xf2\x15\x89\x0f\xdd\x26\x38\x1e\xc3\xd3\x5d\xa6\x35\xe2\x22\x55\x75\x41\x32\x01\xde\x60\x4d\xb2\xf8\x76\xfd\x65\x49\xb1\xe3\x4a\x93\xe1\x8b\x5e\x9c\xc4\x2c\xe2\xeb\x93\x39\xdd\xaa\x2f\x51\xaa\xd6\x6a\xab\x94\xaa\xa6\x16\x2a\xf5\xe4\x53\xaf\x55\x4e\xd3\xcb\x72\x39\x8f\x0c\x2b\xba\xee\xed\xe6\x40\x30\xf4\xce\xa2\x36\x92\x73\xc2\x5d\xaa\x68\xdb\xe2\xca\x4b\x95\x83\x70\xd5\x95\xea\xd5\xe9\xd3\x07\xdd\x6a\x0b\x15\x9c\x24\xaa\x65\x4a\xfc\x58\x6f\x91\xe2\x8d\xfe\x7e\x6b\x54\xc5\xf6\x6f\x6b\x66\x5d\x32\x5a\xd1\x0b\x14\x50\xf1\x53\x2e\x4f\x8b\xd9\x75\x89\x83\x51\x5e\xc0\x16\xfd\xd5\xf1\xc1\xae\xbc\xe9\x54\x2b\xe6\x83\xec\xa2\xa8\x95\x2c\x9c\xbe\x58\x8e\x06\xd9\x62\x70\x16\xd5\xfc\xf4\xd1\x88\x02\xa4\x5b\x41\xbe\xc5\x8c\x2b\xb5\x7b\xcf\xb3\x31\x5c\x44\x2c\x72\x31\x0c\x90\xa7\xcd\x4f\x41\xb7\xba\x00\x37\x07\x75\x7d\xf9\x2d\xc6\x6a\x15\x36\xf9\xa2\x85\x37\x72\x18\x17\xdd\x9a\x0d\xab\x0b\x6e\x51\x27\xe4\x0c\x60\x2d\x0d\x97\x13\xc1\x3d\xdc\x43\xc0\xe2\x2b\x09\x67\xa3\xaa\x5c\xd2\x29\x78\x31\x17\x26\xe3\x16\x9e\xcd\xcc\xb8\x85\x1e\xcc\xc4\x6b\xf8\xc9\x5e\x1b\x29\xd1\xce\x2f\x21\x41\x71\x11\x8d\x80\xa2\xe0\xdb\xcd\xf3\xac\x65\x78\xbc\xce\x3a\xa4\x6e\x2c\x54\xca\xed\xeb\xe4\xe9\xb4\x8d\xfb\x3c\xc7\x21\xbf\x5a\xa5\x13\xc4\xff\x5a\x5c\x40\xd4\xc1\x0b\x99\x34\xd0\xce\x0e\x6a\xf8\xb4\x34\x08\xe9\xfb\x58\xd0\x01\x33\x0d\xee\x28\x2a\xa8\x5b\x62\xa1\xd7\x15\xdd\xe0\x30\xbb\xab\xf0\xe6\x71\x28\x7d\x4f\x24\xd1\x16\x49\x4c\xe1\x32\xc2\x62\x2e\x6f\xc5\x44\xc0\xc1\x37\x3d\x63\xea\x93\xc8\xf3\x84\xb8\xe1\x43\xa6\x4a\x59\x77\x85\x7b\x5f\x84\x5e\xca\x94\xcf\x00\x66\xe0\x14\x37\x15\x9d\x95\xd5\x15\x4a\xbc\xfc\xe4\x09\xab\x20\x95\xf5\x46\x55\x4d\x73\x27\x67\xba\x07\xb4\x21\xf3\x82\x07\x28\x2d\x78\xa1\xfa\xa2\xa8\x82\x2c\x78\x99\xc7\x85\xb3\x4b\xc4\xe7\xbc\xb8\x04\xc0\xe5\x25\xbf\x53\x92\x19\x1e\x94\xdc\x9f\x18\x25\xde\xa7\x19\xcc\xb7\x1c\x9d\xda\x3d\x5f\xde\x57\xaa\xb7\x7c\x6d\x14\x25\xab\x24\x33\xf7\xaf\xd7\x2b\x4a\xd4\xcd\x80\xa2\x06\x81\x44\x30\x51\x1b\x94\x79\xc7\x19\xd9\x68\x49\x9c\x48\xc1\x6b\x86\x50\x46\x0c\x97\xe8\x7e\x3c\xbe\x00\xeb\x5a\x88\x7d\xa5\x2b\x11\x05\xcc\x63\xda\xfb\xdb\x76\x92\xf4\xaa\x1c\x2c\x73\x9d\x32\x46\x7a\x9f\x8d\xc6\x18\xb1\x8b\xcb\x05\x14\x76\x61\xae\x39\x35\x2e\xab\x8c\x26\xef\xa7\xbf\x32\x10\x4e\x92\xe1\x9a\x48\x0e\x5c\x8f\x3e\x9c\x8d\x98\x2e\x41\x89\xea\xfe\x75\x89\xa8\xf6\x5b\x95\x13\xa5\x3f\x9d\x8e\x8b\x6c\xc2\xe6\xc7\xf4\x29\x64\x8d\x8d\x7e\x3c\x2b\x16\x3c\x9e\x89\xcc\x45\x8b\xd7\xb5\x58\x71\x14\x14\xe2\x91\x96\xda\x4a\x5c\xaf\x11\x0e\x44\x49\x7a\xa4\xf8\xcd\x97\x79\x05\xf0\x09\x67\xa4\xd0\x9c\x91\xa9\x72\x43\x71\x39\x56\x04\x43\x67\x0a\xb6\x63\xad\xf2\xa2\xd6\xe4\xe3\x04\x5f\xd1\x66\x22\xa6\x88\x66\x6e\xc2\x5a\x02\x8f\xa5\x73\x1a\x52\x36\x64\x56\x81\x97\x86\xa3\x59\x88\xef\x65\x71\xad\x2b\x6c\x3b\x29\x99\xcb\x0a\x4b\xe8\xba\x36\x1d\xd1\x6f\x77\x7a\x39\x59\x48\xfe\x22\x84\x09\x00\x9d\xf0\x44\xc2\xc7\x18\xb7\x78\xdb\xc6\xff\xa1\xd3\xe4\x23\x5f\x16\x85\x48\x2e\x30\x38\x66\x18\xe4\xd1\xe5\x05\xbf\x50\x38\x18\x5f\xe6\x85\xc3\xf7\x7e\x35\x07\x23\xed\xe4\x62\xbe\xa8\x1e\xdb\x56\x61\x91\x4f\x3f\x4c\x4c\x3c\xa6\x93\xf1\x35\x63\x1e\x35\x29\x89\x48\xfa\xac\xee\x18\xbc\xff\x16\x41\x5d\x0b\xe5\xc6\xac\x38\xcf\x46\x13\x5b\xb9\xaa\xd6\xaf\xf3\xec\xaa\x66\xf5\x0b\x0f\x4e\xd9\x46\x9d\xc8\xcc\x5e\x5f\x7e\xa4\xaa\xc6\x9c\x5b\x1e\x42\x43\x8e\x9c\x2c\xf1\xb0\x5a\xff\x36\x32\x18\x30\xc4\x4f\xc8\x1b\x41\x76\x82\xb7\x76\x1f\x47\x93\x9a\xd9\x24\xe3\xd5\xba\xc5\x67\x94\xfb\xa4\xcc\xe0\x4d\x31\x09\xf0\x9d\x01\xb0\xfc\xda\x16\x97\xf3\xdc\xcc\x22\xbe\x7f\x67\x52\x40\x3d\xbd\x6f\xcc\xa7\xa0\xd3\x04\x60\x56\xcc\x60\x37\xa9\x16\x86\x07\x7a\x83\x80\x97\x21\xe9\x8a\x42\xba\xd8\x6b\x90\x92\x2a\x54\xae\xfa\xd0\x18\x0b\x34\x44\x0a\x2c\x2d\xf0\x71\xc8\xfd\xa2\x96\xf4\x35\x45\xd8\x8f\x53\x3b\x19\x96\x49\x51\x02\x0d\x4b\x92\x41\x42\x2c\x63\xa4\x97\x73\xa5\x8c\x2d\x5a\x86\x6d\xd8\xe0\x64\xe3\x5f\x37\x9b\xbe\xa9\xb3\x5b\xe5\x2c\x94\xa5\x97\xbc\x6e\xe8\xe5\x16\x58\x0d\x7f\x27\x97\xb7\xb7\xde\x82\xa8\x31\x21\x47\xac\xd4\xe3\xcd\x96\x61\x6a\xa7\x19\xc8\x44\x2f\xf7\xe0\x67\xd9\x1c\x34\xa6\xf0\x8e\x7b\x69\x2a\x72\x2d\xae\xcd\x3b\x50\x7c\xd0\x05\x74\x1e\x76\x8d\xf1\xc4\x74\x62\x6c\x85\xe3\x76\x54\x6b\xc5\x09\x7a\xb2\x6e\x10\xdb\xe2\x7d\x5e\x59\x6e\x83\xd5\x4f\x7a\x3f\x7c\x2b\x51\x5f\xcb\x32\x90\x95\x06\x4c\x2d\xbb\x6a\xc6\x89\xb0\x42\x4e\xf2\x75\xa3\xdb\x41\x43\x11\x0f\x91\xbc\x2c\xc8\x5d\x65\x1f\x12\x35\x06\x56\xe8\xb6\x93\xfd\x9d\xa4\xd5\xa6\x2f\x89\x95\xa5\xba\x5e\x3b\xc2\x9a\x8c\xad\x56\x3d\xcc\xda\x49\xa1\xc2\x7b\x84\x2d\x04\xbe\x19\x62\x89\x27\xb6\xb6\xa4\xc8\x89\x73\xfb\x26\x13\xc1\x2f\xb7\x61\x22\x41\x84\x4d\x13\x8f\x5d\x22\x30\x93\x10\xb4\x25\xbc\x7c\xad\xc1\x5d\x9b\x05\x39\x88\x85\x59\x2e\xc8\xc7\xab\xd1\xfa\xd5\x45\x8e\x22\xc3\x24\x36\x3a\xea\xae\x90\xb1\xcf\xb7\xa4\x88\xaa\x1c\xb8\x1b\x47\xb8\xf2\x15\x22\x2e\x8a\xc4\xfd\xa1\xa5\xd3\x7b\x85\x31\xbf\x44\xbc\x6a\xf7\x04\x52\x94\x2d\xc5\x2e\xb9\xdc\x74\xc2\x0b\x9a\x8c\x62\xd1\x20\x30\x1f\x03\x16\x09\x61\xb0\xb1\x21\x10\xec\x21\xe8\x0b\x4a\x3a\xe0\x4b\x81\x32\xf6\xb1\xbe\xfa\x7b\x6b\x77\x27\x9e\x8a\x24\x37\x73\x85\x4b\x50\x40\x46\x2f\xa6\x88\x72\xb6\xe0\xf9\xe2\x45\x4d\x1d\x33\x14\x82\xdf\x14\x6c\xc2\x30\x32\xbe\x2f\xc6\x60\xb2\xc8\x0b\xc6\x57\x97\xf8\xbb\x8e\x7a\xfa\x83\x29\x04\x28\xa9\x12\x65\xb4\x22\x87\x5a\x89\x1e\x10\x25\x1d\x90\x1b\x4b\xac\x6e\xb9\x00\x22\xdc\xd2\xca\x00\xee\x45\x9c\x1d\x55\x32\xa1\x80\x59\x22\x50\xba\x8c\xd1\x4b\xaa\x2d\xe7\x7a\x59\x74\x25\xbe\x7f\x4a\xd0\xd7\xdf\x10\x95\x83\x81\x4f\xe9\x5c\x59\xa6\xc0\xaf\x20\x59\x0d\x0a\x49\x21\xfb\xd4\x18\x07\x72\xf2\x40\xf9\xd0\xe4\x31\x92\xdf\x31\xbd\x0c\xd2\x55\x54\x52\xf3\xe7\xc2\xe2\x63\xc5\xbf\xcf\xc4\xa0\xdd\x53\x81\x93\x20\x2a\xe6\x6c\x68\x78\x0b\x23\x1c\x72\x56\x48\xf9\x2a\xba\x2e\xb9\x96\x2f\xb7\x22\x3e\x22\xb8\x1a\x8e\x21\x00\x14\xa3\x56\x85\x49\xf0\x54\xe0\x26\xee\x06\xbb\x28\x7f\x86\x73\x3c\xae\x33\xb9\x97\x2a\x78\x57\x37\xdf\xf2\x5e\xed\x63\x97\x6a\x94\xb2\x15\xb6\xcf\x99\x55\x2d\xe7\x41\x04\x1d\x3a\xdf\xf3\xe6\x05\x1d\x00\xd7\xa8\x01\xfb\x45\x31\x13\x71\xb3\x68\x67\x11\xd3\x19\x2e\x6d\x0e\x5f\x7d\xdb\x48\xf2\x13\x88\xe4\xfd\xe7\x3b\xbb\xb7\xcf\x4f\xa0\x42\xcb\xa0\x14\xd0\x82\xe0\xab\x3f\x04\x4f\xed\x9f\x67\x83\x4a\x7c\xc5\xca\xdd\x84\xb7\x54\xf5\x1b\xf1\x17\xe4\xd0\x5d\xc2\x5f\xbb\x6f\x11\x2d\x18\x07\xce\x64\xbc\x11\x5e\x77\x35\x66\x2b\xdd\xfe\x2a\x4b\xd2\x92\x3b\x0c\x80\x0d\x5e\x62\x10\x5f\xf4\x2d\x06\x99\xa9\xe5\x11\x84\xf4\x05\xf3\x1b\x0f\xeb\xfb\x95\x7c\x2e\xc8\xfc\x48\x47\xfa\x85\xf0\xbe\xcd\x2f\x35\xbc\xaf\x40\xa6\x26\xc3\x11\x6f\xdc\x7a\xbc\x5f\x09\x79\xd5\xb8\xbf\x0a\x43\x33\xfe\x6f\x28\xe8\xaf\x7a\x8f\xc1\x7f\xa9\x00\xba\xfe\x11\x85\x8c\x1a\xab\x87\xcc\x60\x00\x23\x1a\xac\xf1\xfc\x47\xd3\x27\xc3\x0e\x4f\x63\xd5\x36\xae\xc0\x84\xc2\xc8\xb6\x9b\xd5\x5c\xb4\x44\x59\xe9\xa4\xa5\x7e\xae\xe7\xa6\x25\xaa\xff\x7e\x7e\x5a\x55\x11\xb8\x2d\x49\xd9\xc7\xf6\x68\x57\x2d\x8e\xcb\x9f\xc0\x97\xd8\x2b\x7f\x9e\x5d\x28\xe5\x90\x7d\x5f\x3d\xf6\x02\x71\x45\xdc\x07\x11\xf2\xca\xe4\x34\x5f\xd7\x61\x19\xce\x09\xd2\xb0\xcf\x32\x2b\x15\x13\x4e\xcb\xfc\x13\x72\x5d\xe6\x9f\xa0\x03\xb3\x04\x9c\x68\xc0\xac\xfe\xfd\x28\xde\x20\x7c\xa2\xe5\x9b\x2a\x9e\xd1\x12\x70\xea\x00\x4e\x82\x80\x13\x12\x30\x0d\x99\xf1\x22\x9b\x02\x78\x87\x93\x93\xe5\xbb\xef\xf0\xde\xc4\x6f\xfc\x77\x02\xbf\xbb\xf0\x13\x51\xa0\xa0\xa8\xa1\xf8\x85\x0f\x45\xed\x17\x36\x16\xac\x75\x26\xd7\x11\x9b\x5f\xe0\xd6\xe1\x66\xa7\x65\x8c\xd0\xc6\xa3\xe8\x97\x80\xdf\x2c\x7c\x8c\xb1\xe7\xbe\xe0\x8c\xfd\xd0\x67\x76\x67\x51\xab\x49\x84\xb1\xd3\x6d\xb8\xe8\x99\xb2\x8e\xff\xb2\x21\x7a\x9a\x0e\xc9\xdb\x4e\x5e\x7c\x06\xdf\x70\x91\xe7\x32\xdd\xb7\xcf\x8d\xe2\x05\xa0\x04\x97\x84\x35\x3a\x6d\xef\x32\x09\xc6\xd6\xd3\xc5\x69\xe7\xe0\xb3\xd1\x98\xed\xcf\xcd\x7e\x8a\x70\x01\xa1\x58\x23\x24\x59\xcc\x66\x56\xef\xb3\x90\xac\x26\xd4\x1b\xf8\xc9\x5b\x32\x79\x7d\x3f\x4b\x25\x6a\x57\x12\xf4\x5f\xb4\xab\xa5\x58\x21\xb8\x5f\x8f\xb9\x94\x54\x77\xb7\x54\xb5\x56\x5c\x1c\x54\x3d\xe5\x28\xaf\x9e\x28\x47\xf9\xe5\x72\xdf\x2b\x31\x2b\xd8\xa8\xcf\x8b\xe7\x46\x41\xfd\x88\x8a\x6b\xf6\x8d\x7e\x1d\xe4\xee\x52\xa0\xd4\x12\x20\x3f\xae\xfc\x01\xf1\x03\x33\x54\x74\xb0\x54\xd2\xd8\x0d\xbf\x96\x13\x5b\xf8\xe6\xff\xb2\x01\xf1\x5f\x7e\x09\xb4\xbb\x5c\xa4\x1c\xbc\x9b\x40\xc2\x88\xcf\x25\x55\x0c\x90\x07\x93\x1c\xef\x39\xeb\xe1\x1e\xc1\x93\xa3\xe1\x32\x99\x61\xb4\xc3\x61\x7c\xc3\x94\xf5\x07\xf1\x12\x99\x64\x72\x98\x59\x7b\x2d\x41\x4c\xaa\x04\x32\x41\x0a\xcc\x8c\xc3\xe9\xf4\x42\x4f\x89\xba\x8b\x43\xdd\x18\x51\x4b\xe5\x70\x4e\x3c\xb3\x8b\xad\xe8\xde\xce\xe3\xdd\x27\x7b\x4f\xbf\xdf\x3f\xf8\xef\x67\x87\xcf\x5f\x1c\xbd\xfc\xdf\xc7\x27\xa7\xaf\x7e\xf8\xf1\xa7\x7f\xfc\x9f\xac\x3f\x60\xbb\x8f\x77\x67\xa3\x5f\x7e\x1d\x9f\x4f\xa6\x17\xff\x33\x9b\x2f\x2e\xdf\x7f\xb8\xba\xfe\x67\x23\x4e\xd2\x66\xab\xdd\xe9\xf6\xee\x3f\xdc\x16\x11\x6e\xd5\xd6\x4e\x4d\xda\x95\x51\xd5\x24\x0e\xdc\x4a\xd1\x9e\x1b\x96\x87\x29\xa5\x0a\x39\xed\x50\xd7\x54\x60\xa4\x47\x81\xf5\x46\x5c\xec\x9a\x83\x92\x64\x4c\x0f\xcd\x4d\xe6\x05\x16\xb6\xfa\xc6\x6c\xa2\xc0\xed\x15\xad\x30\x25\x3e\x73\x49\xa0\x49\x15\xa0\x1b\x6f\xe4\x02\x6f\xaa\x61\x04\x54\xae\x10\x4d\xac\xc8\x3d\x70\xd9\x86\xa3\xff\x1b\x6f\xd1\xbc\x5b\x33\x29\x0f\xde\x13\x09\x12\xdf\xbf\x6f\xbd\x50\x6c\xab\xbe\x08\x8e\xd4\x4e\x10\xe2\x40\x93\x0c\x8b\xf0\x51\xe7\xee\x71\x37\xf9\xc6\x1a\xf1\x28\x98\xd9\xa7\x75\xb7\xf5\xbf\xdb\xfa\xab\xad\x3f\xb8\x63\xc7\xed\xe8\xf1\x5e\xe5\x0b\x5a\x71\x9b\x15\x36\xee\x68\xc5\x6d\xfb\x17\xbe\x5d\xcf\x1a\xa0\x91\xf9\x7d\x2f\x6e\x55\xc4\xe1\x16\x2f\x6f\xc5\xed\xe0\xed\xad\xb8\xfd\x27\xb0\x08\x54\xdf\xac\x23\x31\x6e\xb2\x57\xa7\xef\xfb\xa3\x67\x14\x5b\x10\x5e\xb2\x6d\xc9\x22\x74\xc9\x38\x6e\x07\x2e\x19\x93\x9b\x69\x8d\x69\xf8\x96\xb1\x6a\xb2\xea\x55\x63\x03\xe8\x0d\x76\x50\x2e\x13\xdf\xe8\xb2\x1a\xf2\xe6\xaa\x73\xe3\x8b\xde\x46\xf1\x59\xa5\xae\xac\xc9\xc9\xb7\xd2\x9d\x35\xac\xb4\xda\x5d\x63\x59\x4b\xe9\xb7\xf2\xc1\xa7\xbe\x69\x6c\x37\x5c\xed\xa2\x71\x8c\xba\x8f\xc2\xd0\xbe\x66\x0c\x2a\x90\x9e\x2c\x09\x4c\x16\x27\x08\x5b\x14\xe4\xf0\x90\x96\xe3\x50\xe7\xdb\xea\xa1\x30\x04\x5b\x1e\x56\x58\xc3\x8c\x75\xea\xf0\xc6\xeb\xd4\xe1\x17\xb0\x4e\x55\xc1\xe1\xb6\xd7\x29\x72\x3a\x1d\xee\xdd\x2d\x53\xea\x73\x6b\xcb\xd4\xfc\x43\x76\xb1\x37\xc9\x47\xd9\xa4\xb6\xea\x8a\x45\x6d\xc9\xbf\xfc\x25\xeb\xf0\xd3\x2c\x59\x55\xa6\xc9\x97\xbf\x64\xb1\x39\x66\x2f\x5a\x77\x2b\x96\xb7\x62\x19\x33\x66\xa5\xc5\xeb\xb3\xae\x5e\x6a\x5c\x0c\x6c\x01\x90\xd5\x47\x19\x0d\x1f\xdf\x88\xb3\x13\x3e\xb9\x1b\x0d\xf8\x83\x07\x2b\xfc\x25\x74\x5f\xbc\xe5\xef\xf4\xf4\x5f\x66\x2e\x40\xc6\x0a\x5b\x0b\xda\x37\xb2\x16\x88\x1c\xb5\x9f\xd3\x68\x00\x76\x30\xf5\x68\x7e\x96\xc5\xce\xa3\xb3\xf3\x6c\xf0\x09\x4d\x0b\xf5\x48\x36\x8b\xdf\xb0\xb5\x3f\x83\xb9\xc1\xcb\x17\xbb\x86\x29\xc2\x72\x62\x31\xde\x3c\x7f\xd2\xc2\x9a\xe8\x72\xc3\x7e\x50\x3a\x1e\xba\x38\x43\xf2\x70\xcc\x82\xcd\xfd\x60\x55\x5f\xf1\xf2\x2f\x04\x23\x17\x49\xbc\xc1\x6f\x11\x7d\xb4\xf7\x7e\x78\xf9\x16\x17\xdd\xd3\xe9\xb3\x42\x2b\x83\xd1\x87\x0f\x1f\x36\xa7\x17\xc5\x64\x3e\x1f\x6f\x4e\x67\xef\x1e\xe6\xd3\xc1\xfc\x21\x26\xe1\x9e\x3e\x74\xea\x9c\x2d\xce\xc7\x84\x21\x64\xef\xfd\xc5\xb3\x27\x4f\x35\xda\xea\x77\xc5\x60\x08\xcb\xef\x80\x38\x6b\x9c\x77\xfb\x45\xa4\x3c\xc7\x35\x0a\x08\x33\x87\x1f\xa3\x89\xbc\xf6\x62\x84\x7b\xd6\x57\x5d\x9a\x4c\xf2\x25\x5d\xeb\xa6\x8b\x07\x3f\xe0\xa4\x66\x86\xc5\x70\x13\xa4\xb0\x31\x5b\x86\xed\x68\x21\x7c\x91\xdd\x20\xd5\xc6\x0b\x06\xf7\x82\x7b\x9d\x9a\xb7\x73\x02\x2b\x9c\x77\x2f\xc6\x59\x1d\x04\x79\x18\x80\xa4\x8b\x2e\xa4\xd6\x5b\xde\x39\xc4\xdc\x79\xa3\xf1\xb1\xda\xfe\xb8\xf6\xb5\x9b\x00\xd3\x7e\xea\x7b\x38\x85\xd1\xe8\x2d\xb8\x59\xff\x9a\x0f\xbd\xdb\x37\x9c\xe7\x97\xa4\x68\x5a\x5e\xf1\x5f\x7a\xac\xba\x4e\x3e\xbf\x75\xc1\xd4\xed\x61\x6c\x34\x1a\x2e\xe0\x15\x6f\x07\x2d\xbd\xf7\x53\x4d\xdf\x7d\x02\x43\x82\x3c\x02\x5c\x81\x89\xb0\x33\xcc\xc0\x0a\x93\x76\x1d\x2f\x7d\x59\x97\xc7\x02\xa0\x00\x95\x54\x9e\x67\xe3\x45\xb4\x83\xff\xac\xae\x16\x23\x77\x71\xf6\xbe\x0d\xf6\x2a\x60\xf1\x60\x83\xbc\xc9\xaf\x45\x14\x35\xd9\x99\x3a\xe2\x57\x92\xb7\x06\x8b\x5b\xf9\x1d\xed\x9a\x2b\x29\xbc\xf6\x10\x13\xea\x2d\xcc\x74\x21\x3d\xfc\xda\xea\x96\x1a\xb0\x87\x70\x51\xd6\xb3\x83\x84\x15\xba\x16\x86\xa1\x05\x38\x89\x9f\x21\x6d\x3c\xc5\x93\xbc\x6c\x56\x12\xf4\x40\x01\x53\x77\x82\xd5\x93\xe0\x76\x59\xae\x31\xbc\x47\xe2\x17\x51\xce\x10\xe2\xbc\xa8\x7e\x40\x60\xf9\xbd\x58\x98\x00\xbc\xfd\x52\x38\xb3\x38\xb8\x4a\x87\x96\xef\x24\x3e\xe4\x56\x00\xd4\x38\xbc\x39\x10\x3c\x60\xb6\xaf\x2c\xf1\xb2\xcb\x8f\x95\x65\x1f\x79\x20\x9d\x6d\x07\x8a\x64\x36\xc3\x29\xd8\xe3\x57\xbb\x79\x75\x9f\xce\x2f\x01\x3c\xa1\x08\xe7\xbf\xb6\x36\x4a\x31\xdf\x28\x19\x84\x2e\x4d\xfb\xe3\x60\xaf\x90\x0d\x52\x81\xc2\x98\x20\x95\xff\xcc\x60\x2b\x11\x33\x86\x6c\xc3\xa1\xb2\x51\xcb\xb0\xd1\x48\xfe\x23\xa2\x5d\xa8\x00\x73\xb2\x5e\x55\x9f\xeb\x4a\x32\x58\x17\x5f\x53\xf6\xae\x28\x77\x6f\xf1\xf6\x2d\x71\xaf\xcc\xe8\x4d\x75\x69\x6e\x54\xb2\xac\x1b\xc6\x73\x5b\xba\xeb\x17\xf0\xd1\x52\x5d\x2d\xda\x7c\xbd\x0e\x49\xf6\xd5\xaa\xd5\xa3\x80\xda\x70\x13\x30\xa5\x6a\x83\x32\x21\x6b\x5d\x3f\xb4\xe6\xf8\x57\x58\x04\xd9\x6d\x8d\x05\x2f\xae\x94\xaf\x77\x70\x93\x20\xb8\x7d\xe7\xd0\xe8\xbd\x7b\x5d\x6e\x3e\xb7\x5a\xdd\xba\xdc\x92\x6e\x75\x3a\xb0\xa7\xef\xfc\xa1\xf7\xf4\x83\xd1\x05\x13\x4a\x0f\x3e\xb1\x8b\x00\xee\xde\xcd\xa6\x7e\x9f\x4d\xbc\x9f\xb9\xf3\x56\x76\xf3\xbb\xd8\xb1\x97\x20\x71\xe6\x6a\xd3\x6e\x3e\x0c\xbb\x10\xa8\xe7\x4e\x26\x0c\xab\x06\xec\xe1\xb2\x05\x56\xe2\x5f\xa5\x20\x16\x15\x9f\xe2\xc3\x85\x88\xaa\xc0\x8b\xac\x90\x4e\x03\x76\x37\x7c\x6c\x16\x4c\x09\x87\x5d\x64\x26\x7e\x47\x17\xbc\x4f\xe2\x6e\x96\x4c\xb5\x91\xe5\xc5\x60\x74\x9e\x8d\xd9\xb6\x8c\x47\x8e\xcc\x2b\x9f\xdc\x98\x54\xf9\x28\x5a\x11\x84\x3b\x55\x68\xd8\xcd\xae\x9e\x8c\x63\x1d\xfc\x3e\x69\x7a\x0e\x3d\x24\xc6\xa9\x8e\xd9\xf9\xd5\x0e\x76\xac\x9a\x81\x8b\x5a\x66\x99\x4d\x3d\xba\x3a\x81\x04\xa4\x02\x5d\xe3\x60\x81\x26\xaf\xc1\x3e\xe6\x79\xc3\x8a\xc3\xe9\x9c\x3c\x8c\x26\x4c\x94\xdf\x64\x4c\x25\x7b\xd8\x4c\xb7\x06\x9f\xdd\x16\x73\x0c\x1c\x41\x41\xf0\xc7\xda\x49\x25\x90\x5a\x74\x08\x1b\x3d\x38\xdb\x91\x6e\x83\x57\x78\x44\x72\xcf\xae\xc5\x3d\xd2\x22\xa0\x01\x6d\x99\x40\x2d\x5b\x80\xb6\xcd\x87\x57\x5a\xdd\x5d\x67\xb5\x35\x16\x88\xad\x56\x52\x77\x8c\xe7\xdd\x3b\x57\xbb\x3f\xbb\xed\x3b\xb4\x3a\x7a\x91\xcc\xcb\x2c\xe1\xfe\x26\x05\x2f\x00\x2b\x8b\x2b\x99\x88\x0a\x4a\x6c\x9b\x17\x55\xab\x1b\x9f\xcb\xcc\x95\x78\xe5\x75\xa9\xc4\xab\x6c\x24\xae\x68\x8a\x02\x5d\x1d\x2f\x2f\x17\x10\x6c\xe0\x96\x8c\x4a\xa0\xff\xea\xe0\x4d\x96\xe9\x10\x7b\x29\xc4\x04\x75\x13\x99\x5f\xf4\xad\x6a\x31\x34\x2f\x07\x07\x02\x04\x93\x36\x23\x65\x2f\xe2\x77\x14\xd4\x4f\xf7\x86\x7b\xc9\x6d\xb7\xdb\x8c\x2f\x8b\x1b\xa6\x25\xe1\x65\xf9\xa6\xca\x88\x2e\xeb\xd2\x29\x22\x1d\xf1\x4b\x0c\x53\xbc\xa3\x8f\x8d\xb8\x98\xa2\xeb\x65\x91\x31\x9d\x4a\xd2\x5e\xe0\xc2\xa2\xa3\x64\xee\x8c\xc7\xd3\x0f\x51\x36\xeb\x8f\x16\xb3\x6c\x76\x1d\x09\xf3\x12\xeb\x09\x11\x77\xf0\x57\xd3\x22\xf1\x77\xb2\xe1\x12\x42\xb9\xe6\x96\x6a\xd4\x5a\x72\x19\x12\x50\x2a\xb9\x06\x89\xf1\xdf\xd0\xb6\x31\x9d\x31\x56\x9d\x40\x20\xda\x49\x1e\x4d\x2f\x17\xa8\x40\xb8\x51\xf8\x30\x66\x22\xb7\x31\x72\x36\x14\x3f\x78\x2b\x5e\x40\x3a\x51\x63\x64\xd6\x18\x11\x35\x56\x61\x81\xa9\x61\x95\x9c\x96\xd9\x23\x47\x46\xc1\x91\x51\xd0\x6b\xec\xa7\xa3\x63\x1c\x4f\x7e\x0c\xc8\x3a\x0d\xc6\x13\xa6\x14\x4d\xdc\xe6\xc9\x24\x52\xf6\x18\x87\x0d\x6b\x0a\xef\xd7\xa3\x37\xd1\xcf\x90\x7d\xb2\x35\xe0\xff\x11\xb6\xb2\x91\x57\x38\x6d\xf3\xff\xca\x2d\x63\x53\xc7\x26\x36\x72\x7e\x53\x1c\xf9\x3b\xc4\x21\xc3\x15\xe8\x53\x44\x21\x53\x42\xfc\x56\x22\x91\x95\xe4\x2b\xa3\x84\x31\x41\x48\xec\x34\x25\xc7\x03\x76\x52\xdb\x5e\xac\x27\xcc\x7a\x11\xc8\x90\xcc\x5f\x4c\xfc\x31\x86\x8c\x88\x3e\x86\x78\x8d\x70\x8a\x95\x46\x42\x59\x71\xc8\xab\x04\x22\xf3\x4a\xdf\x7e\x30\x32\x93\x25\x64\x2b\x4b\x83\x8f\x7d\xaa\xe8\x61\x48\xea\xff\xf4\xe8\x61\x4b\xd4\xd4\x55\x54\x44\x90\xa1\x9a\x87\x96\x46\x10\x0b\xd7\x58\x1a\x45\x2c\x5c\xf5\x13\x45\x12\xbb\xb9\xb4\xdb\xe5\xe6\x69\xa4\x37\xb1\x1e\x83\x76\xb1\x4f\x07\x47\x2b\x0d\x38\x56\x2a\x31\x4d\x5a\x39\x40\xb5\x86\x22\x35\x0d\x79\xc8\xa4\x50\xd9\x08\x86\x1c\x63\x20\xe9\x43\x22\xb5\xf1\x0f\x38\xc1\xf5\xfe\xd0\x06\xf3\xab\x76\xf3\x01\xf1\x98\x6d\x66\x1f\x00\x2a\x39\xfa\xb6\xce\x9d\xb7\x6c\xdb\xfa\x00\x7d\x1a\x89\xe7\xfc\x9a\xa5\xf3\xe2\x3c\x6f\x2d\x77\xbe\x63\x8f\xd8\xe6\x8a\x78\x98\xb8\xe0\xd8\xc3\x56\x9c\xf8\x0f\xd3\x2e\x51\x32\x75\x1e\xcd\x46\x17\xc5\x79\x1e\xb7\x1b\xa4\xef\x9f\xf5\xe8\xa2\xff\x6b\x3e\x74\xdb\x29\xde\x5f\xb0\xa7\x65\xe7\x0e\x76\xd7\xa7\x79\xf1\x60\x30\xec\x93\x8f\x17\xb3\xc0\xe3\x07\xef\xc6\x59\x7e\x9e\x4d\xa8\xd7\x53\x1a\x58\x31\x70\x1f\x33\xcd\xf6\x41\x36\x99\x8f\xae\x7a\x89\x4b\x04\x78\x35\x9a\x4f\xe3\x46\x9c\xb8\x14\x17\xaf\x7a\x9d\x5e\xc7\x1d\x21\x78\xf5\xcf\x62\x36\x15\x57\xaf\x89\xb7\x93\xc0\x3b\x6e\x23\x7b\x70\x56\x5c\x39\x2f\xb2\xc2\x65\x2e\x1e\x77\x23\xf7\x9e\xcf\x06\xee\xe0\xce\xb2\x3e\xdb\xff\x90\x0f\x1f\x8c\x8b\x77\xd9\xe0\xfa\x53\x9f\x01\xa9\xd9\x83\xbf\xdc\x49\x83\x0f\xf5\x5c\x51\x3f\xc5\x14\xc1\xdf\x30\x33\x1c\xb7\x50\x31\x0f\xd4\xf7\xa4\xa9\xbe\x03\xd7\xcb\xef\xc0\xec\xea\x3b\xff\xa6\x59\x5b\xfb\x97\xe2\x37\xc1\xc8\x1c\x03\xce\xbf\xde\x19\x16\x47\x47\x72\xab\xf1\x8b\x31\xa9\xf5\x4b\xf1\xa6\x7e\x3a\xb5\x6a\x00\x27\xf2\x66\x4d\x06\x54\x4f\x14\xdf\x99\x4f\x38\xbb\xa9\x27\x26\x97\xa9\x87\x13\xfb\x91\xc1\x53\xf8\x1b\x58\x09\xbf\x68\x0e\xe2\x54\x19\x08\x42\x09\x7e\x31\xbe\x4b\x36\x59\xd5\x10\x69\x19\x20\x4d\x59\xbb\xba\x61\x32\x1c\x8a\x4d\x74\x69\xab\x15\xd7\xcb\xad\xc9\x75\x9b\xaf\xb6\x5a\x4d\xf9\x84\x33\xde\x56\xab\x55\xd7\x03\xbf\xd5\x6a\xd7\x6d\xea\x6d\xb5\x3a\xee\x89\xb0\xcb\xca\x5b\xed\x46\x5d\x70\xeb\x56\x1b\xf1\x51\x9c\xb2\xd5\x4e\xea\x26\xaf\x6c\xb5\x9b\x75\x8a\x5b\xb6\xda\x69\xdd\xe4\x90\xad\x76\xab\x6e\xf2\xcf\x56\x1b\xf1\xb2\x78\x66\xab\xdd\xa9\xbb\x5c\xb3\xd5\xee\xd6\x5d\xbe\xd9\x6a\xf7\xea\x1e\x93\x6c\x75\x1a\x75\x82\x9d\xb6\x3a\x88\xbf\x98\x12\x5b\x1d\xc4\x5e\xb0\xc6\x56\xa7\x59\xf7\x98\x63\xab\x83\x88\x03\x1b\x6d\x75\x10\x67\x3d\xcf\xb6\x3a\x6d\xf3\x00\xbd\xae\xa7\xec\x56\x47\x1e\xad\xc3\x64\xde\xea\xf4\xea\x72\xaa\x6e\x75\x1b\xf2\x3b\x9b\xc2\x5b\xdd\xb8\xae\x27\xf7\x56\x17\xd1\xd1\x1c\xbc\xd5\xc5\xc6\x95\xa0\xd9\xea\x36\x99\xd2\xd1\x6e\xdc\x1d\x1e\xfc\xfe\x87\x07\xbb\x67\xc5\xe0\x57\xe8\x14\xce\x14\x7e\x0d\x88\xa7\x39\x9b\x5f\x5e\x00\x61\x0a\x11\x9f\xda\xe8\x37\xea\xf1\x3c\xa4\x79\xf4\xcd\x76\x74\x4f\x42\xbe\x47\x78\x84\xa8\x4b\x1a\xb7\x78\x5c\x51\xea\x8e\xaf\xda\x39\x2e\x20\xe4\x3a\x6e\xf4\x66\xa3\x77\xb8\x27\x03\xb3\x8d\x06\xc3\x3a\x58\xcc\xd0\x74\xbd\xed\xa4\xe7\x30\xa0\xec\x5c\xbe\x83\xdc\x59\x4e\x01\x30\x22\x9c\x65\x93\x7c\x5c\x58\x74\x33\x61\xf7\x49\xc8\x96\x4f\x0d\x56\xf5\xaf\x03\x1a\xb6\x6f\x1e\x4b\x9e\xbb\x40\x8d\x26\x8b\xae\x61\xa1\x1f\x9a\xf5\x95\x61\xc2\x1e\x1d\x7f\x9b\xaf\x6b\x70\x23\xfc\x2b\x80\x8a\x0f\x4c\x6c\xac\x4d\x04\x89\xd8\x94\xa7\xff\x42\x48\xef\x47\xc5\x87\x10\x8a\xc1\xe6\x0d\x84\x0f\x24\x0a\xd1\x6f\xbf\xd9\xe5\x25\xc3\x21\x96\x68\x33\x96\xd5\x21\xd2\xce\x5f\x94\xef\x08\x4e\x3a\x1a\xb8\x57\x75\xc3\x6b\x25\x88\x55\xdc\xa6\xd1\x0a\xb7\xb4\x5a\x0d\xd6\x44\x9a\xac\xda\xc4\x6a\x35\x9e\x8e\xa7\xd9\x3a\x55\xda\x4d\x7c\xae\xcb\xaf\xc9\x4a\x9b\x9c\x83\xeb\x91\xf9\xe8\x7a\x51\x1c\x61\x72\x20\xef\x31\x95\x77\xd9\xe2\xbf\x7d\x3e\xe9\x74\x5b\x55\x66\x84\x2e\xbd\x9a\x09\x41\x43\x7b\xac\x70\x03\x83\x02\x85\x33\x61\x59\xd8\xbb\x12\xd9\x57\xc1\x60\xed\x43\x5f\xe9\xba\x20\x85\xca\x2a\xf9\xb4\x75\x7d\x30\xce\xaf\x93\x3c\x5b\xbb\x73\x83\xb5\x0d\x4d\xb5\xfa\xe2\xa8\x5d\x54\x09\x56\x9d\xda\xa2\x1e\x89\x6b\x84\x54\x47\xac\xf1\x26\xb3\x9e\x01\x35\xe1\x31\xc0\x8b\x26\xa0\xf5\x99\xc2\xdc\x6f\x37\x83\x44\x27\xa2\xe1\x6c\xf6\xee\x12\x44\xf8\x7c\x83\xb0\x8d\xc8\x61\x13\xf2\x7a\xf3\x62\x36\x5d\x4c\x01\x47\x53\x72\x97\x39\x4e\xb8\xab\x0f\x6d\x60\x69\xc7\x77\xba\xce\xef\xa3\xeb\x60\xc0\xe8\xdf\x21\x2e\x11\x99\x53\xa9\x82\x8b\x04\x2e\xb1\xfc\x1c\x2f\x5a\x68\xdb\x3a\x54\x39\x15\xc2\xc2\x28\xc9\x4d\x97\xce\xc9\x1f\xa5\xe9\x85\xe4\x4a\xbb\x49\x4b\x91\x53\x10\x13\xdb\x9c\x7c\x9b\x59\x7f\xce\xbf\xcc\x47\x13\x11\x8c\x15\x44\x46\xe3\x0a\x1c\x88\xf1\x03\x37\x75\x1b\xc4\xf4\xaa\x69\x5b\xa5\xe9\xfc\x01\xf7\x21\x6d\x6f\x0a\xca\x01\xc4\xbd\x35\x09\xae\x52\xdc\x5a\xea\x3b\x80\xc8\xb4\x37\xa5\xa7\x63\xda\x12\x1c\x91\xb9\x8a\x5f\xfb\x92\xb4\x71\xd5\xee\x34\x5b\x49\xda\x88\xeb\xec\x7b\x31\x1c\xe4\x59\xbf\xdb\x23\xf2\x2a\x36\xae\x7a\xdd\x7e\x96\x0f\x86\x90\xd4\x90\x11\x26\x4d\x5a\xcd\x4e\xdb\x2e\xf7\x26\x78\x22\xe6\xa4\xd1\x33\x7b\xf1\x5c\x65\xd2\xa3\xd6\xae\x0f\xd9\x45\x54\xe0\xf5\xea\xe5\x6b\x48\xdc\x0e\xaf\x18\xe1\xe3\x6b\x39\x1a\x1c\x89\xb7\x00\x4f\xa6\x17\x8c\x46\xc4\x29\x30\x8e\xdc\x5b\xa3\xf4\xf3\xd7\xf2\xc7\x1b\x77\x91\x84\x8f\xf1\x1a\x18\xce\x2f\x00\x9f\x5a\xad\x66\xc0\xe4\x37\xc5\xe1\x72\xb8\xf1\x10\xd7\xba\xe6\x86\x73\x47\x3c\xfa\xad\x22\x40\x56\x95\x00\x88\xa1\x8c\xad\x2b\xe9\x3e\xb8\x9b\x39\x87\xec\x5b\x27\x14\xf4\x71\xbf\x45\xd2\x46\x64\xd0\x94\x8d\x41\xc3\x55\x1f\xac\xd2\xb1\x53\x3a\x2e\x2d\x9d\x38\xa5\x93\xd2\xd2\xa9\x53\x3a\x2d\x2d\xdd\x74\x4a\x37\x4b\x4b\xb7\x9c\xd2\xad\xd2\xd2\x6d\xa7\x74\xbb\xb4\x74\xc7\x29\xdd\x29\x2d\xdd\x75\x4a\x77\x4b\x4b\xf7\x9c\xd2\xbd\xf2\xd1\x69\x38\xa3\xb3\x64\x30\x63\xa7\x78\xf9\x68\xc6\x89\x53\xbc\x7c\x38\xe3\xd4\x29\x5e\x3e\x9e\x71\xd3\x29\x5e\x3e\xa0\x71\xcb\x29\xde\xf2\xa4\x01\x9b\x28\x4c\x20\xff\x0a\xce\x5e\xac\x2a\x53\x34\xfb\x94\xda\x0c\x79\x1c\xf7\x5f\x93\x84\xea\xe3\x2b\x92\x28\x03\x7c\x45\x12\x20\xc7\x57\x29\x85\xce\xae\x3e\x83\xb6\xdf\x01\x12\x4f\x9f\xd6\xb2\x7a\xd4\xaf\x47\x83\x7a\x94\xd7\x8d\x09\x5a\x8f\xa2\x4e\x1d\x96\xd0\xc6\x1b\x57\x36\xe4\xbc\x1e\x2b\xae\xaa\x6a\x0a\xb1\x3a\x71\x52\x67\xf5\x62\xaf\xde\x80\xd7\xe3\x2d\xf1\xaa\x7a\xd2\x42\xbd\x0e\xd4\x4b\xbc\x7a\x7d\x5e\x4f\x21\x99\x19\xf5\x52\x56\x2f\xc1\xf6\x52\xaf\x5e\x59\xff\x9a\xaa\x7f\xcd\x95\xfa\xd7\x52\xfd\x6b\xad\xd4\xbf\xb6\xea\x5f\x7b\xa5\xfe\x75\x54\xff\x3a\x2b\xf5\xaf\xab\xfa\xd7\x5d\xa9\x7f\x3d\xd5\xbf\xde\x4a\xfd\x8b\x19\xc3\xf0\xfe\xc5\x3e\xc3\x94\x75\x30\x66\x1c\xc3\x3b\x18\xfb\x1c\x53\xd6\x43\xc0\x92\xf7\x30\xf6\x59\xa6\x94\x45\x19\xcf\x08\x16\xf5\x79\xa6\xb4\x8f\x4d\xd5\x47\x9f\x69\x4a\xfb\xd8\x52\x7d\x44\xae\xf1\x3b\xf9\xfd\xf7\x81\x4e\xb2\x0e\xb6\x78\x27\x7d\xbe\xc9\x79\x45\xb2\x93\xc0\x6f\x3d\x5e\xd1\x67\x9c\x01\xaf\x48\x77\x92\x35\x09\x1d\x65\x15\x7d\xce\xe9\xf3\x8a\x64\x27\x41\x62\x24\x0d\xac\xe8\xb3\x4e\x59\x1f\x5b\xaa\x8f\x09\x2d\x6b\x42\x7d\x04\x9e\xe3\x7d\x4c\x68\x61\x13\xec\x63\x4b\xf6\x31\xa1\xa5\x4d\xa8\x8f\x4d\xd5\xc7\x84\x16\x37\xa1\x3e\xf6\x74\x1f\x69\x79\x13\xec\x63\x53\xf5\x91\x16\x38\xa1\x3e\x82\x60\x14\x7d\xa4\x25\x4e\xa8\x8f\x5d\xdd\x47\x5a\xe4\x04\x79\x95\x35\x29\xfa\x48\xcb\x9c\x50\x1f\x13\xc5\xab\x09\x2d\x74\x42\x7d\xec\xa8\x3e\xa6\xb4\xd0\x09\xf5\x11\xa6\x3f\xef\x63\x1a\xd3\x13\x72\x7f\x3f\xcc\xac\x4d\xc4\x35\xa5\xa5\x0e\xab\x48\x76\x12\xc8\x0a\x73\x8b\x55\xa4\xa5\x0e\xab\x18\x9e\x90\x6d\xac\x48\x4b\x1d\x56\x91\xee\x24\xc3\x33\x49\xb1\xa2\xcf\x3a\x65\x7d\x8c\x75\x1f\x69\xa1\x13\xea\x63\x53\xf7\x91\x16\x3a\xa1\x3e\xe2\x40\xf2\x3e\xd2\x42\x27\xd8\xc7\x86\xea\x23\x2d\x74\x82\x7d\x64\x95\x78\x1f\x9b\xb4\xd0\x09\xf5\xb1\xa1\xfa\xd8\xa4\x85\x4e\xa8\x8f\xa9\xea\x63\x93\x16\x3a\xa1\x3e\x82\x28\xe7\x7d\x6c\xd2\x42\x27\xd4\xc7\x9e\x1a\xc7\x26\x2d\x74\x42\x7d\x84\xe9\x21\xfa\x48\x0b\x9d\x20\xaf\xb6\x24\xaf\x36\x69\xa1\x13\xea\x63\xa2\xfb\xd8\xa1\x27\xe4\xc1\x41\x58\x51\x6d\xf3\x4e\xd2\x52\x87\x55\x24\x3b\x89\x3c\x87\x32\xa0\x49\x4b\x1d\x56\x31\xac\x06\xb4\x50\x05\xa4\xa5\x0e\xab\x48\x76\x12\x64\x47\x82\x64\x6d\xd1\xaa\x4e\xa8\x8f\x30\x1e\xbc\x8f\x2d\x5a\xe8\x84\xfa\x98\xaa\x3e\xb6\x68\xa1\x13\xec\x63\x43\xf5\x91\x16\x3a\xa1\x3e\xc6\xba\x8f\xb4\xd0\x09\xf5\xb1\xab\xc6\xb1\x45\x0b\x9d\x50\x1f\x91\xe7\x78\x1f\x69\xa1\x13\xea\x23\xaa\xe4\xbc\x8f\xb4\xd0\x09\xf6\x91\x91\x55\xf4\x91\x16\x3a\xa1\x3e\x36\x55\x1f\xdb\xb4\xd0\x09\xf6\x31\x96\x7d\x6c\xd3\x42\x27\xd4\xc7\x44\xf5\xb1\x4d\x0b\x9d\x50\x1f\x7b\x6a\x1c\xdb\xa9\x3f\x21\xf1\x18\x65\x51\xcc\xce\x8b\x7c\x04\x9e\xc3\x68\x8d\xc4\xeb\x0a\x76\x39\xd8\xe2\x82\x3d\x0c\xff\xbd\x1f\x65\xae\x85\x95\x97\x89\x45\x99\x18\xca\xf4\xe9\x32\x89\x28\x93\x40\x99\x01\x5d\x26\x15\x65\x52\x28\x93\x7b\xd6\x5c\xc7\x54\xf9\x94\xf0\xd4\x5d\x31\xa0\x2d\x66\x4a\x57\xd9\x74\xd9\x0f\x6a\x63\xce\x1e\xab\x50\x3e\xec\x7b\xd8\x38\x36\x79\x3c\x5a\xcc\x4f\xa7\x8b\x6c\xac\x60\x4e\x9e\xb0\x1a\xfc\x06\xc9\xb7\x51\x97\x80\x8e\x75\x0e\x8b\xe1\x42\x42\x57\x37\x4e\xb0\xbc\xd7\x99\x60\xca\x2b\x85\xe6\x6b\x0d\x12\x6c\x87\x2d\x3c\x78\x6b\x5c\x75\x1b\xfa\xbc\x4d\x97\xf8\x6b\x94\x26\x1e\x73\xd8\x7d\x01\xd3\x20\x9a\xdd\x87\xe3\xe9\x74\x56\x33\x3a\xf9\xd0\xb2\xbd\x87\x3a\x87\x65\x0f\xc1\xd4\xad\x7e\xb9\x13\x47\xa1\x5e\xab\xd5\x34\x6e\xf7\xa3\x76\x93\xe7\x4b\xeb\x61\x30\x51\xf6\x03\x0d\x36\xb4\x7d\x56\x56\x15\x38\x6b\xe3\xac\xf9\xb4\xba\x75\xd6\x07\x27\x4c\xb3\x2e\xb8\x65\xb6\x59\x4f\x4a\xac\xd2\xd9\x66\x95\xce\x1e\x92\x9d\x3d\x5c\xb7\xb3\x87\x64\x67\x0f\xab\x76\xd6\xef\xad\x79\x89\xaa\xa6\xba\x2f\x83\x4d\x61\x4e\x3d\xfa\xfe\x20\x3a\xbc\xf3\x6b\x0c\x78\x47\x91\xba\x49\x55\x9a\x57\x7e\xc9\x6d\x48\xcb\xe6\x4d\xb0\x2f\x70\x7e\xd8\x1c\xbe\xea\xb9\x47\xe0\x88\xcb\x41\x95\x3e\xc9\xd8\xc7\x23\x8c\xfd\xd7\xf4\xd9\xc5\xbe\x38\x25\xab\xd5\xf6\xad\x63\x89\xfd\x95\xcf\x23\xa2\xc8\x80\x22\x78\x60\x3f\x78\x08\xb1\xfc\xc4\xe1\x58\xe4\x06\xc6\x31\x14\x11\x78\x72\xa4\xa9\x5d\xb4\x42\xb2\x72\xbc\x86\x50\x2a\xea\xd1\xc0\x8a\x97\x32\xd5\x09\x1d\xfe\xd4\xc7\xff\xde\xc2\x8b\xaf\xdf\x7a\xbc\x10\xba\x5d\x29\x23\x68\xc0\x5b\xe2\x98\x51\x95\x57\x48\x3a\xa6\xaf\xab\x7a\x04\xbe\x07\x16\xe6\x28\x27\x59\xeb\x19\x9b\x09\xb5\x5a\x9f\x51\x18\x17\xc7\xda\xbf\xe1\x6b\xbe\x01\x62\xe0\x8a\xfd\x59\x18\xed\xa9\x80\xc5\x13\x18\xa6\x39\x9f\xa9\x3c\x4e\x79\x9a\x30\xf1\x3e\xdf\xc0\x6a\xf2\xb4\xf8\xa3\x8b\x95\xb3\xff\xaf\x86\x15\x2e\xc7\xb5\x01\xfb\xf6\xef\x4f\x83\x95\xb3\x09\x5a\x8a\x55\x3f\xfa\x99\x69\x4e\x3f\x03\x62\xb7\x8f\x8c\xa3\x00\x2e\x45\x06\x10\x61\x18\xfd\x76\xcb\xc4\x31\x4f\xab\x4f\x42\x69\xd2\xf9\xdd\x9d\x92\x44\xe9\x55\x82\xac\xc9\x7b\x62\xce\x15\x45\x9c\x0d\x3e\x98\xc0\x65\xb1\x40\xe9\x75\xc3\x9a\x89\x79\xa9\x9c\x5c\x9e\x3f\x69\x11\x77\xbf\xca\xcb\xfb\x17\xbe\x74\x7c\x31\xeb\x30\x5f\xa6\x3e\xe7\x59\xd3\xd9\x2b\xd2\x65\x20\x38\x08\x4b\x72\xd5\xdf\xd6\x10\xac\x15\xda\x61\xf9\xc0\x99\xb7\xfc\x6e\x63\xe0\xf8\xa5\x32\x35\x10\x90\x4f\x1e\x06\xc3\xca\x0c\xef\x8f\x87\x28\xe6\x8f\x89\xce\x66\xcf\xc7\xa5\x34\x03\xbb\x88\x6c\x1d\xf0\x80\x4a\xfe\xd0\x57\xcc\xfe\xfc\x31\xd9\xf8\x64\xfb\x56\xc4\x19\x8a\x9e\x16\x45\xde\xcf\x06\xbf\x8a\xb8\x9a\x70\xc3\x01\xf8\x14\x78\x46\x8d\x37\x3e\xdc\x7d\xfa\x18\x54\x20\x42\x3d\x40\x37\x27\x7c\x6b\x79\xcb\xa1\x87\x0b\x6f\xe5\x39\x00\x10\xce\x3c\x6a\xd6\xb3\x2a\x9b\x7b\x13\x1e\xab\x1c\x1d\xa8\xd8\x6f\xdf\xe1\xe7\x22\xe0\x2e\x23\xdc\x0c\x4b\x5c\x66\xc2\xaa\xa9\x08\x41\x25\x15\x12\xfe\x93\x3a\x67\x36\x42\x79\xf0\x42\x66\x28\x0f\xbb\xbc\x8c\x51\xfe\xac\xb8\x66\xf2\xa2\xc8\xce\x77\x26\xb9\xe8\x1d\xe1\x1d\x39\x15\x6e\xb1\x0a\x5c\x5d\x34\x40\x29\xd9\xc7\xc5\x79\x81\x41\xc6\xd1\x19\x93\x8f\x93\x88\x95\x89\xf7\xe7\x27\x10\x09\x0a\x1f\xd3\xea\x7b\xf1\xfe\xb1\x88\x99\x8a\xad\x6f\xce\xc7\xa3\x41\x51\x93\x28\xa8\x93\x7a\x85\x0b\xe5\x3f\x69\x8d\xda\x93\xe2\xcf\x32\x6a\x37\xa0\x2e\x3a\x0e\xb3\x2a\x2b\xd3\xf6\xb3\xf1\xcd\xa9\xee\x50\xbf\x60\x7b\x03\x71\xeb\x1e\x18\x62\x34\xbd\x9c\x57\x63\x19\xd5\xc5\x4a\xea\x78\x49\x6f\x6a\x4b\xbb\xe0\xdc\x8d\xf0\x37\x6c\xb8\xdf\x7b\xaf\x38\x60\xf4\xde\xec\xb4\x13\x8e\x99\x37\xaf\x5f\x63\x66\xd7\xf7\xe4\x96\x46\x95\x66\x90\x47\xef\xc5\x10\x36\x02\x33\x71\xfa\xbe\x88\x0e\x7e\xc0\xfd\xe7\xfc\xb2\x3f\x67\x2b\x07\xdc\x1a\x09\xef\x9e\x11\x5f\x75\x81\xc1\x24\x22\xe9\x00\xed\xe2\xe3\x0c\x88\x3f\x08\xf0\x11\x5c\x5e\x70\x42\x63\xc1\x9a\x02\x52\x8f\xec\xae\x80\x8b\x35\x2f\xa8\x9f\x91\xd9\x72\xcb\xa3\xc6\x70\xd7\x73\xed\x21\x88\x91\x60\x54\xa3\xb8\x8f\xf6\xf8\x45\x60\x21\xd5\x89\xa7\x8f\x1f\x95\x38\x5c\xcb\x45\x67\x95\x38\x75\xed\xf4\x4e\xf9\xf8\xd2\x95\x0f\xc8\xa9\x51\x30\x48\xef\x0b\xae\x86\x4c\x2f\x41\x95\x37\xd4\x0f\x34\x63\x30\xb5\xb8\x3f\x16\x12\x38\x7a\x32\x8b\x1e\xcf\x46\x19\xdb\x57\xf3\xeb\x99\xd1\x70\x34\x2e\x20\x1c\xdd\x00\x41\xc8\x90\xcf\x18\x01\xdb\xe1\x9f\xd3\x63\x2c\xf2\xdf\xac\xd2\xfe\xec\xb2\x7f\x1d\xfd\x72\x06\xff\x6c\x7e\x28\xfa\xff\xeb\xdd\x79\x36\x1a\x43\xd8\x62\x5a\xdf\x39\x3d\x96\xcd\x95\xa8\x3d\x66\xa1\xca\xda\xcf\x57\x3a\xdf\xcb\x64\x00\x1b\x05\x9e\x32\x89\xbd\x00\xa2\x23\xeb\xa9\x74\x48\x68\x24\xe2\x46\xa1\x0d\x1c\x75\xfe\xed\x5f\x6c\x1e\x33\x51\x34\x1b\x8e\xa7\x1f\xa0\x0e\x2e\x7c\xe0\x36\xa8\xea\xc5\xed\x8d\xbf\x41\xd9\x47\xea\x75\x62\xbe\xee\xba\x6f\x53\xb1\x86\x89\xc6\x10\x4f\x44\x05\xc0\xaa\x76\x19\x47\xc8\x66\x59\x7b\x50\x04\x51\xc6\xa6\x1b\x8f\x54\x95\x44\x57\x51\x65\xbe\x42\x04\x78\x21\x5e\x2a\xb5\x4b\x89\x62\x5f\x21\x2a\xa2\xdc\x47\xfc\x1b\x18\xd2\x2c\x71\xff\x7e\x3f\x35\xde\xe3\x5f\xb2\x0c\x2f\xc2\x4a\x24\x8f\xbe\x0e\x17\x60\xef\x63\xf1\x1e\xfe\xc6\x8e\xcb\x46\xf1\xc7\xfd\x6d\xec\xf9\x77\xdf\x89\x7c\x90\xe6\xe3\x84\x9b\x00\xad\xa7\x02\x21\xbf\x25\x55\xad\x71\xd5\x88\x85\xd5\x4f\x17\x15\x52\x0f\x0a\xc1\xc3\x8f\x2e\x77\x88\xe9\x51\x1b\xf0\x7f\x6d\x1e\x11\x0f\xf9\x09\x92\x64\x25\xfd\x70\x43\x30\x8c\x31\x04\x6c\xf8\x60\x95\xc0\x93\x18\x48\xb1\xa3\x26\x12\x9f\x3c\xde\x4c\xbb\x07\x51\xd8\x2f\xe1\x8e\xc2\x74\x32\xbe\xe6\xd3\xf1\xc9\x8f\x47\xc7\x4f\xa2\x5f\xd8\x12\xd1\x45\x98\xb2\xc1\x98\xc2\x82\x9f\xc5\xd9\x9d\x15\xef\x64\x7f\xe5\x5c\xb2\xf6\x02\x6a\x5e\x6d\x06\x1e\xff\x8e\x3a\xe7\xaa\x2a\xa7\x57\xdc\xd1\x61\xfc\x56\x85\x4c\x54\xcd\x4a\x82\x79\xa8\x97\x69\x3c\xf0\x09\x68\x3d\xa8\x34\xd0\x8d\x94\xeb\x40\xa2\xd9\x95\xf4\x20\xf8\x2c\xd1\x85\xe0\xc3\x27\xe2\x57\x04\xcf\x73\x51\xf4\x95\xab\x1a\x89\xd7\x06\xae\xb6\xe9\x79\x15\xa5\x48\xf4\xcb\x53\x8c\xe0\xb3\x86\x72\x04\x9f\x55\x14\x24\xde\xff\x92\x4d\x97\xe6\x7b\x73\xef\xa5\xa6\xc6\x23\x47\xb3\x52\xc5\x0d\x05\x2b\xa8\x62\x19\x85\x57\xd0\xb4\x9a\x77\x9a\xd6\x97\xae\x69\x05\xf4\xab\x80\x79\xe7\xf4\xb8\x5c\xcf\x59\xd5\xbc\x43\x88\x74\x57\x96\xdf\x09\xf1\x3f\x9f\x10\x2f\xdd\xcd\x7e\x02\x91\x7d\xc0\x0a\x17\x18\xb9\x41\x00\x77\x40\x0a\x3d\x44\x0f\xee\x83\x88\x3b\xd3\x04\xde\xe0\x69\xf9\x6f\x51\xe3\x0f\xb5\x38\x54\x5d\x15\x96\xef\xb7\xa1\xcc\x0a\xab\x40\xeb\x6e\x15\xf8\x43\xac\x02\x7b\x63\x46\xc4\xd9\x74\x32\x1a\x44\x90\x3e\xb8\x3f\x9d\x2e\x37\xf8\xef\xed\x96\x19\xfc\xf9\xdb\x95\x56\x04\x56\xc5\x5a\x01\xe0\xf7\x6d\xad\x00\xa6\x68\xb7\x05\x88\x5d\xaf\xcc\x8a\x09\xf8\x58\x13\xe9\x53\xe1\x97\x17\x6b\xe1\x27\x53\x2f\xed\x2e\xb7\x9b\x61\x99\x15\xe6\xf1\x1f\x3b\x39\xf2\x7f\xce\x3c\x3e\xba\x5c\x40\x62\x96\xca\x87\x76\x47\xa5\x87\x76\x47\xab\x1f\xda\xb9\x5a\xdd\x91\x73\x88\x77\xf4\xfb\x1e\x07\x7d\x72\xad\xce\xb7\xcd\xab\x27\xb7\xab\xd9\x95\x34\xf4\xa5\x68\x77\x7f\xa6\x1d\xf6\x91\x73\xac\x19\x52\xa2\x8e\x2a\x1c\x5a\x1c\xad\x78\x68\x71\x97\xc5\xee\x8f\x21\x7c\x77\x5e\x9c\x1c\x44\x3f\x6d\xf6\x92\x54\x3a\x88\x43\xce\x12\x36\xbd\xdf\x5d\x7b\xd2\x97\x15\xd8\xdc\x99\xcc\x47\x3f\x41\x69\x95\x0b\x8e\x3d\x35\xc5\x1f\x58\x61\x8d\x83\xd0\xd0\x01\xa8\x31\x59\xa5\xbb\xfc\x89\x76\xf8\xb5\x1c\x80\x1f\xd9\x45\x15\x68\x59\x58\x4b\x43\xc7\x21\x18\x99\x82\x6d\x88\x54\xcf\x9c\x60\x56\xe8\x8b\xf7\x92\xbf\xf3\x80\xf1\x07\x0f\x6c\xcc\xfe\xea\xbc\xf7\x5a\xe3\x31\x6d\xc6\xd9\x9c\x47\xce\x8a\x2e\xa6\xf3\x91\x7d\x03\x1f\x1a\x85\xf7\x50\xff\xe5\x54\x76\x56\xb5\x70\xdf\xc1\x88\xed\xdf\x9c\x46\xd8\x6b\xfd\x1b\x09\xa5\xb2\x8d\xd8\x8f\xb9\x28\x31\xdb\xd2\x21\xb5\xec\x46\x74\x48\x2d\xb3\x34\x15\x5c\xcb\xf6\xcc\xbe\xef\x00\x12\x05\x95\x07\xee\xe5\x84\x60\x0e\x97\x23\xbe\x67\x82\x4e\x27\x9c\xb7\x86\x8a\x1b\xf0\x15\x35\xcb\x47\xce\xe8\x73\xcd\x46\xf3\x01\x6c\x7f\x45\xdf\xf5\x41\x90\x81\x82\x58\x57\xbc\x1b\x12\x0e\x98\x40\x6f\xd9\x74\x23\xe5\xa6\x9c\x2e\x10\x48\x75\x25\xd9\x79\xb3\xc4\x64\x77\xb2\xf3\x73\xc9\xce\x83\x93\xa3\x08\x43\xe2\x56\x13\x9d\x07\x22\x80\xee\x4d\x45\xe7\xef\x2e\x0e\xf5\x94\x58\x22\x0f\x09\x51\xc5\xd3\x01\xd0\x11\xe8\x36\x67\xd9\x24\x9f\x9e\xd7\x3c\x09\xb8\xb1\x61\x26\x93\x80\x4f\x39\x1c\x91\x3a\xec\xb5\x27\xe5\x92\x26\x63\x93\x78\x63\xe3\x4e\x50\xb9\x82\x4a\x32\xe7\x4a\x82\xea\x8f\x9d\x79\xe1\x3f\x4a\x50\x3d\x3c\xd8\xdb\x8d\x20\x56\xf7\x83\x38\x12\xbc\x11\x3d\x2f\x16\x67\xd3\x3c\x4a\x42\xd2\x0a\x43\x7b\xaf\x2b\xad\xe0\xca\x21\xde\x1f\xb4\x27\x44\x05\x29\x20\x67\x2f\xd4\xe6\x1f\x39\x69\xad\x06\xfe\x4f\x31\x9b\x62\xee\x30\xf0\xff\x9b\x15\x73\x43\x2e\x5a\x1d\x81\x72\xa2\xc7\xf0\xdb\xc3\x7b\x2d\x59\x20\x26\xe2\x3f\x05\x0e\xf6\x6c\x0c\x36\x8f\xa0\x39\x3c\x7a\x62\xc3\x35\xa4\x73\xc6\x24\x5c\x79\x7c\xf0\x00\xfb\x16\xa2\xa2\x9c\xef\x0f\x1e\x54\x9c\xe0\x38\x9e\xab\x4c\xf0\xce\xcd\xa2\x9c\xdf\x4d\xf0\xcf\xb6\x8b\x8b\x26\xd3\xe9\x45\x35\x35\xe4\x85\x64\xc7\xe0\xcc\x56\xcc\x1e\x9e\x13\xba\x48\x19\xcf\xa9\xa6\x56\x62\xba\x9b\x85\x9b\xbd\x63\xba\xcf\xc5\x74\xff\xc7\x10\x7e\xe5\x2c\x67\xc8\xc0\xdf\x51\xf9\xad\xbc\x71\x36\xf7\xb7\x9e\x02\x5c\xab\x95\xab\xc0\x10\xa2\xdd\x7d\xb4\xd6\x12\x43\xf7\x78\x79\x5c\x01\x56\xf5\x15\xc0\xb7\xeb\x8d\xbc\x48\x01\x68\x59\x50\x65\x3e\x9c\x8d\xc6\x45\x54\xfb\xa6\xa6\xef\x5a\xeb\x18\xdc\x78\xe3\xd0\x8b\xb9\xad\x5c\x38\x3d\x43\xe6\x48\x2d\x49\x42\x44\xd0\xe4\xdd\x8e\x30\x1e\x6f\x95\xd5\xcb\xe0\xa0\x95\x64\xc9\x1f\xfb\xe2\x16\x91\xa3\x8b\x27\xc9\xfa\xb4\x72\x45\x67\x42\xc2\xd6\x7e\xff\x3c\x1f\x9f\x36\x49\x78\xb5\x98\xd8\x5e\xcc\x6b\xf5\xe6\x64\x7f\x27\x8e\x54\xac\x67\xf8\x65\xbc\xf4\x13\x81\x53\x17\x44\x5f\x66\xf3\x39\x4c\xe4\x07\x80\x5a\x1e\x41\xc2\xde\x27\xe0\x00\xce\x73\x42\x3e\x95\x44\x49\xca\x63\x4e\xbf\x7c\xfc\xec\xc9\xd3\x44\xb7\xa6\x7e\x57\x4c\x3c\xbe\x3b\x9d\x0c\x47\xef\x2e\x45\x26\xca\x29\x66\x85\x9c\x97\xe5\x97\x9c\x41\x16\xca\xc5\x75\xf4\x2f\xbe\x2d\xc6\xdb\xa4\x28\x7c\xe1\x76\x28\xdc\x2c\x9d\xc3\x8f\xd1\x44\xa4\x0b\x58\x4c\xd5\x5d\x9a\x4d\xd6\xbf\x61\x76\x39\x5e\x6c\x45\xcd\xa8\x16\x27\x5d\x4c\xa4\xbc\x11\x82\x1f\x48\x68\x0e\x17\x9c\x78\x22\x73\x0d\x0e\xe8\xbf\x0c\xcd\xd1\x42\x24\xcf\x9c\x23\x28\xbd\xa9\x37\x5e\x30\xc0\xac\x16\x24\x69\x32\x80\x5b\x90\x8d\xf4\x8f\x83\xe1\xbb\xad\x10\x95\xe1\x23\xe8\xc2\x00\x24\xdd\x87\x69\xe2\x84\xe0\xe6\x5d\xe1\xa8\x3b\xaf\x34\x42\x56\xe3\x1f\x37\xca\x12\x92\x96\x25\x90\x87\x51\xc9\x35\x6b\xc9\xf9\xb6\x3c\x8b\xe8\x11\xca\xb9\x8f\xd0\x55\x33\x63\x28\xd0\x6f\x40\xf1\x8d\xbc\x6f\x06\xe7\x60\xb0\x8b\xd1\x8d\xde\x42\x62\xd0\x5f\xf3\x61\xe4\x26\x8d\xe7\xdc\x2e\x77\x8f\x96\x1b\xd6\x2a\x15\xff\xa5\x07\xab\xcb\xd3\x4f\xde\x18\x4c\xdd\x1e\x46\xb6\xa1\x75\x01\x97\x64\xaf\x67\xf4\xa6\x1d\x2f\x60\x20\xb6\xd5\x57\xc9\x78\x50\xdc\x5c\x7b\xcb\xf8\x44\x1c\x57\xf0\x1b\x74\x55\x45\xd0\x3a\xf9\x66\x2f\x04\x6c\xe4\x16\xf9\x63\xb3\x62\xe5\x79\x36\x5e\xb0\x0d\x0e\xfc\xb3\x7a\x22\x5a\x79\x8d\xc6\xb8\xd7\x7e\x13\xb6\xe3\x89\xd4\xf3\xe1\xa6\x88\x4a\x52\x93\x9d\xa9\x23\x7e\xc1\x41\x15\xc5\xcd\x71\x75\x6a\xae\x94\xdb\xc5\x1e\x7a\xd2\x81\x70\xb4\x08\x24\x85\x15\xb7\xec\xf1\xee\xbe\xe0\x55\x60\x1f\x2e\x83\xea\x6a\x74\x68\xb7\xc4\x70\x82\x72\xd4\x4d\x39\xb1\x79\xba\x79\xcf\x9e\x43\x85\x7a\x42\x3d\xf9\x80\xe9\x58\x57\x54\x8d\xd7\x10\x7b\x06\x3f\x44\xb4\xce\x25\x21\xba\x14\x2a\x4a\x59\x56\x4f\x82\xf9\xeb\x35\x36\xb2\x92\x7e\x12\xac\x24\x97\xbc\x6d\x18\x95\x4d\xf1\x8b\x72\xc2\xd0\x4b\x0b\x2f\xaa\x1f\x2c\x71\xb2\xb0\x5f\x0a\x45\xdd\xe9\xa0\x8c\x8b\xf4\x9d\xc4\x87\x74\x2e\x50\x9d\x8c\x78\x9e\x63\x99\x2c\x1b\x39\x50\xa7\xf1\xd5\xbd\x76\xc7\x0c\x3e\x58\x51\xa5\x06\xf6\xdf\x2f\xcf\xc6\x80\xf0\x2d\x12\x93\xd4\xf5\x8b\xab\x2c\x46\xfa\x91\xe8\x78\x00\x91\x03\x45\x63\x1a\xf4\xc8\x8c\x66\x27\xc0\x12\x58\x58\xbe\x1c\x31\xf7\xe5\x30\x86\x2f\xe8\xcc\x01\x1f\xa7\x09\xa4\x9e\xa2\xb4\xf9\x92\xa2\x75\x15\x7a\x3b\x34\xa7\x0b\xb8\x9d\x95\xd4\x37\x9f\xd1\x01\xab\x8c\x26\x7e\x3a\x3a\xb6\xbb\x83\x57\xa7\x88\x1b\xd7\x1e\xdd\x7e\xe1\x3e\x30\xbf\x48\x1f\x18\x63\x34\xd9\xd3\x12\xea\xc1\x47\xd7\x78\xfd\x0b\xba\xc3\x78\x1d\x61\xcf\x03\xc4\xfb\xe8\x3f\x76\x03\x4e\xc1\xc7\x10\x0e\xc2\x36\x8d\x8d\x52\x43\xe2\x08\x89\xd7\x8d\x37\xf7\xef\x97\xbb\x14\x19\xc0\x8d\xad\xaf\x94\x1b\x44\x10\x33\xb1\x96\xe9\x7a\x65\x0e\x94\xe6\x36\xe2\x46\x0b\xba\x06\xb3\xe6\x42\xbe\xe2\x22\x7e\x5b\xaa\xe5\x68\x2e\x96\x57\x4b\xb9\x34\x7a\x53\x5d\x35\x30\x2a\xad\x1b\xc4\x86\xab\x08\x4a\x33\xe4\x4a\x61\x48\x4d\x58\xad\x5a\x3d\x0a\xe8\xa6\x37\x01\x53\xaa\x9b\xea\x20\x3b\x6a\x2b\x19\x52\x60\xea\x91\xab\xa8\x0a\xb2\xdb\x6a\x31\x14\x5a\xa2\x3c\x7d\x2c\xf3\x45\xe3\xd0\xe8\x40\x3d\x6e\x0a\x5d\x99\x13\x16\xec\x40\x7f\xec\x3b\xf4\x2b\xa4\x75\x5f\x9e\x1c\xfd\xd3\xda\x8e\x82\xe9\xb5\x43\x59\xaf\xff\x0c\xd6\xa5\x13\x74\xce\xdc\x95\xce\xbb\xdc\x88\x64\x3e\x2c\xb3\x23\x29\x3c\x8e\x8b\xcb\x39\x24\x4d\x13\xe1\xc0\x0c\x74\x4e\x20\x31\x8f\x4a\xb5\xc8\xa1\xb8\x4f\xbe\x97\x65\xfc\x65\xe1\x18\xb3\x29\x47\xc2\xd1\x56\xf8\x18\xfb\x96\x24\x55\x1e\x63\xac\x8c\xc0\x76\xc0\x13\x30\x47\xef\x8b\xd9\x1c\xa3\x96\x9d\x65\x8b\x68\x52\xbc\x83\x8b\x17\x4c\x72\x32\x31\x3c\x10\x29\x55\x17\xc2\xe0\xc3\x1e\x8d\x47\x8b\xc5\xb8\x78\xc0\x03\x5c\x6e\xda\x40\x8b\xd9\x8c\xc9\xec\x7c\x5a\xcc\x27\xf7\x16\x51\x36\x1c\x42\xd8\x33\xa8\xcb\x91\xba\x37\x87\x38\x64\x97\x0c\xad\xeb\xba\xaa\xd8\xbf\x5c\x30\x19\x85\x95\x64\x8d\xd1\x62\xae\x02\x2a\x8c\x58\x7b\xe2\x12\x37\xcf\xeb\x3a\x02\xf9\x0c\x97\xa7\x32\xcb\x7e\x65\x1a\xca\x38\x41\x0e\x79\xe7\x94\xb9\xcc\x79\x6a\x8e\xdf\xba\x49\xdb\xca\x37\x29\xcf\x4c\x37\xe8\xe0\x06\x43\x7b\x6f\xe3\xae\xe1\x7d\xd9\x6e\x61\x24\x76\x68\xb0\x7a\x15\xc1\x7d\xda\x4f\xaa\x5d\xf8\xf6\xda\xd7\x71\x9e\xbd\x86\x23\xf7\xda\x33\x08\xa9\xcc\x83\x05\x40\xe4\xc1\x67\xaf\xc5\x51\x00\xfb\x4d\x24\x91\x7b\xf6\x3a\xe6\x95\x1a\x76\xa5\xb4\xbc\x52\xc2\x2b\xc5\x76\xa5\x46\x79\xa5\x94\x57\x4a\xec\x4a\xb1\xaa\x64\xd7\xa1\xb2\x23\x79\x24\x93\xb7\x0c\x43\x44\xdb\x55\x44\xdb\xa5\x89\xe6\xe3\x63\x90\x4b\xf4\x89\x1f\x98\x40\x18\x54\x9e\x76\x90\x23\xcd\x83\xac\x42\x78\xd4\xe1\x90\xea\xaf\x3f\x10\xa9\x0d\x39\x26\x21\x27\x95\x20\x37\x82\x84\x37\x60\x38\x90\xd3\x4a\x90\xe3\xd0\xe8\xd4\x0d\x18\x0e\xe4\x86\x03\x79\xf9\x40\xee\x66\xb3\xd9\x35\x58\x8f\x09\x13\xd9\xdb\x3e\xee\x3b\x08\x4b\xc6\x82\x0f\x3e\x88\x9e\xf9\xf5\x7c\x51\x9c\x33\x65\xf2\x92\x69\x93\xa3\x73\x77\xec\x57\x0c\xca\x0b\x91\xb5\x4e\x60\xf6\x85\xe3\xc7\x12\x11\x6f\x9f\x4f\xf3\xd1\xf0\x9a\x4b\x42\xce\x87\x15\xb0\xe8\x86\xb1\xd8\x7d\xcd\x2f\x0e\xfc\xf4\x1a\x53\x5e\x62\xb4\x15\x2f\x53\x1c\x95\x24\xf7\x07\x26\x80\x17\x97\x17\xf6\x8b\x92\x1b\x1d\xcb\x37\xfb\x0c\x24\x5e\xed\x28\xdb\xe1\x1f\xfc\xf0\x16\x92\xc9\x1d\xfc\xe0\xa7\x46\x33\x8a\xc4\xbc\x48\x4c\x46\x33\x36\xa7\x34\x0e\xe5\xfc\xb2\xff\xbe\x00\x55\x21\xb4\xf5\x87\x16\x6b\xb5\x1a\xb6\xcd\xa3\x1f\x33\x0e\xc4\x5f\xa1\xe8\xc7\x66\x71\x11\xe6\x58\x95\xd7\xc7\xa1\x74\x98\x63\xd5\x6c\xa2\x9a\x8d\xad\x66\xe3\x65\xcd\xc6\x76\xb3\xf1\x6a\xcd\x62\x18\x9d\x51\x43\x4e\x41\x00\xc2\x50\xb1\x66\x60\xa8\x6a\x8a\x55\x13\x39\x99\xb1\x6a\xc3\x9e\xa6\x81\x11\x11\x6c\x5d\x26\x5a\xe1\xb3\x0b\xd3\x1e\xf6\xf5\x6e\x6c\x7f\xfe\x32\xe6\x2f\x63\xf2\x65\xc2\x5f\x26\xe4\xcb\x94\xbf\x4c\xc9\x97\xcd\xb2\x36\x5b\x65\x6d\xb6\xcb\xda\xec\xa8\x36\x4b\x2c\x52\x95\x24\x0f\x7c\x56\x94\x3e\xf0\xa9\x20\x81\xe0\xf3\xd1\x15\x06\xda\xc4\x70\x2b\xc9\x5d\x83\xc6\x6b\x43\xfb\xa8\x24\x99\xed\x22\x74\xef\xc2\xcb\x3b\x12\x57\xdf\x32\x43\x29\x64\x97\x3e\xe1\xa1\x86\x7e\x42\x26\x8c\x6a\x3f\xc1\xd8\xcb\x59\x82\xbf\xd5\xda\xfb\xc8\xad\x18\xf3\x8a\x89\xa8\xd8\x71\x2a\xb6\x82\x15\x13\x5e\xb1\x29\x2a\xc6\x4e\xc5\x4e\xb0\x62\xca\x2b\xb6\xdf\x28\xd4\xac\x8a\xb1\xae\x78\xa3\x55\xac\x2c\x4a\x3d\x47\x44\xc6\x8e\x3f\x11\x29\xd9\x45\xf0\x78\xfc\xb9\x4e\xf4\x78\x09\x47\x08\x38\x05\x87\x8a\x1f\x4f\xe2\x4b\x5e\xc2\x83\xcf\x73\xf7\x36\xdd\x49\xf9\x2d\x3a\x93\xfb\x95\x1f\x8f\x3e\xb9\xd5\x2f\x47\xef\xf9\x9b\x76\x93\xbd\x70\xcd\x72\x6a\x9a\x28\x86\xad\x85\x1c\x7c\x1c\x17\x7c\x6b\x7e\x58\x6f\x0c\x15\xd4\x71\xf8\x39\xc9\xc0\x79\x74\x9c\x07\x45\xed\x0a\xfa\xc3\xee\x5b\x3e\xb8\xbb\x6e\xbc\x43\xab\xc5\xdd\x6c\x3c\xb8\x1c\xc3\x0c\x83\x5c\xd4\xa1\x66\x77\x45\x22\x98\x5d\x9e\x08\xa6\x71\xd5\xcc\x53\xfc\x1f\x02\x9d\x73\x0d\xcd\xcd\xd7\xb2\x2b\xf2\xc2\xec\xf2\xbc\x30\x8d\x2b\x51\x23\xc5\x98\xf2\xbb\x52\x41\x65\x0a\xe3\x77\xac\xd0\x5b\xe3\xf7\x7f\x45\x71\xb4\xc5\xbe\xf8\x10\x13\x01\x31\xe1\x10\x05\xc0\xa6\x80\x18\x3b\x10\xe3\x0a\x10\x53\x01\x31\xf5\xba\x55\xe3\xed\x58\x10\x93\x0a\x10\x9b\x02\x62\x93\xec\x75\xea\x40\x4c\x2b\x40\x6c\x09\x88\x2d\xb2\xd7\x4d\x07\x62\xb3\x02\xc4\xb6\x80\xd8\x26\x7b\xdd\x72\x20\xb6\x2a\x40\xec\x08\x88\x1d\xb2\xd7\x6d\x07\x62\x7b\x29\x44\xad\xf6\x73\xa0\x56\xf5\x8e\x5b\xdd\xbd\x1d\xa3\x78\x1a\x56\x9f\x77\x0f\x6e\x30\x89\xa0\xd4\xbb\x2b\x94\xd5\x23\xe8\xda\x2e\x91\x84\x03\x8d\x84\x13\xb6\x30\x5d\x0e\x16\xd1\xd9\xe8\xdd\x59\x94\x4d\xf2\x08\x82\x10\x66\xb3\x77\x97\x18\xfe\x05\xaf\x39\xff\xcf\x65\x36\xf3\x12\xf7\x60\x03\x90\x85\x88\xb5\x22\xb5\x38\xc2\x78\xf0\xae\xcf\x8b\xf0\x55\x82\xdc\x3e\xc9\x3e\x5b\x18\xcc\x8a\xf9\xe5\x78\x01\x2e\x31\x25\xcd\x9f\xf1\x25\xa0\xc6\xf0\xf8\x96\x21\xc3\xaf\xae\xc4\x1d\x48\x49\xc0\x1f\xf5\xc5\xa3\x16\x3e\xea\xe3\x23\x0a\xc9\x31\x07\x64\x74\x85\x6f\x09\x59\xf9\x2b\x1c\xe1\x0d\x64\x08\x59\x40\xa9\x9d\x46\x01\x2a\x11\x0c\x74\x08\xce\xac\x30\x9c\xa4\xf9\xf2\x7b\x2e\xe1\xd8\xeb\x9f\x59\xe3\x55\x85\x1c\x6d\x54\xf9\x49\x88\xb8\xef\xb9\x88\xab\xb1\x2f\x1d\x73\xe3\xfb\xbd\xa1\x16\x6c\x88\x02\x6d\xbb\x40\x5b\x17\x70\xf9\xf9\x27\x21\x0d\xbf\xe7\xd2\xb0\xc6\x9b\xd1\xeb\xed\xf7\x52\xfe\xe1\x7a\x7b\x3f\x82\xd6\x7c\x18\x89\x80\x91\x48\x18\xb1\x8d\x40\xec\x61\xd8\xb0\x0b\x34\xca\x30\x4c\x05\xf4\x54\x42\x4f\x6c\x0c\x13\x07\xc3\x98\xc0\xb0\x29\x60\x34\x25\x8c\xd4\x46\x20\xf5\x30\x4c\xec\x02\x49\x19\x86\x2d\x01\xbd\x25\xa1\x37\x6d\x0c\x9b\x0e\x86\x29\x81\x61\x5b\xc0\x68\x4b\x18\x2d\x1b\x81\x96\x87\x61\xd3\x2e\xd0\x2c\xc3\xb0\x23\xa0\x77\xde\x58\x2c\xa2\x30\x6c\x3b\x18\xb6\x2c\x0c\x2b\x25\xfe\x98\xcb\xa4\x13\xca\xd6\x5a\x21\xed\xc4\xb2\xe3\x2e\x0e\x6b\x01\xe1\xc4\x8d\x73\x27\xd3\x92\x2a\x43\x29\x58\x69\x1c\xf8\x69\x91\x7f\x7e\x75\x31\xce\x00\x1b\x06\x2d\x08\x4e\xc4\x99\xa9\xe9\x96\x29\x88\xea\xe0\xaa\xcc\xa8\x6b\x27\xef\x30\x4b\x6e\x94\x9d\x41\x99\x05\x2b\x3b\x23\xd7\xed\xb3\x91\xad\x56\xb3\xae\x0f\x45\xb6\x5a\xed\xba\x38\x2b\xd9\x6a\xc7\x70\x5e\xf5\xc7\x8e\x44\x78\x77\x5e\x75\x77\x5e\xf5\xc9\xce\xab\x9c\x29\xae\xcf\x73\xdc\x93\x9c\x3f\xd6\x19\xce\x6d\x65\x85\x7b\xa6\xb6\xe6\xcf\xec\xad\xf9\xb3\x75\xb7\xe6\xcf\xec\xad\xf9\xb3\xb2\xad\xf9\x32\x03\xf3\xdd\x49\xd5\xdd\x49\xd5\xdd\x49\x95\xf5\xe6\xee\xa4\xea\xee\xa4\xea\xee\xa4\x4a\x37\x7b\x77\x52\xe5\xbe\xbc\x3b\xa9\x0a\xfc\xbc\x3b\xa9\xba\x3b\xa9\xba\x3b\xa9\xc2\xcf\xdd\x49\x95\xa6\xcb\xdd\x49\x95\x01\xf1\xee\xa4\xea\xee\xa4\xea\xee\xa4\xea\xee\xa4\xea\xee\xa4\xea\xee\xa4\xea\xee\xa4\x8a\x3e\xa9\xba\xb5\x33\xaa\xf5\x4e\xa7\xaa\x9c\x4b\x55\x38\x91\xfa\x54\x67\x51\x7f\xec\x7c\x28\x77\x67\x51\x7f\xfe\xb3\x28\xf3\xec\x68\xb7\xb9\xf4\xa2\x93\x79\x72\xc4\x8a\xeb\x63\x23\xfc\xf1\xe9\xcf\x8c\xf8\x2d\x4d\x75\x6a\x44\xe8\x19\xbf\xea\xb8\x05\x65\xc7\x4a\x78\x8d\xd3\xbc\x51\x6c\xc4\x4c\xf7\xed\x15\x10\x13\x62\xde\x9f\x5e\xf9\x70\x4e\x14\x3a\x27\xe6\x31\x9d\xfc\x50\xda\x64\xd2\x6a\x87\x37\xe5\x62\xd3\x3d\x5a\x6e\xc6\x85\x5b\xc9\x84\x1d\xd7\x6e\xb1\x2e\xef\x8f\x2f\x6d\x58\x10\x05\x48\x20\xa3\x4a\x40\xc0\x32\x83\x4e\x01\xd3\xa1\xa8\x04\x4b\x9b\xa4\xfd\x6b\x0b\x90\x1f\x09\xcd\x7a\xed\x05\x45\xa3\xf6\xfe\xd0\x8b\xda\x2f\x6c\x55\x3c\xe1\xaa\xb6\x80\xb0\xc1\x6a\x43\xaf\x02\xb1\x14\x3e\x64\x17\x34\xce\xb8\x6e\xf8\x16\x02\x63\x00\x4e\xe8\x8b\xf1\xf0\x18\x46\x7c\xf9\xf0\xec\x92\x26\x7e\x91\x55\x43\x31\x0d\x1f\x9d\xdb\x34\xcb\x49\x4b\x08\x5a\x41\xbc\xfc\xeb\x30\x3c\xb4\xc9\xd0\x6a\x59\x5d\x72\x66\xc4\x0d\x18\x44\x1a\x41\x63\x08\xd9\x68\x55\xc3\x88\x31\x9f\x1c\xc3\x88\x26\xda\xc8\x79\xf3\x8b\x26\xa7\xb3\xd9\xf3\x13\xae\xa0\x71\xd0\xae\xfe\xab\x89\xa7\x33\x1e\x6a\x36\x4d\xf8\x34\x9a\x70\x93\xda\x84\x98\x44\x23\xb4\xa0\xf3\x84\x71\x82\x2b\xad\xf7\x16\x0b\x07\x18\x97\x64\xda\x12\x86\x0d\x33\xab\x66\x54\xfb\xb9\xdd\xd9\xdf\xa0\x6e\x4d\xcc\x29\xa8\x22\xf0\x7a\xa3\xf3\x1a\x4c\xdc\x3c\x06\xce\xf6\xe9\x15\xc6\x41\x21\x4e\xb4\x34\xab\x9b\xe3\xa5\xd9\xde\x1a\x2b\xa1\x96\x59\xd8\x7d\xa9\x7a\xef\x6e\xf3\x36\x94\x5e\x06\x65\x55\x8d\xd7\x5f\x63\x1d\x75\x77\xb7\x49\xc6\xb6\xc0\x13\x9a\x51\x91\xaf\xb1\xc2\x3f\x99\x4d\x2f\xac\x55\x5e\x3c\x40\x22\x7c\x86\xa8\x78\x39\x34\x67\x07\x9a\x73\xec\xfc\x30\xf0\x50\x4a\x85\x9a\x8b\xe2\x5e\x62\x82\x75\x63\xcd\x01\xea\x46\xd4\x2f\x67\x16\x03\xa8\x2d\x1b\x84\x1d\x31\xae\x92\x12\x03\x6d\xc8\x82\xe5\x67\x18\x40\xcf\x92\x05\x5c\x39\xbe\x00\x5e\xb0\x8a\xff\x1d\x17\xf3\x07\x0f\xc8\x35\x7c\x05\x71\x8f\x1d\x33\x27\xb5\x23\xcd\xbf\xa0\xd9\x06\x24\xba\x9d\x19\x87\x90\x56\x9f\x75\x82\xe7\xcb\x67\x1e\x14\x2a\xdf\x69\xee\x36\x3f\xd5\x36\xf3\x66\xe9\xfa\x3e\xe7\xfe\xf2\x93\xed\x02\x3f\x6f\x10\x67\x10\x55\x6c\x6e\xcf\xde\x33\x76\xae\x0d\x36\xa2\xa4\x11\x27\x51\xff\x3a\xda\xfd\xff\xfe\x6f\x3e\x1b\x0d\xa2\xe7\xc5\x7c\x32\x1a\x6f\x46\x3b\xe3\x71\x34\x1b\xbd\x3b\x63\x44\x11\xe5\xf3\x4d\x06\xe1\xb8\xc8\x47\x10\xde\xa7\x7f\x89\xf0\xc1\x1e\x0b\x41\x79\x46\x93\x68\x3e\xbd\x9c\x0d\x0a\x7c\xd2\x1f\x4d\xb2\xd9\x35\x88\x83\x73\x86\x0c\x8f\xd2\x30\xc3\x7f\xa7\x6c\x2a\x9e\xa3\x4c\x1f\xa0\x64\xad\x47\xd9\xac\x80\x90\x9b\xe7\xa3\x05\x04\x97\x60\x62\xf4\xfd\x28\x87\x28\x13\x10\x74\x02\xe6\xe9\x70\x3a\x1e\x4f\x3f\x40\x0c\xe9\xc1\x74\x92\x8f\xf8\x1c\x86\x4a\xe7\x4c\x90\x89\x19\xff\x20\xb2\xd1\x9a\xa3\x61\x98\xe3\x33\x98\xe6\xac\xec\xe5\x7c\x01\x0b\x35\x9b\x5c\x08\x34\xeb\x43\xdc\xfd\xc1\xf4\xe2\x1a\xbb\x18\x4d\xa6\x8b\xd1\x80\xcd\x4c\x8c\x2b\x34\x66\x90\x00\x80\xd9\xde\x24\x77\x90\x61\xcd\x0d\xd8\x5c\x3d\x67\xf3\x29\x84\x03\x6b\xcb\x20\x84\xc4\x81\xf5\x30\xbf\x1c\x14\xb7\x8e\x46\x24\xba\x96\x4f\x07\x97\x2a\x0e\x06\xd4\x78\xc8\x48\xcf\x63\x64\x9c\xb3\x59\x3f\x1b\x65\xe3\xb9\x26\x33\x8e\x0d\x56\x33\x50\x87\x71\x3e\xdd\x3f\x38\x89\x4e\x8e\x9e\x9e\xfe\xb8\x73\xbc\x17\xb1\xef\x2f\x8f\x8f\x7e\x38\x78\xb2\xf7\x24\x7a\xfc\x8f\xe8\x74\x7f\x2f\xda\x3d\x7a\xf9\x8f\xe3\x83\xef\xf7\x4f\xa3\xfd\xa3\xc3\x27\x7b\xc7\x27\xd1\xce\x8b\x27\xec\xe9\x8b\xd3\xe3\x83\xc7\xaf\x4e\x8f\xd8\x83\xbf\xec\x9c\xb0\x9a\x7f\xc1\x17\x3b\x2f\xfe\x11\xed\xfd\xf4\xf2\x78\xef\xe4\x24\x3a\x3a\x8e\x0e\x9e\xbf\x3c\x3c\x60\xc0\x18\xf4\xe3\x9d\x17\xa7\x07\x7b\x27\xf5\xe8\xe0\xc5\xee\xe1\xab\x27\x07\x2f\xbe\xaf\x47\x0c\x40\xf4\xe2\xe8\x34\x3a\x3c\x78\x7e\x70\xca\x8a\x9d\x1e\xd5\xb1\x51\xbf\x5a\x74\xf4\x34\x7a\xbe\x77\xbc\xbb\xcf\x7e\xee\x3c\x3e\x38\x3c\x38\xfd\x07\xb6\xf7\xf4\xe0\xf4\x05\xb4\xf5\x94\x35\xb6\x13\xbd\xdc\x39\x3e\x3d\xd8\x7d\x75\xb8\x73\x1c\xbd\x7c\x75\xfc\xf2\xe8\x64\x2f\x82\x6e\x3d\x39\x38\xd9\x3d\xdc\x39\x78\xbe\xf7\x64\x93\xb5\xce\x5a\x8c\xf6\x7e\xd8\x7b\x71\x0a\x21\x59\x0f\x0f\xc9\x5e\x02\xee\x56\x1f\x1f\xef\x31\x24\x77\x1e\x1f\xee\xf1\x96\x58\x2f\x9f\x1c\x1c\xef\xed\x9e\x42\x77\xf4\xb7\x5d\x46\x39\x86\xdf\x61\x3d\x3a\x79\xb9\xb7\x7b\x00\x5f\xf6\x7e\xda\x63\x9d\xd9\x39\xfe\x47\x5d\xc0\x3c\xd9\xfb\xdf\xaf\x58\x21\xf6\x32\x7a\xb2\xf3\x7c\xe7\x7b\xd6\xb7\xda\x12\x92\xb0\x31\xd9\x7d\x75\xbc\xf7\x1c\x70\x66\x74\x38\x79\xf5\xf8\xe4\xf4\xe0\xf4\xd5\xe9\x5e\xf4\xfd\xd1\xd1\x13\x24\xf4\xc9\xde\xf1\x0f\x07\xbb\x7b\x27\x8f\xa2\xc3\xa3\x13\xa4\xd6\xab\x93\xbd\x3a\x6b\xe1\x74\x07\x1b\x66\x20\x18\xa9\xd8\x6b\xf6\xfd\xf1\xab\x93\x03\x24\xda\xc1\x8b\xd3\xbd\xe3\xe3\x57\x2f\x4f\x0f\x8e\x5e\x6c\xb0\x9e\xff\xc8\xc8\xc2\x70\xdc\x61\x55\x9f\x20\x75\x8f\x5e\x60\x57\x19\x85\x8e\x8e\xff\x01\x40\x81\x06\x48\xfc\x7a\xf4\xe3\xfe\x1e\x7b\x7e\x0c\x04\x45\x4a\xed\x00\x09\x4e\x18\xc5\x76\x4f\xcd\x62\xac\x3d\x46\xc0\x53\xa3\x8f\xd1\x8b\xbd\xef\x0f\x0f\xbe\xdf\x7b\xb1\xbb\x07\x6f\x8f\x00\xca\x8f\x07\x27\x7b\x1b\x6c\xac\x0e\x4e\xa0\xc0\x01\x6f\xf6\xc7\x1d\xd6\xe6\x2b\xec\x32\x8c\x11\xc3\x8a\x7f\x35\x38\xb6\x8e\x23\x19\x1d\x3c\x8d\x76\x9e\xfc\x70\x00\x68\x8b\xc2\x6c\xec\x4f\x0e\x04\x9f\x20\xc9\x76\xf7\x05\xb9\xd9\x3c\x66\x4b\xa7\x65\xf3\x7a\x9e\x2d\xce\x6e\xd7\xee\x55\x2d\xea\x34\x0f\x7c\xac\x8a\xf0\x9f\x95\xbc\xb3\xf1\xc0\x2e\x9b\x30\xa1\xbd\x00\x27\x6d\x5d\xe5\xed\x3f\xe1\x10\xcb\x0f\xb6\xa9\xf5\xa8\x46\x3d\x8a\x62\xf6\x27\x61\x7f\x52\xf6\xa7\xc9\xfe\xb4\xd8\x9f\x36\xfb\xd3\x61\x7f\xba\xec\x4f\xaf\x1e\xc5\xac\x5c\xcc\xca\xc5\xac\x5c\xcc\xca\xc5\xac\x5c\xdc\x32\x3c\x2c\x3b\xbc\x2e\xbc\x03\x78\x50\x1e\x60\xc4\x2d\x0e\x17\xea\x61\x5b\x3d\x01\x3f\x11\x30\x62\x6c\x43\xc3\x49\x45\x5b\x4d\x81\x4b\x4f\xc0\xe8\x1a\x78\x76\x04\xac\xb6\xc0\x25\xe6\x30\x63\x33\xd6\x72\x2c\xea\x4a\x5c\x1a\x1c\x06\xe2\x21\xf1\x4c\x39\x2c\x80\x1f\x9b\xfd\x36\xe1\x34\x45\xdd\x96\xc0\xbd\x23\x60\x24\x06\x9e\xb1\x80\xd5\x15\xb8\x88\x7e\xc7\xe9\x9b\x8d\x47\xe6\x58\xcc\x96\x8c\x85\xc4\x43\xf6\xaf\x27\xda\x89\x0d\x9c\xdb\x36\x3d\xb0\x6f\xa9\xd3\xf7\xb6\xa8\x93\x6a\x58\x58\xb7\xa5\x71\x96\x30\x24\x3d\xb0\xad\xd8\xe9\x3b\x16\x6a\x19\x1d\xec\x08\x04\xdb\x9a\xb8\x0a\x48\x62\x10\x9a\x23\xab\x01\x75\x45\x1d\x83\x58\x38\x30\x2d\x4d\x5c\x05\x23\x35\x08\xcd\x91\x35\x10\x4a\x04\x65\x1b\x06\x30\x49\x8d\x8e\x1a\x3d\xc5\xa1\x91\xa0\x0e\x47\xd6\x1e\x8d\xf9\xb2\x99\xc1\x51\x14\xb4\x42\xf4\xcc\x96\x24\x6f\xa5\x06\x3d\xdb\xfa\x9d\xc5\xd3\xec\x19\xbc\x42\x52\x49\x7e\x95\x5d\x6c\x69\x9e\x8a\x5b\x06\x59\x3b\xa2\xac\x35\x1e\xb1\x66\x02\x35\x16\x3d\x51\x50\x32\x4f\xd7\x28\x23\x11\xef\xe0\x77\x73\x2f\xa5\xe6\x72\x53\x57\x95\xed\xab\x39\x6f\xce\x89\xae\x05\x52\x83\x92\xf3\xb3\xa5\x79\x5f\xf5\x33\xd1\x28\x28\x3a\x09\x96\xe1\x70\x23\x67\x48\x96\x4d\x10\x81\x98\x45\xfc\x96\x46\x00\xfb\xd9\xd1\x13\x11\x1b\x6c\x0a\x44\xda\x0e\xd2\xa9\x4d\x7c\xd5\xe9\x58\xc3\x51\xb4\x53\x13\x1a\xdf\x5b\x70\x94\x00\x89\x0d\x22\xb5\x75\xbb\x6a\xe2\x89\x09\x1c\xcb\x49\xd9\xa2\x26\x9a\x83\xb8\x04\xa4\x26\x5c\x62\xfc\x2b\x3b\xde\x75\x26\x5a\x8b\x28\x27\x61\xb8\xcc\xa1\x3b\xc5\xfe\x44\x3a\xf0\x32\x0e\xc7\x19\xcc\x10\x62\x3c\x22\x15\xaa\x99\x4d\xaf\xc6\x55\x6b\xa7\x9b\x74\x7a\xbd\x1e\x7c\x6f\xef\x3d\xe9\xed\x3d\xde\x89\xe1\x7b\xf7\x69\xfc\xf8\xf1\xee\x93\x5d\xf8\xbe\xd3\x6b\xa5\x4f\x9f\x34\xf7\xec\xf1\x3e\x9b\x05\x1b\x68\x35\x76\x92\xee\xe3\xbd\x36\x36\xc0\xf6\xaa\x4f\xe2\xa4\x89\x0d\x3c\xe9\x34\xd2\xbd\xa7\x29\x7c\xef\xec\xb0\x5f\xed\x3d\x6c\x58\x22\xf4\x86\xb4\x07\x1c\x1f\xbc\xdc\x7b\xfe\x24\x6e\x37\x30\xfc\xfe\x12\x1b\x92\x2a\xab\xad\x48\xc6\x23\xbe\x2a\xaf\x7b\x56\xc4\x8d\x89\x88\x04\xc9\xea\xac\x83\x9d\x66\x2b\x49\x1b\x48\xc1\xbd\xa7\xbb\x4f\x76\x1e\x77\xb1\x83\xbd\xee\xe3\x9d\x27\xbb\x4f\xf7\xe0\x7b\xdc\x48\x93\x56\xb3\x83\xc4\xd9\x4d\x9f\x24\x7b\xf1\xd3\xc6\x9b\xa0\x69\xbc\xaa\x51\x9e\x34\xec\x56\xbe\xa5\x14\x97\x9c\xd4\x2c\x77\xc7\xe7\x58\xa0\xed\x55\xbb\x45\x06\x8e\x6f\x9e\xbf\x35\x4a\xcb\xc3\x83\xb7\xbe\x23\x93\xd1\x0b\xff\x85\x51\x0f\xac\xdc\x7e\x01\xf8\xd4\x6a\x35\xa3\x31\xed\xf8\x60\x3c\x5c\xcd\xa9\xd4\x03\x28\xfc\x4a\x1d\x80\xbe\x77\xa9\x0f\xae\xc4\x34\xe6\x90\xdc\xb7\xcd\xef\x47\xea\xfc\x01\x58\x31\x78\xe4\x88\x02\x80\x89\x81\x70\x81\x19\x16\x98\x05\x0b\xa0\xfa\xc9\x94\xd0\x70\x01\x84\xf0\xcf\x30\x04\x5c\xa6\xd9\x62\x1d\x2e\x80\x10\xe6\x33\x3a\xa2\x35\xa3\x03\x9b\x65\xbf\xc2\xa6\x99\x15\x1e\x81\x76\x4c\x1c\xd2\x66\x63\xc8\x46\x55\x8f\x06\xec\x4f\xce\xfe\x14\x63\xa2\xa1\x6c\xc6\x0a\xb1\x3f\x03\xf6\x27\x67\x7f\x8a\x99\xdb\x58\x06\xa8\x64\x80\xf0\xbe\x7f\x65\xa4\x0f\x2f\xfb\xfc\x65\xec\xbe\x1c\xc0\xcb\x01\x7f\x99\xb8\x2f\x73\x78\x99\xf3\x97\xa9\xfb\x12\x37\x0c\x05\x7f\xd9\x74\x5f\xaa\x34\xd5\x99\x9d\x97\x5a\x76\xc9\x3d\x15\x24\x5d\x09\xf1\xdf\xfb\xdb\x70\x4a\x44\xcc\x6d\xb0\x7d\xd6\x58\x9f\xef\xeb\x39\x75\xff\x9f\xe3\xd7\xa3\x37\x6f\x36\x7e\xa3\x2e\x31\xe0\xad\x9d\xef\xe2\xf6\x06\x58\xba\x6c\x40\xac\x8d\x68\x18\xd7\xd8\x40\xb0\x71\xc8\xc7\xe0\x52\x74\x36\xa6\xef\xde\x7c\x8c\x94\x41\x6e\xf4\x5d\x9a\x70\x53\x1b\x01\x2d\x71\xa1\x79\x94\xf7\xa0\x35\xbb\x41\x68\xa9\x0b\xcd\x1b\x2a\x0f\x5a\xbb\x19\x84\xd6\x74\xa1\x79\x63\x6b\x40\xfb\x17\x1b\x4a\x0e\xb1\xdb\x08\x42\x6c\xb9\x10\x3d\x86\x40\x88\xf4\x20\x2e\xc8\xe1\x82\x37\xd1\x6c\xba\x18\xd7\x16\xf5\x39\x0c\x2b\x75\x69\x03\x79\x60\x71\xbf\x18\xd3\x43\x8e\x33\xc2\x9b\x52\xf0\x41\xbe\xcd\xa9\x37\xc8\xee\xd8\x2e\xcc\xc9\x98\xbc\x06\x84\xf3\xa5\x4f\xd5\xc6\x69\xe6\x9d\x24\x2a\x54\x19\x83\xdf\x37\xb8\x75\xb6\x3e\xb7\x32\x8a\xcf\xea\x4c\x20\xe4\x33\xa4\xf8\xec\x66\xdc\xda\x74\xa1\xdd\x94\x5b\x6d\x68\x37\xe2\xd6\xc4\x85\x76\x63\x6e\x8d\x5d\x88\xb7\xcc\xad\x33\x3c\xb5\x2e\x61\xd7\x59\x80\x5d\x51\xa2\xce\x28\x76\x45\x41\x4c\xbd\x41\x11\xcd\xd9\x75\x16\x64\x57\x5c\x00\xa8\xda\xb8\x34\xf8\x1e\x1a\xae\x28\x3f\x30\xd3\x31\xa0\x0e\x89\x5e\xbf\x8e\x86\x09\x7f\xb1\xfe\xed\x73\xd7\xdc\x01\x48\xe6\x9c\xe8\xe9\xbe\x70\xe1\xdd\xe7\xee\xb7\x39\x94\xa3\x28\xb2\x2f\xdc\x74\xf7\xb9\x23\x6d\x01\xe5\x32\xb2\x5c\x2a\xca\xa1\xb3\x2c\xae\x08\x7d\xb2\x5c\x53\x94\x43\xc7\xe4\x3e\x94\x1b\x90\xe5\xd0\x81\xd9\x22\x8b\xab\xd6\x3e\x15\xa9\x35\x6e\xe0\x9e\x05\x49\x0a\x95\x32\x04\x3f\x88\x85\x7f\x79\x1a\x46\x59\x72\xf2\x78\xb4\x98\x9f\x4e\x17\x28\xf1\x38\xcc\xc9\x13\x56\x83\x7b\x6d\x7d\x1b\x75\x09\xe8\x58\xe7\xb0\x18\x2e\x22\x37\x69\x23\x96\xf7\x3a\x03\xb9\x96\xbd\x2c\xc4\xf0\xd1\xb9\x1c\x35\x48\xd0\x26\x5b\x6f\xc0\x6b\x02\x13\x0b\x6b\x1f\x09\x55\xe2\xaf\x11\x13\x48\xae\xc2\xa1\x20\x31\x85\x55\x17\xbd\x1f\x81\x7c\x00\x90\xbd\x0d\x00\xd5\x84\x79\x1b\x37\x03\x0a\xb4\xac\xca\xc9\xa1\x95\x67\xe3\x61\x75\xe5\xd9\x03\x26\x14\x67\x07\xd8\x32\xc5\x99\xea\xa8\x99\xa7\x43\xe7\xc3\x94\x39\x76\xd0\x39\x86\x48\xdb\x01\x8e\xfc\x30\x03\x31\xbb\x0b\xbf\x90\x45\x3a\x4e\x5d\xf0\x9d\x97\x9f\xcd\xa5\x9c\x2d\x71\x76\x9b\x3a\x3a\xc1\x38\xfb\xac\x80\xa9\xbe\xdf\x6c\xff\xd6\xaa\xb4\x7d\xa3\x77\x64\xfb\xb8\x15\xdb\x27\x2e\x93\x18\x98\xf8\x2f\xf6\xd5\x75\xbd\x7d\x6b\x7b\xb5\xbf\xf2\xbe\x2a\x8a\x0c\x28\x82\x35\xf6\x83\x9b\xa9\xe5\xae\x70\xc7\xfc\x24\x9c\x0f\xae\xc8\x60\x91\x23\xb1\xed\xa2\xe2\xc4\xdc\x19\x20\x4b\x4c\x0d\xc6\xd3\x49\xb9\x80\x42\x57\x02\x28\xa5\xad\x0b\xf8\x33\xec\x06\xc1\x5f\xbf\xf5\x98\x84\xd7\xf3\x79\x4d\xa0\x89\x6f\x2d\xbf\x28\xfc\xfa\x91\x9f\xfe\xc3\x57\x85\x25\x5b\x9c\xaf\xea\xd1\x75\x3d\xfa\x27\x95\xe6\xa3\x56\xbb\xc2\x9b\x9d\xd7\xf8\xf7\x3f\x75\xb6\xf6\x8f\x1e\x9c\xa4\x1c\x0e\x03\xf4\x37\x06\x86\x5f\x27\xff\x37\xfc\x60\xd0\x54\x72\x10\x0f\x5a\xba\x14\x1a\x00\xfa\x37\x40\xd4\xa8\x05\x60\x35\x97\xc3\xfa\x1b\x42\x40\xdc\xae\x19\x6a\xff\x46\xe4\xc2\x10\x5b\x55\x68\x06\x44\xfb\x4d\x83\x0a\xc0\x42\x55\xe2\xaa\x3e\x21\x21\x5d\x7d\xf7\xdd\x04\xb1\xba\x62\xfc\x5d\x4b\x93\x07\x13\x13\x29\xfe\xa5\xd4\x1b\x46\x3a\xc3\xf0\x3c\x70\xd5\x9c\x61\x82\xd9\x7e\x84\x7f\x0b\xfa\x3c\xc9\xef\x0c\x3e\xf2\xa3\x0f\x26\x90\x9f\x27\x50\x7a\xdd\xc4\x3c\x62\x66\x68\x4f\x16\x69\xc0\xab\xdd\x13\x28\xde\x23\xbc\x70\xc2\xb5\x3e\x48\x6c\x29\x77\x1b\xdf\x38\xe8\xb8\xda\xc8\x02\xd5\x5c\x95\xe4\xe0\x40\xd2\xbe\xcf\x30\x34\x25\xd5\xc1\x75\x1d\xaa\xce\x0b\xd6\x9f\x45\x20\x77\x52\x60\x40\x31\xe5\xe0\x2d\x0e\x28\x4f\x64\xa8\x86\x66\x9f\xfd\xd6\xc3\x63\xba\x58\x11\x23\x64\x15\xf6\x47\x89\xbd\x25\x46\xea\xab\x8f\xfc\x1c\x38\xe0\x1a\x25\x4b\xd2\xd9\x89\xc0\xc5\xa9\x73\xe7\xe2\xf4\x67\xb8\xb2\xf2\xa9\x8f\xee\x9d\xc0\x6a\x46\xc3\x51\x28\x64\xda\xc9\xfe\xce\x83\xb8\xc2\x49\x86\x9f\xab\xfa\x36\xcf\x2f\xb6\xf1\xf6\xa9\x3e\xc2\x80\x88\x55\xd6\xf1\xa9\xf8\xd8\x47\x1a\xc5\x70\x90\x67\xfd\x6e\x8f\x88\xcd\x04\x67\x1c\xfd\x2c\x1f\x0c\x0b\xeb\x8c\x83\x2a\x38\x48\xf3\xa4\x88\x87\x0d\xfb\xdd\xcd\x8f\x40\x42\x1a\x3a\xad\x7c\xfb\x16\x74\x0f\x40\x15\xdb\x33\x6d\x2e\x86\x57\x7d\xda\x58\x8c\x0a\x23\x6d\x2a\xc6\xed\x2a\x6d\x28\x86\x57\x85\x32\x13\x7b\x98\x06\xed\xc4\x41\x9b\x70\x60\xb7\x80\x76\x9f\x88\xdf\x30\x25\x5e\xc3\xe7\x47\xae\xf8\xdb\x21\x30\xec\xbb\xa7\xf2\x23\x0d\x4a\x34\x20\x79\xcf\x81\x01\x64\x9b\x5a\x88\xab\x23\xbe\x76\xf5\xd7\xb8\x69\x7c\x6f\x87\x72\x47\x0a\x94\x6a\x13\xbc\x1c\xcb\xf7\x96\xb8\x7d\x48\x63\x3a\x4c\x0d\xbd\x13\x42\xd3\x52\x2d\x03\x20\x2d\x04\x92\xf1\x9d\x4c\x07\x2f\xc8\x16\xec\xcf\x8f\xf4\x25\x1e\x49\xb5\xa4\x11\xa4\x1a\x9a\xcd\x6a\xb5\x3e\x53\x4b\x07\x5c\xcf\x85\xaf\x39\x42\x6e\x5c\xb5\x32\x7e\x0a\xbb\xc4\xc4\x07\xb7\x41\x96\x35\xd1\x67\xf4\x1a\xb0\x3f\x39\x87\xdc\x2e\xf2\x5e\xd1\xcf\xa8\xa0\x43\x0e\xe4\xf6\x0a\xc8\x73\xdc\xe1\xdb\x40\xf4\xe2\x01\x1c\xe9\x36\x8a\x66\x33\x4d\x9a\xe1\xb6\x1e\x7e\xab\x9a\x03\xdb\xe2\xb7\x0f\x2b\xf7\x05\xe0\xa7\xad\x5e\x9e\x16\x89\x6b\xe5\x41\xf8\xc4\x90\xc2\x7c\xc9\x89\xb2\x30\xc5\x06\x94\x89\x2f\xc2\x26\xd9\xe8\xa7\x0d\xd1\x4b\x1c\x7e\x32\xb7\x28\x9c\x15\x51\xe6\x47\xd2\x16\xe8\xcd\xd7\x4a\xc6\x40\x61\x44\x93\xc6\xb6\xcc\xbd\xe1\xcd\xcb\x48\x43\x20\x1a\x0c\xbd\xc8\x27\xbc\x4c\x62\x1a\x0b\x07\x74\x99\xd4\x34\x14\xe6\x74\x99\xa6\x69\x24\x2c\xdc\x32\x77\x06\xbe\x2f\xd5\xc0\x07\xba\xf0\xe6\x70\x3c\x9d\xce\x4c\x9b\xdb\x43\x5c\xa8\xc5\xe7\x46\x8d\x60\x2c\x04\x0d\x79\x89\x9d\xce\x33\xd3\x7d\x22\x0b\xdd\x8a\x76\x20\xd2\x5c\xf7\x47\xb4\x06\xdd\x99\x10\x3c\x63\x00\xa8\xcf\x2b\x59\x0f\xb0\x42\x99\xe1\xc0\x56\xc8\x6d\x9b\x01\xbc\xbb\x33\x17\xdc\xaa\xb9\x00\xc7\xa3\x82\xa5\x80\x1e\x16\x6d\x24\x10\x43\x13\xbe\x36\x05\x05\xc2\x66\x81\xee\x1f\x3a\xc0\xc6\xfc\x2c\x4b\x5a\xed\x4f\x1d\x1b\x43\xb4\xf2\x9f\x63\x3e\xf0\xcc\x03\xe6\x1e\x9e\x91\xc2\xda\xc5\x1b\xb7\xb0\x5d\xab\x40\xc2\xd6\xfe\x4a\x76\x01\x28\x68\xc1\xc4\xdf\x1c\xf8\x67\xb5\x0d\x0c\xe2\x46\xab\x57\xe4\x5d\xd8\xf2\xa7\xed\xce\x20\x6f\x35\x3a\xf8\xbd\xd1\x69\xe4\x79\x8c\xdf\x87\x6c\x57\xd0\xea\xa5\xb4\xcd\x60\x38\x1c\x34\x1a\xfd\x14\x8d\x0b\xed\x6e\xab\x1b\xb7\x62\xfe\xbd\x39\xec\x75\x87\x19\x02\xe8\x17\xc3\xac\xc9\xfe\x5f\xc1\x5c\x50\x49\xf3\x34\xc4\xbe\x20\x9d\x51\xb3\xe4\x16\x2d\x4a\x54\xa5\xce\x3c\xd8\xf6\x95\x97\xc0\xc1\xd2\xdd\x12\xbd\x64\xc5\x65\x7c\xbc\xea\x22\x0d\x55\x96\x2c\xd3\xd6\xec\xf0\x16\x6a\xf6\xf6\x6e\xa9\xbe\xed\xa5\x1a\x46\xa5\xda\x62\x4d\x0e\x8e\xb5\x5c\xf3\x01\x2a\x5d\xb0\x93\xc4\xbd\xea\x6c\xdc\x6b\x16\xcb\xd1\x56\xa7\x07\x0b\x78\xef\xce\xae\xff\xfb\x2c\xcc\x7f\xbc\x6b\x79\x07\x3c\x89\xc3\xe8\x9f\xea\x56\x6e\x34\x9b\x32\x05\x0e\x2e\xf7\x9a\xf7\xf5\x8c\x1e\xec\x47\x4e\xea\x94\x67\xf6\x31\x80\x34\xd4\x16\x1a\x06\x7f\x43\x69\x30\xf0\x51\x8f\x47\xf3\x97\xb3\xd1\x79\x51\x9b\x90\xcb\xd8\xfc\x7f\x66\x8b\x17\x72\x9f\x0f\x3f\x58\x39\x67\x9f\xa9\x0c\xc1\x7c\x38\x59\xe1\xe4\x91\xfc\xfe\xdd\x36\x87\x20\x1f\x94\xd8\x86\xbf\xa9\x4d\xa2\xbf\x8a\x62\x1b\x41\x7b\xa1\x98\xa3\xc3\x8c\x71\x16\x69\xac\xfb\xca\xfe\x49\xef\xc7\x67\x97\xf6\x0e\x97\x20\xcb\xbb\x62\xf1\x74\x96\xe1\xf7\x6c\x0c\xf6\x07\x82\x40\xea\x08\x7f\x02\xb1\xa0\x26\x18\xd9\x13\xbc\x82\x4c\xe3\x87\x6b\xc9\x32\xda\x92\x56\x6a\x33\x32\x3b\x3e\xc3\x01\x89\xec\xf8\x3d\x1f\xce\x46\xe3\x82\x35\xc2\xdf\x7d\x17\x09\x97\x4c\x97\x8a\x7a\x34\x83\x84\x56\x20\xba\x41\x2a\xef\xbf\xe6\x85\x30\xec\xa8\x47\x08\xe4\x85\x8b\xe9\x87\xda\xa4\x1e\xc5\xd1\xc3\x28\xd9\xa0\x4d\xe1\xde\xa3\x67\x2b\x81\x4d\x37\x3c\x05\x09\x3e\x1c\xc4\xfd\xfb\x4b\x4c\xa1\x13\xab\x84\xd4\x90\x6a\x1a\xe6\xfa\x67\x6c\xa0\x78\x57\xd3\xa6\x7d\x0d\xfd\x93\x9f\xb4\xed\x6f\xce\xc7\xa3\x41\x51\x6b\x6c\x94\xa9\xb1\x77\xa7\x5e\xd6\xa9\x97\xf7\x6a\x88\xaf\x5a\xd4\xab\x77\xf8\xca\x3b\x30\x42\x9d\x05\x5f\x75\x6e\x7c\x8c\xd6\x2e\x89\x75\xff\xb9\x8f\xd1\xde\x65\xe7\xe7\x59\xe3\x4a\x1d\xa6\xc5\x1e\x51\xfc\xd2\xe8\x68\x5c\x93\x35\xc1\xf5\x8e\x1f\x7a\xc9\x27\x60\xe4\x85\x93\xaf\xe8\x67\x1a\x92\xfd\xb1\x20\xc5\x4d\x0f\x52\x0c\x9e\x7c\x95\x20\xd9\xf5\x52\x52\xbc\x58\x3d\x89\x75\xbf\x49\x27\x79\xbb\xb0\xd1\xed\x98\x23\x6b\x74\x3b\xbe\x92\xf1\xa6\x2b\x22\x6b\x43\x4a\x7d\x48\xbd\x15\xbb\x2d\xeb\xd1\x39\x09\xe0\x23\x18\x47\x0c\xe2\x7d\xd1\x73\x0c\xf2\x2b\x7a\x78\xbf\xf4\xc0\x34\x74\xfa\x39\xc0\x5b\x8d\xb5\x22\xfa\x5b\x34\x44\x67\xb7\x7f\xc3\xd7\x77\xd4\xaa\x01\xc5\xcf\x33\x8c\x30\x97\xb1\x32\x7d\x2c\x9e\xf1\xd3\xc1\x9f\x23\x71\x4e\x48\xe1\x8f\xca\xca\xe8\x1d\x20\xae\x8e\x5a\xc5\x61\x9b\x38\x6b\xe5\xae\x7f\xfc\x0d\x23\x9e\x7e\xc3\x88\x6b\xbe\xb2\x2a\xd1\x6b\x9b\x6a\x0c\xf3\xd4\xb0\x8e\x01\x8f\xf3\xc8\xc9\x05\xd6\x6b\x0b\x88\xfc\x4d\x6c\xbc\x89\x63\xf3\x55\x67\x23\xd2\xaf\xd8\x2c\x09\x76\x6d\x01\x2d\x81\xdb\xb1\x68\xf6\x3e\x10\xf5\x3e\x4f\xf4\x16\x3c\x3c\xc6\x8a\x90\xc1\x47\x10\xe6\x3e\x90\x96\x6a\x01\x04\xd7\x3b\x02\x00\xc8\x3a\x37\x4e\x3b\x7c\x40\x3c\x52\xea\x17\xc6\xce\xc4\x6c\x09\x31\x75\xcc\x07\x9f\xb2\x23\x52\x37\xda\x3a\x7c\xca\x8e\x43\x6b\x0b\x20\xc4\x22\xa1\xda\xfa\x4f\x39\x1a\xe5\x65\x5a\xa2\x0c\x86\x0c\x1f\xd2\x65\xda\xa2\x0c\x86\x04\x7f\x47\x97\xe9\x88\x32\x38\xe7\xcf\xee\x8e\x61\xef\x8e\x61\xef\x8e\x61\x7d\x6d\xf3\xee\x18\xf6\x8b\xb4\xf1\xb6\xda\x2b\xdb\x78\x59\x95\x65\x36\x5e\x73\xcf\xe6\xdb\x78\x5b\xed\x3b\x1b\xef\xad\xdb\x78\xd9\xa8\x54\xb4\xf1\x52\x83\x63\xdb\x78\x71\x80\xca\x9d\xb6\xd5\xd9\x19\x7d\x34\xdb\x6d\xfc\xa1\x8f\x66\xaf\xda\xcd\xcf\x92\xb8\x40\xb5\x73\x67\x05\xb6\xad\xc0\x8c\x30\x08\x9d\xfd\x6b\x3c\xff\xa9\xdd\x14\x51\xba\xb1\xc4\xa6\x11\x27\x7a\xa5\x98\x6e\x46\xff\x8e\xf7\x8f\xde\x1e\x3d\x7d\x7a\xb2\x77\x6a\x45\xf0\x87\x57\x2f\x0f\xde\x1e\xbc\x78\xb2\xf7\xd3\x9e\x9f\x95\xfb\xf8\xe8\xd5\x8b\x27\x6f\x21\xe2\xe0\xe9\xce\x0b\x55\xd3\x68\x8e\x9b\x95\x77\xab\x99\x95\x8d\x1a\xb3\xb3\xa9\x0c\xda\xe2\xd8\xa4\x65\xd3\xb0\xbb\x8e\xeb\xd1\x75\x14\x08\x55\xbe\xe0\x26\x91\x05\xf8\xbf\x36\xd9\xbf\x84\x49\xc4\xe8\xf3\xeb\x2b\xa6\xa9\xb4\x98\x78\xb9\xe6\xb7\x07\x17\xf2\x92\x26\x7e\x63\x9b\x04\xb0\x54\x32\x7d\xab\xed\xe9\x22\xa8\x06\x16\x1f\x20\x95\xdd\x35\x2b\xd0\xa2\xb4\xc4\xe2\xc3\x3f\x00\x6a\xc2\xe0\x41\x3b\x29\xb4\xb3\x41\x14\xbe\xe2\x66\xb9\x9f\x9c\xc7\xd7\xfc\xf1\x3f\x02\xa6\x60\x83\x6c\x17\x23\xb6\x2c\x40\x3a\x01\x82\x68\x8a\x32\x57\x9c\x32\x57\xfc\x82\xe6\x15\x41\x18\x55\x94\x53\x97\xfd\x83\x45\xaf\x03\x66\x25\xcd\x20\x36\x19\xaf\x31\xc1\x8f\xdf\x6b\xa0\xab\xdb\xf5\x8f\x4b\xfb\xe6\x9c\x72\xd8\xdc\x70\xf8\xf4\xe4\x18\x70\xbd\x6a\xc4\x14\x33\x98\x79\x27\x02\xf6\x31\x28\x86\x4d\x48\x66\x7d\x3e\xff\xe0\xf0\x16\x59\xec\x50\x16\x0b\x90\x50\x64\x9e\xf8\x85\x35\xdd\x61\xff\x94\x58\xe6\xb0\x0f\x78\x35\x95\x8e\x8a\x22\x9b\xef\x8f\x16\x2f\xa7\x73\x8c\xe3\x0a\x5c\x85\xc9\x72\x7f\x01\x57\x64\xca\x9b\x5a\x02\x37\x2b\x7d\x17\x89\x78\x11\x54\x61\xf8\x78\x1d\xfc\x99\xcd\x35\x68\xc6\x80\x12\x68\xcb\xf6\xa8\x36\x5b\xfd\xfb\x36\x36\x1b\x76\xae\xf6\x5a\x7e\x6e\xb4\x6c\x81\x7a\x40\xec\x7b\x14\x02\xd5\x4c\x4b\x06\x63\x41\xda\x4a\x64\xa0\x25\xc3\xd2\x0d\x7b\xa2\x33\x68\x70\xb2\xc0\xb0\x7b\xcf\x26\xe1\x74\x7c\x3d\x99\x9e\x43\xa6\xf5\xe9\x7b\x26\xde\xbf\x7f\x5a\x4b\x36\xb6\xa2\xab\x9f\xbb\x6c\x16\x5c\xfd\xdc\xc6\xbf\x5b\xf8\x37\x64\x28\x8c\x69\x90\x82\xa3\x79\xf3\xfc\xfe\xc0\xcf\xe0\xcc\x5e\xe2\x32\x1f\x40\x4e\x40\xd8\x26\xf9\x63\xc9\xa1\x97\x23\xe0\xb9\x8d\x4f\x2c\x3f\x32\xc0\x98\x3b\x66\x75\x8f\x7f\xec\xc4\x09\x7c\x2f\x52\x7a\x9a\x32\xc7\xf9\xb3\x28\xce\x99\xba\x02\x51\x8f\xcd\x4c\x74\x30\x05\x4e\xcd\x85\x28\x78\x4a\x49\x66\x9d\xa1\x67\xff\x29\xd9\xb3\x65\x78\x3b\x67\x3b\x69\xb5\x93\x9d\xd4\x3a\xd7\x49\xcb\x4e\x75\x6e\x3f\x95\xc0\xf4\x72\xc1\x98\xfe\x10\xb7\xd6\x56\xd9\x08\x95\xf4\xbc\x98\x33\xd5\x2f\x37\x12\x0d\xb0\x79\x37\x97\x01\xa1\x79\x65\x6b\xb7\x20\x2b\x1f\x4d\xc6\x72\x98\x8c\x18\xdc\xd9\x0c\xb2\xa7\x40\x94\x3d\xa6\x24\xd7\xa3\xb4\xcb\xbe\xb5\xe2\xc4\xad\x2c\x72\x16\x6c\xc1\x3b\xf3\x95\x9b\xb4\x40\x6e\x9a\x83\x79\x0b\xcc\xde\x39\xd0\xd6\xc8\x5f\x80\x96\x5a\xcc\x84\x28\xf7\xde\xf2\xd7\xeb\x37\x01\x69\x5f\x81\xd5\xe0\x83\x70\x38\xcb\xc1\x31\x9c\x64\x3b\x3c\x84\x5b\x16\x4a\x00\x31\x51\xd9\x7a\x71\x05\x68\x37\x1a\x70\xca\xc0\x68\xa9\x32\x25\x98\x94\x00\xed\x25\x35\x0f\x8a\x6f\xf5\x44\x8f\x30\xcc\x11\x44\x23\x8d\x67\xb2\x13\x87\xd9\x04\x6d\x3f\x4e\xd7\x1e\xc2\xe9\xb6\xd7\xee\x4e\x7f\x3e\x9d\xf5\x97\xd3\xdf\x01\x7f\x93\x48\x82\xcf\x93\x51\x64\x1f\x8a\x01\xad\x83\x66\x73\x56\x3c\x26\x8a\x83\xac\x2f\x0b\x66\x11\x4c\x41\x0d\xcd\x97\xc6\x13\x4c\xac\x40\x82\xc9\x3a\x11\x04\x13\x2b\x74\x60\xb2\x7e\xcc\x40\x81\x70\xbc\x0c\xe3\xd8\x46\x39\x5e\x0b\xe7\xd8\x46\x3a\x5e\x05\x6b\x72\x1c\x38\x73\x29\xd3\xc8\x68\xb2\x98\x72\x6e\xa6\x47\x7a\x9c\xa1\x31\x4f\x4e\x67\x82\x14\x50\x62\x13\xf3\xcd\x32\x4d\x06\x50\x0c\x95\x81\x54\xb4\xa2\xcc\xf2\xd3\x88\x63\x58\x60\xe7\x81\x39\xc0\x15\x65\x9c\x07\xfc\x2b\x57\x7a\xf1\x7b\x78\x0a\x30\xe9\xbf\xc8\xfc\x37\x2b\xec\x1a\x0c\x60\xcf\x47\xb0\x05\x19\x5f\x9e\x4f\xb0\x73\xc4\xb4\x92\x14\x94\x6a\x76\x3d\xd2\x9a\x34\x59\x78\xc5\x3d\x89\xd9\x86\x35\x4a\x7a\x87\x12\x68\x08\x3e\x52\xf5\x54\xa3\x57\x56\xf6\xd0\x28\xcb\x46\x31\xa8\x97\x06\xa9\x75\x4a\xea\x39\x54\x4f\x4e\x61\x14\x4e\x5f\x5f\x85\x70\x3f\xbd\xe2\xbc\xb6\x8d\x3d\x08\x17\x42\x66\xdb\x46\xd4\xab\x79\xdf\xac\xc7\x06\x25\x62\x55\xf7\xa8\x89\x5d\xaa\xc1\xb8\x34\x71\x7b\x58\x76\x58\x7e\x7a\x15\xeb\xe2\x71\x95\xe2\x9c\xbf\xd8\x97\xb2\x71\x14\x65\x0f\x55\x59\x1c\xc7\x52\xf6\x9e\x5f\xce\x70\x46\xf1\x74\x22\xc0\xea\xcb\xb9\x9c\x75\x56\xc8\x02\x26\xbf\x04\x6e\xea\x6a\xb0\x68\x5f\xdc\x0f\x0e\x6d\x6f\x10\xda\xa1\x82\xc6\xa5\x06\x87\x76\xe8\x40\x7b\xbe\x1c\xda\x67\x9d\x54\x96\x28\x2c\xe1\x4f\x55\x56\x8a\xc4\x00\x9b\xc2\xa7\xfa\x6e\xef\xf8\x6c\x1a\xbd\x1c\x95\x70\x36\x34\x29\x73\x3e\xc6\x8f\x8c\x9f\x5c\x95\x53\xbf\x6f\xcc\xf2\x16\xe5\x14\xd8\x32\x36\x86\x42\x9c\x83\x96\x09\x24\x59\xfa\x50\x97\x0e\x8b\x24\x5c\x2c\xce\xa6\x47\x5c\x4b\xd9\xb6\xec\x61\x26\x5e\xc1\xce\x1e\x4f\x51\xd1\x2b\x11\xf1\xb0\x81\xd6\x4d\x2c\x35\x3c\x18\x73\xa5\x26\x3b\xcd\x58\x5a\x41\x40\xd6\x96\xfd\xc3\x54\x9a\x69\xc2\x34\x5f\xfd\x3e\xc4\xe8\x0a\xf8\xa1\x06\x7e\x18\x02\xfe\x7c\x45\xe0\xb6\xcd\x43\x77\xb7\x92\xc5\xc3\xed\xb2\xc0\xca\x00\x83\xd6\x0e\x1f\xb9\x76\x73\xed\x9e\x3f\x5f\xd2\xc6\xe1\x8a\x6d\x94\xad\x6d\xb3\x6c\x32\xbf\x98\xce\x4b\xb9\x04\xc5\xef\xcb\xd1\x21\x9f\x18\xa7\xaf\x0d\x83\xa2\xe6\xc3\xe0\x9a\xc7\x2b\x56\x59\xf8\x44\xc9\x65\xab\x5f\x50\x7e\x5c\x8c\xa2\x6c\x81\x4b\x20\xca\x4b\x5a\x27\x3c\x6d\x60\x1f\xc8\xa0\xad\x6a\x73\xd4\x50\x02\x80\x2c\x77\xda\x90\xdd\xe1\xa5\x43\x53\x9e\x95\x13\x9d\x11\xe5\x02\xeb\x16\x58\xbb\xce\xca\x84\x5f\xf5\x65\x7d\xcd\x25\x63\xb9\x68\x94\xf4\x31\xe5\xb0\x5a\x57\x96\x30\xf7\x6a\x22\x55\xd6\x3a\x55\x0c\x57\xbd\x0a\x5b\x66\x65\xa5\x9a\xa1\x84\x6c\x54\x59\x00\x39\x80\xc4\x01\x90\x78\x00\x4a\xa9\x08\xba\x07\x9c\x7f\x55\x59\x67\xb7\x79\x17\x95\xce\xf1\x6f\x81\x3e\x7f\xf0\x37\x89\x0c\xfe\x2c\x93\x1f\x63\x63\xd6\x9c\xea\x29\x64\x42\xc4\x07\x1a\x22\xfb\x79\x73\x03\xed\xc1\x94\xda\x91\x78\xe3\x1d\x9c\x69\x96\x19\x12\xd6\x37\xc7\x98\x89\xef\x97\x6e\xda\x2c\x28\xa1\x89\x68\xe9\x2b\x76\x0d\x98\x92\x25\x87\x1d\x5f\x96\x5b\x14\x61\xa4\xb9\x75\xaf\x28\xaf\xbc\xb2\xf8\x40\x3d\xdf\x0c\xf4\x2d\x1a\xaf\x7c\x3b\xd0\xba\xbe\x54\xf1\x3a\xae\x54\xe8\x24\x35\x28\x46\x63\xdb\xdf\x29\x06\xdb\x9a\x85\x3f\x1c\x6e\x39\x0f\xb0\x71\x3c\xa0\x51\xde\x5c\x5f\x88\x13\xd4\x8d\x6d\x78\xa6\x99\x51\x22\x4f\xda\x20\x19\x99\xa8\xa1\x37\x8b\x48\x2b\xa0\x0f\xf3\x21\xe9\x4c\x77\xf2\x3f\x97\x45\xf1\xcf\xc2\x07\x0a\xf6\x69\xc9\xdc\x95\x72\xd1\x7b\x58\xdc\xc4\x58\xb8\xdc\x26\x54\x5d\xa5\x0f\xa9\xf3\xab\xdb\x10\x75\x7b\x61\xab\x9c\xa1\x1a\x0a\xc3\x9c\xa9\x70\xae\x64\x9b\x33\x40\x09\xf3\x9c\x09\x6a\x5d\xbb\xa2\x26\x45\x79\x27\x0e\xbd\x4e\x1c\xae\xdb\x89\x43\xaf\x13\x87\xab\x75\x82\x1e\x2a\xce\xba\x62\x92\x2d\xa6\xe0\xb8\x37\x1b\x15\xef\x0b\xc2\x01\x11\x3e\x8a\xa3\x37\x2f\x2e\xe7\x67\x12\x0d\x8a\x44\x44\xc9\xe7\x7e\xc9\x9b\x87\x27\x26\x6e\x0f\xa9\xa6\xeb\xfe\x14\x0e\xde\x27\xba\x25\xaf\xc9\xb0\xfc\x12\x2e\x94\x84\x38\x53\x97\x9d\x2a\x48\x88\x8a\x87\x39\xf2\xab\xf6\xcf\x2c\x25\xfb\x9d\xbb\xe6\x9a\xee\x9a\xe9\xaa\xce\x9a\xe9\x32\x57\x4d\xe3\x18\xd6\x73\xd4\x4c\x57\x1b\x88\x3b\x37\xcd\xe5\x6e\x9a\x69\x45\x27\x4d\x62\x58\x2c\x17\xcd\xb4\x8a\x83\x66\x1a\xbe\x86\xaf\x1c\x0f\xb7\xba\x4d\x70\xd7\x8c\xff\x24\xee\x9a\x6e\x80\x9d\x56\x9c\x7c\x36\x1f\x4e\x19\x6e\x07\xda\xfc\x63\x85\xdb\x59\xcb\xdb\x52\xbc\xd6\xde\x9e\xb2\xcc\x4a\x01\x79\x18\xb1\x2c\xb7\x10\xf6\x3b\xe8\x66\xd2\xad\x18\x90\x07\x0a\x5a\xae\x26\x5d\x11\xd5\x02\x5c\x30\x6e\xeb\x0a\xb1\xd9\xfd\x60\x4c\x1e\xcf\xc9\xa1\x71\x35\xe8\xf7\xfb\xbd\xbc\x95\xd7\x8d\x80\x3d\x8c\x6d\x89\x92\xed\xa4\x97\x25\xbd\x24\x33\xc3\xf9\x6c\x10\x71\x7b\x88\xaa\xbd\xb8\xd5\x6b\xc4\xad\xcc\x8c\xfe\x43\x37\x12\xb7\x92\x61\x31\xe0\x31\x83\x64\x6c\xa0\x8a\x8d\xb4\x3b\x69\x9a\xb4\xdb\x3c\xac\x90\x88\x1c\x44\x37\xd2\x2d\xfa\xcd\x66\xd6\xed\x98\x71\x85\x2a\x36\x92\xf7\x1b\x83\xa4\x68\xe4\x66\x18\x22\xba\x91\x66\xa7\xdf\x6a\x76\xe3\xdc\x0c\x52\xe4\xa8\xa6\xb7\x1d\xa5\x08\xf8\x69\xcd\x28\x45\x71\xdb\x2d\x72\x17\xa6\x68\x4d\x9d\xa8\xbb\x72\x98\x22\xa8\xb2\x4c\x2f\x32\x65\x86\xaf\x19\x75\xef\xc2\x14\xdd\xbe\x6e\xd4\xad\x1a\xa6\x88\x1c\x1c\x5b\x3f\xea\x2e\x0d\x53\xc4\x8a\x94\x86\x29\x82\x65\x7c\xab\x9b\x50\xda\x52\xf2\x27\xd1\x96\xfe\xd4\x97\x5b\x6e\xf7\x62\xcb\x27\xba\xb2\xb2\xbe\x12\xc5\x5f\xa9\xee\x0a\x40\x6f\xa5\x07\x2f\x91\xeb\xc6\x76\xf2\xdd\xcc\x2e\x2e\xc6\xd7\x35\xf1\xb0\x1e\x65\xb3\x77\x97\xe7\xc5\x64\xa1\x4c\x16\x72\xd3\x6e\x5e\x9f\xd1\xf8\x60\x28\x25\xdd\x84\xd3\x3c\x5b\x8d\x93\x6e\x96\xb0\xe5\x1a\x56\xe3\xbc\xc3\x7e\x14\x49\xc2\xd8\xd0\x2b\xd7\x89\xd3\x4e\xb3\xd9\xc3\x30\x83\x49\x5a\x0c\xdb\xad\x41\x6e\xaa\x06\x5e\x85\x7e\x6b\xd0\x18\xf6\x07\x43\x4c\x80\x30\x68\xe6\x69\x3f\x19\x52\x80\x8b\x5e\xbf\x95\xf7\xb3\x16\x66\xcf\x8e\xbb\xbd\xbc\xdf\x1f\x94\x02\x4e\x7b\xad\xf6\x20\x69\xf5\x51\x9d\x49\x9b\xdd\x7e\x2b\xed\x52\x80\x5b\xbd\x61\x1c\xc7\x43\xc4\xb8\xdf\x6e\xb4\xf2\x46\xdc\x2b\x05\xdc\x4b\xd2\x61\x37\xc9\x30\xe5\x76\x36\x8c\x7b\x4c\x8b\xe9\x53\x80\xb3\x7e\x3c\x68\x15\x39\x62\x9c\x67\xed\xbc\x1b\xc7\xdd\x52\xc0\x79\xb7\xd1\xc9\x32\x4e\xe3\x2c\x6d\xa4\x8d\xa4\x49\xd2\x38\x4e\xba\x69\xab\xcf\x73\x46\x34\x5b\x9d\x46\x7b\xd8\x2f\x4a\x01\x27\xcd\x34\xee\xb6\xfa\x98\x3b\xa2\x59\x14\xcd\x7e\xd2\x1d\x90\xa4\x68\x35\x06\x9d\x7c\x80\x09\xc4\xf3\xd6\x70\xd8\x6f\x16\x49\x29\xe0\x4e\xd2\x2f\x5a\x79\x07\x49\x31\x4c\x3a\xfd\x6e\xaf\x4d\x0e\x5e\xb7\x91\x17\xfd\x98\x27\xaf\x48\xfb\x71\xbb\xd7\xee\xc7\xe5\x34\xee\xe7\x83\x46\x9b\x47\xa8\x4c\x5a\x83\x4e\x9c\xa4\x2d\x0a\xf0\x20\xee\xf5\x87\x31\x47\x60\x30\x6c\xf7\x92\x76\xaf\x59\x0a\xb8\x68\xf6\xfa\xed\xde\x00\x69\xd7\x2b\x86\x71\x33\xcb\x49\x1a\x17\x8c\xa6\xcd\x4e\x17\xd3\x88\xb3\x95\x65\x98\xb4\x8a\xb4\x14\x70\x63\xc8\x70\xc9\x07\x58\xa1\xdb\xef\x32\xed\xbe\x4f\x62\x9c\x34\x1b\x83\x2c\x1e\x0c\x30\x49\x7b\x27\x1b\xf4\x06\xed\x56\xf9\xe0\xe5\x45\x2f\x19\xb4\x71\x82\xb4\x7a\x49\xbf\x91\x74\x48\xc0\xcd\xac\xd3\xec\x36\x33\xdc\x23\xb4\x8b\xac\x5d\x34\xbb\xe5\x18\xb7\x06\xfd\x46\xc6\x50\x46\xc6\xcf\x9b\xf1\x90\xfd\x45\x4e\xe9\x36\x9b\xf7\xdd\x1c\x01\x77\xd3\x38\x6e\xa5\xfd\x72\x8c\x7b\xdd\xb4\x68\xb1\x8d\x08\x4e\xe9\xa2\xdd\xce\x87\x19\x3d\x41\x18\xb4\x01\x7b\x8d\x83\x9c\xf7\x9b\x69\x12\x37\xca\x65\x45\xa3\x91\x26\x9d\x41\x97\xe7\x7c\x1f\xf6\x93\x38\x25\xd9\xad\x3f\x6c\xf5\x3a\xc3\x81\x88\x6f\x5a\x0c\x1b\x8c\xe7\x4b\x01\x0f\xda\x05\xdb\xee\x0c\x91\xf1\xd3\x3c\xeb\x76\x87\x03\x92\x2b\xf2\x56\xd6\xe9\xc5\x4d\x04\xdc\x4b\x1b\x59\xd6\x49\xca\x49\xd1\x68\x0f\xb2\x76\xda\xe2\xe9\x5d\x18\xf6\xdd\x84\x9e\x20\x71\x93\xed\x0c\x7b\x7c\xef\xd5\xc8\x1a\x45\xbb\xe8\x94\x93\x82\x4d\xb6\x4e\x23\xeb\xa2\x70\x69\xb6\xf3\x24\x61\x3b\x36\x92\xdd\x8a\x18\xc8\x84\x24\x6b\x0d\x92\xf6\x80\x4d\x91\x52\xc0\xcd\x9c\x31\x5b\x3e\x44\xae\x68\x65\x83\x66\x92\x15\x39\x29\x2b\xd2\x94\xcd\xea\x18\x49\xd6\xcb\x7b\xad\x7e\x9a\x0f\x4b\x01\xb7\x5b\x8d\xac\x93\xb6\x9a\x7c\x82\x64\xc3\x76\x9a\x17\x34\xbb\xb5\xb3\x46\xd6\x47\xb9\x9d\x0e\x3a\x9d\x7e\x92\x95\x8b\xcd\x6e\x3c\x48\x58\xb7\xb8\x74\xeb\x14\x79\xc6\x78\x8e\x02\xdc\x4b\x3a\x49\x32\xe0\x24\x8b\x9b\x5d\x26\x51\xd2\x7e\x29\xe0\x2c\xe9\x0f\x8b\x6e\xc6\xe5\xec\x60\x18\x37\xd2\x36\x39\x41\xb2\x6e\x9c\xb5\xdb\x4d\xc4\xb8\xcf\x28\x96\x36\x1a\xe5\xd2\x6d\x90\x34\xfb\x5d\x36\x7e\x28\x67\x1b\xc3\x6e\x8f\x31\x15\x29\xdd\x3a\xed\x41\x2b\xce\x90\xc6\x8d\x76\xab\xd9\x2f\xd2\x72\xae\xc8\x63\x46\x87\x6e\xdc\x43\xc0\xed\x62\xd8\x4a\x62\x72\xcd\xcb\xdb\xbd\x5e\xa3\x9d\xe0\x58\xb4\x5a\xed\x56\xd6\x5b\x32\xf3\x86\xcd\x46\x91\xb6\x38\xed\x5a\x1d\x26\x8e\x1b\x49\x46\xf2\x31\x13\xda\x59\x83\xf7\x2c\x4d\xfa\xfd\x3c\xee\x97\x0f\x5e\xdc\xcb\x9a\x83\x38\x46\xb1\xd9\xef\xe6\x49\xde\x18\x90\x18\xc7\x45\xca\x88\xd1\xe0\x7c\x1c\x37\xe3\x8c\x2d\xe7\x4b\x26\x48\xb3\xdb\xe9\x34\x91\x8f\xf3\x61\xb7\x28\xfa\xbd\x1e\x05\x38\x6d\xf6\x1b\xfd\x41\x1f\x7b\x56\xb0\xd5\xa4\xd9\x5d\xc2\x6e\x69\x2f\x1e\x34\x06\x7d\x1c\x94\x41\x6b\xd0\x6b\xb1\x09\x4e\xca\xe3\x22\xef\x66\x59\x13\xc5\x66\x91\x36\xe3\x6e\x36\x28\x67\xb7\x56\xbf\x37\x18\x64\xcd\x21\x5f\x19\xda\x29\xeb\x31\x09\xb8\xdd\x4d\x8a\xf6\x90\x0b\xab\xbc\xdd\x4f\xfa\xdd\xac\x9c\x14\x8c\x12\x4c\x61\x29\x70\x82\xb4\x98\x6a\xcf\x34\x2c\x52\x56\x74\xba\x59\xab\x9d\xf2\x95\x86\x69\x0c\x9d\x64\xd8\x2e\xe7\x8a\x6e\x73\xd0\xed\x74\x63\xae\x09\xc5\x43\x36\x6b\x3b\xa4\xd8\xec\x0e\x06\x9d\x46\xc2\x07\x8f\x4d\x95\x66\xda\x2b\xca\x75\xb7\x5e\xa3\x5f\x0c\x87\xc3\x8c\x6b\x91\xed\x34\x2e\x12\x92\x2b\xb2\x66\x8b\x49\xd8\x02\x67\x5e\x5e\x74\x93\x3e\x5b\x2b\xcb\x57\x90\x62\xd8\xcb\xd2\x21\x5f\x19\x98\xa0\x63\x13\x8f\xd6\x2b\xda\x9d\xb8\xd3\x1d\xf2\x25\x2c\x65\x52\x9e\x31\x73\xf9\x94\xce\x92\x4e\x5a\x0c\x90\xc6\x45\x96\xb4\xdb\x71\x8f\xa4\x71\x1e\x77\xdb\x4c\x3b\xe0\x8a\x0d\x30\x52\x62\x1b\x01\x7d\x45\x24\xcb\xb3\x4e\x9e\xe3\x04\x19\xe4\x45\x83\xe9\x51\xa4\xd8\x1c\xb6\x3a\x79\x73\xd8\x19\x8a\x45\xb7\xc8\x19\xfe\x4b\x96\xa6\x61\xa3\xdd\xe1\xfa\x42\x27\x89\xd9\x2a\xdf\x27\xa7\x74\x83\xf1\x37\x53\x0a\x71\x82\x64\x4c\x7e\xf6\xba\x59\xf9\x0a\x12\xb3\x55\xb9\xd7\x6d\x34\x85\xe1\xae\xd7\x60\x12\x99\x9c\xd2\xfd\x4e\xdc\xe8\xa7\x5c\x1e\x33\x4d\xa0\xd9\x89\xcb\x69\x9c\x74\xf3\x7e\xa7\x33\x6c\x71\xae\x68\x34\x3b\x79\x97\x94\xc7\x69\x32\xc8\x18\x2b\x22\x57\x30\x6d\xab\x93\x34\x7b\xe5\x13\x24\x1d\xf4\x8a\x7e\xd1\x40\x52\xc4\x6c\x4a\xf7\x8b\x3e\x39\x78\x6c\x52\xe4\xed\xce\x00\x7b\xc6\x74\xc8\x46\x23\x6f\x96\xf3\x71\x73\x30\x68\xe5\x4d\xae\x78\x33\x91\x51\x34\x93\x3e\xb9\x34\x81\xba\x92\xf4\x7a\x28\xac\x86\x4c\x21\xec\x14\x20\x5e\xcb\x64\xc5\x70\xd0\x6f\x33\xb5\x0a\xe5\x6c\x96\xb3\xaf\x05\x89\x71\x7b\xd0\x6c\xc6\xbd\x2e\x02\x6e\x66\xcd\x4e\xab\x1b\x77\x84\x11\xf5\x4d\xc9\xb5\x55\xbd\x2f\x94\x51\x40\xf1\xc1\x2a\x37\x54\x43\x69\xd0\x7e\xb4\x6e\xa8\xaa\xfd\x2d\x61\x22\x2d\xb9\xa1\x0a\xa7\x0c\x95\x8e\x0e\x88\xe3\x88\xb2\x7b\xaa\x9f\xf7\xe8\x80\xe9\x39\xbd\x42\x1a\xdc\xd3\xfe\x60\xd0\x6b\x04\x8e\x0e\xfa\x7d\x36\x53\x0b\xbe\xfc\x32\xa1\xcb\xf4\x4e\x5b\x75\x29\x69\x24\x65\xba\xed\x90\x49\x30\x6c\xa4\x60\x9b\xd5\x2e\x48\x32\xaa\x64\xd6\x6a\x0e\x87\xad\x14\x67\x41\x6b\x18\xe7\x6c\x25\xa8\x6a\xd5\x6f\xc5\x8d\xa2\x95\x70\xe1\x93\xe5\x05\x5b\xa1\xf2\xc0\xd1\x41\xaf\xdf\x68\xb5\xbb\x9c\x21\x13\x36\x23\xda\x83\x78\x58\xb1\x91\x78\xd8\x4d\xf3\x1e\xe7\x79\xb6\x35\x8d\xfb\x6c\xf1\xa3\x1b\x61\x5b\xdd\xc6\x20\xe7\x6a\x50\x9c\xb2\xc9\x14\x77\x7a\xab\x1c\x1d\xdc\xf6\x3d\xd2\x2a\xa1\x61\xb1\x1c\x66\x60\xa5\x23\xbf\xee\x63\xe2\x15\x3a\xf4\xeb\x3e\x32\x35\x1d\xfb\x75\x1f\x8f\x9e\xe9\xe0\xaf\xfb\xcd\x28\x18\xfd\x75\xbf\x15\x05\xc3\xbf\xee\x63\xc4\x21\x3a\xfe\xeb\x7e\x27\xa2\x03\xc0\xf2\x0e\x62\x78\x58\xd2\x0f\x9c\xbf\x1f\xf3\xf7\xfe\x65\x0f\x4e\x03\xac\x4e\x5e\x81\xe2\xef\xc7\xfc\x7d\xa0\xfa\xff\xdf\xde\xf3\x37\xb7\x6d\x2b\xf9\x77\xf3\x29\xd8\xdc\x4c\x2c\x37\xb2\x23\x90\x12\x45\x39\x4d\xef\x52\x37\xbe\xf6\x92\x34\x19\x27\xbd\x66\x26\x93\xbe\x52\x24\x65\xa9\xa5\x25\x8d\x24\xd7\xee\xb5\x79\x73\x5f\xe3\xbe\xde\x7d\x92\xc3\x2e\x00\x02\x20\x17\x20\xed\x38\x7d\xaf\xf7\x9e\xdf\xbc\x46\x24\x16\xbb\x8b\xfd\x85\x05\x09\x2e\x42\xec\x1e\x3a\xbb\x87\xa5\x68\x77\x74\x8f\xb0\x7b\xe4\xec\x1e\x95\xa2\xdd\xd1\x7d\x88\xdd\x87\xce\xee\xc3\x52\xb4\x3b\xba\x8f\xb0\xfb\xc8\xd9\x7d\x54\x8a\x76\x47\xf7\x18\xbb\xc7\xce\xee\x71\x29\xda\x1d\xdd\xc7\xd8\x7d\xec\xec\x3e\x2e\x45\x3b\xb1\xad\xaf\x63\xd1\x63\x61\x19\x14\xf2\x54\x18\x45\xbd\xe2\x1e\x6e\xb9\x15\x06\x41\xf5\x9a\x0a\x5b\xa0\x7a\x65\xc2\x0e\xa8\x5e\x99\x30\x01\xaa\x57\x2e\xd4\x4f\xf5\xca\x85\xe6\xa9\x5e\x85\xd0\x3a\xd5\xab\x10\x0a\xa7\x7a\xcd\x84\xb2\xa9\x5e\x33\xa1\x67\xaa\xd7\x99\xd0\x31\xd5\xeb\x4c\xa8\x97\xea\x35\x17\xaa\xa5\x7a\xcd\x85\x56\x4b\xaa\xee\xa0\xef\xd3\xdd\x8e\xc7\xa1\x3a\xeb\x69\x2b\xfa\xdf\x03\x22\x51\xd8\x95\xec\xfd\x04\x67\x70\xf5\xfa\xac\x09\xd2\xa1\x50\xb4\x20\x03\x22\xf8\x7e\xa1\xbe\x36\x30\xab\x46\xf3\x8c\x23\x7c\x87\x90\x74\x2d\x57\x8d\xa3\x14\x38\xe4\xf7\x05\x75\x1c\xf8\xd5\xfc\x8d\x2a\x50\xc3\x4b\x15\xac\x46\xec\x26\xae\x4a\x3a\x07\xd7\xa9\x50\x7d\x85\x15\x6f\xc5\xcf\xb6\x6f\xf1\x24\x58\x69\xf4\xf0\x7f\x8f\x27\xa0\xe6\x56\x15\xec\xb9\x28\xfe\x6b\x16\xaf\x2e\xb1\x44\xb1\x2a\x07\x6c\xc1\x25\x0d\x38\xdc\xf4\xfa\x43\x60\x83\x8d\x7d\x5f\x98\x0a\xc8\xd2\xe2\xa2\x6c\x72\x31\xa7\xb8\x28\x9b\x5c\xcc\x4d\x2e\x6c\xb8\x71\x13\xce\x51\xc9\xd8\x54\xa9\xa3\x66\x4e\xc5\x3a\x33\x54\xda\xa1\xf8\xb6\xd6\x28\xeb\xa6\x51\xa6\x35\xca\x3a\x69\x94\xcd\xad\x02\xdf\x73\x55\x85\xdb\x28\xcc\x5d\xca\x5a\xdd\x86\x90\x98\x94\xb0\x0d\x86\xfb\x98\x27\x86\x4a\x15\xbe\xb8\x55\xa5\xac\xb4\xd8\x28\x09\x36\xe6\x14\x1b\x65\x83\x8d\xb9\xc5\x86\x8d\x30\x6e\xe0\x0b\x63\xaf\x4e\xaf\x55\x3b\xdc\x17\x4a\xc6\x5a\xed\x63\x9f\xda\x39\xa4\x88\x5c\xe4\xc4\x5c\x83\x2c\x25\xa4\xe7\x9b\x70\x01\xc9\x62\x23\x90\x38\x4f\x85\x56\xb0\x82\x01\x46\x66\x16\x75\xd8\x52\xc1\xb6\xf2\xa0\x23\x4d\x09\x42\x5b\x8c\xeb\x33\x97\x0d\xae\x43\xd9\x5c\x80\xcf\xb1\x66\x1b\xe0\x01\x4d\x0e\xf6\xf9\x24\xd0\x53\xf8\xe4\x9d\x7f\x0d\x58\x70\x14\x34\xb6\x4d\x37\xf9\x80\xff\x2a\x0d\xb6\xb3\x01\xff\xbd\x5f\x79\x8b\x83\x0b\x76\x53\x2e\x50\x8a\x1d\x79\x10\xda\x69\x72\x20\x34\xd1\xa0\x4f\x23\xd5\xb3\x22\x47\xea\x20\xac\x67\x3d\x4e\x88\x9a\xdf\x08\xdc\x22\x0f\x93\x45\xf1\xe1\x23\xbe\xd9\x5c\x96\xc5\x87\x0b\xfa\x3b\x3e\xd1\x47\xf8\x3e\x4f\x9e\x78\x9f\x52\xf6\x81\x8b\xb3\xd2\x53\x4c\x1f\x06\xd0\x4b\x01\xf5\x54\xd0\xc1\xdf\x99\xf8\x3d\x95\xbf\xdd\xdd\x4b\xec\x0e\x54\xa6\x82\x24\xfe\xce\xc4\xef\xa9\xfc\xed\x2f\xc9\x8f\x1c\x00\x59\x0c\x38\x62\x5e\x49\x4b\x51\x5e\x7a\x5f\x14\x3f\x48\xe7\xaa\x62\xbf\x6c\xb4\x6a\xf6\xab\x59\xc5\x68\x1b\xfb\x2b\xf3\xe3\x6a\xaa\x57\x21\x92\x34\xe7\x36\xcd\xd2\xa2\x39\xb7\x69\x96\x26\xcd\x79\x17\x9a\x4c\x8c\xb3\x90\x53\x83\xf8\xde\xa4\x10\x93\x42\xa2\xca\xfe\xcf\xd5\xa1\x15\x46\xe3\x50\x37\x02\xcd\x48\xb5\x89\x32\xdc\x7e\x9a\x62\x9c\x12\x58\xd1\x9c\xdb\x34\x4b\x8b\xe6\xdc\xa6\x59\x9a\x34\xe7\x9a\x26\x99\x75\xb6\x9f\x43\x40\xf3\xfa\x74\x01\x96\xfb\xd4\xfd\x31\xd5\x53\x74\xde\xa7\x0b\xdf\x67\x54\x4f\x31\x18\x3c\x5d\xb8\x42\x28\xc0\xec\x50\x20\xf3\xb2\x62\x91\x72\x4a\x01\x08\x04\xe7\x7a\x2c\x22\x5c\xec\x98\x19\x2e\xe6\x5d\x62\x95\x26\x0b\xff\x05\x89\xf8\x69\xee\x90\x54\x36\xa7\x08\x66\x37\xa2\xf8\x94\x0c\x3d\x75\x8a\x4f\x17\x14\xc5\xa7\x8b\x9b\x50\xa4\x83\x5d\x9d\xe2\xf7\x24\xc5\xef\x29\x8a\xb4\xb5\xd5\x0f\xaf\x70\x90\xc4\x87\x17\xca\xed\x11\xd0\xc9\x1d\x3e\x07\x51\x51\xe9\xbe\x08\x8f\xc8\x62\x68\xb2\xa8\x70\x75\x63\xf3\xbb\x75\x0e\x5f\x26\x5d\xfa\x57\xfa\xf0\x87\xeb\x4d\xd2\xbe\x71\xb9\x79\x46\xb1\x8d\x13\xd0\x8c\xea\x83\x0b\xdb\x19\xd5\x07\xd7\xd0\x05\xd5\x07\x97\xd0\x05\xd5\x07\x97\xe4\xbd\xbc\xc4\xe3\x3b\x4a\xd7\xf9\x1d\xb8\xa6\xef\xe5\x73\x84\x12\xa2\x2b\x4c\xc9\xe5\x0d\xa1\x39\x4f\x02\x01\x4c\x19\xc5\x23\x3e\x52\xc8\x28\x1e\xf1\xe9\xc5\x94\xea\x83\x0f\x2f\xa6\x54\x1f\x7c\x4e\x92\x52\x7d\xf0\x31\x49\xe3\x34\x03\xf8\xc3\xc7\x2e\x3d\x61\xea\xdc\x32\x5c\x43\xc0\x07\x37\x3d\x21\x07\xb0\xac\xfb\x7a\xc6\x11\xd2\xd8\x35\x93\x9d\x5b\x3d\xac\xc4\x78\x66\x88\x99\xc1\xd7\x68\xff\x69\x23\x1b\xf8\xba\x2a\x46\xd1\xfb\x1a\xed\x3e\x15\xcc\x7e\x3d\x30\xb9\x4d\x9b\xcc\xd6\xf1\x18\x4f\x19\x05\x41\x14\xd1\xb4\x49\x90\x69\x82\x28\x9e\xa9\x24\x68\x45\x82\x69\x3b\x41\xe3\xb9\xa4\x20\x18\x62\x88\x6d\x12\x0c\x35\xc1\x70\xae\xe6\xa5\x1e\xc2\x1b\xe1\xb5\x9d\xa0\xf1\x24\x53\x10\x8c\x80\x60\xde\x24\x18\x69\x82\x11\xd0\xca\x25\xc1\xa8\xc5\x1d\xea\x78\x8c\x67\x9f\x82\xe0\x10\x08\x16\x4d\x82\x43\x4d\x70\x08\xb4\x0a\x49\x70\x68\x12\x2c\xda\x09\x1a\x4f\x4b\x05\xc1\x11\x10\x9c\x35\x09\x8e\x34\xc1\x11\xd0\x9a\x49\x82\x23\x93\xe0\xac\x9d\xa0\xf1\x7c\x55\x10\x8c\x71\x51\xd1\x24\x18\x6b\x82\x98\xbd\x9f\x49\x82\xb1\x49\xf0\xac\x9d\xa0\xf1\x44\x56\x10\x1c\x03\xc1\x79\x93\xe0\x58\x13\xc4\x65\x93\x9c\x93\x01\xde\x97\x04\x7c\xf0\xb7\x17\xff\x3c\x14\xe7\xf6\x0e\xc5\x61\x90\xdc\xcb\x93\xcd\x00\x19\xd6\x61\x89\x06\xb7\x7d\x2c\x0e\x4d\x86\xfd\x5d\x1e\x8c\x73\xbc\x5a\xfe\x52\x6c\x44\x95\x5f\xf8\xb0\x22\x0a\x0f\xa6\x8b\x1d\x24\x28\x50\xcf\x17\xf6\x67\x4f\x8b\xd9\x6a\x53\xc8\xed\xd4\x0d\xad\x19\xdf\x9a\x18\xef\xee\x76\xab\x37\x51\x78\x1b\x07\xf1\xfc\x59\x8f\xe0\x31\xf9\xac\xea\x83\x1c\x71\x83\x08\x87\x0f\x22\x59\xa7\xf8\x9f\x5f\x37\x39\x3f\x55\x1a\xb1\xf0\xba\x5f\x37\x41\x97\x96\xaf\x9b\xac\x6d\x0d\x8d\xaf\x9b\x78\xeb\x3f\xbf\x6e\xba\xed\xaf\x9b\x40\x2b\xdd\xbe\x6e\x22\x95\x63\x7d\xdd\x24\x14\xe4\xfd\xba\x49\x7c\x47\xdb\xf1\xeb\xef\xe8\x4f\xfd\x3d\x53\xb1\xcc\x0e\xa6\xe9\xb6\x88\x87\xb5\x86\xf3\x7c\x54\x07\xfd\x65\xfd\x73\x3e\xab\xdd\xcc\x16\x6b\x2e\xe9\x3f\xe4\x93\x28\x83\x55\xbc\x06\x0e\x45\x83\x60\x0c\x7f\x9b\xfc\xfc\x7f\xf8\x74\xca\xfe\xc0\xc9\x75\x26\x10\x6e\x9e\x39\xc6\xa1\x57\x70\xc6\x3d\x8b\x8e\xeb\x98\x9f\x97\x7c\xf5\x87\xb3\xe8\xf1\x7c\xb5\xc8\x0a\x9e\x78\x66\xcd\x2f\x97\x5e\x1e\xc3\xe3\xb8\xb7\x7a\x56\x1a\x8d\xfb\xc1\x70\xc2\xff\xcf\xfa\x41\x14\x41\xc9\xfe\x7e\xc0\xf8\x3d\x7e\x2b\x60\xc6\x56\xa3\x51\xd2\x0f\x46\x03\x0e\x17\x72\x38\x28\xed\x1f\x73\x38\x7e\x8f\xf1\x7b\xa1\x09\x37\x81\x62\xff\x1c\x8e\xe3\x82\xdd\x8a\x21\xc7\x05\x7b\x8b\x18\xe0\x37\xe0\x62\xde\x0f\x3e\x7a\x18\x72\x5c\x11\xc7\x15\x73\xf8\x11\x87\x87\xcf\x05\xa2\x89\x01\x18\xf1\x8e\x21\x6f\x64\xbc\x91\xb7\x05\x31\xef\x04\xfb\xe2\x87\x78\xb4\x80\x09\x08\x9c\xf0\x46\xd8\x61\x1b\x03\x20\xef\xc8\x3d\x3e\x18\xf2\x8e\xd1\xd8\x00\x0c\x39\x3b\x21\x6f\x84\xed\xf8\xbc\x2d\x08\xf9\x30\x42\xde\x99\x01\x3b\x02\xec\x9d\x47\xae\x21\x2d\xd7\xd0\x96\x2b\x70\x01\x72\x84\x71\xc3\xd6\x71\xfe\x0f\x27\x66\x70\x2b\x09\xc3\xb0\x80\x5b\x64\x68\x60\x72\x19\x49\xc1\x01\x57\x00\xc0\xa1\xcc\xe1\xc2\x2e\x70\x90\x07\x08\x18\xb9\x8f\x6c\x45\x80\x42\x41\xc0\x20\xbf\x68\x2c\x04\x3b\x1a\xd5\xe4\x35\x1c\x48\x6d\x8d\x84\xf6\x87\x26\x05\x50\x0d\x98\x46\x04\x2a\x8d\x85\xda\x47\xa6\x0e\x41\x05\x60\x0f\x60\x17\xa0\x43\x10\xac\xca\x6a\xac\x13\xa1\x2e\xce\x2f\xca\x14\x8f\x49\x81\xa4\x72\x3b\x5f\xcc\x1a\x27\x3c\xa1\x17\x7c\xf3\xfa\x2f\xaf\xbe\xfe\xe6\x44\x9c\x29\x05\x12\xe3\xc8\x71\xf0\x20\xa1\x04\x2c\x52\xaa\x09\xa5\x2b\x2d\x95\x49\x75\x86\xd2\x7a\x51\x20\x89\x49\xff\xd5\x97\x2f\xde\xf0\x6c\x3a\x5d\xe6\xb2\x36\xfa\x1a\x55\x2a\xce\xd3\x20\xf8\x00\xf8\xbf\xbc\xb4\xf5\x59\x4b\x29\x07\x57\x83\x23\x5c\x8c\x24\xe1\x60\xd0\xaf\xb7\xa9\xb5\x82\x00\x21\x00\x42\x0b\x80\x83\x84\x0d\x90\xc8\x00\x69\xb6\x0e\xcd\x56\x82\xc0\xc8\x26\x10\x12\x04\x62\x9b\x49\x0a\x64\x5c\x1b\x07\x41\x28\xb1\x18\x69\xa2\x98\xd4\xa9\x34\x51\xa4\x26\x08\x05\x30\xad\x4b\xab\x09\x92\xd5\xc8\x34\x00\xf2\xfa\x50\x9a\x20\x85\x01\xd2\xa4\x30\xb3\xb9\x6c\x76\x4f\x7c\xbd\x59\x62\x0d\x81\xea\x1f\x26\x2d\x04\xa2\xc4\x1e\x42\xd3\x28\xea\x44\x08\xbb\x30\x40\xa8\xf6\xd8\x46\x41\x89\x69\x6c\xa2\x68\x4a\xc1\x66\x92\xa2\x31\xb1\x07\x4a\x18\x44\x9d\x09\xc2\x24\xea\x64\x9a\x9c\x64\x35\x71\x51\x46\x51\xc3\xd2\x34\x09\x4b\x5c\xcd\xf6\x59\x9d\x06\xa1\x79\xa7\xba\x64\x94\x60\xb4\x20\x43\xa3\xd5\xa1\xcc\xc8\x02\x21\xa9\x0f\x6d\x2c\xd4\x18\x47\x26\x08\x69\x13\x26\x9f\x44\xfb\xd8\x66\xc3\xe3\x1b\xcc\x63\xfe\x93\x3a\xa7\xce\x40\xc1\x3c\x1a\x9d\xda\x83\x21\xac\xc2\x1a\x8c\x33\x4e\x30\x8f\xfd\x16\x35\x10\x57\xa8\x60\x74\x28\x48\x5a\x45\xc1\x6c\x10\x4a\x14\x61\x52\x13\x05\x61\x1d\x89\x39\x52\x67\xb4\x70\xdb\xe7\xa8\x4e\x83\x98\x43\x4c\x1a\xae\x48\xe1\x56\x46\x62\x53\x20\x8c\xa2\x06\x40\x80\xa4\xd6\x30\x09\x8b\xa8\xcb\x9b\x98\x3c\xea\x54\x9a\x20\xb9\x01\x42\x58\x84\x3d\x50\x62\xf2\xa8\x6b\xd4\x3c\xa3\xca\x80\xa6\xb3\x0c\xee\xc4\xc9\x70\xc0\x9c\x11\x44\xc2\x38\xd3\x8c\x4a\x81\xae\x08\xa2\x88\x0c\x28\x22\x43\x9b\x08\x09\x33\xb2\xf1\x90\xcc\xc4\x36\x1e\x12\x66\xac\x61\x08\x2a\x66\xb0\x25\xbb\x4f\xea\x24\x08\x24\x69\x7d\x38\xee\x84\x43\x12\x22\x90\x64\x96\x60\x09\x80\x5c\x03\x38\x03\x88\x60\x81\xe8\x3c\xab\x6b\xc5\x99\x77\x79\x85\xc9\x92\x96\x51\x84\x1a\x80\x68\x8d\xea\x24\x28\xdb\xb0\x60\x68\x2a\x23\x1b\x0f\x39\x9a\xd8\x60\x95\x04\x18\xdb\x84\x48\x99\x27\x16\x12\x02\x60\xa2\x01\x9c\x49\x87\xd3\x97\xa6\x75\x0e\xdc\x81\xc4\xeb\x2a\xb9\xa5\x15\x6a\x7a\xa9\xcb\xd4\x1d\x4f\x9c\x16\x64\x2e\x40\x88\x56\x3b\x6e\xd0\xc2\x62\xa1\x05\x44\xeb\x9f\xd5\x02\x08\x6d\x87\xc3\x3a\x39\x0a\xc8\x08\x21\x14\x21\x23\x7a\x38\x62\xe1\xd8\xe6\xd7\x9b\x82\xd3\x18\x8c\xf0\xe1\x60\x32\xb5\x20\x28\x1c\x53\x9b\x0b\x1a\x28\xb3\x06\x43\x11\xca\x6d\x34\x34\x50\x51\xa7\x45\x01\xd5\x82\x89\xc3\x5c\xda\x42\x05\x63\x6d\x1e\xca\xc2\x0e\x0e\xc2\xa2\x16\x2f\x63\xc3\x2e\x81\x6d\xd4\x21\xec\xb0\xd8\x1b\xdd\xd8\xb8\x43\x58\x62\x49\x07\x5f\x64\x93\x0e\x5e\xcf\xd2\xfa\xb8\x48\xe3\x69\x8b\x60\x2c\xf3\x85\x30\x96\xb7\x85\x85\xa2\xae\x23\x0a\x68\x56\x8b\x50\xd7\x49\x55\xd8\x60\xe8\x08\x46\x34\xcb\xa1\x25\x15\xe6\x4c\x51\x04\x6e\x0a\xfb\xd0\x68\x1f\x10\xed\xa3\x9a\x72\x9a\x10\xb1\x25\x34\x8a\xc6\xd8\x82\xa0\x8c\xa0\x61\x4c\x8e\xdc\x44\x53\x71\x65\x26\x6a\xa4\xae\xac\x44\x73\xd1\xe4\x33\xab\x49\xb3\x09\x91\x5b\xd2\x72\xa5\x26\x88\xc1\x91\x96\xc8\xbe\xb4\x04\x7c\xc3\x63\x49\x1b\xfb\x61\x05\xe1\xca\x46\x7c\x86\x32\xb4\x20\x48\x53\x48\xfc\x83\x8f\xad\x76\xc2\x0c\x8c\xf6\x66\x6b\x92\xb8\x45\x37\x49\x7c\xa2\x4b\x6b\x92\x71\x25\x1f\x6e\x27\xc8\xac\x76\x57\xd2\xe1\x33\x8c\xa2\xa6\x3f\x42\xfd\x35\xf9\xb6\x2c\x47\x1c\x46\xa0\x83\x01\x2d\x63\x16\x5a\x00\xce\x94\xc3\x67\x09\xcc\x8a\x09\xae\x54\xc3\x37\x56\x16\x0f\xec\xc1\x12\x20\x3a\x28\x78\x53\x6f\x97\xc0\xd9\xa4\x46\xc2\x99\x6e\xd0\x26\xc5\xa6\xc6\x20\xc9\x61\x66\xd6\x30\x49\x02\x66\x38\x20\x48\x14\x16\x09\x02\x60\x56\x23\x41\x05\xff\x76\xb3\x61\x5e\xbf\x62\x61\x7b\x68\x89\x3c\x91\x83\x0d\x5b\xdc\x9a\x8d\x5a\x63\x0b\x8b\xbd\x0e\xcc\xc6\xad\xb1\x8d\x25\x2d\xc1\x87\x4d\x5a\x3d\x90\xa5\x2d\x61\x80\x4d\x5b\x63\x20\xcb\x5a\x43\x01\xcb\x5b\xe3\x11\x2b\x3c\xc1\x8e\xcd\xec\x68\x74\x9d\xfc\x21\x19\x08\x92\x74\x6c\x51\xd9\x27\xe7\xda\x91\x4a\x28\xa6\x89\xf6\x48\x63\x18\xd2\x86\x38\x74\x1b\xd1\xc8\x96\x08\x9d\x43\x54\xc9\x31\x45\x7e\x6c\x8a\x9d\x68\x4f\x6c\x0c\xae\x0c\x42\xeb\x96\xce\x1f\x44\x3b\x9d\x3b\x68\xf1\xb9\xde\xa0\x68\xf1\x10\x38\x72\xc3\x4b\x1d\x99\x83\x32\x6f\x3a\x77\xd0\x0a\x76\x8c\xdf\xab\x5f\x96\xb8\x87\x17\x26\x6d\xcc\x47\x49\x9b\x00\x86\x1a\x3f\xd9\x3e\x4a\xda\x86\x10\x2b\x08\xc7\xf3\x0b\xaf\xf1\x25\x89\x4f\x7e\x13\x9b\xb8\x2b\x89\xf0\x58\xc7\x34\xf1\x69\x2f\x33\xc5\x43\xf6\xcf\x4d\xfc\x94\xfe\x4d\xf1\x11\xed\x33\x9b\x02\xa5\xe0\x41\x8b\x8b\x30\x36\x18\x78\x55\xc4\xaa\x18\xe0\x26\x52\x85\x01\x57\xde\xe0\x94\x11\x1b\x69\xec\x2e\x0e\x63\x13\x84\xe6\x71\x6c\xf2\xe8\xb6\x74\xbf\xa9\xb1\x89\x3d\x52\x67\xf6\xe0\xb5\x39\x56\x85\x05\x17\x99\xcc\x66\xd6\x99\x43\x78\x0c\x8f\x15\x1a\xc0\x91\x41\x38\xcd\x5e\x64\x0f\xfe\x31\xb0\x56\xbf\x64\xa1\xdb\x31\x59\xd4\xe2\xf6\x6c\xd8\x62\xf8\x6c\xd4\xea\x3b\x2c\x6e\x8f\x6e\x63\x4f\x78\x63\x49\xbb\xf3\x4c\xbc\xf1\x83\xa5\x36\x07\xa4\x31\xb4\x06\x51\x96\x79\x83\x08\xcb\x5b\xa3\x14\x2b\x5a\xc2\x14\x9b\xd9\x71\xe4\x7a\xc9\x03\x19\x53\x14\xbf\x14\xb9\xd0\xe4\x86\x4c\x19\x8e\x02\xbd\x5d\x83\xcc\x18\x24\x00\x3e\x4f\x21\xf3\x86\x2a\xe7\x23\xda\x63\x85\xc0\x05\x30\xd6\x0c\x12\xad\xa6\xce\x29\xfe\x26\x16\x7f\x8e\x9c\x41\x8f\x90\xa0\x30\xd5\x0c\xd2\x2c\x64\x16\x00\x35\x71\x38\x7d\xaf\x30\x95\x43\xa0\x9e\x59\xc2\xa1\x9f\x39\xf8\xfa\xb3\xa4\x45\xb8\x61\x32\x70\x19\x4e\x94\xf8\x0d\x67\x98\xf8\x0c\x67\x94\xb4\xd8\x45\x9c\xb4\x48\x6d\x9c\xb4\x98\x5e\x92\xb4\xa8\x76\x92\xb8\xe4\x9e\x26\x2d\x3a\x9d\x26\x7e\xab\xcd\x92\x16\xab\xc9\x93\x16\xcd\x15\x89\xdf\x70\x67\x89\xcf\xec\x8d\x3c\x80\x9e\x12\x06\x4e\xbd\x32\xe5\xf1\x4e\xc1\xb2\xca\xeb\x5d\xc3\x67\xca\xed\x9d\x00\xa3\x41\x8b\x7a\x59\x3c\x68\xb1\x10\x36\x1e\xf8\x45\x54\xcd\x7b\x4e\x35\xb2\xc9\xa0\x45\x0d\x2c\x1d\xf8\xcd\x9c\x4d\x07\x2d\x66\xc6\x54\x00\x70\xf0\x90\x0f\x5a\x6c\x85\xa9\x30\xe0\xc8\x0e\xbc\x1e\x3e\x68\x73\x71\xc6\x5a\x2c\x8d\x85\x2d\x9e\xce\xa2\x36\x67\x66\xc3\x36\x67\x65\xa3\xb6\x50\x15\xbb\x43\x11\x1b\xb7\x05\x0b\x96\xf8\xdd\x65\xd2\xe6\xf0\x2c\x75\x06\x0b\x36\x6d\xf3\x65\x96\xb5\x84\x0b\x96\x7b\x83\x25\x2b\xda\x42\x19\x9b\xd5\x02\xce\x75\xb2\x02\xc9\x76\x42\x18\x4f\x85\x93\xca\x0b\x44\xdf\x90\x1e\x73\xa4\xdb\x43\x0a\xf7\x50\x4b\x84\xc4\x3f\x32\xc7\x43\x65\x05\x55\x6b\x13\xf7\xd8\x32\x68\xe7\xac\x48\x66\x03\x06\x53\x4d\xc4\xa9\x22\x4b\xb2\x3c\x95\x06\x4a\x65\x00\x86\xac\x9a\xed\xb9\x81\xb6\xd9\x5a\x54\x63\x6d\xb6\xcd\x2c\x29\x53\x23\xf5\x2a\x89\x25\x7e\x25\x85\x89\x63\x44\x51\xe2\xd3\xce\x30\xf1\x8d\x67\x94\xf8\xad\x2e\x4e\xfc\x96\x31\x4e\xdc\xf2\x48\x12\x9f\x5d\x4c\x12\xb7\x3d\xa7\x89\x5f\xf5\xd3\xc4\xaf\xc3\x2c\x71\xd8\x54\x9e\xf8\x55\x54\x24\x3e\x9b\x9a\x25\x7e\x53\xd6\xe9\xbb\x43\x5a\x8c\xb5\x18\x1f\x0b\x5b\x3c\x95\x45\x1e\x03\x64\x43\xaf\x9f\xb2\x51\x8b\x2b\xb2\x78\xe0\xb7\x06\x36\xf6\xfa\x5c\x95\xc1\x3a\x78\x9f\x38\xa3\x76\xea\xf2\x56\x36\x6d\x09\x6d\x2c\xf3\xc4\x45\x96\xb7\xc4\x10\x56\xb4\xf8\x2c\x9b\x79\x83\x1b\xcc\xe8\x0e\xc6\x99\xd7\x94\x58\xe8\x75\x5a\x16\xb5\xf8\x25\x1b\xb6\x38\x26\x1b\x79\x3c\x93\xc5\x2d\xb1\x86\x8d\x5b\x83\x55\x8b\x27\xb1\x49\x8b\x8f\xb2\xd4\x13\x00\xd8\xd4\x1b\xb5\x58\xe6\x0d\x2d\x2c\x77\xf9\x3f\x2b\xda\x5c\x78\x66\x87\x9e\xeb\x4f\xdd\x84\x8d\x28\x56\x87\x03\xd6\x68\x0d\x55\xaa\x41\x4c\xda\x12\x29\xd5\x6d\x58\x25\x39\x54\xeb\xc8\x31\xfc\x58\xa0\x24\xe6\x68\x9d\x32\x35\x5b\x13\x63\x00\xd4\x34\x5d\x8d\xbd\xd9\x35\x35\x8c\xbc\xd9\x3a\x35\x06\x41\x2d\xd5\x8d\x3c\x8e\x98\xa6\x85\xdc\x9a\x58\x0b\x2d\x37\x6a\x91\x6e\x64\xbe\xcd\x91\xfa\xc4\xc0\x12\x5a\xa8\x61\xe2\xd3\x6f\x94\xf8\xc6\x38\x4c\x3c\x86\x33\x4a\x7c\xc2\x8b\x13\xdf\x48\xc6\x89\x4b\x3c\x49\xe2\x31\xab\x49\xe2\x53\x75\x9a\xf8\x34\x32\x4d\x3c\x86\x90\x25\x2e\x33\xcf\x13\x9f\x25\x17\x09\x6d\xb1\xb3\xc4\xa3\x64\x36\xf0\x6a\x99\x31\xaf\xbb\x86\x5e\x7f\x65\x91\xd7\x57\xd8\xd0\xe7\x0e\x6c\xe4\x75\x25\x16\x7b\x1d\x82\x8d\x7d\x11\x41\xce\x37\x64\xd3\xc4\x1b\x2d\x58\xea\xf3\x18\x36\x75\x04\x0d\x96\xb9\x82\x6c\xee\xf5\x5c\x56\x78\x83\x02\x9b\x39\x23\x22\x9f\x6d\x7d\x8c\x32\xaf\x23\xb2\xd0\xef\xdd\x91\xc3\xd2\xd8\xd0\xeb\x68\x6c\xe4\x73\x61\x16\x3b\xfd\x90\x8d\xbd\x91\x81\x25\x5e\xef\x67\x13\xaf\x2f\xb2\xd4\x11\xac\xd8\xd4\xeb\x6e\x2c\xf3\x45\x07\x96\x3b\xbd\x98\x15\xde\xc8\xc1\x66\x46\x70\xb8\xce\x9c\x0a\x4f\x8d\x92\x90\x40\x58\x09\xa7\x19\x8f\x55\x3f\xaa\x31\x12\xfd\x9a\x81\x58\xe2\x23\x9a\x46\x02\x5f\x48\xf2\x11\x57\x8d\x54\x10\x96\x9c\xd0\xf3\x4c\x32\xa0\xf9\x9f\x54\xe3\xa6\x42\xb0\xe0\x93\x6a\x9a\x56\x48\x09\x3e\x33\x6c\x24\x1a\x72\xda\x4e\x8a\x4a\x88\x44\x9f\x99\x64\x82\x68\x52\x2f\x95\x9c\x23\x17\xcd\xcc\x27\x53\x09\x13\xfa\xf4\x2f\x61\x22\x9f\xae\xe5\xfd\xa1\x4f\xd8\x12\x66\xe4\x16\xab\x84\x88\x5b\xc7\x3c\x76\x98\x96\x6c\x4e\x7c\x12\x95\x30\x13\x97\x96\x64\x7b\xea\xb6\x52\x09\x31\xf5\xd9\xa3\x84\xc9\x68\x95\xcb\xd6\xdc\x67\x46\x12\xa6\xf0\x99\xa8\x84\x99\xb9\x3d\x54\x65\xc4\xa4\x63\x33\xdf\x08\x58\xe8\x10\x32\x8b\x5c\x16\xc7\x86\x3e\x66\xd9\xc8\xa7\x16\x16\xfb\x84\xc1\xc6\x9e\x21\xba\xe2\xef\xc4\xad\x42\x96\xfa\x2c\x95\x4d\xbd\xf1\x30\xf3\x79\x14\xcb\xdd\xf6\xcd\x0a\x97\xd1\xb1\x59\xbb\x77\xe9\xc5\x8d\x13\x82\xb9\x34\x23\xdb\xc3\x76\x83\x63\x51\x9b\xf7\xb1\xa1\xd7\xfb\xd8\xc8\x27\x5a\x09\x13\x77\x18\xee\xd8\xa7\x04\x09\x93\xf8\x54\x21\x61\x26\x3e\xb3\x93\x30\x69\xbb\x97\x09\xa3\x70\xb6\x66\x6d\x61\x4d\x18\x86\x87\xcf\xa2\x2d\xe2\x28\x23\x41\x2a\xc6\xcc\x2e\x7e\x9a\x75\x0d\x9e\xa7\xdb\x9f\xb7\xc1\x6e\x9e\xee\x82\x6d\x51\x42\xf5\x21\xa8\x47\x04\xd5\x0b\x82\xc5\x72\xad\x8e\x89\xa8\x2a\x1a\x3c\x7f\xfc\xaa\x76\x70\xb1\xfe\x30\x11\x0f\xff\x91\x17\x78\x80\xa2\xbc\xc0\xdf\xf2\xa2\x6f\x76\x1c\xc8\xbb\x02\x40\x5c\xa8\xdf\x70\xd1\x37\xc6\x53\xe7\xdc\xa8\xaa\xf4\xd5\x93\x57\xa2\x30\x56\x20\x0a\xbf\xf8\xcf\xa8\x02\xe8\xea\x80\x2a\x71\x61\x54\x49\xb9\xe9\x11\x55\xfe\xd2\x7a\x50\x5c\x49\x95\x00\xe3\xbf\x89\xd2\x77\xfc\xae\xaa\xab\x07\xa5\x97\xc8\xb2\x7a\x40\x43\xa8\x68\x14\x43\x11\x8c\x6d\x90\x66\x19\x07\x84\xea\xaf\xbb\x15\x94\x65\x21\xf1\x42\x99\xb8\xc0\x38\x53\x4c\xfd\x51\x67\x87\x8c\x62\xf7\xd9\x21\x1a\xdd\xcb\x15\x20\xe4\xf4\xa0\xfe\xf2\x41\xc0\x88\x1a\xa5\x92\xae\x28\xcf\xdf\x53\xa3\x7b\xab\xfb\xcb\x72\x7c\xf0\x4f\x2f\x62\x1c\x8b\x6e\xc2\x3a\x7c\xfb\xc1\xbd\x06\x62\xa2\x60\xe9\xe3\xed\xb6\x38\x87\x73\xd4\x58\x1c\x6c\x2f\xa6\x1c\x09\x21\x7e\xde\xf0\x94\x37\x54\x2a\xd0\xd7\x6e\xa1\x2c\x5f\x21\x90\x10\x8d\xba\x80\x73\x4d\xaa\x2b\xf7\x11\x2b\xc7\x58\x71\x4a\xf2\x43\x0b\x72\xab\xb0\x4b\x5e\xde\x4a\xa4\xef\x24\x53\x24\x5e\xff\xd1\x2d\xdc\x22\x5e\x61\x55\x94\x47\x46\x11\x94\x0a\xaf\x0b\xa5\x30\xa8\x61\x42\x1a\x54\xd8\xec\x43\x59\x4d\x38\x74\x5b\x8d\x4d\x67\xb6\x59\x9d\x63\x80\x29\xa1\xbc\x61\x98\xa0\x67\x00\x65\xba\xa3\x10\xce\xdb\xde\x22\x78\x20\xce\x86\x18\x60\x01\x47\x65\x5c\xbd\x1e\x67\x51\xda\x20\xd4\x45\x54\x12\xd8\xe7\x26\x14\x26\xef\xb0\xc6\x23\xda\xd6\x82\xdf\x88\x7d\xe7\x48\xd4\xd9\xdb\x2c\xce\xe6\xdd\xf9\x1b\x62\xf9\x4e\xcd\xe4\xbe\xc5\x25\xc7\x02\xcd\x82\x57\xfe\x33\x1c\x3a\x18\xde\x27\x38\x6e\x90\xa5\x2a\xfb\xc3\x00\x16\xcb\xac\x08\x8a\x34\x9b\x4b\xb3\x0b\x16\x5c\x9b\xeb\x75\xb9\x28\x72\xd0\x65\xba\x84\xaa\x60\xe9\x32\xe7\x97\xb2\x2e\x23\x86\xf7\x3e\x89\x0d\x44\x20\xd1\x64\xbc\xe7\xb4\x08\xa6\x9b\xd5\xcf\xc5\x12\x2a\xd3\xad\x82\x44\x14\x05\xde\x06\xdb\x2c\x2d\x05\x7a\x81\x72\x4b\x63\xbb\x9c\x2f\x32\x38\x38\xb0\x5c\x5d\x6e\x11\x35\xe0\xe5\x9d\x38\xda\x8b\x2d\xef\x7f\xc9\x43\xf5\xea\x62\x27\x18\xdc\xf2\xf8\xda\xc4\x22\x05\x8d\xe5\x35\x7b\xfa\x02\x2a\xca\x63\x05\x79\x7d\x0b\x02\x4a\xc4\x28\xc9\x59\x96\xcb\x84\xe5\x8e\xfd\x86\x2b\xd1\x62\x10\xd3\xbf\x31\x66\xf5\x16\x42\x89\x9f\x05\xa0\xfd\x88\x56\x95\x6b\x1c\x63\x73\x1c\xe3\x77\xb2\xb0\xe7\xef\xe6\x2d\x3c\x14\xa0\x71\xd4\x0e\x11\x01\x8f\x45\xe1\x4b\xae\x9a\x5f\x8a\xcd\xb6\x70\x47\x41\x0e\xf0\xaa\x16\x08\xad\x5b\x9d\x26\x08\xe6\x99\x20\x34\x36\x53\x62\xdb\xb7\x6c\x04\x06\x5d\xc7\x6e\x48\xc7\x2a\x71\x59\x2c\x33\xa8\x8c\x76\x8d\xa3\x00\x65\xc5\xda\xd5\x71\xd5\x4f\x03\xf7\xed\x90\xef\x2c\xa1\x9b\x17\x1f\x83\xaa\x96\x88\xaf\x76\xef\xb1\x9f\x72\x5f\x09\x92\x4a\x3a\xfe\xbd\xd8\x99\x79\x9a\xcd\x5c\x89\x48\x03\x7d\x34\x56\x5d\x07\x02\x6c\x53\x07\xc3\x93\xb3\x88\xec\xe3\x9b\xe5\x62\xb7\x48\x4b\xb3\xf4\x95\x0d\x53\x5c\x65\xf3\x74\x79\x56\x3c\x3b\xd5\x65\x51\x45\xe5\x31\x9e\xd4\xcd\xc4\xff\xea\x26\x4d\xf7\x11\xe7\x53\xe3\x8a\x95\xff\xb9\xfa\x9c\x3e\x33\xfb\x84\x48\x27\x92\x7f\xdd\xfa\x24\x82\x37\x4e\x05\xfe\xdf\x91\x37\xec\x33\x92\x7f\x64\x65\x5a\xdf\x51\x6d\xa2\x7c\x18\x7a\x94\xf8\x29\xbc\x0a\x7f\xb7\x1f\xdb\x46\xcc\x44\x64\x3e\x81\xe8\x5c\xc7\x5e\x54\x86\x61\xda\x89\x03\x76\x63\xc3\x6e\x24\x2c\xc9\xe4\x49\xb1\xd8\xee\x8a\xb2\xb2\x62\x1a\xe3\x0c\x07\xdf\x2d\xb5\x48\xfc\x01\x7a\x06\x13\xad\x28\xb5\xc6\x63\x0e\x4f\x08\x24\xb7\x3f\xe8\x70\x0d\x89\x64\xb5\x74\xc1\x6b\x2c\xab\x4d\x89\x86\x08\xd8\x35\x47\xda\xb8\x24\x55\xf3\xa4\x52\xb1\x31\x6b\x0f\xe0\xdf\x2d\xf3\x55\xb0\xbd\x4c\xd7\x22\xfd\x28\xd3\xed\x4e\x18\x43\x33\x84\xef\xfc\x2a\xab\x31\x6b\x2b\xcc\xe7\xf8\x3b\xc2\x86\xb1\xa2\xf8\x75\x5d\xbd\xe1\x1a\xb7\xe6\x82\x37\x71\xf5\x9b\x84\x94\x96\xd0\x45\xac\xc8\x76\x01\x4f\x5e\x1a\x11\xb8\x0a\xb9\x7e\x95\x59\x21\xd7\xad\x33\x6b\xca\xe0\xd3\xbb\x28\x01\x1d\x43\x01\x68\xb3\x65\xf1\x8b\xa3\xc1\xa8\x1b\x1d\x93\x55\xa3\xf9\x40\xc0\x02\xe5\x22\x60\xb3\xda\x6e\x75\x9a\x8e\x35\x0f\x31\x21\xc6\x65\xa9\xe8\x51\x4d\x54\x5a\x70\x3d\x35\x5f\x9d\xa7\xdb\x9f\x2d\x97\x55\xb6\xdb\xeb\x59\x26\x0a\x8e\xa8\x66\xd7\x1f\xac\xa1\x83\xd3\x02\x16\x43\x04\x96\xc9\xfe\x80\x36\x5b\x6b\x2b\x75\x1b\x64\x54\x02\xb3\x84\x52\x7e\xd7\x60\xfb\xf4\x59\x77\xb6\x37\x6e\xb6\x4b\x3f\xdb\xa5\x87\xed\x4d\x07\xb6\xbd\x45\xa4\xb7\xaa\x8a\xb4\x78\xfc\xd1\xad\x8e\x74\x5b\x11\x66\x81\x6b\x57\x5c\xed\xcc\x52\xcc\x5f\x3d\x79\x75\x28\x13\x34\xab\x16\x73\x3f\xc8\x66\x67\x44\x71\xed\x75\x99\x02\x13\x1c\x49\x1d\x8b\x4c\xb8\x7a\x9a\x0e\x85\xa8\xaa\xec\xdc\x7c\x50\x63\x17\xdd\xe6\xed\x64\xc5\xed\xd7\x9b\x05\x1f\xe5\xc1\xf5\x1e\x11\x89\x4e\xd6\x83\x22\xf3\xd6\x9f\xe7\x71\x91\x7c\x10\x01\x6c\x2f\xb0\x42\x69\x56\x3f\x1e\x48\x66\xb1\xc5\x16\x4a\xfa\x82\x5e\x84\x54\x9f\x08\x1d\xaf\x36\x3d\x7d\xce\xba\x3c\x38\x5e\x91\x3e\xdc\x96\x8b\xac\xe8\x41\xc1\xdc\xfd\xc6\x59\x18\x15\xda\xf0\x86\x68\xa1\x60\xad\x07\x6d\x74\x43\xb4\x50\xd6\x77\xdf\x7d\x90\xc6\x8d\xd7\x1e\x5c\x7e\x87\x66\x67\xa3\x87\x53\x32\x87\xe6\x9a\xa3\x43\x87\xa8\x03\x85\xdb\x59\xd3\x00\xad\x6b\x32\x17\x5e\x77\xf8\xac\x03\x05\x7a\xd6\x63\x93\xf0\xd6\xa6\xbd\xbf\x93\xb0\x5a\x45\x97\x5b\x08\xae\x1a\xd7\x35\x43\xac\x2b\xc4\xd9\x81\xb6\x82\xf2\xd6\xcf\xaf\xa0\x6a\x25\xf4\x8d\xc2\xec\x47\xa3\xb0\x5f\xab\xaa\x6f\x14\x77\x3f\x1a\x0d\xfb\xba\xaa\xfb\xd1\x28\xee\xcb\x62\xef\x47\x31\x83\x82\xfb\xc3\x0f\x2a\xb8\xff\x47\x56\xda\xff\x68\xf5\xf0\xff\x36\x95\xed\xf1\xa4\x02\xce\x47\x7e\xbb\x25\xee\xbf\xe4\x6a\xd7\x55\xeb\xf9\x85\xd1\xf6\x26\x0a\xbd\x15\xf0\x9b\xbe\x7c\x15\x0f\x83\x65\xca\x7d\x68\x6d\x7a\xe9\x03\x93\x0d\x00\x01\x36\xc4\xbf\xbf\xbd\xa7\xd0\x3c\xe6\x51\xa3\x3a\xc2\x86\x42\xf3\x26\x1e\x02\x1f\xc8\x14\x47\x74\x28\x2f\x80\x7f\x22\x33\xd0\xa8\x05\x7a\xf9\x38\x85\x87\x27\x9e\x73\x07\xcb\xe2\xb2\xfc\x35\x10\xbe\x96\x53\x84\xcd\x80\x22\xa3\x8d\x3c\xcd\x63\x79\x71\x3e\x2d\x36\xef\x03\x3c\x55\x0a\x4f\x55\x81\x1f\x51\x88\xe9\xfc\xa1\xb7\x0b\x9c\x55\x05\x3d\xe0\x5f\xaa\x83\xdd\xd9\x8e\x6e\x4d\x00\x25\x97\x2b\x2d\x17\x15\x11\x94\x78\xd4\xc4\x2c\xde\xfe\x0d\xc2\x41\x84\xab\xb2\xe1\x60\x34\x88\x07\xf6\xf3\x4e\x25\x69\xf8\x5b\x70\x59\x99\x73\x18\x8c\xb0\x0f\x5c\xd3\x73\x98\x3c\x5f\x8b\x38\xd5\x13\x9b\x61\xb4\x8f\x02\xfb\xf4\x6d\x73\x65\x5e\xd7\xd4\x97\x8b\xdd\xe5\x82\x1b\xe8\xb7\x2f\x5e\x6f\x11\x43\x9b\x62\xd4\x41\x29\xd2\x40\xde\x73\x53\xe2\xfa\x05\xb9\x1c\xa0\x60\xe4\x4c\x92\xce\xf8\x4c\xc2\x5b\xce\xf8\x02\x7b\x79\x76\x0b\x82\x47\x54\x05\x08\x5e\xaa\xe0\x70\xb9\xda\xf5\x9c\x52\xe5\xae\xcb\xdb\xdb\x32\x55\x3c\x93\x45\x08\xf4\xaf\x95\x74\x1f\x92\x60\x42\xb0\x7f\x55\x42\x26\x52\x52\x29\x19\x29\x18\x65\x0d\x5a\x9d\x0f\x2d\xee\xac\x0c\xc0\xa5\x95\xc7\xdf\x7e\x65\x68\x05\x5f\x27\xe0\xbc\xbd\x4e\xb7\xf8\x7a\xa1\x93\x0f\x55\x9a\x42\x1c\xe0\x12\x95\xb2\x78\x1a\xc0\x49\x28\xbc\xb7\xac\x7c\x8e\xf9\x76\x54\x2f\xde\xed\x68\xc5\xf3\xeb\x5e\xca\xb5\xcb\x27\x5c\xc9\x88\xcf\x0c\x38\xb4\x69\x06\x30\xc2\x16\x53\xd0\x7e\x76\x4f\x08\xa4\xcd\x2a\x2a\xcf\x93\xf0\x7f\x98\x7d\xbc\x38\xfd\xd8\xe6\xf1\xe2\xf4\x23\x59\xc7\x8b\xd3\xdb\x31\x0e\x9e\x2f\x98\xb6\xc1\x57\x4a\xdd\x4d\x63\xb5\xb9\xb1\x65\xfc\x7e\x4d\xcb\xf8\xfd\x0f\xb6\x8c\x37\x1f\xdf\x34\xde\x7c\x34\xdb\x78\x73\x5b\xc6\x71\x55\xb3\x8e\xab\x6b\x99\xc7\xd5\x07\xd8\xc7\x0f\xd7\xb4\x8f\x1f\xfe\x20\xfb\xc0\x97\xf2\xa6\x65\x2c\xc5\x93\x51\xb9\x20\x84\xa7\xa2\xdd\xb3\xb2\x25\xda\x84\xb8\xe2\x8b\xdc\x0a\x13\x1e\x61\x73\x5b\xc6\x80\xc8\x6e\xc7\x1c\x10\x95\x65\x10\x78\xe7\x59\x2f\x1c\xf9\xec\x40\x00\x99\xa6\xb0\xa4\xec\x00\x96\x40\xcb\xe0\x73\xd8\xe2\xe3\x78\xd3\x65\x58\x4a\x4f\x9b\xca\xe7\x9f\x07\x4b\x7c\x45\x5e\x19\x83\xd8\x3a\x14\xc2\x11\x9e\xe4\x61\xf5\xb4\x09\x01\x9e\xa6\xad\xbd\x0f\xd4\xe2\xc9\xcf\x90\x89\x86\x0f\xe3\x80\x38\x31\xb4\x41\xba\xfe\xaa\x0b\xc8\xfd\x2d\xad\x17\x1f\xe5\xff\xc3\x99\xef\x69\xcf\xbd\xb8\x50\xd6\x7b\x7a\x4b\xd6\x2b\xf4\x6e\x5b\xaa\x61\xbc\xca\x9e\x3b\x18\x6f\x23\x62\x22\xaa\x1b\xd8\xaf\xe1\x05\x15\x9e\x76\x03\x96\xe4\xff\x70\x0b\x3e\x5d\xed\x52\x38\xbb\xf6\xe3\x06\xe0\x0d\x52\xb9\x2d\x13\x46\x6c\xb7\x63\xc2\x82\x31\xd3\x84\xf9\x9d\xb6\xf8\x0b\x20\xad\xf6\x2b\x47\x84\x76\x20\xa3\xfa\x72\x1f\xd2\x41\x7d\xe7\xb4\x17\x0f\x1b\x66\xf9\xa1\x0a\xbb\xa5\x98\xf3\xe7\xd2\x58\x4b\xc8\x01\x88\xeb\x2b\xec\xb4\xa1\xb0\x67\x37\x51\xd8\xe3\x3c\xff\xd8\x99\x6f\x9a\xe7\x1f\x29\xf3\x15\x47\x7e\xdf\xc6\x9a\x39\xaf\xad\x99\xf3\x6b\xad\x99\xf3\xce\x6b\xe6\xfa\x8c\x70\xbf\x4a\x64\x71\xc3\x28\x9d\xfc\x66\xe9\x66\x03\x0f\x3c\x7b\x6a\x0e\x11\x07\xc3\x5b\xd3\x8a\x3e\x1e\x9e\xc6\xd1\x4c\xa4\xee\xeb\x9c\x9b\xff\x46\x1a\x92\x87\x0f\x8d\xe8\xe2\x8a\x7e\xbb\xf2\x78\x29\x8f\x00\xe7\xde\x6c\x3c\xdb\xdc\x12\xaf\x50\xd6\x9b\xd5\xba\xd8\xec\x7e\x0d\x7e\x93\x47\x0c\x23\x20\x9a\x57\x85\xa2\xf1\x58\x51\x1a\x88\xc6\x67\xe2\x51\x61\xa5\x3a\x13\xdd\x8e\x2e\xfc\xf6\x72\x31\x5b\x64\xe9\x72\x17\x4c\xb1\x7d\xb1\x34\x7c\x03\x89\x7a\x9e\xfe\xea\xe7\xd2\x8a\x19\x75\xe7\x16\x9e\x03\x37\x39\x20\xcc\x5d\xba\xa3\x25\xae\xde\x8b\x35\x98\x65\x5a\xee\x5b\xb2\x6f\x15\x9c\x8d\xb2\x21\x39\x03\x6d\x27\x21\x16\xb6\x9a\x09\xe6\xbb\xfa\xea\xa5\x29\xea\xfa\x28\xac\x77\xbe\xb6\xcf\x7e\x20\xb2\xb7\x8d\xfe\xf0\x77\xdd\xc7\xda\xfd\xeb\x62\x61\x09\x9b\xb0\x94\x4d\xf1\x9b\x9a\x8c\xe5\xac\x60\xb3\xfd\x06\x92\x77\xff\x40\x43\x85\xa3\x6d\x3b\xbf\x1e\x40\xa3\xeb\x57\x66\xdb\x08\xcb\x97\x72\xf3\x04\x86\x45\x75\x21\xfe\xfd\xfd\x77\xe2\x03\x0c\xc8\xfb\x2b\x1f\xf8\xf4\x51\xd0\x7c\x0b\x66\xfe\x89\xb9\x59\x81\x3f\xaa\xd8\xa8\xef\x05\x74\x26\xed\x75\x04\xc2\x87\xca\x62\x79\xc6\xa7\xd3\xcf\x82\xa4\xe3\x56\xea\x7a\xa0\x39\x5e\xc1\x1e\x71\xb5\x34\x34\xc2\xb0\x8c\x0f\x30\x69\xab\xaf\x03\x3a\x05\x1e\x35\x6b\x57\xda\xb5\xde\xcc\xc1\xe1\xf0\x56\x10\xdd\xdb\x06\x79\xba\x4b\x83\x74\x7b\x4d\x3a\x9d\x9f\x64\xd9\x6f\x0a\xaf\x8c\x00\x7d\xb8\x5b\xbd\x89\x42\xf7\xab\x10\x6c\xfe\x80\x3d\x3b\x92\x96\x6d\x54\xc4\xce\x1d\x05\xf7\x4c\x28\xb3\x62\x52\xa9\x97\xda\xc5\x23\xd4\x46\xe0\x12\xc3\xed\xbc\x79\xdf\xa6\xed\xff\xd2\x4b\xbf\xc2\x53\xbd\xde\x36\xb6\xf0\xc3\x9f\xe2\xe1\x70\x7d\xb1\x9d\xf7\x54\x22\x05\x39\x02\xb5\xae\xa4\xa1\x6b\xb9\x04\xfc\xd5\xd7\x97\x2a\x15\x31\x14\xac\x22\x88\xc2\xd9\xb7\xdd\xc6\xb9\x91\xa4\xe1\x15\x88\x06\x4c\x32\x5b\xad\x71\x92\x74\xcc\xfd\x08\xee\x4d\x5b\x2b\xb3\x2f\x82\xac\x5c\x2d\x7d\x2b\x95\xae\x26\x8d\x78\xea\xb6\x8c\x37\xdd\xb6\x8c\xcd\x5e\x5b\x36\x31\x63\x96\x22\xd8\xad\x76\xbe\x52\x3b\x5d\x8f\x11\xfe\x2e\x1a\xf6\x5d\x21\x99\x26\x52\x15\x4b\x05\xbe\x66\x98\x55\xbb\xc6\xdc\x04\xf0\x1b\x26\xf5\x62\x5d\x24\x27\x0e\x32\x95\x0b\x5d\x5a\xfe\xd3\xea\x06\x97\x5d\x7c\xe0\x52\x9a\xbc\x42\xcf\x7f\x52\x62\x0f\x9c\xa6\x8a\xc0\xd6\xfb\xe5\x2a\x3d\x76\xee\x9b\xa9\xed\x96\x91\x5b\x63\x60\xbf\xcb\xa8\xcb\x7e\x97\x07\x9f\x7d\x1a\xcc\x77\xbb\xf5\xf6\xe8\xc1\x83\xf3\xdd\x7c\x7b\x38\x2d\x1e\x5c\xec\x66\xc9\x4f\xdb\xe0\x97\xf0\x90\x1d\x86\x3c\x33\x0b\xfe\xed\x3c\xe5\xea\xe0\x21\x98\x5b\x8c\xde\x20\x83\xbb\x42\xc4\x66\x0f\xae\x89\xaf\x8a\x9d\xf8\x1c\xae\x28\x40\xdc\x8b\x74\x5a\x72\x07\xf9\x51\x52\xfa\xf1\xce\x27\xb8\x8d\x9f\xb7\x3e\xa9\xf6\xbf\x34\x76\xd2\x04\x7b\x42\x79\x7b\xb0\xa9\x45\xde\x7e\xe8\x46\x1f\xfc\x28\x86\x63\x20\x7f\x8e\x37\x34\xee\x73\x79\x6d\xa3\x96\x77\xef\xdd\x23\xf6\xe7\x3c\xb2\x98\xac\x80\xbd\x6c\x9c\xe1\xce\x99\x1f\xfb\x62\x37\xfe\xb7\xab\xbc\x38\xe4\xf2\xe3\x66\xf4\xa5\xd8\x4a\xc3\x93\x5c\x9e\x97\x67\xfc\x7e\x1f\xb1\xc0\xee\xe8\x0b\xd8\xdb\xb3\x83\x79\xed\x47\x90\xa3\x31\x06\xb9\x0f\xa7\x1a\xc3\x99\xbc\xb6\xc7\x20\xee\x3e\x14\x7b\x92\x74\xb7\xc3\x0a\xfa\x91\x89\x8c\x67\x28\x06\xcc\xe5\x62\x99\xc3\xea\xd2\x82\x11\x5b\x87\x80\x97\xc0\xbc\x8d\x9b\x7d\x38\xd7\x9f\x1d\xdc\xda\x1f\xb7\x22\x31\xda\xed\x6e\xc3\x17\xe5\x27\x5c\x68\xc7\xf3\x74\x73\xcc\xe5\xc3\x49\xbf\xc2\x9b\x87\x33\xe3\xae\x14\xfe\xeb\x14\xbe\x27\x44\x19\xd7\x4d\x76\x7d\xb1\xfc\x15\xe4\x7b\xe7\x93\x2a\x82\x5d\x64\xdb\x30\x2f\xe0\x66\x4f\xd0\x11\x03\xc4\x57\x9b\xb8\xf9\x5e\x4d\x81\x78\x2b\x5b\x5d\xc0\x16\x3d\xf9\xe4\x12\x6f\x95\x2a\x56\x88\xee\x3a\x58\x60\x2b\x7e\xcf\xa8\x2e\xf8\x82\x6d\x93\xc2\xc5\xe5\x7c\xc1\x0d\xa2\xa7\xb0\x7d\x2e\x91\x08\xd2\x9f\x60\x1f\x8d\x30\x93\xc3\x7b\xbc\x53\x1d\x78\x60\x01\x2c\xa8\x53\x01\xfc\x05\x67\xe8\xea\xab\x64\x30\x00\x9d\x8b\x5b\x9f\xe3\xad\x2f\x4f\x4e\xe0\x96\x83\x12\x88\x0b\x97\xeb\xdb\x8b\xcd\x66\x05\x1b\x3c\xfa\x68\x75\xf0\x98\xa2\xc0\xef\x3c\xf9\xa2\xf1\x6a\x17\x00\x0b\x69\xc6\x11\x60\x27\x1c\x46\x17\xfe\x90\xc1\x9e\x00\xbf\xc7\xb9\x39\x39\x1e\x0c\xf6\xc1\x42\x39\x63\xf8\xf3\x37\x08\xce\xf0\xc0\xa1\xa2\x8f\xdd\x3e\x11\x92\x17\x53\x79\x4f\x0e\x11\x10\x44\x27\x27\xfb\xf8\x69\xe6\x00\xbe\x71\x35\x30\x63\xc3\xfd\x40\x56\x1c\x92\xd4\x75\x16\x2c\x87\x7a\xb1\xe4\x11\x2a\x9b\x73\x27\xab\xe8\x3d\x0c\x56\x4b\xbe\x28\x4e\xd7\xeb\x02\xc7\xcd\xc7\x0c\xb6\xc0\x41\x17\xbb\x3e\x2c\x34\x33\xd8\x3e\x06\xab\x4d\x10\x44\x85\xa9\x82\x01\x21\xed\xd4\xbe\xa8\x0a\x2b\x4c\xf5\xa9\x71\xb9\x4e\x17\x9b\xe6\xc8\x70\x5c\x92\xd7\x4f\xa4\xe8\x0e\x0e\x24\xef\x77\xea\x03\x70\xf4\x04\x40\xf8\xbf\x8c\xf7\x02\x4a\x79\xe3\x4d\x9c\xa1\x58\xa2\x33\xe0\x2c\xac\x7d\xa1\xb2\x72\x91\xb7\xd8\x46\xce\xe3\x44\x71\xc5\xdb\x0e\x18\x69\xf6\x95\x1f\xed\xed\x19\xc6\x7f\xff\xbe\xe8\xe6\x30\x7e\xa4\xf3\x16\x41\xde\xd5\x8d\x1d\x4c\xe9\x04\x34\x2e\x24\x23\xee\x1e\x3c\x52\xea\x7f\x68\xc8\x2b\xb8\xff\x88\x88\x1f\x0a\xd1\x17\x5f\x70\x53\x52\x06\x04\x8f\xaa\xd0\x87\xa4\x4a\x14\x27\xc2\x58\x79\xab\x65\x87\x95\xf0\x3b\x10\x42\x84\x2e\x25\x55\xc2\xe7\x86\x99\xfd\xfc\x8a\x27\x4a\xe9\xe6\x3f\xa1\x57\x0f\xf4\xf0\x72\xb5\x58\x8a\xdd\xd4\x28\x80\xea\x96\xed\xf1\xfa\xb6\xf0\x7a\x2d\x9c\xdd\x9c\xcf\x2d\xc1\x13\x6e\x88\x9b\x1e\x8e\x6a\xef\x19\xa4\x42\xda\x34\xbf\xbb\xbf\x07\x0f\xeb\x14\x02\xbe\x98\x11\x91\xb5\xc7\xe2\x7d\x7e\xf1\x1d\x77\x8c\xcd\x31\xf7\x01\x9e\xe9\xdd\x17\x08\xc0\xe4\x97\x3c\xfc\xa7\xf8\x95\xb6\x52\xf6\x1e\x34\xaa\x81\xbe\xff\x08\x33\x81\x96\x13\x66\xd5\x90\x89\x6b\x71\xf4\xc5\x6b\x36\x39\x39\x09\x29\x13\xda\xe8\x99\x02\xfc\x42\xf5\x11\x1a\xc5\x47\x95\x57\xc9\x60\xbf\xae\x17\xe1\x10\xc7\xaa\x23\xa5\x16\x03\xed\x3d\x69\x9c\x27\x27\x89\x8c\x75\x32\xcc\xb1\x03\x78\x78\x15\x6c\x79\x8a\xc5\x31\x62\xa0\x73\x33\xaa\x69\x28\xd3\xc1\x89\xf0\xd7\xf3\xe9\xaa\xac\x1c\xc9\x45\x39\x19\xd8\x94\xc3\x26\xe5\x0a\x53\xbb\x90\x62\x21\x20\x26\x05\x74\x3c\xa8\x58\xaa\x36\x1e\x13\x4c\x60\x18\x36\x99\x88\x9a\x4c\x78\x0c\xfe\xe1\x35\x99\x64\xa1\xe0\x72\x20\xb9\x7c\x32\xb0\x50\x70\xdf\xa4\xad\x26\xee\x30\x98\x27\x83\xc6\x60\x86\x1f\x24\x51\x96\x48\x66\xc7\x82\xd9\x93\x8e\xcc\xb2\xf0\xba\x83\xd2\x90\x14\x57\xf6\x40\x6d\x0f\xa8\x6c\x13\x31\xd4\x5d\x02\x52\x7f\x39\x4f\xd4\x93\xa6\x0a\x29\xa4\xee\xcd\xe4\xaa\x91\x35\x69\xf8\xd6\x49\xa5\x02\x55\x37\x40\x09\x22\x5a\x75\x9c\x5c\xf4\x88\x4d\xc2\xe6\x2c\x63\xa0\xe4\x52\x73\xbb\x7e\x2d\xa2\xeb\x6e\x1f\x2b\x11\xae\x64\xcf\x55\x9e\x1f\xaf\x96\xbb\xc5\xf2\x02\x3f\x9e\x45\xed\xeb\x50\x04\x9c\x7c\x83\x63\xe7\x33\x04\x5c\x1c\x43\x62\x41\xcc\x06\x7b\xdf\x2c\x79\xe0\x5e\xe4\x08\x24\xa4\xbd\x27\x87\x55\xc9\xdb\xa6\x12\x08\x84\xf8\xa0\xe0\x6d\x45\xe7\x9d\x74\x13\xe8\x5a\xdd\xbc\x7f\x1f\x92\x71\x15\xa1\x6a\x68\xee\x89\x30\x22\x12\x41\x88\x92\xbf\x19\xc1\x90\x84\x8e\x4e\x2a\xc6\xa0\x58\xc0\x2c\xb8\xe4\xab\x79\x58\x1d\xad\x03\xc8\x54\x79\xa2\xb6\xfb\xdf\xff\xfe\x1f\x35\x2d\x99\x28\x90\xe3\x3b\x8e\x91\x37\x00\xf7\x1a\xc1\x5f\x58\xef\x2b\xf4\x82\x9e\xb6\x72\x00\x66\xa6\x19\x86\xe6\x45\x64\x5e\x0c\x09\xf3\x6d\xea\xea\x03\x54\x65\x63\x7a\xd4\xd4\xba\x94\xec\x2c\x2d\xf1\xe3\x87\x4a\x8e\xa7\xdc\x92\x82\xd9\x62\xb3\xdd\x29\x29\xe1\xb0\xae\xaf\xe6\xe6\xec\x16\xf4\x96\xab\xa6\x78\xb7\xfb\xca\x26\x04\xa1\x7b\x52\xff\x32\xb2\x1a\xbc\x56\xf2\x55\xbc\x36\xe7\xb0\x1a\x9e\x27\xca\xa0\x8e\x15\x2a\x54\x0b\x7c\x59\x4f\x3a\xcc\xc3\x7a\x3c\x30\x91\x31\xb1\xcc\xc0\x9a\x3b\x95\x76\xa9\x04\xac\xb2\x5e\x6d\xbe\x46\x8c\xb2\x13\xf8\x6b\x98\xa0\x4a\xeb\xc5\xd8\xa3\xba\x6c\xcf\xd3\x5f\xb9\x15\x64\xe5\x05\x2e\x42\x60\x71\x61\x2e\x69\x28\x29\x9f\x28\xe9\x3c\xb9\x86\x74\xd0\x94\x6f\x26\xc0\x81\x5c\xa7\x85\xb8\x37\x49\xd0\x32\x05\x1a\xb9\x04\x3a\xc0\xe4\x45\x08\xac\x2d\x3f\xf8\x98\x32\x6f\xce\xf0\x75\x89\x26\x52\xa2\x27\xb7\x2b\x51\x0c\x19\x37\x14\xfa\x18\x85\x0e\x55\x33\x6d\xb1\xf3\xa6\x63\x7e\x07\x25\xd2\x13\x3c\x88\xbb\x95\x3e\x86\x4e\x7d\xe0\x8a\x8a\x58\x63\xb0\x81\xb9\x04\xa3\x35\xa1\xe4\x49\x6a\xe1\xbb\xd7\x27\x07\x09\x8f\xaf\xf0\xa4\xac\xc8\xab\xc8\xab\xc2\xa6\xfc\x02\xab\xba\xc6\x80\x66\x5c\x63\xfc\x79\x58\xcb\x49\x64\xae\xa1\x67\x63\xc1\x5f\x85\xcf\x4e\x49\x0c\x30\x15\xd5\x90\x8a\x19\x00\x8d\xa4\xc4\x88\xb1\xfa\xe9\x8f\x95\xee\xe8\xe7\x44\xbb\xf3\xb5\x91\x8d\xf4\xf8\x25\x6f\xb6\xe7\x92\xfd\xe0\x53\x78\xb0\x06\x41\xb9\x9e\x9d\xc8\x97\x18\xbc\x57\x3d\xcf\x30\x16\xe8\x1a\x5a\x89\xee\xa3\x3c\x7c\x03\xb1\xc2\x67\x6c\x9c\x87\x3d\x28\x03\xc5\x45\xbd\x77\x14\xec\xe1\x43\xdf\xbd\x3e\xdc\x15\xfc\xf0\x9b\x3a\x2b\xc4\xfb\x62\xb8\xf2\xbe\xb8\xe0\x7c\xca\x87\x74\xaf\x56\xe7\x05\x7c\x7d\x18\x4c\x2f\x16\x65\x1e\xac\xd6\xbb\xc5\xf9\xe2\xbf\x38\xfe\x7e\x50\x2e\x7e\x2e\x82\xcd\xe1\x4f\xfc\x27\x46\x00\x7c\xd2\xbe\x5d\x17\x19\x6c\x02\x00\xe7\xcd\x17\xa8\xf0\x75\xba\xdb\x15\x9b\xe5\x16\xf1\x61\x27\x78\x10\x33\x5b\x41\x35\x30\xae\xd3\x23\xf1\xcc\x13\xcc\xaf\xf6\x5d\x64\xb0\xa7\x8c\x66\x4f\x3c\xdc\xb5\x00\xe0\xfb\xc8\xda\x53\xd4\x3b\xea\x13\x49\x68\xbb\xf3\x89\x50\x97\xfc\x68\xb2\x7a\xcc\x6d\x4f\x60\x30\x66\xd4\x1d\x2a\x47\xaf\x2e\x6a\x4f\x8d\x3f\x35\xae\x0f\x97\x5c\x42\xaf\x39\x2b\x3a\x99\xd3\xcf\xaa\xe5\xc2\x63\xb1\x34\x9f\x1b\xc3\x2e\xfd\xd5\x7f\xbc\x0a\x7e\x19\x1c\x26\x87\x03\x5c\x9e\xeb\x1e\xc6\xb7\xa4\x15\x33\x32\x34\x2a\x4c\xe9\xe6\x72\x9e\x96\x35\x4c\xe3\xc3\xc1\x81\x78\x10\xb3\x51\x7b\xa3\xc4\x57\x8c\xf2\xde\x3c\xdd\xbe\xb8\x5c\xbe\x54\x5b\x60\x1e\x49\xa0\x43\xfb\x3e\x82\x57\xaf\x48\xb0\x6a\x9c\x10\x8a\x8a\x18\x36\xb8\x78\x3f\x04\xed\xf8\x21\xf1\x3e\xc8\xc6\x94\x15\x94\x77\x7c\x27\x47\x82\xbf\xad\x87\x5f\xb5\x71\x9d\xce\x17\x3c\xfb\xe0\xb4\x53\x9e\x27\x4e\x03\xf9\xa1\xea\x1d\xf1\xd4\xfa\x50\x1a\xb4\x94\x09\xf7\x1b\xf1\x89\x2a\xbe\x36\x79\xdf\xff\x0d\x5e\x89\xc4\x5d\x5e\x89\x34\xbe\xd8\x7d\xf3\xfc\xd9\xd7\xbb\xdd\xfa\x14\xa6\x8c\xed\x4e\x63\x1b\x77\x7b\xc1\x12\x5c\xa6\x4b\xd0\x2b\xf7\x3c\xf5\x2e\xea\x79\xb1\x9b\xaf\x72\x9c\x0c\xc4\xa7\xbf\x7b\x87\x87\x0f\xce\xf1\x26\xc4\x4b\x05\xc6\xc5\x7c\x8e\xbe\xb0\xad\x81\xea\x06\x00\x47\xf8\x2a\x5c\x7e\x9f\x2e\x7b\x5c\x3a\x11\xe8\x43\xbd\x48\x92\x55\x43\xc4\x00\x9e\xa7\xcb\xf4\x0c\x1f\x75\x03\x58\xfd\xb6\x44\xa7\x58\xd8\x16\xe5\x4c\xbe\x8d\x33\x5a\x04\xab\xdb\xde\xfe\x21\xe7\xe4\x49\x9a\xcd\xb5\xb7\x88\x26\x93\xb8\xee\x70\xc8\x79\xe6\xc0\xaf\x57\x2f\xd0\xb2\x7a\x80\x5c\x0e\xb7\x06\xb8\x2d\x76\xa7\x16\x5b\x08\x5b\xe7\xd5\xe8\xfb\x7e\xdf\x60\x4f\x6e\xe3\x5a\x14\x24\x87\xeb\x3a\x73\xeb\x0e\x7c\xad\x6f\xc6\x92\x7c\xd9\xa7\xa4\x29\xe5\x06\x2f\x58\xac\x17\xad\xa6\xc4\xcf\x8a\x1d\x57\xe1\xe3\x3c\xdf\x14\x5b\x80\x84\x3d\x8d\xc2\x5e\x7a\x36\xdb\xf0\xb5\x32\x0f\xd8\x16\x3c\x0f\xdc\x26\x08\x38\x1f\x07\xe1\x06\xf8\x17\x1f\x18\xee\xdc\xda\x1e\x05\xcc\xbe\x8d\x45\xe2\x4e\x94\xa9\x1d\x05\x6f\xb5\xd9\x1d\x62\x9b\xc4\x56\x81\xbc\xa3\xd5\x21\x46\xb5\x2c\x36\x3c\x87\x7d\xb1\x2c\x5e\x2f\xce\x8b\xeb\x0c\x8f\xea\xe8\x1b\x67\x07\xf8\xae\x03\x5e\x5e\x94\xa5\x77\x50\xbb\x17\xaf\x1f\x3f\x5f\x5c\x41\x45\xa7\x4e\x9a\xaa\xc0\xbd\x8a\x72\x41\x29\xb6\xa3\x0e\x6c\xf7\x03\xfd\x5f\xcf\x10\x32\x51\x0a\x92\x53\x7c\xf9\x52\x16\x75\x6c\x1b\x46\xbd\x8b\x7b\x28\x2d\x90\x6a\x38\xe1\x07\x9a\x5d\xeb\x20\x85\x48\xbf\xe4\xeb\x0d\x58\x65\x75\x55\x94\x84\x6f\xd3\x14\x0d\xd6\x75\x6c\x42\x49\xf5\x11\x7e\x55\xcc\xd2\x8b\x52\xd4\x39\xf9\x16\xf7\x4b\x6a\x1f\xb3\x11\x8a\x37\x0e\x06\x46\x03\x95\x68\xfb\x72\x71\x56\x43\xe1\x15\xd4\xab\x8b\x35\x4c\x5d\x3c\x56\x1c\xf3\x1c\x54\x8f\xcf\xb6\x8c\xc0\x29\x38\x67\x7f\xaf\x1c\xbb\xf6\x52\x62\x1d\xf4\xbb\x0c\xe2\xd5\x2e\x3d\x5f\xdf\x78\x08\xf5\xde\x5d\x06\xd0\xd2\xa7\x8d\x7d\x99\x63\xbe\xb5\x09\xd5\xbc\xc8\x46\x69\xc5\xf5\x7a\x13\x15\x0a\x1b\xdd\xab\x68\x43\xb5\xc8\x81\x34\x9a\x9c\x0a\x73\x41\xd6\x25\xa3\xe1\xde\xc9\x69\x52\xe7\x3d\x7a\xfe\x76\xce\x94\x4a\x52\xba\x73\x23\x6d\xe3\xbc\xc9\x6d\x31\x66\xc2\x74\xf7\x28\x1a\xf4\xef\x56\xe9\x16\xbf\x8c\x79\x2e\x77\x77\xba\x38\x13\x1b\x93\x79\xa6\x76\xb7\x4b\x5e\xb7\x07\x1b\x38\xe0\xd9\x37\x5f\x49\x70\x0d\x36\xa8\x57\x6e\xf7\x10\x92\xd7\x9f\xb6\x73\x58\x60\x73\x22\xab\x4d\x71\x54\xf2\xf5\xc5\x1d\x95\x46\xde\x85\x2c\xac\x13\x49\x10\xcd\xf7\x1c\xd8\x4a\x06\x1f\x94\x8b\xe9\x03\x40\x81\x99\x20\x27\x95\xaf\x38\xa1\x15\x5f\xd1\x6d\x16\x79\xa1\x36\x8f\xa8\xbd\x2a\x77\x8c\x72\x32\x72\x17\x08\x2c\x56\xf7\xaa\xcd\xa9\xb8\xb7\xc4\x02\x38\x14\x24\x6d\x28\xa5\x0a\x0b\x04\x99\xe3\x22\x27\xa4\x21\x5a\xe4\x26\x25\xc5\xf1\xdd\xa3\x30\x7c\xff\x0e\xa4\xd0\x7f\x2b\xa4\xf0\x6e\x9f\x8f\xe0\x5f\x82\xed\xea\x62\x93\x15\xcf\xd3\xf5\x9a\xaf\x5e\xbe\x3b\x7d\xf6\x08\x1a\x0f\x4a\xfc\x20\xe8\xa7\xed\xe1\x79\xba\xbe\xf3\x7f\x83\x18\xba\xfb\xdb\x2c\x06\x00")
 
 func web3JsBytes() ([]byte, error) {
 	return bindataRead(
@@ -104,7 +104,7 @@ func web3Js() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "web3.js", size: 404693, mode: os.FileMode(436), modTime: time.Unix(1547627248, 0)}
+	info := bindataFileInfo{name: "web3.js", size: 404699, mode: os.FileMode(436), modTime: time.Unix(1792398077, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
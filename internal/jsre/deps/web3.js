@@ -13681,8 +13681,8 @@ module.exports = XMLHttpRequest;
         var getOTAMixSet = new Method({
             name: 'getOTAMixSet',
             call: 'wan_getOTAMixSet',
-            params: 2,
-            inputFormatter: [null, null]
+            params: 3,
+            inputFormatter: [null, null, null]
         });
 
         var computeOTAPPKeys = new Method({
@@ -31,6 +31,7 @@ var Modules = map[string]string{
 	"shh":        Shh_JS,
 	"swarmfs":    SWARMFS_JS,
 	"txpool":     TxPool_JS,
+	"wan":        Wan_JS,
 }
 
 const Chequebook_JS = `
@@ -673,6 +674,34 @@ web3._extend({
 			call: 'personal_deriveAccount',
 			params: 3
 		}),
+		new web3._extend.Method({
+			name: 'exportViewKey',
+			call: 'personal_exportViewKey',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'importViewKey',
+			call: 'personal_importViewKey',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'refundOTA',
+			call: 'personal_refundOTA',
+			params: 6,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null, null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'listStamps',
+			call: 'personal_listStamps',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'isOTASpent',
+			call: 'personal_isOTASpent',
+			params: 1
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -766,3 +795,16 @@ web3._extend({
 	]
 });
 `
+
+const Wan_JS = `
+web3._extend({
+	property: 'wan',
+	methods: [
+		new web3._extend.Method({
+			name: 'checkKeyImages',
+			call: 'wan_checkKeyImages',
+			params: 1
+		}),
+	]
+});
+`
@@ -32,6 +32,7 @@ import (
 	"github.com/wanchain/go-wanchain/eth/gasprice"
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/event"
+	"github.com/wanchain/go-wanchain/internal/ethapi"
 	"github.com/wanchain/go-wanchain/light"
 	"github.com/wanchain/go-wanchain/params"
 	"github.com/wanchain/go-wanchain/rpc"
@@ -50,6 +51,18 @@ func (b *LesApiBackend) CurrentBlock() *types.Block {
 	return types.NewBlockWithHeader(b.eth.BlockChain().CurrentHeader())
 }
 
+func (b *LesApiBackend) OTAIndex() (vm.OTAIndex, error) {
+	return nil, nil
+}
+
+func (b *LesApiBackend) OTAKeyImageTx(image []byte) (common.Hash, bool) {
+	return common.Hash{}, false
+}
+
+func (b *LesApiBackend) StampPool() ethapi.StampPool {
+	return nil
+}
+
 func (b *LesApiBackend) SetHead(number uint64) {
 	b.eth.protocolManager.downloader.Cancel()
 	b.eth.blockchain.SetHead(number)
@@ -59,6 +72,13 @@ func (b *LesApiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNum
 	if blockNr == rpc.LatestBlockNumber || blockNr == rpc.PendingBlockNumber {
 		return b.eth.blockchain.CurrentHeader(), nil
 	}
+	if blockNr == rpc.FinalizedBlockNumber {
+		header := b.eth.blockchain.CurrentFinalizedHeader()
+		if header == nil {
+			return nil, core.ErrNoFinalizedBlock
+		}
+		return header, nil
+	}
 
 	return b.eth.blockchain.GetHeaderByNumberOdr(ctx, uint64(blockNr))
 }
@@ -137,6 +157,14 @@ func (b *LesApiBackend) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) e
 	return b.eth.blockchain.SubscribeChainHeadEvent(ch)
 }
 
+func (b *LesApiBackend) SubscribeChainFinalizedEvent(ch chan<- core.ChainFinalizedEvent) event.Subscription {
+	return b.eth.blockchain.SubscribeChainFinalizedEvent(ch)
+}
+
+func (b *LesApiBackend) SubscribeChainReorgEvent(ch chan<- core.ChainReorgEvent) event.Subscription {
+	return b.eth.blockchain.SubscribeChainReorgEvent(ch)
+}
+
 func (b *LesApiBackend) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) event.Subscription {
 	return b.eth.blockchain.SubscribeChainSideEvent(ch)
 }
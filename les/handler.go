@@ -56,6 +56,7 @@ const (
 	MaxCodeFetch         = 64  // Amount of contract codes to allow fetching per request
 	MaxProofsFetch       = 64  // Amount of merkle proofs to be fetched per retrieval request
 	MaxHeaderProofsFetch = 64  // Amount of merkle proofs to be fetched per retrieval request
+	MaxEpochFetch        = 16  // Amount of epoch geneses to be fetched per retrieval request
 	MaxTxSend            = 64  // Amount of transactions to be send per request
 
 	disableClientRemovePeer = false
@@ -85,6 +86,12 @@ type BlockChain interface {
 	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
 }
 
+// epochReader is implemented by chains able to serve pos epoch data to light
+// clients.
+type epochReader interface {
+	GenerateEpochGenesis(epochid uint64) (*types.EpochGenesis, error)
+}
+
 type txPool interface {
 	// AddRemotes should add the given transactions to the pool.
 	AddRemotes([]*types.Transaction) error
@@ -315,7 +322,7 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	}
 }
 
-var reqList = []uint64{GetBlockHeadersMsg, GetBlockBodiesMsg, GetCodeMsg, GetReceiptsMsg, GetProofsMsg, SendTxMsg, GetHeaderProofsMsg}
+var reqList = []uint64{GetBlockHeadersMsg, GetBlockBodiesMsg, GetCodeMsg, GetReceiptsMsg, GetProofsMsg, SendTxMsg, GetHeaderProofsMsg, GetEpochGenesisMsg}
 
 // handleMsg is invoked whenever an inbound message is received from a remote
 // peer. The remote connection is torn down upon returning any error.
@@ -604,7 +611,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		}
 		// Gather state data until the fetch or network limits is reached
 		var (
-			bytes    int
+			bytes   int
 			receipts []rlp.RawValue
 		)
 		reqCnt := len(req.Hashes)
@@ -782,6 +789,65 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			Obj:     resp.Data,
 		}
 
+	case GetEpochGenesisMsg:
+		p.Log().Trace("Received epoch genesis request")
+		// Decode the retrieval message
+		var req struct {
+			ReqID  uint64
+			Epochs []uint64
+		}
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		// Gather epoch geneses until the fetch or network limits is reached
+		var (
+			bytes   int
+			geneses []*types.EpochGenesis
+		)
+		reqCnt := len(req.Epochs)
+		if reject(uint64(reqCnt), MaxEpochFetch) {
+			return errResp(ErrRequestRejected, "")
+		}
+		if reader, ok := pm.blockchain.(epochReader); ok {
+			for _, epochID := range req.Epochs {
+				if bytes >= softResponseLimit {
+					break
+				}
+				genesis, err := reader.GenerateEpochGenesis(epochID)
+				if err != nil {
+					p.Log().Debug("Failed to generate epoch genesis", "epoch", epochID, "err", err)
+					break
+				}
+				if data, err := rlp.EncodeToBytes(genesis); err == nil {
+					geneses = append(geneses, genesis)
+					bytes += len(data)
+				}
+			}
+		}
+		bv, rcost := p.fcClient.RequestProcessed(costs.baseCost + uint64(reqCnt)*costs.reqCost)
+		pm.server.fcCostStats.update(msg.Code, uint64(reqCnt), rcost)
+		return p.SendEpochGenesis(req.ReqID, bv, geneses)
+
+	case EpochGenesisMsg:
+		if pm.odr == nil {
+			return errResp(ErrUnexpectedResponse, "")
+		}
+
+		p.Log().Trace("Received epoch genesis response")
+		var resp struct {
+			ReqID, BV uint64
+			Data      []*types.EpochGenesis
+		}
+		if err := msg.Decode(&resp); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		p.fcServer.GotReply(resp.ReqID, resp.BV)
+		deliverMsg = &Msg{
+			MsgType: MsgEpochGenesis,
+			ReqID:   resp.ReqID,
+			Obj:     resp.Data,
+		}
+
 	case SendTxMsg:
 		if pm.txpool == nil {
 			return errResp(ErrUnexpectedResponse, "")
@@ -43,6 +43,9 @@ var (
 	errReceiptHashMismatch = errors.New("receipt hash mismatch")
 	errDataHashMismatch    = errors.New("data hash mismatch")
 	errCHTHashMismatch     = errors.New("cht hash mismatch")
+	errEpochMismatch       = errors.New("epoch mismatch")
+	errEpochGenesisInvalid = errors.New("invalid epoch genesis")
+	errEpochGenesisAnchor  = errors.New("epoch genesis not anchored in the local chain")
 )
 
 type LesOdrRequest interface {
@@ -64,6 +67,8 @@ func LesRequest(req light.OdrRequest) LesOdrRequest {
 		return (*CodeRequest)(r)
 	case *light.ChtRequest:
 		return (*ChtRequest)(r)
+	case *light.EpochGenesisRequest:
+		return (*EpochGenesisRequest)(r)
 	default:
 		return nil
 	}
@@ -354,3 +359,55 @@ func (r *ChtRequest) Validate(db ethdb.Database, msg *Msg) error {
 
 	return nil
 }
+
+// EpochGenesisRequest is the ODR request type for the genesis of a pos epoch
+type EpochGenesisRequest light.EpochGenesisRequest
+
+// GetCost returns the cost of the given ODR request according to the serving
+// peer's cost table (implementation of LesOdrRequest)
+func (r *EpochGenesisRequest) GetCost(peer *peer) uint64 {
+	return peer.GetRequestCost(GetEpochGenesisMsg, 1)
+}
+
+// CanSend tells if a certain peer is suitable for serving the given request
+func (r *EpochGenesisRequest) CanSend(peer *peer) bool {
+	return peer.ServesRequest(GetEpochGenesisMsg)
+}
+
+// Request sends an ODR request to the LES network (implementation of LesOdrRequest)
+func (r *EpochGenesisRequest) Request(reqID uint64, peer *peer) error {
+	peer.Log().Debug("Requesting epoch genesis", "epoch", r.EpochId)
+	return peer.RequestEpochGenesis(reqID, r.GetCost(peer), []uint64{r.EpochId})
+}
+
+// Valid processes an ODR request reply message from the LES network
+// returns true and stores results in memory if the message was a valid reply
+// to the request (implementation of LesOdrRequest)
+func (r *EpochGenesisRequest) Validate(db ethdb.Database, msg *Msg) error {
+	log.Debug("Validating epoch genesis", "epoch", r.EpochId)
+
+	// Ensure we have a correct message with a single epoch genesis
+	if msg.MsgType != MsgEpochGenesis {
+		return errInvalidMessageType
+	}
+	geneses := msg.Obj.([]*types.EpochGenesis)
+	if len(geneses) != 1 {
+		return errMultipleEntries
+	}
+	genesis := geneses[0]
+
+	// Verify the genesis is the requested one and is sealed by its hash
+	if genesis == nil || genesis.EpochId != r.EpochId {
+		return errEpochMismatch
+	}
+	if hash, err := core.EpochGenesisHash(genesis); err != nil || hash != genesis.GenesisBlkHash {
+		return errEpochGenesisInvalid
+	}
+	// The genesis has to build on the locally verified header chain
+	number := core.GetBlockNumber(db, genesis.PreEpochLastBlkHash)
+	if core.GetHeader(db, genesis.PreEpochLastBlkHash, number) == nil || core.GetCanonicalHash(db, number) != genesis.PreEpochLastBlkHash {
+		return errEpochGenesisAnchor
+	}
+	r.Genesis = genesis
+	return nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package les
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+)
+
+// Tests that epoch geneses are only accepted if anchored in the local chain.
+func TestEpochGenesisRequestValidate(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	anchor := &types.Header{Number: big.NewInt(5), Difficulty: big.NewInt(1)}
+	core.WriteHeader(db, anchor)
+	core.WriteCanonicalHash(db, anchor.Hash(), anchor.Number.Uint64())
+
+	key, _ := crypto.GenerateKey()
+	leaders := [][]byte{crypto.FromECDSAPub(&key.PublicKey)}
+	newGenesis := func(anchor *types.Header) *types.EpochGenesis {
+		genesis := &types.EpochGenesis{
+			ProtocolMagic:       []byte("wanchainpos"),
+			EpochId:             3,
+			PreEpochLastBlkHash: anchor.Hash(),
+			EpochLeaders:        leaders,
+			SlotLeaders:         leaders,
+			RBLeaders:           leaders,
+		}
+		genesis.GenesisBlkHash, _ = core.EpochGenesisHash(genesis)
+		return genesis
+	}
+	genesisMsg := func(genesis *types.EpochGenesis) *Msg {
+		return &Msg{MsgType: MsgEpochGenesis, Obj: []*types.EpochGenesis{genesis}}
+	}
+
+	// A genesis building on an unknown header is rejected
+	unknown := &types.Header{Number: big.NewInt(6), Difficulty: big.NewInt(1)}
+	if err := (&EpochGenesisRequest{EpochId: 3}).Validate(db, genesisMsg(newGenesis(unknown))); err != errEpochGenesisAnchor {
+		t.Errorf("unanchored genesis: have %v, want %v", err, errEpochGenesisAnchor)
+	}
+	if err := (&EpochGenesisRequest{EpochId: 3}).Validate(db, genesisMsg(newGenesis(anchor))); err != nil {
+		t.Fatalf("anchored genesis rejected: %v", err)
+	}
+}
@@ -207,6 +207,12 @@ func (p *peer) SendHeaderProofs(reqID, bv uint64, proofs []ChtResp) error {
 	return sendResponse(p.rw, HeaderProofsMsg, reqID, bv, proofs)
 }
 
+// SendEpochGenesis sends a batch of pos epoch geneses, corresponding to the ones
+// requested.
+func (p *peer) SendEpochGenesis(reqID, bv uint64, geneses []*types.EpochGenesis) error {
+	return sendResponse(p.rw, EpochGenesisMsg, reqID, bv, geneses)
+}
+
 // RequestHeadersByHash fetches a batch of blocks' headers corresponding to the
 // specified header query, based on the hash of an origin block.
 func (p *peer) RequestHeadersByHash(reqID, cost uint64, origin common.Hash, amount int, skip int, reverse bool) error {
@@ -253,6 +259,21 @@ func (p *peer) RequestHeaderProofs(reqID, cost uint64, reqs []*ChtReq) error {
 	return sendRequest(p.rw, GetHeaderProofsMsg, reqID, cost, reqs)
 }
 
+// RequestEpochGenesis fetches a batch of pos epoch geneses from a remote node.
+func (p *peer) RequestEpochGenesis(reqID, cost uint64, epochs []uint64) error {
+	p.Log().Debug("Fetching batch of epoch geneses", "count", len(epochs))
+	return sendRequest(p.rw, GetEpochGenesisMsg, reqID, cost, epochs)
+}
+
+// ServesRequest tells if the peer announced a cost for the given request type,
+// i.e. whether it serves such requests at all.
+func (p *peer) ServesRequest(msgcode uint64) bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.fcCosts[msgcode] != nil
+}
+
 func (p *peer) SendTxs(reqID, cost uint64, txs types.Transactions) error {
 	p.Log().Debug("Fetching batch of transactions", "count", len(txs))
 	return p2p.Send(p.rw, SendTxMsg, txs)
@@ -345,7 +366,7 @@ func (p *peer) Handshake(td *big.Int, head common.Hash, headNum uint64, genesis
 		send = send.add("txRelay", nil)
 		send = send.add("flowControl/BL", server.defParams.BufLimit)
 		send = send.add("flowControl/MRR", server.defParams.MinRecharge)
-		list := server.fcCostStats.getCurrentList()
+		list := server.fcCostStats.getCurrentList().limit(p.version)
 		send = send.add("flowControl/MRC", list)
 		p.fcCosts = list.decode()
 	}
@@ -416,7 +437,7 @@ func (p *peer) Handshake(td *big.Int, head common.Hash, headNum uint64, genesis
 		}
 		p.fcServerParams = params
 		p.fcServer = flowcontrol.NewServerNode(params)
-		p.fcCosts = MRC.decode()
+		p.fcCosts = MRC.limit(p.version).decode()
 	}
 
 	p.headInfo = &announceData{Td: rTd, Hash: rHash, Number: rNum}
@@ -29,13 +29,25 @@ import (
 // Constants to match up protocol versions and messages
 const (
 	lpv1 = 1
+	lpv2 = 2
 )
 
 // Supported versions of the les protocol (first is primary).
-var ProtocolVersions = []uint{lpv1}
+var ProtocolVersions = []uint{lpv2, lpv1}
 
 // Number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = []uint64{15}
+var ProtocolLengths = []uint64{17, 15}
+
+// protocolLength returns the number of messages implemented by a protocol
+// version, or 0 if the version isn't supported.
+func protocolLength(version int) uint64 {
+	for i, v := range ProtocolVersions {
+		if int(v) == version {
+			return ProtocolLengths[i]
+		}
+	}
+	return 0
+}
 
 const (
 	NetworkId          = 1
@@ -60,6 +72,9 @@ const (
 	SendTxMsg          = 0x0c
 	GetHeaderProofsMsg = 0x0d
 	HeaderProofsMsg    = 0x0e
+	// Protocol messages belonging to LPV2
+	GetEpochGenesisMsg = 0x0f
+	EpochGenesisMsg    = 0x10
 )
 
 type errCode int
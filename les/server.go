@@ -106,6 +106,18 @@ type RequestCostList []struct {
 	MsgCode, BaseCost, ReqCost uint64
 }
 
+// limit returns the entries of the requests implemented by a protocol version.
+func (list RequestCostList) limit(version int) RequestCostList {
+	length := protocolLength(version)
+	limited := make(RequestCostList, 0, len(list))
+	for _, e := range list {
+		if e.MsgCode < length {
+			limited = append(limited, e)
+		}
+	}
+	return limited
+}
+
 func (list RequestCostList) decode() requestCostTable {
 	table := make(requestCostTable)
 	for _, e := range list {
@@ -50,16 +50,23 @@ type LightChain struct {
 	chainFeed     event.Feed
 	chainSideFeed event.Feed
 	chainHeadFeed event.Feed
+	finalFeed     event.Feed
 	scope         event.SubscriptionScope
 	genesisBlock  *types.Block
 
 	mu      sync.RWMutex
 	chainmu sync.RWMutex
 
+	finalMu     sync.Mutex // finalized head announcement lock
+	finalNumber uint64     // Number of the last announced finalized header
+
 	bodyCache    *lru.Cache // Cache for the most recent block bodies
 	bodyRLPCache *lru.Cache // Cache for the most recent block bodies in RLP encoded format
 	blockCache   *lru.Cache // Cache for the most recent entire blocks
 
+	leaderCache   *lru.Cache // Cache for the epoch leaders selected from proven states
+	slotDataCache *lru.Cache // Cache for the epoch data slot leader proofs are checked against
+
 	quit    chan struct{}
 	running int32 // running must be called automically
 	// procInterrupt must be atomically called
@@ -76,6 +83,8 @@ func NewLightChain(odr OdrBackend, config *params.ChainConfig, engine consensus.
 	bodyCache, _ := lru.New(bodyCacheLimit)
 	bodyRLPCache, _ := lru.New(bodyCacheLimit)
 	blockCache, _ := lru.New(blockCacheLimit)
+	leaderCache, _ := lru.New(epochCacheLimit)
+	slotDataCache, _ := lru.New(epochCacheLimit)
 
 	bc := &LightChain{
 		chainDb:      odr.Database(),
@@ -85,6 +94,9 @@ func NewLightChain(odr OdrBackend, config *params.ChainConfig, engine consensus.
 		bodyRLPCache: bodyRLPCache,
 		blockCache:   blockCache,
 		engine:       engine,
+
+		leaderCache:   leaderCache,
+		slotDataCache: slotDataCache,
 	}
 	var err error
 	bc.hc, err = core.NewHeaderChain(odr.Database(), config, bc.engine, bc.getProcInterrupt)
@@ -330,6 +342,7 @@ func (self *LightChain) postChainEvents(events []interface{}) {
 		case core.ChainEvent:
 			if self.LastBlockHash() == ev.Hash {
 				self.chainHeadFeed.Send(core.ChainHeadEvent{Block: ev.Block})
+				self.postFinalizedEvent()
 			}
 			self.chainFeed.Send(ev)
 		case core.ChainSideEvent:
@@ -338,6 +351,34 @@ func (self *LightChain) postChainEvents(events []interface{}) {
 	}
 }
 
+// postFinalizedEvent announces the finalized head of the chain if it advanced
+// since the last announcement.
+func (self *LightChain) postFinalizedEvent() {
+	header := self.CurrentFinalizedHeader()
+	if header == nil {
+		return
+	}
+	self.finalMu.Lock()
+	defer self.finalMu.Unlock()
+
+	if header.Number.Uint64() <= self.finalNumber {
+		return
+	}
+	self.finalNumber = header.Number.Uint64()
+	self.finalFeed.Send(core.ChainFinalizedEvent{Block: types.NewBlockWithHeader(header)})
+}
+
+// CurrentFinalizedHeader retrieves the most recent canonical header that can no
+// longer be reorganised away. It returns nil if the consensus engine has no
+// notion of finality or has not finalized any block yet.
+func (self *LightChain) CurrentFinalizedHeader() *types.Header {
+	fc, ok := self.engine.(consensus.ForkChoice)
+	if !ok {
+		return nil
+	}
+	return fc.Finalized(self.hc, self.hc.CurrentHeader())
+}
+
 // InsertHeaderChain attempts to insert the given header chain in to the local
 // chain, possibly creating a reorg. If an error is returned, it will return the
 // index number of the failing header as well an error describing what went wrong.
@@ -354,7 +395,37 @@ func (self *LightChain) InsertHeaderChain(chain []*types.Header, checkFreq int)
 	if i, err := self.hc.ValidateHeaderChain(chain, checkFreq); err != nil {
 		return i, err
 	}
+	// The slot proofs of an epoch are checked against epoch data anchored to the
+	// last header of the previous epoch, so every epoch is inserted before the
+	// proofs of the next one are verified.
+	for offset := 0; offset < len(chain); {
+		end := offset + 1
+		for end < len(chain) && self.headerEpoch(chain[end]) == self.headerEpoch(chain[offset]) {
+			end++
+		}
+		if i, err := self.verifySlotProofs(chain[offset:end]); err != nil {
+			return offset + i, err
+		}
+		if i, err := self.insertHeaderChain(chain[offset:end], start); err != nil {
+			return offset + i, err
+		}
+		offset = end
+	}
+	return 0, nil
+}
 
+// headerEpoch returns the epoch a pos header is sealed in, or 0 for headers
+// predating pos.
+func (self *LightChain) headerEpoch(header *types.Header) uint64 {
+	if !self.hc.Config().IsPosActive(header.Number) {
+		return 0
+	}
+	return header.Difficulty.Uint64() >> 32
+}
+
+// insertHeaderChain writes an already verified header chain and posts the
+// resulting chain events.
+func (self *LightChain) insertHeaderChain(chain []*types.Header, start time.Time) (int, error) {
 	// Make sure only one thread manipulates the chain at once
 	self.chainmu.Lock()
 	defer func() {
@@ -487,11 +558,22 @@ func (self *LightChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) e
 	return self.scope.Track(self.chainHeadFeed.Subscribe(ch))
 }
 
+// SubscribeChainFinalizedEvent registers a subscription of ChainFinalizedEvent.
+func (self *LightChain) SubscribeChainFinalizedEvent(ch chan<- core.ChainFinalizedEvent) event.Subscription {
+	return self.scope.Track(self.finalFeed.Subscribe(ch))
+}
+
 // SubscribeChainSideEvent registers a subscription of ChainSideEvent.
 func (self *LightChain) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) event.Subscription {
 	return self.scope.Track(self.chainSideFeed.Subscribe(ch))
 }
 
+// SubscribeChainReorgEvent implements the interface of ethapi.Backend
+// LightChain does not send core.ChainReorgEvent, so return an empty subscription.
+func (self *LightChain) SubscribeChainReorgEvent(ch chan<- core.ChainReorgEvent) event.Subscription {
+	return self.scope.Track(new(event.Feed).Subscribe(ch))
+}
+
 // SubscribeLogsEvent implements the interface of filters.Backend
 // LightChain does not send logs events, so return an empty subscription.
 func (self *LightChain) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
@@ -157,3 +157,14 @@ func (req *ChtRequest) StoreResult(db ethdb.Database) {
 	core.WriteCanonicalHash(db, hash, num)
 	//storeProof(db, req.Proof)
 }
+
+// EpochGenesisRequest is the ODR request type for the genesis of a pos epoch
+type EpochGenesisRequest struct {
+	OdrRequest
+	EpochId uint64
+	Genesis *types.EpochGenesis
+}
+
+// StoreResult doesn't store the genesis, the leaders it names are unproven. The
+// light chain stores it once they match the ones it selected itself.
+func (req *EpochGenesisRequest) StoreResult(db ethdb.Database) {}
@@ -57,6 +57,7 @@ var (
 type testOdr struct {
 	OdrBackend
 	sdb, ldb ethdb.Database
+	geneses  map[uint64]*types.EpochGenesis
 	disable  bool
 }
 
@@ -80,6 +81,8 @@ func (odr *testOdr) Retrieve(ctx context.Context, req OdrRequest) error {
 		req.Proof = t.Prove(req.Key)
 	case *CodeRequest:
 		req.Data, _ = odr.sdb.Get(req.Hash[:])
+	case *EpochGenesisRequest:
+		req.Genesis = odr.geneses[req.EpochId]
 	}
 	req.StoreResult(odr.ldb)
 	return nil
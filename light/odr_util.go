@@ -162,3 +162,17 @@ func GetBlockReceipts(ctx context.Context, odr OdrBackend, hash common.Hash, num
 	}
 	return r.Receipts, nil
 }
+
+// GetEpochGenesis retrieves the genesis of a pos epoch. A retrieved genesis isn't
+// stored, see EpochGenesisRequest.StoreResult.
+func GetEpochGenesis(ctx context.Context, odr OdrBackend, epochID uint64) (*types.EpochGenesis, error) {
+	genesis := core.GetEpochGenesis(odr.Database(), epochID)
+	if genesis != nil {
+		return genesis, nil
+	}
+	r := &EpochGenesisRequest{EpochId: epochID}
+	if err := odr.Retrieve(ctx, r); err != nil {
+		return nil, err
+	}
+	return r.Genesis, nil
+}
@@ -0,0 +1,228 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package light
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/pos/epochLeader"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/slotleader"
+)
+
+const (
+	// extraSeal is the length of the signer seal closing the extra-data of a
+	// pos header.
+	extraSeal = 65
+
+	// slotProofTimeout bounds the retrievals needed to verify the slot leader
+	// proofs of one header batch.
+	slotProofTimeout = 30 * time.Second
+
+	// epochCacheLimit is the number of epochs whose leaders and slot proof data
+	// are kept in memory.
+	epochCacheLimit = 8
+)
+
+var (
+	// ErrInvalidSlotProof is returned if the slot leader proof of a pos header
+	// doesn't verify against the epoch data of its epoch.
+	ErrInvalidSlotProof = errors.New("invalid slot leader proof")
+
+	// ErrInvalidEpochGenesis is returned if a retrieved epoch genesis names
+	// other leaders than the ones selected from the proven state.
+	ErrInvalidEpochGenesis = errors.New("epoch genesis leaders mismatch")
+)
+
+// epochKey identifies the data derived for an epoch from the state of a header.
+type epochKey struct {
+	hash  common.Hash
+	epoch uint64
+}
+
+// slotProofData is the epoch data the slot leader proofs of an epoch are
+// checked against, see slotleader.VerifySlotProofByLeaders.
+type slotProofData struct {
+	byGenesis bool
+	leaders   [][]byte
+	rb        []byte
+}
+
+// verifySlotProofs checks the slot leader proof of every pos header in chain,
+// all sealed in the same epoch. It returns the index of the first failing
+// header.
+func (self *LightChain) verifySlotProofs(chain []*types.Header) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), slotProofTimeout)
+	defer cancel()
+
+	var data *slotProofData
+	for i, header := range chain {
+		if header.Number.Sign() == 0 || !self.hc.Config().IsPosActive(header.Number) {
+			continue
+		}
+		var err error
+		if data == nil {
+			data, err = self.slotProofData(ctx, header)
+		}
+		if err == nil {
+			err = verifySlotProof(header, data)
+		}
+		if err != nil {
+			if !posconfig.Cfg().HeaderWarnOnly {
+				return i, err
+			}
+			log.Warn("Accepting pos header with unverified slot proof", "number", header.Number, "hash", header.Hash(), "err", err)
+		}
+	}
+	return 0, nil
+}
+
+// verifySlotProof checks the slot leader proof of a header against the data of
+// its epoch.
+func verifySlotProof(header *types.Header, data *slotProofData) error {
+	if len(header.Extra) <= extraSeal {
+		return ErrInvalidSlotProof
+	}
+	proof, proofMeg, err := slotleader.UnpackSlotProof(header.Extra[:len(header.Extra)-extraSeal])
+	if err != nil {
+		return ErrInvalidSlotProof
+	}
+	if !slotleader.VerifySlotProofByLeaders(proof, proofMeg, data.leaders, data.rb, data.byGenesis) {
+		return ErrInvalidSlotProof
+	}
+	return nil
+}
+
+// slotProofData derives the data the slot proofs of the epoch of header are
+// checked against from the state of its ancestors, retrieved and proven through
+// ODR. As on full nodes, epoch e is checked against the genesis leaders in
+// epoch 0, without leaders for epoch e-1 or if none of them completed stage
+// two, and against the leaders of epoch e-1 and the random beacon of epoch e
+// otherwise.
+func (self *LightChain) slotProofData(ctx context.Context, header *types.Header) (*slotProofData, error) {
+	epochID := self.headerEpoch(header)
+	if epochID == 0 {
+		return &slotProofData{byGenesis: true}, nil
+	}
+	parent := self.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	if parent == nil {
+		return nil, ErrNoHeader
+	}
+	anchor := self.epochLastHeader(parent, epochID-1)
+	if anchor == nil {
+		return nil, ErrNoHeader
+	}
+	key := epochKey{anchor.Hash(), epochID}
+	if data, ok := self.slotDataCache.Get(key); ok {
+		return data.(*slotProofData), nil
+	}
+	if err := self.verifyEpochGenesis(ctx, parent, epochID); err != nil {
+		return nil, err
+	}
+	leaders, err := self.epochLeaders(ctx, parent, epochID-1)
+	if err != nil {
+		return nil, err
+	}
+	data := &slotProofData{byGenesis: true}
+	if len(leaders) > 0 {
+		statedb, err := state.New(anchor.Root, NewStateDatabase(ctx, anchor, self.odr))
+		if err != nil {
+			return nil, err
+		}
+		rb := vm.GetR(statedb, epochID)
+		data.byGenesis = slotleader.UsesGenesisProofs(statedb, epochID)
+		if err := statedb.Error(); err != nil {
+			return nil, err
+		}
+		if rb == nil {
+			return nil, ErrInvalidSlotProof
+		}
+		data.leaders, data.rb = leaders, rb.Bytes()
+	}
+	self.slotDataCache.Add(key, data)
+	return data, nil
+}
+
+// verifyEpochGenesis retrieves the genesis of an epoch and stores it if it names
+// the epoch leaders selected from the proven state, so a server can't slip in a
+// forged leader set.
+func (self *LightChain) verifyEpochGenesis(ctx context.Context, head *types.Header, epochID uint64) error {
+	if core.GetEpochGenesis(self.chainDb, epochID) != nil {
+		return nil
+	}
+	genesis, err := GetEpochGenesis(ctx, self.odr, epochID)
+	if err != nil {
+		return err
+	}
+	leaders, err := self.epochLeaders(ctx, head, epochID)
+	if err != nil {
+		return err
+	}
+	if len(genesis.EpochLeaders) != len(leaders) {
+		return ErrInvalidEpochGenesis
+	}
+	for i, leader := range leaders {
+		if !bytes.Equal(genesis.EpochLeaders[i], leader) {
+			return ErrInvalidEpochGenesis
+		}
+	}
+	return core.WriteEpochGenesis(self.chainDb, genesis)
+}
+
+// epochLeaders selects the leaders of an epoch from the state of the last
+// ancestor of head sealed in epoch epochID-2, the genesis before epoch 2.
+func (self *LightChain) epochLeaders(ctx context.Context, head *types.Header, epochID uint64) ([][]byte, error) {
+	source := self.genesisBlock.Header()
+	if epochID >= 2 {
+		if source = self.epochLastHeader(head, epochID-2); source == nil {
+			return nil, ErrNoHeader
+		}
+	}
+	key := epochKey{source.Hash(), epochID}
+	if leaders, ok := self.leaderCache.Get(key); ok {
+		return leaders.([][]byte), nil
+	}
+	statedb, err := state.New(source.Root, NewStateDatabase(ctx, source, self.odr))
+	if err != nil {
+		return nil, err
+	}
+	leaders, err := epochLeader.SelectEpochLeaders(statedb, epochID)
+	if err == nil {
+		err = statedb.Error()
+	}
+	if err != nil {
+		return nil, err
+	}
+	self.leaderCache.Add(key, leaders)
+	return leaders, nil
+}
+
+// epochLastHeader returns the last header sealed in epoch epochID or before it
+// among the ancestors of head, head included.
+func (self *LightChain) epochLastHeader(head *types.Header, epochID uint64) *types.Header {
+	// Walk a side chain back to the canonical chain
+	for head != nil && self.headerEpoch(head) > epochID && core.GetCanonicalHash(self.chainDb, head.Number.Uint64()) != head.Hash() {
+		head = self.GetHeader(head.ParentHash, head.Number.Uint64()-1)
+	}
+	if head == nil || self.headerEpoch(head) <= epochID {
+		return head
+	}
+	// Epochs don't decrease along the canonical chain, search the first header
+	// sealed after epochID below head
+	first := self.hc.Config().PosFirstBlock()
+	n := sort.Search(int(head.Number.Uint64()-first), func(i int) bool {
+		header := self.GetHeaderByNumber(first + uint64(i))
+		return header == nil || self.headerEpoch(header) > epochID
+	})
+	return self.GetHeaderByNumber(first + uint64(n) - 1)
+}
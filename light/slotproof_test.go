@@ -0,0 +1,107 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package light
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/consensus/ethash"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/epochLeader"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+// Tests that the light chain selects the epoch leaders itself from the state
+// proven through ODR and rejects epoch geneses naming a forged leader set.
+func TestEpochGenesisForgedLeaders(t *testing.T) {
+	sdb, _ := ethdb.NewMemDatabase()
+	ldb, _ := ethdb.NewMemDatabase()
+
+	// Register two stakers in the genesis state of the server
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(sdb))
+	stakers := make([]*ecdsa.PrivateKey, 2)
+	for i := range stakers {
+		stakers[i], _ = crypto.GenerateKey()
+		staker := &vm.StakerInfo{
+			Address:   crypto.PubkeyToAddress(stakers[i].PublicKey),
+			PubSec256: crypto.FromECDSAPub(&stakers[i].PublicKey),
+			Amount:    new(big.Int).Mul(big.NewInt(int64(100*(i+1))), big.NewInt(params.Wan)),
+		}
+		data, _ := rlp.EncodeToBytes(staker)
+		statedb.SetStateByteArray(vm.StakersInfoAddr, vm.GetStakeInKeyHash(staker.Address), data)
+	}
+	root, err := statedb.CommitTo(sdb, false)
+	if err != nil {
+		t.Fatalf("failed to commit genesis state: %v", err)
+	}
+	genesis := types.NewBlock(&types.Header{Number: big.NewInt(0), Root: root, Difficulty: big.NewInt(1)}, nil, nil, nil)
+	core.WriteTd(ldb, genesis.Hash(), 0, genesis.Difficulty())
+	core.WriteBlock(ldb, genesis)
+	core.WriteCanonicalHash(ldb, genesis.Hash(), 0)
+	core.WriteHeadBlockHash(ldb, genesis.Hash())
+	core.WriteHeadHeaderHash(ldb, genesis.Hash())
+
+	odr := &testOdr{sdb: sdb, ldb: ldb, geneses: make(map[uint64]*types.EpochGenesis)}
+	lc, err := NewLightChain(odr, params.TestChainConfig, ethash.NewFaker(ldb))
+	if err != nil {
+		t.Fatalf("failed to create light chain: %v", err)
+	}
+	ctx := context.Background()
+
+	// The leaders selected from the proven state match the full node selection
+	fullState, _ := state.New(root, state.NewDatabase(sdb))
+	want, err := epochLeader.SelectEpochLeaders(fullState, 1)
+	if err != nil || len(want) == 0 {
+		t.Fatalf("failed to select epoch leaders: %v", err)
+	}
+	odr.disable = true
+	if leaders, err := lc.epochLeaders(ctx, genesis.Header(), 1); err == nil {
+		t.Fatalf("leaders selected without the state: %x", leaders)
+	}
+	odr.disable = false
+	leaders, err := lc.epochLeaders(ctx, genesis.Header(), 1)
+	if err != nil {
+		t.Fatalf("failed to select epoch leaders through ODR: %v", err)
+	}
+	if len(leaders) != len(want) {
+		t.Fatalf("leader count mismatch: have %d, want %d", len(leaders), len(want))
+	}
+	for i := range want {
+		if !bytes.Equal(leaders[i], want[i]) {
+			t.Fatalf("leader %d mismatch: have %x, want %x", i, leaders[i], want[i])
+		}
+	}
+
+	// A genesis naming another leader set is rejected and not stored
+	forger, _ := crypto.GenerateKey()
+	forged := &types.EpochGenesis{EpochId: 1, PreEpochLastBlkHash: genesis.Hash()}
+	for range want {
+		forged.EpochLeaders = append(forged.EpochLeaders, crypto.FromECDSAPub(&forger.PublicKey))
+	}
+	odr.geneses[1] = forged
+	if err := lc.verifyEpochGenesis(ctx, genesis.Header(), 1); err != ErrInvalidEpochGenesis {
+		t.Fatalf("forged genesis: have %v, want %v", err, ErrInvalidEpochGenesis)
+	}
+	if core.GetEpochGenesis(ldb, 1) != nil {
+		t.Fatalf("forged genesis stored")
+	}
+	// A genesis naming the selected leaders is accepted
+	odr.geneses[1] = &types.EpochGenesis{EpochId: 1, PreEpochLastBlkHash: genesis.Hash(), EpochLeaders: want}
+	if err := lc.verifyEpochGenesis(ctx, genesis.Header(), 1); err != nil {
+		t.Fatalf("genuine genesis rejected: %v", err)
+	}
+	if core.GetEpochGenesis(ldb, 1) == nil {
+		t.Fatalf("genuine genesis not stored")
+	}
+}
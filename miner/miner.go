@@ -73,6 +73,25 @@ type Miner struct {
 	canStart    int32 // can start indicates whether we can start the mining operation
 	shouldStart int32 // should start indicates whether we should start after sync
 	timerStop   chan interface{}
+
+	rpcClient *rpc.Client // client used by the pos timer loop to send stage txs, nil dials IPC
+
+	pos *Pos                       // pos parts of the node, set by SetPos
+	rb  *randombeacon.RandomBeacon // random beacon of the node, run by the pos timer loop
+}
+
+// Pos holds the pos parts of a node, each node owns its own epocher, slot
+// leader selection and incentive.
+type Pos struct {
+	Epocher   *epochLeader.Epocher
+	Sls       *slotleader.SLS
+	Incentive *incentive.Incentive
+}
+
+// posEngine is implemented by the consensus engines sealing blocks with the
+// node's slot leader selection and paying its incentives.
+type posEngine interface {
+	SetPos(sls *slotleader.SLS, inc *incentive.Incentive, clock *util.SlotClock)
 }
 
 func New(eth Backend, config *params.ChainConfig, mux *event.TypeMux, engine consensus.Engine) *Miner {
@@ -90,52 +109,56 @@ func New(eth Backend, config *params.ChainConfig, mux *event.TypeMux, engine con
 	return miner
 }
 
-func PosInit(s Backend) *epochLeader.Epocher {
+// PosInit creates the pos parts of the node and wires them into its chain and
+// consensus engine. The tx pool is not created yet, the caller sets its epocher.
+func PosInit(chain *core.BlockChain) *Pos {
 	log.Info("backendTimerLoop is running!!!!!!")
-	g := s.BlockChain().GetHeaderByNumber(0)
-	posconfig.GenesisPK = hexutil.Encode(g.Extra)[2:]
-	slotleader.SlsInit()
-
-	if posconfig.EpochBaseTime == 0 {
-		h := s.BlockChain().GetHeaderByNumber(1)
-		if nil != h {
-			posconfig.EpochBaseTime = h.Time.Uint64()
-		}
+	config := chain.Config()
+	if config.Pos != nil && config.Pos.BootstrapPK != "" {
+		posconfig.GenesisPK = config.Pos.BootstrapPK
+	} else {
+		g := chain.GetHeaderByNumber(0)
+		posconfig.GenesisPK = hexutil.Encode(g.Extra)[2:]
 	}
+	dbs := chain.PosDbs()
+
+	chain.SlotClock().CalEpochSlotIDByNow()
 
-	epochSelector := epochLeader.NewEpocher(s.BlockChain())
+	epochSelector := epochLeader.NewEpocher(chain, dbs)
 
+	if err := epochSelector.SelectLeadersLoop(0); err != nil {
+		log.Warn("Failed to select the epoch 0 leaders", "err", err)
+	}
+
+	sls := slotleader.NewSLS(dbs.Pos, epochSelector)
+	sls.Init(chain, nil, nil)
 
-	eerr := epochSelector.SelectLeadersLoop(0)
+	inc := incentive.New(epochSelector.GetEpochProbability, epochSelector.SetEpochIncentive, epochSelector.GetRBProposerGroup,
+		epochSelector.GetEpochLeaders, dbs.Incentive)
 
-	sls := slotleader.GetSlotLeaderSelection()
-	sls.Init(s.BlockChain(), nil, nil)
+	chain.SetSlSelector(sls)
+	chain.SetRbSelector(epochSelector)
+	chain.SetEpocher(epochSelector)
 
-	incentive.Init(epochSelector.GetEpochProbability, epochSelector.SetEpochIncentive, epochSelector.GetRBProposerGroup)
-	fmt.Println("posInit: ", eerr)
+	chain.SetSlotValidator(sls)
 
-	s.BlockChain().SetSlSelector(sls)
-	s.BlockChain().SetRbSelector(epochSelector)
+	if engine, ok := chain.Engine().(posEngine); ok {
+		engine.SetPos(sls, inc, chain.SlotClock())
+	}
 
-	s.BlockChain().SetSlotValidator(sls)
+	return &Pos{Epocher: epochSelector, Sls: sls, Incentive: inc}
+}
 
-	return epochSelector
+// SetPos sets the pos parts of the node run by the pos timer loop.
+func (self *Miner) SetPos(pos *Pos) {
+	self.pos = pos
 }
-func posInitMiner(s Backend, key *keystore.Key) {
+
+func (self *Miner) posInitMiner(s Backend, key *keystore.Key) {
 	log.Info("timer backendTimerLoop is running!!!!!!")
 
-	// config
-	if key != nil {
-		posconfig.Cfg().MinerKey = key
-	}
-	epochSelector := epochLeader.NewEpocher(s.BlockChain())
-	randombeacon.GetRandonBeaconInst().Init(epochSelector)
-	if posconfig.EpochBaseTime == 0 {
-		h := s.BlockChain().GetHeaderByNumber(1)
-		if nil != h {
-			posconfig.EpochBaseTime = h.Time.Uint64()
-		}
-	}
+	self.rb = randombeacon.NewRandomBeacon()
+	self.rb.Init(self.pos.Epocher, key)
 }
 
 // backendTimerLoop is pos main time loop
@@ -159,43 +182,49 @@ func (self *Miner) backendTimerLoop(s Backend) {
 	}
 	log.Debug("Get unlocked key success address:" + eb.Hex())
 	localPublicKey := hex.EncodeToString(crypto.FromECDSAPub(&key.PrivateKey.PublicKey))
-	posInitMiner(s, key)
+	// Stop signals the loop while mining, wait for it before bailing out
+	if self.pos == nil {
+		log.Error("The pos parts of the node are not set, the pos timer loop exits")
+		<-self.timerStop
+		return
+	}
 	// get rpcClient
-	url := posconfig.Cfg().NodeCfg.IPCEndpoint()
-	rc, err := rpc.Dial(url)
+	rc, err := self.posRPCClient()
 	if err != nil {
-		fmt.Println("err:", err)
-		panic(err)
+		<-self.timerStop
+		return
 	}
+	self.posInitMiner(s, key)
 
+	clock := s.BlockChain().SlotClock()
 	for {
-		// wait until block1
-		h := s.BlockChain().GetHeaderByNumber(1)
+		// wait until the first pos block
+		h := s.BlockChain().GetHeaderByNumber(s.BlockChain().Config().PosFirstBlock())
 		if nil == h {
 			select {
 			case <-self.timerStop:
-				randombeacon.GetRandonBeaconInst().Stop()
+				self.rb.Stop()
 				return
-			case <-time.After(time.Duration(time.Second)):
+			case <-clock.After(time.Duration(time.Second)):
 				continue
 			}
 
 			continue
 		} else {
-			posconfig.EpochBaseTime = h.Time.Uint64()
-			cur := uint64(time.Now().Unix())
-			if cur < posconfig.EpochBaseTime+posconfig.SlotTime {
-				time.Sleep(time.Duration((posconfig.EpochBaseTime + posconfig.SlotTime - cur)) * time.Second)
+			baseTime := h.Time.Uint64()
+			cur := clock.NowUnix()
+			if cur < baseTime+posconfig.SlotTime {
+				<-clock.After(time.Duration((baseTime + posconfig.SlotTime - cur)) * time.Second)
 			}
 		}
 
-		util.CalEpochSlotIDByNow()
-		epochid, slotid := util.GetEpochSlotID()
+		clock.CalEpochSlotIDByNow()
+		epochid, slotid := clock.GetEpochSlotID()
 		log.Debug("get current period", "epochid", epochid, "slotid", slotid)
 
-		slotleader.GetSlotLeaderSelection().Loop(rc, key, epochid, slotid)
+		self.pos.Sls.Loop(rc, key, epochid, slotid)
 
-		leaderPub, err := slotleader.GetSlotLeaderSelection().GetSlotLeader(epochid, slotid)
+		leaderPub, err := self.pos.Sls.GetSlotLeader(epochid, slotid)
 		if err == nil {
 			leader := hex.EncodeToString(crypto.FromECDSAPub(leaderPub))
 			if leader == localPublicKey {
@@ -212,25 +241,45 @@ func (self *Miner) backendTimerLoop(s Backend) {
 		}
 
 		if stateDb != nil {
-			randombeacon.GetRandonBeaconInst().Loop(stateDb, rc, epochid, slotid)
+			self.rb.Loop(stateDb, rc, epochid, slotid)
 		}
-		cur := uint64(time.Now().Unix())
-		sleepTime := posconfig.SlotTime - (cur - posconfig.EpochBaseTime - (epochid*posconfig.SlotCount+slotid)*posconfig.SlotTime)
+		cur := clock.NowUnix()
+		sleepTime := posconfig.SlotTime - (cur - clock.BaseTime() - (epochid*posconfig.SlotCount+slotid)*posconfig.SlotTime)
 		log.Debug("timeloop sleep", "sleepTime", sleepTime)
 		if sleepTime < 0 {
 			sleepTime = 0
 		}
 		select {
 		case <-self.timerStop:
-			randombeacon.GetRandonBeaconInst().Stop()
+			self.rb.Stop()
 			return
-		case <-time.After(time.Duration(time.Second * time.Duration(sleepTime))):
+		case <-clock.After(time.Duration(time.Second * time.Duration(sleepTime))):
 			continue
 		}
 	}
 	return
 }
 
+// SetPosRPCClient sets the client used by the pos timer loop to send the
+// slot leader and random beacon transactions. When it is not set the loop
+// dials the node's IPC endpoint.
+func (self *Miner) SetPosRPCClient(rc *rpc.Client) {
+	self.rpcClient = rc
+}
+
+func (self *Miner) posRPCClient() (*rpc.Client, error) {
+	if self.rpcClient != nil {
+		return self.rpcClient, nil
+	}
+	url := posconfig.Cfg().NodeCfg.IPCEndpoint()
+	rc, err := rpc.Dial(url)
+	if err != nil {
+		log.Error("Failed to dial the pos rpc client", "url", url, "err", err)
+		return nil, err
+	}
+	return rc, nil
+}
+
 // update keeps track of the downloader events. Please be aware that this is a one shot type of update loop.
 // It's entered once and as soon as `Done` or `Failed` has been broadcasted the events are unregistered and
 // the loop is exited. This to prevent a major security vuln where external parties can DOS you with blocks
@@ -283,10 +332,12 @@ func (self *Miner) Start(coinbase common.Address) {
 }
 
 func (self *Miner) Stop() {
+	wasMining := self.Mining()
 	self.worker.stop()
 	atomic.StoreInt32(&self.mining, 0)
 	atomic.StoreInt32(&self.shouldStart, 0)
-	if self.worker.config.Pluto != nil {
+	// the pos timer loop only runs while mining, don't block on a stopped one
+	if self.worker.config.Pluto != nil && wasMining {
 		self.timerStop <- nil
 	}
 }
@@ -256,8 +256,13 @@ func (self *worker) update() {
 		// A real event arrived, process interesting content
 		select {
 		// Handle ChainHeadEvent
-		case <-self.chainHeadCh:
+		case ev := <-self.chainHeadCh:
 			//self.commitNewWork()
+			// ppow seals on every new head up to the pos fork block, pos
+			// blocks after it are driven by the slot timer
+			if fork := self.config.PosForkBlock; fork != nil && ev.Block.Number().Cmp(fork) < 0 {
+				self.commitNewWork()
+			}
 		case <-self.chainSlotTimer:
 			self.commitNewWork()
 		// Handle ChainSideEvent
@@ -623,7 +628,7 @@ func (env *Work) commitTransactions(mux *event.TypeMux, txs *types.TransactionsB
 func (env *Work) commitTransaction(tx *types.Transaction, bc *core.BlockChain, coinbase common.Address, gp *core.GasPool) (error, []*types.Log) {
 	snap := env.state.Snapshot()
 
-	receipt, _, err := core.ApplyTransaction(env.config, bc, &coinbase, gp, env.state, env.header, tx, env.header.GasUsed, vm.Config{})
+	receipt, _, err := core.ApplyTransaction(env.config, bc, &coinbase, gp, env.state, env.header, tx, env.header.GasUsed, *bc.GetVMConfig())
 	if err != nil {
 		env.state.RevertToSnapshot(snap)
 		return err, nil
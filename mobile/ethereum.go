@@ -84,6 +84,9 @@ func (p *SyncProgress) GetHighestBlock() int64  { return int64(p.progress.Highes
 func (p *SyncProgress) GetPulledStates() int64  { return int64(p.progress.PulledStates) }
 func (p *SyncProgress) GetKnownStates() int64   { return int64(p.progress.KnownStates) }
 
+func (p *SyncProgress) GetPulledEpochGeneses() int64 { return int64(p.progress.PulledEpochGeneses) }
+func (p *SyncProgress) GetKnownEpochGeneses() int64  { return int64(p.progress.KnownEpochGeneses) }
+
 // Topics is a set of topic lists to filter events with.
 type Topics struct{ topics [][]common.Hash }
 
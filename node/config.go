@@ -38,6 +38,7 @@ import (
 const (
 	datadirPrivateKey      = "nodekey"            // Path within the datadir to the node's private key
 	datadirDefaultKeyStore = "keystore"           // Path within the datadir to the keystore
+	datadirViewKeys        = "viewkeys"           // Path within the keystore to the view keys of watch-only accounts
 	datadirStaticNodes     = "static-nodes.json"  // Path within the datadir to the static node list
 	datadirTrustedNodes    = "trusted-nodes.json" // Path within the datadir to the trusted node list
 	datadirNodeDatabase    = "nodes"              // Path within the datadir to store the node infos
@@ -397,6 +398,7 @@ func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
 	// Assemble the account manager and supported backends
 	backends := []accounts.Backend{
 		keystore.NewKeyStore(keydir, scryptN, scryptP),
+		keystore.NewViewKeyStore(filepath.Join(keydir, datadirViewKeys)),
 	}
 	if !conf.NoUSB {
 		// Start a USB hub for Ledger hardware wallets
@@ -18,8 +18,11 @@
 package params
 
 import (
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/big"
+	"reflect"
 
 	"github.com/wanchain/go-wanchain/common"
 )
@@ -110,6 +113,26 @@ var (
 		},
 	}
 
+	// DevPosChainConfig contains the chain parameters of the single node pos
+	// developer chain. Slots are short and the epoch only holds as many leaders
+	// as a lone validator needs to run every stage.
+	DevPosChainConfig = &ChainConfig{
+		ChainId:        big.NewInt(1337),
+		ByzantiumBlock: big.NewInt(0),
+
+		Pluto: &PlutoConfig{
+			Period: 2,
+			Epoch:  100,
+		},
+		Pos: &PosConfig{
+			SlotTime:          2,
+			K:                 2,
+			KCount:            12,
+			EpochLeaderCount:  4,
+			RandomProperCount: 5,
+		},
+	}
+
 	// AllProtocolChanges contains every protocol change (EIPs)
 	// introduced and accepted by the Ethereum core developers.
 	//
@@ -118,7 +141,7 @@ var (
 	// means that all fields must be set at all times. This forces
 	// anyone adding flags to the config to also have to set these
 	// fields.
-	AllProtocolChanges = &ChainConfig{big.NewInt(1337) /* big.NewInt(0),*/ /*nil, false,*/ /* big.NewInt(0), common.Hash{},*/ /*big.NewInt(0),*/ /*big.NewInt(0),*/, big.NewInt(0), new(EthashConfig), nil, nil}
+	AllProtocolChanges = &ChainConfig{big.NewInt(1337) /* big.NewInt(0),*/ /*nil, false,*/ /* big.NewInt(0), common.Hash{},*/ /*big.NewInt(0),*/ /*big.NewInt(0),*/, big.NewInt(0), nil, new(EthashConfig), nil, nil, nil, nil}
 
 	TestChainConfig = &ChainConfig{
 		ChainId:        big.NewInt(1),
@@ -150,11 +173,15 @@ type ChainConfig struct {
 	//EIP158Block *big.Int `json:"eip158Block,omitempty"` // EIP158 HF block
 
 	ByzantiumBlock *big.Int `json:"byzantiumBlock,omitempty"` // Byzantium switch block (nil = no fork, 0 = already on byzantium)
+	PosForkBlock   *big.Int `json:"posForkBlock,omitempty"`   // PPoW to Pluto switch block (nil = pluto from genesis if configured)
 
 	// Various consensus engines
 	Ethash *EthashConfig `json:"ethash,omitempty"`
 	Clique *CliqueConfig `json:"clique,omitempty"`
 	Pluto  *PlutoConfig  `json:"pluto,omitempty"`
+
+	Pos *PosConfig `json:"pos,omitempty"` // Pos epoch parameters (nil = DefaultPosConfig)
+	OTA *OTAConfig `json:"ota,omitempty"` // OTA denominations (nil = DefaultOTAConfig)
 }
 
 // EthashConfig is the consensus engine configs for proof-of-work based sealing.
@@ -187,10 +214,271 @@ func (c *PlutoConfig) String() string {
 	return "pluto"
 }
 
+// PosConfig is the pos epoch configuration. An epoch is divided into KCount
+// stages of K slots each, all stage boundaries are derived from these values.
+type PosConfig struct {
+	SlotTime          uint64 `json:"slotTime"`             // Time span of a slot in seconds
+	K                 uint64 `json:"k"`                    // Number of slots in a stage
+	KCount            uint64 `json:"kCount"`               // Number of stages in an epoch, at least 12
+	EpochLeaderCount  uint64 `json:"epochLeaderCount"`     // Number of epoch leaders selected by stake, at most 256
+	RandomProperCount uint64 `json:"randomProperCount"`    // Number of random proposers selected by stake, at least 4
+	ReorgSlots        uint64 `json:"reorgSlots,omitempty"` // Deepest reorg accepted in slots (0 = 2*K)
+
+	// BootstrapPK is the hex encoded public key sealing epoch 0 when pos starts
+	// at a PosForkBlock. Chains running pos from genesis take it from the
+	// genesis extra data instead.
+	BootstrapPK string `json:"bootstrapPK,omitempty"`
+}
+
+// DefaultPosConfig is the pos epoch configuration used when the genesis does
+// not specify one.
+var DefaultPosConfig = &PosConfig{
+	SlotTime:          10,
+	K:                 10,
+	KCount:            12,
+	EpochLeaderCount:  50,
+	RandomProperCount: 21,
+}
+
+// SlotCount returns the number of slots in an epoch.
+func (c *PosConfig) SlotCount() uint64 {
+	return c.K * c.KCount
+}
+
+// Validate checks that the epoch can hold every pos stage.
+func (c *PosConfig) Validate() error {
+	switch {
+	case c.SlotTime == 0:
+		return errors.New("pos slot time must be positive")
+	case c.K == 0:
+		return errors.New("pos k must be positive")
+	case c.KCount < 12:
+		return fmt.Errorf("pos k count %d too small, need at least 12 stages", c.KCount)
+	case c.EpochLeaderCount == 0 || c.EpochLeaderCount > 256:
+		return fmt.Errorf("pos epoch leader count %d out of range [1, 256]", c.EpochLeaderCount)
+	case c.RandomProperCount < 4:
+		// the random beacon's Reed-Solomon check needs more than degree+2 shares
+		return fmt.Errorf("pos random proposer count %d too small, need at least 4", c.RandomProperCount)
+	}
+	if c.BootstrapPK != "" {
+		if pk, err := hex.DecodeString(c.BootstrapPK); err != nil || len(pk) != 65 {
+			return fmt.Errorf("pos bootstrap public key %q is not an uncompressed hex key", c.BootstrapPK)
+		}
+	}
+	return nil
+}
+
+// String implements the stringer interface, returning the pos epoch details.
+func (c *PosConfig) String() string {
+	return fmt.Sprintf("{SlotTime: %v K: %v KCount: %v EpochLeaders: %v RandomPropers: %v}",
+		c.SlotTime, c.K, c.KCount, c.EpochLeaderCount, c.RandomProperCount)
+}
+
+// OTADenomination is a value the OTAs bought from the wancoin or stamp
+// contract may hold, from an activation block on.
+type OTADenomination struct {
+	Value *big.Int `json:"value"`           // Value of the OTAs in wei
+	Block *big.Int `json:"block,omitempty"` // Activation block (nil = genesis)
+}
+
+// String implements the stringer interface, returning the value and, unless
+// active from genesis, the activation block.
+func (d OTADenomination) String() string {
+	if d.Block == nil || d.Block.Sign() == 0 {
+		return fmt.Sprint(d.Value)
+	}
+	return fmt.Sprintf("%v@%v", d.Value, d.Block)
+}
+
+// active returns whether the denomination is accepted in the block num.
+func (d OTADenomination) active(num *big.Int) bool {
+	return d.Block == nil || isForked(d.Block, num)
+}
+
+// activationBlock returns the activation block, 0 for genesis.
+func (d OTADenomination) activationBlock() *big.Int {
+	if d.Block == nil {
+		return new(big.Int)
+	}
+	return d.Block
+}
+
+// OTAConfig lists the denominations of the OTAs bought from the wancoin and
+// stamp contracts. The OTAs of a denomination are stored together, so they can
+// hide each other in ring signatures. Wallets are only offered a subset of the
+// stamp denominations, so the stamps they buy hide among many others.
+type OTAConfig struct {
+	WanCoins      []OTADenomination `json:"wanCoins"`                // Denominations of the wancoin contract
+	Stamps        []OTADenomination `json:"stamps"`                  // Denominations of the stamp contract
+	OfferedStamps []*big.Int        `json:"offeredStamps,omitempty"` // Stamp values offered to wallets (nil = every stamp)
+}
+
+// String implements the stringer interface, returning the denominations.
+func (c *OTAConfig) String() string {
+	return fmt.Sprintf("{WanCoins: %v Stamps: %v OfferedStamps: %v}", c.WanCoins, c.Stamps, c.OfferedStamps)
+}
+
+func otaDenominations(values ...string) []OTADenomination {
+	denominations := make([]OTADenomination, 0, len(values))
+	for _, value := range values {
+		v, _ := new(big.Int).SetString(value, 10)
+		denominations = append(denominations, OTADenomination{Value: v, Block: new(big.Int)})
+	}
+	return denominations
+}
+
+// DefaultOTAConfig is the OTA denomination configuration used when the genesis
+// does not specify one.
+var DefaultOTAConfig = &OTAConfig{
+	WanCoins: otaDenominations(
+		"10000000000000000000",    // 10
+		"20000000000000000000",    // 20
+		"50000000000000000000",    // 50
+		"100000000000000000000",   // 100
+		"200000000000000000000",   // 200
+		"500000000000000000000",   // 500
+		"1000000000000000000000",  // 1000
+		"5000000000000000000000",  // 5000
+		"50000000000000000000000", // 50000
+	),
+	Stamps: otaDenominations(
+		"1000000000000000",   // 0.001
+		"2000000000000000",   // 0.002
+		"3000000000000000",   // 0.003
+		"5000000000000000",   // 0.005
+		"6000000000000000",   // 0.006
+		"9000000000000000",   // 0.009
+		"30000000000000000",  // 0.03
+		"60000000000000000",  // 0.06
+		"90000000000000000",  // 0.09
+		"200000000000000000", // 0.2
+		"300000000000000000", // 0.3
+		"500000000000000000", // 0.5
+	),
+	OfferedStamps: []*big.Int{
+		big.NewInt(90000000000000000),  // 0.09
+		big.NewInt(200000000000000000), // 0.2
+		big.NewInt(500000000000000000), // 0.5
+	},
+}
+
+// Validate checks that the denominations are positive and listed once per
+// contract, and that only stamp denominations are offered.
+func (c *OTAConfig) Validate() error {
+	if err := validateDenominations("wancoin", c.WanCoins); err != nil {
+		return err
+	}
+	if err := validateDenominations("stamp", c.Stamps); err != nil {
+		return err
+	}
+	for _, value := range c.OfferedStamps {
+		listed := false
+		for _, d := range c.Stamps {
+			if value != nil && d.Value.Cmp(value) == 0 {
+				listed = true
+				break
+			}
+		}
+		if !listed {
+			return fmt.Errorf("offered stamp %v is not a stamp denomination", value)
+		}
+	}
+	return nil
+}
+
+func validateDenominations(name string, denominations []OTADenomination) error {
+	seen := make(map[string]bool)
+	for _, d := range denominations {
+		if d.Value == nil || d.Value.Sign() <= 0 {
+			return fmt.Errorf("%s denomination %v is not positive", name, d.Value)
+		}
+		if d.Block != nil && d.Block.Sign() < 0 {
+			return fmt.Errorf("%s denomination %v activation block %v is negative", name, d.Value, d.Block)
+		}
+		if seen[d.Value.String()] {
+			return fmt.Errorf("%s denomination %v listed twice", name, d.Value)
+		}
+		seen[d.Value.String()] = true
+	}
+	return nil
+}
+
+// OTADenominations returns the OTA denomination configuration of the chain.
+func (c *ChainConfig) OTADenominations() *OTAConfig {
+	if c.OTA == nil {
+		return DefaultOTAConfig
+	}
+	return c.OTA
+}
+
+// WanCoinDenominations returns the values of the wancoins that can be bought
+// in the block num.
+func (c *ChainConfig) WanCoinDenominations(num *big.Int) []*big.Int {
+	return activeDenominations(c.OTADenominations().WanCoins, num)
+}
+
+// StampDenominations returns the values of the stamps that can be bought in
+// the block num.
+func (c *ChainConfig) StampDenominations(num *big.Int) []*big.Int {
+	return activeDenominations(c.OTADenominations().Stamps, num)
+}
+
+// OfferedStampDenominations returns the values of the stamps offered to
+// wallets that can be bought in the block num.
+func (c *ChainConfig) OfferedStampDenominations(num *big.Int) []*big.Int {
+	ota := c.OTADenominations()
+	if ota.OfferedStamps == nil {
+		return c.StampDenominations(num)
+	}
+	values := make([]*big.Int, 0, len(ota.OfferedStamps))
+	for _, value := range ota.OfferedStamps {
+		if isDenomination(ota.Stamps, value, num) {
+			values = append(values, new(big.Int).Set(value))
+		}
+	}
+	return values
+}
+
+// IsWanCoinDenomination returns whether a wancoin of the value can be bought
+// in the block num.
+func (c *ChainConfig) IsWanCoinDenomination(value, num *big.Int) bool {
+	return isDenomination(c.OTADenominations().WanCoins, value, num)
+}
+
+// IsStampDenomination returns whether a stamp of the value can be bought in
+// the block num.
+func (c *ChainConfig) IsStampDenomination(value, num *big.Int) bool {
+	return isDenomination(c.OTADenominations().Stamps, value, num)
+}
+
+func activeDenominations(denominations []OTADenomination, num *big.Int) []*big.Int {
+	values := make([]*big.Int, 0, len(denominations))
+	for _, d := range denominations {
+		if d.active(num) {
+			values = append(values, new(big.Int).Set(d.Value))
+		}
+	}
+	return values
+}
+
+func isDenomination(denominations []OTADenomination, value, num *big.Int) bool {
+	if value == nil {
+		return false
+	}
+	for _, d := range denominations {
+		if d.Value.Cmp(value) == 0 && d.active(num) {
+			return true
+		}
+	}
+	return false
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 	var engine interface{}
 	switch {
+	case c.Ethash != nil && c.Pluto != nil && c.PosForkBlock != nil:
+		engine = fmt.Sprintf("%v->%v@%v", c.Ethash, c.Pluto, c.PosForkBlock)
 	case c.Ethash != nil:
 		engine = c.Ethash
 	case c.Clique != nil:
@@ -201,7 +489,7 @@ func (c *ChainConfig) String() string {
 		engine = "unknown"
 	}
 	//return fmt.Sprintf("{ChainID: %v Homestead: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Engine: %v}",
-	return fmt.Sprintf("{ChainID: %v Byzantium: %v Engine: %v}",
+	return fmt.Sprintf("{ChainID: %v Byzantium: %v Engine: %v OTA: %v}",
 		c.ChainId,
 		//c.HomesteadBlock,
 		//c.DAOForkBlock,
@@ -212,6 +500,7 @@ func (c *ChainConfig) String() string {
 
 		c.ByzantiumBlock,
 		engine,
+		c.OTADenominations(),
 	)
 }
 
@@ -241,6 +530,44 @@ func (c *ChainConfig) String() string {
 //	return isForked(c.ByzantiumBlock, num)
 //}
 
+// IsPosActive returns whether the block num is sealed by pos. A pluto chain
+// without a PosForkBlock runs pos from genesis.
+func (c *ChainConfig) IsPosActive(num *big.Int) bool {
+	if c.Pluto == nil {
+		return false
+	}
+	if c.PosForkBlock == nil {
+		return true
+	}
+	return isForked(c.PosForkBlock, num)
+}
+
+// PosFirstBlock returns the number of the first block sealed by pos. Its
+// timestamp is the base time of epoch 0.
+func (c *ChainConfig) PosFirstBlock() uint64 {
+	if c.PosForkBlock == nil || c.PosForkBlock.Sign() == 0 {
+		return 1
+	}
+	return c.PosForkBlock.Uint64()
+}
+
+// posFirstBlock returns PosFirstBlock as a fork block, nil if the chain never
+// runs pos.
+func (c *ChainConfig) posFirstBlock() *big.Int {
+	if c.Pluto == nil {
+		return nil
+	}
+	return new(big.Int).SetUint64(c.PosFirstBlock())
+}
+
+// PosEpochConfig returns the pos epoch configuration of the chain.
+func (c *ChainConfig) PosEpochConfig() *PosConfig {
+	if c.Pos == nil {
+		return DefaultPosConfig
+	}
+	return c.Pos
+}
+
 // GasTable returns the gas table corresponding to the current phase (homestead or homestead reprice).
 //
 // The returned GasTable's fields shouldn't, under any circumstances, be changed.
@@ -314,9 +641,58 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	//	return newCompatError("Byzantium fork block", c.ByzantiumBlock, newcfg.ByzantiumBlock)
 	//}
 
+	if isForkIncompatible(c.PosForkBlock, newcfg.PosForkBlock, head) {
+		return newCompatError("Pos fork block", c.PosForkBlock, newcfg.PosForkBlock)
+	}
+	s1, s2 := c.posFirstBlock(), newcfg.posFirstBlock()
+	if (isForked(s1, head) || isForked(s2, head)) && !reflect.DeepEqual(c.PosEpochConfig(), newcfg.PosEpochConfig()) {
+		return newCompatError("Pos config", s1, s2)
+	}
+
+	stored, updated := c.OTADenominations(), newcfg.OTADenominations()
+	if err := checkDenominationsCompatible("wancoin", stored.WanCoins, updated.WanCoins, head); err != nil {
+		return err
+	}
+	if err := checkDenominationsCompatible("stamp", stored.Stamps, updated.Stamps, head); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// checkDenominationsCompatible checks that the denominations accepted up to
+// head are the same in both lists. A denomination missing from a list is
+// treated as a fork that never activates.
+func checkDenominationsCompatible(name string, stored, updated []OTADenomination, head *big.Int) *ConfigCompatError {
+	blocks := func(denominations []OTADenomination) map[string]*big.Int {
+		m := make(map[string]*big.Int, len(denominations))
+		for _, d := range denominations {
+			m[d.Value.String()] = d.activationBlock()
+		}
+		return m
+	}
+	storedBlocks, updatedBlocks := blocks(stored), blocks(updated)
+
+	var lowest *ConfigCompatError
+	check := func(value string) {
+		s1, s2 := storedBlocks[value], updatedBlocks[value]
+		if !isForkIncompatible(s1, s2, head) {
+			return
+		}
+		err := newCompatError(name+" denomination "+value, s1, s2)
+		if lowest == nil || err.RewindTo < lowest.RewindTo {
+			lowest = err
+		}
+	}
+	for value := range storedBlocks {
+		check(value)
+	}
+	for value := range updatedBlocks {
+		check(value)
+	}
+	return lowest
+}
+
 // isForkIncompatible returns true if a fork scheduled at s1 cannot be rescheduled to
 // block s2 because head is already past the fork.
 func isForkIncompatible(s1, s2, head *big.Int) bool {
@@ -381,6 +757,7 @@ type Rules struct {
 	ChainId *big.Int
 	//IsHomestead, IsEIP150, IsEIP155, IsEIP158 bool
 	//IsByzantium                               bool
+	IsPos bool
 }
 
 func (c *ChainConfig) Rules(num *big.Int) Rules {
@@ -390,5 +767,5 @@ func (c *ChainConfig) Rules(num *big.Int) Rules {
 	}
 	//return Rules{ChainId: new(big.Int).Set(chainId), IsHomestead: /*c.IsHomestead(num)*/false, IsEIP150: false/*c.IsEIP150(num)*/, IsEIP155: false/*c.IsEIP155(num)*/, IsEIP158:false/* c.IsEIP158(num)*/, IsByzantium: c.IsByzantium(num)}
 
-	return Rules{ChainId: new(big.Int).Set(chainId)}
+	return Rules{ChainId: new(big.Int).Set(chainId), IsPos: c.IsPosActive(num)}
 }
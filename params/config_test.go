@@ -20,6 +20,8 @@ import (
 	"math/big"
 	"reflect"
 	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
 )
 
 func TestCheckCompatible(t *testing.T) {
@@ -37,6 +39,74 @@ func TestCheckCompatible(t *testing.T) {
 			head:    9,
 			wantErr: nil,
 		},
+		{
+			stored:  &ChainConfig{PosForkBlock: big.NewInt(10)},
+			new:     &ChainConfig{PosForkBlock: big.NewInt(20)},
+			head:    9,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{PosForkBlock: big.NewInt(10)},
+			new:    &ChainConfig{PosForkBlock: big.NewInt(20)},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "Pos fork block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(20),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored:  &ChainConfig{Pluto: &PlutoConfig{}, PosForkBlock: big.NewInt(10)},
+			new:     &ChainConfig{Pluto: &PlutoConfig{}, PosForkBlock: big.NewInt(10), Pos: &PosConfig{SlotTime: 5, K: 10, KCount: 12, EpochLeaderCount: 50, RandomProperCount: 21}},
+			head:    9,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{Pluto: &PlutoConfig{}, PosForkBlock: big.NewInt(10)},
+			new:    &ChainConfig{Pluto: &PlutoConfig{}, PosForkBlock: big.NewInt(10), Pos: &PosConfig{SlotTime: 5, K: 10, KCount: 12, EpochLeaderCount: 50, RandomProperCount: 21}},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "Pos config",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored:  &ChainConfig{Pluto: &PlutoConfig{}},
+			new:     &ChainConfig{Pluto: &PlutoConfig{}, Pos: &PosConfig{SlotTime: 10, K: 10, KCount: 12, EpochLeaderCount: 50, RandomProperCount: 21}},
+			head:    15,
+			wantErr: nil,
+		},
+		{
+			stored:  &ChainConfig{OTA: &OTAConfig{Stamps: []OTADenomination{{Value: big.NewInt(1), Block: big.NewInt(10)}}}},
+			new:     &ChainConfig{OTA: &OTAConfig{Stamps: []OTADenomination{{Value: big.NewInt(1), Block: big.NewInt(20)}}}},
+			head:    9,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{OTA: &OTAConfig{Stamps: []OTADenomination{{Value: big.NewInt(1), Block: big.NewInt(10)}}}},
+			new:    &ChainConfig{OTA: &OTAConfig{Stamps: []OTADenomination{{Value: big.NewInt(1), Block: big.NewInt(20)}}}},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "stamp denomination 1",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(20),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored: &ChainConfig{OTA: &OTAConfig{}},
+			new:    &ChainConfig{OTA: &OTAConfig{WanCoins: []OTADenomination{{Value: big.NewInt(1), Block: big.NewInt(5)}}}},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "wancoin denomination 1",
+				StoredConfig: nil,
+				NewConfig:    big.NewInt(5),
+				RewindTo:     4,
+			},
+		},
 		//{
 		//	stored: AllProtocolChanges,
 		//	new:    &ChainConfig{ByzantiumBlock: nil},
@@ -79,3 +149,103 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestIsPosActive(t *testing.T) {
+	tests := []struct {
+		config *ChainConfig
+		num    int64
+		active bool
+		first  uint64
+	}{
+		{&ChainConfig{}, 5, false, 1},
+		{&ChainConfig{Pluto: &PlutoConfig{}}, 0, true, 1},
+		{&ChainConfig{Pluto: &PlutoConfig{}, PosForkBlock: big.NewInt(0)}, 5, true, 1},
+		{&ChainConfig{Pluto: &PlutoConfig{}, PosForkBlock: big.NewInt(10)}, 9, false, 10},
+		{&ChainConfig{Pluto: &PlutoConfig{}, PosForkBlock: big.NewInt(10)}, 10, true, 10},
+		{&ChainConfig{PosForkBlock: big.NewInt(10)}, 10, false, 10},
+	}
+	for i, test := range tests {
+		if active := test.config.IsPosActive(big.NewInt(test.num)); active != test.active {
+			t.Errorf("test %d: IsPosActive(%d) = %v, want %v", i, test.num, active, test.active)
+		}
+		if first := test.config.PosFirstBlock(); first != test.first {
+			t.Errorf("test %d: PosFirstBlock() = %d, want %d", i, first, test.first)
+		}
+	}
+}
+
+func TestOTADenominations(t *testing.T) {
+	config := &ChainConfig{OTA: &OTAConfig{
+		WanCoins: []OTADenomination{{Value: big.NewInt(10)}, {Value: big.NewInt(20), Block: big.NewInt(100)}},
+		Stamps:   []OTADenomination{{Value: big.NewInt(1), Block: big.NewInt(0)}},
+	}}
+	tests := []struct {
+		value, num int64
+		coin       bool
+		stamp      bool
+	}{
+		{10, 0, true, false},
+		{20, 99, false, false},
+		{20, 100, true, false},
+		{1, 0, false, true},
+		{2, 100, false, false},
+	}
+	for i, test := range tests {
+		value, num := big.NewInt(test.value), big.NewInt(test.num)
+		if coin := config.IsWanCoinDenomination(value, num); coin != test.coin {
+			t.Errorf("test %d: IsWanCoinDenomination(%d, %d) = %v, want %v", i, test.value, test.num, coin, test.coin)
+		}
+		if stamp := config.IsStampDenomination(value, num); stamp != test.stamp {
+			t.Errorf("test %d: IsStampDenomination(%d, %d) = %v, want %v", i, test.value, test.num, stamp, test.stamp)
+		}
+	}
+	if coins := config.WanCoinDenominations(big.NewInt(99)); !reflect.DeepEqual(coins, []*big.Int{big.NewInt(10)}) {
+		t.Errorf("WanCoinDenominations(99) = %v, want [10]", coins)
+	}
+
+	defaults := &ChainConfig{}
+	if n := len(defaults.WanCoinDenominations(common.Big0)); n != len(DefaultOTAConfig.WanCoins) {
+		t.Errorf("default wancoin denominations = %d, want %d", n, len(DefaultOTAConfig.WanCoins))
+	}
+	if n := len(defaults.StampDenominations(common.Big0)); n != len(DefaultOTAConfig.Stamps) {
+		t.Errorf("default stamp denominations = %d, want %d", n, len(DefaultOTAConfig.Stamps))
+	}
+	if offered := defaults.OfferedStampDenominations(common.Big0); !reflect.DeepEqual(offered, DefaultOTAConfig.OfferedStamps) {
+		t.Errorf("default offered stamps = %v, want %v", offered, DefaultOTAConfig.OfferedStamps)
+	}
+	// Every active stamp is offered unless a subset is configured
+	if offered := config.OfferedStampDenominations(common.Big0); !reflect.DeepEqual(offered, []*big.Int{big.NewInt(1)}) {
+		t.Errorf("offered stamps = %v, want [1]", offered)
+	}
+	config.OTA.Stamps = append(config.OTA.Stamps, OTADenomination{Value: big.NewInt(2), Block: big.NewInt(100)})
+	config.OTA.OfferedStamps = []*big.Int{big.NewInt(2)}
+	if offered := config.OfferedStampDenominations(big.NewInt(99)); len(offered) != 0 {
+		t.Errorf("offered stamps before activation = %v, want none", offered)
+	}
+	if offered := config.OfferedStampDenominations(big.NewInt(100)); !reflect.DeepEqual(offered, []*big.Int{big.NewInt(2)}) {
+		t.Errorf("offered stamps = %v, want [2]", offered)
+	}
+}
+
+func TestOTAConfigValidate(t *testing.T) {
+	tests := []struct {
+		config *OTAConfig
+		valid  bool
+	}{
+		{DefaultOTAConfig, true},
+		{&OTAConfig{}, true},
+		{&OTAConfig{WanCoins: []OTADenomination{{Value: big.NewInt(10)}, {Value: big.NewInt(10), Block: big.NewInt(5)}}}, false},
+		{&OTAConfig{Stamps: []OTADenomination{{Value: big.NewInt(0)}}}, false},
+		{&OTAConfig{Stamps: []OTADenomination{{}}}, false},
+		{&OTAConfig{Stamps: []OTADenomination{{Value: big.NewInt(1), Block: big.NewInt(-1)}}}, false},
+		{&OTAConfig{WanCoins: []OTADenomination{{Value: big.NewInt(1)}}, Stamps: []OTADenomination{{Value: big.NewInt(1)}}}, true},
+		{&OTAConfig{Stamps: []OTADenomination{{Value: big.NewInt(1), Block: big.NewInt(5)}}, OfferedStamps: []*big.Int{big.NewInt(1)}}, true},
+		{&OTAConfig{Stamps: []OTADenomination{{Value: big.NewInt(1)}}, OfferedStamps: []*big.Int{big.NewInt(2)}}, false},
+		{&OTAConfig{Stamps: []OTADenomination{{Value: big.NewInt(1)}}, OfferedStamps: []*big.Int{nil}}, false},
+	}
+	for i, test := range tests {
+		if err := test.config.Validate(); (err == nil) != test.valid {
+			t.Errorf("test %d: Validate() = %v, want valid %v", i, err, test.valid)
+		}
+	}
+}
@@ -70,8 +70,9 @@ const (
 	Bn256PairingBaseGas     uint64 = 100000 // Base price for an elliptic curve pairing check
 	Bn256PairingPerPointGas uint64 = 80000  // Per-point price for an elliptic curve pairing check
 
-	RequiredGasPerMixPub uint64 = 4000 // ring signature mix difficulty gas
-	GetOTAMixSetMaxSize  uint64 = 20   // Max number of mix ota set size from once getting
+	RequiredGasPerMixPub  uint64 = 4000 // ring signature mix difficulty gas
+	GetOTAMixSetMaxSize   uint64 = 20   // Max number of mix ota set size from once getting
+	CheckKeyImagesMaxSize uint64 = 100  // Max number of ota key images checked at once
 
 	//SlsStgOnePerByteGas		uint64 = 20      // per byte gas for SlsStgOnePerByteGas
 	SlsStgTwoPerByteGas		uint64 = 20      // per byte gas for SlsStgOnePerByteGas
@@ -4,17 +4,20 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/rlp"
 	"math"
 	"math/big"
 	"sort"
+	"sync"
 
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/core"
 	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/crypto/bn256"
+	"github.com/wanchain/go-wanchain/event"
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/params"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
@@ -23,8 +26,6 @@ import (
 
 var (
 	safeK = uint64(1)
-	Nr    = posconfig.RandomProperCount //num of random proposers
-	Ne    = posconfig.EpochLeaderCount  //num of epoch leaders, limited <= 256 now
 
 	Big1                                   = big.NewInt(1)
 	Big0                                   = big.NewInt(0)
@@ -42,49 +43,133 @@ var (
 )
 
 type Epocher struct {
-	rbLeadersDb     *posdb.Db
-	epochLeadersDb  *posdb.Db
-	blkChain        *core.BlockChain
-}
+	rbLeadersDb    *posdb.Db
+	epochLeadersDb *posdb.Db
+	blkChain       *core.BlockChain
+
+	reselectMu sync.Mutex // Serializes the reselection of stale leaders
+
+	blocksMu    sync.Mutex
+	epochBlocks map[uint64]uint64 // Cached number of the last block of an epoch
+	selectedID  uint64            // Latest epoch whose leaders were selected from a head
 
-var epocherInst *Epocher = nil
+	quit     chan struct{} // Closed by Stop to end the chain following loop
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
 
 type puksInfo struct {
 	PubSec256 []byte //staker’s ethereum public key
 	PubBn256  []byte //staker’s bn256 public key
 }
 
-func NewEpocher(blc *core.BlockChain) *Epocher {
-
+// NewEpocher returns the epocher of a chain, storing the leaders in the rb and
+// ep dbs of the node.
+func NewEpocher(blc *core.BlockChain, dbs *posdb.Dbs) *Epocher {
 	if blc == nil {
 		return nil
 	}
+	return NewEpocherWithDbs(blc, dbs.Rb, dbs.Ep)
+}
 
-	if epocherInst == nil {
-		epocherInst = NewEpocherWithLBN(blc, posconfig.RbLocalDB, posconfig.EpLocalDB)
+// NewEpocherWithDbs creates an epocher storing the random proposer groups in
+// rbdb and the epoch leader groups in epdb.
+func NewEpocherWithDbs(blc *core.BlockChain, rbdb *posdb.Db, epdb *posdb.Db) *Epocher {
+	inst := &Epocher{
+		rbLeadersDb:    rbdb,
+		epochLeadersDb: epdb,
+		blkChain:       blc,
+		epochBlocks:    make(map[uint64]uint64),
+		quit:           make(chan struct{}),
 	}
 
-	return epocherInst
+	if blc != nil {
+		headCh := make(chan core.ChainHeadEvent, 16)
+		reorgCh := make(chan core.ChainReorgEvent, 16)
+		inst.wg.Add(1)
+		go inst.loop(headCh, blc.SubscribeChainHeadEvent(headCh), reorgCh, blc.SubscribeChainReorgEvent(reorgCh))
+	}
+	return inst
 }
 
-func GetEpocher() *Epocher {
-	return epocherInst
+// loop follows the chain until it is stopped. It selects the leaders of the
+// next epoch once a head is sealed past the 2K+1 slot of its epoch, and the
+// leaders selected from blocks reorged out of the canonical chain again.
+func (e *Epocher) loop(headCh chan core.ChainHeadEvent, headSub event.Subscription,
+	reorgCh chan core.ChainReorgEvent, reorgSub event.Subscription) {
+	defer e.wg.Done()
+	defer headSub.Unsubscribe()
+	defer reorgSub.Unsubscribe()
+	for {
+		select {
+		case ev := <-headCh:
+			e.selectFromHead(ev.Block.Header())
+		case ev := <-reorgCh:
+			// the leaders of an epoch are selected from the blocks two epochs before
+			for epochID := ev.FromEpoch; epochID <= ev.ToEpoch+2; epochID++ {
+				e.reselectIfStale(epochID)
+			}
+		case <-headSub.Err():
+			return
+		case <-reorgSub.Err():
+			return
+		case <-e.quit:
+			return
+		}
+	}
 }
 
-func NewEpocherWithLBN(blc *core.BlockChain, rbn string, epdbn string) *Epocher {
+// Stop ends the chain following loop of the epocher and waits for it.
+func (e *Epocher) Stop() {
+	e.stopOnce.Do(func() { close(e.quit) })
+	e.wg.Wait()
+}
 
-	rbdb := posdb.NewDb(rbn)
-	epdb := posdb.NewDb(epdbn)
-	inst := &Epocher{rbdb,  epdb, blc}
+// selectFromHead records the head as the last block of its epoch so far and
+// selects the leaders of the head's epoch, and of the next one if the head is
+// past the 2K+1 slot, the chain doesn't reorg deeper than 2K slots. A batch of
+// imported blocks only signals its last head, so the leaders of the head's
+// epoch may still be missing.
+func (e *Epocher) selectFromHead(head *types.Header) {
+	if !e.blkChain.Config().IsPosActive(head.Number) {
+		return
+	}
+	epochID := head.Difficulty.Uint64() >> 32
+	slotID := (head.Difficulty.Uint64() >> 8) & 0x00FFFFFF
 
-	util.SetEpocherInst(inst)
-	return inst
+	target := epochID
+	if slotID >= 2*posconfig.K+1 {
+		target++
+	}
+
+	e.blocksMu.Lock()
+	e.epochBlocks[epochID] = head.Number.Uint64()
+	from := e.selectedID + 1
+	e.blocksMu.Unlock()
+
+	if from < epochID {
+		from = epochID
+	}
+	for id := from; id <= target; id++ {
+		if err := e.SelectLeadersLoop(id); err != nil {
+			log.Warn("Failed to select leaders", "epochID", id, "err", err)
+			return
+		}
+		e.blocksMu.Lock()
+		e.selectedID = id
+		e.blocksMu.Unlock()
+	}
 }
 
 func (e *Epocher) GetBlkChain() *core.BlockChain {
 	return e.blkChain
 }
 
+// GetRbLeadersDb returns the db the random proposer groups are stored in.
+func (e *Epocher) GetRbLeadersDb() *posdb.Db {
+	return e.rbLeadersDb
+}
+
 func (e *Epocher) GetTargetBlkNumber(epochId uint64) uint64 {
 	// TODO how to get thee target blockNumber
 	if epochId < 2 {
@@ -101,8 +186,10 @@ func (e *Epocher) GetTargetBlkNumber(epochId uint64) uint64 {
 func (e *Epocher) GetEpochLastBlkNumber(targetEpochId uint64) uint64 {
 	// TODO how to get thee target blockNumber
 
-	targetBlkNum := util.GetEpochBlock(targetEpochId)
-	if targetBlkNum == 0 {
+	e.blocksMu.Lock()
+	targetBlkNum := e.epochBlocks[targetEpochId]
+	e.blocksMu.Unlock()
+	if targetBlkNum == 0 || !e.isEpochLastBlock(targetEpochId, targetBlkNum) {
 		curNum := e.blkChain.CurrentBlock().NumberU64()
 		for {
 			curBlock := e.blkChain.GetBlockByNumber(curNum)
@@ -113,21 +200,42 @@ func (e *Epocher) GetEpochLastBlkNumber(targetEpochId uint64) uint64 {
 			curNum--
 		}
 		targetBlkNum = curNum
-		util.SetEpochBlock(targetEpochId, targetBlkNum)
+		e.blocksMu.Lock()
+		e.epochBlocks[targetEpochId] = targetBlkNum
+		e.blocksMu.Unlock()
 	}
 
 	return targetBlkNum
 }
 
+// isEpochLastBlock reports whether the canonical block number is the last one
+// sealed in an epoch, the cached numbers are wrong after a reorg.
+func (e *Epocher) isEpochLastBlock(epochId uint64, number uint64) bool {
+	header := e.blkChain.GetHeaderByNumber(number)
+	if header == nil || header.Difficulty.Uint64()>>32 > epochId {
+		return false
+	}
+	next := e.blkChain.GetHeaderByNumber(number + 1)
+	return next == nil || next.Difficulty.Uint64()>>32 > epochId
+}
+
 func (e *Epocher) SelectLeadersLoop(epochId uint64) error {
 
 	targetBlkNum := e.GetTargetBlkNumber(epochId)
+	targetBlk := e.blkChain.GetBlockByNumber(targetBlkNum)
 
-	stateDb, err := e.blkChain.StateAt(e.blkChain.GetBlockByNumber(targetBlkNum).Root())
+	stateDb, err := e.blkChain.StateAt(targetBlk.Root())
 	if err != nil {
 		return err
 	}
 
+	// Drop the leaders selected on a fork before selecting them again
+	for _, db := range []*posdb.Db{e.epochLeadersDb, e.rbLeadersDb} {
+		if anchor := db.GetAnchor(epochId, ""); anchor != nil && anchor.Hash != targetBlk.Hash() {
+			db.DeleteEpoch(epochId)
+		}
+	}
+
 	epochIdIn := epochId
 	if epochIdIn > 0 {
 		epochIdIn--
@@ -141,13 +249,40 @@ func (e *Epocher) SelectLeadersLoop(epochId uint64) error {
 
 	r := rb.Bytes()
 
-	err = e.selectLeaders(r, Ne, Nr, stateDb, epochId)
+	err = e.selectLeaders(r, posconfig.EpochLeaderCount, posconfig.RandomProperCount, stateDb, epochId)
 	if err != nil {
 		return err
 	}
 
+	// Scope the leaders to the block they were selected from
+	e.epochLeadersDb.PutAnchor(epochId, "", targetBlk.Header())
+	e.rbLeadersDb.PutAnchor(epochId, "", targetBlk.Header())
+
 	return nil
 }
+
+// isStale reports whether the leaders of an epoch were selected on a fork.
+func (e *Epocher) isStale(epochID uint64) bool {
+	return e.epochLeadersDb.IsStale(epochID, "", e.blkChain) || e.rbLeadersDb.IsStale(epochID, "", e.blkChain)
+}
+
+// reselectIfStale selects the leaders of an epoch again if the block they were
+// selected from was reorged out of the canonical chain.
+func (e *Epocher) reselectIfStale(epochID uint64) {
+	if !e.isStale(epochID) {
+		return
+	}
+	e.reselectMu.Lock()
+	defer e.reselectMu.Unlock()
+
+	if !e.isStale(epochID) {
+		return
+	}
+	log.Info("Reselecting leaders selected on a fork", "epochID", epochID)
+	if err := e.SelectLeadersLoop(epochID); err != nil {
+		log.Warn("Failed to reselect leaders", "epochID", epochID, "err", err)
+	}
+}
 func (e *Epocher) selectLeaders(r []byte, ne int, nr int, statedb *state.StateDB, epochId uint64) error {
 
 	log.Debug("select randoms", epochId, common.ToHex(r))
@@ -310,8 +445,28 @@ func (e *Epocher) createStakerProbabilityArray(statedb *state.StateDB, epochId u
 
 //samples nr random proposers by random number r（Random Beacon) from PublicKeys based on proportion of Probabilities
 func (e *Epocher) epochLeaderSelection(r []byte, nr int, ps ProposerSorter, epochId uint64) error {
+	idxs, err := epochLeaderIndexes(r, nr, ps)
+	if err != nil {
+		return err
+	}
+	log.Debug("epochLeaderSelection selecting")
+	for i, idx := range idxs {
+		log.Debug("select epoch leader", "epochid=", epochId, "idx=", i, "pub=", ps[idx].PubSec256)
+		val, err := rlp.EncodeToBytes(&ps[idx])
+		if err != nil {
+			continue
+		}
+		e.epochLeadersDb.PutWithIndex(epochId, uint64(i), "", val)
+	}
+
+	return nil
+}
+
+// epochLeaderIndexes samples the indexes of nr epoch leaders from ps by the
+// random number r, in selection order.
+func epochLeaderIndexes(r []byte, nr int, ps ProposerSorter) ([]int, error) {
 	if r == nil || nr <= 0 || len(ps) == 0 {
-		return ErrInvalidRandomProposerSelection
+		return nil, ErrInvalidRandomProposerSelection
 	}
 
 	//the last one is total properties
@@ -324,29 +479,52 @@ func (e *Epocher) epochLeaderSelection(r []byte, nr int, ps ProposerSorter, epoc
 	r0 := buffer.Bytes()       //r0 = 0||r
 	cr := crypto.Keccak256(r0) //cr = hash(r0)
 
-	//randomProposerPublicKeys := make([]*ecdsa.PublicKey, 0)  //store the selected publickeys
-	log.Debug("epochLeaderSelection selecting")
+	idxs := make([]int, nr)
 	for i := 0; i < nr; i++ {
 
 		crBig := new(big.Int).SetBytes(cr)
 		crBig = crBig.Mod(crBig, tp) //cr_big = cr mod tp
 
-		//fmt.Println("epoch leader mod tp=" + common.ToHex(crBig.Bytes()))
 		//select pki whose probability bigger than cr_big left
-		idx := sort.Search(len(ps), func(i int) bool { return ps[i].Probabilities.Cmp(crBig) > 0 })
-
-		log.Debug("select epoch leader", "epochid=", epochId, "idx=", i, "pub=", ps[idx].PubSec256)
-		//randomProposerPublicKeys = append(randomProposerPublicKeys, ps[idx].PubSec256)
-		val, err := rlp.EncodeToBytes(&ps[idx])
-		if err != nil {
-			continue
-		}
-		e.epochLeadersDb.PutWithIndex(epochId, uint64(i), "", val)
+		idxs[i] = sort.Search(len(ps), func(i int) bool { return ps[i].Probabilities.Cmp(crBig) > 0 })
 
 		cr = crypto.Keccak256(cr)
 	}
 
-	return nil
+	return idxs, nil
+}
+
+// SelectEpochLeaders selects the public keys of the leaders of an epoch from
+// statedb, the state of the last block of epoch epochId-2 (the genesis state
+// before epoch 2). It's the selection SelectLeadersLoop stores, for nodes
+// without an Epocher to verify the leaders they're handed. Read errors are
+// reported by statedb.Error().
+func SelectEpochLeaders(statedb *state.StateDB, epochId uint64) ([][]byte, error) {
+	epochIdIn := epochId
+	if epochIdIn > 0 {
+		epochIdIn--
+	}
+	rb := vm.GetR(statedb, epochIdIn)
+	if rb == nil {
+		rb = big.NewInt(1)
+	}
+
+	ps, err := new(Epocher).createStakerProbabilityArray(statedb, epochId)
+	if err != nil {
+		return nil, err
+	}
+	if len(ps) == 0 {
+		return nil, nil
+	}
+	idxs, err := epochLeaderIndexes(rb.Bytes(), posconfig.EpochLeaderCount, ps)
+	if err != nil {
+		return nil, err
+	}
+	leaders := make([][]byte, len(idxs))
+	for i, idx := range idxs {
+		leaders[i] = ps[idx].PubSec256
+	}
+	return leaders, nil
 }
 
 //*bn256.G1
@@ -391,9 +569,8 @@ func (e *Epocher) randomProposerSelection(r []byte, nr int, ps ProposerSorter, e
 
 //get epochLeaders of epochID in localdb
 func (e *Epocher) GetEpochLeaders(epochID uint64) [][]byte {
-
 	// TODO: how to cache these
-	ksarray := posdb.GetEpochLeaderGroup(epochID)
+	ksarray := e.epochLeadersDb.GetEpochLeaderGroup(epochID)
 
 	return ksarray
 
@@ -425,6 +602,12 @@ func (e *Epocher) GetRBProposerGroup(epochID uint64) []vm.Leader {
 	return g1s
 }
 
+// GetRBProposerG1 returns the bn256 keys of the random proposer group of an
+// epoch.
+func (e *Epocher) GetRBProposerG1(epochID uint64) []bn256.G1 {
+	return e.rbLeadersDb.GetRBProposerGroup(epochID)
+}
+
 func (e *Epocher) GetProposerBn256PK(epochID uint64, idx uint64, addr common.Address) []byte {
 	valSet := e.rbLeadersDb.GetStorageByteArray(epochID)
 
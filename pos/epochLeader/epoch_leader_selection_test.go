@@ -14,7 +14,7 @@ import (
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/params"
-	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/pos/posdb"
 	"math/big"
 	"testing"
 	"time"
@@ -160,13 +160,13 @@ func newTestBlockChain(fake bool) (*core.BlockChain, *core.ChainEnv) {
 
 func TestGetEpochLeaders(t *testing.T) {
 
-	epochID, slotID := util.GetEpochSlotID()
-	fmt.Println("epochID:", epochID, " slotID:", slotID)
-
    blkChain,_ := newTestBlockChain(true)
 
-	epocher1 := NewEpocherWithLBN(blkChain,"rb1","epdb1")
-	epocher2 := NewEpocherWithLBN(blkChain,"rb2","epdb2")
+	epochID, slotID := blkChain.SlotClock().GetEpochSlotID()
+	fmt.Println("epochID:", epochID, " slotID:", slotID)
+
+	epocher1 := NewEpocherWithDbs(blkChain, posdb.NewMemDb(), posdb.NewMemDb())
+	epocher2 := NewEpocherWithDbs(blkChain, posdb.NewMemDb(), posdb.NewMemDb())
 
 	epocher1.SelectLeadersLoop(0)
 
@@ -359,7 +359,7 @@ func TestGetEpochLeaders(t *testing.T) {
 //}
 func TestCalProbability(t *testing.T) {
 	blkChain, _ := newTestBlockChain(true)
-	epocherInst := NewEpocherWithLBN(blkChain, "countrb1", "countepdb1")
+	epocherInst := NewEpocherWithDbs(blkChain, posdb.NewMemDb(), posdb.NewMemDb())
 	addr := common.Address{}
 	addr.SetString("0xd1d1079cdb7249eee955ce34d90f215571c0781d")
 	amount := math.MustParseBig256("1000000000000000000000000")
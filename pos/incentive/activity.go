@@ -6,12 +6,16 @@ import (
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/log"
-	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/pos/util/convert"
 )
 
-func getEpochLeaderActivity(stateDb vm.StateDB, epochID uint64) ([]common.Address, []int) {
-	epochLeaders := util.GetEpocherInst().GetEpochLeaders(epochID)
+func (inc *Incentive) getEpochLeaderActivity(stateDb vm.StateDB, epochID uint64) ([]common.Address, []int) {
+	if inc.getEpochLeaders == nil {
+		log.Error("incentive activity getEpochLeaders == nil", "epochID", epochID)
+		return []common.Address{}, []int{}
+	}
+
+	epochLeaders := inc.getEpochLeaders(epochID)
 	if epochLeaders == nil || len(epochLeaders) == 0 {
 		log.Error("incentive activity GetEpochLeaders error", "epochID", epochID)
 		return []common.Address{}, []int{}
@@ -52,13 +56,13 @@ func getEpochLeaderActivity(stateDb vm.StateDB, epochID uint64) ([]common.Addres
 	return addrs, activity
 }
 
-func getRandomProposerActivity(stateDb vm.StateDB, epochID uint64) ([]common.Address, []int) {
-	if getRandomProposerAddress == nil {
+func (inc *Incentive) getRandomProposerActivity(stateDb vm.StateDB, epochID uint64) ([]common.Address, []int) {
+	if inc.getRandomProposerAddress == nil {
 		log.Error("incentive activity getRandomProposerAddress == nil", "epochID", epochID)
 		return []common.Address{}, []int{}
 	}
 
-	leaders := getRandomProposerAddress(epochID)
+	leaders := inc.getRandomProposerAddress(epochID)
 	addrs := make([]common.Address, len(leaders))
 	for i := 0; i < len(leaders); i++ {
 		addrs[i] = leaders[i].SecAddr
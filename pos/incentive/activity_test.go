@@ -7,12 +7,12 @@ import (
 	"testing"
 
 	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/crypto/bn256"
 
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/params"
-	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/pos/util/convert"
 )
 
@@ -76,12 +76,14 @@ func (t *TestSelectLead) GetProposerBn256PK(epochID uint64, idx uint64, addr com
 	return nil
 }
 
+func (t *TestSelectLead) GetRBProposerG1(epochID uint64) []bn256.G1 { return nil }
+
 func TestGetEpochLeaderAddressAndActivity(t *testing.T) {
 	generateTestAddrs()
 	generateTestStaker()
 
 	epochID := uint64(0)
-	util.SetEpocherInst(&TestSelectLead{})
+	testIncentive.getEpochLeaders = (&TestSelectLead{}).GetEpochLeaders
 
 	for i := 0; i < len(epAddrs); i++ {
 		epochIDBuf := convert.Uint64ToBytes(epochID)
@@ -95,7 +97,7 @@ func TestGetEpochLeaderAddressAndActivity(t *testing.T) {
 		statedb.SetStateByteArray(vm.GetSlotLeaderSCAddress(), keyHash, buf)
 	}
 
-	addrs, activity := getEpochLeaderActivity(statedb, epochID)
+	addrs, activity := testIncentive.getEpochLeaderActivity(statedb, epochID)
 
 	for i := 0; i < len(addrs); i++ {
 		if addrs[i].Hex() != epAddrs[i].Hex() {
@@ -128,11 +130,11 @@ func testSimulateData(epochID uint64, index uint32) {
 func TestGetRandomProposerActivity(t *testing.T) {
 	generateTestAddrs()
 	generateTestStaker()
-	setRBAddressInterface(testGetRBAddress)
+	testIncentive.setRBAddressInterface(testGetRBAddress)
 
 	epochID := 0
 
-	addrs, activity := getRandomProposerActivity(statedb, uint64(epochID))
+	addrs, activity := testIncentive.getRandomProposerActivity(statedb, uint64(epochID))
 
 	for i := 0; i < len(addrs); i++ {
 		if addrs[i].Hex() != rpAddrs[i].Hex() {
@@ -147,7 +149,7 @@ func TestGetRandomProposerActivity(t *testing.T) {
 		testSimulateData(uint64(epochID), uint32(i))
 	}
 
-	addrs, activity = getRandomProposerActivity(statedb, uint64(epochID))
+	addrs, activity = testIncentive.getRandomProposerActivity(statedb, uint64(epochID))
 
 	for i := 0; i < len(addrs); i++ {
 		if addrs[i].Hex() != rpAddrs[i].Hex() {
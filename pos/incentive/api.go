@@ -17,8 +17,6 @@ import (
 	"github.com/wanchain/go-wanchain/rlp"
 )
 
-var localDb *posdb.Db
-
 var (
 	dictAllTotal       = "all_total"
 	dictEpochTotal     = "epoch_total"
@@ -34,11 +32,11 @@ var (
 	dictEpochPayDetail = "epoch_pay_detail"
 )
 
-func initLocalDb(dbName string) {
-	localDb = posdb.NewDb(dbName)
+func (inc *Incentive) initLocalDb(db *posdb.Db) {
+	inc.localDb = db
 }
 
-func saveIncentiveHistory(epochID uint64, payments [][]vm.ClientIncentive) {
+func (inc *Incentive) saveIncentiveHistory(epochID uint64, payments [][]vm.ClientIncentive) {
 	if payments == nil {
 		return
 	}
@@ -48,48 +46,48 @@ func saveIncentiveHistory(epochID uint64, payments [][]vm.ClientIncentive) {
 		log.Error(err.Error())
 		return
 	}
-	localDb.Put(epochID, dictEpochPayDetail, buf)
+	inc.localDb.Put(epochID, dictEpochPayDetail, buf)
 
-	saveOtherInfomation(epochID, payments)
+	inc.saveOtherInfomation(epochID, payments)
 }
 
-func saveTotalIncentive(epochID uint64, incentives [][]vm.ClientIncentive) {
+func (inc *Incentive) saveTotalIncentive(epochID uint64, incentives [][]vm.ClientIncentive) {
 	if incentives == nil {
 		return
 	}
 	totalIncome := sumIncentive(incentives)
-	localDbAddValue(0, dictAllTotal, totalIncome)
+	inc.localDbAddValue(0, dictAllTotal, totalIncome)
 }
 
-func saveEpochTotalIncentive(epochID uint64, incentives [][]vm.ClientIncentive) {
+func (inc *Incentive) saveEpochTotalIncentive(epochID uint64, incentives [][]vm.ClientIncentive) {
 	if incentives == nil {
 		return
 	}
 	totalIncome := sumIncentive(incentives)
-	localDb.Put(epochID, dictEpochTotal, totalIncome.Bytes())
+	inc.localDb.Put(epochID, dictEpochTotal, totalIncome.Bytes())
 }
 
-func saveRemain(epochID uint64, remain *big.Int) {
+func (inc *Incentive) saveRemain(epochID uint64, remain *big.Int) {
 	if remain == nil {
 		return
 	}
-	localDb.Put(epochID, dictEpochRemain, remain.Bytes())
-	localDbAddValue(0, dictTotalRemain, remain)
+	inc.localDb.Put(epochID, dictEpochRemain, remain.Bytes())
+	inc.localDbAddValue(0, dictTotalRemain, remain)
 }
 
-func addRunTimes() {
-	localDbAddValue(0, dictRunTimes, big.NewInt(1))
+func (inc *Incentive) addRunTimes() {
+	inc.localDbAddValue(0, dictRunTimes, big.NewInt(1))
 }
 
-func saveOtherInfomation(epochID uint64, incentives [][]vm.ClientIncentive) {
-	saveTotalIncentive(epochID, incentives)
-	saveEpochTotalIncentive(epochID, incentives)
-	addRunTimes()
+func (inc *Incentive) saveOtherInfomation(epochID uint64, incentives [][]vm.ClientIncentive) {
+	inc.saveTotalIncentive(epochID, incentives)
+	inc.saveEpochTotalIncentive(epochID, incentives)
+	inc.addRunTimes()
 }
 
-func localDbGetValue(epochID uint64, key string) (*big.Int, error) {
-	total, err := localDb.Get(epochID, key)
-	if err != nil && err.Error() != "leveldb: not found" {
+func (inc *Incentive) localDbGetValue(epochID uint64, key string) (*big.Int, error) {
+	total, err := inc.localDb.Get(epochID, key)
+	if err != nil && err != posdb.ErrNotFound {
 		log.Error(err.Error())
 		return nil, err
 	}
@@ -101,9 +99,9 @@ func localDbGetValue(epochID uint64, key string) (*big.Int, error) {
 	return big.NewInt(0).SetBytes(total), nil
 }
 
-func localDbAddValue(epochID uint64, key string, value *big.Int) {
-	total, err := localDb.Get(epochID, key)
-	if err != nil && err.Error() != "leveldb: not found" {
+func (inc *Incentive) localDbAddValue(epochID uint64, key string, value *big.Int) {
+	total, err := inc.localDb.Get(epochID, key)
+	if err != nil && err != posdb.ErrNotFound {
 		log.Error(err.Error())
 		return
 	}
@@ -112,12 +110,12 @@ func localDbAddValue(epochID uint64, key string, value *big.Int) {
 		totalNum.SetBytes(total)
 	}
 	totalNum.Add(totalNum, value)
-	localDb.Put(0, key, totalNum.Bytes())
+	inc.localDb.Put(0, key, totalNum.Bytes())
 }
 
 // GetEpochPayDetail use to get detail payment array
-func GetEpochPayDetail(epochID uint64) ([][]vm.ClientIncentive, error) {
-	buf, err := localDb.Get(epochID, dictEpochPayDetail)
+func (inc *Incentive) GetEpochPayDetail(epochID uint64) ([][]vm.ClientIncentive, error) {
+	buf, err := inc.localDb.Get(epochID, dictEpochPayDetail)
 	if err != nil {
 		log.Error(err.Error())
 		return nil, err
@@ -135,28 +133,28 @@ func GetEpochPayDetail(epochID uint64) ([][]vm.ClientIncentive, error) {
 }
 
 // GetTotalIncentive get total incentive of all epoch
-func GetTotalIncentive() (*big.Int, error) {
-	return localDbGetValue(0, dictAllTotal)
+func (inc *Incentive) GetTotalIncentive() (*big.Int, error) {
+	return inc.localDbGetValue(0, dictAllTotal)
 }
 
 // GetEpochIncentive get total incentive of all epoch
-func GetEpochIncentive(epochID uint64) (*big.Int, error) {
-	return localDbGetValue(epochID, dictEpochTotal)
+func (inc *Incentive) GetEpochIncentive(epochID uint64) (*big.Int, error) {
+	return inc.localDbGetValue(epochID, dictEpochTotal)
 }
 
 // GetEpochRemain get remain of epoch input
-func GetEpochRemain(epochID uint64) (*big.Int, error) {
-	return localDbGetValue(epochID, dictEpochRemain)
+func (inc *Incentive) GetEpochRemain(epochID uint64) (*big.Int, error) {
+	return inc.localDbGetValue(epochID, dictEpochRemain)
 }
 
 // GetTotalRemain get remain of epoch input
-func GetTotalRemain() (*big.Int, error) {
-	return localDbGetValue(0, dictTotalRemain)
+func (inc *Incentive) GetTotalRemain() (*big.Int, error) {
+	return inc.localDbGetValue(0, dictTotalRemain)
 }
 
 // GetRunTimes returns incentive run times
-func GetRunTimes() (*big.Int, error) {
-	return localDbGetValue(0, dictRunTimes)
+func (inc *Incentive) GetRunTimes() (*big.Int, error) {
+	return inc.localDbGetValue(0, dictRunTimes)
 }
 
 // GetEpochGasPool use to get epoch gas pool
@@ -165,12 +163,12 @@ func GetEpochGasPool(stateDb vm.StateDB, epochID uint64) *big.Int {
 }
 
 // GetRBAddress use to get random proposer address list
-func GetRBAddress(epochID uint64) []common.Address {
-	if getRandomProposerAddress == nil {
+func (inc *Incentive) GetRBAddress(epochID uint64) []common.Address {
+	if inc.getRandomProposerAddress == nil {
 		return nil
 	}
 
-	leaders := getRandomProposerAddress(epochID)
+	leaders := inc.getRandomProposerAddress(epochID)
 	addrs := make([]common.Address, len(leaders))
 	for i := 0; i < len(leaders); i++ {
 		addrs[i] = leaders[i].SecAddr
@@ -185,16 +183,16 @@ func GetIncentivePool(stateDb *state.StateDB, epochID uint64) (*big.Int, *big.In
 }
 
 // GetEpochLeaderActivity can get the address and activity of epoch leaders
-func GetEpochLeaderActivity(stateDb vm.StateDB, epochID uint64) ([]common.Address, []int) {
-	return getEpochLeaderActivity(stateDb, epochID)
+func (inc *Incentive) GetEpochLeaderActivity(stateDb vm.StateDB, epochID uint64) ([]common.Address, []int) {
+	return inc.getEpochLeaderActivity(stateDb, epochID)
 }
 
 // GetEpochRBLeaderActivity can get the address and activity of RB leaders
-func GetEpochRBLeaderActivity(stateDb vm.StateDB, epochID uint64) ([]common.Address, []int) {
-	return getRandomProposerActivity(stateDb, epochID)
+func (inc *Incentive) GetEpochRBLeaderActivity(stateDb vm.StateDB, epochID uint64) ([]common.Address, []int) {
+	return inc.getRandomProposerActivity(stateDb, epochID)
 }
 
 // GetSlotLeaderActivity can get the address, blockCnt, and activity of slotleader
 func GetSlotLeaderActivity(chain consensus.ChainReader, epochID uint64) ([]common.Address, []int, float64) {
-	return getSlotLeaderActivity(chain, epochID, posconfig.SlotCount)
+	return getSlotLeaderActivity(chain, epochID, int(posconfig.SlotCount))
 }
@@ -2,15 +2,14 @@ package incentive
 
 import (
 	"math/big"
-	"os"
 	"testing"
 
 	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/pos/posdb"
 )
 
 func testInitDb() {
-	os.RemoveAll("/tmp/pluto/gwan/incentive")
-	initLocalDb("/tmp/pluto/gwan/incentive")
+	testIncentive.initLocalDb(posdb.NewMemDb())
 }
 
 func TestInitLocalDB(t *testing.T) {
@@ -34,9 +33,9 @@ func TestGetEpochPayDetail(t *testing.T) {
 		},
 	}
 
-	saveIncentiveHistory(epochID, nil)
-	saveIncentiveHistory(epochID, payExample)
-	pay, err := GetEpochPayDetail(epochID)
+	testIncentive.saveIncentiveHistory(epochID, nil)
+	testIncentive.saveIncentiveHistory(epochID, payExample)
+	pay, err := testIncentive.GetEpochPayDetail(epochID)
 	if err != nil {
 		t.FailNow()
 	}
@@ -49,31 +48,31 @@ func TestGetEpochPayDetail(t *testing.T) {
 		}
 	}
 
-	saveIncentiveHistory(1, payExample)
+	testIncentive.saveIncentiveHistory(1, payExample)
 
-	total, err := GetTotalIncentive()
+	total, err := testIncentive.GetTotalIncentive()
 	if total.Uint64() != 3000 || err != nil {
 		t.FailNow()
 	}
 
-	total, err = GetEpochIncentive(1)
+	total, err = testIncentive.GetEpochIncentive(1)
 	if total.Uint64() != 1500 || err != nil {
 		t.FailNow()
 	}
 
-	saveRemain(0, big.NewInt(100))
-	saveRemain(1, big.NewInt(300))
+	testIncentive.saveRemain(0, big.NewInt(100))
+	testIncentive.saveRemain(1, big.NewInt(300))
 
-	epRemain, err := GetEpochRemain(1)
+	epRemain, err := testIncentive.GetEpochRemain(1)
 	if err != nil || epRemain.Uint64() != 300 {
 		t.FailNow()
 	}
-	epRemain, err = GetTotalRemain()
+	epRemain, err = testIncentive.GetTotalRemain()
 	if err != nil || epRemain.Uint64() != 400 {
 		t.FailNow()
 	}
 
-	value, err := GetRunTimes()
+	value, err := testIncentive.GetRunTimes()
 	if err != nil || value.Uint64() != 2 {
 		t.FailNow()
 	}
@@ -10,11 +10,11 @@ import (
 )
 
 // delegate can calc the delegate division
-func delegate(addrs []common.Address, values []*big.Int, epochID uint64) ([][]vm.ClientIncentive, *big.Int, error) {
+func (inc *Incentive) delegate(addrs []common.Address, values []*big.Int, epochID uint64) ([][]vm.ClientIncentive, *big.Int, error) {
 	finalIncentive := make([][]vm.ClientIncentive, len(addrs))
 	remain := big.NewInt(0)
 	for i := 0; i < len(addrs); i++ {
-		stakers, division, totalProbility, err := getStakerInfoAndCheck(epochID, addrs[i])
+		stakers, division, totalProbility, err := inc.getStakerInfoAndCheck(epochID, addrs[i])
 		if err != nil {
 			return nil, nil, err
 		}
@@ -26,8 +26,8 @@ func delegate(addrs []common.Address, values []*big.Int, epochID uint64) ([][]vm
 	return finalIncentive, remain, nil
 }
 
-func getStakerInfoAndCheck(epochID uint64, addr common.Address) ([]vm.ClientProbability, uint64, *big.Int, error) {
-	stakers, division, totalProbility, err := getStakerInfo(epochID, addr)
+func (inc *Incentive) getStakerInfoAndCheck(epochID uint64, addr common.Address) ([]vm.ClientProbability, uint64, *big.Int, error) {
+	stakers, division, totalProbility, err := inc.getStakerInfo(epochID, addr)
 	if err != nil {
 		log.Error("getStakerInfo error", "error", err.Error())
 		return nil, 0, nil, err
@@ -11,6 +11,7 @@ import (
 	"github.com/wanchain/go-wanchain/log"
 
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posdb"
 
 	"github.com/wanchain/go-wanchain/pos/util/convert"
 
@@ -21,15 +22,19 @@ import (
 )
 
 var (
-	redutionYears            = 5
-	subsidyReductionInterval = uint64((365 * 24 * 3600 * redutionYears) / (posconfig.SlotTime * posconfig.SlotCount)) // Epoch count in 5 years
-	percentOfEpochLeader     = 20                                                                                     //20%
-	percentOfRandomProposer  = 20                                                                                     //20%
-	percentOfSlotLeader      = 60                                                                                     //60%
-	ceilingPercentS0         = 100.0                                                                                  //10%
-	openIncentive            = true                                                                                   //If the incentive function is open
+	redutionYears           = 5
+	percentOfEpochLeader    = 20    //20%
+	percentOfRandomProposer = 20    //20%
+	percentOfSlotLeader     = 60    //60%
+	ceilingPercentS0        = 100.0 //10%
+	openIncentive           = true  //If the incentive function is open
 )
 
+// subsidyReductionInterval returns the epoch count in redutionYears years.
+func subsidyReductionInterval() uint64 {
+	return uint64(365*24*3600*redutionYears) / (posconfig.SlotTime * posconfig.SlotCount)
+}
+
 const (
 	dictGasCollection = "gas_collection"
 	dictEpochRun      = "epoch_run"
@@ -37,17 +42,36 @@ const (
 	dictFinished      = "finished"
 )
 
-// Incentive is use for interface export
-type Incentive struct{}
-
-// Init should be called at init time before run
-func (inc *Incentive) Init(get GetStakerInfoFn, set SetStakerInfoFn, getRbAddr GetRandomProposerAddressFn) {
-	Init(get, set, getRbAddr)
+// Incentive pays the epoch incentives of a node, the payment history is kept
+// in the node's local db.
+type Incentive struct {
+	localDb *posdb.Db
+
+	getStakerInfo            GetStakerInfoFn
+	setStakerInfo            SetStakerInfoFn
+	getEpochLeaderInfo       GetEpochLeaderInfoFn
+	getRandomProposerInfo    GetRandomProposerInfoFn
+	getSlotLeaderInfo        GetSlotLeaderInfoFn
+	getRandomProposerAddress GetRandomProposerAddressFn
+	getEpochLeaders          GetEpochLeadersFn
 }
 
-// Run should be called in consensus finalize.
-func (inc *Incentive) Run(chain consensus.ChainReader, stateDb *state.StateDB, epochID uint64, blockNumber uint64) {
-	Run(chain, stateDb, epochID, blockNumber)
+// New creates the incentive of a node with the outsides interface of staker.
+// Should be called at the node start. The incentive history is kept in db.
+func New(get GetStakerInfoFn, set SetStakerInfoFn, getRbAddr GetRandomProposerAddressFn,
+	getEpochLeaders GetEpochLeadersFn, db *posdb.Db) *Incentive {
+	if get == nil || set == nil || getRbAddr == nil || getEpochLeaders == nil {
+		log.Error("incentive New input param error (get == nil || set == nil || getRbAddr == nil || getEpochLeaders == nil)")
+	}
+
+	inc := &Incentive{getEpochLeaders: getEpochLeaders}
+	inc.setStakerInterface(get, set)
+	inc.setActivityInterface(inc.getEpochLeaderActivity, inc.getRandomProposerActivity, getSlotLeaderActivity)
+	inc.setRBAddressInterface(getRbAddr)
+
+	inc.initLocalDb(db)
+	log.Info("--------Incentive Init Finish----------")
+	return inc
 }
 
 // AddEpochGas is called to collect gas fee for every transactions
@@ -55,25 +79,10 @@ func (inc *Incentive) AddEpochGas(stateDb vm.StateDB, gasValue *big.Int, epochID
 	AddEpochGas(stateDb, gasValue, epochID)
 }
 
-// Init is use to init the outsides interface of staker.
-// Should be called at the node start
-func Init(get GetStakerInfoFn, set SetStakerInfoFn, getRbAddr GetRandomProposerAddressFn) {
-	if get == nil || set == nil || getRbAddr == nil {
-		log.Error("incentive Init input param error (get == nil || set == nil || getRbAddr == nil)")
-	}
-
-	setStakerInterface(get, set)
-	setActivityInterface(getEpochLeaderActivity, getRandomProposerActivity, getSlotLeaderActivity)
-	setRBAddressInterface(getRbAddr)
-
-	initLocalDb("incentive")
-	log.Info("--------Incentive Init Finish----------")
-}
-
 // Run is use to run the incentive should be called in Finalize of consensus
-func Run(chain consensus.ChainReader, stateDb *state.StateDB, epochID uint64, blockNumber uint64) bool {
-	if chain == nil || stateDb == nil {
-		log.Error("incentive Run input param error (chain == nil || stateDb == nil)")
+func (inc *Incentive) Run(chain consensus.ChainReader, stateDb *state.StateDB, epochID uint64, blockNumber uint64) bool {
+	if inc == nil || chain == nil || stateDb == nil {
+		log.Error("incentive Run input param error (inc == nil || chain == nil || stateDb == nil)")
 		return false
 	}
 
@@ -87,9 +96,9 @@ func Run(chain consensus.ChainReader, stateDb *state.StateDB, epochID uint64, bl
 	total, foundation, gasPool := calculateIncentivePool(stateDb, epochID)
 	saveIncentiveIncome(total, foundation, gasPool)
 
-	epAddrs, epAct := getEpochLeaderInfo(stateDb, epochID)
-	rpAddrs, rpAct := getRandomProposerInfo(stateDb, epochID)
-	slAddrs, slBlk, slAct := getSlotLeaderInfo(chain, epochID, posconfig.SlotCount)
+	epAddrs, epAct := inc.getEpochLeaderInfo(stateDb, epochID)
+	rpAddrs, rpAct := inc.getRandomProposerInfo(stateDb, epochID)
+	slAddrs, slBlk, slAct := inc.getSlotLeaderInfo(chain, epochID, int(posconfig.SlotCount))
 
 	epochLeaderSubsidy := calcPercent(total, float64(percentOfEpochLeader))
 	randomProposerSubsidy := calcPercent(total, float64(percentOfRandomProposer))
@@ -103,7 +112,7 @@ func Run(chain consensus.ChainReader, stateDb *state.StateDB, epochID uint64, bl
 	sumRemain := big.NewInt(0).Sub(total, sum)
 	remainsAll.Add(remainsAll, sumRemain)
 
-	incentives, remains, err := epochLeaderAllocate(epochLeaderSubsidy, epAddrs, epAct, epochID)
+	incentives, remains, err := inc.epochLeaderAllocate(epochLeaderSubsidy, epAddrs, epAct, epochID)
 	if err != nil {
 		log.Error("Incentive epochLeaderAllocate error", "error", err.Error(), "epochLeaderSubsidy", epochLeaderSubsidy.String(), "epAddrs", epAddrs)
 		return false
@@ -111,7 +120,7 @@ func Run(chain consensus.ChainReader, stateDb *state.StateDB, epochID uint64, bl
 	finalIncentive = append(finalIncentive, incentives...)
 	remainsAll.Add(remainsAll, remains)
 
-	incentives, remains, err = randomProposerAllocate(randomProposerSubsidy, rpAddrs, rpAct, epochID)
+	incentives, remains, err = inc.randomProposerAllocate(randomProposerSubsidy, rpAddrs, rpAct, epochID)
 	if err != nil {
 		log.Error("Incentive randomProposerAllocate error", "error", err.Error(), "randomProposerSubsidy", randomProposerSubsidy.String(), "rpAddrs", rpAddrs)
 		return false
@@ -119,7 +128,7 @@ func Run(chain consensus.ChainReader, stateDb *state.StateDB, epochID uint64, bl
 	finalIncentive = append(finalIncentive, incentives...)
 	remainsAll.Add(remainsAll, remains)
 
-	incentives, remains, err = slotLeaderAllocate(slotLeaderSubsidy, slAddrs, slBlk, slAct, posconfig.SlotCount, epochID)
+	incentives, remains, err = inc.slotLeaderAllocate(slotLeaderSubsidy, slAddrs, slBlk, slAct, int(posconfig.SlotCount), epochID)
 	if err != nil {
 		log.Error("Incentive slotLeaderAllocate error", "slotLeaderSubsidy", slotLeaderSubsidy.String(), "slAddrs", slAddrs)
 		return false
@@ -136,12 +145,12 @@ func Run(chain consensus.ChainReader, stateDb *state.StateDB, epochID uint64, bl
 	}
 
 	addRemainIncentivePool(stateDb, epochID, remainsAll)
-	saveRemain(epochID, remainsAll)
+	inc.saveRemain(epochID, remainsAll)
 
 	pay(finalIncentive, stateDb)
 
-	setStakerInfo(epochID, finalIncentive)
-	saveIncentiveHistory(epochID, finalIncentive)
+	inc.setStakerInfo(epochID, finalIncentive)
+	inc.saveIncentiveHistory(epochID, finalIncentive)
 
 	finished(stateDb, epochID)
 	log.Info("--------Incentive Run Success Finish----------", "epochID", epochID)
@@ -170,7 +179,7 @@ func finished(stateDb *state.StateDB, epochID uint64) {
 }
 
 // protocalRunerAllocate use to calc the subsidy of protocal Participant (Epoch leader and Random proposer)
-func protocalRunerAllocate(funds *big.Int, addrs []common.Address, acts []int,
+func (inc *Incentive) protocalRunerAllocate(funds *big.Int, addrs []common.Address, acts []int,
 	epochID uint64) ([][]vm.ClientIncentive, *big.Int, error) {
 	remains := big.NewInt(0)
 	count := len(addrs)
@@ -199,7 +208,7 @@ func protocalRunerAllocate(funds *big.Int, addrs []common.Address, acts []int,
 		}
 	}
 
-	finalIncentive, subRemain, err := delegate(fundAddrs, fundValues, epochID)
+	finalIncentive, subRemain, err := inc.delegate(fundAddrs, fundValues, epochID)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -209,19 +218,19 @@ func protocalRunerAllocate(funds *big.Int, addrs []common.Address, acts []int,
 }
 
 // epochLeaderAllocate input funds, address and activity returns address and its amount allocate and remaining funds.
-func epochLeaderAllocate(funds *big.Int, addrs []common.Address, acts []int,
+func (inc *Incentive) epochLeaderAllocate(funds *big.Int, addrs []common.Address, acts []int,
 	epochID uint64) ([][]vm.ClientIncentive, *big.Int, error) {
-	return protocalRunerAllocate(funds, addrs, acts, epochID)
+	return inc.protocalRunerAllocate(funds, addrs, acts, epochID)
 }
 
 //randomProposerAllocate input funds, address and activity returns address and its amount allocate and remaining funds.
-func randomProposerAllocate(funds *big.Int, addrs []common.Address, acts []int,
+func (inc *Incentive) randomProposerAllocate(funds *big.Int, addrs []common.Address, acts []int,
 	epochID uint64) ([][]vm.ClientIncentive, *big.Int, error) {
-	return protocalRunerAllocate(funds, addrs, acts, epochID)
+	return inc.protocalRunerAllocate(funds, addrs, acts, epochID)
 }
 
 //slotLeaderAllocate input funds, address, blocks and activity returns address and its amount allocate and remaining funds.
-func slotLeaderAllocate(funds *big.Int, addrs []common.Address, blocks []int,
+func (inc *Incentive) slotLeaderAllocate(funds *big.Int, addrs []common.Address, blocks []int,
 	act float64, slotCount int, epochID uint64) ([][]vm.ClientIncentive, *big.Int, error) {
 	remains := big.NewInt(0)
 
@@ -243,7 +252,7 @@ func slotLeaderAllocate(funds *big.Int, addrs []common.Address, blocks []int,
 		fundValues = append(fundValues, big.NewInt(0).Mul(incentiveActive, big.NewInt(int64(blocks[i]))))
 	}
 
-	finalIncentive, subRemain, err := delegate(fundAddrs, fundValues, epochID)
+	finalIncentive, subRemain, err := inc.delegate(fundAddrs, fundValues, epochID)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -10,12 +10,15 @@ import (
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posdb"
 )
 
 // Prepare a simulate stateDB ---------------------------------------------
 var (
 	db, _      = ethdb.NewMemDatabase()
 	statedb, _ = state.New(common.Hash{}, state.NewDatabase(db))
+
+	testIncentive = &Incentive{}
 )
 
 func TestRun(t *testing.T) {
@@ -25,8 +28,8 @@ func TestRun(t *testing.T) {
 	testTimes := 1
 
 	for i := 0; i < testTimes; i++ {
-		for m := 0; m < posconfig.SlotCount; m++ {
-			if !Run(&TestChainReader{}, statedb, uint64(i), uint64(m)) {
+		for m := uint64(0); m < posconfig.SlotCount; m++ {
+			if !testIncentive.Run(&TestChainReader{}, statedb, uint64(i), m) {
 				t.FailNow()
 			}
 		}
@@ -53,7 +56,7 @@ func TestRun(t *testing.T) {
 }
 
 func TestRunFail(t *testing.T) {
-	if Run(nil, nil, 0, 0) {
+	if testIncentive.Run(nil, nil, 0, 0) {
 		t.FailNow()
 	}
 }
@@ -92,9 +95,12 @@ func TestCheckTotalValue(t *testing.T) {
 }
 
 func TestInit(t *testing.T) {
-	Init(getInfo, setInfo, testGetRBAddress)
+	inc := New(getInfo, setInfo, testGetRBAddress, (&TestSelectLead{}).GetEpochLeaders, posdb.NewMemDb())
+	if inc.getEpochLeaderInfo == nil || inc.getRandomProposerAddress == nil || inc.localDb == nil {
+		t.FailNow()
+	}
 }
 
 func TestInitFail(t *testing.T) {
-	Init(nil, nil, nil)
+	New(nil, nil, nil, nil, posdb.NewMemDb())
 }
@@ -26,32 +26,23 @@ type GetSlotLeaderInfoFn func(chain consensus.ChainReader, epochID uint64, slotC
 // GetRandomProposerAddressFn is use to get rb group address
 type GetRandomProposerAddressFn func(epochID uint64) []vm.Leader
 
-var getStakerInfo GetStakerInfoFn
-
-var setStakerInfo SetStakerInfoFn
-
-var getEpochLeaderInfo GetEpochLeaderInfoFn
-
-var getRandomProposerInfo GetRandomProposerInfoFn
-
-var getSlotLeaderInfo GetSlotLeaderInfoFn
-
-var getRandomProposerAddress GetRandomProposerAddressFn
+// GetEpochLeadersFn is use to get the epoch leader public keys
+type GetEpochLeadersFn func(epochID uint64) [][]byte
 
 // setStakerInterface is used for Staker module to set its interface
-func setStakerInterface(get GetStakerInfoFn, set SetStakerInfoFn) {
-	getStakerInfo = get
-	setStakerInfo = set
+func (inc *Incentive) setStakerInterface(get GetStakerInfoFn, set SetStakerInfoFn) {
+	inc.getStakerInfo = get
+	inc.setStakerInfo = set
 }
 
 // setActivityInterface is used for get activty module to set its interface
-func setActivityInterface(getEpl GetEpochLeaderInfoFn, getRnp GetRandomProposerInfoFn, getSlr GetSlotLeaderInfoFn) {
-	getEpochLeaderInfo = getEpl
-	getRandomProposerInfo = getRnp
-	getSlotLeaderInfo = getSlr
+func (inc *Incentive) setActivityInterface(getEpl GetEpochLeaderInfoFn, getRnp GetRandomProposerInfoFn, getSlr GetSlotLeaderInfoFn) {
+	inc.getEpochLeaderInfo = getEpl
+	inc.getRandomProposerInfo = getRnp
+	inc.getSlotLeaderInfo = getSlr
 }
 
 // setRBAddressInterface is used to get random proposer address of epoch
-func setRBAddressInterface(getRBAddress GetRandomProposerAddressFn) {
-	getRandomProposerAddress = getRBAddress
+func (inc *Incentive) setRBAddressInterface(getRBAddress GetRandomProposerAddressFn) {
+	inc.getRandomProposerAddress = getRBAddress
 }
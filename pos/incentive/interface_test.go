@@ -57,13 +57,13 @@ func generateTestAddrs() {
 
 	for i := 0; i < addrsCount; i++ {
 		slAddrs[i] = epAddrs[i]
-		slBlks[i] = posconfig.SlotCount / addrsCount
+		slBlks[i] = int(posconfig.SlotCount) / addrsCount
 	}
 }
 
 func TestSetActivityInterface(t *testing.T) {
 	generateTestAddrs()
-	setActivityInterface(testgetEpLeader, testgetRProposer, testgetSltLeader)
+	testIncentive.setActivityInterface(testgetEpLeader, testgetRProposer, testgetSltLeader)
 }
 
 var (
@@ -127,5 +127,5 @@ func TestSetStakerInterface(t *testing.T) {
 	// fmt.Println(delegateStakerMap)
 	// fmt.Println(delegateStakerProbilityMap)
 
-	setStakerInterface(getInfo, setInfo)
+	testIncentive.setStakerInterface(getInfo, setInfo)
 }
@@ -39,10 +39,10 @@ func TestCalcBaseSubsidy(t *testing.T) {
 func TestGetBaseSubsidyTotalForSlot(t *testing.T) {
 	statedb.Reset(common.Hash{})
 	year := big.NewInt(0).Mul(big.NewInt(2.1e6), big.NewInt(1e18))
-	base := calcBaseSubsidy(year, posconfig.SlotTime).Uint64()
+	base := calcBaseSubsidy(year, int64(posconfig.SlotTime)).Uint64()
 	for i := uint64(1); i < uint64(500); i++ {
-		fmt.Println(subsidyReductionInterval)
-		subsidy := getBaseSubsidyTotalForSlot(statedb, subsidyReductionInterval*i)
+		fmt.Println(subsidyReductionInterval())
+		subsidy := getBaseSubsidyTotalForSlot(statedb, subsidyReductionInterval()*i)
 		fmt.Println(subsidy.String(), float64(subsidy.Uint64())/float64(1e18))
 		if subsidy.Uint64() == 0 {
 			fmt.Println("finish", i)
@@ -3,7 +3,6 @@ package posapi
 import (
 	"encoding/hex"
 	"fmt"
-	"time"
 
 	"github.com/wanchain/go-wanchain/params"
 
@@ -22,6 +21,7 @@ import (
 	"encoding/binary"
 
 	"github.com/wanchain/go-wanchain/consensus"
+	"github.com/wanchain/go-wanchain/core"
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/internal/ethapi"
@@ -35,13 +35,25 @@ import (
 type PosApi struct {
 	chain   consensus.ChainReader
 	backend ethapi.Backend
+	dbs     *posdb.Dbs
+	epocher *epochLeader.Epocher
+	sls     *slotleader.SLS
+	inc     *incentive.Incentive
 }
 
-func APIs(chain consensus.ChainReader, backend ethapi.Backend) []rpc.API {
+var (
+	errNoSls       = errors.New("slot leader selection does not exist")
+	errNoIncentive = errors.New("incentive does not exist")
+)
+
+// APIs returns the pos APIs of a node keeping its pos data in dbs, backed by
+// the node's epocher, slot leader selection and incentive.
+func APIs(chain consensus.ChainReader, backend ethapi.Backend, dbs *posdb.Dbs, epocher *epochLeader.Epocher,
+	sls *slotleader.SLS, inc *incentive.Incentive) []rpc.API {
 	return []rpc.API{{
 		Namespace: "pos",
 		Version:   "1.0",
-		Service:   &PosApi{chain, backend},
+		Service:   &PosApi{chain, backend, dbs, epocher, sls, inc},
 		Public:    true,
 	}}
 }
@@ -53,7 +65,7 @@ func (a PosApi) Version() string {
 func (a PosApi) GetSlotLeadersByEpochID(epochID uint64) map[string]string {
 	infoMap := make(map[string]string, 0)
 	for i := uint64(0); i < posconfig.SlotCount; i++ {
-		buf, err := posdb.GetDb().GetWithIndex(epochID, i, slotleader.SlotLeader)
+		buf, err := a.dbs.Pos.GetWithIndex(epochID, i, slotleader.SlotLeader)
 		if err != nil {
 			infoMap[fmt.Sprintf("%06d", i)] = fmt.Sprintf("epochID:%d, index:%d, error:%s \n", epochID, i, err.Error())
 		} else {
@@ -71,9 +83,7 @@ func (a PosApi) GetEpochLeadersByEpochID(epochID uint64) (map[string]string, err
 		GetEpochLeaders(epochID uint64) [][]byte
 	}
 
-	//	selector := posdb.GetEpocherInst()
-	selector := epochLeader.GetEpocher()
-
+	selector := a.epocher
 	if selector == nil {
 		return nil, errors.New("GetEpocherInst error")
 	}
@@ -89,7 +99,10 @@ func (a PosApi) GetEpochLeadersByEpochID(epochID uint64) (map[string]string, err
 }
 
 func (a PosApi) GetLocalPK() (string, error) {
-	pk, err := slotleader.GetSlotLeaderSelection().GetLocalPublicKey()
+	if a.sls == nil {
+		return "nil", errNoSls
+	}
+	pk, err := a.sls.GetLocalPublicKey()
 	if err != nil {
 		return "nil", err
 	}
@@ -102,11 +115,14 @@ func (a PosApi) GetBootNodePK() string {
 }
 
 func (a PosApi) GetSlotScCallTimesByEpochID(epochID uint64) uint64 {
-	return vm.GetSlotScCallTimes(epochID)
+	return vm.GetSlotScCallTimes(a.dbs.Pos, epochID)
 }
 
 func (a PosApi) GetSmaByEpochID(epochID uint64) (map[string]string, error) {
-	pks, _, err := slotleader.GetSlotLeaderSelection().GetSma(epochID)
+	if a.sls == nil {
+		return nil, errNoSls
+	}
+	pks, _, err := a.sls.GetSma(epochID)
 	if err != nil {
 		return nil, err
 	}
@@ -121,7 +137,7 @@ func (a PosApi) GetSmaByEpochID(epochID uint64) (map[string]string, error) {
 }
 
 func (a PosApi) GetRandomProposersByEpochID(epochID uint64) map[string]string {
-	leaders := posdb.GetRBProposerGroup(epochID)
+	leaders := a.dbs.Rb.GetRBProposerGroup(epochID)
 	info := make(map[string]string, 0)
 	for i := 0; i < len(leaders); i++ {
 		info[fmt.Sprintf("%06d", i)] = hex.EncodeToString(leaders[i].Marshal())
@@ -130,7 +146,10 @@ func (a PosApi) GetRandomProposersByEpochID(epochID uint64) map[string]string {
 }
 
 func (a PosApi) GetSlotCreateStatusByEpochID(epochID uint64) bool {
-	return slotleader.GetSlotLeaderSelection().GetSlotCreateStatusByEpochID(epochID)
+	if a.sls == nil {
+		return false
+	}
+	return a.sls.GetSlotCreateStatusByEpochID(epochID)
 }
 
 func (a PosApi) Random(epochId uint64, blockNr int64) (*big.Int, error) {
@@ -148,10 +167,7 @@ func (a PosApi) Random(epochId uint64, blockNr int64) (*big.Int, error) {
 }
 
 func (a PosApi) GetReorg(epochID uint64) ([]uint64, error) {
-	reOrgDb := posdb.GetDbByName("forkdb")
-	if reOrgDb == nil {
-		return nil, errors.New("not find db")
-	}
+	reOrgDb := a.dbs.Fork
 
 	var forkNum, reOrgNum, reOrgLen uint64
 
@@ -203,7 +219,7 @@ type StakerInfo struct {
 
 func (a PosApi) GetEpochStakerInfo(epochID uint64, addr common.Address) (StakerInfo, error) {
 	skInfo := StakerInfo{}
-	epocherInst := epochLeader.GetEpocher()
+	epocherInst := a.epocher
 	if epocherInst == nil {
 		return skInfo, errors.New("epocher instance does not exist")
 	}
@@ -235,7 +251,7 @@ type StakerJson struct {
 // this is the static snap of stekers by the block Number.
 func (a PosApi) GetStakerInfo(targetBlkNum uint64) ([]StakerJson, error) {
 	stakers := make([]StakerJson, 0)
-	epocherInst := epochLeader.GetEpocher()
+	epocherInst := a.epocher
 	if epocherInst == nil {
 		return stakers, errors.New("epocher instance do not exist")
 	}
@@ -273,11 +289,11 @@ func (a PosApi) GetStakerInfo(targetBlkNum uint64) ([]StakerJson, error) {
 }
 
 func (a PosApi) GetEpochStakerInfoAll(epochID uint64) ([]StakerInfo, error) {
-	targetBlkNum := epochLeader.GetEpocher().GetTargetBlkNumber(epochID)
-	epocherInst := epochLeader.GetEpocher()
+	epocherInst := a.epocher
 	if epocherInst == nil {
 		return nil, errors.New("epocher instance do not exist")
 	}
+	targetBlkNum := epocherInst.GetTargetBlkNumber(epochID)
 	block := epocherInst.GetBlkChain().GetBlockByNumber(targetBlkNum)
 	if block == nil {
 		return nil, errors.New("Unkown block")
@@ -324,32 +340,52 @@ func biToString(value *big.Int, err error) (string, error) {
 	return value.String(), err
 }
 func (a PosApi) GetEpochIncentivePayDetail(epochID uint64) ([][]vm.ClientIncentive, error) {
-	return incentive.GetEpochPayDetail(epochID)
+	if a.inc == nil {
+		return nil, errNoIncentive
+	}
+	return a.inc.GetEpochPayDetail(epochID)
 }
 
 func (a PosApi) GetTotalIncentive() (string, error) {
-	return biToString(incentive.GetTotalIncentive())
+	if a.inc == nil {
+		return "", errNoIncentive
+	}
+	return biToString(a.inc.GetTotalIncentive())
 }
 
 func (a PosApi) GetEpochIncentive(epochID uint64) (string, error) {
-	return biToString(incentive.GetEpochIncentive(epochID))
+	if a.inc == nil {
+		return "", errNoIncentive
+	}
+	return biToString(a.inc.GetEpochIncentive(epochID))
 }
 
 func (a PosApi) GetEpochRemain(epochID uint64) (string, error) {
-	return biToString(incentive.GetEpochRemain(epochID))
+	if a.inc == nil {
+		return "", errNoIncentive
+	}
+	return biToString(a.inc.GetEpochRemain(epochID))
 }
 
 func (a PosApi) GetTotalRemain() (string, error) {
-	return biToString(incentive.GetTotalRemain())
+	if a.inc == nil {
+		return "", errNoIncentive
+	}
+	return biToString(a.inc.GetTotalRemain())
 }
 
 func (a PosApi) GetIncentiveRunTimes() (string, error) {
-	return biToString(incentive.GetRunTimes())
+	if a.inc == nil {
+		return "", errNoIncentive
+	}
+	return biToString(a.inc.GetRunTimes())
 }
 
 func (a PosApi) GetEpochGasPool(epochID uint64) (string, error) {
-	s := slotleader.GetSlotLeaderSelection()
-	db, err := s.GetCurrentStateDb()
+	if a.sls == nil {
+		return "", errNoSls
+	}
+	db, err := a.sls.GetCurrentStateDb()
 	if err != nil {
 		return "", err
 	}
@@ -357,12 +393,17 @@ func (a PosApi) GetEpochGasPool(epochID uint64) (string, error) {
 }
 
 func (a PosApi) GetRBAddress(epochID uint64) []common.Address {
-	return incentive.GetRBAddress(epochID)
+	if a.inc == nil {
+		return nil
+	}
+	return a.inc.GetRBAddress(epochID)
 }
 
 func (a PosApi) GetIncentivePool(epochID uint64) ([]string, error) {
-	s := slotleader.GetSlotLeaderSelection()
-	db, err := s.GetCurrentStateDb()
+	if a.sls == nil {
+		return nil, errNoSls
+	}
+	db, err := a.sls.GetCurrentStateDb()
 	if err != nil {
 		return nil, err
 	}
@@ -372,42 +413,50 @@ func (a PosApi) GetIncentivePool(epochID uint64) ([]string, error) {
 
 // GetActivity get epoch leader, random proposer, slot leader 's addresses and activity
 func (a PosApi) GetActivity(epochID uint64) (*incentive.Activity, error) {
-	s := slotleader.GetSlotLeaderSelection()
+	if a.sls == nil {
+		return nil, errNoSls
+	}
+	if a.inc == nil {
+		return nil, errNoIncentive
+	}
+	s := a.sls
 	db, err := s.GetCurrentStateDb()
 	if err != nil {
 		return nil, err
 	}
 
 	activity := incentive.Activity{}
-	activity.EpLeader, activity.EpActivity = incentive.GetEpochLeaderActivity(db, epochID)
-	activity.RpLeader, activity.RpActivity = incentive.GetEpochRBLeaderActivity(db, epochID)
+	activity.EpLeader, activity.EpActivity = a.inc.GetEpochLeaderActivity(db, epochID)
+	activity.RpLeader, activity.RpActivity = a.inc.GetEpochRBLeaderActivity(db, epochID)
 	activity.SltLeader, activity.SlBlocks, activity.SlActivity = incentive.GetSlotLeaderActivity(s.GetChainReader(), epochID)
 	return &activity, nil
 }
 
 func (a PosApi) GetEpochID() uint64 {
-	ep, _ := util.CalEpochSlotID(uint64(time.Now().Unix()))
+	clock := util.ChainSlotClock(a.chain)
+	ep, _ := clock.CalEpochSlotID(clock.NowUnix())
 	return ep
 }
 
 func (a PosApi) GetSlotID() uint64 {
-	_, sl := util.CalEpochSlotID(uint64(time.Now().Unix()))
+	clock := util.ChainSlotClock(a.chain)
+	_, sl := clock.CalEpochSlotID(clock.NowUnix())
 	return sl
 }
 
 func (a PosApi) GetSlotCount() int {
-	return posconfig.SlotCount
+	return int(posconfig.SlotCount)
 }
 
 func (a PosApi) GetSlotTime() int {
-	return posconfig.SlotTime
+	return int(posconfig.SlotTime)
 }
 
 // CalProbability use to calc the probability of a staker with amount by stake wan coins.
 // The probability is different in different time, so you should input each epoch ID you want to calc
 // Such as CalProbability(390, 10000, 60, 360) means begin from epoch 360 lock 60 epochs stake 10000 to calc 390's probability.
 func (a PosApi) CalProbability(epochId uint64, amountCoin uint64, lockTime uint64, startEpochId uint64) (string, error) {
-	epocherInst := epochLeader.GetEpocher()
+	epocherInst := a.epocher
 	if epocherInst == nil {
 		return "", errors.New("epocher instance do not exist")
 	}
@@ -418,3 +467,105 @@ func (a PosApi) CalProbability(epochId uint64, amountCoin uint64, lockTime uint6
 	probablity := epocherInst.CalProbability(epochId, amountWin, lockTime, startEpochId)
 	return biToString(probablity, nil)
 }
+
+// GetFinalizedBlock returns the most recent block that can no longer be
+// reorganised away, in the same format as eth_getBlockByNumber.
+func (a PosApi) GetFinalizedBlock(ctx context.Context, fullTx bool) (map[string]interface{}, error) {
+	return ethapi.NewPublicBlockChainAPI(a.backend).GetBlockByNumber(ctx, rpc.FinalizedBlockNumber, fullTx)
+}
+
+// NewFinalizedBlocks sends a notification with the header of the finalized
+// block each time the finalized head of the chain advances.
+func (a PosApi) NewFinalizedBlocks(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		finalCh := make(chan core.ChainFinalizedEvent)
+		finalSub := a.backend.SubscribeChainFinalizedEvent(finalCh)
+		defer finalSub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-finalCh:
+				notifier.Notify(rpcSub.ID, ev.Block.Header())
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// RPCReorg is the notification sent to the subscribers of pos reorgs.
+type RPCReorg struct {
+	OldHead              common.Hash    `json:"oldHead"`
+	OldHeadNumber        hexutil.Uint64 `json:"oldHeadNumber"`
+	NewHead              common.Hash    `json:"newHead"`
+	NewHeadNumber        hexutil.Uint64 `json:"newHeadNumber"`
+	CommonAncestor       common.Hash    `json:"commonAncestor"`
+	CommonAncestorNumber hexutil.Uint64 `json:"commonAncestorNumber"`
+	Depth                hexutil.Uint64 `json:"depth"`
+	FromEpoch            hexutil.Uint64 `json:"fromEpoch"`
+	FromSlot             hexutil.Uint64 `json:"fromSlot"`
+	ToEpoch              hexutil.Uint64 `json:"toEpoch"`
+	ToSlot               hexutil.Uint64 `json:"toSlot"`
+	Dropped              []common.Hash  `json:"dropped"`
+	Added                []common.Hash  `json:"added"`
+}
+
+func newRPCReorg(ev core.ChainReorgEvent) *RPCReorg {
+	return &RPCReorg{
+		OldHead:              ev.OldHead.Hash(),
+		OldHeadNumber:        hexutil.Uint64(ev.OldHead.Number.Uint64()),
+		NewHead:              ev.NewHead.Hash(),
+		NewHeadNumber:        hexutil.Uint64(ev.NewHead.Number.Uint64()),
+		CommonAncestor:       ev.CommonAncestor.Hash(),
+		CommonAncestorNumber: hexutil.Uint64(ev.CommonAncestor.Number.Uint64()),
+		Depth:                hexutil.Uint64(ev.Depth),
+		FromEpoch:            hexutil.Uint64(ev.FromEpoch),
+		FromSlot:             hexutil.Uint64(ev.FromSlot),
+		ToEpoch:              hexutil.Uint64(ev.ToEpoch),
+		ToSlot:               hexutil.Uint64(ev.ToSlot),
+		Dropped:              ev.Dropped,
+		Added:                ev.Added,
+	}
+}
+
+// Reorgs sends a notification each time the canonical chain is reorganised,
+// carrying both heads, the common ancestor, the depth, the slot range affected
+// and the dropped and added block hashes.
+func (a PosApi) Reorgs(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		reorgCh := make(chan core.ChainReorgEvent)
+		reorgSub := a.backend.SubscribeChainReorgEvent(reorgCh)
+		defer reorgSub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-reorgCh:
+				notifier.Notify(rpcSub.ID, newRPCReorg(ev))
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
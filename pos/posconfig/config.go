@@ -1,17 +1,11 @@
 package posconfig
 
 import (
-	"math/big"
-
-	"github.com/wanchain/go-wanchain/accounts/keystore"
-	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/node"
-	bn256 "github.com/wanchain/go-wanchain/crypto/bn256"
+	"github.com/wanchain/go-wanchain/params"
 )
 
 var (
-	// EpochBaseTime is the pos start time such as: 2018-12-12 00:00:00 == 1544544000
-	EpochBaseTime = uint64(0)
 	// SelfTestMode config whether it is in a simlate tese mode
 	SelfTestMode = false
 )
@@ -20,49 +14,123 @@ const (
 	RbLocalDB  = "rblocaldb"
 	EpLocalDB  = "eplocaldb"
 	PosLocalDB = "pos"
+
+	IncentiveLocalDB = "incentive"
+	ForkLocalDB      = "forkdb"
+	EpochGenLocalDB  = "epochGendb"
 )
 
 const (
+	//Incentive should perform delay some epochs.
+	IncentiveDelayEpochs = 1
+)
+
+// The pos epoch parameters below are loaded from the chain config by Init.
+var (
 	// EpochLeaderCount is count of pk in epoch leader group which is select by stake
 	EpochLeaderCount = 50
 	// RandomProperCount is count of pk in random leader group which is select by stake
 	RandomProperCount = 21
 	// SlotTime is the time span of a slot in second, So it's 1 hours for a epoch
-	SlotTime = 10
-	// GenesisPK is the epoch0 pk
-
+	SlotTime = uint64(10)
 
-	//Incentive should perform delay some epochs.
-	IncentiveDelayEpochs = 1
-	IncentiveStartStage  = Stage2K
+	IncentiveStartStage uint64
 
 	// K count of each epoch
-	KCount = 12
-	K      = 10
+	KCount = uint64(12)
+	K      = uint64(10)
 	// SlotCount is slot count in an epoch
+	SlotCount uint64
+
+	// Stage1K is divde a epoch into KCount pieces
+	Stage1K  uint64
+	Stage2K  uint64
+	Stage3K  uint64
+	Stage4K  uint64
+	Stage5K  uint64
+	Stage6K  uint64
+	Stage7K  uint64
+	Stage8K  uint64
+	Stage9K  uint64
+	Stage10K uint64
+	Stage11K uint64
+	Stage12K uint64
+
+	Sma1Start uint64
+	Sma1End   uint64
+	Sma2Start uint64
+	Sma2End   uint64
+	Sma3Start uint64
+	Sma3End   uint64
+
+	// MaxReorgSlots is the deepest reorg in slots the fork choice accepts,
+	// blocks older than that are final.
+	MaxReorgSlots uint64
+)
+
+func init() {
+	setEpochParams(params.DefaultPosConfig)
+}
+
+// Init loads the pos epoch parameters from the chain config and derives the
+// stage boundaries and DKG windows from them. A nil config selects
+// params.DefaultPosConfig.
+func Init(c *params.PosConfig) error {
+	if c == nil {
+		c = params.DefaultPosConfig
+	}
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	setEpochParams(c)
+	return nil
+}
+
+func setEpochParams(c *params.PosConfig) {
+	EpochLeaderCount = int(c.EpochLeaderCount)
+	RandomProperCount = int(c.RandomProperCount)
+	SlotTime = c.SlotTime
+	K = c.K
+	KCount = c.KCount
 	SlotCount = K * KCount
 
-	// Stage1K is divde a epoch into 10 pieces
-	Stage1K  = uint64(K)
-	Stage2K  = Stage1K * 2
-	Stage3K  = Stage1K * 3
-	Stage4K  = Stage1K * 4
-	Stage5K  = Stage1K * 5
-	Stage6K  = Stage1K * 6
-	Stage7K  = Stage1K * 7
-	Stage8K  = Stage1K * 8
-	Stage9K  = Stage1K * 9
+	Stage1K = K
+	Stage2K = Stage1K * 2
+	Stage3K = Stage1K * 3
+	Stage4K = Stage1K * 4
+	Stage5K = Stage1K * 5
+	Stage6K = Stage1K * 6
+	Stage7K = Stage1K * 7
+	Stage8K = Stage1K * 8
+	Stage9K = Stage1K * 9
 	Stage10K = Stage1K * 10
 	Stage11K = Stage1K * 11
 	Stage12K = Stage1K * 12
 
 	Sma1Start = 0
-	Sma1End   = Stage3K
+	Sma1End = Stage3K
 	Sma2Start = Stage6K
-	Sma2End   = Stage8K
+	Sma2End = Stage8K
 	Sma3Start = Stage10K
-	Sma3End   = Stage12K
-)
+	Sma3End = Stage12K
+
+	IncentiveStartStage = Stage2K
+
+	MaxReorgSlots = c.ReorgSlots
+	if MaxReorgSlots == 0 {
+		MaxReorgSlots = Stage2K
+	}
+
+	DefaultConfig.PolymDegree = uint(RandomProperCount-1) / 2
+	DefaultConfig.K = uint(K)
+	DefaultConfig.RBThres = DefaultConfig.PolymDegree + 1
+	DefaultConfig.Dkg1End = Stage2K - 1
+	DefaultConfig.Dkg2Begin = Stage4K
+	DefaultConfig.Dkg2End = Stage6K - 1
+	DefaultConfig.SignBegin = Stage8K
+	DefaultConfig.SignEnd = Stage10K - 1
+}
+
 var GenesisPK = "04dc40d03866f7335e40084e39c3446fe676b021d1fcead11f2e2715e10a399b498e8875d348ee40358545e262994318e4dcadbc865bcf9aac1fc330f22ae2c786"
 type Config struct {
 	PolymDegree   uint
@@ -70,56 +138,27 @@ type Config struct {
 	RBThres       uint
 	EpochInterval uint64
 	PosStartTime  int64
-	MinerKey      *keystore.Key
-	Dbpath        string
 	NodeCfg       *node.Config
 	Dkg1End       uint64
 	Dkg2Begin     uint64
 	Dkg2End       uint64
 	SignBegin     uint64
 	SignEnd       uint64
-}
 
-var DefaultConfig = Config{
-	10,
-	K,
-	11,
-	0,
-	0,
-	nil,
-	"",
-	nil,
-	Stage2K - 1,
-	Stage4K,
-	Stage6K - 1,
-	Stage8K,
-	Stage10K - 1,
-}
+	// HeaderWarnOnly makes the strict pos header checks log failures instead
+	// of rejecting the headers, for rolling the checks out on a live network.
+	HeaderWarnOnly bool
 
-func Cfg() *Config {
-	return &DefaultConfig
+	// RetainEpochs is the number of epochs before the current one whose local
+	// pos data is kept, older epochs are pruned. 0 keeps everything.
+	RetainEpochs uint64
 }
 
-func (c *Config) GetMinerAddr() common.Address {
-	if c.MinerKey == nil {
-		return common.Address{}
-	}
+// DefaultConfig holds the node's pos settings, the polynomial degree, K and
+// the DKG windows are filled in from the epoch parameters by Init.
+var DefaultConfig = Config{}
 
-	return c.MinerKey.Address
-}
-
-func (c *Config) GetMinerBn256PK() *bn256.G1 {
-	if c.MinerKey == nil {
-		return nil
-	}
-
-	return new(bn256.G1).Set(c.MinerKey.PrivateKey3.PublicKeyBn256.G1)
+func Cfg() *Config {
+	return &DefaultConfig
 }
 
-func (c *Config) GetMinerBn256SK() *big.Int {
-	if c.MinerKey == nil {
-		return nil
-	}
-
-	return new(big.Int).Set(c.MinerKey.PrivateKey3.D)
-}
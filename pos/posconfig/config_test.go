@@ -0,0 +1,53 @@
+package posconfig
+
+import (
+	"testing"
+
+	"github.com/wanchain/go-wanchain/params"
+)
+
+func TestInitDerivesStages(t *testing.T) {
+	defer Init(nil)
+
+	c := &params.PosConfig{SlotTime: 2, K: 3, KCount: 14, EpochLeaderCount: 8, RandomProperCount: 5}
+	if err := Init(c); err != nil {
+		t.Fatal(err)
+	}
+	if SlotCount != 42 || SlotTime != 2 || EpochLeaderCount != 8 || RandomProperCount != 5 {
+		t.Fatalf("epoch params not loaded: slots %d, slot time %d, leaders %d, propers %d",
+			SlotCount, SlotTime, EpochLeaderCount, RandomProperCount)
+	}
+	if Stage2K != 6 || Sma1End != 9 || Sma2Start != 18 || Sma2End != 24 || Sma3Start != 30 || Sma3End != 36 {
+		t.Fatalf("stage boundaries not derived from k: %d %d %d %d %d %d", Stage2K, Sma1End, Sma2Start, Sma2End, Sma3Start, Sma3End)
+	}
+	cfg := Cfg()
+	if cfg.Dkg1End != 5 || cfg.Dkg2Begin != 12 || cfg.Dkg2End != 17 || cfg.SignBegin != 24 || cfg.SignEnd != 29 {
+		t.Fatalf("dkg windows not derived from k: %+v", cfg)
+	}
+	if cfg.PolymDegree != 2 || cfg.RBThres != 3 {
+		t.Fatalf("random beacon threshold: degree %d, threshold %d", cfg.PolymDegree, cfg.RBThres)
+	}
+}
+
+func TestInitDefaults(t *testing.T) {
+	if err := Init(nil); err != nil {
+		t.Fatal(err)
+	}
+	if SlotCount != 120 || EpochLeaderCount != 50 || RandomProperCount != 21 || SlotTime != 10 {
+		t.Fatalf("unexpected default epoch params")
+	}
+	if Cfg().PolymDegree != 10 || Cfg().RBThres != 11 || Cfg().SignEnd != Stage10K-1 {
+		t.Fatalf("unexpected default random beacon config: %+v", Cfg())
+	}
+}
+
+func TestInitRejectsShortEpoch(t *testing.T) {
+	defer Init(nil)
+
+	if err := Init(&params.PosConfig{SlotTime: 10, K: 10, KCount: 11, EpochLeaderCount: 50, RandomProperCount: 21}); err == nil {
+		t.Fatal("epoch shorter than 12 stages accepted")
+	}
+	if SlotCount != 120 {
+		t.Fatalf("rejected config was applied: slot count %d", SlotCount)
+	}
+}
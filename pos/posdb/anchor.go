@@ -0,0 +1,105 @@
+package posdb
+
+import (
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/pos/util/convert"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+// anchorPrefix prefixes the keys recording the anchors of an epoch.
+const anchorPrefix = "anchor_"
+
+// Anchor is the block the entries stored for an epoch were derived from.
+// Entries anchored to a block that was reorged out of the canonical chain are
+// stale and have to be derived again.
+type Anchor struct {
+	Number uint64
+	Hash   common.Hash
+}
+
+// HeaderReader retrieves the canonical headers anchors are resolved against.
+type HeaderReader interface {
+	GetHeaderByNumber(number uint64) *types.Header
+}
+
+// Canonical reports whether the anchor block is part of the canonical chain.
+func (a *Anchor) Canonical(chain HeaderReader) bool {
+	header := chain.GetHeaderByNumber(a.Number)
+	return header != nil && header.Hash() == a.Hash
+}
+
+// PutAnchor records the block the entries stored for an epoch under key were
+// derived from.
+func (s *Db) PutAnchor(epochID uint64, key string, header *types.Header) error {
+	value, err := rlp.EncodeToBytes(&Anchor{Number: header.Number.Uint64(), Hash: header.Hash()})
+	if err != nil {
+		return err
+	}
+	if _, err = s.put(epochID, 0, anchorPrefix+key, value, false); err != nil {
+		return err
+	}
+
+	names := s.anchorNames(epochID)
+	for _, name := range names {
+		if name == key {
+			return nil
+		}
+	}
+	value, err = rlp.EncodeToBytes(append(names, key))
+	if err != nil {
+		return err
+	}
+	_, err = s.putNoCount(0, s.getAnchorListName(epochID), value)
+	return err
+}
+
+// GetAnchor returns the block the entries stored for an epoch under key were
+// derived from, nil if none was recorded.
+func (s *Db) GetAnchor(epochID uint64, key string) *Anchor {
+	value, err := s.GetWithIndex(epochID, 0, anchorPrefix+key)
+	if err != nil {
+		return nil
+	}
+	anchor := new(Anchor)
+	if err := rlp.DecodeBytes(value, anchor); err != nil {
+		return nil
+	}
+	return anchor
+}
+
+// IsStale reports whether the entries stored for an epoch under key were
+// derived from a block that isn't part of the canonical chain anymore. Entries
+// without an anchor are never stale.
+func (s *Db) IsStale(epochID uint64, key string, chain HeaderReader) bool {
+	anchor := s.GetAnchor(epochID, key)
+	return anchor != nil && !anchor.Canonical(chain)
+}
+
+// anchorNames returns the keys anchors were recorded under for an epoch.
+func (s *Db) anchorNames(epochID uint64) []string {
+	var names []string
+
+	value, err := s.Get(0, s.getAnchorListName(epochID))
+	if err != nil {
+		return nil
+	}
+	if err := rlp.DecodeBytes(value, &names); err != nil {
+		return nil
+	}
+	return names
+}
+
+// anchorKeys returns the db keys of the anchors recorded for an epoch and of
+// the list naming them.
+func (s *Db) anchorKeys(epochID uint64) [][]byte {
+	var keys [][]byte
+	for _, name := range s.anchorNames(epochID) {
+		keys = append(keys, s.getUniqueKeyBytes(epochID, 0, anchorPrefix+name))
+	}
+	return append(keys, s.getUniqueKeyBytes(0, 0, s.getAnchorListName(epochID)))
+}
+
+func (s *Db) getAnchorListName(epochID uint64) string {
+	return "anchors_" + convert.Uint64ToString(epochID)
+}
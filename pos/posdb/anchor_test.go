@@ -0,0 +1,51 @@
+package posdb
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/core/types"
+)
+
+type testHeaderReader map[uint64]*types.Header
+
+func (r testHeaderReader) GetHeaderByNumber(number uint64) *types.Header {
+	return r[number]
+}
+
+func TestAnchor(t *testing.T) {
+	db := NewMemDb()
+
+	canonical := &types.Header{Number: big.NewInt(10), Extra: []byte("canonical")}
+	fork := &types.Header{Number: big.NewInt(10), Extra: []byte("fork")}
+	chain := testHeaderReader{10: canonical}
+
+	db.PutWithIndex(3, 0, "", []byte{1})
+	if db.IsStale(3, "", chain) {
+		t.Errorf("entries without anchor reported stale")
+	}
+	if err := db.PutAnchor(3, "", fork); err != nil {
+		t.Fatalf("failed to store anchor: %v", err)
+	}
+	if anchor := db.GetAnchor(3, ""); anchor == nil || anchor.Hash != fork.Hash() || anchor.Number != 10 {
+		t.Fatalf("anchor mismatch: have %+v", anchor)
+	}
+	if !db.IsStale(3, "", chain) {
+		t.Errorf("entries anchored to a fork reported canonical")
+	}
+	db.PutAnchor(3, "", canonical)
+	if db.IsStale(3, "", chain) {
+		t.Errorf("entries anchored to the canonical chain reported stale")
+	}
+	// Anchors don't show up in the entries of the epoch
+	if have := len(db.GetStorageByteArray(3)); have != 1 {
+		t.Errorf("entries mismatch: have %d, want 1", have)
+	}
+	// Anchors are deleted together with the epoch
+	if _, err := db.DeleteEpoch(3); err != nil {
+		t.Fatalf("failed to delete epoch: %v", err)
+	}
+	if anchor := db.GetAnchor(3, ""); anchor != nil {
+		t.Errorf("anchor left after deleting the epoch: %+v", anchor)
+	}
+}
@@ -1,13 +1,10 @@
 package posdb
 
 import (
-	"io/ioutil"
+	"fmt"
 	"math/big"
-	"os"
-	"path"
-	"strings"
-	"sync"
 
+	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/wanchain/go-wanchain/rlp"
 
 	"github.com/wanchain/go-wanchain/ethdb"
@@ -19,89 +16,91 @@ import (
 
 //Db is the wanpos leveldb class
 type Db struct {
-	db *ethdb.LDBDatabase
+	db ethdb.Database
 }
 
-var (
-	dbInstMap = make(map[string]*Db)
-	//RANDOMBEACON_DB_KEY = "PosRandomBeacon"
-
-	mu sync.RWMutex
-)
-
-func NewDb(fileName string) *Db {
-	mu.Lock()
-	defer mu.Unlock()
-	db := GetDbByName(fileName)
-	if db != nil {
-		return db
-	}
-
-	dbInst := &Db{db: nil}
-
-	dbInst.DbInit(fileName)
-
-	dbInstMap[fileName] = dbInst
-
-	return dbInst
-}
-
-var dbInstance *Db
-
-func init() {
-	dbInstance = NewDb("")
+// NewDbWithDatabase creates a Db storing its entries in the given database.
+func NewDbWithDatabase(db ethdb.Database) *Db {
+	return &Db{db: db}
 }
 
-// DbInitAll init all db files
-func DbInitAll(pathname string) {
-	posconfig.Cfg().Dbpath = pathname
-	dbInstance = NewDb(posconfig.PosLocalDB)
-	NewDb(posconfig.RbLocalDB)
-	NewDb(posconfig.EpLocalDB)
+// NewMemDb creates a Db kept in memory, for tests.
+func NewMemDb() *Db {
+	db, _ := ethdb.NewMemDatabase()
+	return NewDbWithDatabase(db)
 }
 
-//GetDb can get a Db instance to use
-func GetDb() *Db {
-	return dbInstance
+// OpenDb creates a Db backed by a leveldb in the given directory.
+func OpenDb(dirname string) (*Db, error) {
+	db, err := ethdb.NewLDBDatabase(dirname, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return NewDbWithDatabase(db), nil
 }
 
-func GetDbByName(name string) *Db {
-	return dbInstMap[name]
+// ErrNotFound is returned for keys without an entry, whatever database backs
+// the Db.
+var ErrNotFound = leveldb.ErrNotFound
+
+// Dbs are the pos databases of a node. They are opened by the node and handed
+// to the pos components using them.
+type Dbs struct {
+	Pos       *Db // slot leader selection data
+	Rb        *Db // random proposer groups
+	Ep        *Db // epoch leaders
+	Incentive *Db // incentive history
+	Fork      *Db // fork and reorg counters
+	EpochGen  *Db // epoch geneses
 }
 
-//DbInit use to init leveldb in this object, user should not use this. It is automate called in init().
-func (s *Db) DbInit(dbPath string) {
-	var dirname string
-	var err error
-	if dbPath == "" {
-		dirname, err = ioutil.TempDir(os.TempDir(), "wanpos_tmpdb_")
+// OpenDbs opens the pos databases of a node, open opening a database by name,
+// e.g. with node.ServiceContext.OpenDatabase.
+func OpenDbs(open func(name string) (ethdb.Database, error)) (*Dbs, error) {
+	dbs := new(Dbs)
+	for _, entry := range dbs.entries() {
+		db, err := open(entry.name)
 		if err != nil {
-			panic("failed to create wanpos_tmpdb file: " + err.Error())
-		}
-	} else {
-		nameIdx := strings.LastIndex(dbPath, string(os.PathSeparator))
-		if nameIdx < 0 {
-			dirname = path.Join(posconfig.Cfg().Dbpath, "gwan")
-			dirname = path.Join(dirname, dbPath)
-		} else {
-			dbPath = path.Join(dbPath, "gwan")
-			dirname = path.Join(dbPath, "wanposdb")
+			dbs.Close()
+			return nil, fmt.Errorf("failed to open posdb %s: %v", entry.name, err)
 		}
+		*entry.db = NewDbWithDatabase(db)
 	}
+	return dbs, nil
+}
 
-	if s.db != nil {
-		s.db.Close()
-	}
-
-	inst, ok := dbInstMap[dbPath]
+// NewMemDbs creates pos databases kept in memory, for tests.
+func NewMemDbs() *Dbs {
+	dbs, _ := OpenDbs(func(string) (ethdb.Database, error) {
+		return ethdb.NewMemDatabase()
+	})
+	return dbs
+}
 
-	if ok {
-		inst.DbClose()
+// Close closes the databases opened.
+func (d *Dbs) Close() {
+	for _, entry := range d.entries() {
+		if *entry.db != nil {
+			(*entry.db).DbClose()
+		}
 	}
+}
 
-	s.db, err = ethdb.NewLDBDatabase(dirname, 0, 0)
-	if err != nil {
-		panic("failed to create wanpos_tmpdb database: " + dbPath + "_" + err.Error())
+// entries names the databases of d.
+func (d *Dbs) entries() []struct {
+	name string
+	db   **Db
+} {
+	return []struct {
+		name string
+		db   **Db
+	}{
+		{posconfig.PosLocalDB, &d.Pos},
+		{posconfig.RbLocalDB, &d.Rb},
+		{posconfig.EpLocalDB, &d.Ep},
+		{posconfig.IncentiveLocalDB, &d.Incentive},
+		{posconfig.ForkLocalDB, &d.Fork},
+		{posconfig.EpochGenLocalDB, &d.EpochGen},
 	}
 }
 
@@ -136,6 +135,9 @@ func (s *Db) GetWithIndex(epochID uint64, index uint64, key string) ([]byte, err
 	ret, err := s.db.Get(newKey)
 	if err != nil {
 		//debug.PrintStack()
+		if has, herr := s.db.Has(newKey); herr == nil && !has {
+			err = ErrNotFound
+		}
 	}
 	return ret, err
 }
@@ -248,15 +250,10 @@ type Proposer struct {
 	Probabilities *big.Int
 }
 
-func GetRBProposerGroup(epochId uint64) []bn256.G1 {
-	db := NewDb(posconfig.RbLocalDB)
-	if db == nil {
-		// todo : os.Exit ??
-		log.Error("GetRBProposerGroup create db error")
-		return nil
-	}
-
-	proposersArray := db.GetStorageByteArray(epochId)
+// GetRBProposerGroup returns the bn256 keys of the random proposer group of an
+// epoch.
+func (s *Db) GetRBProposerGroup(epochId uint64) []bn256.G1 {
+	proposersArray := s.GetStorageByteArray(epochId)
 	length := len(proposersArray)
 	g1s := make([]bn256.G1, length, length)
 
@@ -275,14 +272,11 @@ func GetRBProposerGroup(epochId uint64) []bn256.G1 {
 	return g1s
 
 }
-func GetEpochLeaderGroup(epochId uint64) [][]byte {
-	db := NewDb(posconfig.EpLocalDB)
-	if db == nil {
-		log.Error("GetEpochLeaderGroup create db error")
-		return nil
-	}
 
-	proposersArray := db.GetStorageByteArray(epochId)
+// GetEpochLeaderGroup returns the secp256k1 keys of the epoch leaders of an
+// epoch.
+func (s *Db) GetEpochLeaderGroup(epochId uint64) [][]byte {
+	proposersArray := s.GetStorageByteArray(epochId)
 	length := len(proposersArray)
 	pks := make([][]byte, length, length)
 
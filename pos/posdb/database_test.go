@@ -2,11 +2,13 @@ package posdb
 
 import (
 	"encoding/hex"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/wanchain/go-wanchain/crypto"
-	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/pos/util/convert"
 )
@@ -59,22 +61,19 @@ func TestUint64Convert(t *testing.T) {
 	}
 }
 
-func TestDbInitAll(t *testing.T) {
-	os.RemoveAll("/tmp/gwan")
-	DbInitAll("/tmp")
-	db := NewDb(posconfig.PosLocalDB)
-	if db == nil {
-		t.Fail()
+func TestOpenDbs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "posdb")
+	if err != nil {
+		t.Fatal(err)
 	}
+	defer os.RemoveAll(dir)
 
-	db = NewDb(posconfig.RbLocalDB)
-	if db == nil {
-		t.Fail()
+	open := func(name string) (ethdb.Database, error) {
+		return ethdb.NewLDBDatabase(filepath.Join(dir, name), 0, 0)
 	}
-
-	db = NewDb(posconfig.EpLocalDB)
-	if db == nil {
-		t.Fail()
+	dbs, err := OpenDbs(open)
+	if err != nil {
+		t.Fatalf("failed to open dbs: %v", err)
 	}
 
 	testCount := 1000
@@ -91,18 +90,17 @@ func TestDbInitAll(t *testing.T) {
 
 	go func() {
 		for i := 0; i < testCount; i++ {
-			db := NewDb(posconfig.PosLocalDB)
-			db.PutWithIndex(0, uint64(i), "", keys[i])
+			dbs.Pos.PutWithIndex(0, uint64(i), "", keys[i])
 		}
 
 		for i := 0; i < testCount; i++ {
-			value, err := db.GetWithIndex(0, uint64(i), "")
+			value, err := dbs.Pos.GetWithIndex(0, uint64(i), "")
 			if hex.EncodeToString(value) != hex.EncodeToString(keys[i]) || err != nil {
 				t.Fail()
 			}
 		}
 
-		bufs := db.GetStorageByteArray(0)
+		bufs := dbs.Pos.GetStorageByteArray(0)
 		for i := 0; i < testCount; i++ {
 			if hex.EncodeToString(bufs[i]) != hex.EncodeToString(keys[i]) {
 				t.Fail()
@@ -114,18 +112,17 @@ func TestDbInitAll(t *testing.T) {
 
 	go func() {
 		for i := 0; i < testCount; i++ {
-			db := NewDb(posconfig.RbLocalDB)
-			db.PutWithIndex(0, uint64(i), "", keys[i])
+			dbs.Rb.PutWithIndex(0, uint64(i), "", keys[i])
 		}
 
 		for i := 0; i < testCount; i++ {
-			value, err := db.GetWithIndex(0, uint64(i), "")
+			value, err := dbs.Rb.GetWithIndex(0, uint64(i), "")
 			if hex.EncodeToString(value) != hex.EncodeToString(keys[i]) || err != nil {
 				t.Fail()
 			}
 		}
 
-		bufs := db.GetStorageByteArray(0)
+		bufs := dbs.Rb.GetStorageByteArray(0)
 		for i := 0; i < testCount; i++ {
 			if hex.EncodeToString(bufs[i]) != hex.EncodeToString(keys[i]) {
 				t.Fail()
@@ -137,18 +134,17 @@ func TestDbInitAll(t *testing.T) {
 
 	go func() {
 		for i := 0; i < testCount; i++ {
-			db := NewDb(posconfig.EpLocalDB)
-			db.PutWithIndex(0, uint64(i), "", keys[i])
+			dbs.Ep.PutWithIndex(0, uint64(i), "", keys[i])
 		}
 
 		for i := 0; i < testCount; i++ {
-			value, err := db.GetWithIndex(0, uint64(i), "")
+			value, err := dbs.Ep.GetWithIndex(0, uint64(i), "")
 			if hex.EncodeToString(value) != hex.EncodeToString(keys[i]) || err != nil {
 				t.Fail()
 			}
 		}
 
-		bufs := db.GetStorageByteArray(0)
+		bufs := dbs.Ep.GetStorageByteArray(0)
 		for i := 0; i < testCount; i++ {
 			if hex.EncodeToString(bufs[i]) != hex.EncodeToString(keys[i]) {
 				t.Fail()
@@ -168,17 +164,33 @@ func TestDbInitAll(t *testing.T) {
 	case <-allQuit:
 	}
 
-	db = NewDb("test")
-	db.Put(0, "hello", []byte{1, 2, 3})
-	buf, err := db.Get(0, "hello")
-	if buf[0] != 1 || buf[1] != 2 || buf[2] != 3 || err != nil {
-		t.Fail()
+	// the entries are kept across reopens
+	dbs.Close()
+	if dbs, err = OpenDbs(open); err != nil {
+		t.Fatalf("failed to reopen dbs: %v", err)
+	}
+	defer dbs.Close()
+	for i, db := range []*Db{dbs.Pos, dbs.Rb, dbs.Ep} {
+		if bufs := db.GetStorageByteArray(0); len(bufs) != testCount {
+			t.Errorf("db %d entries mismatch after reopen: have %d, want %d", i, len(bufs), testCount)
+		}
 	}
+	if bufs := dbs.Incentive.GetStorageByteArray(0); len(bufs) != 0 {
+		t.Errorf("dbs share entries: have %d, want 0", len(bufs))
+	}
+}
 
-	db = GetDb()
-	db.Put(0, "hello", []byte{3, 4, 5})
-	buf, err = db.Get(0, "hello")
-	if buf[0] != 3 || buf[1] != 4 || buf[2] != 5 || err != nil {
-		t.Fail()
+func TestMemDb(t *testing.T) {
+	db1, db2 := NewMemDb(), NewMemDb()
+
+	db1.PutWithIndex(1, 2, "key", []byte{1, 2, 3})
+	if buf, err := db1.GetWithIndex(1, 2, "key"); err != nil || hex.EncodeToString(buf) != "010203" {
+		t.Fatalf("value mismatch: have %x, %v", buf, err)
+	}
+	if _, err := db2.GetWithIndex(1, 2, "key"); err != ErrNotFound {
+		t.Fatalf("dbs share entries: have %v, want %v", err, ErrNotFound)
+	}
+	if arrays := db1.GetStorageByteArray(1); len(arrays) != 1 {
+		t.Fatalf("epoch entries mismatch: have %d, want 1", len(arrays))
 	}
 }
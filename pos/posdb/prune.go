@@ -0,0 +1,199 @@
+package posdb
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/pos/util/convert"
+)
+
+const (
+	// MinRetainEpochs is the smallest retention the pruner accepts, the pos
+	// workflow still reads the data of the previous epochs.
+	MinRetainEpochs = 2
+
+	// lastPrunedKey stores the last epoch pruned from a db.
+	lastPrunedKey = "lastPrunedEpoch"
+
+	// pruneInterval is the time between two pruning passes.
+	pruneInterval = 10 * time.Minute
+)
+
+// PruneStats reports the data removed by pruning.
+type PruneStats struct {
+	Epochs  uint64             // Number of epochs pruned
+	Entries uint64             // Number of entries deleted
+	Size    common.StorageSize // Size of the deleted keys and values
+}
+
+func (s *PruneStats) add(other PruneStats) {
+	s.Epochs += other.Epochs
+	s.Entries += other.Entries
+	s.Size += other.Size
+}
+
+// DeleteEpoch deletes the entries stored for an epoch together with the key
+// list tracking them and their anchors. Epoch 0 holds the key lists and the counters kept across
+// epochs, so it can't be deleted.
+func (s *Db) DeleteEpoch(epochID uint64) (PruneStats, error) {
+	var stats PruneStats
+	if epochID == 0 {
+		return stats, nil
+	}
+
+	keys := s.getAllKeys(epochID)
+	index := make([][]byte, 0, len(keys)+1)
+	for i := range keys {
+		index = append(index, s.getUniqueKeyBytes(0, 0, s.getKeyName(epochID, uint64(i))))
+	}
+	index = append(index, s.getUniqueKeyBytes(0, 0, s.getKeyCountName(epochID)))
+	index = append(index, s.anchorKeys(epochID)...)
+
+	remove := func(key []byte) error {
+		value, err := s.db.Get(key)
+		if err != nil {
+			return nil
+		}
+		if err := s.db.Delete(key); err != nil {
+			return err
+		}
+		stats.Entries++
+		stats.Size += common.StorageSize(len(key) + len(value))
+		return nil
+	}
+	// Delete the entries before the index, an interrupted deletion is retried
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := remove([]byte(key)); err != nil {
+			return stats, err
+		}
+	}
+	for _, key := range index {
+		if err := remove(key); err != nil {
+			return stats, err
+		}
+	}
+	stats.Epochs = 1
+	return stats, nil
+}
+
+// Prune deletes the entries of every epoch before the given one that wasn't
+// pruned yet and compacts the db to hand the space back to the file system.
+func (s *Db) Prune(before uint64) (PruneStats, error) {
+	var stats PruneStats
+
+	last := uint64(0)
+	if ret, err := s.Get(0, lastPrunedKey); err == nil {
+		last = convert.BytesToUint64(ret)
+	}
+	for epochID := last + 1; epochID < before; epochID++ {
+		epochStats, err := s.DeleteEpoch(epochID)
+		if err != nil {
+			return stats, err
+		}
+		stats.add(epochStats)
+
+		if _, err := s.putNoCount(0, lastPrunedKey, convert.Uint64ToBytes(epochID)); err != nil {
+			return stats, err
+		}
+	}
+	if ldb, ok := s.db.(*ethdb.LDBDatabase); ok && stats.Entries > 0 {
+		if err := ldb.LDB().CompactRange(util.Range{}); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// Pruner deletes the data of the epochs older than a retention window from a
+// set of dbs in the background.
+type Pruner struct {
+	retain uint64         // Number of epochs before the current one to keep
+	dbs    map[string]*Db // Dbs to prune by name
+	epoch  func() uint64  // Returns the current epoch
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewPruner creates a pruner keeping the current epoch and the retain epochs
+// before it in the dbs, which are named by the logs only. Retentions below
+// MinRetainEpochs are rejected.
+//
+// Only dbs holding data that is rebuilt every epoch may be pruned: the incentive
+// history and the fork counters of the forkdb are kept for good and must not be
+// passed in.
+func NewPruner(retain uint64, epoch func() uint64, dbs map[string]*Db) (*Pruner, error) {
+	if retain < MinRetainEpochs {
+		return nil, fmt.Errorf("posdb retention of %d epochs below the minimum of %d", retain, MinRetainEpochs)
+	}
+	return &Pruner{
+		retain: retain,
+		dbs:    dbs,
+		epoch:  epoch,
+		quit:   make(chan struct{}),
+	}, nil
+}
+
+// Start starts pruning in the background.
+func (p *Pruner) Start() {
+	p.wg.Add(1)
+	go p.loop()
+	log.Info("Started posdb pruner", "retain", p.retain)
+}
+
+// Stop terminates the background pruning.
+func (p *Pruner) Stop() {
+	close(p.quit)
+	p.wg.Wait()
+	log.Info("Stopped posdb pruner")
+}
+
+func (p *Pruner) loop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		p.Prune()
+
+		select {
+		case <-ticker.C:
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// Prune runs a single pruning pass over the dbs and returns the data removed.
+func (p *Pruner) Prune() PruneStats {
+	var total PruneStats
+
+	current := p.epoch()
+	if current <= p.retain {
+		return total
+	}
+	before := current - p.retain
+
+	for name, db := range p.dbs {
+		start := time.Now()
+		stats, err := db.Prune(before)
+		if err != nil {
+			log.Warn("Failed to prune posdb", "db", name, "before", before, "err", err)
+		}
+		if stats.Epochs > 0 {
+			log.Info("Pruned posdb", "db", name, "epochs", stats.Epochs, "entries", stats.Entries,
+				"reclaimed", stats.Size, "elapsed", common.PrettyDuration(time.Since(start)))
+		}
+		total.add(stats)
+	}
+	return total
+}
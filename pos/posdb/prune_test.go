@@ -0,0 +1,70 @@
+package posdb
+
+import (
+	"testing"
+)
+
+func TestPrune(t *testing.T) {
+	db := NewMemDb()
+
+	db.Put(0, "global", []byte{0xff})
+	for epochID := uint64(1); epochID <= 5; epochID++ {
+		db.Put(epochID, "a", []byte{byte(epochID)})
+		db.PutWithIndex(epochID, 1, "b", []byte{byte(epochID), 1})
+	}
+
+	stats, err := db.Prune(4)
+	if err != nil {
+		t.Fatalf("failed to prune: %v", err)
+	}
+	// Epochs 1 to 3, each with two entries, two key names and a key count
+	if stats.Epochs != 3 || stats.Entries != 15 || stats.Size == 0 {
+		t.Fatalf("stats mismatch: have %+v", stats)
+	}
+	for epochID := uint64(1); epochID <= 5; epochID++ {
+		_, err := db.Get(epochID, "a")
+		if pruned := err != nil; pruned != (epochID < 4) {
+			t.Errorf("epoch %d: pruned %v", epochID, pruned)
+		}
+		if have := len(db.GetStorageByteArray(epochID)); (epochID < 4 && have != 0) || (epochID >= 4 && have != 2) {
+			t.Errorf("epoch %d: %d keys listed", epochID, have)
+		}
+	}
+	if _, err := db.Get(0, "global"); err != nil {
+		t.Errorf("epoch 0 pruned: %v", err)
+	}
+
+	// Pruning again only visits the epochs not pruned yet
+	if stats, err = db.Prune(4); err != nil || stats.Epochs != 0 {
+		t.Errorf("repeated prune: have %+v, %v", stats, err)
+	}
+	if stats, err = db.Prune(5); err != nil || stats.Epochs != 1 || stats.Entries != 5 {
+		t.Errorf("next prune: have %+v, %v", stats, err)
+	}
+}
+
+func TestPrunerRetention(t *testing.T) {
+	db := NewMemDb()
+
+	for epochID := uint64(1); epochID <= 10; epochID++ {
+		db.Put(epochID, "a", []byte{byte(epochID)})
+	}
+	current := uint64(10)
+	if _, err := NewPruner(MinRetainEpochs-1, func() uint64 { return current }, map[string]*Db{"test": db}); err == nil {
+		t.Fatal("retention below the minimum accepted")
+	}
+	pruner, err := NewPruner(3, func() uint64 { return current }, map[string]*Db{"test": db})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats := pruner.Prune(); stats.Epochs != 6 {
+		t.Fatalf("pruned epochs mismatch: have %d, want 6", stats.Epochs)
+	}
+	for epochID := uint64(1); epochID <= 10; epochID++ {
+		_, err := db.Get(epochID, "a")
+		if pruned := err != nil; pruned != (epochID < 7) {
+			t.Errorf("epoch %d: pruned %v", epochID, pruned)
+		}
+	}
+}
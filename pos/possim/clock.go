@@ -0,0 +1,74 @@
+package possim
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// SimClock is a manually advanced clock shared by all nodes of a simulated
+// network. It implements util.Clock.
+type SimClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*simWaiter
+}
+
+type simWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// NewSimClock returns a clock that starts at the given time.
+func NewSimClock(start time.Time) *SimClock {
+	return &SimClock{now: start}
+}
+
+// Now returns the current simulated time.
+func (c *SimClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns a channel which fires once the simulated time has advanced by d.
+func (c *SimClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, &simWaiter{deadline: c.now.Add(d), ch: ch})
+	sort.Slice(c.waiters, func(i, j int) bool {
+		return c.waiters[i].deadline.Before(c.waiters[j].deadline)
+	})
+	return ch
+}
+
+// Advance moves the simulated time forward by d and fires every waiter whose
+// deadline has been reached.
+func (c *SimClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	fired := 0
+	for _, w := range c.waiters {
+		if w.deadline.After(c.now) {
+			break
+		}
+		w.ch <- c.now
+		fired++
+	}
+	c.waiters = c.waiters[fired:]
+}
+
+// Waiters returns the number of pending After calls.
+func (c *SimClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
@@ -0,0 +1,486 @@
+// Package possim runs a network of in-memory PoS validators for tests.
+//
+// Every validator is a full eth node with its own keystore, connected to the
+// others through the p2p/simulations in-memory adapter. All nodes share one
+// simulated clock, so a test advances slots and epochs explicitly and then
+// inspects the chain state of any node.
+package possim
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/wanchain/go-wanchain/accounts"
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/accounts/keystore/bn256"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/eth"
+	"github.com/wanchain/go-wanchain/miner"
+	"github.com/wanchain/go-wanchain/node"
+	"github.com/wanchain/go-wanchain/p2p/discover"
+	"github.com/wanchain/go-wanchain/p2p/simulations"
+	"github.com/wanchain/go-wanchain/p2p/simulations/adapters"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+const (
+	serviceName = "wan"
+	passphrase  = "possim"
+)
+
+var (
+	errNoValidators = errors.New("possim: at least one validator is required")
+	errNotStarted   = errors.New("possim: network not started")
+	errTimeout      = errors.New("possim: timeout")
+
+	defaultStake   = new(big.Int).Mul(big.NewInt(100000), big.NewInt(params.Wan))
+	defaultBalance = new(big.Int).Mul(big.NewInt(1000000), big.NewInt(params.Wan))
+)
+
+// Config describes the simulated network.
+type Config struct {
+	Validators int      // Number of validator nodes, the first one signs epoch 0
+	Stake      *big.Int // Genesis stake of each validator
+	Balance    *big.Int // Genesis balance of each validator
+
+	Pos *params.PosConfig // Pos epoch parameters, nil selects params.DefaultPosConfig
+
+	Start time.Time     // Simulated genesis time, should lie in the past of the wall clock
+	Step  time.Duration // Simulated time added per clock step
+	Tick  time.Duration // Wall time the nodes get to react to every step
+
+	// Settle is the wall time the nodes get to agree on a head after every
+	// slot, so they don't fork faster than blocks propagate
+	Settle time.Duration
+}
+
+func (c *Config) sanitize() {
+	if c.Stake == nil {
+		c.Stake = defaultStake
+	}
+	if c.Balance == nil {
+		c.Balance = defaultBalance
+	}
+	if c.Start.IsZero() {
+		// Blocks ahead of the wall clock are rejected as future blocks, so
+		// keep the simulated time well behind it.
+		c.Start = time.Now().Add(-30 * 24 * time.Hour).Truncate(time.Second)
+	}
+	if c.Step == 0 {
+		c.Step = time.Second
+	}
+	if c.Tick == 0 {
+		c.Tick = 50 * time.Millisecond
+	}
+	if c.Settle == 0 {
+		c.Settle = 2 * time.Second
+	}
+}
+
+// Validator holds the identity of one simulated validator.
+type Validator struct {
+	NodeKey *ecdsa.PrivateKey // devp2p identity
+	Key     *keystore.Key     // Staking and signing key, imported into the node keystore
+}
+
+// Node is a running validator node.
+type Node struct {
+	ID        discover.NodeID
+	Validator *Validator
+
+	mu  sync.RWMutex
+	eth *eth.Ethereum
+}
+
+// Ethereum returns the node's eth service, nil before the network is started.
+func (n *Node) Ethereum() *eth.Ethereum {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.eth
+}
+
+// BlockChain returns the node's chain.
+func (n *Node) BlockChain() *core.BlockChain {
+	return n.Ethereum().BlockChain()
+}
+
+// Pos returns the node's own epocher, slot leader selection and incentive.
+func (n *Node) Pos() *miner.Pos {
+	return n.Ethereum().Pos()
+}
+
+// State returns the state at the node's current head.
+func (n *Node) State() (*state.StateDB, error) {
+	return n.BlockChain().State()
+}
+
+// Head returns the number of the node's current head block.
+func (n *Node) Head() uint64 {
+	return n.BlockChain().CurrentBlock().NumberU64()
+}
+
+// Network is a simulated PoS network.
+type Network struct {
+	config  Config
+	clock   *SimClock
+	genesis *core.Genesis
+
+	net     *simulations.Network
+	nodes   []*Node
+	byID    map[discover.NodeID]*Node
+	started bool
+}
+
+// NewNetwork generates the validator keys and the genesis block of a new
+// simulated network. The nodes are not started.
+func NewNetwork(config Config) (*Network, error) {
+	if config.Validators <= 0 {
+		return nil, errNoValidators
+	}
+	config.sanitize()
+
+	n := &Network{
+		config: config,
+		clock:  NewSimClock(config.Start),
+		byID:   make(map[discover.NodeID]*Node),
+	}
+	for i := 0; i < config.Validators; i++ {
+		v, err := newValidator()
+		if err != nil {
+			return nil, err
+		}
+		var id discover.NodeID
+		copy(id[:], crypto.FromECDSAPub(&v.NodeKey.PublicKey)[1:])
+
+		node := &Node{ID: id, Validator: v}
+		n.nodes = append(n.nodes, node)
+		n.byID[id] = node
+	}
+	n.genesis = n.makeGenesis()
+
+	adapter := adapters.NewSimAdapter(adapters.Services{serviceName: n.newService})
+	n.net = simulations.NewNetwork(adapter, &simulations.NetworkConfig{
+		ID:             "possim",
+		DefaultService: serviceName,
+	})
+	return n, nil
+}
+
+func newValidator() (*Validator, error) {
+	nodeKey, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	sk1, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	sk2, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	sk3, err := bn256.GenerateBn256()
+	if err != nil {
+		return nil, err
+	}
+	key := &keystore.Key{
+		Address:     crypto.PubkeyToAddress(sk1.PublicKey),
+		PrivateKey:  sk1,
+		PrivateKey2: sk2,
+		PrivateKey3: sk3,
+	}
+	return &Validator{NodeKey: nodeKey, Key: key}, nil
+}
+
+// makeGenesis stakes every validator in the genesis alloc and makes the
+// first validator the epoch 0 leader.
+func (n *Network) makeGenesis() *core.Genesis {
+	alloc := make(core.GenesisAlloc)
+	for _, node := range n.nodes {
+		key := node.Validator.Key
+		alloc[key.Address] = core.GenesisAccount{
+			Balance: n.config.Balance,
+			Staking: core.GenesisAccountStaking{
+				Amount:  n.config.Stake,
+				S256pk:  crypto.FromECDSAPub(&key.PrivateKey.PublicKey),
+				Bn256pk: key.PrivateKey3.PublicKeyBn256.G1.Marshal(),
+			},
+		}
+	}
+	config := *params.PlutoChainConfig
+	config.Pos = n.config.Pos
+	return &core.Genesis{
+		Config:     &config,
+		Timestamp:  uint64(n.config.Start.Unix()),
+		ExtraData:  crypto.FromECDSAPub(&n.nodes[0].Validator.Key.PrivateKey.PublicKey),
+		GasLimit:   0x47b760,
+		Difficulty: big.NewInt(1),
+		Alloc:      alloc,
+	}
+}
+
+// newService creates the eth service of a simulated node and unlocks the
+// validator key in the node's own keystore.
+func (n *Network) newService(ctx *adapters.ServiceContext) (node.Service, error) {
+	nd, ok := n.byID[ctx.Config.ID]
+	if !ok {
+		return nil, fmt.Errorf("possim: unknown node %s", ctx.Config.ID)
+	}
+	ks := ctx.NodeContext.AccountManager.Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	key := nd.Validator.Key
+	account, err := ks.Find(accounts.Account{Address: key.Address})
+	if err != nil {
+		account, err = ks.ImportECDSA(key.PrivateKey, key.PrivateKey2, key.PrivateKey3, passphrase)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := ks.Unlock(account, passphrase); err != nil {
+		return nil, err
+	}
+
+	config := eth.DefaultConfig
+	config.Genesis = n.genesis
+	config.NetworkId = n.genesis.Config.ChainId.Uint64()
+	config.Etherbase = key.Address
+	config.PosClock = n.clock
+
+	service, err := eth.New(ctx.NodeContext, &config)
+	if err != nil {
+		return nil, err
+	}
+	nd.mu.Lock()
+	nd.eth = service
+	nd.mu.Unlock()
+	return service, nil
+}
+
+// Clock returns the simulated clock shared by the nodes.
+func (n *Network) Clock() *SimClock { return n.clock }
+
+// Genesis returns the genesis specification of the network.
+func (n *Network) Genesis() *core.Genesis { return n.genesis }
+
+// Nodes returns the validator nodes in creation order.
+func (n *Network) Nodes() []*Node { return n.nodes }
+
+// Start boots all nodes on the simulated clock, connects them to each other
+// and starts mining on every validator.
+func (n *Network) Start() error {
+	for _, nd := range n.nodes {
+		conf := &adapters.NodeConfig{
+			ID:         nd.ID,
+			PrivateKey: nd.Validator.NodeKey,
+			Name:       nd.ID.TerminalString(),
+			Services:   []string{serviceName},
+		}
+		if _, err := n.net.NewNodeWithConfig(conf); err != nil {
+			return err
+		}
+		if err := n.net.Start(nd.ID); err != nil {
+			return err
+		}
+	}
+	for i := range n.nodes {
+		for j := i + 1; j < len(n.nodes); j++ {
+			if err := n.net.Connect(n.nodes[i].ID, n.nodes[j].ID); err != nil {
+				return err
+			}
+		}
+	}
+	for _, nd := range n.nodes {
+		client, err := n.net.GetNode(nd.ID).Client()
+		if err != nil {
+			return err
+		}
+		nd.Ethereum().Miner().SetPosRPCClient(client)
+		if err := nd.Ethereum().StartMining(true); err != nil {
+			return err
+		}
+	}
+	n.started = true
+	return nil
+}
+
+// Shutdown stops all nodes.
+func (n *Network) Shutdown() {
+	n.net.Shutdown()
+	n.started = false
+}
+
+// Advance moves the simulated clock forward by d in configured steps, giving
+// the nodes Tick of wall time to react to every step.
+func (n *Network) Advance(d time.Duration) {
+	for d > 0 {
+		step := n.config.Step
+		if step > d {
+			step = d
+		}
+		n.clock.Advance(step)
+		time.Sleep(n.config.Tick)
+		d -= step
+	}
+}
+
+// AdvanceSlots moves the simulated clock forward by count slots.
+func (n *Network) AdvanceSlots(count uint64) {
+	n.Advance(time.Duration(count*posconfig.SlotTime) * time.Second)
+}
+
+// AdvanceEpochs moves the simulated clock forward by count epochs.
+func (n *Network) AdvanceEpochs(count uint64) {
+	n.AdvanceSlots(count * posconfig.SlotCount)
+}
+
+// WaitForBlock advances the simulated clock until every node has imported
+// the given block number, or until maxSlots slots have passed.
+func (n *Network) WaitForBlock(number uint64, maxSlots uint64) error {
+	if !n.started {
+		return errNotStarted
+	}
+	for i := uint64(0); i <= maxSlots; i++ {
+		if n.minHead() >= number {
+			return nil
+		}
+		n.AdvanceSlots(1)
+		n.settle()
+	}
+	if n.minHead() >= number {
+		return nil
+	}
+	return errTimeout
+}
+
+// WaitForEpoch advances the simulated clock until every node's head block
+// belongs to the given epoch or a later one, or until maxSlots slots have
+// passed.
+func (n *Network) WaitForEpoch(epochID uint64, maxSlots uint64) error {
+	if !n.started {
+		return errNotStarted
+	}
+	for i := uint64(0); i <= maxSlots; i++ {
+		if n.minHeadEpoch() >= epochID {
+			return nil
+		}
+		n.AdvanceSlots(1)
+		n.settle()
+	}
+	if n.minHeadEpoch() >= epochID {
+		return nil
+	}
+	return errTimeout
+}
+
+// CurrentEpochSlot returns the epoch and slot of the simulated clock, 0 and 0
+// before the network is started.
+func (n *Network) CurrentEpochSlot() (uint64, uint64) {
+	if !n.started {
+		return 0, 0
+	}
+	clock := n.nodes[0].Ethereum().BlockChain().SlotClock()
+	return clock.CalEpochSlotID(uint64(n.clock.Now().Unix()))
+}
+
+// StakeOf returns the staked amount of a validator in the given node's head
+// state.
+func (n *Network) StakeOf(nd *Node, v *Validator) (*big.Int, error) {
+	statedb, err := nd.State()
+	if err != nil {
+		return nil, err
+	}
+	info, err := stakerInfo(statedb, v.Key.Address)
+	if err != nil {
+		return nil, err
+	}
+	return info.Amount, nil
+}
+
+// settle waits until all nodes share the same head and the head didn't move
+// for a few ticks, so a block still being sealed is imported everywhere, or
+// until Settle of wall time has passed.
+func (n *Network) settle() {
+	var (
+		deadline = time.Now().Add(n.config.Settle)
+		quiet    = 5 * n.config.Tick
+		head     common.Hash
+		since    time.Time
+	)
+	for time.Now().Before(deadline) {
+		current := n.nodes[0].BlockChain().CurrentBlock().Hash()
+		if current != head {
+			head, since = current, time.Now()
+		}
+		if n.sameHead() && time.Since(since) >= quiet {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (n *Network) sameHead() bool {
+	head := n.nodes[0].BlockChain().CurrentBlock().Hash()
+	for _, nd := range n.nodes[1:] {
+		if nd.BlockChain().CurrentBlock().Hash() != head {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *Network) minHead() uint64 {
+	min := ^uint64(0)
+	for _, nd := range n.nodes {
+		if h := nd.Head(); h < min {
+			min = h
+		}
+	}
+	return min
+}
+
+func (n *Network) minHeadEpoch() uint64 {
+	min := ^uint64(0)
+	for _, nd := range n.nodes {
+		head := nd.BlockChain().CurrentBlock()
+		epochID := uint64(0)
+		if head.NumberU64() > 0 {
+			epochID = head.Difficulty().Uint64() >> 32
+		}
+		if epochID < min {
+			min = epochID
+		}
+	}
+	return min
+}
+
+// HashesAt returns the canonical hash of every node at the given height.
+func (n *Network) HashesAt(number uint64) []common.Hash {
+	hashes := make([]common.Hash, len(n.nodes))
+	for i, nd := range n.nodes {
+		if h := nd.BlockChain().GetHeaderByNumber(number); h != nil {
+			hashes[i] = h.Hash()
+		}
+	}
+	return hashes
+}
+
+func stakerInfo(statedb *state.StateDB, addr common.Address) (*vm.StakerInfo, error) {
+	buf := statedb.GetStateByteArray(vm.StakersInfoAddr, common.BytesToHash(addr[:]))
+	if len(buf) == 0 {
+		return nil, fmt.Errorf("possim: %s is not a staker", addr.Hex())
+	}
+	var info vm.StakerInfo
+	if err := rlp.DecodeBytes(buf, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
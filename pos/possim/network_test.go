@@ -0,0 +1,191 @@
+package possim
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+)
+
+func TestSimClock(t *testing.T) {
+	start := time.Unix(1000, 0)
+	c := NewSimClock(start)
+
+	ch := c.After(5 * time.Second)
+	c.Advance(4 * time.Second)
+	select {
+	case <-ch:
+		t.Fatal("waiter fired before its deadline")
+	default:
+	}
+	c.Advance(time.Second)
+	select {
+	case now := <-ch:
+		if !now.Equal(start.Add(5 * time.Second)) {
+			t.Fatalf("waiter fired at %v, want %v", now, start.Add(5*time.Second))
+		}
+	default:
+		t.Fatal("waiter did not fire at its deadline")
+	}
+	if c.Waiters() != 0 {
+		t.Fatalf("pending waiters: have %d, want 0", c.Waiters())
+	}
+}
+
+func TestNetworkProducesBlocks(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping pos network simulation in short mode")
+	}
+	net, err := NewNetwork(Config{Validators: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := net.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer net.Shutdown()
+
+	if err := net.WaitForBlock(3, 20); err != nil {
+		t.Fatalf("chain did not advance: %v", err)
+	}
+	hashes := net.HashesAt(3)
+	for i := 1; i < len(hashes); i++ {
+		if hashes[i] != hashes[0] {
+			t.Fatalf("node %d diverged at block 3: %x != %x", i, hashes[i], hashes[0])
+		}
+	}
+	for _, nd := range net.Nodes() {
+		for _, v := range net.Nodes() {
+			stake, err := net.StakeOf(nd, v.Validator)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stake.Cmp(defaultStake) != 0 {
+				t.Fatalf("stake mismatch: have %v, want %v", stake, defaultStake)
+			}
+		}
+	}
+}
+
+func TestNetworkRunsEpochs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping pos network simulation in short mode")
+	}
+	pos := &params.PosConfig{SlotTime: 2, K: 2, KCount: 12, EpochLeaderCount: 4, RandomProperCount: 5}
+	net, err := NewNetwork(Config{Validators: 4, Pos: pos})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := net.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer net.Shutdown()
+
+	if posconfig.SlotCount != pos.SlotCount() {
+		t.Fatalf("slot count: have %d, want %d", posconfig.SlotCount, pos.SlotCount())
+	}
+	const lastEpoch = 4
+	if err := net.WaitForEpoch(lastEpoch, (lastEpoch+1)*pos.SlotCount()); err != nil {
+		t.Fatalf("chain did not reach epoch %d: %v", lastEpoch, err)
+	}
+
+	// Every node selects the same leaders on its own
+	head := net.minHead()
+	hashes := net.HashesAt(head)
+	for i := 1; i < len(hashes); i++ {
+		if hashes[i] != hashes[0] {
+			t.Fatalf("node %d diverged at block %d: %x != %x", i, head, hashes[i], hashes[0])
+		}
+	}
+	nodes := net.Nodes()
+	for epochID := uint64(1); epochID < lastEpoch; epochID++ {
+		want := nodes[0].Pos().Epocher.GetEpochLeaders(epochID)
+		if len(want) != int(pos.EpochLeaderCount) {
+			t.Fatalf("epoch %d leaders: have %d, want %d", epochID, len(want), pos.EpochLeaderCount)
+		}
+		for i, nd := range nodes[1:] {
+			have := nd.Pos().Epocher.GetEpochLeaders(epochID)
+			if !reflect.DeepEqual(have, want) {
+				t.Fatalf("node %d epoch %d leaders differ", i+1, epochID)
+			}
+		}
+	}
+	// The slot leaders past epoch 1 are selected from the stage 2 txs of the
+	// epoch leaders, only the leaders of the previous epoch hold the SMA
+	for epochID := uint64(2); epochID <= lastEpoch; epochID++ {
+		leader := epochLeaderNode(nodes, nodes[0].Pos().Epocher.GetEpochLeaders(epochID-1))
+		if leader == nil {
+			t.Fatalf("no node leads epoch %d", epochID-1)
+		}
+		sma, isGenesis, err := leader.Pos().Sls.GetSma(epochID)
+		if err != nil {
+			t.Fatalf("epoch %d sma: %v", epochID, err)
+		}
+		if isGenesis || len(sma) == 0 {
+			t.Fatalf("epoch %d sma was not built from stage 2 txs", epochID)
+		}
+	}
+
+	// The blocks past epoch 0 are sealed by more than one validator
+	chain := nodes[0].BlockChain()
+	sealers := make(map[common.Address]bool)
+	for number := uint64(1); number <= head; number++ {
+		header := chain.GetHeaderByNumber(number)
+		if header.Difficulty.Uint64()>>32 > 0 {
+			sealers[header.Coinbase] = true
+		}
+	}
+	if len(sealers) < 2 {
+		t.Fatalf("sealers past epoch 0: have %d, want at least 2", len(sealers))
+	}
+
+	// The random proposers ran the DKG and signed the randoms of the next epochs
+	statedb, err := nodes[0].State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for epochID := uint64(2); epochID <= lastEpoch; epochID++ {
+		if vm.GetStateR(statedb, epochID) == nil {
+			t.Fatalf("no random beacon of epoch %d", epochID)
+		}
+	}
+
+	// The incentives of the past epochs were paid to the stakers
+	for i, nd := range nodes {
+		runs, err := nd.Pos().Incentive.GetRunTimes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if runs.Sign() == 0 {
+			t.Fatalf("node %d did not run the incentive", i)
+		}
+	}
+	paid := false
+	for _, nd := range nodes {
+		if statedb.GetBalance(nd.Validator.Key.Address).Cmp(defaultBalance) > 0 {
+			paid = true
+		}
+	}
+	if !paid {
+		t.Fatal("no validator was paid an incentive")
+	}
+}
+
+// epochLeaderNode returns the first node whose validator is one of leaders.
+func epochLeaderNode(nodes []*Node, leaders [][]byte) *Node {
+	for _, nd := range nodes {
+		pk := crypto.FromECDSAPub(&nd.Validator.Key.PrivateKey.PublicKey)
+		for _, leader := range leaders {
+			if bytes.Equal(leader, pk) {
+				return nd
+			}
+		}
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package randombeacon
 import (
 	"crypto/rand"
 	"errors"
+	"github.com/wanchain/go-wanchain/accounts/keystore"
 	"github.com/wanchain/go-wanchain/pos/rbselection"
 
 	"github.com/wanchain/go-wanchain/common"
@@ -15,7 +16,6 @@ import (
 
 	"github.com/wanchain/go-wanchain/pos/epochLeader"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
-	"github.com/wanchain/go-wanchain/pos/posdb"
 	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/rlp"
 	"github.com/wanchain/go-wanchain/rpc"
@@ -65,6 +65,7 @@ type RandomBeacon struct {
 	statedb   vm.StateDB
 	epocher   *epochLeader.Epocher
 	rpcClient *rpc.Client
+	key       *keystore.Key
 
 	// based function
 	getRBProposerGroupF GetRBProposerGroupFunc
@@ -76,7 +77,6 @@ type RandomBeacon struct {
 var (
 	maxUint64      = uint64(1<<64 - 1)
 	loopEventCount = 1000
-	randomBeacon   RandomBeacon
 )
 
 
@@ -88,11 +88,13 @@ var (
 	errInsufficient = errors.New("insufficient proposer")
 )
 
-func GetRandonBeaconInst() *RandomBeacon {
-	return &randomBeacon
+// NewRandomBeacon returns the random beacon of a node, Init starts it with
+// the node's epocher and miner key.
+func NewRandomBeacon() *RandomBeacon {
+	return &RandomBeacon{}
 }
 
-func (rb *RandomBeacon) Init(epocher *epochLeader.Epocher) {
+func (rb *RandomBeacon) Init(epocher *epochLeader.Epocher, key *keystore.Key) {
 	if rb.loopEvents != nil {
 		return
 	}
@@ -103,9 +105,10 @@ func (rb *RandomBeacon) Init(epocher *epochLeader.Epocher) {
 	rb.rpcClient = nil
 
 	rb.epocher = epocher
+	rb.key = key
 
 	// function
-	rb.getRBProposerGroupF = posdb.GetRBProposerGroup
+	rb.getRBProposerGroupF = epocher.GetRBProposerG1
 	rb.getCji = vm.GetCji
 	rb.getEns = vm.GetEncryptShare
 	rb.getRBM = vm.GetRBM
@@ -273,7 +276,7 @@ func (rb *RandomBeacon) getMyRBProposerId(epochId uint64) []uint32 {
 		return nil
 	}
 
-	selfPk := posconfig.Cfg().GetMinerBn256PK()
+	selfPk := rb.getMinerBn256PK()
 	if selfPk == nil {
 		return nil
 	}
@@ -472,7 +475,11 @@ func (rb *RandomBeacon) doSIG(proposerId uint32) error {
 }
 
 func (rb *RandomBeacon) generateSIG(proposerId uint32) (*vm.RbSIGTxPayload, error) {
-	prikey := posconfig.Cfg().GetMinerBn256SK()
+	prikey := rb.getMinerBn256SK()
+	if prikey == nil {
+		return nil, errInvalidInParam
+	}
+
 	datas := make([]RbEnsDataCollector, 0)
 
 	for id, pk := range rb.proposerPks {
@@ -565,7 +572,27 @@ func (rb *RandomBeacon) doSendRBTx(payload []byte) error {
 }
 
 func (rb *RandomBeacon) getTxFrom() common.Address {
-	return posconfig.Cfg().GetMinerAddr()
+	if rb.key == nil {
+		return common.Address{}
+	}
+
+	return rb.key.Address
+}
+
+func (rb *RandomBeacon) getMinerBn256PK() *bn256.G1 {
+	if rb.key == nil {
+		return nil
+	}
+
+	return new(bn256.G1).Set(rb.key.PrivateKey3.PublicKeyBn256.G1)
+}
+
+func (rb *RandomBeacon) getMinerBn256SK() *big.Int {
+	if rb.key == nil {
+		return nil
+	}
+
+	return new(big.Int).Set(rb.key.PrivateKey3.D)
 }
 
 func (rb *RandomBeacon) getRBProposerGroup(epochId uint64) []bn256.G1 {
@@ -33,7 +33,7 @@ func TestInit(t *testing.T) {
 		t.Error("generate bn256 fail, ", err)
 	}
 
-	rb.Init(&epocher)
+	rb.Init(&epocher, &key)
 
 	if rb.epochStage != vm.RbDkg1Stage {
 		t.Error("invalid epoch stage")
@@ -77,14 +77,13 @@ func TestGetMyRBProposerId(t *testing.T) {
 	}
 
 	selfPrivate = key.PrivateKey3
-	posconfig.Cfg().MinerKey = &key
 
 	commityPrivate, err = accBn256.GenerateBn256()
 	if err != nil {
 		t.Error("generate bn256 fail, ", err)
 	}
 
-	rb.Init(&epocher)
+	rb.Init(&epocher, &key)
 	rb.getRBProposerGroupF = tmpGetRBProposerGroup
 
 	ids := rb.getMyRBProposerId(0)
@@ -122,9 +121,8 @@ func TestDoGenerateDKG1(t *testing.T) {
 
 	selfPrivate = key.PrivateKey3
 	commityPrivate = selfPrivate
-	posconfig.Cfg().MinerKey = &key
 
-	rb.Init(&epocher)
+	rb.Init(&epocher, &key)
 	rb.getRBProposerGroupF = tmpGetRBProposerGroup
 	rb.getCji = tmpGetCji
 
@@ -192,9 +190,8 @@ func TestGenerateDKG2(t *testing.T) {
 
 	selfPrivate = key.PrivateKey3
 	commityPrivate = selfPrivate
-	posconfig.Cfg().MinerKey = &key
 
-	rb.Init(&epocher)
+	rb.Init(&epocher, &key)
 	rb.getRBProposerGroupF = tmpGetRBProposerGroup
 	rb.getCji = tmpGetCji
 
@@ -286,9 +283,8 @@ func TestGenerateSIG(t *testing.T) {
 
 	selfPrivate = key.PrivateKey3
 	commityPrivate = selfPrivate
-	posconfig.Cfg().MinerKey = &key
 
-	rb.Init(&epocher)
+	rb.Init(&epocher, &key)
 	rb.getRBProposerGroupF = tmpGetRBProposerGroup
 	rb.getEns = tmpGetEnsFunc
 	rb.getRBM = tmpGetRBM
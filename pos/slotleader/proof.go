@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"math/big"
 
+	"github.com/wanchain/go-wanchain/core/state"
 	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/core/vm"
 
@@ -35,7 +36,7 @@ func (s *SLS) VerifySlotProof(block *types.Block, epochID uint64, slotID uint64,
 
 	rbBytes := rbPtr.Bytes()
 	// stage two info from trans
-	validEpochLeadersIndex, stageTwoAlphaPKi, err := s.getStageTwoFromTrans(epochID)
+	validEpochLeadersIndex, stageTwoAlphaPKi, err := s.getStageTwoFromTrans(block, epochID)
 	if err != nil {
 		log.Error(err.Error())
 		// no stage2 trans on the block chain.
@@ -124,16 +125,64 @@ func (s *SLS) PackSlotProof(epochID uint64, slotID uint64, prvKey *ecdsa.Private
 }
 
 func (s *SLS) GetInfoFromHeadExtra(epochID uint64, input []byte) ([]*big.Int, []*ecdsa.PublicKey, error) {
-	var info Pack
-	err := rlp.DecodeBytes(input, &info)
+	proof, proofMeg, err := UnpackSlotProof(input)
 	if err != nil {
 		log.Error("GetInfoFromHeadExtra rlp.DecodeBytes failed", "epochID", epochID, "input", hex.EncodeToString(input))
 		return nil, nil, err
 	}
 
+	return proof, proofMeg, nil
+}
+
+// UnpackSlotProof decodes a slot leader proof packed by PackSlotProof.
+func UnpackSlotProof(input []byte) ([]*big.Int, []*ecdsa.PublicKey, error) {
+	var info Pack
+	if err := rlp.DecodeBytes(input, &info); err != nil {
+		return nil, nil, err
+	}
 	return convert.ByteArrayToBigIntArray(info.Proof), convert.ByteArrayToPkArray(info.ProofMeg), nil
 }
 
+// VerifySlotProofByLeaders checks a slot leader proof against the leaders of the
+// previous epoch and the random beacon of the proof's epoch, or against the
+// genesis leaders if byGenesis is set. It is the check available to light
+// clients: without the stage two transactions the skGt part of the proof can't
+// be verified. Use UsesGenesisProofs to tell which check an epoch needs.
+func VerifySlotProofByLeaders(Proof []*big.Int, ProofMeg []*ecdsa.PublicKey, preEpochLeaders [][]byte, rb []byte, byGenesis bool) bool {
+	if byGenesis {
+		return uleaderselection.VerifySlotLeaderProof(Proof, ProofMeg, epoch0LeadersPK(), big.NewInt(1).Bytes())
+	}
+	if len(preEpochLeaders) == 0 || len(rb) == 0 {
+		return false
+	}
+	pks := make([]*ecdsa.PublicKey, len(preEpochLeaders))
+	for i, leader := range preEpochLeaders {
+		pks[i] = crypto.ToECDSAPub(leader)
+	}
+	return uleaderselection.VerifySlotLeaderProof(Proof, ProofMeg, pks, rb)
+}
+
+// UsesGenesisProofs tells if the slot leaders of an epoch prove their slots with
+// the genesis leaders, reading the stage two txs of the previous epoch from
+// stateDb, the state of the last block of that epoch. Full nodes do so in epoch
+// 0 and whenever no epoch leader of the previous epoch completed stage two.
+// Read errors are reported by stateDb.Error().
+func UsesGenesisProofs(stateDb *state.StateDB, epochID uint64) bool {
+	if epochID == 0 {
+		return true
+	}
+	validEpochLeadersIndex, _, err := stageTwoFromState(stateDb, epochID)
+	if err != nil {
+		return true
+	}
+	for _, valid := range validEpochLeadersIndex {
+		if valid {
+			return false
+		}
+	}
+	return true
+}
+
 func (s *SLS) getSlotLeaderProofByGenesis(PrivateKey *ecdsa.PrivateKey, epochID uint64,
 	slotID uint64) ([]*ecdsa.PublicKey, []*big.Int, error) {
 	//1. SMA PRE
@@ -274,14 +323,35 @@ func (s *SLS) getSkGtFromTrans(epochLeadersPtrPre []*ecdsa.PublicKey, epochID ui
 	return skGt
 }
 
-func (s *SLS) getStageTwoFromTrans(epochID uint64) (validEpochLeadersIndex [posconfig.EpochLeaderCount]bool,
-	stageTwoAlphaPKi [posconfig.EpochLeaderCount][posconfig.EpochLeaderCount]*ecdsa.PublicKey, err error) {
+// getStageTwoFromTrans reads the stage two txs of the epoch before epochID
+// from the state the block is built on, the current state if block is nil.
+func (s *SLS) getStageTwoFromTrans(block *types.Block, epochID uint64) (validEpochLeadersIndex []bool,
+	stageTwoAlphaPKi [][]*ecdsa.PublicKey, err error) {
+
+	stateDb, err := s.parentState(block)
+	if err != nil {
+		log.Error("getStageTwoFromTrans", "parentState error", err.Error())
+		validEpochLeadersIndex = make([]bool, posconfig.EpochLeaderCount)
+		for i := 0; i < posconfig.EpochLeaderCount; i++ {
+			validEpochLeadersIndex[i] = true
+		}
+		return validEpochLeadersIndex, newAlphaPKiArray(), err
+	}
+	return stageTwoFromState(stateDb, epochID)
+}
+
+// stageTwoFromState reads the stage two txs of the epoch before epochID from
+// stateDb.
+func stageTwoFromState(stateDb *state.StateDB, epochID uint64) (validEpochLeadersIndex []bool,
+	stageTwoAlphaPKi [][]*ecdsa.PublicKey, err error) {
 
+	validEpochLeadersIndex = make([]bool, posconfig.EpochLeaderCount)
+	stageTwoAlphaPKi = newAlphaPKiArray()
 	for i := 0; i < posconfig.EpochLeaderCount; i++ {
 		validEpochLeadersIndex[i] = true
 	}
 
-	indexesSentTran, err := s.getSlotLeaderStage2TxIndexes(epochID - 1)
+	indexesSentTran, err := getSlotLeaderStage2TxIndexes(stateDb, epochID-1)
 	log.Debug("VerifySlotProof", "indexesSentTran", indexesSentTran)
 	if err != nil {
 		log.Error("getStageTwoFromTrans", "indexesSentTran error", err.Error())
@@ -293,7 +363,7 @@ func (s *SLS) getStageTwoFromTrans(epochID uint64) (validEpochLeadersIndex [posc
 			validEpochLeadersIndex[i] = false
 			continue
 		}
-		alphaPki, _, err := vm.GetStage2TxAlphaPki(s.stateDb, epochID-1, uint64(i))
+		alphaPki, _, err := vm.GetStage2TxAlphaPki(stateDb, epochID-1, uint64(i))
 		if err != nil {
 			log.Debug("VerifySlotProof:GetStage2TxAlphaPki", "index", i, "error", err.Error())
 			validEpochLeadersIndex[i] = false
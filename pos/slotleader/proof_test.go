@@ -8,7 +8,15 @@ import (
 	"testing"
 	"time"
 
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/uleaderselection"
+	"github.com/wanchain/go-wanchain/pos/util/convert"
+	"github.com/wanchain/go-wanchain/rlp"
 )
 
 func Wadd(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
@@ -85,3 +93,68 @@ func TestVerifyDleqProof(t *testing.T) {
 
 	fmt.Println("TestVerifyDleqProof total:", time.Since(t0))
 }
+
+func TestVerifySlotProofByLeaders(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	pk := crypto.FromECDSAPub(&key.PublicKey)
+	other, _ := crypto.GenerateKey()
+
+	// A single leader is picked for every slot
+	leaders := [][]byte{pk, pk, pk}
+	rb := big.NewInt(7).Bytes()
+	proofMeg, proof, err := uleaderselection.GenerateSlotLeaderProof2(key, []*ecdsa.PublicKey{&key.PublicKey},
+		[]*ecdsa.PublicKey{&key.PublicKey, &key.PublicKey, &key.PublicKey}, rb, 3, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifySlotProofByLeaders(proof, proofMeg, leaders, rb, false) {
+		t.Error("valid proof rejected")
+	}
+	if VerifySlotProofByLeaders(proof, proofMeg, [][]byte{crypto.FromECDSAPub(&other.PublicKey)}, rb, false) {
+		t.Error("proof of a foreign leader accepted")
+	}
+	if VerifySlotProofByLeaders(proof, proofMeg, nil, nil, false) {
+		t.Error("proof without leaders accepted")
+	}
+	if VerifySlotProofByLeaders(proof, proofMeg, leaders, rb, true) {
+		t.Error("leader proof accepted as a genesis proof")
+	}
+}
+
+func TestUsesGenesisProofs(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	stateDb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	key, _ := crypto.GenerateKey()
+	alphaPki := make([]*ecdsa.PublicKey, posconfig.EpochLeaderCount)
+	for i := range alphaPki {
+		alphaPki[i] = &key.PublicKey
+	}
+	stage2, err := vm.RlpPackStage2DataForTx(1, 0, &key.PublicKey, alphaPki, []*big.Int{big.NewInt(1), big.NewInt(2)}, vm.GetSlotLeaderScAbiString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	setIndexes := func(sent ...bool) {
+		data, _ := rlp.EncodeToBytes(sent)
+		stateDb.SetStateByteArray(vm.GetSlotLeaderSCAddress(), vm.GetSlotLeaderStage2IndexesKeyHash(convert.Uint64ToBytes(1)), data)
+	}
+
+	if !UsesGenesisProofs(stateDb, 0) {
+		t.Error("epoch 0 not proven by genesis")
+	}
+	if !UsesGenesisProofs(stateDb, 2) {
+		t.Error("epoch without stage two txs not proven by genesis")
+	}
+	setIndexes(false, true)
+	if !UsesGenesisProofs(stateDb, 2) {
+		t.Error("epoch without valid stage two txs not proven by genesis")
+	}
+	stateDb.SetStateByteArray(vm.GetSlotLeaderSCAddress(), vm.GetSlotLeaderStage2KeyHash(convert.Uint64ToBytes(1), convert.Uint64ToBytes(0)), stage2)
+	if !UsesGenesisProofs(stateDb, 2) {
+		t.Error("stage two tx of a leader missing from the indexes counted")
+	}
+	setIndexes(true)
+	if UsesGenesisProofs(stateDb, 2) {
+		t.Error("epoch with a valid stage two tx proven by genesis")
+	}
+}
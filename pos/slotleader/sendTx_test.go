@@ -6,12 +6,14 @@ import (
 	"github.com/wanchain/go-wanchain/accounts/keystore"
 
 	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/pos/posdb"
 	"github.com/wanchain/go-wanchain/rpc"
 )
 
-func testInit() {
-	SlsInit()
-	GetSlotLeaderSelection().Init(nil, &rpc.Client{}, &keystore.Key{})
+func testInit() *SLS {
+	s := NewSLS(posdb.NewMemDb(), &TestSelectLead{})
+	s.Init(nil, &rpc.Client{}, &keystore.Key{})
+	return s
 }
 
 func testSender(rc *rpc.Client, tx map[string]interface{}) (common.Hash, error) {
@@ -19,16 +21,14 @@ func testSender(rc *rpc.Client, tx map[string]interface{}) (common.Hash, error)
 }
 
 func TestSendStage1Tx(t *testing.T) {
-	testInit()
-	err := GetSlotLeaderSelection().sendSlotTx(nil, testSender)
+	err := testInit().sendSlotTx(nil, testSender)
 	if err != nil {
 		t.FailNow()
 	}
 }
 
 func TestSendStage2Tx(t *testing.T) {
-	testInit()
-	err := GetSlotLeaderSelection().sendSlotTx(nil, testSender)
+	err := testInit().sendSlotTx(nil, testSender)
 	if err != nil {
 		t.FailNow()
 	}
@@ -47,7 +47,7 @@ const (
 	slotLeaderSelectionStageFinished = iota + 1 //5
 )
 
-var (
+const (
 	errorRetry = 3
 )
 
@@ -61,39 +61,39 @@ type SLS struct {
 	epochLeadersArray []string            // len(pki)=65 hex.EncodeToString
 	epochLeadersMap   map[string][]uint64 // key: pki value: []uint64 the indexes of this pki. hex.EncodeToString
 
-	slotLeadersPtrArray  [posconfig.SlotCount]*ecdsa.PublicKey
-	slotLeadersIndex     [posconfig.SlotCount]uint64
-	epochLeadersPtrArray [posconfig.EpochLeaderCount]*ecdsa.PublicKey
+	slotLeadersPtrArray  []*ecdsa.PublicKey
+	slotLeadersIndex     []uint64
+	epochLeadersPtrArray []*ecdsa.PublicKey
 	// true: can be used to slot leader false: can not be used to slot leader
-	validEpochLeadersIndex [posconfig.EpochLeaderCount]bool
+	validEpochLeadersIndex []bool
 
-	stageOneMi       [posconfig.EpochLeaderCount]*ecdsa.PublicKey
-	stageTwoAlphaPKi [posconfig.EpochLeaderCount][posconfig.EpochLeaderCount]*ecdsa.PublicKey
-	stageTwoProof    [posconfig.EpochLeaderCount][StageTwoProofCount]*big.Int //[0]: e; [1]:Z
+	stageOneMi       []*ecdsa.PublicKey
+	stageTwoAlphaPKi [][]*ecdsa.PublicKey
+	stageTwoProof    [][StageTwoProofCount]*big.Int //[0]: e; [1]:Z
 
 	slotCreateStatus map[uint64]bool
 	blockChain       *core.BlockChain
 
-	epochLeadersPtrArrayGenesis [posconfig.EpochLeaderCount]*ecdsa.PublicKey
-	stageOneMiGenesis           [posconfig.EpochLeaderCount]*ecdsa.PublicKey
-	stageTwoAlphaPKiGenesis     [posconfig.EpochLeaderCount][posconfig.EpochLeaderCount]*ecdsa.PublicKey
-	stageTwoProofGenesis        [posconfig.EpochLeaderCount][StageTwoProofCount]*big.Int //[0]: e; [1]:Z
+	epochLeadersPtrArrayGenesis []*ecdsa.PublicKey
+	stageOneMiGenesis           []*ecdsa.PublicKey
+	stageTwoAlphaPKiGenesis     [][]*ecdsa.PublicKey
+	stageTwoProofGenesis        [][StageTwoProofCount]*big.Int //[0]: e; [1]:Z
 	randomGenesis               *big.Int
-	smaGenesis                  [posconfig.EpochLeaderCount]*ecdsa.PublicKey
+	smaGenesis                  []*ecdsa.PublicKey
 
 	sendTransactionFn SendTxFn
-}
 
-var slotLeaderSelection *SLS
+	db      *posdb.Db       // Local pos data
+	epocher util.SelectLead // Epoch leader selection of the node
+
+	retries int // Retries left to generate the security message of the working epoch
+}
 
 type Pack struct {
 	Proof    [][]byte
 	ProofMeg [][]byte
 }
 
-func GetSlotLeaderSelection() *SLS {
-	return slotLeaderSelection
-}
 func (s *SLS) GetLocalPublicKey() (*ecdsa.PublicKey, error) {
 	return s.getLocalPublicKey()
 }
@@ -120,14 +120,23 @@ func (s *SLS) GetSlotLeader(epochID uint64, slotID uint64) (slotLeader *ecdsa.Pu
 		return nil, vm.ErrSlotIDOutOfRange
 	}
 
+	// regenerate the slot leaders derived on a fork
+	if s.isStale(epochID, SlotLeader) {
+		log.Info("Regenerating slot leaders derived on a fork", "epochID", epochID)
+		delete(s.slotCreateStatus, epochID)
+		if err := s.generateSlotLeadsGroup(epochID); err != nil || !s.slotCreateStatus[epochID] {
+			return nil, vm.ErrSlotLeaderGroupNotReady
+		}
+	}
+
 	// read from memory
 	created, ok := s.slotCreateStatus[epochID]
 	if ok && created {
 		return s.slotLeadersPtrArray[slotID], nil
 	}
 	// read from local db
-	for i := 0; i < posconfig.SlotCount; i++ {
-		pkByte, err := posdb.GetDb().GetWithIndex(epochID, uint64(i), SlotLeader)
+	for i := uint64(0); i < posconfig.SlotCount; i++ {
+		pkByte, err := s.db.GetWithIndex(epochID, i, SlotLeader)
 		if err != nil {
 			return nil, vm.ErrSlotLeaderGroupNotReady
 		}
@@ -141,12 +150,14 @@ func (s *SLS) GetSma(epochID uint64) (ret []*ecdsa.PublicKey, isGenesis bool, er
 	return s.getSMAPieces(epochID)
 }
 
-func SlsInit() {
-	slotLeaderSelection = &SLS{}
-	slotLeaderSelection.epochLeadersMap = make(map[string][]uint64)
-	slotLeaderSelection.epochLeadersArray = make([]string, 0)
-	slotLeaderSelection.slotCreateStatus = make(map[uint64]bool)
-	s := slotLeaderSelection
+// NewSLS creates the slot leader selection of a node keeping its local data in
+// db and taking the epoch leaders from epocher.
+func NewSLS(db *posdb.Db, epocher util.SelectLead) *SLS {
+	s := &SLS{db: db, epocher: epocher, retries: errorRetry}
+	s.epochLeadersMap = make(map[string][]uint64)
+	s.epochLeadersArray = make([]string, 0)
+	s.slotCreateStatus = make(map[uint64]bool)
+	s.allocArrays()
 	s.randomGenesis = big.NewInt(1)
 	epoch0Leaders := s.getEpoch0LeadersPK()
 	for index, value := range epoch0Leaders {
@@ -204,15 +215,38 @@ func SlsInit() {
 	}
 	log.Debug("slot_leader_selection:init", "genesis sma pieces", smaPiecesHexStr)
 
+	return s
 }
 
-func (s *SLS) getSlotLeaderStage2TxIndexes(epochID uint64) (indexesSentTran []bool, err error) {
-	var ret [posconfig.EpochLeaderCount]bool
-	stateDb, err := s.getCurrentStateDb()
-	if err != nil {
-		return ret[:], err
+// allocArrays sizes the epoch and slot leader arrays from the pos epoch parameters.
+func (s *SLS) allocArrays() {
+	s.slotLeadersPtrArray = make([]*ecdsa.PublicKey, posconfig.SlotCount)
+	s.slotLeadersIndex = make([]uint64, posconfig.SlotCount)
+	s.epochLeadersPtrArray = make([]*ecdsa.PublicKey, posconfig.EpochLeaderCount)
+	s.validEpochLeadersIndex = make([]bool, posconfig.EpochLeaderCount)
+
+	s.stageOneMi = make([]*ecdsa.PublicKey, posconfig.EpochLeaderCount)
+	s.stageTwoAlphaPKi = newAlphaPKiArray()
+	s.stageTwoProof = make([][StageTwoProofCount]*big.Int, posconfig.EpochLeaderCount)
+
+	s.epochLeadersPtrArrayGenesis = make([]*ecdsa.PublicKey, posconfig.EpochLeaderCount)
+	s.stageOneMiGenesis = make([]*ecdsa.PublicKey, posconfig.EpochLeaderCount)
+	s.stageTwoAlphaPKiGenesis = newAlphaPKiArray()
+	s.stageTwoProofGenesis = make([][StageTwoProofCount]*big.Int, posconfig.EpochLeaderCount)
+	s.smaGenesis = make([]*ecdsa.PublicKey, posconfig.EpochLeaderCount)
+}
+
+// newAlphaPKiArray returns an EpochLeaderCount x EpochLeaderCount array of stage two alpha*PKi.
+func newAlphaPKiArray() [][]*ecdsa.PublicKey {
+	ret := make([][]*ecdsa.PublicKey, posconfig.EpochLeaderCount)
+	for i := range ret {
+		ret[i] = make([]*ecdsa.PublicKey, posconfig.EpochLeaderCount)
 	}
+	return ret
+}
 
+func getSlotLeaderStage2TxIndexes(stateDb *state.StateDB, epochID uint64) (indexesSentTran []bool, err error) {
+	ret := make([]bool, posconfig.EpochLeaderCount)
 	slotLeaderPrecompileAddr := vm.GetSlotLeaderSCAddress()
 
 	keyHash := vm.GetSlotLeaderStage2IndexesKeyHash(convert.Uint64ToBytes(epochID))
@@ -226,10 +260,12 @@ func (s *SLS) getSlotLeaderStage2TxIndexes(epochID uint64) (indexesSentTran []bo
 		return ret[:], vm.ErrNoTx2TransInDB
 	}
 
-	err = rlp.DecodeBytes(data, &ret)
+	var sent []bool
+	err = rlp.DecodeBytes(data, &sent)
 	if err != nil {
 		return ret[:], vm.ErrNoTx2TransInDB
 	}
+	copy(ret, sent)
 	return ret[:], nil
 }
 
@@ -238,7 +274,7 @@ func (s *SLS) getAlpha(epochID uint64, selfIndex uint64) (*big.Int, error) {
 		ret := big.NewInt(123)
 		return ret, nil
 	}
-	buf, err := posdb.GetDb().GetWithIndex(epochID, selfIndex, "alpha")
+	buf, err := s.db.GetWithIndex(epochID, selfIndex, "alpha")
 	if err != nil {
 		return nil, err
 	}
@@ -262,7 +298,7 @@ func (s *SLS) getEpochLeaders(epochID uint64) [][]byte {
 	//test := false
 	if posconfig.SelfTestMode {
 		//test: generate test publicKey
-		epochLeaderAllBytes, err := posdb.GetDb().Get(epochID, EpochLeaders)
+		epochLeaderAllBytes, err := s.db.Get(epochID, EpochLeaders)
 		if err != nil {
 			return nil
 		}
@@ -279,17 +315,11 @@ func (s *SLS) getEpochLeaders(epochID uint64) [][]byte {
 		}
 		return ret
 	} else {
-		type epoch interface {
-			GetEpochLeaders(epochID uint64) [][]byte
-		}
-
-		selector := util.GetEpocherInst() //TODO:CHECK INIT
-
-		if selector == nil {
+		if s.epocher == nil {
 			return nil
 		}
 
-		epochLeaders := selector.GetEpochLeaders(epochID)
+		epochLeaders := s.epocher.GetEpochLeaders(epochID)
 		if epochLeaders != nil {
 			log.Debug(fmt.Sprintf("getEpochLeaders called return len(epochLeaders):%d", len(epochLeaders)))
 		}
@@ -323,6 +353,10 @@ func (s *SLS) getPreEpochLeadersPK(epochID uint64) ([]*ecdsa.PublicKey, error) {
 }
 
 func (s *SLS) getEpoch0LeadersPK() []*ecdsa.PublicKey {
+	return epoch0LeadersPK()
+}
+
+func epoch0LeadersPK() []*ecdsa.PublicKey {
 	pks := make([]*ecdsa.PublicKey, posconfig.EpochLeaderCount)
 	for i := 0; i < posconfig.EpochLeaderCount; i++ {
 		pkBuf, err := hex.DecodeString(posconfig.GenesisPK)
@@ -398,11 +432,11 @@ func (s *SLS) clearData() {
 		}
 	}
 
-	for i := 0; i < posconfig.SlotCount; i++ {
+	for i := uint64(0); i < posconfig.SlotCount; i++ {
 		s.slotLeadersPtrArray[i] = nil
 	}
 
-	for i := 0; i < posconfig.SlotCount; i++ {
+	for i := uint64(0); i < posconfig.SlotCount; i++ {
 		s.slotLeadersIndex[i] = 0
 	}
 }
@@ -496,21 +530,31 @@ func (s *SLS) buildEpochLeaderGroup(epochID uint64) {
 }
 
 func (s *SLS) getRandom(block *types.Block, epochID uint64) (ret *big.Int, err error) {
-	// If db is nil, use current stateDB
-	var db *state.StateDB
+	rb, _ := s.getRandomWithSource(block, epochID)
+	return rb, nil
+}
+
+// getRandomWithSource returns the random of an epoch like getRandom, with the
+// header of the block whose state it was read from, nil if the state is missing.
+func (s *SLS) getRandomWithSource(block *types.Block, epochID uint64) (*big.Int, *types.Header) {
+	// If block is nil, use current stateDB
+	var (
+		db     *state.StateDB
+		source *types.Header
+		err    error
+	)
 	if block == nil {
-		db, err = s.getCurrentStateDb()
+		db, source, err = s.currentState()
 		if err != nil {
 			log.Error("SLS.getRandom getStateDb return error, use a default value", "epochID", epochID)
-			rb := big.NewInt(1)
-			return rb, nil
+			return big.NewInt(1), nil
 		}
 	} else {
-		db, err = s.blockChain.StateAt(s.blockChain.GetBlockByHash(block.ParentHash()).Root())
+		source = s.blockChain.GetHeaderByHash(block.ParentHash())
+		db, err = s.blockChain.StateAt(source.Root)
 		if err != nil {
 			log.Error("Update stateDb error in SLS.updateToLastStateDb", "error", err.Error())
-			rb := big.NewInt(1)
-			return rb, nil
+			return big.NewInt(1), nil
 		}
 	}
 
@@ -519,7 +563,7 @@ func (s *SLS) getRandom(block *types.Block, epochID uint64) (ret *big.Int, err e
 		log.Error("vm.GetR return nil, use a default value", "epochID", epochID)
 		rb = big.NewInt(1)
 	}
-	return rb, nil
+	return rb, source
 }
 
 // getSMAPieces can get the SMA info generate in pre epoch.
@@ -529,8 +573,17 @@ func (s *SLS) getSMAPieces(epochID uint64) (ret []*ecdsa.PublicKey, isGenesis bo
 	if epochID == uint64(0) {
 		return s.smaGenesis[:], true, nil
 	} else {
+		// regenerate the SMA derived on a fork, the nodes that can't are left
+		// without SMA as the ones that never had it
+		if s.isStale(epochID, SecurityMsg) {
+			log.Info("Regenerating SMA derived on a fork", "epochID", epochID)
+			if err := s.regenerateSecurityMsg(epochID); err != nil {
+				log.Warn("getSMAPieces can not regenerate SMA use epoch 0 SMA", "epochID", epochID, "error", err.Error())
+				return s.smaGenesis[:], true, nil
+			}
+		}
 		// pieces: alpha[1]*G, alpha[2]*G, .....
-		pieces, err := posdb.GetDb().Get(epochID, SecurityMsg)
+		pieces, err := s.db.Get(epochID, SecurityMsg)
 		if err != nil {
 			log.Warn("getSMAPieces error use epoch 0 SMA", "epochID", epochID, "SecurityMsg", SecurityMsg)
 			return s.smaGenesis[:], true, nil
@@ -565,11 +618,8 @@ func (s *SLS) generateSlotLeadsGroup(epochID uint64) error {
 		epochIDGet = 0
 
 	}
-	// get random
-	random, err := s.getRandom(nil, epochIDGet)
-	if err != nil {
-		return vm.ErrInvalidRandom
-	}
+	// get random, the slot leaders are scoped to the block it was read from
+	random, anchor := s.getRandomWithSource(nil, epochIDGet)
 	log.Debug("generateSlotLeadsGroup", "Random got", hex.EncodeToString(random.Bytes()))
 
 	// return slot leaders pointers.
@@ -609,12 +659,13 @@ func (s *SLS) generateSlotLeadsGroup(epochID uint64) error {
 
 	// insert slot address to local DB
 	for index, val := range slotLeadersPtr {
-		_, err = posdb.GetDb().PutWithIndex(uint64(epochID), uint64(index), SlotLeader, crypto.FromECDSAPub(val))
+		_, err = s.db.PutWithIndex(uint64(epochID), uint64(index), SlotLeader, crypto.FromECDSAPub(val))
 		if err != nil {
 			log.Error("generateSlotLeadsGroup:PutWithIndex", "error", err.Error())
 			return err
 		}
 	}
+	s.putAnchor(epochID, SlotLeader, anchor)
 
 	for index, val := range slotLeadersPtr {
 		s.slotLeadersPtrArray[index] = val
@@ -633,15 +684,16 @@ func (s *SLS) generateSlotLeadsGroup(epochID uint64) error {
 }
 
 // create alpha1*pki,alpha1*PKi,alphaN*PKi,...
-// used to create security message.
-func (s *SLS) buildSecurityPieces(epochID uint64) (pieces []*ecdsa.PublicKey, err error) {
+// used to create security message. epochLeadersMap maps the epoch leaders of
+// epochID to their indexes.
+func (s *SLS) buildSecurityPieces(epochID uint64, epochLeadersMap map[string][]uint64) (pieces []*ecdsa.PublicKey, err error) {
 
 	selfPk, err := s.getLocalPublicKey()
 	if err != nil {
 		return nil, err
 	}
 
-	indexes, exist := s.epochLeadersMap[hex.EncodeToString(crypto.FromECDSAPub(selfPk))]
+	indexes, exist := epochLeadersMap[hex.EncodeToString(crypto.FromECDSAPub(selfPk))]
 	if exist == false {
 		log.Warn(fmt.Sprintf("%v not in epoch leaders", hex.EncodeToString(crypto.FromECDSAPub(selfPk))))
 		return nil, nil
@@ -649,7 +701,7 @@ func (s *SLS) buildSecurityPieces(epochID uint64) (pieces []*ecdsa.PublicKey, er
 
 	selfPkReceivePiecesMap := make(map[uint64][]*ecdsa.PublicKey, 0)
 	for _, selfIndex := range indexes {
-		for i := 0; i < len(s.epochLeadersArray); i++ {
+		for i := 0; i < posconfig.EpochLeaderCount; i++ {
 			if (s.stageTwoAlphaPKi[i][selfIndex] != nil) && (s.validEpochLeadersIndex[i]) {
 				selfPkReceivePiecesMap[selfIndex] = append(selfPkReceivePiecesMap[selfIndex],
 					s.stageTwoAlphaPKi[i][selfIndex])
@@ -665,11 +717,17 @@ func (s *SLS) buildSecurityPieces(epochID uint64) (pieces []*ecdsa.PublicKey, er
 	return piece, nil
 }
 
-func (s *SLS) collectStagesData(epochID uint64) (err error) {
-	indexesSentTran, err := s.getSlotLeaderStage2TxIndexes(epochID)
+// collectStagesData collects the stage two data of an epoch from the current
+// state, returning the header of the block the state belongs to.
+func (s *SLS) collectStagesData(epochID uint64) (source *types.Header, err error) {
+	stateDb, source, err := s.currentState()
+	if err != nil {
+		return nil, vm.ErrCollectTxData
+	}
+	indexesSentTran, err := getSlotLeaderStage2TxIndexes(stateDb, epochID)
 	log.Debug("collectStagesData", "indexesSentTran", indexesSentTran)
 	if err != nil {
-		return vm.ErrCollectTxData
+		return nil, vm.ErrCollectTxData
 	}
 	for i := 0; i < posconfig.EpochLeaderCount; i++ {
 
@@ -677,8 +735,7 @@ func (s *SLS) collectStagesData(epochID uint64) (err error) {
 			s.validEpochLeadersIndex[i] = false
 			continue
 		}
-		// no need get current stateDB, because in getSlotLeaderStage2TxIndexes, have got the current db in s.stateDb.
-		alphaPki, proof, err := vm.GetStage2TxAlphaPki(s.stateDb, epochID, uint64(i))
+		alphaPki, proof, err := vm.GetStage2TxAlphaPki(stateDb, epochID, uint64(i))
 		if err != nil {
 			log.Warn("GetStage2TxAlphaPki", "error", err.Error(), "index", i)
 			s.validEpochLeadersIndex[i] = false
@@ -689,6 +746,7 @@ func (s *SLS) collectStagesData(epochID uint64) (err error) {
 			log.Warn("GetStage2TxAlphaPki", "error", "len(alphaPkis) or len(proofs) is wrong.", "index", i)
 			s.validEpochLeadersIndex[i] = false
 		} else {
+			s.validEpochLeadersIndex[i] = true
 			for j := 0; j < posconfig.EpochLeaderCount; j++ {
 				s.stageTwoAlphaPKi[i][j] = alphaPki[j]
 			}
@@ -698,7 +756,7 @@ func (s *SLS) collectStagesData(epochID uint64) (err error) {
 			}
 		}
 	}
-	return nil
+	return source, nil
 }
 
 func (s *SLS) generateSecurityMsg(epochID uint64, PrivateKey *ecdsa.PrivateKey) error {
@@ -707,14 +765,39 @@ func (s *SLS) generateSecurityMsg(epochID uint64, PrivateKey *ecdsa.PrivateKey)
 			hex.EncodeToString(crypto.FromECDSAPub(&PrivateKey.PublicKey)))
 		return vm.ErrPkNotInCurrentEpochLeadersGroup
 	}
+	return s.buildSecurityMsg(epochID, PrivateKey, s.epochLeadersMap)
+}
+
+// regenerateSecurityMsg builds again the security message used in an epoch,
+// derived on a fork, from the stage two data of the previous epoch in the
+// canonical chain. Only the epoch leaders of the previous epoch can build it.
+func (s *SLS) regenerateSecurityMsg(epochID uint64) error {
+	selfPk, err := s.getLocalPublicKey()
+	if err != nil {
+		return err
+	}
+	epochLeadersMap := make(map[string][]uint64)
+	for index, value := range s.getEpochLeaders(epochID - 1) {
+		epochLeadersMap[hex.EncodeToString(value)] = append(epochLeadersMap[hex.EncodeToString(value)], uint64(index))
+	}
+	if _, ok := epochLeadersMap[hex.EncodeToString(crypto.FromECDSAPub(selfPk))]; !ok {
+		return vm.ErrPkNotInCurrentEpochLeadersGroup
+	}
+	return s.buildSecurityMsg(epochID-1, s.key.PrivateKey, epochLeadersMap)
+}
+
+// buildSecurityMsg builds the security message of the epoch after epochID
+// from the stage two data of epochID, whose epoch leaders are mapped to their
+// indexes by epochLeadersMap, and scopes it to the block the data was read from.
+func (s *SLS) buildSecurityMsg(epochID uint64, PrivateKey *ecdsa.PrivateKey, epochLeadersMap map[string][]uint64) error {
 	// collect data
-	err := s.collectStagesData(epochID)
+	anchor, err := s.collectStagesData(epochID)
 	if err != nil {
 		return vm.ErrCollectTxData
 	}
 
 	// build security self pieces. alpha1*pki, alpha2*pk2, alpha3*pk3....
-	ArrayPiece, err := s.buildSecurityPieces(epochID)
+	ArrayPiece, err := s.buildSecurityPieces(epochID, epochLeadersMap)
 	if err != nil {
 		log.Warn("generateSecurityMsg:buildSecurityPieces", "error", err.Error())
 		return err
@@ -733,11 +816,12 @@ func (s *SLS) generateSecurityMsg(epochID uint64, PrivateKey *ecdsa.PrivateKey)
 		log.Debug(fmt.Sprintf("epochID+1 = %d set security message is %v\n", epochID+1,
 			hex.EncodeToString(crypto.FromECDSAPub(value))))
 	}
-	_, err = posdb.GetDb().Put(uint64(epochID+1), SecurityMsg, smasBytes.Bytes())
+	_, err = s.db.Put(uint64(epochID+1), SecurityMsg, smasBytes.Bytes())
 	if err != nil {
 		log.Warn("generateSecurityMsg:Put", "error", err.Error())
 		return err
 	}
+	s.putAnchor(epochID+1, SecurityMsg, anchor)
 	return nil
 }
 
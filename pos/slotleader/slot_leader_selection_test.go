@@ -17,8 +17,7 @@ import (
 )
 
 func TestSlotLeaderSelectionGetInstance(t *testing.T) {
-	posdb.GetDb().DbInit("test")
-	slot := GetSlotLeaderSelection()
+	slot := NewSLS(posdb.NewMemDb(), &TestSelectLead{})
 	if slot == nil {
 		t.Fail()
 	}
@@ -201,7 +200,7 @@ func TestCRSave(t *testing.T) {
 func TestArraySave(t *testing.T) {
 
 	fmt.Printf("TestArraySave\n\n\n")
-	var sendtrans [posconfig.EpochLeaderCount]bool
+	sendtrans := make([]bool, posconfig.EpochLeaderCount)
 	for index := range sendtrans {
 		sendtrans[index] = false
 	}
@@ -215,10 +214,10 @@ func TestArraySave(t *testing.T) {
 		t.Error(err.Error())
 	}
 
-	db := posdb.NewDb("testArraySave")
+	db := posdb.NewMemDb()
 	db.Put(uint64(0), "TestArraySave", bytes)
 
-	var sendtransGet [posconfig.EpochLeaderCount]bool
+	var sendtransGet []bool
 	bytesGet, err := db.Get(uint64(0), "TestArraySave")
 	if err != nil {
 		t.Error(err.Error())
@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/log"
 )
 
@@ -32,6 +33,47 @@ func (s *SLS) updateToLastStateDb() {
 	s.stateDb = stateDb
 }
 
+// currentState returns the state of the current block with the header of the
+// block, which the data derived from the state is scoped to.
+func (s *SLS) currentState() (*state.StateDB, *types.Header, error) {
+	block := s.blockChain.CurrentBlock()
+	stateDb, err := s.blockChain.StateAt(block.Root())
+	if err != nil {
+		return nil, nil, err
+	}
+	return stateDb, block.Header(), nil
+}
+
+// parentState returns the state the block is built on, the current state if
+// block is nil.
+func (s *SLS) parentState(block *types.Block) (*state.StateDB, error) {
+	if block == nil {
+		return s.getCurrentStateDb()
+	}
+	parent := s.blockChain.GetHeaderByHash(block.ParentHash())
+	if parent == nil {
+		return nil, errNoStateDbInstance
+	}
+	return s.blockChain.StateAt(parent.Root)
+}
+
+// putAnchor scopes the entries stored for an epoch under key to the block of
+// the state they were derived from.
+func (s *SLS) putAnchor(epochID uint64, key string, header *types.Header) {
+	if header == nil {
+		return
+	}
+	if err := s.db.PutAnchor(epochID, key, header); err != nil {
+		log.Warn("Failed to store anchor", "epochID", epochID, "key", key, "error", err.Error())
+	}
+}
+
+// isStale reports whether the entries stored for an epoch under key were
+// derived from a block reorged out of the canonical chain.
+func (s *SLS) isStale(epochID uint64, key string) bool {
+	return s.blockChain != nil && s.db.IsStale(epochID, key, s.blockChain)
+}
+
 func (s *SLS) getLastEpochIDFromChain() uint64 {
 	lastEpochID := uint64((s.blockChain.CurrentBlock().Difficulty().Int64() >> 32))
 	return lastEpochID
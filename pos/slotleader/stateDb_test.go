@@ -10,13 +10,14 @@ import (
 	"github.com/wanchain/go-wanchain/core"
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/pos/posdb"
 	"github.com/wanchain/go-wanchain/rpc"
 )
 
 var s *SLS
 
 func testInitSlotleader() {
-	s = GetSlotLeaderSelection()
+	s = NewSLS(posdb.NewMemDb(), &TestSelectLead{})
 
 	// Create the database in memory or in a temporary directory.
 	db, _ := ethdb.NewMemDatabase()
@@ -19,7 +19,6 @@ import (
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/functrace"
 	"github.com/wanchain/go-wanchain/log"
-	"github.com/wanchain/go-wanchain/pos/posdb"
 	"github.com/wanchain/go-wanchain/rpc"
 	"github.com/wanchain/go-wanchain/pos/uleaderselection"
 )
@@ -89,7 +88,7 @@ func (s *SLS) Loop(rc *rpc.Client, key *keystore.Key, epochID uint64, slotID uin
 			break
 		}
 
-		go doStage2Work()
+		go s.doStage2Work()
 		s.setWorkStage(epochID, slotLeaderSelectionStage3)
 	case slotLeaderSelectionStage3:
 		if slotID < posconfig.Sma3Start {
@@ -103,13 +102,13 @@ func (s *SLS) Loop(rc *rpc.Client, key *keystore.Key, epochID uint64, slotID uin
 			log.Info("generateSecurityMsg SMA success!")
 		}
 
-		if err != nil && errorRetry > 0 {
-			errorRetry--
+		if err != nil && s.retries > 0 {
+			s.retries--
 			break
 		}
 
 		s.setWorkStage(epochID, slotLeaderSelectionStageFinished)
-		errorRetry = 3
+		s.retries = errorRetry
 	case slotLeaderSelectionStageFinished:
 	default:
 	}
@@ -158,8 +157,7 @@ func (s *SLS) startStage1Work() error {
 	return nil
 }
 
-func doStage2Work() {
-	s := GetSlotLeaderSelection()
+func (s *SLS) doStage2Work() {
 	err := s.startStage2Work()
 	if err != nil {
 		log.Error(err.Error())
@@ -222,7 +220,7 @@ func (s *SLS) generateCommitment(publicKey *ecdsa.PublicKey,
 	buffer, err := vm.RlpPackStage1DataForTx(epochID, selfIndexInEpochLeader, commitment[1],
 		vm.GetSlotLeaderScAbiString())
 
-	posdb.GetDb().PutWithIndex(epochID, selfIndexInEpochLeader, "alpha", alpha.Bytes())
+	s.db.PutWithIndex(epochID, selfIndexInEpochLeader, "alpha", alpha.Bytes())
 
 	log.Debug(fmt.Sprintf("----Put alpha epochID:%d, selfIndex:%d, alpha:%s, mi:%s, pk:%s", epochID,
 		selfIndexInEpochLeader, alpha.String(), hex.EncodeToString(crypto.FromECDSAPub(commitment[1])),
@@ -245,10 +243,10 @@ func (s *SLS) setCurrentWorkStage(workStage int) {
 }
 
 func (s *SLS) getWorkingEpochID() uint64 {
-	ret, err := posdb.GetDb().Get(0, "slotLeaderCurrentSlotID")
+	ret, err := s.db.Get(0, "slotLeaderCurrentSlotID")
 	if err != nil {
 		if err.Error() == "leveldb: not found" {
-			posdb.GetDb().Put(0, "slotLeaderCurrentSlotID", convert.Uint64ToBytes(0))
+			s.db.Put(0, "slotLeaderCurrentSlotID", convert.Uint64ToBytes(0))
 			return 0
 		}
 	}
@@ -257,12 +255,12 @@ func (s *SLS) getWorkingEpochID() uint64 {
 }
 
 func (s *SLS) setWorkingEpochID(workingEpochID uint64) error {
-	_, err := posdb.GetDb().Put(0, "slotLeaderCurrentSlotID", convert.Uint64ToBytes(workingEpochID))
+	_, err := s.db.Put(0, "slotLeaderCurrentSlotID", convert.Uint64ToBytes(workingEpochID))
 	return err
 }
 
 func (s *SLS) getWorkStage(epochID uint64) int {
-	ret, err := posdb.GetDb().Get(epochID, "slotLeaderWorkStage")
+	ret, err := s.db.Get(epochID, "slotLeaderWorkStage")
 	if err != nil {
 		if err.Error() == "leveldb: not found" {
 			s.setWorkStage(epochID, slotLeaderSelectionInit)
@@ -277,6 +275,6 @@ func (s *SLS) getWorkStage(epochID uint64) int {
 
 func (s *SLS) setWorkStage(epochID uint64, workStage int) error {
 	workStageBig := big.NewInt(int64(workStage))
-	_, err := posdb.GetDb().Put(epochID, "slotLeaderWorkStage", workStageBig.Bytes())
+	_, err := s.db.Put(epochID, "slotLeaderWorkStage", workStageBig.Bytes())
 	return err
 }
@@ -8,13 +8,13 @@ import (
 	"time"
 
 	"github.com/wanchain/go-wanchain/pos/posconfig"
-	"github.com/wanchain/go-wanchain/pos/util"
 
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/wanchain/go-wanchain/accounts/keystore"
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/crypto/bn256"
 	"github.com/wanchain/go-wanchain/pos/posdb"
 	"github.com/wanchain/go-wanchain/rlp"
 	"github.com/wanchain/go-wanchain/rpc"
@@ -40,6 +40,8 @@ func (t *TestSelectLead) GetProposerBn256PK(epochID uint64, idx uint64, addr com
 	return nil
 }
 
+func (t *TestSelectLead) GetRBProposerG1(epochID uint64) []bn256.G1 { return nil }
+
 func generateTestAddrs() {
 	for i := 0; i < addrsCount; i++ {
 		key, _ := crypto.GenerateKey()
@@ -49,11 +51,8 @@ func generateTestAddrs() {
 }
 
 func TestLoop(t *testing.T) {
-	posdb.GetDb().DbInit("test")
-
 	generateTestAddrs()
 	testInitSlotleader()
-	util.SetEpocherInst(&TestSelectLead{})
 
 	key := &keystore.Key{}
 	key.PrivateKey, _ = crypto.GenerateKey()
@@ -61,18 +60,17 @@ func TestLoop(t *testing.T) {
 
 	epochIDStart := time.Now().Second()
 
-	for i := 0; i < posconfig.SlotCount; i++ {
-		s.Loop(&rpc.Client{}, key, uint64(epochIDStart+0), uint64(i))
+	for i := uint64(0); i < posconfig.SlotCount; i++ {
+		s.Loop(&rpc.Client{}, key, uint64(epochIDStart+0), i)
 	}
 
-	for i := 0; i < posconfig.SlotCount; i++ {
-		s.Loop(&rpc.Client{}, key, uint64(epochIDStart+1), uint64(i))
+	for i := uint64(0); i < posconfig.SlotCount; i++ {
+		s.Loop(&rpc.Client{}, key, uint64(epochIDStart+1), i)
 	}
 }
 
 func TestGenerateCommitmentSuccess(t *testing.T) {
-	posdb.GetDb().DbInit("test")
-	slot := GetSlotLeaderSelection()
+	slot := NewSLS(posdb.NewMemDb(), &TestSelectLead{})
 
 	privKey, err := crypto.GenerateKey()
 	if err != nil {
@@ -116,8 +114,7 @@ func TestGenerateCommitmentSuccess(t *testing.T) {
 }
 
 func TestGenerateCommitmentFailed(t *testing.T) {
-	posdb.GetDb().DbInit("test")
-	slot := GetSlotLeaderSelection()
+	slot := NewSLS(posdb.NewMemDb(), &TestSelectLead{})
 
 	privKey, err := crypto.GenerateKey()
 	if err != nil {
@@ -0,0 +1,138 @@
+package util
+
+import (
+	"sync"
+	"time"
+
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+)
+
+// Clock is the time source used by the pos slot timing. It defaults to the
+// system clock; simulations replace it to drive slots faster than wall time.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// SlotClock is the pos slot timing of a node: its time source and the epoch
+// base time, the timestamp of the chain's first pos block. A nil SlotClock
+// runs on the system clock without a base time.
+type SlotClock struct {
+	mu       sync.RWMutex
+	clock    Clock
+	baseTime uint64
+
+	curEpochId uint64 // Epoch of the last CalEpochSlotIDByNow
+	curSlotId  uint64 // Slot of the last CalEpochSlotIDByNow
+}
+
+// NewSlotClock creates a slot clock on the time source, a nil clock selects
+// the system clock.
+func NewSlotClock(clock Clock) *SlotClock {
+	sc := new(SlotClock)
+	sc.SetClock(clock)
+	return sc
+}
+
+// SlotClockReader is implemented by the chains keeping a node's slot clock.
+type SlotClockReader interface {
+	SlotClock() *SlotClock
+}
+
+// ChainSlotClock returns the slot clock of chain, nil if it keeps none.
+func ChainSlotClock(chain interface{}) *SlotClock {
+	if r, ok := chain.(SlotClockReader); ok {
+		return r.SlotClock()
+	}
+	return nil
+}
+
+// SetClock replaces the time source. A nil clock restores the system clock.
+func (sc *SlotClock) SetClock(clock Clock) {
+	if clock == nil {
+		clock = systemClock{}
+	}
+	sc.mu.Lock()
+	sc.clock = clock
+	sc.mu.Unlock()
+}
+
+func (sc *SlotClock) source() Clock {
+	if sc == nil {
+		return systemClock{}
+	}
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return sc.clock
+}
+
+// Now returns the current time of the time source.
+func (sc *SlotClock) Now() time.Time {
+	return sc.source().Now()
+}
+
+// NowUnix returns the current unix time of the time source in seconds.
+func (sc *SlotClock) NowUnix() uint64 {
+	return uint64(sc.Now().Unix())
+}
+
+// After waits for the duration to elapse on the time source.
+func (sc *SlotClock) After(d time.Duration) <-chan time.Time {
+	return sc.source().After(d)
+}
+
+// BaseTime returns the epoch base time, 0 until the first pos block is known.
+func (sc *SlotClock) BaseTime() uint64 {
+	if sc == nil {
+		return 0
+	}
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return sc.baseTime
+}
+
+// SetBaseTime sets the epoch base time.
+func (sc *SlotClock) SetBaseTime(baseTime uint64) {
+	sc.mu.Lock()
+	sc.baseTime = baseTime
+	sc.mu.Unlock()
+}
+
+// CalEpochSlotID returns the epoch and slot of the unix time, 0 and 0 while
+// the base time is unknown.
+func (sc *SlotClock) CalEpochSlotID(time uint64) (epochId, slotId uint64) {
+	baseTime := sc.BaseTime()
+	if baseTime == 0 {
+		return
+	}
+	epochTimespan := uint64(posconfig.SlotTime * posconfig.SlotCount)
+	epochId = uint64((time - baseTime) / epochTimespan)
+	slotId = uint64((time - baseTime) / posconfig.SlotTime % posconfig.SlotCount)
+	return epochId, slotId
+}
+
+// CalEpochSlotIDByNow updates the current epoch and slot from the time source.
+func (sc *SlotClock) CalEpochSlotIDByNow() {
+	if sc.BaseTime() == 0 {
+		return
+	}
+	epochId, slotId := sc.CalEpochSlotID(sc.NowUnix())
+	sc.mu.Lock()
+	sc.curEpochId, sc.curSlotId = epochId, slotId
+	sc.mu.Unlock()
+}
+
+// GetEpochSlotID returns the epoch and slot of the last CalEpochSlotIDByNow.
+func (sc *SlotClock) GetEpochSlotID() (uint64, uint64) {
+	if sc == nil {
+		return 0, 0
+	}
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return sc.curEpochId, sc.curSlotId
+}
@@ -6,11 +6,22 @@ import (
 	"testing"
 
 	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
 )
 
-func TestGetEpochSlotID(t *testing.T) {
-	epochID, slotID := GetEpochSlotID()
-	fmt.Println("epochID:", epochID, " slotID:", slotID)
+func TestSlotClock(t *testing.T) {
+	var sc *SlotClock
+	if epochID, slotID := sc.CalEpochSlotID(1000); epochID != 0 || slotID != 0 {
+		t.Fatalf("nil clock: have epoch %d slot %d, want 0 0", epochID, slotID)
+	}
+
+	sc = NewSlotClock(nil)
+	sc.SetBaseTime(1000)
+	epochSpan := posconfig.SlotTime * posconfig.SlotCount
+	epochID, slotID := sc.CalEpochSlotID(1000 + 2*epochSpan + 3*posconfig.SlotTime)
+	if epochID != 2 || slotID != 3 {
+		t.Fatalf("have epoch %d slot %d, want 2 3", epochID, slotID)
+	}
 }
 
 func TestPkCompress(t *testing.T) {
@@ -0,0 +1,51 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package wanclient
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/accounts/abi/bind"
+	"github.com/wanchain/go-wanchain/accounts/abi/bind/backends"
+	"github.com/wanchain/go-wanchain/core"
+)
+
+func ExampleRefundOTA() {
+	// A node connection from ethclient.Dial works the same way
+	backend := backends.NewSimulatedBackendEx(core.GenesisAlloc{testAddr: {Balance: testBalance}})
+
+	// The keys of a WAddress, loaded locally with keystore.DecryptKey
+	a, b := newTestWAddress()
+
+	// An OTA paid to the WAddress, among others of the same value
+	ota := newTestOTA(a, b)
+	buyTestCoins(backend, ota, newTestOTA(newTestWAddress()), newTestOTA(newTestWAddress()))
+
+	// Spend the OTA to the test account, without sending any key to the node
+	otaKey, err := OTAPrivateKey(a, b, ota)
+	if err != nil {
+		fmt.Println("failed to compute OTA key:", err)
+		return
+	}
+	before, _ := backend.BalanceAt(context.Background(), testAddr, nil)
+	tx, err := RefundOTA(bind.NewKeyedTransactor(testKey), backend, otaKey, ota, testValue, 2, nil)
+	if err != nil {
+		fmt.Println("failed to refund OTA:", err)
+		return
+	}
+	backend.Commit()
+
+	// The sender is paid the value of the OTA, less the gas of the refund
+	receipt, _ := backend.TransactionReceipt(context.Background(), tx.Hash())
+	after, _ := backend.BalanceAt(context.Background(), testAddr, nil)
+	refunded := new(big.Int).Sub(after, before)
+	refunded.Add(refunded, new(big.Int).Mul(receipt.GasUsed, tx.GasPrice()))
+
+	fmt.Println("refund status:", receipt.Status)
+	fmt.Println("refunded:", refunded)
+	// Output:
+	// refund status: 1
+	// refunded: 10000000000000000000
+}
@@ -0,0 +1,179 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+// Package wanclient builds Wanchain privacy transactions on the client, so that
+// the keys spending an OTA never have to be sent to a node.
+package wanclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain"
+	"github.com/wanchain/go-wanchain/accounts/abi/bind"
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethclient"
+)
+
+var (
+	ErrNotOTAOwner    = errors.New("OTA is not owned by the keys")
+	ErrOTAKeyMismatch = errors.New("private key does not match the OTA")
+	ErrInvalidMixSize = errors.New("invalid OTA mix set size")
+	ErrNoSigner       = errors.New("no signer to authorize the transaction with")
+	errShortOTAMixSet = errors.New("OTA mix set is smaller than requested")
+)
+
+// Backend is the node functionality needed to build and submit privacy
+// transactions. None of its methods receive private key material.
+type Backend interface {
+	bind.ContractTransactor
+
+	// GetOTAMixSet returns size OTAs, in WAddress format, holding the same
+	// value as ota, to hide it among in a ring signature. The decoys are
+	// selected as by q, or uniformly if q is nil.
+	GetOTAMixSet(ctx context.Context, ota []byte, size int, q *ethereum.DecoyQuery) ([][]byte, error)
+}
+
+// Verify that the RPC client can be used as a backend.
+var _ Backend = (*ethclient.Client)(nil)
+
+// OTAPrivateKey computes the private key of an OTA, in WAddress format, paid to
+// the WAddress of the key pair a, b.
+func OTAPrivateKey(a, b *ecdsa.PrivateKey, ota []byte) (*ecdsa.PrivateKey, error) {
+	A1, R, err := keystore.GeneratePKPairFromWAddress(ota)
+	if err != nil {
+		return nil, err
+	}
+	if !crypto.CompareA1(b.D.Bytes(), &a.PublicKey, R, A1) {
+		return nil, ErrNotOTAOwner
+	}
+	priv, _, err := crypto.GenerateOneTimePrivateKey2528(a, b, A1, R)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.ToECDSA(common.LeftPadBytes(priv.D.Bytes(), 32))
+}
+
+// OTAKeyImage returns the key image the ring signatures made with the private
+// key of an OTA carry, which the node records once the OTA is spent.
+func OTAKeyImage(otaKey *ecdsa.PrivateKey) []byte {
+	return crypto.FromECDSAPub(crypto.KeyImage(otaKey.D, &otaKey.PublicKey))
+}
+
+// RingSignOTA signs msg with the private key of an OTA hidden among the OTAs of
+// mixSet, and returns the signature in the format the precompiled contracts
+// decode.
+func RingSignOTA(msg []byte, otaKey *ecdsa.PrivateKey, mixSet [][]byte) (string, error) {
+	publicKeys := make([]*ecdsa.PublicKey, 0, len(mixSet)+1)
+	publicKeys = append(publicKeys, &otaKey.PublicKey)
+	for _, mix := range mixSet {
+		A, _, err := keystore.GeneratePKPairFromWAddress(mix)
+		if err != nil {
+			return "", err
+		}
+		publicKeys = append(publicKeys, A)
+	}
+	publicKeys, keyImage, w, q, err := crypto.RingSign(msg, otaKey.D, publicKeys)
+	if err != nil {
+		return "", err
+	}
+	return vm.EncodeRingSignOut(publicKeys, keyImage, w, q), nil
+}
+
+// RefundOTA refunds the value of an OTA to the account of opts. The OTA, in
+// WAddress format, is hidden among mixSize OTAs of the same value fetched from
+// the backend, selected as by decoys or uniformly if decoys is nil, and spent
+// with a ring signature made locally with otaKey. The refund transaction is
+// signed by opts.Signer and submitted as a raw transaction.
+func RefundOTA(opts *bind.TransactOpts, backend Backend, otaKey *ecdsa.PrivateKey, ota []byte, value *big.Int, mixSize int, decoys *ethereum.DecoyQuery) (*types.Transaction, error) {
+	if mixSize <= 0 {
+		return nil, ErrInvalidMixSize
+	}
+	A1, _, err := keystore.GeneratePKPairFromWAddress(ota)
+	if err != nil {
+		return nil, err
+	}
+	if A1.X.Cmp(otaKey.PublicKey.X) != 0 || A1.Y.Cmp(otaKey.PublicKey.Y) != 0 {
+		return nil, ErrOTAKeyMismatch
+	}
+	ctx := ensureContext(opts.Context)
+
+	// Hide the OTA among others of its value and spend it for the sender
+	mixSet, err := backend.GetOTAMixSet(ctx, ota, mixSize, decoys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve OTA mix set: %v", err)
+	}
+	if len(mixSet) < mixSize {
+		return nil, errShortOTAMixSet
+	}
+	ringSigned, err := RingSignOTA(opts.From.Bytes(), otaKey, mixSet)
+	if err != nil {
+		return nil, err
+	}
+	to, input, err := vm.PackRefundCoin(ringSigned, value)
+	if err != nil {
+		return nil, err
+	}
+	return transact(ctx, opts, backend, to, input)
+}
+
+// transact signs and submits a call of the contract at to, resolving the
+// parameters left empty in opts the way the contract bindings do.
+func transact(ctx context.Context, opts *bind.TransactOpts, backend Backend, to common.Address, input []byte) (*types.Transaction, error) {
+	var err error
+
+	value := opts.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+	var nonce uint64
+	if opts.Nonce == nil {
+		nonce, err = backend.PendingNonceAt(ctx, opts.From)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve account nonce: %v", err)
+		}
+	} else {
+		nonce = opts.Nonce.Uint64()
+	}
+	gasPrice := opts.GasPrice
+	if gasPrice == nil {
+		gasPrice, err = backend.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to suggest gas price: %v", err)
+		}
+	}
+	gasLimit := opts.GasLimit
+	if gasLimit == nil {
+		msg := ethereum.CallMsg{From: opts.From, To: &to, Value: value, Data: input}
+		gasLimit, err = backend.EstimateGas(ctx, msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to estimate gas needed: %v", err)
+		}
+	}
+	if opts.Signer == nil {
+		return nil, ErrNoSigner
+	}
+	signedTx, err := opts.Signer(types.HomesteadSigner{}, opts.From, types.NewTransaction(nonce, to, value, gasLimit, gasPrice, input))
+	if err != nil {
+		return nil, err
+	}
+	if err := backend.SendTransaction(ctx, signedTx); err != nil {
+		return nil, err
+	}
+	return signedTx, nil
+}
+
+// ensureContext is a helper method to ensure a context is not nil, even if the
+// user specified it as such.
+func ensureContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.TODO()
+	}
+	return ctx
+}
@@ -0,0 +1,141 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package wanclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/wanchain/go-wanchain"
+	"github.com/wanchain/go-wanchain/accounts/abi"
+	"github.com/wanchain/go-wanchain/accounts/abi/bind"
+	"github.com/wanchain/go-wanchain/accounts/abi/bind/backends"
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+)
+
+// Verify that the simulated backend can be used as a backend.
+var _ Backend = (*backends.SimulatedBackend)(nil)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
+	testBalance = new(big.Int).Mul(big.NewInt(1000), big.NewInt(1e18))
+	testValue   = new(big.Int).Mul(big.NewInt(10), big.NewInt(1e18))
+
+	testCoinAddr = common.BytesToAddress([]byte{100})
+	testCoinABI  = `[{"constant": false,"type": "function","stateMutability": "nonpayable","inputs": [{"name": "OtaAddr","type":"string"},{"name": "Value","type": "uint256"}],"name": "buyCoinNote","outputs": [{"name": "OtaAddr","type":"string"},{"name": "Value","type": "uint256"}]}]`
+)
+
+// newTestWAddress creates the key pair of a random WAddress.
+func newTestWAddress() (a, b *ecdsa.PrivateKey) {
+	a, _ = crypto.GenerateKey()
+	b, _ = crypto.GenerateKey()
+	return a, b
+}
+
+// newTestOTA generates an OTA paid to the WAddress of the key pair a, b.
+func newTestOTA(a, b *ecdsa.PrivateKey) []byte {
+	wAddr := keystore.GenerateWaddressFromPK(&a.PublicKey, &b.PublicKey)
+	A, B, err := keystore.GeneratePKPairFromWAddress(wAddr[:])
+	if err != nil {
+		panic(err)
+	}
+	pks := hexutil.PKPair2HexSlice(A, B)
+	ota, err := crypto.GenerateOneTimeKey(pks[0], pks[1], pks[2], pks[3])
+	if err != nil {
+		panic(err)
+	}
+	raw := common.FromHex(strings.Replace(strings.Join(ota, ""), "0x", "", -1))
+	otaAddr, err := keystore.WaddrFromUncompressedRawBytes(raw)
+	if err != nil {
+		panic(err)
+	}
+	return otaAddr[:]
+}
+
+// buyTestCoins buys a wancoin of testValue for every OTA from the test account
+// and mines the purchases.
+func buyTestCoins(backend *backends.SimulatedBackend, otas ...[]byte) {
+	coinAbi, err := abi.JSON(strings.NewReader(testCoinABI))
+	if err != nil {
+		panic(err)
+	}
+	nonce, _ := backend.PendingNonceAt(context.Background(), testAddr)
+	for i, ota := range otas {
+		payload, err := coinAbi.Pack("buyCoinNote", hexutil.Encode(ota), testValue)
+		if err != nil {
+			panic(err)
+		}
+		tx := types.NewTransaction(nonce+uint64(i), testCoinAddr, testValue, big.NewInt(1000000), big.NewInt(1), payload)
+		tx, err = types.SignTx(tx, types.HomesteadSigner{}, testKey)
+		if err != nil {
+			panic(err)
+		}
+		backend.SendTransaction(context.Background(), tx)
+	}
+	backend.Commit()
+}
+
+func TestRefundOTA(t *testing.T) {
+	backend := backends.NewSimulatedBackendEx(core.GenesisAlloc{testAddr: {Balance: testBalance}})
+	opts := bind.NewKeyedTransactor(testKey)
+
+	// Pay an OTA to the local keys and hide it among the ones of others
+	a, b := newTestWAddress()
+	ota := newTestOTA(a, b)
+	buyTestCoins(backend, ota, newTestOTA(newTestWAddress()), newTestOTA(newTestWAddress()), newTestOTA(newTestWAddress()))
+
+	if _, err := OTAPrivateKey(b, a, ota); err != ErrNotOTAOwner {
+		t.Fatalf("OTA key of foreign keys: have %v, want %v", err, ErrNotOTAOwner)
+	}
+	otaKey, err := OTAPrivateKey(a, b, ota)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RefundOTA(opts, backend, otaKey, ota, testValue, 0, nil); err != ErrInvalidMixSize {
+		t.Fatalf("refund with an empty mix set: have %v, want %v", err, ErrInvalidMixSize)
+	}
+	if _, err := RefundOTA(opts, backend, testKey, ota, testValue, 2, nil); err != ErrOTAKeyMismatch {
+		t.Fatalf("refund with a foreign key: have %v, want %v", err, ErrOTAKeyMismatch)
+	}
+	if _, err := RefundOTA(opts, backend, otaKey, ota, testValue, 2, &ethereum.DecoyQuery{Strategy: "recent"}); err == nil {
+		t.Fatalf("refund with recent decoys succeeded without an OTA index")
+	}
+
+	// Refund the OTA and check the value was paid to the sender
+	before, _ := backend.BalanceAt(context.Background(), testAddr, nil)
+	tx, err := RefundOTA(opts, backend, otaKey, ota, testValue, 2, &ethereum.DecoyQuery{Strategy: "uniform"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Commit()
+
+	receipt, _ := backend.TransactionReceipt(context.Background(), tx.Hash())
+	if receipt == nil || receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("refund failed: receipt %v", receipt)
+	}
+	want := new(big.Int).Sub(before, new(big.Int).Mul(receipt.GasUsed, tx.GasPrice()))
+	want.Add(want, testValue)
+	if after, _ := backend.BalanceAt(context.Background(), testAddr, nil); after.Cmp(want) != 0 {
+		t.Errorf("balance after refund mismatch: have %v, want %v", after, want)
+	}
+
+	// A second refund reuses the key image and is rejected
+	tx, err = RefundOTA(opts, backend, otaKey, ota, testValue, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Commit()
+
+	if receipt, _ := backend.TransactionReceipt(context.Background(), tx.Hash()); receipt == nil || receipt.Status != types.ReceiptStatusFailed {
+		t.Fatalf("double refund succeeded: receipt %v", receipt)
+	}
+}